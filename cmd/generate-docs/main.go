@@ -93,6 +93,7 @@ func generateDirectoryStructure() string {
 	buf.WriteString("├── daemon.sock         # Unix socket for CLI communication\n")
 	buf.WriteString("├── daemon.log          # Daemon activity log\n")
 	buf.WriteString("├── state.json          # Persistent daemon state\n")
+	buf.WriteString("├── state-backups/      # Recent copies of state.json\n")
 	buf.WriteString("│\n")
 	buf.WriteString("├── repos/              # Cloned repositories\n")
 	buf.WriteString("│   └── <repo-name>/    # Git clone of tracked repo\n")
@@ -112,6 +113,18 @@ func generateDirectoryStructure() string {
 	buf.WriteString("    └── <agent-name>.md\n")
 	buf.WriteString("```\n\n")
 
+	// XDG layout
+	buf.WriteString("## XDG Layout\n\n")
+	buf.WriteString("The layout above is the default. The XDG layout splits it across the XDG base directories, ")
+	buf.WriteString("so backups of the state can skip the logs:\n\n")
+	buf.WriteString("| Directory | Contents |\n")
+	buf.WriteString("|-----------|----------|\n")
+	buf.WriteString("| `$XDG_STATE_HOME/multiclaude/` (`~/.local/state`) | Everything above except the logs |\n")
+	buf.WriteString("| `$XDG_CACHE_HOME/multiclaude/` (`~/.cache`) | `daemon.log` and `output/` |\n")
+	buf.WriteString("| `$XDG_CONFIG_HOME/multiclaude/` (`~/.config`) | `paths.json` |\n\n")
+	buf.WriteString("It is opt-in: `{\"layout\": \"xdg\"}` in the XDG `paths.json` selects it, and `MULTICLAUDE_XDG=1` or `=0` overrides that. ")
+	buf.WriteString("`multiclaude migrate-paths` moves an existing `~/.multiclaude` into it.\n\n")
+
 	// Generate detailed descriptions
 	docs := config.DirectoryDocs()
 	buf.WriteString("## Path Descriptions\n\n")
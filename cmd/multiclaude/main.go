@@ -10,8 +10,10 @@ import (
 
 func main() {
 	if err := run(); err != nil {
-		fmt.Fprintln(os.Stderr, errors.Format(err))
-		os.Exit(1)
+		if msg := errors.Format(err); msg != "" {
+			fmt.Fprintln(os.Stderr, msg)
+		}
+		os.Exit(errors.ExitCode(err))
 	}
 }
 
@@ -0,0 +1,126 @@
+// Package archive keeps git bundles of the branches of removed workers, so
+// that their work can be restored with git fetch after the branch is gone.
+// Bundles live in a per-repository directory and are pruned by age and total
+// size.
+package archive
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// Ext is the file extension of archived bundles
+const Ext = ".bundle"
+
+// Archive is a bundle in an archives directory
+type Archive struct {
+	Path    string
+	Name    string    // File name, e.g. work-fox-20261015-143000.bundle
+	Created time.Time // Modification time of the file
+	Size    int64
+}
+
+// FileName returns the file name of the bundle of branch archived at t, e.g.
+// work-fox-20261015-143000.bundle for work/fox
+func FileName(branch string, t time.Time) string {
+	return strings.ReplaceAll(branch, "/", "-") + "-" + t.Format("20060102-150405") + Ext
+}
+
+// Create bundles branch into dir and returns the path of the bundle
+func Create(wt *worktree.Manager, dir, branch string, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create archives directory: %w", err)
+	}
+	path := filepath.Join(dir, FileName(branch, now))
+	if err := wt.CreateBundle(branch, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// List returns the bundles in dir, newest first. A missing directory has no
+// bundles.
+func List(dir string) ([]Archive, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archives directory: %w", err)
+	}
+
+	var archives []Archive
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), Ext) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed since ReadDir
+		}
+		archives = append(archives, Archive{
+			Path:    filepath.Join(dir, entry.Name()),
+			Name:    entry.Name(),
+			Created: info.ModTime(),
+			Size:    info.Size(),
+		})
+	}
+	sort.Slice(archives, func(i, j int) bool { return archives[i].Created.After(archives[j].Created) })
+	return archives, nil
+}
+
+// Resolve returns the path of a bundle given by name. A bare file name is
+// looked up in dir; anything else is taken as a path.
+func Resolve(dir, name string) (string, error) {
+	path := name
+	if !strings.ContainsRune(name, filepath.Separator) {
+		path = filepath.Join(dir, name)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("bundle %s not found: %w", name, err)
+	}
+	return filepath.Abs(path)
+}
+
+// Prune removes the bundles in dir older than maxAge, then the oldest ones
+// until the rest take at most maxSize bytes. A zero maxAge or maxSize does not
+// limit. It returns the bundles it removed.
+func Prune(dir string, maxAge time.Duration, maxSize int64, now time.Time) ([]Archive, error) {
+	archives, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var kept, expired []Archive
+	var total int64
+	for _, a := range archives {
+		if maxAge > 0 && now.Sub(a.Created) > maxAge {
+			expired = append(expired, a)
+			continue
+		}
+		kept = append(kept, a)
+		total += a.Size
+	}
+	// kept is newest first, so the oldest go first
+	for maxSize > 0 && total > maxSize && len(kept) > 0 {
+		oldest := kept[len(kept)-1]
+		kept = kept[:len(kept)-1]
+		expired = append(expired, oldest)
+		total -= oldest.Size
+	}
+
+	var removed []Archive
+	for _, a := range expired {
+		if err := os.Remove(a.Path); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove %s: %w", a.Name, err)
+		}
+		removed = append(removed, a)
+	}
+	return removed, nil
+}
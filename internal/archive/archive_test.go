@@ -0,0 +1,122 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeBundle writes a fake bundle of size bytes last modified at t
+func writeBundle(t *testing.T, dir, name string, size int, modTime time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set mtime of %s: %v", name, err)
+	}
+}
+
+func names(archives []Archive) []string {
+	var out []string
+	for _, a := range archives {
+		out = append(out, a.Name)
+	}
+	return out
+}
+
+func TestFileName(t *testing.T) {
+	at := time.Date(2026, 10, 15, 14, 30, 0, 0, time.UTC)
+	if got := FileName("work/fox", at); got != "work-fox-20261015-143000.bundle" {
+		t.Errorf("FileName() = %q", got)
+	}
+}
+
+func TestList(t *testing.T) {
+	if archives, err := List(filepath.Join(t.TempDir(), "missing")); err != nil || archives != nil {
+		t.Errorf("List() of a missing directory = %v, %v; want nothing", archives, err)
+	}
+
+	dir := t.TempDir()
+	now := time.Now()
+	writeBundle(t, dir, "old.bundle", 10, now.Add(-2*time.Hour))
+	writeBundle(t, dir, "new.bundle", 20, now)
+	writeBundle(t, dir, "notes.txt", 5, now)
+
+	archives, err := List(dir)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if got := names(archives); len(got) != 2 || got[0] != "new.bundle" || got[1] != "old.bundle" {
+		t.Errorf("List() = %v, want the bundles newest first", got)
+	}
+	if archives[0].Size != 20 {
+		t.Errorf("Size = %d, want 20", archives[0].Size)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Now()
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		writeBundle(t, dir, "a.bundle", 100, now.Add(-40*24*time.Hour))
+		writeBundle(t, dir, "b.bundle", 100, now.Add(-3*time.Hour))
+		writeBundle(t, dir, "c.bundle", 100, now.Add(-2*time.Hour))
+		writeBundle(t, dir, "d.bundle", 100, now.Add(-1*time.Hour))
+		return dir
+	}
+
+	tests := []struct {
+		name    string
+		maxAge  time.Duration
+		maxSize int64
+		removed []string
+		kept    []string
+	}{
+		{"by age", 30 * 24 * time.Hour, 0, []string{"a.bundle"}, []string{"d.bundle", "c.bundle", "b.bundle"}},
+		{"by size, oldest first", 0, 250, []string{"a.bundle", "b.bundle"}, []string{"d.bundle", "c.bundle"}},
+		{"both", 30 * 24 * time.Hour, 100, []string{"a.bundle", "b.bundle", "c.bundle"}, []string{"d.bundle"}},
+		{"no limits", 0, 0, nil, []string{"d.bundle", "c.bundle", "b.bundle", "a.bundle"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setup(t)
+			removed, err := Prune(dir, tt.maxAge, tt.maxSize, now)
+			if err != nil {
+				t.Fatalf("Prune() failed: %v", err)
+			}
+			if got := names(removed); len(got) != len(tt.removed) {
+				t.Errorf("removed %v, want %v", got, tt.removed)
+			}
+			kept, _ := List(dir)
+			if got := names(kept); len(got) != len(tt.kept) {
+				t.Errorf("kept %v, want %v", got, tt.kept)
+			} else {
+				for i := range got {
+					if got[i] != tt.kept[i] {
+						t.Errorf("kept %v, want %v", got, tt.kept)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	writeBundle(t, dir, "work-fox.bundle", 1, time.Now())
+
+	path, err := Resolve(dir, "work-fox.bundle")
+	if err != nil || path != filepath.Join(dir, "work-fox.bundle") {
+		t.Errorf("Resolve() of a name = %q, %v", path, err)
+	}
+	if path, err := Resolve(t.TempDir(), filepath.Join(dir, "work-fox.bundle")); err != nil || path != filepath.Join(dir, "work-fox.bundle") {
+		t.Errorf("Resolve() of a path = %q, %v", path, err)
+	}
+	if _, err := Resolve(dir, "missing.bundle"); err == nil {
+		t.Error("Resolve() of a missing bundle should fail")
+	}
+}
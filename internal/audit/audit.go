@@ -0,0 +1,268 @@
+// Package audit records mutating operations to an append-only NDJSON log so
+// that changes to repos, agents and config can be traced to who made them.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// SourceDaemon marks entries written by the daemon for socket commands
+	SourceDaemon = "daemon"
+	// SourceCLI marks entries for CLI operations that bypass the daemon
+	SourceCLI = "cli"
+
+	// DefaultBufferSize is the number of entries the daemon queues before dropping
+	DefaultBufferSize = 256
+
+	redactedValue = "<redacted>"
+)
+
+// secretKeyParts are substrings of argument names whose values are never logged
+var secretKeyParts = []string{"token", "secret", "password", "passwd", "credential", "api_key", "apikey"}
+
+// Caller describes the process that requested an operation
+type Caller struct {
+	PID   int    `json:"pid,omitempty"`
+	UID   int    `json:"uid"`
+	Cwd   string `json:"cwd,omitempty"`
+	Repo  string `json:"repo,omitempty"`
+	Agent string `json:"agent,omitempty"`
+}
+
+// Entry is a single audit log record
+type Entry struct {
+	Time    time.Time              `json:"time"`
+	Source  string                 `json:"source"`
+	Command string                 `json:"command"`
+	Args    map[string]interface{} `json:"args,omitempty"`
+	Caller  *Caller                `json:"caller,omitempty"`
+	Success bool                   `json:"success"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// CurrentCaller returns caller info for this process
+func CurrentCaller() *Caller {
+	cwd, _ := os.Getwd()
+	return &Caller{
+		PID: os.Getpid(),
+		UID: os.Getuid(),
+		Cwd: cwd,
+	}
+}
+
+// CallerFromMeta builds caller info from socket request metadata.
+// Returns nil if no metadata was sent.
+func CallerFromMeta(meta map[string]string) *Caller {
+	if len(meta) == 0 {
+		return nil
+	}
+	caller := &Caller{Cwd: meta["cwd"]}
+	caller.PID, _ = strconv.Atoi(meta["pid"])
+	caller.UID, _ = strconv.Atoi(meta["uid"])
+	return caller
+}
+
+// RedactArgs returns a copy of args with secret-bearing values replaced
+func RedactArgs(args map[string]interface{}) map[string]interface{} {
+	return RedactArgsMatching(args, secretKeyParts)
+}
+
+// RedactArgsMatching returns a copy of args with the values of keys
+// containing any of keyParts (case-insensitive) replaced
+func RedactArgsMatching(args map[string]interface{}, keyParts []string) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if matchesKey(k, keyParts) {
+			out[k] = redactedValue
+			continue
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			out[k] = RedactArgsMatching(nested, keyParts)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func matchesKey(key string, keyParts []string) bool {
+	key = strings.ToLower(key)
+	for _, part := range keyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Append synchronously writes a single entry to the log at path
+func Append(path string, entry Entry) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+	return writeEntry(f, entry)
+}
+
+func writeEntry(f *os.File, entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Args = RedactArgs(entry.Args)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// Logger writes entries in the background. Log never blocks: when the
+// buffer is full, entries are dropped and counted instead.
+type Logger struct {
+	path    string
+	entries chan Entry
+	done    chan struct{}
+	dropped atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewLogger starts a background writer appending to path
+func NewLogger(path string, bufferSize int) *Logger {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	l := &Logger{
+		path:    path,
+		entries: make(chan Entry, bufferSize),
+		done:    make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Log queues an entry for writing without blocking
+func (l *Logger) Log(entry Entry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		l.dropped.Add(1)
+		return
+	}
+
+	select {
+	case l.entries <- entry:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of entries discarded because the buffer was
+// full, the log was closed, or the file could not be written
+func (l *Logger) Dropped() int64 {
+	return l.dropped.Load()
+}
+
+// Close stops accepting entries and waits for queued entries to be written
+func (l *Logger) Close() {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.closed = true
+	close(l.entries)
+	l.mu.Unlock()
+
+	<-l.done
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+
+	var f *os.File
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	for entry := range l.entries {
+		if f == nil {
+			var err error
+			f, err = os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+			if err != nil {
+				l.dropped.Add(1)
+				f = nil
+				continue
+			}
+		}
+		if err := writeEntry(f, entry); err != nil {
+			l.dropped.Add(1)
+		}
+	}
+}
+
+// Filter selects entries when reading the log
+type Filter struct {
+	// Since excludes entries older than this time (zero means no limit)
+	Since time.Time
+	// Command matches entries with this command name (empty matches all)
+	Command string
+}
+
+// Read returns entries from the log at path that match the filter, oldest
+// first. A missing log yields no entries; malformed lines are skipped.
+func Read(path string, filter Filter) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Entry{}, nil
+		}
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if !filter.Since.IsZero() && entry.Time.Before(filter.Since) {
+			continue
+		}
+		if filter.Command != "" && entry.Command != filter.Command {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
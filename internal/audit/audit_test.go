@@ -0,0 +1,178 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactArgs(t *testing.T) {
+	args := map[string]interface{}{
+		"repo":      "my-repo",
+		"api_token": "sk-123",
+		"nested": map[string]interface{}{
+			"Password": "hunter2",
+			"name":     "ok",
+		},
+	}
+
+	got := RedactArgs(args)
+	if got["repo"] != "my-repo" {
+		t.Errorf("repo should be kept, got %v", got["repo"])
+	}
+	if got["api_token"] != redactedValue {
+		t.Errorf("api_token should be redacted, got %v", got["api_token"])
+	}
+	nested := got["nested"].(map[string]interface{})
+	if nested["Password"] != redactedValue || nested["name"] != "ok" {
+		t.Errorf("nested redaction wrong: %v", nested)
+	}
+
+	// The input is not modified
+	if args["api_token"] != "sk-123" {
+		t.Error("RedactArgs should not modify its input")
+	}
+	if RedactArgs(nil) != nil {
+		t.Error("RedactArgs(nil) should be nil")
+	}
+}
+
+func TestRedactArgsMatching(t *testing.T) {
+	got := RedactArgsMatching(map[string]interface{}{"ssh_key": "abc", "repo": "r"}, []string{"key"})
+	if got["ssh_key"] != redactedValue || got["repo"] != "r" {
+		t.Errorf("RedactArgsMatching() = %v", got)
+	}
+}
+
+func TestCallerFromMeta(t *testing.T) {
+	if CallerFromMeta(nil) != nil {
+		t.Error("CallerFromMeta(nil) should be nil")
+	}
+
+	caller := CallerFromMeta(map[string]string{"pid": "42", "uid": "1000", "cwd": "/work"})
+	if caller.PID != 42 || caller.UID != 1000 || caller.Cwd != "/work" {
+		t.Errorf("CallerFromMeta() = %+v", caller)
+	}
+}
+
+func TestAppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	// Missing log reads as empty
+	entries, err := Read(path, Filter{})
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Read() on missing log = %v, %v", entries, err)
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, e := range []Entry{
+		{Time: old, Source: SourceDaemon, Command: "add_repo", Success: true},
+		{Source: SourceDaemon, Command: "remove_agent", Args: map[string]interface{}{"agent": "w1", "token": "x"}, Success: true},
+		{Source: SourceCLI, Command: "local_cleanup", Caller: CurrentCaller(), Success: false, Error: "boom"},
+	} {
+		if err := Append(path, e); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	// A malformed line is skipped
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("not json\n")
+	f.Close()
+
+	entries, err = Read(path, Filter{})
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Read() returned %d entries, want 3", len(entries))
+	}
+	if entries[1].Args["token"] != redactedValue {
+		t.Errorf("secret args should be redacted on write, got %v", entries[1].Args["token"])
+	}
+	if entries[2].Caller == nil || entries[2].Caller.PID != os.Getpid() {
+		t.Errorf("caller not recorded: %+v", entries[2].Caller)
+	}
+
+	entries, _ = Read(path, Filter{Since: time.Now().Add(-24 * time.Hour)})
+	if len(entries) != 2 {
+		t.Errorf("Since filter returned %d entries, want 2", len(entries))
+	}
+	entries, _ = Read(path, Filter{Command: "remove_agent"})
+	if len(entries) != 1 || entries[0].Command != "remove_agent" {
+		t.Errorf("Command filter returned %v", entries)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() failed: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("audit log mode = %o, want 600", perm)
+	}
+}
+
+func TestLoggerWritesInBackground(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := NewLogger(path, 16)
+
+	for i := 0; i < 5; i++ {
+		l.Log(Entry{Source: SourceDaemon, Command: "add_agent", Success: true})
+	}
+	l.Close()
+
+	entries, err := Read(path, Filter{})
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Errorf("got %d entries, want 5", len(entries))
+	}
+	if entries[0].Time.IsZero() {
+		t.Error("Log should timestamp entries")
+	}
+
+	// Logging after Close is dropped rather than panicking
+	l.Log(Entry{Command: "late"})
+	if l.Dropped() != 1 {
+		t.Errorf("Dropped() = %d, want 1", l.Dropped())
+	}
+	l.Close()
+}
+
+func TestLoggerNeverBlocks(t *testing.T) {
+	// Point the writer at a directory so every write fails; the buffer
+	// fills up and further entries must be dropped, not block
+	l := NewLogger(t.TempDir(), 1)
+	defer l.Close()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			l.Log(Entry{Command: "add_agent"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Log blocked with a full buffer")
+	}
+	if l.Dropped() == 0 {
+		t.Error("expected entries to be dropped")
+	}
+}
+
+func TestEntryJSONIsSingleLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := Append(path, Entry{Command: "add_agent", Args: map[string]interface{}{"task": "line one\nline two"}}); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Count(string(data), "\n") != 1 {
+		t.Errorf("entry should be a single NDJSON line: %q", data)
+	}
+}
@@ -1,59 +1,69 @@
 package bugreport
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"runtime"
 	"strconv"
 	"strings"
 
+	"github.com/dlorenc/multiclaude/internal/envfile"
 	"github.com/dlorenc/multiclaude/internal/redact"
 	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/claude"
 	"github.com/dlorenc/multiclaude/pkg/config"
 )
 
 // Report contains all collected diagnostic information
 type Report struct {
-	Description string
-	Verbose     bool
+	Description string `json:"description"`
+	Verbose     bool   `json:"verbose"`
 
 	// Environment
-	Version   string
-	GoVersion string
-	OS        string
-	Arch      string
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
 
 	// Tool versions
-	TmuxVersion  string
-	GitVersion   string
-	ClaudeExists bool
+	TmuxVersion   string `json:"tmuxVersion"`
+	GitVersion    string `json:"gitVersion"`
+	ClaudeExists  bool   `json:"claudeExists"`
+	ClaudeVersion string `json:"claudeVersion"`
 
 	// Daemon status
-	DaemonRunning bool
-	DaemonPID     int
+	DaemonRunning bool `json:"daemonRunning"`
+	DaemonPID     int  `json:"daemonPid"`
 
 	// Statistics
-	RepoCount        int
-	WorkerCount      int
-	SupervisorCount  int
-	MergeQueueCount  int
-	WorkspaceCount   int
-	ReviewAgentCount int
+	RepoCount        int `json:"repoCount"`
+	WorkerCount      int `json:"workerCount"`
+	SupervisorCount  int `json:"supervisorCount"`
+	MergeQueueCount  int `json:"mergeQueueCount"`
+	WorkspaceCount   int `json:"workspaceCount"`
+	ReviewAgentCount int `json:"reviewAgentCount"`
+	EphemeralCount   int `json:"ephemeralCount"`
 
 	// Verbose stats (per-repo breakdown)
-	RepoStats []RepoStat
+	RepoStats []RepoStat `json:"repoStats,omitempty"`
 
 	// Logs
-	DaemonLogTail string
+	DaemonLogTail string `json:"daemonLogTail"`
+
+	// State backups, listed only when the collector's IncludeBackups is
+	// set. Their content is never included.
+	StateBackupsIncluded bool               `json:"stateBackupsIncluded"`
+	StateBackups         []state.BackupInfo `json:"stateBackups,omitempty"`
 }
 
 // RepoStat contains per-repo statistics for verbose mode
 type RepoStat struct {
-	Name           string // redacted
-	WorkerCount    int
-	HasSupervisor  bool
-	HasMergeQueue  bool
-	WorkspaceCount int
+	Name           string `json:"name"` // redacted
+	WorkerCount    int    `json:"workerCount"`
+	HasSupervisor  bool   `json:"hasSupervisor"`
+	HasMergeQueue  bool   `json:"hasMergeQueue"`
+	WorkspaceCount int    `json:"workspaceCount"`
 }
 
 // Collector gathers diagnostic information
@@ -61,6 +71,10 @@ type Collector struct {
 	paths    *config.Paths
 	redactor *redact.Redactor
 	version  string
+
+	// IncludeBackups lists the state backups in the report. They are left
+	// out by default.
+	IncludeBackups bool
 }
 
 // NewCollector creates a new diagnostic collector
@@ -87,6 +101,9 @@ func (c *Collector) Collect(description string, verbose bool) (*Report, error) {
 	report.TmuxVersion = c.getTmuxVersion()
 	report.GitVersion = c.getGitVersion()
 	report.ClaudeExists = c.checkClaudeExists()
+	if report.ClaudeExists {
+		report.ClaudeVersion = c.getClaudeVersion()
+	}
 
 	// Check daemon status
 	report.DaemonRunning, report.DaemonPID = c.checkDaemonStatus()
@@ -99,6 +116,11 @@ func (c *Collector) Collect(description string, verbose bool) (*Report, error) {
 	// Collect daemon log tail
 	report.DaemonLogTail = c.collectDaemonLog()
 
+	if c.IncludeBackups {
+		report.StateBackupsIncluded = true
+		report.StateBackups, _, _ = state.ListBackups(c.paths.StateFile)
+	}
+
 	return report, nil
 }
 
@@ -122,6 +144,15 @@ func (c *Collector) getGitVersion() string {
 	return strings.TrimSpace(string(output))
 }
 
+// getClaudeVersion returns the claude CLI version or an error message
+func (c *Collector) getClaudeVersion() string {
+	version, err := claude.BinaryVersion(context.Background(), claude.ResolveBinaryPath())
+	if err != nil {
+		return "unknown"
+	}
+	return version
+}
+
 // checkClaudeExists checks if the claude CLI is available
 func (c *Collector) checkClaudeExists() bool {
 	_, err := exec.LookPath("claude")
@@ -170,7 +201,19 @@ func (c *Collector) collectAgentStats(report *Report) error {
 			Name: c.redactor.RepoName(repoName),
 		}
 
+		// Values from repo env files must never appear in the report
+		if env, err := envfile.ForRepo(c.paths.RepoDir(repoName), repo.EnvFile); err == nil {
+			for _, v := range env {
+				c.redactor.AddSecrets(v)
+			}
+		}
+
 		for _, agent := range repo.Agents {
+			// Nor may values of agents' own environment
+			for _, v := range agent.Environment {
+				c.redactor.AddSecrets(v)
+			}
+
 			switch agent.Type {
 			case state.AgentTypeWorker:
 				report.WorkerCount++
@@ -186,6 +229,8 @@ func (c *Collector) collectAgentStats(report *Report) error {
 				repoStat.WorkspaceCount++
 			case state.AgentTypeReview:
 				report.ReviewAgentCount++
+			case state.AgentTypeEphemeral:
+				report.EphemeralCount++
 			}
 		}
 
@@ -178,6 +178,60 @@ func TestCollector_CollectVerbose(t *testing.T) {
 	}
 }
 
+func TestCollector_RedactsEnvSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &config.Paths{
+		Root:      tmpDir,
+		DaemonPID: filepath.Join(tmpDir, "daemon.pid"),
+		DaemonLog: filepath.Join(tmpDir, "daemon.log"),
+		StateFile: filepath.Join(tmpDir, "state.json"),
+		ReposDir:  filepath.Join(tmpDir, "repos"),
+	}
+
+	// Repo env file plus an extra --env-file
+	repoEnvDir := filepath.Join(paths.RepoDir("test-repo"), ".multiclaude")
+	if err := os.MkdirAll(repoEnvDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(repoEnvDir, "env"), []byte("STAGING_TOKEN=s3cr3t-staging\n"), 0600)
+	extraEnv := filepath.Join(tmpDir, "extra.env")
+	os.WriteFile(extraEnv, []byte("OTHER_KEY=another-value-42\n"), 0600)
+
+	testState := struct {
+		Repos map[string]*state.Repository `json:"repos"`
+	}{
+		Repos: map[string]*state.Repository{
+			"test-repo": {
+				TmuxSession: "test-session",
+				Agents: map[string]state.Agent{
+					"supervisor": {
+						Type:        state.AgentTypeSupervisor,
+						Environment: map[string]string{"VAULT_TOKEN": "hvs-agent-only-7"},
+					},
+				},
+				EnvFile: extraEnv,
+			},
+		},
+	}
+	stateData, _ := json.Marshal(testState)
+	os.WriteFile(paths.StateFile, stateData, 0644)
+
+	logContent := "worker output: token=s3cr3t-staging other=another-value-42 vault=hvs-agent-only-7"
+	os.WriteFile(paths.DaemonLog, []byte(logContent), 0644)
+
+	report, err := NewCollector(paths, "1.0.0-test").Collect("", false)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	if strings.Contains(report.DaemonLogTail, "s3cr3t-staging") || strings.Contains(report.DaemonLogTail, "another-value-42") || strings.Contains(report.DaemonLogTail, "hvs-agent-only-7") {
+		t.Errorf("daemon log tail contains env secret: %s", report.DaemonLogTail)
+	}
+	if !strings.Contains(report.DaemonLogTail, "<secret>") {
+		t.Errorf("expected <secret> placeholder in log tail: %s", report.DaemonLogTail)
+	}
+}
+
 func TestFormatMarkdown(t *testing.T) {
 	report := &Report{
 		Description:      "Test bug",
@@ -188,6 +242,7 @@ func TestFormatMarkdown(t *testing.T) {
 		TmuxVersion:      "tmux 3.3a",
 		GitVersion:       "git version 2.40.0",
 		ClaudeExists:     true,
+		ClaudeVersion:    "1.5.0",
 		DaemonRunning:    true,
 		DaemonPID:        12345,
 		RepoCount:        2,
@@ -220,6 +275,9 @@ func TestFormatMarkdown(t *testing.T) {
 	if !strings.Contains(markdown, "## Daemon Status") {
 		t.Error("missing daemon status section")
 	}
+	if !strings.Contains(markdown, "| claude CLI | installed (1.5.0) |") {
+		t.Error("missing claude CLI version")
+	}
 	if !strings.Contains(markdown, "Running (PID: 12345)") {
 		t.Error("missing daemon PID")
 	}
@@ -320,3 +378,47 @@ func TestFormatMarkdown_StalePID(t *testing.T) {
 		t.Error("should show stale PID when daemon is not running but PID exists")
 	}
 }
+
+func TestFormatJSON(t *testing.T) {
+	report := &Report{
+		Description:   "Test bug",
+		Version:       "1.0.0",
+		OS:            "darwin",
+		DaemonRunning: true,
+		DaemonPID:     12345,
+		WorkerCount:   5,
+		RepoStats:     []RepoStat{{Name: "repo-1", WorkerCount: 5, HasSupervisor: true}},
+	}
+
+	data, err := FormatJSON(report)
+	if err != nil {
+		t.Fatalf("FormatJSON() failed: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("FormatJSON() produced invalid JSON: %v\n%s", err, data)
+	}
+	for key, want := range map[string]interface{}{
+		"description":   "Test bug",
+		"os":            "darwin",
+		"daemonRunning": true,
+		"daemonPid":     float64(12345),
+		"workerCount":   float64(5),
+	} {
+		if decoded[key] != want {
+			t.Errorf("%s = %v, want %v", key, decoded[key], want)
+		}
+	}
+	stats, _ := decoded["repoStats"].([]interface{})
+	if len(stats) != 1 || stats[0].(map[string]interface{})["hasSupervisor"] != true {
+		t.Errorf("repoStats = %v, want one repo with a supervisor", decoded["repoStats"])
+	}
+	if _, ok := decoded["stateBackups"]; ok {
+		t.Error("stateBackups should be left out when not collected")
+	}
+
+	var roundTrip Report
+	if err := json.Unmarshal(data, &roundTrip); err != nil || roundTrip.DaemonPID != 12345 || roundTrip.RepoStats[0].Name != "repo-1" {
+		t.Errorf("round trip = %+v, %v", roundTrip, err)
+	}
+}
@@ -1,6 +1,7 @@
 package bugreport
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -38,6 +39,9 @@ func FormatMarkdown(report *Report) string {
 	claudeStatus := "not found"
 	if report.ClaudeExists {
 		claudeStatus = "installed"
+		if report.ClaudeVersion != "" {
+			claudeStatus = "installed (" + report.ClaudeVersion + ")"
+		}
 	}
 	sb.WriteString(fmt.Sprintf("| claude CLI | %s |\n", claudeStatus))
 	sb.WriteString("\n")
@@ -63,6 +67,7 @@ func FormatMarkdown(report *Report) string {
 	sb.WriteString(fmt.Sprintf("| Merge Queues | %d |\n", report.MergeQueueCount))
 	sb.WriteString(fmt.Sprintf("| Workspaces | %d |\n", report.WorkspaceCount))
 	sb.WriteString(fmt.Sprintf("| Review Agents | %d |\n", report.ReviewAgentCount))
+	sb.WriteString(fmt.Sprintf("| Ephemeral Agents | %d |\n", report.EphemeralCount))
 	sb.WriteString("\n")
 
 	// Verbose per-repo breakdown
@@ -85,6 +90,21 @@ func FormatMarkdown(report *Report) string {
 		sb.WriteString("\n")
 	}
 
+	// State backups, when asked for
+	if report.StateBackupsIncluded {
+		sb.WriteString("## State Backups\n\n")
+		if len(report.StateBackups) == 0 {
+			sb.WriteString("No backups\n\n")
+		} else {
+			sb.WriteString("| Backup | Size |\n")
+			sb.WriteString("|--------|------|\n")
+			for _, b := range report.StateBackups {
+				sb.WriteString(fmt.Sprintf("| %s | %d |\n", b.ID, b.Size))
+			}
+			sb.WriteString("\n")
+		}
+	}
+
 	// Daemon log section
 	sb.WriteString("## Daemon Log (last 50 lines, redacted)\n\n")
 	sb.WriteString("```\n")
@@ -96,3 +116,9 @@ func FormatMarkdown(report *Report) string {
 
 	return sb.String()
 }
+
+// FormatJSON formats the report as a JSON object with the fields of
+// Report, for tools that collect reports automatically
+func FormatJSON(report *Report) ([]byte, error) {
+	return json.Marshal(report)
+}
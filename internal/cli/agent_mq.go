@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// mqTrackPR records the merge-queue's status for a PR so it survives restarts
+func (c *CLI) mqTrackPR(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude agent mq track <pr-number> [--status <status>] [--url <url>] [--notes <text>] [--repo <repo>]")
+	}
+	number, err := parsePRNumber(posArgs[0])
+	if err != nil {
+		return err
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "mq_track_pr",
+		Args: map[string]interface{}{
+			"repo":   repoName,
+			"number": number,
+			"url":    flags["url"],
+			"status": flags["status"],
+			"notes":  flags["notes"],
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("tracking PR", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to track PR", fmt.Errorf("%s", resp.Error))
+	}
+
+	fmt.Printf("Tracking PR #%d", number)
+	if status := flags["status"]; status != "" {
+		fmt.Printf(" (%s)", status)
+	}
+	fmt.Println()
+	return nil
+}
+
+// mqUntrackPR removes the merge-queue's record for a PR
+func (c *CLI) mqUntrackPR(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude agent mq untrack <pr-number> [--repo <repo>]")
+	}
+	number, err := parsePRNumber(posArgs[0])
+	if err != nil {
+		return err
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "mq_untrack_pr",
+		Args: map[string]interface{}{
+			"repo":   repoName,
+			"number": number,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("untracking PR", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to untrack PR", fmt.Errorf("%s", resp.Error))
+	}
+
+	fmt.Printf("Stopped tracking PR #%d\n", number)
+	return nil
+}
+
+// mqListPRs lists the PRs the merge-queue is tracking
+func (c *CLI) mqListPRs(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "mq_list_prs",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("listing tracked PRs", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to list tracked PRs", fmt.Errorf("%s", resp.Error))
+	}
+
+	prs, _ := resp.Data.([]interface{})
+	if len(prs) == 0 {
+		fmt.Printf("No PRs tracked by the merge queue in '%s'\n", repoName)
+		format.Dimmed("\nTrack a PR with: multiclaude agent mq track <pr-number> --status <status>")
+		return nil
+	}
+
+	format.Header("PRs tracked by the merge queue in '%s' (%d):", repoName, len(prs))
+	fmt.Println()
+
+	table := format.NewColoredTable("PR", "STATUS", "LAST ACTION", "NOTES")
+	for _, p := range prs {
+		pr, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		number, _ := pr["number"].(float64)
+		status, _ := pr["status"].(string)
+		notes, _ := pr["notes"].(string)
+		if status == "" {
+			status = "-"
+		}
+		lastAction := "-"
+		if ts, ok := pr["last_action"].(string); ok {
+			if t, err := time.Parse(time.RFC3339, ts); err == nil {
+				lastAction = format.TimeAgo(t)
+			}
+		}
+		table.AddRow(
+			format.ColorCell(fmt.Sprintf("#%d", int(number)), format.Cyan),
+			format.Cell(status),
+			format.ColorCell(lastAction, format.Dim),
+			format.Cell(format.Truncate(notes, 60)),
+		)
+	}
+	table.Print()
+
+	return nil
+}
+
+// parsePRNumber parses a PR number argument, accepting an optional leading '#'
+func parsePRNumber(arg string) (int, error) {
+	if len(arg) > 0 && arg[0] == '#' {
+		arg = arg[1:]
+	}
+	number, err := strconv.Atoi(arg)
+	if err != nil || number <= 0 {
+		return 0, errors.InvalidUsage(fmt.Sprintf("invalid PR number: %q", arg))
+	}
+	return number, nil
+}
@@ -0,0 +1,342 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/gh"
+	"github.com/dlorenc/multiclaude/internal/githuburl"
+	"github.com/dlorenc/multiclaude/internal/prcomments"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// resolvedThreadsShown is how many resolved threads agent pr-comments lists
+// before only counting the rest
+const resolvedThreadsShown = 20
+
+// prCommentsView is what agent pr-comments shows of a PR's feedback
+type prCommentsView struct {
+	// ReadAt is when the newest comment shown last time was made; later
+	// ones are marked new
+	ReadAt time.Time
+	// Since, when set, leaves out comments made before it
+	Since time.Time
+	// Unresolved leaves out resolved threads
+	Unresolved bool
+}
+
+// extractUnresolvedFlag removes --unresolved, which takes no value, from
+// args so that ParseFlags does not take a following argument as its value
+func extractUnresolvedFlag(args []string) (bool, []string) {
+	unresolved := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--unresolved" || arg == "--unresolved=true" {
+			unresolved = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return unresolved, rest
+}
+
+// parseSinceTime parses --since as a duration back from now, e.g. 2h, or
+// an RFC 3339 time
+func parseSinceTime(value string) (time.Time, error) {
+	if d, err := parseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.InvalidUsage(fmt.Sprintf("invalid --since %q: use a duration such as 2h or 1d, or a time such as 2026-01-02T15:04:05Z", value))
+}
+
+// agentPRComments prints the review feedback on the PR of the agent whose
+// worktree it runs in, marking what is new since the agent last ran it
+func (c *CLI) agentPRComments(args []string) error {
+	unresolved, args := extractUnresolvedFlag(args)
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) > 0 {
+		return errors.InvalidUsage("usage: multiclaude agent pr-comments [--since <time>] [--unresolved]")
+	}
+	view := prCommentsView{Unresolved: unresolved}
+	sinceValue, hasSince := flags["since"]
+	if hasSince {
+		since, err := parseSinceTime(sinceValue)
+		if err != nil {
+			return err
+		}
+		view.Since = since
+	}
+
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return err
+	}
+	if err := requireGH(); err != nil {
+		return err
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "get_agent",
+		Args: map[string]interface{}{
+			"repo":  repoName,
+			"agent": agentName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("looking up agent", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to look up agent", fmt.Errorf("%s", resp.Error))
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	if registered, _ := data["registered"].(bool); !registered {
+		return errors.AgentNotRegistered(agentName, repoName)
+	}
+	if s, ok := data["pr_comments_read_at"].(string); ok {
+		view.ReadAt, _ = time.Parse(time.RFC3339Nano, s)
+	}
+
+	ctx := context.Background()
+	prURL, _ := data["pr_url"].(string)
+	if prURL == "" {
+		prURL, err = branchPRURL(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	owner, name, number, err := githuburl.ParsePR(prURL)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to find the PR's repository", err)
+	}
+
+	feedback, err := prcomments.Fetch(ctx, owner, name, number)
+	if err != nil {
+		if ghUnavailable(err) {
+			return ghError(err)
+		}
+		return errors.Wrap(errors.CategoryRuntime, "failed to fetch PR comments", err)
+	}
+	printPRComments(os.Stdout, prURL, feedback, view)
+
+	// Looking back with --since is not reading what is new
+	if hasSince {
+		return nil
+	}
+	readAt := feedback.Latest()
+	if readAt.Before(view.ReadAt) {
+		readAt = view.ReadAt
+	}
+	resp, err = client.Send(socket.Request{
+		Command: "mark_pr_comments_read",
+		Args: map[string]interface{}{
+			"repo":    repoName,
+			"agent":   agentName,
+			"read_at": readAt.Format(time.RFC3339Nano),
+			"count":   feedback.Count(),
+		},
+	})
+	if err == nil && !resp.Success {
+		err = fmt.Errorf("%s", resp.Error)
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to record the comments as read, they will show as new again: %v\n", err)
+	}
+	return nil
+}
+
+// branchPRURL asks gh for the PR of the branch checked out in the current
+// directory, for workers whose PR was not recorded
+func branchPRURL(ctx context.Context) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	head, err := worktree.ReadHeadState(cwd)
+	if err != nil || head.Branch == "" {
+		return "", errors.New(errors.CategoryNotFound, "no pull request recorded and no branch checked out to look one up by").
+			WithSuggestion("check out your branch, or record the PR with: multiclaude agent complete --pr-url <url>")
+	}
+
+	var pr struct {
+		URL string `json:"url"`
+	}
+	if err := gh.PRView(ctx, gh.Repo{Dir: cwd}, head.Branch, "url", &pr); err != nil {
+		if ghUnavailable(err) {
+			return "", ghError(err)
+		}
+		return "", errors.New(errors.CategoryNotFound, fmt.Sprintf("no pull request found for branch '%s'", head.Branch)).
+			WithSuggestion("push the branch and open a PR with: gh pr create")
+	}
+	return pr.URL, nil
+}
+
+// printPRComments prints a PR's feedback compactly for an agent to act on:
+// reviews, open threads with their file and line, then the conversation,
+// with resolved threads summarized one per line at the end
+func printPRComments(w io.Writer, prURL string, f *prcomments.Feedback, view prCommentsView) {
+	var open, resolved []prcomments.Thread
+	for _, thread := range f.Threads {
+		if !threadActiveSince(thread, view.Since) {
+			continue
+		}
+		if thread.Resolved {
+			resolved = append(resolved, thread)
+		} else {
+			open = append(open, thread)
+		}
+	}
+
+	summary := fmt.Sprintf("%s, %d resolved", plural(len(open), "open thread"), len(resolved))
+	if view.Unresolved {
+		summary = plural(len(open), "open thread")
+	}
+	fmt.Fprintf(w, "PR %s: %s, %d new\n", prURL, summary, f.CountSince(view.ReadAt))
+
+	newMark := func(t time.Time) string {
+		if t.After(view.ReadAt) {
+			return "[new] "
+		}
+		return ""
+	}
+	shown := func(t time.Time) bool {
+		return !t.Before(view.Since)
+	}
+
+	var reviews []prcomments.Review
+	for _, r := range f.Reviews {
+		if shown(r.SubmittedAt) {
+			reviews = append(reviews, r)
+		}
+	}
+	if len(reviews) > 0 {
+		fmt.Fprintln(w, "\nReviews:")
+		for _, r := range reviews {
+			fmt.Fprintf(w, "  %s%s %s (%s)%s\n", newMark(r.SubmittedAt), r.Author, reviewVerb(r.State), format.TimeAgo(r.SubmittedAt), commentText(r.Body, "    "))
+		}
+	}
+
+	if len(open) > 0 {
+		fmt.Fprintln(w, "\nOpen threads:")
+		for _, thread := range open {
+			fmt.Fprintf(w, "  %s\n", threadLocation(thread))
+			for _, c := range thread.Comments {
+				fmt.Fprintf(w, "    %s%s (%s)%s\n", newMark(c.CreatedAt), c.Author, format.TimeAgo(c.CreatedAt), commentText(c.Body, "      "))
+			}
+		}
+	}
+
+	var conversation []prcomments.Comment
+	for _, c := range f.Comments {
+		if shown(c.CreatedAt) {
+			conversation = append(conversation, c)
+		}
+	}
+	if len(conversation) > 0 {
+		fmt.Fprintln(w, "\nConversation:")
+		for _, c := range conversation {
+			fmt.Fprintf(w, "  %s%s (%s)%s\n", newMark(c.CreatedAt), c.Author, format.TimeAgo(c.CreatedAt), commentText(c.Body, "    "))
+		}
+	}
+
+	if len(resolved) > 0 && !view.Unresolved {
+		fmt.Fprintf(w, "\nResolved threads (%d):\n", len(resolved))
+		for i, thread := range resolved {
+			if i == resolvedThreadsShown {
+				fmt.Fprintf(w, "  ... and %d more\n", len(resolved)-i)
+				break
+			}
+			first := thread.Comments[0]
+			fmt.Fprintf(w, "  %s%s: %s: %s (%s)\n", newMark(latestComment(thread)), threadLocation(thread), first.Author,
+				format.Truncate(firstLine(first.Body), 60), plural(len(thread.Comments), "comment"))
+		}
+	}
+
+	if len(reviews)+len(open)+len(conversation)+len(resolved) == 0 {
+		fmt.Fprintln(w, "No review comments.")
+	}
+}
+
+// threadActiveSince reports whether a thread has a comment made at or
+// after since
+func threadActiveSince(thread prcomments.Thread, since time.Time) bool {
+	return len(thread.Comments) > 0 && !latestComment(thread).Before(since)
+}
+
+// latestComment returns when the newest comment in a thread was made
+func latestComment(thread prcomments.Thread) time.Time {
+	var latest time.Time
+	for _, c := range thread.Comments {
+		if c.CreatedAt.After(latest) {
+			latest = c.CreatedAt
+		}
+	}
+	return latest
+}
+
+// threadLocation formats where a thread is as path:line
+func threadLocation(thread prcomments.Thread) string {
+	location := thread.Path
+	if thread.Line > 0 {
+		location = fmt.Sprintf("%s:%d", thread.Path, thread.Line)
+	}
+	if thread.Outdated {
+		location += " (outdated)"
+	}
+	return location
+}
+
+// reviewVerb describes a review state, e.g. "requested changes"
+func reviewVerb(state string) string {
+	switch state {
+	case "APPROVED":
+		return "approved"
+	case "CHANGES_REQUESTED":
+		return "requested changes"
+	case "DISMISSED":
+		return "review dismissed"
+	default:
+		return "commented"
+	}
+}
+
+// commentText formats a comment body after its author: a one-line body on
+// the same line, a longer one on lines of its own indented by indent
+func commentText(body, indent string) string {
+	body = strings.TrimSpace(strings.ReplaceAll(body, "\r\n", "\n"))
+	if body == "" {
+		return ""
+	}
+	if !strings.Contains(body, "\n") {
+		return ": " + body
+	}
+	return ":\n" + indent + strings.ReplaceAll(body, "\n", "\n"+indent)
+}
+
+// firstLine returns the first non-empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// plural formats a count with a noun, adding an s unless the count is one
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
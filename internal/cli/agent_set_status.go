@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// suggestedAgentStatuses are the statuses agent set-status suggests. Any
+// other string is accepted too.
+var suggestedAgentStatuses = []string{"running", "blocked", "waiting", "testing"}
+
+// setAgentStatus overrides the status shown for an agent, so a human can
+// mark one as blocked or waiting without stopping it. With --clear the
+// status computed by the daemon is shown again.
+func (c *CLI) setAgentStatus(args []string) error {
+	// --clear takes no value; remove it before ParseFlags, which would
+	// otherwise take the agent name following it as the flag's value
+	clearStatus := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--clear" || arg == "--clear=true" {
+			clearStatus = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	flags, posArgs := ParseFlags(rest)
+
+	suggestion := "common statuses: " + strings.Join(suggestedAgentStatuses, ", ")
+	if len(posArgs) < 1 || (!clearStatus && len(posArgs) < 2) {
+		return errors.InvalidUsage("usage: multiclaude agent set-status <name> <status> [--repo <repo>]").
+			WithSuggestion(suggestion)
+	}
+	if clearStatus && len(posArgs) > 1 {
+		return errors.InvalidUsage("agent set-status takes either a status or --clear, not both")
+	}
+	agentName := posArgs[0]
+	status := ""
+	if !clearStatus {
+		status = strings.TrimSpace(strings.Join(posArgs[1:], " "))
+		if status == "" {
+			return errors.InvalidUsage("the status must not be empty; use --clear to remove it").WithSuggestion(suggestion)
+		}
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "update_agent_status",
+		Args: map[string]interface{}{
+			"repo":   repoName,
+			"agent":  agentName,
+			"status": status,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("updating the agent status", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to update the agent status", fmt.Errorf("%s", resp.Error))
+	}
+
+	if clearStatus {
+		fmt.Printf("Cleared the status of %s\n", agentName)
+		return nil
+	}
+	fmt.Printf("Set the status of %s to %q\n", agentName, status)
+	known := false
+	for _, s := range suggestedAgentStatuses {
+		known = known || s == status
+	}
+	if !known {
+		fmt.Printf("Note: %s\n", suggestion)
+	}
+	return nil
+}
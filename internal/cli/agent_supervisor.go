@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/hooks"
+	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/pkg/claude"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// addSupervisor starts a supervisor in a repository initialized with
+// init --no-supervisor, filling the slot reserved for it
+func (c *CLI) addSupervisor(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("checking existing agents", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to check existing agents", fmt.Errorf("%s", resp.Error))
+	}
+	agents, _ := resp.Data.([]interface{})
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			if name, _ := agentMap["name"].(string); name == "supervisor" {
+				return errors.New(errors.CategoryUsage, fmt.Sprintf("repo '%s' already has a supervisor", repoName)).
+					WithSuggestion(fmt.Sprintf("multiclaude agent restart supervisor --repo %s", repoName))
+			}
+		}
+	}
+
+	repoPath := c.paths.RepoDir(repoName)
+	tmuxSession := c.repoTmuxSession(repoName)
+
+	// The session is gone if every agent of the repository was removed
+	hasSession, err := tmux.NewClient().HasSession(context.Background(), tmuxSession)
+	if err != nil {
+		return errors.TmuxOperationFailed("check session", err)
+	}
+	fmt.Printf("Creating tmux window: supervisor\n")
+	if err := c.newAgentWindow(tmuxSession, "supervisor", repoPath, !hasSession); err != nil {
+		return errors.TmuxOperationFailed("create supervisor window", err)
+	}
+
+	sessionID, err := claude.GenerateSessionID()
+	if err != nil {
+		return fmt.Errorf("failed to generate supervisor session ID: %w", err)
+	}
+
+	promptFile, err := c.writePromptFile(repoPath, prompts.TypeSupervisor, "supervisor")
+	if err != nil {
+		return fmt.Errorf("failed to write supervisor prompt: %w", err)
+	}
+
+	if err := hooks.CopyConfig(repoPath, repoPath); err != nil {
+		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+	}
+
+	// Start Claude in supervisor window (skip in test mode)
+	var pid int
+	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+		claudeBinary, err := c.getClaudeBinary()
+		if err != nil {
+			return fmt.Errorf("failed to resolve claude binary: %w", err)
+		}
+
+		fmt.Println("Starting Claude Code in supervisor window...")
+		pid, err = c.startClaudeInTmux(claudeBinary, tmuxSession, "supervisor", repoPath, sessionID, promptFile, repoName, "")
+		if err != nil {
+			return fmt.Errorf("failed to start supervisor Claude: %w", err)
+		}
+
+		if err := c.setupOutputCapture(tmuxSession, "supervisor", repoName, "supervisor", "supervisor"); err != nil {
+			fmt.Printf("Warning: failed to setup output capture for supervisor: %v\n", err)
+		}
+	}
+
+	// Registering the supervisor clears the repository's no-supervisor
+	// setting; the daemon tells the other agents to report to it
+	resp, err = client.Send(socket.Request{
+		Command: "add_agent",
+		Args: map[string]interface{}{
+			"repo":          repoName,
+			"agent":         "supervisor",
+			"type":          "supervisor",
+			"worktree_path": repoPath,
+			"tmux_window":   "supervisor",
+			"docs_hash":     promptDocsHash(promptFile),
+			"session_id":    sessionID,
+			"pid":           pid,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register supervisor: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to register supervisor: %s", resp.Error)
+	}
+
+	fmt.Printf("✓ Added supervisor to repo %s\n", repoName)
+	fmt.Println("The repository's other agents have been told to report to it.")
+	return nil
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// agentWhoami tells an agent whether the daemon still knows about it. An
+// agent removed from state (by repair, say) gets no nudges, messages or
+// cleanup, and nothing else tells it so. The exit status is 0 when the
+// agent is registered, errors.ExitNotRegistered when it is not, and 1 when
+// that could not be checked.
+func (c *CLI) agentWhoami(args []string) error {
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return err
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "get_agent",
+		Args: map[string]interface{}{
+			"repo":  repoName,
+			"agent": agentName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("checking agent registration", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to check agent registration", fmt.Errorf("%s", resp.Error))
+	}
+	data, _ := resp.Data.(map[string]interface{})
+
+	if registered, _ := data["registered"].(bool); !registered {
+		fmt.Printf("WARNING: %s is NOT registered with multiclaude\n", agentName)
+		if tracked, _ := data["repo_tracked"].(bool); !tracked {
+			fmt.Printf("The repository '%s' is not tracked either.\n", repoName)
+		}
+		fmt.Println("The daemon has no record of this agent, so it will not nudge it, deliver")
+		fmt.Println("its messages or clean up after it. Ask the supervisor to replace it,")
+		fmt.Println("and finish by pushing your work somewhere it can be picked up.")
+		fmt.Println()
+		return errors.AgentNotRegistered(agentName, repoName)
+	}
+
+	agentType, _ := data["type"].(string)
+	status, _ := data["status"].(string)
+	sessionID, _ := data["session_id"].(string)
+	pending, _ := data["messages_pending"].(float64)
+	var lastNudge time.Time
+	if s, ok := data["last_nudge"].(string); ok {
+		lastNudge, _ = time.Parse(time.RFC3339Nano, s)
+	}
+
+	fmt.Printf("Registered: %s in %s\n", agentName, repoName)
+	fmt.Printf("  Type:             %s\n", agentType)
+	fmt.Printf("  Status:           %s\n", status)
+	fmt.Printf("  Session ID:       %s\n", sessionID)
+	fmt.Printf("  Pending messages: %d\n", int(pending))
+	fmt.Printf("  Last nudge:       %s\n", format.TimeAgo(lastNudge))
+	c.printDocsCheck(repoName, agentName, prompts.AgentType(agentType))
+	return nil
+}
+
+// printDocsCheck compares the CLI reference in an agent's prompt with the
+// one the multiclaude binary now running would write: an agent started by
+// another version may be told about commands or flags this one lacks
+func (c *CLI) printDocsCheck(repoName, agentName string, agentType prompts.AgentType) {
+	data, err := os.ReadFile(filepath.Join(c.paths.Root, "prompts", agentName+".md"))
+	if err != nil {
+		fmt.Println("  CLI reference:    prompt file not found")
+		return
+	}
+	version, hash, ok := prompts.ParseDocsStamp(string(data))
+	if !ok {
+		fmt.Println("  CLI reference:    not stamped in the prompt")
+		return
+	}
+	_, current, err := c.currentDocs(repoName, agentType)
+	if err != nil {
+		fmt.Printf("  CLI reference:    could not be checked: %v\n", err)
+		return
+	}
+	if hash == current {
+		fmt.Printf("  CLI reference:    current (multiclaude %s)\n", version)
+		return
+	}
+	fmt.Printf("  CLI reference:    STALE (written by multiclaude %s, running %s)\n", version, Version)
+	fmt.Println()
+	fmt.Println("WARNING: the CLI reference in your prompt documents other commands than the")
+	fmt.Println("installed multiclaude. Check a command with --help before relying on your")
+	fmt.Println("prompt, and ask the supervisor to refresh it with:")
+	fmt.Printf("  multiclaude agent restart %s --refresh-docs --repo %s\n", agentName, repoName)
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// auditLocal records an operation the CLI performed without the daemon.
+// Audit failures are reported but never fail the operation itself.
+func (c *CLI) auditLocal(command string, args map[string]interface{}, opErr error) {
+	entry := audit.Entry{
+		Source:  audit.SourceCLI,
+		Command: command,
+		Args:    args,
+		Caller:  audit.CurrentCaller(),
+		Success: opErr == nil,
+	}
+	if opErr != nil {
+		entry.Error = opErr.Error()
+	}
+	if err := audit.Append(c.paths.AuditLog(), entry); err != nil {
+		fmt.Printf("Warning: failed to write audit log: %v\n", err)
+	}
+}
+
+// showAudit prints audit log entries, optionally filtered by age and command
+func (c *CLI) showAudit(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	filter := audit.Filter{Command: flags["command"]}
+	if since, ok := flags["since"]; ok {
+		duration, err := parseDuration(since)
+		if err != nil {
+			return errors.InvalidDuration(since)
+		}
+		filter.Since = time.Now().Add(-duration)
+	}
+
+	entries, err := audit.Read(c.paths.AuditLog(), filter)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to read audit log", err)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No audit entries found")
+		return nil
+	}
+
+	format.Header("Audit log (%d):", len(entries))
+	fmt.Println()
+
+	table := format.NewColoredTable("TIME", "SOURCE", "COMMAND", "CALLER", "RESULT", "ARGS")
+	for _, entry := range entries {
+		result := format.ColorCell("ok", format.Green)
+		if !entry.Success {
+			result = format.ColorCell(format.Truncate("failed: "+entry.Error, 40), format.Red)
+		}
+
+		table.AddRow(
+			format.Cell(entry.Time.Local().Format("2006-01-02 15:04:05")),
+			format.ColorCell(entry.Source, format.Dim),
+			format.ColorCell(entry.Command, format.Cyan),
+			format.Cell(formatAuditCaller(entry.Caller)),
+			result,
+			format.Cell(format.Truncate(formatAuditArgs(entry.Args), 60)),
+		)
+	}
+	table.Print()
+
+	return nil
+}
+
+// formatAuditCaller renders caller info as agent@repo (pid N, uid N)
+func formatAuditCaller(caller *audit.Caller) string {
+	if caller == nil {
+		return "-"
+	}
+	var who string
+	if caller.Agent != "" {
+		who = fmt.Sprintf("%s@%s ", caller.Agent, caller.Repo)
+	}
+	return fmt.Sprintf("%spid %d, uid %d", who, caller.PID, caller.UID)
+}
+
+// formatAuditArgs renders args as sorted key=value pairs
+func formatAuditArgs(args map[string]interface{}) string {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, args[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// daemonConnectionAudit shows the daemon's in-memory record of recent socket requests
+func (c *CLI) daemonConnectionAudit(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	reqArgs := map[string]interface{}{}
+	if lastStr, ok := flags["last"]; ok {
+		last, err := strconv.Atoi(lastStr)
+		if err != nil || last < 1 {
+			return errors.InvalidUsage(fmt.Sprintf("--last must be a positive integer, got %q", lastStr))
+		}
+		reqArgs["last"] = last
+	}
+	if command := flags["command"]; command != "" {
+		reqArgs["command"] = command
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "connection_audit",
+		Args:    reqArgs,
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("reading connection audit", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to read connection audit", fmt.Errorf("%s", resp.Error))
+	}
+
+	records, _ := resp.Data.([]interface{})
+	if len(records) == 0 {
+		fmt.Println("No recent socket requests")
+		return nil
+	}
+
+	format.Header("Recent socket requests (%d, newest first):", len(records))
+	fmt.Println()
+
+	table := format.NewColoredTable("TIME", "COMMAND", "RESULT", "DURATION", "ARGS")
+	for _, r := range records {
+		rec, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		timeStr := "-"
+		if ts, _ := rec["time"].(string); ts != "" {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				timeStr = t.Local().Format("2006-01-02 15:04:05")
+			}
+		}
+
+		result := format.ColorCell("ok", format.Green)
+		if success, _ := rec["success"].(bool); !success {
+			errMsg, _ := rec["error"].(string)
+			result = format.ColorCell(format.Truncate("failed: "+errMsg, 40), format.Red)
+		}
+
+		durationMs, _ := rec["duration_ms"].(float64)
+		recArgs, _ := rec["args"].(map[string]interface{})
+		command, _ := rec["command"].(string)
+
+		table.AddRow(
+			format.Cell(timeStr),
+			format.ColorCell(command, format.Cyan),
+			result,
+			format.Cell(fmt.Sprintf("%dms", int64(durationMs))),
+			format.Cell(format.Truncate(formatAuditArgs(recArgs), 60)),
+		)
+	}
+	table.Print()
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/pkg/claude"
+)
+
+// restartDaemonCommand is suggested when agents may be running different
+// claude binaries
+const restartDaemonCommand = "multiclaude daemon stop && multiclaude daemon start"
+
+// repoClaudeBinary returns the claude binary to start a repository's agents
+// with: the binary pinned with config --pin-claude-path if there is one,
+// otherwise binaryPath. A pinned binary that cannot be used is an error.
+func (c *CLI) repoClaudeBinary(repoName, binaryPath string) (string, error) {
+	st, err := c.loadState()
+	if err != nil {
+		return "", err
+	}
+	repo, exists := st.GetRepo(repoName)
+	if !exists || repo.ClaudePath == "" {
+		return binaryPath, nil
+	}
+	if err := claude.CheckExecutable(repo.ClaudePath); err != nil {
+		return "", errors.PinnedClaudeUnavailable(repoName, err)
+	}
+	return repo.ClaudePath, nil
+}
+
+// describeClaudeBinary formats a claude binary reported by the daemon as
+// "<version> (<path>)"
+func describeClaudeBinary(v interface{}) string {
+	info, _ := v.(map[string]interface{})
+	version, _ := info["version"].(string)
+	path, _ := info["path"].(string)
+	if version == "" {
+		version = "unknown version"
+	}
+	return fmt.Sprintf("%s (%s)", version, path)
+}
+
+// claudeBinaryChange describes the claude binary change reported in a daemon
+// status response as "<old> -> <new>", or returns "" if there is none
+func claudeBinaryChange(status map[string]interface{}) string {
+	change, ok := status["claude_binary_change"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s -> %s", describeClaudeBinary(change["old"]), describeClaudeBinary(change["new"]))
+}
+
+// daemonClaudeBinaryChange asks the daemon whether the claude binary changed
+// since it started. It returns "" when there is no change or the daemon is
+// not reachable.
+func (c *CLI) daemonClaudeBinaryChange() string {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{Command: "status"})
+	if err != nil || !resp.Success {
+		return ""
+	}
+	status, _ := resp.Data.(map[string]interface{})
+	return claudeBinaryChange(status)
+}
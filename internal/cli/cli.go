@@ -1,21 +1,28 @@
 package cli
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/dlorenc/multiclaude/internal/bugreport"
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
 	"github.com/dlorenc/multiclaude/internal/daemon"
 	"github.com/dlorenc/multiclaude/internal/errors"
 	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/gh"
+	"github.com/dlorenc/multiclaude/internal/githuburl"
 	"github.com/dlorenc/multiclaude/internal/hooks"
 	"github.com/dlorenc/multiclaude/internal/messages"
 	"github.com/dlorenc/multiclaude/internal/names"
@@ -36,6 +43,15 @@ type Command struct {
 	Name        string
 	Description string
 	Usage       string
+	Notes       string // Extra detail for the generated docs, e.g. output formats agents rely on
+	Flags       []FlagSpec
+	// FreeformArgs skips rejecting undeclared flags, for commands whose
+	// arguments are free text such as a message body
+	FreeformArgs bool
+	// Predicate marks commands that answer yes or no through their exit
+	// status: 0, errors.ExitNo, or errors.ExitUndetermined for any failure
+	// to answer, including bad flags
+	Predicate   bool
 	Run         func(args []string) error
 	Subcommands map[string]*Command
 }
@@ -99,6 +115,24 @@ func (c *CLI) getClaudeBinary() (string, error) {
 	return binaryPath, nil
 }
 
+// checkClaudeVersion refuses a claude binary older than the repository's
+// minimum version (multiclaude config --min-claude-version), if one is set
+func (c *CLI) checkClaudeVersion(repoName, binaryPath string) error {
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	repo, exists := st.GetRepo(repoName)
+	if !exists || repo.MinClaudeVersion == "" {
+		return nil
+	}
+	runner := claude.NewRunner(claude.WithBinaryPath(binaryPath), claude.WithBinaryValidator(repo.MinClaudeVersion, ""))
+	if err := runner.ValidateBinary(context.Background()); err != nil {
+		return errors.Wrap(errors.CategoryConfig, "claude binary rejected", err)
+	}
+	return nil
+}
+
 // loadState loads the state file, wrapping errors with context
 func (c *CLI) loadState() (*state.State, error) {
 	st, err := state.Load(c.paths.StateFile)
@@ -130,20 +164,44 @@ func sanitizeTmuxSessionName(repoName string) string {
 	return fmt.Sprintf("mc-%s", tmuxSanitizer.Replace(sanitized))
 }
 
+// repoTmuxSession returns the tmux session recorded for a repository, which
+// for an imported repository is the session it already ran in, falling back
+// to the mc-<repo> name given to sessions multiclaude creates
+func (c *CLI) repoTmuxSession(repoName string) string {
+	if st, err := state.Load(c.paths.StateFile); err == nil {
+		if repo, exists := st.GetRepo(repoName); exists && repo.TmuxSession != "" {
+			return repo.TmuxSession
+		}
+	}
+	return sanitizeTmuxSessionName(repoName)
+}
+
+// agentWindowTarget returns what to address an agent's tmux window by, given
+// its list_agents entry: the window ID, which survives renames, or its name
+// if the daemon has not recorded an ID
+func agentWindowTarget(agentInfo map[string]interface{}) string {
+	if id, _ := agentInfo["tmux_window_id"].(string); id != "" {
+		return id
+	}
+	window, _ := agentInfo["tmux_window"].(string)
+	return window
+}
+
 // Execute executes the CLI with the given arguments
 func (c *CLI) Execute(args []string) error {
 	if len(args) == 0 {
 		return c.showHelp()
 	}
 
-	return c.executeCommand(c.rootCmd, args)
+	return c.executeCommand(c.rootCmd, "multiclaude", args)
 }
 
-// executeCommand recursively executes commands and subcommands
-func (c *CLI) executeCommand(cmd *Command, args []string) error {
+// executeCommand recursively executes commands and subcommands; path is how
+// cmd was invoked, e.g. "multiclaude work list"
+func (c *CLI) executeCommand(cmd *Command, path string, args []string) error {
 	if len(args) == 0 {
 		if cmd.Run != nil {
-			return cmd.Run([]string{})
+			return runCommand(cmd, cmd.Run([]string{}))
 		}
 		return c.showCommandHelp(cmd)
 	}
@@ -155,17 +213,29 @@ func (c *CLI) executeCommand(cmd *Command, args []string) error {
 
 	// Check for subcommands
 	if subcmd, exists := cmd.Subcommands[args[0]]; exists {
-		return c.executeCommand(subcmd, args[1:])
+		return c.executeCommand(subcmd, path+" "+args[0], args[1:])
 	}
 
 	// No subcommand found, run this command with args
 	if cmd.Run != nil {
-		return cmd.Run(args)
+		if err := checkFlags(cmd, path, args); err != nil {
+			return runCommand(cmd, err)
+		}
+		return runCommand(cmd, cmd.Run(args))
 	}
 
 	return errors.UnknownCommand(args[0])
 }
 
+// runCommand returns the error of running cmd, giving a predicate's
+// failures to answer their exit status
+func runCommand(cmd *Command, err error) error {
+	if cmd.Predicate {
+		return errors.Undetermined(err)
+	}
+	return err
+}
+
 // showHelp shows the main help message
 func (c *CLI) showHelp() error {
 	fmt.Println("multiclaude - repo-centric orchestrator for Claude Code")
@@ -192,6 +262,13 @@ func (c *CLI) showCommandHelp(cmd *Command) error {
 		fmt.Println()
 	}
 
+	if len(cmd.Flags) > 0 {
+		var sb strings.Builder
+		writeFlagsHelp(&sb, cmd.Flags)
+		fmt.Println("Flags:")
+		fmt.Println(sb.String())
+	}
+
 	if len(cmd.Subcommands) > 0 {
 		fmt.Println("Subcommands:")
 		for name, subcmd := range cmd.Subcommands {
@@ -213,7 +290,8 @@ func (c *CLI) registerCommands() {
 	c.rootCmd.Subcommands["start"] = &Command{
 		Name:        "start",
 		Description: "Start the multiclaude daemon",
-		Usage:       "multiclaude start",
+		Usage:       "multiclaude start [--watch-fs]",
+		Flags:       []FlagSpec{watchFSFlag},
 		Run:         c.startDaemon,
 	}
 
@@ -226,8 +304,11 @@ func (c *CLI) registerCommands() {
 	daemonCmd.Subcommands["start"] = &Command{
 		Name:        "start",
 		Description: "Start the daemon",
-		Usage:       "multiclaude daemon start",
-		Run:         c.startDaemon,
+		Usage:       "multiclaude daemon start [--watch-fs]",
+		Flags:       []FlagSpec{watchFSFlag},
+		Notes: "With `--watch-fs` the daemon watches agent worktrees and records the branch checked out when it changes, " +
+			"including by git run by hand, so that `work list` shows the right branch.",
+		Run: c.startDaemon,
 	}
 
 	daemonCmd.Subcommands["stop"] = &Command{
@@ -240,20 +321,157 @@ func (c *CLI) registerCommands() {
 	daemonCmd.Subcommands["status"] = &Command{
 		Name:        "status",
 		Description: "Show daemon status",
-		Usage:       "multiclaude daemon status",
-		Run:         c.daemonStatus,
+		Usage:       "multiclaude daemon status [--quiet [--verbose]]",
+		Flags: []FlagSpec{
+			{Name: "quiet", Type: "bool", Description: "Only answer whether the daemon is running, by exit status"},
+			verboseFlag,
+		},
+		Notes: "With `--quiet` the command is a predicate for scripts: it succeeds only if the daemon is running and answers requests. " + predicateNotes,
+		Run:   c.daemonStatus,
+	}
+
+	daemonCmd.Subcommands["ping"] = &Command{
+		Name:        "ping",
+		Description: "Measure the round-trip time of requests to the daemon",
+		Usage:       "multiclaude daemon ping [--count 5] [--interval 1s] [--quiet]",
+		Flags: []FlagSpec{
+			{Name: "count", Type: "int", Default: "5", Description: "Number of pings to send"},
+			{Name: "interval", Type: "duration", Default: "1s", Description: "Time to wait between pings"},
+			{Name: "quiet", Type: "bool", Description: "Print only the statistics"},
+		},
+		Notes: "Sends pings over the daemon's socket one at a time and prints each round-trip time, then the packet loss and min/avg/max, as ping(8) does. " +
+			"A ping with no reply within 2s, or one that cannot connect, is reported as a timeout, and any timeout makes the command fail. " +
+			"Use it to spot a socket on a slow filesystem or intermittent disconnects.",
+		Run: c.daemonPing,
 	}
 
 	daemonCmd.Subcommands["logs"] = &Command{
 		Name:        "logs",
 		Description: "View daemon logs",
 		Usage:       "multiclaude daemon logs [-f|--follow] [-n <lines>]",
-		Run:         c.daemonLogs,
+		Flags: []FlagSpec{
+			{Name: "follow", Shorthand: "f", Type: "bool", Description: "Keep printing new lines as they are written"},
+			{Shorthand: "n", Type: "int", Default: "50", Description: "Number of lines to show"},
+		},
+		Run: c.daemonLogs,
+	}
+
+	daemonCmd.Subcommands["throttle"] = &Command{
+		Name:        "throttle",
+		Description: "Limit how many workers can run concurrently in a repository",
+		Usage:       "multiclaude daemon throttle [<repo>] [--ephemeral] [--max-concurrent-agents <n>] [--reset]",
+		Flags: []FlagSpec{
+			{Name: "ephemeral", Type: "bool", Description: "Read or set the limit for ephemeral agents instead of workers"},
+			{Name: "max-concurrent-agents", Type: "int", Description: "Most workers that may run at once; 0 for no limit"},
+			{Name: "reset", Type: "bool", Description: "Remove the limit"},
+		},
+		Notes: "With `--ephemeral` the command reads or sets the separate limit for ephemeral agents (`multiclaude work --ephemeral`), which do not count towards the worker limit.",
+		Run:   c.daemonThrottle,
+	}
+
+	daemonCmd.Subcommands["check-upgrade"] = &Command{
+		Name:        "check-upgrade",
+		Description: "Check GitHub for a newer multiclaude release",
+		Usage:       "multiclaude daemon check-upgrade [--json] [--check-interval <duration>]",
+		Flags: []FlagSpec{
+			{Name: "json", Type: "bool", Description: "Print the result as JSON"},
+			{Name: "check-interval", Type: "duration", Description: "Have the daemon check this often, e.g. 24h; 0 turns it off"},
+		},
+		Notes: "Compares the latest release on GitHub with the running version and prints a link to its release notes if it is newer. " +
+			"The check gives up after 5s. Its result is saved and shown by `multiclaude daemon status`. " +
+			"With `--check-interval` nothing is checked right away; the daemon checks on its own at that interval, logging a warning when a check fails. " +
+			"Automatic checks are off by default.",
+		Run: c.daemonCheckUpgrade,
+	}
+
+	daemonCmd.Subcommands["describe-state"] = &Command{
+		Name:        "describe-state",
+		Description: "Show repositories and agents as a tree with live status",
+		Usage:       "multiclaude daemon describe-state [--repo <repo>] [--json]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "json", Type: "bool", Description: "Print the tree as JSON"},
+		},
+		Run: c.describeState,
+	}
+
+	daemonCmd.Subcommands["connection-audit"] = &Command{
+		Name:        "connection-audit",
+		Description: "Show recent requests sent to the daemon",
+		Usage:       "multiclaude daemon connection-audit [--last <n>] [--command <name>]",
+		Flags: []FlagSpec{
+			{Name: "last", Type: "int", Description: "Show only the most recent N requests"},
+			{Name: "command", Type: "string", Description: "Show only requests for this socket command"},
+		},
+		Run: c.daemonConnectionAudit,
+	}
+
+	daemonCmd.Subcommands["profile"] = &Command{
+		Name:        "profile",
+		Description: "Write a pprof profile of the daemon to a file",
+		Usage:       "multiclaude daemon profile [--type cpu|mem|goroutine] [--duration 30s] [--output <file>]",
+		Flags: []FlagSpec{
+			{Name: "type", Type: "string", Default: "cpu", Description: "Profile to collect: cpu, mem or goroutine"},
+			{Name: "duration", Type: "duration", Default: "30s", Description: "How long to collect a CPU profile, at most 5m"},
+			{Name: "output", Type: "path", Default: "multiclaude-<type>-<timestamp>.prof", Description: "File to write the profile to"},
+		},
+		Notes: "A CPU profile (the default) is collected for `--duration`, at most 5m; heap (`mem`) and goroutine profiles are snapshots. " +
+			"The profile is written to `multiclaude-<type>-<timestamp>.prof` in the current directory unless `--output` is given. " +
+			"Analyze it with `go tool pprof`.",
+		Run: c.daemonProfile,
+	}
+
+	daemonCmd.Subcommands["stress-test"] = &Command{
+		Name:        "stress-test",
+		Description: "Load the daemon with fake agents and messages and check its state",
+		Usage:       "multiclaude daemon stress-test [--agents <n>] [--messages <n>] [--duration 30s] [--repo <repo>] [--skip-tmux]",
+		Flags: []FlagSpec{
+			{Name: "agents", Type: "int", Default: "10", Description: "Number of fake workers"},
+			{Name: "messages", Type: "int", Default: "1000", Description: "Most messages to send"},
+			{Name: "duration", Type: "duration", Default: "30s", Description: "Longest time to run"},
+			repoFlag,
+			{Name: "skip-tmux", Type: "bool", Description: "Create no tmux windows for the fake agents"},
+		},
+		Notes: "For integration testing only; use a repository set aside for it. " +
+			"Registers `--agents` fake workers (default 10), each with a tmux window running `cat`, and sends up to `--messages` messages (default 1000) between random pairs " +
+			"for at most `--duration`, while message routing, health checks, cleanup and queries run concurrently. " +
+			"It then checks that state.json parses, every fake agent is still registered and every message is in its recipient's directory, " +
+			"reports throughput, error rate and any violations, and removes the fake agents and their messages. " +
+			"`--skip-tmux` creates no windows and so triggers no health checks or cleanup, which would remove agents without windows.",
+		Run: c.daemonStressTest,
+	}
+
+	daemonCmd.Subcommands["migrate-paths"] = &Command{
+		Name:        "migrate-paths",
+		Description: "Update recorded paths after moving the multiclaude directory",
+		Usage:       "multiclaude daemon migrate-paths --old-root <path> --new-root <path> [--dry-run]",
+		Flags: []FlagSpec{
+			{Name: "old-root", Type: "path", Description: "The directory's previous location (required)"},
+			{Name: "new-root", Type: "path", Description: "The directory's new location (required)"},
+			{Name: "dry-run", Type: "bool", Description: "Show what would change without changing anything"},
+		},
+		Notes: "Run it after moving the directory, with the daemon stopped. It rewrites the paths under the old root in state.json " +
+			"(agent worktrees, env files, pinned claude binaries, local repositories), in paths.json and in the prompt files, " +
+			"checks that each new path exists and runs `git worktree repair` so git finds the moved worktrees. " +
+			"`--dry-run` shows what would change without changing anything.",
+		Run: c.daemonMigratePaths,
+	}
+
+	daemonCmd.Subcommands["watch-fs"] = &Command{
+		Name:        "watch-fs",
+		Description: "Print branch switches and commits in agent worktrees as they happen",
+		Usage:       "multiclaude daemon watch-fs [--repo <repo>]",
+		Flags:       []FlagSpec{repoFlag},
+		Notes: "Reads the HEAD of every agent worktree, or only those of `--repo`, every 2s until interrupted and prints what changed, " +
+			"including changes made by running git by hand. Branch switches are passed on to the daemon, which records the new branch. " +
+			"To have the daemon watch on its own, start it with `multiclaude daemon start --watch-fs`.",
+		Run: c.daemonWatchFS,
 	}
 
 	daemonCmd.Subcommands["_run"] = &Command{
 		Name:        "_run",
 		Description: "Internal: run daemon in foreground (used by daemon start)",
+		Flags:       []FlagSpec{watchFSFlag},
 		Run:         c.runDaemon,
 	}
 
@@ -264,24 +482,111 @@ func (c *CLI) registerCommands() {
 		Name:        "stop-all",
 		Description: "Stop daemon and kill all multiclaude tmux sessions",
 		Usage:       "multiclaude stop-all [--clean] [--yes]",
-		Run:         c.stopAll,
+		Flags: []FlagSpec{
+			{Name: "clean", Type: "bool", Description: "Also delete all worktrees, agent state and message queues"},
+			yesFlag,
+		},
+		Run: c.stopAll,
 	}
 
 	// Repository commands
 	c.rootCmd.Subcommands["init"] = &Command{
 		Name:        "init",
 		Description: "Initialize a repository",
-		Usage:       "multiclaude init <github-url> [name] [--no-merge-queue] [--mq-track=all|author|assigned]",
-		Run:         c.initRepo,
+		Usage:       "multiclaude init <github-url> [name] [--no-merge-queue] [--mq-track=all|author|assigned] [--worktree-limit <n>] [--no-workspace] [--no-supervisor] [--template <url> [--no-template-prompts]] [--quiet] | --local <path> [name] | --wizard | --import-from-existing --repo-path <path> --tmux-session <session> [--name <name>]",
+		Flags: []FlagSpec{
+			{Name: "no-merge-queue", Type: "bool", Description: "Start no merge-queue agent"},
+			{Name: "mq-track", Type: "string", Default: "all", Description: "PRs the merge queue tracks: all, author or assigned"},
+			{Name: "worktree-limit", Type: "int", Description: "Most worktrees the repository may have"},
+			{Name: "no-workspace", Type: "bool", Description: "Create no default workspace"},
+			{Name: "no-supervisor", Type: "bool", Description: "Start no supervisor"},
+			{Name: "template", Type: "string", Description: "URL of a repository whose .multiclaude/ directory is copied into the clone"},
+			{Name: "no-template-prompts", Type: "bool", Description: "Leave the prompt override files out of --template"},
+			{Name: "local", Type: "path", Description: "Clone a local git repository instead of a GitHub one"},
+			{Name: "wizard", Type: "bool", Description: "Ask for each setting interactively"},
+			{Name: "quiet", Type: "bool", Description: "Print nothing but errors"},
+			{Name: "import-from-existing", Type: "bool", Description: "Register an existing clone and tmux session instead of cloning"},
+			{Name: "repo-path", Type: "path", Description: "Existing clone to import"},
+			{Name: "tmux-session", Type: "string", Description: "Existing tmux session to import"},
+			{Name: "name", Type: "string", Description: "Name of the imported repository (default: from its origin URL)"},
+		},
+		Notes: "`--wizard` asks for each setting interactively: the URL (checked with `gh`), the name, the merge queue and its track mode, " +
+			"whether to create the default workspace, and whether to write template prompt override files into `.multiclaude/` (optionally committing them). " +
+			"It needs a terminal; in scripts pass the flags instead. " +
+			"`--template <url>` copies the `.multiclaude/` directory of another repository (hooks, prompt overrides, configuration) into the new clone before any agent starts; " +
+			"files the repository already has are kept, and nothing is committed. `--no-template-prompts` leaves out the prompt override files. " +
+			"`--local <path>` clones a local git repository instead of a GitHub one (no network needed); the name defaults to the directory name " +
+			"and the merge queue is off, since there are no PRs to merge. " +
+			"`--no-supervisor` starts no supervisor, for operators who manage workers themselves; the other agents are told not to report to one. " +
+			"Add one later with `multiclaude agent add-supervisor`. " +
+			"Init runs in stages (clone, session, registration, prompts, then the agents together), shown as a live checklist on a terminal and as plain logs otherwise; " +
+			"a failure names the stage and shows its output. `--quiet` prints nothing but errors. " +
+			"`--import-from-existing --repo-path <path> --tmux-session <session>` registers a clone and tmux session set up by hand, without cloning or creating a session: " +
+			"the GitHub URL comes from the clone's `origin` remote, the clone is linked into the repos directory, " +
+			"and Claude running in `supervisor` or `merge-queue` windows of the session is registered as those agents.",
+		Run: c.initRepo,
 	}
 
 	c.rootCmd.Subcommands["list"] = &Command{
 		Name:        "list",
 		Description: "List tracked repositories",
-		Usage:       "multiclaude list",
-		Run:         c.listRepos,
+		Usage:       "multiclaude list [--group <name>]",
+		Flags: []FlagSpec{
+			groupFlag,
+		},
+		Run: c.listRepos,
+	}
+
+	// Repository group commands
+	groupCmd := &Command{
+		Name:        "group",
+		Description: "Manage named groups of repositories",
+		Notes: "A group names a set of tracked repositories, e.g. those of one project, so that `list`, `work list`, " +
+			"`workspace list` and `cleanup --merged` can act on them together with `--group <name>`, one section per repository. " +
+			"A repository may belong to several groups, and removing it with `repo rm` removes it from its groups.",
+		Subcommands: make(map[string]*Command),
+	}
+
+	groupCmd.Subcommands["create"] = &Command{
+		Name:        "create",
+		Description: "Create a group of tracked repositories",
+		Usage:       "multiclaude group create <name> [<repo>...]",
+		Run:         c.createGroup,
+	}
+
+	groupCmd.Subcommands["add"] = &Command{
+		Name:        "add",
+		Description: "Add repositories to a group",
+		Usage:       "multiclaude group add <name> <repo>...",
+		Run:         c.addToGroup,
+	}
+
+	groupCmd.Subcommands["remove"] = &Command{
+		Name:        "remove",
+		Description: "Remove repositories from a group",
+		Usage:       "multiclaude group remove <name> <repo>...",
+		Run:         c.removeFromGroup,
+	}
+
+	groupCmd.Subcommands["delete"] = &Command{
+		Name:        "delete",
+		Description: "Delete a group, leaving its repositories alone",
+		Usage:       "multiclaude group delete <name>",
+		Run:         c.deleteGroup,
+	}
+
+	groupCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List groups and their repositories",
+		Usage:       "multiclaude group list [--json]",
+		Flags: []FlagSpec{
+			{Name: "json", Type: "bool", Description: "Print as JSON"},
+		},
+		Run: c.listGroups,
 	}
 
+	c.rootCmd.Subcommands["group"] = groupCmd
+
 	// Repository commands (repo subcommand)
 	repoCmd := &Command{
 		Name:        "repo",
@@ -292,8 +597,11 @@ func (c *CLI) registerCommands() {
 	repoCmd.Subcommands["rm"] = &Command{
 		Name:        "rm",
 		Description: "Remove a tracked repository",
-		Usage:       "multiclaude repo rm <name>",
-		Run:         c.removeRepo,
+		Usage:       "multiclaude repo rm <name> [--yes]",
+		Flags: []FlagSpec{
+			yesFlag,
+		},
+		Run: c.removeRepo,
 	}
 
 	repoCmd.Subcommands["use"] = &Command{
@@ -310,6 +618,16 @@ func (c *CLI) registerCommands() {
 		Run:         c.getCurrentRepo,
 	}
 
+	repoCmd.Subcommands["tracked"] = &Command{
+		Name:        "tracked",
+		Description: "Check whether a repository is tracked",
+		Usage:       "multiclaude repo tracked <name> [--verbose]",
+		Flags:       []FlagSpec{verboseFlag},
+		Notes:       predicateNotes,
+		Predicate:   true,
+		Run:         c.repoTracked,
+	}
+
 	repoCmd.Subcommands["unset"] = &Command{
 		Name:        "unset",
 		Description: "Clear the default repository",
@@ -317,13 +635,71 @@ func (c *CLI) registerCommands() {
 		Run:         c.clearCurrentRepo,
 	}
 
+	repoCmd.Subcommands["set-url"] = &Command{
+		Name:        "set-url",
+		Description: "Point a repository at a new GitHub URL after a rename or transfer",
+		Usage:       "multiclaude repo set-url <name> <new-github-url>",
+		Run:         c.setRepoURL,
+	}
+
+	repoCmd.Subcommands["sync"] = &Command{
+		Name:        "sync",
+		Description: "Pull upstream into a repository's primary clone",
+		Usage:       "multiclaude repo sync [<name>] [--all]",
+		Flags: []FlagSpec{
+			{Name: "all", Type: "bool", Description: "Sync every tracked repository"},
+			repoFlag,
+		},
+		Notes: "Fetches the upstream remote (upstream if there is one, otherwise origin) into the primary clone, pruning remote branches that were deleted, " +
+			"and fast-forwards the default branch, which new workers start from. Other branches and agent worktrees are not touched. " +
+			"A default branch with local commits is left alone with guidance. When the branch moves, the supervisor, merge-queue and workspace agents get a message listing the new commits and the sync is recorded in `multiclaude history`. " +
+			"`multiclaude config <repo> --auto-sync=<duration>` has the daemon sync on its own; it skips a sync while the remote is unreachable.",
+		Run: c.syncRepo,
+	}
+
+	repoCmd.Subcommands["health"] = &Command{
+		Name:        "health",
+		Description: "Run a comprehensive health check of a repository",
+		Usage:       "multiclaude repo health [<name>] [--fix]",
+		Flags: []FlagSpec{
+			{Name: "fix", Type: "bool", Description: "Repair the problems found where possible"},
+			repoFlag,
+		},
+		Run: c.repoHealth,
+	}
+
 	c.rootCmd.Subcommands["repo"] = repoCmd
 
 	// Worker commands
 	workCmd := &Command{
 		Name:        "work",
 		Description: "Manage worker agents",
-		Usage:       "multiclaude work [<task>] [--repo <repo>] [--branch <branch>] [--push-to <branch>]",
+		Usage:       "multiclaude work [<task>] [--repo <repo>] [--branch <branch>] [--push-to <branch>] [--path <dir>]... [--ephemeral] [--allow-duplicate] [--context-file <path>]... [--context -] [--no-submodules] [--legacy-local]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "branch", Type: "string", Default: "the default branch", Description: "Branch to start the worker from"},
+			{Name: "push-to", Type: "string", Description: "Existing branch to push to instead of a new one"},
+			{Name: "path", Type: "path", Description: "Directory to scope the task to; repeatable"},
+			{Name: "ephemeral", Type: "bool", Description: "Start a read-only agent with no worktree or branch"},
+			{Name: "allow-duplicate", Type: "bool", Description: "Start even if a live worker has the same task"},
+			{Name: "name", Type: "string", Description: "Worker name instead of a generated one"},
+			contextFileFlag,
+			contextFlag,
+			noSubmodulesFlag,
+			{Name: "legacy-local", Type: "bool", Description: "Create the worker in the CLI, not the daemon"},
+		},
+		Notes: "`--context-file` copies a file into the worker's worktree under `.multiclaude/context/` and points the initial message at it instead of pasting its content; " +
+			"repeat it for several files, or pass `--context -` to read one from stdin. The directory is git-ignored and removed with the worktree. " +
+			"`review` and `workspace add` accept the same flags. " +
+			"`--ephemeral` starts a read-only agent in the repository's primary checkout with no worktree or branch of its own. " +
+			"Its prompt forbids committing or modifying files, removing it never touches the checkout, and it counts against a separate limit " +
+			"(`multiclaude daemon throttle --ephemeral`). " +
+			"A worker whose task matches (ignoring case and whitespace) that of a live worker created in the last 10 minutes is refused as a likely duplicate, " +
+			"e.g. from running the same command in two terminals; `--allow-duplicate` starts it anyway, and `multiclaude config <repo> --duplicate-window=<duration>` changes the window (0 disables the check). " +
+			"In a repository with git submodules, `git submodule update --init --recursive` runs in the new worktree, with its output in the agent's log, " +
+			"bounded by `multiclaude config <repo> --submodule-timeout=<duration>` (default 10m); `--no-submodules` skips it. `review` and `workspace add` do the same. " +
+			"The daemon creates the worker, one at a time per repository, so workers created at once by you and the supervisor cannot race on git or tmux; " +
+			"`--legacy-local` creates it from the CLI process instead, for a daemon too old to do so (to be removed in the next release).",
 		Subcommands: make(map[string]*Command),
 	}
 
@@ -332,17 +708,176 @@ func (c *CLI) registerCommands() {
 	workCmd.Subcommands["list"] = &Command{
 		Name:        "list",
 		Description: "List active workers",
-		Usage:       "multiclaude work list [--repo <repo>]",
-		Run:         c.listWorkers,
+		Usage:       "multiclaude work list [--repo <repo> | --group <name>] [--sort-by name|created|status|task|commits|messages] [--sort-order asc|desc] [--with-log-tail [N]] [--output-template <template>|@<file>]",
+		Flags: []FlagSpec{
+			repoFlag,
+			groupFlag,
+			{Name: "sort-by", Type: "string", Default: "name", Description: "name, created, status, task, commits or messages"},
+			{Name: "sort-order", Type: "string", Default: "depends on --sort-by", Description: "asc or desc"},
+			{Name: "with-log-tail", Type: "int", Default: "3", Description: "Also show the last N lines of each worker's log"},
+			{Name: "output-template", Type: "string", Description: "Go text/template to print the workers with, or @<file> to read it from a file"},
+		},
+		Notes: "Workers are listed by name unless `--sort-by` says otherwise. `commits` counts commits ahead of the default branch " +
+			"and `messages` counts unread messages; both list the most first unless `--sort-order asc` is given. " +
+			"The other fields sort ascending: `created` oldest first, `status` running, stopped, then completed. " +
+			"`--output-template` executes a Go template once with the workers (`.Name`, `.Repo`, `.Type`, `.Status`, `.Branch`, `.Task`, " +
+			"`.MessagesPending`, `.MessagesTotal`, `.CommitsAhead`); `{{.Task | truncate 30}}` shortens a field.",
+		Run: c.listWorkers,
+	}
+
+	workCmd.Subcommands["split"] = &Command{
+		Name:        "split",
+		Description: "Fork a worker into two workers with separate tasks",
+		Usage:       "multiclaude work split <worker-name> --into <task-a> --and <task-b> [--repo <repo>] [--remove-original]",
+		Flags: []FlagSpec{
+			{Name: "into", Type: "string", Description: "Task of the first worker (required)"},
+			{Name: "and", Type: "string", Description: "Task of the second worker (required)"},
+			repoFlag,
+			{Name: "remove-original", Type: "bool", Description: "Remove the worker that was split"},
+		},
+		Run: c.splitWorker,
+	}
+
+	workCmd.Subcommands["set-task"] = &Command{
+		Name:        "set-task",
+		Description: "Change a running worker's task",
+		Usage:       "multiclaude work set-task <worker-name> \"<new task>\" [--repo <repo>] [--notify]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "notify", Type: "bool", Description: "Also send the worker a message with its new task"},
+		},
+		Notes: "The new task shows in `work list` and, once the worker is cleaned up, in its task history entry along with the old one. " +
+			"`--notify` also sends the worker a message with the new task; without it, tell the worker yourself.",
+		Run: c.setWorkerTask,
+	}
+
+	workCmd.Subcommands["exists"] = &Command{
+		Name:        "exists",
+		Description: "Check whether a worker exists",
+		Usage:       "multiclaude work exists <name> [--repo <repo>] [--verbose]",
+		Flags:       []FlagSpec{repoFlag, verboseFlag},
+		Notes:       "Ephemeral workers count. " + predicateNotes,
+		Predicate:   true,
+		Run:         c.workerExists,
+	}
+
+	workCmd.Subcommands["unblock"] = &Command{
+		Name:        "unblock",
+		Description: "Close a pager, answer a [y/n] prompt or abort a conflicted rebase in a stuck worker",
+		Usage:       "multiclaude work unblock <worker-name> [--repo <repo>] [--force] [--auto-approve]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "force", Type: "bool", Description: "Don't ask when in doubt"},
+			{Name: "auto-approve", Type: "bool", Description: "Answer prompts y unasked"},
+		},
+		Run: c.unblockWorker,
+	}
+
+	workCmd.Subcommands["commit"] = &Command{
+		Name:        "commit",
+		Description: "Commit in a worker's worktree from outside it, e.g. from CI",
+		Usage:       "multiclaude work commit <worker-name> [--message <msg>] [--add-all] [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "message", Type: "string", Default: defaultCommitMessage, Description: "Commit message"},
+			{Name: "add-all", Type: "bool", Description: "Stage every change first (git add -A)"},
+			repoFlag,
+		},
+		Notes: "Power users only: the worker's Claude process is paused (SIGSTOP) during the commit, but it may be mid-edit. Prints the new commit.",
+		Run:   c.commitWorker,
+	}
+
+	workCmd.Subcommands["merge-into-workspace"] = &Command{
+		Name:        "merge-into-workspace",
+		Description: "Merge a worker's branch into a workspace's branch",
+		Usage:       "multiclaude work merge-into-workspace <worker-name> <workspace-name> [--strategy ours|theirs|recursive] [--squash] [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "strategy", Type: "string", Description: "Settle conflicts with ours or theirs, or use the recursive strategy"},
+			{Name: "squash", Type: "bool", Description: "Combine the worker's commits into one commit"},
+			repoFlag,
+		},
+		Notes: "The merge runs in the workspace's worktree, which must have no uncommitted changes, and the workspace's agent is sent a message about it. " +
+			"The worker and its branch are left as they are. `--strategy ours` or `theirs` settles conflicting hunks in favor of the workspace or the worker; " +
+			"`recursive` uses git's recursive strategy. Other conflicts abort the merge, leaving the workspace as it was, and list the conflicting files. " +
+			"`--squash` combines the worker's commits into one commit.",
+		Run: c.mergeWorkerIntoWorkspace,
 	}
 
 	workCmd.Subcommands["rm"] = &Command{
 		Name:        "rm",
 		Description: "Remove a worker",
-		Usage:       "multiclaude work rm <worker-name>",
-		Run:         c.removeWorker,
+		Usage:       "multiclaude work rm <worker-name> [--yes] [--no-archive] [--force]",
+		Flags: []FlagSpec{
+			yesFlag,
+			{Name: "no-archive", Type: "bool", Description: "Keep the branch instead of saving it as a bundle and deleting it"},
+			{Name: "force", Type: "bool", Description: "Remove the worker even if its bundle cannot be created"},
+			repoFlag,
+		},
+		Notes: "Uncommitted or unpushed work triggers a confirmation prompt. Without a terminal on stdin (e.g. when run by an agent) the prompt fails immediately instead of waiting; pass `--yes` or set `MULTICLAUDE_ASSUME_YES=1` to proceed. " +
+			"The worker's branch is first saved as a git bundle (see `work archives`) and then deleted with the worktree; `--no-archive` skips the bundle and keeps the branch. " +
+			"If the bundle cannot be created the worker is not removed, unless `--force` is given.",
+		Run: c.removeWorker,
+	}
+
+	workCmd.Subcommands["gc-branches"] = &Command{
+		Name:        "gc-branches",
+		Description: "Delete merged or stale work/* branches",
+		Usage:       "multiclaude work gc-branches [--repo <repo>] [--merged] [--stale-after 30d] [--dry-run] [--yes]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "merged", Type: "bool", Description: "Select branches merged into origin's default branch"},
+			{Name: "stale-after", Type: "duration", Description: "Select branches with no commits in this long, e.g. 30d"},
+			{Name: "dry-run", Type: "bool", Description: "Only list the branches"},
+			yesFlag,
+		},
+		Notes: "`--merged` selects work/* branches merged into origin's default branch, both local ones and those on origin; " +
+			"`--stale-after` selects those with no commits in that long (e.g. `30d`, `12h`). Pass either or both. " +
+			"Local merged branches are deleted with `git branch -d`; stale ones are first bundled into the repo's archives (see `work archives`), " +
+			"kept if that fails, and deleted with `git branch -D`. Remote ones are deleted with `git push origin --delete`. " +
+			"Branches checked out in a worktree, i.e. those of running workers, are skipped. " +
+			"Deleting asks for confirmation unless `--yes` is given; `--dry-run` only lists the branches.",
+		Run: c.gcWorkBranches,
+	}
+
+	workArchivesCmd := &Command{
+		Name:        "archives",
+		Description: "Bundles of removed workers' branches",
+		Usage:       "multiclaude work archives [list|restore] [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Notes: "`work rm`, the daemon's cleanup of finished workers and `cleanup --merged` save each branch they delete as a git bundle in " +
+			"`output/<repo>/archives/<branch>-<date>.bundle`. The daemon removes bundles older than 30 days; " +
+			"change that with `multiclaude config <repo> --archive-max-age=<duration>` and cap their total size with `--archive-max-size-mb=<n>`.",
+		Subcommands: make(map[string]*Command),
+	}
+
+	workArchivesCmd.Run = c.listArchives // Default action: list
+
+	workArchivesCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List archived branch bundles",
+		Usage:       "multiclaude work archives list [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Run: c.listArchives,
+	}
+
+	workArchivesCmd.Subcommands["restore"] = &Command{
+		Name:        "restore",
+		Description: "Recreate a branch from an archived bundle",
+		Usage:       "multiclaude work archives restore <bundle> [--as-branch <name>] [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "as-branch", Type: "string", Description: "Branch name to use when the original one is taken"},
+			repoFlag,
+		},
+		Notes: "<bundle> is a file name from `work archives list` or a path to a bundle. The branch is fetched from it under its original name, " +
+			"or under `--as-branch` when that name is taken. Start a worker from it with `multiclaude work \"<task>\" --branch <name>`.",
+		Run: c.restoreArchive,
 	}
 
+	workCmd.Subcommands["archives"] = workArchivesCmd
+
 	c.rootCmd.Subcommands["work"] = workCmd
 
 	// Workspace commands
@@ -350,6 +885,12 @@ func (c *CLI) registerCommands() {
 		Name:        "workspace",
 		Description: "Manage workspaces",
 		Usage:       "multiclaude workspace [<name>]",
+		Flags: []FlagSpec{
+			{Name: "all-repos", Type: "bool", Description: "List the workspaces of every tracked repository"},
+			{Name: "json", Type: "bool", Description: "List as JSON"},
+			{Name: "read-only", Shorthand: "r", Type: "bool", Description: "Connect without sending keystrokes"},
+			repoFlag,
+		},
 		Subcommands: make(map[string]*Command),
 	}
 
@@ -358,29 +899,282 @@ func (c *CLI) registerCommands() {
 	workspaceCmd.Subcommands["add"] = &Command{
 		Name:        "add",
 		Description: "Add a new workspace",
-		Usage:       "multiclaude workspace add <name> [--branch <branch>]",
-		Run:         c.addWorkspace,
+		Usage:       "multiclaude workspace add <name> [--branch <branch> | --copy-from <ws> [--copy-messages]] [--context-file <path>]... [--context -] [--no-submodules]",
+		Flags: []FlagSpec{
+			{Name: "branch", Type: "string", Default: "the default branch", Description: "Branch to start the workspace from"},
+			{Name: "copy-from", Type: "string", Description: "Fork a workspace: its HEAD and prompt"},
+			{Name: "copy-messages", Type: "bool", Description: "Also copy its received messages"},
+			contextFileFlag,
+			contextFlag,
+			noSubmodulesFlag,
+			repoFlag,
+		},
+		Run: c.addWorkspace,
 	}
 
 	workspaceCmd.Subcommands["rm"] = &Command{
 		Name:        "rm",
 		Description: "Remove a workspace",
-		Usage:       "multiclaude workspace rm <name>",
-		Run:         c.removeWorkspace,
+		Usage:       "multiclaude workspace rm <name> [--yes]",
+		Flags: []FlagSpec{
+			yesFlag,
+			repoFlag,
+		},
+		Notes: "Uncommitted or unpushed work triggers a confirmation prompt. Without a terminal on stdin (e.g. when run by an agent) the prompt fails immediately instead of waiting; pass `--yes` or set `MULTICLAUDE_ASSUME_YES=1` to proceed.",
+		Run:   c.removeWorkspace,
 	}
 
 	workspaceCmd.Subcommands["list"] = &Command{
 		Name:        "list",
 		Description: "List workspaces",
-		Usage:       "multiclaude workspace list",
-		Run:         c.listWorkspaces,
+		Usage:       "multiclaude workspace list [--all-repos | --group <name>] [--show-messages] [--json]",
+		Flags: []FlagSpec{
+			{Name: "all-repos", Type: "bool", Description: "List the workspaces of every tracked repository"},
+			{Name: "show-messages", Type: "bool", Description: "Add unread and total message columns"},
+			{Name: "json", Type: "bool", Description: "Print as JSON"},
+			repoFlag,
+			groupFlag,
+		},
+		Notes: "`--all-repos` lists the workspaces of every tracked repository in one table with a REPO column, sorted by repository then name. Repositories that cannot be queried are reported as warnings. " +
+			"`--group` lists those of a group's repositories, one section per repository; with `--json` they are nested by group, then repository.",
+		Run:   c.listWorkspaces,
+	}
+
+	workspaceCmd.Subcommands["show"] = &Command{
+		Name:        "show",
+		Description: "Print one field of a workspace, for scripts",
+		Usage:       "multiclaude workspace show <name> [--repo <repo>] [--field name|branch|path|session-id|status]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "field", Type: "string", Default: "path", Description: "Field to print: name, branch, path, session-id or status"},
+		},
+		Notes: "Prints the worktree path unless `--field` picks another field, with no trailing newline, e.g. `cd \"$(multiclaude workspace show dev)\"`. " +
+			"The fields are those of `workspace list --json`.",
+		Run: c.showWorkspace,
 	}
 
 	workspaceCmd.Subcommands["connect"] = &Command{
 		Name:        "connect",
 		Description: "Connect to a workspace",
 		Usage:       "multiclaude workspace connect <name>",
-		Run:         c.connectWorkspace,
+		Flags: []FlagSpec{
+			{Name: "read-only", Shorthand: "r", Type: "bool", Description: "Attach without sending keystrokes"},
+			repoFlag,
+		},
+		Run: c.connectWorkspace,
+	}
+
+	workspaceCmd.Subcommands["create-pr"] = &Command{
+		Name:        "create-pr",
+		Description: "Push a workspace branch and open a pull request",
+		Usage:       "multiclaude workspace create-pr <name> [--title \"...\"] [--body \"...\"] [--base main] [--draft] [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "title", Type: "string", Default: "the last commit's subject", Description: "PR title"},
+			{Name: "body", Type: "string", Description: "PR description"},
+			{Name: "base", Type: "string", Default: "the upstream, else the default branch", Description: "Branch to merge into"},
+			{Name: "draft", Type: "bool", Description: "Open the PR as a draft"},
+			repoFlag,
+		},
+		Notes: "Without `--base`, the PR targets the branch set with `workspace set-upstream`, else the remote's default branch.",
+		Run:   c.createWorkspacePR,
+	}
+
+	workspaceCmd.Subcommands["set-upstream"] = &Command{
+		Name:        "set-upstream",
+		Description: "Make a workspace track a remote branch",
+		Usage:       "multiclaude workspace set-upstream <name> --branch <branch> [--remote origin] [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "branch", Type: "string", Description: "Branch on the remote to track"},
+			{Name: "remote", Type: "string", Default: "origin", Description: "Remote the branch is on"},
+			repoFlag,
+		},
+		Notes: "Runs `git branch --set-upstream-to=<remote>/<branch>` in the workspace, fetching the remote first if the branch is not known yet, " +
+			"and records the upstream. `git pull` and `workspace fetch` then follow it, and `workspace create-pr` opens PRs against it.",
+		Run: c.setWorkspaceUpstream,
+	}
+
+	workspaceCmd.Subcommands["show-upstream"] = &Command{
+		Name:        "show-upstream",
+		Description: "Show the branch a workspace tracks",
+		Usage:       "multiclaude workspace show-upstream <name> [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Notes: "Shows the upstream set with `workspace set-upstream`, the branch git tracks, the commits ahead of and behind it, " +
+			"and warns when git no longer tracks the recorded upstream.",
+		Run: c.showWorkspaceUpstream,
+	}
+
+	workspaceCmd.Subcommands["create-from-pr"] = &Command{
+		Name:        "create-from-pr",
+		Description: "Create a workspace on a pull request's branch",
+		Usage:       "multiclaude workspace create-from-pr <pr-url> [--name <name>] [--repo <repo>] [--watch] [--no-submodules]",
+		Flags: []FlagSpec{
+			{Name: "name", Type: "string", Default: "pr-<number>", Description: "Workspace name"},
+			repoFlag,
+			{Name: "watch", Type: "bool", Description: "Message the workspace once the PR is merged or closed"},
+			noSubmodulesFlag,
+		},
+		Notes: "Looks the PR up with `gh pr view`, fetches its branch and checks it out under its own name, tracking `origin/<branch>`, " +
+			"so pushes from the workspace update the PR. The workspace is named `pr-<number>` unless `--name` is given, and records the PR " +
+			"for `workspace pr-status` and `workspace show-pr`. With `--watch` the daemon checks the PR every 5 minutes and messages the " +
+			"workspace once it is merged or closed. PRs from forks cannot be pushed to; use `multiclaude review` for those.",
+		Run: c.createWorkspaceFromPR,
+	}
+
+	workspaceCmd.Subcommands["pr-status"] = &Command{
+		Name:        "pr-status",
+		Description: "Show the GitHub status of workspace pull requests",
+		Usage:       "multiclaude workspace pr-status [<name>] [--repo <repo>] [--all]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "all", Type: "bool", Description: "Cover every tracked repository"},
+		},
+		Notes: "With a name, reports the state, mergeability, reviews and CI checks of the PR recorded by `workspace create-pr`. " +
+			"Without one, shows a table of every workspace with a PR in the repository; `--all` covers every tracked repository. Requires the `gh` CLI.",
+		Run: c.workspacePRStatus,
+	}
+
+	workspaceCmd.Subcommands["fetch"] = &Command{
+		Name:        "fetch",
+		Description: "Fetch origin and report what arrived for each workspace",
+		Usage:       "multiclaude workspace fetch [<name>] [--all] [--prune] [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "all", Type: "bool", Description: "Cover every tracked repository"},
+			{Name: "prune", Type: "bool", Description: "Remove remote-tracking branches deleted on origin"},
+		},
+		Notes: "Reports the commits that arrived on each workspace's upstream, or the default branch if it has none. " +
+			"Each repository is fetched once, as worktrees share refs; `--all` fetches up to 3 at a time. No branch is changed.",
+		Run: c.fetchWorkspaces,
+	}
+
+	workspaceCmd.Subcommands["compare"] = &Command{
+		Name:        "compare",
+		Description: "Diff the branches of two workspaces",
+		Usage:       "multiclaude workspace compare <workspace-a> <workspace-b> [--repo <repo>] [--base main] [--stat] [--output-format unified|stat|name-only]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "base", Type: "string", Description: "Show what each workspace changed since its merge base with this branch"},
+			{Name: "stat", Type: "bool", Description: "Short for --output-format stat"},
+			{Name: "output-format", Type: "string", Default: "unified", Description: "unified, stat or name-only"},
+		},
+		Notes: "Runs `git diff workspace/<a> workspace/<b>` in the repository's main checkout. With `--base`, shows instead what each workspace " +
+			"changed since the common ancestor of both branches and the base (via `git merge-base`), one after the other. " +
+			"`--stat` is short for `--output-format stat`. Read-only: it works whatever the workspaces' agents are doing, as long as both branches exist locally.",
+		Run: c.compareWorkspaces,
+	}
+
+	workspaceCmd.Subcommands["rebase-interactive"] = &Command{
+		Name:        "rebase-interactive",
+		Description: "Start an interactive rebase in a workspace",
+		Usage:       "multiclaude workspace rebase-interactive <name> [--onto main] [--last N] [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "onto", Type: "string", Default: "the default branch", Description: "Branch to rebase onto"},
+			{Name: "last", Type: "int", Description: "Rebase only the last N commits"},
+			repoFlag,
+		},
+		Run: c.rebaseWorkspaceInteractive,
+	}
+
+	workspaceCmd.Subcommands["show-pr"] = &Command{
+		Name:        "show-pr",
+		Description: "Open a workspace's pull request in the browser",
+		Usage:       "multiclaude workspace show-pr <name> [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Run: c.showWorkspacePR,
+	}
+
+	workspaceCmd.Subcommands["snapshot"] = &Command{
+		Name:        "snapshot",
+		Description: "Save a workspace's uncommitted work without committing it",
+		Usage:       "multiclaude workspace snapshot <name> [--label <text>] [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "label", Type: "string", Description: "Text to remember the snapshot by"},
+			repoFlag,
+		},
+		Notes: "Saves tracked and untracked files, but not ignored ones, as a commit under the hidden ref " +
+			"`refs/multiclaude/snapshots/<name>/<id>`, written through a temporary index: the working tree, staged changes and branch " +
+			"are left alone, and the snapshot never appears in the branch's history. The daemon keeps the newest 20 snapshots of each " +
+			"workspace; change that with `multiclaude config --snapshot-keep=<n>`.",
+		Run: c.snapshotWorkspace,
+	}
+
+	workspaceSnapshotsCmd := &Command{
+		Name:        "snapshots",
+		Description: "List a workspace's snapshots",
+		Usage:       "multiclaude workspace snapshots list <name> [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Subcommands: make(map[string]*Command),
+		Run:         c.listWorkspaceSnapshots,
+	}
+	workspaceSnapshotsCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List a workspace's snapshots, newest first",
+		Usage:       "multiclaude workspace snapshots list <name> [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Notes: "Shows each snapshot's ID, age, label and how much it changes relative to the commit it was taken on. Snapshots of removed workspaces are listed until pruned.",
+		Run:   c.listWorkspaceSnapshots,
+	}
+	workspaceCmd.Subcommands["snapshots"] = workspaceSnapshotsCmd
+
+	workspaceCmd.Subcommands["restore"] = &Command{
+		Name:        "restore",
+		Description: "Restore a workspace's working tree from a snapshot",
+		Usage:       "multiclaude workspace restore <name> <snapshot-id> [--force] [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "force", Type: "bool", Description: "Skip the confirmation before saving uncommitted changes"},
+			yesFlag,
+			repoFlag,
+		},
+		Notes: "Restores the snapshot's files and removes files added since; ignored files, the branch and HEAD are left alone, and the " +
+			"restored changes are left unstaged. If the workspace has uncommitted changes, they are saved as a new snapshot first, " +
+			"after a confirmation prompt that `--force` skips.",
+		Run: c.restoreWorkspaceSnapshot,
+	}
+
+	workspaceCmd.Subcommands["revert"] = &Command{
+		Name:        "revert",
+		Description: "Reset a workspace's branch to an earlier commit",
+		Usage:       "multiclaude workspace revert <name> --to-commit <ref> [--soft] [--force] [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "to-commit", Type: "string", Description: "Commit ID, tag or relative ref (e.g. HEAD~3) to reset to"},
+			{Name: "soft", Type: "bool", Description: "Keep the working tree, staging the changes reset past"},
+			{Name: "force", Type: "bool", Description: "Discard uncommitted changes instead of refusing"},
+			repoFlag,
+		},
+		Notes: "Runs `git reset --hard` (or `--soft`) in the workspace's worktree, pausing its Claude process with SIGSTOP meanwhile, " +
+			"then messages the workspace that it was reverted. A hard reset refuses to discard uncommitted changes unless `--force` " +
+			"is given; take a `workspace snapshot` first to keep them.",
+		Run: c.revertWorkspace,
+	}
+
+	workspaceCmd.Subcommands["set-context"] = &Command{
+		Name:        "set-context",
+		Description: "Set a context value listed in agents' prompts",
+		Usage:       "multiclaude workspace set-context <key> <value> [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Notes: "Context values (sprint goals, freeze dates, who is on call, ...) are listed in a \"Current Context\" table in every prompt file " +
+			"written from then on, for all agent types; agents already running are not told. An empty value (`\"\"`) removes the key.",
+		Run: c.setContext,
+	}
+
+	workspaceCmd.Subcommands["get-context"] = &Command{
+		Name:        "get-context",
+		Description: "Show a context value, or all of them",
+		Usage:       "multiclaude workspace get-context [<key>] [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Run: c.getContext,
 	}
 
 	c.rootCmd.Subcommands["workspace"] = workspaceCmd
@@ -390,7 +1184,14 @@ func (c *CLI) registerCommands() {
 		Name:        "history",
 		Description: "Show task history for a repository",
 		Usage:       "multiclaude history [--repo <repo>] [-n <count>] [--status <status>] [--search <query>] [--full]",
-		Run:         c.showHistory,
+		Flags: []FlagSpec{
+			repoFlag,
+			{Shorthand: "n", Type: "int", Default: "10", Description: "Number of tasks to show"},
+			{Name: "status", Type: "string", Description: "Task status: merged, open, closed, failed, no-pr or synced"},
+			{Name: "search", Type: "string", Description: "Show only tasks whose description contains this text"},
+			{Name: "full", Type: "bool", Description: "Show full task descriptions"},
+		},
+		Run: c.showHistory,
 	}
 
 	// Agent commands (run from within Claude)
@@ -403,22 +1204,76 @@ func (c *CLI) registerCommands() {
 	agentCmd.Subcommands["send-message"] = &Command{
 		Name:        "send-message",
 		Description: "Send a message to another agent",
-		Usage:       "multiclaude agent send-message <recipient> <message>",
-		Run:         c.sendMessage,
+		Usage:       "multiclaude agent send-message <recipient> <message> | <recipient> --template <name> [--var key=value]... [--schedule <time>] [--idempotency-key <key>]",
+		Flags: []FlagSpec{
+			{Name: "template", Type: "string", Description: "Message template to fill in instead of a message"},
+			{Name: "var", Type: "string", Description: "key=value for a template placeholder; repeatable"},
+			{Name: "schedule", Type: "string", Description: "Deliver later: a delay such as +2h, or a local time"},
+			{Name: "idempotency-key", Type: "string", Description: "Do not send again while a message with this key is waiting"},
+		},
+		Notes: "`--schedule` holds the message until a later time: a delay such as `+2h`, `+30m` or `+1d`, or a local time such as `\"2024-01-15 09:00\"`. " +
+			"Until then it can be cancelled with `multiclaude agent cancel-message <id>`; one still undelivered a day after its time is dropped. " +
+			"`--idempotency-key` makes retries safe: if a message to the same recipient with the same key is still there, it is not sent again. " +
+			"Templates fill `{{var}}` placeholders from `--var` (repeatable); `from`, `to` and `repo` are set automatically. " +
+			"A repository can add or override templates in `.multiclaude/messages/<name>.md`. Built-in templates:\n\n" +
+			messages.TemplatesDoc(messages.BuiltinTemplates()),
+		FreeformArgs: true,
+		Run:          c.sendMessage,
+	}
+
+	agentCmd.Subcommands["message-templates"] = &Command{
+		Name:        "message-templates",
+		Description: "List message templates for send-message --template",
+		Usage:       "multiclaude agent message-templates [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Run: c.listMessageTemplates,
 	}
 
 	agentCmd.Subcommands["list-messages"] = &Command{
 		Name:        "list-messages",
-		Description: "List pending messages",
-		Usage:       "multiclaude agent list-messages",
-		Run:         c.listMessages,
+		Description: "List messages, newest first",
+		Usage:       "multiclaude agent list-messages [--status pending|delivered|read|acked] [--unread] [--from <agent>] [--limit N] [--plain] | --scheduled [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "status", Type: "string", Description: "Message status: pending, delivered, read or acked"},
+			{Name: "unread", Type: "bool", Description: "Show pending and delivered messages"},
+			{Name: "from", Type: "string", Description: "Show only messages from this agent"},
+			{Name: "limit", Type: "int", Description: "Show at most N messages"},
+			{Name: "plain", Type: "bool", Description: "Print tab-separated fields with no header"},
+			{Name: "scheduled", Type: "bool", Description: "List messages waiting for their --schedule time"},
+			repoFlag,
+		},
+		Notes: "`--unread` shows pending and delivered messages. " +
+			"`--scheduled` instead lists every message in the repository still waiting for its `--schedule` time. " +
+			"`--plain` prints one message per line as tab-separated fields, with no header: " +
+			"`<id>\\t<timestamp RFC3339>\\t<from>\\t<status>\\t<body>`. " +
+			"IDs are never shortened, and whitespace in the body (including tabs and newlines) is collapsed to single spaces.",
+		Run: c.listMessages,
+	}
+
+	agentCmd.Subcommands["has-messages"] = &Command{
+		Name:        "has-messages",
+		Description: "Check whether you have messages waiting",
+		Usage:       "multiclaude agent has-messages [--unread] [--verbose]",
+		Flags: []FlagSpec{
+			{Name: "unread", Type: "bool", Description: "Count only pending and delivered messages"},
+			verboseFlag,
+		},
+		Notes:     "Counts messages not yet acknowledged, or with `--unread` not yet read. " + predicateNotes,
+		Predicate: true,
+		Run:       c.agentHasMessages,
 	}
 
 	agentCmd.Subcommands["read-message"] = &Command{
 		Name:        "read-message",
 		Description: "Read a specific message",
-		Usage:       "multiclaude agent read-message <message-id>",
-		Run:         c.readMessage,
+		Usage:       "multiclaude agent read-message <message-id> [--full]",
+		Flags: []FlagSpec{
+			{Name: "full", Type: "bool", Description: "Print all of a message delivered as an excerpt"},
+		},
+		Notes: "A message too large to deliver inline arrives as an excerpt; `--full` prints all of it.",
+		Run:   c.readMessage,
 	}
 
 	agentCmd.Subcommands["ack-message"] = &Command{
@@ -428,20 +1283,177 @@ func (c *CLI) registerCommands() {
 		Run:         c.ackMessage,
 	}
 
+	agentCmd.Subcommands["cancel-message"] = &Command{
+		Name:        "cancel-message",
+		Description: "Cancel a message that has not been delivered yet",
+		Usage:       "multiclaude agent cancel-message <message-id> [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Run: c.cancelMessage,
+	}
+
+	agentCmd.Subcommands["export-messages"] = &Command{
+		Name:        "export-messages",
+		Description: "Export an agent's messages to a file",
+		Usage:       "multiclaude agent export-messages <agent> --output <file> [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "output", Type: "path", Description: "File to write the messages to"},
+			repoFlag,
+		},
+		Notes: "Writes every message of the agent, whatever its status, as one JSON object per line (NDJSON), oldest first. " +
+			"Bodies too large to deliver inline are included in full. Use it to back up messages or move an agent to another machine.",
+		Run: c.exportMessages,
+	}
+
+	agentCmd.Subcommands["import-messages"] = &Command{
+		Name:        "import-messages",
+		Description: "Import messages from an export-messages file",
+		Usage:       "multiclaude agent import-messages <file> [--agent <name>] [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "agent", Type: "string", Description: "Import the messages for this agent instead"},
+			{Name: "repo", Type: "string", Description: "Import the messages into this repository instead"},
+		},
+		Notes: "Messages go back to the repository and agent they were exported from unless `--repo` or `--agent` says otherwise. " +
+			"Messages whose IDs are already there are skipped, so importing a file twice is harmless. " +
+			"The whole file is checked before anything is written.",
+		Run: c.importMessages,
+	}
+
 	agentCmd.Subcommands["complete"] = &Command{
 		Name:        "complete",
 		Description: "Signal worker completion",
 		Usage:       "multiclaude agent complete [--summary <text>] [--failure <reason>] [--pr-url <url>] [--pr-number <num>]",
-		Run:         c.completeWorker,
+		Flags: []FlagSpec{
+			{Name: "summary", Type: "string", Description: "What the worker did"},
+			{Name: "failure", Type: "string", Description: "Why the worker could not finish"},
+			{Name: "pr-url", Type: "string", Description: "URL of the worker's PR"},
+			{Name: "pr-number", Type: "int", Description: "Number of the worker's PR"},
+		},
+		Run: c.completeWorker,
+	}
+
+	agentCmd.Subcommands["set-status"] = &Command{
+		Name:        "set-status",
+		Description: "Mark an agent blocked, waiting or the like without stopping it",
+		Usage:       "multiclaude agent set-status <name> <status> [--repo <repo>] [--clear]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "clear", Type: "bool", Description: "Remove the status, showing the computed one again"},
+		},
+		Notes: "Any string is accepted; running, blocked, waiting and testing are the usual ones. " +
+			"`work list` shows the status in place of the computed one until the agent completes.",
+		Run: c.setAgentStatus,
+	}
+
+	agentCmd.Subcommands["whoami"] = &Command{
+		Name:        "whoami",
+		Description: "Check that the daemon still has this agent registered",
+		Usage:       "multiclaude agent whoami",
+		Notes: "Run from an agent's worktree or window. Prints the agent's type, status, session ID, pending messages and last nudge, " +
+			"or a warning that the daemon has no record of it and so will not nudge it, deliver its messages or clean up after it. " +
+			"Exits 0 when registered, 2 when not, and 1 when registration could not be checked, e.g. with the daemon down.",
+		Run: c.agentWhoami,
+	}
+
+	agentCmd.Subcommands["pr-comments"] = &Command{
+		Name:        "pr-comments",
+		Description: "Show the review comments on this worker's PR",
+		Usage:       "multiclaude agent pr-comments [--since <time>] [--unresolved]",
+		Flags: []FlagSpec{
+			{Name: "since", Type: "string", Description: "Show only comments from this long ago (e.g. 2h) or this time (RFC 3339) onwards"},
+			{Name: "unresolved", Type: "bool", Description: "Leave out resolved threads"},
+		},
+		Notes: "Run from the agent's worktree. The PR is the one recorded for the agent, or else gh's PR for the checked out branch. " +
+			"Open threads are printed with their file:line, resolved ones summarized a line each, and comments made since the last run marked [new]. " +
+			"A run without --since records the comments as read, so the daemon's nudges about new review comments only count later ones.",
+		Run: c.agentPRComments,
 	}
 
 	agentCmd.Subcommands["restart"] = &Command{
 		Name:        "restart",
 		Description: "Restart a crashed or exited agent",
-		Usage:       "multiclaude agent restart <name> [--repo <repo>] [--force]",
-		Run:         c.restartAgentCmd,
+		Usage:       "multiclaude agent restart <name> [--repo <repo>] [--force] [--clear-quarantine] [--refresh-docs]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "force", Type: "bool", Description: "Restart the agent even if it is running"},
+			{Name: "clear-quarantine", Type: "bool", Description: "Lift the quarantine of an agent that kept crashing and reset its restart count"},
+			{Name: "refresh-docs", Type: "bool", Description: "Replace the CLI reference in the agent's prompt with the installed multiclaude's, then restart it even if it is running"},
+		},
+		Notes: "The daemon restarts a persistent agent whose process dies, backing off between attempts. " +
+			"One that dies more often than the repository's crash-loop limit within its window (`multiclaude config --crash-loop-limit/--crash-loop-window`, 5 in 10m by default) is quarantined: " +
+			"it is no longer restarted, and the supervisor is sent the end of its pane. Fix the cause, then restart it with `--clear-quarantine`. " +
+			"`--refresh-docs` is for agents started by another multiclaude version, which `status` and `agent whoami` report: the rest of the prompt is kept.",
+		Run: c.restartAgentCmd,
+	}
+
+	agentCmd.Subcommands["add-supervisor"] = &Command{
+		Name:        "add-supervisor",
+		Description: "Add a supervisor to a repository initialized without one",
+		Usage:       "multiclaude agent add-supervisor [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Notes: "For repositories created with `init --no-supervisor`. The supervisor starts in its own window, and the repository's other agents are told to report to it from now on.",
+		Run:   c.addSupervisor,
+	}
+
+	agentCmd.Subcommands["set-env"] = &Command{
+		Name:        "set-env",
+		Description: "Set environment variables for an agent and restart it",
+		Usage:       "multiclaude agent set-env <name> KEY=VALUE [KEY=VALUE...] [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Notes: "Values are given to this agent's pane only, not to the repository's tmux session, so other agents never see them. " +
+			"The agent is stopped with SIGTERM, its pane respawned with the values and Claude relaunched, resuming its conversation. Values are never printed or logged.",
+		Run: c.setAgentEnv,
+	}
+
+	agentMQCmd := &Command{
+		Name:        "mq",
+		Description: "Merge-queue PR tracking that survives agent restarts",
+		Subcommands: make(map[string]*Command),
+	}
+
+	agentMQCmd.Subcommands["track"] = &Command{
+		Name:        "track",
+		Description: "Record the merge queue's status for a PR",
+		Usage:       "multiclaude agent mq track <pr-number> [--status <status>] [--url <url>] [--notes <text>] [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "status", Type: "string", Description: "The PR's status in the merge queue"},
+			{Name: "url", Type: "string", Description: "URL of the PR"},
+			{Name: "notes", Type: "string", Description: "Free-form notes"},
+			repoFlag,
+		},
+		Notes: "Tracking an already tracked PR updates it; omitted fields keep their previous values. " +
+			"Records are stored in the daemon state, pruned when the PR is merged or closed, " +
+			"and summarized to a freshly started merge-queue agent.",
+		Run: c.mqTrackPR,
+	}
+
+	agentMQCmd.Subcommands["untrack"] = &Command{
+		Name:        "untrack",
+		Description: "Stop tracking a PR",
+		Usage:       "multiclaude agent mq untrack <pr-number> [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Run: c.mqUntrackPR,
+	}
+
+	agentMQCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List PRs tracked by the merge queue",
+		Usage:       "multiclaude agent mq list [--repo <repo>]",
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Run: c.mqListPRs,
 	}
 
+	agentCmd.Subcommands["mq"] = agentMQCmd
+
 	c.rootCmd.Subcommands["agent"] = agentCmd
 
 	// Attach command
@@ -449,22 +1461,144 @@ func (c *CLI) registerCommands() {
 		Name:        "attach",
 		Description: "Attach to an agent",
 		Usage:       "multiclaude attach <agent-name> [--read-only]",
-		Run:         c.attachAgent,
+		Flags: []FlagSpec{
+			{Name: "read-only", Shorthand: "r", Type: "bool", Description: "Attach without sending keystrokes"},
+			repoFlag,
+		},
+		Run: c.attachAgent,
+	}
+
+	c.rootCmd.Subcommands["map"] = &Command{
+		Name:        "map",
+		Description: "Show a repository's tmux windows and the agent in each",
+		Usage:       "multiclaude map [--repo <repo>] [--watch]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "watch", Type: "bool", Description: "Redraw every few seconds"},
+		},
+		Notes: "Windows are listed in index order with the agent's name, type, status, idle time and task; the active window is marked `*` " +
+			"and windows no agent runs in are flagged `(unmanaged)`. `--watch` redraws the map every few seconds. " +
+			"When the tmux session cannot be reached, the agents are listed from state without window indices.",
+		Run: c.sessionMap,
 	}
 
 	// Maintenance commands
 	c.rootCmd.Subcommands["cleanup"] = &Command{
 		Name:        "cleanup",
 		Description: "Clean up orphaned resources",
-		Usage:       "multiclaude cleanup [--dry-run] [--verbose] [--merged]",
-		Run:         c.cleanup,
+		Usage:       "multiclaude cleanup [--dry-run] [--verbose] [--aggressive] [--merged [--group <name>]] [--no-archive] [--force]",
+		Flags: []FlagSpec{
+			{Name: "dry-run", Type: "bool", Description: "Show what would be removed without removing it"},
+			{Name: "verbose", Shorthand: "v", Type: "bool", Description: "Show details"},
+			{Name: "aggressive", Type: "bool", Description: "Also kill untagged mc-* tmux sessions this root does not track"},
+			{Name: "merged", Type: "bool", Description: "Also delete local branches merged upstream"},
+			{Name: "no-archive", Type: "bool", Description: "Delete merged branches without saving them as bundles"},
+			{Name: "force", Type: "bool", Description: "Delete branches whose bundle cannot be created"},
+			groupFlag,
+		},
+		Notes: "With `--merged`, each merged branch is saved as a git bundle (see `work archives`) before it is deleted; `--no-archive` skips that. " +
+			"A branch whose bundle cannot be created is kept, unless `--force` is given. `--group` limits `--merged` to a group's repositories. " +
+			"Tmux sessions are tagged with the multiclaude root that created them, and only sessions of this root are killed. " +
+			"Untagged `mc-*` sessions this root does not track may belong to another root sharing the tmux server; they are reported, and killed only with `--aggressive`.",
+		Run: c.cleanup,
 	}
 
 	c.rootCmd.Subcommands["repair"] = &Command{
 		Name:        "repair",
 		Description: "Repair state after crash",
-		Usage:       "multiclaude repair [--verbose]",
-		Run:         c.repair,
+		Usage:       "multiclaude repair [--repo <name>] [--verbose]",
+		Flags: []FlagSpec{
+			{Name: "repo", Type: "string", Description: "Repair only this repository"},
+			{Name: "verbose", Shorthand: "v", Type: "bool", Description: "Show details"},
+		},
+		Run: c.repair,
+	}
+
+	stateCmd := &Command{
+		Name:        "state",
+		Description: "Back up and restore state.json",
+		Subcommands: make(map[string]*Command),
+	}
+
+	backupsCmd := &Command{
+		Name:        "backups",
+		Description: "Manage the automatic backups of state.json",
+		Subcommands: make(map[string]*Command),
+	}
+
+	backupsCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List the backups of state.json",
+		Usage:       "multiclaude state backups list",
+		Notes: "Every save of state.json that changes it also writes a backup to state-backups/ next to it, atomically, and rewrites " +
+			"state-backups/manifest.json with the backups kept. By default the last 10 are kept, plus the newest of each of the last 7 days. " +
+			"Bug reports leave the backups out unless given --include-backups.",
+		Run: c.stateBackupsList,
+	}
+
+	backupsCmd.Subcommands["config"] = &Command{
+		Name:        "config",
+		Description: "Show or change how many state backups are kept",
+		Usage:       "multiclaude state backups config [--keep <n>] [--days <n>]",
+		Flags: []FlagSpec{
+			{Name: "keep", Type: "int", Default: "10", Description: "Number of most recent backups to keep"},
+			{Name: "days", Type: "int", Default: "7", Description: "Number of days to keep one backup a day for"},
+		},
+		Notes: "The settings are kept in state-backups/manifest.json, so they take effect at the daemon's next save without a restart. " +
+			"Backups the new settings no longer keep are removed at once.",
+		Run: c.stateBackupsConfig,
+	}
+
+	stateCmd.Subcommands["backups"] = backupsCmd
+
+	stateCmd.Subcommands["restore"] = &Command{
+		Name:        "restore",
+		Description: "Restore state.json from a backup and repair it",
+		Usage:       "multiclaude state restore <timestamp> [--dry-run] [--force]",
+		Flags: []FlagSpec{
+			{Name: "dry-run", Type: "bool", Description: "Show how the backup differs from now without restoring it"},
+			{Name: "force", Type: "bool", Description: "Pause a running daemon during the restore instead of refusing"},
+		},
+		Notes: "<timestamp> is a backup ID from `multiclaude state backups list`, or a time (e.g. 2026-01-02T15:04:05Z, \"2026-01-02 15:04\" " +
+			"in local time, or a date for the end of that day) to restore the newest backup taken at or before it. " +
+			"First shows how the backup differs from the current state and from the tmux sessions and worktrees that exist now. " +
+			"Refuses while the daemon runs, since it would overwrite the restored file, unless --force, which pauses the daemon and " +
+			"has it reload the state afterwards. The replaced state.json is backed up first, then `multiclaude repair --verbose` " +
+			"reconciles the restored state with what exists.",
+		Run: c.stateRestore,
+	}
+
+	c.rootCmd.Subcommands["state"] = stateCmd
+
+	c.rootCmd.Subcommands["smoke"] = &Command{
+		Name:        "smoke",
+		Description: "Run an end-to-end self-test in a throwaway root",
+		Usage:       "multiclaude smoke [--verbose]",
+		Flags: []FlagSpec{
+			{Name: "verbose", Type: "bool", Description: "Show the output of init"},
+		},
+		Notes: "Starts a daemon in a temporary root with its own tmux server, initializes a scratch repository with `init --local`, " +
+			"starts a fake agent running `cat` instead of claude, sends it a message and checks that routing marks it delivered " +
+			"and that output capture recorded it, then tears everything down. Prints PASS or FAIL per stage and exits non-zero on any failure. " +
+			"Needs no network or claude and never touches ~/.multiclaude, so it can run in CI. `--verbose` shows the output of init.",
+		Run: c.smoke,
+	}
+
+	c.rootCmd.Subcommands["migrate-paths"] = &Command{
+		Name:        "migrate-paths",
+		Description: "Move ~/.multiclaude into the XDG base directories",
+		Usage:       "multiclaude migrate-paths [--dry-run] [--yes]",
+		Flags: []FlagSpec{
+			{Name: "dry-run", Type: "bool", Description: "Show the plan without changing anything"},
+			yesFlag,
+		},
+		Notes: "The XDG layout keeps state in $XDG_STATE_HOME/multiclaude (default ~/.local/state), logs and agent output in " +
+			"$XDG_CACHE_HOME/multiclaude (default ~/.cache) and paths.json in $XDG_CONFIG_HOME/multiclaude (default ~/.config). " +
+			"It is opt-in: `\"layout\": \"xdg\"` in that paths.json selects it, and MULTICLAUDE_XDG=1 or =0 overrides the file. " +
+			"migrate-paths stops the daemon, moves the classic layout's files, rewrites the absolute paths in state.json and the prompt files, " +
+			"writes the XDG paths.json, repairs git's worktree links and checks that every agent's worktree still resolves. " +
+			"Re-running it skips whatever was already moved. `--dry-run` shows the plan without changing anything.",
+		Run: c.migratePaths,
 	}
 
 	// Claude restart command - for resuming Claude after exit
@@ -479,16 +1613,30 @@ func (c *CLI) registerCommands() {
 	c.rootCmd.Subcommands["docs"] = &Command{
 		Name:        "docs",
 		Description: "Show generated CLI documentation",
-		Usage:       "multiclaude docs",
-		Run:         c.showDocs,
+		Usage:       "multiclaude docs [--agent-type <type>] [--repo <repo>]",
+		Flags: []FlagSpec{
+			{Name: "agent-type", Type: "string", Description: "Show the reference this type of agent gets"},
+			repoFlag,
+		},
+		Notes: "Agents get this reference in their prompt. When an agent's composed prompt is over the budget (40000 characters, about 10000 tokens; " +
+			"set `MULTICLAUDE_PROMPT_BUDGET` to a number of characters, a number of tokens such as `10000t`, or `0` for no budget), " +
+			"the reference is trimmed to the commands that agent type uses. The full reference is over the budget for every type, so trimming is the norm and agent creation does not mention it. " +
+			"`--agent-type` (supervisor, worker, merge-queue, workspace, review or ephemeral) shows the reference that type of agent gets in the repository, its size and the commands left out.",
+		Run: c.showDocs,
 	}
 
 	// Review command
 	c.rootCmd.Subcommands["review"] = &Command{
 		Name:        "review",
 		Description: "Spawn a review agent for a PR",
-		Usage:       "multiclaude review <pr-url>",
-		Run:         c.reviewPR,
+		Usage:       "multiclaude review <pr-url> [--context-file <path>]... [--context -] [--no-submodules]",
+		Flags: []FlagSpec{
+			contextFileFlag,
+			contextFlag,
+			noSubmodulesFlag,
+			repoFlag,
+		},
+		Run: c.reviewPR,
 	}
 
 	// Logs commands
@@ -496,6 +1644,11 @@ func (c *CLI) registerCommands() {
 		Name:        "logs",
 		Description: "View and manage agent output logs",
 		Usage:       "multiclaude logs [<agent-name>] [-f|--follow]",
+		Flags: []FlagSpec{
+			{Name: "follow", Shorthand: "f", Type: "bool", Description: "Keep printing new lines as they are written"},
+			{Name: "lines", Type: "int", Default: "100", Description: "Number of lines to show"},
+			repoFlag,
+		},
 		Subcommands: make(map[string]*Command),
 	}
 
@@ -505,21 +1658,106 @@ func (c *CLI) registerCommands() {
 		Name:        "list",
 		Description: "List log files",
 		Usage:       "multiclaude logs list [--repo <repo>]",
-		Run:         c.listLogs,
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Run: c.listLogs,
 	}
 
 	logsCmd.Subcommands["search"] = &Command{
 		Name:        "search",
 		Description: "Search across logs",
 		Usage:       "multiclaude logs search <pattern> [--repo <repo>]",
-		Run:         c.searchLogs,
+		Flags: []FlagSpec{
+			repoFlag,
+		},
+		Notes: "The pattern is a Go regular expression, or plain text if it is not a valid one. " +
+			"Logs are searched as output capture filters them, without escape codes or repeated redraws, even when kept raw with `config --raw-logs`; " +
+			"line numbers count the filtered lines.",
+		Run: c.searchLogs,
+	}
+
+	logsCmd.Subcommands["_capture"] = &Command{
+		Name:        "_capture",
+		Description: "Internal: filter pane output from stdin into a log file (used by output capture)",
+		Run:         c.captureLog,
 	}
 
 	logsCmd.Subcommands["clean"] = &Command{
 		Name:        "clean",
 		Description: "Remove old logs",
 		Usage:       "multiclaude logs clean --older-than <duration>",
-		Run:         c.cleanLogs,
+		Flags: []FlagSpec{
+			{Name: "older-than", Type: "duration", Description: "Remove logs not written to in this long (required)"},
+		},
+		Run: c.cleanLogs,
+	}
+
+	logsCmd.Subcommands["diff"] = &Command{
+		Name:        "diff",
+		Description: "Show log lines written within a time window",
+		Usage:       "multiclaude logs diff <agent-name> [--repo <repo>] [--after <time>] [--before <time>] [--context N]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "after", Type: "time", Default: "start of the log", Description: "Start of the window"},
+			{Name: "before", Type: "time", Default: "now", Description: "End of the window"},
+			{Name: "context", Type: "int", Description: "Also show N lines before and after the window"},
+		},
+		Notes: "Times may be `15:04:05` (today), RFC3339, or relative to now (`30m ago`, `2h ago`, `1d ago`). " +
+			"`--after` defaults to the start of the log and `--before` to now. " +
+			"Captured agent output is stamped line by line as it is written (not in repos keeping raw logs); " +
+			"lines without a timestamp of their own belong to the nearest timestamped line above them. " +
+			"Each line is prefixed with `[+Ns]`, the seconds elapsed since the start of the window.",
+		Run: c.diffLogs,
+	}
+
+	logsCmd.Subcommands["correlate"] = &Command{
+		Name:        "correlate",
+		Description: "Interleave two agents' logs by timestamp",
+		Usage:       "multiclaude logs correlate <agent-a> <agent-b> [--repo <repo>] [--since <duration>] [--highlight-messages]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "since", Type: "duration", Description: "Show only lines from this long ago onwards"},
+			{Name: "highlight-messages", Type: "bool", Description: "Mark lines mentioning a message the two agents exchanged"},
+		},
+		Notes: "Each line is prefixed with `[<agent>]`, in a color of its own. " +
+			"Lines without a timestamp of their own take the one of the line above them, so they stay with it. " +
+			"`--highlight-messages` marks lines containing the ID of a message either agent sent the other with ✉, followed by who sent it to whom.",
+		Run: c.correlateLogs,
+	}
+
+	logsCmd.Subcommands["format"] = &Command{
+		Name:        "format",
+		Description: "Print an agent's log through a template, without escape codes",
+		Usage:       "multiclaude logs format <agent-name> [--repo <repo>] [--template '{{.Time}} {{.Level}} {{.Message}}'] [--strip-ansi]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "template", Type: "string", Default: "{{.Time}} {{.Level}} {{.Message}}", Description: "Go text/template for each line"},
+			{Name: "strip-ansi", Type: "bool", Description: "Only remove escape codes"},
+		},
+		Notes: "Terminal escape codes are removed, and each line starting with a timestamp is split into `.Time`, `.Level` (e.g. `INFO`, empty if none), " +
+			"`.Message` (the rest) and `.RawText` (the line as logged) for the Go text/template given with `--template`. " +
+			"`.Time` prints as `2006-01-02 15:04:05` and takes time.Time's methods, e.g. `{{.Time.Format \"15:04\"}}`. " +
+			"The default template is `" + defaultLogTemplate + "`. Lines without a timestamp are printed unchanged apart from the escape codes. " +
+			"`--strip-ansi` only removes the escape codes.",
+		Run: c.formatLogs,
+	}
+
+	logsCmd.Subcommands["stream"] = &Command{
+		Name:        "stream",
+		Description: "Stream new log lines to WebSocket clients",
+		Usage:       "multiclaude logs stream [--port 7890] [--agent <name>] [--repo <repo>] [--exit-on-empty]",
+		Flags: []FlagSpec{
+			{Name: "port", Type: "int", Default: "7890", Description: "Local port to serve on"},
+			{Name: "agent", Type: "string", Description: "Stream only this agent's log"},
+			repoFlag,
+			{Name: "exit-on-empty", Type: "bool", Description: "Stop when the last client disconnects"},
+		},
+		Notes: "Serves `ws://127.0.0.1:<port>/ws` on the local machine only, and refuses browser pages not served from localhost. Each new log line is sent as a JSON text frame " +
+			"`{\"agent\", \"repo\", \"line\", \"timestamp\"}`, where `timestamp` is when the line was read. " +
+			"Only lines written after the stream starts are sent, to every connected client. Secrets from env files are redacted. " +
+			"The stream runs until interrupted, or with `--exit-on-empty` until the last client disconnects.",
+		Run: c.streamLogs,
 	}
 
 	c.rootCmd.Subcommands["logs"] = logsCmd
@@ -528,27 +1766,84 @@ func (c *CLI) registerCommands() {
 	c.rootCmd.Subcommands["config"] = &Command{
 		Name:        "config",
 		Description: "View or modify repository configuration",
-		Usage:       "multiclaude config [repo] [--mq-enabled=true|false] [--mq-track=all|author|assigned]",
-		Run:         c.configRepo,
+		Usage:       "multiclaude config [repo] [<key>=<value>...] [--unset <key>[,<key>...]] [--show-env] [--apply-git-config]",
+		Flags: append([]FlagSpec{
+			{Name: "unset", Type: "string", Description: "Revert keys, comma-separated, to their defaults"},
+			{Name: "show-env", Type: "bool", Description: "List the names of the variables set for agents"},
+			{Name: "apply-git-config", Type: "bool", Description: "Apply the git identity settings to existing worktrees"},
+		}, configFlags()...),
+		Notes: "Without settings, lists every key with its value and whether that is the default. " +
+			"`<key>=<value>` sets a key (an empty value reverts it), and each key can also be given as a flag, e.g. `--mq-enabled=false`; values are checked against the key's type before anything changes. " +
+			"Durations take Go units or days (`7d`), and message sizes take KB or MB. " +
+			"`pin-claude-path` starts the repository's agents with that claude binary only: if it goes missing they are not started (or restarted) with any other. " +
+			"The git identity keys set the committer and commit signing in each new agent worktree; `signing-key` takes a GPG key ID, or an SSH key file, which implies `signing-format=ssh`. " +
+			"Worktrees created before a change keep their settings until `--apply-git-config` updates them, and `multiclaude repo health` checks that the signing key can sign.",
+		Run: c.configRepo,
 	}
 
 	// Bug report command
 	c.rootCmd.Subcommands["bug"] = &Command{
 		Name:        "bug",
 		Description: "Generate a diagnostic bug report",
-		Usage:       "multiclaude bug [--output <file>] [--verbose] [description]",
-		Run:         c.bugReport,
+		Usage:       "multiclaude bug [--output <file>] [--verbose] [--include-backups] [--format markdown|json] [--pretty] [description]",
+		Flags: []FlagSpec{
+			{Name: "output", Type: "path", Description: "Write the report to a file instead of stdout"},
+			{Name: "verbose", Shorthand: "v", Type: "bool", Description: "Include more detail"},
+			{Name: "include-backups", Type: "bool", Description: "List state backups, not their content"},
+			{Name: "format", Type: "string", Default: "markdown", Description: "markdown or json"},
+			{Name: "pretty", Type: "bool", Description: "Indent the JSON"},
+		},
+		Run: c.bugReport,
+	}
+
+	c.rootCmd.Subcommands["audit"] = &Command{
+		Name:        "audit",
+		Description: "Show the audit log of mutating operations",
+		Usage:       "multiclaude audit [--since <duration>] [--command <name>]",
+		Flags: []FlagSpec{
+			{Name: "since", Type: "duration", Description: "Show only entries from this long ago onwards"},
+			{Name: "command", Type: "string", Description: "Show only this command's entries"},
+		},
+		Run: c.showAudit,
+	}
+
+	hooksCmd := &Command{
+		Name:        "hooks",
+		Description: "Work with lifecycle hooks",
+		Subcommands: make(map[string]*Command),
 	}
+	hooksCmd.Subcommands["test"] = &Command{
+		Name:        "test",
+		Description: "Fire a synthetic lifecycle event and show what its hooks do",
+		Usage:       "multiclaude hooks test <worker_created|worker_completed|agent_removed|repo_initialized> [--repo <repo>] [--agent <name>] [--task <task>]",
+		Flags: []FlagSpec{
+			repoFlag,
+			{Name: "agent", Type: "string", Default: "test-worker", Description: "Agent name in the event"},
+			{Name: "task", Type: "string", Description: "Task in the event"},
+		},
+		Notes: "Lifecycle hooks are commands the daemon runs on the host when an event happens, configured in `.multiclaude/lifecycle-hooks.json` " +
+			"in the repository and in `lifecycle-hooks.json` in the multiclaude config directory for every repository, e.g. " +
+			"`{\"timeout\": \"30s\", \"hooks\": {\"worker_completed\": [\"./scripts/notify.sh\"]}}`. " +
+			"Each runs with `sh -c` in the directory of its file, gets the event as JSON on stdin and as `MULTICLAUDE_*` environment variables, " +
+			"and is stopped at the timeout (default 30s). The daemon appends hook output to its log, and a failing hook never blocks the operation. " +
+			"`hooks test` runs the hooks in the foreground with a synthetic event (`MULTICLAUDE_SYNTHETIC=1`) and prints their output. " +
+			"Hooks are skipped in `MULTICLAUDE_TEST_MODE` unless `MULTICLAUDE_LIFECYCLE_HOOKS=1`.",
+		Run: c.testLifecycleHook,
+	}
+	c.rootCmd.Subcommands["hooks"] = hooksCmd
 }
 
 // Daemon command implementations
 
 func (c *CLI) startDaemon(args []string) error {
-	return daemon.RunDetached()
+	watchFS, _ := extractWatchFSFlag(args)
+	return daemon.RunDetached(daemon.Options{WatchFS: watchFS})
 }
 
 func (c *CLI) runDaemon(args []string) error {
-	return daemon.Run()
+	daemon.Version = Version
+	watchFS, _ := extractWatchFSFlag(args)
+	return daemon.Run(daemon.Options{WatchFS: watchFS, DocsHash: c.docsHashFor})
 }
 
 func (c *CLI) stopDaemon(args []string) error {
@@ -569,6 +1864,11 @@ func (c *CLI) stopDaemon(args []string) error {
 }
 
 func (c *CLI) daemonStatus(args []string) error {
+	verbose, args := extractVerboseFlag(args)
+	if flags, _ := ParseFlags(args); flags["quiet"] == "true" {
+		return c.daemonRunning(verbose)
+	}
+
 	// Check PID file first
 	pidFile := daemon.NewPIDFile(c.paths.DaemonPID)
 	running, pid, err := pidFile.IsRunning()
@@ -603,6 +1903,130 @@ func (c *CLI) daemonStatus(args []string) error {
 		fmt.Printf("  Repos: %v\n", statusMap["repos"])
 		fmt.Printf("  Agents: %v\n", statusMap["agents"])
 		fmt.Printf("  Socket: %v\n", statusMap["socket_path"])
+		if watchFS, _ := statusMap["watch_fs"].(bool); watchFS {
+			fmt.Println("  Watching worktrees: yes (--watch-fs)")
+		}
+		if transports, ok := statusMap["transports"].([]interface{}); ok {
+			names := make([]string, 0, len(transports))
+			for _, t := range transports {
+				names = append(names, fmt.Sprint(t))
+			}
+			fmt.Printf("  Transports: %s\n", strings.Join(names, ", "))
+		}
+		if repoTransports, ok := statusMap["message_transports"].(map[string]interface{}); ok && len(repoTransports) > 0 {
+			fmt.Println("  Message delivery:")
+			repoNames := make([]string, 0, len(repoTransports))
+			for name := range repoTransports {
+				repoNames = append(repoNames, name)
+			}
+			sort.Strings(repoNames)
+			for _, name := range repoNames {
+				cfg, _ := repoTransports[name].(map[string]interface{})
+				var overrides []string
+				for agentType, transport := range cfg {
+					if agentType != "default" {
+						overrides = append(overrides, fmt.Sprintf("%s=%v", agentType, transport))
+					}
+				}
+				sort.Strings(overrides)
+				line := fmt.Sprintf("%v", cfg["default"])
+				if len(overrides) > 0 {
+					line += fmt.Sprintf(" (%s)", strings.Join(overrides, ", "))
+				}
+				fmt.Printf("    %s: %s\n", name, line)
+			}
+		}
+		if stats, ok := statusMap["messages"].(map[string]interface{}); ok && len(stats) > 0 {
+			fmt.Println("  Messages:")
+			repoNames := make([]string, 0, len(stats))
+			for name := range stats {
+				repoNames = append(repoNames, name)
+			}
+			sort.Strings(repoNames)
+			for _, name := range repoNames {
+				summary, _ := stats[name].(map[string]interface{})
+				fmt.Printf("    %s: %s\n", name, formatMessageSummary(summary))
+			}
+		}
+		if binary, ok := statusMap["claude_binary"].(map[string]interface{}); ok {
+			fmt.Printf("  Claude: %s\n", describeClaudeBinary(binary))
+		}
+		if change := claudeBinaryChange(statusMap); change != "" {
+			fmt.Printf("  Warning: the claude binary changed since the daemon started: %s\n", change)
+			fmt.Println("    Agents (re)started since then run the new version while the others keep the old one.")
+			fmt.Printf("    Run: %s\n", restartDaemonCommand)
+		}
+		c.printUpgradeStatus(statusMap)
+		if quarantined, ok := statusMap["quarantined"].(map[string]interface{}); ok && len(quarantined) > 0 {
+			names := make([]string, 0, len(quarantined))
+			for name := range quarantined {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				info, _ := quarantined[name].(map[string]interface{})
+				fmt.Printf("  Warning: agent %s is quarantined since %v: it kept crashing and is no longer restarted\n", name, info["since"])
+				repoName, agentName, _ := strings.Cut(name, "/")
+				fmt.Printf("    Fix the cause, then run: multiclaude agent restart %s --repo %s --clear-quarantine\n", agentName, repoName)
+			}
+		}
+		if drift, ok := statusMap["prompt_drift"].([]interface{}); ok && len(drift) > 0 {
+			names := make([]string, len(drift))
+			for i, name := range drift {
+				names[i] = fmt.Sprint(name)
+			}
+			fmt.Printf("  Warning: %d agent(s) run on a prompt that has changed on disk since they started: %s\n", len(names), strings.Join(names, ", "))
+			fmt.Println("    Run: multiclaude agent restart <name> --force --repo <repo>")
+		}
+		if stale, ok := statusMap["stale_docs"].([]interface{}); ok && len(stale) > 0 {
+			names := make([]string, len(stale))
+			for i, name := range stale {
+				names[i] = fmt.Sprint(name)
+			}
+			fmt.Printf("  Warning: %d agent(s) run with a CLI reference from another multiclaude version: %s\n", len(names), strings.Join(names, ", "))
+			fmt.Println("    Run: multiclaude agent restart <name> --refresh-docs --repo <repo>")
+		}
+		if loops, ok := statusMap["loops"].([]interface{}); ok {
+			for _, l := range loops {
+				loop, _ := l.(map[string]interface{})
+				if lastErr, _ := loop["last_error"].(string); lastErr != "" {
+					fmt.Printf("  Warning: the daemon's %v loop failed on its last run (%v): %s\n", loop["name"], loop["last_error_at"], lastErr)
+				}
+				if panics, _ := loop["panics"].(float64); panics > 0 {
+					fmt.Printf("  Warning: the daemon's %v loop has panicked %d time(s); see the daemon log\n", loop["name"], int(panics))
+				}
+			}
+		}
+		printSessionWarnings(statusMap["sessions"])
+		if moved, ok := statusMap["moved_repos"].(map[string]interface{}); ok && len(moved) > 0 {
+			repoNames := make([]string, 0, len(moved))
+			for name := range moved {
+				repoNames = append(repoNames, name)
+			}
+			sort.Strings(repoNames)
+			for _, name := range repoNames {
+				fmt.Printf("  Warning: repository '%s' has moved on GitHub to %v\n", name, moved[name])
+				fmt.Printf("    Run: multiclaude repo set-url %s %v\n", name, moved[name])
+			}
+		}
+		if unavailable, ok := statusMap["gh_unavailable"].(map[string]interface{}); ok && len(unavailable) > 0 {
+			repoNames := make([]string, 0, len(unavailable))
+			for name := range unavailable {
+				repoNames = append(repoNames, name)
+			}
+			sort.Strings(repoNames)
+			for _, name := range repoNames {
+				status, _ := unavailable[name].(map[string]interface{})
+				var features []string
+				if list, ok := status["features"].([]interface{}); ok {
+					for _, f := range list {
+						features = append(features, fmt.Sprint(f))
+					}
+				}
+				fmt.Printf("  gh: unavailable (%v) for %s; turned off: %s\n", status["reason"], name, strings.Join(features, ", "))
+			}
+			fmt.Printf("    Install gh (https://cli.github.com) or run 'gh auth login', then: %s\n", restartDaemonCommand)
+		}
 	} else {
 		// Fallback: print as JSON
 		jsonData, _ := json.MarshalIndent(resp.Data, "  ", "  ")
@@ -639,9 +2063,9 @@ func (c *CLI) daemonLogs(args []string) error {
 }
 
 func (c *CLI) stopAll(args []string) error {
+	assumeYes, args := extractYesFlag(args)
 	flags, _ := ParseFlags(args)
 	clean := flags["clean"] == "true"
-	skipConfirm := flags["yes"] == "true"
 
 	// Get list of repos (try daemon first, then state file)
 	var repos []string
@@ -667,71 +2091,59 @@ func (c *CLI) stopAll(args []string) error {
 	// If --clean is specified, require confirmation
 	if clean {
 		fmt.Println("WARNING: This will permanently delete:")
-		fmt.Println("  - All worktrees (~/.multiclaude/wts/)")
+		fmt.Printf("  - All worktrees (%s)\n", c.paths.WorktreesDir)
 		fmt.Println("  - All agent state (state.json agents section)")
-		fmt.Println("  - All message queues (~/.multiclaude/messages/)")
-		fmt.Println("  - All output logs (~/.multiclaude/output/)")
-		fmt.Println("  - All agent configs (~/.multiclaude/claude-config/)")
-		fmt.Println("  - All prompts (~/.multiclaude/prompts/)")
+		fmt.Printf("  - All message queues (%s)\n", c.paths.MessagesDir)
+		fmt.Printf("  - All output logs (%s)\n", c.paths.OutputDir)
+		fmt.Printf("  - All agent configs (%s)\n", c.paths.ClaudeConfigDir)
+		fmt.Printf("  - All prompts (%s)\n", filepath.Join(c.paths.Root, "prompts"))
 		fmt.Println("  - Local branches (work/*, multiclaude/*)")
 		fmt.Println()
 		fmt.Println("The following will be PRESERVED:")
-		fmt.Println("  - Cloned repositories (~/.multiclaude/repos/)")
+		fmt.Printf("  - Cloned repositories (%s)\n", c.paths.ReposDir)
 		fmt.Println("  - Git credentials")
+		fmt.Printf("  - Audit log (%s)\n", c.paths.AuditLog())
 		fmt.Println()
 
-		if !skipConfirm {
-			fmt.Print("Type 'NUKE' to confirm: ")
-			reader := bufio.NewReader(os.Stdin)
-			input, err := reader.ReadString('\n')
-			if err != nil {
-				return fmt.Errorf("failed to read input: %w", err)
-			}
-			input = strings.TrimSpace(input)
-			if input != "NUKE" {
-				fmt.Println("Aborted.")
-				return nil
-			}
-			fmt.Println()
+		ok, err := confirm(confirmation{
+			Prompt:    "Type 'NUKE' to confirm:",
+			Phrase:    "NUKE",
+			AssumeYes: assumeYes,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Aborted.")
+			return nil
 		}
+		fmt.Println()
 	}
 
 	fmt.Println("Stopping all multiclaude sessions...")
 
-	// Kill all multiclaude tmux sessions
+	// Kill this root's tmux sessions, leaving those of other multiclaude
+	// roots sharing the tmux server alone
 	tmuxClient := tmux.NewClient()
 	if tmuxClient.IsTmuxAvailable() {
-		for _, repo := range repos {
-			sessionName := fmt.Sprintf("mc-%s", repo)
-			exists, err := tmuxClient.HasSession(context.Background(), sessionName)
-			if err == nil && exists {
-				fmt.Printf("Killing tmux session: %s\n", sessionName)
-				if err := tmuxClient.KillSession(context.Background(), sessionName); err != nil {
-					fmt.Printf("Warning: failed to kill session %s: %v\n", sessionName, err)
+		st, _ := state.Load(c.paths.StateFile)
+		if survey, err := c.surveySessions(tmuxClient, trackedSessions(repos, st)); err == nil {
+			for _, session := range survey.Own {
+				fmt.Printf("Killing tmux session: %s\n", session)
+				if err := tmuxClient.KillSession(context.Background(), session); err != nil {
+					fmt.Printf("Warning: failed to kill session %s: %v\n", session, err)
 				}
 			}
-		}
-
-		// Also check for any mc-* sessions we might have missed
-		sessions, err := tmuxClient.ListSessions(context.Background())
-		if err == nil {
-			for _, session := range sessions {
-				if strings.HasPrefix(session, "mc-") {
-					exists := false
-					for _, repo := range repos {
-						if fmt.Sprintf("mc-%s", repo) == session {
-							exists = true
-							break
-						}
-					}
-					if !exists {
-						fmt.Printf("Killing orphaned tmux session: %s\n", session)
-						if err := tmuxClient.KillSession(context.Background(), session); err != nil {
-							fmt.Printf("Warning: failed to kill session %s: %v\n", session, err)
-						}
-					}
+			for _, session := range survey.Orphaned {
+				fmt.Printf("Killing orphaned tmux session: %s\n", session)
+				if err := tmuxClient.KillSession(context.Background(), session); err != nil {
+					fmt.Printf("Warning: failed to kill session %s: %v\n", session, err)
 				}
 			}
+			for _, session := range survey.Unknown {
+				fmt.Printf("Leaving untagged tmux session %s: it may belong to another multiclaude root (multiclaude cleanup --aggressive kills it)\n", session)
+			}
+			printForeignSessions(survey)
 		}
 	}
 
@@ -745,6 +2157,7 @@ func (c *CLI) stopAll(args []string) error {
 	}
 
 	// Full cleanup if --clean is specified
+	var removedPaths []string
 	if clean {
 		// Remove worktrees directory
 		fmt.Println("\nRemoving worktrees...")
@@ -753,6 +2166,7 @@ func (c *CLI) stopAll(args []string) error {
 				fmt.Printf("  Warning: failed to remove worktrees: %v\n", err)
 			} else {
 				fmt.Printf("  Removed %s\n", c.paths.WorktreesDir)
+				removedPaths = append(removedPaths, c.paths.WorktreesDir)
 			}
 		}
 
@@ -763,6 +2177,7 @@ func (c *CLI) stopAll(args []string) error {
 				fmt.Printf("  Warning: failed to remove messages: %v\n", err)
 			} else {
 				fmt.Printf("  Removed %s\n", c.paths.MessagesDir)
+				removedPaths = append(removedPaths, c.paths.MessagesDir)
 			}
 		}
 
@@ -773,6 +2188,7 @@ func (c *CLI) stopAll(args []string) error {
 				fmt.Printf("  Warning: failed to remove output logs: %v\n", err)
 			} else {
 				fmt.Printf("  Removed %s\n", c.paths.OutputDir)
+				removedPaths = append(removedPaths, c.paths.OutputDir)
 			}
 		}
 
@@ -783,6 +2199,7 @@ func (c *CLI) stopAll(args []string) error {
 				fmt.Printf("  Warning: failed to remove agent configs: %v\n", err)
 			} else {
 				fmt.Printf("  Removed %s\n", c.paths.ClaudeConfigDir)
+				removedPaths = append(removedPaths, c.paths.ClaudeConfigDir)
 			}
 		}
 
@@ -794,6 +2211,7 @@ func (c *CLI) stopAll(args []string) error {
 				fmt.Printf("  Warning: failed to remove prompts: %v\n", err)
 			} else {
 				fmt.Printf("  Removed %s\n", promptsDir)
+				removedPaths = append(removedPaths, promptsDir)
 			}
 		}
 
@@ -860,65 +2278,235 @@ func (c *CLI) stopAll(args []string) error {
 		fmt.Println("\n✓ All multiclaude sessions stopped")
 	}
 
+	c.auditLocal("stop_all", map[string]interface{}{
+		"clean":   clean,
+		"repos":   repos,
+		"removed": removedPaths,
+	}, nil)
+
 	return nil
 }
 
+// initOptions are the settings for initializing a repository, whether they
+// come from flags or from init --wizard
+type initOptions struct {
+	GithubURL     string
+	RepoName      string
+	MQConfig      state.MergeQueueConfig
+	WorktreeLimit int
+	// NoWorkspace skips creating the default workspace
+	NoWorkspace bool
+	// SeedPrompts writes template prompt override files into the clone's
+	// .multiclaude directory; CommitPrompts also commits them
+	SeedPrompts   bool
+	CommitPrompts bool
+	// TemplateURL is a repository whose .multiclaude directory is copied
+	// into the clone; NoTemplatePrompts leaves out its prompt overrides
+	TemplateURL       string
+	NoTemplatePrompts bool
+	// Local means GithubURL is the path of a local git repository (init
+	// --local), e.g. a scratch repository with no GitHub remote
+	Local bool
+	// NoSupervisor skips the supervisor agent, for operators who manage
+	// workers themselves
+	NoSupervisor bool
+	// Quiet prints nothing but errors
+	Quiet bool
+}
+
 func (c *CLI) initRepo(args []string) error {
 	flags, posArgs := ParseFlags(args)
 
+	// ParseFlags takes a URL following --no-template-prompts as its value
+	noTemplatePrompts, hasNoTemplatePrompts := flags["no-template-prompts"]
+	if hasNoTemplatePrompts && noTemplatePrompts != "true" {
+		posArgs = append([]string{noTemplatePrompts}, posArgs...)
+	}
+	// Likewise for --no-supervisor
+	noSupervisorValue, noSupervisor := flags["no-supervisor"]
+	if noSupervisor && noSupervisorValue != "true" {
+		posArgs = append([]string{noSupervisorValue}, posArgs...)
+	}
+	quietValue, quiet := flags["quiet"]
+	if quiet && quietValue != "true" {
+		posArgs = append([]string{quietValue}, posArgs...)
+	}
+	if _, ok := flags["import-from-existing"]; ok {
+		return c.importExistingRepo(flags, posArgs)
+	}
+	templateURL := strings.TrimRight(flags["template"], "/")
+	if _, ok := flags["template"]; ok && (templateURL == "" || templateURL == "true") {
+		return errors.MissingArgument("--template", "url")
+	}
+	if hasNoTemplatePrompts && templateURL == "" {
+		return errors.InvalidUsage("--no-template-prompts requires --template <url>")
+	}
+
+	// --local initializes a local git repository instead of cloning from
+	// GitHub. Without GitHub there are no PRs to merge, so the merge queue
+	// is off.
+	localPath, isLocal := flags["local"]
+	if isLocal {
+		if localPath == "" || localPath == "true" {
+			return errors.MissingArgument("--local", "path")
+		}
+		if _, ok := flags["wizard"]; ok {
+			return errors.InvalidUsage("--local cannot be combined with --wizard")
+		}
+		absPath, err := filepath.Abs(localPath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", localPath, err)
+		}
+		if err := exec.Command("git", "-C", absPath, "rev-parse", "--git-dir").Run(); err != nil {
+			return errors.InvalidUsage(fmt.Sprintf("--local %s is not a git repository", localPath))
+		}
+		posArgs = append([]string{absPath}, posArgs...)
+	}
+
+	if wizard, ok := flags["wizard"]; ok {
+		// ParseFlags takes a URL following --wizard as the flag's value
+		if wizard != "true" {
+			posArgs = append([]string{wizard}, posArgs...)
+		}
+		defaultURL := ""
+		if len(posArgs) > 0 {
+			defaultURL = posArgs[0]
+		}
+		opts, err := c.initWizard(defaultURL)
+		if err != nil {
+			return err
+		}
+		opts.TemplateURL = templateURL
+		opts.NoTemplatePrompts = hasNoTemplatePrompts
+		opts.NoSupervisor = noSupervisor
+		opts.Quiet = quiet
+		return c.runInit(opts)
+	}
+
 	if len(posArgs) < 1 {
-		return errors.InvalidUsage("usage: multiclaude init <github-url> [name] [--no-merge-queue] [--mq-track=all|author|assigned]")
+		return errors.InvalidUsage("usage: multiclaude init <github-url> [name] [--no-merge-queue] [--mq-track=all|author|assigned] [--worktree-limit <n>] [--no-workspace] [--no-supervisor] [--template <url> [--no-template-prompts]] [--quiet] | --local <path> [name] | --wizard | --import-from-existing --repo-path <path> --tmux-session <session> [--name <name>]")
 	}
 
-	githubURL := strings.TrimRight(posArgs[0], "/")
+	opts := initOptions{
+		GithubURL:         strings.TrimRight(posArgs[0], "/"),
+		NoWorkspace:       flags["no-workspace"] == "true",
+		TemplateURL:       templateURL,
+		NoTemplatePrompts: hasNoTemplatePrompts,
+		Local:             isLocal,
+		NoSupervisor:      noSupervisor,
+		Quiet:             quiet,
+	}
 
 	// Parse repository name from URL if not provided
-	var repoName string
 	if len(posArgs) >= 2 {
-		repoName = posArgs[1]
+		opts.RepoName = posArgs[1]
+	} else if isLocal {
+		opts.RepoName = filepath.Base(opts.GithubURL)
 	} else {
-		// Extract repo name from URL (e.g., github.com/user/repo -> repo)
-		// A valid GitHub URL has format: https://github.com/owner/repo
-		// When split by "/": ["https:", "", "github.com", "owner", "repo"] - 5+ parts
-		parts := strings.Split(githubURL, "/")
-		if len(parts) < 5 {
-			return errors.InvalidUsage("could not determine repository name from URL; please provide a name: multiclaude init <url> <name>")
+		repoName, err := repoNameFromURL(opts.GithubURL)
+		if err != nil {
+			return err
 		}
-		repoName = strings.TrimSuffix(parts[len(parts)-1], ".git")
+		opts.RepoName = repoName
 	}
 
 	// Validate repository name before any operations
-	if repoName == "" {
+	if opts.RepoName == "" {
 		return errors.InvalidUsage("could not determine repository name from URL; please provide a name: multiclaude init <url> <name>")
 	}
 
 	// Parse merge queue configuration flags
-	mqEnabled := flags["no-merge-queue"] != "true"
-	mqTrackMode := state.TrackModeAll
+	opts.MQConfig = state.MergeQueueConfig{
+		Enabled:   flags["no-merge-queue"] != "true" && !isLocal,
+		TrackMode: state.TrackModeAll,
+	}
 	if trackMode, ok := flags["mq-track"]; ok {
-		switch trackMode {
-		case "all":
-			mqTrackMode = state.TrackModeAll
-		case "author":
-			mqTrackMode = state.TrackModeAuthor
-		case "assigned":
-			mqTrackMode = state.TrackModeAssigned
-		default:
-			return fmt.Errorf("invalid --mq-track value: %s (must be 'all', 'author', or 'assigned')", trackMode)
+		mode, err := parseTrackMode(trackMode)
+		if err != nil {
+			return err
 		}
+		opts.MQConfig.TrackMode = mode
 	}
 
-	mqConfig := state.MergeQueueConfig{
-		Enabled:   mqEnabled,
-		TrackMode: mqTrackMode,
+	if value, ok := flags["worktree-limit"]; ok {
+		limit, err := parseWorktreeLimit(value)
+		if err != nil {
+			return err
+		}
+		opts.WorktreeLimit = limit
 	}
 
-	fmt.Printf("Initializing repository: %s\n", repoName)
-	fmt.Printf("GitHub URL: %s\n", githubURL)
-	if mqEnabled {
-		fmt.Printf("Merge queue: enabled (tracking: %s)\n", mqTrackMode)
-	} else {
-		fmt.Printf("Merge queue: disabled\n")
+	if opts.NoSupervisor && !opts.MQConfig.Enabled && opts.NoWorkspace {
+		return errors.InvalidUsage("--no-supervisor with the merge queue off and --no-workspace leaves the repository with no agents")
+	}
+
+	return c.runInit(opts)
+}
+
+// repoNameFromURL derives a repository name from its GitHub URL
+// (e.g., github.com/user/repo -> repo)
+func repoNameFromURL(githubURL string) (string, error) {
+	// A valid GitHub URL has format: https://github.com/owner/repo
+	// When split by "/": ["https:", "", "github.com", "owner", "repo"] - 5+ parts
+	parts := strings.Split(strings.TrimRight(githubURL, "/"), "/")
+	if len(parts) < 5 {
+		return "", errors.InvalidUsage("could not determine repository name from URL; please provide a name: multiclaude init <url> <name>")
+	}
+	name := strings.TrimSuffix(parts[len(parts)-1], ".git")
+	if name == "" {
+		return "", errors.InvalidUsage("could not determine repository name from URL; please provide a name: multiclaude init <url> <name>")
+	}
+	return name, nil
+}
+
+// parseTrackMode parses an --mq-track value
+func parseTrackMode(trackMode string) (state.TrackMode, error) {
+	switch trackMode {
+	case "all":
+		return state.TrackModeAll, nil
+	case "author":
+		return state.TrackModeAuthor, nil
+	case "assigned":
+		return state.TrackModeAssigned, nil
+	default:
+		return "", fmt.Errorf("invalid --mq-track value: %s (must be 'all', 'author', or 'assigned')", trackMode)
+	}
+}
+
+// runInit clones a repository, starts its agents and registers it with the
+// daemon, in named stages run by a stageRunner. Stages that do not depend on
+// each other run concurrently: the supervisor, merge-queue and default
+// workspace start together once their prompt files are written.
+func (c *CLI) runInit(opts initOptions) error {
+	githubURL := opts.GithubURL
+	repoName := opts.RepoName
+	mqConfig := opts.MQConfig
+	mqEnabled := mqConfig.Enabled
+	mqTrackMode := mqConfig.TrackMode
+	worktreeLimit := opts.WorktreeLimit
+
+	if !opts.Quiet {
+		fmt.Printf("Initializing repository: %s\n", repoName)
+		if opts.Local {
+			fmt.Printf("Local repository: %s\n", githubURL)
+		} else {
+			fmt.Printf("GitHub URL: %s\n", githubURL)
+		}
+		if mqEnabled {
+			fmt.Printf("Merge queue: enabled (tracking: %s)\n", mqTrackMode)
+		} else {
+			fmt.Printf("Merge queue: disabled\n")
+		}
+		if worktreeLimit > 0 {
+			fmt.Printf("Worktree limit: %d\n", worktreeLimit)
+		}
+		if opts.NoSupervisor {
+			fmt.Printf("Supervisor: none (workers are managed by you)\n")
+		}
+		if opts.TemplateURL != "" {
+			fmt.Printf("Template: %s\n", opts.TemplateURL)
+		}
+		fmt.Println()
 	}
 
 	// Check if daemon is running
@@ -928,169 +2516,289 @@ func (c *CLI) initRepo(args []string) error {
 		return errors.DaemonNotRunning()
 	}
 
-	// Clone repository
 	repoPath := c.paths.RepoDir(repoName)
-	fmt.Printf("Cloning to: %s\n", repoPath)
-
-	cmd := exec.Command("git", "clone", githubURL, repoPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return errors.GitOperationFailed("clone", err)
-	}
-
-	// Create tmux session
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	tmuxSession := c.repoTmuxSession(repoName)
 	if tmuxSession == "mc-" {
 		return fmt.Errorf("invalid tmux session name: repository name cannot be empty")
 	}
-
-	fmt.Printf("Creating tmux session: %s\n", tmuxSession)
-
-	// Create session with supervisor window
-	cmd = exec.Command("tmux", "new-session", "-d", "-s", tmuxSession, "-n", "supervisor", "-c", repoPath)
-	if err := cmd.Run(); err != nil {
-		return errors.TmuxOperationFailed("create session", err)
+	// The supervisor's window creates the session, or else the
+	// merge-queue's. Without either the default workspace's window does.
+	sessionWindows := []string{}
+	if !opts.NoSupervisor {
+		sessionWindows = append(sessionWindows, "supervisor")
 	}
-
-	// Create merge-queue window only if enabled
 	if mqEnabled {
-		cmd = exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", "merge-queue", "-c", repoPath)
-		if err := cmd.Run(); err != nil {
-			return errors.TmuxOperationFailed("create merge-queue window", err)
-		}
+		sessionWindows = append(sessionWindows, "merge-queue")
+	}
+
+	var (
+		templateDir     string
+		cleanupTemplate func()
+		// Prompt files by agent name, written by the prompts stage
+		promptFiles = make(map[string]string)
+		stages      []stage
+	)
+	defer func() {
+		if cleanupTemplate != nil {
+			cleanupTemplate()
+		}
+	}()
+
+	// Fetch the template before the full clone, so a bad template fails fast
+	var cloneNeeds []string
+	if opts.TemplateURL != "" {
+		stages = append(stages, stage{Name: "template", Run: func(out io.Writer) error {
+			fmt.Fprintf(out, "Checking template repository %s\n", opts.TemplateURL)
+			if err := checkTemplateURL(opts.TemplateURL); err != nil {
+				return err
+			}
+			dir, cleanup, err := fetchTemplate(opts.TemplateURL)
+			if err != nil {
+				return err
+			}
+			templateDir, cleanupTemplate = dir, cleanup
+			return nil
+		}})
+		cloneNeeds = []string{"template"}
 	}
 
-	// Generate session IDs for agents
-	supervisorSessionID, err := claude.GenerateSessionID()
-	if err != nil {
-		return fmt.Errorf("failed to generate supervisor session ID: %w", err)
+	stages = append(stages, stage{Name: "clone", Needs: cloneNeeds, Run: func(out io.Writer) error {
+		fmt.Fprintf(out, "Cloning to: %s\n", repoPath)
+		cmd := exec.Command("git", "clone", githubURL, repoPath)
+		cmd.Stdout = out
+		cmd.Stderr = out
+		if _, _, err := cmdrun.Run(cmd); err != nil {
+			return errors.GitOperationFailed("clone", err)
+		}
+
+		// Copy the template's configuration before any prompt or hook is
+		// set up, so the agents started later already see it
+		if templateDir != "" {
+			written, err := copyTemplateConfig(templateDir, repoPath, !opts.NoTemplatePrompts)
+			if err != nil {
+				return err
+			}
+			for _, path := range written {
+				fmt.Fprintf(out, "Copied from template: %s\n", path)
+			}
+			if len(written) > 0 {
+				fmt.Fprintln(out, "Template files are not committed; commit and push them to share them with every clone.")
+			}
+		}
+
+		// Seed prompt override templates before any prompt is written, so
+		// the agents started later already see them
+		if opts.SeedPrompts {
+			seedPromptOverrides(out, repoPath, opts.CommitPrompts)
+		}
+		return nil
+	}})
+
+	registrationNeeds := []string{"clone"}
+	if len(sessionWindows) > 0 {
+		stages = append(stages, stage{Name: "session", Needs: []string{"clone"}, Run: func(out io.Writer) error {
+			fmt.Fprintf(out, "Creating tmux session: %s\n", tmuxSession)
+			for i, window := range sessionWindows {
+				if err := c.newAgentWindow(tmuxSession, window, repoPath, i == 0); err != nil {
+					return errors.TmuxOperationFailed(fmt.Sprintf("create %s window", window), err)
+				}
+			}
+			return nil
+		}})
+		registrationNeeds = []string{"session"}
 	}
 
-	var mergeQueueSessionID string
-	if mqEnabled {
-		mergeQueueSessionID, err = claude.GenerateSessionID()
+	// Add repository to daemon state (with merge queue config) before any
+	// prompt is written, as prompts depend on whether it has a supervisor
+	stages = append(stages, stage{Name: "registration", Needs: registrationNeeds, Run: func(out io.Writer) error {
+		resp, err := client.Send(socket.Request{
+			Command: "add_repo",
+			Args: map[string]interface{}{
+				"name":           repoName,
+				"github_url":     githubURL,
+				"tmux_session":   tmuxSession,
+				"mq_enabled":     mqConfig.Enabled,
+				"mq_track_mode":  string(mqConfig.TrackMode),
+				"worktree_limit": worktreeLimit,
+				"no_supervisor":  opts.NoSupervisor,
+			},
+		})
 		if err != nil {
-			return fmt.Errorf("failed to generate merge-queue session ID: %w", err)
+			return fmt.Errorf("failed to register repository with daemon: %w", err)
+		}
+		if !resp.Success {
+			return fmt.Errorf("failed to register repository: %s", resp.Error)
+		}
+		fmt.Fprintf(out, "Registered %s with the daemon\n", repoName)
+		return nil
+	}})
+
+	stages = append(stages, stage{Name: "prompts", Needs: []string{"registration"}, Run: func(out io.Writer) error {
+		writers := map[string]func() (string, error){}
+		if !opts.NoSupervisor {
+			writers["supervisor"] = func() (string, error) {
+				return c.writePromptFile(repoPath, prompts.TypeSupervisor, "supervisor")
+			}
+		}
+		if mqEnabled {
+			writers["merge-queue"] = func() (string, error) {
+				return c.writeMergeQueuePromptFile(repoPath, "merge-queue", mqConfig)
+			}
+		}
+		if !opts.NoWorkspace {
+			writers["default"] = func() (string, error) {
+				return c.writePromptFile(repoPath, prompts.TypeWorkspace, "default")
+			}
+		}
+
+		// The prompt files are independent of each other
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		var errs []error
+		for name, write := range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				path, err := write()
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to write %s prompt: %w", name, err))
+					return
+				}
+				promptFiles[name] = path
+				fmt.Fprintf(out, "Wrote %s\n", path)
+			}()
+		}
+		wg.Wait()
+		if len(errs) > 0 {
+			return errs[0]
 		}
+
+		// Copy hooks configuration if it exists (for supervisor and merge-queue)
+		if err := hooks.CopyConfig(repoPath, repoPath); err != nil {
+			fmt.Fprintf(out, "Warning: failed to copy hooks config: %v\n", err)
+		}
+		return nil
+	}})
+
+	if !opts.NoSupervisor {
+		stages = append(stages, stage{Name: "supervisor", Needs: []string{"prompts"}, Run: func(out io.Writer) error {
+			return c.startInitAgent(out, repoName, tmuxSession, initAgent{
+				Name: "supervisor", Type: "supervisor", Dir: repoPath, PromptFile: promptFiles["supervisor"],
+			})
+		}})
+	}
+	if mqEnabled {
+		stages = append(stages, stage{Name: "merge-queue", Needs: []string{"prompts"}, Run: func(out io.Writer) error {
+			return c.startInitAgent(out, repoName, tmuxSession, initAgent{
+				Name: "merge-queue", Type: "merge-queue", Dir: repoPath, PromptFile: promptFiles["merge-queue"],
+			})
+		}})
+	}
+	if !opts.NoWorkspace {
+		stages = append(stages, stage{Name: "workspace", Needs: []string{"prompts"}, Run: func(out io.Writer) error {
+			return c.createDefaultWorkspace(out, repoName, repoPath, tmuxSession, len(sessionWindows) == 0, promptFiles["default"])
+		}})
 	}
 
-	// Write prompt files
-	supervisorPromptFile, err := c.writePromptFile(repoPath, prompts.TypeSupervisor, "supervisor")
-	if err != nil {
-		return fmt.Errorf("failed to write supervisor prompt: %w", err)
+	if err := newStageRunner(opts.Quiet).Run(stages); err != nil {
+		return err
+	}
+	if opts.Quiet {
+		return nil
 	}
 
-	var mergeQueuePromptFile string
+	fmt.Println()
+	fmt.Println("✓ Repository initialized successfully!")
+	fmt.Printf("  Tmux session: %s\n", tmuxSession)
+	var agents []string
+	if !opts.NoSupervisor {
+		agents = append(agents, "supervisor")
+	}
 	if mqEnabled {
-		mergeQueuePromptFile, err = c.writeMergeQueuePromptFile(repoPath, "merge-queue", mqConfig)
-		if err != nil {
-			return fmt.Errorf("failed to write merge-queue prompt: %w", err)
-		}
+		agents = append(agents, "merge-queue")
+	}
+	if !opts.NoWorkspace {
+		agents = append(agents, "default (workspace)")
+	}
+	fmt.Printf("  Agents: %s\n", strings.Join(agents, ", "))
+	fmt.Printf("\nAttach to session: tmux attach -t %s\n", tmuxSession)
+	if opts.NoWorkspace {
+		fmt.Printf("Add a workspace later: multiclaude workspace add <name>\n")
+	} else {
+		fmt.Printf("Or connect to your workspace: multiclaude workspace connect default\n")
+	}
+	if opts.NoSupervisor {
+		fmt.Printf("Add a supervisor later: multiclaude agent add-supervisor --repo %s\n", repoName)
 	}
 
-	// Copy hooks configuration if it exists (for supervisor and merge-queue)
-	if err := hooks.CopyConfig(repoPath, repoPath); err != nil {
-		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+	return nil
+}
+
+// initAgent is an agent init starts in its own tmux window
+type initAgent struct {
+	Name       string // Agent and tmux window name
+	Type       string
+	Dir        string // Where the agent works
+	PromptFile string
+}
+
+// startInitAgent starts Claude in an agent's window, which must exist, and
+// registers the agent with the daemon
+func (c *CLI) startInitAgent(out io.Writer, repoName, tmuxSession string, agent initAgent) error {
+	sessionID, err := claude.GenerateSessionID()
+	if err != nil {
+		return fmt.Errorf("failed to generate %s session ID: %w", agent.Name, err)
 	}
 
-	// Start Claude in supervisor window (skip in test mode)
-	var supervisorPID, mergeQueuePID int
+	// Start Claude in the agent's window (skip in test mode)
+	var pid int
 	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
-		// Resolve claude binary
 		claudeBinary, err := c.getClaudeBinary()
 		if err != nil {
 			return fmt.Errorf("failed to resolve claude binary: %w", err)
 		}
 
-		fmt.Println("Starting Claude Code in supervisor window...")
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, "supervisor", repoPath, supervisorSessionID, supervisorPromptFile, repoName, "")
+		fmt.Fprintf(out, "Starting Claude Code in %s window...\n", agent.Name)
+		pid, err = c.startClaudeInTmux(claudeBinary, tmuxSession, agent.Name, agent.Dir, sessionID, agent.PromptFile, repoName, "")
 		if err != nil {
-			return fmt.Errorf("failed to start supervisor Claude: %w", err)
-		}
-		supervisorPID = pid
-
-		// Set up output capture for supervisor
-		if err := c.setupOutputCapture(tmuxSession, "supervisor", repoName, "supervisor", "supervisor"); err != nil {
-			fmt.Printf("Warning: failed to setup output capture for supervisor: %v\n", err)
+			return fmt.Errorf("failed to start %s Claude: %w", agent.Name, err)
 		}
 
-		// Start Claude in merge-queue window only if enabled
-		if mqEnabled {
-			fmt.Println("Starting Claude Code in merge-queue window...")
-			pid, err = c.startClaudeInTmux(claudeBinary, tmuxSession, "merge-queue", repoPath, mergeQueueSessionID, mergeQueuePromptFile, repoName, "")
-			if err != nil {
-				return fmt.Errorf("failed to start merge-queue Claude: %w", err)
-			}
-			mergeQueuePID = pid
-
-			// Set up output capture for merge-queue
-			if err := c.setupOutputCapture(tmuxSession, "merge-queue", repoName, "merge-queue", "merge-queue"); err != nil {
-				fmt.Printf("Warning: failed to setup output capture for merge-queue: %v\n", err)
-			}
+		if err := c.setupOutputCapture(tmuxSession, agent.Name, repoName, agent.Name, agent.Type); err != nil {
+			fmt.Fprintf(out, "Warning: failed to setup output capture for %s: %v\n", agent.Name, err)
 		}
 	}
 
-	// Add repository to daemon state (with merge queue config)
-	resp, err := client.Send(socket.Request{
-		Command: "add_repo",
-		Args: map[string]interface{}{
-			"name":          repoName,
-			"github_url":    githubURL,
-			"tmux_session":  tmuxSession,
-			"mq_enabled":    mqConfig.Enabled,
-			"mq_track_mode": string(mqConfig.TrackMode),
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to register repository with daemon: %w", err)
-	}
-	if !resp.Success {
-		return fmt.Errorf("failed to register repository: %s", resp.Error)
-	}
-
-	// Add supervisor agent
-	resp, err = client.Send(socket.Request{
+	resp, err := socket.NewClient(c.paths.DaemonSock).Send(socket.Request{
 		Command: "add_agent",
 		Args: map[string]interface{}{
 			"repo":          repoName,
-			"agent":         "supervisor",
-			"type":          "supervisor",
-			"worktree_path": repoPath,
-			"tmux_window":   "supervisor",
-			"session_id":    supervisorSessionID,
-			"pid":           supervisorPID,
+			"agent":         agent.Name,
+			"type":          agent.Type,
+			"worktree_path": agent.Dir,
+			"tmux_window":   agent.Name,
+			"session_id":    sessionID,
+			"pid":           pid,
+			"docs_hash":     promptDocsHash(agent.PromptFile),
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to register supervisor: %w", err)
+		return fmt.Errorf("failed to register %s: %w", agent.Name, err)
 	}
 	if !resp.Success {
-		return fmt.Errorf("failed to register supervisor: %s", resp.Error)
-	}
-
-	// Add merge-queue agent only if enabled
-	if mqEnabled {
-		resp, err = client.Send(socket.Request{
-			Command: "add_agent",
-			Args: map[string]interface{}{
-				"repo":          repoName,
-				"agent":         "merge-queue",
-				"type":          "merge-queue",
-				"worktree_path": repoPath,
-				"tmux_window":   "merge-queue",
-				"session_id":    mergeQueueSessionID,
-				"pid":           mergeQueuePID,
-			},
-		})
-		if err != nil {
-			return fmt.Errorf("failed to register merge-queue: %w", err)
-		}
-		if !resp.Success {
-			return fmt.Errorf("failed to register merge-queue: %s", resp.Error)
-		}
+		return fmt.Errorf("failed to register %s: %s", agent.Name, resp.Error)
 	}
+	fmt.Fprintf(out, "Registered %s\n", agent.Name)
+	return nil
+}
 
+// createDefaultWorkspace creates the default workspace worktree, starts its
+// agent with the given prompt file and registers it with the daemon. With
+// newSession its window creates the repository's tmux session, as no other
+// agent's window did.
+func (c *CLI) createDefaultWorkspace(out io.Writer, repoName, repoPath, tmuxSession string, newSession bool, promptFile string) error {
 	// Create default workspace worktree
 	wt := worktree.NewManager(repoPath)
 	workspacePath := c.paths.AgentWorktree(repoName, "default")
@@ -1107,95 +2815,59 @@ func (c *CLI) initRepo(args []string) error {
 		return fmt.Errorf("failed to check workspace branch state: %w", err)
 	}
 	if migrated {
-		fmt.Println("Migrated legacy 'workspace' branch to 'workspace/default'")
+		fmt.Fprintln(out, "Migrated legacy 'workspace' branch to 'workspace/default'")
 	}
 	workspaceBranch := "workspace/default"
 
-	fmt.Printf("Creating default workspace worktree at: %s\n", workspacePath)
+	fmt.Fprintf(out, "Creating default workspace worktree at: %s\n", workspacePath)
 	if err := wt.CreateNewBranch(workspacePath, workspaceBranch, "HEAD"); err != nil {
 		return fmt.Errorf("failed to create default workspace worktree: %w", err)
 	}
+	c.applyGitIdentity(repoName, workspacePath)
+	if err := c.checkoutSubmodules(repoName, workspacePath, "default", "workspace"); err != nil {
+		// The other agents are already running; the workspace can retry
+		fmt.Fprintf(out, "Warning: %v\n", err)
+	}
 
 	// Create default workspace tmux window (detached so it doesn't switch focus)
-	cmd = exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", "default", "-c", workspacePath)
-	if err := cmd.Run(); err != nil {
+	if err := c.newAgentWindow(tmuxSession, "default", workspacePath, newSession); err != nil {
 		return fmt.Errorf("failed to create workspace window: %w", err)
 	}
 
-	// Generate session ID for workspace
-	workspaceSessionID, err := claude.GenerateSessionID()
-	if err != nil {
-		return fmt.Errorf("failed to generate workspace session ID: %w", err)
-	}
-
-	// Write prompt file for default workspace
-	workspacePromptFile, err := c.writePromptFile(repoPath, prompts.TypeWorkspace, "default")
-	if err != nil {
-		return fmt.Errorf("failed to write default workspace prompt: %w", err)
-	}
-
 	// Copy hooks configuration if it exists
 	if err := hooks.CopyConfig(repoPath, workspacePath); err != nil {
-		fmt.Printf("Warning: failed to copy hooks config to default workspace: %v\n", err)
+		fmt.Fprintf(out, "Warning: failed to copy hooks config to default workspace: %v\n", err)
 	}
 
-	// Start Claude in default workspace window (skip in test mode)
-	var workspacePID int
-	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
-		// Resolve claude binary
-		claudeBinary, err := c.getClaudeBinary()
-		if err != nil {
-			return fmt.Errorf("failed to resolve claude binary: %w", err)
-		}
-
-		fmt.Println("Starting Claude Code in default workspace window...")
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, "default", workspacePath, workspaceSessionID, workspacePromptFile, repoName, "")
-		if err != nil {
-			return fmt.Errorf("failed to start default workspace Claude: %w", err)
-		}
-		workspacePID = pid
-
-		// Set up output capture for default workspace
-		if err := c.setupOutputCapture(tmuxSession, "default", repoName, "default", "workspace"); err != nil {
-			fmt.Printf("Warning: failed to setup output capture for default workspace: %v\n", err)
-		}
-	}
-
-	// Add default workspace agent
-	resp, err = client.Send(socket.Request{
-		Command: "add_agent",
-		Args: map[string]interface{}{
-			"repo":          repoName,
-			"agent":         "default",
-			"type":          "workspace",
-			"worktree_path": workspacePath,
-			"tmux_window":   "default",
-			"session_id":    workspaceSessionID,
-			"pid":           workspacePID,
-		},
+	return c.startInitAgent(out, repoName, tmuxSession, initAgent{
+		Name: "default", Type: "workspace", Dir: workspacePath, PromptFile: promptFile,
 	})
-	if err != nil {
-		return fmt.Errorf("failed to register default workspace: %w", err)
+}
+
+// newAgentWindow creates a detached tmux window for an agent, starting in dir.
+// With newSession it creates the repository's session with the window,
+// tagged with the multiclaude root it belongs to.
+func (c *CLI) newAgentWindow(tmuxSession, window, dir string, newSession bool) error {
+	args := []string{"new-window", "-d", "-t", tmuxSession, "-n", window, "-c", dir}
+	if newSession {
+		args = []string{"new-session", "-d", "-s", tmuxSession, "-n", window, "-c", dir}
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to register default workspace: %s", resp.Error)
+	if _, _, err := cmdrun.Run(exec.Command("tmux", args...)); err != nil {
+		return err
 	}
-
-	fmt.Println()
-	fmt.Println("✓ Repository initialized successfully!")
-	fmt.Printf("  Tmux session: %s\n", tmuxSession)
-	if mqEnabled {
-		fmt.Printf("  Agents: supervisor, merge-queue, default (workspace)\n")
-	} else {
-		fmt.Printf("  Agents: supervisor, default (workspace)\n")
+	if newSession {
+		c.tagSession(tmux.NewClient(), tmuxSession)
 	}
-	fmt.Printf("\nAttach to session: tmux attach -t %s\n", tmuxSession)
-	fmt.Printf("Or connect to your workspace: multiclaude workspace connect default\n")
-
 	return nil
 }
 
 func (c *CLI) listRepos(args []string) error {
+	flags, _ := ParseFlags(args)
+	groupName, members, err := c.groupRepos(flags)
+	if err != nil {
+		return err
+	}
+
 	client := socket.NewClient(c.paths.DaemonSock)
 	resp, err := client.Send(socket.Request{
 		Command: "list_repos",
@@ -1216,13 +2888,21 @@ func (c *CLI) listRepos(args []string) error {
 		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
 	}
 
-	if len(repos) == 0 {
+	if groupName != "" {
+		repos = filterGroupRepos(repos, members)
+		if len(repos) == 0 {
+			fmt.Printf("No repositories in group '%s'\n", groupName)
+			format.Dimmed("\nAdd some with: multiclaude group add %s <repo>...", groupName)
+			return nil
+		}
+		format.Header("Repositories in group '%s' (%d):", groupName, len(repos))
+	} else if len(repos) == 0 {
 		fmt.Println("No repositories tracked")
 		format.Dimmed("\nInitialize a repository with: multiclaude init <github-url>")
 		return nil
+	} else {
+		format.Header("Tracked repositories (%d):", len(repos))
 	}
-
-	format.Header("Tracked repositories (%d):", len(repos))
 	fmt.Println()
 
 	table := format.NewColoredTable("REPO", "AGENTS", "STATUS", "SESSION")
@@ -1239,6 +2919,9 @@ func (c *CLI) listRepos(args []string) error {
 			}
 			sessionHealthy, _ := repoMap["session_healthy"].(bool)
 			tmuxSession, _ := repoMap["tmux_session"].(string)
+			if hasSubmodules, _ := repoMap["has_submodules"].(bool); hasSubmodules {
+				name += " (submodules)"
+			}
 
 			// Format agent count
 			agentStr := fmt.Sprintf("%d total", totalAgents)
@@ -1268,6 +2951,8 @@ func (c *CLI) listRepos(args []string) error {
 }
 
 func (c *CLI) removeRepo(args []string) error {
+	assumeYes, args := extractYesFlag(args)
+
 	var repoName string
 	if len(args) > 0 {
 		repoName = args[0]
@@ -1334,12 +3019,15 @@ func (c *CLI) removeRepo(args []string) error {
 					if err == nil && hasUncommitted {
 						agentName, _ := agentMap["name"].(string)
 						fmt.Printf("\nWarning: Agent '%s' has uncommitted changes!\n", agentName)
-						fmt.Println("Files may be lost if you continue.")
-						fmt.Print("Continue with removal? [y/N]: ")
-
-						var response string
-						fmt.Scanln(&response)
-						if response != "y" && response != "Y" {
+						ok, err := confirm(confirmation{
+							Consequence: "Files may be lost if you continue.",
+							Prompt:      "Continue with removal?",
+							AssumeYes:   assumeYes,
+						})
+						if err != nil {
+							return err
+						}
+						if !ok {
 							fmt.Println("Removal cancelled")
 							return nil
 						}
@@ -1351,7 +3039,7 @@ func (c *CLI) removeRepo(args []string) error {
 	}
 
 	// Kill tmux session
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	tmuxSession := c.repoTmuxSession(repoName)
 	tmuxClient := tmux.NewClient()
 	if exists, err := tmuxClient.HasSession(context.Background(), tmuxSession); err == nil && exists {
 		fmt.Printf("Killing tmux session: %s\n", tmuxSession)
@@ -1477,178 +3165,206 @@ func (c *CLI) clearCurrentRepo(args []string) error {
 	return nil
 }
 
-func (c *CLI) configRepo(args []string) error {
-	flags, posArgs := ParseFlags(args)
+// rollback records undo actions for resources created during a command so a
+// later failure can release them in reverse order instead of leaving debris
+type rollback struct {
+	steps []rollbackStep
+}
 
-	// Determine repository
-	var repoName string
-	if len(posArgs) >= 1 {
-		repoName = posArgs[0]
-	} else {
-		// Try to infer from current directory
-		cwd, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
-		}
+type rollbackStep struct {
+	description string
+	undo        func() error
+}
 
-		// Check if we're in a tracked repo
-		repos := c.getReposList()
-		for _, repo := range repos {
-			repoPath := c.paths.RepoDir(repo)
-			if strings.HasPrefix(cwd, repoPath) {
-				repoName = repo
-				break
-			}
-		}
+// add registers an undo action for a resource that was just created
+func (r *rollback) add(description string, undo func() error) {
+	r.steps = append(r.steps, rollbackStep{description: description, undo: undo})
+}
 
-		if repoName == "" {
-			// If only one repo exists, use it
-			if len(repos) == 1 {
-				repoName = repos[0]
-			} else {
-				return fmt.Errorf("please specify a repository name or run from within a tracked repository")
-			}
+// run executes all undo actions, most recent first. Failures are reported
+// but do not stop the remaining steps.
+func (r *rollback) run() {
+	for i := len(r.steps) - 1; i >= 0; i-- {
+		step := r.steps[i]
+		fmt.Printf("Rolling back: %s\n", step.description)
+		if err := step.undo(); err != nil {
+			fmt.Printf("Warning: failed to %s: %v\n", step.description, err)
 		}
 	}
-
-	// Check if any config flags are provided
-	hasMqEnabled := flags["mq-enabled"] != ""
-	hasMqTrack := flags["mq-track"] != ""
-
-	if !hasMqEnabled && !hasMqTrack {
-		// No flags - just show current config
-		return c.showRepoConfig(repoName)
-	}
-
-	// Apply config changes
-	return c.updateRepoConfig(repoName, flags)
+	r.steps = nil
 }
 
-func (c *CLI) showRepoConfig(repoName string) error {
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "get_repo_config",
-		Args: map[string]interface{}{
-			"name": repoName,
-		},
-	})
+func (c *CLI) createWorker(args []string) error {
+	contextFiles, args, err := extractContextFlags(args, os.Stdin)
 	if err != nil {
-		return fmt.Errorf("failed to get repo config: %w (is daemon running?)", err)
+		return err
 	}
-
-	if !resp.Success {
-		return fmt.Errorf("failed to get repo config: %s", resp.Error)
+	noSubmodules, args := extractNoSubmodulesFlag(args)
+	legacyLocal, args := extractLegacyLocalFlag(args)
+	paths, args, err := extractPathFlags(args)
+	if err != nil {
+		return err
 	}
+	flags, posArgs := ParseFlags(args)
 
-	// Parse response
-	configMap, ok := resp.Data.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("unexpected response format")
+	// `--ephemeral <task>` parses the first word of the task as the flag's value
+	ephemeral, isEphemeral := flags["ephemeral"]
+	if isEphemeral && ephemeral != "true" {
+		posArgs = append([]string{ephemeral}, posArgs...)
 	}
-
-	fmt.Printf("Configuration for repository: %s\n\n", repoName)
-	fmt.Println("Merge Queue:")
-
-	mqEnabled := true
-	if enabled, ok := configMap["mq_enabled"].(bool); ok {
-		mqEnabled = enabled
+	allowDuplicate, hasAllowDuplicate := flags["allow-duplicate"]
+	if hasAllowDuplicate && allowDuplicate != "true" {
+		posArgs = append([]string{allowDuplicate}, posArgs...)
 	}
 
-	mqTrackMode := "all"
-	if trackMode, ok := configMap["mq_track_mode"].(string); ok {
-		mqTrackMode = trackMode
+	// Get task description
+	task := strings.Join(posArgs, " ")
+	if task == "" {
+		return errors.InvalidUsage("usage: multiclaude work <task description>")
 	}
 
-	if mqEnabled {
-		fmt.Printf("  Enabled: true\n")
-		fmt.Printf("  Track mode: %s\n", mqTrackMode)
-	} else {
-		fmt.Printf("  Enabled: false\n")
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
 	}
 
-	fmt.Println("\nTo modify:")
-	fmt.Printf("  multiclaude config %s --mq-enabled=true|false\n", repoName)
-	fmt.Printf("  multiclaude config %s --mq-track=all|author|assigned\n", repoName)
-
-	return nil
-}
-
-func (c *CLI) updateRepoConfig(repoName string, flags map[string]string) error {
-	// Build update args
-	updateArgs := map[string]interface{}{
-		"name": repoName,
+	// Ephemeral agents run read-only in the primary checkout, so there is
+	// no branch to start from or push to
+	if isEphemeral {
+		if _, hasBranch := flags["branch"]; hasBranch {
+			return errors.InvalidUsage("--ephemeral cannot be combined with --branch")
+		}
+		if _, hasPushTo := flags["push-to"]; hasPushTo {
+			return errors.InvalidUsage("--ephemeral cannot be combined with --push-to")
+		}
+		if len(contextFiles) > 0 {
+			return errors.InvalidUsage("--ephemeral cannot be combined with --context-file or --context: ephemeral agents have no worktree to copy them into")
+		}
+		if len(paths) > 0 {
+			return errors.InvalidUsage("--ephemeral cannot be combined with --path")
+		}
+		_, err := c.launchEphemeral(repoName, task, flags["name"])
+		return err
 	}
 
-	// Parse and validate flags
-	if mqEnabled, ok := flags["mq-enabled"]; ok {
-		switch mqEnabled {
-		case "true":
-			updateArgs["mq_enabled"] = true
-		case "false":
-			updateArgs["mq_enabled"] = false
-		default:
-			return fmt.Errorf("invalid --mq-enabled value: %s (must be 'true' or 'false')", mqEnabled)
+	// Check for --push-to flag (for iterating on existing PRs)
+	pushTo, hasPushTo := flags["push-to"]
+	if hasPushTo {
+		// --push-to requires --branch to specify the remote branch to start from
+		if _, hasBranch := flags["branch"]; !hasBranch {
+			return errors.InvalidUsage("--push-to requires --branch to specify the remote branch (e.g., --branch origin/work/jolly-hawk --push-to work/jolly-hawk)")
 		}
 	}
 
-	if mqTrack, ok := flags["mq-track"]; ok {
-		switch mqTrack {
-		case "all", "author", "assigned":
-			updateArgs["mq_track_mode"] = mqTrack
-		default:
-			return fmt.Errorf("invalid --mq-track value: %s (must be 'all', 'author', or 'assigned')", mqTrack)
-		}
+	if len(paths) > 0 && legacyLocal {
+		return errors.InvalidUsage("--path cannot be combined with --legacy-local")
 	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "update_repo_config",
-		Args:    updateArgs,
+	_, err = c.launchWorker(repoName, workerSpec{
+		Task:           task,
+		Name:           flags["name"],
+		Branch:         flags["branch"],
+		PushTo:         pushTo,
+		Paths:          paths,
+		ContextFiles:   contextFiles,
+		AllowDuplicate: hasAllowDuplicate,
+		NoSubmodules:   noSubmodules,
+		LegacyLocal:    legacyLocal,
 	})
-	if err != nil {
-		return fmt.Errorf("failed to update repo config: %w (is daemon running?)", err)
-	}
+	return err
+}
 
-	if !resp.Success {
-		return fmt.Errorf("failed to update repo config: %s", resp.Error)
-	}
+// workerSpec describes a worker to launch
+type workerSpec struct {
+	Task   string
+	Name   string // Generated when empty
+	Branch string // Start point; defaults to origin/main, or HEAD without a remote
+	PushTo string // Existing PR branch to push to instead of a new work/<name> branch
 
-	fmt.Printf("Configuration updated for repository: %s\n", repoName)
+	// Directories the task is scoped to; the first is where Claude starts
+	Paths []string
 
-	// Show the updated config
-	return c.showRepoConfig(repoName)
-}
+	// Copied into the worktree and pointed to from the initial message
+	ContextFiles []contextFile
 
-func (c *CLI) createWorker(args []string) error {
-	flags, posArgs := ParseFlags(args)
+	// Set when the worker was split from another worker
+	OriginWorker string
+	OriginTask   string
 
-	// Get task description
-	task := strings.Join(posArgs, " ")
-	if task == "" {
-		return errors.InvalidUsage("usage: multiclaude work <task description>")
+	// Start the worker even if a recent worker has the same task
+	AllowDuplicate bool
+
+	// Leave the worktree's git submodules unchecked out
+	NoSubmodules bool
+
+	// Create the worker from this process rather than the daemon
+	LegacyLocal bool
+}
+
+// launchWorker has the daemon create a worker, or creates it from this
+// process with spec.LegacyLocal. Returns the worker's name.
+func (c *CLI) launchWorker(repoName string, spec workerSpec) (string, error) {
+	if spec.LegacyLocal {
+		return c.launchWorkerLocal(repoName, spec)
 	}
+	return c.requestWorker(repoName, spec)
+}
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
+// launchWorkerLocal creates a worker's worktree and tmux window, starts
+// Claude with its task and registers it with the daemon, undoing everything
+// it created if a step fails. Returns the worker's name. Unlike create_worker
+// it is not serialized with other worker creations; it remains for daemons
+// that predate create_worker.
+func (c *CLI) launchWorkerLocal(repoName string, spec workerSpec) (string, error) {
+	task := spec.Task
+	pushTo := spec.PushTo
+	hasPushTo := pushTo != ""
+
+	// Look up existing agents before touching git, tmux or the filesystem
+	client := socket.NewClient(c.paths.DaemonSock)
+	existingAgents, err := c.existingAgentTypes(repoName)
 	if err != nil {
-		return errors.NotInRepo()
+		return "", err
 	}
 
-	// Generate worker name (Docker-style)
-	workerName := names.Generate()
-	if name, ok := flags["name"]; ok {
-		workerName = name
+	// Refuse early if the repository is at its worker limit
+	if max, err := c.maxConcurrentWorkers(repoName); err == nil && max > 0 {
+		if workerCount := countAgentType(existingAgents, state.AgentTypeWorker); workerCount >= max {
+			return "", errors.WorkerLimitReached(repoName, workerCount, max)
+		}
+	}
+	if limit, err := c.worktreeLimit(repoName); err == nil && limit > 0 {
+		if workerCount := countAgentType(existingAgents, state.AgentTypeWorker); workerCount >= limit {
+			return "", errors.WorktreeLimitReached(repoName, workerCount, limit)
+		}
 	}
 
-	// Check for --push-to flag (for iterating on existing PRs)
-	pushTo, hasPushTo := flags["push-to"]
-	if hasPushTo {
-		// --push-to requires --branch to specify the remote branch to start from
-		if _, hasBranch := flags["branch"]; !hasBranch {
-			return errors.InvalidUsage("--push-to requires --branch to specify the remote branch (e.g., --branch origin/work/jolly-hawk --push-to work/jolly-hawk)")
+	// Refuse a likely duplicate of a worker recently started with the same
+	// task (e.g. `multiclaude work` run twice) before creating anything. The
+	// daemon checks again on registration in case another invocation races us.
+	if !spec.AllowDuplicate {
+		if existing, err := c.findDuplicateWorker(repoName, task); err == nil && existing != "" {
+			return "", errors.PossibleDuplicateWorker(existing, repoName)
 		}
 	}
 
+	// Generate worker name with the repo's naming scheme, avoiding names
+	// already in use
+	workerName, err := chooseAgentName(repoName, spec.Name, task, c.repoNaming(repoName), existingAgents)
+	if err != nil {
+		return "", err
+	}
+
+	// Undo everything created below if a later step fails
+	var created rollback
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			created.run()
+		}
+	}()
+
 	// Get repository path
 	repoPath := c.paths.RepoDir(repoName)
 
@@ -1660,7 +3376,7 @@ func (c *CLI) createWorker(args []string) error {
 	fmt.Println("Fetching latest from origin...")
 	fetchCmd := exec.Command("git", "fetch", "origin")
 	fetchCmd.Dir = repoPath
-	if err := fetchCmd.Run(); err != nil {
+	if _, _, err := cmdrun.Run(fetchCmd); err != nil {
 		// Best effort - don't fail if offline or fetch fails
 		fmt.Printf("Warning: failed to fetch from origin: %v (continuing with local refs)\n", err)
 	}
@@ -1674,7 +3390,7 @@ func (c *CLI) createWorker(args []string) error {
 	if err := checkOriginCmd.Run(); err == nil {
 		startBranch = "origin/main"
 	}
-	if branch, ok := flags["branch"]; ok {
+	if branch := spec.Branch; branch != "" {
 		startBranch = branch
 		if hasPushTo {
 			fmt.Printf("Creating worker '%s' in repo '%s' to iterate on branch '%s'\n", workerName, repoName, pushTo)
@@ -1698,60 +3414,54 @@ func (c *CLI) createWorker(args []string) error {
 		fmt.Printf("Creating worktree at: %s (checking out %s)\n", wtPath, startBranch)
 		// Use git worktree add with -b to create local branch tracking the remote
 		if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
-			return errors.WorktreeCreationFailed(err)
+			return "", errors.WorktreeCreationFailed(err)
 		}
 	} else {
 		// Normal case: create a new branch for this worker
 		branchName = fmt.Sprintf("work/%s", workerName)
 		fmt.Printf("Creating worktree at: %s\n", wtPath)
 		if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
-			return errors.WorktreeCreationFailed(err)
+			return "", errors.WorktreeCreationFailed(err)
 		}
 	}
+	created.add("delete branch "+branchName, func() error { return wt.DeleteBranch(branchName) })
+	created.add("remove worktree "+wtPath, func() error { return wt.Remove(wtPath, true) })
+	c.applyGitIdentity(repoName, wtPath)
 
-	// Get repository info to determine tmux session
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-		},
-	})
-	if err != nil {
-		return errors.DaemonCommunicationFailed("getting repo info", err)
-	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get repo info", fmt.Errorf("%s", resp.Error))
+	if !spec.NoSubmodules {
+		if err := c.checkoutSubmodules(repoName, wtPath, workerName, "worker"); err != nil {
+			return "", err
+		}
 	}
 
-	// Get tmux session name (it's mc-<reponame>)
-	tmuxSession := sanitizeTmuxSessionName(repoName)
-
-	// Ensure tmux session exists before creating window
-	// This handles cases where the session was killed or daemon didn't restore it
+	// Get tmux session name
+	tmuxSession := c.repoTmuxSession(repoName)
 	tmuxClient := tmux.NewClient()
-	hasSession, err := tmuxClient.HasSession(context.Background(), tmuxSession)
-	if err != nil {
-		return errors.TmuxOperationFailed("check session", err)
-	}
-	if !hasSession {
-		fmt.Printf("Tmux session '%s' not found, creating it...\n", tmuxSession)
-		if err := tmuxClient.CreateSession(context.Background(), tmuxSession, true); err != nil {
-			return errors.TmuxOperationFailed("create session", err)
-		}
+	if err := c.ensureTmuxSession(tmuxClient, tmuxSession, &created); err != nil {
+		return "", err
 	}
 
 	// Create tmux window for worker (detached so it doesn't switch focus)
 	fmt.Printf("Creating tmux window: %s\n", workerName)
 	cmd := exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", workerName, "-c", wtPath)
-	if err := cmd.Run(); err != nil {
-		return errors.TmuxOperationFailed("create window", err)
+	if _, _, err := cmdrun.Run(cmd); err != nil {
+		return "", errors.TmuxOperationFailed("create window", err)
+	}
+	created.add("kill tmux window "+workerName, func() error {
+		return tmuxClient.KillWindow(context.Background(), tmuxSession, workerName)
+	})
+
+	// Copy context files into the worktree (removing the worktree on
+	// rollback removes them too)
+	contextPaths, err := writeContextFiles(wtPath, spec.ContextFiles)
+	if err != nil {
+		return "", fmt.Errorf("failed to copy context files: %w", err)
 	}
 
 	// Generate session ID for worker
 	workerSessionID, err := claude.GenerateSessionID()
 	if err != nil {
-		return fmt.Errorf("failed to generate worker session ID: %w", err)
+		return "", fmt.Errorf("failed to generate worker session ID: %w", err)
 	}
 
 	// Write prompt file for worker (with push-to config if specified)
@@ -1761,8 +3471,9 @@ func (c *CLI) createWorker(args []string) error {
 	}
 	workerPromptFile, err := c.writeWorkerPromptFile(repoPath, workerName, workerConfig)
 	if err != nil {
-		return fmt.Errorf("failed to write worker prompt: %w", err)
+		return "", fmt.Errorf("failed to write worker prompt: %w", err)
 	}
+	created.add("remove prompt file "+workerPromptFile, func() error { return os.Remove(workerPromptFile) })
 
 	// Copy hooks configuration if it exists
 	if err := hooks.CopyConfig(repoPath, wtPath); err != nil {
@@ -1775,14 +3486,20 @@ func (c *CLI) createWorker(args []string) error {
 		// Resolve claude binary
 		claudeBinary, err := c.getClaudeBinary()
 		if err != nil {
-			return fmt.Errorf("failed to resolve claude binary: %w", err)
+			return "", fmt.Errorf("failed to resolve claude binary: %w", err)
 		}
 
 		fmt.Println("Starting Claude Code in worker window...")
 		initialMessage := fmt.Sprintf("Task: %s", task)
+		if spec.OriginWorker != "" {
+			initialMessage += fmt.Sprintf("\n\nThis task was split from worker '%s', whose task was: %s\nYour branch starts from its latest commit.", spec.OriginWorker, spec.OriginTask)
+		}
+		if note := contextFilesNote(contextPaths); note != "" {
+			initialMessage += "\n\n" + note
+		}
 		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, workerName, wtPath, workerSessionID, workerPromptFile, repoName, initialMessage)
 		if err != nil {
-			return fmt.Errorf("failed to start worker Claude: %w", err)
+			return "", fmt.Errorf("failed to start worker Claude: %w", err)
 		}
 		workerPID = pid
 
@@ -1793,25 +3510,44 @@ func (c *CLI) createWorker(args []string) error {
 	}
 
 	// Register worker with daemon
-	resp, err = client.Send(socket.Request{
+	agentArgs := map[string]interface{}{
+		"repo":          repoName,
+		"agent":         workerName,
+		"type":          "worker",
+		"worktree_path": wtPath,
+		"tmux_window":   workerName,
+		"docs_hash":     promptDocsHash(workerPromptFile),
+		"task":          task,
+		"session_id":    workerSessionID,
+		"pid":           workerPID,
+	}
+	if spec.OriginWorker != "" {
+		agentArgs["origin_worker"] = spec.OriginWorker
+	}
+	if len(contextPaths) > 0 {
+		agentArgs["context_files"] = contextPaths
+	}
+	if spec.AllowDuplicate {
+		agentArgs["allow_duplicate"] = true
+	}
+	resp, err := client.Send(socket.Request{
 		Command: "add_agent",
-		Args: map[string]interface{}{
-			"repo":          repoName,
-			"agent":         workerName,
-			"type":          "worker",
-			"worktree_path": wtPath,
-			"tmux_window":   workerName,
-			"task":          task,
-			"session_id":    workerSessionID,
-			"pid":           workerPID,
-		},
+		Args:    agentArgs,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to register worker: %w", err)
+		return "", fmt.Errorf("failed to register worker: %w", err)
 	}
 	if !resp.Success {
-		return fmt.Errorf("failed to register worker: %s", resp.Error)
+		// Another invocation registered the same task first; the rollback
+		// removes everything this one created
+		if data, ok := resp.Data.(map[string]interface{}); ok {
+			if existing, _ := data["duplicate_of"].(string); existing != "" {
+				return "", errors.PossibleDuplicateWorker(existing, repoName)
+			}
+		}
+		return "", fmt.Errorf("failed to register worker: %s", resp.Error)
 	}
+	succeeded = true
 
 	fmt.Println()
 	fmt.Println("✓ Worker created successfully!")
@@ -1824,53 +3560,191 @@ func (c *CLI) createWorker(args []string) error {
 	fmt.Printf("\nAttach to worker: tmux select-window -t %s:%s\n", tmuxSession, workerName)
 	fmt.Printf("Or use: multiclaude attach %s\n", workerName)
 
-	return nil
+	return workerName, nil
 }
 
-func (c *CLI) listWorkers(args []string) error {
-	flags, _ := ParseFlags(args)
-
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
+// existingAgentTypes returns the type of every agent registered in a
+// repository, keyed by agent name
+func (c *CLI) existingAgentTypes(repoName string) (map[string]string, error) {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
 	if err != nil {
-		return errors.NotInRepo()
+		return nil, errors.DaemonCommunicationFailed("getting repo info", err)
+	}
+	if !resp.Success {
+		return nil, errors.Wrap(errors.CategoryRuntime, "failed to get repo info", fmt.Errorf("%s", resp.Error))
+	}
+	existingAgents := make(map[string]string) // name -> type
+	if agents, ok := resp.Data.([]interface{}); ok {
+		for _, agent := range agents {
+			if agentMap, ok := agent.(map[string]interface{}); ok {
+				name, _ := agentMap["name"].(string)
+				agentType, _ := agentMap["type"].(string)
+				existingAgents[name] = agentType
+			}
+		}
 	}
+	return existingAgents, nil
+}
 
+// findDuplicateWorker asks the daemon for a live worker recently created
+// with the same task, returning "" if there is none
+func (c *CLI) findDuplicateWorker(repoName, task string) (string, error) {
 	client := socket.NewClient(c.paths.DaemonSock)
 	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
+		Command: "find_duplicate_worker",
 		Args: map[string]interface{}{
 			"repo": repoName,
-			"rich": true,
+			"task": task,
 		},
 	})
 	if err != nil {
-		return errors.DaemonCommunicationFailed("listing workers", err)
+		return "", errors.DaemonCommunicationFailed("checking for duplicate workers", err)
 	}
-
 	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to list workers", fmt.Errorf("%s", resp.Error))
+		return "", errors.Wrap(errors.CategoryRuntime, "failed to check for duplicate workers", fmt.Errorf("%s", resp.Error))
 	}
+	existing, _ := resp.Data.(string)
+	return existing, nil
+}
 
-	agents, ok := resp.Data.([]interface{})
-	if !ok {
-		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+// countAgentType returns how many of the agents from existingAgentTypes
+// have the given type
+func countAgentType(existingAgents map[string]string, agentType state.AgentType) int {
+	count := 0
+	for _, t := range existingAgents {
+		if t == string(agentType) {
+			count++
+		}
 	}
+	return count
+}
 
-	// Filter for workers and workspace
-	workers := []map[string]interface{}{}
-	var workspace map[string]interface{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			if agentType == "worker" {
-				workers = append(workers, agentMap)
-			} else if agentType == "workspace" {
-				workspace = agentMap
+// chooseAgentName validates a requested agent name, or generates one for
+// the task with the repository's naming scheme when none was given,
+// avoiding names already in use
+func chooseAgentName(repoName, requested, task string, naming names.Config, existingAgents map[string]string) (string, error) {
+	if requested != "" {
+		if err := validateAgentName(requested); err != nil {
+			return "", err
+		}
+		if agentType, exists := existingAgents[requested]; exists {
+			if agentType == string(state.AgentTypeWorkspace) {
+				return "", errors.InvalidAgentName(requested, "a workspace with this name already exists")
 			}
+			return "", errors.AgentAlreadyExists(requested, repoName)
+		}
+		return requested, nil
+	}
+
+	vars := names.Vars{User: names.CurrentUser(), Date: time.Now(), Task: task}
+	name, err := naming.Generate(vars, func(name string) bool {
+		_, exists := existingAgents[name]
+		return exists
+	})
+	if err != nil {
+		return "", errors.Wrap(errors.CategoryConfig, fmt.Sprintf("failed to generate an agent name for repo '%s'", repoName), err).
+			WithSuggestion(fmt.Sprintf("multiclaude config %s --name-scheme=docker, or pass --name", repoName))
+	}
+	return name, nil
+}
+
+// repoNaming returns a repository's naming scheme, falling back to
+// Docker-style names when the daemon cannot say
+func (c *CLI) repoNaming(repoName string) names.Config {
+	values, err := c.repoConfig(repoName)
+	if err != nil {
+		return names.Config{}
+	}
+	return names.Config{Scheme: names.Scheme(values["name-scheme"]), Template: values["name-template"]}
+}
+
+// ensureTmuxSession creates the repository's tmux session if it is missing,
+// e.g. because it was killed or the daemon didn't restore it. A session
+// created here is killed if the command is later rolled back.
+func (c *CLI) ensureTmuxSession(tmuxClient *tmux.Client, tmuxSession string, created *rollback) error {
+	hasSession, err := tmuxClient.HasSession(context.Background(), tmuxSession)
+	if err != nil {
+		return errors.TmuxOperationFailed("check session", err)
+	}
+	if !hasSession {
+		fmt.Printf("Tmux session '%s' not found, creating it...\n", tmuxSession)
+		if err := tmuxClient.CreateSession(context.Background(), tmuxSession, true); err != nil {
+			return errors.TmuxOperationFailed("create session", err)
+		}
+		c.tagSession(tmuxClient, tmuxSession)
+		created.add("kill tmux session "+tmuxSession, func() error {
+			return tmuxClient.KillSession(context.Background(), tmuxSession)
+		})
+	}
+	return nil
+}
+
+func (c *CLI) listWorkers(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	sortBy := flags["sort-by"]
+	if sortBy == "" {
+		sortBy = "name"
+	}
+	sortOrder, err := workerSortOrder(sortBy, flags["sort-order"])
+	if err != nil {
+		return err
+	}
+	logTail := 0
+	if value, ok := flags["with-log-tail"]; ok {
+		if logTail, err = logTailCount(value); err != nil {
+			return err
 		}
 	}
 
+	var tmpl *template.Template
+	if text, ok := flags["output-template"]; ok {
+		if logTail > 0 {
+			return errors.InvalidUsage("--output-template cannot be combined with --with-log-tail")
+		}
+		if tmpl, err = parseWorkerTemplate(text); err != nil {
+			return err
+		}
+	}
+
+	groupName, members, err := c.groupRepos(flags)
+	if err != nil {
+		return err
+	}
+	if groupName != "" {
+		if tmpl != nil {
+			return c.printWorkerTemplate(tmpl, members, sortBy, sortOrder)
+		}
+		return c.forEachGroupRepo(groupName, members, func(repoName string) error {
+			return c.listRepoWorkers(repoName, sortBy, sortOrder, logTail)
+		})
+	}
+
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	if tmpl != nil {
+		return c.printWorkerTemplate(tmpl, []string{repoName}, sortBy, sortOrder)
+	}
+	return c.listRepoWorkers(repoName, sortBy, sortOrder, logTail)
+}
+
+// listRepoWorkers prints the workspace and workers of one repository for
+// work list
+func (c *CLI) listRepoWorkers(repoName, sortBy, sortOrder string, logTail int) error {
+	workers, workspace, err := c.fetchWorkers(repoName)
+	if err != nil {
+		return err
+	}
+
 	// Show workspace first if it exists
 	if workspace != nil {
 		format.Header("Workspace in '%s':", repoName)
@@ -1881,6 +3755,8 @@ func (c *CLI) listWorkers(args []string) error {
 			statusCell = format.ColorCell(format.ColoredStatus(format.StatusRunning), nil)
 		case "completed":
 			statusCell = format.ColorCell(format.ColoredStatus(format.StatusCompleted), nil)
+		case "quarantined":
+			statusCell = format.ColorCell(format.ColoredStatus(format.StatusQuarantined), nil)
 		default:
 			statusCell = format.ColorCell(format.ColoredStatus(format.StatusIdle), nil)
 		}
@@ -1896,10 +3772,41 @@ func (c *CLI) listWorkers(args []string) error {
 		return nil
 	}
 
-	format.Header("Workers in '%s' (%d):", repoName, len(workers))
+	// Workers and ephemeral agents are counted against separate limits
+	ephemeralCount := 0
+	for _, worker := range workers {
+		if agentType, _ := worker["type"].(string); agentType == "ephemeral" {
+			ephemeralCount++
+		}
+	}
+	workerCount := len(workers) - ephemeralCount
+	counts := fmt.Sprintf("%d", workerCount)
+	maxWorkers, _ := c.maxConcurrentWorkers(repoName)
+	if maxWorkers > 0 {
+		counts = fmt.Sprintf("%d/%d", workerCount, maxWorkers)
+	}
+	if limit, err := c.worktreeLimit(repoName); err == nil && limit > 0 {
+		if maxWorkers > 0 {
+			counts += fmt.Sprintf(", %d/%d worktrees", workerCount, limit)
+		} else {
+			counts = fmt.Sprintf("%d/%d", workerCount, limit)
+		}
+	}
+	if ephemeralCount > 0 {
+		if max, err := c.maxConcurrentEphemeral(repoName); err == nil && max > 0 {
+			counts += fmt.Sprintf(", %d/%d ephemeral", ephemeralCount, max)
+		} else {
+			counts += fmt.Sprintf(", %d ephemeral", ephemeralCount)
+		}
+	}
+	format.Header("Workers in '%s' (%s):", repoName, counts)
 	fmt.Println()
 
+	sortWorkers(workers, sortBy, sortOrder)
+	duplicates := duplicateWorkerGroups(repoName, workers)
+
 	table := format.NewColoredTable("NAME", "STATUS", "BRANCH", "MSGS", "TASK")
+	nameCells := make(map[string]format.ColoredCell, len(workers))
 	for _, worker := range workers {
 		name, _ := worker["name"].(string)
 		task, _ := worker["task"].(string)
@@ -1909,9 +3816,13 @@ func (c *CLI) listWorkers(args []string) error {
 		if v, ok := worker["messages_total"].(float64); ok {
 			msgsTotal = int(v)
 		}
-		msgsPending := 0
-		if v, ok := worker["messages_pending"].(float64); ok {
-			msgsPending = int(v)
+		msgsUndelivered := 0
+		if v, ok := worker["messages_undelivered"].(float64); ok {
+			msgsUndelivered = int(v)
+		}
+		msgsAwaitingAck := 0
+		if v, ok := worker["messages_awaiting_ack"].(float64); ok {
+			msgsAwaitingAck = int(v)
 		}
 
 		// Format status with color
@@ -1926,21 +3837,41 @@ func (c *CLI) listWorkers(args []string) error {
 		default:
 			statusCell = format.ColorCell(format.ColoredStatus(format.StatusIdle), nil)
 		}
+		// A status set by hand with agent set-status takes its place
+		if custom, _ := worker["custom_status"].(string); custom != "" && status != "completed" {
+			statusCell = format.ColorCell("◆ "+custom, format.Magenta)
+		}
 
-		// Format branch
+		// Format branch; ephemeral agents have none and are read-only
 		branchCell := format.ColorCell(branch, format.Cyan)
-		if branch == "" {
+		if agentType, _ := worker["type"].(string); agentType == "ephemeral" {
+			branchCell = format.ColorCell("(ephemeral, read-only)", format.Yellow)
+		} else if branch == "" {
 			branchCell = format.ColorCell("-", format.Dim)
 		}
 
 		// Format message count
-		msgStr := format.MessageBadge(msgsPending, msgsTotal)
+		msgStr := format.MessageBadge(msgsUndelivered, msgsAwaitingAck, msgsTotal)
 
-		// Truncate task
+		// Truncate task, after the directories it is scoped to
+		if paths, _ := worker["paths"].([]interface{}); len(paths) > 0 {
+			scope := make([]string, len(paths))
+			for i, path := range paths {
+				scope[i] = fmt.Sprint(path)
+			}
+			task = "[" + strings.Join(scope, ", ") + "] " + task
+		}
 		truncTask := format.Truncate(task, 40)
 
+		// Badge workers that share a task with another worker
+		nameCell := format.Cell(name)
+		if _, isDuplicate := duplicates[name]; isDuplicate {
+			nameCell = format.ColorCell(name+" [dup]", format.Yellow)
+		}
+		nameCells[name] = nameCell
+
 		table.AddRow(
-			format.Cell(name),
+			nameCell,
 			statusCell,
 			branchCell,
 			format.Cell(msgStr),
@@ -1949,9 +3880,109 @@ func (c *CLI) listWorkers(args []string) error {
 	}
 	table.Print()
 
+	if len(duplicates) > 0 {
+		var older []string
+		for name, newest := range duplicates {
+			if name != newest {
+				older = append(older, name)
+			}
+		}
+		sort.Strings(older)
+		fmt.Println()
+		fmt.Println("Workers marked [dup] have the same task. Remove the older ones if they are not needed:")
+		for _, name := range older {
+			fmt.Printf("  multiclaude work rm %s  (same task as %s)\n", name, duplicates[name])
+		}
+	}
+
+	if logTail > 0 {
+		c.printLogTails(repoName, workers, nameCells, logTail)
+	}
+
 	return nil
 }
 
+// fetchWorkers returns the workers and ephemeral agents of a repository,
+// and its workspace if it has one, from a rich list_agents response
+func (c *CLI) fetchWorkers(repoName string) ([]map[string]interface{}, map[string]interface{}, error) {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+			"rich": true,
+		},
+	})
+	if err != nil {
+		return nil, nil, errors.DaemonCommunicationFailed("listing workers", err)
+	}
+
+	if !resp.Success {
+		return nil, nil, errors.Wrap(errors.CategoryRuntime, "failed to list workers", fmt.Errorf("%s", resp.Error))
+	}
+
+	agents, ok := resp.Data.([]interface{})
+	if !ok {
+		return nil, nil, errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	}
+
+	// Filter for workers and workspace
+	workers := []map[string]interface{}{}
+	var workspace map[string]interface{}
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			agentType, _ := agentMap["type"].(string)
+			if agentType == "worker" || agentType == "ephemeral" {
+				workers = append(workers, agentMap)
+			} else if agentType == "workspace" {
+				workspace = agentMap
+			}
+		}
+	}
+	return workers, workspace, nil
+}
+
+// duplicateWorkerGroups finds workers whose tasks have the same
+// state.TaskHash. It maps each such worker to the newest worker of its group.
+func duplicateWorkerGroups(repoName string, workers []map[string]interface{}) map[string]string {
+	type member struct {
+		name    string
+		created time.Time
+	}
+	groups := make(map[string][]member)
+	for _, worker := range workers {
+		if agentType, _ := worker["type"].(string); agentType != string(state.AgentTypeWorker) {
+			continue
+		}
+		task, _ := worker["task"].(string)
+		if task == "" {
+			continue
+		}
+		name, _ := worker["name"].(string)
+		createdStr, _ := worker["created_at"].(string)
+		created, _ := time.Parse(time.RFC3339Nano, createdStr)
+		hash := state.TaskHash(repoName, task)
+		groups[hash] = append(groups[hash], member{name: name, created: created})
+	}
+
+	duplicates := make(map[string]string)
+	for _, members := range groups {
+		if len(members) < 2 {
+			continue
+		}
+		newest := members[0]
+		for _, m := range members[1:] {
+			if m.created.After(newest.created) || (m.created.Equal(newest.created) && m.name > newest.name) {
+				newest = m
+			}
+		}
+		for _, m := range members {
+			duplicates[m.name] = newest.name
+		}
+	}
+	return duplicates
+}
+
 func (c *CLI) showHistory(args []string) error {
 	flags, _ := ParseFlags(args)
 
@@ -1976,10 +4007,10 @@ func (c *CLI) showHistory(args []string) error {
 
 	// Validate status filter if provided
 	validStatuses := map[string]bool{
-		"merged": true, "open": true, "closed": true, "failed": true, "no-pr": true,
+		"merged": true, "open": true, "closed": true, "failed": true, "no-pr": true, "synced": true,
 	}
 	if statusFilter != "" && !validStatuses[statusFilter] {
-		return errors.InvalidUsage(fmt.Sprintf("invalid status filter: %s (valid values: merged, open, closed, failed, no-pr)", statusFilter))
+		return errors.InvalidUsage(fmt.Sprintf("invalid status filter: %s (valid values: merged, open, closed, failed, no-pr, synced)", statusFilter))
 	}
 
 	// When filtering, fetch more history to ensure we get enough results
@@ -2014,8 +4045,10 @@ func (c *CLI) showHistory(args []string) error {
 		return nil
 	}
 
-	// Query GitHub for PR status for each task with a branch
+	// Query GitHub for PR status for each task with a branch. Without gh,
+	// tasks with a recorded PR show it and the rest show unknown.
 	repoPath := c.paths.RepoDir(repoName)
+	ghErr := ghError(gh.Check(context.Background()))
 
 	// Build filtered header
 	headerParts := []string{fmt.Sprintf("Task History for '%s'", repoName)}
@@ -2033,6 +4066,7 @@ func (c *CLI) showHistory(args []string) error {
 		name          string
 		summary       string
 		failureReason string
+		taskUpdates   []interface{}
 	}
 	var detailsToShow []entryDetails
 
@@ -2057,9 +4091,14 @@ func (c *CLI) showHistory(args []string) error {
 		summary, _ := entry["summary"].(string)
 		failureReason, _ := entry["failure_reason"].(string)
 		storedStatus, _ := entry["status"].(string)
+		taskUpdates, _ := entry["task_updates"].([]interface{})
 
-		// Try to get PR status from GitHub if we have a branch
-		prStatus, prLink := c.getPRStatusForBranch(repoPath, branch, prURL)
+		// Try to get PR status from GitHub if we have a branch. Upstream
+		// syncs record the default branch, which has no PR of its own.
+		prStatus, prLink := "synced", ""
+		if storedStatus != "synced" {
+			prStatus, prLink = c.getPRStatusForBranch(repoPath, branch, prURL)
+		}
 
 		// Use stored status if it indicates failure
 		if storedStatus == "failed" {
@@ -2089,12 +4128,13 @@ func (c *CLI) showHistory(args []string) error {
 
 		displayedCount++
 
-		// Collect entries with summary or failure for detailed display
-		if summary != "" || failureReason != "" {
+		// Collect entries with summary, failure or task changes for detailed display
+		if summary != "" || failureReason != "" || len(taskUpdates) > 0 {
 			detailsToShow = append(detailsToShow, entryDetails{
 				name:          name,
 				summary:       summary,
 				failureReason: failureReason,
+				taskUpdates:   taskUpdates,
 			})
 		}
 
@@ -2109,6 +4149,8 @@ func (c *CLI) showHistory(args []string) error {
 			statusCell = format.ColorCell("closed", format.Red)
 		case "failed":
 			statusCell = format.ColorCell("failed", format.Red)
+		case "synced":
+			statusCell = format.ColorCell("synced", format.Cyan)
 		default:
 			statusCell = format.ColorCell("no-pr", format.Dim)
 		}
@@ -2152,6 +4194,9 @@ func (c *CLI) showHistory(args []string) error {
 	}
 
 	table.Print()
+	if ghErr != nil {
+		format.Dimmed("\nPR status unavailable: %v", ghErr)
+	}
 
 	// Print detailed summary/failure section if any entries have them
 	if len(detailsToShow) > 0 {
@@ -2165,6 +4210,12 @@ func (c *CLI) showHistory(args []string) error {
 			if d.failureReason != "" {
 				format.Red.Printf("  Failure: %s\n", d.failureReason)
 			}
+			for _, item := range d.taskUpdates {
+				update, _ := item.(map[string]interface{})
+				oldTask, _ := update["old_task"].(string)
+				newTask, _ := update["new_task"].(string)
+				format.Dimmed("  Task changed: %q -> %q", oldTask, newTask)
+			}
 		}
 	}
 
@@ -2190,20 +4241,17 @@ func (c *CLI) getPRStatusForBranch(repoPath, branch, existingPRURL string) (stat
 	}
 
 	// Query GitHub for PR associated with this branch using gh CLI
-	cmd := exec.Command("gh", "pr", "list", "--head", branch, "--state", "all", "--json", "number,state,url", "--limit", "1")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
-	if err != nil {
-		return "no-pr", ""
-	}
-
-	// Parse JSON output
 	var prs []struct {
 		Number int    `json:"number"`
 		State  string `json:"state"`
 		URL    string `json:"url"`
 	}
-	if err := json.Unmarshal(output, &prs); err != nil || len(prs) == 0 {
+	err := gh.PRList(context.Background(), gh.Repo{Dir: repoPath}, "number,state,url", &prs,
+		"--head", branch, "--state", "all", "--limit", "1")
+	if ghUnavailable(err) {
+		return "unknown", ""
+	}
+	if err != nil || len(prs) == 0 {
 		return "no-pr", ""
 	}
 
@@ -2223,6 +4271,8 @@ func (c *CLI) getPRStatusForBranch(repoPath, branch, existingPRURL string) (stat
 }
 
 func (c *CLI) removeWorker(args []string) error {
+	assumeYes, args := extractYesFlag(args)
+	noArchive, force, args := extractArchiveFlags(args)
 	flags, remainingArgs := ParseFlags(args)
 
 	// Determine repository
@@ -2254,7 +4304,7 @@ func (c *CLI) removeWorker(args []string) error {
 		workerName = remainingArgs[0]
 	} else {
 		// Interactive selection
-		items := agentsToSelectableItems(agents, []string{"worker"})
+		items := agentsToSelectableItems(agents, []string{"worker", "ephemeral"})
 		if len(items) == 0 {
 			return errors.NoWorkersFound(repoName)
 		}
@@ -2286,6 +4336,11 @@ func (c *CLI) removeWorker(args []string) error {
 		return errors.AgentNotFound("worker", workerName, repoName)
 	}
 
+	// Ephemeral agents run in the primary checkout, which must never be removed
+	if agentType, _ := workerInfo["type"].(string); agentType == string(state.AgentTypeEphemeral) {
+		return c.removeEphemeral(repoName, workerName, workerInfo)
+	}
+
 	// Get worktree path
 	wtPath := workerInfo["worktree_path"].(string)
 
@@ -2295,12 +4350,15 @@ func (c *CLI) removeWorker(args []string) error {
 		fmt.Printf("Warning: failed to check for uncommitted changes: %v\n", err)
 	} else if hasUncommitted {
 		fmt.Println("\nWarning: Worker has uncommitted changes!")
-		fmt.Println("Files may be lost if you continue with cleanup.")
-		fmt.Print("Continue with cleanup? [y/N]: ")
-
-		var response string
-		fmt.Scanln(&response)
-		if response != "y" && response != "Y" {
+		ok, err := confirm(confirmation{
+			Consequence: "Files may be lost if you continue with cleanup.",
+			Prompt:      "Continue with cleanup?",
+			AssumeYes:   assumeYes,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
 			fmt.Println("Cleanup cancelled")
 			return nil
 		}
@@ -2317,35 +4375,68 @@ func (c *CLI) removeWorker(args []string) error {
 		if err == nil {
 			fmt.Printf("Branch '%s' has commits not pushed to remote.\n", branch)
 		}
-		fmt.Println("These commits may be lost if you continue with cleanup.")
-		fmt.Print("Continue with cleanup? [y/N]: ")
-
-		var response string
-		fmt.Scanln(&response)
-		if response != "y" && response != "Y" {
+		ok, err := confirm(confirmation{
+			Consequence: "These commits may be lost if you continue with cleanup.",
+			Prompt:      "Continue with cleanup?",
+			AssumeYes:   assumeYes,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
 			fmt.Println("Cleanup cancelled")
 			return nil
 		}
 	}
 
+	repoPath := c.paths.RepoDir(repoName)
+	wt := worktree.NewManager(repoPath)
+
+	// Bundle the worker's branch before anything is removed, so its work can
+	// be restored with work archives restore. Only an archived branch is
+	// deleted along with the worktree.
+	archivedBranch := ""
+	if !noArchive {
+		branch, err := worktree.GetCurrentBranch(wtPath)
+		if err != nil || branch == "HEAD" {
+			branch = "work/" + workerName
+		}
+		if exists, _ := wt.BranchExists(branch); exists {
+			path, err := c.archiveBranch(repoName, wt, branch, force)
+			if err != nil {
+				return err
+			}
+			if path != "" {
+				fmt.Printf("Archived branch %s to %s\n", branch, path)
+				archivedBranch = branch
+			}
+		}
+	}
+
 	// Kill tmux window
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	tmuxSession := c.repoTmuxSession(repoName)
 	tmuxWindow := workerInfo["tmux_window"].(string)
 	fmt.Printf("Killing tmux window: %s\n", tmuxWindow)
-	cmd := exec.Command("tmux", "kill-window", "-t", fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow))
-	if err := cmd.Run(); err != nil {
+	cmd := exec.Command("tmux", "kill-window", "-t", fmt.Sprintf("%s:%s", tmuxSession, agentWindowTarget(workerInfo)))
+	if _, _, err := cmdrun.Run(cmd); err != nil {
 		fmt.Printf("Warning: failed to kill tmux window: %v\n", err)
 	}
 
 	// Remove worktree
-	repoPath := c.paths.RepoDir(repoName)
-	wt := worktree.NewManager(repoPath)
-
 	fmt.Printf("Removing worktree: %s\n", wtPath)
+	removeContextFiles(wtPath)
 	if err := wt.Remove(wtPath, false); err != nil {
 		fmt.Printf("Warning: failed to remove worktree: %v\n", err)
 	}
 
+	if archivedBranch != "" {
+		if err := wt.DeleteBranch(archivedBranch); err != nil {
+			fmt.Printf("Warning: failed to delete branch %s: %v\n", archivedBranch, err)
+		} else {
+			fmt.Printf("Deleted branch: %s\n", archivedBranch)
+		}
+	}
+
 	// Unregister from daemon
 	resp, err = client.Send(socket.Request{
 		Command: "remove_agent",
@@ -2385,10 +4476,27 @@ func (c *CLI) workspaceDefault(args []string) error {
 
 // addWorkspace creates a new workspace
 func (c *CLI) addWorkspace(args []string) error {
+	contextFiles, args, err := extractContextFlags(args, os.Stdin)
+	if err != nil {
+		return err
+	}
+	noSubmodules, args := extractNoSubmodulesFlag(args)
+	copyMessages, args := extractCopyMessagesFlag(args)
 	flags, posArgs := ParseFlags(args)
 
 	if len(posArgs) < 1 {
-		return errors.InvalidUsage("usage: multiclaude workspace add <name> [--branch <branch>]")
+		return errors.InvalidUsage("usage: multiclaude workspace add <name> [--branch <branch> | --copy-from <workspace> [--copy-messages]] [--context-file <path>]... [--context -] [--no-submodules]")
+	}
+	copyFrom := flags["copy-from"]
+	if copyFrom == "true" {
+		return errors.MissingArgument("--copy-from", "workspace")
+	}
+	if copyFrom != "" {
+		if _, ok := flags["branch"]; ok {
+			return errors.InvalidUsage("--branch and --copy-from cannot be used together; --copy-from starts from the source workspace's HEAD")
+		}
+	} else if copyMessages {
+		return errors.InvalidUsage("--copy-messages requires --copy-from")
 	}
 
 	workspaceName := posArgs[0]
@@ -2413,7 +4521,9 @@ func (c *CLI) addWorkspace(args []string) error {
 
 	// Determine branch to start from
 	startBranch := "HEAD" // Default to current branch/HEAD
-	if branch, ok := flags["branch"]; ok {
+	if copyFrom != "" {
+		fmt.Printf("Creating workspace '%s' in repo '%s' from workspace '%s'\n", workspaceName, repoName, copyFrom)
+	} else if branch, ok := flags["branch"]; ok {
 		startBranch = branch
 		fmt.Printf("Creating workspace '%s' in repo '%s' from branch '%s'\n", workspaceName, repoName, branch)
 	} else {
@@ -2446,26 +4556,84 @@ func (c *CLI) addWorkspace(args []string) error {
 		}
 	}
 
+	var fork *workspaceFork
+	if copyFrom != "" {
+		if fork, err = c.forkWorkspace(repoName, copyFrom, copyMessages); err != nil {
+			return err
+		}
+		startBranch = fork.Head
+		if fork.Messages != nil {
+			contextFiles = uniqueContextNames(append(contextFiles, *fork.Messages))
+		}
+	}
+
 	// Get repository path
 	repoPath := c.paths.RepoDir(repoName)
 
-	// Create worktree
-	wt := worktree.NewManager(repoPath)
-	wtPath := c.paths.AgentWorktree(repoName, workspaceName)
-	branchName := fmt.Sprintf("workspace/%s", workspaceName)
+	// Create worktree
+	wt := worktree.NewManager(repoPath)
+	wtPath := c.paths.AgentWorktree(repoName, workspaceName)
+	branchName := fmt.Sprintf("workspace/%s", workspaceName)
+
+	fmt.Printf("Creating worktree at: %s\n", wtPath)
+	if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
+		return errors.WorktreeCreationFailed(err)
+	}
+	c.applyGitIdentity(repoName, wtPath)
+	if !noSubmodules {
+		if err := c.checkoutSubmodules(repoName, wtPath, workspaceName, "workspace"); err != nil {
+			// Remove the worktree so the workspace can be added again
+			removeNewWorktree(wt, wtPath, branchName)
+			return err
+		}
+	}
+
+	promptSource := ""
+	if fork != nil {
+		promptSource = fork.PromptFile
+	}
+	if err := c.startWorkspaceAgent(repoName, workspaceName, wtPath, contextFiles, "", promptSource); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("✓ Workspace created successfully!")
+	fmt.Printf("  Name: %s\n", workspaceName)
+	fmt.Printf("  Branch: %s\n", branchName)
+	if fork != nil {
+		fmt.Printf("  Forked from: %s at %s\n", fork.Source, fork.Head[:min(len(fork.Head), 12)])
+	}
+	fmt.Printf("  Worktree: %s\n", wtPath)
+	fmt.Printf("\nConnect to workspace: multiclaude workspace connect %s\n", workspaceName)
+	fmt.Printf("Or use: multiclaude attach %s\n", workspaceName)
+
+	return nil
+}
 
-	fmt.Printf("Creating worktree at: %s\n", wtPath)
-	if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
-		return errors.WorktreeCreationFailed(err)
+// startWorkspaceAgent copies context files into a new workspace's worktree,
+// starts Claude for it in a tmux window with initialMessage (if any) and
+// registers it with the daemon. The workspace gets a copy of promptSource
+// as its prompt if set, or a freshly composed one.
+func (c *CLI) startWorkspaceAgent(repoName, workspaceName, wtPath string, contextFiles []contextFile, initialMessage, promptSource string) error {
+	// Copy context files into the worktree
+	contextPaths, err := writeContextFiles(wtPath, contextFiles)
+	if err != nil {
+		return fmt.Errorf("failed to copy context files: %w", err)
+	}
+	if note := contextFilesNote(contextPaths); note != "" {
+		if initialMessage != "" {
+			initialMessage += "\n\n"
+		}
+		initialMessage += note
 	}
 
 	// Get tmux session name
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	tmuxSession := c.repoTmuxSession(repoName)
 
 	// Create tmux window for workspace (detached so it doesn't switch focus)
 	fmt.Printf("Creating tmux window: %s\n", workspaceName)
 	cmd := exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", workspaceName, "-c", wtPath)
-	if err := cmd.Run(); err != nil {
+	if _, _, err := cmdrun.Run(cmd); err != nil {
 		return errors.TmuxOperationFailed("create window", err)
 	}
 
@@ -2476,7 +4644,13 @@ func (c *CLI) addWorkspace(args []string) error {
 	}
 
 	// Write prompt file for workspace
-	workspacePromptFile, err := c.writePromptFile(repoPath, prompts.TypeWorkspace, workspaceName)
+	repoPath := c.paths.RepoDir(repoName)
+	var workspacePromptFile string
+	if promptSource != "" {
+		workspacePromptFile, err = c.copyPromptFile(promptSource, workspaceName)
+	} else {
+		workspacePromptFile, err = c.writePromptFile(repoPath, prompts.TypeWorkspace, workspaceName)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to write workspace prompt: %w", err)
 	}
@@ -2496,7 +4670,7 @@ func (c *CLI) addWorkspace(args []string) error {
 		}
 
 		fmt.Println("Starting Claude Code in workspace window...")
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, workspaceName, wtPath, workspaceSessionID, workspacePromptFile, repoName, "")
+		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, workspaceName, wtPath, workspaceSessionID, workspacePromptFile, repoName, initialMessage)
 		if err != nil {
 			return fmt.Errorf("failed to start workspace Claude: %w", err)
 		}
@@ -2509,17 +4683,23 @@ func (c *CLI) addWorkspace(args []string) error {
 	}
 
 	// Register workspace with daemon
-	resp, err = client.Send(socket.Request{
+	agentArgs := map[string]interface{}{
+		"repo":          repoName,
+		"agent":         workspaceName,
+		"type":          "workspace",
+		"worktree_path": wtPath,
+		"tmux_window":   workspaceName,
+		"docs_hash":     promptDocsHash(workspacePromptFile),
+		"session_id":    workspaceSessionID,
+		"pid":           workspacePID,
+	}
+	if len(contextPaths) > 0 {
+		agentArgs["context_files"] = contextPaths
+	}
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
 		Command: "add_agent",
-		Args: map[string]interface{}{
-			"repo":          repoName,
-			"agent":         workspaceName,
-			"type":          "workspace",
-			"worktree_path": wtPath,
-			"tmux_window":   workspaceName,
-			"session_id":    workspaceSessionID,
-			"pid":           workspacePID,
-		},
+		Args:    agentArgs,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to register workspace: %w", err)
@@ -2527,20 +4707,12 @@ func (c *CLI) addWorkspace(args []string) error {
 	if !resp.Success {
 		return fmt.Errorf("failed to register workspace: %s", resp.Error)
 	}
-
-	fmt.Println()
-	fmt.Println("✓ Workspace created successfully!")
-	fmt.Printf("  Name: %s\n", workspaceName)
-	fmt.Printf("  Branch: %s\n", branchName)
-	fmt.Printf("  Worktree: %s\n", wtPath)
-	fmt.Printf("\nConnect to workspace: multiclaude workspace connect %s\n", workspaceName)
-	fmt.Printf("Or use: multiclaude attach %s\n", workspaceName)
-
 	return nil
 }
 
 // removeWorkspace removes a workspace
 func (c *CLI) removeWorkspace(args []string) error {
+	assumeYes, args := extractYesFlag(args)
 	flags, remainingArgs := ParseFlags(args)
 
 	// Determine repository
@@ -2615,12 +4787,15 @@ func (c *CLI) removeWorkspace(args []string) error {
 		fmt.Printf("Warning: failed to check for uncommitted changes: %v\n", err)
 	} else if hasUncommitted {
 		fmt.Println("\nWarning: Workspace has uncommitted changes!")
-		fmt.Println("Files may be lost if you continue with removal.")
-		fmt.Print("Continue with removal? [y/N]: ")
-
-		var response string
-		fmt.Scanln(&response)
-		if response != "y" && response != "Y" {
+		ok, err := confirm(confirmation{
+			Consequence: "Files may be lost if you continue with removal.",
+			Prompt:      "Continue with removal?",
+			AssumeYes:   assumeYes,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
 			fmt.Println("Removal cancelled")
 			return nil
 		}
@@ -2637,23 +4812,26 @@ func (c *CLI) removeWorkspace(args []string) error {
 		if err == nil {
 			fmt.Printf("Branch '%s' has commits not pushed to remote.\n", branch)
 		}
-		fmt.Println("These commits may be lost if you continue with removal.")
-		fmt.Print("Continue with removal? [y/N]: ")
-
-		var response string
-		fmt.Scanln(&response)
-		if response != "y" && response != "Y" {
+		ok, err := confirm(confirmation{
+			Consequence: "These commits may be lost if you continue with removal.",
+			Prompt:      "Continue with removal?",
+			AssumeYes:   assumeYes,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
 			fmt.Println("Removal cancelled")
 			return nil
 		}
 	}
 
 	// Kill tmux window
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	tmuxSession := c.repoTmuxSession(repoName)
 	tmuxWindow := workspaceInfo["tmux_window"].(string)
 	fmt.Printf("Killing tmux window: %s\n", tmuxWindow)
-	cmd := exec.Command("tmux", "kill-window", "-t", fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow))
-	if err := cmd.Run(); err != nil {
+	cmd := exec.Command("tmux", "kill-window", "-t", fmt.Sprintf("%s:%s", tmuxSession, agentWindowTarget(workspaceInfo)))
+	if _, _, err := cmdrun.Run(cmd); err != nil {
 		fmt.Printf("Warning: failed to kill tmux window: %v\n", err)
 	}
 
@@ -2662,6 +4840,7 @@ func (c *CLI) removeWorkspace(args []string) error {
 	wt := worktree.NewManager(repoPath)
 
 	fmt.Printf("Removing worktree: %s\n", wtPath)
+	removeContextFiles(wtPath)
 	if err := wt.Remove(wtPath, false); err != nil {
 		fmt.Printf("Warning: failed to remove worktree: %v\n", err)
 	}
@@ -2688,87 +4867,36 @@ func (c *CLI) removeWorkspace(args []string) error {
 // listWorkspaces lists all workspaces in a repository
 func (c *CLI) listWorkspaces(args []string) error {
 	flags, _ := ParseFlags(args)
+	jsonOutput := flags["json"] == "true"
+	showMessages := flags["show-messages"] == "true"
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return errors.NotInRepo()
+	if flags["all-repos"] == "true" {
+		return c.listAllWorkspaces(jsonOutput, showMessages)
 	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-			"rich": true,
-		},
-	})
+	groupName, members, err := c.groupRepos(flags)
 	if err != nil {
-		return errors.DaemonCommunicationFailed("listing workspaces", err)
-	}
-
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to list workspaces", fmt.Errorf("%s", resp.Error))
+		return err
 	}
-
-	agents, ok := resp.Data.([]interface{})
-	if !ok {
-		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	if groupName != "" {
+		return c.listGroupWorkspaces(groupName, members, jsonOutput, showMessages)
 	}
 
-	// Filter for workspaces
-	workspaces := []map[string]interface{}{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			if agentType == "workspace" {
-				workspaces = append(workspaces, agentMap)
-			}
-		}
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
 	}
 
-	if len(workspaces) == 0 {
-		fmt.Printf("No workspaces in repository '%s'\n", repoName)
-		format.Dimmed("\nCreate a workspace with: multiclaude workspace add <name>")
-		return nil
+	workspaces, err := c.repoWorkspaces(repoName)
+	if err != nil {
+		return err
 	}
 
-	format.Header("Workspaces in '%s' (%d):", repoName, len(workspaces))
-	fmt.Println()
-
-	table := format.NewColoredTable("NAME", "BRANCH", "STATUS")
-	for _, ws := range workspaces {
-		name, _ := ws["name"].(string)
-		status, _ := ws["status"].(string)
-		branch, _ := ws["branch"].(string)
-
-		// Format status with color
-		var statusCell format.ColoredCell
-		switch status {
-		case "running":
-			statusCell = format.ColorCell(format.ColoredStatus(format.StatusRunning), nil)
-		case "completed":
-			statusCell = format.ColorCell(format.ColoredStatus(format.StatusCompleted), nil)
-		case "stopped":
-			statusCell = format.ColorCell(format.ColoredStatus(format.StatusError), nil)
-		default:
-			statusCell = format.ColorCell(format.ColoredStatus(format.StatusIdle), nil)
-		}
-
-		// Format branch
-		branchCell := format.ColorCell(branch, format.Cyan)
-		if branch == "" {
-			branchCell = format.ColorCell("-", format.Dim)
-		}
-
-		table.AddRow(
-			format.Cell(name),
-			branchCell,
-			statusCell,
-		)
+	if jsonOutput {
+		return printWorkspacesJSON(workspaces)
 	}
-	table.Print()
-
+	printRepoWorkspaces(repoName, workspaces, showMessages)
 	return nil
 }
 
@@ -2838,8 +4966,8 @@ func (c *CLI) connectWorkspace(args []string) error {
 	}
 
 	// Get tmux session and window
-	tmuxSession := sanitizeTmuxSessionName(repoName)
-	tmuxWindow := workspaceInfo["tmux_window"].(string)
+	tmuxSession := c.repoTmuxSession(repoName)
+	tmuxWindow := agentWindowTarget(workspaceInfo)
 
 	// Attach to tmux
 	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
@@ -2860,40 +4988,19 @@ func (c *CLI) connectWorkspace(args []string) error {
 
 // validateWorkspaceName validates that a workspace name follows branch name restrictions
 func validateWorkspaceName(name string) error {
-	if name == "" {
-		return errors.InvalidWorkspaceName("name cannot be empty")
-	}
-
-	// Git branch name restrictions
-	// - Cannot start with . or -
-	// - Cannot contain consecutive dots ..
-	// - Cannot contain \ or any of these characters: ~ ^ : ? * [ @ { } space
-	// - Cannot end with . or /
-	// - Cannot be "." or ".."
-
-	if name == "." || name == ".." {
-		return errors.InvalidWorkspaceName("cannot be '.' or '..'")
-	}
-
-	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "-") {
-		return errors.InvalidWorkspaceName("cannot start with '.' or '-'")
-	}
-
-	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, "/") {
-		return errors.InvalidWorkspaceName("cannot end with '.' or '/'")
-	}
-
-	if strings.Contains(name, "..") {
-		return errors.InvalidWorkspaceName("cannot contain '..'")
+	if reason := names.Violation(name); reason != "" {
+		return errors.InvalidWorkspaceName(reason)
 	}
+	return nil
+}
 
-	invalidChars := []string{"\\", "~", "^", ":", "?", "*", "[", "@", "{", "}", " ", "\t", "\n"}
-	for _, char := range invalidChars {
-		if strings.Contains(name, char) {
-			return errors.InvalidWorkspaceName(fmt.Sprintf("cannot contain '%s'", char))
-		}
+// validateAgentName validates that a worker name follows the same restrictions
+// as workspace names, has no '/' and is not reserved for a persistent agent.
+// The daemon applies the same check to create_worker requests.
+func validateAgentName(name string) error {
+	if reason := names.AgentViolation(name); reason != "" {
+		return errors.InvalidAgentName(name, reason)
 	}
-
 	return nil
 }
 
@@ -2925,8 +5032,26 @@ func (c *CLI) getReposList() []string {
 }
 
 func (c *CLI) sendMessage(args []string) error {
+	schedule, args, err := extractScheduleFlag(args)
+	if err != nil {
+		return err
+	}
+	idempotencyKey, args, err := extractIdempotencyKeyFlag(args)
+	if err != nil {
+		return err
+	}
+
 	if len(args) < 2 {
-		return errors.InvalidUsage("usage: multiclaude agent send-message <to> <message>")
+		return errors.InvalidUsage("usage: multiclaude agent send-message <to> <message> | <to> --template <name> [--var key=value]... [--schedule <time>] [--idempotency-key <key>]")
+	}
+
+	var scheduledFor *time.Time
+	if schedule != "" {
+		at, err := parseSchedule(schedule, time.Now())
+		if err != nil {
+			return err
+		}
+		scheduledFor = &at
 	}
 
 	to := args[0]
@@ -2938,14 +5063,49 @@ func (c *CLI) sendMessage(args []string) error {
 		return err
 	}
 
-	// Create message manager
-	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	// Message text is taken verbatim unless a template is selected
+	if isTemplateMessage(args[1:]) {
+		name, vars, err := parseTemplateArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		body, err = c.renderMessageTemplate(repoName, agentName, to, name, vars)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create message manager with the repo's size limits
+	limits := c.messageLimits(repoName)
+	msgMgr := messages.NewManager(c.paths.MessagesDir).WithLimits(limits)
 
-	// Send message
-	msg, err := msgMgr.Send(repoName, agentName, to, body)
+	// Send message; with a key, a message already sent with it is not sent again
+	var msg *messages.Message
+	if idempotencyKey != "" {
+		var duplicate bool
+		msg, duplicate, err = msgMgr.SendOnce(repoName, agentName, to, body, idempotencyKey, scheduledFor)
+		if err == nil && duplicate {
+			fmt.Printf("Message already sent (idempotent) to %s (ID: %s)\n", to, msg.ID)
+			return nil
+		}
+	} else {
+		msg, err = msgMgr.Schedule(repoName, agentName, to, body, scheduledFor)
+	}
+	if tooLarge, ok := err.(*messages.BodyTooLargeError); ok {
+		return messageTooLarge(tooLarge)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to send message: %w", err)
 	}
+	if msg.Spilled() {
+		fmt.Printf("Note: the message is %s, over the %s inline limit. %s gets an excerpt and reads the rest with 'multiclaude agent read-message %s --full'.\n",
+			formatByteSize(len(body)), formatByteSize(limits.EffectiveMaxBodySize()), to, msg.ID)
+	}
+
+	if scheduledFor != nil {
+		fmt.Printf("Message to %s scheduled for %s (ID: %s)\n", to, scheduledFor.Format("2006-01-02 15:04 MST"), msg.ID)
+		return nil
+	}
 
 	// Trigger immediate routing (best-effort, polling is fallback)
 	client := socket.NewClient(c.paths.DaemonSock)
@@ -2957,12 +5117,35 @@ func (c *CLI) sendMessage(args []string) error {
 }
 
 func (c *CLI) listMessages(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	if flags["scheduled"] == "true" {
+		repoName, err := c.resolveRepo(flags)
+		if err != nil {
+			return errors.NotInRepo()
+		}
+		return c.listScheduledMessages(repoName)
+	}
+
 	// Determine current agent and repo
 	repoName, agentName, err := c.inferAgentContext()
 	if err != nil {
 		return err
 	}
 
+	statuses, err := messageStatusFilter(flags)
+	if err != nil {
+		return err
+	}
+
+	limit := 0
+	if l, ok := flags["limit"]; ok {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 {
+			return errors.InvalidUsage(fmt.Sprintf("--limit must be a positive integer, got %q", l))
+		}
+	}
+
 	msgMgr := messages.NewManager(c.paths.MessagesDir)
 
 	// List messages
@@ -2971,34 +5154,156 @@ func (c *CLI) listMessages(args []string) error {
 		return fmt.Errorf("failed to list messages: %w", err)
 	}
 
+	msgs = filterMessages(msgs, statuses, flags["from"])
+	sort.Slice(msgs, func(i, j int) bool {
+		return msgs[i].Timestamp.After(msgs[j].Timestamp)
+	})
+	if limit > 0 && len(msgs) > limit {
+		msgs = msgs[:limit]
+	}
+
+	if flags["plain"] == "true" {
+		for _, msg := range msgs {
+			fmt.Println(formatPlainMessage(msg))
+		}
+		return nil
+	}
+
 	if len(msgs) == 0 {
 		fmt.Println("No messages")
 		return nil
 	}
 
-	fmt.Printf("Messages for %s (%d):\n", agentName, len(msgs))
+	format.Header("Messages for %s (%d):", agentName, len(msgs))
+	fmt.Println()
+
+	// Give the preview whatever width the other columns leave
+	fixedWidth := 0
+	widths := map[string]int{"ID": 2, "TIME": 4, "FROM": 4, "STATUS": 6}
 	for _, msg := range msgs {
-		status := msg.Status
-		if msg.Status == messages.StatusAcked && msg.AckedAt != nil {
-			status = messages.Status(fmt.Sprintf("acked (%s)", formatTime(*msg.AckedAt)))
-		}
-		fmt.Printf("  [%s] %s - From: %s - %s - %s\n",
-			msg.ID,
-			formatTime(msg.Timestamp),
-			msg.From,
-			status,
-			truncateString(msg.Body, 60))
+		widths["ID"] = max(widths["ID"], len(msg.ID))
+		widths["TIME"] = max(widths["TIME"], len(formatTime(msg.Timestamp)))
+		widths["FROM"] = max(widths["FROM"], len(msg.From))
+		widths["STATUS"] = max(widths["STATUS"], len(messageStatusText(msg)))
+	}
+	for _, w := range widths {
+		fixedWidth += w + 2
+	}
+	previewWidth := max(format.TerminalWidth()-fixedWidth, 20)
+
+	table := format.NewColoredTable("ID", "TIME", "FROM", "STATUS", "PREVIEW")
+	for _, msg := range msgs {
+		table.AddRow(
+			format.ColorCell(msg.ID, format.Dim),
+			format.Cell(formatTime(msg.Timestamp)),
+			format.ColorCell(msg.From, format.Cyan),
+			messageStatusCell(msg),
+			format.Cell(format.Truncate(flattenWhitespace(msg.Body), previewWidth)),
+		)
 	}
+	table.Print()
 
 	return nil
 }
 
+// messageStatusFilter returns the statuses selected by --status or --unread.
+// A nil result matches every status.
+func messageStatusFilter(flags map[string]string) (map[messages.Status]bool, error) {
+	status, hasStatus := flags["status"]
+	unread := flags["unread"] == "true"
+
+	if hasStatus && unread {
+		return nil, errors.InvalidUsage("--status and --unread cannot be used together")
+	}
+	if unread {
+		return map[messages.Status]bool{messages.StatusPending: true, messages.StatusDelivered: true}, nil
+	}
+	if !hasStatus {
+		return nil, nil
+	}
+
+	switch s := messages.Status(status); s {
+	case messages.StatusPending, messages.StatusDelivered, messages.StatusRead, messages.StatusAcked:
+		return map[messages.Status]bool{s: true}, nil
+	default:
+		return nil, errors.InvalidUsage(fmt.Sprintf("invalid --status %q (must be pending, delivered, read, or acked)", status))
+	}
+}
+
+// filterMessages keeps messages matching the status set (nil matches all)
+// and sender (empty matches all)
+func filterMessages(msgs []*messages.Message, statuses map[messages.Status]bool, from string) []*messages.Message {
+	filtered := make([]*messages.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		if statuses != nil && !statuses[msg.Status] {
+			continue
+		}
+		if from != "" && msg.From != from {
+			continue
+		}
+		filtered = append(filtered, msg)
+	}
+	return filtered
+}
+
+// messageStatusText renders a message status, including when it was acked
+// or when a scheduled message will be delivered
+func messageStatusText(msg *messages.Message) string {
+	if msg.Status == messages.StatusAcked && msg.AckedAt != nil {
+		return fmt.Sprintf("acked (%s)", formatTime(*msg.AckedAt))
+	}
+	if msg.Status == messages.StatusPending && msg.IsScheduled(time.Now()) {
+		return fmt.Sprintf("scheduled (%s)", formatTime(*msg.ScheduledFor))
+	}
+	return string(msg.Status)
+}
+
+// messageStatusCell colors a message status, highlighting messages that
+// still need attention
+func messageStatusCell(msg *messages.Message) format.ColoredCell {
+	text := messageStatusText(msg)
+	switch msg.Status {
+	case messages.StatusPending, messages.StatusDelivered:
+		return format.ColorCell(text, format.Yellow)
+	case messages.StatusAcked:
+		return format.ColorCell(text, format.Green)
+	default:
+		return format.ColorCell(text, format.Dim)
+	}
+}
+
+// formatPlainMessage renders a message as a stable tab-separated line for
+// agents to parse: id, RFC3339 timestamp, sender, status, body
+func formatPlainMessage(msg *messages.Message) string {
+	return strings.Join([]string{
+		msg.ID,
+		msg.Timestamp.Format(time.RFC3339),
+		msg.From,
+		string(msg.Status),
+		flattenWhitespace(msg.Body),
+	}, "\t")
+}
+
+// flattenWhitespace collapses newlines, tabs and runs of spaces into single spaces
+func flattenWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
 func (c *CLI) readMessage(args []string) error {
-	if len(args) < 1 {
-		return errors.InvalidUsage("usage: multiclaude agent read-message <message-id>")
+	full := false
+	var posArgs []string
+	for _, arg := range args {
+		if arg == "--full" || arg == "--full=true" {
+			full = true
+			continue
+		}
+		posArgs = append(posArgs, arg)
+	}
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude agent read-message <message-id> [--full]")
 	}
 
-	messageID := args[0]
+	messageID := posArgs[0]
 
 	// Determine current agent and repo
 	repoName, agentName, err := c.inferAgentContext()
@@ -3031,7 +5336,15 @@ func (c *CLI) readMessage(args []string) error {
 		fmt.Printf("Acked: %s\n", msg.AckedAt.Format(time.RFC3339))
 	}
 	fmt.Println()
-	fmt.Println(msg.Body)
+	if full {
+		body, err := msgMgr.FullBody(repoName, agentName, msg)
+		if err != nil {
+			return err
+		}
+		fmt.Println(body)
+	} else {
+		fmt.Println(msg.Body)
+	}
 
 	return nil
 }
@@ -3101,61 +5414,22 @@ func (c *CLI) inferRepoFromCwd() (string, error) {
 	return "", fmt.Errorf("not in a multiclaude directory")
 }
 
-// normalizeGitHubURL normalizes GitHub URLs to a common format for comparison.
-// It handles both SSH (git@github.com:user/repo.git) and HTTPS (https://github.com/user/repo) formats.
-// Returns lowercase "github.com/user/repo" format for comparison.
-func normalizeGitHubURL(url string) string {
-	url = strings.TrimSpace(url)
-	lowerURL := strings.ToLower(url)
-
-	// Handle SSH format: git@github.com:user/repo.git
-	if strings.HasPrefix(lowerURL, "git@github.com:") {
-		path := url[len("git@github.com:"):]
-		path = strings.TrimSuffix(path, ".git")
-		return strings.ToLower("github.com/" + path)
-	}
-
-	// Handle HTTPS format: https://github.com/user/repo or https://github.com/user/repo.git
-	if strings.HasPrefix(lowerURL, "https://github.com/") {
-		path := url[len("https://"):]
-		path = strings.TrimSuffix(path, ".git")
-		return strings.ToLower(path)
-	}
-
-	// Handle HTTP format: http://github.com/user/repo
-	if strings.HasPrefix(lowerURL, "http://github.com/") {
-		path := url[len("http://"):]
-		path = strings.TrimSuffix(path, ".git")
-		return strings.ToLower(path)
-	}
-
-	// Handle git:// protocol: git://github.com/user/repo.git
-	if strings.HasPrefix(lowerURL, "git://github.com/") {
-		path := url[len("git://"):]
-		path = strings.TrimSuffix(path, ".git")
-		return strings.ToLower(path)
-	}
-
-	// Return empty string for non-GitHub URLs
-	return ""
-}
-
 // findRepoFromGitRemote looks for a git remote in the current directory
 // and tries to match it against known repositories in state.
 func (c *CLI) findRepoFromGitRemote() (string, error) {
 	// Run git remote get-url origin
 	cmd := exec.Command("git", "remote", "get-url", "origin")
-	output, err := cmd.Output()
+	output, err := cmdrun.Output(cmd)
 	if err != nil {
 		return "", fmt.Errorf("failed to get git remote: %w", err)
 	}
 
-	remoteURL := strings.TrimSpace(string(output))
+	remoteURL := strings.TrimSpace(output)
 	if remoteURL == "" {
 		return "", fmt.Errorf("git remote URL is empty")
 	}
 
-	normalizedRemote := normalizeGitHubURL(remoteURL)
+	normalizedRemote := githuburl.Normalize(remoteURL)
 	if normalizedRemote == "" {
 		return "", fmt.Errorf("not a GitHub URL: %s", remoteURL)
 	}
@@ -3173,7 +5447,7 @@ func (c *CLI) findRepoFromGitRemote() (string, error) {
 			continue
 		}
 
-		normalizedStateURL := normalizeGitHubURL(repo.GithubURL)
+		normalizedStateURL := githuburl.Normalize(repo.GithubURL)
 		if normalizedStateURL != "" && normalizedStateURL == normalizedRemote {
 			return repoName, nil
 		}
@@ -3231,12 +5505,13 @@ func (c *CLI) inferAgentContext() (repoName, agentName string, err error) {
 	}
 
 	// Check if we're in a worktree path
-	// Path format: ~/.multiclaude/wts/<repo>/<agent>
+	// Path format: ~/.multiclaude/wts/<repo>/<agent>, possibly in a
+	// subdirectory such as a worker's --path
 	if hasPathPrefix(cwd, c.paths.WorktreesDir) {
 		// Extract repo and agent from path
 		rel, err := filepath.Rel(c.paths.WorktreesDir, cwd)
 		if err == nil {
-			parts := strings.SplitN(rel, string(filepath.Separator), 2)
+			parts := strings.SplitN(rel, string(filepath.Separator), 3)
 			if len(parts) >= 2 {
 				return parts[0], parts[1], nil
 			}
@@ -3260,9 +5535,9 @@ func (c *CLI) inferAgentContext() (repoName, agentName string, err error) {
 				if tmuxWindow != "" {
 					// Get window name from tmux
 					cmd := exec.Command("tmux", "display-message", "-p", "#{window_name}")
-					output, err := cmd.Output()
+					output, err := cmdrun.Output(cmd)
 					if err == nil {
-						windowName := strings.TrimSpace(string(output))
+						windowName := strings.TrimSpace(output)
 						return parts[0], windowName, nil
 					}
 				}
@@ -3304,6 +5579,28 @@ func formatTime(t time.Time) string {
 	return t.Format("Jan 02 15:04")
 }
 
+// formatMessageSummary renders a messages.MessageSummary decoded from a
+// socket response on one line
+func formatMessageSummary(summary map[string]interface{}) string {
+	count := func(key string) int {
+		n, _ := summary[key].(float64)
+		return int(n)
+	}
+
+	line := fmt.Sprintf("%d total, %d pending, %d delivered, %d read, %d acked",
+		count("total_messages"), count("pending_messages"), count("delivered_messages"),
+		count("read_messages"), count("acked_messages"))
+	if expired := count("expired_messages"); expired > 0 {
+		line += fmt.Sprintf(", %d expired", expired)
+	}
+	if oldest, _ := summary["oldest_pending"].(string); oldest != "" {
+		if t, err := time.Parse(time.RFC3339Nano, oldest); err == nil {
+			line += fmt.Sprintf(" (oldest pending since %s)", formatTime(t))
+		}
+	}
+	return line
+}
+
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -3378,7 +5675,7 @@ func (c *CLI) restartAgentCmd(args []string) error {
 
 	// Get agent name from args
 	if len(remaining) < 1 {
-		return errors.InvalidUsage("usage: multiclaude agent restart <name> [--repo <repo>] [--force]")
+		return errors.InvalidUsage("usage: multiclaude agent restart <name> [--repo <repo>] [--force] [--clear-quarantine] [--refresh-docs]")
 	}
 	agentName := remaining[0]
 
@@ -3394,16 +5691,45 @@ func (c *CLI) restartAgentCmd(args []string) error {
 	}
 
 	force := flags["force"] == "true"
+	clearQuarantine := flags["clear-quarantine"] == "true"
+
+	client := socket.NewClient(c.paths.DaemonSock)
+
+	// An agent only reads its prompt when it starts, so a refreshed one is
+	// restarted even if it is running
+	if flags["refresh-docs"] == "true" {
+		resp, err := client.Send(socket.Request{
+			Command: "get_agent",
+			Args: map[string]interface{}{
+				"repo":  repoName,
+				"agent": agentName,
+			},
+		})
+		if err != nil {
+			return errors.DaemonCommunicationFailed("getting agent", err)
+		}
+		data, _ := resp.Data.(map[string]interface{})
+		if registered, _ := data["registered"].(bool); !resp.Success || !registered {
+			return errors.AgentNotRegistered(agentName, repoName)
+		}
+		agentType, _ := data["type"].(string)
+		hash, err := c.refreshPromptDocs(repoName, agentName, prompts.AgentType(agentType))
+		if err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to refresh the CLI reference", err)
+		}
+		fmt.Printf("✓ Refreshed the CLI reference in the prompt of '%s' (multiclaude %s, %s)\n", agentName, Version, hash)
+		force = true
+	}
 
 	fmt.Printf("Restarting agent '%s' in repository '%s'...\n", agentName, repoName)
 
-	client := socket.NewClient(c.paths.DaemonSock)
 	resp, err := client.Send(socket.Request{
 		Command: "restart_agent",
 		Args: map[string]interface{}{
-			"repo":  repoName,
-			"agent": agentName,
-			"force": force,
+			"repo":             repoName,
+			"agent":            agentName,
+			"force":            force,
+			"clear_quarantine": clearQuarantine,
 		},
 	})
 	if err != nil {
@@ -3428,8 +5754,13 @@ func (c *CLI) restartAgentCmd(args []string) error {
 }
 
 func (c *CLI) reviewPR(args []string) error {
+	contextFiles, args, err := extractContextFlags(args, os.Stdin)
+	if err != nil {
+		return err
+	}
+	noSubmodules, args := extractNoSubmodulesFlag(args)
 	if len(args) < 1 {
-		return errors.InvalidUsage("usage: multiclaude review <pr-url>")
+		return errors.InvalidUsage("usage: multiclaude review <pr-url> [--context-file <path>]... [--context -] [--no-submodules]")
 	}
 
 	prURL := args[0]
@@ -3476,8 +5807,19 @@ func (c *CLI) reviewPR(args []string) error {
 		}
 	}
 
-	// Generate review agent name
+	// Review agents are named after the PR unless the repo picked a naming
+	// scheme other than the default
 	reviewerName := fmt.Sprintf("review-%s", prNumber)
+	if naming := c.repoNaming(repoName); naming.Scheme != "" && naming.Scheme != names.SchemeDocker {
+		existingAgents, err := c.existingAgentTypes(repoName)
+		if err != nil {
+			return err
+		}
+		reviewerName, err = chooseAgentName(repoName, "", "review pr "+prNumber, naming, existingAgents)
+		if err != nil {
+			return err
+		}
+	}
 
 	fmt.Printf("Creating review agent '%s' in repo '%s'\n", reviewerName, repoName)
 
@@ -3491,8 +5833,11 @@ func (c *CLI) reviewPR(args []string) error {
 	localRef := fmt.Sprintf("refs/multiclaude/pr-%s", prNumber)
 	cmd := exec.Command("git", "fetch", "origin", fmt.Sprintf("%s:%s", prRef, localRef))
 	cmd.Dir = repoPath
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to fetch PR #%s: %s", prNumber, strings.TrimSpace(string(output))), err).
+	if _, _, err := cmdrun.Run(cmd); err != nil {
+		if cliErr, ok := err.(*errors.CLIError); ok {
+			return cliErr
+		}
+		return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to fetch PR #%s", prNumber), err).
 			WithSuggestion("ensure the PR exists and you have access to the repository")
 	}
 
@@ -3505,14 +5850,27 @@ func (c *CLI) reviewPR(args []string) error {
 	if err := wt.CreateNewBranch(wtPath, reviewBranch, localRef); err != nil {
 		return fmt.Errorf("failed to create worktree: %w", err)
 	}
+	c.applyGitIdentity(repoName, wtPath)
+	if !noSubmodules {
+		if err := c.checkoutSubmodules(repoName, wtPath, reviewerName, "review"); err != nil {
+			removeNewWorktree(wt, wtPath, reviewBranch)
+			return err
+		}
+	}
+
+	// Copy context files into the worktree
+	contextPaths, err := writeContextFiles(wtPath, contextFiles)
+	if err != nil {
+		return fmt.Errorf("failed to copy context files: %w", err)
+	}
 
 	// Get tmux session name
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	tmuxSession := c.repoTmuxSession(repoName)
 
 	// Create tmux window for reviewer (detached so it doesn't switch focus)
 	fmt.Printf("Creating tmux window: %s\n", reviewerName)
 	cmd = exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", reviewerName, "-c", wtPath)
-	if err := cmd.Run(); err != nil {
+	if _, _, err := cmdrun.Run(cmd); err != nil {
 		return fmt.Errorf("failed to create tmux window: %w", err)
 	}
 
@@ -3544,6 +5902,9 @@ func (c *CLI) reviewPR(args []string) error {
 
 		fmt.Println("Starting Claude Code in reviewer window...")
 		initialMessage := fmt.Sprintf("Review PR #%s: https://github.com/%s/%s/pull/%s", prNumber, parts[1], parts[2], prNumber)
+		if note := contextFilesNote(contextPaths); note != "" {
+			initialMessage += "\n\n" + note
+		}
 		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, reviewerName, wtPath, reviewerSessionID, reviewerPromptFile, repoName, initialMessage)
 		if err != nil {
 			return fmt.Errorf("failed to start reviewer Claude: %w", err)
@@ -3558,18 +5919,23 @@ func (c *CLI) reviewPR(args []string) error {
 
 	// Register reviewer with daemon
 	client := socket.NewClient(c.paths.DaemonSock)
+	agentArgs := map[string]interface{}{
+		"repo":          repoName,
+		"agent":         reviewerName,
+		"type":          "review",
+		"worktree_path": wtPath,
+		"tmux_window":   reviewerName,
+		"docs_hash":     promptDocsHash(reviewerPromptFile),
+		"task":          fmt.Sprintf("Review PR #%s", prNumber),
+		"session_id":    reviewerSessionID,
+		"pid":           reviewerPID,
+	}
+	if len(contextPaths) > 0 {
+		agentArgs["context_files"] = contextPaths
+	}
 	resp, err := client.Send(socket.Request{
 		Command: "add_agent",
-		Args: map[string]interface{}{
-			"repo":          repoName,
-			"agent":         reviewerName,
-			"type":          "review",
-			"worktree_path": wtPath,
-			"tmux_window":   reviewerName,
-			"task":          fmt.Sprintf("Review PR #%s", prNumber),
-			"session_id":    reviewerSessionID,
-			"pid":           reviewerPID,
-		},
+		Args:    agentArgs,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to register reviewer: %w", err)
@@ -3599,6 +5965,39 @@ func (c *CLI) viewLogs(args []string) error {
 	agentName := args[0]
 	flags, _ := ParseFlags(args[1:])
 
+	repoName, logFile, err := c.resolveAgentLogFile(agentName, flags)
+	if err != nil {
+		return err
+	}
+
+	// Values from the repo's env files must never be shown
+	redactor := c.secretsRedactor(repoName)
+
+	// Check for --follow flag
+	_, follow := flags["follow"]
+	if _, ok := flags["f"]; ok {
+		follow = true
+	}
+	if follow {
+		// Use tail -f
+		cmd := exec.Command("tail", "-f", logFile)
+		return runRedacted(cmd, redactor)
+	}
+
+	// Determine number of lines
+	lines := "100"
+	if l, ok := flags["lines"]; ok {
+		lines = l
+	}
+
+	// Use tail to get recent lines
+	cmd := exec.Command("tail", "-n", lines, logFile)
+	return runRedacted(cmd, redactor)
+}
+
+// resolveAgentLogFile determines the repository (from --repo, or the only
+// tracked repo) and the log file for the named agent.
+func (c *CLI) resolveAgentLogFile(agentName string, flags map[string]string) (string, string, error) {
 	// Determine repository
 	var repoName string
 	if r, ok := flags["repo"]; ok {
@@ -3606,12 +6005,12 @@ func (c *CLI) viewLogs(args []string) error {
 	} else {
 		repos := c.getReposList()
 		if len(repos) == 0 {
-			return errors.NoRepositoriesFound()
+			return "", "", errors.NoRepositoriesFound()
 		}
 		if len(repos) == 1 {
 			repoName = repos[0]
 		} else {
-			return errors.MultipleRepos()
+			return "", "", errors.MultipleRepos()
 		}
 	}
 
@@ -3619,35 +6018,13 @@ func (c *CLI) viewLogs(args []string) error {
 	workerLogFile := c.paths.AgentLogFile(repoName, agentName, true)
 	systemLogFile := c.paths.AgentLogFile(repoName, agentName, false)
 
-	var logFile string
 	if _, err := os.Stat(workerLogFile); err == nil {
-		logFile = workerLogFile
-	} else if _, err := os.Stat(systemLogFile); err == nil {
-		logFile = systemLogFile
-	} else {
-		return errors.LogFileNotFound(agentName, repoName)
-	}
-
-	// Check for --follow flag
-	if _, ok := flags["follow"]; ok {
-		// Use tail -f
-		cmd := exec.Command("tail", "-f", logFile)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		return cmd.Run()
+		return repoName, workerLogFile, nil
 	}
-
-	// Determine number of lines
-	lines := "100"
-	if l, ok := flags["lines"]; ok {
-		lines = l
+	if _, err := os.Stat(systemLogFile); err == nil {
+		return repoName, systemLogFile, nil
 	}
-
-	// Use tail to get recent lines
-	cmd := exec.Command("tail", "-n", lines, logFile)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return "", "", errors.LogFileNotFound(agentName, repoName)
 }
 
 func (c *CLI) listLogs(args []string) error {
@@ -3700,7 +6077,8 @@ func (c *CLI) listLogsForRepo(repoName string) error {
 		if entry.IsDir() && entry.Name() == "workers" {
 			continue
 		}
-		if strings.HasSuffix(entry.Name(), ".log") {
+		// Symlinked entries could point outside the output directory
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), ".log") {
 			info, _ := entry.Info()
 			agentName := strings.TrimSuffix(entry.Name(), ".log")
 			if info != nil {
@@ -3718,7 +6096,7 @@ func (c *CLI) listLogsForRepo(repoName string) error {
 		if err == nil && len(workerEntries) > 0 {
 			fmt.Println("  workers/")
 			for _, entry := range workerEntries {
-				if strings.HasSuffix(entry.Name(), ".log") {
+				if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), ".log") {
 					info, _ := entry.Info()
 					workerName := strings.TrimSuffix(entry.Name(), ".log")
 					if info != nil {
@@ -3734,60 +6112,6 @@ func (c *CLI) listLogsForRepo(repoName string) error {
 	return nil
 }
 
-func (c *CLI) searchLogs(args []string) error {
-	if len(args) < 1 {
-		return errors.InvalidUsage("usage: multiclaude logs search <pattern> [--repo <repo>]")
-	}
-
-	pattern := args[0]
-	flags, _ := ParseFlags(args[1:])
-
-	// Determine repository
-	var repoName string
-	if r, ok := flags["repo"]; ok {
-		repoName = r
-	}
-
-	// Get search directories
-	var searchPaths []string
-	if repoName != "" {
-		repoOutputDir := c.paths.RepoOutputDir(repoName)
-		if _, err := os.Stat(repoOutputDir); err == nil {
-			searchPaths = append(searchPaths, repoOutputDir)
-		}
-	} else {
-		// Search all repos
-		repos := c.getReposList()
-		for _, repo := range repos {
-			repoOutputDir := c.paths.RepoOutputDir(repo)
-			if _, err := os.Stat(repoOutputDir); err == nil {
-				searchPaths = append(searchPaths, repoOutputDir)
-			}
-		}
-	}
-
-	if len(searchPaths) == 0 {
-		fmt.Println("No log directories found")
-		return nil
-	}
-
-	// Use grep to search recursively
-	grepArgs := []string{"-r", "-n", "--include=*.log", pattern}
-	grepArgs = append(grepArgs, searchPaths...)
-
-	cmd := exec.Command("grep", grepArgs...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	// Run grep (exit code 1 means no matches, which is fine)
-	err := cmd.Run()
-	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-		fmt.Println("No matches found")
-		return nil
-	}
-	return err
-}
-
 func (c *CLI) cleanLogs(args []string) error {
 	flags, _ := ParseFlags(args)
 
@@ -3808,16 +6132,7 @@ func (c *CLI) cleanLogs(args []string) error {
 	var deletedCount, deletedBytes int64
 
 	// Walk output directory
-	err = filepath.Walk(c.paths.OutputDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
-		if info.IsDir() {
-			return nil
-		}
-		if !strings.HasSuffix(path, ".log") {
-			return nil
-		}
+	err = c.paths.WalkLogFiles(func(path string, info os.FileInfo) error {
 		if info.ModTime().Before(cutoff) {
 			deletedBytes += info.Size()
 			if err := os.Remove(path); err != nil {
@@ -3927,8 +6242,8 @@ func (c *CLI) attachAgent(args []string) error {
 	}
 
 	// Get tmux session and window
-	tmuxSession := sanitizeTmuxSessionName(repoName)
-	tmuxWindow := agentInfo["tmux_window"].(string)
+	tmuxSession := c.repoTmuxSession(repoName)
+	tmuxWindow := agentWindowTarget(agentInfo)
 
 	// Attach to tmux
 	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
@@ -3951,6 +6266,17 @@ func (c *CLI) cleanup(args []string) error {
 	dryRun := flags["dry-run"] == "true"
 	verbose := flags["verbose"] == "true" || flags["v"] == "true"
 	cleanMerged := flags["merged"] == "true"
+	noArchive := flags["no-archive"] == "true"
+	force := flags["force"] == "true"
+	aggressive := flags["aggressive"] == "true"
+
+	groupName, members, err := c.groupRepos(flags)
+	if err != nil {
+		return err
+	}
+	if groupName != "" && !cleanMerged {
+		return errors.InvalidUsage("--group only applies with --merged")
+	}
 
 	if dryRun {
 		fmt.Println("Running cleanup in dry-run mode (no changes will be made)...")
@@ -3960,16 +6286,16 @@ func (c *CLI) cleanup(args []string) error {
 
 	// If --merged flag is set, run merged branch cleanup
 	if cleanMerged {
-		return c.cleanupMergedBranches(dryRun, verbose)
+		return c.cleanupMergedBranches(dryRun, verbose, !noArchive, force, groupName, members)
 	}
 
 	client := socket.NewClient(c.paths.DaemonSock)
 
 	// Check if daemon is running
-	_, err := client.Send(socket.Request{Command: "ping"})
+	_, err = client.Send(socket.Request{Command: "ping"})
 	if err != nil {
 		fmt.Println("Daemon is not running. Running local cleanup...")
-		return c.localCleanup(dryRun, verbose)
+		return c.localCleanup(dryRun, verbose, aggressive)
 	}
 
 	// Trigger daemon cleanup
@@ -3986,12 +6312,23 @@ func (c *CLI) cleanup(args []string) error {
 		return fmt.Errorf("cleanup failed: %s", resp.Error)
 	}
 
+	// The daemon leaves tmux sessions it does not track alone
+	if aggressive {
+		if tmuxClient := tmux.NewClient(); tmuxClient.IsTmuxAvailable() {
+			st, _ := state.Load(c.paths.StateFile)
+			c.cleanupSessions(tmuxClient, trackedSessions(nil, st), dryRun, aggressive, verbose)
+		}
+	}
+
 	fmt.Println("Cleanup completed")
 	return nil
 }
 
-// cleanupMergedBranches cleans up branches that have been merged upstream
-func (c *CLI) cleanupMergedBranches(dryRun bool, verbose bool) error {
+// cleanupMergedBranches cleans up branches that have been merged upstream.
+// With archiveBranches, each branch is bundled before it is deleted, and one
+// that cannot be bundled is kept unless force is set. With a group, only its
+// repositories are cleaned up.
+func (c *CLI) cleanupMergedBranches(dryRun bool, verbose bool, archiveBranches bool, force bool, groupName string, members []string) error {
 	fmt.Println("\nChecking for branches merged upstream...")
 
 	// Load state to get repository list
@@ -4005,6 +6342,13 @@ func (c *CLI) cleanupMergedBranches(dryRun bool, verbose bool) error {
 
 	// Process each repository
 	repos := st.ListRepos()
+	if groupName != "" {
+		repos = members
+		if len(repos) == 0 {
+			fmt.Printf("No repositories in group '%s'. Nothing to clean up.\n", groupName)
+			return nil
+		}
+	}
 	if len(repos) == 0 {
 		fmt.Println("No repositories tracked. Nothing to clean up.")
 		return nil
@@ -4073,6 +6417,17 @@ func (c *CLI) cleanupMergedBranches(dryRun bool, verbose bool) error {
 				if dryRun {
 					fmt.Printf("  Would delete: %s\n", branch)
 				} else {
+					if archiveBranches {
+						path, err := c.archiveBranch(repoName, wt, branch, force)
+						if err != nil {
+							fmt.Printf("  Kept %s: %v\n", branch, err)
+							continue
+						}
+						if path != "" && verbose {
+							fmt.Printf("  Archived %s to %s\n", branch, path)
+						}
+					}
+
 					// Delete local branch
 					if err := wt.DeleteBranch(branch); err != nil {
 						fmt.Printf("  Failed to delete %s: %v\n", branch, err)
@@ -4111,7 +6466,7 @@ func (c *CLI) cleanupMergedBranches(dryRun bool, verbose bool) error {
 	return nil
 }
 
-func (c *CLI) localCleanup(dryRun bool, verbose bool) error {
+func (c *CLI) localCleanup(dryRun bool, verbose bool, aggressive bool) error {
 	// Clean up orphaned worktrees, tmux sessions, and other resources
 	fmt.Println("\nChecking for orphaned resources...")
 
@@ -4125,42 +6480,10 @@ func (c *CLI) localCleanup(dryRun bool, verbose bool) error {
 		st = state.New(c.paths.StateFile)
 	}
 
-	// Check for orphaned tmux sessions (mc-* sessions not in state)
+	// Check for orphaned tmux sessions: this root's sessions not in state
 	tmuxClient := tmux.NewClient()
 	if tmuxClient.IsTmuxAvailable() {
-		sessions, err := tmuxClient.ListSessions(context.Background())
-		if err == nil {
-			repos := st.ListRepos()
-			validSessions := make(map[string]bool)
-			for _, repo := range repos {
-				validSessions[fmt.Sprintf("mc-%s", repo)] = true
-			}
-
-			orphanedSessions := []string{}
-			for _, session := range sessions {
-				if strings.HasPrefix(session, "mc-") && !validSessions[session] {
-					orphanedSessions = append(orphanedSessions, session)
-				}
-			}
-
-			if len(orphanedSessions) > 0 {
-				fmt.Printf("\nOrphaned tmux sessions (%d):\n", len(orphanedSessions))
-				for _, session := range orphanedSessions {
-					if dryRun {
-						fmt.Printf("  Would kill: %s\n", session)
-					} else {
-						if err := tmuxClient.KillSession(context.Background(), session); err != nil {
-							fmt.Printf("  Failed to kill %s: %v\n", session, err)
-						} else {
-							fmt.Printf("  Killed: %s\n", session)
-							totalRemoved++
-						}
-					}
-				}
-			} else if verbose {
-				fmt.Println("\nNo orphaned tmux sessions found")
-			}
-		}
+		totalRemoved += c.cleanupSessions(tmuxClient, trackedSessions(st.ListRepos(), st), dryRun, aggressive, verbose)
 	}
 
 	// Check for orphaned worktree directories (in wts/ but not in any repo's git worktrees)
@@ -4212,32 +6535,10 @@ func (c *CLI) localCleanup(dryRun bool, verbose bool) error {
 				}
 			} else {
 				// Dry run: just check what would be removed
-				gitWorktrees, _ := wt.List()
-				gitPaths := make(map[string]bool)
-				for _, gwt := range gitWorktrees {
-					absPath, _ := filepath.Abs(gwt.Path)
-					evalPath, err := filepath.EvalSymlinks(absPath)
-					if err != nil {
-						evalPath = absPath
-					}
-					gitPaths[evalPath] = true
-				}
-
-				dirEntries, _ := os.ReadDir(wtRootDir)
-				for _, de := range dirEntries {
-					if !de.IsDir() {
-						continue
-					}
-					path := filepath.Join(wtRootDir, de.Name())
-					absPath, _ := filepath.Abs(path)
-					evalPath, err := filepath.EvalSymlinks(absPath)
-					if err != nil {
-						evalPath = absPath
-					}
-					if !gitPaths[evalPath] {
-						fmt.Printf("  Would remove: %s\n", path)
-						totalIssues++
-					}
+				orphaned, _ := worktree.FindOrphaned(wtRootDir, wt)
+				for _, path := range orphaned {
+					fmt.Printf("  Would remove: %s\n", path)
+					totalIssues++
 				}
 			}
 
@@ -4378,6 +6679,7 @@ func (c *CLI) localCleanup(dryRun bool, verbose bool) error {
 		} else {
 			fmt.Println("✓ Cleanup completed: no orphaned resources found")
 		}
+		c.auditLocal("local_cleanup", map[string]interface{}{"removed": totalRemoved}, nil)
 	}
 
 	return nil
@@ -4386,6 +6688,7 @@ func (c *CLI) localCleanup(dryRun bool, verbose bool) error {
 func (c *CLI) repair(args []string) error {
 	flags, _ := ParseFlags(args)
 	verbose := flags["verbose"] == "true" || flags["v"] == "true"
+	repoName := flags["repo"]
 
 	fmt.Println("Repairing state...")
 
@@ -4395,13 +6698,15 @@ func (c *CLI) repair(args []string) error {
 	if err != nil {
 		// Daemon not running - do local repair
 		fmt.Println("Daemon is not running. Performing local repair...")
-		return c.localRepair(verbose)
+		return c.localRepair(repoName, verbose)
 	}
 
 	// Trigger state repair via daemon
-	resp, err := client.Send(socket.Request{
-		Command: "repair_state",
-	})
+	req := socket.Request{Command: "repair_state"}
+	if repoName != "" {
+		req.Args = map[string]interface{}{"repo": repoName}
+	}
+	resp, err := client.Send(req)
 	if err != nil {
 		return fmt.Errorf("failed to trigger repair: %w", err)
 	}
@@ -4422,38 +6727,32 @@ func (c *CLI) repair(args []string) error {
 	return nil
 }
 
-// localRepair performs state repair without the daemon running
-func (c *CLI) localRepair(verbose bool) error {
+// localRepair performs state repair without the daemon running, of every
+// repository or only the named one
+func (c *CLI) localRepair(repoName string, verbose bool) error {
 	// Load state from disk
 	st, err := c.loadState()
 	if err != nil {
 		return err
 	}
+	repos := st.GetAllRepos()
+	if repoName != "" {
+		repo, exists := repos[repoName]
+		if !exists {
+			return errors.New(errors.CategoryNotFound, fmt.Sprintf("repository '%s' not found", repoName))
+		}
+		repos = map[string]*state.Repository{repoName: repo}
+	}
 
 	tmuxClient := tmux.NewClient()
 	agentsRemoved := 0
 	issuesFixed := 0
 
-	// Track orphaned tmux sessions
-	orphanedSessions := []string{}
-
-	// Get all tmux sessions and find orphaned ones
-	sessions, err := tmuxClient.ListSessions(context.Background())
-	if err == nil {
-		repos := st.ListRepos()
-		validSessions := make(map[string]bool)
-		for _, repo := range repos {
-			validSessions[fmt.Sprintf("mc-%s", repo)] = true
-		}
-		for _, session := range sessions {
-			if strings.HasPrefix(session, "mc-") && !validSessions[session] {
-				orphanedSessions = append(orphanedSessions, session)
-			}
-		}
-	}
+	// Find this root's tmux sessions that are no longer in state, and those
+	// of other roots sharing the tmux server
+	survey, _ := c.surveySessions(tmuxClient, trackedSessions(st.ListRepos(), st))
 
 	// Check each repo and its agents
-	repos := st.GetAllRepos()
 	for repoName, repo := range repos {
 		if verbose {
 			fmt.Printf("\nChecking repository: %s\n", repoName)
@@ -4486,7 +6785,7 @@ func (c *CLI) localRepair(verbose bool) error {
 		// Check each agent
 		for agentName, agent := range repo.Agents {
 			// Check if window exists
-			hasWindow, _ := tmuxClient.HasWindow(context.Background(), repo.TmuxSession, agent.TmuxWindow)
+			hasWindow, _ := tmuxClient.HasWindow(context.Background(), repo.TmuxSession, agent.WindowTarget())
 			if !hasWindow {
 				if verbose {
 					fmt.Printf("  Removing agent %s (window %s not found)\n", agentName, agent.TmuxWindow)
@@ -4515,7 +6814,7 @@ func (c *CLI) localRepair(verbose bool) error {
 	}
 
 	// Clean up orphaned worktrees
-	for _, repoName := range st.ListRepos() {
+	for repoName := range repos {
 		repoPath := c.paths.RepoDir(repoName)
 		wtRootDir := c.paths.WorktreeDir(repoName)
 
@@ -4547,7 +6846,7 @@ func (c *CLI) localRepair(verbose bool) error {
 
 	// Clean up orphaned message directories
 	msgMgr := messages.NewManager(c.paths.MessagesDir)
-	for _, repoName := range st.ListRepos() {
+	for repoName := range repos {
 		validAgents, _ := st.ListAgents(repoName)
 		if count, err := msgMgr.CleanupOrphaned(repoName, validAgents); err == nil && count > 0 {
 			if verbose {
@@ -4558,19 +6857,32 @@ func (c *CLI) localRepair(verbose bool) error {
 	}
 
 	// Report orphaned tmux sessions
-	if len(orphanedSessions) > 0 {
-		fmt.Printf("\nFound %d orphaned tmux session(s) not in state:\n", len(orphanedSessions))
-		for _, session := range orphanedSessions {
+	if len(survey.Orphaned) > 0 {
+		fmt.Printf("\nFound %d orphaned tmux session(s) not in state:\n", len(survey.Orphaned))
+		for _, session := range survey.Orphaned {
 			fmt.Printf("  - %s\n", session)
 		}
-		fmt.Println("To remove these, run: tmux kill-session -t <session>")
-		fmt.Println("Or use: multiclaude stop-all")
+		fmt.Println("To remove these, run: multiclaude cleanup")
 	}
+	if len(survey.Unknown) > 0 {
+		fmt.Printf("\nFound %d untagged tmux session(s) not in state, possibly of another multiclaude root:\n", len(survey.Unknown))
+		for _, session := range survey.Unknown {
+			fmt.Printf("  - %s\n", session)
+		}
+		fmt.Println("To remove these, run: multiclaude cleanup --aggressive")
+	}
+	printForeignSessions(survey)
 
 	// Save updated state
+	repairArgs := map[string]interface{}{
+		"agents_removed": agentsRemoved,
+		"issues_fixed":   issuesFixed,
+	}
 	if err := st.Save(); err != nil {
+		c.auditLocal("local_repair", repairArgs, err)
 		return fmt.Errorf("failed to save repaired state: %w", err)
 	}
+	c.auditLocal("local_repair", repairArgs, nil)
 
 	fmt.Println("\n✓ Local repair completed")
 	if agentsRemoved > 0 {
@@ -4620,7 +6932,7 @@ func (c *CLI) restartClaude(args []string) error {
 
 	// The path encoding replaces / with - and prefixes with -
 	// e.g., /Users/foo/bar becomes -Users-foo-bar
-	encodedPath := strings.ReplaceAll(agent.WorktreePath, "/", "-")
+	encodedPath := strings.ReplaceAll(agent.WorkDir(), "/", "-")
 	sessionFile := filepath.Join(claudeProjectsDir, encodedPath, agent.SessionID+".jsonl")
 
 	if info, err := os.Stat(sessionFile); err == nil {
@@ -4642,12 +6954,19 @@ func (c *CLI) restartClaude(args []string) error {
 		fmt.Printf("Starting new Claude session %s...\n", agent.SessionID)
 	}
 
-	// Add common flags
-	cmdArgs = append(cmdArgs, "--dangerously-skip-permissions")
-	if _, err := os.Stat(promptFile); err == nil {
-		cmdArgs = append(cmdArgs, "--append-system-prompt-file", promptFile)
+	// Without its prompt file the agent would run with no system prompt, so
+	// regenerate it if it was deleted or emptied
+	if !promptFileUsable(promptFile) {
+		repo, _ := st.GetRepo(repoName)
+		if promptFile, err = c.restorePromptFile(repoName, repo, agentName, agent); err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to regenerate missing prompt file", err)
+		}
+		fmt.Printf("Regenerated missing prompt file %s\n", promptFile)
 	}
 
+	// Add common flags
+	cmdArgs = append(cmdArgs, "--dangerously-skip-permissions", "--append-system-prompt-file", promptFile)
+
 	// Exec claude
 	claudePath := "claude"
 
@@ -4658,33 +6977,81 @@ func (c *CLI) restartClaude(args []string) error {
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	cmd.Dir = agent.WorktreePath
+	cmd.Dir = agent.WorkDir()
 
 	return cmd.Run()
 }
 
+// scopePrompt is the section prepended to the prompt of a worker whose task
+// is scoped to some directories of the repository
+func scopePrompt(paths []string) string {
+	quoted := make([]string, len(paths))
+	for i, path := range paths {
+		quoted[i] = "`" + path + "`"
+	}
+	return fmt.Sprintf(`## Path Scope
+
+**Your task is scoped to %s.** You start in %s; the rest of the repository is checked out only because git needs it.
+
+- Confine your changes to these directories. If the task truly needs a change elsewhere, keep it minimal and explain why in the PR description.
+- Start your PR title with the scope, e.g. `+"`[%s] Fix the bug`"+`.
+
+---
+
+`, strings.Join(quoted, ", "), quoted[0], strings.Join(paths, ", "))
+}
+
 func (c *CLI) showDocs(args []string) error {
-	fmt.Println(c.documentation)
-	return nil
+	flags, _ := ParseFlags(args)
+	agentType, ok := flags["agent-type"]
+	if !ok {
+		fmt.Println(c.documentation)
+		return nil
+	}
+	return c.showAgentDocs(prompts.AgentType(agentType), flags)
 }
 
 // GenerateDocumentation generates markdown documentation for all CLI commands
 func (c *CLI) GenerateDocumentation() string {
+	return c.generateDocumentation(nil)
+}
+
+// generateDocumentation generates markdown documentation for the commands
+// include accepts, given their path such as "agent send-message". A nil
+// include documents every command.
+func (c *CLI) generateDocumentation(include func(path string) bool) string {
 	var sb strings.Builder
 
 	sb.WriteString("# Multiclaude CLI Reference\n\n")
 	sb.WriteString("This is an automatically generated reference for all multiclaude commands.\n\n")
 
 	// Generate docs for each top-level command
-	for name, cmd := range c.rootCmd.Subcommands {
-		c.generateCommandDocs(&sb, name, cmd, 0)
+	for _, name := range sortedSubcommands(c.rootCmd, "", include) {
+		c.generateCommandDocs(&sb, name, c.rootCmd.Subcommands[name], 0, name, include)
 	}
 
 	return sb.String()
 }
 
+// sortedSubcommands returns the names of cmd's documented subcommands in
+// order. Internal commands, whose names start with "_", are left out.
+func sortedSubcommands(cmd *Command, path string, include func(path string) bool) []string {
+	var names []string
+	for name := range cmd.Subcommands {
+		if strings.HasPrefix(name, "_") {
+			continue
+		}
+		if include != nil && !include(strings.TrimSpace(path+" "+name)) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // generateCommandDocs recursively generates documentation for a command and its subcommands
-func (c *CLI) generateCommandDocs(sb *strings.Builder, name string, cmd *Command, level int) {
+func (c *CLI) generateCommandDocs(sb *strings.Builder, name string, cmd *Command, level int, path string, include func(path string) bool) {
 	indent := strings.Repeat("#", level+2)
 
 	// Command header
@@ -4700,23 +7067,26 @@ func (c *CLI) generateCommandDocs(sb *strings.Builder, name string, cmd *Command
 		sb.WriteString(fmt.Sprintf("**Usage:** `%s`\n\n", cmd.Usage))
 	}
 
+	if len(cmd.Flags) > 0 {
+		writeFlagsTable(sb, cmd.Flags)
+	}
+
+	if cmd.Notes != "" {
+		sb.WriteString(fmt.Sprintf("%s\n\n", cmd.Notes))
+	}
+
 	// Subcommands
-	if len(cmd.Subcommands) > 0 {
+	subNames := sortedSubcommands(cmd, path, include)
+	if len(subNames) > 0 {
 		sb.WriteString("**Subcommands:**\n\n")
-		for subName, subCmd := range cmd.Subcommands {
-			// Skip internal commands
-			if strings.HasPrefix(subName, "_") {
-				continue
-			}
-			sb.WriteString(fmt.Sprintf("- `%s` - %s\n", subName, subCmd.Description))
+		for _, subName := range subNames {
+			sb.WriteString(fmt.Sprintf("- `%s` - %s\n", subName, cmd.Subcommands[subName].Description))
 		}
 		sb.WriteString("\n")
 
 		// Recursively document subcommands
-		for subName, subCmd := range cmd.Subcommands {
-			if !strings.HasPrefix(subName, "_") {
-				c.generateCommandDocs(sb, subName, subCmd, level+1)
-			}
+		for _, subName := range subNames {
+			c.generateCommandDocs(sb, subName, cmd.Subcommands[subName], level+1, path+" "+subName, include)
 		}
 	}
 }
@@ -4763,7 +7133,7 @@ func ParseFlags(args []string) (map[string]string, []string) {
 // writePromptFile writes the agent prompt to a temporary file and returns the path
 func (c *CLI) writePromptFile(repoPath string, agentType prompts.AgentType, agentName string) (string, error) {
 	// Get the complete prompt (default + custom + CLI docs)
-	promptText, err := prompts.GetPrompt(repoPath, agentType, c.documentation)
+	promptText, err := c.composePrompt(repoPath, agentType)
 	if err != nil {
 		return "", fmt.Errorf("failed to get prompt: %w", err)
 	}
@@ -4785,13 +7155,16 @@ func (c *CLI) writePromptFile(repoPath string, agentType prompts.AgentType, agen
 // writeMergeQueuePromptFile writes a merge-queue prompt file with tracking mode configuration
 func (c *CLI) writeMergeQueuePromptFile(repoPath string, agentName string, mqConfig state.MergeQueueConfig) (string, error) {
 	// Get the complete prompt (default + custom + CLI docs)
-	promptText, err := prompts.GetPrompt(repoPath, prompts.TypeMergeQueue, c.documentation)
+	promptText, err := c.composePrompt(repoPath, prompts.TypeMergeQueue)
 	if err != nil {
 		return "", fmt.Errorf("failed to get prompt: %w", err)
 	}
 
 	// Add tracking mode configuration to the prompt
 	trackingConfig := prompts.GenerateTrackingModePrompt(string(mqConfig.TrackMode))
+	if reviewGate := prompts.GenerateReviewGatePrompt(mqConfig.MQReviewRequired, mqConfig.MQReviewPattern); reviewGate != "" {
+		trackingConfig += "\n\n" + reviewGate
+	}
 	promptText = trackingConfig + "\n\n" + promptText
 
 	// Create a prompt file in the prompts directory
@@ -4810,13 +7183,35 @@ func (c *CLI) writeMergeQueuePromptFile(repoPath string, agentName string, mqCon
 
 // WorkerConfig holds configuration for creating worker prompts
 type WorkerConfig struct {
-	PushToBranch string // Branch to push to instead of creating a new PR (for iterating on existing PRs)
+	PushToBranch string   // Branch to push to instead of creating a new PR (for iterating on existing PRs)
+	Paths        []string // Directories the task is scoped to (work --path)
 }
 
 // writeWorkerPromptFile writes a worker prompt file with optional configuration
 func (c *CLI) writeWorkerPromptFile(repoPath string, agentName string, config WorkerConfig) (string, error) {
+	promptText, err := c.workerPromptText(repoPath, config)
+	if err != nil {
+		return "", err
+	}
+
+	// Create a prompt file in the prompts directory
+	promptDir := filepath.Join(c.paths.Root, "prompts")
+	if err := os.MkdirAll(promptDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create prompt directory: %w", err)
+	}
+
+	promptPath := filepath.Join(promptDir, fmt.Sprintf("%s.md", agentName))
+	if err := os.WriteFile(promptPath, []byte(promptText), 0644); err != nil {
+		return "", fmt.Errorf("failed to write prompt file: %w", err)
+	}
+
+	return promptPath, nil
+}
+
+// workerPromptText composes a worker's prompt with optional configuration
+func (c *CLI) workerPromptText(repoPath string, config WorkerConfig) (string, error) {
 	// Get the complete prompt (default + custom + CLI docs)
-	promptText, err := prompts.GetPrompt(repoPath, prompts.TypeWorker, c.documentation)
+	promptText, err := c.composePrompt(repoPath, prompts.TypeWorker)
 	if err != nil {
 		return "", fmt.Errorf("failed to get prompt: %w", err)
 	}
@@ -4842,26 +7237,19 @@ Do NOT create a new PR. The existing PR will be updated automatically when you p
 		promptText = pushToConfig + promptText
 	}
 
-	// Create a prompt file in the prompts directory
-	promptDir := filepath.Join(c.paths.Root, "prompts")
-	if err := os.MkdirAll(promptDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create prompt directory: %w", err)
-	}
-
-	promptPath := filepath.Join(promptDir, fmt.Sprintf("%s.md", agentName))
-	if err := os.WriteFile(promptPath, []byte(promptText), 0644); err != nil {
-		return "", fmt.Errorf("failed to write prompt file: %w", err)
+	if len(config.Paths) > 0 {
+		promptText = scopePrompt(config.Paths) + promptText
 	}
 
-	return promptPath, nil
+	return promptText, nil
 }
 
 // setupOutputCapture sets up tmux pipe-pane to capture agent output to a log file.
 // It creates the necessary directories and starts the pipe-pane command.
-// The agentType should be "worker" for worker agents, anything else for system agents.
+// The agentType should be "worker", "review" or "ephemeral" for task agents, anything else for system agents.
 func (c *CLI) setupOutputCapture(tmuxSession, tmuxWindow, repoName, agentName, agentType string) error {
 	// Determine log file path based on agent type
-	isWorker := agentType == "worker" || agentType == "review"
+	isWorker := agentType == "worker" || agentType == "review" || agentType == "ephemeral"
 	logFile := c.paths.AgentLogFile(repoName, agentName, isWorker)
 
 	// Ensure directory exists
@@ -4871,8 +7259,13 @@ func (c *CLI) setupOutputCapture(tmuxSession, tmuxWindow, repoName, agentName, a
 	}
 
 	// Set up pipe-pane
-	tmuxClient := tmux.NewClient()
-	if err := tmuxClient.StartPipePane(context.Background(), tmuxSession, tmuxWindow, logFile); err != nil {
+	rawLogs := false
+	if st, err := c.loadState(); err == nil {
+		if repo, exists := st.GetRepo(repoName); exists {
+			rawLogs = repo.RawLogs
+		}
+	}
+	if err := startOutputCapture(context.Background(), tmux.NewClient(), tmuxSession, tmuxWindow, logFile, rawLogs); err != nil {
 		return fmt.Errorf("failed to start output capture: %w", err)
 	}
 
@@ -4882,9 +7275,25 @@ func (c *CLI) setupOutputCapture(tmuxSession, tmuxWindow, repoName, agentName, a
 // startClaudeInTmux starts Claude Code in a tmux window with the given configuration
 // Returns the PID of the Claude process
 func (c *CLI) startClaudeInTmux(binaryPath, tmuxSession, tmuxWindow, workDir, sessionID, promptFile, repoName string, initialMessage string) (int, error) {
+	binaryPath, err := c.repoClaudeBinary(repoName, binaryPath)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.checkClaudeVersion(repoName, binaryPath); err != nil {
+		return 0, err
+	}
+
 	// Build Claude command - uses global ~/.claude/ for auth and slash commands are embedded in prompts
 	claudeCmd := fmt.Sprintf("%s --session-id %s --dangerously-skip-permissions", binaryPath, sessionID)
 
+	// Inject repo env via the session environment; the shell imports it by name
+	envKeys, err := c.applyRepoEnv(repoName, tmuxSession)
+	if err != nil {
+		fmt.Printf("Warning: failed to apply repo environment: %v\n", err)
+	} else if len(envKeys) > 0 {
+		claudeCmd = tmux.ImportEnvironmentCommand(envKeys...) + " && " + claudeCmd
+	}
+
 	// Add prompt file if provided
 	if promptFile != "" {
 		claudeCmd += fmt.Sprintf(" --append-system-prompt-file %s", promptFile)
@@ -4893,7 +7302,7 @@ func (c *CLI) startClaudeInTmux(binaryPath, tmuxSession, tmuxWindow, workDir, se
 	// Send command to tmux window
 	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
 	cmd := exec.Command("tmux", "send-keys", "-t", target, claudeCmd, "C-m")
-	if err := cmd.Run(); err != nil {
+	if _, _, err := cmdrun.Run(cmd); err != nil {
 		return 0, fmt.Errorf("failed to start Claude in tmux: %w", err)
 	}
 
@@ -4931,6 +7340,18 @@ func (c *CLI) bugReport(args []string) error {
 	// Check for verbose flag
 	verbose := flags["verbose"] == "true" || flags["v"] == "true"
 
+	format := flags["format"]
+	if format == "" {
+		format = "markdown"
+	}
+	if format != "markdown" && format != "json" {
+		return errors.InvalidUsage(fmt.Sprintf("invalid --format value: %q (must be markdown or json)", format))
+	}
+	pretty := flags["pretty"] == "true"
+	if pretty && format != "json" {
+		return errors.InvalidUsage("--pretty only applies to --format json")
+	}
+
 	// Get optional description from positional args
 	description := ""
 	if len(positionalArgs) > 0 {
@@ -4939,17 +7360,33 @@ func (c *CLI) bugReport(args []string) error {
 
 	// Create collector and generate report
 	collector := bugreport.NewCollector(c.paths, Version)
+	collector.IncludeBackups = flags["include-backups"] == "true"
 	report, err := collector.Collect(description, verbose)
 	if err != nil {
 		return fmt.Errorf("failed to collect diagnostic information: %w", err)
 	}
 
-	// Format as Markdown
-	markdown := bugreport.FormatMarkdown(report)
+	var formatted []byte
+	if format == "json" {
+		data, err := bugreport.FormatJSON(report)
+		if err != nil {
+			return fmt.Errorf("failed to format report as JSON: %w", err)
+		}
+		if pretty {
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, data, "", "  "); err != nil {
+				return fmt.Errorf("failed to format report as JSON: %w", err)
+			}
+			data = indented.Bytes()
+		}
+		formatted = append(data, '\n')
+	} else {
+		formatted = []byte(bugreport.FormatMarkdown(report))
+	}
 
 	// Check if output file specified
 	if outputFile, ok := flags["output"]; ok {
-		if err := os.WriteFile(outputFile, []byte(markdown), 0644); err != nil {
+		if err := os.WriteFile(outputFile, formatted, 0644); err != nil {
 			return fmt.Errorf("failed to write report to %s: %w", outputFile, err)
 		}
 		fmt.Printf("Bug report written to: %s\n", outputFile)
@@ -4957,7 +7394,7 @@ func (c *CLI) bugReport(args []string) error {
 	}
 
 	// Print to stdout
-	fmt.Print(markdown)
+	os.Stdout.Write(formatted)
 	return nil
 }
 
@@ -4965,13 +7402,13 @@ func (c *CLI) bugReport(args []string) error {
 func (c *CLI) listBranchesWithPrefix(repoPath, prefix string) ([]string, error) {
 	cmd := exec.Command("git", "branch", "--list", prefix+"*")
 	cmd.Dir = repoPath
-	output, err := cmd.Output()
+	output, err := cmdrun.Output(cmd)
 	if err != nil {
 		return nil, err
 	}
 
 	var branches []string
-	for _, line := range strings.Split(string(output), "\n") {
+	for _, line := range strings.Split(output, "\n") {
 		branch := strings.TrimSpace(line)
 		branch = strings.TrimPrefix(branch, "* ") // Remove current branch marker
 		if branch != "" {
@@ -4985,5 +7422,6 @@ func (c *CLI) listBranchesWithPrefix(repoPath, prefix string) ([]string, error)
 func (c *CLI) deleteBranch(repoPath, branch string) error {
 	cmd := exec.Command("git", "branch", "-D", branch)
 	cmd.Dir = repoPath
-	return cmd.Run()
+	_, _, err := cmdrun.Run(cmd)
+	return err
 }
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+)
+
+// AssumeYesEnv answers yes to every confirmation prompt when set to "1",
+// like passing --yes to each command
+const AssumeYesEnv = "MULTICLAUDE_ASSUME_YES"
+
+// extractYesFlag removes --yes / -y from args and reports whether it was
+// present. It runs before ParseFlags, which would otherwise take the next
+// argument (e.g. the name in `work rm -y fox`) as the flag's value.
+func extractYesFlag(args []string) (bool, []string) {
+	yes := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg {
+		case "--yes", "-y", "--yes=true", "-y=true":
+			yes = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return yes, rest
+}
+
+// confirmation describes a destructive action awaiting the user's approval
+type confirmation struct {
+	// Consequence is printed before asking, e.g. "Files may be lost"
+	Consequence string
+	// Prompt is the question; " [y/N]: " is appended unless Phrase is set
+	Prompt string
+	// Phrase, if set, must be typed exactly instead of answering y/N
+	Phrase string
+	// AssumeYes is true when the command was given --yes
+	AssumeYes bool
+}
+
+// confirm asks for confirmation on stdin. See confirmFrom.
+func confirm(c confirmation) (bool, error) {
+	return confirmFrom(os.Stdin, c)
+}
+
+// confirmFrom prints the consequence and asks for confirmation on in. It
+// answers yes without asking when --yes or MULTICLAUDE_ASSUME_YES=1 is set.
+// When in is not a terminal (a script, or an agent running the command)
+// nobody can answer, so it fails immediately with a hint to pass --yes
+// instead of blocking on a read.
+func confirmFrom(in *os.File, c confirmation) (bool, error) {
+	if c.Consequence != "" {
+		fmt.Println(c.Consequence)
+	}
+
+	if c.AssumeYes || os.Getenv(AssumeYesEnv) == "1" {
+		fmt.Println("Proceeding (--yes)")
+		return true, nil
+	}
+
+	if !isTerminal(in) {
+		return false, errors.ConfirmationRequired(c.Prompt)
+	}
+
+	if c.Phrase != "" {
+		fmt.Printf("%s ", c.Prompt)
+	} else {
+		fmt.Printf("%s [y/N]: ", c.Prompt)
+	}
+	input, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && input == "" {
+		return false, nil
+	}
+	input = strings.TrimSpace(input)
+
+	if c.Phrase != "" {
+		return input == c.Phrase, nil
+	}
+	return strings.EqualFold(input, "y") || strings.EqualFold(input, "yes"), nil
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
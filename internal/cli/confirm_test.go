@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+)
+
+// pipedStdin returns the read end of a pipe holding input, standing in for
+// stdin when a command is run by a script or an agent
+func pipedStdin(t *testing.T, input string) *os.File {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	if _, err := w.WriteString(input); err != nil {
+		t.Fatalf("Failed to write to pipe: %v", err)
+	}
+	w.Close()
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestConfirmPipedStdin(t *testing.T) {
+	t.Setenv(AssumeYesEnv, "")
+
+	// Even an explicit "y" on a pipe is not taken as an answer
+	in := pipedStdin(t, "y\n")
+	ok, err := confirmFrom(in, confirmation{
+		Consequence: "Files may be lost.",
+		Prompt:      "Continue with removal?",
+	})
+	if ok {
+		t.Error("confirmFrom() should default to no when stdin is not a terminal")
+	}
+	if err == nil {
+		t.Fatal("confirmFrom() should fail when stdin is not a terminal")
+	}
+	cliErr, isCLIErr := err.(*errors.CLIError)
+	if !isCLIErr {
+		t.Fatalf("expected *errors.CLIError, got %T", err)
+	}
+	if !strings.Contains(cliErr.Suggestion, "--yes") {
+		t.Errorf("expected --yes guidance, got suggestion: %q", cliErr.Suggestion)
+	}
+}
+
+func TestConfirmAssumeYes(t *testing.T) {
+	t.Run("flag", func(t *testing.T) {
+		t.Setenv(AssumeYesEnv, "")
+		ok, err := confirmFrom(pipedStdin(t, ""), confirmation{Prompt: "Continue?", AssumeYes: true})
+		if err != nil || !ok {
+			t.Errorf("confirmFrom() with --yes = %v, %v; want true, nil", ok, err)
+		}
+	})
+
+	t.Run("env var", func(t *testing.T) {
+		t.Setenv(AssumeYesEnv, "1")
+		ok, err := confirmFrom(pipedStdin(t, ""), confirmation{Prompt: "Type 'NUKE' to confirm:", Phrase: "NUKE"})
+		if err != nil || !ok {
+			t.Errorf("confirmFrom() with %s=1 = %v, %v; want true, nil", AssumeYesEnv, ok, err)
+		}
+	})
+
+	t.Run("env var other value", func(t *testing.T) {
+		t.Setenv(AssumeYesEnv, "true")
+		if ok, _ := confirmFrom(pipedStdin(t, ""), confirmation{Prompt: "Continue?"}); ok {
+			t.Errorf("confirmFrom() should only honor %s=1", AssumeYesEnv)
+		}
+	})
+}
+
+func TestExtractYesFlag(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantYes  bool
+		wantRest []string
+	}{
+		{[]string{"fox"}, false, []string{"fox"}},
+		{[]string{"--yes", "fox"}, true, []string{"fox"}},
+		{[]string{"-y", "fox", "--repo", "r"}, true, []string{"fox", "--repo", "r"}},
+		{[]string{"fox", "--yes=true"}, true, []string{"fox"}},
+	}
+
+	for _, tt := range tests {
+		yes, rest := extractYesFlag(tt.args)
+		if yes != tt.wantYes {
+			t.Errorf("extractYesFlag(%v) yes = %v, want %v", tt.args, yes, tt.wantYes)
+		}
+		if strings.Join(rest, " ") != strings.Join(tt.wantRest, " ") {
+			t.Errorf("extractYesFlag(%v) rest = %v, want %v", tt.args, rest, tt.wantRest)
+		}
+	}
+
+	// The name after -y must stay positional
+	yes, rest := extractYesFlag([]string{"-y", "fox"})
+	_, posArgs := ParseFlags(rest)
+	if !yes || len(posArgs) != 1 || posArgs[0] != "fox" {
+		t.Errorf("expected -y fox to keep fox positional, got yes=%v args=%v", yes, posArgs)
+	}
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// stdinContextName is the file name given to context read with --context -
+const stdinContextName = "stdin.md"
+
+// contextFile is a file handed to a new agent as context for its task
+type contextFile struct {
+	Name string
+	Data []byte
+}
+
+// extractContextFlags removes --context-file <path> and --context - (and
+// their --flag=value forms) from the arguments of work, review and workspace
+// add, and reads the files they name. Both may be repeated, so they cannot
+// go through ParseFlags. Reading everything up front means a missing file is
+// reported before any worktree is created.
+func extractContextFlags(args []string, stdin io.Reader) ([]contextFile, []string, error) {
+	var files []contextFile
+	rest := make([]string, 0, len(args))
+	readStdin := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		if name != "--context-file" && name != "--context" {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, errors.MissingArgument(name, "path")
+			}
+			value = args[i+1]
+			i++
+		}
+
+		if name == "--context" {
+			if value != "-" {
+				return nil, nil, errors.InvalidUsage("--context only accepts - (read from stdin); use --context-file <path> for files")
+			}
+			if readStdin {
+				return nil, nil, errors.InvalidUsage("--context - can only be given once")
+			}
+			readStdin = true
+			data, err := io.ReadAll(stdin)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read context from stdin: %w", err)
+			}
+			files = append(files, contextFile{Name: stdinContextName, Data: data})
+			continue
+		}
+
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return nil, nil, errors.Wrap(errors.CategoryUsage, fmt.Sprintf("failed to read context file %s", value), err)
+		}
+		files = append(files, contextFile{Name: filepath.Base(value), Data: data})
+	}
+
+	return uniqueContextNames(files), rest, nil
+}
+
+// uniqueContextNames renames context files that share a base name, e.g. two
+// notes.md from different directories become notes.md and notes-2.md
+func uniqueContextNames(files []contextFile) []contextFile {
+	used := make(map[string]bool)
+	for i := range files {
+		name := files[i].Name
+		ext := filepath.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		for n := 2; used[name] || name == ".gitignore"; n++ {
+			name = fmt.Sprintf("%s-%d%s", base, n, ext)
+		}
+		used[name] = true
+		files[i].Name = name
+	}
+	return files
+}
+
+// writeContextFiles copies context files into an agent's worktree and
+// returns their paths relative to it
+func writeContextFiles(wtPath string, files []contextFile) ([]string, error) {
+	var paths []string
+	for _, f := range files {
+		path, err := worktree.WriteContextFile(wtPath, f.Name, f.Data)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Copied context file: %s\n", path)
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// contextFilesNote tells an agent where its context files are, for its
+// initial message. Pasting the content instead would flood the terminal.
+func contextFilesNote(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Context for this task is in these files in your worktree (read them before starting; they are git-ignored, so they stay off your branch):")
+	for _, path := range paths {
+		b.WriteString("\n- " + path)
+	}
+	return b.String()
+}
+
+// removeContextFiles deletes an agent's context directory ahead of removing
+// its worktree, so the files go even if the worktree removal fails
+func removeContextFiles(wtPath string) {
+	if err := worktree.RemoveContextDir(wtPath); err != nil {
+		fmt.Printf("Warning: failed to remove context files: %v\n", err)
+	}
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// daemonPingTimeout is how long a ping waits for the daemon's reply before
+// it is counted as lost
+const daemonPingTimeout = 2 * time.Second
+
+// daemonPing sends the daemon a number of pings over its socket, one at a
+// time, and reports the round-trip time of each in the style of ping(8), for
+// spotting a slow or flaky socket
+func (c *CLI) daemonPing(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	count, err := stressIntFlag(flags, "count", 5)
+	if err != nil {
+		return err
+	}
+	interval := time.Second
+	if value, ok := flags["interval"]; ok {
+		interval, err = time.ParseDuration(value)
+		if err != nil || interval < 0 {
+			return errors.InvalidDuration(value)
+		}
+	}
+	quiet := flags["quiet"] == "true"
+
+	if !quiet {
+		fmt.Printf("PING multiclaude daemon (%s)\n", c.paths.DaemonSock)
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	var rtts []time.Duration
+	for seq := 1; seq <= count; seq++ {
+		if seq > 1 {
+			time.Sleep(interval)
+		}
+		start := time.Now()
+		resp, err := client.SendTimeout(socket.Request{Command: "ping"}, daemonPingTimeout)
+		rtt := time.Since(start)
+		if err == nil && !resp.Success {
+			err = fmt.Errorf("daemon replied: %s", resp.Error)
+		}
+		if err != nil {
+			if !quiet {
+				fmt.Printf("Request timeout for seq=%d (%v)\n", seq, err)
+			}
+			continue
+		}
+		rtts = append(rtts, rtt)
+		if !quiet {
+			fmt.Printf("reply from daemon: seq=%d time=%s ms\n", seq, formatRTT(rtt))
+		}
+	}
+
+	if !quiet {
+		fmt.Println()
+		fmt.Println("--- multiclaude daemon ping statistics ---")
+	}
+	fmt.Print(pingSummary(count, rtts))
+
+	if lost := count - len(rtts); lost > 0 {
+		return errors.New(errors.CategoryConnection, fmt.Sprintf("%d of %d pings to the daemon got no reply", lost, count)).
+			WithSuggestion("multiclaude daemon status")
+	}
+	return nil
+}
+
+// pingSummary returns ping(8)'s closing statistics for sent pings of which
+// the ones in rtts were answered
+func pingSummary(sent int, rtts []time.Duration) string {
+	var sb strings.Builder
+	loss := 0.0
+	if sent > 0 {
+		loss = float64(sent-len(rtts)) * 100 / float64(sent)
+	}
+	fmt.Fprintf(&sb, "%d pings transmitted, %d received, %.1f%% packet loss\n", sent, len(rtts), loss)
+	if len(rtts) == 0 {
+		return sb.String()
+	}
+
+	lowest, highest, total := rtts[0], rtts[0], time.Duration(0)
+	for _, rtt := range rtts {
+		lowest = min(lowest, rtt)
+		highest = max(highest, rtt)
+		total += rtt
+	}
+	average := total / time.Duration(len(rtts))
+	fmt.Fprintf(&sb, "round-trip min/avg/max = %s/%s/%s ms\n", formatRTT(lowest), formatRTT(average), formatRTT(highest))
+	return sb.String()
+}
+
+// formatRTT formats a round-trip time as milliseconds, as ping(8) does
+func formatRTT(d time.Duration) string {
+	return fmt.Sprintf("%.3f", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/daemon"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// daemonProfile asks the daemon for a pprof profile of itself and writes it
+// to a file for go tool pprof
+func (c *CLI) daemonProfile(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	profileType := "cpu"
+	if value, ok := flags["type"]; ok {
+		profileType = value
+	}
+	valid := false
+	for _, t := range daemon.ProfileTypes {
+		if profileType == t {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return errors.InvalidUsage(fmt.Sprintf("--type must be one of: %s, got %q", strings.Join(daemon.ProfileTypes, ", "), profileType))
+	}
+
+	duration := daemon.DefaultProfileDuration
+	if value, ok := flags["duration"]; ok {
+		if profileType != "cpu" {
+			return errors.InvalidUsage(fmt.Sprintf("--duration only applies to CPU profiles; a %s profile is a snapshot", profileType))
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return errors.InvalidUsage(fmt.Sprintf("--duration must be a positive duration such as 30s, got %q", value))
+		}
+		if parsed > daemon.MaxProfileDuration {
+			return errors.InvalidUsage(fmt.Sprintf("--duration must be at most %s", daemon.MaxProfileDuration))
+		}
+		duration = parsed
+	}
+
+	output := flags["output"]
+	if output == "" || output == "true" {
+		output = fmt.Sprintf("multiclaude-%s-%s.prof", profileType, time.Now().Format("20060102-150405"))
+	}
+
+	reqArgs := map[string]interface{}{"type": profileType}
+	if profileType == "cpu" {
+		reqArgs["duration"] = duration.String()
+		fmt.Printf("Collecting a CPU profile of the daemon for %s...\n", duration)
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "start_profiling",
+		Args:    reqArgs,
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("profiling the daemon", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to profile the daemon", fmt.Errorf("%s", resp.Error))
+	}
+
+	// The profile's bytes arrive base64 encoded in the JSON response
+	data, _ := resp.Data.(map[string]interface{})
+	encoded, _ := data["profile"].(string)
+	profile, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("failed to decode profile: %w", err)
+	}
+	if err := os.WriteFile(output, profile, 0644); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+
+	fmt.Printf("✓ Wrote %s profile to %s (%d bytes)\n", profileType, output, len(profile))
+	fmt.Println("\nAnalyze it with:")
+	fmt.Printf("  go tool pprof -top %s\n", output)
+	fmt.Printf("  go tool pprof -http=:8080 %s\n", output)
+	return nil
+}
@@ -0,0 +1,300 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// stressSenders is how many goroutines send messages during a stress test
+const stressSenders = 8
+
+// stressLoop is a daemon request repeated during a stress test
+type stressLoop struct {
+	command  string
+	args     map[string]interface{}
+	interval time.Duration
+}
+
+// stressCounters counts the daemon requests and messages of a stress test
+type stressCounters struct {
+	requests atomic.Int64
+	errors   atomic.Int64
+	sent     atomic.Int64
+}
+
+// request sends a request to the daemon, counting it and any failure
+func (s *stressCounters) request(client *socket.Client, command string, args map[string]interface{}) (*socket.Response, error) {
+	s.requests.Add(1)
+	resp, err := client.Send(socket.Request{Command: command, Args: args})
+	if err == nil && !resp.Success {
+		err = fmt.Errorf("%s: %s", command, resp.Error)
+	}
+	if err != nil {
+		s.errors.Add(1)
+	}
+	return resp, err
+}
+
+// daemonStressTest registers fake agents with the daemon, sends messages
+// between them while health checks, cleanup and message routing run, then
+// checks that the daemon's state is consistent. The fake agents and their
+// messages are removed afterwards.
+func (c *CLI) daemonStressTest(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	agentCount, err := stressIntFlag(flags, "agents", 10)
+	if err != nil {
+		return err
+	}
+	if agentCount < 2 {
+		return errors.InvalidUsage("--agents must be at least 2, so messages have a sender and a recipient")
+	}
+	messageCount, err := stressIntFlag(flags, "messages", 1000)
+	if err != nil {
+		return err
+	}
+	duration := 30 * time.Second
+	if value, ok := flags["duration"]; ok {
+		duration, err = time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			return errors.InvalidDuration(value)
+		}
+	}
+	skipTmux := flags["skip-tmux"] == "true"
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	repo, exists := st.GetRepo(repoName)
+	if !exists {
+		return errors.New(errors.CategoryNotFound, fmt.Sprintf("repository '%s' not found", repoName))
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	if _, err := client.Send(socket.Request{Command: "ping"}); err != nil {
+		return errors.DaemonCommunicationFailed("starting the stress test", err)
+	}
+
+	runID := strconv.FormatInt(time.Now().Unix(), 36)
+	agentNames := make([]string, agentCount)
+	for i := range agentNames {
+		agentNames[i] = fmt.Sprintf("stress-%s-%d", runID, i)
+	}
+
+	fmt.Printf("Stress testing the daemon in repo '%s': %d agents, up to %d messages, for up to %s\n", repoName, agentCount, messageCount, duration)
+	if skipTmux {
+		fmt.Println("--skip-tmux: agents get no tmux windows, so health checks and cleanup (which would remove them) are not triggered")
+	}
+
+	counters := &stressCounters{}
+	defer c.removeStressAgents(repoName, repo.TmuxSession, agentNames, skipTmux)
+
+	// Register the agents concurrently. With tmux each gets a window running
+	// cat, which swallows messages pasted into it.
+	var wg sync.WaitGroup
+	for _, name := range agentNames {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if !skipTmux {
+				cmd := exec.Command("tmux", "new-window", "-d", "-t", repo.TmuxSession, "-n", name, "cat >/dev/null")
+				if _, _, err := cmdrun.Run(cmd); err != nil {
+					counters.errors.Add(1)
+					return
+				}
+			}
+			counters.request(client, "add_agent", map[string]interface{}{
+				"repo":            repoName,
+				"agent":           name,
+				"type":            string(state.AgentTypeWorker),
+				"worktree_path":   c.paths.AgentWorktree(repoName, name),
+				"tmux_window":     name,
+				"task":            "stress test " + name,
+				"allow_duplicate": true,
+			})
+		}(name)
+	}
+	wg.Wait()
+
+	// Send messages between random pairs of agents while other goroutines
+	// route messages, run health checks and query the daemon
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	sentTo := make(map[string]int)
+	var sentMu sync.Mutex
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	started := time.Now()
+
+	var next atomic.Int64
+	var senders sync.WaitGroup
+	for i := 0; i < stressSenders; i++ {
+		senders.Add(1)
+		go func(seed int64) {
+			defer senders.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for ctx.Err() == nil && next.Add(1) <= int64(messageCount) {
+				from := agentNames[rng.Intn(agentCount)]
+				to := agentNames[rng.Intn(agentCount)]
+				for to == from {
+					to = agentNames[rng.Intn(agentCount)]
+				}
+				if _, err := msgMgr.Send(repoName, from, to, "stress test message from "+from); err != nil {
+					counters.errors.Add(1)
+					continue
+				}
+				counters.sent.Add(1)
+				sentMu.Lock()
+				sentTo[to]++
+				sentMu.Unlock()
+			}
+		}(time.Now().UnixNano() + int64(i))
+	}
+
+	background := []stressLoop{
+		{"route_messages", nil, 50 * time.Millisecond},
+		{"list_agents", map[string]interface{}{"repo": repoName, "rich": true}, 20 * time.Millisecond},
+		{"status", nil, 20 * time.Millisecond},
+	}
+	if !skipTmux {
+		background = append(background, stressLoop{"trigger_cleanup", nil, 250 * time.Millisecond})
+	}
+	sendersDone := make(chan struct{})
+	var loops sync.WaitGroup
+	for _, loop := range background {
+		loops.Add(1)
+		go func(loop stressLoop) {
+			defer loops.Done()
+			ticker := time.NewTicker(loop.interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-sendersDone:
+					return
+				case <-ticker.C:
+					counters.request(client, loop.command, loop.args)
+				}
+			}
+		}(loop)
+	}
+
+	senders.Wait()
+	close(sendersDone)
+	loops.Wait()
+	elapsed := time.Since(started)
+
+	violations := c.stressViolations(repoName, agentNames, sentTo, msgMgr)
+
+	sent := counters.sent.Load()
+	requests := counters.requests.Load()
+	failures := counters.errors.Load()
+	fmt.Println()
+	fmt.Printf("Messages sent:   %d in %s (%.1f/sec)\n", sent, elapsed.Round(time.Millisecond), float64(sent)/elapsed.Seconds())
+	fmt.Printf("Daemon requests: %d (%.1f/sec)\n", requests, float64(requests)/elapsed.Seconds())
+	errorRate := 0.0
+	if total := requests + sent; total > 0 {
+		errorRate = float64(failures) / float64(total) * 100
+	}
+	fmt.Printf("Errors:          %d (%.2f%%)\n", failures, errorRate)
+
+	if len(violations) > 0 {
+		fmt.Printf("\nInvariant violations (%d):\n", len(violations))
+		for _, v := range violations {
+			fmt.Printf("  ✗ %s\n", v)
+		}
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("stress test found %d invariant violation(s)", len(violations)))
+	}
+	fmt.Println("\n✓ No invariant violations: state.json parses, all agents are registered, every message is accounted for")
+	return nil
+}
+
+// stressViolations checks the daemon's state after a stress test: state.json
+// must parse, every fake agent must still be registered, and every message
+// sent must be in its recipient's directory, with none addressed elsewhere
+func (c *CLI) stressViolations(repoName string, agentNames []string, sentTo map[string]int, msgMgr *messages.Manager) []string {
+	var violations []string
+
+	st, err := state.Load(c.paths.StateFile)
+	if err != nil {
+		return append(violations, fmt.Sprintf("state.json does not parse: %v", err))
+	}
+	repo, exists := st.GetRepo(repoName)
+	if !exists {
+		return append(violations, fmt.Sprintf("repository '%s' is missing from state.json", repoName))
+	}
+
+	for _, name := range agentNames {
+		if _, registered := repo.Agents[name]; !registered {
+			violations = append(violations, fmt.Sprintf("agent %s is missing from state", name))
+		}
+
+		msgs, err := msgMgr.List(repoName, name)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("messages of %s cannot be read: %v", name, err))
+			continue
+		}
+		if len(msgs) != sentTo[name] {
+			violations = append(violations, fmt.Sprintf("agent %s has %d message(s), %d were sent to it", name, len(msgs), sentTo[name]))
+		}
+		if _, registered := repo.Agents[name]; !registered && len(msgs) > 0 {
+			violations = append(violations, fmt.Sprintf("%d orphaned message(s) for unregistered agent %s", len(msgs), name))
+		}
+	}
+	return violations
+}
+
+// removeStressAgents unregisters a stress test's fake agents and removes
+// their windows and messages. The messages go first, so that the daemon
+// has no unread messages to bounce to the other fake agents or the
+// supervisor when an agent is removed.
+func (c *CLI) removeStressAgents(repoName, tmuxSession string, agentNames []string, skipTmux bool) {
+	for _, name := range agentNames {
+		if err := os.RemoveAll(filepath.Join(c.paths.MessagesDir, repoName, name)); err != nil {
+			fmt.Printf("Warning: failed to remove messages of %s: %v\n", name, err)
+		}
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	tmuxClient := tmux.NewClient()
+	for _, name := range agentNames {
+		client.Send(socket.Request{
+			Command: "remove_agent",
+			Args:    map[string]interface{}{"repo": repoName, "agent": name},
+		})
+		if !skipTmux {
+			tmuxClient.KillWindow(context.Background(), tmuxSession, name)
+		}
+	}
+}
+
+// stressIntFlag reads a positive integer flag, or returns def when it is absent
+func stressIntFlag(flags map[string]string, name string, def int) (int, error) {
+	value, ok := flags[name]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, errors.InvalidUsage(fmt.Sprintf("--%s must be a positive number, got %q", name, value))
+	}
+	return n, nil
+}
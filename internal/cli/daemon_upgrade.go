@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/upgrade"
+)
+
+// latestReleaseURL is where check-upgrade looks for the latest release;
+// tests point it at a fake server
+var latestReleaseURL = upgrade.LatestReleaseURL
+
+// daemonCheckUpgrade checks GitHub for a newer multiclaude release and
+// saves the result for the status command, or with --check-interval sets
+// how often the daemon does so on its own
+func (c *CLI) daemonCheckUpgrade(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	if interval, ok := flags["check-interval"]; ok {
+		return c.setUpgradeCheckInterval(interval)
+	}
+
+	result, err := upgrade.Check(context.Background(), http.DefaultClient, latestReleaseURL, Version)
+	if err != nil {
+		return errors.Wrap(errors.CategoryConnection, "failed to check for a newer release", err).
+			WithSuggestion("check your network connection, or try again later if GitHub is rate limiting you")
+	}
+	if err := upgrade.WriteResult(c.paths.UpgradeCheckFile(), result); err != nil {
+		return err
+	}
+
+	if flags["json"] == "true" {
+		jsonData, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode upgrade check: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	fmt.Println(describeUpgradeCheck(result))
+	if result.Available {
+		fmt.Printf("Release notes: %s\n", result.ReleaseURL)
+	}
+	return nil
+}
+
+// setUpgradeCheckInterval tells the daemon how often to check for a newer
+// release
+func (c *CLI) setUpgradeCheckInterval(interval string) error {
+	if interval == "" || interval == "true" {
+		return errors.MissingArgument("--check-interval", "duration")
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil || d < 0 {
+		return errors.InvalidArgument("--check-interval", interval, "a duration such as 24h, or 0 to turn the check off")
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "set_upgrade_check_interval",
+		Args:    map[string]interface{}{"interval": interval},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("setting the upgrade check interval", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to set the upgrade check interval", fmt.Errorf("%s", resp.Error))
+	}
+
+	if d == 0 {
+		fmt.Println("✓ Turned off automatic upgrade checks")
+	} else {
+		fmt.Printf("✓ The daemon will check for a newer release every %s\n", d)
+	}
+	return nil
+}
+
+// describeUpgradeCheck summarizes a check's result in one line
+func describeUpgradeCheck(result *upgrade.Result) string {
+	switch {
+	case result.Available:
+		return fmt.Sprintf("multiclaude %s is available (running %s)", result.Latest, result.Current)
+	case result.Current == "dev":
+		return fmt.Sprintf("Running a development build; the latest release is %s", result.Latest)
+	default:
+		return fmt.Sprintf("multiclaude %s is up to date (latest release: %s)", result.Current, result.Latest)
+	}
+}
+
+// printUpgradeStatus prints the result of the last upgrade check, if any,
+// for daemon status
+func (c *CLI) printUpgradeStatus(statusMap map[string]interface{}) {
+	result, err := upgrade.ReadResult(c.paths.UpgradeCheckFile())
+	if err != nil || result == nil {
+		if every, _ := statusMap["upgrade_check_every"].(string); every != "" {
+			fmt.Printf("  Upgrade: not checked yet (every %s)\n", every)
+		}
+		return
+	}
+	line := fmt.Sprintf("  Upgrade: %s, checked %s", describeUpgradeCheck(result), result.CheckedAt.Format("2006-01-02 15:04"))
+	if every, _ := statusMap["upgrade_check_every"].(string); every != "" {
+		line += fmt.Sprintf(" (every %s)", every)
+	}
+	fmt.Println(line)
+	if result.Available {
+		fmt.Printf("    Release notes: %s\n", result.ReleaseURL)
+	}
+}
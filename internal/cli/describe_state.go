@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// treeNode is a line in a box-drawn tree along with its children
+type treeNode struct {
+	label    string
+	children []treeNode
+}
+
+// describeState prints the daemon's topology: repositories, their agents
+// grouped by type, and each agent's live status
+func (c *CLI) describeState(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	reqArgs := map[string]interface{}{}
+	if repo := flags["repo"]; repo != "" && repo != "true" {
+		reqArgs["repo"] = repo
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "describe_state",
+		Args:    reqArgs,
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("describing state", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to describe state", fmt.Errorf("%s", resp.Error))
+	}
+
+	if flags["json"] == "true" {
+		jsonData, err := json.MarshalIndent(resp.Data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode state: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	data, _ := resp.Data.(map[string]interface{})
+	daemonInfo, _ := data["daemon"].(map[string]interface{})
+	pid, _ := daemonInfo["pid"].(float64)
+
+	root := treeNode{label: fmt.Sprintf("multiclaude daemon (pid %d)", int(pid))}
+	repos, _ := data["repos"].([]interface{})
+	for _, r := range repos {
+		if repo, ok := r.(map[string]interface{}); ok {
+			root.children = append(root.children, describeRepoNode(repo))
+		}
+	}
+	if len(root.children) == 0 {
+		root.children = append(root.children, treeNode{label: "(no repositories)"})
+	}
+
+	fmt.Println(root.label)
+	printTree(root.children, "")
+	return nil
+}
+
+// describeRepoNode builds the tree for one repository from describe_state data
+func describeRepoNode(repo map[string]interface{}) treeNode {
+	name, _ := repo["name"].(string)
+	session, _ := repo["tmux_session"].(string)
+	health := "session missing"
+	if healthy, _ := repo["session_healthy"].(bool); healthy {
+		health = "session healthy"
+	}
+	node := treeNode{label: fmt.Sprintf("%s  [tmux %s, %s]", name, session, health)}
+
+	groups, _ := repo["agent_types"].([]interface{})
+	for _, g := range groups {
+		group, ok := g.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		agentType, _ := group["type"].(string)
+		agents, _ := group["agents"].([]interface{})
+
+		groupNode := treeNode{label: fmt.Sprintf("%s (%d)", agentType, len(agents))}
+		for _, a := range agents {
+			if agent, ok := a.(map[string]interface{}); ok {
+				groupNode.children = append(groupNode.children, treeNode{label: describeAgentLine(agent)})
+			}
+		}
+		node.children = append(node.children, groupNode)
+	}
+	if len(node.children) == 0 {
+		node.children = append(node.children, treeNode{label: "(no agents)"})
+	}
+	return node
+}
+
+// describeAgentLine summarises one agent from describe_state data
+func describeAgentLine(agent map[string]interface{}) string {
+	name, _ := agent["name"].(string)
+	status, _ := agent["status"].(string)
+	branch, _ := agent["branch"].(string)
+	target, _ := agent["tmux_target"].(string)
+	pid, _ := agent["pid"].(float64)
+	pidAlive, _ := agent["pid_alive"].(bool)
+	pending, _ := agent["messages_pending"].(float64)
+
+	if branch == "" {
+		branch = "-"
+	}
+	pidStr := "pid -"
+	if pid > 0 {
+		liveness := "dead"
+		if pidAlive {
+			liveness = "alive"
+		}
+		pidStr = fmt.Sprintf("pid %d (%s)", int(pid), liveness)
+	}
+
+	parts := []string{
+		name,
+		status,
+		"branch " + branch,
+		pidStr,
+		fmt.Sprintf("%d pending", int(pending)),
+		target,
+	}
+	return strings.Join(parts, "  ")
+}
+
+// printTree prints nodes with box-drawing connectors, each level indented
+// beneath the last
+func printTree(nodes []treeNode, prefix string) {
+	for i, node := range nodes {
+		connector, childPrefix := "├── ", "│   "
+		if i == len(nodes)-1 {
+			connector, childPrefix = "└── ", "    "
+		}
+		fmt.Println(prefix + connector + node.label)
+		printTree(node.children, prefix+childPrefix)
+	}
+}
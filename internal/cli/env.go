@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/envfile"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/redact"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// repoEnv loads the environment configured for a repository from
+// <repo>/.multiclaude/env and the repo's --env-file (if any)
+func (c *CLI) repoEnv(repoName string) (map[string]string, error) {
+	envFile := ""
+	if st, err := c.loadState(); err == nil {
+		if repo, exists := st.GetRepo(repoName); exists {
+			envFile = repo.EnvFile
+		}
+	}
+	return envfile.ForRepo(c.paths.RepoDir(repoName), envFile)
+}
+
+// applyRepoEnv sets the repository's environment on its tmux session and
+// returns the variable names that were set. Values are passed to tmux
+// set-environment directly and are never typed into a pane.
+func (c *CLI) applyRepoEnv(repoName, tmuxSession string) ([]string, error) {
+	env, err := c.repoEnv(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	tmuxClient := tmux.NewClient()
+	keys := envfile.Keys(env)
+	for _, key := range keys {
+		if err := tmuxClient.SetEnvironment(context.Background(), tmuxSession, key, env[key]); err != nil {
+			return nil, fmt.Errorf("failed to set %s in tmux session: %w", key, err)
+		}
+	}
+	return keys, nil
+}
+
+// secretsRedactor returns a redactor primed with the env values of the given
+// repositories and of their agents, or nil if none of them have any
+func (c *CLI) secretsRedactor(repoNames ...string) *redact.Redactor {
+	st, _ := c.loadState()
+
+	var r *redact.Redactor
+	for _, repoName := range repoNames {
+		var values []string
+		if env, err := c.repoEnv(repoName); err == nil {
+			for _, v := range env {
+				values = append(values, v)
+			}
+		}
+		if st != nil {
+			if repo, exists := st.GetRepo(repoName); exists {
+				for _, agent := range repo.Agents {
+					for _, v := range agent.Environment {
+						values = append(values, v)
+					}
+				}
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if r == nil {
+			r = redact.New()
+		}
+		r.AddSecrets(values...)
+	}
+	return r
+}
+
+// runRedacted runs cmd with its stdout filtered line by line through the
+// redactor. With a nil redactor the output is passed through unchanged.
+func runRedacted(cmd *exec.Cmd, r *redact.Redactor) error {
+	cmd.Stderr = os.Stderr
+	if r == nil {
+		cmd.Stdout = os.Stdout
+		return cmd.Run()
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fmt.Println(r.Secrets(scanner.Text()))
+	}
+	return cmd.Wait()
+}
+
+// showRepoEnv lists the names (never the values) of the variables injected
+// into a repository's agents
+func (c *CLI) showRepoEnv(repoName string) error {
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	repo, exists := st.GetRepo(repoName)
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	env, err := envfile.ForRepo(c.paths.RepoDir(repoName), repo.EnvFile)
+	if err != nil {
+		return fmt.Errorf("failed to load env for %s: %w", repoName, err)
+	}
+
+	fmt.Printf("Environment for repository: %s\n\n", repoName)
+	fmt.Printf("Sources:\n")
+	fmt.Printf("  %s\n", envfile.RepoEnvPath(c.paths.RepoDir(repoName)))
+	if repo.EnvFile != "" {
+		fmt.Printf("  %s\n", repo.EnvFile)
+	}
+
+	if len(env) == 0 {
+		fmt.Println("\nNo variables configured")
+		return nil
+	}
+
+	fmt.Println("\nVariables:")
+	for _, key := range envfile.Keys(env) {
+		fmt.Printf("  %s\n", key)
+	}
+	return nil
+}
+
+// setAgentEnv sets environment variables for an agent and restarts it so the
+// new values take effect, e.g. after refreshing an auth token. Values are
+// handed to the daemon, which sets them on the repo's tmux session; they are
+// never printed or typed into a pane.
+func (c *CLI) setAgentEnv(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 2 {
+		return errors.InvalidUsage("usage: multiclaude agent set-env <name> KEY=VALUE [KEY=VALUE...] [--repo <repo>]")
+	}
+	agentName := posArgs[0]
+
+	env := make(map[string]interface{}, len(posArgs)-1)
+	names := make([]string, 0, len(posArgs)-1)
+	for _, assignment := range posArgs[1:] {
+		key, value, ok := strings.Cut(assignment, "=")
+		if !ok || !envfile.ValidKey(key) {
+			return errors.InvalidUsage(fmt.Sprintf("invalid assignment %q: expected KEY=VALUE", key))
+		}
+		if _, dup := env[key]; !dup {
+			names = append(names, key)
+		}
+		env[key] = value
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	fmt.Printf("Setting %s for agent '%s' and restarting it...\n", strings.Join(names, ", "), agentName)
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "set_agent_env",
+		Args: map[string]interface{}{
+			"repo":  repoName,
+			"agent": agentName,
+			"env":   env,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("setting agent environment", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to set agent environment", fmt.Errorf("%s", resp.Error))
+	}
+
+	if data, ok := resp.Data.(map[string]interface{}); ok {
+		if pid, ok := data["pid"].(float64); ok {
+			fmt.Printf("✓ Agent '%s' restarted with the new environment (PID: %d)\n", agentName, int(pid))
+			return nil
+		}
+	}
+	fmt.Printf("✓ Agent '%s' restarted with the new environment\n", agentName)
+	return nil
+}
@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+)
+
+// FlagSpec describes a flag a command accepts, for its help and the
+// generated docs and for rejecting flags it does not know
+type FlagSpec struct {
+	Name        string // Long name without dashes; a trailing "<placeholder>" stands for a family, e.g. "transport-<agent-type>"
+	Shorthand   string // Single-letter name without the dash; a flag may have only this
+	Type        string // "bool" for switches, otherwise the kind of value: "string", "int", "duration", ...
+	Default     string // Value used when the flag is absent; empty when there is none
+	Description string
+}
+
+// Flags shared by many commands
+var (
+	repoFlag = FlagSpec{Name: "repo", Type: "string",
+		Description: "Repository, if not the current one"}
+	groupFlag = FlagSpec{Name: "group", Type: "string",
+		Description: "Act on every repository in a group (see `group create`)"}
+	yesFlag = FlagSpec{Name: "yes", Shorthand: "y", Type: "bool",
+		Description: "Skip the confirmation prompt"}
+	contextFileFlag = FlagSpec{Name: "context-file", Type: "path",
+		Description: "File to copy into the worktree for the agent; repeatable"}
+	contextFlag = FlagSpec{Name: "context", Type: "string",
+		Description: "Pass - to read a context file from stdin"}
+	noSubmodulesFlag = FlagSpec{Name: "no-submodules", Type: "bool",
+		Description: "Skip initializing git submodules in the new worktree"}
+)
+
+// matches reports whether name, as ParseFlags stores it, is this flag. The
+// parser ignores how many dashes a flag has, so neither does this.
+func (f FlagSpec) matches(name string) bool {
+	if (f.Name != "" && name == f.Name) || (f.Shorthand != "" && name == f.Shorthand) {
+		return true
+	}
+	if i := strings.Index(f.Name, "<"); i > 0 {
+		return strings.HasPrefix(name, f.Name[:i]) && len(name) > i
+	}
+	return false
+}
+
+// usage returns how the flag is written, e.g. "-y, --yes" or "--repo <string>"
+func (f FlagSpec) usage() string {
+	s := strings.Join(f.names(), ", ")
+	if f.Type != "" && f.Type != "bool" {
+		s += " <" + f.Type + ">"
+	}
+	return s
+}
+
+// names returns the flag's forms with their dashes, shorthand first
+func (f FlagSpec) names() []string {
+	var names []string
+	if f.Shorthand != "" {
+		names = append(names, "-"+f.Shorthand)
+	}
+	if f.Name != "" {
+		names = append(names, "--"+f.Name)
+	}
+	return names
+}
+
+// writeFlagsHelp prints a command's flags for --help, one per line, with
+// long names lined up whether or not a flag has a shorthand
+func writeFlagsHelp(sb *strings.Builder, flags []FlagSpec) {
+	hasShorthand := false
+	for _, f := range flags {
+		hasShorthand = hasShorthand || f.Shorthand != ""
+	}
+	label := func(f FlagSpec) string {
+		if hasShorthand && f.Shorthand == "" {
+			return "    " + f.usage()
+		}
+		return f.usage()
+	}
+	width := 0
+	for _, f := range flags {
+		width = max(width, len(label(f)))
+	}
+	for _, f := range flags {
+		desc := f.Description
+		if f.Default != "" {
+			desc += fmt.Sprintf(" (default %s)", f.Default)
+		}
+		fmt.Fprintf(sb, "  %-*s  %s\n", width, label(f), desc)
+	}
+}
+
+// writeFlagsTable writes a command's flags as a markdown table for the
+// generated docs. The Default column is left out when no flag has one, to
+// keep agents' prompts short.
+func writeFlagsTable(sb *strings.Builder, flags []FlagSpec) {
+	cell := strings.NewReplacer("|", `\|`, "\n", " ")
+	hasDefault := false
+	for _, f := range flags {
+		hasDefault = hasDefault || f.Default != ""
+	}
+	sb.WriteString("**Flags:**\n\n")
+	if hasDefault {
+		sb.WriteString("| Flag | Type | Default | Description |\n|---|---|---|---|\n")
+	} else {
+		sb.WriteString("| Flag | Type | Description |\n|---|---|---|\n")
+	}
+	for _, f := range flags {
+		row := []string{"`" + strings.Join(f.names(), "`, `") + "`", f.Type}
+		if hasDefault {
+			def := ""
+			if f.Default != "" {
+				def = "`" + cell.Replace(f.Default) + "`"
+			}
+			row = append(row, def)
+		}
+		row = append(row, cell.Replace(f.Description))
+		fmt.Fprintf(sb, "| %s |\n", strings.Join(row, " | "))
+	}
+	sb.WriteString("\n")
+}
+
+// checkFlags rejects flags in args that cmd, run as path, does not declare,
+// suggesting the declared flag closest to a misspelled one. It reads args
+// the way ParseFlags does, so a lone "-" (stdin) is not a flag and --help is
+// always accepted.
+func checkFlags(cmd *Command, path string, args []string) error {
+	if cmd.FreeformArgs {
+		return nil
+	}
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			continue
+		}
+		given, _, _ := strings.Cut(arg, "=")
+		name := strings.TrimLeft(given, "-")
+		if name == "" || name == "help" || name == "h" {
+			continue
+		}
+		known := false
+		for _, f := range cmd.Flags {
+			if f.matches(name) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			didYouMean := ""
+			if suggestion := suggestFlag(name, cmd.Flags); suggestion != "" {
+				didYouMean = "--" + suggestion
+			}
+			return errors.UnknownFlag(given, didYouMean, path)
+		}
+	}
+	return nil
+}
+
+// suggestFlag returns the declared flag name closest to name, or "" when
+// none is close: within two edits (one for two-letter names), or one name
+// a prefix of the other (--repository for --repo)
+func suggestFlag(name string, flags []FlagSpec) string {
+	best, bestDist := "", -1
+	for _, f := range flags {
+		if f.Name == "" || strings.Contains(f.Name, "<") {
+			continue
+		}
+		dist := editDistance(name, f.Name)
+		near := dist <= min(2, len(name)-1) ||
+			(min(len(name), len(f.Name)) >= 3 && (strings.HasPrefix(name, f.Name) || strings.HasPrefix(f.Name, name)))
+		if near && (bestDist == -1 || dist < bestDist) {
+			best, bestDist = f.Name, dist
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// walkCommands calls fn for every command under cmd, with its path
+func walkCommands(cmd *Command, path string, fn func(cmd *Command, path string)) {
+	for name, sub := range cmd.Subcommands {
+		subPath := path + " " + name
+		fn(sub, subPath)
+		walkCommands(sub, subPath, fn)
+	}
+}
+
+func TestCommandFlagsDocumented(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	docs := cli.GenerateDocumentation()
+	usageFlag := regexp.MustCompile(`(?:^|[\s\[|])--?([a-z][a-z0-9-]*(?:<[a-z-]+>)?)`)
+
+	walkCommands(cli.rootCmd, "multiclaude", func(cmd *Command, path string) {
+		// Every flag in the usage line is declared
+		for _, m := range usageFlag.FindAllStringSubmatch(cmd.Usage, -1) {
+			declared := false
+			for _, f := range cmd.Flags {
+				declared = declared || f.matches(m[1])
+			}
+			if !declared {
+				t.Errorf("%s: usage mentions %s but it is not in Flags", path, m[0])
+			}
+		}
+
+		if strings.HasPrefix(cmd.Name, "_") || len(cmd.Flags) == 0 {
+			return
+		}
+
+		// ... and every declared flag is in the docs and the help
+		help := captureStdout(t, func() { cli.showCommandHelp(cmd) })
+		for _, f := range cmd.Flags {
+			if f.Description == "" || f.Type == "" {
+				t.Errorf("%s: flag %v needs a type and description", path, f.names())
+			}
+			for _, name := range f.names() {
+				if !strings.Contains(docs, "`"+name+"`") {
+					t.Errorf("%s: docs do not list %s", path, name)
+				}
+			}
+			if !strings.Contains(help, f.usage()) {
+				t.Errorf("%s --help does not list %s:\n%s", path, f.usage(), help)
+			}
+		}
+	})
+
+	if !strings.Contains(docs, "| Flag | Type | Default | Description |") || !strings.Contains(docs, "| `-y`, `--yes` | bool |") {
+		t.Error("docs should render flags as a table")
+	}
+}
+
+func TestCheckFlags(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"work", "list", "--repository", "r"}, "unknown flag --repository, did you mean --repo?"},
+		{[]string{"work", "merge-into-workspace", "a", "b", "--sqash"}, "unknown flag --sqash, did you mean --squash?"},
+		{[]string{"workspace", "create-pr", "ws", "--titel=x"}, "unknown flag --titel, did you mean --title?"},
+		{[]string{"history", "--bogus"}, "unknown flag --bogus"},
+		{[]string{"history", "-x"}, "unknown flag -x"},
+		{[]string{"daemon", "status", "--json"}, "unknown flag --json"},
+		{[]string{"work", "list", "--repo=r", "--sort-by", "name"}, ""},
+		{[]string{"history", "-n", "5", "--full"}, ""},
+		{[]string{"config", "r", "--transport-worker=inbox"}, ""},
+		{[]string{"work", "a task", "--context", "-", "--yes"}, "unknown flag --yes"},
+		{[]string{"work", "a task", "--context", "-"}, ""},
+		{[]string{"agent", "send-message", "supervisor", "use", "--repository", "here"}, ""},
+		{[]string{"work", "list", "--help"}, ""},
+	}
+	for _, tt := range tests {
+		cmd, path, args := cli.rootCmd, "multiclaude", tt.args
+		for len(args) > 0 && cmd.Subcommands[args[0]] != nil {
+			cmd, path, args = cmd.Subcommands[args[0]], path+" "+args[0], args[1:]
+		}
+		err := checkFlags(cmd, path, args)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("checkFlags(%v) = %v, want nil", tt.args, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("checkFlags(%v) = %v, want %q", tt.args, err, tt.wantErr)
+		}
+		if err != nil && strings.HasSuffix(tt.wantErr, "--bogus") && strings.Contains(err.Error(), "did you mean") {
+			t.Errorf("checkFlags(%v) = %v, want no suggestion", tt.args, err)
+		}
+	}
+
+	// Unknown flags are rejected before the command runs
+	err := cli.Execute([]string{"work", "list", "--repository", "r"})
+	if err == nil || !strings.Contains(err.Error(), "did you mean --repo?") {
+		t.Errorf("work list --repository: error = %v, want a suggestion of --repo", err)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"repo", "repo", 0},
+		{"repo", "rep", 1},
+		{"sqash", "squash", 1},
+		{"titel", "title", 2},
+		{"repository", "repo", 6},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"context"
+	goerrors "errors"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/gh"
+)
+
+// requireGH checks that gh is installed and logged in, for commands that
+// cannot do anything useful without it. They call it before doing any
+// other work, so that a missing gh does not leave a job half done.
+func requireGH() error {
+	return ghError(gh.Check(context.Background()))
+}
+
+// ghUnavailable reports whether err means gh is not installed or not
+// logged in
+func ghUnavailable(err error) bool {
+	return goerrors.Is(err, gh.ErrGHUnavailable)
+}
+
+// ghError turns gh being unavailable into an error saying how to install it
+// or log in, and passes other errors through
+func ghError(err error) error {
+	var unavailable *gh.UnavailableError
+	if !goerrors.As(err, &unavailable) {
+		return err
+	}
+	if unavailable.NotInstalled {
+		return errors.GhNotInstalled(unavailable)
+	}
+	return errors.GHNotAuthenticated(unavailable)
+}
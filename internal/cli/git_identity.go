@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// applyGitIdentity sets the repository's committer identity and commit
+// signing in a worktree just created for an agent. A failure is reported but
+// does not stop the agent from starting.
+func (c *CLI) applyGitIdentity(repoName, wtPath string) {
+	st, err := c.loadState()
+	if err != nil {
+		fmt.Printf("Warning: failed to apply git identity: %v\n", err)
+		return
+	}
+	repo, exists := st.GetRepo(repoName)
+	if !exists {
+		return
+	}
+	if err := worktree.SetWorktreeConfig(wtPath, repo.GitIdentity.GitConfig()); err != nil {
+		fmt.Printf("Warning: failed to apply git identity: %v\n", err)
+	}
+}
+
+// applyGitConfig applies the repository's git identity to the worktrees of
+// its agents, for worktrees created before the identity last changed
+func (c *CLI) applyGitConfig(repoName string) error {
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	repo, exists := st.GetRepo(repoName)
+	if !exists {
+		return errors.New(errors.CategoryNotFound, fmt.Sprintf("repository '%s' not found", repoName)).
+			WithSuggestion("multiclaude list")
+	}
+
+	paths := gitIdentityWorktrees(c.paths.RepoDir(repoName), repo)
+	if len(paths) == 0 {
+		fmt.Println("No agent worktrees to update")
+		return nil
+	}
+	values := repo.GitIdentity.GitConfig()
+	failed := 0
+	for _, path := range paths {
+		if err := worktree.SetWorktreeConfig(path, values); err != nil {
+			fmt.Printf("Warning: failed to update %s: %v\n", path, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("failed to apply the git identity to %d of %d worktree(s)", failed, len(paths)))
+	}
+	fmt.Printf("✓ Applied the git identity to %d worktree(s)\n", len(paths))
+	return nil
+}
+
+// gitIdentityWorktrees returns the existing worktrees of a repository's
+// agents, sorted. The main checkout is left out: agents working there do not
+// get worktree-specific config.
+func gitIdentityWorktrees(repoPath string, repo *state.Repository) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, agent := range repo.Agents {
+		path := agent.WorktreePath
+		if path == "" || path == repoPath || seen[path] {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// sendGroupRequest sends a group command to the daemon with the group's
+// name and, when given, repositories
+func (c *CLI) sendGroupRequest(command, action, group string, repos []string) error {
+	args := map[string]interface{}{"group": group}
+	if repos != nil {
+		list := make([]interface{}, len(repos))
+		for i, repo := range repos {
+			list[i] = repo
+		}
+		args["repos"] = list
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{Command: command, Args: args})
+	if err != nil {
+		return errors.DaemonCommunicationFailed(action, err)
+	}
+	if !resp.Success {
+		if strings.Contains(resp.Error, fmt.Sprintf("group %q not found", group)) {
+			return errors.GroupNotFound(group)
+		}
+		return errors.Wrap(errors.CategoryRuntime, "failed "+action, fmt.Errorf("%s", resp.Error))
+	}
+	return nil
+}
+
+// createGroup creates a named group of tracked repositories
+func (c *CLI) createGroup(args []string) error {
+	_, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude group create <name> [<repo>...]")
+	}
+	name, repos := posArgs[0], posArgs[1:]
+
+	if err := c.sendGroupRequest("create_group", "creating group", name, repos); err != nil {
+		return err
+	}
+	if len(repos) == 0 {
+		fmt.Printf("✓ Created group '%s' with no repositories\n", name)
+	} else {
+		fmt.Printf("✓ Created group '%s': %s\n", name, strings.Join(repos, ", "))
+	}
+	return nil
+}
+
+// addToGroup adds repositories to a group
+func (c *CLI) addToGroup(args []string) error {
+	_, posArgs := ParseFlags(args)
+	if len(posArgs) < 2 {
+		return errors.InvalidUsage("usage: multiclaude group add <name> <repo>...")
+	}
+	name, repos := posArgs[0], posArgs[1:]
+
+	if err := c.sendGroupRequest("add_to_group", "adding to group", name, repos); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Added %s to group '%s'\n", strings.Join(repos, ", "), name)
+	return nil
+}
+
+// removeFromGroup removes repositories from a group
+func (c *CLI) removeFromGroup(args []string) error {
+	_, posArgs := ParseFlags(args)
+	if len(posArgs) < 2 {
+		return errors.InvalidUsage("usage: multiclaude group remove <name> <repo>...")
+	}
+	name, repos := posArgs[0], posArgs[1:]
+
+	if err := c.sendGroupRequest("remove_from_group", "removing from group", name, repos); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Removed %s from group '%s'\n", strings.Join(repos, ", "), name)
+	return nil
+}
+
+// deleteGroup deletes a group; its repositories are not touched
+func (c *CLI) deleteGroup(args []string) error {
+	_, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude group delete <name>")
+	}
+	name := posArgs[0]
+
+	if err := c.sendGroupRequest("delete_group", "deleting group", name, nil); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Deleted group '%s'\n", name)
+	return nil
+}
+
+// listGroups lists the groups and their repositories
+func (c *CLI) listGroups(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	groups, err := c.fetchGroups()
+	if err != nil {
+		return err
+	}
+
+	if flags["json"] == "true" {
+		jsonData, err := json.MarshalIndent(groups, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode groups: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	if len(groups) == 0 {
+		fmt.Println("No groups")
+		format.Dimmed("\nCreate one with: multiclaude group create <name> <repo>...")
+		return nil
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	format.Header("Groups (%d):", len(groups))
+	fmt.Println()
+	table := format.NewTable("GROUP", "REPOS")
+	for _, name := range names {
+		repos := strings.Join(groups[name], ", ")
+		if repos == "" {
+			repos = "-"
+		}
+		table.AddRow(name, repos)
+	}
+	fmt.Print(table.String())
+	return nil
+}
+
+// fetchGroups returns every group and its repositories from the daemon
+func (c *CLI) fetchGroups() (map[string][]string, error) {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{Command: "list_groups"})
+	if err != nil {
+		return nil, errors.DaemonCommunicationFailed("listing groups", err)
+	}
+	if !resp.Success {
+		return nil, errors.Wrap(errors.CategoryRuntime, "failed to list groups", fmt.Errorf("%s", resp.Error))
+	}
+
+	data, _ := resp.Data.(map[string]interface{})
+	groups := make(map[string][]string, len(data))
+	for name, members := range data {
+		list, _ := members.([]interface{})
+		repos := make([]string, 0, len(list))
+		for _, m := range list {
+			if repo, ok := m.(string); ok {
+				repos = append(repos, repo)
+			}
+		}
+		groups[name] = repos
+	}
+	return groups, nil
+}
+
+// groupRepos returns the name and repositories of the group named by
+// --group. The name is empty when the flag is not given.
+func (c *CLI) groupRepos(flags map[string]string) (string, []string, error) {
+	name, ok := flags["group"]
+	if !ok {
+		return "", nil, nil
+	}
+	if name == "" || name == "true" {
+		return "", nil, errors.MissingArgument("--group", "group name")
+	}
+	if _, hasRepo := flags["repo"]; hasRepo {
+		return "", nil, errors.InvalidUsage("--group and --repo cannot be used together")
+	}
+
+	groups, err := c.fetchGroups()
+	if err != nil {
+		return "", nil, err
+	}
+	repos, exists := groups[name]
+	if !exists {
+		return "", nil, errors.GroupNotFound(name)
+	}
+	return name, repos, nil
+}
+
+// filterGroupRepos keeps the entries of a rich list_repos response that
+// belong to a group
+func filterGroupRepos(repos []interface{}, members []string) []interface{} {
+	inGroup := make(map[string]bool, len(members))
+	for _, member := range members {
+		inGroup[member] = true
+	}
+	kept := make([]interface{}, 0, len(members))
+	for _, repo := range repos {
+		if repoMap, ok := repo.(map[string]interface{}); ok {
+			if name, _ := repoMap["name"].(string); inGroup[name] {
+				kept = append(kept, repo)
+			}
+		}
+	}
+	return kept
+}
+
+// forEachGroupRepo runs list for each repository of a group in turn,
+// separating their sections with a blank line. A repository that fails is
+// reported as a warning and the others are still listed.
+func (c *CLI) forEachGroupRepo(groupName string, members []string, list func(repoName string) error) error {
+	if len(members) == 0 {
+		fmt.Printf("No repositories in group '%s'\n", groupName)
+		format.Dimmed("\nAdd some with: multiclaude group add %s <repo>...", groupName)
+		return nil
+	}
+	for i, repoName := range members {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := list(repoName); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to list %s: %v\n", repoName, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,575 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/procs"
+	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/claude"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+const (
+	// healthMaxDivergence is the number of commits a branch may diverge from
+	// the main checkout before it is reported as unhealthy
+	healthMaxDivergence = 1000
+	// healthMinFreeDiskPercent is the minimum free disk space (in percent)
+	// required under the multiclaude root
+	healthMinFreeDiskPercent = 10.0
+	// healthMaxPendingMessages is the number of pending messages an agent may
+	// have queued before its inbox is considered backlogged
+	healthMaxPendingMessages = 100
+)
+
+// healthCheck is the result of a single repo health check
+type healthCheck struct {
+	Key     string // Stable identifier used to pick a repair action
+	Name    string
+	OK      bool
+	Details []string
+	// Fixable is true when `repo health --fix` knows how to repair a failure
+	Fixable bool
+}
+
+// repoHealth runs a comprehensive health check of a repository
+func (c *CLI) repoHealth(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	fix := flags["fix"] == "true"
+
+	var repoName string
+	if len(posArgs) > 0 {
+		repoName = posArgs[0]
+	} else {
+		resolved, err := c.resolveRepo(flags)
+		if err != nil {
+			return errors.NotInRepo()
+		}
+		repoName = resolved
+	}
+
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	repo, exists := st.GetRepo(repoName)
+	if !exists {
+		return errors.New(errors.CategoryNotFound, fmt.Sprintf("repository '%s' not found", repoName)).
+			WithSuggestion("multiclaude list")
+	}
+
+	checks := c.checkRepoHealth(repoName, repo)
+	printHealthChecks(repoName, checks)
+
+	failed := 0
+	fixable := 0
+	for _, check := range checks {
+		if !check.OK {
+			failed++
+			if check.Fixable {
+				fixable++
+			}
+		}
+	}
+
+	if failed == 0 {
+		fmt.Println("\n✓ All checks passed")
+		return nil
+	}
+
+	if !fix {
+		if fixable > 0 {
+			format.Dimmed("\n%d issue(s) can be repaired automatically: multiclaude repo health %s --fix", fixable, repoName)
+		}
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("%d health check(s) failed for repo '%s'", failed, repoName))
+	}
+
+	fmt.Println("\nRepairing fixable issues...")
+	if err := c.fixRepoHealth(repoName, repo, checks); err != nil {
+		return err
+	}
+
+	// Re-run the checks so the user sees what is still broken
+	st, err = c.loadState()
+	if err != nil {
+		return err
+	}
+	repo, exists = st.GetRepo(repoName)
+	if !exists {
+		return errors.New(errors.CategoryNotFound, fmt.Sprintf("repository '%s' not found", repoName))
+	}
+	fmt.Println()
+	checks = c.checkRepoHealth(repoName, repo)
+	printHealthChecks(repoName, checks)
+
+	for _, check := range checks {
+		if !check.OK {
+			return errors.New(errors.CategoryRuntime, fmt.Sprintf("repo '%s' still has failing health checks after repair", repoName))
+		}
+	}
+	fmt.Println("\n✓ All checks passed")
+	return nil
+}
+
+// printHealthChecks prints one checkmark or cross per health check
+func printHealthChecks(repoName string, checks []healthCheck) {
+	format.Header("Health of %s:", repoName)
+	for _, check := range checks {
+		if check.OK {
+			fmt.Printf("  %s %s\n", format.Green.Sprint("✓"), check.Name)
+			continue
+		}
+		fmt.Printf("  %s %s\n", format.Red.Sprint("✗"), check.Name)
+		for _, detail := range check.Details {
+			format.Dimmed("      %s", detail)
+		}
+	}
+}
+
+// checkRepoHealth runs every health check for a repository
+func (c *CLI) checkRepoHealth(repoName string, repo *state.Repository) []healthCheck {
+	ctx := context.Background()
+	tmuxClient := tmux.NewClient()
+	repoPath := c.paths.RepoDir(repoName)
+
+	agentNames := make([]string, 0, len(repo.Agents))
+	for name := range repo.Agents {
+		agentNames = append(agentNames, name)
+	}
+	sort.Strings(agentNames)
+
+	var checks []healthCheck
+
+	// 1. tmux session exists
+	hasSession, _ := tmuxClient.HasSession(ctx, repo.TmuxSession)
+	session := healthCheck{Key: "session", Name: fmt.Sprintf("tmux session %s exists", repo.TmuxSession), OK: hasSession}
+	if !hasSession {
+		session.Details = append(session.Details, "session not found")
+	}
+	checks = append(checks, session)
+
+	// 2. All registered windows exist
+	windows := healthCheck{Key: "windows", Name: "all agent windows exist", OK: true, Fixable: true}
+	for _, name := range agentNames {
+		agent := repo.Agents[name]
+		if !hasSession {
+			windows.OK = false
+			windows.Details = append(windows.Details, fmt.Sprintf("%s: window %s unreachable (no session)", name, agent.TmuxWindow))
+			continue
+		}
+		if ok, _ := tmuxClient.HasWindow(ctx, repo.TmuxSession, agent.WindowTarget()); !ok {
+			windows.OK = false
+			windows.Details = append(windows.Details, fmt.Sprintf("%s: window %s not found", name, agent.TmuxWindow))
+		}
+	}
+	checks = append(checks, windows)
+
+	// 3. All Claude processes are alive
+	processes := healthCheck{Key: "processes", Name: "all Claude processes are alive", OK: true, Fixable: true}
+	for _, name := range agentNames {
+		agent := repo.Agents[name]
+		if agent.PID <= 0 {
+			continue
+		}
+		if !procs.IsAlive(agent.PID) {
+			processes.OK = false
+			processes.Details = append(processes.Details, fmt.Sprintf("%s: process %d is not running", name, agent.PID))
+		}
+	}
+	checks = append(checks, processes)
+
+	// 4. All worktrees are valid git worktrees
+	worktrees := healthCheck{Key: "worktrees", Name: "all worktrees are valid git worktrees", OK: true, Fixable: true}
+	wt := worktree.NewManager(repoPath)
+	for _, name := range agentNames {
+		agent := repo.Agents[name]
+		if agent.WorktreePath == "" || agent.WorktreePath == repoPath {
+			continue
+		}
+		if _, err := os.Stat(agent.WorktreePath); os.IsNotExist(err) {
+			worktrees.OK = false
+			worktrees.Details = append(worktrees.Details, fmt.Sprintf("%s: %s does not exist", name, agent.WorktreePath))
+			continue
+		}
+		if ok, err := wt.Exists(agent.WorktreePath); err != nil || !ok {
+			worktrees.OK = false
+			worktrees.Details = append(worktrees.Details, fmt.Sprintf("%s: %s is not listed by git worktree list", name, agent.WorktreePath))
+		}
+	}
+	checks = append(checks, worktrees)
+
+	// 5. All prompt files exist
+	promptFiles := healthCheck{Key: "prompts", Name: "all prompt files exist", OK: true, Fixable: true}
+	for _, name := range agentNames {
+		promptFile := filepath.Join(c.paths.Root, "prompts", name+".md")
+		if !promptFileUsable(promptFile) {
+			promptFiles.OK = false
+			promptFiles.Details = append(promptFiles.Details, fmt.Sprintf("%s: %s missing or empty", name, promptFile))
+		}
+	}
+	checks = append(checks, promptFiles)
+
+	// 6. Main repo is not on a detached HEAD
+	head := healthCheck{Key: "head", Name: "main repository HEAD is attached", OK: true}
+	branch, err := worktree.GetCurrentBranch(repoPath)
+	if err != nil {
+		head.OK = false
+		head.Details = append(head.Details, err.Error())
+	} else if branch == "HEAD" {
+		head.OK = false
+		head.Details = append(head.Details, fmt.Sprintf("%s is in detached HEAD state", repoPath))
+	}
+	checks = append(checks, head)
+
+	// 7. No branch has diverged too far from the main checkout
+	divergence := healthCheck{Key: "divergence", Name: fmt.Sprintf("no branch diverged by more than %d commits", healthMaxDivergence), OK: true}
+	for _, name := range agentNames {
+		agent := repo.Agents[name]
+		if agent.WorktreePath == "" || agent.WorktreePath == repoPath {
+			continue
+		}
+		agentBranch, err := worktree.GetCurrentBranch(agent.WorktreePath)
+		if err != nil || agentBranch == "HEAD" {
+			continue
+		}
+		count, err := branchDivergence(repoPath, agentBranch)
+		if err != nil {
+			continue
+		}
+		if count > healthMaxDivergence {
+			divergence.OK = false
+			divergence.Details = append(divergence.Details, fmt.Sprintf("%s: branch %s diverged by %d commits", name, agentBranch, count))
+		}
+	}
+	checks = append(checks, divergence)
+
+	// 8. Disk space above threshold
+	disk := healthCheck{Key: "disk", Name: fmt.Sprintf("disk space above %.0f%% free", healthMinFreeDiskPercent), OK: true}
+	// The XDG layout keeps logs apart from the state, possibly on another disk
+	diskDirs := []string{c.paths.Root}
+	if c.paths.CacheDir != "" && c.paths.CacheDir != c.paths.Root {
+		if _, err := os.Stat(c.paths.CacheDir); err == nil {
+			diskDirs = append(diskDirs, c.paths.CacheDir)
+		}
+	}
+	for _, dir := range diskDirs {
+		if free, err := freeDiskPercent(dir); err != nil {
+			disk.OK = false
+			disk.Details = append(disk.Details, err.Error())
+		} else if free < healthMinFreeDiskPercent {
+			disk.OK = false
+			disk.Details = append(disk.Details, fmt.Sprintf("only %.1f%% free on %s", free, dir))
+		}
+	}
+	checks = append(checks, disk)
+
+	// 9. Message queues are not backlogged
+	queues := healthCheck{Key: "messages", Name: fmt.Sprintf("no message queue has more than %d pending messages", healthMaxPendingMessages), OK: true}
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	for _, name := range agentNames {
+		msgs, err := msgMgr.List(repoName, name)
+		if err != nil {
+			continue
+		}
+		pending := 0
+		for _, msg := range msgs {
+			if msg.Status == messages.StatusPending {
+				pending++
+			}
+		}
+		if pending > healthMaxPendingMessages {
+			queues.OK = false
+			queues.Details = append(queues.Details, fmt.Sprintf("%s: %d pending messages", name, pending))
+		}
+	}
+	checks = append(checks, queues)
+
+	// 10. Agents are started with a single, usable claude binary
+	binary := healthCheck{Key: "claude", Name: "claude binary unchanged since the daemon started", OK: true}
+	if repo.ClaudePath != "" {
+		binary.Name = fmt.Sprintf("pinned claude binary %s is usable", repo.ClaudePath)
+		if err := claude.CheckExecutable(repo.ClaudePath); err != nil {
+			binary.OK = false
+			binary.Details = append(binary.Details, err.Error(), "agents will not start until it is restored or unpinned")
+		}
+	} else if change := c.daemonClaudeBinaryChange(); change != "" {
+		binary.OK = false
+		binary.Details = append(binary.Details,
+			fmt.Sprintf("claude changed from %s", change),
+			fmt.Sprintf("agents may run different versions; restart the daemon: %s", restartDaemonCommand))
+	}
+	checks = append(checks, binary)
+
+	// 11. The commit signing key can sign
+	if identity := repo.GitIdentity; identity.SignCommits {
+		signing := healthCheck{Key: "signing", Name: fmt.Sprintf("commit signing key is usable (%s)", identity.EffectiveSigningFormat()), OK: true}
+		if err := worktree.TestSign(identity.EffectiveSigningFormat(), identity.SigningKey); err != nil {
+			signing.OK = false
+			signing.Details = append(signing.Details, err.Error(), "commits in agent worktrees will fail until the key is usable")
+		}
+		checks = append(checks, signing)
+	}
+
+	// 12. Agent worktrees use the repository's git identity
+	identity := healthCheck{Key: "git-identity", Name: "agent worktrees use the repository's git identity", OK: true, Fixable: true}
+	values := repo.GitIdentity.GitConfig()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, path := range gitIdentityWorktrees(repoPath, repo) {
+		for _, key := range keys {
+			got, err := worktree.GetWorktreeConfig(path, key)
+			if err != nil || got != values[key] {
+				identity.OK = false
+				identity.Details = append(identity.Details, fmt.Sprintf("%s: %s is %q, want %q", path, key, got, values[key]))
+			}
+		}
+	}
+	checks = append(checks, identity)
+
+	// 13. Agents' prompts document this binary's commands
+	docs := healthCheck{Key: "docs", Name: "agent prompts document the installed multiclaude's commands", OK: true, Fixable: true}
+	for _, name := range agentNames {
+		data, err := os.ReadFile(filepath.Join(c.paths.Root, "prompts", name+".md"))
+		if err != nil {
+			continue
+		}
+		version, hash, ok := prompts.ParseDocsStamp(string(data))
+		if !ok {
+			continue
+		}
+		if _, current, err := c.currentDocs(repoName, prompts.AgentType(repo.Agents[name].Type)); err == nil && hash != current {
+			docs.OK = false
+			docs.Details = append(docs.Details, fmt.Sprintf("%s: CLI reference written by multiclaude %s differs from %s's", name, version, Version))
+		}
+	}
+	checks = append(checks, docs)
+
+	return checks
+}
+
+// fixRepoHealth repairs the fixable issues reported by checkRepoHealth
+func (c *CLI) fixRepoHealth(repoName string, repo *state.Repository, checks []healthCheck) error {
+	needsRepair := false
+	needsRestart := false
+	needsPrune := false
+	needsPrompts := false
+	needsGitConfig := false
+	needsDocs := false
+	for _, check := range checks {
+		if check.OK || !check.Fixable {
+			continue
+		}
+		switch check.Key {
+		case "windows":
+			needsRepair = true
+		case "processes":
+			needsRestart = true
+		case "worktrees":
+			needsPrune = true
+		case "prompts":
+			needsPrompts = true
+		case "git-identity":
+			needsGitConfig = true
+		case "docs":
+			needsDocs = true
+		}
+	}
+
+	if needsGitConfig {
+		if err := c.applyGitConfig(repoName); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	if needsPrune {
+		wt := worktree.NewManager(c.paths.RepoDir(repoName))
+		if err := wt.Prune(); err != nil {
+			fmt.Printf("Warning: failed to prune worktrees: %v\n", err)
+		} else {
+			fmt.Println("✓ Pruned stale worktree references")
+		}
+	}
+
+	if needsPrompts {
+		restored, err := c.restorePromptFiles(repoName, repo)
+		if err != nil {
+			fmt.Printf("Warning: failed to restore prompt files: %v\n", err)
+		} else if restored > 0 {
+			fmt.Printf("✓ Restored %d prompt file(s)\n", restored)
+		}
+	}
+
+	if needsDocs {
+		refreshed := c.refreshStaleDocs(repoName, repo)
+		if refreshed > 0 {
+			fmt.Printf("✓ Refreshed the CLI reference in %d prompt file(s); agents read it when restarted\n", refreshed)
+		}
+	}
+
+	if needsRepair {
+		if err := c.repair([]string{"--repo", repoName}); err != nil {
+			return err
+		}
+	}
+
+	// Restart after repair, which drops agents whose windows are gone
+	if needsRestart {
+		if restarted := c.restartDeadAgents(repoName, repo); restarted > 0 {
+			fmt.Printf("✓ Restarted %d agent(s) whose Claude process had exited\n", restarted)
+		}
+	}
+
+	return nil
+}
+
+// restartDeadAgents has the daemon restart the agents of a repository whose
+// Claude process is no longer running, and returns how many it restarted
+func (c *CLI) restartDeadAgents(repoName string, repo *state.Repository) int {
+	names := make([]string, 0, len(repo.Agents))
+	for name := range repo.Agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	restarted := 0
+	for _, name := range names {
+		if pid := repo.Agents[name].PID; pid <= 0 || procs.IsAlive(pid) {
+			continue
+		}
+		resp, err := client.Send(socket.Request{
+			Command: "restart_agent",
+			Args: map[string]interface{}{
+				"repo":  repoName,
+				"agent": name,
+			},
+		})
+		if err == nil && !resp.Success {
+			err = fmt.Errorf("%s", resp.Error)
+		}
+		if err != nil {
+			fmt.Printf("Warning: failed to restart %s: %v\n", name, err)
+			continue
+		}
+		restarted++
+	}
+	return restarted
+}
+
+// refreshStaleDocs replaces the stale CLI references in the prompt files of
+// a repository's agents, and returns how many it refreshed
+func (c *CLI) refreshStaleDocs(repoName string, repo *state.Repository) int {
+	refreshed := 0
+	for name, agent := range repo.Agents {
+		promptFile := filepath.Join(c.paths.Root, "prompts", name+".md")
+		hash := promptDocsHash(promptFile)
+		if hash == "" {
+			continue
+		}
+		if _, current, err := c.currentDocs(repoName, prompts.AgentType(agent.Type)); err != nil || hash == current {
+			continue
+		}
+		if _, err := c.refreshPromptDocs(repoName, name, prompts.AgentType(agent.Type)); err != nil {
+			fmt.Printf("Warning: failed to refresh the CLI reference of %s: %v\n", name, err)
+			continue
+		}
+		refreshed++
+	}
+	return refreshed
+}
+
+// restorePromptFiles regenerates missing or empty prompt files for a
+// repository's agents
+func (c *CLI) restorePromptFiles(repoName string, repo *state.Repository) (int, error) {
+	restored := 0
+	for name, agent := range repo.Agents {
+		if promptFileUsable(filepath.Join(c.paths.Root, "prompts", name+".md")) {
+			continue
+		}
+		if _, err := c.restorePromptFile(repoName, repo, name, agent); err != nil {
+			return restored, err
+		}
+		restored++
+	}
+	return restored, nil
+}
+
+// restorePromptFile writes an agent's prompt file again from its type and
+// repository, and a worker's from the paths and branch it was created with,
+// and returns its path
+func (c *CLI) restorePromptFile(repoName string, repo *state.Repository, name string, agent state.Agent) (string, error) {
+	repoPath := c.paths.RepoDir(repoName)
+	switch agent.Type {
+	case state.AgentTypeMergeQueue:
+		return c.writeMergeQueuePromptFile(repoPath, name, repo.MergeQueueConfig)
+	case state.AgentTypeWorker:
+		return c.writeWorkerPromptFile(repoPath, name, recordedWorkerConfig(name, agent))
+	default:
+		return c.writePromptFile(repoPath, prompts.AgentType(agent.Type), name)
+	}
+}
+
+// recordedWorkerConfig returns the configuration a worker was created with:
+// its paths, and the PR branch it pushes to when it is not on a work/<name>
+// branch of its own
+func recordedWorkerConfig(name string, agent state.Agent) WorkerConfig {
+	config := WorkerConfig{Paths: agent.Paths}
+	branch := agent.Branch
+	if agent.WorktreePath != "" {
+		if current, err := worktree.GetCurrentBranch(agent.WorktreePath); err == nil {
+			branch = current
+		}
+	}
+	if branch != "" && branch != "HEAD" && branch != "work/"+name {
+		config.PushToBranch = branch
+	}
+	return config
+}
+
+// promptFileUsable reports whether a prompt file exists and is not empty
+func promptFileUsable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Size() > 0
+}
+
+// branchDivergence returns the number of commits by which branch and HEAD
+// have diverged in the repository at repoPath (commits on either side)
+func branchDivergence(repoPath, branch string) (int, error) {
+	cmd := exec.Command("git", "rev-list", "--count", "HEAD..."+branch)
+	cmd.Dir = repoPath
+	output, err := cmdrun.Output(cmd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count divergence for %s: %w", branch, err)
+	}
+	return strconv.Atoi(strings.TrimSpace(output))
+}
+
+// freeDiskPercent returns the percentage of free disk space for the
+// filesystem containing path
+func freeDiskPercent(path string) (float64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("failed to stat filesystem: %w", err)
+	}
+	if stat.Blocks == 0 {
+		return 0, fmt.Errorf("filesystem reports no blocks")
+	}
+	return float64(stat.Bavail) / float64(stat.Blocks) * 100, nil
+}
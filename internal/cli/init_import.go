@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/claude"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// importableAgents are the agents init --import-from-existing registers when
+// the session has a window of the same name, by window name and agent type
+var importableAgents = []struct {
+	Window string
+	Type   string
+}{
+	{Window: "supervisor", Type: "supervisor"},
+	{Window: "merge-queue", Type: "merge-queue"},
+}
+
+// importExistingRepo registers a repository that was cloned and given a tmux
+// session by hand (init --import-from-existing), without cloning or creating
+// a session. The clone stays where it is; the repository's directory under
+// repos/ links to it, as every other command looks for it there.
+func (c *CLI) importExistingRepo(flags map[string]string, posArgs []string) error {
+	if len(posArgs) > 0 {
+		return errors.InvalidUsage("init --import-from-existing takes no URL; it reads the clone's origin remote")
+	}
+	for _, flag := range []string{"local", "wizard", "template"} {
+		if _, ok := flags[flag]; ok {
+			return errors.InvalidUsage(fmt.Sprintf("--%s cannot be combined with --import-from-existing", flag))
+		}
+	}
+
+	pathArg := flags["repo-path"]
+	if pathArg == "" || pathArg == "true" {
+		return errors.MissingArgument("--repo-path", "path")
+	}
+	tmuxSession := flags["tmux-session"]
+	if tmuxSession == "" || tmuxSession == "true" {
+		return errors.MissingArgument("--tmux-session", "session name")
+	}
+
+	repoPath, err := filepath.Abs(pathArg)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", pathArg, err)
+	}
+	if err := exec.Command("git", "-C", repoPath, "rev-parse", "HEAD").Run(); err != nil {
+		return errors.InvalidUsage(fmt.Sprintf("--repo-path %s is not a git repository with commits", pathArg))
+	}
+
+	out, err := exec.Command("git", "-C", repoPath, "remote", "get-url", "origin").Output()
+	if err != nil {
+		return errors.New(errors.CategoryConfig, fmt.Sprintf("%s has no origin remote", repoPath)).
+			WithSuggestion(fmt.Sprintf("git -C %s remote add origin <github-url>", repoPath))
+	}
+	githubURL := strings.TrimRight(strings.TrimSpace(string(out)), "/")
+
+	repoName := flags["name"]
+	if repoName == "" || repoName == "true" {
+		if repoName, err = repoNameFromURL(githubURL); err != nil {
+			repoName = filepath.Base(repoPath)
+		}
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	if _, err := client.Send(socket.Request{Command: "ping"}); err != nil {
+		return errors.DaemonNotRunning()
+	}
+	if c.repoNameTaken(repoName) {
+		return errors.New(errors.CategoryUsage, fmt.Sprintf("a repository named '%s' is already tracked", repoName)).
+			WithSuggestion("pass another name with --name <name>")
+	}
+
+	tmuxClient := tmux.NewClient()
+	ctx := context.Background()
+	hasSession, err := tmuxClient.HasSession(ctx, tmuxSession)
+	if err != nil {
+		return errors.TmuxOperationFailed("check session", err)
+	}
+	if !hasSession {
+		return errors.TmuxTargetNotFound(tmuxSession, nil)
+	}
+	windows, err := tmuxClient.ListWindows(ctx, tmuxSession)
+	if err != nil {
+		return errors.TmuxOperationFailed("list windows", err)
+	}
+	hasWindow := make(map[string]bool, len(windows))
+	for _, window := range windows {
+		hasWindow[window] = true
+	}
+
+	fmt.Printf("Importing repository: %s\n", repoName)
+	fmt.Printf("Clone: %s\n", repoPath)
+	fmt.Printf("GitHub URL: %s\n", githubURL)
+	fmt.Printf("Tmux session: %s\n", tmuxSession)
+
+	linkPath := c.paths.RepoDir(repoName)
+	if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
+		return fmt.Errorf("failed to create repos directory: %w", err)
+	}
+	if err := os.Symlink(repoPath, linkPath); err != nil {
+		return fmt.Errorf("failed to link %s to the clone: %w", linkPath, err)
+	}
+
+	// The merge queue is on if the session already runs one. Without a
+	// supervisor window the repository has none, and one can be added later.
+	resp, err := client.Send(socket.Request{
+		Command: "add_repo",
+		Args: map[string]interface{}{
+			"name":          repoName,
+			"github_url":    githubURL,
+			"tmux_session":  tmuxSession,
+			"mq_enabled":    hasWindow["merge-queue"],
+			"mq_track_mode": string(state.TrackModeAll),
+			"no_supervisor": !hasWindow["supervisor"],
+		},
+	})
+	if err == nil && !resp.Success {
+		err = fmt.Errorf("%s", resp.Error)
+	}
+	if err != nil {
+		os.Remove(linkPath)
+		return errors.Wrap(errors.CategoryRuntime, "failed to register repository", err)
+	}
+
+	var imported []string
+	for _, agent := range importableAgents {
+		if !hasWindow[agent.Window] {
+			continue
+		}
+		if err := c.importAgent(tmuxClient, repoName, repoPath, tmuxSession, agent.Window, agent.Type); err != nil {
+			fmt.Printf("Warning: failed to register %s: %v\n", agent.Window, err)
+			continue
+		}
+		imported = append(imported, agent.Window)
+	}
+
+	fmt.Println()
+	fmt.Println("✓ Repository imported successfully!")
+	if len(imported) > 0 {
+		fmt.Printf("  Agents: %s\n", strings.Join(imported, ", "))
+	} else {
+		fmt.Println("  Agents: none (no supervisor or merge-queue window found)")
+	}
+	if !hasWindow["supervisor"] {
+		fmt.Printf("Add a supervisor: multiclaude agent add-supervisor --repo %s\n", repoName)
+	}
+	fmt.Printf("Add a workspace: multiclaude workspace add <name> --repo %s\n", repoName)
+	return nil
+}
+
+// importAgent registers the Claude already running in a window of an
+// imported session as an agent. Its Claude session is unknown, so the agent
+// gets a new session ID, which a restart starts fresh with.
+func (c *CLI) importAgent(tmuxClient *tmux.Client, repoName, repoPath, tmuxSession, window, agentType string) error {
+	pid, err := tmuxClient.GetPanePID(context.Background(), tmuxSession, window)
+	if err != nil {
+		return fmt.Errorf("failed to get pane PID: %w", err)
+	}
+	sessionID, err := claude.GenerateSessionID()
+	if err != nil {
+		return fmt.Errorf("failed to generate session ID: %w", err)
+	}
+
+	resp, err := socket.NewClient(c.paths.DaemonSock).Send(socket.Request{
+		Command: "add_agent",
+		Args: map[string]interface{}{
+			"repo":          repoName,
+			"agent":         window,
+			"type":          agentType,
+			"worktree_path": repoPath,
+			"tmux_window":   window,
+			"session_id":    sessionID,
+			"pid":           pid,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("%s", resp.Error)
+	}
+
+	if err := c.setupOutputCapture(tmuxSession, window, repoName, window, agentType); err != nil {
+		fmt.Printf("Warning: failed to setup output capture for %s: %v\n", window, err)
+	}
+	fmt.Printf("Registered %s (pid %d)\n", window, pid)
+	return nil
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+func TestInitImportFromExisting(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repoPath := filepath.Join(t.TempDir(), "my-clone")
+	setupTestRepo(t, repoPath)
+
+	// Validation happens before anything is registered
+	err := cli.Execute([]string{"init", "--import-from-existing", "--tmux-session", "hand-made"})
+	if err == nil || !strings.Contains(err.Error(), "--repo-path") {
+		t.Errorf("import without --repo-path = %v, want a missing argument error", err)
+	}
+	err = cli.Execute([]string{"init", "--import-from-existing", "--repo-path", t.TempDir(), "--tmux-session", "hand-made"})
+	if err == nil || !strings.Contains(err.Error(), "not a git repository") {
+		t.Errorf("import of a non-repository = %v, want a usage error", err)
+	}
+	err = cli.Execute([]string{"init", "--import-from-existing", "--repo-path", repoPath, "--tmux-session", "hand-made"})
+	if err == nil || !strings.Contains(err.Error(), "origin") {
+		t.Errorf("import of a clone without origin = %v, want an error about the remote", err)
+	}
+
+	if err := exec.Command("git", "-C", repoPath, "remote", "add", "origin", "https://github.com/user/imported.git").Run(); err != nil {
+		t.Fatalf("Failed to add origin: %v", err)
+	}
+
+	if !tmux.NewClient().IsTmuxAvailable() {
+		t.Skip("tmux not available")
+	}
+	err = cli.Execute([]string{"init", "--import-from-existing", "--repo-path", repoPath, "--tmux-session", "mc-test-missing-session"})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("import of a missing session = %v, want a not found error", err)
+	}
+
+	// A session started by hand with Claude in a supervisor window
+	session := "mc-test-hand-made"
+	if err := exec.Command("tmux", "new-session", "-d", "-s", session, "-n", "supervisor", "cat").Run(); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer exec.Command("tmux", "kill-session", "-t", session).Run()
+	if err := exec.Command("tmux", "new-window", "-d", "-t", session, "-n", "notes", "cat").Run(); err != nil {
+		t.Fatalf("Failed to create tmux window: %v", err)
+	}
+
+	if err := cli.Execute([]string{"init", "--import-from-existing", "--repo-path", repoPath, "--tmux-session", session}); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	repo, ok := d.GetState().GetRepo("imported")
+	if !ok {
+		t.Fatal("repository should be registered under the name from its origin URL")
+	}
+	if repo.GithubURL != "https://github.com/user/imported.git" || repo.TmuxSession != session {
+		t.Errorf("repo = %+v, want the clone's origin and the given session", repo)
+	}
+	if repo.MergeQueueConfig.Enabled {
+		t.Error("the merge queue should be off without a merge-queue window")
+	}
+	if _, ok := repo.Agents["supervisor"]; !ok {
+		t.Errorf("agents = %v, want the supervisor window registered", repo.Agents)
+	}
+	if len(repo.Agents) != 1 {
+		t.Errorf("agents = %v, want only the supervisor", repo.Agents)
+	}
+
+	// The clone stays where it is, linked into the repos directory
+	if target, err := os.Readlink(cli.paths.RepoDir("imported")); err != nil || target != repoPath {
+		t.Errorf("repos directory link = %q, %v; want %s", target, err, repoPath)
+	}
+
+	err = cli.Execute([]string{"init", "--import-from-existing", "--repo-path", repoPath, "--tmux-session", session})
+	if err == nil || !strings.Contains(err.Error(), "already tracked") {
+		t.Errorf("importing the same name twice = %v, want an error", err)
+	}
+}
+
+func TestAddSupervisorToImportedRepo(t *testing.T) {
+	if !tmux.NewClient().IsTmuxAvailable() {
+		t.Skip("tmux not available")
+	}
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repoPath := filepath.Join(t.TempDir(), "my-clone")
+	setupTestRepo(t, repoPath)
+	if err := exec.Command("git", "-C", repoPath, "remote", "add", "origin", "https://github.com/user/imported.git").Run(); err != nil {
+		t.Fatalf("Failed to add origin: %v", err)
+	}
+
+	// A session started by hand, not named mc-<repo>, without a supervisor
+	session := "mc-test-hand-made-nosup"
+	if err := exec.Command("tmux", "new-session", "-d", "-s", session, "-n", "notes", "cat").Run(); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer exec.Command("tmux", "kill-session", "-t", session).Run()
+
+	if err := cli.Execute([]string{"init", "--import-from-existing", "--repo-path", repoPath, "--tmux-session", session}); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if err := cli.Execute([]string{"agent", "add-supervisor", "--repo", "imported"}); err != nil {
+		t.Fatalf("add-supervisor failed: %v", err)
+	}
+
+	hasWindow, err := tmux.NewClient().HasWindow(context.Background(), session, "supervisor")
+	if err != nil || !hasWindow {
+		t.Errorf("supervisor window in %s = %v, %v; want it created in the imported session", session, hasWindow, err)
+	}
+	if has, _ := tmux.NewClient().HasSession(context.Background(), sanitizeTmuxSessionName("imported")); has {
+		exec.Command("tmux", "kill-session", "-t", sanitizeTmuxSessionName("imported")).Run()
+		t.Error("add-supervisor created a new mc-imported session instead of using the imported one")
+	}
+	if _, ok := d.GetState().GetAgent("imported", "supervisor"); !ok {
+		t.Error("supervisor should be registered")
+	}
+}
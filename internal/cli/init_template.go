@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/prompts"
+)
+
+// checkTemplateURL verifies that a template repository can be read, without
+// cloning it
+func checkTemplateURL(url string) error {
+	if _, _, err := cmdrun.Run(exec.Command("git", "ls-remote", url, "HEAD")); err != nil {
+		return errors.TemplateUnavailable(url, err)
+	}
+	return nil
+}
+
+// fetchTemplate makes a shallow clone of a template repository in a
+// temporary directory and returns the path of its .multiclaude directory.
+// The caller must call cleanup when done with it.
+func fetchTemplate(url string) (string, func(), error) {
+	tmpDir, err := os.MkdirTemp("", "multiclaude-template-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp directory for template: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	cloneDir := filepath.Join(tmpDir, "template")
+	if _, _, err := cmdrun.Run(exec.Command("git", "clone", "--quiet", "--depth", "1", url, cloneDir)); err != nil {
+		cleanup()
+		return "", nil, errors.TemplateUnavailable(url, err)
+	}
+
+	configDir := filepath.Join(cloneDir, ".multiclaude")
+	if info, err := os.Stat(configDir); err != nil || !info.IsDir() {
+		cleanup()
+		return "", nil, errors.TemplateUnavailable(url, fmt.Errorf("template has no .multiclaude/ directory"))
+	}
+	return configDir, cleanup, nil
+}
+
+// copyTemplateConfig copies the files in a template's .multiclaude directory
+// into the repository's, returning the paths it wrote. Files the repository
+// already has are left alone. With includePrompts false the prompt override
+// files (SUPERVISOR.md, WORKER.md, ...) are skipped, so only hooks and
+// other configuration are copied.
+func copyTemplateConfig(templateDir, repoPath string, includePrompts bool) ([]string, error) {
+	promptFiles := make(map[string]bool)
+	if !includePrompts {
+		for _, agentType := range prompts.CustomPromptTypes {
+			if name, err := prompts.CustomPromptFile(agentType); err == nil {
+				promptFiles[name] = true
+			}
+		}
+	}
+
+	destDir := filepath.Join(repoPath, ".multiclaude")
+	var written []string
+	err := filepath.WalkDir(templateDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(templateDir, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(destDir, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(dest, 0755)
+		}
+		// Symlinks and other special files could point outside the template
+		if !d.Type().IsRegular() {
+			fmt.Printf("Skipping template file %s: not a regular file\n", rel)
+			return nil
+		}
+		if promptFiles[rel] {
+			return nil
+		}
+		if _, err := os.Stat(dest); err == nil {
+			fmt.Printf("Keeping the repository's own .multiclaude/%s\n", rel)
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(dest, data, info.Mode().Perm()); err != nil {
+			return err
+		}
+		written = append(written, dest)
+		return nil
+	})
+	if err != nil {
+		return written, fmt.Errorf("failed to copy template configuration: %w", err)
+	}
+	return written, nil
+}
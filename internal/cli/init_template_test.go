@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupTemplateRepo creates a git repository holding only a .multiclaude
+// directory with the given files
+func setupTemplateRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	repoPath := filepath.Join(t.TempDir(), "template")
+	setupTestRepo(t, repoPath)
+	for name, content := range files {
+		path := filepath.Join(repoPath, ".multiclaude", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-m", "Add template"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	return repoPath
+}
+
+func TestInitTemplate(t *testing.T) {
+	templateRepo := setupTemplateRepo(t, map[string]string{
+		"hooks.json":          `{"hooks": {}}`,
+		"SUPERVISOR.md":       "Template supervisor instructions",
+		"WORKER.md":           "Template worker instructions",
+		"messages/handoff.md": "Handing over {{task}}",
+	})
+
+	if err := checkTemplateURL(templateRepo); err != nil {
+		t.Fatalf("checkTemplateURL() failed for a readable template: %v", err)
+	}
+	if err := checkTemplateURL(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("checkTemplateURL() should fail for a missing repository")
+	}
+
+	configDir, cleanup, err := fetchTemplate(templateRepo)
+	if err != nil {
+		t.Fatalf("fetchTemplate() failed: %v", err)
+	}
+	defer cleanup()
+
+	// A repository's own files win over the template's
+	target := filepath.Join(t.TempDir(), "target")
+	if err := os.MkdirAll(filepath.Join(target, ".multiclaude"), 0755); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(target, ".multiclaude", "WORKER.md"), []byte("Own worker instructions"), 0644); err != nil {
+		t.Fatalf("Failed to write WORKER.md: %v", err)
+	}
+
+	written, err := copyTemplateConfig(configDir, target, true)
+	if err != nil {
+		t.Fatalf("copyTemplateConfig() failed: %v", err)
+	}
+	if len(written) != 3 {
+		t.Errorf("copyTemplateConfig() wrote %v, want hooks.json, SUPERVISOR.md and messages/handoff.md", written)
+	}
+	for name, want := range map[string]string{
+		"hooks.json":          `{"hooks": {}}`,
+		"SUPERVISOR.md":       "Template supervisor instructions",
+		"WORKER.md":           "Own worker instructions",
+		"messages/handoff.md": "Handing over {{task}}",
+	} {
+		data, err := os.ReadFile(filepath.Join(target, ".multiclaude", name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+
+	// Without prompts only hooks and configuration are copied
+	noPrompts := filepath.Join(t.TempDir(), "no-prompts")
+	if _, err := copyTemplateConfig(configDir, noPrompts, false); err != nil {
+		t.Fatalf("copyTemplateConfig() without prompts failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(noPrompts, ".multiclaude", "SUPERVISOR.md")); !os.IsNotExist(err) {
+		t.Error("prompt overrides should not be copied with includePrompts false")
+	}
+	if _, err := os.Stat(filepath.Join(noPrompts, ".multiclaude", "hooks.json")); err != nil {
+		t.Errorf("hooks.json should be copied: %v", err)
+	}
+
+	// A template without .multiclaude/ is rejected
+	bare := filepath.Join(t.TempDir(), "bare")
+	setupTestRepo(t, bare)
+	if _, _, err := fetchTemplate(bare); err == nil || !strings.Contains(err.Error(), bare) {
+		t.Errorf("fetchTemplate() without .multiclaude/ = %v, want a template error", err)
+	}
+}
+
+func TestInitTemplateFlagValidation(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	err := cli.Execute([]string{"init", "https://github.com/user/repo", "--no-template-prompts"})
+	if err == nil || !strings.Contains(err.Error(), "requires --template") {
+		t.Errorf("--no-template-prompts without --template = %v, want a usage error", err)
+	}
+
+	err = cli.Execute([]string{"init", "https://github.com/user/repo", "--template"})
+	if err == nil || !strings.Contains(err.Error(), "--template") {
+		t.Errorf("--template without a URL = %v, want a missing argument error", err)
+	}
+
+	// The template is checked before the repository is cloned
+	missing := filepath.Join(t.TempDir(), "missing-template")
+	err = cli.Execute([]string{"init", "--template", missing, "https://github.com/user/repo"})
+	if err == nil || !strings.Contains(err.Error(), "template") {
+		t.Errorf("init with an unreadable template = %v, want a template error", err)
+	}
+	if _, statErr := os.Stat(cli.paths.RepoDir("repo")); !os.IsNotExist(statErr) {
+		t.Error("the repository should not be cloned when the template is unreadable")
+	}
+}
@@ -0,0 +1,296 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/gh"
+	"github.com/dlorenc/multiclaude/internal/githuburl"
+	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// initWizard asks the questions for init --wizard and turns the answers into
+// initOptions. Input and output are injected so tests can drive it without
+// a terminal.
+type initWizard struct {
+	in  *bufio.Reader
+	out io.Writer
+	// checkRepo reports an error if owner/name does not exist on GitHub
+	checkRepo func(owner, name string) error
+	// nameTaken reports whether a repository name is already in use
+	nameTaken func(name string) bool
+}
+
+// initWizard runs the init wizard on the terminal. It fails immediately when
+// stdin is not a terminal, since nobody could answer.
+func (c *CLI) initWizard(defaultURL string) (initOptions, error) {
+	if !isTerminal(os.Stdin) {
+		return initOptions{}, errors.WizardNeedsTerminal()
+	}
+
+	// Check the daemon before asking anything
+	client := socket.NewClient(c.paths.DaemonSock)
+	if _, err := client.Send(socket.Request{Command: "ping"}); err != nil {
+		return initOptions{}, errors.DaemonNotRunning()
+	}
+
+	w := &initWizard{
+		in:        bufio.NewReader(os.Stdin),
+		out:       os.Stdout,
+		checkRepo: ghRepoExists,
+		nameTaken: c.repoNameTaken,
+	}
+	return w.run(defaultURL)
+}
+
+// run asks each question in turn, re-asking after an invalid answer
+func (w *initWizard) run(defaultURL string) (initOptions, error) {
+	opts := initOptions{}
+	fmt.Fprintln(w.out, "multiclaude init wizard. Press Enter to accept the default in brackets.")
+	fmt.Fprintln(w.out)
+
+	// Repository URL, checked against GitHub
+	suggestedName := ""
+	for {
+		url, err := w.ask("GitHub repository URL", defaultURL)
+		if err != nil {
+			return opts, err
+		}
+		url = strings.TrimRight(url, "/")
+		if url == "" {
+			fmt.Fprintln(w.out, "  A repository URL is required.")
+			continue
+		}
+		owner, name, err := githuburl.Parse(url)
+		if err != nil {
+			fmt.Fprintf(w.out, "  %v\n", err)
+			continue
+		}
+		fmt.Fprintf(w.out, "  Checking %s/%s on GitHub...\n", owner, name)
+		if err := w.checkRepo(owner, name); ghUnavailable(err) {
+			fmt.Fprintf(w.out, "  Skipping the check: %v\n", ghError(err))
+		} else if err != nil {
+			fmt.Fprintf(w.out, "  Could not find %s/%s: %v\n", owner, name, err)
+			continue
+		}
+		opts.GithubURL = url
+		suggestedName = name
+		break
+	}
+
+	// Repository name, defaulting to the one in the URL
+	for {
+		name, err := w.ask("Repository name", suggestedName)
+		if err != nil {
+			return opts, err
+		}
+		if name == "" {
+			fmt.Fprintln(w.out, "  A repository name is required.")
+			continue
+		}
+		if w.nameTaken(name) {
+			fmt.Fprintf(w.out, "  A repository named %q is already tracked or cloned; choose another name.\n", name)
+			continue
+		}
+		opts.RepoName = name
+		break
+	}
+
+	// Merge queue
+	fmt.Fprintln(w.out)
+	fmt.Fprintln(w.out, "The merge queue agent watches multiclaude PRs and merges them once CI passes.")
+	mqEnabled, err := w.askYesNo("Enable the merge queue?", true)
+	if err != nil {
+		return opts, err
+	}
+	opts.MQConfig = state.MergeQueueConfig{Enabled: mqEnabled, TrackMode: state.TrackModeAll}
+	if mqEnabled {
+		fmt.Fprintln(w.out, "Which PRs should it track?")
+		fmt.Fprintln(w.out, "  all       every PR with the multiclaude label, whoever wrote it")
+		fmt.Fprintln(w.out, "  author    only PRs authored by you or multiclaude's workers")
+		fmt.Fprintln(w.out, "  assigned  only PRs assigned to you or multiclaude")
+		for {
+			answer, err := w.ask("Track mode", "all")
+			if err != nil {
+				return opts, err
+			}
+			mode, err := parseTrackMode(answer)
+			if err != nil {
+				fmt.Fprintln(w.out, "  Choose all, author or assigned.")
+				continue
+			}
+			opts.MQConfig.TrackMode = mode
+			break
+		}
+	}
+
+	// Default workspace
+	fmt.Fprintln(w.out)
+	fmt.Fprintln(w.out, "A workspace is an interactive agent with its own worktree, for your own work.")
+	createWorkspace, err := w.askYesNo("Create a default workspace?", true)
+	if err != nil {
+		return opts, err
+	}
+	opts.NoWorkspace = !createWorkspace
+
+	// Prompt override templates
+	fmt.Fprintln(w.out)
+	fmt.Fprintln(w.out, "Files in the repository's .multiclaude/ directory (SUPERVISOR.md, WORKER.md, ...) add")
+	fmt.Fprintln(w.out, "project-specific instructions to each agent's prompt.")
+	opts.SeedPrompts, err = w.askYesNo("Write template prompt override files into the clone?", false)
+	if err != nil {
+		return opts, err
+	}
+	if opts.SeedPrompts {
+		opts.CommitPrompts, err = w.askYesNo("Commit them in the clone?", false)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	fmt.Fprintln(w.out)
+	fmt.Fprintf(w.out, "Equivalent command: %s\n", initCommandLine(opts))
+	proceed, err := w.askYesNo("Initialize with these settings?", true)
+	if err != nil {
+		return opts, err
+	}
+	if !proceed {
+		return opts, errors.New(errors.CategoryUsage, "init cancelled")
+	}
+	return opts, nil
+}
+
+// ask prints a question and returns the trimmed answer, or def for an empty
+// answer. Running out of input aborts the wizard.
+func (w *initWizard) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+	line, err := w.in.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(w.out)
+		return "", errors.New(errors.CategoryUsage, "init wizard aborted: no more input")
+	}
+	answer := strings.TrimSpace(line)
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// askYesNo asks a yes/no question, re-asking until the answer is one
+func (w *initWizard) askYesNo(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer, err := w.ask(fmt.Sprintf("%s (%s)", question, hint), "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(w.out, "  Please answer y or n.")
+	}
+}
+
+// initCommandLine returns the flag-driven init command equivalent to opts.
+// Seeding prompt overrides has no flag and is left out.
+func initCommandLine(opts initOptions) string {
+	parts := []string{"multiclaude", "init", opts.GithubURL}
+	if derived, err := repoNameFromURL(opts.GithubURL); err != nil || derived != opts.RepoName {
+		parts = append(parts, opts.RepoName)
+	}
+	if !opts.MQConfig.Enabled {
+		parts = append(parts, "--no-merge-queue")
+	} else if opts.MQConfig.TrackMode != state.TrackModeAll {
+		parts = append(parts, "--mq-track="+string(opts.MQConfig.TrackMode))
+	}
+	if opts.WorktreeLimit > 0 {
+		parts = append(parts, fmt.Sprintf("--worktree-limit=%d", opts.WorktreeLimit))
+	}
+	if opts.NoWorkspace {
+		parts = append(parts, "--no-workspace")
+	}
+	return strings.Join(parts, " ")
+}
+
+// repoNameTaken reports whether name is already tracked by the daemon or has
+// a clone on disk
+func (c *CLI) repoNameTaken(name string) bool {
+	if _, err := os.Stat(c.paths.RepoDir(name)); err == nil {
+		return true
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{Command: "list_repos"})
+	if err != nil || !resp.Success {
+		return false
+	}
+	repos, _ := resp.Data.([]interface{})
+	for _, r := range repos {
+		if repoName, _ := r.(string); repoName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ghRepoExists checks with gh that owner/name exists and is accessible
+func ghRepoExists(owner, name string) error {
+	var repo struct {
+		Name string `json:"name"`
+	}
+	return gh.RepoView(context.Background(), owner+"/"+name, "name", &repo)
+}
+
+// seedPromptOverrides writes template prompt override files into a fresh
+// clone and, if commit is set, commits them there. Failures are reported as
+// warnings: the rest of init does not depend on them.
+func seedPromptOverrides(out io.Writer, repoPath string, commit bool) {
+	written, err := prompts.SeedCustomPrompts(repoPath)
+	if err != nil {
+		fmt.Fprintf(out, "Warning: failed to write prompt override templates: %v\n", err)
+	}
+	if len(written) == 0 {
+		return
+	}
+	for _, path := range written {
+		fmt.Fprintf(out, "Wrote prompt override template: %s\n", path)
+	}
+
+	if !commit {
+		fmt.Fprintln(out, "Edit the templates, then commit and push them to share them with every clone.")
+		return
+	}
+
+	addArgs := append([]string{"-C", repoPath, "add", "--"}, written...)
+	if _, _, err := cmdrun.Run(exec.Command("git", addArgs...)); err != nil {
+		fmt.Fprintf(out, "Warning: failed to stage prompt override templates: %v\n", err)
+		return
+	}
+	commitArgs := append([]string{"-C", repoPath, "commit", "-m", "Add multiclaude prompt override templates", "--"}, written...)
+	if _, _, err := cmdrun.Run(exec.Command("git", commitArgs...)); err != nil {
+		fmt.Fprintf(out, "Warning: failed to commit prompt override templates: %v\n", err)
+		return
+	}
+	fmt.Fprintf(out, "Committed prompt override templates. Push them with: git -C %s push\n", repoPath)
+}
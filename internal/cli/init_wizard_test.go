@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/gh"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func newTestWizard(input string, out *bytes.Buffer) *initWizard {
+	return &initWizard{
+		in:  bufio.NewReader(strings.NewReader(input)),
+		out: out,
+		checkRepo: func(owner, name string) error {
+			if name == "missing" {
+				return fmt.Errorf("repository not found")
+			}
+			return nil
+		},
+		nameTaken: func(name string) bool { return name == "taken" },
+	}
+}
+
+func TestInitWizardDefaults(t *testing.T) {
+	var out bytes.Buffer
+	// URL, then Enter for every default
+	w := newTestWizard("https://github.com/acme/widgets\n\n\n\n\n\n\n", &out)
+
+	opts, err := w.run("")
+	if err != nil {
+		t.Fatalf("run() failed: %v\n%s", err, out.String())
+	}
+	if opts.GithubURL != "https://github.com/acme/widgets" || opts.RepoName != "widgets" {
+		t.Errorf("URL/name = %q/%q, want the URL and a name derived from it", opts.GithubURL, opts.RepoName)
+	}
+	if !opts.MQConfig.Enabled || opts.MQConfig.TrackMode != state.TrackModeAll {
+		t.Errorf("MQConfig = %+v, want enabled tracking all", opts.MQConfig)
+	}
+	if opts.NoWorkspace || opts.SeedPrompts || opts.CommitPrompts {
+		t.Errorf("opts = %+v, want a workspace and no seeded prompts", opts)
+	}
+	if !strings.Contains(out.String(), "Equivalent command: multiclaude init https://github.com/acme/widgets\n") {
+		t.Errorf("expected the equivalent command in output:\n%s", out.String())
+	}
+}
+
+func TestInitWizardRetriesInvalidAnswers(t *testing.T) {
+	var out bytes.Buffer
+	input := strings.Join([]string{
+		"not a url",                        // rejected by the URL parser
+		"https://github.com/acme/missing",  // rejected by the GitHub check
+		"https://github.com/acme/widgets/", // accepted, trailing slash trimmed
+		"taken",                            // name collision
+		"gadgets",                          // accepted
+		"maybe",                            // not yes/no
+		"y",                                // merge queue on
+		"mine",                             // invalid track mode
+		"author",                           // accepted
+		"n",                                // no workspace
+		"y",                                // seed prompts
+		"y",                                // commit them
+		"",                                 // confirm
+	}, "\n") + "\n"
+	w := newTestWizard(input, &out)
+
+	opts, err := w.run("")
+	if err != nil {
+		t.Fatalf("run() failed: %v\n%s", err, out.String())
+	}
+	if opts.GithubURL != "https://github.com/acme/widgets" {
+		t.Errorf("GithubURL = %q", opts.GithubURL)
+	}
+	if opts.RepoName != "gadgets" {
+		t.Errorf("RepoName = %q, want gadgets", opts.RepoName)
+	}
+	if opts.MQConfig.TrackMode != state.TrackModeAuthor {
+		t.Errorf("TrackMode = %q, want author", opts.MQConfig.TrackMode)
+	}
+	if !opts.NoWorkspace || !opts.SeedPrompts || !opts.CommitPrompts {
+		t.Errorf("opts = %+v, want no workspace and committed prompt templates", opts)
+	}
+	for _, want := range []string{"Could not find acme/missing", "already tracked", "Please answer y or n", "Choose all, author or assigned",
+		"Equivalent command: multiclaude init https://github.com/acme/widgets gadgets --mq-track=author --no-workspace"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestInitWizardDisableMergeQueueAndCancel(t *testing.T) {
+	var out bytes.Buffer
+	// Default URL accepted, merge queue off, then decline the summary
+	w := newTestWizard("\n\nn\n\n\nn\n", &out)
+
+	_, err := w.run("https://github.com/acme/widgets")
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("run() error = %v, want cancellation", err)
+	}
+	if !strings.Contains(out.String(), "--no-merge-queue") {
+		t.Errorf("expected --no-merge-queue in the equivalent command:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "Track mode") {
+		t.Error("track mode should not be asked when the merge queue is disabled")
+	}
+}
+
+func TestInitWizardEndOfInput(t *testing.T) {
+	var out bytes.Buffer
+	w := newTestWizard("https://github.com/acme/widgets\n", &out)
+
+	if _, err := w.run(""); err == nil {
+		t.Error("run() should fail when input ends before the questions do")
+	}
+}
+
+func TestInitWizardWithoutGH(t *testing.T) {
+	var out bytes.Buffer
+	w := newTestWizard("https://github.com/acme/widgets\n\n\n\n\n\n\n", &out)
+	w.checkRepo = func(owner, name string) error {
+		return &gh.UnavailableError{NotInstalled: true, Err: exec.ErrNotFound}
+	}
+
+	// The GitHub check is skipped rather than failing every URL
+	opts, err := w.run("")
+	if err != nil || opts.GithubURL != "https://github.com/acme/widgets" {
+		t.Fatalf("run() = %+v, %v\n%s", opts, err, out.String())
+	}
+	if !strings.Contains(out.String(), "Skipping the check: the GitHub CLI (gh) is required but was not found") {
+		t.Errorf("expected the skipped check in output:\n%s", out.String())
+	}
+}
+
+func TestInitWizardRequiresTerminal(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	oldStdin := os.Stdin
+	os.Stdin = pipedStdin(t, "https://github.com/acme/widgets\n")
+	defer func() { os.Stdin = oldStdin }()
+
+	err := cli.Execute([]string{"init", "--wizard"})
+	if err == nil || !strings.Contains(err.Error(), "interactive terminal") {
+		t.Errorf("init --wizard without a terminal: error = %v, want a terminal error", err)
+	}
+}
+
+func TestSeedPromptOverridesCommits(t *testing.T) {
+	repoPath := t.TempDir()
+	for _, args := range [][]string{
+		{"init"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", repoPath}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	seedPromptOverrides(io.Discard, repoPath, true)
+
+	if _, err := os.Stat(filepath.Join(repoPath, ".multiclaude", "SUPERVISOR.md")); err != nil {
+		t.Errorf("expected SUPERVISOR.md to be written: %v", err)
+	}
+	out, err := exec.Command("git", "-C", repoPath, "log", "--format=%s", "--name-only").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git log failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "Add multiclaude prompt override templates") || !strings.Contains(string(out), ".multiclaude/REVIEWER.md") {
+		t.Errorf("expected a commit adding the templates, got:\n%s", out)
+	}
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/hooks"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// testLifecycleHook fires a synthetic lifecycle event, running the hooks the
+// daemon would run for it in the foreground and printing their output, so
+// hook scripts can be developed without creating real workers
+func (c *CLI) testLifecycleHook(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude hooks test <event> [--repo <repo>] [--agent <name>] [--task <task>]")
+	}
+	eventName := posArgs[0]
+	if !hooks.IsLifecycleEvent(eventName) {
+		return errors.InvalidUsage(fmt.Sprintf("unknown event %q", eventName)).
+			WithSuggestion("events: " + strings.Join(hooks.LifecycleEvents, ", "))
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	event := syntheticLifecycleEvent(eventName, repoName, flags)
+	if event.Agent != "" {
+		event.WorktreePath = filepath.Join(c.paths.WorktreesDir, repoName, event.Agent)
+	}
+	if st, err := state.Load(c.paths.StateFile); err == nil {
+		if repo, exists := st.GetRepo(repoName); exists {
+			event.GithubURL = repo.GithubURL
+		}
+	}
+
+	if !hooks.LifecycleHooksEnabled() {
+		fmt.Printf("Lifecycle hooks are skipped in MULTICLAUDE_TEST_MODE; set %s=1 to run them\n", hooks.LifecycleTestModeEnv)
+		return nil
+	}
+
+	repoPath := c.paths.RepoDir(repoName)
+	lifecycleHooks, loadErr := hooks.LoadLifecycleHooks(c.paths.ConfigDir, repoPath, eventName)
+	if loadErr != nil {
+		fmt.Printf("%s %v\n", format.Yellow.Sprint("Warning:"), loadErr)
+	}
+	if len(lifecycleHooks) == 0 {
+		fmt.Printf("No %s hooks in %s or %s\n", eventName,
+			filepath.Join(c.paths.ConfigDir, hooks.LifecycleConfigFile),
+			filepath.Join(repoPath, ".multiclaude", hooks.LifecycleConfigFile))
+		return loadErr
+	}
+
+	failed := 0
+	for _, result := range hooks.RunLifecycleHooks(context.Background(), lifecycleHooks, event) {
+		fmt.Printf("$ %s  %s\n", result.Hook.Command, format.Dim.Sprintf("(%s)", result.Hook.Source))
+		if result.Output != "" {
+			fmt.Print(result.Output)
+			if !strings.HasSuffix(result.Output, "\n") {
+				fmt.Println()
+			}
+		}
+		if result.Err != nil {
+			failed++
+			fmt.Printf("%s %v after %s\n\n", format.Red.Sprint("✗ failed:"), result.Err, result.Duration.Round(time.Millisecond))
+			continue
+		}
+		fmt.Printf("%s in %s\n\n", format.Green.Sprint("✓ succeeded"), result.Duration.Round(time.Millisecond))
+	}
+	if failed > 0 {
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("%d of %d %s hook(s) failed", failed, len(lifecycleHooks), eventName))
+	}
+	return nil
+}
+
+// syntheticLifecycleEvent builds a plausible event for hooks test, using
+// --agent and --task where given
+func syntheticLifecycleEvent(eventName, repoName string, flags map[string]string) hooks.Event {
+	event := hooks.Event{Name: eventName, Repo: repoName, Synthetic: true, Time: time.Now()}
+	if eventName == hooks.EventRepoInitialized {
+		return event
+	}
+
+	event.Agent = flags["agent"]
+	if event.Agent == "" {
+		event.Agent = "test-worker"
+	}
+	event.AgentType = "worker"
+	event.Branch = "work/" + event.Agent
+	event.Task = flags["task"]
+	if event.Task == "" {
+		event.Task = "Synthetic task for testing lifecycle hooks"
+	}
+	switch eventName {
+	case hooks.EventWorkerCompleted:
+		event.Summary = "Synthetic completion report"
+		event.PRURL = "https://github.com/example/repo/pull/1"
+	case hooks.EventAgentRemoved:
+		event.Reason = "removed"
+	}
+	return event
+}
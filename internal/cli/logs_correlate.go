@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/messages"
+)
+
+// correlatedLine is a line of one of two merged logs
+type correlatedLine struct {
+	Agent string
+	Text  string
+	// Time is the line's timestamp, or that of the nearest timestamped line
+	// above it; zero before the first one
+	Time time.Time
+}
+
+// extractHighlightMessagesFlag removes --highlight-messages, which takes no
+// value, from args so that ParseFlags does not take an agent name following
+// it as its value
+func extractHighlightMessagesFlag(args []string) (bool, []string) {
+	highlight := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--highlight-messages" || arg == "--highlight-messages=true" {
+			highlight = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return highlight, rest
+}
+
+// correlateLogs prints two agents' logs interleaved by timestamp, each line
+// prefixed with the agent that wrote it
+func (c *CLI) correlateLogs(args []string) error {
+	highlight, args := extractHighlightMessagesFlag(args)
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 2 {
+		return errors.InvalidUsage("usage: multiclaude logs correlate <agent-a> <agent-b> [--repo <repo>] [--since <duration>] [--highlight-messages]")
+	}
+	agentA, agentB := posArgs[0], posArgs[1]
+	if agentA == agentB {
+		return errors.InvalidUsage("logs correlate needs two different agents")
+	}
+
+	var since time.Time
+	if s, ok := flags["since"]; ok {
+		d, err := parseDuration(s)
+		if err != nil {
+			return errors.InvalidDuration(s)
+		}
+		since = time.Now().Add(-d)
+	}
+
+	repoName, logA, err := c.resolveAgentLogFile(agentA, flags)
+	if err != nil {
+		return err
+	}
+	// Both logs come from the same repository
+	flags["repo"] = repoName
+	_, logB, err := c.resolveAgentLogFile(agentB, flags)
+	if err != nil {
+		return err
+	}
+	linesA, err := readLogLines(logA)
+	if err != nil {
+		return fmt.Errorf("failed to read log file: %w", err)
+	}
+	linesB, err := readLogLines(logB)
+	if err != nil {
+		return fmt.Errorf("failed to read log file: %w", err)
+	}
+
+	var exchanged map[string]*messages.Message
+	if highlight {
+		exchanged, err = c.messagesBetween(repoName, agentA, agentB)
+		if err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to read messages", err)
+		}
+	}
+
+	merged := mergeLogs(agentA, linesA, agentB, linesB)
+	colors := map[string]func(a ...interface{}) string{
+		agentA: format.Cyan.SprintFunc(),
+		agentB: format.Magenta.SprintFunc(),
+	}
+	redactor := c.secretsRedactor(repoName)
+	printed := 0
+	for _, line := range merged {
+		if !since.IsZero() && line.Time.Before(since) {
+			continue
+		}
+		text := line.Text
+		if redactor != nil {
+			text = redactor.Secrets(text)
+		}
+		prefix := colors[line.Agent]("[" + line.Agent + "]")
+		if msg := mentionedMessage(line.Text, exchanged); msg != nil {
+			fmt.Printf("%s %s %s\n", prefix, format.Yellow.Sprint("✉"), text)
+			fmt.Printf("%s   %s\n", strings.Repeat(" ", len(line.Agent)+2), format.Yellow.Sprintf("message %s: %s → %s", msg.ID, msg.From, msg.To))
+		} else {
+			fmt.Printf("%s %s\n", prefix, text)
+		}
+		printed++
+	}
+	if printed == 0 {
+		fmt.Println("No log lines to show")
+	}
+	return nil
+}
+
+// mergeLogs interleaves two logs by timestamp. Lines without a timestamp
+// take the previous line's, so they stay with the line they continue, and
+// each log keeps its own order; on equal times agent A's line comes first.
+func mergeLogs(agentA string, linesA []string, agentB string, linesB []string) []correlatedLine {
+	a := stampLines(agentA, linesA)
+	b := stampLines(agentB, linesB)
+	merged := make([]correlatedLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if b[j].Time.Before(a[i].Time) {
+			merged = append(merged, b[j])
+			j++
+		} else {
+			merged = append(merged, a[i])
+			i++
+		}
+	}
+	merged = append(merged, a[i:]...)
+	return append(merged, b[j:]...)
+}
+
+// stampLines gives each line of an agent's log the timestamp it was
+// written at, as far as the log tells
+func stampLines(agent string, lines []string) []correlatedLine {
+	stamped := make([]correlatedLine, len(lines))
+	var current time.Time
+	for i, line := range lines {
+		if t, ok := parseLineTimestamp(line); ok {
+			current = t
+		}
+		stamped[i] = correlatedLine{Agent: agent, Text: line, Time: current}
+	}
+	return stamped
+}
+
+// messagesBetween returns the messages either agent sent the other, by ID
+func (c *CLI) messagesBetween(repoName, agentA, agentB string) (map[string]*messages.Message, error) {
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	exchanged := make(map[string]*messages.Message)
+	for _, pair := range [][2]string{{agentA, agentB}, {agentB, agentA}} {
+		inbox, err := msgMgr.List(repoName, pair[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range inbox {
+			if msg.From == pair[1] {
+				exchanged[msg.ID] = msg
+			}
+		}
+	}
+	return exchanged, nil
+}
+
+// mentionedMessage returns the message of exchanged whose ID appears in
+// line, if any
+func mentionedMessage(line string, exchanged map[string]*messages.Message) *messages.Message {
+	for id, msg := range exchanged {
+		if strings.Contains(line, id) {
+			return msg
+		}
+	}
+	return nil
+}
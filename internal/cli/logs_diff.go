@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+)
+
+// ansiEscape matches terminal escape sequences captured by tmux pipe-pane
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07]*\x07`)
+
+// logLineTimeLayouts are the timestamp formats recognised at the start of a
+// log line. The second is what the standard library logger (and therefore the
+// daemon log) writes.
+var logLineTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+}
+
+// diffLogs prints the lines of an agent's log that were written within a
+// time window
+func (c *CLI) diffLogs(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	usage := "usage: multiclaude logs diff <agent-name> [--repo <repo>] [--after <time>] [--before <time>] [--context N]"
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage(usage)
+	}
+	agentName := posArgs[0]
+
+	afterStr, hasAfter := flags["after"]
+	beforeStr, hasBefore := flags["before"]
+	if !hasAfter && !hasBefore {
+		return errors.InvalidUsage("at least one of --after or --before is required\n" + usage)
+	}
+
+	now := time.Now()
+	var start time.Time
+	end := now
+	if hasAfter {
+		t, err := parseLogTime(afterStr, now)
+		if err != nil {
+			return errors.InvalidTime(afterStr)
+		}
+		start = t
+	}
+	if hasBefore {
+		t, err := parseLogTime(beforeStr, now)
+		if err != nil {
+			return errors.InvalidTime(beforeStr)
+		}
+		end = t
+	}
+	if !start.IsZero() && end.Before(start) {
+		return errors.InvalidUsage("--before must not be earlier than --after")
+	}
+
+	context := 0
+	if v, ok := flags["context"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return errors.InvalidUsage("--context must be a non-negative integer")
+		}
+		context = n
+	}
+
+	repoName, logFile, err := c.resolveAgentLogFile(agentName, flags)
+	if err != nil {
+		return err
+	}
+
+	lines, err := readLogLines(logFile)
+	if err != nil {
+		return fmt.Errorf("failed to read log file: %w", err)
+	}
+
+	out, timestamped := logWindow(lines, start, end, context)
+	if !timestamped {
+		fmt.Printf("No timestamped lines found in %s\n", logFile)
+		return nil
+	}
+	if len(out) == 0 {
+		fmt.Println("No log lines in the requested time window")
+		return nil
+	}
+
+	// Values from the repo's env files must never be shown
+	redactor := c.secretsRedactor(repoName)
+	for _, line := range out {
+		if redactor != nil {
+			line = redactor.Secrets(line)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// parseLogTime parses a --before/--after value: "15:04:05" for a time today,
+// RFC3339 for an absolute time, or "<duration> ago" (e.g. "30m ago")
+func parseLogTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	if rel, ok := strings.CutSuffix(s, " ago"); ok {
+		d, err := parseDuration(strings.TrimSpace(rel))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-d), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	clock, err := time.ParseInLocation("15:04:05", s, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unrecognised time: %s", s)
+	}
+	y, m, d := now.Date()
+	return time.Date(y, m, d, clock.Hour(), clock.Minute(), clock.Second(), 0, now.Location()), nil
+}
+
+// parseLineTimestamp extracts the timestamp at the start of a log line, if
+// any. Terminal escape sequences and an opening bracket are skipped first.
+func parseLineTimestamp(line string) (time.Time, bool) {
+	t, _, ok := splitLineTimestamp(ansiEscape.ReplaceAllString(line, ""))
+	return t, ok
+}
+
+// splitLineTimestamp parses the timestamp at the start of a line already
+// stripped of escape sequences, optionally in brackets, and returns it along
+// with the rest of the line
+func splitLineTimestamp(line string) (time.Time, string, bool) {
+	s := strings.TrimLeft(line, " \t")
+	bracketed := strings.HasPrefix(s, "[")
+	s = strings.TrimPrefix(s, "[")
+	rest := func(s string) string {
+		if bracketed {
+			s = strings.TrimPrefix(s, "]")
+		}
+		return strings.TrimLeft(s, " \t")
+	}
+
+	// RFC3339 has no fixed width, so take the first field
+	if field, _, _ := strings.Cut(s, " "); field != "" {
+		trimmed := strings.TrimRight(field, "]")
+		if t, err := time.Parse(time.RFC3339Nano, trimmed); err == nil {
+			return t, rest(s[len(trimmed):]), true
+		}
+	}
+
+	for _, layout := range logLineTimeLayouts {
+		if len(s) < len(layout) {
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, s[:len(layout)], time.Local); err == nil {
+			return t, rest(s[len(layout):]), true
+		}
+	}
+	return time.Time{}, "", false
+}
+
+// readLogLines reads a log file into memory, one entry per line
+func readLogLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// logWindow selects the lines stamped within [start, end] (a zero start means
+// no lower bound) along with context lines around each block, separating
+// non-adjacent blocks with "--" as grep does. Lines without a timestamp take
+// the one from the nearest stamped line above them. Every line is prefixed
+// with the seconds elapsed since the start of the window, or since the first
+// selected line when there is no start. The second return value reports
+// whether the log had any timestamps at all.
+func logWindow(lines []string, start, end time.Time, context int) ([]string, bool) {
+	stamps := make([]time.Time, len(lines))
+	var current time.Time
+	for i, line := range lines {
+		if t, ok := parseLineTimestamp(line); ok {
+			current = t
+		}
+		stamps[i] = current
+	}
+	if current.IsZero() {
+		return nil, false
+	}
+
+	origin := start
+	include := make([]bool, len(lines))
+	for i, ts := range stamps {
+		if ts.IsZero() || ts.Before(start) || ts.After(end) {
+			continue
+		}
+		if origin.IsZero() {
+			origin = ts
+		}
+		for j := max(0, i-context); j <= min(len(lines)-1, i+context); j++ {
+			include[j] = true
+		}
+	}
+
+	var out []string
+	last := -1
+	for i, line := range lines {
+		if !include[i] {
+			continue
+		}
+		if context > 0 && last >= 0 && i > last+1 {
+			out = append(out, "--")
+		}
+		last = i
+
+		prefix := "[?]"
+		if !stamps[i].IsZero() {
+			prefix = fmt.Sprintf("[%+ds]", int(stamps[i].Sub(origin).Seconds()))
+		}
+		out = append(out, prefix+" "+line)
+	}
+	return out, true
+}
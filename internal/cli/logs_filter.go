@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/logfilter"
+	"github.com/dlorenc/multiclaude/internal/redact"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// captureLog reads a pane's output from stdin and appends it, filtered, to
+// a log file. tmux pipe-pane runs it for every agent whose repository does
+// not keep raw logs. Values from the repository's env files are redacted
+// before they reach the file.
+func (c *CLI) captureLog(args []string) error {
+	if len(args) != 1 {
+		return errors.InvalidUsage("usage: multiclaude logs _capture <log-file>")
+	}
+	logFile := args[0]
+
+	var rewrite func(string) string
+	if repoName := c.logFileRepo(logFile); repoName != "" {
+		if redactor := c.secretsRedactor(repoName); redactor != nil {
+			rewrite = redactor.Secrets
+		}
+	}
+	return logfilter.Capture(os.Stdin, logFile, rewrite)
+}
+
+// logFileRepo returns the repository whose output directory holds a log
+// file, or "" for a file outside them
+func (c *CLI) logFileRepo(logFile string) string {
+	rel, err := filepath.Rel(c.paths.OutputDir, logFile)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	repoName, _, found := strings.Cut(filepath.ToSlash(rel), "/")
+	if !found {
+		return ""
+	}
+	return repoName
+}
+
+// startOutputCapture pipes a window's output into logFile: through the log
+// filter, or unchanged when the repository keeps raw logs. Test mode keeps
+// raw logs too, as the running binary there is a test binary without a
+// logs _capture command.
+func startOutputCapture(ctx context.Context, client *tmux.Client, session, window, logFile string, rawLogs bool) error {
+	command, err := logfilter.PipeCommand(logFile, rawLogs || os.Getenv("MULTICLAUDE_TEST_MODE") == "1")
+	if err != nil {
+		return err
+	}
+	return client.StartPipePaneCommand(ctx, session, window, command)
+}
+
+func (c *CLI) searchLogs(args []string) error {
+	if len(args) < 1 {
+		return errors.InvalidUsage("usage: multiclaude logs search <pattern> [--repo <repo>]")
+	}
+
+	pattern := args[0]
+	flags, _ := ParseFlags(args[1:])
+
+	// Determine repository
+	var repoName string
+	if r, ok := flags["repo"]; ok {
+		repoName = r
+	}
+
+	// Get search directories
+	var searchPaths []string
+	var searchRepos []string
+	if repoName != "" {
+		repoOutputDir := c.paths.RepoOutputDir(repoName)
+		if _, err := os.Stat(repoOutputDir); err == nil {
+			searchPaths = append(searchPaths, repoOutputDir)
+			searchRepos = append(searchRepos, repoName)
+		}
+	} else {
+		// Search all repos
+		repos := c.getReposList()
+		for _, repo := range repos {
+			repoOutputDir := c.paths.RepoOutputDir(repo)
+			if _, err := os.Stat(repoOutputDir); err == nil {
+				searchPaths = append(searchPaths, repoOutputDir)
+				searchRepos = append(searchRepos, repo)
+			}
+		}
+	}
+
+	if len(searchPaths) == 0 {
+		fmt.Println("No log directories found")
+		return nil
+	}
+
+	// A pattern that is not a valid regular expression is searched for as is
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		re = regexp.MustCompile(regexp.QuoteMeta(pattern))
+	}
+	redactor := c.secretsRedactor(searchRepos...)
+
+	matches := 0
+	for _, dir := range searchPaths {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || filepath.Ext(path) != ".log" {
+				return nil
+			}
+			n, err := searchLogFile(os.Stdout, path, re, redactor)
+			matches += n
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to search logs: %w", err)
+		}
+	}
+
+	if matches == 0 {
+		fmt.Println("No matches found")
+	}
+	return nil
+}
+
+// searchLogFile writes the lines of a log matching re to w as
+// path:line:text, and returns how many matched. The log is read through the
+// same filter as output capture, so a raw log's escape sequences do not
+// split matches and its redraws match once; line numbers count the
+// filtered lines.
+func searchLogFile(w io.Writer, path string, re *regexp.Regexp, redactor *redact.Redactor) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var filtered bytes.Buffer
+	filter := logfilter.New(&filtered)
+	if _, err := io.Copy(filter, file); err != nil {
+		return 0, err
+	}
+	if err := filter.Flush(); err != nil {
+		return 0, err
+	}
+	if filtered.Len() == 0 {
+		return 0, nil
+	}
+
+	matches := 0
+	for i, line := range strings.Split(strings.TrimSuffix(filtered.String(), "\n"), "\n") {
+		if !re.MatchString(line) {
+			continue
+		}
+		if redactor != nil {
+			line = redactor.Secrets(line)
+		}
+		fmt.Fprintf(w, "%s:%d:%s\n", path, i+1, line)
+		matches++
+	}
+	return matches, nil
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+)
+
+// defaultLogTemplate is the logs format template used without --template
+const defaultLogTemplate = "{{.Time}}{{if .Level}} {{.Level}}{{end}} {{.Message}}"
+
+// logLevels are the level names recognised after a log line's timestamp
+var logLevels = map[string]bool{
+	"DEBUG": true, "INFO": true, "WARN": true, "WARNING": true, "ERROR": true, "FATAL": true,
+}
+
+// logTime is the timestamp of a log line. It prints as "2006-01-02 15:04:05"
+// (nothing when unset) and has all of time.Time's methods, e.g.
+// {{.Time.Format "15:04"}} in a template.
+type logTime struct {
+	time.Time
+}
+
+// String formats the timestamp for templates
+func (t logTime) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// logLine is a log line split into the parts a logs format template can use
+type logLine struct {
+	Time    logTime
+	Level   string // e.g. INFO; empty when the line has none
+	Message string // The text after the timestamp and level, without escape sequences
+	RawText string // The line as written to the log
+}
+
+// parseLogLine splits a log line into its timestamp, level and message. It
+// reports false for lines that do not start with a timestamp.
+func parseLogLine(raw string) (logLine, bool) {
+	line := logLine{RawText: raw}
+	t, rest, ok := splitLineTimestamp(ansiEscape.ReplaceAllString(raw, ""))
+	if !ok {
+		return line, false
+	}
+	line.Time = logTime{t}
+
+	// The level is a bracketed or bare word, optionally followed by a colon:
+	// "[INFO] msg", "INFO msg" or "info: msg"
+	field, after, _ := strings.Cut(rest, " ")
+	word := strings.TrimSuffix(field, ":")
+	if strings.HasPrefix(word, "[") && strings.HasSuffix(word, "]") {
+		word = word[1 : len(word)-1]
+	}
+	if level := strings.ToUpper(word); logLevels[level] {
+		line.Level = level
+		rest = strings.TrimLeft(after, " \t")
+	}
+	line.Message = rest
+	return line, true
+}
+
+// formatLogs prints an agent's log with escape sequences removed, each line
+// with a timestamp rendered through a template
+func (c *CLI) formatLogs(args []string) error {
+	stripOnly, args := extractStripANSIFlag(args)
+	flags, posArgs := ParseFlags(args)
+
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude logs format <agent-name> [--repo <repo>] [--template '<template>'] [--strip-ansi]")
+	}
+	agentName := posArgs[0]
+
+	text, hasTemplate := flags["template"]
+	if stripOnly && hasTemplate {
+		return errors.InvalidUsage("--strip-ansi cannot be combined with --template")
+	}
+	if !hasTemplate {
+		text = defaultLogTemplate
+	}
+	tmpl, err := template.New("log").Parse(text)
+	if err != nil {
+		return errors.InvalidUsage(fmt.Sprintf("invalid --template: %v", err))
+	}
+
+	repoName, logFile, err := c.resolveAgentLogFile(agentName, flags)
+	if err != nil {
+		return err
+	}
+	lines, err := readLogLines(logFile)
+	if err != nil {
+		return fmt.Errorf("failed to read log file: %w", err)
+	}
+
+	// Values from the repo's env files must never be shown
+	redactor := c.secretsRedactor(repoName)
+
+	for _, raw := range lines {
+		line, ok := parseLogLine(raw)
+		var formatted string
+		if stripOnly || !ok {
+			// Lines without a timestamp are passed through, escape codes aside
+			formatted = ansiEscape.ReplaceAllString(raw, "")
+		} else {
+			var sb strings.Builder
+			if err := tmpl.Execute(&sb, line); err != nil {
+				return errors.InvalidUsage(fmt.Sprintf("invalid --template: %v", err))
+			}
+			formatted = sb.String()
+		}
+		if redactor != nil {
+			formatted = redactor.Secrets(formatted)
+		}
+		fmt.Println(formatted)
+	}
+	return nil
+}
+
+// extractStripANSIFlag removes --strip-ansi, which takes no value, from args
+// so that ParseFlags does not take the agent name following it as its value
+func extractStripANSIFlag(args []string) (bool, []string) {
+	strip := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--strip-ansi" || arg == "--strip-ansi=true" {
+			strip = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return strip, rest
+}
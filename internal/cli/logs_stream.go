@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/logstream"
+)
+
+// defaultLogStreamPort is the port logs stream listens on without --port
+const defaultLogStreamPort = 7890
+
+// streamLogs serves new agent log lines to WebSocket clients until
+// interrupted, or with --exit-on-empty until the last client disconnects
+func (c *CLI) streamLogs(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	port := defaultLogStreamPort
+	if value, ok := flags["port"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 65535 {
+			return errors.InvalidUsage(fmt.Sprintf("invalid --port value: %q (must be a port number between 1 and 65535)", value))
+		}
+		port = n
+	}
+	repoFilter := flags["repo"]
+	agentFilter := flags["agent"]
+	exitOnEmpty := flags["exit-on-empty"] == "true"
+
+	// Values from the repos' env files must never be streamed
+	redactor := c.secretsRedactor(c.getReposList()...)
+
+	server := logstream.NewServer(logstream.Options{
+		Sources: func() []logstream.Source {
+			return c.logStreamSources(repoFilter, agentFilter)
+		},
+		Rewrite: func(repo, line string) string {
+			if redactor == nil {
+				return line
+			}
+			return redactor.Secrets(line)
+		},
+	})
+
+	// Only local tools may read the logs
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to listen on port %d", port), err).
+			WithSuggestion("choose another port with --port <n>")
+	}
+	httpServer := &http.Server{Handler: server.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go server.Run(ctx)
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- httpServer.Serve(listener) }()
+
+	fmt.Printf("Streaming logs on ws://%s/ws (Ctrl-C to stop)\n", listener.Addr())
+	if exitOnEmpty {
+		fmt.Println("The stream stops when the last client disconnects.")
+	}
+
+	var empty <-chan struct{}
+	if exitOnEmpty {
+		empty = server.Empty()
+	}
+	select {
+	case <-ctx.Done():
+	case <-empty:
+	case err := <-serveErr:
+		return errors.Wrap(errors.CategoryRuntime, "log stream server failed", err)
+	}
+
+	stop()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return httpServer.Shutdown(shutdownCtx)
+}
+
+// logStreamSources lists the agent logs to stream: those of every
+// repository, or of repoFilter, optionally only agentFilter's
+func (c *CLI) logStreamSources(repoFilter, agentFilter string) []logstream.Source {
+	var sources []logstream.Source
+	c.paths.WalkLogFiles(func(path string, info os.FileInfo) error {
+		rel, err := filepath.Rel(c.paths.OutputDir, path)
+		if err != nil {
+			return nil
+		}
+		// Logs are <repo>/<agent>.log, or <repo>/workers/<agent>.log
+		parts := strings.Split(rel, string(filepath.Separator))
+		switch {
+		case len(parts) == 2:
+		case len(parts) == 3 && parts[1] == "workers":
+		default:
+			return nil
+		}
+		repo, agent := parts[0], strings.TrimSuffix(parts[len(parts)-1], ".log")
+		if (repoFilter != "" && repo != repoFilter) || (agentFilter != "" && agent != agentFilter) {
+			return nil
+		}
+		sources = append(sources, logstream.Source{Repo: repo, Agent: agent, Path: path})
+		return nil
+	})
+	return sources
+}
@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// scheduleLayouts are the absolute times accepted by send-message --schedule,
+// interpreted in the local time zone
+var scheduleLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+}
+
+// extractScheduleFlag removes --schedule <time> / --schedule=<time> from
+// send-message arguments and returns its value. It runs before the message
+// text is joined, so the flag may appear anywhere after the recipient.
+func extractScheduleFlag(args []string) (string, []string, error) {
+	return extractMessageFlag(args, "--schedule", "time")
+}
+
+// extractIdempotencyKeyFlag removes --idempotency-key <key> /
+// --idempotency-key=<key> from send-message arguments, like
+// extractScheduleFlag
+func extractIdempotencyKeyFlag(args []string) (string, []string, error) {
+	return extractMessageFlag(args, "--idempotency-key", "key")
+}
+
+// extractMessageFlag removes a flag taking a value from send-message
+// arguments and returns the value; argName names the value in errors
+func extractMessageFlag(args []string, flag, argName string) (string, []string, error) {
+	value := ""
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == flag:
+			if i+1 >= len(args) {
+				return "", nil, errors.MissingArgument(flag, argName)
+			}
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(arg, flag+"="):
+			value = strings.TrimPrefix(arg, flag+"=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return value, rest, nil
+}
+
+// parseSchedule parses a --schedule value: a delay relative to now such as
+// "+2h", "+30m" or "+1d", or a local time such as "2024-01-15 09:00" (RFC3339
+// is accepted too). The time must be in the future.
+func parseSchedule(value string, now time.Time) (time.Time, error) {
+	var at time.Time
+	if delay, ok := strings.CutPrefix(value, "+"); ok {
+		// Go durations first: parseDuration would read "1h30m" as 1m
+		d, err := time.ParseDuration(delay)
+		if err != nil {
+			if d, err = parseDuration(delay); err != nil {
+				return time.Time{}, errors.InvalidSchedule(value)
+			}
+		}
+		at = now.Add(d)
+	} else if t, err := time.Parse(time.RFC3339, value); err == nil {
+		at = t
+	} else {
+		parsed := false
+		for _, layout := range scheduleLayouts {
+			if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+				at, parsed = t, true
+				break
+			}
+		}
+		if !parsed {
+			return time.Time{}, errors.InvalidSchedule(value)
+		}
+	}
+
+	if !at.After(now) {
+		return time.Time{}, errors.New(errors.CategoryUsage, fmt.Sprintf("--schedule time %s is not in the future", at.Format(time.RFC3339)))
+	}
+	return at, nil
+}
+
+// cancelMessage deletes a message that has not been delivered yet, typically
+// one scheduled with send-message --schedule
+func (c *CLI) cancelMessage(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude agent cancel-message <message-id> [--repo <repo>]")
+	}
+	messageID := posArgs[0]
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	msg, err := msgMgr.Find(repoName, messageID)
+	if err != nil {
+		return errors.Wrap(errors.CategoryNotFound, "failed to cancel message", err)
+	}
+	if msg.Status != messages.StatusPending {
+		return errors.New(errors.CategoryUsage, fmt.Sprintf("message %s was already delivered to %s and can no longer be cancelled", messageID, msg.To))
+	}
+
+	if err := msgMgr.Delete(repoName, msg.To, messageID); err != nil {
+		return fmt.Errorf("failed to cancel message: %w", err)
+	}
+
+	fmt.Printf("Message %s to %s cancelled\n", messageID, msg.To)
+	return nil
+}
+
+// listScheduledMessages lists messages in a repository waiting for their
+// delivery time
+func (c *CLI) listScheduledMessages(repoName string) error {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "scheduled_messages",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("listing scheduled messages", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to list scheduled messages", fmt.Errorf("%s", resp.Error))
+	}
+
+	scheduled, _ := resp.Data.([]interface{})
+	if len(scheduled) == 0 {
+		fmt.Println("No scheduled messages")
+		return nil
+	}
+
+	format.Header("Scheduled messages in '%s' (%d):", repoName, len(scheduled))
+	fmt.Println()
+
+	table := format.NewColoredTable("ID", "DELIVER AT", "FROM", "TO", "PREVIEW")
+	for _, m := range scheduled {
+		msg, ok := m.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := msg["id"].(string)
+		from, _ := msg["from"].(string)
+		to, _ := msg["to"].(string)
+		body, _ := msg["body"].(string)
+		deliverAt, _ := msg["scheduled_for"].(string)
+		if t, err := time.Parse(time.RFC3339, deliverAt); err == nil {
+			deliverAt = t.Local().Format("2006-01-02 15:04")
+		}
+		table.AddRow(
+			format.ColorCell(id, format.Dim),
+			format.Cell(deliverAt),
+			format.ColorCell(from, format.Cyan),
+			format.ColorCell(to, format.Cyan),
+			format.Cell(format.Truncate(flattenWhitespace(body), 50)),
+		)
+	}
+	table.Print()
+
+	fmt.Println()
+	format.Dimmed("Cancel with: multiclaude agent cancel-message <message-id>")
+	return nil
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/messages"
+)
+
+// formatByteSize renders a size in KB or MB, with a decimal only when the
+// size is not a whole number of them
+func formatByteSize(size int) string {
+	switch {
+	case size >= 1024*1024 && size%(1024*1024) == 0:
+		return fmt.Sprintf("%d MB", size/(1024*1024))
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024 && size%1024 == 0:
+		return fmt.Sprintf("%d KB", size/1024)
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
+
+// messageLimits returns a repository's message size limits, falling back to
+// the defaults when the daemon cannot say
+func (c *CLI) messageLimits(repoName string) messages.Limits {
+	values, err := c.repoConfig(repoName)
+	if err != nil {
+		return messages.Limits{}
+	}
+	maxSize, _ := strconv.Atoi(values["message-max-size"])
+	hardCap, _ := strconv.Atoi(values["message-hard-cap"])
+	return messages.Limits{MaxBodySize: maxSize, HardCap: hardCap}
+}
+
+// messageTooLarge turns a rejected oversized body into an error that
+// suggests sharing the content another way
+func messageTooLarge(tooLarge *messages.BodyTooLargeError) error {
+	return errors.New(errors.CategoryUsage, fmt.Sprintf("message is %s, over the %s limit for messages", formatByteSize(tooLarge.Size), formatByteSize(tooLarge.Cap))).
+		WithSuggestion("write the content to a file and send its path instead, or hand it to a new worker with 'multiclaude work <task> --context-file <path>'")
+}
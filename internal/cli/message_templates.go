@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/messages"
+)
+
+// listMessageTemplates prints the message templates available in a
+// repository: the built-in ones and any in its .multiclaude/messages
+func (c *CLI) listMessageTemplates(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	var templates []messages.Template
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		// Outside a repository only the built-in templates apply
+		templates = messages.BuiltinTemplates()
+	} else {
+		templates, err = messages.LoadTemplates(c.paths.RepoDir(repoName))
+		if err != nil {
+			return errors.Wrap(errors.CategoryConfig, "failed to load message templates", err)
+		}
+	}
+
+	format.Header("Message templates (%d):", len(templates))
+	fmt.Println()
+
+	table := format.NewColoredTable("NAME", "DESCRIPTION", "VARIABLES", "SOURCE")
+	for _, t := range templates {
+		vars := strings.Join(t.Variables(), ", ")
+		if vars == "" {
+			vars = "-"
+		}
+		source := t.Source
+		if source != messages.TemplateSourceBuiltin {
+			source = "repo"
+		}
+		table.AddRow(
+			format.ColorCell(t.Name, format.Cyan),
+			format.Cell(t.Description),
+			format.Cell(vars),
+			format.ColorCell(source, format.Dim),
+		)
+	}
+	table.Print()
+
+	fmt.Println()
+	format.Dimmed("Send with: multiclaude agent send-message <to> --template <name> [--var key=value]...")
+	format.Dimmed("Add repo templates as .multiclaude/messages/<name>.md")
+	return nil
+}
+
+// isTemplateMessage reports whether send-message arguments (after the
+// recipient) select a template rather than giving the message text
+func isTemplateMessage(args []string) bool {
+	for _, arg := range args {
+		if arg == "--template" || strings.HasPrefix(arg, "--template=") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTemplateArgs parses "--template <name>" and repeated "--var key=value"
+// arguments. ParseFlags keeps only the last value of a repeated flag, so
+// these are parsed here.
+func parseTemplateArgs(args []string) (string, map[string]string, error) {
+	usage := "usage: multiclaude agent send-message <to> --template <name> [--var key=value]..."
+
+	var name string
+	vars := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		flag, value, hasValue := strings.Cut(args[i], "=")
+		if flag != "--template" && flag != "--var" {
+			return "", nil, errors.InvalidUsage(fmt.Sprintf("unexpected argument %q with --template\n%s", args[i], usage))
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return "", nil, errors.InvalidUsage(fmt.Sprintf("%s requires a value\n%s", flag, usage))
+			}
+			i++
+			value = args[i]
+		}
+
+		if flag == "--template" {
+			name = value
+			continue
+		}
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return "", nil, errors.InvalidUsage(fmt.Sprintf("--var must be key=value, got %q", value))
+		}
+		vars[key] = val
+	}
+
+	if name == "" {
+		return "", nil, errors.InvalidUsage(usage)
+	}
+	return name, vars, nil
+}
+
+// renderMessageTemplate renders a repository's message template. The from,
+// to and repo variables are filled in automatically unless given explicitly.
+func (c *CLI) renderMessageTemplate(repoName, from, to, name string, vars map[string]string) (string, error) {
+	tmpl, found, err := messages.FindTemplate(c.paths.RepoDir(repoName), name)
+	if err != nil {
+		return "", errors.Wrap(errors.CategoryConfig, "failed to load message templates", err)
+	}
+	if !found {
+		return "", errors.MessageTemplateNotFound(name)
+	}
+
+	values := map[string]string{"from": from, "to": to, "repo": repoName}
+	for k, v := range vars {
+		values[k] = v
+	}
+
+	body, err := tmpl.Render(values)
+	if missingErr, ok := err.(*messages.MissingVariablesError); ok {
+		return "", errors.MissingTemplateVariables(name, missingErr.Missing)
+	}
+	return body, err
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/messages"
+)
+
+// exportMessages writes every message of an agent to one NDJSON file, for
+// backing them up or moving the agent to another machine
+func (c *CLI) exportMessages(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude agent export-messages <agent> --output <file> [--repo <repo>]")
+	}
+	agentName := posArgs[0]
+	output := flags["output"]
+	if output == "" || output == "true" {
+		return errors.MissingArgument("--output", "file")
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	msgs, err := msgMgr.List(repoName, agentName)
+	if err != nil {
+		return fmt.Errorf("failed to list messages: %w", err)
+	}
+	if err := msgMgr.ExportToFile(repoName, agentName, output); err != nil {
+		return fmt.Errorf("failed to export messages: %w", err)
+	}
+
+	fmt.Printf("Exported %d message(s) of %s to %s\n", len(msgs), agentName, output)
+	return nil
+}
+
+// importMessages writes the messages in an export-messages file back, to
+// the agent and repository they came from unless --agent or --repo says
+// otherwise. Messages already there are skipped.
+func (c *CLI) importMessages(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude agent import-messages <file> [--agent <name>] [--repo <repo>]")
+	}
+	path := posArgs[0]
+
+	agentName := flags["agent"]
+	if agentName == "true" {
+		return errors.MissingArgument("--agent", "name")
+	}
+	repoName := flags["repo"]
+	if repoName == "true" {
+		return errors.MissingArgument("--repo", "name")
+	}
+
+	n, err := messages.NewManager(c.paths.MessagesDir).ImportFromFileAs(path, repoName, agentName)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to import messages from %s", filepath.Base(path)), err)
+	}
+
+	fmt.Printf("Imported %d message(s) from %s; messages already present were skipped\n", n, path)
+	return nil
+}
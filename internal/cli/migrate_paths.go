@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/daemon"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/config"
+)
+
+// daemonMigratePaths updates the absolute paths recorded under a
+// multiclaude directory after it was moved from --old-root to --new-root:
+// the paths in state.json, the path overrides in paths.json, the generated
+// prompt files and git's links between each repository and its worktrees.
+// The daemon socket, PID file and logs live at fixed places under the root,
+// so they need no change.
+func (c *CLI) daemonMigratePaths(args []string) error {
+	flags, _ := ParseFlags(args)
+	dryRun := flags["dry-run"] == "true"
+
+	var roots [2]string
+	for i, name := range []string{"old-root", "new-root"} {
+		value := flags[name]
+		if value == "" || value == "true" {
+			return errors.MissingArgument("--"+name, "path")
+		}
+		abs, err := filepath.Abs(value)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", value, err)
+		}
+		roots[i] = abs
+	}
+	oldRoot, newRoot := roots[0], roots[1]
+	if oldRoot == newRoot {
+		return errors.InvalidUsage("--old-root and --new-root are the same directory")
+	}
+
+	newPaths := config.NewPaths(newRoot)
+
+	// The daemon rewrites state.json as it runs, so it must not be running
+	// from either root (or from the default one) while paths are migrated
+	if !dryRun {
+		for _, pidPath := range uniqueStrings(c.paths.DaemonPID, newPaths.DaemonPID, filepath.Join(oldRoot, "daemon.pid")) {
+			if running, pid, _ := daemon.NewPIDFile(pidPath).IsRunning(); running {
+				return errors.DaemonMustBeStopped("migrating paths", pid)
+			}
+		}
+	}
+
+	if _, err := os.Stat(newPaths.StateFile); err != nil {
+		return errors.New(errors.CategoryNotFound, fmt.Sprintf("no state.json in %s", newRoot)).
+			WithSuggestion("move the multiclaude directory to --new-root first, then run migrate-paths")
+	}
+
+	st, err := state.Load(newPaths.StateFile)
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	changes, err := st.MigrateRoot(oldRoot, newRoot, dryRun)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to save migrated state", err)
+	}
+
+	overrideChanges, err := migratePathOverrides(newRoot, oldRoot, dryRun)
+	if err != nil {
+		return err
+	}
+	promptFiles, err := migratePromptFiles(filepath.Join(newRoot, "prompts"), oldRoot, newRoot, dryRun)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		format.Header("Paths that would change (%s -> %s):", oldRoot, newRoot)
+	} else {
+		format.Header("Migrated paths (%s -> %s):", oldRoot, newRoot)
+	}
+	if len(changes)+len(overrideChanges)+len(promptFiles) == 0 {
+		fmt.Println("  Nothing refers to the old root")
+	}
+
+	// Each new path should exist now that the directory has moved; a
+	// missing one usually means a worktree was already gone before the move
+	missing := 0
+	printChange := func(where, oldPath, newPath string) {
+		mark := format.Green.Sprint("✓")
+		if _, err := os.Stat(newPath); err != nil {
+			mark = format.Red.Sprint("✗")
+			missing++
+		}
+		fmt.Printf("  %s %s\n", mark, where)
+		format.Dimmed("      %s\n      -> %s", oldPath, newPath)
+	}
+	for _, change := range changes {
+		printChange(agentLabel(change.Repo, change.Agent)+" "+change.Field, change.Old, change.New)
+	}
+	for _, change := range overrideChanges {
+		printChange(config.PathsConfigFile+" "+change.Field, change.Old, change.New)
+	}
+	for _, path := range promptFiles {
+		fmt.Printf("  %s prompt %s\n", format.Green.Sprint("✓"), filepath.Base(path))
+	}
+
+	if missing > 0 {
+		fmt.Printf("\nWarning: %d new path(s) do not exist; 'multiclaude repair' cleans up agents whose worktrees are gone\n", missing)
+	}
+
+	if dryRun {
+		format.Dimmed("\nDry run: nothing was changed. Run without --dry-run to apply.")
+		return nil
+	}
+
+	repairWorktreeLinks(st, newPaths)
+
+	fmt.Println("\n✓ Paths migrated")
+	if resolved, err := filepath.EvalSymlinks(c.paths.Root); err != nil || resolved != newRoot {
+		format.Dimmed("multiclaude uses %s; make it a symlink to %s before starting the daemon", c.paths.Root, newRoot)
+	}
+	return nil
+}
+
+// migratePathOverrides rebases the directories in the root's paths.json
+// that lie under the old root
+func migratePathOverrides(root, oldRoot string, dryRun bool) ([]state.PathChange, error) {
+	configPath := filepath.Join(root, config.PathsConfigFile)
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+
+	var overrides config.PathOverrides
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", configPath, err)
+	}
+
+	var changes []state.PathChange
+	for _, field := range []struct {
+		name string
+		dir  *string
+	}{
+		{"output_dir", &overrides.OutputDir},
+		{"worktrees_dir", &overrides.WorktreesDir},
+	} {
+		if *field.dir == "" {
+			continue
+		}
+		if rebased, ok := config.RebasePath(*field.dir, oldRoot, root); ok {
+			changes = append(changes, state.PathChange{Field: field.name, Old: *field.dir, New: rebased})
+			*field.dir = rebased
+		}
+	}
+	if dryRun || len(changes) == 0 {
+		return changes, nil
+	}
+
+	data, err = json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal path overrides: %w", err)
+	}
+	if err := os.WriteFile(configPath, append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", configPath, err)
+	}
+	return changes, nil
+}
+
+// migratePromptFiles rewrites references to the old root in the prompt
+// files written for agents. It returns the files that refer to the old root.
+func migratePromptFiles(promptDir, oldRoot, newRoot string, dryRun bool) ([]string, error) {
+	entries, err := os.ReadDir(promptDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompts directory: %w", err)
+	}
+
+	oldPrefix := oldRoot + string(filepath.Separator)
+	newPrefix := newRoot + string(filepath.Separator)
+	var changed []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+		path := filepath.Join(promptDir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return changed, fmt.Errorf("failed to read prompt file: %w", err)
+		}
+		if !strings.Contains(string(data), oldPrefix) {
+			continue
+		}
+		changed = append(changed, path)
+		if dryRun {
+			continue
+		}
+		updated := strings.ReplaceAll(string(data), oldPrefix, newPrefix)
+		if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+			return changed, fmt.Errorf("failed to write prompt file: %w", err)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// repairWorktreeLinks points git's links between each repository and its
+// worktrees at their new locations. Both sides record absolute paths, so
+// every worktree looks broken to git after the directory moves.
+func repairWorktreeLinks(st *state.State, paths *config.Paths) {
+	for repoName, repo := range st.GetAllRepos() {
+		repoPath := paths.RepoDir(repoName)
+		var worktrees []string
+		for _, agent := range repo.Agents {
+			if agent.WorktreePath == "" || agent.WorktreePath == repoPath {
+				continue
+			}
+			if _, err := os.Stat(agent.WorktreePath); err == nil {
+				worktrees = append(worktrees, agent.WorktreePath)
+			}
+		}
+		if len(worktrees) == 0 {
+			continue
+		}
+		sort.Strings(worktrees)
+
+		cmd := exec.Command("git", append([]string{"worktree", "repair"}, worktrees...)...)
+		cmd.Dir = repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			fmt.Printf("Warning: failed to repair worktrees of %s: %v\n%s", repoName, err, output)
+		}
+	}
+}
+
+// uniqueStrings returns its arguments without duplicates, in order
+func uniqueStrings(values ...string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/config"
+)
+
+// setupMovedRoot creates a multiclaude directory with a repository, a
+// worker worktree and a prompt file, then moves it, returning the old and
+// new roots
+func setupMovedRoot(t *testing.T) (string, string) {
+	t.Helper()
+	base := t.TempDir()
+	oldRoot := filepath.Join(base, "old", "multiclaude")
+	newRoot := filepath.Join(base, "new", "multiclaude")
+	paths := config.NewPaths(oldRoot)
+
+	repoPath := paths.RepoDir("repo")
+	setupTestRepo(t, repoPath)
+	wtPath := paths.AgentWorktree("repo", "fox")
+	if output, err := exec.Command("git", "-C", repoPath, "worktree", "add", "-b", "work/fox", wtPath).CombinedOutput(); err != nil {
+		t.Fatalf("git worktree add failed: %v\n%s", err, output)
+	}
+
+	st := state.New(paths.StateFile)
+	if err := st.AddRepo("repo", &state.Repository{
+		GithubURL: "https://github.com/org/repo",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, WorktreePath: repoPath},
+			"fox":        {Type: state.AgentTypeWorker, WorktreePath: wtPath},
+		},
+	}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	promptDir := filepath.Join(oldRoot, "prompts")
+	if err := os.MkdirAll(promptDir, 0755); err != nil {
+		t.Fatalf("Failed to create prompts dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(promptDir, "fox.md"), []byte("Your worktree is "+wtPath+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write prompt: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newRoot), 0755); err != nil {
+		t.Fatalf("Failed to create new parent: %v", err)
+	}
+	if err := os.Rename(oldRoot, newRoot); err != nil {
+		t.Fatalf("Failed to move root: %v", err)
+	}
+	return oldRoot, newRoot
+}
+
+func TestDaemonMigratePaths(t *testing.T) {
+	oldRoot, newRoot := setupMovedRoot(t)
+	newPaths := config.NewPaths(newRoot)
+	cli := NewWithPaths(newPaths)
+	wtPath := newPaths.AgentWorktree("repo", "fox")
+
+	// A dry run changes nothing
+	output := captureStdout(t, func() {
+		if err := cli.Execute([]string{"daemon", "migrate-paths", "--old-root", oldRoot, "--new-root", newRoot, "--dry-run"}); err != nil {
+			t.Errorf("migrate-paths --dry-run failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "repo/fox worktree_path") {
+		t.Errorf("dry run output missing the planned changes:\n%s", output)
+	}
+	st, err := state.Load(newPaths.StateFile)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if repo, _ := st.GetRepo("repo"); !strings.HasPrefix(repo.Agents["fox"].WorktreePath, oldRoot) {
+		t.Error("migrate-paths --dry-run should not change the state")
+	}
+
+	captureStdout(t, func() {
+		if err := cli.Execute([]string{"daemon", "migrate-paths", "--old-root", oldRoot, "--new-root", newRoot}); err != nil {
+			t.Errorf("migrate-paths failed: %v", err)
+		}
+	})
+
+	st, err = state.Load(newPaths.StateFile)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	repo, _ := st.GetRepo("repo")
+	if got := repo.Agents["fox"].WorktreePath; got != wtPath {
+		t.Errorf("fox worktree = %q, want %q", got, wtPath)
+	}
+	if got := repo.Agents["supervisor"].WorktreePath; got != newPaths.RepoDir("repo") {
+		t.Errorf("supervisor worktree = %q, want %q", got, newPaths.RepoDir("repo"))
+	}
+
+	prompt, err := os.ReadFile(filepath.Join(newRoot, "prompts", "fox.md"))
+	if err != nil || !strings.Contains(string(prompt), wtPath) || strings.Contains(string(prompt), oldRoot) {
+		t.Errorf("prompt file = %q, %v; want it to refer to the new root", prompt, err)
+	}
+
+	// git finds the moved worktree again
+	if output, err := exec.Command("git", "-C", wtPath, "status").CombinedOutput(); err != nil {
+		t.Errorf("git status in the moved worktree failed: %v\n%s", err, output)
+	}
+	worktrees, _ := exec.Command("git", "-C", newPaths.RepoDir("repo"), "worktree", "list").CombinedOutput()
+	if !strings.Contains(string(worktrees), wtPath) || strings.Contains(string(worktrees), "prunable") {
+		t.Errorf("git worktree list after migration:\n%s", worktrees)
+	}
+}
+
+func TestDaemonMigratePathsOverrides(t *testing.T) {
+	oldRoot, newRoot := setupMovedRoot(t)
+	overrides := config.PathOverrides{
+		OutputDir:    filepath.Join(oldRoot, "logs"),
+		WorktreesDir: "/mnt/big-disk/wts",
+	}
+	data, _ := json.Marshal(overrides)
+	configPath := filepath.Join(newRoot, config.PathsConfigFile)
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		t.Fatalf("Failed to write paths.json: %v", err)
+	}
+
+	changes, err := migratePathOverrides(newRoot, oldRoot, false)
+	if err != nil {
+		t.Fatalf("migratePathOverrides() failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Field != "output_dir" {
+		t.Errorf("migratePathOverrides() = %+v, want only output_dir", changes)
+	}
+
+	data, _ = os.ReadFile(configPath)
+	var migrated config.PathOverrides
+	if err := json.Unmarshal(data, &migrated); err != nil {
+		t.Fatalf("Failed to parse migrated paths.json: %v", err)
+	}
+	if migrated.OutputDir != filepath.Join(newRoot, "logs") || migrated.WorktreesDir != "/mnt/big-disk/wts" {
+		t.Errorf("migrated overrides = %+v", migrated)
+	}
+}
+
+func TestDaemonMigratePathsValidation(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+	root := cli.paths.Root
+
+	err := cli.Execute([]string{"daemon", "migrate-paths", "--new-root", root})
+	if err == nil || !strings.Contains(err.Error(), "--old-root") {
+		t.Errorf("migrate-paths without --old-root = %v, want a missing argument error", err)
+	}
+
+	err = cli.Execute([]string{"daemon", "migrate-paths", "--old-root", root, "--new-root", root})
+	if err == nil || !strings.Contains(err.Error(), "same directory") {
+		t.Errorf("migrate-paths with equal roots = %v, want a usage error", err)
+	}
+
+	// The test daemon is running from the root
+	err = cli.Execute([]string{"daemon", "migrate-paths", "--old-root", "/old/multiclaude", "--new-root", root})
+	if err == nil || !strings.Contains(err.Error(), "daemon is running") {
+		t.Errorf("migrate-paths with a running daemon = %v, want a daemon running error", err)
+	}
+}
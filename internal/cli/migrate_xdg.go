@@ -0,0 +1,370 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/daemon"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/config"
+)
+
+// daemonStopTimeout is how long migrate-paths waits for the daemon to exit
+const daemonStopTimeout = 10 * time.Second
+
+// layoutMove is a file or directory migrate-paths moves from the classic
+// layout to the XDG layout
+type layoutMove struct {
+	Name string // Path relative to the classic root
+	From string
+	To   string
+}
+
+// migratePaths moves the classic ~/.multiclaude layout into the XDG layout:
+// state under $XDG_STATE_HOME, logs under $XDG_CACHE_HOME and paths.json
+// (which then selects the XDG layout) under $XDG_CONFIG_HOME. It stops the
+// daemon first, rewrites the absolute paths stored in state.json and the
+// prompt files, repairs git's worktree links and checks that every agent's
+// worktree still resolves. Whatever was already moved is skipped, so it can
+// be re-run after an interruption.
+func (c *CLI) migratePaths(args []string) error {
+	assumeYes, args := extractYesFlag(args)
+	flags, _ := ParseFlags(args)
+	dryRun := flags["dry-run"] == "true"
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to find home directory: %w", err)
+	}
+	classic := config.ClassicPaths(home)
+	if err := classic.LoadOverrides(); err != nil {
+		return err
+	}
+	xdg := config.XDGPaths(home)
+
+	moves, err := planXDGMoves(classic, xdg)
+	if err != nil {
+		return err
+	}
+	pending := pendingMoves(moves)
+
+	format.Header("Migrating %s to the XDG layout:", classic.Root)
+	fmt.Printf("  State:  %s\n", xdg.Root)
+	fmt.Printf("  Logs:   %s\n", xdg.CacheDir)
+	fmt.Printf("  Config: %s\n", xdg.ConfigDir)
+	fmt.Println()
+
+	if len(pending) == 0 {
+		fmt.Println("Nothing left to move; the classic layout is already migrated")
+	}
+	for _, move := range pending {
+		fmt.Printf("  %s -> %s\n", move.Name, move.To)
+	}
+
+	if dryRun {
+		// Show the state paths that would change, from the state as it is now
+		statePath := classic.StateFile
+		if _, err := os.Stat(statePath); err != nil {
+			statePath = xdg.StateFile
+		}
+		if st, err := state.Load(statePath); err == nil {
+			for _, move := range moves {
+				changes, _ := st.MigrateRoot(move.From, move.To, true)
+				for _, change := range changes {
+					fmt.Printf("  state: %s %s -> %s\n", agentLabel(change.Repo, change.Agent), change.Field, change.New)
+				}
+			}
+		}
+		format.Dimmed("\nDry run: nothing was changed. Run without --dry-run to migrate.")
+		return nil
+	}
+
+	if len(pending) > 0 {
+		ok, err := confirm(confirmation{
+			Consequence: "\nThe daemon will be stopped and the paths above moved. Restart agents that misbehave afterwards.",
+			Prompt:      "Migrate to the XDG layout?",
+			AssumeYes:   assumeYes,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Aborted")
+			return nil
+		}
+		if err := stopDaemonAt(classic); err != nil {
+			return err
+		}
+	}
+	// A daemon already running in the XDG layout would overwrite the
+	// rewritten state
+	if running, pid, _ := daemon.NewPIDFile(xdg.DaemonPID).IsRunning(); running {
+		if len(pending) > 0 {
+			return errors.DaemonMustBeStopped("migrating paths", pid)
+		}
+		st, err := state.Load(xdg.StateFile)
+		if err != nil {
+			return fmt.Errorf("failed to load state: %w", err)
+		}
+		if broken := verifyAgentWorktrees(st); broken > 0 {
+			fmt.Printf("Warning: %d agent worktree(s) do not resolve; 'multiclaude repair' cleans up agents whose worktrees are gone\n", broken)
+		}
+		return nil
+	}
+
+	for _, move := range pending {
+		if err := moveLayoutPath(move); err != nil {
+			return err
+		}
+	}
+
+	// Rewrite paths for every move, not only the pending ones, in case an
+	// earlier run was interrupted between moving and rewriting
+	st, err := state.Load(xdg.StateFile)
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	for _, move := range moves {
+		if _, err := st.MigrateRoot(move.From, move.To, false); err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to save migrated state", err)
+		}
+		if _, err := migratePromptFiles(filepath.Join(xdg.Root, "prompts"), move.From, move.To, false); err != nil {
+			return err
+		}
+	}
+
+	if err := writeXDGConfig(classic, xdg, moves); err != nil {
+		return err
+	}
+	repairWorktreeLinks(st, xdg)
+
+	// The daemon recreates its PID file and socket on start
+	os.Remove(classic.DaemonPID)
+	os.Remove(classic.DaemonSock)
+	if err := os.Remove(classic.Root); err != nil && !os.IsNotExist(err) {
+		if entries, readErr := os.ReadDir(classic.Root); readErr == nil {
+			var left []string
+			for _, entry := range entries {
+				left = append(left, entry.Name())
+			}
+			fmt.Printf("\nLeft in %s (not part of the layout): %s\n", classic.Root, strings.Join(left, ", "))
+		}
+	}
+
+	broken := verifyAgentWorktrees(st)
+
+	fmt.Println("\n✓ Migrated to the XDG layout")
+	if broken > 0 {
+		fmt.Printf("Warning: %d agent worktree(s) do not resolve; 'multiclaude repair' cleans up agents whose worktrees are gone\n", broken)
+	}
+	if value := os.Getenv(config.XDGEnvVar); value != "" && !config.UseXDG(home) {
+		fmt.Printf("Warning: %s=%s selects the classic layout; unset it to use the migrated one\n", config.XDGEnvVar, value)
+	}
+	fmt.Println("Start the daemon again with: multiclaude start")
+	return nil
+}
+
+// planXDGMoves lists every file and directory of the classic layout with
+// its place in the XDG layout. Directories relocated with paths.json are not
+// under the classic root and stay where they are; paths.json itself is
+// rewritten by writeXDGConfig rather than moved.
+func planXDGMoves(classic, xdg *config.Paths) ([]layoutMove, error) {
+	moves := []layoutMove{
+		{Name: "state.json", To: xdg.StateFile},
+		{Name: state.BackupsDirName, To: state.BackupDir(xdg.StateFile)},
+		{Name: "audit.log", To: xdg.AuditLog()},
+		{Name: "upgrade-check.json", To: xdg.UpgradeCheckFile()},
+		{Name: "repos", To: xdg.ReposDir},
+		{Name: "messages", To: xdg.MessagesDir},
+		{Name: "claude-config", To: xdg.ClaudeConfigDir},
+		{Name: "prompts", To: filepath.Join(xdg.Root, "prompts")},
+		{Name: "daemon.log", To: xdg.DaemonLog},
+	}
+	if classic.WorktreesDir == filepath.Join(classic.Root, "wts") {
+		moves = append(moves, layoutMove{Name: "wts", To: xdg.WorktreesDir})
+	}
+	if classic.OutputDir == filepath.Join(classic.Root, "output") {
+		moves = append(moves, layoutMove{Name: "output", To: xdg.OutputDir})
+	}
+
+	// Rotated daemon logs (daemon.log.<timestamp>) go with the log
+	rotated, err := filepath.Glob(filepath.Join(classic.Root, "daemon.log.*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(rotated)
+	for _, path := range rotated {
+		name := filepath.Base(path)
+		moves = append(moves, layoutMove{Name: name, To: filepath.Join(xdg.CacheDir, name)})
+	}
+
+	for i := range moves {
+		moves[i].From = filepath.Join(classic.Root, moves[i].Name)
+	}
+	return moves, nil
+}
+
+// pendingMoves returns the moves whose source still exists, leaving out
+// what never existed or was moved by an earlier run
+func pendingMoves(moves []layoutMove) []layoutMove {
+	var pending []layoutMove
+	for _, move := range moves {
+		if _, err := os.Lstat(move.From); err == nil {
+			pending = append(pending, move)
+		}
+	}
+	return pending
+}
+
+// moveLayoutPath moves one file or directory into the XDG layout. An empty
+// directory at the destination (e.g. created by a daemon started in the new
+// layout) is replaced; anything else there is a conflict to resolve by hand.
+func moveLayoutPath(move layoutMove) error {
+	if info, err := os.Lstat(move.To); err == nil {
+		entries, readErr := os.ReadDir(move.To)
+		if !info.IsDir() || readErr != nil || len(entries) > 0 {
+			return errors.New(errors.CategoryRuntime, fmt.Sprintf("cannot move %s: %s already exists", move.From, move.To)).
+				WithSuggestion("merge or remove one of them, then run 'multiclaude migrate-paths' again")
+		}
+		if err := os.Remove(move.To); err != nil {
+			return fmt.Errorf("failed to replace empty %s: %w", move.To, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(move.To), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(move.To), err)
+	}
+	if err := os.Rename(move.From, move.To); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to move %s to %s", move.From, move.To), err).
+			WithSuggestion("across filesystems, move it by hand (e.g. with mv) and run 'multiclaude migrate-paths' again")
+	}
+	fmt.Printf("Moved %s\n", move.Name)
+	return nil
+}
+
+// writeXDGConfig writes the XDG config directory's paths.json, selecting the
+// XDG layout and carrying over the classic paths.json overrides (rebased if
+// they pointed into a moved directory), then removes the classic one
+func writeXDGConfig(classic, xdg *config.Paths, moves []layoutMove) error {
+	var overrides config.PathOverrides
+	xdgConfigPath := filepath.Join(xdg.ConfigDir, config.PathsConfigFile)
+	classicConfigPath := filepath.Join(classic.Root, config.PathsConfigFile)
+	for _, path := range []string{xdgConfigPath, classicConfigPath} {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	overrides.Layout = config.LayoutXDG
+	for _, dir := range []*string{&overrides.OutputDir, &overrides.WorktreesDir} {
+		for _, move := range moves {
+			if rebased, ok := config.RebasePath(*dir, move.From, move.To); ok && *dir != "" {
+				*dir = rebased
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal path overrides: %w", err)
+	}
+	if err := os.MkdirAll(xdg.ConfigDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", xdg.ConfigDir, err)
+	}
+	if err := os.WriteFile(xdgConfigPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", xdgConfigPath, err)
+	}
+	if err := os.Remove(classicConfigPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", classicConfigPath, err)
+	}
+	return nil
+}
+
+// stopDaemonAt stops the daemon serving paths, if it is running, and waits
+// for it to exit
+func stopDaemonAt(paths *config.Paths) error {
+	pidFile := daemon.NewPIDFile(paths.DaemonPID)
+	running, pid, _ := pidFile.IsRunning()
+	if !running {
+		return nil
+	}
+
+	fmt.Printf("Stopping daemon (PID: %d)...\n", pid)
+	resp, err := socket.NewClient(paths.DaemonSock).Send(socket.Request{Command: "stop"})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("stopping the daemon", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("daemon stop failed: %s", resp.Error)
+	}
+
+	deadline := time.Now().Add(daemonStopTimeout)
+	for time.Now().Before(deadline) {
+		if running, _, _ := pidFile.IsRunning(); !running {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return errors.DaemonMustBeStopped("migrating paths", pid)
+}
+
+// verifyAgentWorktrees checks that every agent's worktree exists and is a
+// git checkout, printing the result per agent, and returns how many do not
+func verifyAgentWorktrees(st *state.State) int {
+	repos := st.GetAllRepos()
+	repoNames := make([]string, 0, len(repos))
+	for name := range repos {
+		repoNames = append(repoNames, name)
+	}
+	sort.Strings(repoNames)
+
+	broken := 0
+	fmt.Println()
+	for _, repoName := range repoNames {
+		agents := repos[repoName].Agents
+		agentNames := make([]string, 0, len(agents))
+		for name := range agents {
+			agentNames = append(agentNames, name)
+		}
+		sort.Strings(agentNames)
+
+		for _, agentName := range agentNames {
+			path := agents[agentName].WorktreePath
+			if path == "" {
+				continue
+			}
+			if err := exec.Command("git", "-C", path, "rev-parse", "--git-dir").Run(); err != nil {
+				broken++
+				fmt.Printf("  %s %s: %s does not resolve\n", format.Red.Sprint("✗"), agentLabel(repoName, agentName), path)
+				continue
+			}
+			fmt.Printf("  %s %s\n", format.Green.Sprint("✓"), agentLabel(repoName, agentName))
+		}
+	}
+	return broken
+}
+
+// agentLabel names an agent as repo/agent, or just the repo for
+// repository-level settings
+func agentLabel(repoName, agentName string) string {
+	if agentName == "" {
+		return repoName
+	}
+	return repoName + "/" + agentName
+}
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/config"
+)
+
+// setupClassicLayout creates a classic ~/.multiclaude layout under a
+// temporary HOME with a repository, a worker worktree, a prompt file and a
+// log, and returns the home directory
+func setupClassicLayout(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, name := range []string{"XDG_STATE_HOME", "XDG_CONFIG_HOME", "XDG_CACHE_HOME", config.XDGEnvVar} {
+		t.Setenv(name, "")
+	}
+	paths := config.ClassicPaths(home)
+
+	repoPath := paths.RepoDir("repo")
+	setupTestRepo(t, repoPath)
+	wtPath := paths.AgentWorktree("repo", "fox")
+	if output, err := exec.Command("git", "-C", repoPath, "worktree", "add", "-b", "work/fox", wtPath).CombinedOutput(); err != nil {
+		t.Fatalf("git worktree add failed: %v\n%s", err, output)
+	}
+
+	st := state.New(paths.StateFile)
+	if err := st.AddRepo("repo", &state.Repository{
+		GithubURL: "https://github.com/org/repo",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, WorktreePath: repoPath},
+			"fox":        {Type: state.AgentTypeWorker, WorktreePath: wtPath},
+		},
+	}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	promptDir := filepath.Join(paths.Root, "prompts")
+	if err := os.MkdirAll(promptDir, 0755); err != nil {
+		t.Fatalf("Failed to create prompts dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(promptDir, "fox.md"), []byte("Your worktree is "+wtPath+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write prompt: %v", err)
+	}
+	if err := os.WriteFile(paths.DaemonLog, []byte("log\n"), 0644); err != nil {
+		t.Fatalf("Failed to write daemon log: %v", err)
+	}
+	return home
+}
+
+func TestMigratePathsXDG(t *testing.T) {
+	home := setupClassicLayout(t)
+	classic := config.ClassicPaths(home)
+	xdg := config.XDGPaths(home)
+	cli := NewWithPaths(classic)
+
+	// A dry run changes nothing
+	output := captureStdout(t, func() {
+		if err := cli.Execute([]string{"migrate-paths", "--dry-run"}); err != nil {
+			t.Errorf("migrate-paths --dry-run failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "state.json -> "+xdg.StateFile) || !strings.Contains(output, "repo/fox worktree_path") {
+		t.Errorf("dry run output missing the plan:\n%s", output)
+	}
+	if _, err := os.Stat(classic.StateFile); err != nil {
+		t.Errorf("migrate-paths --dry-run should not move state.json: %v", err)
+	}
+
+	captureStdout(t, func() {
+		if err := cli.Execute([]string{"migrate-paths", "--yes"}); err != nil {
+			t.Errorf("migrate-paths failed: %v", err)
+		}
+	})
+
+	if _, err := os.Stat(classic.Root); !os.IsNotExist(err) {
+		t.Errorf("classic root should be gone after migration: %v", err)
+	}
+	if _, err := os.Stat(xdg.DaemonLog); err != nil {
+		t.Errorf("daemon.log should be in the cache directory: %v", err)
+	}
+
+	st, err := state.Load(xdg.StateFile)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	wtPath := xdg.AgentWorktree("repo", "fox")
+	repo, _ := st.GetRepo("repo")
+	if got := repo.Agents["fox"].WorktreePath; got != wtPath {
+		t.Errorf("fox worktree = %q, want %q", got, wtPath)
+	}
+	if got := repo.Agents["supervisor"].WorktreePath; got != xdg.RepoDir("repo") {
+		t.Errorf("supervisor worktree = %q, want %q", got, xdg.RepoDir("repo"))
+	}
+	prompt, err := os.ReadFile(filepath.Join(xdg.Root, "prompts", "fox.md"))
+	if err != nil || !strings.Contains(string(prompt), wtPath) {
+		t.Errorf("prompt file = %q, %v; want it to refer to the XDG layout", prompt, err)
+	}
+	if output, err := exec.Command("git", "-C", wtPath, "status").CombinedOutput(); err != nil {
+		t.Errorf("git status in the moved worktree failed: %v\n%s", err, output)
+	}
+
+	// paths.json now selects the XDG layout
+	data, err := os.ReadFile(filepath.Join(xdg.ConfigDir, config.PathsConfigFile))
+	if err != nil {
+		t.Fatalf("Failed to read XDG paths.json: %v", err)
+	}
+	var overrides config.PathOverrides
+	if err := json.Unmarshal(data, &overrides); err != nil || overrides.Layout != config.LayoutXDG {
+		t.Errorf("XDG paths.json = %s, %v; want layout xdg", data, err)
+	}
+	if !config.UseXDG(home) {
+		t.Error("UseXDG() = false after migration, want true")
+	}
+
+	// Re-running finds nothing left to move and keeps the state intact
+	output = captureStdout(t, func() {
+		if err := cli.Execute([]string{"migrate-paths", "--yes"}); err != nil {
+			t.Errorf("second migrate-paths failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Nothing left to move") {
+		t.Errorf("second run output:\n%s", output)
+	}
+	st, err = state.Load(xdg.StateFile)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if repo, _ := st.GetRepo("repo"); repo.Agents["fox"].WorktreePath != wtPath {
+		t.Errorf("fox worktree after re-run = %q, want %q", repo.Agents["fox"].WorktreePath, wtPath)
+	}
+}
+
+func TestMoveLayoutPathConflict(t *testing.T) {
+	dir := t.TempDir()
+	from := filepath.Join(dir, "from")
+	to := filepath.Join(dir, "to")
+	for _, path := range []string{from, to} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", path, err)
+		}
+	}
+
+	// An empty destination is replaced
+	captureStdout(t, func() {
+		if err := moveLayoutPath(layoutMove{Name: "from", From: from, To: to}); err != nil {
+			t.Errorf("moveLayoutPath() onto an empty directory failed: %v", err)
+		}
+	})
+
+	// A non-empty one is a conflict
+	if err := os.MkdirAll(from, 0755); err != nil {
+		t.Fatalf("Failed to recreate %s: %v", from, err)
+	}
+	if err := os.WriteFile(filepath.Join(to, "file"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	err := moveLayoutPath(layoutMove{Name: "from", From: from, To: to})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("moveLayoutPath() onto a non-empty directory = %v, want a conflict error", err)
+	}
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/dlorenc/multiclaude/internal/daemon"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// predicateNotes documents the exit status contract of predicate commands
+const predicateNotes = "Exits 0 for yes, 1 for no, 2 if it could not tell (bad arguments, no daemon); silent unless `--verbose`."
+
+// verboseFlag is the flag predicate commands print their answer with
+var verboseFlag = FlagSpec{Name: "verbose", Type: "bool", Description: "Print the answer and details"}
+
+// extractVerboseFlag removes --verbose, which takes no value, from args so
+// that ParseFlags does not take the name following it as its value
+func extractVerboseFlag(args []string) (bool, []string) {
+	verbose := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--verbose" || arg == "--verbose=true" {
+			verbose = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return verbose, rest
+}
+
+// daemonRunning answers daemon status --quiet: whether the daemon is
+// running and answering requests
+func (c *CLI) daemonRunning(verbose bool) error {
+	running, pid, err := daemon.NewPIDFile(c.paths.DaemonPID).IsRunning()
+	if err != nil {
+		return errors.Undetermined(errors.Wrap(errors.CategoryRuntime, "failed to check daemon status", err))
+	}
+	if !running {
+		if verbose {
+			fmt.Println("Daemon is not running")
+		}
+		return errors.PredicateNo()
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	if resp, err := client.Send(socket.Request{Command: "ping"}); err != nil || !resp.Success {
+		if verbose {
+			fmt.Printf("Daemon PID file exists (PID: %d) but daemon is not responding\n", pid)
+		}
+		return errors.PredicateNo()
+	}
+	if verbose {
+		fmt.Printf("Daemon is running (PID: %d)\n", pid)
+	}
+	return nil
+}
+
+// workerExists answers work exists: whether the repository has a worker,
+// ephemeral ones included, with the given name
+func (c *CLI) workerExists(args []string) error {
+	verbose, args := extractVerboseFlag(args)
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude work exists <name> [--repo <repo>] [--verbose]")
+	}
+	name := posArgs[0]
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("listing workers", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to list workers", fmt.Errorf("%s", resp.Error))
+	}
+
+	agents, _ := resp.Data.([]interface{})
+	for _, agent := range agents {
+		agentMap, ok := agent.(map[string]interface{})
+		if !ok || workerName(agentMap) != name {
+			continue
+		}
+		agentType, _ := agentMap["type"].(string)
+		if agentType != "worker" && agentType != "ephemeral" {
+			continue
+		}
+		if verbose {
+			task, _ := agentMap["task"].(string)
+			fmt.Printf("Worker '%s' exists in '%s' (%s): %s\n", name, repoName, agentType, task)
+		}
+		return nil
+	}
+	if verbose {
+		fmt.Printf("No worker '%s' in '%s'\n", name, repoName)
+	}
+	return errors.PredicateNo()
+}
+
+// agentHasMessages answers agent has-messages: whether the agent running
+// the command has messages it has not acknowledged, or with --unread not
+// read
+func (c *CLI) agentHasMessages(args []string) error {
+	verbose, args := extractVerboseFlag(args)
+	flags, _ := ParseFlags(args)
+	unreadOnly := flags["unread"] == "true"
+
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return err
+	}
+
+	summary, err := messages.NewManager(c.paths.MessagesDir).AgentSummary(repoName, agentName)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to read messages", err)
+	}
+	count, kind := summary.PendingMessages+summary.AwaitingAck(), "unacknowledged"
+	if unreadOnly {
+		count, kind = summary.Unread(), "unread"
+	}
+	if verbose {
+		fmt.Printf("%d %s message(s) for %s\n", count, kind, agentName)
+	}
+	if count == 0 {
+		return errors.PredicateNo()
+	}
+	return nil
+}
+
+// repoTracked answers repo tracked: whether the daemon tracks a repository
+// with the given name
+func (c *CLI) repoTracked(args []string) error {
+	verbose, args := extractVerboseFlag(args)
+	_, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude repo tracked <name> [--verbose]")
+	}
+	name := posArgs[0]
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{Command: "list_repos"})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("listing repositories", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to list repositories", fmt.Errorf("%s", resp.Error))
+	}
+
+	repos, _ := resp.Data.([]interface{})
+	for _, repo := range repos {
+		if repo == name {
+			if verbose {
+				fmt.Printf("Repository '%s' is tracked\n", name)
+			}
+			return nil
+		}
+	}
+	if verbose {
+		fmt.Printf("Repository '%s' is not tracked\n", name)
+	}
+	return errors.PredicateNo()
+}
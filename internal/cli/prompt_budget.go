@@ -0,0 +1,272 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// defaultPromptBudget is the size in characters, about 10000 tokens, above
+// which the CLI reference in an agent's prompt is trimmed to the commands
+// its type uses
+const defaultPromptBudget = 40000
+
+// promptBudgetEnv overrides defaultPromptBudget with a number of characters,
+// a number of tokens such as "10000t", or 0 for no budget
+const promptBudgetEnv = "MULTICLAUDE_PROMPT_BUDGET"
+
+// charsPerToken is the rough number of characters per token used to estimate
+// prompt sizes in tokens
+const charsPerToken = 4
+
+// messagingDocCommands are the commands every agent uses to talk to the
+// others and to report bugs
+var messagingDocCommands = []string{
+	"agent send-message", "agent message-templates", "agent list-messages",
+	"agent read-message", "agent ack-message", "agent complete", "bug",
+}
+
+// agentDocCommands lists, for each agent type, the commands whose reference
+// is kept when its prompt is over budget. An entry keeps its subcommands.
+var agentDocCommands = map[prompts.AgentType][]string{
+	prompts.TypeWorker: append([]string{
+		"agent pr-comments", "work merge-into-workspace",
+	}, messagingDocCommands...),
+	prompts.TypeReview:    messagingDocCommands,
+	prompts.TypeEphemeral: messagingDocCommands,
+	prompts.TypeSupervisor: append([]string{
+		"agent cancel-message", "agent restart", "agent set-env",
+		"work", "workspace list", "workspace set-context", "workspace get-context", "review", "list", "history", "logs", "attach", "repo health", "cleanup", "repair",
+	}, messagingDocCommands...),
+	prompts.TypeMergeQueue: append([]string{
+		"agent mq", "work list", "review", "list", "history",
+	}, messagingDocCommands...),
+	// Workspaces leave out work commit, which is for scripts and CI, the
+	// logs commands for streaming, cleaning up and debugging delivery, and
+	// the workspace commands that attach a terminal or open a browser
+	prompts.TypeWorkspace: append([]string{
+		"agent cancel-message", "work list", "work split", "work set-task", "work exists", "work unblock",
+		"work merge-into-workspace", "work rm", "work gc-branches", "work archives",
+		"workspace add", "workspace rm", "workspace list", "workspace show", "workspace create-pr",
+		"workspace set-upstream", "workspace show-upstream", "workspace create-from-pr", "workspace pr-status",
+		"workspace fetch", "workspace compare", "workspace rebase-interactive", "workspace snapshot",
+		"workspace snapshots", "workspace restore", "workspace revert", "workspace set-context", "workspace get-context",
+		"review", "list", "history", "logs list", "logs search", "logs diff", "logs format", "attach",
+	}, messagingDocCommands...),
+}
+
+// promptBudget returns the prompt size budget in characters; zero means none
+func promptBudget() (int, error) {
+	value := strings.TrimSpace(os.Getenv(promptBudgetEnv))
+	if value == "" {
+		return defaultPromptBudget, nil
+	}
+	multiplier := 1
+	if n, ok := strings.CutSuffix(value, "t"); ok {
+		value, multiplier = n, charsPerToken
+	}
+	budget, err := strconv.Atoi(value)
+	if err != nil || budget < 0 {
+		return 0, errors.InvalidUsage(fmt.Sprintf("invalid %s value: %q (must be a number of characters, a number of tokens such as 10000t, or 0 for no budget)", promptBudgetEnv, os.Getenv(promptBudgetEnv)))
+	}
+	return budget * multiplier, nil
+}
+
+// documentationFor returns the CLI reference limited to the commands in the
+// agent type's allowlist, along with the top-level commands it leaves out
+func (c *CLI) documentationFor(agentType prompts.AgentType) (string, []string) {
+	allowed := agentDocCommands[agentType]
+	include := func(path string) bool {
+		for _, entry := range allowed {
+			// The command itself, one of its ancestors, or one of its
+			// descendants (whose parent's section must stay) is allowed
+			if path == entry || strings.HasPrefix(path, entry+" ") || strings.HasPrefix(entry, path+" ") {
+				return true
+			}
+		}
+		return false
+	}
+
+	var omitted []string
+	for _, name := range sortedSubcommands(c.rootCmd, "", nil) {
+		if !include(name) {
+			omitted = append(omitted, name)
+		}
+	}
+	return c.generateDocumentation(include), omitted
+}
+
+// composedPrompt is an agent's prompt as composed under the prompt budget
+type composedPrompt struct {
+	Text     string
+	Docs     string   // The CLI reference included in Text
+	DocsHash string   // prompts.DocsHash of Docs, stamped on it in Text
+	Omitted  []string // Top-level commands trimmed from the reference
+	FullSize int      // Size of the prompt with the full reference
+	Budget   int      // Zero means no budget
+}
+
+// budgetedPrompt builds an agent's prompt from its default prompt, the CLI
+// reference and the repository's additions. When that is over the prompt
+// budget, the reference is cut down to the commands the agent type uses.
+// The reference is stamped with this binary's version and its hash.
+func (c *CLI) budgetedPrompt(repoPath string, agentType prompts.AgentType) (composedPrompt, error) {
+	budget, err := promptBudget()
+	if err != nil {
+		return composedPrompt{}, err
+	}
+	text, err := prompts.GetPrompt(repoPath, agentType, prompts.StampDocs(c.documentation, Version))
+	if err != nil {
+		return composedPrompt{}, err
+	}
+	p := composedPrompt{Text: text, Docs: c.documentation, FullSize: len(text), Budget: budget}
+	if budget != 0 && len(text) > budget {
+		p.Docs, p.Omitted = c.documentationFor(agentType)
+		if p.Text, err = prompts.GetPrompt(repoPath, agentType, prompts.StampDocs(p.Docs, Version)); err != nil {
+			return composedPrompt{}, err
+		}
+	}
+	p.DocsHash = prompts.DocsHash(p.Docs)
+	return p, nil
+}
+
+// currentDocs returns the stamped CLI reference this binary puts in the
+// prompts of a repository's agents of a type, and its hash
+func (c *CLI) currentDocs(repoName string, agentType prompts.AgentType) (string, string, error) {
+	p, err := c.budgetedPrompt(c.paths.RepoDir(repoName), agentType)
+	if err != nil {
+		return "", "", err
+	}
+	return prompts.StampDocs(p.Docs, Version), p.DocsHash, nil
+}
+
+// promptDocsHash returns the hash stamped on the CLI reference in a prompt
+// file, or "" if it has none or cannot be read
+func promptDocsHash(promptFile string) string {
+	data, err := os.ReadFile(promptFile)
+	if err != nil {
+		return ""
+	}
+	_, hash, _ := prompts.ParseDocsStamp(string(data))
+	return hash
+}
+
+// refreshPromptDocs replaces the CLI reference in an agent's prompt file
+// with the one this binary generates, keeping the rest of the prompt, and
+// returns its new hash. The agent reads it when next restarted.
+func (c *CLI) refreshPromptDocs(repoName, agentName string, agentType prompts.AgentType) (string, error) {
+	promptFile := filepath.Join(c.paths.Root, "prompts", agentName+".md")
+	data, err := os.ReadFile(promptFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt file: %w", err)
+	}
+	docs, hash, err := c.currentDocs(repoName, agentType)
+	if err != nil {
+		return "", err
+	}
+	text, ok := prompts.ReplaceDocs(string(data), docs)
+	if !ok {
+		return "", fmt.Errorf("prompt file %s has no stamped CLI reference to replace", promptFile)
+	}
+	if err := os.WriteFile(promptFile, []byte(text), 0644); err != nil {
+		return "", fmt.Errorf("failed to write prompt file: %w", err)
+	}
+	return hash, nil
+}
+
+// docsHashFor returns the hash of the CLI reference this binary puts in the
+// prompts of a repository's agents of a type, or "" if it cannot be built.
+// The daemon compares it with the hashes agents were registered with.
+func (c *CLI) docsHashFor(repoName string, agentType state.AgentType) string {
+	_, hash, err := c.currentDocs(repoName, prompts.AgentType(agentType))
+	if err != nil {
+		return ""
+	}
+	return hash
+}
+
+// composePrompt returns an agent's prompt within the prompt budget. The full
+// CLI reference is over the default budget for every agent type, so the
+// routine trim is not reported here, on every spawn; `docs --agent-type`
+// shows what was left out. Only a prompt still over budget after trimming
+// is warned about. In a repository without a supervisor, the prompt says so
+// first.
+func (c *CLI) composePrompt(repoPath string, agentType prompts.AgentType) (string, error) {
+	p, err := c.budgetedPrompt(repoPath, agentType)
+	if err != nil {
+		return "", err
+	}
+	if repo := c.promptRepo(filepath.Base(repoPath)); repo != nil {
+		if repo.NoSupervisor {
+			p.Text = prompts.WithoutSupervisor(agentType, p.Text)
+		}
+		p.Text = prompts.WithGitIdentity(agentType, p.Text, gitIdentityPrompt(repo.GitIdentity))
+		p.Text = prompts.WithContext(p.Text, repo.ContextVars)
+	}
+	if p.Omitted != nil && len(p.Text) > p.Budget {
+		fmt.Fprintf(os.Stderr, "Warning: the %s prompt is still %d characters after trimming its CLI reference, over the budget of %d; see: multiclaude docs --agent-type %s\n",
+			agentType, len(p.Text), p.Budget, agentType)
+	}
+	return p.Text, nil
+}
+
+// showAgentDocs prints the CLI reference an agent type gets in its prompt
+// for the current repository, and how its prompt compares to the budget
+func (c *CLI) showAgentDocs(agentType prompts.AgentType, flags map[string]string) error {
+	if _, ok := agentDocCommands[agentType]; !ok {
+		names := make([]string, 0, len(prompts.CustomPromptTypes))
+		for _, t := range prompts.CustomPromptTypes {
+			names = append(names, string(t))
+		}
+		return errors.InvalidUsage(fmt.Sprintf("unknown --agent-type %q (must be one of: %s)", agentType, strings.Join(names, ", ")))
+	}
+
+	// Outside a tracked repository the prompt has no repository additions
+	repoPath := ""
+	if repoName, err := c.resolveRepo(flags); err == nil {
+		repoPath = c.paths.RepoDir(repoName)
+	}
+
+	p, err := c.budgetedPrompt(repoPath, agentType)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(p.Docs)
+	size := len(p.Text)
+	if p.Budget == 0 {
+		format.Dimmed("%s prompt: %d characters (~%d tokens), no budget", agentType, size, size/charsPerToken)
+	} else {
+		format.Dimmed("%s prompt: %d characters (~%d tokens) of a %d character budget", agentType, size, size/charsPerToken, p.Budget)
+	}
+	if p.Omitted != nil {
+		format.Dimmed("Trimmed from the reference: %s", strings.Join(p.Omitted, ", "))
+	}
+	return nil
+}
+
+// promptRepo returns the tracked repository whose settings shape its
+// agents' prompts, or nil if it is not tracked
+func (c *CLI) promptRepo(repoName string) *state.Repository {
+	st, err := c.loadState()
+	if err != nil {
+		return nil
+	}
+	repo, exists := st.GetRepo(repoName)
+	if !exists {
+		return nil
+	}
+	return repo
+}
+
+// gitIdentityPrompt states a repository's git identity in agent prompts
+func gitIdentityPrompt(identity state.GitIdentity) string {
+	return prompts.GenerateGitIdentityPrompt(identity.CommitterName(), identity.Email, identity.SignCommits, identity.EffectiveSigningFormat())
+}
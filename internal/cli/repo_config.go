@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/repoconfig"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// configFlags declares a flag for each config key, so that each can also be
+// set with --<key>=<value>
+func configFlags() []FlagSpec {
+	flags := make([]FlagSpec, 0, len(repoconfig.Keys()))
+	for _, key := range repoconfig.Keys() {
+		flags = append(flags, FlagSpec{Name: key.Name, Type: string(key.Type), Default: key.Default, Description: key.Description})
+	}
+	return flags
+}
+
+// configRepo lists a repository's settings, or sets (<key>=<value> or
+// --<key>=<value>) and unsets (--unset <key>[,<key>...]) them
+func (c *CLI) configRepo(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	var repoName string
+	var assignments []string
+	for _, arg := range posArgs {
+		if strings.Contains(arg, "=") {
+			assignments = append(assignments, arg)
+		} else if repoName == "" {
+			repoName = arg
+		} else {
+			return errors.InvalidUsage(fmt.Sprintf("unexpected argument %q (settings are given as <key>=<value>)", arg))
+		}
+	}
+	if repoName == "" {
+		// Try to infer from current directory
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		// Check if we're in a tracked repo
+		repos := c.getReposList()
+		for _, repo := range repos {
+			repoPath := c.paths.RepoDir(repo)
+			if strings.HasPrefix(cwd, repoPath) {
+				repoName = repo
+				break
+			}
+		}
+
+		if repoName == "" {
+			// If only one repo exists, use it
+			if len(repos) == 1 {
+				repoName = repos[0]
+			} else {
+				return fmt.Errorf("please specify a repository name or run from within a tracked repository")
+			}
+		}
+	}
+
+	if flags["show-env"] == "true" {
+		return c.showRepoEnv(repoName)
+	}
+
+	set := make(map[string]string)
+	for name, value := range flags {
+		key, ok := repoconfig.Lookup(name)
+		if !ok {
+			continue
+		}
+		// A bare --<key> of a string key is missing its value
+		if value == "true" && (key.Type == repoconfig.String || key.Type == repoconfig.Path) {
+			return errors.MissingArgument("--"+name, "value")
+		}
+		parsed, err := key.Parse(value)
+		if err != nil {
+			return errors.InvalidUsage(fmt.Sprintf("invalid --%s value: %v", name, err))
+		}
+		set[name] = parsed
+	}
+	for _, assignment := range assignments {
+		name, value, _ := strings.Cut(assignment, "=")
+		key, err := lookupConfigKey(name)
+		if err != nil {
+			return err
+		}
+		parsed, err := key.Parse(value)
+		if err != nil {
+			return errors.InvalidUsage(fmt.Sprintf("invalid %s value: %v", name, err))
+		}
+		set[name] = parsed
+	}
+	var unset []string
+	if value, ok := flags["unset"]; ok {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if _, err := lookupConfigKey(name); err != nil {
+				return err
+			}
+			if _, ok := set[name]; ok {
+				return errors.InvalidUsage(fmt.Sprintf("%s cannot be both set and unset", name))
+			}
+			unset = append(unset, name)
+		}
+	}
+
+	applyGitConfig := flags["apply-git-config"] == "true"
+	if len(set) == 0 && len(unset) == 0 {
+		if applyGitConfig {
+			return c.applyGitConfig(repoName)
+		}
+		return c.showRepoConfig(repoName)
+	}
+
+	if err := c.updateRepoConfig(repoName, set, unset); err != nil {
+		return err
+	}
+	if applyGitConfig {
+		fmt.Println()
+		return c.applyGitConfig(repoName)
+	}
+	for _, name := range append(unset, mapKeys(set)...) {
+		if strings.HasPrefix(name, "git-") || strings.HasPrefix(name, "sign") {
+			format.Dimmed("\nNew worktrees get the git identity; update existing ones with: multiclaude config %s --apply-git-config", repoName)
+			break
+		}
+	}
+	return nil
+}
+
+// lookupConfigKey returns a config key, or a usage error naming the key
+// closest to a misspelled one
+func lookupConfigKey(name string) (*repoconfig.Key, error) {
+	if key, ok := repoconfig.Lookup(name); ok {
+		return key, nil
+	}
+	msg := fmt.Sprintf("unknown config key %q", name)
+	if suggestion := suggestFlag(name, configFlags()); suggestion != "" {
+		msg = fmt.Sprintf("unknown config key %q, did you mean %s?", name, suggestion)
+	}
+	return nil, errors.InvalidUsage(msg).WithSuggestion("multiclaude config <repo> lists the keys")
+}
+
+func mapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseWorktreeLimit parses a --worktree-limit value (0 means unlimited)
+func parseWorktreeLimit(value string) (int, error) {
+	key, _ := repoconfig.Lookup("worktree-limit")
+	parsed, err := key.Parse(value)
+	if err != nil || parsed == "" {
+		return 0, errors.InvalidUsage(fmt.Sprintf("invalid --worktree-limit value: %q (must be a non-negative integer)", value))
+	}
+	return key.LegacyValue(parsed).(int), nil
+}
+
+// repoConfig returns a repository's settings by config key
+func (c *CLI) repoConfig(repoName string) (map[string]string, error) {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "get_repo_config",
+		Args: map[string]interface{}{
+			"name": repoName,
+		},
+	})
+	if err != nil {
+		return nil, errors.DaemonCommunicationFailed("getting repo config", err)
+	}
+	if !resp.Success {
+		return nil, errors.Wrap(errors.CategoryRuntime, "failed to get repo config", fmt.Errorf("%s", resp.Error))
+	}
+
+	data, _ := resp.Data.(map[string]interface{})
+	raw, _ := data["config"].(map[string]interface{})
+	values := make(map[string]string, len(raw))
+	for key, v := range raw {
+		values[key], _ = v.(string)
+	}
+	return values, nil
+}
+
+// showRepoConfig lists every config key with its value in the repository,
+// and whether that is the default
+func (c *CLI) showRepoConfig(repoName string) error {
+	values, err := c.repoConfig(repoName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Configuration for repository: %s\n\n", repoName)
+	table := format.NewTable("KEY", "VALUE", "SOURCE")
+	for _, key := range repoconfig.Keys() {
+		value := values[key.Name]
+		shown := value
+		if shown == "" {
+			shown = "(none)"
+		}
+		source := "default"
+		if value != key.Default {
+			def := key.Default
+			if def == "" {
+				def = "(none)"
+			}
+			source = "overridden (default: " + def + ")"
+		}
+		table.AddRow(key.Name, shown, source)
+	}
+	fmt.Print(table.String())
+
+	fmt.Println("\nTo modify:")
+	fmt.Printf("  multiclaude config %s <key>=<value>  (an empty value reverts it)\n", repoName)
+	fmt.Printf("  multiclaude config %s --unset <key>[,<key>...]\n", repoName)
+	fmt.Printf("  multiclaude config %s --apply-git-config  (update existing worktrees)\n", repoName)
+	fmt.Printf("  multiclaude config --help  (what each key does)\n")
+	return nil
+}
+
+// updateRepoConfig sends parsed settings to the daemon, which applies them
+// all or none, and lists the resulting configuration
+func (c *CLI) updateRepoConfig(repoName string, set map[string]string, unset []string) error {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "update_repo_config",
+		Args: map[string]interface{}{
+			"name":  repoName,
+			"set":   set,
+			"unset": unset,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update repo config: %w (is daemon running?)", err)
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("failed to update repo config: %s", resp.Error)
+	}
+
+	fmt.Printf("Configuration updated for repository: %s\n", repoName)
+
+	// Show the updated config
+	return c.showRepoConfig(repoName)
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// syncRepo syncs the primary clone of one repository, or of every tracked
+// repository with --all, with upstream
+func (c *CLI) syncRepo(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	if flags["all"] == "true" {
+		if len(posArgs) > 0 {
+			return errors.InvalidUsage("--all cannot be used with a repository name")
+		}
+		repos := c.getReposList()
+		if len(repos) == 0 {
+			fmt.Println("No repositories tracked")
+			return nil
+		}
+		failed := 0
+		for i, repoName := range repos {
+			if i > 0 {
+				fmt.Println()
+			}
+			if err := c.syncOneRepo(repoName); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to sync %s: %v\n", repoName, err)
+				failed++
+			}
+		}
+		if failed > 0 {
+			return errors.New(errors.CategoryRuntime, fmt.Sprintf("%d of %d repositories failed to sync", failed, len(repos)))
+		}
+		return nil
+	}
+
+	var repoName string
+	if len(posArgs) > 0 {
+		repoName = posArgs[0]
+	} else {
+		var err error
+		if repoName, err = c.resolveRepo(flags); err != nil {
+			return errors.NotInRepo()
+		}
+	}
+	return c.syncOneRepo(repoName)
+}
+
+// syncOneRepo asks the daemon to sync a repository and reports what moved
+func (c *CLI) syncOneRepo(repoName string) error {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "sync_repo",
+		Args:    map[string]interface{}{"name": repoName},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("syncing repository", err)
+	}
+	if !resp.Success {
+		syncErr := errors.Wrap(errors.CategoryRuntime, "failed to sync "+repoName, fmt.Errorf("%s", resp.Error))
+		if strings.Contains(resp.Error, "diverged") {
+			return syncErr.WithSuggestion(fmt.Sprintf("the default branch of the primary clone has local commits; inspect them with 'git -C %s log @{upstream}..' and push or reset them, then sync again", c.paths.RepoDir(repoName)))
+		}
+		return syncErr
+	}
+
+	data, _ := resp.Data.(map[string]interface{})
+	branch, _ := data["branch"].(string)
+	remote, _ := data["remote"].(string)
+	oldCommit, _ := data["old_commit"].(string)
+	newCommit, _ := data["new_commit"].(string)
+	commits, _ := data["commits"].([]interface{})
+	pruned, _ := data["pruned"].([]interface{})
+
+	if oldCommit == newCommit {
+		fmt.Printf("✓ %s: %s is up to date with %s/%s\n", repoName, branch, remote, branch)
+	} else {
+		fmt.Printf("✓ %s: pulled %d commit(s) into %s (%s..%s)\n", repoName, len(commits), branch, shortCommit(oldCommit), shortCommit(newCommit))
+		for _, commit := range commits {
+			fmt.Printf("    %v\n", commit)
+		}
+		fmt.Printf("  Agents notified: %v\n", data["agents_notified"])
+	}
+	if len(pruned) > 0 {
+		names := make([]string, len(pruned))
+		for i, branch := range pruned {
+			names[i] = fmt.Sprint(branch)
+		}
+		fmt.Printf("  Pruned %d deleted remote branch(es): %s\n", len(pruned), strings.Join(names, ", "))
+	}
+	return nil
+}
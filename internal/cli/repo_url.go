@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/githuburl"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// setRepoURL points a tracked repository at a new GitHub URL after it was
+// renamed or transferred
+func (c *CLI) setRepoURL(args []string) error {
+	_, posArgs := ParseFlags(args)
+
+	if len(posArgs) < 2 {
+		return errors.InvalidUsage("usage: multiclaude repo set-url <name> <new-github-url>")
+	}
+	repoName := posArgs[0]
+	newURL := strings.TrimRight(posArgs[1], "/")
+
+	if _, _, err := githuburl.Parse(newURL); err != nil {
+		return errors.InvalidUsage(err.Error())
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "set_repo_url",
+		Args: map[string]interface{}{
+			"name":       repoName,
+			"github_url": newURL,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("updating repository URL", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to update repository URL", fmt.Errorf("%s", resp.Error))
+	}
+
+	data, _ := resp.Data.(map[string]interface{})
+	fmt.Printf("✓ Repository '%s' now points at %s\n", repoName, newURL)
+	if oldURL, _ := data["old_url"].(string); oldURL != "" {
+		fmt.Printf("  Previously: %s\n", oldURL)
+	}
+	fmt.Printf("  Remotes updated: %v\n", data["remotes_updated"])
+	if failed, _ := data["remotes_failed"].([]interface{}); len(failed) > 0 {
+		fmt.Println("  Warning: failed to update origin in:")
+		for _, path := range failed {
+			fmt.Printf("    %v\n", path)
+		}
+	}
+	fmt.Printf("  Agents notified: %v\n", data["agents_notified"])
+	return nil
+}
@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// mapWatchInterval is how often map --watch redraws the map
+const mapWatchInterval = 3 * time.Second
+
+// mapTaskWidth is how much of an agent's task the map shows
+const mapTaskWidth = 50
+
+// sessionMap prints a repository's tmux windows in index order, each with
+// the agent running in it. With --watch the map is redrawn until interrupted.
+func (c *CLI) sessionMap(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	if flags["watch"] != "true" {
+		return c.printSessionMap(repoName)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(mapWatchInterval)
+	defer ticker.Stop()
+	for {
+		// Clear the screen and move the cursor home before each redraw
+		fmt.Print("\033[H\033[2J")
+		if err := c.printSessionMap(repoName); err != nil {
+			return err
+		}
+		format.Dimmed("\nRefreshing every %s (Ctrl-C to stop)", mapWatchInterval)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// printSessionMap prints the map of one repository. When its tmux session
+// cannot be listed the agents are printed from state alone, without window
+// indices.
+func (c *CLI) printSessionMap(repoName string) error {
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	repo, exists := st.GetRepo(repoName)
+	if !exists {
+		return errors.New(errors.CategoryNotFound, fmt.Sprintf("repository '%s' not found", repoName))
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+			"rich": true,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("listing agents", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to list agents", fmt.Errorf("%s", resp.Error))
+	}
+	list, _ := resp.Data.([]interface{})
+
+	agents := make([]map[string]interface{}, 0, len(list))
+	for _, a := range list {
+		if agent, ok := a.(map[string]interface{}); ok {
+			agents = append(agents, agent)
+		}
+	}
+
+	windows, tmuxErr := tmux.NewClient().ListWindowInfo(context.Background(), repo.TmuxSession)
+	if tmuxErr != nil {
+		root := treeNode{label: fmt.Sprintf("%s  [tmux %s not reachable, showing state only]", repoName, repo.TmuxSession)}
+		for _, agent := range agents {
+			root.children = append(root.children, treeNode{label: mapAgentLine(agent, nil)})
+		}
+		if len(root.children) == 0 {
+			root.children = append(root.children, treeNode{label: "(no agents)"})
+		}
+		fmt.Println(root.label)
+		printTree(root.children, "")
+		return nil
+	}
+
+	root := mapTree(repoName, repo.TmuxSession, windows, agents)
+	fmt.Println(root.label)
+	printTree(root.children, "")
+	return nil
+}
+
+// mapTree joins a session's windows with the agents whose tmux_window_id,
+// or failing that tmux_window, names them. Windows without an agent are
+// flagged unmanaged; agents without a window are listed after the windows.
+func mapTree(repoName, session string, windows []tmux.WindowInfo, agents []map[string]interface{}) treeNode {
+	byWindow := make(map[string]map[string]interface{}, len(agents))
+	byID := make(map[string]map[string]interface{}, len(agents))
+	for _, agent := range agents {
+		if window, _ := agent["tmux_window"].(string); window != "" {
+			byWindow[window] = agent
+		}
+		if id, _ := agent["tmux_window_id"].(string); id != "" {
+			byID[id] = agent
+		}
+	}
+
+	root := treeNode{label: fmt.Sprintf("%s  [tmux %s, %d windows]", repoName, session, len(windows))}
+	seen := make(map[string]bool, len(windows))
+	for _, w := range windows {
+		index := fmt.Sprintf("%d:", w.Index)
+		if w.Active {
+			index = fmt.Sprintf("%d*", w.Index)
+		}
+		if w.Panes > 1 {
+			index += fmt.Sprintf(" (%d panes)", w.Panes)
+		}
+
+		w := w
+		agent, managed := byID[w.ID]
+		if !managed {
+			agent, managed = byWindow[w.Name]
+		}
+		if !managed {
+			root.children = append(root.children, treeNode{label: fmt.Sprintf("%s %s  (unmanaged)  %s", index, w.Name, mapIdle(w.Activity))})
+			continue
+		}
+		name, _ := agent["name"].(string)
+		seen[name] = true
+		root.children = append(root.children, treeNode{label: index + " " + mapAgentLine(agent, &w)})
+	}
+
+	for _, agent := range agents {
+		if name, _ := agent["name"].(string); !seen[name] {
+			root.children = append(root.children, treeNode{label: "-  " + mapAgentLine(agent, nil) + "  (no window)"})
+		}
+	}
+	if len(root.children) == 0 {
+		root.children = append(root.children, treeNode{label: "(no windows)"})
+	}
+	return root
+}
+
+// mapAgentLine summarises an agent for the map: name, type, status, idle
+// time when its window is known, and the start of its task
+func mapAgentLine(agent map[string]interface{}, window *tmux.WindowInfo) string {
+	name, _ := agent["name"].(string)
+	agentType, _ := agent["type"].(string)
+	status, _ := agent["status"].(string)
+	task, _ := agent["task"].(string)
+
+	parts := []string{name, agentType, status}
+	if window != nil {
+		parts = append(parts, mapIdle(window.Activity))
+	}
+	if task != "" {
+		task = strings.Join(strings.Fields(task), " ")
+		parts = append(parts, fmt.Sprintf("%q", format.Truncate(task, mapTaskWidth)))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// mapIdle formats how long a window has had no output
+func mapIdle(activity time.Time) string {
+	if activity.IsZero() {
+		return "idle -"
+	}
+	idle := time.Since(activity)
+	switch {
+	case idle < time.Minute:
+		return fmt.Sprintf("idle %ds", int(idle.Seconds()))
+	case idle < time.Hour:
+		return fmt.Sprintf("idle %dm", int(idle.Minutes()))
+	case idle < 24*time.Hour:
+		return fmt.Sprintf("idle %dh", int(idle.Hours()))
+	default:
+		return fmt.Sprintf("idle %dd", int(idle.Hours()/24))
+	}
+}
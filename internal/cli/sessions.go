@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/sessions"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// tagSession records that a tmux session this command created belongs to
+// this multiclaude root, so that cleanup by other roots sharing the tmux
+// server leaves it alone
+func (c *CLI) tagSession(tmuxClient *tmux.Client, session string) {
+	if err := sessions.Tag(context.Background(), tmuxClient, session, c.paths.Root); err != nil {
+		fmt.Printf("Warning: failed to tag tmux session %s with its root: %v\n", session, err)
+	}
+}
+
+// trackedSessions returns the tmux sessions of repos, named after them,
+// and the sessions recorded for them in st if it is not nil
+func trackedSessions(repos []string, st *state.State) map[string]bool {
+	tracked := make(map[string]bool, len(repos))
+	for _, repo := range repos {
+		tracked[sanitizeTmuxSessionName(repo)] = true
+	}
+	if st != nil {
+		for _, repo := range st.GetAllRepos() {
+			if repo.TmuxSession != "" {
+				tracked[repo.TmuxSession] = true
+			}
+		}
+	}
+	return tracked
+}
+
+// surveySessions sorts the sessions on the tmux server by the multiclaude
+// root they belong to, adopting the untagged sessions this root tracks
+func (c *CLI) surveySessions(tmuxClient *tmux.Client, tracked map[string]bool) (sessions.Survey, error) {
+	survey, err := sessions.Take(context.Background(), tmuxClient, c.paths.Root, tracked)
+	if err != nil {
+		return sessions.Survey{}, err
+	}
+	adopted, err := sessions.Adopt(context.Background(), tmuxClient, c.paths.Root, survey)
+	if err != nil {
+		fmt.Printf("Warning: failed to tag tmux sessions: %v\n", err)
+	}
+	if len(adopted) > 0 {
+		survey.Own = append(survey.Own, adopted...)
+		sort.Strings(survey.Own)
+	}
+	survey.Adoptable = nil
+	return survey, nil
+}
+
+// printForeignSessions warns about sessions of other multiclaude roots,
+// which are never touched
+func printForeignSessions(survey sessions.Survey) {
+	if survey.ForeignCount() == 0 {
+		return
+	}
+	roots := make([]string, 0, len(survey.Foreign))
+	for root := range survey.Foreign {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	fmt.Printf("\nTmux sessions of other multiclaude roots on this tmux server (%d, left alone):\n", survey.ForeignCount())
+	for _, root := range roots {
+		fmt.Printf("  %s: %s\n", root, strings.Join(survey.Foreign[root], ", "))
+	}
+}
+
+// cleanupSessions kills the tmux sessions of this root that it no longer
+// tracks. Untagged mc-* sessions it does not track may belong to another
+// root and are only reported, unless aggressive. It returns the number of
+// sessions killed.
+func (c *CLI) cleanupSessions(tmuxClient *tmux.Client, tracked map[string]bool, dryRun, aggressive, verbose bool) int {
+	survey, err := c.surveySessions(tmuxClient, tracked)
+	if err != nil {
+		fmt.Printf("Warning: failed to list tmux sessions: %v\n", err)
+		return 0
+	}
+
+	killed := 0
+	kill := func(heading string, names []string) {
+		fmt.Printf("\n%s (%d):\n", heading, len(names))
+		for _, session := range names {
+			if dryRun {
+				fmt.Printf("  Would kill: %s\n", session)
+				continue
+			}
+			if err := tmuxClient.KillSession(context.Background(), session); err != nil {
+				fmt.Printf("  Failed to kill %s: %v\n", session, err)
+			} else {
+				fmt.Printf("  Killed: %s\n", session)
+				killed++
+			}
+		}
+	}
+
+	if len(survey.Orphaned) > 0 {
+		kill("Orphaned tmux sessions", survey.Orphaned)
+	} else if verbose {
+		fmt.Println("\nNo orphaned tmux sessions found")
+	}
+
+	if len(survey.Unknown) > 0 {
+		if aggressive {
+			kill("Untagged tmux sessions", survey.Unknown)
+		} else {
+			fmt.Printf("\nUntagged tmux sessions not tracked by this root (%d, left alone):\n", len(survey.Unknown))
+			for _, session := range survey.Unknown {
+				fmt.Printf("  %s\n", session)
+			}
+			fmt.Println("  They may belong to another multiclaude root; kill them with: multiclaude cleanup --aggressive")
+		}
+	}
+
+	printForeignSessions(survey)
+	return killed
+}
+
+// printSessionWarnings prints the daemon status warnings about tmux
+// sessions of other multiclaude roots and untagged ones no root tracks
+func printSessionWarnings(data interface{}) {
+	sessionsMap, _ := data.(map[string]interface{})
+	if foreign, ok := sessionsMap["foreign"].(map[string]interface{}); ok && len(foreign) > 0 {
+		roots := make([]string, 0, len(foreign))
+		for root := range foreign {
+			roots = append(roots, root)
+		}
+		sort.Strings(roots)
+		for _, root := range roots {
+			names, _ := foreign[root].([]interface{})
+			fmt.Printf("  Warning: the tmux server also runs %d session(s) of the multiclaude root %s; they are left alone\n", len(names), root)
+		}
+	}
+	if unknown, ok := sessionsMap["unknown"].([]interface{}); ok && len(unknown) > 0 {
+		names := make([]string, 0, len(unknown))
+		for _, name := range unknown {
+			names = append(names, fmt.Sprint(name))
+		}
+		fmt.Printf("  Warning: untagged tmux sessions no multiclaude root has adopted: %s\n", strings.Join(names, ", "))
+		fmt.Println("    Run: multiclaude cleanup --aggressive")
+	}
+}
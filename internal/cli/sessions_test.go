@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/sessions"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+func TestLocalCleanupOnlyKillsOwnSessions(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Skip("tmux not available, skipping test")
+	}
+
+	// Aggressive cleanup kills every untagged mc-* session it can see, so
+	// the test gets its own tmux server rather than the developer's
+	tmuxDir, err := os.MkdirTemp("", "mc-tmux-")
+	if err != nil {
+		t.Fatalf("Failed to create tmux dir: %v", err)
+	}
+	defer os.RemoveAll(tmuxDir)
+	// An empty TMUX still leaves the server to TMUX_TMPDIR, and keeps tmux
+	// from printing format tabs as "_" in a non-UTF-8 locale
+	t.Setenv("TMUX", "")
+	t.Setenv("TMUX_TMPDIR", tmuxDir)
+	// Runs before TMUX_TMPDIR is restored, so only this server is killed
+	defer exec.Command("tmux", "kill-server").Run()
+
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	legacy := "mc-legacy-" + suffix     // untagged, tracked: adopted
+	orphan := "mc-orphan-" + suffix     // tagged with this root, untracked: killed
+	theirs := "mc-theirs-" + suffix     // tagged with another root: left alone
+	stranger := "mc-stranger-" + suffix // untagged, untracked: left alone
+	for _, name := range []string{legacy, orphan, theirs, stranger} {
+		if err := tmuxClient.CreateSession(ctx, name, true); err != nil {
+			t.Fatalf("Failed to create session %s: %v", name, err)
+		}
+		defer tmuxClient.KillSession(ctx, name)
+	}
+	if err := sessions.Tag(ctx, tmuxClient, orphan, cli.paths.Root); err != nil {
+		t.Fatalf("Failed to tag session: %v", err)
+	}
+	if err := sessions.Tag(ctx, tmuxClient, theirs, "/some/other/root"); err != nil {
+		t.Fatalf("Failed to tag session: %v", err)
+	}
+	if err := d.GetState().AddRepo("legacy-"+suffix, &state.Repository{
+		GithubURL:   "https://github.com/test/legacy",
+		TmuxSession: legacy,
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	exists := func(name string) bool {
+		has, err := tmuxClient.HasSession(ctx, name)
+		return err == nil && has
+	}
+
+	if err := cli.localCleanup(false, false, false); err != nil {
+		t.Fatalf("localCleanup() error = %v", err)
+	}
+	if exists(orphan) {
+		t.Error("orphaned session of this root was not killed")
+	}
+	for _, name := range []string{legacy, theirs, stranger} {
+		if !exists(name) {
+			t.Errorf("session %s was killed", name)
+		}
+	}
+	tags, err := tmuxClient.ListSessionOption(ctx, sessions.RootOption)
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	if tags[legacy] != cli.paths.Root {
+		t.Errorf("tracked session tagged %q, want it adopted by %s", tags[legacy], cli.paths.Root)
+	}
+
+	// --aggressive also kills the untagged session nobody tracks, but
+	// never another root's
+	if err := cli.localCleanup(false, false, true); err != nil {
+		t.Fatalf("localCleanup() error = %v", err)
+	}
+	if exists(stranger) {
+		t.Error("untagged session was not killed with --aggressive")
+	}
+	if !exists(theirs) || !exists(legacy) {
+		t.Error("--aggressive killed a session of another root or a tracked one")
+	}
+}
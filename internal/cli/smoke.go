@@ -0,0 +1,388 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/daemon"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/pkg/config"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+const (
+	// smokeRepoName and smokeAgentName name the scratch repository and its
+	// fake agent
+	smokeRepoName  = "smoke"
+	smokeAgentName = "smoke-worker"
+	// smokeWaitTimeout bounds each stage that waits on the daemon or tmux,
+	// keeping the whole run well under the 15 seconds CI allows it
+	smokeWaitTimeout = 5 * time.Second
+	// smokeOutputTail is how many lines of captured output a failed stage shows
+	smokeOutputTail = 10
+)
+
+// smokeStage is one step of the smoke test
+type smokeStage struct {
+	Name string
+	Run  func() error
+}
+
+// smokeRun holds what the smoke test stages share: the temporary root, the
+// daemon running in it and the CLI pointed at it
+type smokeRun struct {
+	root    string
+	paths   *config.Paths
+	daemon  *daemon.Daemon
+	cli     *CLI
+	verbose bool
+
+	repoPath string
+	// token is the unique text of the message sent to the fake agent
+	token string
+	// restoreEnv undoes the environment changes that isolate the run
+	restoreEnv func()
+}
+
+// smoke runs an end-to-end self-test in a throwaway root: daemon, a local
+// scratch repository initialized without GitHub, a fake agent running cat
+// instead of claude, message routing and output capture. The default root
+// (~/.multiclaude), its daemon and the user's tmux server are never touched.
+func (c *CLI) smoke(args []string) error {
+	flags, _ := ParseFlags(args)
+	run := &smokeRun{verbose: flags["verbose"] == "true"}
+
+	stages := []smokeStage{
+		{"tmux and git available", run.checkTools},
+		{"create temporary root", run.createRoot},
+		{"start daemon", run.startDaemon},
+		{"create scratch repository", run.createScratchRepo},
+		{"init --local", run.initRepo},
+		{"start fake agent", run.startFakeAgent},
+		{"route message", run.routeMessage},
+		{"capture agent output", run.checkOutputCapture},
+	}
+
+	start := time.Now()
+	format.Header("Smoke test:")
+	failed := 0
+	for _, stage := range stages {
+		if failed > 0 {
+			// Every stage builds on the previous ones
+			fmt.Printf("  %s %s\n", format.Dim.Sprint("SKIP"), stage.Name)
+			continue
+		}
+		if !run.runStage(stage) {
+			failed++
+		}
+	}
+	if !run.runStage(smokeStage{"tear down", run.teardown}) {
+		failed++
+	}
+
+	if failed > 0 {
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("smoke test failed after %s", time.Since(start).Round(time.Millisecond)))
+	}
+	fmt.Printf("\n✓ Smoke test passed in %s\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// runStage runs one stage and prints PASS or FAIL for it
+func (r *smokeRun) runStage(stage smokeStage) bool {
+	start := time.Now()
+	err := stage.Run()
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err == nil {
+		fmt.Printf("  %s %s (%s)\n", format.Green.Sprint("PASS"), stage.Name, elapsed)
+		return true
+	}
+	fmt.Printf("  %s %s (%s)\n", format.Red.Sprint("FAIL"), stage.Name, elapsed)
+	for _, line := range strings.Split(strings.TrimRight(err.Error(), "\n"), "\n") {
+		format.Dimmed("      %s", line)
+	}
+	return false
+}
+
+// checkTools fails early if the smoke test cannot run at all
+func (r *smokeRun) checkTools() error {
+	for _, tool := range []string{"tmux", "git", "cat"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("%s not found in PATH", tool)
+		}
+	}
+	return nil
+}
+
+// createRoot creates the temporary root and isolates the run from the
+// user's setup: tmux gets its own server (TMUX_TMPDIR) and agents are not
+// started with claude (test mode)
+func (r *smokeRun) createRoot() error {
+	root, err := os.MkdirTemp("", "mc-smoke-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary root: %w", err)
+	}
+	// Resolve symlinks (macOS /var -> /private/var) so paths compare equal
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	r.root = root
+	r.paths = config.NewPaths(root)
+
+	saved := make(map[string]*string)
+	for _, name := range []string{"TMUX", "TMUX_TMPDIR", "MULTICLAUDE_TEST_MODE"} {
+		if value, ok := os.LookupEnv(name); ok {
+			saved[name] = &value
+		} else {
+			saved[name] = nil
+		}
+	}
+	r.restoreEnv = func() {
+		for name, value := range saved {
+			if value == nil {
+				os.Unsetenv(name)
+			} else {
+				os.Setenv(name, *value)
+			}
+		}
+	}
+	os.Unsetenv("TMUX")
+	os.Setenv("TMUX_TMPDIR", root)
+	os.Setenv("MULTICLAUDE_TEST_MODE", "1")
+	return nil
+}
+
+// startDaemon starts a daemon in this process, serving the temporary root
+func (r *smokeRun) startDaemon() error {
+	d, err := daemon.New(r.paths)
+	if err != nil {
+		return err
+	}
+	if err := d.Start(); err != nil {
+		return err
+	}
+	r.daemon = d
+	r.cli = NewWithPaths(r.paths)
+
+	client := socket.NewClient(r.paths.DaemonSock)
+	return waitFor("the daemon to answer ping", func() (bool, error) {
+		resp, err := client.Send(socket.Request{Command: "ping"})
+		return err == nil && resp.Success, nil
+	})
+}
+
+// createScratchRepo creates a local git repository with one commit
+func (r *smokeRun) createScratchRepo() error {
+	r.repoPath = filepath.Join(r.root, "scratch", smokeRepoName)
+	if err := os.MkdirAll(r.repoPath, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(r.repoPath, "README.md"), []byte("multiclaude smoke test\n"), 0644); err != nil {
+		return err
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "README.md"},
+		{"-c", "user.name=multiclaude smoke", "-c", "user.email=smoke@localhost", "commit", "--quiet", "-m", "Scratch repository"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = r.repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s failed: %v\n%s", args[0], err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// initRepo initializes the scratch repository through the CLI, as a user
+// would, with --local so no network is needed
+func (r *smokeRun) initRepo() error {
+	output, err := r.captureOutput(func() error {
+		return r.cli.Execute([]string{"init", "--local", r.repoPath, smokeRepoName, "--no-workspace"})
+	})
+	if err != nil {
+		return fmt.Errorf("%v\n%s", err, tailLines(output, smokeOutputTail))
+	}
+	if _, exists := r.daemon.GetState().GetRepo(smokeRepoName); !exists {
+		return fmt.Errorf("the daemon does not track %s after init", smokeRepoName)
+	}
+	return nil
+}
+
+// startFakeAgent starts a worker window running cat instead of claude,
+// captures its output and registers it with the daemon. cat echoes whatever
+// is typed into it, which makes delivered messages show up in its output.
+func (r *smokeRun) startFakeAgent() error {
+	ctx := context.Background()
+	tmuxSession := sanitizeTmuxSessionName(smokeRepoName)
+	cmd := exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", smokeAgentName, "-c", r.repoPath, "cat")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create agent window: %v\n%s", err, strings.TrimSpace(string(output)))
+	}
+
+	pid, err := tmux.NewClient().GetPanePID(ctx, tmuxSession, smokeAgentName)
+	if err != nil {
+		return fmt.Errorf("failed to get agent PID: %w", err)
+	}
+	if err := r.cli.setupOutputCapture(tmuxSession, smokeAgentName, smokeRepoName, smokeAgentName, "worker"); err != nil {
+		return err
+	}
+
+	resp, err := socket.NewClient(r.paths.DaemonSock).Send(socket.Request{
+		Command: "add_agent",
+		Args: map[string]interface{}{
+			"repo":          smokeRepoName,
+			"agent":         smokeAgentName,
+			"type":          "worker",
+			"worktree_path": r.repoPath,
+			"tmux_window":   smokeAgentName,
+			"task":          "echo messages (smoke test)",
+			"pid":           pid,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register agent: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to register agent: %s", resp.Error)
+	}
+	return nil
+}
+
+// routeMessage sends the fake agent a message and waits for the daemon to
+// route it and mark it delivered
+func (r *smokeRun) routeMessage() error {
+	r.token = fmt.Sprintf("smoke-%d", time.Now().UnixNano())
+	msgMgr := messages.NewManager(r.paths.MessagesDir)
+	msg, err := msgMgr.Send(smokeRepoName, "smoke", smokeAgentName, "ping "+r.token)
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	// Ask for routing now rather than waiting for the daemon's next pass
+	resp, err := socket.NewClient(r.paths.DaemonSock).Send(socket.Request{Command: "route_messages"})
+	if err != nil {
+		return fmt.Errorf("failed to trigger message routing: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to trigger message routing: %s", resp.Error)
+	}
+
+	return waitFor(fmt.Sprintf("message %s to be delivered", msg.ID), func() (bool, error) {
+		current, err := msgMgr.Get(smokeRepoName, smokeAgentName, msg.ID)
+		if err != nil {
+			return false, err
+		}
+		return current.Status == messages.StatusDelivered, nil
+	})
+}
+
+// checkOutputCapture waits for the delivered message to show up in the
+// agent's captured output
+func (r *smokeRun) checkOutputCapture() error {
+	logFile := r.paths.AgentLogFile(smokeRepoName, smokeAgentName, true)
+	var output string
+	err := waitFor(fmt.Sprintf("the message to appear in %s", logFile), func() (bool, error) {
+		data, err := os.ReadFile(logFile)
+		if err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+		output = string(data)
+		return strings.Contains(output, r.token), nil
+	})
+	if err != nil && output != "" {
+		return fmt.Errorf("%v; captured output ends with:\n%s", err, tailLines(output, smokeOutputTail))
+	}
+	return err
+}
+
+// teardown stops the daemon and the isolated tmux server, restores the
+// environment and removes the temporary root. It runs whether or not the
+// other stages passed, and copes with any of them not having run.
+func (r *smokeRun) teardown() error {
+	var problems []string
+	if r.daemon != nil {
+		if err := r.daemon.Stop(); err != nil {
+			problems = append(problems, fmt.Sprintf("failed to stop daemon: %v", err))
+		}
+	}
+	if r.root != "" {
+		// TMUX_TMPDIR still points into the root here, so this only kills
+		// the smoke test's own tmux server. No server is fine.
+		exec.Command("tmux", "kill-server").Run()
+	}
+	if r.restoreEnv != nil {
+		r.restoreEnv()
+	}
+	if r.root != "" {
+		if err := os.RemoveAll(r.root); err != nil {
+			problems = append(problems, fmt.Sprintf("failed to remove %s: %v", r.root, err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// captureOutput runs fn with stdout and stderr redirected to a file in the
+// root, so the commands a stage runs do not interleave with the stage
+// report, and returns what they printed. With --verbose the output is shown as well.
+func (r *smokeRun) captureOutput(fn func() error) (string, error) {
+	if r.verbose {
+		return "", fn()
+	}
+	logPath := filepath.Join(r.root, "smoke-output.log")
+	f, err := os.Create(logPath)
+	if err != nil {
+		return "", fn()
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = f, f
+	runErr := fn()
+	os.Stdout, os.Stderr = stdout, stderr
+	f.Close()
+
+	data, _ := os.ReadFile(logPath)
+	return string(data), runErr
+}
+
+// waitFor polls check until it reports true, fails, or smokeWaitTimeout
+// passes
+func waitFor(what string, check func() (bool, error)) error {
+	deadline := time.Now().Add(smokeWaitTimeout)
+	for {
+		done, err := check()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for %s", smokeWaitTimeout, what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// tailLines returns the last n non-empty lines of s
+func tailLines(s string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
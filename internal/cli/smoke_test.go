@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+func TestCLISmoke(t *testing.T) {
+	if !tmux.NewClient().IsTmuxAvailable() {
+		t.Skip("tmux not available, skipping test")
+	}
+
+	tmuxTmpdir, hadTmuxTmpdir := os.LookupEnv("TMUX_TMPDIR")
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	var err error
+	output := captureStdout(t, func() {
+		err = cli.Execute([]string{"smoke"})
+	})
+	if err != nil {
+		t.Fatalf("smoke failed: %v\n%s", err, output)
+	}
+	lines := strings.Split(output, "\n")
+	for _, stage := range []string{"start daemon", "init --local", "route message", "capture agent output", "tear down"} {
+		passed := false
+		for _, line := range lines {
+			if strings.Contains(line, "PASS") && strings.Contains(line, stage) {
+				passed = true
+			}
+		}
+		if !passed {
+			t.Errorf("smoke output missing PASS for %q:\n%s", stage, output)
+		}
+	}
+	if strings.Contains(output, "FAIL") {
+		t.Errorf("smoke output has a failed stage:\n%s", output)
+	}
+
+	// The environment is restored and the caller's root untouched
+	if value, ok := os.LookupEnv("TMUX_TMPDIR"); ok != hadTmuxTmpdir || value != tmuxTmpdir {
+		t.Errorf("TMUX_TMPDIR = %q, %v after smoke; want %q, %v", value, ok, tmuxTmpdir, hadTmuxTmpdir)
+	}
+	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+		t.Error("MULTICLAUDE_TEST_MODE should be restored after smoke")
+	}
+	if _, err := os.Stat(cli.paths.RepoDir(smokeRepoName)); !os.IsNotExist(err) {
+		t.Error("smoke should not create repositories under the caller's root")
+	}
+}
+
+func TestInitLocalFlagValidation(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	err := cli.Execute([]string{"init", "--local"})
+	if err == nil || !strings.Contains(err.Error(), "--local") {
+		t.Errorf("--local without a path = %v, want a missing argument error", err)
+	}
+
+	notRepo := t.TempDir()
+	err = cli.Execute([]string{"init", "--local", notRepo})
+	if err == nil || !strings.Contains(err.Error(), "not a git repository") {
+		t.Errorf("--local with a plain directory = %v, want a not a git repository error", err)
+	}
+
+	repoPath := filepath.Join(t.TempDir(), "scratch")
+	setupTestRepo(t, repoPath)
+	err = cli.Execute([]string{"init", "--local", repoPath, "--wizard"})
+	if err == nil || !strings.Contains(err.Error(), "--wizard") {
+		t.Errorf("--local with --wizard = %v, want a usage error", err)
+	}
+
+	err = cli.Execute([]string{"init", "--local", repoPath, "--no-supervisor", "--no-merge-queue", "--no-workspace"})
+	if err == nil || !strings.Contains(err.Error(), "--no-supervisor") {
+		t.Errorf("--no-supervisor without other agents = %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,375 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+)
+
+// stageRedrawInterval is how often the live checklist is redrawn
+const stageRedrawInterval = 100 * time.Millisecond
+
+// spinnerFrames animate the running stages of the live checklist
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// stage is one named step of a multi-step command such as init. Run writes
+// its progress and the output of the commands it runs to out, which the
+// runner keeps for the failure report.
+type stage struct {
+	Name string
+	// Needs names earlier stages that must succeed first. Stages that do
+	// not need each other run concurrently.
+	Needs []string
+	Run   func(out io.Writer) error
+}
+
+// stageStatus is where a stage is in a run
+type stageStatus int
+
+const (
+	stagePending stageStatus = iota
+	stageRunning
+	stageDone
+	stageFailed
+	stageSkipped
+)
+
+// stageRunner runs stages and shows their progress: as a checklist redrawn
+// in place with per-stage timing on a terminal, as plain logs otherwise, or
+// not at all when quiet. Whatever the display, a failure is reported with
+// the stage that failed and the output it captured.
+type stageRunner struct {
+	out   io.Writer
+	live  bool
+	quiet bool
+	width int
+	// captureStray redirects stdout and stderr during a live run, so that
+	// prints from deep inside a stage do not break the checklist; they are
+	// shown with a failure
+	captureStray bool
+}
+
+// newStageRunner returns a runner for stdout, live if stdout is a terminal
+func newStageRunner(quiet bool) *stageRunner {
+	live := !quiet && format.IsTerminal(os.Stdout)
+	return &stageRunner{
+		out:          os.Stdout,
+		live:         live,
+		quiet:        quiet,
+		width:        format.TerminalWidth(),
+		captureStray: live,
+	}
+}
+
+// stageState is the progress of one stage in a run
+type stageState struct {
+	stage
+	status  stageStatus
+	started time.Time
+	elapsed time.Duration
+	err     error
+	output  stageOutput
+	done    chan struct{}
+}
+
+// stageOutput collects a stage's output, remembering its last line for the
+// live checklist
+type stageOutput struct {
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	lastLine string
+}
+
+func (o *stageOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf.Write(p)
+	// Progress meters such as git's redraw their line with \r
+	for _, line := range strings.FieldsFunc(string(p), func(r rune) bool { return r == '\n' || r == '\r' }) {
+		if line = strings.TrimSpace(line); line != "" {
+			o.lastLine = line
+		}
+	}
+	return len(p), nil
+}
+
+// String returns everything the stage wrote
+func (o *stageOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.String()
+}
+
+// last returns the last line the stage wrote
+func (o *stageOutput) last() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.lastLine
+}
+
+// Run runs the stages, each as soon as the stages it needs have succeeded.
+// A failure skips the stages that need the failed one, but lets the others
+// finish. The error names the first stage, in order, that failed.
+func (r *stageRunner) Run(stages []stage) error {
+	states := make([]*stageState, len(stages))
+	byName := make(map[string]*stageState, len(stages))
+	for i, s := range stages {
+		for _, need := range s.Needs {
+			if byName[need] == nil {
+				return fmt.Errorf("stage %q needs %q, which is not an earlier stage", s.Name, need)
+			}
+		}
+		if byName[s.Name] != nil {
+			return fmt.Errorf("duplicate stage %q", s.Name)
+		}
+		states[i] = &stageState{stage: s, done: make(chan struct{})}
+		byName[s.Name] = states[i]
+	}
+
+	var mu sync.Mutex // guards the states' status, timing and err, and out
+	var stray bytes.Buffer
+	restore := func() {}
+	if r.captureStray {
+		restore = redirectOutput(&stray)
+	}
+
+	var wg sync.WaitGroup
+	for _, st := range states {
+		wg.Add(1)
+		go func(st *stageState) {
+			defer wg.Done()
+			defer close(st.done)
+
+			for _, need := range st.Needs {
+				<-byName[need].done
+				mu.Lock()
+				ok := byName[need].status == stageDone
+				mu.Unlock()
+				if !ok {
+					mu.Lock()
+					st.status = stageSkipped
+					if !r.live && !r.quiet {
+						fmt.Fprintf(r.out, "- %s skipped, as %s did not finish\n", st.Name, need)
+					}
+					mu.Unlock()
+					return
+				}
+			}
+
+			mu.Lock()
+			st.status, st.started = stageRunning, time.Now()
+			if !r.live && !r.quiet {
+				fmt.Fprintf(r.out, "==> %s\n", st.Name)
+			}
+			mu.Unlock()
+
+			err := st.Run(&st.output)
+
+			mu.Lock()
+			st.elapsed, st.err = time.Since(st.started), err
+			st.status = stageDone
+			if err != nil {
+				st.status = stageFailed
+			}
+			mu.Unlock()
+			if !r.live && !r.quiet {
+				r.logStage(st, &mu)
+			}
+		}(st)
+	}
+
+	if r.live {
+		stop := make(chan struct{})
+		drawn := make(chan struct{})
+		go func() {
+			defer close(drawn)
+			ticker := time.NewTicker(stageRedrawInterval)
+			defer ticker.Stop()
+			lines := 0
+			for frame := 0; ; frame++ {
+				lines = r.drawChecklist(states, &mu, frame, lines)
+				select {
+				case <-ticker.C:
+				case <-stop:
+					r.drawChecklist(states, &mu, frame, lines)
+					return
+				}
+			}
+		}()
+		wg.Wait()
+		close(stop)
+		<-drawn
+	} else {
+		wg.Wait()
+	}
+
+	restore()
+	if err := r.failure(states, stray.String()); err != nil {
+		return err
+	}
+	if r.live {
+		r.warnings(states, stray.String())
+	}
+	return nil
+}
+
+// warnings prints the warnings in the output of a successful live run, which
+// the checklist would otherwise hide
+func (r *stageRunner) warnings(states []*stageState, stray string) {
+	outputs := []string{stray}
+	for _, st := range states {
+		outputs = append(outputs, st.output.String())
+	}
+	for _, output := range outputs {
+		for _, line := range outputLines(output) {
+			if strings.HasPrefix(line, "Warning:") {
+				fmt.Fprintln(r.out, line)
+			}
+		}
+	}
+}
+
+// logStage prints a finished stage's result and output as one block, so the
+// logs of concurrent stages do not interleave
+func (r *stageRunner) logStage(st *stageState, mu *sync.Mutex) {
+	mu.Lock()
+	defer mu.Unlock()
+	var sb strings.Builder
+	for _, line := range outputLines(st.output.String()) {
+		fmt.Fprintf(&sb, "    %s\n", line)
+	}
+	if st.err != nil {
+		fmt.Fprintf(&sb, "%s %s failed after %s: %v\n", format.Red.Sprint("✗"), st.Name, formatStageTime(st.elapsed), st.err)
+	} else {
+		fmt.Fprintf(&sb, "%s %s (%s)\n", format.Green.Sprint("✓"), st.Name, formatStageTime(st.elapsed))
+	}
+	io.WriteString(r.out, sb.String())
+}
+
+// drawChecklist draws the checklist of stages, over the previous drawing of
+// it when lines is its height, and returns the new height
+func (r *stageRunner) drawChecklist(states []*stageState, mu *sync.Mutex, frame, lines int) int {
+	mu.Lock()
+	defer mu.Unlock()
+
+	nameWidth := 0
+	for _, st := range states {
+		nameWidth = max(nameWidth, len(st.Name))
+	}
+
+	var sb strings.Builder
+	if lines > 0 {
+		fmt.Fprintf(&sb, "\033[%dA", lines)
+	}
+	for _, st := range states {
+		var icon, timing, detail string
+		switch st.status {
+		case stagePending:
+			icon = format.Dim.Sprint("·")
+		case stageRunning:
+			icon = format.Cyan.Sprint(spinnerFrames[frame%len(spinnerFrames)])
+			timing = formatStageTime(time.Since(st.started))
+			detail = st.output.last()
+		case stageDone:
+			icon, timing = format.Green.Sprint("✓"), formatStageTime(st.elapsed)
+		case stageFailed:
+			icon, timing = format.Red.Sprint("✗"), formatStageTime(st.elapsed)
+			detail = st.err.Error()
+		case stageSkipped:
+			icon, detail = format.Dim.Sprint("-"), "skipped"
+		}
+		line := fmt.Sprintf("  %s %-*s %7s", icon, nameWidth, st.Name, timing)
+		if detail != "" {
+			// The line must fit the terminal, or redrawing in place fails
+			room := r.width - nameWidth - 15
+			if room > 3 {
+				line += "  " + format.Dim.Sprint(format.Truncate(detail, room))
+			}
+		}
+		fmt.Fprintf(&sb, "\r\033[K%s\n", line)
+	}
+	io.WriteString(r.out, sb.String())
+	return len(states)
+}
+
+// failure returns the error of the first failed stage, after printing the
+// output of every failed stage (already shown when logging plainly) and any
+// stray output
+func (r *stageRunner) failure(states []*stageState, stray string) error {
+	var first *stageState
+	for _, st := range states {
+		if st.status != stageFailed {
+			continue
+		}
+		if first == nil {
+			first = st
+		}
+		if r.live || r.quiet {
+			if lines := outputLines(st.output.String()); len(lines) > 0 {
+				fmt.Fprintf(r.out, "\nOutput of %s:\n", st.Name)
+				for _, line := range lines {
+					fmt.Fprintf(r.out, "    %s\n", line)
+				}
+			}
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	if lines := outputLines(stray); len(lines) > 0 {
+		fmt.Fprintln(r.out, "\nOther output:")
+		for _, line := range lines {
+			fmt.Fprintf(r.out, "    %s\n", line)
+		}
+	}
+	return errors.StageFailed(first.Name, first.err)
+}
+
+// outputLines splits captured output into lines, keeping only the final
+// state of lines redrawn with \r
+func outputLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if i := strings.LastIndex(strings.TrimRight(line, "\r"), "\r"); i >= 0 {
+			line = line[i+1:]
+		}
+		if line = strings.TrimRight(line, "\r "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// formatStageTime formats a stage's elapsed time to a tenth of a second
+func formatStageTime(d time.Duration) string {
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
+
+// redirectOutput sends what is written to os.Stdout and os.Stderr to w
+// until the returned function restores them
+func redirectOutput(w io.Writer) func() {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return func() {}
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = writer, writer
+	copied := make(chan struct{})
+	go func() {
+		io.Copy(w, reader)
+		close(copied)
+	}()
+	return func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		writer.Close()
+		<-copied
+		reader.Close()
+	}
+}
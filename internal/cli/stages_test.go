@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+)
+
+// fakeStage returns a stage that records its run in order and writes a line
+// of output before returning err
+func fakeStage(name string, needs []string, order *[]string, mu *sync.Mutex, err error) stage {
+	return stage{Name: name, Needs: needs, Run: func(out io.Writer) error {
+		mu.Lock()
+		*order = append(*order, name)
+		mu.Unlock()
+		fmt.Fprintf(out, "running %s\n", name)
+		return err
+	}}
+}
+
+func TestStageRunnerOrdersByNeeds(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	stages := []stage{
+		fakeStage("clone", nil, &order, &mu, nil),
+		fakeStage("session", []string{"clone"}, &order, &mu, nil),
+		fakeStage("prompts", []string{"session"}, &order, &mu, nil),
+	}
+
+	var out bytes.Buffer
+	r := &stageRunner{out: &out}
+	if err := r.Run(stages); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if got := strings.Join(order, ","); got != "clone,session,prompts" {
+		t.Errorf("stages ran in order %s, want clone,session,prompts", got)
+	}
+
+	// Plain logs show each stage starting, its output and its result
+	for _, want := range []string{"==> clone", "    running clone", "✓ clone (", "✓ prompts ("} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestStageRunnerRunsIndependentStagesConcurrently(t *testing.T) {
+	// Each of the two stages waits for the other to start, so they only
+	// finish if they run at the same time
+	supervisorStarted := make(chan struct{})
+	mergeQueueStarted := make(chan struct{})
+	waitFor := func(started, other chan struct{}) func(io.Writer) error {
+		return func(io.Writer) error {
+			close(started)
+			select {
+			case <-other:
+				return nil
+			case <-time.After(5 * time.Second):
+				return fmt.Errorf("the other stage did not start")
+			}
+		}
+	}
+	stages := []stage{
+		{Name: "prompts", Run: func(io.Writer) error { return nil }},
+		{Name: "supervisor", Needs: []string{"prompts"}, Run: waitFor(supervisorStarted, mergeQueueStarted)},
+		{Name: "merge-queue", Needs: []string{"prompts"}, Run: waitFor(mergeQueueStarted, supervisorStarted)},
+	}
+
+	r := &stageRunner{out: io.Discard}
+	if err := r.Run(stages); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+}
+
+func TestStageRunnerReportsFailedStage(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	stages := []stage{
+		fakeStage("clone", nil, &order, &mu, nil),
+		{Name: "session", Needs: []string{"clone"}, Run: func(out io.Writer) error {
+			fmt.Fprintln(out, "tmux: no server running")
+			return fmt.Errorf("exit status 1")
+		}},
+		fakeStage("registration", []string{"session"}, &order, &mu, nil),
+		fakeStage("notes", []string{"clone"}, &order, &mu, nil),
+	}
+
+	for _, quiet := range []bool{false, true} {
+		order = nil
+		var out bytes.Buffer
+		r := &stageRunner{out: &out, quiet: quiet}
+		err := r.Run(stages)
+		if err == nil {
+			t.Fatalf("quiet=%v: Run() should fail", quiet)
+		}
+
+		cliErr, ok := err.(*errors.CLIError)
+		if !ok {
+			t.Fatalf("quiet=%v: error is %T, want *errors.CLIError", quiet, err)
+		}
+		if !strings.Contains(cliErr.Message, "session stage failed") {
+			t.Errorf("quiet=%v: error %q should name the session stage", quiet, cliErr.Message)
+		}
+		if !strings.Contains(out.String(), "tmux: no server running") {
+			t.Errorf("quiet=%v: output should include the failed stage's output:\n%s", quiet, out.String())
+		}
+
+		// The stage that needs the failed one is skipped; the independent
+		// one still runs
+		mu.Lock()
+		ran := strings.Join(order, ",")
+		mu.Unlock()
+		if strings.Contains(ran, "registration") {
+			t.Errorf("quiet=%v: registration ran after session failed", quiet)
+		}
+		if !strings.Contains(ran, "notes") {
+			t.Errorf("quiet=%v: notes should run despite session failing, ran %s", quiet, ran)
+		}
+
+		if quiet && strings.Contains(out.String(), "running clone") {
+			t.Errorf("quiet run printed a successful stage's output:\n%s", out.String())
+		}
+		if !quiet && !strings.Contains(out.String(), "- registration skipped, as session did not finish") {
+			t.Errorf("plain output should note the skipped stage:\n%s", out.String())
+		}
+	}
+}
+
+func TestStageRunnerQuiet(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	var out bytes.Buffer
+	r := &stageRunner{out: &out, quiet: true}
+	if err := r.Run([]stage{fakeStage("clone", nil, &order, &mu, nil)}); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("quiet run printed %q, want nothing", out.String())
+	}
+}
+
+func TestStageRunnerLive(t *testing.T) {
+	stages := []stage{
+		{Name: "clone", Run: func(out io.Writer) error {
+			fmt.Fprint(out, "Receiving objects:  50%\rReceiving objects: 100%\n")
+			return nil
+		}},
+		{Name: "prompts", Needs: []string{"clone"}, Run: func(out io.Writer) error {
+			fmt.Fprintln(out, "Warning: failed to copy hooks config: denied")
+			return nil
+		}},
+	}
+
+	var out bytes.Buffer
+	r := &stageRunner{out: &out, live: true, width: 80}
+	if err := r.Run(stages); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	// The final drawing of the checklist shows both stages done, and the
+	// warning the checklist hid is printed after it
+	got := out.String()
+	final := got[strings.LastIndex(got, "\033[2A"):]
+	for _, want := range []string{"✓", "clone", "prompts"} {
+		if !strings.Contains(final, want) {
+			t.Errorf("final checklist missing %q:\n%q", want, final)
+		}
+	}
+	if !strings.HasSuffix(got, "Warning: failed to copy hooks config: denied\n") {
+		t.Errorf("live output should end with the stage's warning:\n%q", got)
+	}
+}
+
+func TestStageRunnerRejectsUnknownNeed(t *testing.T) {
+	r := &stageRunner{out: io.Discard}
+	err := r.Run([]stage{
+		{Name: "session", Needs: []string{"clone"}, Run: func(io.Writer) error { return nil }},
+	})
+	if err == nil || !strings.Contains(err.Error(), `needs "clone"`) {
+		t.Errorf("Run() = %v, want an error about the unknown need", err)
+	}
+}
+
+func TestOutputLines(t *testing.T) {
+	got := outputLines("Cloning\nReceiving 10%\rReceiving 100%\r\n\ndone\n")
+	want := []string{"Cloning", "Receiving 100%", "done"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("outputLines() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/dlorenc/multiclaude/internal/daemon"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// stateBackupsList lists the backups of state.json, newest first
+func (c *CLI) stateBackupsList(args []string) error {
+	backups, policy, err := state.ListBackups(c.paths.StateFile)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to list state backups", err)
+	}
+
+	fmt.Printf("Backups in %s (keeping the last %d, plus one a day for %d days)\n", state.BackupDir(c.paths.StateFile), policy.Keep, policy.Days)
+	if len(backups) == 0 {
+		fmt.Println("No backups yet; one is written every time the state changes")
+		return nil
+	}
+
+	fmt.Println()
+	table := format.NewColoredTable("ID", "TAKEN", "AGE", "SIZE")
+	for i := len(backups) - 1; i >= 0; i-- {
+		b := backups[i]
+		table.AddRow(
+			format.Cell(b.ID),
+			format.Cell(b.Time.Local().Format("2006-01-02 15:04:05")),
+			format.Cell(format.TimeAgo(b.Time)),
+			format.Cell(formatByteSize(int(b.Size))),
+		)
+	}
+	table.Print()
+	return nil
+}
+
+// stateBackupsConfig shows or changes how many state backups are kept
+func (c *CLI) stateBackupsConfig(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	_, policy, err := state.ListBackups(c.paths.StateFile)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to read state backup settings", err)
+	}
+
+	changed := false
+	for _, name := range []string{"keep", "days"} {
+		value, ok := flags[name]
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return errors.InvalidUsage(fmt.Sprintf("--%s must be a positive number, got %q", name, value))
+		}
+		if name == "keep" {
+			policy.Keep = n
+		} else {
+			policy.Days = n
+		}
+		changed = true
+	}
+
+	if changed {
+		if err := state.SetBackupPolicy(c.paths.StateFile, policy); err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to save state backup settings", err)
+		}
+		c.auditLocal("state_backups_config", map[string]interface{}{"keep": policy.Keep, "days": policy.Days}, nil)
+	}
+
+	fmt.Printf("Keeping the last %d state backups, plus one a day for %d days\n", policy.Keep, policy.Days)
+	return nil
+}
+
+// stateRestore swaps a backup in as state.json and repairs it against the
+// tmux sessions and worktrees that exist now. The daemon keeps state in
+// memory and would overwrite the restored file, so a running daemon is
+// refused unless --force, which pauses it during the swap and has it reload
+// the state afterwards.
+func (c *CLI) stateRestore(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) == 0 {
+		return errors.MissingArgument("timestamp", "backup ID or time")
+	}
+	dryRun := flags["dry-run"] == "true"
+	force := flags["force"] == "true"
+
+	backup, err := state.FindBackup(c.paths.StateFile, posArgs[0])
+	if err != nil {
+		return errors.New(errors.CategoryNotFound, err.Error()).WithSuggestion("multiclaude state backups list")
+	}
+	restored, err := state.LoadBackup(c.paths.StateFile, backup.ID)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to load backup", err)
+	}
+	current, err := c.loadState()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Backup %s, taken %s (%s)\n", backup.ID, backup.Time.Local().Format("2006-01-02 15:04:05"), format.TimeAgo(backup.Time))
+	divergence := stateDivergence(current, restored, tmux.NewClient(), func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	})
+	if len(divergence) == 0 {
+		fmt.Println("It matches the current state and the running tmux sessions and worktrees")
+	} else {
+		fmt.Println("Differences from now:")
+		for _, line := range divergence {
+			fmt.Printf("  - %s\n", line)
+		}
+	}
+
+	if dryRun {
+		fmt.Println("\nDry run: state.json was not changed")
+		return nil
+	}
+
+	running, pid, _ := daemon.NewPIDFile(c.paths.DaemonPID).IsRunning()
+	if running && !force {
+		return errors.DaemonMustBeStopped("restoring state", pid).
+			WithSuggestion("multiclaude daemon stop, or pass --force to pause the daemon during the restore")
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	paused := false
+	if running {
+		resp, err := client.Send(socket.Request{Command: "pause"})
+		if err != nil {
+			return errors.DaemonCommunicationFailed("pausing the daemon", err)
+		}
+		if !resp.Success {
+			return errors.New(errors.CategoryRuntime, "failed to pause the daemon: "+resp.Error)
+		}
+		paused = true
+		defer func() {
+			if paused {
+				client.Send(socket.Request{Command: "resume"})
+			}
+		}()
+	}
+
+	restoreArgs := map[string]interface{}{"backup": backup.ID}
+	if err := state.RestoreBackup(c.paths.StateFile, backup.ID); err != nil {
+		c.auditLocal("state_restore", restoreArgs, err)
+		return errors.Wrap(errors.CategoryRuntime, "failed to restore state", err)
+	}
+	c.auditLocal("state_restore", restoreArgs, nil)
+	fmt.Printf("\n✓ Restored state.json from backup %s (the replaced state was backed up first)\n\n", backup.ID)
+
+	if paused {
+		resp, err := client.Send(socket.Request{Command: "resume"})
+		if err != nil {
+			return errors.DaemonCommunicationFailed("resuming the daemon", err)
+		}
+		if !resp.Success {
+			return errors.New(errors.CategoryRuntime, "failed to resume the daemon: "+resp.Error).
+				WithSuggestion("multiclaude daemon stop && multiclaude start")
+		}
+		paused = false
+	}
+
+	return c.repair([]string{"--verbose"})
+}
+
+// stateDivergence describes how the restored state differs from the
+// current one and from what exists now: agents added or lost by the
+// restore, and agents whose tmux session, window or worktree is gone.
+// Repair removes the agents whose tmux session or window is gone.
+func stateDivergence(current, restored *state.State, tmuxClient *tmux.Client, exists func(path string) bool) []string {
+	var lines []string
+	ctx := context.Background()
+
+	currentRepos := current.GetAllRepos()
+	restoredRepos := restored.GetAllRepos()
+	for _, repoName := range sortedKeys(currentRepos) {
+		repo, ok := restoredRepos[repoName]
+		if !ok {
+			lines = append(lines, fmt.Sprintf("repo %s is tracked now but not in the backup", repoName))
+			continue
+		}
+		for _, agentName := range sortedKeys(currentRepos[repoName].Agents) {
+			if _, ok := repo.Agents[agentName]; !ok {
+				lines = append(lines, fmt.Sprintf("agent %s/%s exists now but not in the backup", repoName, agentName))
+			}
+		}
+	}
+
+	for _, repoName := range sortedKeys(restoredRepos) {
+		repo := restoredRepos[repoName]
+		currentRepo, tracked := currentRepos[repoName]
+		if !tracked {
+			lines = append(lines, fmt.Sprintf("repo %s is in the backup but not tracked now", repoName))
+		}
+		if hasSession, err := tmuxClient.HasSession(ctx, repo.TmuxSession); err == nil && !hasSession {
+			line := fmt.Sprintf("repo %s: tmux session %s is gone", repoName, repo.TmuxSession)
+			if len(repo.Agents) > 0 {
+				line += fmt.Sprintf(", repair removes its %d agent(s)", len(repo.Agents))
+			}
+			lines = append(lines, line)
+			continue
+		}
+		for _, agentName := range sortedKeys(repo.Agents) {
+			agent := repo.Agents[agentName]
+			if tracked {
+				if _, ok := currentRepo.Agents[agentName]; !ok {
+					lines = append(lines, fmt.Sprintf("agent %s/%s is in the backup but not now", repoName, agentName))
+				}
+			}
+			if hasWindow, err := tmuxClient.HasWindow(ctx, repo.TmuxSession, agent.WindowTarget()); err == nil && !hasWindow {
+				lines = append(lines, fmt.Sprintf("agent %s/%s: tmux window %s is gone, repair removes it", repoName, agentName, agent.TmuxWindow))
+				continue
+			}
+			if agent.WorktreePath != "" && !exists(agent.WorktreePath) {
+				lines = append(lines, fmt.Sprintf("agent %s/%s: worktree %s is missing", repoName, agentName, agent.WorktreePath))
+			}
+		}
+	}
+	return lines
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
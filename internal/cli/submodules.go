@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// extractNoSubmodulesFlag removes --no-submodules, which takes no value, from
+// args so that ParseFlags does not take the task or name following it as its
+// value
+func extractNoSubmodulesFlag(args []string) (bool, []string) {
+	skip := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--no-submodules" || arg == "--no-submodules=true" {
+			skip = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return skip, rest
+}
+
+// submoduleTimeout returns how long checking out a repository's submodules
+// may take, falling back to the default when the daemon cannot say
+func (c *CLI) submoduleTimeout(repoName string) time.Duration {
+	values, err := c.repoConfig(repoName)
+	if err != nil {
+		return state.DefaultSubmoduleTimeout
+	}
+	timeout, err := time.ParseDuration(values["submodule-timeout"])
+	if err != nil || timeout <= 0 {
+		return state.DefaultSubmoduleTimeout
+	}
+	return timeout
+}
+
+// checkoutSubmodules runs `git submodule update --init --recursive` in a new
+// worktree that declares submodules, so the agent starts with them in place.
+// Git's output goes to the agent's log; agentType picks the log as in
+// setupOutputCapture.
+func (c *CLI) checkoutSubmodules(repoName, wtPath, agentName, agentType string) error {
+	if !worktree.HasSubmodules(wtPath) {
+		return nil
+	}
+
+	isWorker := agentType == "worker" || agentType == "review" || agentType == "ephemeral"
+	logFile := c.paths.AgentLogFile(repoName, agentName, isWorker)
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open agent log: %w", err)
+	}
+	defer f.Close()
+
+	timeout := c.submoduleTimeout(repoName)
+	fmt.Printf("Checking out submodules (output in %s)...\n", logFile)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := worktree.UpdateSubmodules(ctx, wtPath, f); err != nil {
+		return errors.SubmoduleUpdateFailed(err)
+	}
+	return nil
+}
+
+// removeNewWorktree undoes the creation of a worktree and its branch after a
+// later setup step failed, reporting anything left behind
+func removeNewWorktree(wt *worktree.Manager, wtPath, branch string) {
+	if err := wt.Remove(wtPath, true); err != nil {
+		fmt.Printf("Warning: failed to remove worktree %s: %v\n", wtPath, err)
+	}
+	if err := wt.DeleteBranch(branch); err != nil {
+		fmt.Printf("Warning: failed to delete branch %s: %v\n", branch, err)
+	}
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// daemonThrottle sets, resets or shows the per-repo worker limit, or the
+// separate ephemeral agent limit with --ephemeral
+func (c *CLI) daemonThrottle(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	var repoName string
+	if len(posArgs) > 0 {
+		repoName = posArgs[0]
+	} else {
+		var err error
+		repoName, err = c.resolveRepo(flags)
+		if err != nil {
+			return errors.NotInRepo()
+		}
+	}
+
+	// Ephemeral agents are capped separately from workers
+	configKey, noun := "max-concurrent-workers", "worker"
+	if flags["ephemeral"] == "true" {
+		configKey, noun = "max-concurrent-ephemeral", "ephemeral agent"
+	}
+
+	maxStr, hasMax := flags["max-concurrent-agents"]
+	reset := flags["reset"] == "true"
+	if hasMax && reset {
+		return errors.InvalidUsage("--max-concurrent-agents and --reset cannot be used together")
+	}
+
+	if !hasMax && !reset {
+		max, err := c.repoAgentLimit(repoName, configKey)
+		if err != nil {
+			return err
+		}
+		if max == 0 {
+			fmt.Printf("Repository '%s' has no %s limit\n", repoName, noun)
+		} else {
+			fmt.Printf("Repository '%s' is limited to %d concurrent %ss\n", repoName, max, noun)
+		}
+		return nil
+	}
+
+	max := 0
+	if hasMax {
+		n, err := strconv.Atoi(maxStr)
+		if err != nil || n < 1 {
+			return errors.InvalidUsage(fmt.Sprintf("--max-concurrent-agents must be a positive integer, got %q (use --reset to remove the limit)", maxStr))
+		}
+		max = n
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "update_repo_config",
+		Args: map[string]interface{}{
+			"name": repoName,
+			"set":  map[string]string{configKey: strconv.Itoa(max)},
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("updating "+noun+" limit", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to update "+noun+" limit", fmt.Errorf("%s", resp.Error))
+	}
+
+	if max == 0 {
+		fmt.Printf("Removed %s limit for repository '%s'\n", noun, repoName)
+	} else {
+		fmt.Printf("Limited repository '%s' to %d concurrent %ss\n", repoName, max, noun)
+	}
+	return nil
+}
+
+// maxConcurrentWorkers returns the worker limit for a repository (0 means unlimited)
+func (c *CLI) maxConcurrentWorkers(repoName string) (int, error) {
+	return c.repoAgentLimit(repoName, "max-concurrent-workers")
+}
+
+// maxConcurrentEphemeral returns the ephemeral agent limit for a repository
+// (0 means unlimited)
+func (c *CLI) maxConcurrentEphemeral(repoName string) (int, error) {
+	return c.repoAgentLimit(repoName, "max-concurrent-ephemeral")
+}
+
+// worktreeLimit returns the worker worktree limit for a repository (0 means
+// unlimited)
+func (c *CLI) worktreeLimit(repoName string) (int, error) {
+	return c.repoAgentLimit(repoName, "worktree-limit")
+}
+
+// repoAgentLimit reads an agent limit from a repository's config
+func (c *CLI) repoAgentLimit(repoName, configKey string) (int, error) {
+	values, err := c.repoConfig(repoName)
+	if err != nil {
+		return 0, err
+	}
+	max, _ := strconv.Atoi(values[configKey])
+	return max, nil
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// watchFSInterval is how often daemon watch-fs reads worktree HEADs
+const watchFSInterval = 2 * time.Second
+
+// watchFSFlag turns on the daemon's filesystem watcher
+var watchFSFlag = FlagSpec{Name: "watch-fs", Type: "bool", Description: "Record branches switched in agent worktrees outside multiclaude"}
+
+// extractWatchFSFlag removes --watch-fs, which takes no value, from args so
+// that ParseFlags does not take the name following it as its value
+func extractWatchFSFlag(args []string) (bool, []string) {
+	watch := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--watch-fs" || arg == "--watch-fs=true" {
+			watch = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return watch, rest
+}
+
+// daemonWatchFS prints changes to the HEAD of agent worktrees, such as
+// branches switched or commits made by hand, until interrupted. Branch
+// changes are passed on to the daemon so that its state stays accurate.
+func (c *CLI) daemonWatchFS(args []string) error {
+	flags, _ := ParseFlags(args)
+	repoName := flags["repo"]
+	if repoName != "" {
+		st, err := c.loadState()
+		if err != nil {
+			return err
+		}
+		if _, exists := st.GetRepo(repoName); !exists {
+			return errors.New(errors.CategoryNotFound, fmt.Sprintf("repository '%s' not found", repoName))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if repoName == "" {
+		format.Dimmed("Watching the worktrees of all repositories (Ctrl-C to stop)")
+	} else {
+		format.Dimmed("Watching the worktrees of %s (Ctrl-C to stop)", repoName)
+	}
+	return c.watchWorktrees(ctx, repoName, watchFSInterval, os.Stdout)
+}
+
+// watchedWorktree is an agent worktree daemon watch-fs reads the HEAD of
+type watchedWorktree struct {
+	repo, agent string
+}
+
+// watchWorktrees polls the worktrees of repoName's agents, or of every
+// repository's if it is "", every interval until ctx is done, writing each
+// change to out
+func (c *CLI) watchWorktrees(ctx context.Context, repoName string, interval time.Duration, out io.Writer) error {
+	watcher := worktree.NewHeadWatcher()
+	client := socket.NewClient(c.paths.DaemonSock)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		owners, err := c.agentWorktrees(repoName)
+		if err != nil {
+			return err
+		}
+		paths := make([]string, 0, len(owners))
+		for path := range owners {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, event := range watcher.Poll(paths) {
+			owner := owners[event.Path]
+			switch event.Kind {
+			case worktree.WatchEventAdded:
+				fmt.Fprintf(out, "%s/%s: watching %s\n", owner.repo, owner.agent, headName(event.New))
+			case worktree.WatchEventBranch:
+				fmt.Fprintf(out, "%s/%s: switched from %s to %s\n", owner.repo, owner.agent, headName(event.Old), headName(event.New))
+				notifyBranchChange(client, owner, out)
+			case worktree.WatchEventCommit:
+				fmt.Fprintf(out, "%s/%s: %s moved to %s\n", owner.repo, owner.agent, headName(event.Old), shortCommit(event.New.Commit))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// agentWorktrees returns the worktrees of the agents in repoName, or in
+// every repository if it is "", by path. Ephemeral agents work in the
+// primary checkout and have none of their own.
+func (c *CLI) agentWorktrees(repoName string) (map[string]watchedWorktree, error) {
+	st, err := c.loadState()
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[string]watchedWorktree)
+	for name, repo := range st.GetAllRepos() {
+		if repoName != "" && name != repoName {
+			continue
+		}
+		for agentName, agent := range repo.Agents {
+			if agent.WorktreePath == "" || agent.Type == state.AgentTypeEphemeral {
+				continue
+			}
+			owners[agent.WorktreePath] = watchedWorktree{repo: name, agent: agentName}
+		}
+	}
+	return owners, nil
+}
+
+// notifyBranchChange asks the daemon to record the branch now checked out
+// in a worktree. A daemon that is not running is not an error; watch-fs
+// still prints the changes.
+func notifyBranchChange(client *socket.Client, owner watchedWorktree, out io.Writer) {
+	resp, err := client.Send(socket.Request{
+		Command: "detect_branch_change",
+		Args: map[string]interface{}{
+			"repo":  owner.repo,
+			"agent": owner.agent,
+		},
+	})
+	if err != nil {
+		return
+	}
+	if !resp.Success {
+		fmt.Fprintf(out, "  warning: daemon did not record the branch: %s\n", resp.Error)
+	}
+}
+
+// headName names what a worktree has checked out
+func headName(head worktree.HeadState) string {
+	if head.Branch == "" {
+		return "detached HEAD at " + shortCommit(head.Commit)
+	}
+	return head.Branch
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// syncBuffer is a bytes.Buffer that can be written and read concurrently
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestDaemonWatchFS(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repoPath := filepath.Join(cli.paths.Root, "watch-repo")
+	setupTestRepo(t, repoPath)
+	wtPath := filepath.Join(cli.paths.WorktreesDir, "watch-repo", "fox")
+	if out, err := exec.Command("git", "-C", repoPath, "worktree", "add", "-b", "work/fox", wtPath).CombinedOutput(); err != nil {
+		t.Fatalf("Failed to add worktree: %v\n%s", err, out)
+	}
+	if err := d.GetState().AddRepo("watch-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/watch-repo",
+		TmuxSession: "mc-watch-repo",
+		Agents: map[string]state.Agent{
+			"fox": {Type: state.AgentTypeWorker, WorktreePath: wtPath, TmuxWindow: "fox", CreatedAt: time.Now()},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	var out syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- cli.watchWorktrees(ctx, "watch-repo", 10*time.Millisecond, &out) }()
+
+	waitForOutput := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("watch-fs output %q does not contain %q", out.String(), want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitForOutput("watch-repo/fox: watching work/fox")
+
+	if out, err := exec.Command("git", "-C", wtPath, "checkout", "-b", "experiment").CombinedOutput(); err != nil {
+		t.Fatalf("Failed to switch branch: %v\n%s", err, out)
+	}
+	waitForOutput("watch-repo/fox: switched from work/fox to experiment")
+
+	// The switch is passed on to the daemon
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		agent, _ := d.GetState().GetAgent("watch-repo", "fox")
+		if agent.Branch == "experiment" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("daemon recorded branch %q, want experiment", agent.Branch)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("watchWorktrees() error = %v", err)
+	}
+
+	if err := cli.Execute([]string{"daemon", "watch-fs", "--repo", "no-such-repo"}); err == nil {
+		t.Error("watch-fs of an unknown repository should fail")
+	}
+}
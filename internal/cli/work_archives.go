@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/archive"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// extractArchiveFlags removes --no-archive and --force from args. Neither
+// takes a value, and ParseFlags would otherwise take the argument following
+// them, e.g. the worker name, as theirs.
+func extractArchiveFlags(args []string) (noArchive, force bool, rest []string) {
+	rest = make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg {
+		case "--no-archive", "--no-archive=true":
+			noArchive = true
+		case "--force", "--force=true":
+			force = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return noArchive, force, rest
+}
+
+// archiveBranch bundles a branch that is about to be deleted into the
+// repository's archives directory and returns the bundle's path. With force,
+// a failure is only reported and "" is returned, so removal can go ahead.
+func (c *CLI) archiveBranch(repoName string, wt *worktree.Manager, branch string, force bool) (string, error) {
+	path, err := archive.Create(wt, c.paths.ArchivesDir(repoName), branch, time.Now())
+	if err != nil {
+		if !force {
+			return "", errors.BranchArchiveFailed(branch, err)
+		}
+		fmt.Printf("Warning: failed to archive branch %s: %v (continuing because of --force)\n", branch, err)
+		return "", nil
+	}
+	return path, nil
+}
+
+// listArchives lists the bundles of removed branches kept for a repository
+func (c *CLI) listArchives(args []string) error {
+	flags, _ := ParseFlags(args)
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	dir := c.paths.ArchivesDir(repoName)
+	archives, err := archive.List(dir)
+	if err != nil {
+		return err
+	}
+	if len(archives) == 0 {
+		fmt.Printf("No archived branches for %s\n", repoName)
+		return nil
+	}
+
+	format.Header("Archived branches for %s (%s):", repoName, dir)
+	table := format.NewTable("BUNDLE", "SIZE", "ARCHIVED")
+	for _, a := range archives {
+		table.AddRow(a.Name, fmt.Sprintf("%.1f MB", float64(a.Size)/(1024*1024)), format.TimeAgo(a.Created))
+	}
+	fmt.Print(table.String())
+	format.Dimmed("\nRestore one with: multiclaude work archives restore <bundle> [--as-branch <name>]")
+	return nil
+}
+
+// restoreArchive recreates the branch stored in a bundle, under its original
+// name or the one given with --as-branch
+func (c *CLI) restoreArchive(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude work archives restore <bundle> [--as-branch <name>] [--repo <repo>]")
+	}
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	path, err := archive.Resolve(c.paths.ArchivesDir(repoName), posArgs[0])
+	if err != nil {
+		return errors.ArchiveNotFound(posArgs[0])
+	}
+
+	wt := worktree.NewManager(c.paths.RepoDir(repoName))
+	heads, err := wt.BundleHeads(path)
+	if err != nil {
+		return errors.GitOperationFailed("bundle list-heads", err)
+	}
+	ref := ""
+	for _, head := range heads {
+		if strings.HasPrefix(head, "refs/heads/") {
+			ref = head
+			break
+		}
+	}
+	if ref == "" {
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("bundle %s contains no branch", filepath.Base(path)))
+	}
+
+	branch := strings.TrimPrefix(ref, "refs/heads/")
+	if value, ok := flags["as-branch"]; ok {
+		if value == "" || value == "true" {
+			return errors.MissingArgument("--as-branch", "branch name")
+		}
+		branch = value
+	}
+
+	if exists, err := wt.BranchExists(branch); err != nil {
+		return errors.GitOperationFailed("show-ref", err)
+	} else if exists {
+		return errors.New(errors.CategoryUsage, fmt.Sprintf("branch '%s' already exists", branch)).
+			WithSuggestion("restore it under another name with --as-branch <name>")
+	}
+
+	if err := wt.FetchBundle(path, ref, branch); err != nil {
+		return errors.GitOperationFailed("fetch from bundle", err)
+	}
+
+	fmt.Printf("✓ Restored branch %s from %s\n", branch, filepath.Base(path))
+	fmt.Printf("Start a worker from it with: multiclaude work \"<task>\" --branch %s\n", branch)
+	return nil
+}
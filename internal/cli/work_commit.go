@@ -0,0 +1,77 @@
+package cli
+
+import (
+	goerrors "errors"
+	"fmt"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// defaultCommitMessage is the message of commits made by work commit
+// without --message
+const defaultCommitMessage = "automated commit by multiclaude"
+
+// extractAddAllFlag removes --add-all, which takes no value, from args so
+// that ParseFlags does not take the worker name following it as its value
+func extractAddAllFlag(args []string) (bool, []string) {
+	addAll := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--add-all" || arg == "--add-all=true" {
+			addAll = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return addAll, rest
+}
+
+// commitWorker commits in a worker's worktree from outside its tmux window,
+// e.g. from a CI job. The worker's Claude process is paused meanwhile so it
+// does not run git at the same time, but it may be in the middle of an
+// edit, which the commit then captures half done.
+func (c *CLI) commitWorker(args []string) error {
+	addAll, args := extractAddAllFlag(args)
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude work commit <worker-name> [--message <msg>] [--add-all] [--repo <repo>]")
+	}
+	workerName := posArgs[0]
+
+	message, hasMessage := flags["message"]
+	if !hasMessage {
+		message = defaultCommitMessage
+	} else if message == "" || message == "true" {
+		return errors.InvalidUsage("--message needs a commit message")
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	workerInfo, err := c.findWorker(repoName, workerName)
+	if err != nil {
+		return err
+	}
+	wtPath, _ := workerInfo["worktree_path"].(string)
+
+	fmt.Printf("Warning: committing in %s's worktree while its agent may be editing it; the agent is paused meanwhile\n", workerName)
+	pid, _ := workerInfo["pid"].(float64)
+	resume := pauseAgent(int(pid))
+	commit, err := worktree.Commit(wtPath, message, addAll)
+	resume()
+	if goerrors.Is(err, worktree.ErrNothingToCommit) {
+		suggestion := "stage changes in the worktree first"
+		if !addAll {
+			suggestion = "pass --add-all to commit every change in the worktree"
+		}
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("nothing to commit in worker '%s'", workerName)).WithSuggestion(suggestion)
+	}
+	if err != nil {
+		return errors.GitOperationFailed("commit", err)
+	}
+
+	fmt.Printf("✓ Committed in worker '%s': %s\n", workerName, commit)
+	return nil
+}
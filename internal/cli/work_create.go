@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// extractLegacyLocalFlag removes --legacy-local, which takes no value, from
+// args so that ParseFlags does not take the task following it as its value
+func extractLegacyLocalFlag(args []string) (bool, []string) {
+	legacy := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--legacy-local" || arg == "--legacy-local=true" {
+			legacy = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return legacy, rest
+}
+
+// extractPathFlags removes the repeatable --path flag from args, returning
+// the directories in the order given, cleaned and relative to the
+// repository root
+func extractPathFlags(args []string) ([]string, []string, error) {
+	var paths []string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--path" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, errors.MissingArgument("--path", "directory")
+			}
+			value = args[i+1]
+			i++
+		}
+		path, err := cleanScopePath(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rest, nil
+}
+
+// cleanScopePath checks that a --path names a directory inside the
+// repository and returns it in the form git uses
+func cleanScopePath(value string) (string, error) {
+	path := filepath.ToSlash(filepath.Clean(value))
+	if value == "" || path == "." || filepath.IsAbs(value) || path == ".." || strings.HasPrefix(path, "../") {
+		return "", errors.InvalidUsage(fmt.Sprintf("invalid --path value: %q (must be a directory relative to the repository root)", value))
+	}
+	return path, nil
+}
+
+// requestWorker has the daemon create a worker with create_worker, which
+// creates one worker at a time per repository so concurrent callers cannot
+// race on git or tmux, and prints the daemon's progress. The daemon returns
+// its progress lines with the response, once the worker is created, so a
+// line is printed before the request to show that work is under way. The
+// CLI only composes the prompt, since the CLI reference in it comes from the
+// CLI.
+func (c *CLI) requestWorker(repoName string, spec workerSpec) (string, error) {
+	// Check a requested name here, where the error can say what is wrong
+	// with it; the daemon checks that it is free
+	if spec.Name != "" {
+		if err := validateAgentName(spec.Name); err != nil {
+			return "", err
+		}
+	}
+
+	prompt, err := c.workerPromptText(c.paths.RepoDir(repoName), WorkerConfig{PushToBranch: spec.PushTo, Paths: spec.Paths})
+	if err != nil {
+		return "", err
+	}
+
+	args := map[string]interface{}{
+		"repo":   repoName,
+		"task":   spec.Task,
+		"prompt": prompt,
+	}
+	if spec.Name != "" {
+		args["name"] = spec.Name
+	}
+	if spec.Branch != "" {
+		args["branch"] = spec.Branch
+	}
+	if spec.PushTo != "" {
+		args["push_to"] = spec.PushTo
+	}
+	if spec.OriginWorker != "" {
+		args["origin_worker"] = spec.OriginWorker
+		args["origin_task"] = spec.OriginTask
+	}
+	if len(spec.ContextFiles) > 0 {
+		files := make([]interface{}, len(spec.ContextFiles))
+		for i, f := range spec.ContextFiles {
+			files[i] = map[string]interface{}{"name": f.Name, "content": string(f.Data)}
+		}
+		args["context_files"] = files
+	}
+	if len(spec.Paths) > 0 {
+		paths := make([]interface{}, len(spec.Paths))
+		for i, path := range spec.Paths {
+			paths[i] = path
+		}
+		args["paths"] = paths
+	}
+	if spec.AllowDuplicate {
+		args["allow_duplicate"] = true
+	}
+	if spec.NoSubmodules {
+		args["no_submodules"] = true
+	}
+
+	// Cloning and setting up the worktree can take a while, and the daemon's
+	// progress only arrives at the end
+	fmt.Println("Creating worker (worktree, tmux window and Claude); this may take a moment...")
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "create_worker",
+		Args:    args,
+	})
+	if err != nil {
+		return "", errors.DaemonCommunicationFailed("creating worker", err)
+	}
+
+	data, _ := resp.Data.(map[string]interface{})
+	if lines, ok := data["progress"].([]interface{}); ok {
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+	}
+	if !resp.Success {
+		return "", createWorkerError(repoName, resp.Error, data)
+	}
+
+	workerName, _ := data["name"].(string)
+	branchName, _ := data["branch"].(string)
+	wtPath, _ := data["worktree_path"].(string)
+	tmuxSession, _ := data["tmux_session"].(string)
+
+	fmt.Println()
+	fmt.Println("✓ Worker created successfully!")
+	fmt.Printf("  Name: %s\n", workerName)
+	fmt.Printf("  Branch: %s\n", branchName)
+	fmt.Printf("  Worktree: %s\n", wtPath)
+	if len(spec.Paths) > 0 {
+		fmt.Printf("  Scope: %s (starts in %s)\n", strings.Join(spec.Paths, ", "), spec.Paths[0])
+	}
+	if spec.PushTo != "" {
+		fmt.Printf("  Mode: Push to existing PR branch (%s)\n", spec.PushTo)
+	}
+	fmt.Printf("\nAttach to worker: tmux select-window -t %s:%s\n", tmuxSession, workerName)
+	fmt.Printf("Or use: multiclaude attach %s\n", workerName)
+
+	return workerName, nil
+}
+
+// createWorkerError turns a failed create_worker response into the error
+// launchWorkerLocal would have returned
+func createWorkerError(repoName, message string, data map[string]interface{}) error {
+	if strings.HasPrefix(message, "unknown command") {
+		return errors.Wrap(errors.CategoryConnection, "the running daemon does not support creating workers", fmt.Errorf("%s", message)).
+			WithSuggestion("restart it with: " + restartDaemonCommand + "\nor create the worker from this process with --legacy-local")
+	}
+	if existing, _ := data["duplicate_of"].(string); existing != "" {
+		return errors.PossibleDuplicateWorker(existing, repoName)
+	}
+	if name, _ := data["agent_exists"].(string); name != "" {
+		return errors.AgentAlreadyExists(name, repoName)
+	}
+	if _, ok := data["invalid_path"]; ok {
+		return errors.InvalidUsage(message)
+	}
+	if _, ok := data["submodules"]; ok {
+		return errors.SubmoduleUpdateFailed(fmt.Errorf("%s", message))
+	}
+	count, _ := data["count"].(float64)
+	max, _ := data["max"].(float64)
+	switch data["limit"] {
+	case "workers":
+		return errors.WorkerLimitReached(repoName, int(count), int(max))
+	case "worktrees":
+		return errors.WorktreeLimitReached(repoName, int(count), int(max))
+	}
+	return errors.Wrap(errors.CategoryRuntime, "failed to create worker", fmt.Errorf("%s", message))
+}
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/claude"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// launchEphemeral starts a read-only agent in the repository's primary
+// checkout. Unlike launchWorker it creates no worktree or branch, so nothing
+// it creates lives inside the repository. Returns the agent's name.
+func (c *CLI) launchEphemeral(repoName, task, name string) (string, error) {
+	client := socket.NewClient(c.paths.DaemonSock)
+	existingAgents, err := c.existingAgentTypes(repoName)
+	if err != nil {
+		return "", err
+	}
+
+	// Ephemeral agents have their own limit and don't count as workers
+	if max, err := c.maxConcurrentEphemeral(repoName); err == nil && max > 0 {
+		if count := countAgentType(existingAgents, state.AgentTypeEphemeral); count >= max {
+			return "", errors.EphemeralLimitReached(repoName, count, max)
+		}
+	}
+
+	agentName, err := chooseAgentName(repoName, name, task, c.repoNaming(repoName), existingAgents)
+	if err != nil {
+		return "", err
+	}
+
+	// Undo everything created below if a later step fails
+	var created rollback
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			created.run()
+		}
+	}()
+
+	repoPath := c.paths.RepoDir(repoName)
+	if _, err := os.Stat(repoPath); err != nil {
+		return "", errors.Wrap(errors.CategoryNotFound, fmt.Sprintf("repository checkout not found at %s", repoPath), err)
+	}
+
+	fmt.Printf("Creating ephemeral agent '%s' in repo '%s'\n", agentName, repoName)
+	fmt.Printf("Task: %s\n", task)
+
+	tmuxSession := c.repoTmuxSession(repoName)
+	tmuxClient := tmux.NewClient()
+	if err := c.ensureTmuxSession(tmuxClient, tmuxSession, &created); err != nil {
+		return "", err
+	}
+
+	// The window runs in the primary checkout; there is no worktree
+	fmt.Printf("Creating tmux window: %s\n", agentName)
+	cmd := exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", agentName, "-c", repoPath)
+	if _, _, err := cmdrun.Run(cmd); err != nil {
+		return "", errors.TmuxOperationFailed("create window", err)
+	}
+	created.add("kill tmux window "+agentName, func() error {
+		return tmuxClient.KillWindow(context.Background(), tmuxSession, agentName)
+	})
+
+	sessionID, err := claude.GenerateSessionID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate ephemeral agent session ID: %w", err)
+	}
+
+	promptFile, err := c.writePromptFile(repoPath, prompts.TypeEphemeral, agentName)
+	if err != nil {
+		return "", fmt.Errorf("failed to write ephemeral agent prompt: %w", err)
+	}
+	created.add("remove prompt file "+promptFile, func() error { return os.Remove(promptFile) })
+
+	// Hooks config is not copied: it would be written into the primary checkout
+
+	// Start Claude in the window with the task (skip in test mode)
+	var pid int
+	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+		claudeBinary, err := c.getClaudeBinary()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve claude binary: %w", err)
+		}
+
+		fmt.Println("Starting Claude Code in ephemeral agent window...")
+		pid, err = c.startClaudeInTmux(claudeBinary, tmuxSession, agentName, repoPath, sessionID, promptFile, repoName, fmt.Sprintf("Task: %s", task))
+		if err != nil {
+			return "", fmt.Errorf("failed to start ephemeral agent Claude: %w", err)
+		}
+
+		if err := c.setupOutputCapture(tmuxSession, agentName, repoName, agentName, "ephemeral"); err != nil {
+			fmt.Printf("Warning: failed to setup output capture for ephemeral agent: %v\n", err)
+		}
+	}
+
+	resp, err := client.Send(socket.Request{
+		Command: "add_agent",
+		Args: map[string]interface{}{
+			"repo":          repoName,
+			"agent":         agentName,
+			"type":          string(state.AgentTypeEphemeral),
+			"worktree_path": repoPath,
+			"tmux_window":   agentName,
+			"task":          task,
+			"docs_hash":     promptDocsHash(promptFile),
+			"session_id":    sessionID,
+			"pid":           pid,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to register ephemeral agent: %w", err)
+	}
+	if !resp.Success {
+		return "", fmt.Errorf("failed to register ephemeral agent: %s", resp.Error)
+	}
+	succeeded = true
+
+	fmt.Println()
+	fmt.Println("✓ Ephemeral agent created successfully!")
+	fmt.Printf("  Name: %s\n", agentName)
+	fmt.Printf("  Directory: %s (read-only, no worktree or branch)\n", repoPath)
+	fmt.Printf("\nAttach to agent: tmux select-window -t %s:%s\n", tmuxSession, agentName)
+	fmt.Printf("Or use: multiclaude attach %s\n", agentName)
+
+	return agentName, nil
+}
+
+// removeEphemeral closes an ephemeral agent's window and unregisters it. Its
+// directory is the primary checkout, so nothing on disk is touched.
+func (c *CLI) removeEphemeral(repoName, agentName string, agentInfo map[string]interface{}) error {
+	tmuxSession := c.repoTmuxSession(repoName)
+	tmuxWindow, _ := agentInfo["tmux_window"].(string)
+	fmt.Printf("Killing tmux window: %s\n", tmuxWindow)
+	cmd := exec.Command("tmux", "kill-window", "-t", fmt.Sprintf("%s:%s", tmuxSession, agentWindowTarget(agentInfo)))
+	if _, _, err := cmdrun.Run(cmd); err != nil {
+		fmt.Printf("Warning: failed to kill tmux window: %v\n", err)
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "remove_agent",
+		Args: map[string]interface{}{
+			"repo":  repoName,
+			"agent": agentName,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unregister ephemeral agent: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to unregister ephemeral agent: %s", resp.Error)
+	}
+
+	fmt.Println("✓ Ephemeral agent removed successfully")
+	return nil
+}
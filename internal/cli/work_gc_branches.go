@@ -0,0 +1,263 @@
+package cli
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// gcBranchPrefix is the prefix of the worker branches gc-branches cleans up
+const gcBranchPrefix = "work/"
+
+// gcBranch is a worker branch selected for deletion, locally, on origin or both
+type gcBranch struct {
+	Name         string
+	Local        bool
+	Remote       bool
+	LocalMerged  bool // Delete the local branch with git branch -d
+	LocalReason  string
+	RemoteReason string
+}
+
+// gcWorkBranches deletes work/* branches, local and on origin, that were
+// merged into the default branch or have had no commits for a while.
+// Branches checked out in a worktree, i.e. those of running workers, are
+// never touched.
+func (c *CLI) gcWorkBranches(args []string) error {
+	assumeYes, args := extractYesFlag(args)
+	flags, _ := ParseFlags(args)
+	dryRun := flags["dry-run"] == "true"
+	merged := flags["merged"] == "true"
+
+	var staleAfter time.Duration
+	if value, ok := flags["stale-after"]; ok {
+		if value == "" || value == "true" {
+			return errors.MissingArgument("--stale-after", "duration")
+		}
+		d, err := parseDuration(value)
+		if err != nil || d <= 0 {
+			return errors.InvalidDuration(value)
+		}
+		staleAfter = d
+	}
+	if !merged && staleAfter == 0 {
+		return errors.InvalidUsage("usage: multiclaude work gc-branches [--repo <repo>] [--merged] [--stale-after <duration>] [--dry-run] [--yes] (pass --merged, --stale-after or both)")
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	repoPath := c.paths.RepoDir(repoName)
+	wt := worktree.NewManager(repoPath)
+
+	hasOrigin := exec.Command("git", "-C", repoPath, "remote", "get-url", "origin").Run() == nil
+	if hasOrigin {
+		fmt.Println("Fetching latest from origin...")
+		if err := wt.FetchRemote("origin"); err != nil {
+			fmt.Printf("Warning: failed to fetch from origin: %v (continuing with local refs)\n", err)
+		}
+	}
+
+	branches, err := findGCBranches(wt, hasOrigin, merged, staleAfter)
+	if err != nil {
+		return err
+	}
+
+	if len(branches) == 0 {
+		fmt.Printf("No %s* branches to clean up in %s\n", gcBranchPrefix, repoName)
+		return nil
+	}
+
+	if dryRun {
+		format.Header("Branches that would be deleted in %s:", repoName)
+	} else {
+		format.Header("Branches to delete in %s:", repoName)
+	}
+	for _, b := range branches {
+		if b.Local {
+			fmt.Printf("  %s (local, %s)\n", b.Name, b.LocalReason)
+		}
+		if b.Remote {
+			fmt.Printf("  origin/%s (remote, %s)\n", b.Name, b.RemoteReason)
+		}
+	}
+
+	if dryRun {
+		format.Dimmed("\nDry run: nothing was deleted. Run without --dry-run to delete them.")
+		return nil
+	}
+
+	ok, err := confirm(confirmation{
+		Consequence: "\nRemote branches are deleted from origin for everyone.",
+		Prompt:      "Delete these branches?",
+		AssumeYes:   assumeYes,
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted")
+		return nil
+	}
+
+	deleted, failed := 0, 0
+	for _, b := range branches {
+		if b.Local {
+			// Merged branches go through git's own check. Stale ones may
+			// hold work nothing else has, so they are bundled first, to be
+			// restored with work archives restore, and kept if that fails.
+			deleteLocal := wt.SafeDeleteBranch
+			if !b.LocalMerged {
+				path, err := c.archiveBranch(repoName, wt, b.Name, false)
+				if err != nil {
+					fmt.Printf("Warning: kept %s: %v\n", b.Name, err)
+					failed++
+					continue
+				}
+				fmt.Printf("Archived branch %s to %s\n", b.Name, path)
+				deleteLocal = wt.DeleteBranch
+			}
+			if err := deleteLocal(b.Name); err != nil {
+				fmt.Printf("Warning: failed to delete %s: %v\n", b.Name, err)
+				failed++
+			} else {
+				fmt.Printf("Deleted branch %s\n", b.Name)
+				deleted++
+			}
+		}
+		if b.Remote {
+			if err := wt.DeleteRemoteBranch("origin", b.Name); err != nil {
+				fmt.Printf("Warning: failed to delete origin/%s: %v\n", b.Name, err)
+				failed++
+			} else {
+				fmt.Printf("Deleted branch origin/%s\n", b.Name)
+				deleted++
+			}
+		}
+	}
+
+	fmt.Printf("\n✓ Deleted %d branch(es)", deleted)
+	if failed > 0 {
+		fmt.Printf(", %d failed", failed)
+	}
+	fmt.Println()
+	return nil
+}
+
+// findGCBranches selects the work/* branches to delete: with merged, those
+// merged into origin's default branch (the local default branch when there
+// is no origin), and with staleAfter, those whose last commit is older than
+// that. Branches checked out in a worktree are skipped, remote ones included.
+func findGCBranches(wt *worktree.Manager, hasOrigin, merged bool, staleAfter time.Duration) ([]*gcBranch, error) {
+	worktrees, err := wt.List()
+	if err != nil {
+		return nil, errors.GitOperationFailed("worktree list", err)
+	}
+	inUse := make(map[string]bool)
+	for _, w := range worktrees {
+		inUse[w.Branch] = true
+	}
+
+	byName := make(map[string]*gcBranch)
+	add := func(name string, remote bool, reason string) *gcBranch {
+		if inUse[name] {
+			return nil
+		}
+		b, ok := byName[name]
+		if !ok {
+			b = &gcBranch{Name: name}
+			byName[name] = b
+		}
+		if remote && !b.Remote {
+			b.Remote, b.RemoteReason = true, reason
+		} else if !remote && !b.Local {
+			b.Local, b.LocalReason = true, reason
+		}
+		return b
+	}
+
+	if merged {
+		base, err := gcBaseRef(wt, hasOrigin)
+		if err != nil {
+			return nil, err
+		}
+		local, err := wt.ListMergedBranches(base, gcBranchPrefix)
+		if err != nil {
+			return nil, errors.GitOperationFailed("list merged branches", err)
+		}
+		for _, name := range local {
+			if b := add(name, false, "merged into "+base); b != nil {
+				b.LocalMerged = true
+			}
+		}
+		if hasOrigin {
+			remote, err := wt.ListMergedRemoteBranches("origin", base, gcBranchPrefix)
+			if err != nil {
+				return nil, errors.GitOperationFailed("list merged remote branches", err)
+			}
+			for _, name := range remote {
+				add(name, true, "merged into "+base)
+			}
+		}
+	}
+
+	if staleAfter > 0 {
+		cutoff := time.Now().Add(-staleAfter)
+		local, err := wt.ListBranchesWithPrefix(gcBranchPrefix)
+		if err != nil {
+			return nil, errors.GitOperationFailed("list branches", err)
+		}
+		var remote []string
+		if hasOrigin {
+			if remote, err = wt.ListRemoteBranchesWithPrefix("origin", gcBranchPrefix); err != nil {
+				return nil, errors.GitOperationFailed("list remote branches", err)
+			}
+		}
+		for _, ref := range []struct {
+			names  []string
+			remote bool
+		}{{local, false}, {remote, true}} {
+			for _, name := range ref.names {
+				rev := name
+				if ref.remote {
+					rev = "origin/" + name
+				}
+				last, err := wt.LastCommitTime(rev)
+				if err != nil || !last.Before(cutoff) {
+					continue
+				}
+				add(name, ref.remote, "last commit "+format.TimeAgo(last))
+			}
+		}
+	}
+
+	branches := make([]*gcBranch, 0, len(byName))
+	for _, b := range byName {
+		branches = append(branches, b)
+	}
+	sort.Slice(branches, func(i, j int) bool { return branches[i].Name < branches[j].Name })
+	return branches, nil
+}
+
+// gcBaseRef returns the branch merged work is measured against: origin's
+// default branch, or the local main or master without an origin
+func gcBaseRef(wt *worktree.Manager, hasOrigin bool) (string, error) {
+	if hasOrigin {
+		if branch, err := wt.GetDefaultBranch("origin"); err == nil {
+			return "origin/" + branch, nil
+		}
+	}
+	for _, branch := range []string{"main", "master"} {
+		if exists, _ := wt.BranchExists(branch); exists {
+			return branch, nil
+		}
+	}
+	return "", errors.New(errors.CategoryNotFound, "could not determine the default branch to check merges against").
+		WithSuggestion("fetch origin, or use --stale-after instead of --merged")
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+)
+
+// workerSortFields are the fields work list --sort-by accepts, each with the
+// order it uses without --sort-order
+var workerSortFields = map[string]string{
+	"name":     "asc",
+	"created":  "asc",  // oldest first
+	"status":   "asc",  // running, stopped, completed, unknown
+	"task":     "asc",  // alphabetical
+	"commits":  "desc", // most commits ahead of main first
+	"messages": "desc", // most pending messages first
+}
+
+// workerStatusRank orders statuses for --sort-by status
+var workerStatusRank = map[string]int{
+	"running":   0,
+	"stopped":   1,
+	"completed": 2,
+}
+
+// workerSortOrder checks work list's --sort-by and --sort-order values and
+// returns the order to sort in, the field's default if order is ""
+func workerSortOrder(field, order string) (string, error) {
+	defaultOrder, ok := workerSortFields[field]
+	if !ok {
+		return "", errors.InvalidUsage(fmt.Sprintf("invalid --sort-by value: %q (must be one of: name, created, status, task, commits, messages)", field))
+	}
+	switch order {
+	case "":
+		return defaultOrder, nil
+	case "asc", "desc":
+		return order, nil
+	default:
+		return "", errors.InvalidUsage(fmt.Sprintf("invalid --sort-order value: %q (must be 'asc' or 'desc')", order))
+	}
+}
+
+// sortWorkers orders workers from a rich list_agents response by field, in
+// order ("asc" or "desc"). Ties are broken by name.
+func sortWorkers(workers []map[string]interface{}, field, order string) {
+	// compare returns <0, 0 or >0 as a sorts before b in ascending order
+	compare := func(a, b map[string]interface{}) int {
+		switch field {
+		case "name":
+			return strings.Compare(workerName(a), workerName(b))
+		case "created":
+			return workerCreatedAt(a).Compare(workerCreatedAt(b))
+		case "status":
+			return workerStatusOrder(a) - workerStatusOrder(b)
+		case "task":
+			taskA, _ := a["task"].(string)
+			taskB, _ := b["task"].(string)
+			return strings.Compare(strings.ToLower(taskA), strings.ToLower(taskB))
+		case "commits":
+			return workerCount(a, "commits_ahead") - workerCount(b, "commits_ahead")
+		case "messages":
+			return workerCount(a, "messages_pending") - workerCount(b, "messages_pending")
+		}
+		return 0
+	}
+
+	sort.SliceStable(workers, func(i, j int) bool {
+		c := compare(workers[i], workers[j])
+		if order == "desc" {
+			c = -c
+		}
+		if c == 0 {
+			c = strings.Compare(workerName(workers[i]), workerName(workers[j]))
+		}
+		return c < 0
+	})
+}
+
+func workerName(worker map[string]interface{}) string {
+	name, _ := worker["name"].(string)
+	return name
+}
+
+// workerCreatedAt parses a worker's created_at, which arrives as an RFC 3339
+// string
+func workerCreatedAt(worker map[string]interface{}) time.Time {
+	createdAt, _ := worker["created_at"].(string)
+	t, _ := time.Parse(time.RFC3339Nano, createdAt)
+	return t
+}
+
+// workerStatusOrder ranks a worker's status, unknown statuses last
+func workerStatusOrder(worker map[string]interface{}) int {
+	status, _ := worker["status"].(string)
+	if rank, ok := workerStatusRank[status]; ok {
+		return rank
+	}
+	return len(workerStatusRank)
+}
+
+// workerCount reads a numeric field, which arrives as a float64
+func workerCount(worker map[string]interface{}, key string) int {
+	n, _ := worker[key].(float64)
+	return int(n)
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/logfilter"
+)
+
+// defaultLogTailLines is how many lines of each worker's log work list
+// --with-log-tail shows when not given a number
+const defaultLogTailLines = 3
+
+// logTailBytes is how much of the end of a log is read to find its last
+// lines; a raw log's redraws can take many bytes per line kept
+const logTailBytes = 64 * 1024
+
+// logTailCount parses the value of --with-log-tail, which may be given
+// without a number
+func logTailCount(value string) (int, error) {
+	if value == "" || value == "true" {
+		return defaultLogTailLines, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, errors.InvalidArgument("--with-log-tail", value, "a number of lines of at least 1")
+	}
+	return n, nil
+}
+
+// printLogTails prints the last n lines of each worker's log under its
+// name, colored as in the table above. Workers without a log, or with
+// nothing in it yet, are left out.
+func (c *CLI) printLogTails(repoName string, workers []map[string]interface{}, nameCells map[string]format.ColoredCell, n int) {
+	width := format.TerminalWidth() - 2
+	printed := false
+	for _, worker := range workers {
+		name, _ := worker["name"].(string)
+		lines, err := readLogTail(c.paths.AgentLogFile(repoName, name, true), n)
+		if err != nil || len(lines) == 0 {
+			continue
+		}
+
+		if !printed {
+			fmt.Println()
+			format.Header("Recent output:")
+			printed = true
+		}
+		header := name
+		if cell := nameCells[name]; cell.Color != nil {
+			header = cell.Color.Sprint(name)
+		}
+		fmt.Println()
+		fmt.Println(header)
+		for _, line := range lines {
+			fmt.Printf("  %s\n", truncateRunes(line, width))
+		}
+	}
+}
+
+// readLogTail returns the last n lines of a log, without escape codes or
+// redraws: the end of the log is read through the same filter as output
+// capture
+func readLogTail(path string, n int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := max(info.Size()-logTailBytes, 0)
+	data := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(data, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if offset > 0 {
+		// Drop the line the read started in the middle of
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+
+	var filtered bytes.Buffer
+	filter := logfilter.New(&filtered)
+	filter.Write(data)
+	if err := filter.Flush(); err != nil {
+		return nil, err
+	}
+	text := strings.TrimSuffix(filtered.String(), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// truncateRunes shortens s to at most width characters, ending it with
+// "..." when cut
+func truncateRunes(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	if width <= 3 {
+		return string(runes[:max(width, 0)])
+	}
+	return string(runes[:width-3]) + "..."
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+)
+
+// AgentInfo is a worker as seen by a work list --output-template template
+type AgentInfo struct {
+	Name            string
+	Repo            string
+	Type            string // worker or ephemeral
+	Status          string // running, stopped or completed
+	CustomStatus    string // set by hand with agent set-status, if any
+	Branch          string
+	Task            string
+	MessagesPending int
+	MessagesTotal   int
+	CommitsAhead    int
+}
+
+// workerTemplateFuncs are the functions available to --output-template.
+// truncate takes the length first so it can end a pipeline:
+// {{.Task | truncate 40}}.
+var workerTemplateFuncs = template.FuncMap{
+	"truncate": func(n int, s string) string { return format.Truncate(s, n) },
+}
+
+// newAgentInfo converts a worker from a rich list_agents response
+func newAgentInfo(repoName string, worker map[string]interface{}) AgentInfo {
+	info := AgentInfo{
+		Name:            workerName(worker),
+		Repo:            repoName,
+		MessagesPending: workerCount(worker, "messages_pending"),
+		MessagesTotal:   workerCount(worker, "messages_total"),
+		CommitsAhead:    workerCount(worker, "commits_ahead"),
+	}
+	info.Type, _ = worker["type"].(string)
+	info.Status, _ = worker["status"].(string)
+	info.CustomStatus, _ = worker["custom_status"].(string)
+	info.Branch, _ = worker["branch"].(string)
+	info.Task, _ = worker["task"].(string)
+	return info
+}
+
+// parseWorkerTemplate parses an --output-template value, reading it from a
+// file when it starts with @
+func parseWorkerTemplate(text string) (*template.Template, error) {
+	if path, ok := strings.CutPrefix(text, "@"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.InvalidUsage(fmt.Sprintf("failed to read --output-template file: %v", err))
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("work-list").Funcs(workerTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, errors.InvalidUsage(fmt.Sprintf("invalid --output-template: %v", err))
+	}
+	return tmpl, nil
+}
+
+// printWorkerTemplate executes tmpl once with the sorted workers of the
+// given repositories
+func (c *CLI) printWorkerTemplate(tmpl *template.Template, repos []string, sortBy, sortOrder string) error {
+	var workers []map[string]interface{}
+	for _, repoName := range repos {
+		repoWorkers, _, err := c.fetchWorkers(repoName)
+		if err != nil {
+			return err
+		}
+		for _, worker := range repoWorkers {
+			worker["repo"] = repoName
+		}
+		workers = append(workers, repoWorkers...)
+	}
+	sortWorkers(workers, sortBy, sortOrder)
+
+	infos := make([]AgentInfo, len(workers))
+	for i, worker := range workers {
+		repoName, _ := worker["repo"].(string)
+		infos[i] = newAgentInfo(repoName, worker)
+	}
+	if err := tmpl.Execute(os.Stdout, infos); err != nil {
+		return errors.InvalidUsage(fmt.Sprintf("invalid --output-template: %v", err))
+	}
+	return nil
+}
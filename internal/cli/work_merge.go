@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// extractSquashFlag removes --squash, which takes no value, from args so
+// that ParseFlags does not take the worker name following it as its value
+func extractSquashFlag(args []string) (bool, []string) {
+	squash := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--squash" || arg == "--squash=true" {
+			squash = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return squash, rest
+}
+
+// findWorker returns the daemon's record for a named worker
+func (c *CLI) findWorker(repoName, workerName string) (map[string]interface{}, error) {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return nil, errors.DaemonCommunicationFailed("getting worker info", err)
+	}
+	if !resp.Success {
+		return nil, errors.Wrap(errors.CategoryRuntime, "failed to get worker info", fmt.Errorf("%s", resp.Error))
+	}
+
+	agents, _ := resp.Data.([]interface{})
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			agentType, _ := agentMap["type"].(string)
+			name, _ := agentMap["name"].(string)
+			if agentType == string(state.AgentTypeWorker) && name == workerName {
+				return agentMap, nil
+			}
+		}
+	}
+	return nil, errors.AgentNotFound("worker", workerName, repoName)
+}
+
+// mergeWorkerIntoWorkspace merges a worker's branch into a workspace's, for
+// trying the worker's changes together with others before they reach main.
+// Only the workspace's branch changes; the worker carries on as it was.
+func (c *CLI) mergeWorkerIntoWorkspace(args []string) error {
+	squash, args := extractSquashFlag(args)
+	flags, posArgs := ParseFlags(args)
+
+	if len(posArgs) != 2 {
+		return errors.InvalidUsage("usage: multiclaude work merge-into-workspace <worker-name> <workspace-name> [--strategy ours|theirs|recursive] [--squash] [--repo <repo>]")
+	}
+	workerName, workspaceName := posArgs[0], posArgs[1]
+
+	strategy, hasStrategy := flags["strategy"]
+	if hasStrategy {
+		switch strategy {
+		case worktree.MergeStrategyOurs, worktree.MergeStrategyTheirs, worktree.MergeStrategyRecursive:
+		default:
+			return errors.InvalidUsage(fmt.Sprintf("invalid --strategy value: %q (must be ours, theirs or recursive)", strategy))
+		}
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	workerInfo, err := c.findWorker(repoName, workerName)
+	if err != nil {
+		return err
+	}
+	workspaceInfo, err := c.findWorkspace(repoName, workspaceName)
+	if err != nil {
+		return err
+	}
+	workerPath, _ := workerInfo["worktree_path"].(string)
+	wtPath, _ := workspaceInfo["worktree_path"].(string)
+
+	workerBranch, err := worktree.GetCurrentBranch(workerPath)
+	if err != nil {
+		return errors.GitOperationFailed("get worker branch", err)
+	}
+	workspaceBranch, err := worktree.GetCurrentBranch(wtPath)
+	if err != nil {
+		return errors.GitOperationFailed("get workspace branch", err)
+	}
+
+	hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
+	if err != nil {
+		return errors.GitOperationFailed("check for uncommitted changes", err)
+	}
+	if hasUncommitted {
+		return errors.WorkspaceHasUncommittedChanges(workspaceName)
+	}
+	if dirty, err := worktree.HasUncommittedChanges(workerPath); err == nil && dirty {
+		fmt.Printf("Warning: worker '%s' has uncommitted changes; only its commits are merged\n", workerName)
+	}
+
+	fmt.Printf("Merging %s into %s (workspace '%s')...\n", workerBranch, workspaceBranch, workspaceName)
+	result, err := worktree.MergeBranch(wtPath, workerBranch, worktree.MergeOptions{
+		Strategy: strategy,
+		Squash:   squash,
+		Message:  fmt.Sprintf("Merge worker %s (%s) into workspace %s", workerName, workerBranch, workspaceName),
+	})
+	if err != nil {
+		if result != nil && len(result.Conflicts) > 0 {
+			fmt.Println("\nConflicting files:")
+			for _, file := range result.Conflicts {
+				fmt.Printf("  %s\n", file)
+			}
+			if result.Output != "" {
+				fmt.Printf("\ngit merge output:\n%s\n", result.Output)
+			}
+			return errors.MergeConflict(workerBranch, workspaceName, result.Conflicts, err)
+		}
+		return errors.GitOperationFailed("merge", err)
+	}
+	if result.UpToDate {
+		fmt.Printf("Workspace '%s' already has every commit of %s\n", workspaceName, workerBranch)
+		return nil
+	}
+	fmt.Printf("✓ Merged %s into workspace '%s' (now at %s)\n", workerBranch, workspaceName, shortCommit(result.Commit))
+
+	// Tell the workspace's agent its branch moved. The message comes from
+	// the agent running the command, e.g. the worker, or from the user.
+	from := "user"
+	if ctxRepo, ctxAgent, err := c.inferAgentContext(); err == nil && ctxRepo == repoName {
+		from = ctxAgent
+	}
+	how := "merged"
+	if squash {
+		how = "squash-merged"
+	}
+	body := fmt.Sprintf("Worker %s's branch %s was %s into your branch %s (now at %s). Its changes are in your working tree; "+
+		"the worker is unchanged and may still add commits.", workerName, workerBranch, how, workspaceBranch, shortCommit(result.Commit))
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	msg, err := msgMgr.Send(repoName, from, workspaceName, body)
+	if err != nil {
+		fmt.Printf("Warning: failed to notify workspace '%s': %v\n", workspaceName, err)
+		return nil
+	}
+
+	// Trigger immediate routing (best-effort, polling is fallback)
+	client := socket.NewClient(c.paths.DaemonSock)
+	_, _ = client.Send(socket.Request{Command: "route_messages"})
+
+	fmt.Printf("Notified workspace '%s' (message ID: %s)\n", workspaceName, msg.ID)
+	return nil
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// setWorkerTask replaces a running worker's task, for workers that pivot
+// once they find the problem is different than expected. With --notify the
+// worker is also sent a message with the new task.
+func (c *CLI) setWorkerTask(args []string) error {
+	// --notify takes no value; remove it before ParseFlags, which would
+	// otherwise take the task following it as the flag's value
+	notify := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--notify" || arg == "--notify=true" {
+			notify = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	flags, posArgs := ParseFlags(rest)
+
+	if len(posArgs) < 2 {
+		return errors.InvalidUsage("usage: multiclaude work set-task <worker-name> \"<new task>\" [--repo <repo>] [--notify]")
+	}
+	workerName := posArgs[0]
+	task := strings.TrimSpace(strings.Join(posArgs[1:], " "))
+	if task == "" {
+		return errors.InvalidUsage("the new task must not be empty")
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "update_agent_task",
+		Args: map[string]interface{}{
+			"repo":  repoName,
+			"agent": workerName,
+			"task":  task,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("updating the task", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to update the task", fmt.Errorf("%s", resp.Error))
+	}
+
+	oldTask := ""
+	if data, ok := resp.Data.(map[string]interface{}); ok {
+		oldTask, _ = data["old_task"].(string)
+	}
+	fmt.Printf("Updated task of %s\n", workerName)
+	if oldTask != "" {
+		fmt.Printf("  was: %s\n", oldTask)
+	}
+	fmt.Printf("  now: %s\n", task)
+
+	if !notify {
+		return nil
+	}
+
+	// The message comes from the agent running the command, e.g. the
+	// supervisor, or from the user outside any agent
+	from := "user"
+	if ctxRepo, ctxAgent, err := c.inferAgentContext(); err == nil && ctxRepo == repoName {
+		from = ctxAgent
+	}
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	msg, err := msgMgr.Send(repoName, from, workerName, "Your task has been updated: "+task)
+	if err != nil {
+		return fmt.Errorf("task updated, but failed to notify %s: %w", workerName, err)
+	}
+
+	// Trigger immediate routing (best-effort, polling is fallback)
+	_, _ = client.Send(socket.Request{Command: "route_messages"})
+
+	fmt.Printf("Notified %s (message ID: %s)\n", workerName, msg.ID)
+	return nil
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// splitWorker forks a worker into two new workers that both start from the
+// source worker's current HEAD, each with its own task
+func (c *CLI) splitWorker(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	usage := "usage: multiclaude work split <worker-name> --into <task-a> --and <task-b> [--repo <repo>] [--remove-original]"
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage(usage)
+	}
+	sourceName := posArgs[0]
+	taskA, taskB := flags["into"], flags["and"]
+	if taskA == "" || taskA == "true" || taskB == "" || taskB == "true" {
+		return errors.InvalidUsage(usage)
+	}
+	removeOriginal := flags["remove-original"] == "true"
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	// Removing the original kills its tmux window, which would take this
+	// command down with it if it is running there
+	if removeOriginal {
+		if ctxRepo, ctxAgent, err := c.inferAgentContext(); err == nil && ctxRepo == repoName && ctxAgent == sourceName {
+			return errors.InvalidUsage("--remove-original cannot be used from inside the worker being split; run 'multiclaude agent complete' there once the split workers are running")
+		}
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("getting worker info", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to get worker info", fmt.Errorf("%s", resp.Error))
+	}
+
+	var source map[string]interface{}
+	workerCount := 0
+	agents, _ := resp.Data.([]interface{})
+	for _, agent := range agents {
+		agentMap, ok := agent.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if agentType, _ := agentMap["type"].(string); agentType != string(state.AgentTypeWorker) {
+			continue
+		}
+		workerCount++
+		if name, _ := agentMap["name"].(string); name == sourceName {
+			source = agentMap
+		}
+	}
+	if source == nil {
+		return errors.AgentNotFound("worker", sourceName, repoName)
+	}
+
+	// Both split workers run alongside the original until it is removed
+	if max, err := c.maxConcurrentWorkers(repoName); err == nil && max > 0 && workerCount+2 > max {
+		return errors.WorkerLimitReached(repoName, workerCount, max)
+	}
+
+	sourcePath, _ := source["worktree_path"].(string)
+	sourceTask, _ := source["task"].(string)
+
+	output, err := cmdrun.Output(exec.Command("git", "-C", sourcePath, "rev-parse", "HEAD"))
+	if err != nil {
+		return errors.GitOperationFailed("get HEAD of worker "+sourceName, err)
+	}
+	head := strings.TrimSpace(output)
+
+	if hasUncommitted, err := worktree.HasUncommittedChanges(sourcePath); err == nil && hasUncommitted {
+		fmt.Printf("Warning: worker '%s' has uncommitted changes; they will not be carried into the split workers\n", sourceName)
+	}
+
+	fmt.Printf("Splitting worker '%s' at %s\n\n", sourceName, head[:min(len(head), 12)])
+
+	var splitNames []string
+	for _, task := range []string{taskA, taskB} {
+		name, err := c.launchWorker(repoName, workerSpec{
+			Task:         task,
+			Branch:       head,
+			OriginWorker: sourceName,
+			OriginTask:   sourceTask,
+		})
+		if err != nil {
+			if len(splitNames) > 0 {
+				return fmt.Errorf("created worker '%s' but failed to create the second split worker: %w", splitNames[0], err)
+			}
+			return err
+		}
+		splitNames = append(splitNames, name)
+		fmt.Println()
+	}
+
+	fmt.Printf("✓ Split worker '%s' into '%s' and '%s'\n", sourceName, splitNames[0], splitNames[1])
+
+	if removeOriginal {
+		fmt.Println()
+		if err := c.removeWorker([]string{sourceName, "--repo", repoName}); err != nil {
+			return fmt.Errorf("split workers were created but removing '%s' failed: %w", sourceName, err)
+		}
+	}
+
+	return nil
+}
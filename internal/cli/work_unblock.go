@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// unblockCaptureLines is how much of a worker's pane work unblock reads
+const unblockCaptureLines = 40
+
+// unblockPromptLines is how many of the pane's last non-empty lines can hold
+// a prompt the worker is waiting at; an older one was already answered
+const unblockPromptLines = 5
+
+// blockingCondition is a common way for a worker to get stuck, with the
+// keys that usually free it
+type blockingCondition struct {
+	Name   string // pager, conflict or permission
+	Reason string // what was seen in the pane
+	Action string // what sending Keys does, for the user
+	Keys   string
+	Enter  bool   // send Enter after Keys
+	Match  string // the pane line that matched
+}
+
+// detectBlockingConditions looks for the conditions work unblock fixes in a
+// captured pane, most pressing first: a pager must be closed before anything
+// behind it can be seen, and a prompt is answered before a conflict is
+// aborted. Pagers and prompts count only in the last few lines; conflict
+// markers anywhere in the capture.
+func detectBlockingConditions(pane string) []blockingCondition {
+	var lines []string
+	for _, line := range strings.Split(pane, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, strings.TrimRight(line, " "))
+		}
+	}
+	bottom := lines
+	if len(bottom) > unblockPromptLines {
+		bottom = bottom[len(bottom)-unblockPromptLines:]
+	}
+
+	var found []blockingCondition
+	for _, line := range bottom {
+		lower := strings.ToLower(line)
+		if strings.Contains(lower, "q to quit") || strings.TrimSpace(line) == "(END)" {
+			found = append(found, blockingCondition{
+				Name: "pager", Reason: "a pager is open", Action: "close the pager",
+				Keys: "q", Match: line,
+			})
+			break
+		}
+	}
+	for i := len(bottom) - 1; i >= 0; i-- {
+		if strings.Contains(strings.ToLower(bottom[i]), "[y/n]") {
+			found = append(found, blockingCondition{
+				Name: "permission", Reason: "a prompt is waiting for yes or no", Action: "answer yes",
+				Keys: "y", Enter: true, Match: bottom[i],
+			})
+			break
+		}
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "<<<<<<<") {
+			found = append(found, blockingCondition{
+				Name: "conflict", Reason: "conflict markers are showing", Action: "abort the rebase",
+				Keys: "git rebase --abort", Enter: true, Match: line,
+			})
+			break
+		}
+	}
+	return found
+}
+
+// extractUnblockFlags removes --force and --auto-approve, which take no
+// value, from args so that ParseFlags does not take the worker name as one
+func extractUnblockFlags(args []string) (force, autoApprove bool, rest []string) {
+	for _, arg := range args {
+		switch arg {
+		case "--force", "--force=true":
+			force = true
+		case "--auto-approve", "--auto-approve=true":
+			autoApprove = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return force, autoApprove, rest
+}
+
+// unblockWorker looks at a worker's pane for a common reason it is stuck
+// and sends the keys that usually fix it
+func (c *CLI) unblockWorker(args []string) error {
+	force, autoApprove, args := extractUnblockFlags(args)
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude work unblock <worker-name> [--repo <repo>] [--force] [--auto-approve]")
+	}
+	workerName := posArgs[0]
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	workerInfo, err := c.findWorker(repoName, workerName)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	tmuxClient := tmux.NewClient()
+	tmuxSession := c.repoTmuxSession(repoName)
+	tmuxWindow := agentWindowTarget(workerInfo)
+	pane, err := tmuxClient.CapturePane(ctx, tmuxSession, tmuxWindow, unblockCaptureLines)
+	if err != nil {
+		return errors.TmuxOperationFailed("capture pane", err)
+	}
+
+	found := detectBlockingConditions(pane)
+	if len(found) == 0 {
+		fmt.Printf("No blocking condition detected in worker '%s'\n", workerName)
+		fmt.Println("Look at its window with: multiclaude attach " + workerName)
+		return nil
+	}
+	condition := found[0]
+	fmt.Printf("Worker '%s': %s\n", workerName, condition.Reason)
+	fmt.Printf("  %s\n", format.Dim.Sprint(strings.TrimSpace(condition.Match)))
+
+	// Several conditions at once, or conflict markers outside a rebase,
+	// may not mean what the heuristics think
+	var doubts []string
+	if len(found) > 1 {
+		var others []string
+		for _, other := range found[1:] {
+			others = append(others, other.Reason)
+		}
+		doubts = append(doubts, "also detected: "+strings.Join(others, "; "))
+	}
+	if condition.Name == "conflict" && !strings.Contains(strings.ToLower(pane), "rebase") {
+		doubts = append(doubts, "the pane does not mention a rebase")
+	}
+
+	prompt := fmt.Sprintf("Send %q to worker '%s' to %s?", condition.Keys, workerName, condition.Action)
+	ask := len(doubts) > 0 && !force
+	if condition.Name == "permission" {
+		switch {
+		case autoApprove:
+			fmt.Println(format.Yellow.Sprint("Warning: --auto-approve answers yes without anyone reviewing what the worker asked to do"))
+		case force:
+			return errors.InvalidUsage("--force does not answer permission prompts").
+				WithSuggestion("review the prompt with 'multiclaude attach " + workerName + "', or pass --auto-approve to answer yes anyway")
+		default:
+			ask = true
+		}
+	}
+	if ask {
+		for _, doubt := range doubts {
+			fmt.Printf("Note: %s\n", doubt)
+		}
+		ok, err := confirm(confirmation{Prompt: prompt})
+		if err != nil {
+			if cliErr, isCLI := err.(*errors.CLIError); isCLI {
+				return cliErr.WithSuggestion("re-run with --force to act when in doubt, or --auto-approve to answer a permission prompt")
+			}
+			return err
+		}
+		if !ok {
+			fmt.Println("Cancelled")
+			return nil
+		}
+	}
+
+	err = tmuxClient.SendKeysLiteral(ctx, tmuxSession, tmuxWindow, condition.Keys)
+	if err == nil && condition.Enter {
+		err = tmuxClient.SendEnter(ctx, tmuxSession, tmuxWindow)
+	}
+	c.auditLocal("work_unblock", map[string]interface{}{
+		"repo":      repoName,
+		"worker":    workerName,
+		"condition": condition.Name,
+		"keys":      condition.Keys,
+	}, err)
+	if err != nil {
+		return errors.TmuxOperationFailed("send keys", err)
+	}
+	fmt.Printf("✓ Sent %q to worker '%s' to %s (detected: %s)\n", condition.Keys, workerName, condition.Action, condition.Name)
+	return nil
+}
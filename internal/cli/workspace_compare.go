@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// compareOutputFormats maps each --output-format to the git diff flag that
+// produces it
+var compareOutputFormats = map[string]string{
+	"unified":   "",
+	"stat":      "--stat",
+	"name-only": "--name-only",
+}
+
+// compareWorkspaces diffs the branches of two workspaces in the repository's
+// main checkout. It only reads git, so it works whatever state the
+// workspaces' agents are in, or after they were removed.
+func (c *CLI) compareWorkspaces(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	// ParseFlags takes a name following --stat as the flag's value
+	if stat, ok := flags["stat"]; ok && stat != "true" {
+		posArgs = append([]string{stat}, posArgs...)
+		flags["stat"] = "true"
+	}
+
+	usage := "usage: multiclaude workspace compare <workspace-a> <workspace-b> [--repo <repo>] [--base main] [--stat] [--output-format unified|stat|name-only]"
+	if len(posArgs) != 2 {
+		return errors.InvalidUsage(usage)
+	}
+	nameA, nameB := posArgs[0], posArgs[1]
+
+	outputFormat := flags["output-format"]
+	if flags["stat"] == "true" {
+		if outputFormat != "" && outputFormat != "stat" {
+			return errors.InvalidUsage(fmt.Sprintf("--stat conflicts with --output-format %s", outputFormat))
+		}
+		outputFormat = "stat"
+	}
+	if outputFormat == "" {
+		outputFormat = "unified"
+	}
+	formatFlag, ok := compareOutputFormats[outputFormat]
+	if !ok {
+		return errors.InvalidUsage(fmt.Sprintf("--output-format must be unified, stat or name-only, got %q", outputFormat))
+	}
+
+	base, hasBase := flags["base"]
+	if hasBase && (base == "" || base == "true") {
+		return errors.MissingArgument("--base", "branch")
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	repoPath := c.paths.RepoDir(repoName)
+
+	wt := worktree.NewManager(repoPath)
+	for _, name := range []string{nameA, nameB} {
+		exists, err := wt.BranchExists("workspace/" + name)
+		if err != nil {
+			return errors.GitOperationFailed("look up workspace branch", err)
+		}
+		if !exists {
+			return errors.WorkspaceBranchNotFound(name, repoName)
+		}
+	}
+	branchA, branchB := "workspace/"+nameA, "workspace/"+nameB
+
+	if !hasBase {
+		return runWorkspaceDiff(repoPath, formatFlag, branchA, branchB)
+	}
+
+	// Three-way: show what each workspace changed since the point where both
+	// of them and the base last agreed
+	baseRef := "origin/" + base
+	if err := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "--quiet", baseRef).Run(); err != nil {
+		baseRef = base
+	}
+	out, err := cmdrun.Output(exec.Command("git", "-C", repoPath, "merge-base", "--octopus", baseRef, branchA, branchB))
+	if err != nil {
+		return errors.GitOperationFailed(fmt.Sprintf("merge-base of %s, %s and %s", baseRef, branchA, branchB), err)
+	}
+	ancestor := strings.TrimSpace(out)
+
+	for i, branch := range []string{branchA, branchB} {
+		if i > 0 {
+			fmt.Println()
+		}
+		format.Header("%s since %s (common ancestor with %s)", branch, shortCommit(ancestor), baseRef)
+		if err := runWorkspaceDiff(repoPath, formatFlag, ancestor, branch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runWorkspaceDiff prints git diff from one revision to another
+func runWorkspaceDiff(repoPath, formatFlag, from, to string) error {
+	gitArgs := []string{"-C", repoPath, "--no-pager", "diff"}
+	if formatFlag != "" {
+		gitArgs = append(gitArgs, formatFlag)
+	}
+	gitArgs = append(gitArgs, from, to, "--")
+
+	cmd := exec.Command("git", gitArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if _, _, err := cmdrun.Run(cmd); err != nil {
+		return errors.GitOperationFailed("diff", err)
+	}
+	return nil
+}
+
+// shortCommit abbreviates a commit hash for display
+func shortCommit(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// setContext sets a repository context variable, listed in the "Current
+// Context" section of prompt files written from then on. An empty value
+// removes it.
+func (c *CLI) setContext(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 2 {
+		return errors.InvalidUsage("usage: multiclaude workspace set-context <key> <value> [--repo <repo>] (an empty value \"\" removes the key)")
+	}
+	key := posArgs[0]
+	value := strings.Join(posArgs[1:], " ")
+	if err := state.ValidateContextKey(key); err != nil {
+		return errors.InvalidUsage(err.Error())
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "set_context_var",
+		Args: map[string]interface{}{
+			"repo":  repoName,
+			"key":   key,
+			"value": value,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("setting context", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to set context", fmt.Errorf("%s", resp.Error))
+	}
+
+	if value == "" {
+		fmt.Printf("✓ Removed context %s\n", key)
+	} else {
+		fmt.Printf("✓ Set context %s\n", key)
+	}
+	format.Dimmed("Prompt files written from now on include it; running agents are not told.")
+	return nil
+}
+
+// getContext prints the value of a repository context variable, or all of
+// them without a key
+func (c *CLI) getContext(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "get_context_vars",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("getting context", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to get context", fmt.Errorf("%s", resp.Error))
+	}
+	vars, _ := resp.Data.(map[string]interface{})
+
+	if len(posArgs) > 0 {
+		key := posArgs[0]
+		value, ok := vars[key].(string)
+		if !ok {
+			return errors.New(errors.CategoryNotFound, fmt.Sprintf("repo '%s' has no context '%s'", repoName, key)).
+				WithSuggestion("multiclaude workspace get-context")
+		}
+		fmt.Println(value)
+		return nil
+	}
+
+	if len(vars) == 0 {
+		fmt.Printf("No context set for repo %s\n", repoName)
+		format.Dimmed("Set some with: multiclaude workspace set-context <key> <value>")
+		return nil
+	}
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	table := format.NewTable("KEY", "VALUE")
+	for _, key := range keys {
+		value, _ := vars[key].(string)
+		table.AddRow(key, value)
+	}
+	fmt.Print(table.String())
+	return nil
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// maxConcurrentFetches bounds how many repositories workspace fetch --all
+// fetches at once
+const maxConcurrentFetches = 3
+
+// workspaceWorktree is a workspace and the worktree it works in
+type workspaceWorktree struct {
+	Name string
+	Path string
+}
+
+// repoFetch is the outcome of fetching one repository for its workspaces
+type repoFetch struct {
+	Repo       string
+	Workspaces []workspaceWorktree
+	Result     *worktree.FetchResult
+	Err        error
+}
+
+// fetchWorkspaces fetches origin for one workspace, every workspace of a
+// repository, or with --all those of every tracked repository, and
+// reports the commits that arrived for each
+func (c *CLI) fetchWorkspaces(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	prune := flags["prune"] == "true"
+
+	// ParseFlags takes a name following --all as the flag's value
+	all, isAll := flags["all"]
+	if isAll && all != "true" {
+		posArgs = append([]string{all}, posArgs...)
+	}
+	if isAll && len(posArgs) > 0 {
+		return errors.InvalidUsage("workspace fetch takes either a workspace name or --all, not both")
+	}
+
+	var fetches []*repoFetch
+	if len(posArgs) > 0 {
+		repoName, err := c.resolveRepo(flags)
+		if err != nil {
+			return errors.NotInRepo()
+		}
+		info, err := c.findWorkspace(repoName, posArgs[0])
+		if err != nil {
+			return err
+		}
+		path, _ := info["worktree_path"].(string)
+		fetches = append(fetches, &repoFetch{Repo: repoName, Workspaces: []workspaceWorktree{{Name: posArgs[0], Path: path}}})
+	} else {
+		var repos []string
+		if isAll {
+			repos = c.getReposList()
+		} else {
+			repoName, err := c.resolveRepo(flags)
+			if err != nil {
+				return errors.NotInRepo()
+			}
+			repos = []string{repoName}
+		}
+		for _, repoName := range repos {
+			workspaces, err := c.workspaceWorktrees(repoName)
+			if err != nil {
+				if !isAll {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Warning: failed to list workspaces for %s: %v\n", repoName, err)
+				continue
+			}
+			if len(workspaces) > 0 {
+				fetches = append(fetches, &repoFetch{Repo: repoName, Workspaces: workspaces})
+			}
+		}
+	}
+	if len(fetches) == 0 {
+		fmt.Println("No workspaces to fetch")
+		return nil
+	}
+
+	// Worktrees share their repository's refs, so each repository is
+	// fetched once: in the workspace's worktree when one was named, else
+	// in the primary clone
+	sem := make(chan struct{}, maxConcurrentFetches)
+	var wg sync.WaitGroup
+	for _, f := range fetches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			dir := c.paths.RepoDir(f.Repo)
+			if len(posArgs) > 0 {
+				dir = f.Workspaces[0].Path
+			}
+			paths := make([]string, len(f.Workspaces))
+			for i, ws := range f.Workspaces {
+				paths[i] = ws.Path
+			}
+			f.Result, f.Err = worktree.NewManager(dir).Fetch("origin", prune, paths)
+		}()
+	}
+	wg.Wait()
+
+	failed, workspaces, newCommits, pruned := 0, 0, 0, 0
+	for i, f := range fetches {
+		if len(fetches) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", f.Repo)
+		}
+		if f.Err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to fetch %s: %v\n", f.Repo, f.Err)
+			failed++
+			continue
+		}
+		for _, ws := range f.Workspaces {
+			n, ok := f.Result.NewCommits[ws.Path]
+			switch {
+			case !ok:
+				fmt.Printf("  workspace/%s: followed branch no longer exists on origin\n", ws.Name)
+			case n == 0:
+				fmt.Printf("  workspace/%s: up to date\n", ws.Name)
+			default:
+				fmt.Printf("  workspace/%s: %s\n", ws.Name, newCommitsText(n))
+			}
+			workspaces++
+			newCommits += n
+		}
+		if len(f.Result.Pruned) > 0 {
+			fmt.Printf("  Pruned %d deleted remote branch(es): %s\n", len(f.Result.Pruned), strings.Join(f.Result.Pruned, ", "))
+			pruned += len(f.Result.Pruned)
+		}
+	}
+
+	fmt.Printf("\nFetched %d workspace(s): %s, %d pruned branch(es)\n", workspaces, newCommitsText(newCommits), pruned)
+	if failed > 0 {
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("%d of %d repositories failed to fetch", failed, len(fetches)))
+	}
+	return nil
+}
+
+// newCommitsText describes n new commits
+func newCommitsText(n int) string {
+	if n == 1 {
+		return "1 new commit"
+	}
+	return fmt.Sprintf("%d new commits", n)
+}
+
+// workspaceWorktrees lists the workspaces of a repository, sorted by name
+func (c *CLI) workspaceWorktrees(repoName string) ([]workspaceWorktree, error) {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return nil, errors.DaemonCommunicationFailed("listing workspaces", err)
+	}
+	if !resp.Success {
+		return nil, errors.Wrap(errors.CategoryRuntime, "failed to list workspaces", fmt.Errorf("%s", resp.Error))
+	}
+
+	var workspaces []workspaceWorktree
+	agents, _ := resp.Data.([]interface{})
+	for _, agent := range agents {
+		agentMap, ok := agent.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		agentType, _ := agentMap["type"].(string)
+		path, _ := agentMap["worktree_path"].(string)
+		if agentType != "workspace" || path == "" {
+			continue
+		}
+		name, _ := agentMap["name"].(string)
+		workspaces = append(workspaces, workspaceWorktree{Name: name, Path: path})
+	}
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].Name < workspaces[j].Name })
+	return workspaces, nil
+}
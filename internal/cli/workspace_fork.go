@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// workspaceFork is what workspace add --copy-from carries over from the
+// workspace it forks
+type workspaceFork struct {
+	Source     string
+	Head       string       // commit the new workspace's branch starts at
+	PromptFile string       // the source's prompt file; empty if it has none
+	Messages   *contextFile // the source's delivered and read messages, with --copy-messages
+}
+
+// extractCopyMessagesFlag removes --copy-messages, which takes no value,
+// from args so that ParseFlags does not take the workspace name as one
+func extractCopyMessagesFlag(args []string) (bool, []string) {
+	copyMessages := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--copy-messages" || arg == "--copy-messages=true" {
+			copyMessages = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return copyMessages, rest
+}
+
+// forkWorkspace gathers what a new workspace takes from an existing one:
+// its current HEAD, its prompt file and, if copyMessages is set, the
+// messages it has received
+func (c *CLI) forkWorkspace(repoName, sourceName string, copyMessages bool) (*workspaceFork, error) {
+	source, err := c.findWorkspace(repoName, sourceName)
+	if err != nil {
+		return nil, err
+	}
+	sourcePath, _ := source["worktree_path"].(string)
+
+	output, err := cmdrun.Output(exec.Command("git", "-C", sourcePath, "rev-parse", "HEAD"))
+	if err != nil {
+		return nil, errors.GitOperationFailed("get HEAD of workspace "+sourceName, err)
+	}
+	fork := &workspaceFork{Source: sourceName, Head: strings.TrimSpace(output)}
+
+	if hasUncommitted, err := worktree.HasUncommittedChanges(sourcePath); err == nil && hasUncommitted {
+		fmt.Printf("Warning: workspace '%s' has uncommitted changes; they will not be carried into the new workspace\n", sourceName)
+	}
+
+	promptFile := filepath.Join(c.paths.Root, "prompts", sourceName+".md")
+	if _, err := os.Stat(promptFile); err == nil {
+		fork.PromptFile = promptFile
+	} else {
+		fmt.Printf("Warning: workspace '%s' has no prompt file; the new workspace gets the default prompt\n", sourceName)
+	}
+
+	if copyMessages {
+		msgMgr := messages.NewManager(c.paths.MessagesDir)
+		file, count, err := receivedMessagesContext(msgMgr, repoName, sourceName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy messages from workspace '%s': %w", sourceName, err)
+		}
+		if count == 0 {
+			fmt.Printf("Workspace '%s' has no delivered or read messages to copy\n", sourceName)
+		} else {
+			fmt.Printf("Copying %d message(s) from workspace '%s'\n", count, sourceName)
+			fork.Messages = file
+		}
+	}
+
+	return fork, nil
+}
+
+// receivedMessagesContext renders the delivered and read messages of an
+// agent, oldest first, as a context file for another agent. Pending
+// messages were never seen and acked ones are dealt with, so neither is
+// context worth carrying over.
+func receivedMessagesContext(msgMgr *messages.Manager, repoName, agentName string) (*contextFile, int, error) {
+	msgs, err := msgMgr.List(repoName, agentName)
+	if err != nil {
+		return nil, 0, err
+	}
+	var received []*messages.Message
+	for _, msg := range msgs {
+		if msg.Status == messages.StatusDelivered || msg.Status == messages.StatusRead {
+			received = append(received, msg)
+		}
+	}
+	if len(received) == 0 {
+		return nil, 0, nil
+	}
+	sort.Slice(received, func(i, j int) bool {
+		return received[i].Timestamp.Before(received[j].Timestamp)
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Messages received by %s\n\n", agentName)
+	fmt.Fprintf(&b, "Copied when this workspace was forked from '%s'. They are context only: do not reply to or acknowledge them.\n", agentName)
+	for _, msg := range received {
+		body, err := msgMgr.FullBody(repoName, agentName, msg)
+		if err != nil {
+			return nil, 0, err
+		}
+		fmt.Fprintf(&b, "\n## From %s at %s (%s)\n\n%s\n", msg.From, msg.Timestamp.Format(time.RFC3339), msg.Status, strings.TrimRight(body, "\n"))
+	}
+	return &contextFile{Name: "messages-from-" + agentName + ".md", Data: []byte(b.String())}, len(received), nil
+}
+
+// copyPromptFile gives agentName a copy of the prompt file at sourcePath
+// and returns the copy's path
+func (c *CLI) copyPromptFile(sourcePath, agentName string) (string, error) {
+	data, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt file: %w", err)
+	}
+	promptDir := filepath.Join(c.paths.Root, "prompts")
+	if err := os.MkdirAll(promptDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create prompt directory: %w", err)
+	}
+	promptPath := filepath.Join(promptDir, fmt.Sprintf("%s.md", agentName))
+	if err := os.WriteFile(promptPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write prompt file: %w", err)
+	}
+	return promptPath, nil
+}
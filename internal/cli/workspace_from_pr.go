@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/gh"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// prViewFields are the fields create-from-pr asks gh pr view for
+const prViewFields = "number,url,state,headRefName,isCrossRepository"
+
+// prView is the part of gh pr view --json output create-from-pr uses
+type prView struct {
+	Number            int    `json:"number"`
+	URL               string `json:"url"`
+	State             string `json:"state"`
+	HeadRefName       string `json:"headRefName"`
+	IsCrossRepository bool   `json:"isCrossRepository"`
+}
+
+// extractWatchFlag removes --watch, which takes no value, from args so that
+// ParseFlags does not take the PR URL following it as its value
+func extractWatchFlag(args []string) (bool, []string) {
+	watch := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--watch" || arg == "--watch=true" {
+			watch = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return watch, rest
+}
+
+// createWorkspaceFromPR creates a workspace on the head branch of a pull
+// request, so that pushes from it update the PR
+func (c *CLI) createWorkspaceFromPR(args []string) error {
+	noSubmodules, args := extractNoSubmodulesFlag(args)
+	watch, args := extractWatchFlag(args)
+	flags, posArgs := ParseFlags(args)
+
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude workspace create-from-pr <pr-url> [--name <name>] [--repo <repo>] [--watch] [--no-submodules]")
+	}
+	prURL := posArgs[0]
+	if parts := strings.Split(strings.TrimPrefix(strings.TrimPrefix(prURL, "https://"), "http://"), "/"); len(parts) < 5 || parts[3] != "pull" {
+		return errors.InvalidPRURL()
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	repoPath := c.paths.RepoDir(repoName)
+	if err := requireGH(); err != nil {
+		return err
+	}
+
+	var pr prView
+	if err := gh.PRView(context.Background(), gh.Repo{Dir: repoPath}, prURL, prViewFields, &pr); err != nil {
+		if cliErr, ok := ghError(err).(*errors.CLIError); ok {
+			return cliErr
+		}
+		return errors.Wrap(errors.CategoryRuntime, "failed to look up pull request", err)
+	}
+	if pr.HeadRefName == "" {
+		return errors.New(errors.CategoryRuntime, "unexpected output from gh pr view: no head branch")
+	}
+	if pr.IsCrossRepository {
+		return errors.New(errors.CategoryUsage, fmt.Sprintf("PR #%d comes from a fork, so its branch '%s' cannot be pushed to from here", pr.Number, pr.HeadRefName)).
+			WithSuggestion("multiclaude review " + prURL)
+	}
+	if pr.State != "OPEN" {
+		fmt.Printf("Warning: PR #%d is %s\n", pr.Number, strings.ToLower(pr.State))
+	}
+
+	workspaceName := flags["name"]
+	if workspaceName == "" {
+		workspaceName = fmt.Sprintf("pr-%d", pr.Number)
+	}
+	if err := validateWorkspaceName(workspaceName); err != nil {
+		return err
+	}
+	if _, err := c.findWorkspace(repoName, workspaceName); err == nil {
+		return fmt.Errorf("workspace '%s' already exists in repo '%s'", workspaceName, repoName)
+	} else if cliErr, ok := err.(*errors.CLIError); !ok || cliErr.Category != errors.CategoryNotFound {
+		return err
+	}
+
+	fmt.Printf("Creating workspace '%s' in repo '%s' for PR #%d (branch '%s')\n", workspaceName, repoName, pr.Number, pr.HeadRefName)
+
+	// Fetch into the remote-tracking branch explicitly, in case the
+	// clone's fetch refspec does not cover it
+	remoteBranch := "origin/" + pr.HeadRefName
+	fmt.Printf("Fetching %s...\n", remoteBranch)
+	cmd := exec.Command("git", "fetch", "origin", fmt.Sprintf("+refs/heads/%s:refs/remotes/%s", pr.HeadRefName, remoteBranch))
+	cmd.Dir = repoPath
+	if _, _, err := cmdrun.Run(cmd); err != nil {
+		if cliErr, ok := err.(*errors.CLIError); ok {
+			return cliErr
+		}
+		return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to fetch branch '%s' of PR #%d", pr.HeadRefName, pr.Number), err)
+	}
+
+	// The local branch has the PR branch's name and tracks it, so a plain
+	// git push from the workspace updates the PR
+	wt := worktree.NewManager(repoPath)
+	wtPath := c.paths.AgentWorktree(repoName, workspaceName)
+	fmt.Printf("Creating worktree at: %s\n", wtPath)
+	if exists, err := wt.BranchExists(pr.HeadRefName); err == nil && exists {
+		// Left behind by an earlier workspace (workspace rm keeps branches).
+		// Reuse it only if every commit on it is on the PR.
+		unpushed, err := cmdrun.Output(exec.Command("git", "-C", repoPath, "rev-list", remoteBranch+".."+pr.HeadRefName))
+		if err != nil || strings.TrimSpace(unpushed) != "" {
+			return errors.New(errors.CategoryUsage, fmt.Sprintf("branch '%s' of PR #%d already exists locally in repo '%s' with commits not on the PR", pr.HeadRefName, pr.Number, repoName)).
+				WithSuggestion(fmt.Sprintf("push or delete them, e.g. 'git -C %s branch -D %s', then try again", repoPath, pr.HeadRefName))
+		}
+		if _, _, err := cmdrun.Run(exec.Command("git", "-C", repoPath, "branch", "-f", pr.HeadRefName, remoteBranch)); err != nil {
+			return errors.GitOperationFailed("update "+pr.HeadRefName, err)
+		}
+		if err := wt.Create(wtPath, pr.HeadRefName); err != nil {
+			return errors.WorktreeCreationFailed(err)
+		}
+	} else if err := wt.CreateNewBranch(wtPath, pr.HeadRefName, remoteBranch); err != nil {
+		return errors.WorktreeCreationFailed(err)
+	}
+	if _, _, err := cmdrun.Run(exec.Command("git", "-C", wtPath, "branch", "--set-upstream-to="+remoteBranch)); err != nil {
+		removeNewWorktree(wt, wtPath, pr.HeadRefName)
+		return errors.GitOperationFailed("track "+remoteBranch, err)
+	}
+	c.applyGitIdentity(repoName, wtPath)
+	if !noSubmodules {
+		if err := c.checkoutSubmodules(repoName, wtPath, workspaceName, "workspace"); err != nil {
+			// Remove the worktree so the workspace can be created again
+			removeNewWorktree(wt, wtPath, pr.HeadRefName)
+			return err
+		}
+	}
+
+	initialMessage := fmt.Sprintf("This workspace tracks PR #%d: %s\n"+
+		"The branch checked out here is the PR's branch '%s'; commits you push update the PR.", pr.Number, pr.URL, pr.HeadRefName)
+	if err := c.startWorkspaceAgent(repoName, workspaceName, wtPath, nil, initialMessage, ""); err != nil {
+		return err
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "update_agent_pr",
+		Args: map[string]interface{}{
+			"repo":      repoName,
+			"agent":     workspaceName,
+			"pr_url":    pr.URL,
+			"pr_number": pr.Number,
+			"watch":     watch,
+		},
+	})
+	if err != nil {
+		fmt.Printf("Warning: failed to record PR in state: %v\n", err)
+	} else if !resp.Success {
+		fmt.Printf("Warning: failed to record PR in state: %s\n", resp.Error)
+	}
+
+	fmt.Println()
+	fmt.Println("✓ Workspace created successfully!")
+	fmt.Printf("  Name: %s\n", workspaceName)
+	fmt.Printf("  Branch: %s (PR #%d)\n", pr.HeadRefName, pr.Number)
+	fmt.Printf("  Worktree: %s\n", wtPath)
+	if watch {
+		fmt.Println("  Watching: the workspace gets a message when the PR is merged or closed")
+	}
+	fmt.Printf("\nConnect to workspace: multiclaude workspace connect %s\n", workspaceName)
+	fmt.Printf("Or use: multiclaude attach %s\n", workspaceName)
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// workspaceEntry is one workspace as shown by workspace list
+type workspaceEntry struct {
+	Repo      string `json:"repo"`
+	Name      string `json:"name"`
+	Branch    string `json:"branch"`
+	Status    string `json:"status"`
+	Path      string `json:"path"`
+	SessionID string `json:"session_id"`
+	// Unread and total messages addressed to the workspace
+	MessagesPending int `json:"messages_pending"`
+	MessagesTotal   int `json:"messages_total"`
+}
+
+// repoWorkspaces returns the workspaces registered in a repository
+func (c *CLI) repoWorkspaces(repoName string) ([]workspaceEntry, error) {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+			"rich": true,
+		},
+	})
+	if err != nil {
+		return nil, errors.DaemonCommunicationFailed("listing workspaces", err)
+	}
+	if !resp.Success {
+		return nil, errors.Wrap(errors.CategoryRuntime, "failed to list workspaces", fmt.Errorf("%s", resp.Error))
+	}
+
+	agents, ok := resp.Data.([]interface{})
+	if !ok {
+		return nil, errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	}
+
+	workspaces := []workspaceEntry{}
+	for _, agent := range agents {
+		agentMap, ok := agent.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if agentType, _ := agentMap["type"].(string); agentType != "workspace" {
+			continue
+		}
+		ws := workspaceEntry{Repo: repoName}
+		ws.Name, _ = agentMap["name"].(string)
+		ws.Branch, _ = agentMap["branch"].(string)
+		ws.Status, _ = agentMap["status"].(string)
+		ws.Path, _ = agentMap["worktree_path"].(string)
+		ws.SessionID, _ = agentMap["session_id"].(string)
+		ws.MessagesPending = workerCount(agentMap, "messages_pending")
+		ws.MessagesTotal = workerCount(agentMap, "messages_total")
+		workspaces = append(workspaces, ws)
+	}
+	return workspaces, nil
+}
+
+// listAllWorkspaces lists the workspaces of every tracked repository, sorted
+// by repository then name. A repository that cannot be queried is reported
+// as a warning and does not hide the others.
+func (c *CLI) listAllWorkspaces(jsonOutput, showMessages bool) error {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{Command: "list_repos"})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("listing repositories", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to list repositories", fmt.Errorf("%s", resp.Error))
+	}
+	repos, _ := resp.Data.([]interface{})
+
+	workspaces := []workspaceEntry{}
+	for _, r := range repos {
+		repoName, ok := r.(string)
+		if !ok {
+			continue
+		}
+		repoWorkspaces, err := c.repoWorkspaces(repoName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to list workspaces for %s: %v\n", repoName, err)
+			continue
+		}
+		workspaces = append(workspaces, repoWorkspaces...)
+	}
+
+	sort.Slice(workspaces, func(i, j int) bool {
+		if workspaces[i].Repo != workspaces[j].Repo {
+			return workspaces[i].Repo < workspaces[j].Repo
+		}
+		return workspaces[i].Name < workspaces[j].Name
+	})
+
+	if jsonOutput {
+		return printWorkspacesJSON(workspaces)
+	}
+
+	if len(workspaces) == 0 {
+		fmt.Println("No workspaces in any repository")
+		format.Dimmed("\nCreate a workspace with: multiclaude workspace add <name>")
+		return nil
+	}
+
+	format.Header("Workspaces in all repositories (%d):", len(workspaces))
+	fmt.Println()
+
+	table := format.NewColoredTable(workspaceColumns(showMessages, "REPO", "NAME", "BRANCH", "STATUS")...)
+	for _, ws := range workspaces {
+		table.AddRow(workspaceRow(ws, showMessages,
+			format.Cell(ws.Repo),
+			format.Cell(ws.Name),
+			workspaceBranchCell(ws.Branch),
+			workspaceStatusCell(ws.Status),
+		)...)
+	}
+	table.Print()
+
+	return nil
+}
+
+// listGroupWorkspaces lists the workspaces of a group's repositories, one
+// section per repository. The JSON form nests them by group, then
+// repository.
+func (c *CLI) listGroupWorkspaces(groupName string, members []string, jsonOutput, showMessages bool) error {
+	if !jsonOutput {
+		return c.forEachGroupRepo(groupName, members, func(repoName string) error {
+			workspaces, err := c.repoWorkspaces(repoName)
+			if err != nil {
+				return err
+			}
+			printRepoWorkspaces(repoName, workspaces, showMessages)
+			return nil
+		})
+	}
+
+	byRepo := make(map[string][]workspaceEntry, len(members))
+	for _, repoName := range members {
+		workspaces, err := c.repoWorkspaces(repoName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to list workspaces for %s: %v\n", repoName, err)
+			continue
+		}
+		byRepo[repoName] = workspaces
+	}
+	jsonData, err := json.MarshalIndent(map[string]map[string][]workspaceEntry{groupName: byRepo}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode workspaces: %w", err)
+	}
+	fmt.Println(string(jsonData))
+	return nil
+}
+
+// printRepoWorkspaces prints the workspaces of one repository as a table
+func printRepoWorkspaces(repoName string, workspaces []workspaceEntry, showMessages bool) {
+	if len(workspaces) == 0 {
+		fmt.Printf("No workspaces in repository '%s'\n", repoName)
+		format.Dimmed("\nCreate a workspace with: multiclaude workspace add <name>")
+		return
+	}
+
+	format.Header("Workspaces in '%s' (%d):", repoName, len(workspaces))
+	fmt.Println()
+
+	table := format.NewColoredTable(workspaceColumns(showMessages, "NAME", "BRANCH", "STATUS")...)
+	for _, ws := range workspaces {
+		table.AddRow(workspaceRow(ws, showMessages,
+			format.Cell(ws.Name),
+			workspaceBranchCell(ws.Branch),
+			workspaceStatusCell(ws.Status),
+		)...)
+	}
+	table.Print()
+}
+
+// printWorkspacesJSON writes workspaces to stdout as a JSON array
+func printWorkspacesJSON(workspaces []workspaceEntry) error {
+	jsonData, err := json.MarshalIndent(workspaces, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode workspaces: %w", err)
+	}
+	fmt.Println(string(jsonData))
+	return nil
+}
+
+// workspaceColumns returns the headers of a workspace table, with the
+// message columns appended if showMessages is set
+func workspaceColumns(showMessages bool, headers ...string) []string {
+	if showMessages {
+		headers = append(headers, "MSGS_PENDING", "MSGS_TOTAL")
+	}
+	return headers
+}
+
+// workspaceRow returns the cells of a workspace's table row, with its
+// message counts appended if showMessages is set. Pending messages are
+// highlighted so a workspace with mail to read stands out.
+func workspaceRow(ws workspaceEntry, showMessages bool, cells ...format.ColoredCell) []format.ColoredCell {
+	if !showMessages {
+		return cells
+	}
+	pending := format.ColorCell("0", format.Dim)
+	if ws.MessagesPending > 0 {
+		pending = format.ColorCell(fmt.Sprint(ws.MessagesPending), format.Yellow)
+	}
+	return append(cells, pending, format.Cell(fmt.Sprint(ws.MessagesTotal)))
+}
+
+// workspaceStatusCell formats a workspace's status with color
+func workspaceStatusCell(status string) format.ColoredCell {
+	switch status {
+	case "running":
+		return format.ColorCell(format.ColoredStatus(format.StatusRunning), nil)
+	case "completed":
+		return format.ColorCell(format.ColoredStatus(format.StatusCompleted), nil)
+	case "stopped":
+		return format.ColorCell(format.ColoredStatus(format.StatusError), nil)
+	case "quarantined":
+		return format.ColorCell(format.ColoredStatus(format.StatusQuarantined), nil)
+	default:
+		return format.ColorCell(format.ColoredStatus(format.StatusIdle), nil)
+	}
+}
+
+// workspaceBranchCell formats a workspace's branch, dimming a missing one
+func workspaceBranchCell(branch string) format.ColoredCell {
+	if branch == "" {
+		return format.ColorCell("-", format.Dim)
+	}
+	return format.ColorCell(branch, format.Cyan)
+}
@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/gh"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// findWorkspace returns the daemon's record for a named workspace
+func (c *CLI) findWorkspace(repoName, workspaceName string) (map[string]interface{}, error) {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return nil, errors.DaemonCommunicationFailed("getting workspace info", err)
+	}
+	if !resp.Success {
+		return nil, errors.Wrap(errors.CategoryRuntime, "failed to get workspace info", fmt.Errorf("%s", resp.Error))
+	}
+
+	agents, _ := resp.Data.([]interface{})
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			agentType, _ := agentMap["type"].(string)
+			name, _ := agentMap["name"].(string)
+			if agentType == "workspace" && name == workspaceName {
+				return agentMap, nil
+			}
+		}
+	}
+	return nil, errors.WorkspaceNotFound(workspaceName, repoName)
+}
+
+// createWorkspacePR pushes a workspace branch and opens a pull request for it
+func (c *CLI) createWorkspacePR(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude workspace create-pr <name> [--title \"...\"] [--body \"...\"] [--base main] [--draft] [--repo <repo>]")
+	}
+	workspaceName := posArgs[0]
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	workspaceInfo, err := c.findWorkspace(repoName, workspaceName)
+	if err != nil {
+		return err
+	}
+	wtPath, _ := workspaceInfo["worktree_path"].(string)
+
+	branch, err := worktree.GetCurrentBranch(wtPath)
+	if err != nil {
+		return errors.GitOperationFailed("get workspace branch", err)
+	}
+	// On a detached HEAD there is no branch to push, only "HEAD"
+	if branch == "HEAD" {
+		return errors.InvalidUsage(fmt.Sprintf("workspace '%s' is on a detached HEAD; check out a branch in %s before creating a pull request", workspaceName, wtPath))
+	}
+
+	// Default to the upstream set with workspace set-upstream, then to the
+	// remote's default branch, falling back to main
+	upstream, _ := workspaceInfo["upstream_branch"].(string)
+	base, baseRef := flags["base"], ""
+	if b, ok := upstreamBase(upstream); base == "" && ok {
+		base, baseRef = b, upstream
+	}
+	if base == "" {
+		base = "main"
+		if b, err := worktree.NewManager(c.paths.RepoDir(repoName)).GetDefaultBranch("origin"); err == nil {
+			base = b
+		}
+	}
+
+	// Compare against the remote-tracking branch when we have one
+	if baseRef == "" {
+		baseRef = "origin/" + base
+	}
+	if err := exec.Command("git", "-C", wtPath, "rev-parse", "--verify", "--quiet", baseRef).Run(); err != nil {
+		baseRef = base
+	}
+	ahead, err := worktree.CommitsAhead(wtPath, baseRef)
+	if err != nil {
+		return errors.GitOperationFailed("compare with "+baseRef, err)
+	}
+	if ahead == 0 {
+		return errors.NoCommitsForPR(branch, baseRef)
+	}
+
+	title := flags["title"]
+	body, hasBody := flags["body"]
+	if title == "" || !hasBody {
+		subject, commitBody, err := worktree.LastCommitMessage(wtPath)
+		if err != nil {
+			return errors.GitOperationFailed("read last commit message", err)
+		}
+		if title == "" {
+			title = subject
+		}
+		if !hasBody {
+			body = commitBody
+		}
+	}
+
+	// Without gh the PR could not be opened after the push
+	if err := requireGH(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Pushing %s (%d commit(s) ahead of %s) to origin...\n", branch, ahead, baseRef)
+	if err := worktree.PushBranch(wtPath, "origin", branch); err != nil {
+		return errors.GitOperationFailed("push", err)
+	}
+	// Pushing with -u tracks the pushed branch; keep tracking the upstream
+	if remote, upstreamBranch, ok := strings.Cut(upstream, "/"); ok {
+		if err := worktree.SetUpstream(wtPath, remote, upstreamBranch); err != nil {
+			fmt.Printf("Warning: failed to restore upstream %s: %v\n", upstream, err)
+		}
+	}
+
+	prURL, err := gh.PRCreate(context.Background(), gh.Repo{Dir: wtPath}, gh.PRCreateOptions{
+		Head:  branch,
+		Base:  base,
+		Title: title,
+		Body:  body,
+		Draft: flags["draft"] == "true",
+	})
+	if err != nil {
+		if cliErr, ok := ghError(err).(*errors.CLIError); ok {
+			return cliErr
+		}
+		return errors.Wrap(errors.CategoryRuntime, "failed to create pull request", err)
+	}
+
+	reqArgs := map[string]interface{}{
+		"repo":   repoName,
+		"agent":  workspaceName,
+		"pr_url": prURL,
+	}
+	if idx := strings.LastIndex(prURL, "/pull/"); idx >= 0 {
+		if num, err := strconv.Atoi(prURL[idx+len("/pull/"):]); err == nil {
+			reqArgs["pr_number"] = num
+		}
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "update_agent_pr",
+		Args:    reqArgs,
+	})
+	if err != nil {
+		fmt.Printf("Warning: failed to record PR in state: %v\n", err)
+	} else if !resp.Success {
+		fmt.Printf("Warning: failed to record PR in state: %s\n", resp.Error)
+	}
+
+	fmt.Printf("✓ Pull request created: %s\n", prURL)
+	return nil
+}
+
+// showWorkspacePR opens a workspace's recorded pull request in the browser
+func (c *CLI) showWorkspacePR(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude workspace show-pr <name> [--repo <repo>]")
+	}
+	workspaceName := posArgs[0]
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	workspaceInfo, err := c.findWorkspace(repoName, workspaceName)
+	if err != nil {
+		return err
+	}
+
+	prURL, _ := workspaceInfo["pr_url"].(string)
+	if prURL == "" {
+		return errors.NoPRForWorkspace(workspaceName, repoName)
+	}
+	if err := requireGH(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Opening %s\n", prURL)
+	if err := gh.PRViewWeb(context.Background(), gh.Repo{}, prURL); err != nil {
+		if cliErr, ok := ghError(err).(*errors.CLIError); ok {
+			return cliErr
+		}
+		return errors.Wrap(errors.CategoryRuntime, "failed to open pull request", err)
+	}
+	return nil
+}
+
+// rebaseWorkspaceInteractive starts an interactive rebase in a workspace's
+// tmux window. The rebase opens an editor there, so the user finishes it by
+// attaching to the workspace.
+func (c *CLI) rebaseWorkspaceInteractive(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude workspace rebase-interactive <name> [--onto main] [--last N] [--repo <repo>]")
+	}
+	workspaceName := posArgs[0]
+
+	onto, hasOnto := flags["onto"]
+	lastStr, hasLast := flags["last"]
+	if hasOnto && hasLast {
+		return errors.InvalidUsage("--onto and --last cannot be used together")
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	workspaceInfo, err := c.findWorkspace(repoName, workspaceName)
+	if err != nil {
+		return err
+	}
+	wtPath, _ := workspaceInfo["worktree_path"].(string)
+	tmuxWindow := agentWindowTarget(workspaceInfo)
+
+	hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
+	if err != nil {
+		return errors.GitOperationFailed("check for uncommitted changes", err)
+	}
+	if hasUncommitted {
+		return errors.WorkspaceHasUncommittedChanges(workspaceName)
+	}
+
+	var upstream string
+	if hasLast {
+		n, err := strconv.Atoi(lastStr)
+		if err != nil || n < 1 {
+			return errors.InvalidUsage(fmt.Sprintf("--last must be a positive integer, got %q", lastStr))
+		}
+		upstream = fmt.Sprintf("HEAD~%d", n)
+	} else {
+		if onto == "" {
+			onto = "main"
+			if b, err := worktree.NewManager(c.paths.RepoDir(repoName)).GetDefaultBranch("origin"); err == nil {
+				onto = b
+			}
+		}
+		// Prefer the remote-tracking branch when we have one
+		upstream = "origin/" + onto
+		if err := exec.Command("git", "-C", wtPath, "rev-parse", "--verify", "--quiet", upstream).Run(); err != nil {
+			upstream = onto
+		}
+	}
+
+	if err := exec.Command("git", "-C", wtPath, "rev-parse", "--verify", "--quiet", upstream).Run(); err != nil {
+		return errors.GitOperationFailed("resolve "+upstream, err)
+	}
+
+	// Target the repository's recorded session and the workspace's window
+	// ID, as attach does, so imported sessions and renamed windows work
+	tmuxSession := c.repoTmuxSession(repoName)
+	tmuxClient := tmux.NewClient()
+	if err := tmuxClient.SendKeys(context.Background(), tmuxSession, tmuxWindow, "git rebase -i "+upstream); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to start interactive rebase", err)
+	}
+
+	fmt.Println("Attached to workspace for interactive rebase")
+	fmt.Printf("Run 'multiclaude workspace connect %s' to complete the rebase in the editor\n", workspaceName)
+	return nil
+}
@@ -0,0 +1,350 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/gh"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// prStatusFields are the gh pr view --json fields workspace pr-status reads
+const prStatusFields = "state,mergeable,reviews,statusCheckRollup"
+
+// prStatus is the part of gh pr view --json output workspace pr-status shows
+type prStatus struct {
+	State     string `json:"state"`     // OPEN, MERGED or CLOSED
+	Mergeable string `json:"mergeable"` // MERGEABLE, CONFLICTING or UNKNOWN
+	Reviews   []struct {
+		Author struct {
+			Login string `json:"login"`
+		} `json:"author"`
+		State string `json:"state"`
+	} `json:"reviews"`
+	// StatusCheckRollup mixes check runs (status and conclusion) and
+	// commit statuses (state)
+	StatusCheckRollup []struct {
+		Name       string `json:"name"`
+		Context    string `json:"context"`
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+		State      string `json:"state"`
+	} `json:"statusCheckRollup"`
+}
+
+// prCheckSummary counts a PR's CI checks by outcome
+type prCheckSummary struct {
+	Passed, Failed, Pending int
+	// FailedNames and PendingNames list the checks that need attention
+	FailedNames, PendingNames []string
+}
+
+// workspacePR is a workspace with a recorded pull request
+type workspacePR struct {
+	Repo  string
+	Name  string
+	PRURL string
+}
+
+// fetchPRStatus asks gh for the status of a pull request
+func fetchPRStatus(prURL string) (*prStatus, error) {
+	var status prStatus
+	if err := gh.PRView(context.Background(), gh.Repo{}, prURL, prStatusFields, &status); err != nil {
+		return nil, ghError(err)
+	}
+	return &status, nil
+}
+
+// reviewCounts returns how many reviewers currently approve the PR and how
+// many request changes. Only each reviewer's latest approval or change
+// request counts; comments do not override either.
+func (s *prStatus) reviewCounts() (approvals, changeRequests int) {
+	latest := make(map[string]string)
+	for _, r := range s.Reviews {
+		if r.State == "APPROVED" || r.State == "CHANGES_REQUESTED" || r.State == "DISMISSED" {
+			latest[r.Author.Login] = r.State
+		}
+	}
+	for _, state := range latest {
+		switch state {
+		case "APPROVED":
+			approvals++
+		case "CHANGES_REQUESTED":
+			changeRequests++
+		}
+	}
+	return approvals, changeRequests
+}
+
+// checkSummary sorts the PR's CI checks into passed, failed and pending
+func (s *prStatus) checkSummary() prCheckSummary {
+	var summary prCheckSummary
+	for _, check := range s.StatusCheckRollup {
+		name := check.Name
+		if name == "" {
+			name = check.Context
+		}
+		outcome := check.Conclusion
+		if check.State != "" {
+			outcome = check.State
+		} else if check.Status != "" && check.Status != "COMPLETED" {
+			outcome = "PENDING"
+		}
+		switch outcome {
+		case "SUCCESS", "NEUTRAL", "SKIPPED":
+			summary.Passed++
+		case "PENDING", "EXPECTED", "":
+			summary.Pending++
+			summary.PendingNames = append(summary.PendingNames, name)
+		default:
+			summary.Failed++
+			summary.FailedNames = append(summary.FailedNames, name)
+		}
+	}
+	return summary
+}
+
+// String describes the summary as e.g. "5 passed, 1 failed, 2 pending"
+func (s prCheckSummary) String() string {
+	if s.Passed+s.Failed+s.Pending == 0 {
+		return "no checks"
+	}
+	var parts []string
+	if s.Passed > 0 {
+		parts = append(parts, fmt.Sprintf("%d passed", s.Passed))
+	}
+	if s.Failed > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", s.Failed))
+	}
+	if s.Pending > 0 {
+		parts = append(parts, fmt.Sprintf("%d pending", s.Pending))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// reviewSummary describes review counts as e.g. "2 approvals, 1 change request"
+func reviewSummary(approvals, changeRequests int) string {
+	if approvals == 0 && changeRequests == 0 {
+		return "none"
+	}
+	plural := func(n int, word string) string {
+		if n == 1 {
+			return fmt.Sprintf("%d %s", n, word)
+		}
+		return fmt.Sprintf("%d %ss", n, word)
+	}
+	return plural(approvals, "approval") + ", " + plural(changeRequests, "change request")
+}
+
+// prStateCell formats a PR state with color
+func prStateCell(state string) format.ColoredCell {
+	text := strings.ToLower(state)
+	switch state {
+	case "OPEN":
+		return format.ColorCell(text, format.Green)
+	case "MERGED":
+		return format.ColorCell(text, format.Cyan)
+	default:
+		return format.ColorCell(text, format.Dim)
+	}
+}
+
+// mergeableText describes gh's mergeable value
+func mergeableText(mergeable string) string {
+	switch mergeable {
+	case "MERGEABLE":
+		return "yes"
+	case "CONFLICTING":
+		return "no (conflicts)"
+	default:
+		return "unknown"
+	}
+}
+
+// workspacePRStatus shows the GitHub status of workspace pull requests:
+// a detailed report for one workspace, or a table for every workspace with
+// a PR in the repository (or in all repositories with --all)
+func (c *CLI) workspacePRStatus(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	// ParseFlags takes a name following --all as the flag's value
+	all, isAll := flags["all"]
+	if isAll && all != "true" {
+		posArgs = append([]string{all}, posArgs...)
+	}
+	if isAll && len(posArgs) > 0 {
+		return errors.InvalidUsage("workspace pr-status takes either a workspace name or --all, not both")
+	}
+
+	if len(posArgs) > 0 {
+		workspaceName := posArgs[0]
+		repoName, err := c.resolveRepo(flags)
+		if err != nil {
+			return errors.NotInRepo()
+		}
+		workspaceInfo, err := c.findWorkspace(repoName, workspaceName)
+		if err != nil {
+			return err
+		}
+		prURL, _ := workspaceInfo["pr_url"].(string)
+		if prURL == "" {
+			return errors.NoPRForWorkspace(workspaceName, repoName)
+		}
+		status, err := fetchPRStatus(prURL)
+		if err != nil {
+			if cliErr, ok := err.(*errors.CLIError); ok {
+				return cliErr
+			}
+			return errors.Wrap(errors.CategoryRuntime, "failed to get pull request status", err)
+		}
+		printPRStatusReport(workspacePR{Repo: repoName, Name: workspaceName, PRURL: prURL}, status)
+		return nil
+	}
+
+	var repos []string
+	if isAll {
+		repos = c.getReposList()
+	} else {
+		repoName, err := c.resolveRepo(flags)
+		if err != nil {
+			return errors.NotInRepo()
+		}
+		repos = []string{repoName}
+	}
+
+	var prs []workspacePR
+	for _, repoName := range repos {
+		repoPRs, err := c.workspacePRs(repoName)
+		if err != nil {
+			if !isAll {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Warning: failed to list workspaces for %s: %v\n", repoName, err)
+			continue
+		}
+		prs = append(prs, repoPRs...)
+	}
+
+	if len(prs) == 0 {
+		fmt.Println("No workspaces with pull requests")
+		format.Dimmed("\nOpen one with: multiclaude workspace create-pr <name>")
+		return nil
+	}
+	if err := requireGH(); err != nil {
+		return err
+	}
+
+	format.Header("Workspace pull requests (%d):", len(prs))
+	fmt.Println()
+
+	var table *format.ColoredTable
+	if isAll {
+		table = format.NewColoredTable("REPO", "WORKSPACE", "PR", "STATE", "MERGEABLE", "REVIEWS", "CHECKS")
+	} else {
+		table = format.NewColoredTable("WORKSPACE", "PR", "STATE", "MERGEABLE", "REVIEWS", "CHECKS")
+	}
+	for _, pr := range prs {
+		var cells []format.ColoredCell
+		if isAll {
+			cells = append(cells, format.Cell(pr.Repo))
+		}
+		cells = append(cells, format.Cell(pr.Name), format.Cell(prNumberFromURL(pr.PRURL)))
+
+		status, err := fetchPRStatus(pr.PRURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			cells = append(cells, format.ColorCell("error", format.Red), format.Cell("-"), format.Cell("-"), format.Cell("-"))
+			table.AddRow(cells...)
+			continue
+		}
+		approvals, changeRequests := status.reviewCounts()
+		checks := status.checkSummary()
+		checksColor := format.Green
+		if checks.Failed > 0 {
+			checksColor = format.Red
+		} else if checks.Pending > 0 {
+			checksColor = format.Yellow
+		}
+		cells = append(cells,
+			prStateCell(status.State),
+			format.Cell(mergeableText(status.Mergeable)),
+			format.Cell(fmt.Sprintf("%d✓ %d✗", approvals, changeRequests)),
+			format.ColorCell(checks.String(), checksColor),
+		)
+		table.AddRow(cells...)
+	}
+	table.Print()
+
+	fmt.Println()
+	format.Dimmed("Details: multiclaude workspace pr-status <name>")
+	return nil
+}
+
+// workspacePRs returns the workspaces in a repository with a recorded PR,
+// sorted by name
+func (c *CLI) workspacePRs(repoName string) ([]workspacePR, error) {
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return nil, errors.DaemonCommunicationFailed("listing workspaces", err)
+	}
+	if !resp.Success {
+		return nil, errors.Wrap(errors.CategoryRuntime, "failed to list workspaces", fmt.Errorf("%s", resp.Error))
+	}
+
+	var prs []workspacePR
+	agents, _ := resp.Data.([]interface{})
+	for _, agent := range agents {
+		agentMap, ok := agent.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		agentType, _ := agentMap["type"].(string)
+		prURL, _ := agentMap["pr_url"].(string)
+		if agentType != "workspace" || prURL == "" {
+			continue
+		}
+		name, _ := agentMap["name"].(string)
+		prs = append(prs, workspacePR{Repo: repoName, Name: name, PRURL: prURL})
+	}
+	sort.Slice(prs, func(i, j int) bool { return prs[i].Name < prs[j].Name })
+	return prs, nil
+}
+
+// prNumberFromURL returns "#<n>" for a pull request URL, or the URL itself
+// if it has no number
+func prNumberFromURL(prURL string) string {
+	if idx := strings.LastIndex(prURL, "/pull/"); idx >= 0 {
+		return "#" + prURL[idx+len("/pull/"):]
+	}
+	return prURL
+}
+
+// printPRStatusReport prints the detailed status of one workspace's PR
+func printPRStatusReport(pr workspacePR, status *prStatus) {
+	approvals, changeRequests := status.reviewCounts()
+	checks := status.checkSummary()
+
+	format.Header("Workspace '%s' in '%s'", pr.Name, pr.Repo)
+	fmt.Printf("  PR:        %s\n", pr.PRURL)
+	fmt.Printf("  State:     %s\n", strings.ToLower(status.State))
+	fmt.Printf("  Mergeable: %s\n", mergeableText(status.Mergeable))
+	fmt.Printf("  Reviews:   %s\n", reviewSummary(approvals, changeRequests))
+	fmt.Printf("  Checks:    %s\n", checks)
+	for _, name := range checks.FailedNames {
+		fmt.Printf("    %s %s\n", format.Red.Sprint("✗"), name)
+	}
+	for _, name := range checks.PendingNames {
+		fmt.Printf("    %s %s\n", format.Yellow.Sprint("…"), name)
+	}
+}
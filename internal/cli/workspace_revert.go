@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/procs"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// extractRevertFlags removes --soft and --force, which take no value, from
+// args so that ParseFlags does not take the workspace name as their value
+func extractRevertFlags(args []string) (soft, force bool, rest []string) {
+	rest = make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg {
+		case "--soft", "--soft=true":
+			soft = true
+		case "--force", "--force=true":
+			force = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return soft, force, rest
+}
+
+// revertWorkspace resets a workspace's branch to an earlier commit, for
+// rolling back changes its agent got wrong. The agent is paused while the
+// worktree changes under it and then told what happened.
+func (c *CLI) revertWorkspace(args []string) error {
+	soft, force, args := extractRevertFlags(args)
+	flags, posArgs := ParseFlags(args)
+	ref := flags["to-commit"]
+	if len(posArgs) < 1 || ref == "" || ref == "true" {
+		return errors.InvalidUsage("usage: multiclaude workspace revert <name> --to-commit <ref> [--soft] [--force] [--repo <repo>]")
+	}
+	workspaceName := posArgs[0]
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	workspaceInfo, err := c.findWorkspace(repoName, workspaceName)
+	if err != nil {
+		return err
+	}
+	wtPath, _ := workspaceInfo["worktree_path"].(string)
+
+	if _, err := worktree.ResolveCommit(wtPath, ref); err != nil {
+		return errors.InvalidArgument("--to-commit", ref, "a commit ID, tag, branch or relative ref such as HEAD~3")
+	}
+
+	// A soft reset leaves the working tree alone, so only a hard one can
+	// lose uncommitted changes
+	if !soft && !force {
+		hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
+		if err != nil {
+			return errors.GitOperationFailed("check for uncommitted changes", err)
+		}
+		if hasUncommitted {
+			return errors.WorkspaceHasUncommittedChanges(workspaceName).
+				WithSuggestion(fmt.Sprintf("save them with 'multiclaude workspace snapshot %s', then rerun with --force", workspaceName))
+		}
+	}
+
+	pid, _ := workspaceInfo["pid"].(float64)
+	resume := pauseAgent(int(pid))
+	commit, err := worktree.ResetTo(wtPath, ref, soft)
+	resume()
+	if err != nil {
+		return errors.GitOperationFailed("reset", err)
+	}
+
+	mode := "hard"
+	if soft {
+		mode = "soft"
+	}
+	fmt.Printf("✓ Reverted workspace '%s' to %s (%s reset, now at %s)\n", workspaceName, ref, mode, shortCommit(commit))
+
+	// The message comes from the agent running the command, e.g. the
+	// supervisor, or from the user outside any agent
+	from := "user"
+	if ctxRepo, ctxAgent, err := c.inferAgentContext(); err == nil && ctxRepo == repoName {
+		from = ctxAgent
+	}
+	body := fmt.Sprintf("Workspace reverted to %s. Please review your context before continuing.", ref)
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	msg, err := msgMgr.Send(repoName, from, workspaceName, body)
+	if err != nil {
+		fmt.Printf("Warning: failed to notify workspace '%s': %v\n", workspaceName, err)
+		return nil
+	}
+
+	// Trigger immediate routing (best-effort, polling is fallback)
+	client := socket.NewClient(c.paths.DaemonSock)
+	_, _ = client.Send(socket.Request{Command: "route_messages"})
+
+	fmt.Printf("Notified workspace '%s' (message ID: %s)\n", workspaceName, msg.ID)
+	return nil
+}
+
+// pauseAgent stops an agent's process with SIGSTOP and returns a function
+// that resumes it. Agents with no known PID, or whose process has exited,
+// are left alone.
+func pauseAgent(pid int) (resume func()) {
+	if pid <= 0 || !procs.IsAlive(pid) {
+		return func() {}
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return func() {}
+	}
+	if err := process.Signal(syscall.SIGSTOP); err != nil {
+		fmt.Printf("Warning: failed to pause the agent (PID %d): %v\n", pid, err)
+		return func() {}
+	}
+	return func() {
+		if err := process.Signal(syscall.SIGCONT); err != nil {
+			fmt.Printf("Warning: failed to resume the agent (PID %d): %v\n", pid, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+)
+
+// workspaceFields are the fields workspace show can print, by the names
+// --field accepts. Each matches a key of workspace list --json, with
+// session-id also accepted as session_id.
+var workspaceFields = map[string]func(workspaceEntry) string{
+	"name":       func(ws workspaceEntry) string { return ws.Name },
+	"branch":     func(ws workspaceEntry) string { return ws.Branch },
+	"path":       func(ws workspaceEntry) string { return ws.Path },
+	"session-id": func(ws workspaceEntry) string { return ws.SessionID },
+	"status":     func(ws workspaceEntry) string { return ws.Status },
+}
+
+// showWorkspace prints one field of a workspace, its worktree path by
+// default, without a trailing newline so it can be used in $(...)
+func (c *CLI) showWorkspace(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude workspace show <name> [--repo <repo>] [--field name|branch|path|session-id|status]")
+	}
+	workspaceName := posArgs[0]
+
+	field := "path"
+	if value, ok := flags["field"]; ok {
+		field = strings.ReplaceAll(value, "_", "-")
+	}
+	get, ok := workspaceFields[field]
+	if !ok {
+		return errors.InvalidUsage(fmt.Sprintf("unknown --field %q (must be one of: name, branch, path, session-id, status)", flags["field"]))
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	workspaces, err := c.repoWorkspaces(repoName)
+	if err != nil {
+		return err
+	}
+	for _, ws := range workspaces {
+		if ws.Name == workspaceName {
+			fmt.Print(get(ws))
+			return nil
+		}
+	}
+	return errors.WorkspaceNotFound(workspaceName, repoName)
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// snapshotWorkspace saves a workspace's working tree as a snapshot, leaving
+// the working tree, index and branch as they are
+func (c *CLI) snapshotWorkspace(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude workspace snapshot <name> [--label <text>] [--repo <repo>]")
+	}
+	workspaceName := posArgs[0]
+	label := flags["label"]
+	if label == "true" {
+		return errors.MissingArgument("--label", "text")
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	workspaceInfo, err := c.findWorkspace(repoName, workspaceName)
+	if err != nil {
+		return err
+	}
+	wtPath, _ := workspaceInfo["worktree_path"].(string)
+
+	snapshot, err := worktree.CreateSnapshot(wtPath, workspaceName, label, time.Now())
+	if err != nil {
+		return errors.GitOperationFailed("snapshot", err)
+	}
+
+	fmt.Printf("✓ Saved snapshot %s of workspace %s\n", snapshot.ID, workspaceName)
+	format.Dimmed("Restore it with: multiclaude workspace restore %s %s", workspaceName, snapshot.ID)
+	return nil
+}
+
+// listWorkspaceSnapshots lists a workspace's snapshots, newest first. The
+// workspace need not exist any more: its snapshots are kept until pruned.
+func (c *CLI) listWorkspaceSnapshots(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude workspace snapshots list <name> [--repo <repo>]")
+	}
+	workspaceName := posArgs[0]
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	repoPath := c.paths.RepoDir(repoName)
+
+	snapshots, err := worktree.ListSnapshots(repoPath, workspaceName)
+	if err != nil {
+		return errors.GitOperationFailed("for-each-ref", err)
+	}
+	if len(snapshots) == 0 {
+		fmt.Printf("No snapshots of workspace %s\n", workspaceName)
+		format.Dimmed("Take one with: multiclaude workspace snapshot %s [--label <text>]", workspaceName)
+		return nil
+	}
+
+	format.Header("Snapshots of workspace %s:", workspaceName)
+	table := format.NewTable("ID", "CREATED", "LABEL", "CHANGES")
+	for _, s := range snapshots {
+		stat, err := worktree.SnapshotStat(repoPath, s)
+		if err != nil {
+			stat = "?"
+		} else if stat == "" {
+			stat = "no changes"
+		}
+		label := s.Label
+		if label == "" {
+			label = "-"
+		}
+		table.AddRow(s.ID, format.TimeAgo(s.Created), format.Truncate(label, 40), stat)
+	}
+	fmt.Print(table.String())
+	format.Dimmed("\nChanges are relative to the commit each snapshot was taken on.")
+	format.Dimmed("Restore one with: multiclaude workspace restore %s <id>", workspaceName)
+	return nil
+}
+
+// restoreWorkspaceSnapshot makes a workspace's working tree match one of its
+// snapshots. Uncommitted changes that would be overwritten need confirmation
+// or --force, and are saved as a snapshot first.
+func (c *CLI) restoreWorkspaceSnapshot(args []string) error {
+	assumeYes, args := extractYesFlag(args)
+	_, force, args := extractArchiveFlags(args)
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 2 {
+		return errors.InvalidUsage("usage: multiclaude workspace restore <name> <snapshot-id> [--force] [--repo <repo>]")
+	}
+	workspaceName, id := posArgs[0], posArgs[1]
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	workspaceInfo, err := c.findWorkspace(repoName, workspaceName)
+	if err != nil {
+		return err
+	}
+	wtPath, _ := workspaceInfo["worktree_path"].(string)
+
+	if _, err := worktree.GetSnapshot(wtPath, workspaceName, id); err != nil {
+		return errors.New(errors.CategoryNotFound, fmt.Sprintf("workspace '%s' has no snapshot '%s'", workspaceName, id)).
+			WithSuggestion(fmt.Sprintf("multiclaude workspace snapshots list %s", workspaceName))
+	}
+
+	hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
+	if err != nil {
+		return errors.GitOperationFailed("check for uncommitted changes", err)
+	}
+	if hasUncommitted {
+		fmt.Printf("\nWarning: Workspace '%s' has uncommitted changes!\n", workspaceName)
+		ok, err := confirm(confirmation{
+			Consequence: "They will be replaced by the snapshot, after being saved as a snapshot of their own.",
+			Prompt:      "Continue with restore?",
+			AssumeYes:   force || assumeYes,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Restore cancelled")
+			return nil
+		}
+
+		backup, err := worktree.CreateSnapshot(wtPath, workspaceName, "before restoring "+id, time.Now())
+		if err != nil {
+			return errors.GitOperationFailed("snapshot", err)
+		}
+		fmt.Printf("Saved the current changes as snapshot %s\n", backup.ID)
+	}
+
+	if err := worktree.RestoreSnapshot(wtPath, workspaceName, id); err != nil {
+		return errors.GitOperationFailed("restore snapshot", err)
+	}
+	fmt.Printf("✓ Restored workspace %s to snapshot %s\n", workspaceName, id)
+	return nil
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// setWorkspaceUpstream makes a workspace's branch track <remote>/<branch>,
+// which workspace create-pr then opens pull requests against
+func (c *CLI) setWorkspaceUpstream(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude workspace set-upstream <name> --branch <branch> [--remote origin] [--repo <repo>]")
+	}
+	workspaceName := posArgs[0]
+
+	branch := flags["branch"]
+	if branch == "" || branch == "true" {
+		return errors.MissingArgument("--branch", "branch")
+	}
+	remote := flags["remote"]
+	if remote == "" {
+		remote = "origin"
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	workspaceInfo, err := c.findWorkspace(repoName, workspaceName)
+	if err != nil {
+		return err
+	}
+	wtPath, _ := workspaceInfo["worktree_path"].(string)
+
+	// A shared branch may be newer than the last fetch
+	upstream := remote + "/" + branch
+	if err := exec.Command("git", "-C", wtPath, "rev-parse", "--verify", "--quiet", "refs/remotes/"+upstream).Run(); err != nil {
+		fmt.Printf("Fetching %s...\n", remote)
+		if err := worktree.NewManager(wtPath).FetchRemote(remote); err != nil {
+			return errors.GitOperationFailed("fetch", err)
+		}
+	}
+	if err := worktree.SetUpstream(wtPath, remote, branch); err != nil {
+		return errors.GitOperationFailed("branch --set-upstream-to", err)
+	}
+
+	client := socket.NewClient(c.paths.DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "set_agent_upstream",
+		Args: map[string]interface{}{
+			"repo":     repoName,
+			"agent":    workspaceName,
+			"upstream": upstream,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("recording upstream", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to record upstream", fmt.Errorf("%s", resp.Error))
+	}
+
+	fmt.Printf("✓ Workspace '%s' now tracks %s\n", workspaceName, upstream)
+	fmt.Printf("  workspace create-pr opens its pull requests against %s\n", branch)
+	return nil
+}
+
+// showWorkspaceUpstream shows the upstream recorded for a workspace, the one
+// git tracks, and how far the workspace's branch is from it
+func (c *CLI) showWorkspaceUpstream(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude workspace show-upstream <name> [--repo <repo>]")
+	}
+	workspaceName := posArgs[0]
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	workspaceInfo, err := c.findWorkspace(repoName, workspaceName)
+	if err != nil {
+		return err
+	}
+	wtPath, _ := workspaceInfo["worktree_path"].(string)
+	recorded, _ := workspaceInfo["upstream_branch"].(string)
+
+	branch, err := worktree.GetCurrentBranch(wtPath)
+	if err != nil {
+		return errors.GitOperationFailed("get workspace branch", err)
+	}
+	tracking, err := worktree.GetUpstream(wtPath)
+	if err != nil {
+		return errors.GitOperationFailed("get upstream", err)
+	}
+
+	fmt.Printf("Workspace: %s\n", workspaceName)
+	fmt.Printf("Branch:    %s\n", branch)
+	switch {
+	case recorded != "":
+		fmt.Printf("Upstream:  %s\n", recorded)
+	case tracking != "":
+		fmt.Printf("Upstream:  %s (tracked by git, not set with workspace set-upstream)\n", tracking)
+	default:
+		fmt.Println("Upstream:  (none)")
+	}
+	if tracking != "" {
+		if ahead, behind, err := aheadBehind(wtPath, tracking); err == nil {
+			fmt.Printf("Status:    %d ahead, %d behind %s\n", ahead, behind, tracking)
+		}
+	}
+	if base, ok := upstreamBase(recorded); ok {
+		fmt.Printf("PR base:   %s\n", base)
+	}
+
+	if recorded != "" && tracking != recorded {
+		now := tracking
+		if now == "" {
+			now = "nothing"
+		}
+		fmt.Printf("\nWarning: git now tracks %s, not %s; run 'multiclaude workspace set-upstream %s --branch <branch>' to set it again\n",
+			now, recorded, workspaceName)
+	}
+	return nil
+}
+
+// upstreamBase returns the branch a workspace's recorded upstream
+// ("origin/feature") names on its remote
+func upstreamBase(upstream string) (string, bool) {
+	_, branch, ok := strings.Cut(upstream, "/")
+	return branch, ok && branch != ""
+}
+
+// aheadBehind counts the commits HEAD has that ref does not, and the other
+// way around
+func aheadBehind(path, ref string) (int, int, error) {
+	output, err := exec.Command("git", "-C", path, "rev-list", "--left-right", "--count", "HEAD..."+ref).Output()
+	if err != nil {
+		return 0, 0, err
+	}
+	var ahead, behind int
+	if _, err := fmt.Sscanf(string(output), "%d %d", &ahead, &behind); err != nil {
+		return 0, 0, err
+	}
+	return ahead, behind, nil
+}
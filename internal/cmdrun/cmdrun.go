@@ -0,0 +1,46 @@
+// Package cmdrun runs external commands such as git, gh and tmux, capturing
+// their stderr so that failures can be reported with what the command said
+// rather than just its exit status.
+package cmdrun
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+)
+
+// Run runs cmd and returns what it wrote to stdout and stderr. If the caller
+// already set cmd.Stdout or cmd.Stderr, output still goes there; stderr is
+// captured as well, stdout only when it was unset. A failure is classified
+// with errors.ClassifyCommand: known git, gh and tmux failures come back as
+// typed errors with suggestions, and any other failure includes the stderr.
+func Run(cmd *exec.Cmd) (stdout, stderr string, err error) {
+	var outBuf, errBuf bytes.Buffer
+	if cmd.Stdout == nil {
+		cmd.Stdout = &outBuf
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = &errBuf
+	} else {
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, &errBuf)
+	}
+
+	runErr := cmd.Run()
+	stdout, stderr = outBuf.String(), errBuf.String()
+	if runErr != nil {
+		var args []string
+		if len(cmd.Args) > 1 {
+			args = cmd.Args[1:]
+		}
+		return stdout, stderr, errors.ClassifyCommand(cmd.Path, args, stderr, runErr)
+	}
+	return stdout, stderr, nil
+}
+
+// Output runs cmd like Run and returns only its stdout
+func Output(cmd *exec.Cmd) (string, error) {
+	stdout, _, err := Run(cmd)
+	return stdout, err
+}
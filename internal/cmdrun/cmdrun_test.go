@@ -0,0 +1,56 @@
+package cmdrun
+
+import (
+	"bytes"
+	goerrors "errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/errors"
+)
+
+func TestRun(t *testing.T) {
+	stdout, stderr, err := Run(exec.Command("git", "--version"))
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if !strings.HasPrefix(stdout, "git version") {
+		t.Errorf("stdout = %q, want the git version", stdout)
+	}
+	if stderr != "" {
+		t.Errorf("stderr = %q, want none", stderr)
+	}
+}
+
+func TestRun_ClassifiesFailure(t *testing.T) {
+	dir := t.TempDir()
+	cmd := exec.Command("git", "-C", dir, "status")
+	cmd.Env = append(cmd.Environ(), "GIT_CEILING_DIRECTORIES="+dir)
+
+	_, stderr, err := Run(cmd)
+	if !strings.Contains(stderr, "not a git repository") {
+		t.Errorf("stderr = %q, want git's message", stderr)
+	}
+	var cliErr *errors.CLIError
+	if !goerrors.As(err, &cliErr) || cliErr.Message != "not a git repository" {
+		t.Errorf("Run() error = %v, want it classified as not a git repository", err)
+	}
+}
+
+func TestRun_KeepsCallerStderr(t *testing.T) {
+	var tee bytes.Buffer
+	cmd := exec.Command("git", "--no-such-option")
+	cmd.Stderr = &tee
+
+	_, stderr, err := Run(cmd)
+	if err == nil {
+		t.Fatal("Run() should fail")
+	}
+	if stderr == "" || tee.String() != stderr {
+		t.Errorf("caller's stderr = %q, captured = %q; want both to get the output", tee.String(), stderr)
+	}
+	if !strings.Contains(err.Error(), "unknown option: --no-such-option") {
+		t.Errorf("unclassified error %q should include the stderr", err.Error())
+	}
+}
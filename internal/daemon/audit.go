@@ -0,0 +1,121 @@
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// auditedCommands are the socket commands that change state and are
+// recorded in the audit log
+var auditedCommands = map[string]bool{
+	"stop":                       true,
+	"add_repo":                   true,
+	"remove_repo":                true,
+	"add_agent":                  true,
+	"create_worker":              true,
+	"remove_agent":               true,
+	"complete_agent":             true,
+	"restart_agent":              true,
+	"set_agent_env":              true,
+	"update_agent_pr":            true,
+	"update_agent_task":          true,
+	"update_agent_status":        true,
+	"trigger_cleanup":            true,
+	"repair_state":               true,
+	"update_repo_config":         true,
+	"set_repo_url":               true,
+	"set_current_repo":           true,
+	"clear_current_repo":         true,
+	"mq_track_pr":                true,
+	"mq_untrack_pr":              true,
+	"set_context_var":            true,
+	"pause":                      true,
+	"resume":                     true,
+	"create_group":               true,
+	"add_to_group":               true,
+	"remove_from_group":          true,
+	"delete_group":               true,
+	"sync_repo":                  true,
+	"set_upgrade_check_interval": true,
+	"set_agent_upstream":         true,
+	"mark_pr_comments_read":      true,
+	"detect_branch_change":       true,
+}
+
+// auditRequest queues an audit entry for a handled request. It never blocks.
+func (d *Daemon) auditRequest(req socket.Request, resp socket.Response) {
+	d.auditLog.Log(audit.Entry{
+		Source:  audit.SourceDaemon,
+		Command: req.Command,
+		Args:    audit.RedactArgs(auditArgs(req)),
+		Caller:  d.auditCaller(req.Meta),
+		Success: resp.Success,
+		Error:   resp.Error,
+	})
+}
+
+// auditArgs returns the request args to record. set_agent_env and add_agent
+// can carry environment values, which are as often as not secrets, so only
+// the variable names are kept. create_worker carries the whole composed
+// prompt and the contents of its context files, which are large and may hold
+// secrets too, so the prompt is reduced to its length and the context files
+// to their names.
+func auditArgs(req socket.Request) map[string]interface{} {
+	env, hasEnv := req.Args["env"].(map[string]interface{})
+	hasEnv = hasEnv && (req.Command == "set_agent_env" || req.Command == "add_agent")
+	if !hasEnv && req.Command != "create_worker" {
+		return req.Args
+	}
+
+	args := make(map[string]interface{}, len(req.Args))
+	for k, v := range req.Args {
+		args[k] = v
+	}
+	if hasEnv {
+		names := make(map[string]interface{}, len(env))
+		for name := range env {
+			names[name] = "<redacted>"
+		}
+		args["env"] = names
+	}
+	if req.Command == "create_worker" {
+		if prompt, ok := args["prompt"].(string); ok {
+			args["prompt"] = fmt.Sprintf("<%d characters>", len(prompt))
+		}
+		if files, ok := args["context_files"].([]interface{}); ok {
+			names := make([]interface{}, 0, len(files))
+			for _, f := range files {
+				file, _ := f.(map[string]interface{})
+				if name, ok := file["name"].(string); ok {
+					names = append(names, name)
+				}
+			}
+			args["context_files"] = names
+		}
+	}
+	return args
+}
+
+// auditCaller builds caller info from request metadata, attributing the
+// request to an agent when it came from inside an agent worktree
+func (d *Daemon) auditCaller(meta map[string]string) *audit.Caller {
+	caller := audit.CallerFromMeta(meta)
+	if caller == nil || caller.Cwd == "" {
+		return caller
+	}
+
+	rel, err := filepath.Rel(d.paths.WorktreesDir, caller.Cwd)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return caller
+	}
+	parts := strings.SplitN(rel, string(filepath.Separator), 3)
+	caller.Repo = parts[0]
+	if len(parts) > 1 {
+		caller.Agent = parts[1]
+	}
+	return caller
+}
@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestHandleRequestWritesAuditLog(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "test-session",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	meta := map[string]string{
+		"pid": "4242",
+		"uid": "1000",
+		"cwd": filepath.Join(d.paths.WorktreesDir, "test-repo", "clever-fox", "src"),
+	}
+
+	// A mutating command that succeeds, one that fails, and a read-only one
+	d.handleRequest(socket.Request{
+		Command: "update_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo", "mq_enabled": false, "github_token": "ghp_secret"},
+		Meta:    meta,
+	})
+	d.handleRequest(socket.Request{
+		Command: "remove_repo",
+		Args:    map[string]interface{}{"name": "nonexistent"},
+	})
+	d.handleRequest(socket.Request{Command: "list_repos"})
+
+	d.auditLog.Close()
+
+	entries, err := audit.Read(d.paths.AuditLog(), audit.Filter{})
+	if err != nil {
+		t.Fatalf("audit.Read() failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d audit entries, want 2: %+v", len(entries), entries)
+	}
+
+	config := entries[0]
+	if config.Command != "update_repo_config" || !config.Success || config.Source != audit.SourceDaemon {
+		t.Errorf("unexpected config entry: %+v", config)
+	}
+	if config.Args["github_token"] == "ghp_secret" {
+		t.Error("secret-bearing args must be redacted")
+	}
+	if config.Caller == nil || config.Caller.PID != 4242 || config.Caller.UID != 1000 {
+		t.Fatalf("caller not recorded: %+v", config.Caller)
+	}
+	if config.Caller.Repo != "test-repo" || config.Caller.Agent != "clever-fox" {
+		t.Errorf("agent context = %s/%s, want test-repo/clever-fox", config.Caller.Repo, config.Caller.Agent)
+	}
+
+	remove := entries[1]
+	if remove.Command != "remove_repo" || remove.Success || remove.Error == "" {
+		t.Errorf("failed command should be recorded with its error: %+v", remove)
+	}
+	if remove.Caller != nil {
+		t.Errorf("requests without metadata should have no caller, got %+v", remove.Caller)
+	}
+}
+
+func TestAuditCallerOutsideWorktrees(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	caller := d.auditCaller(map[string]string{"pid": "1", "cwd": "/home/user/project"})
+	if caller.Repo != "" || caller.Agent != "" {
+		t.Errorf("caller outside worktrees should have no agent context: %+v", caller)
+	}
+
+	caller = d.auditCaller(map[string]string{"pid": "1", "cwd": d.paths.WorktreesDir})
+	if caller.Repo != "" {
+		t.Errorf("worktrees root should have no repo: %+v", caller)
+	}
+}
+
+func TestAuditArgsKeepsOnlyEnvNames(t *testing.T) {
+	req := socket.Request{
+		Command: "set_agent_env",
+		Args: map[string]interface{}{
+			"repo":  "test-repo",
+			"agent": "clever-fox",
+			"env":   map[string]interface{}{"GH_AUTH": "ghp_secret"},
+		},
+	}
+
+	args := auditArgs(req)
+	env, _ := args["env"].(map[string]interface{})
+	if _, ok := env["GH_AUTH"]; !ok || env["GH_AUTH"] == "ghp_secret" {
+		t.Errorf("audited env = %v, want the name with its value redacted", env)
+	}
+	if args["agent"] != "clever-fox" {
+		t.Errorf("other args should be kept, got %v", args)
+	}
+	if req.Args["env"].(map[string]interface{})["GH_AUTH"] != "ghp_secret" {
+		t.Error("auditArgs() must not modify the request")
+	}
+}
+
+func TestAuditArgsRedactsAddAgentEnv(t *testing.T) {
+	args := auditArgs(socket.Request{
+		Command: "add_agent",
+		Args: map[string]interface{}{
+			"repo":  "test-repo",
+			"agent": "supervisor",
+			"env":   map[string]interface{}{"ANTHROPIC_API_KEY": "sk-vault"},
+		},
+	})
+	env, _ := args["env"].(map[string]interface{})
+	if _, ok := env["ANTHROPIC_API_KEY"]; !ok || env["ANTHROPIC_API_KEY"] == "sk-vault" {
+		t.Errorf("audited env = %v, want the name with its value redacted", env)
+	}
+}
+
+func TestAuditArgsSummarizesCreateWorker(t *testing.T) {
+	args := auditArgs(socket.Request{
+		Command: "create_worker",
+		Args: map[string]interface{}{
+			"repo":   "test-repo",
+			"task":   "Fix the flaky test",
+			"prompt": "You are a worker. Use token ghp_prompt.",
+			"context_files": []interface{}{
+				map[string]interface{}{"name": "notes.md", "content": "DATABASE_URL=postgres://secret"},
+			},
+		},
+	})
+
+	if args["prompt"] != "<39 characters>" {
+		t.Errorf("audited prompt = %v, want only its length", args["prompt"])
+	}
+	files, _ := args["context_files"].([]interface{})
+	if len(files) != 1 || files[0] != "notes.md" {
+		t.Errorf("audited context_files = %v, want only the names", args["context_files"])
+	}
+	if args["task"] != "Fix the flaky test" {
+		t.Errorf("other args should be kept, got %v", args)
+	}
+
+	data, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "postgres://secret") || strings.Contains(string(data), "ghp_prompt") {
+		t.Errorf("audited args contain file or prompt contents: %s", data)
+	}
+}
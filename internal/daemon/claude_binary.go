@@ -0,0 +1,146 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/claude"
+)
+
+// claudeBinaryCheckInterval is how often the daemon re-inspects the claude
+// binary in PATH. Inspecting runs the binary, and upgrades are rare.
+const claudeBinaryCheckInterval = 15 * time.Minute
+
+// claudeBinaryChange records that the claude binary in PATH is no longer
+// the one the daemon started with
+type claudeBinaryChange struct {
+	Old        claude.BinaryInfo `json:"old"`
+	New        claude.BinaryInfo `json:"new"`
+	DetectedAt time.Time         `json:"detected_at"`
+}
+
+// inspectPathClaude inspects the claude binary found in PATH
+func inspectPathClaude(ctx context.Context) (claude.BinaryInfo, error) {
+	binaryPath, err := exec.LookPath("claude")
+	if err != nil {
+		return claude.BinaryInfo{}, fmt.Errorf("claude binary not found in PATH: %w", err)
+	}
+	return claude.InspectBinary(ctx, binaryPath)
+}
+
+// recordClaudeBinary remembers the claude binary in PATH at startup, as the
+// baseline later checks compare against
+func (d *Daemon) recordClaudeBinary() {
+	info, err := d.inspectClaude(d.ctx)
+	if err != nil {
+		d.logger.Warn("Could not inspect claude binary: %v", err)
+		return
+	}
+
+	d.claudeBinaryMu.Lock()
+	d.claudeBinary = &info
+	d.claudeBinaryMu.Unlock()
+	d.logger.Info("Using claude %s", info)
+}
+
+// checkClaudeBinary compares the claude binary in PATH with the one the
+// daemon started with. A change (typically a package manager upgrade) means
+// agents started from now on run a different claude than their siblings, so
+// it is logged, reported by the status command and announced to each
+// repository's supervisor, once per distinct binary.
+func (d *Daemon) checkClaudeBinary() {
+	current, err := d.inspectClaude(d.ctx)
+	if err != nil {
+		d.logger.Debug("Could not inspect claude binary: %v", err)
+		return
+	}
+
+	d.claudeBinaryMu.Lock()
+	if d.claudeBinary == nil {
+		// claude was installed after the daemon started
+		d.claudeBinary = &current
+		d.claudeBinaryMu.Unlock()
+		d.logger.Info("Using claude %s", current)
+		return
+	}
+	baseline := *d.claudeBinary
+	if current.SameBinary(baseline) {
+		if d.claudeBinaryChange != nil {
+			d.logger.Info("claude binary is back to the one the daemon started with: %s", current)
+			d.claudeBinaryChange = nil
+		}
+		d.claudeBinaryMu.Unlock()
+		return
+	}
+	if d.claudeBinaryChange != nil && d.claudeBinaryChange.New.SameBinary(current) {
+		d.claudeBinaryMu.Unlock()
+		return
+	}
+	change := &claudeBinaryChange{Old: baseline, New: current, DetectedAt: time.Now()}
+	d.claudeBinaryChange = change
+	d.claudeBinaryMu.Unlock()
+
+	d.logger.Warn("claude binary changed since the daemon started: %s -> %s; restart the daemon so all agents run the same version",
+		change.Old, change.New)
+	d.notifyClaudeBinaryChange(change)
+}
+
+// notifyClaudeBinaryChange tells each repository's supervisor that agents
+// may now run different claude versions
+func (d *Daemon) notifyClaudeBinaryChange(change *claudeBinaryChange) {
+	notice := fmt.Sprintf("FYI: the claude binary changed while the daemon was running, from %s to %s. "+
+		"Agents started or restarted from now on run the new version while the others keep the old one, "+
+		"so they may behave differently. If that causes trouble, ask the user to restart the daemon.",
+		change.Old, change.New)
+
+	msgMgr := d.getMessageManager()
+	for repoName, repo := range d.state.GetAllRepos() {
+		if _, exists := repo.Agents["supervisor"]; !exists {
+			continue
+		}
+		if _, err := msgMgr.Send(repoName, "daemon", "supervisor", notice); err != nil {
+			d.logger.Warn("Failed to notify supervisor of %s about the claude binary change: %v", repoName, err)
+		}
+	}
+}
+
+// claudeBinaryStatus returns the startup claude binary and any change
+// detected since, for the status command
+func (d *Daemon) claudeBinaryStatus() (*claude.BinaryInfo, *claudeBinaryChange) {
+	d.claudeBinaryMu.Lock()
+	defer d.claudeBinaryMu.Unlock()
+
+	var info *claude.BinaryInfo
+	if d.claudeBinary != nil {
+		copied := *d.claudeBinary
+		info = &copied
+	}
+	var change *claudeBinaryChange
+	if d.claudeBinaryChange != nil {
+		copied := *d.claudeBinaryChange
+		change = &copied
+	}
+	return info, change
+}
+
+// getClaudeBinaryPath resolves the claude binary agents of a repository are
+// started with: the pinned binary (config --pin-claude-path) if there is
+// one, otherwise the claude in PATH. A pinned binary that has gone missing
+// is an error rather than a reason to fall back to another binary.
+func (d *Daemon) getClaudeBinaryPath(repo *state.Repository) (string, error) {
+	if repo.ClaudePath != "" {
+		if err := claude.CheckExecutable(repo.ClaudePath); err != nil {
+			return "", fmt.Errorf("pinned %w; refusing to start agents with any other binary", err)
+		}
+		return repo.ClaudePath, nil
+	}
+
+	binaryPath, err := exec.LookPath("claude")
+	if err != nil {
+		return "", fmt.Errorf("claude binary not found in PATH: %w", err)
+	}
+	return binaryPath, nil
+}
@@ -0,0 +1,145 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/claude"
+)
+
+func TestCheckClaudeBinaryDetectsChange(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	for _, name := range []string{"with-supervisor", "without-supervisor"} {
+		if err := d.state.AddRepo(name, &state.Repository{
+			GithubURL:   "https://github.com/test/" + name,
+			TmuxSession: "mc-" + name,
+			Agents:      make(map[string]state.Agent),
+		}); err != nil {
+			t.Fatalf("Failed to add repo: %v", err)
+		}
+	}
+	if err := d.state.AddAgent("with-supervisor", "supervisor", state.Agent{
+		Type:       state.AgentTypeSupervisor,
+		TmuxWindow: "supervisor",
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add supervisor: %v", err)
+	}
+
+	original := claude.BinaryInfo{Path: "/usr/local/bin/claude", Version: "1.5.0", Fingerprint: "aaa"}
+	upgraded := claude.BinaryInfo{Path: "/usr/local/bin/claude", Version: "1.6.0", Fingerprint: "bbb"}
+	current := original
+	d.inspectClaude = func(ctx context.Context) (claude.BinaryInfo, error) {
+		return current, nil
+	}
+
+	supervisorMessages := func() int {
+		t.Helper()
+		msgs, err := d.getMessageManager().List("with-supervisor", "supervisor")
+		if err != nil {
+			t.Fatalf("Failed to list messages: %v", err)
+		}
+		return len(msgs)
+	}
+
+	d.recordClaudeBinary()
+	d.checkClaudeBinary()
+	if info, change := d.claudeBinaryStatus(); info == nil || *info != original || change != nil {
+		t.Fatalf("status = %v, %v; want the original binary and no change", info, change)
+	}
+
+	current = upgraded
+	d.checkClaudeBinary()
+	info, change := d.claudeBinaryStatus()
+	if info == nil || *info != original {
+		t.Errorf("baseline = %v, want it to stay the startup binary", info)
+	}
+	if change == nil || change.Old != original || change.New != upgraded {
+		t.Fatalf("change = %+v, want %v -> %v", change, original, upgraded)
+	}
+	if got := supervisorMessages(); got != 1 {
+		t.Fatalf("supervisor got %d messages, want 1", got)
+	}
+	msgs, _ := d.getMessageManager().List("with-supervisor", "supervisor")
+	if !strings.Contains(msgs[0].Body, "1.5.0") || !strings.Contains(msgs[0].Body, "1.6.0") {
+		t.Errorf("notice should mention both versions: %q", msgs[0].Body)
+	}
+
+	// The same change is only announced once
+	d.checkClaudeBinary()
+	if got := supervisorMessages(); got != 1 {
+		t.Errorf("supervisor got %d messages after a repeated check, want 1", got)
+	}
+
+	resp := d.handleStatus(socket.Request{Command: "status"})
+	data, _ := resp.Data.(map[string]interface{})
+	if reported, _ := data["claude_binary_change"].(*claudeBinaryChange); reported == nil || reported.New != upgraded {
+		t.Errorf("status claude_binary_change = %v, want the upgrade", data["claude_binary_change"])
+	}
+
+	// Going back to the startup binary clears the change
+	current = original
+	d.checkClaudeBinary()
+	if _, change := d.claudeBinaryStatus(); change != nil {
+		t.Errorf("change = %+v, want none after reverting", change)
+	}
+}
+
+func TestGetClaudeBinaryPathPinned(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	pinned := filepath.Join(t.TempDir(), "claude")
+	if err := os.WriteFile(pinned, []byte("#!/bin/sh\necho '1.5.0 (Claude Code)'\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake claude: %v", err)
+	}
+
+	got, err := d.getClaudeBinaryPath(&state.Repository{ClaudePath: pinned})
+	if err != nil || got != pinned {
+		t.Errorf("getClaudeBinaryPath() = %q, %v; want the pinned binary", got, err)
+	}
+
+	_, err = d.getClaudeBinaryPath(&state.Repository{ClaudePath: filepath.Join(t.TempDir(), "gone")})
+	if err == nil || !strings.Contains(err.Error(), "refusing") {
+		t.Errorf("getClaudeBinaryPath() with a missing pinned binary = %v, want a refusal", err)
+	}
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	update := func(path string) socket.Response {
+		return d.handleUpdateRepoConfig(socket.Request{
+			Command: "update_repo_config",
+			Args:    map[string]interface{}{"name": "test-repo", "claude_path": path},
+		})
+	}
+
+	if resp := update("relative/claude"); resp.Success {
+		t.Error("update_repo_config should reject a relative claude_path")
+	}
+	if resp := update(pinned); !resp.Success {
+		t.Fatalf("update_repo_config claude_path failed: %s", resp.Error)
+	}
+	resp := d.handleGetRepoConfig(socket.Request{Command: "get_repo_config", Args: map[string]interface{}{"name": "test-repo"}})
+	if data, _ := resp.Data.(map[string]interface{}); data["claude_path"] != pinned {
+		t.Errorf("get_repo_config claude_path = %v, want %q", data["claude_path"], pinned)
+	}
+	if resp := update(""); !resp.Success {
+		t.Fatalf("unpinning failed: %s", resp.Error)
+	}
+	if repo, _ := d.state.GetRepo("test-repo"); repo.ClaudePath != "" {
+		t.Errorf("ClaudePath = %q, want it cleared", repo.ClaudePath)
+	}
+}
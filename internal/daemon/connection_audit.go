@@ -0,0 +1,110 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// connectionAuditSize is the number of recent socket requests kept in memory
+const connectionAuditSize = 100
+
+// connectionAuditSecretKeys are substrings of argument names whose values
+// are redacted in the connection audit
+var connectionAuditSecretKeys = []string{"token", "password", "key", "secret"}
+
+// requestRecord describes a single handled socket request
+type requestRecord struct {
+	Time     time.Time
+	Command  string
+	Args     map[string]interface{}
+	Success  bool
+	Error    string
+	Duration time.Duration
+}
+
+// requestRing is a fixed-size, in-memory ring buffer of recent requests.
+// It is never persisted.
+type requestRing struct {
+	mu      sync.Mutex
+	records []requestRecord
+	next    int
+	full    bool
+}
+
+func newRequestRing(size int) *requestRing {
+	return &requestRing{records: make([]requestRecord, size)}
+}
+
+// Add records a request, overwriting the oldest record when full
+func (r *requestRing) Add(rec requestRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records[r.next] = rec
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Snapshot returns the recorded requests, oldest first
+func (r *requestRing) Snapshot() []requestRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]requestRecord(nil), r.records[:r.next]...)
+	}
+	out := make([]requestRecord, 0, len(r.records))
+	out = append(out, r.records[r.next:]...)
+	return append(out, r.records[:r.next]...)
+}
+
+// recordRequest adds a handled request to the connection audit. Its args are
+// stripped like the audit log's (see auditArgs) before secret-looking ones
+// are redacted, so env values and worker prompts are never kept.
+func (d *Daemon) recordRequest(req socket.Request, resp socket.Response, started time.Time) {
+	d.recentRequests.Add(requestRecord{
+		Time:     started,
+		Command:  req.Command,
+		Args:     audit.RedactArgsMatching(auditArgs(req), connectionAuditSecretKeys),
+		Success:  resp.Success,
+		Error:    resp.Error,
+		Duration: time.Since(started),
+	})
+}
+
+// handleConnectionAudit returns recent socket requests, newest first,
+// optionally filtered by command and limited to the last N
+func (d *Daemon) handleConnectionAudit(req socket.Request) socket.Response {
+	command, _ := req.Args["command"].(string)
+	last := 0
+	if l, ok := req.Args["last"].(float64); ok {
+		last = int(l)
+	}
+
+	records := d.recentRequests.Snapshot()
+	result := []map[string]interface{}{}
+	for i := len(records) - 1; i >= 0; i-- {
+		rec := records[i]
+		if command != "" && rec.Command != command {
+			continue
+		}
+		result = append(result, map[string]interface{}{
+			"time":        rec.Time,
+			"command":     rec.Command,
+			"args":        rec.Args,
+			"success":     rec.Success,
+			"error":       rec.Error,
+			"duration_ms": rec.Duration.Milliseconds(),
+		})
+		if last > 0 && len(result) >= last {
+			break
+		}
+	}
+
+	return socket.Response{Success: true, Data: result}
+}
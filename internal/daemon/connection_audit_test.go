@@ -0,0 +1,98 @@
+package daemon
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+func TestRequestRingWraps(t *testing.T) {
+	ring := newRequestRing(3)
+	if got := ring.Snapshot(); len(got) != 0 {
+		t.Fatalf("empty ring Snapshot() = %v", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		ring.Add(requestRecord{Command: fmt.Sprintf("cmd-%d", i)})
+	}
+
+	got := ring.Snapshot()
+	if len(got) != 3 {
+		t.Fatalf("Snapshot() returned %d records, want 3", len(got))
+	}
+	for i, want := range []string{"cmd-2", "cmd-3", "cmd-4"} {
+		if got[i].Command != want {
+			t.Errorf("Snapshot()[%d] = %s, want %s", i, got[i].Command, want)
+		}
+	}
+}
+
+func TestHandleConnectionAudit(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	d.handleRequest(socket.Request{Command: "ping"})
+	d.handleRequest(socket.Request{Command: "list_agents", Args: map[string]interface{}{"repo": "missing"}})
+	d.handleRequest(socket.Request{
+		Command: "update_repo_config",
+		Args: map[string]interface{}{
+			"name":       "missing",
+			"github_key": "ghp_secret",
+			"auth_token": "abc",
+		},
+	})
+
+	resp := d.handleConnectionAudit(socket.Request{Command: "connection_audit", Args: map[string]interface{}{}})
+	records, ok := resp.Data.([]map[string]interface{})
+	if !resp.Success || !ok {
+		t.Fatalf("handleConnectionAudit() = %+v", resp)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+
+	// Newest first, with secrets redacted
+	newest := records[0]
+	if newest["command"] != "update_repo_config" || newest["success"] != false {
+		t.Errorf("newest record = %v", newest)
+	}
+	args := newest["args"].(map[string]interface{})
+	if args["github_key"] == "ghp_secret" || args["auth_token"] == "abc" || args["name"] != "missing" {
+		t.Errorf("args not redacted correctly: %v", args)
+	}
+
+	resp = d.handleConnectionAudit(socket.Request{Command: "connection_audit", Args: map[string]interface{}{"command": "ping"}})
+	if records := resp.Data.([]map[string]interface{}); len(records) != 1 || records[0]["command"] != "ping" {
+		t.Errorf("command filter returned %v", records)
+	}
+
+	resp = d.handleConnectionAudit(socket.Request{Command: "connection_audit", Args: map[string]interface{}{"last": float64(2)}})
+	if records := resp.Data.([]map[string]interface{}); len(records) != 2 {
+		t.Errorf("last=2 returned %d records", len(records))
+	}
+}
+
+func TestConnectionAuditKeepsOnlyEnvNames(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	d.handleRequest(socket.Request{
+		Command: "set_agent_env",
+		Args: map[string]interface{}{
+			"repo":  "missing",
+			"agent": "clever-fox",
+			"env":   map[string]interface{}{"DATABASE_URL": "postgres://user:pw@db"},
+		},
+	})
+
+	resp := d.handleConnectionAudit(socket.Request{Command: "connection_audit", Args: map[string]interface{}{}})
+	records, ok := resp.Data.([]map[string]interface{})
+	if !resp.Success || !ok || len(records) != 1 {
+		t.Fatalf("handleConnectionAudit() = %+v", resp)
+	}
+	env, _ := records[0]["args"].(map[string]interface{})["env"].(map[string]interface{})
+	if _, ok := env["DATABASE_URL"]; !ok || env["DATABASE_URL"] == "postgres://user:pw@db" {
+		t.Errorf("recorded env = %v, want the name with its value redacted", env)
+	}
+}
@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// handleSetContextVar sets or, given an empty value, removes a repository
+// context variable. Prompt files written from then on list it; running
+// agents are not told.
+func (d *Daemon) handleSetContextVar(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	key, errResp, ok := getRequiredStringArg(req.Args, "key", "context key is required")
+	if !ok {
+		return errResp
+	}
+	value, _ := req.Args["value"].(string)
+
+	if err := d.state.SetContextVar(repoName, key, value); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	if value == "" {
+		d.logger.Info("Removed context variable %s of repo %s", key, repoName)
+	} else {
+		d.logger.Info("Set context variable %s of repo %s", key, repoName)
+	}
+	return socket.Response{Success: true}
+}
+
+// handleGetContextVars returns a repository's context variables
+func (d *Daemon) handleGetContextVars(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	vars, err := d.state.GetContextVars(repoName)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	return socket.Response{Success: true, Data: vars}
+}
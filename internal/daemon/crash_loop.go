@@ -0,0 +1,123 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// crashLoopBackoffBase is the wait after an agent's first automatic restart
+// before it is restarted again; it doubles with each further restart within
+// the crash-loop window, up to crashLoopBackoffMax
+const (
+	crashLoopBackoffBase = 15 * time.Second
+	crashLoopBackoffMax  = 2 * time.Minute
+)
+
+// quarantineOutputLines is how much of a quarantined agent's pane is kept
+// and sent to the supervisor
+const quarantineOutputLines = 30
+
+// crashLoopBackoff returns how long to wait after the last of attempts
+// restarts before restarting again
+func crashLoopBackoff(attempts int) time.Duration {
+	if attempts <= 0 {
+		return 0
+	}
+	backoff := crashLoopBackoffBase
+	for i := 1; i < attempts && backoff < crashLoopBackoffMax; i++ {
+		backoff *= 2
+	}
+	return min(backoff, crashLoopBackoffMax)
+}
+
+// autoRestartAgent restarts a persistent agent whose process died, unless
+// it keeps dying: restarts back off exponentially, and an agent that has
+// already been restarted the repository's crash-loop limit of times within
+// its window is quarantined instead, so a broken claude binary does not
+// thrash tmux and the logs forever.
+func (d *Daemon) autoRestartAgent(repoName, agentName string, agent state.Agent, repo *state.Repository, now time.Time) {
+	if agent.Quarantined() {
+		d.logger.Debug("Agent %s/%s is quarantined, not restarting it", repoName, agentName)
+		return
+	}
+
+	window := repo.CrashLoopWindowDuration()
+	var recent []time.Time
+	for _, attempt := range agent.RestartAttempts {
+		if now.Sub(attempt) < window {
+			recent = append(recent, attempt)
+		}
+	}
+	if len(recent) >= repo.CrashLoopMaxRestarts() {
+		d.quarantineAgent(repoName, agentName, agent, repo, len(recent), window, now)
+		return
+	}
+	if len(recent) > 0 {
+		if wait := crashLoopBackoff(len(recent)) - now.Sub(recent[len(recent)-1]); wait > 0 {
+			d.logger.Info("Agent %s/%s died again; backing off %s before restarting it", repoName, agentName, wait.Round(time.Second))
+			return
+		}
+	}
+
+	if _, err := d.state.RecordRestartAttempt(repoName, agentName, now, window); err != nil {
+		d.logger.Warn("Failed to record restart of %s/%s: %v", repoName, agentName, err)
+	}
+	d.logger.Info("Attempting to auto-restart agent %s (attempt %d in %s)", agentName, len(recent)+1, window)
+	if err := d.restartAgent(repoName, agentName, agent, repo); err != nil {
+		d.logger.Error("Failed to restart agent %s: %v", agentName, err)
+		return
+	}
+	d.logger.Info("Successfully restarted agent %s", agentName)
+}
+
+// quarantineAgent stops restarting an agent that keeps dying and tells the
+// supervisor, with the end of the agent's pane, so a human can fix the cause
+func (d *Daemon) quarantineAgent(repoName, agentName string, agent state.Agent, repo *state.Repository, restarts int, window time.Duration, now time.Time) {
+	output, err := d.tmux.CapturePane(d.ctx, repo.TmuxSession, agent.WindowTarget(), quarantineOutputLines)
+	if err != nil {
+		d.logger.Debug("Failed to capture pane of %s/%s: %v", repoName, agentName, err)
+		output = ""
+	}
+	// The pane may show the values of the repository's env files
+	output = d.redactSecrets(repoName, output)
+	if err := d.state.QuarantineAgent(repoName, agentName, now, output); err != nil {
+		d.logger.Warn("Failed to quarantine %s/%s: %v", repoName, agentName, err)
+		return
+	}
+	d.logger.Error("Quarantined agent %s/%s: it died %d times within %s; not restarting it until 'multiclaude agent restart %s --clear-quarantine'",
+		repoName, agentName, restarts+1, window, agentName)
+
+	// A quarantined supervisor has only the log and daemon status to report it
+	if _, hasSupervisor := repo.Agents["supervisor"]; !hasSupervisor || agentName == "supervisor" {
+		return
+	}
+	notice := fmt.Sprintf("Agent %s keeps crashing: it died %d times within %s, so the daemon stopped restarting it. "+
+		"Once the cause is fixed (e.g. a broken claude binary), restart it with: multiclaude agent restart %s --clear-quarantine",
+		agentName, restarts+1, window, agentName)
+	if output != "" {
+		notice += "\n\nLast output of its pane:\n" + output
+	} else {
+		notice += "\n\nIts pane output could not be captured."
+	}
+	if _, err := d.getMessageManager().Send(repoName, "daemon", "supervisor", notice); err != nil {
+		d.logger.Warn("Failed to tell the supervisor of %s about quarantined agent %s: %v", repoName, agentName, err)
+	}
+}
+
+// quarantinedAgents lists the quarantined agents as repo/agent
+func (d *Daemon) quarantinedAgents() map[string]interface{} {
+	quarantined := map[string]interface{}{}
+	for repoName, repo := range d.state.GetAllRepos() {
+		for agentName, agent := range repo.Agents {
+			if agent.Quarantined() {
+				quarantined[repoName+"/"+agentName] = map[string]interface{}{
+					"since":    agent.QuarantinedAt.Format(time.RFC3339),
+					"restarts": len(agent.RestartAttempts),
+				}
+			}
+		}
+	}
+	return quarantined
+}
@@ -0,0 +1,200 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/envfile"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestCrashLoopBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 15 * time.Second},
+		{2, 30 * time.Second},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{10, 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := crashLoopBackoff(tt.attempts); got != tt.want {
+			t.Errorf("crashLoopBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestAutoRestartAgentQuarantinesCrashLoop(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	// A claude that exits immediately fails every restart
+	stub := filepath.Join(d.paths.Root, "claude")
+	if err := os.WriteFile(stub, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("Failed to write claude stub: %v", err)
+	}
+	if err := os.MkdirAll(d.paths.RepoDir("test-repo"), 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:        "https://github.com/test/repo",
+		TmuxSession:      "mc-test-repo",
+		ClaudePath:       stub,
+		MinClaudeVersion: "1.0.0",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor"},
+			"workspace":  {Type: state.AgentTypeWorkspace, TmuxWindow: "workspace"},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	start := time.Now()
+	restart := func(after time.Duration) state.Agent {
+		t.Helper()
+		repo, _ := d.state.GetRepo("test-repo")
+		d.autoRestartAgent("test-repo", "workspace", repo.Agents["workspace"], repo, start.Add(after))
+		agent, _ := d.state.GetAgent("test-repo", "workspace")
+		return agent
+	}
+
+	if agent := restart(0); len(agent.RestartAttempts) != 1 {
+		t.Fatalf("RestartAttempts = %d after the first death, want 1", len(agent.RestartAttempts))
+	}
+	if agent := restart(5 * time.Second); len(agent.RestartAttempts) != 1 {
+		t.Errorf("RestartAttempts = %d, want the restart within the backoff skipped", len(agent.RestartAttempts))
+	}
+
+	// Each further restart waits twice as long, until the limit of 5
+	elapsed := time.Duration(0)
+	for attempts := 1; attempts < 5; attempts++ {
+		elapsed += crashLoopBackoff(attempts)
+		if agent := restart(elapsed); len(agent.RestartAttempts) != attempts+1 {
+			t.Fatalf("RestartAttempts = %d, want %d", len(agent.RestartAttempts), attempts+1)
+		}
+	}
+	if agent, _ := d.state.GetAgent("test-repo", "workspace"); agent.Quarantined() {
+		t.Fatal("agent quarantined before it used up its restarts")
+	}
+
+	elapsed += crashLoopBackoff(5)
+	agent := restart(elapsed)
+	if !agent.Quarantined() {
+		t.Fatal("agent should be quarantined after 5 restarts within the window")
+	}
+	msgs, err := d.getMessageManager().List("test-repo", "supervisor")
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("supervisor got %d messages (%v), want 1", len(msgs), err)
+	}
+	if !strings.Contains(msgs[0].Body, "--clear-quarantine") {
+		t.Errorf("notice should say how to lift the quarantine: %q", msgs[0].Body)
+	}
+
+	// A quarantined agent is left alone
+	if agent := restart(elapsed + time.Hour); len(agent.RestartAttempts) != 5 {
+		t.Errorf("RestartAttempts = %d, want a quarantined agent not restarted", len(agent.RestartAttempts))
+	}
+
+	resp := d.dispatchRequest(socket.Request{Command: "status"})
+	quarantined, _ := resp.Data.(map[string]interface{})["quarantined"].(map[string]interface{})
+	if _, ok := quarantined["test-repo/workspace"]; !ok {
+		t.Errorf("status quarantined = %v, want test-repo/workspace", quarantined)
+	}
+	resp = d.dispatchRequest(socket.Request{Command: "list_agents", Args: map[string]interface{}{"repo": "test-repo", "rich": true}})
+	for _, a := range resp.Data.([]map[string]interface{}) {
+		if a["name"] == "workspace" && a["status"] != "quarantined" {
+			t.Errorf("list_agents status = %v, want quarantined", a["status"])
+		}
+	}
+
+	// restart_agent refuses until the quarantine is cleared explicitly
+	resp = d.dispatchRequest(socket.Request{Command: "restart_agent", Args: map[string]interface{}{
+		"repo": "test-repo", "agent": "workspace",
+	}})
+	if resp.Success || !strings.Contains(resp.Error, "--clear-quarantine") {
+		t.Errorf("restart_agent without clear_quarantine = %+v, want it refused", resp)
+	}
+	d.dispatchRequest(socket.Request{Command: "restart_agent", Args: map[string]interface{}{
+		"repo": "test-repo", "agent": "workspace", "clear_quarantine": true,
+	}})
+	agent, _ = d.state.GetAgent("test-repo", "workspace")
+	if agent.Quarantined() || len(agent.RestartAttempts) != 0 {
+		t.Errorf("after clear_quarantine: quarantined=%v attempts=%d, want cleared", agent.Quarantined(), len(agent.RestartAttempts))
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repoDir := d.paths.RepoDir("test-repo")
+	if err := os.MkdirAll(filepath.Dir(envfile.RepoEnvPath(repoDir)), 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	if err := os.WriteFile(envfile.RepoEnvPath(repoDir), []byte("DB_PASSWORD=s3cret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"workspace": {Type: state.AgentTypeWorkspace, Environment: map[string]string{"API_TOKEN": "hunter2"}},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	got := d.redactSecrets("test-repo", "password s3cret, token hunter2")
+	if got != "password <secret>, token <secret>" {
+		t.Errorf("redactSecrets() = %q, want both values redacted", got)
+	}
+	if got := d.redactSecrets("other-repo", "token hunter2"); got != "token hunter2" {
+		t.Errorf("redactSecrets() of another repo = %q, want it unchanged", got)
+	}
+}
+
+func TestCrashLoopConfig(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      map[string]state.Agent{},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	resp := d.dispatchRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name": "test-repo", "crash_loop_limit": float64(3),
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	resp = d.dispatchRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name": "test-repo", "crash_loop_window": "30m",
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+
+	resp = d.dispatchRequest(socket.Request{Command: "get_repo_config", Args: map[string]interface{}{"name": "test-repo"}})
+	data := resp.Data.(map[string]interface{})
+	if data["crash_loop_limit"] != 3 || data["crash_loop_window"] != "30m0s" {
+		t.Errorf("crash loop config = %v, %v; want 3, 30m0s", data["crash_loop_limit"], data["crash_loop_window"])
+	}
+
+	resp = d.dispatchRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name": "test-repo", "crash_loop_limit": -1,
+	}})
+	if resp.Success {
+		t.Error("a negative crash_loop_limit should be rejected")
+	}
+}
@@ -0,0 +1,510 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
+	"github.com/dlorenc/multiclaude/internal/hooks"
+	"github.com/dlorenc/multiclaude/internal/logfilter"
+	"github.com/dlorenc/multiclaude/internal/names"
+	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/claude"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// repoLocks serializes operations that mutate a repository's clone and tmux
+// session, such as creating workers, so concurrent callers cannot race on
+// git's locks or tmux window indices
+type repoLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks the repository's mutex and returns the function that unlocks it
+func (r *repoLocks) lock(repoName string) func() {
+	r.mu.Lock()
+	if r.locks == nil {
+		r.locks = make(map[string]*sync.Mutex)
+	}
+	l, ok := r.locks[repoName]
+	if !ok {
+		l = &sync.Mutex{}
+		r.locks[repoName] = l
+	}
+	r.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// workerRequest is a create_worker request
+type workerRequest struct {
+	Task           string
+	Name           string   // Generated when empty
+	Branch         string   // Start point; defaults to origin/main, or HEAD without a remote
+	PushTo         string   // Existing PR branch to push to instead of a new work/<name> branch
+	Paths          []string // Directories the task is scoped to; Claude starts in the first
+	Prompt         string   // Composed by the caller; the daemon's own prompt when empty
+	ContextFiles   []workerContextFile
+	OriginWorker   string
+	OriginTask     string
+	AllowDuplicate bool
+	NoSubmodules   bool
+}
+
+// workerContextFile is a file copied into a new worker's worktree as context
+type workerContextFile struct {
+	Name    string
+	Content string
+}
+
+// workerProgress collects the progress lines of a worker creation, which are
+// returned to the caller to print. They are sent with the response, not as
+// each step happens, so callers should say up front that creation may take
+// a while.
+type workerProgress struct {
+	lines []string
+}
+
+func (p *workerProgress) printf(format string, args ...interface{}) {
+	p.lines = append(p.lines, fmt.Sprintf(format, args...))
+}
+
+// handleCreateWorker creates a worker: its worktree and branch, tmux window,
+// prompt and Claude process, then registers it. Worker creations in the same
+// repository run one at a time. Everything created is undone if a step fails.
+func (d *Daemon) handleCreateWorker(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	wr, err := parseWorkerRequest(req.Args)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	unlock := d.repoLocks.lock(repoName)
+	defer unlock()
+
+	progress := &workerProgress{}
+	data, err := d.createWorker(repoName, wr, progress)
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["progress"] = progress.lines
+	if err != nil {
+		d.logger.Warn("Failed to create worker in repo %s: %v", repoName, err)
+		return socket.Response{Success: false, Error: err.Error(), Data: data}
+	}
+	return socket.Response{Success: true, Data: data}
+}
+
+// parseWorkerRequest reads the arguments of a create_worker request
+func parseWorkerRequest(args map[string]interface{}) (workerRequest, error) {
+	var wr workerRequest
+	wr.Task, _ = args["task"].(string)
+	if wr.Task == "" {
+		return wr, fmt.Errorf("task is required")
+	}
+	// The name becomes a worktree directory and a branch, so check it before
+	// anything touches the filesystem or git
+	wr.Name, _ = args["name"].(string)
+	if wr.Name != "" {
+		if reason := names.AgentViolation(wr.Name); reason != "" {
+			return wr, fmt.Errorf("invalid worker name %q: %s", wr.Name, reason)
+		}
+	}
+	wr.Branch, _ = args["branch"].(string)
+	wr.PushTo, _ = args["push_to"].(string)
+	if wr.PushTo != "" && wr.Branch == "" {
+		return wr, fmt.Errorf("push_to requires branch, the remote branch to start from")
+	}
+	wr.Prompt, _ = args["prompt"].(string)
+	wr.OriginWorker, _ = args["origin_worker"].(string)
+	wr.OriginTask, _ = args["origin_task"].(string)
+	wr.AllowDuplicate, _ = args["allow_duplicate"].(bool)
+	wr.NoSubmodules, _ = args["no_submodules"].(bool)
+
+	paths, _ := args["paths"].([]interface{})
+	for _, p := range paths {
+		path, _ := p.(string)
+		clean := filepath.ToSlash(filepath.Clean(path))
+		if path == "" || clean == "." || filepath.IsAbs(path) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return wr, fmt.Errorf("invalid path %q: must be a directory relative to the repository root", path)
+		}
+		wr.Paths = append(wr.Paths, clean)
+	}
+
+	files, _ := args["context_files"].([]interface{})
+	for _, f := range files {
+		file, _ := f.(map[string]interface{})
+		name, _ := file["name"].(string)
+		content, _ := file["content"].(string)
+		if name == "" {
+			return wr, fmt.Errorf("context file without a name")
+		}
+		wr.ContextFiles = append(wr.ContextFiles, workerContextFile{Name: name, Content: content})
+	}
+	return wr, nil
+}
+
+// createWorker does the work of handleCreateWorker with the repository
+// locked. The returned data describes the refusal when a limit, a duplicate
+// or a name in use stops the worker, and the worker when it was created.
+func (d *Daemon) createWorker(repoName string, wr workerRequest, progress *workerProgress) (map[string]interface{}, error) {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return nil, fmt.Errorf("repository '%s' not found in state", repoName)
+	}
+
+	// Refuse early if the repository is at one of its limits
+	workers := 0
+	for _, agent := range repo.Agents {
+		if agent.Type == state.AgentTypeWorker {
+			workers++
+		}
+	}
+	if repo.MaxConcurrentWorkers > 0 && workers >= repo.MaxConcurrentWorkers {
+		return map[string]interface{}{"limit": "workers", "count": workers, "max": repo.MaxConcurrentWorkers},
+			fmt.Errorf("repository '%s' is at its worker limit (%d/%d)", repoName, workers, repo.MaxConcurrentWorkers)
+	}
+	if repo.WorktreeLimit > 0 && workers >= repo.WorktreeLimit {
+		return map[string]interface{}{"limit": "worktrees", "count": workers, "max": repo.WorktreeLimit},
+			fmt.Errorf("repository '%s' is at its worktree limit (%d/%d)", repoName, workers, repo.WorktreeLimit)
+	}
+
+	// Refuse a likely duplicate before creating anything; registration
+	// checks again
+	if !wr.AllowDuplicate {
+		if existing, err := d.state.FindDuplicateWorker(repoName, wr.Task); err == nil && existing != "" {
+			return map[string]interface{}{"duplicate_of": existing},
+				&state.DuplicateTaskError{Repo: repoName, Existing: existing}
+		}
+	}
+
+	workerName := wr.Name
+	if workerName == "" {
+		naming := names.Config{Scheme: names.Scheme(repo.NameScheme), Template: repo.NameTemplate}
+		vars := names.Vars{User: names.CurrentUser(), Date: time.Now(), Task: wr.Task}
+		var err error
+		workerName, err = naming.Generate(vars, func(name string) bool {
+			_, exists := repo.Agents[name]
+			return exists
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate a worker name: %w", err)
+		}
+	}
+	if _, exists := repo.Agents[workerName]; exists {
+		return map[string]interface{}{"agent_exists": workerName},
+			fmt.Errorf("agent '%s' already exists in repository '%s'", workerName, repoName)
+	}
+
+	// Undo everything created below if a later step fails, newest first
+	var undo []func()
+	succeeded := false
+	defer func() {
+		if succeeded {
+			return
+		}
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}()
+
+	repoPath := d.paths.RepoDir(repoName)
+
+	// Fetch so the worker starts from the latest code. "git fetch origin"
+	// rather than "main:main", which fails while main is checked out.
+	progress.printf("Fetching latest from origin...")
+	fetchCmd := exec.Command("git", "fetch", "origin")
+	fetchCmd.Dir = repoPath
+	if _, _, err := cmdrun.Run(fetchCmd); err != nil {
+		progress.printf("Warning: failed to fetch from origin: %v (continuing with local refs)", err)
+	}
+
+	// Prefer origin/main, updated by the fetch, falling back to HEAD for
+	// repositories without a remote
+	startBranch := "HEAD"
+	checkOriginCmd := exec.Command("git", "rev-parse", "--verify", "origin/main")
+	checkOriginCmd.Dir = repoPath
+	if err := checkOriginCmd.Run(); err == nil {
+		startBranch = "origin/main"
+	}
+	if wr.Branch != "" {
+		startBranch = wr.Branch
+		if wr.PushTo != "" {
+			progress.printf("Creating worker '%s' in repo '%s' to iterate on branch '%s'", workerName, repoName, wr.PushTo)
+		} else {
+			progress.printf("Creating worker '%s' in repo '%s' from branch '%s'", workerName, repoName, wr.Branch)
+		}
+	} else {
+		progress.printf("Creating worker '%s' in repo '%s'", workerName, repoName)
+	}
+	progress.printf("Task: %s", wr.Task)
+
+	// A scope that is not in the start point would leave Claude nowhere to
+	// start, so check it before creating anything
+	for _, path := range wr.Paths {
+		if !worktree.IsDirAt(repoPath, startBranch, path) {
+			return map[string]interface{}{"invalid_path": path},
+				fmt.Errorf("path '%s' is not a directory in %s", path, startBranch)
+		}
+	}
+
+	wt := worktree.NewManager(repoPath)
+	wtPath := d.paths.AgentWorktree(repoName, workerName)
+	branchName := "work/" + workerName
+	if wr.PushTo != "" {
+		branchName = wr.PushTo
+		progress.printf("Creating worktree at: %s (checking out %s)", wtPath, startBranch)
+	} else {
+		progress.printf("Creating worktree at: %s", wtPath)
+	}
+	if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
+		return nil, fmt.Errorf("failed to create worktree: %w", err)
+	}
+	undo = append(undo, func() {
+		if err := wt.DeleteBranch(branchName); err != nil {
+			d.logger.Warn("Failed to delete branch %s: %v", branchName, err)
+		}
+	}, func() {
+		if err := wt.Remove(wtPath, true); err != nil {
+			d.logger.Warn("Failed to remove worktree %s: %v", wtPath, err)
+		}
+	})
+
+	d.applyGitIdentity(repoName, repo, wtPath)
+
+	if !wr.NoSubmodules && worktree.HasSubmodules(wtPath) {
+		progress.printf("Checking out submodules (output in %s)...", d.paths.AgentLogFile(repoName, workerName, true))
+		if err := d.checkoutWorkerSubmodules(repoName, repo, wtPath, workerName); err != nil {
+			return map[string]interface{}{"submodules": true}, fmt.Errorf("failed to check out git submodules: %w", err)
+		}
+	}
+
+	// Create the session if it is missing, e.g. because it was killed
+	hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check tmux session: %w", err)
+	}
+	if !hasSession {
+		progress.printf("Tmux session '%s' not found, creating it...", repo.TmuxSession)
+		if err := d.tmux.CreateSession(d.ctx, repo.TmuxSession, true); err != nil {
+			return nil, fmt.Errorf("failed to create tmux session: %w", err)
+		}
+		d.tagSession(repo.TmuxSession)
+		undo = append(undo, func() {
+			if err := d.tmux.KillSession(d.ctx, repo.TmuxSession); err != nil {
+				d.logger.Warn("Failed to kill tmux session %s: %v", repo.TmuxSession, err)
+			}
+		})
+	}
+
+	workDir := wtPath
+	if len(wr.Paths) > 0 {
+		workDir = filepath.Join(wtPath, wr.Paths[0])
+		progress.printf("Scoped to: %s", strings.Join(wr.Paths, ", "))
+	}
+
+	progress.printf("Creating tmux window: %s", workerName)
+	cmd := exec.Command("tmux", "new-window", "-d", "-t", repo.TmuxSession, "-n", workerName, "-c", workDir)
+	if _, _, err := cmdrun.Run(cmd); err != nil {
+		return nil, fmt.Errorf("failed to create tmux window: %w", err)
+	}
+	undo = append(undo, func() {
+		if err := d.tmux.KillWindow(d.ctx, repo.TmuxSession, workerName); err != nil {
+			d.logger.Warn("Failed to kill tmux window %s: %v", workerName, err)
+		}
+	})
+
+	// Removing the worktree on failure removes the context files too
+	var contextPaths []string
+	for _, f := range wr.ContextFiles {
+		path, err := worktree.WriteContextFile(wtPath, f.Name, []byte(f.Content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy context files: %w", err)
+		}
+		progress.printf("Copied context file: %s", path)
+		contextPaths = append(contextPaths, path)
+	}
+
+	sessionID, err := claude.GenerateSessionID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate worker session ID: %w", err)
+	}
+
+	promptFile, err := d.writeWorkerPromptFile(repoName, workerName, wr.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	undo = append(undo, func() { os.Remove(promptFile) })
+
+	if err := hooks.CopyConfig(repoPath, wtPath); err != nil {
+		progress.printf("Warning: failed to copy hooks config: %v", err)
+	}
+
+	// Start Claude with the task (skipped in test mode)
+	var pid int
+	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+		progress.printf("Starting Claude Code in worker window...")
+		pid, err = d.startWorkerClaude(repoName, repo, workerName, sessionID, promptFile, workerInitialMessage(wr, contextPaths))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	agent := state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: wtPath,
+		TmuxWindow:   workerName,
+		TmuxWindowID: d.windowID(repo.TmuxSession, workerName),
+		SessionID:    sessionID,
+		PID:          pid,
+		Task:         wr.Task,
+		OriginWorker: wr.OriginWorker,
+		ContextFiles: contextPaths,
+		Paths:        wr.Paths,
+		PromptHash:   hashPromptFile(promptFile),
+		DocsHash:     promptDocsHash(promptFile),
+		CreatedAt:    time.Now(),
+	}
+	addAgent := d.state.AddAgentUnlessDuplicate
+	if wr.AllowDuplicate {
+		addAgent = d.state.AddAgent
+	}
+	if err := addAgent(repoName, workerName, agent); err != nil {
+		var dupErr *state.DuplicateTaskError
+		if errors.As(err, &dupErr) {
+			return map[string]interface{}{"duplicate_of": dupErr.Existing}, err
+		}
+		return nil, fmt.Errorf("failed to register worker: %w", err)
+	}
+	succeeded = true
+
+	d.logger.Info("Created worker %s in repo %s", workerName, repoName)
+	event := agentEvent(hooks.EventWorkerCreated, repoName, workerName, agent)
+	event.Branch = branchName
+	d.fireLifecycleEvent(event)
+	return map[string]interface{}{
+		"name":          workerName,
+		"branch":        branchName,
+		"worktree_path": wtPath,
+		"tmux_session":  repo.TmuxSession,
+	}, nil
+}
+
+// workerInitialMessage is the message a new worker's Claude starts with: its
+// task, where it was split from, and where its context files are
+func workerInitialMessage(wr workerRequest, contextPaths []string) string {
+	message := fmt.Sprintf("Task: %s", wr.Task)
+	if wr.OriginWorker != "" {
+		message += fmt.Sprintf("\n\nThis task was split from worker '%s', whose task was: %s\nYour branch starts from its latest commit.", wr.OriginWorker, wr.OriginTask)
+	}
+	if len(contextPaths) > 0 {
+		message += "\n\nContext for this task is in these files in your worktree (read them before starting; they are git-ignored, so they stay off your branch):"
+		for _, path := range contextPaths {
+			message += "\n- " + path
+		}
+	}
+	return message
+}
+
+// checkoutWorkerSubmodules checks out a new worker's submodules like
+// updateSubmodules, with git's output going to the worker's log
+func (d *Daemon) checkoutWorkerSubmodules(repoName string, repo *state.Repository, wtPath, workerName string) error {
+	logFile := d.paths.AgentLogFile(repoName, workerName, true)
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open agent log: %w", err)
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithTimeout(d.ctx, repo.SubmoduleTimeoutDuration())
+	defer cancel()
+	return worktree.UpdateSubmodules(ctx, wtPath, f)
+}
+
+// writeWorkerPromptFile writes a worker's prompt file. The caller composes
+// the prompt, since only the CLI has the CLI reference agents are given;
+// without one the daemon's own worker prompt is used.
+func (d *Daemon) writeWorkerPromptFile(repoName, workerName, promptText string) (string, error) {
+	if strings.TrimSpace(promptText) == "" {
+		return d.writePromptFile(repoName, prompts.TypeWorker, workerName)
+	}
+
+	promptDir := filepath.Join(d.paths.Root, "prompts")
+	if err := os.MkdirAll(promptDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create prompt directory: %w", err)
+	}
+	promptPath := filepath.Join(promptDir, workerName+".md")
+	if err := os.WriteFile(promptPath, []byte(promptText), 0644); err != nil {
+		return "", fmt.Errorf("failed to write prompt file: %w", err)
+	}
+	return promptPath, nil
+}
+
+// startWorkerClaude starts Claude in a new worker's window with its task as
+// the initial message, capturing its output to the worker's log, and returns
+// its PID
+func (d *Daemon) startWorkerClaude(repoName string, repo *state.Repository, workerName, sessionID, promptFile, initialMessage string) (int, error) {
+	binaryPath, err := d.getClaudeBinaryPath(repo)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve claude binary: %w", err)
+	}
+	if err := d.checkClaudeVersion(repo, binaryPath); err != nil {
+		return 0, err
+	}
+
+	claudeCmd := fmt.Sprintf("%s --session-id %s --dangerously-skip-permissions --append-system-prompt-file %s",
+		binaryPath, sessionID, promptFile)
+	if envKeys := d.applyRepoEnv(repoName, repo.TmuxSession); len(envKeys) > 0 {
+		claudeCmd = tmux.ImportEnvironmentCommand(envKeys...) + " && " + claudeCmd
+	}
+
+	target := fmt.Sprintf("%s:%s", repo.TmuxSession, workerName)
+	cmd := exec.Command("tmux", "send-keys", "-t", target, claudeCmd, "C-m")
+	if _, _, err := cmdrun.Run(cmd); err != nil {
+		return 0, fmt.Errorf("failed to start Claude in tmux: %w", err)
+	}
+
+	// Wait a moment for Claude to start
+	time.Sleep(500 * time.Millisecond)
+
+	pid, err := d.tmux.GetPanePID(d.ctx, repo.TmuxSession, workerName)
+	if err != nil {
+		// Non-fatal - the health check works without a PID
+		d.logger.Warn("Failed to get PID of worker %s: %v", workerName, err)
+	}
+
+	logFile := d.paths.AgentLogFile(repoName, workerName, true)
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		d.logger.Warn("Failed to create output directory for worker %s: %v", workerName, err)
+	} else if command, err := logfilter.PipeCommand(logFile, repo.RawLogs); err != nil {
+		d.logger.Warn("Failed to set up output capture for worker %s: %v", workerName, err)
+	} else if err := d.tmux.StartPipePaneCommand(d.ctx, repo.TmuxSession, workerName, command); err != nil {
+		d.logger.Warn("Failed to set up output capture for worker %s: %v", workerName, err)
+	}
+
+	// Wait a bit more for Claude to initialize before sending the task
+	time.Sleep(1 * time.Second)
+	if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, workerName, initialMessage); err != nil {
+		return 0, fmt.Errorf("failed to send task to worker: %w", err)
+	}
+	return pid, nil
+}
@@ -2,20 +2,29 @@ package daemon
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"time"
 
+	"github.com/dlorenc/multiclaude/internal/archive"
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
+	"github.com/dlorenc/multiclaude/internal/envfile"
 	"github.com/dlorenc/multiclaude/internal/hooks"
 	"github.com/dlorenc/multiclaude/internal/logging"
 	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/procs"
 	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/redact"
+	"github.com/dlorenc/multiclaude/internal/repoconfig"
 	"github.com/dlorenc/multiclaude/internal/socket"
 	"github.com/dlorenc/multiclaude/internal/state"
 	"github.com/dlorenc/multiclaude/internal/worktree"
@@ -34,6 +43,71 @@ type Daemon struct {
 	pidFile      *PIDFile
 	claudeRunner *claude.Runner
 
+	transports   map[string]DeliveryTransport
+	transportsMu sync.RWMutex
+
+	auditLog       *audit.Logger
+	recentRequests *requestRing
+
+	// repoLocks serializes worker creation per repository
+	repoLocks repoLocks
+
+	// repoMoves records repositories GitHub reports under a new name
+	repoMoves          map[string]string
+	repoMovesMu        sync.Mutex
+	lookupRepoFullName func(ctx context.Context, owner, name string) (string, error)
+
+	// lookupPRState reports a PR's GitHub state, for pruning merge-queue records
+	lookupPRState func(ctx context.Context, owner, name string, number int) (string, error)
+	// lookupPRComments counts the comments and reviews on a PR, for wake
+	// nudges, the way agent pr-comments does
+	lookupPRComments func(ctx context.Context, owner, name string, number int) (int, error)
+	// ghUnavailable records the gh features turned off per repository
+	// because gh is not installed or not logged in
+	ghUnavailable   map[string]*ghRepoStatus
+	ghUnavailableMu sync.Mutex
+
+	// foreignSessions are the tmux sessions of other multiclaude roots on
+	// the same server, by root, and unknownSessions the untagged mc-*
+	// sessions no root tracks, as of the last health check
+	foreignSessions map[string][]string
+	unknownSessions []string
+	sessionsMu      sync.Mutex
+
+	// claudeBinary is the claude in PATH when the daemon started, and
+	// claudeBinaryChange a different binary found there since
+	claudeBinary       *claude.BinaryInfo
+	claudeBinaryChange *claudeBinaryChange
+	claudeBinaryMu     sync.Mutex
+	inspectClaude      func(ctx context.Context) (claude.BinaryInfo, error)
+
+	// currentDocsHash returns the hash of the CLI reference the daemon's
+	// binary puts in a repository's prompts for an agent type, or "" if it
+	// cannot tell; nil when the daemon was not started by the CLI.
+	// staleDocs lists the agents registered with another reference, and
+	// staleDocsNotified the reference each was reported to the supervisor for.
+	currentDocsHash   func(repoName string, agentType state.AgentType) string
+	staleDocs         []string
+	staleDocsNotified map[string]string
+	staleDocsMu       sync.Mutex
+
+	// lastUpstreamSync records when each repository's primary clone was
+	// last synced with upstream; upstreamSyncMu also serializes syncs
+	lastUpstreamSync map[string]time.Time
+	upstreamSyncMu   sync.Mutex
+
+	// paused is set while a state restore swaps the state file
+	paused atomic.Bool
+
+	// The periodic loops, run by scheduler
+	scheduler *scheduler
+	router    *Router
+	waker     *Waker
+	health    *HealthChecker
+	rotator   *Rotator
+	// fsWatcher is nil unless the daemon was started with --watch-fs
+	fsWatcher *FSWatcher
+
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
@@ -62,22 +136,122 @@ func New(paths *config.Paths) (*Daemon, error) {
 
 	tmuxClient := tmux.NewClient()
 	d := &Daemon{
-		paths:        paths,
-		state:        st,
-		tmux:         tmuxClient,
-		logger:       logger,
-		pidFile:      NewPIDFile(paths.DaemonPID),
-		claudeRunner: claude.NewRunner(claude.WithTerminal(tmuxClient)),
-		ctx:          ctx,
-		cancel:       cancel,
-	}
+		paths:              paths,
+		state:              st,
+		tmux:               tmuxClient,
+		logger:             logger,
+		pidFile:            NewPIDFile(paths.DaemonPID),
+		claudeRunner:       claude.NewRunner(claude.WithTerminal(tmuxClient)),
+		auditLog:           audit.NewLogger(paths.AuditLog(), audit.DefaultBufferSize),
+		recentRequests:     newRequestRing(connectionAuditSize),
+		repoMoves:          make(map[string]string),
+		lookupRepoFullName: ghRepoFullName,
+		lookupPRState:      ghPRState,
+		lookupPRComments:   ghPRCommentCount,
+		ghUnavailable:      make(map[string]*ghRepoStatus),
+		lastUpstreamSync:   make(map[string]time.Time),
+		inspectClaude:      inspectPathClaude,
+		ctx:                ctx,
+		cancel:             cancel,
+	}
+
+	// Register built-in message delivery transports
+	d.transports = make(map[string]DeliveryTransport)
+	d.RegisterTransport(&tmuxTransport{ctx: ctx, tmux: tmuxClient, state: st})
+	d.RegisterTransport(&inboxTransport{})
 
 	// Create socket server
 	d.server = socket.NewServer(paths.DaemonSock, socket.HandlerFunc(d.handleRequest))
 
+	d.addLoops()
+
 	return d, nil
 }
 
+// addLoops creates the daemon's periodic loops and hands them to its
+// scheduler
+func (d *Daemon) addLoops() {
+	d.router = &Router{
+		store:        d.state,
+		messages:     d.getMessageManager,
+		transportFor: d.transportFor,
+		logger:       d.logger,
+	}
+	d.waker = &Waker{
+		store:  d.state,
+		tmux:   d.tmux,
+		deltas: d.nudgeDeltas,
+		logger: d.logger,
+	}
+	d.health = &HealthChecker{
+		store:           d.state,
+		tmux:            d.tmux,
+		agents:          d,
+		foreignSessions: d.surveySessions,
+		housekeeping: []func(){
+			d.cleanupMergedBranches,
+			d.pruneArchives,
+			d.pruneSnapshots,
+			d.checkRepoMoves,
+			d.pruneTrackedPRs,
+			d.refreshSubmodules,
+			d.autoAckMessages,
+			d.auditPromptFiles,
+			d.checkStaleDocs,
+		},
+		logger: d.logger,
+	}
+	d.rotator = &Rotator{paths: d.paths, logger: d.logger}
+
+	d.scheduler = newScheduler(d.logger, d.paused.Load)
+	d.scheduler.Add(d.health)
+	d.scheduler.Add(d.rotator)
+	d.scheduler.Add(d.router)
+	d.scheduler.Add(d.waker)
+	d.scheduler.Add(loopFunc{
+		name: "worktree refresh",
+		// The first refresh waits a little so startup is not slowed by fetches
+		schedule: LoopSchedule{Every: 5 * time.Minute, RunAtStart: true, Delay: 30 * time.Second},
+		tick:     func(context.Context) error { d.refreshWorktrees(); return nil },
+	})
+	// Inspecting the binary runs it, so the first inspection, which records
+	// the baseline later ones compare against, stays off the startup path
+	recorded := false
+	d.scheduler.Add(loopFunc{
+		name:     "claude binary",
+		schedule: LoopSchedule{Every: claudeBinaryCheckInterval, RunAtStart: true},
+		tick: func(context.Context) error {
+			if !recorded {
+				recorded = true
+				d.recordClaudeBinary()
+				return nil
+			}
+			d.checkClaudeBinary()
+			return nil
+		},
+	})
+	d.scheduler.Add(loopFunc{
+		name:     "PR watch",
+		schedule: LoopSchedule{Every: prWatchInterval},
+		tick:     func(context.Context) error { d.checkWatchedPRs(); return nil },
+	})
+	d.scheduler.Add(loopFunc{
+		name:     "upgrade check",
+		schedule: LoopSchedule{Every: upgradeCheckTick},
+		tick: func(context.Context) error {
+			if d.upgradeCheckDue(time.Now()) {
+				d.checkForUpgrade()
+			}
+			return nil
+		},
+	})
+	d.scheduler.Add(loopFunc{
+		name:     "upstream sync",
+		schedule: LoopSchedule{Every: upstreamSyncTick},
+		tick:     func(context.Context) error { d.autoSyncRepos(time.Now()); return nil },
+	})
+}
+
 // Start starts the daemon
 func (d *Daemon) Start() error {
 	d.logger.Info("Starting daemon")
@@ -101,12 +275,9 @@ func (d *Daemon) Start() error {
 	d.restoreTrackedRepos()
 
 	// Start core loops after restore completes
-	d.wg.Add(5)
-	go d.healthCheckLoop()
-	go d.messageRouterLoop()
-	go d.wakeLoop()
+	d.wg.Add(1)
 	go d.serverLoop()
-	go d.worktreeRefreshLoop()
+	d.scheduler.Start(d.ctx, &d.wg)
 
 	return nil
 }
@@ -131,6 +302,26 @@ func (d *Daemon) TriggerHealthCheck() {
 	d.checkAgentHealth()
 }
 
+// checkAgentHealth checks if agents are still alive (see HealthChecker)
+func (d *Daemon) checkAgentHealth() {
+	d.health.Check(d.ctx)
+}
+
+// routeMessages delivers pending messages now (see Router)
+func (d *Daemon) routeMessages() {
+	d.router.Tick(d.ctx)
+}
+
+// wakeAgents nudges agents with news now (see Waker)
+func (d *Daemon) wakeAgents() {
+	d.waker.Tick(d.ctx)
+}
+
+// rotateLogsIfNeeded rotates logs over MaxLogFileSize now (see Rotator)
+func (d *Daemon) rotateLogsIfNeeded() {
+	d.rotator.Tick(d.ctx)
+}
+
 // TriggerMessageRouting triggers an immediate message routing (for testing)
 func (d *Daemon) TriggerMessageRouting() {
 	d.routeMessages()
@@ -156,11 +347,20 @@ func (d *Daemon) Stop() error {
 		d.logger.Error("Failed to stop socket server: %v", err)
 	}
 
-	// Save state
-	if err := d.state.Save(); err != nil {
+	// Save state, unless paused: a restore may have swapped the state file
+	// under the daemon
+	if d.paused.Load() {
+		d.logger.Warn("Stopping while paused, leaving the state file as it is")
+	} else if err := d.state.Save(); err != nil {
 		d.logger.Error("Failed to save state: %v", err)
 	}
 
+	// Flush queued audit entries
+	d.auditLog.Close()
+	if dropped := d.auditLog.Dropped(); dropped > 0 {
+		d.logger.Warn("Dropped %d audit log entries", dropped)
+	}
+
 	// Remove PID file
 	if err := d.pidFile.Remove(); err != nil {
 		d.logger.Error("Failed to remove PID file: %v", err)
@@ -183,6 +383,18 @@ func getRequiredStringArg(args map[string]interface{}, key, description string)
 	return val, socket.Response{}, true
 }
 
+// intArg extracts an optional integer argument, which arrives as a float64
+// over the socket or as an int when handlers are called directly
+func intArg(args map[string]interface{}, key string) (int, bool) {
+	switch v := args[key].(type) {
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	}
+	return 0, false
+}
+
 // serverLoop handles socket connections
 func (d *Daemon) serverLoop() {
 	defer d.wg.Done()
@@ -204,192 +416,23 @@ func (d *Daemon) serverLoop() {
 	}
 }
 
-// healthCheckLoop periodically checks agent health
-func (d *Daemon) healthCheckLoop() {
-	defer d.wg.Done()
-	d.logger.Info("Starting health check loop")
-
-	ticker := time.NewTicker(2 * time.Minute)
-	defer ticker.Stop()
-
-	// Run once immediately on startup
-	d.checkAgentHealth()
-	d.rotateLogsIfNeeded()
-	d.cleanupMergedBranches()
-
-	for {
-		select {
-		case <-ticker.C:
-			d.checkAgentHealth()
-			d.rotateLogsIfNeeded()
-			d.cleanupMergedBranches()
-		case <-d.ctx.Done():
-			d.logger.Info("Health check loop stopped")
-			return
-		}
-	}
-}
-
-// checkAgentHealth checks if agents are still alive
-func (d *Daemon) checkAgentHealth() {
-	d.logger.Debug("Checking agent health")
-
-	deadAgents := make(map[string][]string) // repo -> []agent names
-
-	// Get a snapshot of repos to avoid concurrent map access
-	repos := d.state.GetAllRepos()
-	for repoName, repo := range repos {
-		// Check if tmux session exists
-		hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession)
-		if err != nil {
-			d.logger.Error("Failed to check session %s: %v", repo.TmuxSession, err)
-			continue
-		}
-
-		if !hasSession {
-			d.logger.Warn("Tmux session %s not found for repo %s, attempting restoration", repo.TmuxSession, repoName)
-			// Try to restore the session and agents instead of cleaning up
-			if err := d.restoreRepoAgents(repoName, repo); err != nil {
-				d.logger.Error("Failed to restore repo %s: %v, marking all agents for cleanup", repoName, err)
-				// Only mark for cleanup if restoration failed
-				for agentName := range repo.Agents {
-					if deadAgents[repoName] == nil {
-						deadAgents[repoName] = []string{}
-					}
-					deadAgents[repoName] = append(deadAgents[repoName], agentName)
-				}
-			} else {
-				d.logger.Info("Successfully restored tmux session and agents for repo %s", repoName)
-			}
+// autoAckMessages acknowledges messages that agents read but never acked,
+// in repositories with an auto-ack period
+func (d *Daemon) autoAckMessages() {
+	msgMgr := d.getMessageManager()
+	now := time.Now()
+	for repoName, repo := range d.state.GetAllRepos() {
+		after := repo.AutoAckAfterDuration()
+		if after == 0 {
 			continue
 		}
-
-		// Check each agent
-		for agentName, agent := range repo.Agents {
-			// Check if agent is marked as ready for cleanup
-			if agent.ReadyForCleanup {
-				d.logger.Info("Agent %s is ready for cleanup", agentName)
-				if deadAgents[repoName] == nil {
-					deadAgents[repoName] = []string{}
-				}
-				deadAgents[repoName] = append(deadAgents[repoName], agentName)
-				continue
-			}
-
-			// Check if window exists
-			hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
+		for agentName := range repo.Agents {
+			acked, err := msgMgr.AutoAck(repoName, agentName, after, now)
 			if err != nil {
-				d.logger.Error("Failed to check window %s: %v", agent.TmuxWindow, err)
-				continue
-			}
-
-			if !hasWindow {
-				d.logger.Warn("Agent %s window not found, marking for cleanup", agentName)
-				if deadAgents[repoName] == nil {
-					deadAgents[repoName] = []string{}
-				}
-				deadAgents[repoName] = append(deadAgents[repoName], agentName)
-				continue
+				d.logger.Error("Failed to auto-ack messages for %s/%s: %v", repoName, agentName, err)
 			}
-
-			// Check if process is alive (if we have a PID)
-			if agent.PID > 0 {
-				if !isProcessAlive(agent.PID) {
-					d.logger.Warn("Agent %s process (PID %d) not running", agentName, agent.PID)
-
-					// For persistent agents (supervisor, merge-queue, workspace), attempt auto-restart
-					if agent.Type == state.AgentTypeSupervisor || agent.Type == state.AgentTypeMergeQueue || agent.Type == state.AgentTypeWorkspace {
-						d.logger.Info("Attempting to auto-restart agent %s", agentName)
-						if err := d.restartAgent(repoName, agentName, agent, repo); err != nil {
-							d.logger.Error("Failed to restart agent %s: %v", agentName, err)
-						} else {
-							d.logger.Info("Successfully restarted agent %s", agentName)
-						}
-					}
-					// For transient agents (workers, review), don't auto-restart - they complete and clean up
-				}
-			}
-		}
-	}
-
-	// Clean up dead agents
-	if len(deadAgents) > 0 {
-		d.cleanupDeadAgents(deadAgents)
-	}
-
-	// Clean up orphaned worktrees
-	d.cleanupOrphanedWorktrees()
-}
-
-// messageRouterLoop watches for new messages and delivers them
-func (d *Daemon) messageRouterLoop() {
-	defer d.wg.Done()
-	d.logger.Info("Starting message router loop")
-
-	ticker := time.NewTicker(2 * time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			d.routeMessages()
-		case <-d.ctx.Done():
-			d.logger.Info("Message router loop stopped")
-			return
-		}
-	}
-}
-
-// routeMessages checks for pending messages and delivers them
-func (d *Daemon) routeMessages() {
-	d.logger.Debug("Routing messages")
-
-	// Get messages manager
-	msgMgr := d.getMessageManager()
-
-	// Get a snapshot of repos to avoid concurrent map access
-	repos := d.state.GetAllRepos()
-
-	// Check each repository
-	for repoName, repo := range repos {
-		// Check each agent for messages
-		for agentName, agent := range repo.Agents {
-			// Skip workspace agent - it should only receive direct user input
-			if agent.Type == state.AgentTypeWorkspace {
-				continue
-			}
-
-			// Get unread messages (pending or delivered but not yet read)
-			unreadMsgs, err := msgMgr.ListUnread(repoName, agentName)
-			if err != nil {
-				d.logger.Error("Failed to list messages for %s/%s: %v", repoName, agentName, err)
-				continue
-			}
-
-			// Deliver each pending message
-			for _, msg := range unreadMsgs {
-				if msg.Status != messages.StatusPending {
-					// Already delivered, skip
-					continue
-				}
-
-				// Format message for delivery
-				messageText := fmt.Sprintf("📨 Message from %s: %s", msg.From, msg.Body)
-
-				// Send via tmux using atomic method to avoid race conditions
-				// where Enter might be lost between separate exec calls (issue #63)
-				if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, agent.TmuxWindow, messageText); err != nil {
-					d.logger.Error("Failed to deliver message %s to %s/%s: %v", msg.ID, repoName, agentName, err)
-					continue
-				}
-
-				// Mark as delivered
-				if err := msgMgr.UpdateStatus(repoName, agentName, msg.ID, messages.StatusDelivered); err != nil {
-					d.logger.Error("Failed to update message %s status: %v", msg.ID, err)
-					continue
-				}
-
-				d.logger.Info("Delivered message %s from %s to %s/%s", msg.ID, msg.From, repoName, agentName)
+			if acked > 0 {
+				d.logger.Info("Auto-acked %d message(s) read over %s ago by %s/%s", acked, after, repoName, agentName)
 			}
 		}
 	}
@@ -400,104 +443,6 @@ func (d *Daemon) getMessageManager() *messages.Manager {
 	return messages.NewManager(d.paths.MessagesDir)
 }
 
-// wakeLoop periodically wakes agents with status checks
-func (d *Daemon) wakeLoop() {
-	defer d.wg.Done()
-	d.logger.Info("Starting wake loop")
-
-	ticker := time.NewTicker(2 * time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			d.wakeAgents()
-		case <-d.ctx.Done():
-			d.logger.Info("Wake loop stopped")
-			return
-		}
-	}
-}
-
-// wakeAgents sends periodic nudges to agents
-func (d *Daemon) wakeAgents() {
-	d.logger.Debug("Waking agents")
-
-	now := time.Now()
-
-	// Get a snapshot of repos to avoid concurrent map access
-	repos := d.state.GetAllRepos()
-	for repoName, repo := range repos {
-		for agentName, agent := range repo.Agents {
-			// Skip workspace agent - it should only receive direct user input
-			if agent.Type == state.AgentTypeWorkspace {
-				continue
-			}
-
-			// Skip if nudged recently (within last 2 minutes)
-			if !agent.LastNudge.IsZero() && now.Sub(agent.LastNudge) < 2*time.Minute {
-				continue
-			}
-
-			// Send wake message based on agent type
-			var message string
-			switch agent.Type {
-			case state.AgentTypeSupervisor:
-				message = "Status check: Review worker progress and check merge queue."
-			case state.AgentTypeMergeQueue:
-				message = "Status check: Review open PRs and check CI status."
-			case state.AgentTypeWorker:
-				message = "Status check: Update on your progress?"
-			case state.AgentTypeReview:
-				message = "Status check: Update on your review progress?"
-			}
-
-			// Send message using atomic method to avoid race conditions (issue #63)
-			if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, agent.TmuxWindow, message); err != nil {
-				d.logger.Error("Failed to send wake message to agent %s: %v", agentName, err)
-				continue
-			}
-
-			// Update last nudge time
-			agent.LastNudge = now
-			if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
-				d.logger.Error("Failed to update agent %s last nudge: %v", agentName, err)
-			}
-
-			d.logger.Debug("Woke agent %s in repo %s", agentName, repoName)
-		}
-	}
-}
-
-// worktreeRefreshLoop periodically syncs worker worktrees with main branch
-func (d *Daemon) worktreeRefreshLoop() {
-	defer d.wg.Done()
-	d.logger.Info("Starting worktree refresh loop")
-
-	// Run every 5 minutes
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	// Run once after a short delay on startup (respecting context cancellation)
-	select {
-	case <-time.After(30 * time.Second):
-		d.refreshWorktrees()
-	case <-d.ctx.Done():
-		d.logger.Info("Worktree refresh loop stopped")
-		return
-	}
-
-	for {
-		select {
-		case <-ticker.C:
-			d.refreshWorktrees()
-		case <-d.ctx.Done():
-			d.logger.Info("Worktree refresh loop stopped")
-			return
-		}
-	}
-}
-
 // refreshWorktrees syncs worker worktrees that are behind main
 func (d *Daemon) refreshWorktrees() {
 	d.logger.Debug("Checking worker worktrees for refresh")
@@ -597,6 +542,21 @@ func (d *Daemon) TriggerWorktreeRefresh() {
 func (d *Daemon) handleRequest(req socket.Request) socket.Response {
 	d.logger.Debug("Handling request: %s", req.Command)
 
+	started := time.Now()
+	resp := d.dispatchRequest(req)
+	d.recordRequest(req, resp, started)
+	if auditedCommands[req.Command] {
+		d.auditRequest(req, resp)
+	}
+	return resp
+}
+
+// dispatchRequest routes a socket request to its handler
+func (d *Daemon) dispatchRequest(req socket.Request) socket.Response {
+	if d.paused.Load() && !allowedWhilePaused[req.Command] {
+		return pausedResponse(req.Command)
+	}
+
 	switch req.Command {
 	case "ping":
 		return socket.Response{Success: true, Data: "pong"}
@@ -623,24 +583,60 @@ func (d *Daemon) handleRequest(req socket.Request) socket.Response {
 	case "add_agent":
 		return d.handleAddAgent(req)
 
+	case "create_worker":
+		return d.handleCreateWorker(req)
+
 	case "remove_agent":
 		return d.handleRemoveAgent(req)
 
+	case "find_duplicate_worker":
+		return d.handleFindDuplicateWorker(req)
+
 	case "list_agents":
 		return d.handleListAgents(req)
 
+	case "get_agent":
+		return d.handleGetAgent(req)
+
 	case "complete_agent":
 		return d.handleCompleteAgent(req)
 
+	case "update_agent_pr":
+		return d.handleUpdateAgentPR(req)
+
+	case "mark_pr_comments_read":
+		return d.handleMarkPRCommentsRead(req)
+
+	case "update_agent_status":
+		return d.handleUpdateAgentStatus(req)
+
+	case "update_agent_task":
+		return d.handleUpdateAgentTask(req)
+
+	case "set_agent_upstream":
+		return d.handleSetAgentUpstream(req)
+
+	case "detect_branch_change":
+		return d.handleDetectBranchChange(req)
+
 	case "restart_agent":
 		return d.handleRestartAgent(req)
 
+	case "set_agent_env":
+		return d.handleSetAgentEnv(req)
+
 	case "trigger_cleanup":
 		return d.handleTriggerCleanup(req)
 
 	case "repair_state":
 		return d.handleRepairState(req)
 
+	case "set_repo_url":
+		return d.handleSetRepoURL(req)
+
+	case "sync_repo":
+		return d.handleSyncRepo(req)
+
 	case "get_repo_config":
 		return d.handleGetRepoConfig(req)
 
@@ -663,6 +659,57 @@ func (d *Daemon) handleRequest(req socket.Request) socket.Response {
 	case "task_history":
 		return d.handleTaskHistory(req)
 
+	case "describe_state":
+		return d.handleDescribeState(req)
+
+	case "connection_audit":
+		return d.handleConnectionAudit(req)
+
+	case "scheduled_messages":
+		return d.handleScheduledMessages(req)
+
+	case "mq_track_pr":
+		return d.handleMQTrackPR(req)
+
+	case "mq_untrack_pr":
+		return d.handleMQUntrackPR(req)
+
+	case "mq_list_prs":
+		return d.handleMQListPRs(req)
+
+	case "set_context_var":
+		return d.handleSetContextVar(req)
+
+	case "get_context_vars":
+		return d.handleGetContextVars(req)
+
+	case "create_group":
+		return d.handleCreateGroup(req)
+
+	case "add_to_group":
+		return d.handleAddToGroup(req)
+
+	case "remove_from_group":
+		return d.handleRemoveFromGroup(req)
+
+	case "delete_group":
+		return d.handleDeleteGroup(req)
+
+	case "list_groups":
+		return d.handleListGroups(req)
+
+	case "set_upgrade_check_interval":
+		return d.handleSetUpgradeCheckInterval(req)
+
+	case "start_profiling":
+		return d.handleStartProfiling(req)
+
+	case "pause":
+		return d.handlePause(req)
+
+	case "resume":
+		return d.handleResume(req)
+
 	default:
 		return socket.Response{
 			Success: false,
@@ -680,14 +727,60 @@ func (d *Daemon) handleStatus(req socket.Request) socket.Response {
 		agentCount += len(agents)
 	}
 
+	// Effective message transport per repo, with any per-agent-type overrides
+	messageTransports := make(map[string]interface{}, len(repos))
+	for name, repo := range d.state.GetAllRepos() {
+		cfg := map[string]string{"default": DefaultTransport}
+		if repo.MessageTransport.Default != "" {
+			cfg["default"] = repo.MessageTransport.Default
+		}
+		for agentType, transport := range repo.MessageTransport.ByAgentType {
+			cfg[string(agentType)] = transport
+		}
+		messageTransports[name] = cfg
+	}
+
+	// Message statistics per repo
+	msgManager := messages.NewManager(d.paths.MessagesDir)
+	messageStats := make(map[string]*messages.MessageSummary, len(repos))
+	for _, repo := range repos {
+		summary, err := msgManager.Summary(repo)
+		if err != nil {
+			d.logger.Warn("Failed to summarize messages for %s: %v", repo, err)
+			continue
+		}
+		messageStats[repo] = summary
+	}
+
+	claudeBinary, claudeChange := d.claudeBinaryStatus()
+
+	upgradeCheckEvery := ""
+	if interval := d.state.GetUpgradeCheckInterval(); interval > 0 {
+		upgradeCheckEvery = interval.String()
+	}
+
 	return socket.Response{
 		Success: true,
 		Data: map[string]interface{}{
-			"running":     true,
-			"pid":         os.Getpid(),
-			"repos":       len(repos),
-			"agents":      agentCount,
-			"socket_path": d.paths.DaemonSock,
+			"running":              true,
+			"pid":                  os.Getpid(),
+			"repos":                len(repos),
+			"agents":               agentCount,
+			"socket_path":          d.paths.DaemonSock,
+			"transports":           d.transportNames(),
+			"message_transports":   messageTransports,
+			"moved_repos":          d.repoMovesSnapshot(),
+			"gh_unavailable":       d.ghUnavailableSnapshot(),
+			"messages":             messageStats,
+			"claude_binary":        claudeBinary,
+			"claude_binary_change": claudeChange,
+			"upgrade_check_every":  upgradeCheckEvery,
+			"prompt_drift":         d.promptDrift(),
+			"stale_docs":           d.staleDocsSnapshot(),
+			"quarantined":          d.quarantinedAgents(),
+			"loops":                d.scheduler.Status(),
+			"sessions":             d.sessionsSnapshot(),
+			"watch_fs":             d.fsWatcher != nil,
 		},
 	}
 }
@@ -732,6 +825,7 @@ func (d *Daemon) handleListRepos(req socket.Request) socket.Response {
 			"total_agents":    totalAgents,
 			"worker_count":    workerCount,
 			"session_healthy": sessionHealthy,
+			"has_submodules":  repo.HasSubmodules,
 		})
 	}
 
@@ -778,11 +872,26 @@ func (d *Daemon) handleAddRepo(req socket.Request) socket.Response {
 		MergeQueueConfig: mqConfig,
 	}
 
+	// Optional worker worktree limit; JSON numbers arrive as float64
+	if v, ok := req.Args["worktree_limit"].(float64); ok {
+		repo.WorktreeLimit = int(v)
+	} else if v, ok := req.Args["worktree_limit"].(int); ok {
+		repo.WorktreeLimit = v
+	}
+	if repo.WorktreeLimit < 0 {
+		return socket.Response{Success: false, Error: fmt.Sprintf("worktree limit must not be negative, got %d", repo.WorktreeLimit)}
+	}
+	repo.HasSubmodules = worktree.HasSubmodules(d.paths.RepoDir(name))
+	if noSupervisor, ok := req.Args["no_supervisor"].(bool); ok {
+		repo.NoSupervisor = noSupervisor
+	}
+
 	if err := d.state.AddRepo(name, repo); err != nil {
 		return socket.Response{Success: false, Error: err.Error()}
 	}
 
 	d.logger.Info("Added repository: %s (merge queue: enabled=%v, track=%s)", name, mqConfig.Enabled, mqConfig.TrackMode)
+	d.fireLifecycleEvent(hooks.Event{Name: hooks.EventRepoInitialized, Repo: name, GithubURL: githubURL})
 	return socket.Response{Success: true}
 }
 
@@ -842,28 +951,104 @@ func (d *Daemon) handleAddAgent(req socket.Request) socket.Response {
 		pid = pidInt
 	}
 
+	// Record the window's ID so it is still reached if renamed
+	var windowID string
+	if repo, exists := d.state.GetRepo(repoName); exists {
+		windowID = d.windowID(repo.TmuxSession, tmuxWindow)
+	}
+
 	agent := state.Agent{
 		Type:         state.AgentType(agentTypeStr),
 		WorktreePath: worktreePath,
 		TmuxWindow:   tmuxWindow,
+		TmuxWindowID: windowID,
 		SessionID:    sessionID,
 		PID:          pid,
+		PromptHash:   hashPromptFile(d.promptFilePath(agentName)),
 		CreatedAt:    time.Now(),
 	}
+	// The CLI stamps the hash of the reference it wrote into the prompt
+	if docsHash, ok := req.Args["docs_hash"].(string); ok {
+		agent.DocsHash = docsHash
+	} else {
+		agent.DocsHash = promptDocsHash(d.promptFilePath(agentName))
+	}
 
 	// Optional task field for workers
 	if task, ok := req.Args["task"].(string); ok {
 		agent.Task = task
 	}
+	if origin, ok := req.Args["origin_worker"].(string); ok {
+		agent.OriginWorker = origin
+	}
+	if files, ok := req.Args["context_files"].([]interface{}); ok {
+		for _, f := range files {
+			if path, ok := f.(string); ok {
+				agent.ContextFiles = append(agent.ContextFiles, path)
+			}
+		}
+	}
+	// An optional "env" map is kept so restarts give the agent the same
+	// variables. No CLI command sends it yet; it is for callers that launch
+	// an agent with its own variables, while `agent set-env` covers agents
+	// that are already running.
+	env, err := envArg(req.Args)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	agent.Environment = env
 
-	if err := d.state.AddAgent(repoName, agentName, agent); err != nil {
+	// Workers are checked for duplicates of a recent worker's task, e.g. from
+	// racing `multiclaude work` invocations, unless the caller allows them
+	addAgent := d.state.AddAgentUnlessDuplicate
+	if allow, _ := req.Args["allow_duplicate"].(bool); allow {
+		addAgent = d.state.AddAgent
+	}
+	if err := addAgent(repoName, agentName, agent); err != nil {
+		var dupErr *state.DuplicateTaskError
+		if errors.As(err, &dupErr) {
+			d.logger.Info("Refused worker %s in repo %s as a possible duplicate of %s", agentName, repoName, dupErr.Existing)
+			return socket.Response{Success: false, Error: err.Error(), Data: map[string]interface{}{"duplicate_of": dupErr.Existing}}
+		}
 		return socket.Response{Success: false, Error: err.Error()}
 	}
 
+	// A supervisor added to a repository initialized without one
+	// (agent add-supervisor) fills the reserved slot
+	if agent.Type == state.AgentTypeSupervisor {
+		if repo, exists := d.state.GetRepo(repoName); exists && repo.NoSupervisor {
+			d.announceSupervisor(repoName)
+		}
+	}
+
 	d.logger.Info("Added agent %s to repo %s", agentName, repoName)
+	if isWorkerType(agent.Type) {
+		d.fireLifecycleEvent(agentEvent(hooks.EventWorkerCreated, repoName, agentName, agent))
+	}
 	return socket.Response{Success: true}
 }
 
+// handleFindDuplicateWorker returns the name of a live worker recently
+// created with the same task, or "" if there is none, so the CLI can refuse
+// a duplicate before creating its worktree
+func (d *Daemon) handleFindDuplicateWorker(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	task, errResp, ok := getRequiredStringArg(req.Args, "task", "task is required")
+	if !ok {
+		return errResp
+	}
+
+	existing, err := d.state.FindDuplicateWorker(repoName, task)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	return socket.Response{Success: true, Data: existing}
+}
+
 // handleRemoveAgent removes an agent
 func (d *Daemon) handleRemoveAgent(req socket.Request) socket.Response {
 	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
@@ -876,14 +1061,34 @@ func (d *Daemon) handleRemoveAgent(req socket.Request) socket.Response {
 		return errResp
 	}
 
+	agent, existed := d.state.GetAgent(repoName, agentName)
 	if err := d.state.RemoveAgent(repoName, agentName); err != nil {
 		return socket.Response{Success: false, Error: err.Error()}
 	}
 
 	d.logger.Info("Removed agent %s from repo %s", agentName, repoName)
+	d.bounceMessages(repoName, agentName)
+	if existed {
+		event := agentEvent(hooks.EventAgentRemoved, repoName, agentName, agent)
+		event.Reason = "removed"
+		d.fireLifecycleEvent(event)
+	}
 	return socket.Response{Success: true}
 }
 
+// bounceMessages tells the senders of messages a removed agent never read
+// that they were not delivered
+func (d *Daemon) bounceMessages(repoName, agentName string) {
+	liveAgents, _ := d.state.ListAgents(repoName)
+	count, err := d.getMessageManager().Bounce(repoName, agentName, liveAgents)
+	if err != nil {
+		d.logger.Warn("Failed to bounce messages for removed agent %s/%s: %v", repoName, agentName, err)
+	}
+	if count > 0 {
+		d.logger.Info("Bounced %d undelivered message(s) for removed agent %s/%s", count, repoName, agentName)
+	}
+}
+
 // handleListAgents lists agents for a repository
 func (d *Daemon) handleListAgents(req socket.Request) socket.Response {
 	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
@@ -902,6 +1107,10 @@ func (d *Daemon) handleListAgents(req socket.Request) socket.Response {
 	// Get repository to check session
 	repo, repoExists := d.state.GetRepo(repoName)
 
+	// Workers' branches are compared with the default branch, looked up
+	// once for all of them
+	baseRef, baseRefKnown := "", false
+
 	// Get full agent details
 	agentDetails := make([]map[string]interface{}, 0, len(agents))
 	for _, agentName := range agents {
@@ -911,51 +1120,47 @@ func (d *Daemon) handleListAgents(req socket.Request) socket.Response {
 		}
 
 		detail := map[string]interface{}{
-			"name":          agentName,
-			"type":          agent.Type,
-			"worktree_path": agent.WorktreePath,
-			"tmux_window":   agent.TmuxWindow,
-			"task":          agent.Task,
-			"created_at":    agent.CreatedAt,
+			"name":           agentName,
+			"type":           agent.Type,
+			"worktree_path":  agent.WorktreePath,
+			"tmux_window":    agent.TmuxWindow,
+			"tmux_window_id": agent.TmuxWindowID,
+			"session_id":     agent.SessionID,
+			"pid":            agent.PID,
+			"task":           agent.Task,
+			"pr_url":         agent.PRURL,
+			"origin_worker":  agent.OriginWorker,
+			"context_files":  agent.ContextFiles,
+			"paths":          agent.Paths,
+			"created_at":     agent.CreatedAt,
+			"custom_status":  agent.Status,
+		}
+		if agent.UpstreamBranch != "" {
+			detail["upstream_branch"] = agent.UpstreamBranch
 		}
 
 		// Add rich status information if requested
 		if rich {
-			// Determine agent status
-			status := "unknown"
-			if agent.ReadyForCleanup {
-				status = "completed"
-			} else if repoExists {
-				// Check if window exists (means agent is running)
-				hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
-				if err == nil && hasWindow {
-					status = "running"
-				} else {
-					status = "stopped"
-				}
-			}
-			detail["status"] = status
-
-			// Get current branch from worktree
-			branch := ""
-			if agent.WorktreePath != "" {
-				if b, err := worktree.GetCurrentBranch(agent.WorktreePath); err == nil {
-					branch = b
-				}
+			var session string
+			if repoExists {
+				session = repo.TmuxSession
 			}
-			detail["branch"] = branch
-
-			// Get message counts
-			msgManager := messages.NewManager(d.paths.MessagesDir)
-			allMsgs, _ := msgManager.List(repoName, agentName)
-			pendingCount := 0
-			for _, msg := range allMsgs {
-				if msg.Status == messages.StatusPending || msg.Status == messages.StatusDelivered {
-					pendingCount++
+			detail["status"] = d.agentStatus(session, agent)
+			detail["branch"] = agentBranch(agent)
+
+			summary := d.agentMessageSummary(repoName, agentName)
+			detail["messages_total"] = summary.TotalMessages
+			detail["messages_pending"] = summary.Unread()
+			detail["messages_undelivered"] = summary.PendingMessages
+			detail["messages_awaiting_ack"] = summary.AwaitingAck()
+			detail["message_stats"] = summary
+
+			if agent.Type == state.AgentTypeWorker && agent.WorktreePath != "" {
+				if !baseRefKnown {
+					baseRef, baseRefKnown = d.mainBaseRef(repoName), true
 				}
+				detail["commits_ahead"] = agentCommitsAhead(agent, baseRef)
 			}
-			detail["messages_total"] = len(allMsgs)
-			detail["messages_pending"] = pendingCount
 		}
 
 		agentDetails = append(agentDetails, detail)
@@ -964,6 +1169,116 @@ func (d *Daemon) handleListAgents(req socket.Request) socket.Response {
 	return socket.Response{Success: true, Data: agentDetails}
 }
 
+// handleGetAgent returns one agent's record, for agent whoami. An agent that
+// is not in state is not an error: the response says it is not registered.
+func (d *Daemon) handleGetAgent(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	repo, repoExists := d.state.GetRepo(repoName)
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !repoExists || !exists {
+		return socket.Response{Success: true, Data: map[string]interface{}{
+			"registered":   false,
+			"repo_tracked": repoExists,
+		}}
+	}
+
+	summary := d.agentMessageSummary(repoName, agentName)
+	return socket.Response{Success: true, Data: map[string]interface{}{
+		"registered":          true,
+		"repo_tracked":        true,
+		"name":                agentName,
+		"type":                agent.Type,
+		"status":              d.agentStatus(repo.TmuxSession, agent),
+		"session_id":          agent.SessionID,
+		"tmux_window":         agent.TmuxWindow,
+		"messages_pending":    summary.Unread(),
+		"last_nudge":          agent.LastNudge,
+		"pr_url":              agent.PRURL,
+		"pr_number":           agent.PRNumber,
+		"pr_comments_read_at": agent.PRCommentsReadAt,
+	}}
+}
+
+// agentStatus reports whether an agent is completed, running (its tmux
+// window exists) or stopped. Without a session the status is unknown.
+func (d *Daemon) agentStatus(session string, agent state.Agent) string {
+	if agent.ReadyForCleanup {
+		return "completed"
+	}
+	if agent.Quarantined() {
+		return "quarantined"
+	}
+	if session == "" {
+		return "unknown"
+	}
+	hasWindow, err := d.tmux.HasWindow(d.ctx, session, agent.WindowTarget())
+	if err == nil && hasWindow {
+		return "running"
+	}
+	return "stopped"
+}
+
+// agentBranch returns the branch checked out in an agent's worktree, or the
+// one last recorded by the filesystem watcher if git cannot tell. Ephemeral
+// agents have no branch of their own.
+func agentBranch(agent state.Agent) string {
+	if agent.WorktreePath == "" || agent.Type == state.AgentTypeEphemeral {
+		return ""
+	}
+	branch, err := worktree.GetCurrentBranch(agent.WorktreePath)
+	if err != nil {
+		return agent.Branch
+	}
+	return branch
+}
+
+// mainBaseRef returns the remote-tracking ref of a repository's default
+// branch, e.g. origin/main, or "" if it cannot be determined
+func (d *Daemon) mainBaseRef(repoName string) string {
+	wt := worktree.NewManager(d.paths.RepoDir(repoName))
+	remote, err := wt.GetUpstreamRemote()
+	if err != nil {
+		return ""
+	}
+	branch, err := wt.GetDefaultBranch(remote)
+	if err != nil {
+		return ""
+	}
+	return remote + "/" + branch
+}
+
+// agentCommitsAhead returns how many commits an agent's branch has that
+// baseRef does not, or 0 if that cannot be determined
+func agentCommitsAhead(agent state.Agent, baseRef string) int {
+	if baseRef == "" {
+		return 0
+	}
+	ahead, err := worktree.CommitsAhead(agent.WorktreePath, baseRef)
+	if err != nil {
+		return 0
+	}
+	return ahead
+}
+
+// agentMessageSummary returns message statistics for an agent. Unreadable
+// messages are left out rather than failing the caller.
+func (d *Daemon) agentMessageSummary(repoName, agentName string) *messages.MessageSummary {
+	summary, err := messages.NewManager(d.paths.MessagesDir).AgentSummary(repoName, agentName)
+	if err != nil {
+		return &messages.MessageSummary{}
+	}
+	return summary
+}
+
 // handleCompleteAgent marks an agent as ready for cleanup
 func (d *Daemon) handleCompleteAgent(req socket.Request) socket.Response {
 	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
@@ -981,8 +1296,9 @@ func (d *Daemon) handleCompleteAgent(req socket.Request) socket.Response {
 		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' not found in repository '%s' - check available agents with: multiclaude work list --repo %s", agentName, repoName, repoName)}
 	}
 
-	// Mark as ready for cleanup
+	// Mark as ready for cleanup; a status set by hand no longer applies
 	agent.ReadyForCleanup = true
+	agent.Status = ""
 
 	// Optional: capture summary, failure reason, and PR info for task history
 	if summary, ok := req.Args["summary"].(string); ok && summary != "" {
@@ -1003,19 +1319,30 @@ func (d *Daemon) handleCompleteAgent(req socket.Request) socket.Response {
 	}
 
 	d.logger.Info("Agent %s/%s marked as ready for cleanup", repoName, agentName)
+	if isWorkerType(agent.Type) {
+		d.fireLifecycleEvent(agentEvent(hooks.EventWorkerCompleted, repoName, agentName, agent))
+	}
 
-	// Notify supervisor and merge-queue that worker or review agent completed
-	if agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview {
+	// Notify supervisor and merge-queue that worker, ephemeral or review agent completed
+	if agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeEphemeral || agent.Type == state.AgentTypeReview {
 		msgMgr := d.getMessageManager()
 		task := agent.Task
 		if task == "" {
 			task = "unknown task"
 		}
 
+		// A repository initialized with --no-supervisor has nobody to tell
+		noSupervisor := false
+		if repo, exists := d.state.GetRepo(repoName); exists {
+			noSupervisor = repo.NoSupervisor
+		}
+
 		if agent.Type == state.AgentTypeWorker {
 			// Notify supervisor
 			supervisorMessage := fmt.Sprintf("Worker '%s' has completed its task: %s", agentName, task)
-			if _, err := msgMgr.Send(repoName, agentName, "supervisor", supervisorMessage); err != nil {
+			if noSupervisor {
+				d.logger.Debug("Repo %s has no supervisor to notify of worker %s", repoName, agentName)
+			} else if _, err := msgMgr.Send(repoName, agentName, "supervisor", supervisorMessage); err != nil {
 				d.logger.Error("Failed to send completion message to supervisor: %v", err)
 			} else {
 				d.logger.Info("Sent completion notification to supervisor for worker %s", agentName)
@@ -1028,6 +1355,16 @@ func (d *Daemon) handleCompleteAgent(req socket.Request) socket.Response {
 			} else {
 				d.logger.Info("Sent completion notification to merge-queue for worker %s", agentName)
 			}
+		} else if agent.Type == state.AgentTypeEphemeral {
+			// Ephemeral agents cannot open PRs, so only the supervisor needs to know
+			supervisorMessage := fmt.Sprintf("Ephemeral agent '%s' has completed its task: %s", agentName, task)
+			if noSupervisor {
+				d.logger.Debug("Repo %s has no supervisor to notify of ephemeral agent %s", repoName, agentName)
+			} else if _, err := msgMgr.Send(repoName, agentName, "supervisor", supervisorMessage); err != nil {
+				d.logger.Error("Failed to send completion message to supervisor: %v", err)
+			} else {
+				d.logger.Info("Sent completion notification to supervisor for ephemeral agent %s", agentName)
+			}
 		} else if agent.Type == state.AgentTypeReview {
 			// Review agent completed - notify merge-queue to process the review results
 			mergeQueueMessage := fmt.Sprintf("Review agent '%s' has completed its review. Task: %s. Please check the review summary and decide on next steps.", agentName, task)
@@ -1048,6 +1385,170 @@ func (d *Daemon) handleCompleteAgent(req socket.Request) socket.Response {
 	return socket.Response{Success: true}
 }
 
+// handleUpdateAgentPR records the pull request opened for an agent's branch
+func (d *Daemon) handleUpdateAgentPR(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	prURL, errResp, ok := getRequiredStringArg(req.Args, "pr_url", "pull request URL is required")
+	if !ok {
+		return errResp
+	}
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' not found in repository '%s'", agentName, repoName)}
+	}
+
+	agent.PRURL = prURL
+	if prNumber, ok := req.Args["pr_number"].(float64); ok && prNumber > 0 {
+		agent.PRNumber = int(prNumber)
+	}
+	if watch, ok := req.Args["watch"].(bool); ok {
+		agent.WatchPR = watch && agent.PRNumber > 0
+	}
+
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.logger.Info("Recorded PR %s for agent %s/%s", prURL, repoName, agentName)
+	return socket.Response{Success: true}
+}
+
+// handleMarkPRCommentsRead records how far an agent has read the comments
+// on its PR, so that the wake loop does not nudge it about those
+func (d *Daemon) handleMarkPRCommentsRead(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	readAtArg, errResp, ok := getRequiredStringArg(req.Args, "read_at", "time of the newest comment read is required")
+	if !ok {
+		return errResp
+	}
+	readAt, err := time.Parse(time.RFC3339Nano, readAtArg)
+	if err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("invalid read_at %q: %v", readAtArg, err)}
+	}
+	count, _ := req.Args["count"].(float64)
+
+	if err := d.state.MarkPRCommentsRead(repoName, agentName, readAt, int(count)); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	return socket.Response{Success: true}
+}
+
+// handleUpdateAgentTask replaces a worker's task, e.g. when it turns out the
+// problem is different than expected
+func (d *Daemon) handleUpdateAgentTask(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	task, errResp, ok := getRequiredStringArg(req.Args, "task", "task is required")
+	if !ok {
+		return errResp
+	}
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' not found in repository '%s'", agentName, repoName)}
+	}
+	if agent.ReadyForCleanup {
+		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is marked as complete and pending cleanup - start a new worker for the new task instead", agentName)}
+	}
+
+	oldTask, err := d.state.UpdateAgentTask(repoName, agentName, task)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.logger.Info("Updated task of %s/%s: %q -> %q", repoName, agentName, oldTask, task)
+	return socket.Response{Success: true, Data: map[string]interface{}{"old_task": oldTask}}
+}
+
+// handleUpdateAgentStatus sets or, given an empty status, clears the status
+// a human gave an agent
+func (d *Daemon) handleUpdateAgentStatus(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	status, _ := req.Args["status"].(string)
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' not found in repository '%s'", agentName, repoName)}
+	}
+	if agent.ReadyForCleanup && status != "" {
+		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is marked as complete and pending cleanup", agentName)}
+	}
+
+	if err := d.state.UpdateAgentStatus(repoName, agentName, status); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.logger.Info("Updated status of %s/%s: %q -> %q", repoName, agentName, agent.Status, status)
+	return socket.Response{Success: true, Data: map[string]interface{}{"old_status": agent.Status}}
+}
+
+// handleSetAgentUpstream records the remote-tracking branch a workspace's
+// branch was set to track; an empty upstream clears it
+func (d *Daemon) handleSetAgentUpstream(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	upstream, _ := req.Args["upstream"].(string)
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' not found in repository '%s'", agentName, repoName)}
+	}
+	if agent.Type != state.AgentTypeWorkspace {
+		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is a %s; only workspaces have an upstream", agentName, agent.Type)}
+	}
+
+	if err := d.state.UpdateAgentUpstream(repoName, agentName, upstream); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.logger.Info("Set upstream of %s/%s: %q -> %q", repoName, agentName, agent.UpstreamBranch, upstream)
+	return socket.Response{Success: true, Data: map[string]interface{}{"old_upstream": agent.UpstreamBranch}}
+}
+
 // handleRestartAgent restarts an agent that has crashed or exited
 func (d *Daemon) handleRestartAgent(req socket.Request) socket.Response {
 	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
@@ -1061,6 +1562,7 @@ func (d *Daemon) handleRestartAgent(req socket.Request) socket.Response {
 	}
 
 	force, _ := req.Args["force"].(bool)
+	clearQuarantine, _ := req.Args["clear_quarantine"].(bool)
 
 	agent, exists := d.state.GetAgent(repoName, agentName)
 	if !exists {
@@ -1072,13 +1574,31 @@ func (d *Daemon) handleRestartAgent(req socket.Request) socket.Response {
 		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is marked as complete and pending cleanup - cannot restart a completed agent", agentName)}
 	}
 
+	// A quarantined agent kept crashing; restarting it is only useful once
+	// the cause is fixed, which the caller confirms by clearing it
+	if agent.Quarantined() {
+		if !clearQuarantine {
+			return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is quarantined after crashing repeatedly since %s - fix the cause, then use --clear-quarantine", agentName, agent.QuarantinedAt.Format(time.RFC3339))}
+		}
+		if err := d.state.ClearQuarantine(repoName, agentName); err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("failed to clear quarantine: %v", err)}
+		}
+		d.logger.Info("Cleared quarantine of agent %s/%s", repoName, agentName)
+		agent, _ = d.state.GetAgent(repoName, agentName)
+	} else if clearQuarantine {
+		// Forget earlier crashes, so the agent gets its full restart budget
+		if err := d.state.ClearQuarantine(repoName, agentName); err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("failed to clear restart attempts: %v", err)}
+		}
+	}
+
 	// Check if tmux window exists
 	repo, exists := d.state.GetRepo(repoName)
 	if !exists {
 		return socket.Response{Success: false, Error: fmt.Sprintf("repository '%s' not found in state", repoName)}
 	}
 
-	hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agentName)
+	hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.WindowTarget())
 	if err != nil {
 		return socket.Response{Success: false, Error: fmt.Sprintf("failed to check tmux window: %v", err)}
 	}
@@ -1087,7 +1607,7 @@ func (d *Daemon) handleRestartAgent(req socket.Request) socket.Response {
 	}
 
 	// Check if agent is already running
-	if agent.PID > 0 && isProcessAlive(agent.PID) {
+	if agent.PID > 0 && procs.IsAlive(agent.PID) {
 		if !force {
 			return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is already running with PID %d - use --force to restart anyway", agentName, agent.PID)}
 		}
@@ -1112,6 +1632,87 @@ func (d *Daemon) handleRestartAgent(req socket.Request) socket.Response {
 	}
 }
 
+// handleSetAgentEnv sets environment variables for an agent and restarts it
+// so its Claude process picks them up. The agent's pane is respawned with the
+// variables before Claude is relaunched, resuming its conversation; the repo's
+// tmux session, and so every other window, is left without them. Values are
+// never logged.
+func (d *Daemon) handleSetAgentEnv(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	env, err := envArg(req.Args)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	if len(env) == 0 {
+		return socket.Response{Success: false, Error: "missing 'env': at least one KEY=VALUE is required"}
+	}
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' not found in repository '%s' - check available agents with: multiclaude work list --repo %s", agentName, repoName, repoName)}
+	}
+	if agent.ReadyForCleanup {
+		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is marked as complete and pending cleanup - cannot restart a completed agent", agentName)}
+	}
+
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return socket.Response{Success: false, Error: fmt.Sprintf("repository '%s' not found in state", repoName)}
+	}
+
+	// Record the variables first so restartAgent, now and after any later
+	// crash, sets them again
+	if err := d.state.SetAgentEnvironment(repoName, agentName, env); err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("failed to save environment: %v", err)}
+	}
+	agent, _ = d.state.GetAgent(repoName, agentName)
+
+	// restartAgent respawns the pane with the agent's Environment
+	keys := envfile.Keys(env)
+	if err := d.restartAgent(repoName, agentName, agent, repo); err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("environment saved, but failed to restart agent: %v - retry with: multiclaude agent restart %s --force", err, agentName)}
+	}
+	d.logger.Info("Set %s for agent %s/%s and restarted it", strings.Join(keys, ", "), repoName, agentName)
+
+	updatedAgent, _ := d.state.GetAgent(repoName, agentName)
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"agent": agentName,
+			"repo":  repoName,
+			"pid":   updatedAgent.PID,
+			"keys":  keys,
+		},
+	}
+}
+
+// envArg reads the optional "env" argument, a map of variable names to
+// values, checking that each name is a valid variable name
+func envArg(args map[string]interface{}) (map[string]string, error) {
+	raw, _ := args["env"].(map[string]interface{})
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	env := make(map[string]string, len(raw))
+	for key, v := range raw {
+		value, ok := v.(string)
+		if !ok || !envfile.ValidKey(key) {
+			return nil, fmt.Errorf("invalid environment variable %q", key)
+		}
+		env[key] = value
+	}
+	return env, nil
+}
+
 // handleTriggerCleanup manually triggers cleanup operations
 func (d *Daemon) handleTriggerCleanup(req socket.Request) socket.Response {
 	d.logger.Info("Manual cleanup triggered")
@@ -1132,8 +1733,16 @@ func (d *Daemon) handleRepairState(req socket.Request) socket.Response {
 	agentsRemoved := 0
 	issuesFixed := 0
 
-	// Get a snapshot of repos to avoid concurrent map access
+	// Get a snapshot of repos to avoid concurrent map access. The optional
+	// "repo" argument limits the repair to one repository.
 	repos := d.state.GetAllRepos()
+	if name, _ := req.Args["repo"].(string); name != "" {
+		repo, exists := repos[name]
+		if !exists {
+			return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", name)}
+		}
+		repos = map[string]*state.Repository{name: repo}
+	}
 
 	// Check all agents and verify resources exist
 	for repoName, repo := range repos {
@@ -1158,7 +1767,7 @@ func (d *Daemon) handleRepairState(req socket.Request) socket.Response {
 
 		// Check each agent's resources
 		for agentName, agent := range repo.Agents {
-			hasWindow, _ := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
+			hasWindow, _ := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.WindowTarget())
 			if !hasWindow {
 				d.logger.Info("Removing agent %s (window not found)", agentName)
 				if err := d.state.RemoveAgent(repoName, agentName); err == nil {
@@ -1178,13 +1787,10 @@ func (d *Daemon) handleRepairState(req socket.Request) socket.Response {
 		}
 	}
 
-	// Clean up orphaned worktrees
-	d.cleanupOrphanedWorktrees()
-
-	// Clean up orphaned message directories
+	// Clean up orphaned worktrees and message directories
 	msgMgr := d.getMessageManager()
-	repoNames := d.state.ListRepos()
-	for _, repoName := range repoNames {
+	for repoName := range repos {
+		d.cleanupRepoOrphanedWorktrees(repoName)
 		validAgents, _ := d.state.ListAgents(repoName)
 		if count, err := msgMgr.CleanupOrphaned(repoName, validAgents); err == nil && count > 0 {
 			issuesFixed += count
@@ -1202,7 +1808,9 @@ func (d *Daemon) handleRepairState(req socket.Request) socket.Response {
 	}
 }
 
-// handleGetRepoConfig returns the configuration for a repository
+// handleGetRepoConfig returns the configuration for a repository: every
+// registered key's value under "config", and each value under its legacy
+// name for older clients
 func (d *Daemon) handleGetRepoConfig(req socket.Request) socket.Response {
 	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
 	if !ok {
@@ -1214,64 +1822,135 @@ func (d *Daemon) handleGetRepoConfig(req socket.Request) socket.Response {
 		return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", name)}
 	}
 
-	// Get merge queue config (use default if not set for backward compatibility)
-	mqConfig := repo.MergeQueueConfig
-	if mqConfig.TrackMode == "" {
-		mqConfig = state.DefaultMergeQueueConfig()
+	values := repoconfig.Values(repo)
+	data := map[string]interface{}{
+		"config":         values,
+		"has_submodules": repo.HasSubmodules,
+	}
+	for _, key := range repoconfig.Keys() {
+		if key.Legacy != "" {
+			data[key.Legacy] = key.LegacyValue(values[key.Name])
+		}
+	}
+	agentTransports := make(map[string]string, len(repo.MessageTransport.ByAgentType))
+	for agentType, transport := range repo.MessageTransport.ByAgentType {
+		agentTransports[string(agentType)] = transport
+	}
+	data["agent_transports"] = agentTransports
+
+	return socket.Response{Success: true, Data: data}
+}
+
+// handleUpdateRepoConfig sets the keys in "set" and reverts those in "unset"
+// to their defaults, all or none of them. Settings given under their legacy
+// names, as older clients send them, are set too.
+func (d *Daemon) handleUpdateRepoConfig(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	set := make(map[string]string)
+	switch values := req.Args["set"].(type) {
+	case map[string]interface{}:
+		for key, v := range values {
+			value, ok := v.(string)
+			if !ok {
+				return socket.Response{Success: false, Error: fmt.Sprintf("value of config key %q must be a string", key)}
+			}
+			set[key] = value
+		}
+	case map[string]string:
+		for key, value := range values {
+			set[key] = value
+		}
+	}
+	var unset []string
+	switch keys := req.Args["unset"].(type) {
+	case []interface{}:
+		for _, k := range keys {
+			key, _ := k.(string)
+			unset = append(unset, key)
+		}
+	case []string:
+		unset = keys
+	}
+
+	for _, key := range repoconfig.Keys() {
+		if v, ok := req.Args[key.Legacy]; ok && key.Legacy != "" {
+			value, err := legacyConfigValue(v)
+			if err != nil {
+				return socket.Response{Success: false, Error: fmt.Sprintf("invalid %s: %v", key.Legacy, err)}
+			}
+			set[key.Name] = value
+		}
+	}
+	if overrides, ok := req.Args["agent_transports"].(map[string]interface{}); ok {
+		for agentType, v := range overrides {
+			key := repoconfig.TransportKey(agentType)
+			if _, ok := repoconfig.Lookup(key); !ok {
+				return socket.Response{Success: false, Error: fmt.Sprintf("invalid agent type for transport override: %s (must be supervisor, worker, merge-queue, workspace, review, or ephemeral)", agentType)}
+			}
+			set[key], _ = v.(string)
+		}
 	}
 
-	return socket.Response{
-		Success: true,
-		Data: map[string]interface{}{
-			"mq_enabled":    mqConfig.Enabled,
-			"mq_track_mode": string(mqConfig.TrackMode),
-		},
+	// Transport names are checked here, where the registered ones are known
+	for key, value := range set {
+		if value != "" && (key == "transport" || strings.HasPrefix(key, "transport-")) {
+			if errResp, ok := d.validateTransportName(value); !ok {
+				return errResp
+			}
+		}
 	}
-}
 
-// handleUpdateRepoConfig updates the configuration for a repository
-func (d *Daemon) handleUpdateRepoConfig(req socket.Request) socket.Response {
-	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
-	if !ok {
-		return errResp
+	repo, exists := d.state.GetRepo(name)
+	if !exists {
+		return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", name)}
 	}
-
-	// Get current merge queue config
-	currentMQConfig, err := d.state.GetMergeQueueConfig(name)
-	if err != nil {
+	before := repoconfig.Values(repo)
+	if err := d.state.UpdateRepoConfig(name, func(r *state.Repository) error {
+		return repoconfig.Apply(r, set, unset)
+	}); err != nil {
 		return socket.Response{Success: false, Error: err.Error()}
 	}
 
-	// Update merge queue config with provided values
-	mqUpdated := false
-	if mqEnabled, ok := req.Args["mq_enabled"].(bool); ok {
-		currentMQConfig.Enabled = mqEnabled
-		mqUpdated = true
-	}
-	if mqTrackMode, ok := req.Args["mq_track_mode"].(string); ok {
-		switch mqTrackMode {
-		case "all":
-			currentMQConfig.TrackMode = state.TrackModeAll
-		case "author":
-			currentMQConfig.TrackMode = state.TrackModeAuthor
-		case "assigned":
-			currentMQConfig.TrackMode = state.TrackModeAssigned
-		default:
-			return socket.Response{Success: false, Error: fmt.Sprintf("invalid track mode: %s", mqTrackMode)}
+	repo, _ = d.state.GetRepo(name)
+	after := repoconfig.Values(repo)
+	reviewUpdated := false
+	for _, key := range repoconfig.Keys() {
+		if before[key.Name] == after[key.Name] {
+			continue
 		}
-		mqUpdated = true
+		d.logger.Info("Updated %s for repo %s: %q", key.Name, name, after[key.Name])
+		reviewUpdated = reviewUpdated || key.Name == "mq-review-enabled" || key.Name == "mq-review-pattern"
 	}
-
-	if mqUpdated {
-		if err := d.state.UpdateMergeQueueConfig(name, currentMQConfig); err != nil {
-			return socket.Response{Success: false, Error: err.Error()}
+	if reviewUpdated {
+		if mqConfig, err := d.state.GetMergeQueueConfig(name); err == nil {
+			d.applyReviewGate(name, mqConfig)
 		}
-		d.logger.Info("Updated merge queue config for repo %s: enabled=%v, track=%s", name, currentMQConfig.Enabled, currentMQConfig.TrackMode)
 	}
 
 	return socket.Response{Success: true}
 }
 
+// legacyConfigValue converts a setting sent under its legacy name, as a JSON
+// bool, number or string, to the string the registry parses
+func legacyConfigValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.Itoa(int(v)), nil
+	case int:
+		return strconv.Itoa(v), nil
+	default:
+		return "", fmt.Errorf("unexpected value %v", v)
+	}
+}
+
 // handleSetCurrentRepo sets the current/default repository
 func (d *Daemon) handleSetCurrentRepo(req socket.Request) socket.Response {
 	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
@@ -1330,7 +2009,7 @@ func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 			}
 
 			// Kill tmux window
-			if err := d.tmux.KillWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow); err != nil {
+			if err := d.tmux.KillWindow(d.ctx, repo.TmuxSession, agent.WindowTarget()); err != nil {
 				d.logger.Warn("Failed to kill tmux window %s: %v", agent.TmuxWindow, err)
 			} else {
 				d.logger.Info("Killed tmux window for agent %s: %s", agentName, agent.TmuxWindow)
@@ -1339,24 +2018,36 @@ func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 			// Remove from state
 			if err := d.state.RemoveAgent(repoName, agentName); err != nil {
 				d.logger.Error("Failed to remove agent %s/%s from state: %v", repoName, agentName, err)
+			} else {
+				event := agentEvent(hooks.EventAgentRemoved, repoName, agentName, agent)
+				event.Reason = "cleaned_up"
+				d.fireLifecycleEvent(event)
 			}
 
-			// Clean up worktree and branch if they exist (workers and review agents have worktrees)
+			// Clean up worktree and branch if they exist (workers and review agents have worktrees).
+			// Ephemeral agents run in the primary checkout, which must never be removed.
 			if agent.WorktreePath != "" && (agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview) {
 				repoPath := d.paths.RepoDir(repoName)
 				wt := worktree.NewManager(repoPath)
+				if err := worktree.RemoveContextDir(agent.WorktreePath); err != nil {
+					d.logger.Warn("Failed to remove context files in %s: %v", agent.WorktreePath, err)
+				}
 				if err := wt.Remove(agent.WorktreePath, true); err != nil {
 					d.logger.Warn("Failed to remove worktree %s: %v", agent.WorktreePath, err)
 				} else {
 					d.logger.Info("Removed worktree for dead agent: %s", agent.WorktreePath)
 				}
 
-				// Delete the branch (work/<agentName>) after worktree removal
+				// Delete the branch (work/<agentName>) after worktree removal,
+				// bundling it first so the work can be restored. A branch that
+				// could not be bundled is kept rather than lost.
 				branchName := "work/" + agentName
-				if err := wt.DeleteBranch(branchName); err != nil {
-					d.logger.Warn("Failed to delete branch %s: %v", branchName, err)
-				} else {
-					d.logger.Info("Deleted branch for dead agent: %s", branchName)
+				if d.archiveBranch(repoName, wt, branchName) {
+					if err := wt.DeleteBranch(branchName); err != nil {
+						d.logger.Warn("Failed to delete branch %s: %v", branchName, err)
+					} else {
+						d.logger.Info("Deleted branch for dead agent: %s", branchName)
+					}
 				}
 			}
 
@@ -1370,7 +2061,8 @@ func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 				}
 			}
 
-			// Clean up message directory
+			// Bounce unread messages, then clean up the message directory
+			d.bounceMessages(repoName, agentName)
 			msgMgr := d.getMessageManager()
 			validAgents, _ := d.state.ListAgents(repoName)
 			if _, err := msgMgr.CleanupOrphaned(repoName, validAgents); err != nil {
@@ -1380,6 +2072,102 @@ func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 	}
 }
 
+// archiveBranch bundles a branch about to be deleted into the repository's
+// archives directory. It reports whether the branch may be deleted: true
+// once it is bundled, or when it does not exist.
+func (d *Daemon) archiveBranch(repoName string, wt *worktree.Manager, branchName string) bool {
+	exists, err := wt.BranchExists(branchName)
+	if err != nil {
+		d.logger.Warn("Failed to check branch %s, keeping it: %v", branchName, err)
+		return false
+	}
+	if !exists {
+		return true
+	}
+	path, err := archive.Create(wt, d.paths.ArchivesDir(repoName), branchName, time.Now())
+	if err != nil {
+		d.logger.Warn("Failed to archive branch %s, keeping it: %v", branchName, err)
+		return false
+	}
+	d.logger.Info("Archived branch %s to %s", branchName, path)
+	return true
+}
+
+// pruneArchives removes branch bundles past each repository's retention
+func (d *Daemon) pruneArchives() {
+	for repoName, repo := range d.state.GetAllRepos() {
+		maxSize := int64(repo.ArchiveMaxSizeMB) * 1024 * 1024
+		removed, err := archive.Prune(d.paths.ArchivesDir(repoName), repo.ArchiveMaxAgeDuration(), maxSize, time.Now())
+		if err != nil {
+			d.logger.Warn("Failed to prune archives for %s: %v", repoName, err)
+		}
+		for _, a := range removed {
+			d.logger.Info("Removed archived bundle %s for %s", a.Name, repoName)
+		}
+	}
+}
+
+// pruneSnapshots removes workspace snapshots past each repository's
+// SnapshotKeep, including those of workspaces removed since
+func (d *Daemon) pruneSnapshots() {
+	for repoName, repo := range d.state.GetAllRepos() {
+		repoPath := d.paths.RepoDir(repoName)
+		if _, err := os.Stat(repoPath); err != nil {
+			continue
+		}
+		workspaces, err := worktree.SnapshotWorkspaces(repoPath)
+		if err != nil {
+			d.logger.Warn("Failed to list snapshots for %s: %v", repoName, err)
+			continue
+		}
+		for _, ws := range workspaces {
+			removed, err := worktree.PruneSnapshots(repoPath, ws, repo.SnapshotKeepCount())
+			if err != nil {
+				d.logger.Warn("Failed to prune snapshots of %s/%s: %v", repoName, ws, err)
+			}
+			for _, s := range removed {
+				d.logger.Info("Removed snapshot %s of %s/%s", s.ID, repoName, ws)
+			}
+		}
+	}
+}
+
+// refreshSubmodules records which repositories declare git submodules, so
+// list and status can show it
+func (d *Daemon) refreshSubmodules() {
+	for repoName := range d.state.GetAllRepos() {
+		if err := d.state.SetHasSubmodules(repoName, worktree.HasSubmodules(d.paths.RepoDir(repoName))); err != nil {
+			d.logger.Warn("Failed to record submodules for %s: %v", repoName, err)
+		}
+	}
+}
+
+// applyGitIdentity sets a repository's committer identity and commit signing
+// in a worktree the daemon has just created
+func (d *Daemon) applyGitIdentity(repoName string, repo *state.Repository, wtPath string) {
+	if err := worktree.SetWorktreeConfig(wtPath, repo.GitIdentity.GitConfig()); err != nil {
+		d.logger.Warn("Failed to apply git identity to %s in repo %s: %v", wtPath, repoName, err)
+	}
+}
+
+// updateSubmodules checks out the submodules of a worktree the daemon has
+// just created, appending git's output to the agent's log
+func (d *Daemon) updateSubmodules(repoName string, repo *state.Repository, wtPath, agentName string) error {
+	logFile := d.paths.AgentLogFile(repoName, agentName, false)
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open agent log: %w", err)
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithTimeout(d.ctx, repo.SubmoduleTimeoutDuration())
+	defer cancel()
+	return worktree.UpdateSubmodules(ctx, wtPath, f)
+}
+
 // recordTaskHistory saves a worker's task to the history before cleanup
 func (d *Daemon) recordTaskHistory(repoName, agentName string, agent state.Agent) {
 	// Get the branch name from the worktree if it exists
@@ -1415,6 +2203,7 @@ func (d *Daemon) recordTaskHistory(repoName, agentName string, agent state.Agent
 		FailureReason: agent.FailureReason,
 		CreatedAt:     agent.CreatedAt,
 		CompletedAt:   time.Now(),
+		TaskUpdates:   agent.TaskUpdates,
 	}
 
 	if err := d.state.AddTaskHistory(repoName, entry); err != nil {
@@ -1462,43 +2251,84 @@ func (d *Daemon) handleTaskHistory(req socket.Request) socket.Response {
 			"failure_reason": entry.FailureReason,
 			"created_at":     entry.CreatedAt,
 			"completed_at":   entry.CompletedAt,
+			"task_updates":   entry.TaskUpdates,
 		}
 	}
 
 	return socket.Response{Success: true, Data: result}
 }
 
-// cleanupOrphanedWorktrees removes worktree directories without git tracking
-func (d *Daemon) cleanupOrphanedWorktrees() {
+// handleScheduledMessages lists messages waiting for a future delivery time,
+// in one repository or, without a repo argument, in all of them
+func (d *Daemon) handleScheduledMessages(req socket.Request) socket.Response {
 	repoNames := d.state.ListRepos()
-	for _, repoName := range repoNames {
-		repoPath := d.paths.RepoDir(repoName)
-		wtRootDir := d.paths.WorktreeDir(repoName)
-
-		// Check if worktree directory exists
-		if _, err := os.Stat(wtRootDir); os.IsNotExist(err) {
-			continue
+	if repoName, ok := req.Args["repo"].(string); ok && repoName != "" {
+		if _, exists := d.state.GetRepo(repoName); !exists {
+			return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", repoName)}
 		}
+		repoNames = []string{repoName}
+	}
 
-		wt := worktree.NewManager(repoPath)
-		removed, err := worktree.CleanupOrphaned(wtRootDir, wt)
+	msgMgr := d.getMessageManager()
+	now := time.Now()
+	result := []map[string]interface{}{}
+	for _, repoName := range repoNames {
+		scheduled, err := msgMgr.ListScheduled(repoName, now)
 		if err != nil {
-			d.logger.Error("Failed to cleanup orphaned worktrees for %s: %v", repoName, err)
-			continue
+			return socket.Response{Success: false, Error: err.Error()}
 		}
-
-		if len(removed) > 0 {
-			d.logger.Info("Cleaned up %d orphaned worktree(s) for %s", len(removed), repoName)
-			for _, path := range removed {
-				d.logger.Debug("Removed orphaned worktree: %s", path)
-			}
+		for _, msg := range scheduled {
+			result = append(result, map[string]interface{}{
+				"id":            msg.ID,
+				"repo":          repoName,
+				"from":          msg.From,
+				"to":            msg.To,
+				"body":          msg.Body,
+				"timestamp":     msg.Timestamp,
+				"scheduled_for": *msg.ScheduledFor,
+			})
 		}
+	}
 
-		// Also prune git worktree references
-		if err := wt.Prune(); err != nil {
-			d.logger.Warn("Failed to prune worktrees for %s: %v", repoName, err)
+	return socket.Response{Success: true, Data: result}
+}
+
+// cleanupOrphanedWorktrees removes worktree directories without git tracking
+func (d *Daemon) cleanupOrphanedWorktrees() {
+	for _, repoName := range d.state.ListRepos() {
+		d.cleanupRepoOrphanedWorktrees(repoName)
+	}
+}
+
+// cleanupRepoOrphanedWorktrees removes the worktree directories of a
+// repository that git no longer knows
+func (d *Daemon) cleanupRepoOrphanedWorktrees(repoName string) {
+	repoPath := d.paths.RepoDir(repoName)
+	wtRootDir := d.paths.WorktreeDir(repoName)
+
+	// Check if worktree directory exists
+	if _, err := os.Stat(wtRootDir); os.IsNotExist(err) {
+		return
+	}
+
+	wt := worktree.NewManager(repoPath)
+	removed, err := worktree.CleanupOrphaned(wtRootDir, wt)
+	if err != nil {
+		d.logger.Error("Failed to cleanup orphaned worktrees for %s: %v", repoName, err)
+		return
+	}
+
+	if len(removed) > 0 {
+		d.logger.Info("Cleaned up %d orphaned worktree(s) for %s", len(removed), repoName)
+		for _, path := range removed {
+			d.logger.Debug("Removed orphaned worktree: %s", path)
 		}
 	}
+
+	// Also prune git worktree references
+	if err := wt.Prune(); err != nil {
+		d.logger.Warn("Failed to prune worktrees for %s: %v", repoName, err)
+	}
 }
 
 // cleanupMergedBranches cleans up branches that have been merged upstream
@@ -1577,7 +2407,7 @@ func (d *Daemon) restoreDeadAgents(repoName string, repo *state.Repository) {
 		}
 
 		// Check if the tmux window still exists
-		hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
+		hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.WindowTarget())
 		if err != nil {
 			d.logger.Error("Failed to check window for agent %s: %v", agentName, err)
 			continue
@@ -1589,7 +2419,7 @@ func (d *Daemon) restoreDeadAgents(repoName string, repo *state.Repository) {
 		}
 
 		// Check if the process is still alive
-		if isProcessAlive(agent.PID) {
+		if procs.IsAlive(agent.PID) {
 			d.logger.Debug("Agent %s process (PID %d) is alive", agentName, agent.PID)
 			continue
 		}
@@ -1600,11 +2430,7 @@ func (d *Daemon) restoreDeadAgents(repoName string, repo *state.Repository) {
 		// For persistent agents (supervisor, merge-queue, workspace), auto-restart
 		// For transient agents (workers, review), they will be cleaned up by health check
 		if agent.Type == state.AgentTypeSupervisor || agent.Type == state.AgentTypeMergeQueue || agent.Type == state.AgentTypeWorkspace {
-			if err := d.restartAgent(repoName, agentName, agent, repo); err != nil {
-				d.logger.Error("Failed to restart agent %s: %v", agentName, err)
-			} else {
-				d.logger.Info("Successfully restarted agent %s with --resume", agentName)
-			}
+			d.autoRestartAgent(repoName, agentName, agent, repo, time.Now())
 		} else {
 			d.logger.Debug("Skipping transient agent %s (type %s) - will be cleaned up", agentName, agent.Type)
 		}
@@ -1628,11 +2454,30 @@ func (d *Daemon) restoreRepoAgents(repoName string, repo *state.Repository) erro
 		}
 	}
 
-	// Create tmux session with supervisor window
-	d.logger.Info("Creating tmux session %s for repo %s", repo.TmuxSession, repoName)
-	cmd := exec.Command("tmux", "new-session", "-d", "-s", repo.TmuxSession, "-n", "supervisor", "-c", repoPath)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create tmux session: %w", err)
+	// The first agent's window creates the tmux session: the supervisor's,
+	// or the next one's in a repository without a supervisor
+	sessionCreated := false
+	newWindow := func(window, dir string) error {
+		args := []string{"new-window", "-d", "-t", repo.TmuxSession, "-n", window, "-c", dir}
+		if !sessionCreated {
+			d.logger.Info("Creating tmux session %s for repo %s", repo.TmuxSession, repoName)
+			args = []string{"new-session", "-d", "-s", repo.TmuxSession, "-n", window, "-c", dir}
+		}
+		if _, _, err := cmdrun.Run(exec.Command("tmux", args...)); err != nil {
+			return err
+		}
+		if !sessionCreated {
+			d.tagSession(repo.TmuxSession)
+		}
+		sessionCreated = true
+		return nil
+	}
+
+	// Create supervisor window unless the repository runs without one
+	if !repo.NoSupervisor {
+		if err := newWindow("supervisor", repoPath); err != nil {
+			return fmt.Errorf("failed to create tmux session: %w", err)
+		}
 	}
 
 	// Get merge queue config (use default if not set for backward compatibility)
@@ -1643,14 +2488,15 @@ func (d *Daemon) restoreRepoAgents(repoName string, repo *state.Repository) erro
 
 	// Create merge-queue window only if enabled
 	if mqConfig.Enabled {
-		cmd = exec.Command("tmux", "new-window", "-d", "-t", repo.TmuxSession, "-n", "merge-queue", "-c", repoPath)
-		if err := cmd.Run(); err != nil {
+		if err := newWindow("merge-queue", repoPath); err != nil {
 			return fmt.Errorf("failed to create merge-queue window: %w", err)
 		}
 	}
 
 	// Start supervisor agent
-	if err := d.startAgent(repoName, repo, "supervisor", prompts.TypeSupervisor, repoPath); err != nil {
+	if repo.NoSupervisor {
+		d.logger.Info("Repo %s has no supervisor, skipping supervisor agent", repoName)
+	} else if err := d.startAgent(repoName, repo, "supervisor", prompts.TypeSupervisor, repoPath); err != nil {
 		d.logger.Error("Failed to start supervisor for %s: %v", repoName, err)
 	}
 
@@ -1701,12 +2547,24 @@ func (d *Daemon) restoreRepoAgents(repoName string, repo *state.Repository) erro
 				d.logger.Error("Failed to create workspace worktree with new branch for %s: %v", repoName, err)
 			}
 		}
+
+		if _, err := os.Stat(workspacePath); err == nil {
+			d.applyGitIdentity(repoName, repo, workspacePath)
+		}
+
+		// The workspace still starts without its submodules, as the agent can
+		// retry the checkout itself
+		if _, err := os.Stat(workspacePath); err == nil && worktree.HasSubmodules(repoPath) {
+			d.logger.Info("Checking out submodules in workspace worktree for %s", repoName)
+			if err := d.updateSubmodules(repoName, repo, workspacePath, "workspace"); err != nil {
+				d.logger.Error("Failed to check out submodules in workspace worktree for %s: %v", repoName, err)
+			}
+		}
 	}
 
 	// Now start the workspace agent if worktree exists
 	if _, err := os.Stat(workspacePath); err == nil {
-		cmd = exec.Command("tmux", "new-window", "-d", "-t", repo.TmuxSession, "-n", "workspace", "-c", workspacePath)
-		if err := cmd.Run(); err != nil {
+		if err := newWindow("workspace", workspacePath); err != nil {
 			d.logger.Error("Failed to create workspace window: %v", err)
 		} else {
 			if err := d.startAgent(repoName, repo, "workspace", prompts.TypeWorkspace, workspacePath); err != nil {
@@ -1715,25 +2573,32 @@ func (d *Daemon) restoreRepoAgents(repoName string, repo *state.Repository) erro
 		}
 	}
 
+	if !sessionCreated {
+		return fmt.Errorf("repository has no supervisor, merge queue or workspace to restore")
+	}
 	return nil
 }
 
-// getClaudeBinaryPath resolves the claude CLI binary path
-func (d *Daemon) getClaudeBinaryPath() (string, error) {
-	binaryPath, err := exec.LookPath("claude")
-	if err != nil {
-		return "", fmt.Errorf("claude binary not found in PATH: %w", err)
+// checkClaudeVersion refuses a claude binary older than the repository's
+// minimum version, if one is configured
+func (d *Daemon) checkClaudeVersion(repo *state.Repository, binaryPath string) error {
+	runner := claude.NewRunner(claude.WithBinaryPath(binaryPath), claude.WithBinaryValidator(repo.MinClaudeVersion, ""))
+	if err := runner.ValidateBinary(d.ctx); err != nil {
+		return fmt.Errorf("claude binary rejected: %w", err)
 	}
-	return binaryPath, nil
+	return nil
 }
 
 // startAgent starts a Claude agent in a tmux window and registers it with state
 func (d *Daemon) startAgent(repoName string, repo *state.Repository, agentName string, agentType prompts.AgentType, workDir string) error {
 	// Resolve claude binary path
-	binaryPath, err := d.getClaudeBinaryPath()
+	binaryPath, err := d.getClaudeBinaryPath(repo)
 	if err != nil {
 		return fmt.Errorf("failed to resolve claude binary: %w", err)
 	}
+	if err := d.checkClaudeVersion(repo, binaryPath); err != nil {
+		return err
+	}
 
 	// Generate session ID
 	sessionID, err := claude.GenerateSessionID()
@@ -1756,11 +2621,14 @@ func (d *Daemon) startAgent(repoName string, repo *state.Repository, agentName s
 	// Build CLI command
 	claudeCmd := fmt.Sprintf("%s --session-id %s --dangerously-skip-permissions --append-system-prompt-file %s",
 		binaryPath, sessionID, promptFile)
+	if envKeys := d.applyRepoEnv(repoName, repo.TmuxSession); len(envKeys) > 0 {
+		claudeCmd = tmux.ImportEnvironmentCommand(envKeys...) + " && " + claudeCmd
+	}
 
 	// Send command to tmux window
 	target := fmt.Sprintf("%s:%s", repo.TmuxSession, agentName)
 	cmd := exec.Command("tmux", "send-keys", "-t", target, claudeCmd, "C-m")
-	if err := cmd.Run(); err != nil {
+	if _, _, err := cmdrun.Run(cmd); err != nil {
 		return fmt.Errorf("failed to start Claude in tmux: %w", err)
 	}
 
@@ -1778,8 +2646,10 @@ func (d *Daemon) startAgent(repoName string, repo *state.Repository, agentName s
 		Type:         state.AgentType(agentType),
 		WorktreePath: workDir,
 		TmuxWindow:   agentName,
+		TmuxWindowID: d.windowID(repo.TmuxSession, agentName),
 		SessionID:    sessionID,
 		PID:          pid,
+		PromptHash:   hashPromptFile(promptFile),
 		CreatedAt:    time.Now(),
 	}
 
@@ -1794,10 +2664,13 @@ func (d *Daemon) startAgent(repoName string, repo *state.Repository, agentName s
 // startMergeQueueAgent starts a merge-queue agent with tracking mode configuration
 func (d *Daemon) startMergeQueueAgent(repoName string, repo *state.Repository, workDir string, mqConfig state.MergeQueueConfig) error {
 	// Resolve claude binary path
-	binaryPath, err := d.getClaudeBinaryPath()
+	binaryPath, err := d.getClaudeBinaryPath(repo)
 	if err != nil {
 		return fmt.Errorf("failed to resolve claude binary: %w", err)
 	}
+	if err := d.checkClaudeVersion(repo, binaryPath); err != nil {
+		return err
+	}
 
 	// Generate session ID
 	sessionID, err := claude.GenerateSessionID()
@@ -1820,11 +2693,14 @@ func (d *Daemon) startMergeQueueAgent(repoName string, repo *state.Repository, w
 	// Build CLI command
 	claudeCmd := fmt.Sprintf("%s --session-id %s --dangerously-skip-permissions --append-system-prompt-file %s",
 		binaryPath, sessionID, promptFile)
+	if envKeys := d.applyRepoEnv(repoName, repo.TmuxSession); len(envKeys) > 0 {
+		claudeCmd = tmux.ImportEnvironmentCommand(envKeys...) + " && " + claudeCmd
+	}
 
 	// Send command to tmux window
 	target := fmt.Sprintf("%s:merge-queue", repo.TmuxSession)
 	cmd := exec.Command("tmux", "send-keys", "-t", target, claudeCmd, "C-m")
-	if err := cmd.Run(); err != nil {
+	if _, _, err := cmdrun.Run(cmd); err != nil {
 		return fmt.Errorf("failed to start Claude in tmux: %w", err)
 	}
 
@@ -1842,8 +2718,10 @@ func (d *Daemon) startMergeQueueAgent(repoName string, repo *state.Repository, w
 		Type:         state.AgentTypeMergeQueue,
 		WorktreePath: workDir,
 		TmuxWindow:   "merge-queue",
+		TmuxWindowID: d.windowID(repo.TmuxSession, "merge-queue"),
 		SessionID:    sessionID,
 		PID:          pid,
+		PromptHash:   hashPromptFile(promptFile),
 		CreatedAt:    time.Now(),
 	}
 
@@ -1851,10 +2729,77 @@ func (d *Daemon) startMergeQueueAgent(repoName string, repo *state.Repository, w
 		return fmt.Errorf("failed to register agent: %w", err)
 	}
 
+	// Tell the fresh session which PRs it was tracking before the restart
+	if summary := d.trackedPRsSummary(repoName); summary != "" {
+		time.Sleep(1 * time.Second)
+		if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, "merge-queue", summary); err != nil {
+			d.logger.Warn("Failed to send tracked PR summary to %s/merge-queue: %v", repoName, err)
+		}
+	}
+
 	d.logger.Info("Started and registered merge-queue agent %s/merge-queue (track mode: %s)", repoName, mqConfig.TrackMode)
 	return nil
 }
 
+// announceSupervisor records that a repository initialized without a
+// supervisor now has one. The prompt files of its persistent agents are
+// rewritten for their next restart, and every agent is told now, since a
+// running agent does not reread its prompt.
+func (d *Daemon) announceSupervisor(repoName string) {
+	if err := d.state.SetNoSupervisor(repoName, false); err != nil {
+		d.logger.Warn("Failed to record the supervisor of repo %s: %v", repoName, err)
+		return
+	}
+
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return
+	}
+	msgMgr := d.getMessageManager()
+	for agentName, agent := range repo.Agents {
+		var err error
+		switch agent.Type {
+		case state.AgentTypeSupervisor:
+			continue
+		case state.AgentTypeMergeQueue:
+			_, err = d.writeMergeQueuePromptFile(repoName, agentName, repo.MergeQueueConfig)
+		case state.AgentTypeWorkspace:
+			_, err = d.writePromptFile(repoName, prompts.TypeWorkspace, agentName)
+		}
+		if err != nil {
+			d.logger.Warn("Failed to rewrite prompt of %s/%s: %v", repoName, agentName, err)
+		}
+
+		msg := "This repository now has a supervisor agent. From now on, message, report to and ask the supervisor as your instructions describe."
+		if _, err := msgMgr.Send(repoName, "daemon", agentName, msg); err != nil {
+			d.logger.Warn("Failed to tell %s/%s about the new supervisor: %v", repoName, agentName, err)
+		}
+	}
+	d.logger.Info("Repo %s now has a supervisor", repoName)
+}
+
+// applyReviewGate brings a running merge-queue agent in line with changed
+// review settings: its prompt file is rewritten for the next restart, and it
+// is told now, since a running agent does not reread its prompt
+func (d *Daemon) applyReviewGate(repoName string, mqConfig state.MergeQueueConfig) {
+	agent, exists := d.state.GetAgent(repoName, "merge-queue")
+	if !exists || agent.Type != state.AgentTypeMergeQueue {
+		return
+	}
+
+	if _, err := d.writeMergeQueuePromptFile(repoName, "merge-queue", mqConfig); err != nil {
+		d.logger.Warn("Failed to rewrite merge-queue prompt for repo %s: %v", repoName, err)
+	}
+
+	msg := "Review gating has been turned off for this repository: merge PRs without waiting for a review agent's approval."
+	if gate := prompts.GenerateReviewGatePrompt(mqConfig.MQReviewRequired, mqConfig.MQReviewPattern); gate != "" {
+		msg = "The review settings of this repository changed. From now on:\n\n" + gate
+	}
+	if _, err := d.getMessageManager().Send(repoName, "daemon", "merge-queue", msg); err != nil {
+		d.logger.Warn("Failed to tell merge-queue of repo %s about review settings: %v", repoName, err)
+	}
+}
+
 // writeMergeQueuePromptFile writes a merge-queue prompt file with tracking mode configuration
 func (d *Daemon) writeMergeQueuePromptFile(repoName string, agentName string, mqConfig state.MergeQueueConfig) (string, error) {
 	repoPath := d.paths.RepoDir(repoName)
@@ -1867,7 +2812,16 @@ func (d *Daemon) writeMergeQueuePromptFile(repoName string, agentName string, mq
 
 	// Add tracking mode configuration to the prompt
 	trackingConfig := prompts.GenerateTrackingModePrompt(string(mqConfig.TrackMode))
+	if reviewGate := prompts.GenerateReviewGatePrompt(mqConfig.MQReviewRequired, mqConfig.MQReviewPattern); reviewGate != "" {
+		trackingConfig += "\n\n" + reviewGate
+	}
 	promptText = trackingConfig + "\n\n" + promptText
+	if repo, exists := d.state.GetRepo(repoName); exists {
+		if repo.NoSupervisor {
+			promptText = prompts.WithoutSupervisor(prompts.TypeMergeQueue, promptText)
+		}
+		promptText = prompts.WithContext(promptText, repo.ContextVars)
+	}
 
 	// Create prompt file in prompts directory
 	promptDir := filepath.Join(d.paths.Root, "prompts")
@@ -1894,7 +2848,7 @@ func (d *Daemon) restartAgent(repoName, agentName string, agent state.Agent, rep
 	}
 
 	claudeProjectsDir := filepath.Join(home, ".claude", "projects")
-	encodedPath := strings.ReplaceAll(agent.WorktreePath, "/", "-")
+	encodedPath := strings.ReplaceAll(agent.WorkDir(), "/", "-")
 	sessionFile := filepath.Join(claudeProjectsDir, encodedPath, agent.SessionID+".jsonl")
 
 	hasHistory := false
@@ -1902,22 +2856,51 @@ func (d *Daemon) restartAgent(repoName, agentName string, agent state.Agent, rep
 		hasHistory = true
 	}
 
-	// Get the existing prompt file path
-	promptFile := filepath.Join(d.paths.Root, "prompts", agentName+".md")
-	if _, err := os.Stat(promptFile); os.IsNotExist(err) {
-		// Regenerate the prompt file if it doesn't exist
-		promptFile, err = d.writePromptFile(repoName, prompts.AgentType(agent.Type), agentName)
+	// Claude started without its prompt file would run with no system
+	// prompt, so regenerate it if it was deleted or emptied
+	promptFile, regenerated, err := d.ensurePromptFile(repoName, agentName, agent, repo)
+	if err != nil {
+		return err
+	}
+	if regenerated {
+		d.logger.Warn("Regenerated missing prompt file of %s/%s before restarting it", repoName, agentName)
+	}
+
+	// A repository with a pinned binary restarts with that binary or not at all
+	runner := d.claudeRunner
+	if repo.ClaudePath != "" {
+		binaryPath, err := d.getClaudeBinaryPath(repo)
 		if err != nil {
-			return fmt.Errorf("failed to regenerate prompt file: %w", err)
+			return err
 		}
+		runner = claude.NewRunner(claude.WithTerminal(d.tmux), claude.WithBinaryPath(binaryPath))
+	}
+
+	if err := d.checkClaudeVersion(repo, runner.BinaryPath); err != nil {
+		return err
+	}
+
+	// A merge-queue agent without history to resume starts over, so remind
+	// it which PRs it was tracking
+	initialMessage := ""
+	if agent.Type == state.AgentTypeMergeQueue && !hasHistory {
+		initialMessage = d.trackedPRsSummary(repoName)
+	}
+
+	envKeys, err := d.applyAgentEnv(repoName, repo.TmuxSession, agent)
+	if err != nil {
+		return err
 	}
 
 	// Restart Claude using the runner
 	// Note: Slash commands are embedded in prompts, not via CLAUDE_CONFIG_DIR
-	result, err := d.claudeRunner.Start(d.ctx, repo.TmuxSession, agentName, claude.Config{
+	result, err := runner.Start(d.ctx, repo.TmuxSession, agentName, claude.Config{
 		SessionID:        agent.SessionID,
 		Resume:           hasHistory,
+		WorkDir:          agent.WorkDir(),
 		SystemPromptFile: promptFile,
+		EnvKeys:          envKeys,
+		InitialMessage:   initialMessage,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to restart Claude: %w", err)
@@ -1927,11 +2910,91 @@ func (d *Daemon) restartAgent(repoName, agentName string, agent state.Agent, rep
 	if err := d.state.UpdateAgentPID(repoName, agentName, result.PID); err != nil {
 		d.logger.Warn("Failed to update agent PID: %v", err)
 	}
+	if err := d.state.UpdateAgentPromptHash(repoName, agentName, hashPromptFile(promptFile), promptDocsHash(promptFile)); err != nil {
+		d.logger.Warn("Failed to record prompt hash of %s/%s: %v", repoName, agentName, err)
+	}
 
 	d.logger.Info("Restarted agent %s with PID %d (resumed=%v)", agentName, result.PID, hasHistory)
 	return nil
 }
 
+// applyRepoEnv sets the repository's env file variables on its tmux session
+// and returns their names. Failures are logged (without values) and yield no keys.
+func (d *Daemon) applyRepoEnv(repoName, tmuxSession string) []string {
+	envFile := ""
+	if repo, exists := d.state.GetRepo(repoName); exists {
+		envFile = repo.EnvFile
+	}
+
+	env, err := envfile.ForRepo(d.paths.RepoDir(repoName), envFile)
+	if err != nil {
+		d.logger.Warn("Failed to load env for repo %s: %v", repoName, err)
+		return nil
+	}
+
+	keys := envfile.Keys(env)
+	for _, key := range keys {
+		if err := d.tmux.SetEnvironment(d.ctx, tmuxSession, key, env[key]); err != nil {
+			d.logger.Warn("Failed to set %s in tmux session %s: %v", key, tmuxSession, err)
+			return nil
+		}
+	}
+	if len(keys) > 0 {
+		d.logger.Debug("Applied %d env variable(s) to session %s", len(keys), tmuxSession)
+	}
+	return keys
+}
+
+// applyAgentEnv prepares the agent's window for launching Claude. The
+// repository's env goes on the tmux session like applyRepoEnv; the agent's own
+// Environment is given to its pane only, by respawning the pane with it, so
+// neither later windows nor other agents see it. It returns the repository
+// variables the shell must import, leaving out those the agent overrides.
+func (d *Daemon) applyAgentEnv(repoName, tmuxSession string, agent state.Agent) ([]string, error) {
+	keys := d.applyRepoEnv(repoName, tmuxSession)
+	if len(agent.Environment) == 0 {
+		return keys, nil
+	}
+
+	if err := d.tmux.RestartWithEnv(d.ctx, tmuxSession, agent.WindowTarget(), agent.Environment); err != nil {
+		return nil, fmt.Errorf("failed to set agent environment: %w", err)
+	}
+
+	var imported []string
+	for _, key := range keys {
+		if _, overridden := agent.Environment[key]; !overridden {
+			imported = append(imported, key)
+		}
+	}
+	return imported, nil
+}
+
+// redactSecrets replaces the values of a repository's env files and of its
+// agents' environments in text, e.g. pane output passed on to an agent
+func (d *Daemon) redactSecrets(repoName, text string) string {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return text
+	}
+	var values []string
+	if env, err := envfile.ForRepo(d.paths.RepoDir(repoName), repo.EnvFile); err == nil {
+		for _, v := range env {
+			values = append(values, v)
+		}
+	}
+	for _, agent := range repo.Agents {
+		for _, v := range agent.Environment {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return text
+	}
+	r := redact.New()
+	r.AddSecrets(values...)
+	return r.Secrets(text)
+}
+
 // writePromptFile writes the agent prompt to a file and returns the path
 func (d *Daemon) writePromptFile(repoName string, agentType prompts.AgentType, agentName string) (string, error) {
 	repoPath := d.paths.RepoDir(repoName)
@@ -1941,6 +3004,15 @@ func (d *Daemon) writePromptFile(repoName string, agentType prompts.AgentType, a
 	if err != nil {
 		return "", fmt.Errorf("failed to get prompt: %w", err)
 	}
+	if repo, exists := d.state.GetRepo(repoName); exists {
+		if repo.NoSupervisor {
+			promptText = prompts.WithoutSupervisor(agentType, promptText)
+		}
+		identity := repo.GitIdentity
+		promptText = prompts.WithGitIdentity(agentType, promptText,
+			prompts.GenerateGitIdentityPrompt(identity.CommitterName(), identity.Email, identity.SignCommits, identity.EffectiveSigningFormat()))
+		promptText = prompts.WithContext(promptText, repo.ContextVars)
+	}
 
 	// Create prompt file in prompts directory
 	promptDir := filepath.Join(d.paths.Root, "prompts")
@@ -1956,20 +3028,8 @@ func (d *Daemon) writePromptFile(repoName string, agentType prompts.AgentType, a
 	return promptPath, nil
 }
 
-// isProcessAlive checks if a process is running
-func isProcessAlive(pid int) bool {
-	process, err := os.FindProcess(pid)
-	if err != nil {
-		return false
-	}
-
-	// Send signal 0 to check if process exists (doesn't actually signal, just checks)
-	err = process.Signal(syscall.Signal(0))
-	return err == nil
-}
-
 // Run runs the daemon in the foreground
-func Run() error {
+func Run(opts Options) error {
 	paths, err := config.DefaultPaths()
 	if err != nil {
 		return fmt.Errorf("failed to get paths: %w", err)
@@ -1979,6 +3039,10 @@ func Run() error {
 	if err != nil {
 		return fmt.Errorf("failed to create daemon: %w", err)
 	}
+	if opts.WatchFS {
+		d.EnableFSWatch()
+	}
+	d.currentDocsHash = opts.DocsHash
 
 	if err := d.Start(); err != nil {
 		return fmt.Errorf("failed to start daemon: %w", err)
@@ -1991,7 +3055,7 @@ func Run() error {
 }
 
 // RunDetached starts the daemon in detached mode
-func RunDetached() error {
+func RunDetached(opts Options) error {
 	paths, err := config.DefaultPaths()
 	if err != nil {
 		return fmt.Errorf("failed to get paths: %w", err)
@@ -2003,9 +3067,10 @@ func RunDetached() error {
 		return fmt.Errorf("daemon already running (PID: %d)", pid)
 	}
 
-	// Ensure config directory exists
-	if err := os.MkdirAll(paths.Root, 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+	// Ensure the directories exist, including the log's (under the cache
+	// directory in the XDG layout)
+	if err := paths.EnsureDirectories(); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
 	}
 
 	// Create log file for output
@@ -2033,7 +3098,11 @@ func RunDetached() error {
 	}
 
 	// Start daemon process
-	process, err := os.StartProcess(executable, []string{executable, "daemon", "_run"}, attr)
+	argv := []string{executable, "daemon", "_run"}
+	if opts.WatchFS {
+		argv = append(argv, "--watch-fs")
+	}
+	process, err := os.StartProcess(executable, argv, attr)
 	if err != nil {
 		return fmt.Errorf("failed to start daemon process: %w", err)
 	}
@@ -2047,63 +3116,6 @@ func RunDetached() error {
 	return nil
 }
 
-// MaxLogFileSize is the threshold for log rotation (10MB)
-const MaxLogFileSize = 10 * 1024 * 1024
-
-// rotateLogsIfNeeded checks log files and rotates any that exceed MaxLogFileSize
-func (d *Daemon) rotateLogsIfNeeded() {
-	d.logger.Debug("Checking for log rotation")
-
-	err := filepath.Walk(d.paths.OutputDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
-		if info.IsDir() {
-			return nil
-		}
-		if !isLogFile(path) {
-			return nil
-		}
-
-		if info.Size() > MaxLogFileSize {
-			if err := d.rotateLog(path); err != nil {
-				d.logger.Error("Failed to rotate log %s: %v", path, err)
-			} else {
-				d.logger.Info("Rotated log %s (was %d bytes)", path, info.Size())
-			}
-		}
-		return nil
-	})
-
-	if err != nil {
-		d.logger.Error("Failed to walk output directory for log rotation: %v", err)
-	}
-}
-
-// rotateLog rotates a single log file by renaming it with a timestamp suffix
-func (d *Daemon) rotateLog(logPath string) error {
-	// Generate rotated filename with timestamp
-	timestamp := time.Now().Format("20060102-150405")
-	rotatedPath := logPath + "." + timestamp
-
-	// Rename the current log file
-	if err := os.Rename(logPath, rotatedPath); err != nil {
-		return fmt.Errorf("failed to rename log: %w", err)
-	}
-
-	// The tmux pipe-pane will create a new file automatically when it next writes
-	// No need to recreate the file or restart the pipe
-
-	return nil
-}
-
-// isLogFile checks if a file is a log file
-func isLogFile(path string) bool {
-	base := filepath.Base(path)
-	// Only match .log files, not already-rotated files (which have timestamps)
-	return len(base) > 4 && base[len(base)-4:] == ".log"
-}
-
 // linkGlobalCredentials creates a symlink from the Claude config directory's .credentials.json
 // to the global ~/.claude/.credentials.json. This ensures workers can access OAuth
 // credentials without duplicating sensitive files.
@@ -2,17 +2,22 @@ package daemon
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/dlorenc/multiclaude/internal/hooks"
 	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/procs"
 	"github.com/dlorenc/multiclaude/internal/prompts"
 	"github.com/dlorenc/multiclaude/internal/socket"
 	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/claude"
 	"github.com/dlorenc/multiclaude/pkg/config"
 	"github.com/dlorenc/multiclaude/pkg/tmux"
 )
@@ -244,6 +249,68 @@ func TestCleanupDeadAgentsCleansConfigDir(t *testing.T) {
 	}
 }
 
+func TestHandleUpdateAgentPR(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "test-session",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	agent := state.Agent{
+		Type:         state.AgentTypeWorkspace,
+		WorktreePath: "/tmp/test",
+		TmuxWindow:   "my-ws",
+		CreatedAt:    time.Now(),
+	}
+	if err := d.state.AddAgent("test-repo", "my-ws", agent); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+
+	// Missing PR URL
+	resp := d.handleUpdateAgentPR(socket.Request{
+		Command: "update_agent_pr",
+		Args:    map[string]interface{}{"repo": "test-repo", "agent": "my-ws"},
+	})
+	if resp.Success {
+		t.Error("Expected failure with missing pr_url")
+	}
+
+	// Unknown agent
+	resp = d.handleUpdateAgentPR(socket.Request{
+		Command: "update_agent_pr",
+		Args:    map[string]interface{}{"repo": "test-repo", "agent": "nope", "pr_url": "https://github.com/test/repo/pull/7"},
+	})
+	if resp.Success {
+		t.Error("Expected failure for non-existent agent")
+	}
+
+	resp = d.handleUpdateAgentPR(socket.Request{
+		Command: "update_agent_pr",
+		Args: map[string]interface{}{
+			"repo":      "test-repo",
+			"agent":     "my-ws",
+			"pr_url":    "https://github.com/test/repo/pull/7",
+			"pr_number": float64(7),
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("handleUpdateAgentPR() failed: %s", resp.Error)
+	}
+
+	updated, _ := d.state.GetAgent("test-repo", "my-ws")
+	if updated.PRURL != "https://github.com/test/repo/pull/7" || updated.PRNumber != 7 {
+		t.Errorf("PR not recorded: url=%q number=%d", updated.PRURL, updated.PRNumber)
+	}
+	if updated.ReadyForCleanup {
+		t.Error("Recording a PR should not mark the agent for cleanup")
+	}
+}
+
 func TestHandleCompleteAgent(t *testing.T) {
 	d, cleanup := setupTestDaemon(t)
 	defer cleanup()
@@ -433,14 +500,14 @@ func TestHandleRestartAgent(t *testing.T) {
 func TestIsProcessAlive(t *testing.T) {
 	// Test with PID 1 (init, should be alive on Unix systems)
 	// This is more reliable than testing our own process
-	if isProcessAlive(1) {
+	if procs.IsAlive(1) {
 		t.Log("PID 1 is alive (as expected)")
 	} else {
 		t.Skip("PID 1 not available on this system")
 	}
 
 	// Test with very high invalid PID (should be dead)
-	if isProcessAlive(999999) {
+	if procs.IsAlive(999999) {
 		t.Error("Invalid PID 999999 should be reported as dead")
 	}
 }
@@ -469,6 +536,13 @@ func TestHandleStatus(t *testing.T) {
 		t.Fatalf("Failed to add agent: %v", err)
 	}
 
+	msgMgr := messages.NewManager(d.paths.MessagesDir)
+	for i := 0; i < 2; i++ {
+		if _, err := msgMgr.Send("test-repo", "worker1", "supervisor", "done"); err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+	}
+
 	resp := d.handleStatus(socket.Request{Command: "status"})
 
 	if !resp.Success {
@@ -491,6 +565,11 @@ func TestHandleStatus(t *testing.T) {
 	if agents, ok := data["agents"].(int); !ok || agents != 1 {
 		t.Errorf("handleStatus() agents = %v, want 1", data["agents"])
 	}
+
+	stats, _ := data["messages"].(map[string]*messages.MessageSummary)
+	if summary := stats["test-repo"]; summary == nil || summary.TotalMessages != 2 || summary.PendingMessages != 2 || summary.OldestPending == nil {
+		t.Errorf("handleStatus() messages[test-repo] = %+v, want 2 pending", stats["test-repo"])
+	}
 }
 
 func TestHandleListRepos(t *testing.T) {
@@ -722,6 +801,80 @@ func TestHandleAddAgent(t *testing.T) {
 	}
 }
 
+func TestRemovedAgentMessagesBounce(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "test-session",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor"},
+			"removed":    {Type: state.AgentTypeWorker, TmuxWindow: "removed"},
+			"dead":       {Type: state.AgentTypeWorker, TmuxWindow: "dead"},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	msgMgr := d.getMessageManager()
+	send := func(to, body string, status messages.Status) {
+		t.Helper()
+		msg, err := msgMgr.Send("test-repo", "supervisor", to, body)
+		if err != nil {
+			t.Fatalf("Send() failed: %v", err)
+		}
+		if status != messages.StatusPending {
+			if err := msgMgr.UpdateStatus("test-repo", to, msg.ID, status); err != nil {
+				t.Fatalf("UpdateStatus() failed: %v", err)
+			}
+		}
+	}
+	send("removed", "pending for removed", messages.StatusPending)
+	send("removed", "read by removed", messages.StatusRead)
+	send("removed", "acked by removed", messages.StatusAcked)
+	send("dead", "delivered to dead", messages.StatusDelivered)
+
+	resp := d.handleRemoveAgent(socket.Request{
+		Command: "remove_agent",
+		Args:    map[string]interface{}{"repo": "test-repo", "agent": "removed"},
+	})
+	if !resp.Success {
+		t.Fatalf("handleRemoveAgent() failed: %s", resp.Error)
+	}
+	d.cleanupDeadAgents(map[string][]string{"test-repo": {"dead"}})
+
+	notices, err := msgMgr.List("test-repo", "supervisor")
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	var bodies []string
+	for _, n := range notices {
+		bodies = append(bodies, n.Body)
+	}
+	joined := strings.Join(bodies, "\n---\n")
+	if len(notices) != 2 ||
+		!strings.Contains(joined, "could not deliver to removed: agent was removed") || !strings.Contains(joined, "> pending for removed") ||
+		!strings.Contains(joined, "could not deliver to dead: agent was removed") || !strings.Contains(joined, "> delivered to dead") {
+		t.Errorf("supervisor should be told about the two unread messages, got:\n%s", joined)
+	}
+	if strings.Contains(joined, "read by removed") || strings.Contains(joined, "acked by removed") {
+		t.Errorf("read and acked messages should not bounce, got:\n%s", joined)
+	}
+
+	// The removed agent's unread message stays, marked failed, until its
+	// directory is cleaned up; the dead agent's directory is already gone
+	msgs, _ := msgMgr.List("test-repo", "removed")
+	for _, msg := range msgs {
+		if msg.Body == "pending for removed" && msg.Status != messages.StatusFailed {
+			t.Errorf("bounced message status = %s, want failed", msg.Status)
+		}
+	}
+	if msgs, _ := msgMgr.List("test-repo", "dead"); len(msgs) != 0 {
+		t.Errorf("dead agent's messages should be cleaned up, got %d", len(msgs))
+	}
+}
+
 func TestHandleRemoveAgent(t *testing.T) {
 	d, cleanup := setupTestDaemon(t)
 	defer cleanup()
@@ -861,6 +1014,165 @@ func TestHandleListAgents(t *testing.T) {
 	if len(agents) != 2 {
 		t.Errorf("handleListAgents() returned %d agents, want 2", len(agents))
 	}
+
+	// Rich format includes per-agent message statistics
+	msgMgr := messages.NewManager(d.paths.MessagesDir)
+	msg, err := msgMgr.Send("test-repo", "supervisor", "worker1", "hello")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	if _, err := msgMgr.Send("test-repo", "supervisor", "worker1", "again"); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	if err := msgMgr.Ack("test-repo", "worker1", msg.ID); err != nil {
+		t.Fatalf("Failed to ack message: %v", err)
+	}
+
+	resp = d.handleListAgents(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": "test-repo",
+			"rich": true,
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("handleListAgents(rich) failed: %s", resp.Error)
+	}
+	for _, agent := range resp.Data.([]map[string]interface{}) {
+		if agent["name"] != "worker1" {
+			continue
+		}
+		stats, ok := agent["message_stats"].(*messages.MessageSummary)
+		if !ok {
+			t.Fatalf("message_stats = %T, want *messages.MessageSummary", agent["message_stats"])
+		}
+		if stats.TotalMessages != 2 || stats.PendingMessages != 1 || stats.AckedMessages != 1 {
+			t.Errorf("message_stats = %+v, want 1 pending and 1 acked", stats)
+		}
+		if agent["messages_total"] != 2 || agent["messages_pending"] != 1 {
+			t.Errorf("messages_total/pending = %v/%v, want 2/1", agent["messages_total"], agent["messages_pending"])
+		}
+	}
+}
+
+func TestHandleGetAgent(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "test-session",
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	nudged := time.Now().Add(-time.Minute)
+	if err := d.state.AddAgent("test-repo", "worker1", state.Agent{
+		Type:       state.AgentTypeWorker,
+		TmuxWindow: "worker1",
+		SessionID:  "session-worker1",
+		CreatedAt:  time.Now(),
+		LastNudge:  nudged,
+	}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+	if _, err := messages.NewManager(d.paths.MessagesDir).Send("test-repo", "supervisor", "worker1", "hello"); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	get := func(args map[string]interface{}) socket.Response {
+		return d.handleGetAgent(socket.Request{Command: "get_agent", Args: args})
+	}
+
+	if resp := get(map[string]interface{}{"repo": "test-repo"}); resp.Success {
+		t.Error("handleGetAgent() should fail without an agent name")
+	}
+
+	resp := get(map[string]interface{}{"repo": "test-repo", "agent": "worker1"})
+	if !resp.Success {
+		t.Fatalf("handleGetAgent() failed: %s", resp.Error)
+	}
+	data := resp.Data.(map[string]interface{})
+	if data["registered"] != true || data["type"] != state.AgentTypeWorker || data["session_id"] != "session-worker1" {
+		t.Errorf("handleGetAgent() = %v, want worker1's record", data)
+	}
+	if data["messages_pending"] != 1 || !data["last_nudge"].(time.Time).Equal(nudged) {
+		t.Errorf("messages_pending/last_nudge = %v/%v, want 1/%v", data["messages_pending"], data["last_nudge"], nudged)
+	}
+
+	// Unknown agents and repositories are reported, not failed
+	for _, args := range []map[string]interface{}{
+		{"repo": "test-repo", "agent": "removed"},
+		{"repo": "other-repo", "agent": "worker1"},
+	} {
+		resp := get(args)
+		if !resp.Success {
+			t.Fatalf("handleGetAgent(%v) failed: %s", args, resp.Error)
+		}
+		data := resp.Data.(map[string]interface{})
+		if data["registered"] != false || data["repo_tracked"] != (args["repo"] == "test-repo") {
+			t.Errorf("handleGetAgent(%v) = %v, want it not registered", args, data)
+		}
+	}
+}
+
+func TestHandleListAgentsCommitsAhead(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.email=test@example.com", "-c", "user.name=Test"}, args...)...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	// A clone of an origin with one commit, and a worker two commits ahead
+	origin := filepath.Join(t.TempDir(), "origin")
+	if err := os.MkdirAll(origin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	git(origin, "init", "-b", "main")
+	git(origin, "commit", "--allow-empty", "-m", "initial")
+	repoPath := d.paths.RepoDir("test-repo")
+	git(filepath.Dir(repoPath), "clone", origin, repoPath)
+	wtPath := filepath.Join(t.TempDir(), "worker1")
+	git(repoPath, "worktree", "add", "-b", "work/worker1", wtPath, "origin/main")
+	git(wtPath, "commit", "--allow-empty", "-m", "one")
+	git(wtPath, "commit", "--allow-empty", "-m", "two")
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"worker1":    {Type: state.AgentTypeWorker, WorktreePath: wtPath},
+			"supervisor": {Type: state.AgentTypeSupervisor, WorktreePath: repoPath},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	resp := d.handleListAgents(socket.Request{
+		Command: "list_agents",
+		Args:    map[string]interface{}{"repo": "test-repo", "rich": true},
+	})
+	if !resp.Success {
+		t.Fatalf("handleListAgents(rich) failed: %s", resp.Error)
+	}
+	for _, agent := range resp.Data.([]map[string]interface{}) {
+		switch agent["name"] {
+		case "worker1":
+			if agent["commits_ahead"] != 2 {
+				t.Errorf("worker1 commits_ahead = %v, want 2", agent["commits_ahead"])
+			}
+		case "supervisor":
+			if _, ok := agent["commits_ahead"]; ok {
+				t.Error("only workers should report commits_ahead")
+			}
+		}
+	}
 }
 
 func TestHandleRequest(t *testing.T) {
@@ -1035,6 +1347,143 @@ func TestWorkspaceAgentExcludedFromWakeLoop(t *testing.T) {
 	// we can only verify the workspace was skipped (verified above)
 }
 
+func TestApplyAgentEnvWithRealTmux(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Skip("tmux not available")
+	}
+
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	sessionName := "mc-test-agent-env"
+	if err := tmuxClient.CreateSession(context.Background(), sessionName, true); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(context.Background(), sessionName)
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		TmuxSession: sessionName,
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	envDir := filepath.Join(d.paths.RepoDir("test-repo"), ".multiclaude")
+	if err := os.MkdirAll(envDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(envDir, "env"), []byte("ANTHROPIC_API_KEY=repo-key\nREGION=eu\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tmuxClient.CreateWindow(context.Background(), sessionName, "worker-1"); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	keys, err := d.applyAgentEnv("test-repo", sessionName, state.Agent{
+		TmuxWindow:  "worker-1",
+		Environment: map[string]string{"ANTHROPIC_API_KEY": "vault-key"},
+	})
+	if err != nil {
+		t.Fatalf("applyAgentEnv() failed: %v", err)
+	}
+	if strings.Join(keys, ",") != "REGION" {
+		t.Errorf("keys = %v, want only the repo variables the agent does not override", keys)
+	}
+
+	// The agent's value is in its pane only
+	if got := paneEnv(t, tmuxClient, sessionName, "worker-1")["ANTHROPIC_API_KEY"]; got != "vault-key" {
+		t.Errorf("pane ANTHROPIC_API_KEY = %q, want the agent's value", got)
+	}
+	value, _, err := tmuxClient.GetEnvironment(context.Background(), sessionName, "ANTHROPIC_API_KEY")
+	if err != nil {
+		t.Fatalf("GetEnvironment() failed: %v", err)
+	}
+	if value != "repo-key" {
+		t.Errorf("session ANTHROPIC_API_KEY = %q, want the repo's value", value)
+	}
+}
+
+func TestHandleSetAgentEnvWithRealTmux(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Skip("tmux not available")
+	}
+
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	// Launch a no-op in place of Claude
+	d.claudeRunner = claude.NewRunner(
+		claude.WithTerminal(tmuxClient),
+		claude.WithBinaryPath("true"),
+		claude.WithStartupDelay(10*time.Millisecond),
+	)
+
+	sessionName := "mc-test-set-agent-env"
+	if err := tmuxClient.CreateSession(context.Background(), sessionName, true); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(context.Background(), sessionName)
+	if err := tmuxClient.CreateWindow(context.Background(), sessionName, "worker-1"); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		TmuxSession: sessionName,
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	if err := d.state.AddAgent("test-repo", "worker-1", state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: t.TempDir(),
+		TmuxWindow:   "worker-1",
+		SessionID:    "test-session-id",
+	}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+
+	resp := d.handleSetAgentEnv(socket.Request{
+		Command: "set_agent_env",
+		Args: map[string]interface{}{
+			"repo":  "test-repo",
+			"agent": "worker-1",
+			"env":   map[string]interface{}{"ANTHROPIC_API_KEY": "vault-key"},
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("handleSetAgentEnv() failed: %s", resp.Error)
+	}
+
+	if got := paneEnv(t, tmuxClient, sessionName, "worker-1")["ANTHROPIC_API_KEY"]; got != "vault-key" {
+		t.Errorf("pane ANTHROPIC_API_KEY = %q, want the value set", got)
+	}
+	if _, ok, err := tmuxClient.GetEnvironment(context.Background(), sessionName, "ANTHROPIC_API_KEY"); err != nil || ok {
+		t.Errorf("ANTHROPIC_API_KEY set in the session environment (ok=%v, err=%v)", ok, err)
+	}
+}
+
+// paneEnv returns the environment of the process running in a tmux window's
+// pane, read from /proc
+func paneEnv(t *testing.T, tmuxClient *tmux.Client, session, window string) map[string]string {
+	t.Helper()
+	pid, err := tmuxClient.GetPanePID(context.Background(), session, window)
+	if err != nil {
+		t.Fatalf("GetPanePID() failed: %v", err)
+	}
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/environ", pid))
+	if err != nil {
+		t.Skipf("cannot read pane environment: %v", err)
+	}
+	env := make(map[string]string)
+	for _, entry := range strings.Split(string(data), "\x00") {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			env[key] = value
+		}
+	}
+	return env
+}
+
 func TestHealthCheckLoopWithRealTmux(t *testing.T) {
 	tmuxClient := tmux.NewClient()
 	if !tmuxClient.IsTmuxAvailable() {
@@ -1262,11 +1711,13 @@ func TestWakeLoopUpdatesNudgeTime(t *testing.T) {
 		t.Fatalf("Failed to create supervisor window: %v", err)
 	}
 
-	// Add repo and agent with zero LastNudge
+	// Add repo and agent with zero LastNudge. The agent has no news, so
+	// it is only nudged because the repo asks for idle nudges.
 	repo := &state.Repository{
-		GithubURL:   "https://github.com/test/repo",
-		TmuxSession: sessionName,
-		Agents:      make(map[string]state.Agent),
+		GithubURL:     "https://github.com/test/repo",
+		TmuxSession:   sessionName,
+		Agents:        make(map[string]state.Agent),
+		NudgeWhenIdle: true,
 	}
 	if err := d.state.AddRepo("test-repo", repo); err != nil {
 		t.Fatalf("Failed to add repo: %v", err)
@@ -1481,6 +1932,36 @@ func TestDaemonRepairStateCommand(t *testing.T) {
 	}
 }
 
+func TestHandleRepairStateOneRepo(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	// Neither repository's session exists, so repair removes their agents
+	for _, name := range []string{"repo-a", "repo-b"} {
+		if err := d.state.AddRepo(name, &state.Repository{
+			TmuxSession: "mc-repair-" + name + "-nonexistent",
+			Agents:      map[string]state.Agent{"worker-1": {Type: state.AgentTypeWorker}},
+		}); err != nil {
+			t.Fatalf("Failed to add repo: %v", err)
+		}
+	}
+
+	resp := d.handleRepairState(socket.Request{Args: map[string]interface{}{"repo": "repo-a"}})
+	if !resp.Success {
+		t.Fatalf("repair_state failed: %s", resp.Error)
+	}
+	if _, exists := d.state.GetAgent("repo-a", "worker-1"); exists {
+		t.Error("repair of repo-a should remove its dead agent")
+	}
+	if _, exists := d.state.GetAgent("repo-b", "worker-1"); !exists {
+		t.Error("repair of repo-a should leave repo-b alone")
+	}
+
+	if resp := d.handleRepairState(socket.Request{Args: map[string]interface{}{"repo": "missing"}}); resp.Success {
+		t.Error("repair_state of an unknown repository should fail")
+	}
+}
+
 func TestDaemonRouteMessagesCommand(t *testing.T) {
 	d, cleanup := setupTestDaemon(t)
 	defer cleanup()
@@ -1620,7 +2101,7 @@ func TestRotateLog(t *testing.T) {
 	}
 
 	// Rotate the log
-	if err := d.rotateLog(logPath); err != nil {
+	if err := rotateLog(logPath); err != nil {
 		t.Fatalf("rotateLog() failed: %v", err)
 	}
 
@@ -1714,17 +2195,92 @@ func TestRotateLogsIfNeeded(t *testing.T) {
 	}
 }
 
-// Tests for prompt file functions
-
-func TestWritePromptFile(t *testing.T) {
+func TestRotateLogsIfNeededSymlinkedOutputDir(t *testing.T) {
 	d, cleanup := setupTestDaemon(t)
 	defer cleanup()
 
-	// Create repo directory structure
-	repoName := "test-repo"
-	repoPath := d.paths.RepoDir(repoName)
-	if err := os.MkdirAll(repoPath, 0755); err != nil {
-		t.Fatalf("Failed to create repo dir: %v", err)
+	// Point the output directory at another location, as when it is
+	// symlinked onto a larger disk
+	dataDir := t.TempDir()
+	if err := os.Remove(d.paths.OutputDir); err != nil {
+		t.Fatalf("Failed to remove output dir: %v", err)
+	}
+	if err := os.Symlink(dataDir, d.paths.OutputDir); err != nil {
+		t.Fatalf("Failed to symlink output dir: %v", err)
+	}
+
+	workersDir := filepath.Join(dataDir, "repo", "workers")
+	if err := os.MkdirAll(workersDir, 0755); err != nil {
+		t.Fatalf("Failed to create workers dir: %v", err)
+	}
+	largeLogPath := filepath.Join(workersDir, "large.log")
+	if err := os.WriteFile(largeLogPath, make([]byte, MaxLogFileSize+1000), 0644); err != nil {
+		t.Fatalf("Failed to create large log: %v", err)
+	}
+
+	d.rotateLogsIfNeeded()
+
+	if _, err := os.Stat(largeLogPath); !os.IsNotExist(err) {
+		t.Error("Large log under a symlinked output dir should have been rotated")
+	}
+	matches, _ := filepath.Glob(filepath.Join(workersDir, "large.log.*"))
+	if len(matches) != 1 {
+		t.Errorf("Expected 1 rotated log next to the original, found %v", matches)
+	}
+}
+
+func TestCopyTruncateLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "agent.log")
+	rotatedPath := logPath + ".20060102-150405"
+	content := []byte("line 1\nline 2\n")
+	if err := os.WriteFile(logPath, content, 0644); err != nil {
+		t.Fatalf("Failed to create log: %v", err)
+	}
+
+	if err := copyTruncateLog(logPath, rotatedPath); err != nil {
+		t.Fatalf("copyTruncateLog() failed: %v", err)
+	}
+
+	// The original stays in place (so an open writer keeps working) but empty
+	info, err := os.Stat(logPath)
+	if err != nil {
+		t.Fatalf("Original log should still exist: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("Original log size = %d, want 0", info.Size())
+	}
+	rotated, err := os.ReadFile(rotatedPath)
+	if err != nil {
+		t.Fatalf("Failed to read rotated log: %v", err)
+	}
+	if string(rotated) != string(content) {
+		t.Errorf("Rotated log content = %q, want %q", rotated, content)
+	}
+
+	// An existing rotated file is never overwritten
+	if err := os.WriteFile(logPath, content, 0644); err != nil {
+		t.Fatalf("Failed to rewrite log: %v", err)
+	}
+	if err := copyTruncateLog(logPath, rotatedPath); err == nil {
+		t.Error("copyTruncateLog() should fail when the rotated file already exists")
+	}
+	if info, _ := os.Stat(logPath); info.Size() == 0 {
+		t.Error("Original log should not be truncated when the copy fails")
+	}
+}
+
+// Tests for prompt file functions
+
+func TestWritePromptFile(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	// Create repo directory structure
+	repoName := "test-repo"
+	repoPath := d.paths.RepoDir(repoName)
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
 	}
 
 	// Write prompt file for supervisor
@@ -2325,8 +2881,58 @@ func TestHandleUpdateRepoConfigInvalidTrackMode(t *testing.T) {
 	if resp.Success {
 		t.Error("Should fail with invalid track mode")
 	}
-	if !contains(resp.Error, "invalid track mode") {
-		t.Errorf("Error should mention 'invalid track mode', got: %s", resp.Error)
+	if !contains(resp.Error, "invalid mq-track value") {
+		t.Errorf("Error should mention 'invalid mq-track value', got: %s", resp.Error)
+	}
+}
+
+func TestHandleUpdateRepoConfigKeys(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "test-session",
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	resp := d.handleUpdateRepoConfig(socket.Request{Args: map[string]interface{}{
+		"name":             "test-repo",
+		"set":              map[string]interface{}{"mq-track": "author", "snapshot-keep": "5"},
+		"worktree_limit":   3.0,
+		"agent_transports": map[string]interface{}{"worker": "tmux"},
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	resp = d.handleUpdateRepoConfig(socket.Request{Args: map[string]interface{}{
+		"name":  "test-repo",
+		"unset": []interface{}{"snapshot-keep"},
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config unset failed: %s", resp.Error)
+	}
+
+	resp = d.handleGetRepoConfig(socket.Request{Args: map[string]interface{}{"name": "test-repo"}})
+	data, _ := resp.Data.(map[string]interface{})
+	values, _ := data["config"].(map[string]string)
+	if values["mq-track"] != "author" || values["worktree-limit"] != "3" || values["snapshot-keep"] != "20" || values["transport-worker"] != "tmux" {
+		t.Errorf("config = %v", values)
+	}
+	if data["mq_track_mode"] != "author" || data["worktree_limit"] != 3 {
+		t.Errorf("legacy keys = %v, %v", data["mq_track_mode"], data["worktree_limit"])
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"name": "test-repo", "set": map[string]interface{}{"mq-trak": "all"}},
+		{"name": "test-repo", "set": map[string]interface{}{"transport": "carrier-pigeon"}},
+		{"name": "test-repo", "unset": []interface{}{"bogus"}},
+	} {
+		if resp := d.handleUpdateRepoConfig(socket.Request{Args: args}); resp.Success {
+			t.Errorf("update_repo_config %v should fail", args)
+		}
 	}
 }
 
@@ -2369,6 +2975,676 @@ func TestHandleUpdateRepoConfigSuccess(t *testing.T) {
 	}
 }
 
+func TestHandleUpdateRepoConfigArchiveRetention(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	update := func(args map[string]interface{}) socket.Response {
+		args["name"] = "test-repo"
+		return d.handleUpdateRepoConfig(socket.Request{Command: "update_repo_config", Args: args})
+	}
+
+	// Each limit can be set alone without resetting the other
+	if resp := update(map[string]interface{}{"archive_max_size_mb": float64(200)}); !resp.Success {
+		t.Fatalf("setting archive_max_size_mb failed: %s", resp.Error)
+	}
+	if resp := update(map[string]interface{}{"archive_max_age": "168h0m0s"}); !resp.Success {
+		t.Fatalf("setting archive_max_age failed: %s", resp.Error)
+	}
+	repo, _ := d.state.GetRepo("test-repo")
+	if repo.ArchiveMaxAgeDuration() != 168*time.Hour || repo.ArchiveMaxSizeMB != 200 {
+		t.Errorf("retention = %s, %d MB; want 168h, 200 MB", repo.ArchiveMaxAgeDuration(), repo.ArchiveMaxSizeMB)
+	}
+
+	if resp := update(map[string]interface{}{"archive_max_age": "whenever"}); resp.Success {
+		t.Error("an invalid archive_max_age should be rejected")
+	}
+
+	configResp := d.handleGetRepoConfig(socket.Request{
+		Command: "get_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo"},
+	})
+	data, _ := configResp.Data.(map[string]interface{})
+	if data["archive_max_age"] != "168h0m0s" || data["archive_max_size_mb"] != 200 {
+		t.Errorf("get_repo_config archive retention = %v, %v", data["archive_max_age"], data["archive_max_size_mb"])
+	}
+}
+
+func TestHandleUpdateRepoConfigReviewGate(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:        "https://github.com/test/repo",
+		TmuxSession:      "mc-test-repo",
+		MergeQueueConfig: state.DefaultMergeQueueConfig(),
+		Agents: map[string]state.Agent{
+			"merge-queue": {Type: state.AgentTypeMergeQueue},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	if err := os.MkdirAll(d.paths.RepoDir("test-repo"), 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+
+	update := func(args map[string]interface{}) socket.Response {
+		args["name"] = "test-repo"
+		return d.handleUpdateRepoConfig(socket.Request{Command: "update_repo_config", Args: args})
+	}
+
+	if resp := update(map[string]interface{}{"mq_review_pattern": "([a-z"}); resp.Success {
+		t.Error("an invalid mq_review_pattern should be rejected")
+	}
+
+	if resp := update(map[string]interface{}{"mq_review_required": true, "mq_review_pattern": "breaking:"}); !resp.Success {
+		t.Fatalf("setting review gating failed: %s", resp.Error)
+	}
+	mqConfig, _ := d.state.GetMergeQueueConfig("test-repo")
+	if !mqConfig.MQReviewRequired || mqConfig.MQReviewPattern != "breaking:" || !mqConfig.Enabled {
+		t.Errorf("merge queue config = %+v, want review of breaking: PRs", mqConfig)
+	}
+
+	configResp := d.handleGetRepoConfig(socket.Request{
+		Command: "get_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo"},
+	})
+	data, _ := configResp.Data.(map[string]interface{})
+	if data["mq_review_required"] != true || data["mq_review_pattern"] != "breaking:" {
+		t.Errorf("get_repo_config review gating = %v, %v", data["mq_review_required"], data["mq_review_pattern"])
+	}
+
+	// The merge-queue prompt is rewritten and the agent told
+	prompt, err := os.ReadFile(filepath.Join(d.paths.Root, "prompts", "merge-queue.md"))
+	if err != nil || !strings.Contains(string(prompt), "## Review Gate") {
+		t.Errorf("merge-queue prompt should contain the review gate (err %v)", err)
+	}
+	msgs, _ := d.getMessageManager().List("test-repo", "merge-queue")
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Body, "breaking:") {
+		t.Errorf("merge-queue should get one message about the review gate, got %+v", msgs)
+	}
+
+	// Unchanged settings are not announced again
+	if resp := update(map[string]interface{}{"mq_review_required": true}); !resp.Success {
+		t.Fatalf("update failed: %s", resp.Error)
+	}
+	if msgs, _ := d.getMessageManager().List("test-repo", "merge-queue"); len(msgs) != 1 {
+		t.Errorf("merge-queue got %d messages, want 1", len(msgs))
+	}
+}
+
+func TestHandleUpdateRepoConfigNameScheme(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	update := func(args map[string]interface{}) socket.Response {
+		args["name"] = "test-repo"
+		return d.handleUpdateRepoConfig(socket.Request{Command: "update_repo_config", Args: args})
+	}
+	getConfig := func() map[string]interface{} {
+		resp := d.handleGetRepoConfig(socket.Request{
+			Command: "get_repo_config",
+			Args:    map[string]interface{}{"name": "test-repo"},
+		})
+		data, _ := resp.Data.(map[string]interface{})
+		return data
+	}
+
+	if data := getConfig(); data["name_scheme"] != "docker" || data["name_template"] != "" {
+		t.Errorf("default naming = %v, %v; want docker and no template", data["name_scheme"], data["name_template"])
+	}
+
+	// The template scheme needs a template
+	if resp := update(map[string]interface{}{"name_scheme": "template"}); resp.Success {
+		t.Error("the template scheme without a template should be rejected")
+	}
+	if resp := update(map[string]interface{}{"name_scheme": "template", "name_template": "{user}-{slug}"}); !resp.Success {
+		t.Fatalf("setting the template scheme failed: %s", resp.Error)
+	}
+	// The template is kept when only the scheme changes
+	if resp := update(map[string]interface{}{"name_scheme": "task-slug"}); !resp.Success {
+		t.Fatalf("setting name_scheme failed: %s", resp.Error)
+	}
+	if data := getConfig(); data["name_scheme"] != "task-slug" || data["name_template"] != "{user}-{slug}" {
+		t.Errorf("naming = %v, %v; want task-slug and the template kept", data["name_scheme"], data["name_template"])
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"name_scheme": "random"},
+		{"name_template": "{owner}"},
+	} {
+		if resp := update(args); resp.Success {
+			t.Errorf("update %v should be rejected", args)
+		}
+	}
+}
+
+func TestPruneArchives(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	dir := d.paths.ArchivesDir("test-repo")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create archives dir: %v", err)
+	}
+	for name, age := range map[string]time.Duration{
+		"work-old-20260101-000000.bundle": state.DefaultArchiveMaxAge + time.Hour,
+		"work-new-20261001-000000.bundle": time.Hour,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("bundle"), 0644); err != nil {
+			t.Fatalf("Failed to write bundle: %v", err)
+		}
+		modTime := time.Now().Add(-age)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set mtime: %v", err)
+		}
+	}
+
+	d.pruneArchives()
+
+	if _, err := os.Stat(filepath.Join(dir, "work-old-20260101-000000.bundle")); !os.IsNotExist(err) {
+		t.Error("a bundle past the default max age should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "work-new-20261001-000000.bundle")); err != nil {
+		t.Errorf("a recent bundle should be kept: %v", err)
+	}
+
+	// A repository's own retention replaces the defaults
+	if err := d.state.AddRepo("tight-repo", &state.Repository{
+		Agents:           make(map[string]state.Agent),
+		ArchiveMaxAge:    "2h0m0s",
+		ArchiveMaxSizeMB: 1,
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	dir = d.paths.ArchivesDir("tight-repo")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create archives dir: %v", err)
+	}
+	bundle := make([]byte, 700*1024)
+	for name, age := range map[string]time.Duration{
+		"work-expired.bundle": 3 * time.Hour,
+		"work-older.bundle":   time.Hour,
+		"work-newer.bundle":   30 * time.Minute,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, bundle, 0644); err != nil {
+			t.Fatalf("Failed to write bundle: %v", err)
+		}
+		modTime := time.Now().Add(-age)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set mtime: %v", err)
+		}
+	}
+
+	d.pruneArchives()
+
+	for name, kept := range map[string]bool{
+		"work-expired.bundle": false, // past the repository's max age
+		"work-older.bundle":   false, // over the repository's size cap
+		"work-newer.bundle":   true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if kept && err != nil {
+			t.Errorf("%s should be kept: %v", name, err)
+		} else if !kept && !os.IsNotExist(err) {
+			t.Errorf("%s should be removed by the repository's retention", name)
+		}
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	repoPath := d.paths.RepoDir("test-repo")
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	cmd := exec.Command("git", "init", "-b", "main", repoPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to init git repo: %v\n%s", err, output)
+	}
+
+	base := time.Date(2026, 6, 12, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if _, err := worktree.CreateSnapshot(repoPath, "dev", "", base.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("CreateSnapshot() failed: %v", err)
+		}
+	}
+
+	if resp := d.handleUpdateRepoConfig(socket.Request{
+		Command: "update_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo", "snapshot_keep": 2},
+	}); !resp.Success {
+		t.Fatalf("setting snapshot_keep failed: %s", resp.Error)
+	}
+	configResp := d.handleGetRepoConfig(socket.Request{
+		Command: "get_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo"},
+	})
+	if data, _ := configResp.Data.(map[string]interface{}); data["snapshot_keep"] != 2 {
+		t.Errorf("snapshot_keep = %v, want 2", data["snapshot_keep"])
+	}
+
+	d.pruneSnapshots()
+
+	snapshots, err := worktree.ListSnapshots(repoPath, "dev")
+	if err != nil {
+		t.Fatalf("ListSnapshots() failed: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[1].ID != "20260612-090100" {
+		t.Errorf("snapshots after pruning = %+v, want the newest two", snapshots)
+	}
+
+	if resp := d.handleUpdateRepoConfig(socket.Request{
+		Command: "update_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo", "snapshot_keep": -1},
+	}); resp.Success {
+		t.Error("a negative snapshot_keep should be rejected")
+	}
+}
+
+func TestRefreshSubmodules(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	repoPath := d.paths.RepoDir("test-repo")
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, ".gitmodules"), []byte("[submodule \"lib\"]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .gitmodules: %v", err)
+	}
+
+	d.refreshSubmodules()
+	repo, _ := d.state.GetRepo("test-repo")
+	if !repo.HasSubmodules {
+		t.Error("HasSubmodules should be set for a repo with .gitmodules")
+	}
+
+	if resp := d.handleUpdateRepoConfig(socket.Request{
+		Command: "update_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo", "submodule_timeout": "2m"},
+	}); !resp.Success {
+		t.Fatalf("setting submodule_timeout failed: %s", resp.Error)
+	}
+	configResp := d.handleGetRepoConfig(socket.Request{
+		Command: "get_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo"},
+	})
+	data, _ := configResp.Data.(map[string]interface{})
+	if data["has_submodules"] != true || data["submodule_timeout"] != "2m0s" {
+		t.Errorf("get_repo_config submodules = %v, %v", data["has_submodules"], data["submodule_timeout"])
+	}
+
+	if err := os.Remove(filepath.Join(repoPath, ".gitmodules")); err != nil {
+		t.Fatalf("Failed to remove .gitmodules: %v", err)
+	}
+	d.refreshSubmodules()
+	repo, _ = d.state.GetRepo("test-repo")
+	if repo.HasSubmodules {
+		t.Error("HasSubmodules should be cleared once .gitmodules is gone")
+	}
+}
+
+func TestAutoAckMessages(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		Agents: map[string]state.Agent{"worker1": {Type: state.AgentTypeWorker, TmuxWindow: "worker1"}},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	msgMgr := d.getMessageManager()
+	old, _ := msgMgr.Send("test-repo", "supervisor", "worker1", "read long ago")
+	queued, _ := msgMgr.Send("test-repo", "supervisor", "worker1", "not delivered yet")
+	if err := msgMgr.UpdateStatus("test-repo", "worker1", old.ID, messages.StatusRead); err != nil {
+		t.Fatalf("UpdateStatus() failed: %v", err)
+	}
+
+	// Off by default
+	d.autoAckMessages()
+	if msg, _ := msgMgr.Get("test-repo", "worker1", old.ID); msg.Status != messages.StatusRead {
+		t.Errorf("status without auto-ack = %s, want read", msg.Status)
+	}
+
+	resp := d.handleListAgents(socket.Request{Command: "list_agents", Args: map[string]interface{}{"repo": "test-repo", "rich": true}})
+	agents, _ := resp.Data.([]map[string]interface{})
+	if len(agents) != 1 || agents[0]["messages_undelivered"] != 1 || agents[0]["messages_awaiting_ack"] != 1 {
+		t.Errorf("list_agents message counts = %v", agents)
+	}
+
+	if resp := d.handleUpdateRepoConfig(socket.Request{
+		Command: "update_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo", "auto_ack_after": "1ns"},
+	}); !resp.Success {
+		t.Fatalf("setting auto_ack_after failed: %s", resp.Error)
+	}
+	d.autoAckMessages()
+	if msg, _ := msgMgr.Get("test-repo", "worker1", old.ID); msg.Status != messages.StatusAcked || !msg.AutoAcked {
+		t.Errorf("read message after auto-ack = %+v, want auto-acked", msg)
+	}
+	if msg, _ := msgMgr.Get("test-repo", "worker1", queued.ID); msg.Status != messages.StatusPending {
+		t.Errorf("queued message after auto-ack = %s, want pending", msg.Status)
+	}
+}
+
+func TestHandleAddAgentEnforcesWorkerLimit(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "test-session",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	// Set the limit the way the CLI does (JSON numbers decode as float64)
+	resp := d.handleUpdateRepoConfig(socket.Request{
+		Command: "update_repo_config",
+		Args: map[string]interface{}{
+			"name":                   "test-repo",
+			"max_concurrent_workers": float64(1),
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("handleUpdateRepoConfig() failed: %s", resp.Error)
+	}
+
+	addAgent := func(name, agentType string) socket.Response {
+		return d.handleAddAgent(socket.Request{
+			Command: "add_agent",
+			Args: map[string]interface{}{
+				"repo":          "test-repo",
+				"agent":         name,
+				"type":          agentType,
+				"worktree_path": "/tmp/" + name,
+				"tmux_window":   name,
+			},
+		})
+	}
+
+	if resp := addAgent("worker-1", "worker"); !resp.Success {
+		t.Fatalf("first worker should be allowed: %s", resp.Error)
+	}
+	if resp := addAgent("worker-2", "worker"); resp.Success {
+		t.Error("second worker should be rejected by the limit")
+	}
+	for _, agentType := range []string{"supervisor", "merge-queue", "workspace"} {
+		if resp := addAgent(agentType, agentType); !resp.Success {
+			t.Errorf("%s should be exempt from the worker limit: %s", agentType, resp.Error)
+		}
+	}
+
+	configResp := d.handleGetRepoConfig(socket.Request{
+		Command: "get_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo"},
+	})
+	data, _ := configResp.Data.(map[string]interface{})
+	if data["max_concurrent_workers"] != 1 {
+		t.Errorf("max_concurrent_workers = %v, want 1", data["max_concurrent_workers"])
+	}
+
+	// Resetting removes the limit
+	resp = d.handleUpdateRepoConfig(socket.Request{
+		Command: "update_repo_config",
+		Args: map[string]interface{}{
+			"name":                   "test-repo",
+			"max_concurrent_workers": 0,
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("handleUpdateRepoConfig() reset failed: %s", resp.Error)
+	}
+	if resp := addAgent("worker-2", "worker"); !resp.Success {
+		t.Errorf("worker should be allowed after reset: %s", resp.Error)
+	}
+}
+
+func TestHandleAddAgentEnforcesWorktreeLimit(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	resp := d.handleAddRepo(socket.Request{
+		Command: "add_repo",
+		Args: map[string]interface{}{
+			"name":           "test-repo",
+			"github_url":     "https://github.com/test/repo",
+			"tmux_session":   "test-session",
+			"worktree_limit": float64(1),
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("handleAddRepo() failed: %s", resp.Error)
+	}
+
+	addAgent := func(name, agentType string) socket.Response {
+		return d.handleAddAgent(socket.Request{
+			Command: "add_agent",
+			Args: map[string]interface{}{
+				"repo":          "test-repo",
+				"agent":         name,
+				"type":          agentType,
+				"worktree_path": "/tmp/" + name,
+				"tmux_window":   name,
+			},
+		})
+	}
+
+	if resp := addAgent("worker-1", "worker"); !resp.Success {
+		t.Fatalf("first worker should be allowed: %s", resp.Error)
+	}
+	resp = addAgent("worker-2", "worker")
+	if resp.Success {
+		t.Fatal("second worker should be rejected by the worktree limit")
+	}
+	if !strings.Contains(resp.Error, "multiclaude work rm") {
+		t.Errorf("error should suggest work rm, got: %s", resp.Error)
+	}
+	if resp := addAgent("workspace", "workspace"); !resp.Success {
+		t.Errorf("workspace should be exempt from the worktree limit: %s", resp.Error)
+	}
+
+	configResp := d.handleGetRepoConfig(socket.Request{
+		Command: "get_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo"},
+	})
+	data, _ := configResp.Data.(map[string]interface{})
+	if data["worktree_limit"] != 1 {
+		t.Errorf("worktree_limit = %v, want 1", data["worktree_limit"])
+	}
+
+	resp = d.handleUpdateRepoConfig(socket.Request{
+		Command: "update_repo_config",
+		Args: map[string]interface{}{
+			"name":           "test-repo",
+			"worktree_limit": float64(2),
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("handleUpdateRepoConfig() failed: %s", resp.Error)
+	}
+	if resp := addAgent("worker-2", "worker"); !resp.Success {
+		t.Errorf("worker should be allowed after raising the limit: %s", resp.Error)
+	}
+
+	resp = d.handleUpdateRepoConfig(socket.Request{
+		Command: "update_repo_config",
+		Args: map[string]interface{}{
+			"name":           "test-repo",
+			"worktree_limit": float64(-1),
+		},
+	})
+	if resp.Success {
+		t.Error("handleUpdateRepoConfig() should reject a negative worktree limit")
+	}
+}
+
+func TestHandleAddAgentRefusesDuplicateTask(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	resp := d.handleAddRepo(socket.Request{
+		Command: "add_repo",
+		Args: map[string]interface{}{
+			"name":         "test-repo",
+			"github_url":   "https://github.com/test/repo",
+			"tmux_session": "test-session",
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("handleAddRepo() failed: %s", resp.Error)
+	}
+
+	addWorker := func(name, task string, allowDuplicate bool) socket.Response {
+		args := map[string]interface{}{
+			"repo":          "test-repo",
+			"agent":         name,
+			"type":          "worker",
+			"worktree_path": "/tmp/" + name,
+			"tmux_window":   name,
+			"task":          task,
+		}
+		if allowDuplicate {
+			args["allow_duplicate"] = true
+		}
+		return d.handleAddAgent(socket.Request{Command: "add_agent", Args: args})
+	}
+
+	if resp := addWorker("fox", "Fix the login bug", false); !resp.Success {
+		t.Fatalf("first worker should be allowed: %s", resp.Error)
+	}
+
+	found := d.handleFindDuplicateWorker(socket.Request{
+		Command: "find_duplicate_worker",
+		Args:    map[string]interface{}{"repo": "test-repo", "task": "fix the login bug"},
+	})
+	if !found.Success || found.Data != "fox" {
+		t.Errorf("handleFindDuplicateWorker() = %+v, want fox", found)
+	}
+
+	resp = addWorker("owl", "Fix the  LOGIN bug", false)
+	if resp.Success {
+		t.Fatal("worker with the same task should be refused")
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	if data["duplicate_of"] != "fox" || !strings.Contains(resp.Error, "possible duplicate") {
+		t.Errorf("duplicate response = %+v, want duplicate_of fox", resp)
+	}
+
+	if resp := addWorker("owl", "Fix the login bug", true); !resp.Success {
+		t.Errorf("allow_duplicate should register the worker: %s", resp.Error)
+	}
+
+	resp = d.handleUpdateRepoConfig(socket.Request{
+		Command: "update_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo", "duplicate_window": "0"},
+	})
+	if !resp.Success {
+		t.Fatalf("handleUpdateRepoConfig() failed: %s", resp.Error)
+	}
+	if resp := addWorker("bat", "Fix the login bug", false); !resp.Success {
+		t.Errorf("a disabled duplicate window should allow the worker: %s", resp.Error)
+	}
+	configResp := d.handleGetRepoConfig(socket.Request{
+		Command: "get_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo"},
+	})
+	if data, _ := configResp.Data.(map[string]interface{}); data["duplicate_window"] != "0s" {
+		t.Errorf("duplicate_window = %v, want 0s", data["duplicate_window"])
+	}
+}
+
+func TestRouteMessagesHoldsScheduledMessages(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addTransportTestRepo(t, d, t.TempDir())
+
+	recorder := &recordingTransport{name: "recorder"}
+	d.RegisterTransport(recorder)
+	resp := d.handleUpdateRepoConfig(socket.Request{
+		Command: "update_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo", "message_transport": "recorder"},
+	})
+	if !resp.Success {
+		t.Fatalf("handleUpdateRepoConfig() failed: %s", resp.Error)
+	}
+
+	msgMgr := d.getMessageManager()
+	later := time.Now().Add(2 * time.Hour)
+	due := time.Now().Add(-time.Minute)
+	stale := time.Now().Add(-messages.ExpiryAge - time.Hour)
+	future, err := msgMgr.Schedule("test-repo", "supervisor", "worker-1", "later", &later)
+	if err != nil {
+		t.Fatalf("Failed to schedule message: %v", err)
+	}
+	ready, err := msgMgr.Schedule("test-repo", "supervisor", "worker-1", "now", &due)
+	if err != nil {
+		t.Fatalf("Failed to schedule message: %v", err)
+	}
+	expired, err := msgMgr.Schedule("test-repo", "supervisor", "worker-1", "too late", &stale)
+	if err != nil {
+		t.Fatalf("Failed to schedule message: %v", err)
+	}
+
+	d.routeMessages()
+
+	if len(recorder.delivered) != 1 || recorder.delivered[0].ID != ready.ID {
+		t.Errorf("deliveries = %v, want only the message whose time has come", recorder.delivered)
+	}
+	if msg, err := msgMgr.Get("test-repo", "worker-1", future.ID); err != nil || msg.Status != messages.StatusPending {
+		t.Errorf("future message should stay pending, got %v (err %v)", msg, err)
+	}
+	if _, err := msgMgr.Get("test-repo", "worker-1", expired.ID); err == nil {
+		t.Error("scheduled message past its expiry should be pruned")
+	}
+
+	resp = d.handleScheduledMessages(socket.Request{
+		Command: "scheduled_messages",
+		Args:    map[string]interface{}{"repo": "test-repo"},
+	})
+	if !resp.Success {
+		t.Fatalf("handleScheduledMessages() failed: %s", resp.Error)
+	}
+	scheduled, _ := resp.Data.([]map[string]interface{})
+	if len(scheduled) != 1 || scheduled[0]["id"] != future.ID || scheduled[0]["to"] != "worker-1" {
+		t.Errorf("scheduled messages = %v, want only the future message", scheduled)
+	}
+
+	resp = d.handleScheduledMessages(socket.Request{
+		Command: "scheduled_messages",
+		Args:    map[string]interface{}{"repo": "nonexistent"},
+	})
+	if resp.Success {
+		t.Error("handleScheduledMessages() should fail for an unknown repository")
+	}
+}
+
 func TestHandleListReposRichFormat(t *testing.T) {
 	tmuxClient := tmux.NewClient()
 	d, cleanup := setupTestDaemon(t)
@@ -2828,3 +4104,154 @@ func TestHandleClearCurrentRepoWhenNone(t *testing.T) {
 		t.Errorf("clear_current_repo should succeed even when no repo set: %s", resp.Error)
 	}
 }
+
+func TestAddSupervisorToRepoWithoutOne(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if resp := d.handleAddRepo(socket.Request{
+		Command: "add_repo",
+		Args: map[string]interface{}{
+			"name":          "test-repo",
+			"github_url":    "https://github.com/test/repo",
+			"tmux_session":  "mc-test-repo",
+			"no_supervisor": true,
+		},
+	}); !resp.Success {
+		t.Fatalf("handleAddRepo() failed: %s", resp.Error)
+	}
+	if repo, _ := d.state.GetRepo("test-repo"); !repo.NoSupervisor {
+		t.Fatal("add_repo with no_supervisor should record it")
+	}
+	if err := d.state.AddAgent("test-repo", "default", state.Agent{Type: state.AgentTypeWorkspace, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+
+	// Without a supervisor, prompts say so
+	promptPath, err := d.writePromptFile("test-repo", prompts.TypeWorkspace, "default")
+	if err != nil {
+		t.Fatalf("writePromptFile() failed: %v", err)
+	}
+	if data, _ := os.ReadFile(promptPath); !strings.Contains(string(data), "## No Supervisor") {
+		t.Error("workspace prompt should say the repo has no supervisor")
+	}
+
+	if resp := d.handleAddAgent(socket.Request{
+		Command: "add_agent",
+		Args: map[string]interface{}{
+			"repo":          "test-repo",
+			"agent":         "supervisor",
+			"type":          "supervisor",
+			"worktree_path": d.paths.RepoDir("test-repo"),
+			"tmux_window":   "supervisor",
+		},
+	}); !resp.Success {
+		t.Fatalf("handleAddAgent() failed: %s", resp.Error)
+	}
+
+	if repo, _ := d.state.GetRepo("test-repo"); repo.NoSupervisor {
+		t.Error("adding a supervisor should clear no_supervisor")
+	}
+	if data, _ := os.ReadFile(promptPath); strings.Contains(string(data), "## No Supervisor") {
+		t.Error("workspace prompt should be rewritten once the repo has a supervisor")
+	}
+	msgs, err := d.getMessageManager().List("test-repo", "default")
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Body, "now has a supervisor") {
+		t.Errorf("workspace should be told about the new supervisor, got %d messages", len(msgs))
+	}
+	if supMsgs, _ := d.getMessageManager().List("test-repo", "supervisor"); len(supMsgs) != 0 {
+		t.Errorf("the supervisor should not be told about itself, got %d messages", len(supMsgs))
+	}
+}
+
+func TestUpdateRepoConfigGitIdentity(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	update := func(args map[string]interface{}) socket.Response {
+		args["name"] = "test-repo"
+		return d.handleUpdateRepoConfig(socket.Request{Command: "update_repo_config", Args: args})
+	}
+	if resp := update(map[string]interface{}{"git_name": "Jane Doe", "git_email": "jane@example.com"}); !resp.Success {
+		t.Fatalf("setting the identity failed: %s", resp.Error)
+	}
+	// Settings given alone keep the others
+	if resp := update(map[string]interface{}{"sign_commits": true, "signing_key": "ABCDEF"}); !resp.Success {
+		t.Fatalf("turning on signing failed: %s", resp.Error)
+	}
+	if resp := update(map[string]interface{}{"signing_format": "ssh", "signing_key": ""}); resp.Success {
+		t.Error("ssh signing without a key should be rejected")
+	}
+
+	configResp := d.handleGetRepoConfig(socket.Request{
+		Command: "get_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo"},
+	})
+	data, _ := configResp.Data.(map[string]interface{})
+	if data["git_name"] != "Jane Doe" || data["git_email"] != "jane@example.com" || data["sign_commits"] != true ||
+		data["signing_key"] != "ABCDEF" || data["signing_format"] != "openpgp" {
+		t.Errorf("get_repo_config git identity = %v", data)
+	}
+
+	// New workers' prompts state the identity
+	promptPath, err := d.writePromptFile("test-repo", prompts.TypeWorker, "worker")
+	if err != nil {
+		t.Fatalf("writePromptFile() failed: %v", err)
+	}
+	if content, _ := os.ReadFile(promptPath); !strings.Contains(string(content), "`Jane Doe <jane@example.com>`") {
+		t.Error("worker prompt should state the git identity")
+	}
+}
+
+func TestContextVars(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	set := func(key, value string) socket.Response {
+		return d.handleSetContextVar(socket.Request{
+			Command: "set_context_var",
+			Args:    map[string]interface{}{"repo": "test-repo", "key": key, "value": value},
+		})
+	}
+	if resp := set("FREEZE_DATE", "2024-03-01"); !resp.Success {
+		t.Fatalf("handleSetContextVar() failed: %s", resp.Error)
+	}
+	if resp := set("bad key", "x"); resp.Success {
+		t.Error("handleSetContextVar() should reject an invalid key")
+	}
+
+	resp := d.handleGetContextVars(socket.Request{
+		Command: "get_context_vars",
+		Args:    map[string]interface{}{"repo": "test-repo"},
+	})
+	if vars, _ := resp.Data.(map[string]string); !resp.Success || vars["FREEZE_DATE"] != "2024-03-01" {
+		t.Errorf("handleGetContextVars() = %+v", resp)
+	}
+
+	// Prompt files written from now on list the context
+	for _, write := range []func() (string, error){
+		func() (string, error) { return d.writePromptFile("test-repo", prompts.TypeSupervisor, "supervisor") },
+		func() (string, error) {
+			return d.writeMergeQueuePromptFile("test-repo", "merge-queue", state.DefaultMergeQueueConfig())
+		},
+	} {
+		promptPath, err := write()
+		if err != nil {
+			t.Fatalf("writing prompt file failed: %v", err)
+		}
+		if content, _ := os.ReadFile(promptPath); !strings.Contains(string(content), "| FREEZE_DATE | 2024-03-01 |") {
+			t.Errorf("%s should list the context", promptPath)
+		}
+	}
+}
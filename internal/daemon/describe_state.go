@@ -0,0 +1,135 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/dlorenc/multiclaude/internal/procs"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// agentTypeOrder is the order agent types are listed in within a repository;
+// types not listed here follow in alphabetical order
+var agentTypeOrder = []state.AgentType{
+	state.AgentTypeSupervisor,
+	state.AgentTypeMergeQueue,
+	state.AgentTypeWorkspace,
+	state.AgentTypeWorker,
+	state.AgentTypeEphemeral,
+	state.AgentTypeReview,
+}
+
+// handleDescribeState returns the daemon's topology as a nested object:
+// repositories, their agents grouped by type, and the live status of each
+func (d *Daemon) handleDescribeState(req socket.Request) socket.Response {
+	repos := d.state.GetAllRepos()
+
+	repoNames := make([]string, 0, len(repos))
+	if name, _ := req.Args["repo"].(string); name != "" {
+		if _, exists := repos[name]; !exists {
+			return socket.Response{Success: false, Error: fmt.Sprintf("repository '%s' not found", name)}
+		}
+		repoNames = append(repoNames, name)
+	} else {
+		for name := range repos {
+			repoNames = append(repoNames, name)
+		}
+		sort.Strings(repoNames)
+	}
+
+	repoDescs := make([]map[string]interface{}, 0, len(repoNames))
+	for _, name := range repoNames {
+		repoDescs = append(repoDescs, d.describeRepo(name, repos[name]))
+	}
+
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"daemon": map[string]interface{}{
+				"pid":         os.Getpid(),
+				"socket_path": d.paths.DaemonSock,
+			},
+			"repos": repoDescs,
+		},
+	}
+}
+
+// describeRepo builds the describe_state entry for one repository
+func (d *Daemon) describeRepo(repoName string, repo *state.Repository) map[string]interface{} {
+	sessionHealthy := false
+	if hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession); err == nil {
+		sessionHealthy = hasSession
+	}
+
+	byType := make(map[state.AgentType][]string)
+	for agentName, agent := range repo.Agents {
+		byType[agent.Type] = append(byType[agent.Type], agentName)
+	}
+
+	types := make([]state.AgentType, 0, len(byType))
+	for _, t := range agentTypeOrder {
+		if _, ok := byType[t]; ok {
+			types = append(types, t)
+		}
+	}
+	var extra []state.AgentType
+	for t := range byType {
+		if agentTypeRank(t) < 0 {
+			extra = append(extra, t)
+		}
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i] < extra[j] })
+	types = append(types, extra...)
+
+	groups := make([]map[string]interface{}, 0, len(types))
+	for _, t := range types {
+		names := byType[t]
+		sort.Strings(names)
+
+		agents := make([]map[string]interface{}, 0, len(names))
+		for _, agentName := range names {
+			agents = append(agents, d.describeAgent(repoName, repo.TmuxSession, agentName, repo.Agents[agentName]))
+		}
+		groups = append(groups, map[string]interface{}{
+			"type":   string(t),
+			"agents": agents,
+		})
+	}
+
+	return map[string]interface{}{
+		"name":            repoName,
+		"github_url":      repo.GithubURL,
+		"tmux_session":    repo.TmuxSession,
+		"session_healthy": sessionHealthy,
+		"agent_count":     len(repo.Agents),
+		"agent_types":     groups,
+	}
+}
+
+// describeAgent builds the describe_state entry for one agent
+func (d *Daemon) describeAgent(repoName, session, agentName string, agent state.Agent) map[string]interface{} {
+	pending := d.agentMessageSummary(repoName, agentName).Unread()
+
+	return map[string]interface{}{
+		"name":             agentName,
+		"status":           d.agentStatus(session, agent),
+		"branch":           agentBranch(agent),
+		"pid":              agent.PID,
+		"pid_alive":        agent.PID > 0 && procs.IsAlive(agent.PID),
+		"messages_pending": pending,
+		"tmux_target":      fmt.Sprintf("%s:%s", session, agent.TmuxWindow),
+		"task":             agent.Task,
+	}
+}
+
+// agentTypeRank returns the position of t in agentTypeOrder, or -1
+func agentTypeRank(t state.AgentType) int {
+	for i, known := range agentTypeOrder {
+		if known == t {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"os"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestHandleDescribeState(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	for _, name := range []string{"beta", "alpha"} {
+		if err := d.state.AddRepo(name, &state.Repository{
+			GithubURL:   "https://github.com/test/" + name,
+			TmuxSession: "mc-" + name,
+			Agents:      make(map[string]state.Agent),
+		}); err != nil {
+			t.Fatalf("Failed to add repo: %v", err)
+		}
+	}
+	agents := map[string]state.Agent{
+		"worker-b":   {Type: state.AgentTypeWorker, TmuxWindow: "worker-b", PID: os.Getpid()},
+		"worker-a":   {Type: state.AgentTypeWorker, TmuxWindow: "worker-a", ReadyForCleanup: true},
+		"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor"},
+	}
+	for name, agent := range agents {
+		if err := d.state.AddAgent("alpha", name, agent); err != nil {
+			t.Fatalf("Failed to add agent: %v", err)
+		}
+	}
+	if _, err := messages.NewManager(d.paths.MessagesDir).Send("alpha", "supervisor", "worker-b", "hello"); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	resp := d.handleDescribeState(socket.Request{Command: "describe_state"})
+	if !resp.Success {
+		t.Fatalf("describe_state failed: %s", resp.Error)
+	}
+	data := resp.Data.(map[string]interface{})
+	repos := data["repos"].([]map[string]interface{})
+	if len(repos) != 2 || repos[0]["name"] != "alpha" || repos[1]["name"] != "beta" {
+		t.Fatalf("Expected repos sorted by name, got %v", repos)
+	}
+
+	groups := repos[0]["agent_types"].([]map[string]interface{})
+	if len(groups) != 2 || groups[0]["type"] != "supervisor" || groups[1]["type"] != "worker" {
+		t.Fatalf("Expected supervisor then worker groups, got %v", groups)
+	}
+	workers := groups[1]["agents"].([]map[string]interface{})
+	if len(workers) != 2 || workers[0]["name"] != "worker-a" {
+		t.Fatalf("Expected workers sorted by name, got %v", workers)
+	}
+	if workers[0]["status"] != "completed" {
+		t.Errorf("Expected completed worker, got %v", workers[0]["status"])
+	}
+	if workers[1]["pid_alive"] != true {
+		t.Errorf("Expected live PID for worker-b, got %v", workers[1]["pid_alive"])
+	}
+	if workers[1]["messages_pending"] != 1 {
+		t.Errorf("Expected 1 pending message, got %v", workers[1]["messages_pending"])
+	}
+	if workers[1]["tmux_target"] != "mc-alpha:worker-b" {
+		t.Errorf("Unexpected tmux target %v", workers[1]["tmux_target"])
+	}
+
+	if groups := repos[1]["agent_types"].([]map[string]interface{}); len(groups) != 0 {
+		t.Errorf("Expected no agent groups for empty repo, got %v", groups)
+	}
+
+	resp = d.handleDescribeState(socket.Request{Command: "describe_state", Args: map[string]interface{}{"repo": "beta"}})
+	if !resp.Success {
+		t.Fatalf("describe_state for one repo failed: %s", resp.Error)
+	}
+	if repos := resp.Data.(map[string]interface{})["repos"].([]map[string]interface{}); len(repos) != 1 || repos[0]["name"] != "beta" {
+		t.Errorf("Expected only beta, got %v", repos)
+	}
+
+	resp = d.handleDescribeState(socket.Request{Command: "describe_state", Args: map[string]interface{}{"repo": "missing"}})
+	if resp.Success {
+		t.Error("describe_state for unknown repo should fail")
+	}
+}
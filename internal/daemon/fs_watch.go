@@ -0,0 +1,155 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/logging"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// fsWatchInterval is how often the filesystem watcher reads worktree HEADs
+const fsWatchInterval = 2 * time.Second
+
+// Options are the daemon's opt-in behaviors, set by daemon start
+type Options struct {
+	// WatchFS watches agent worktrees for branches switched and commits
+	// made outside multiclaude
+	WatchFS bool
+	// DocsHash returns the hash of the CLI reference the binary puts in a
+	// repository's prompts for an agent type, against which agents'
+	// references are checked for staleness
+	DocsHash func(repoName string, agentType state.AgentType) string
+}
+
+// FSWatcher notices agent worktrees whose HEAD changes, including by git
+// run by hand in another terminal, and records the branch now checked out
+type FSWatcher struct {
+	store   repoStore
+	watcher *worktree.HeadWatcher
+	// branchChanged is called for a worktree seen for the first time or
+	// with another branch checked out
+	branchChanged func(repoName, agentName string)
+	logger        *logging.Logger
+}
+
+func (f *FSWatcher) Name() string { return "filesystem watch" }
+
+func (f *FSWatcher) Schedule() LoopSchedule {
+	return LoopSchedule{Every: fsWatchInterval, RunAtStart: true}
+}
+
+// Tick reads the HEAD of every agent worktree and reacts to what changed
+func (f *FSWatcher) Tick(ctx context.Context) error {
+	type owner struct{ repo, agent string }
+	owners := make(map[string]owner)
+	var paths []string
+	for repoName, repo := range f.store.GetAllRepos() {
+		for agentName, agent := range repo.Agents {
+			// Ephemeral agents run in the primary checkout, not a worktree of their own
+			if agent.WorktreePath == "" || agent.Type == state.AgentTypeEphemeral {
+				continue
+			}
+			owners[agent.WorktreePath] = owner{repoName, agentName}
+			paths = append(paths, agent.WorktreePath)
+		}
+	}
+
+	for _, event := range f.watcher.Poll(paths) {
+		o := owners[event.Path]
+		switch event.Kind {
+		case worktree.WatchEventAdded:
+			f.branchChanged(o.repo, o.agent)
+		case worktree.WatchEventBranch:
+			f.logger.Info("Worktree of %s/%s switched from %s to %s", o.repo, o.agent, describeHead(event.Old), describeHead(event.New))
+			f.branchChanged(o.repo, o.agent)
+		case worktree.WatchEventCommit:
+			f.logger.Info("Worktree of %s/%s moved to %s", o.repo, o.agent, describeHead(event.New))
+		}
+	}
+	return nil
+}
+
+// describeHead names what a worktree has checked out, for logs
+func describeHead(head worktree.HeadState) string {
+	commit := shortCommit(head.Commit)
+	if head.Branch == "" {
+		return "detached HEAD at " + commit
+	}
+	if commit == "" {
+		return head.Branch
+	}
+	return head.Branch + " at " + commit
+}
+
+// EnableFSWatch turns on the filesystem watcher; call it before Start
+func (d *Daemon) EnableFSWatch() {
+	d.fsWatcher = &FSWatcher{
+		store:   d.state,
+		watcher: worktree.NewHeadWatcher(),
+		branchChanged: func(repoName, agentName string) {
+			if _, _, err := d.detectBranchChange(repoName, agentName); err != nil {
+				d.logger.Warn("Failed to update the branch of %s/%s: %v", repoName, agentName, err)
+			}
+		},
+		logger: d.logger,
+	}
+	d.scheduler.Add(d.fsWatcher)
+}
+
+// detectBranchChange looks up the branch checked out in an agent's worktree
+// and records it if it changed, returning the branch recorded before and
+// the one checked out now
+func (d *Daemon) detectBranchChange(repoName, agentName string) (previous, current string, err error) {
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return "", "", fmt.Errorf("agent '%s' not found in repository '%s'", agentName, repoName)
+	}
+	if agent.WorktreePath == "" || agent.Type == state.AgentTypeEphemeral {
+		return agent.Branch, agent.Branch, nil
+	}
+
+	current, err = worktree.GetCurrentBranch(agent.WorktreePath)
+	if err != nil {
+		return agent.Branch, "", err
+	}
+	if current == agent.Branch {
+		return agent.Branch, current, nil
+	}
+	if err := d.state.UpdateAgentBranch(repoName, agentName, current); err != nil {
+		return agent.Branch, current, err
+	}
+	if agent.Branch != "" {
+		d.logger.Info("Branch of %s/%s changed from %s to %s", repoName, agentName, agent.Branch, current)
+	}
+	return agent.Branch, current, nil
+}
+
+// handleDetectBranchChange records the branch checked out in an agent's
+// worktree, for the filesystem watcher of daemon watch-fs
+func (d *Daemon) handleDetectBranchChange(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	previous, current, err := d.detectBranchChange(repoName, agentName)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"previous": previous,
+			"branch":   current,
+			"changed":  previous != current,
+		},
+	}
+}
@@ -0,0 +1,104 @@
+package daemon
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestFSWatcherRecordsBranchSwitches(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repoPath := filepath.Join(t.TempDir(), "repo")
+	wtPath := filepath.Join(t.TempDir(), "wt")
+	runTestGit(t, filepath.Dir(repoPath), "init", "-q", "-b", "main", repoPath)
+	runTestGit(t, repoPath, "commit", "-q", "--allow-empty", "-m", "Initial commit")
+	runTestGit(t, repoPath, "worktree", "add", "-q", "-b", "work/fox", wtPath)
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"fox":   {Type: state.AgentTypeWorker, WorktreePath: wtPath, TmuxWindow: "fox", CreatedAt: time.Now()},
+			"quick": {Type: state.AgentTypeEphemeral, WorktreePath: repoPath, TmuxWindow: "quick", CreatedAt: time.Now()},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	d.EnableFSWatch()
+	branchOf := func(name string) string {
+		agent, _ := d.state.GetAgent("test-repo", name)
+		return agent.Branch
+	}
+
+	// The first tick records the branch of each worktree
+	if err := d.fsWatcher.Tick(context.Background()); err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+	if got := branchOf("fox"); got != "work/fox" {
+		t.Errorf("branch after the first tick = %q, want work/fox", got)
+	}
+	if got := branchOf("quick"); got != "" {
+		t.Errorf("ephemeral agent got branch %q", got)
+	}
+
+	// A branch switched by hand is picked up on the next tick
+	runTestGit(t, wtPath, "checkout", "-q", "-b", "experiment")
+	if err := d.fsWatcher.Tick(context.Background()); err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+	if got := branchOf("fox"); got != "experiment" {
+		t.Errorf("branch after switching = %q, want experiment", got)
+	}
+
+	resp := d.handleStatus(socket.Request{})
+	if watching, _ := resp.Data.(map[string]interface{})["watch_fs"].(bool); !watching {
+		t.Error("status does not report the filesystem watcher")
+	}
+}
+
+func TestHandleDetectBranchChange(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	wtPath := filepath.Join(t.TempDir(), "wt")
+	runTestGit(t, filepath.Dir(wtPath), "init", "-q", "-b", "main", wtPath)
+	runTestGit(t, wtPath, "commit", "-q", "--allow-empty", "-m", "Initial commit")
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"fox": {Type: state.AgentTypeWorker, WorktreePath: wtPath, Branch: "work/fox", TmuxWindow: "fox", CreatedAt: time.Now()},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	resp := d.handleDetectBranchChange(socket.Request{Args: map[string]interface{}{"repo": "test-repo", "agent": "fox"}})
+	if !resp.Success {
+		t.Fatalf("detect_branch_change failed: %s", resp.Error)
+	}
+	data := resp.Data.(map[string]interface{})
+	if data["previous"] != "work/fox" || data["branch"] != "main" || data["changed"] != true {
+		t.Errorf("detect_branch_change = %+v", data)
+	}
+	if agent, _ := d.state.GetAgent("test-repo", "fox"); agent.Branch != "main" {
+		t.Errorf("recorded branch = %q, want main", agent.Branch)
+	}
+	if resp := d.handleDetectBranchChange(socket.Request{Args: map[string]interface{}{"repo": "test-repo", "agent": "fox"}}); resp.Data.(map[string]interface{})["changed"] != false {
+		t.Errorf("second detect_branch_change = %+v", resp.Data)
+	}
+
+	if resp := d.handleDetectBranchChange(socket.Request{Args: map[string]interface{}{"repo": "test-repo", "agent": "nobody"}}); resp.Success {
+		t.Error("detect_branch_change of an unknown agent should fail")
+	}
+	if resp := d.handleDetectBranchChange(socket.Request{Args: map[string]interface{}{"repo": "test-repo"}}); resp.Success {
+		t.Error("detect_branch_change without an agent should fail")
+	}
+}
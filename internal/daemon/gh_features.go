@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	goerrors "errors"
+	"sort"
+
+	"github.com/dlorenc/multiclaude/internal/gh"
+)
+
+// Background features that need gh, as named in daemon status
+const (
+	ghFeatureRepoMoves  = "repo move detection"
+	ghFeaturePRPruning  = "merge-queue PR pruning"
+	ghFeaturePRWatch    = "PR watching"
+	ghFeatureWakeDeltas = "PR comment nudges"
+)
+
+// ghRepoStatus records the gh features turned off for a repository because
+// gh is unavailable
+type ghRepoStatus struct {
+	reason   string
+	features map[string]bool
+}
+
+// ghDisabled reports whether feature has been turned off for a repository
+func (d *Daemon) ghDisabled(repoName, feature string) bool {
+	d.ghUnavailableMu.Lock()
+	defer d.ghUnavailableMu.Unlock()
+	status, ok := d.ghUnavailable[repoName]
+	return ok && status.features[feature]
+}
+
+// ghFailed reports whether err means gh is unavailable. If it does, feature
+// is turned off for the repository until the daemon restarts, rather than
+// failing again every interval, and the first feature turned off for the
+// repository logs a warning.
+func (d *Daemon) ghFailed(repoName, feature string, err error) bool {
+	var unavailable *gh.UnavailableError
+	if !goerrors.As(err, &unavailable) {
+		return false
+	}
+
+	d.ghUnavailableMu.Lock()
+	defer d.ghUnavailableMu.Unlock()
+	status, ok := d.ghUnavailable[repoName]
+	if !ok {
+		status = &ghRepoStatus{reason: unavailable.Reason(), features: make(map[string]bool)}
+		d.ghUnavailable[repoName] = status
+		d.logger.Warn("gh is %s; turning off GitHub features for %s (install gh or run 'gh auth login', then restart the daemon): %v",
+			status.reason, repoName, err)
+	}
+	status.features[feature] = true
+	return true
+}
+
+// ghUnavailableSnapshot returns, for each repository with gh features
+// turned off, why and which features
+func (d *Daemon) ghUnavailableSnapshot() map[string]interface{} {
+	d.ghUnavailableMu.Lock()
+	defer d.ghUnavailableMu.Unlock()
+
+	snapshot := make(map[string]interface{}, len(d.ghUnavailable))
+	for repoName, status := range d.ghUnavailable {
+		features := make([]string, 0, len(status.features))
+		for feature := range status.features {
+			features = append(features, feature)
+		}
+		sort.Strings(features)
+		snapshot[repoName] = map[string]interface{}{
+			"reason":   status.reason,
+			"features": features,
+		}
+	}
+	return snapshot
+}
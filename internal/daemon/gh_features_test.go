@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/gh"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestGHUnavailableTurnsOffFeatures(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	for _, name := range []string{"repo-a", "repo-b"} {
+		if err := d.state.AddRepo(name, &state.Repository{
+			GithubURL:   "https://github.com/test/" + name,
+			TmuxSession: "mc-" + name,
+			Agents: map[string]state.Agent{
+				"pr-1": {Type: state.AgentTypeWorkspace, PRURL: "https://github.com/test/" + name + "/pull/1", PRNumber: 1, WatchPR: true},
+				"pr-2": {Type: state.AgentTypeWorkspace, PRURL: "https://github.com/test/" + name + "/pull/2", PRNumber: 2, WatchPR: true},
+			},
+		}); err != nil {
+			t.Fatalf("Failed to add repo: %v", err)
+		}
+	}
+
+	unavailable := &gh.UnavailableError{Err: exec.ErrNotFound, NotInstalled: true}
+	prLookups, repoLookups := 0, 0
+	d.lookupPRState = func(ctx context.Context, owner, name string, number int) (string, error) {
+		prLookups++
+		return "", unavailable
+	}
+	d.lookupRepoFullName = func(ctx context.Context, owner, name string) (string, error) {
+		repoLookups++
+		return "", unavailable
+	}
+
+	// The first failure in each repository turns the feature off there
+	d.checkWatchedPRs()
+	d.checkRepoMoves()
+	if prLookups != 2 || repoLookups != 2 {
+		t.Errorf("lookups = %d PR, %d repo, want one of each per repository", prLookups, repoLookups)
+	}
+	d.checkWatchedPRs()
+	d.checkRepoMoves()
+	if prLookups != 2 || repoLookups != 2 {
+		t.Errorf("lookups after turning features off = %d PR, %d repo, want no more", prLookups, repoLookups)
+	}
+	if agent, _ := d.state.GetAgent("repo-a", "pr-1"); !agent.WatchPR {
+		t.Error("pr-1 should still be watched once gh is available")
+	}
+
+	// One warning per repository, however many features
+	logData, err := os.ReadFile(d.paths.DaemonLog)
+	if err != nil {
+		t.Fatalf("Failed to read daemon log: %v", err)
+	}
+	if n := strings.Count(string(logData), "turning off GitHub features"); n != 2 {
+		t.Errorf("logged %d warnings, want one per repository:\n%s", n, logData)
+	}
+
+	resp := d.handleStatus(socket.Request{Command: "status"})
+	data, _ := resp.Data.(map[string]interface{})
+	status, _ := data["gh_unavailable"].(map[string]interface{})
+	repoA, _ := status["repo-a"].(map[string]interface{})
+	features, _ := repoA["features"].([]string)
+	if repoA["reason"] != "not installed" || strings.Join(features, ", ") != "PR watching, repo move detection" {
+		t.Errorf("gh_unavailable[repo-a] = %v", repoA)
+	}
+}
@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// reposArg returns the "repos" argument of a group request as strings
+func reposArg(args map[string]interface{}) []string {
+	list, _ := args["repos"].([]interface{})
+	repos := make([]string, 0, len(list))
+	for _, r := range list {
+		if name, ok := r.(string); ok {
+			repos = append(repos, name)
+		}
+	}
+	return repos
+}
+
+// handleCreateGroup creates a named group of tracked repositories
+func (d *Daemon) handleCreateGroup(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "group", "group name is required")
+	if !ok {
+		return errResp
+	}
+	repos := reposArg(req.Args)
+
+	if err := d.state.CreateGroup(name, repos); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	d.logger.Info("Created group %s with %d repositories", name, len(repos))
+	return socket.Response{Success: true}
+}
+
+// handleAddToGroup adds tracked repositories to a group
+func (d *Daemon) handleAddToGroup(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "group", "group name is required")
+	if !ok {
+		return errResp
+	}
+
+	if err := d.state.AddToGroup(name, reposArg(req.Args)); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	d.logger.Info("Added repositories to group %s", name)
+	return socket.Response{Success: true}
+}
+
+// handleRemoveFromGroup removes repositories from a group
+func (d *Daemon) handleRemoveFromGroup(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "group", "group name is required")
+	if !ok {
+		return errResp
+	}
+
+	if err := d.state.RemoveFromGroup(name, reposArg(req.Args)); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	d.logger.Info("Removed repositories from group %s", name)
+	return socket.Response{Success: true}
+}
+
+// handleDeleteGroup deletes a group, leaving its repositories alone
+func (d *Daemon) handleDeleteGroup(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "group", "group name is required")
+	if !ok {
+		return errResp
+	}
+
+	if err := d.state.DeleteGroup(name); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	d.logger.Info("Deleted group %s", name)
+	return socket.Response{Success: true}
+}
+
+// handleListGroups returns every group and its member repositories
+func (d *Daemon) handleListGroups(req socket.Request) socket.Response {
+	return socket.Response{Success: true, Data: d.state.ListGroups()}
+}
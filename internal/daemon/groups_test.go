@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestGroupHandlers(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	for _, name := range []string{"api", "web"} {
+		if err := d.state.AddRepo(name, &state.Repository{Agents: make(map[string]state.Agent)}); err != nil {
+			t.Fatalf("Failed to add repo: %v", err)
+		}
+	}
+
+	send := func(command string, args map[string]interface{}) socket.Response {
+		return d.dispatchRequest(socket.Request{Command: command, Args: args})
+	}
+	if resp := send("create_group", map[string]interface{}{"group": "backend", "repos": []interface{}{"api"}}); !resp.Success {
+		t.Fatalf("create_group failed: %s", resp.Error)
+	}
+	if resp := send("create_group", map[string]interface{}{"repos": []interface{}{"api"}}); resp.Success {
+		t.Error("create_group without a name should fail")
+	}
+	if resp := send("add_to_group", map[string]interface{}{"group": "backend", "repos": []interface{}{"missing"}}); resp.Success {
+		t.Error("add_to_group with an untracked repository should fail")
+	}
+	if resp := send("add_to_group", map[string]interface{}{"group": "backend", "repos": []interface{}{"web"}}); !resp.Success {
+		t.Fatalf("add_to_group failed: %s", resp.Error)
+	}
+	if resp := send("remove_from_group", map[string]interface{}{"group": "backend", "repos": []interface{}{"api"}}); !resp.Success {
+		t.Fatalf("remove_from_group failed: %s", resp.Error)
+	}
+
+	resp := send("list_groups", nil)
+	if groups, _ := resp.Data.(map[string][]string); !resp.Success || !reflect.DeepEqual(groups, map[string][]string{"backend": {"web"}}) {
+		t.Errorf("list_groups = %+v", resp)
+	}
+
+	if resp := send("delete_group", map[string]interface{}{"group": "backend"}); !resp.Success {
+		t.Fatalf("delete_group failed: %s", resp.Error)
+	}
+	if resp := send("delete_group", map[string]interface{}{"group": "backend"}); resp.Success {
+		t.Error("delete_group of an unknown group should fail")
+	}
+}
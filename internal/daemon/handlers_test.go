@@ -390,6 +390,310 @@ func TestHandleRemoveAgentTableDriven(t *testing.T) {
 	}
 }
 
+// TestHandleSetAgentEnvTableDriven tests the argument checks of handleSetAgentEnv
+func TestHandleSetAgentEnvTableDriven(t *testing.T) {
+	withAgent := func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:   "https://github.com/test/repo",
+			TmuxSession: "test-session",
+			Agents:      make(map[string]state.Agent),
+		})
+		s.AddAgent("test-repo", "test-agent", state.Agent{
+			Type:       state.AgentTypeWorker,
+			TmuxWindow: "test-agent",
+			CreatedAt:  time.Now(),
+		})
+		s.AddAgent("test-repo", "done-agent", state.Agent{
+			Type:            state.AgentTypeWorker,
+			TmuxWindow:      "done-agent",
+			CreatedAt:       time.Now(),
+			ReadyForCleanup: true,
+		})
+	}
+
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantError string
+	}{
+		{
+			name:      "missing repo argument",
+			args:      map[string]interface{}{"agent": "test-agent", "env": map[string]interface{}{"TOKEN": "x"}},
+			wantError: "missing 'repo'",
+		},
+		{
+			name:      "missing agent argument",
+			args:      map[string]interface{}{"repo": "test-repo", "env": map[string]interface{}{"TOKEN": "x"}},
+			wantError: "missing 'agent'",
+		},
+		{
+			name:      "missing env",
+			args:      map[string]interface{}{"repo": "test-repo", "agent": "test-agent"},
+			wantError: "missing 'env'",
+		},
+		{
+			name:      "invalid variable name",
+			args:      map[string]interface{}{"repo": "test-repo", "agent": "test-agent", "env": map[string]interface{}{"BAD-NAME": "x"}},
+			wantError: "invalid environment variable",
+		},
+		{
+			name:      "non-string value",
+			args:      map[string]interface{}{"repo": "test-repo", "agent": "test-agent", "env": map[string]interface{}{"TOKEN": 42.0}},
+			wantError: "invalid environment variable",
+		},
+		{
+			name:      "agent does not exist",
+			args:      map[string]interface{}{"repo": "test-repo", "agent": "nonexistent", "env": map[string]interface{}{"TOKEN": "x"}},
+			wantError: "not found",
+		},
+		{
+			name:      "completed agent",
+			args:      map[string]interface{}{"repo": "test-repo", "agent": "done-agent", "env": map[string]interface{}{"TOKEN": "x"}},
+			wantError: "pending cleanup",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, cleanup := setupTestDaemonWithState(t, withAgent)
+			defer cleanup()
+
+			resp := d.handleSetAgentEnv(socket.Request{
+				Command: "set_agent_env",
+				Args:    tt.args,
+			})
+			if resp.Success {
+				t.Fatal("handleSetAgentEnv() should fail")
+			}
+			if !contains(resp.Error, tt.wantError) {
+				t.Errorf("handleSetAgentEnv() error = %q, want it to contain %q", resp.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestHandleUpdateAgentTask(t *testing.T) {
+	withAgents := func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:   "https://github.com/test/repo",
+			TmuxSession: "test-session",
+			Agents:      make(map[string]state.Agent),
+		})
+		s.AddAgent("test-repo", "test-agent", state.Agent{
+			Type:       state.AgentTypeWorker,
+			TmuxWindow: "test-agent",
+			Task:       "Fix the flaky test",
+			CreatedAt:  time.Now(),
+		})
+		s.AddAgent("test-repo", "done-agent", state.Agent{
+			Type:            state.AgentTypeWorker,
+			TmuxWindow:      "done-agent",
+			CreatedAt:       time.Now(),
+			ReadyForCleanup: true,
+		})
+		s.AddAgent("test-repo", "supervisor", state.Agent{
+			Type:       state.AgentTypeSupervisor,
+			TmuxWindow: "supervisor",
+			CreatedAt:  time.Now(),
+		})
+	}
+
+	t.Run("updates the task", func(t *testing.T) {
+		d, cleanup := setupTestDaemonWithState(t, withAgents)
+		defer cleanup()
+
+		resp := d.handleUpdateAgentTask(socket.Request{
+			Command: "update_agent_task",
+			Args:    map[string]interface{}{"repo": "test-repo", "agent": "test-agent", "task": "Fix the race the test exposed"},
+		})
+		if !resp.Success {
+			t.Fatalf("handleUpdateAgentTask() failed: %s", resp.Error)
+		}
+		if data, _ := resp.Data.(map[string]interface{}); data["old_task"] != "Fix the flaky test" {
+			t.Errorf("old_task = %v, want the previous task", data["old_task"])
+		}
+
+		agent, _ := d.state.GetAgent("test-repo", "test-agent")
+		if agent.Task != "Fix the race the test exposed" {
+			t.Errorf("task = %q, want the new task", agent.Task)
+		}
+		if len(agent.TaskUpdates) != 1 || agent.TaskUpdates[0].OldTask != "Fix the flaky test" || agent.TaskUpdates[0].UpdatedAt.IsZero() {
+			t.Errorf("task updates = %+v, want one update from the old task", agent.TaskUpdates)
+		}
+
+		// The update is carried into the task history
+		d.recordTaskHistory("test-repo", "test-agent", agent)
+		history, _ := d.state.GetTaskHistory("test-repo", 1)
+		if len(history) != 1 || len(history[0].TaskUpdates) != 1 || history[0].TaskUpdates[0].NewTask != "Fix the race the test exposed" {
+			t.Errorf("task history = %+v, want the task update recorded", history)
+		}
+	})
+
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantError string
+	}{
+		{
+			name:      "missing task",
+			args:      map[string]interface{}{"repo": "test-repo", "agent": "test-agent"},
+			wantError: "missing 'task'",
+		},
+		{
+			name:      "agent does not exist",
+			args:      map[string]interface{}{"repo": "test-repo", "agent": "nonexistent", "task": "x"},
+			wantError: "not found",
+		},
+		{
+			name:      "completed agent",
+			args:      map[string]interface{}{"repo": "test-repo", "agent": "done-agent", "task": "x"},
+			wantError: "pending cleanup",
+		},
+		{
+			name:      "not a worker",
+			args:      map[string]interface{}{"repo": "test-repo", "agent": "supervisor", "task": "x"},
+			wantError: "only workers have a task",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, cleanup := setupTestDaemonWithState(t, withAgents)
+			defer cleanup()
+
+			resp := d.handleUpdateAgentTask(socket.Request{
+				Command: "update_agent_task",
+				Args:    tt.args,
+			})
+			if resp.Success {
+				t.Fatal("handleUpdateAgentTask() should fail")
+			}
+			if !contains(resp.Error, tt.wantError) {
+				t.Errorf("handleUpdateAgentTask() error = %q, want it to contain %q", resp.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestHandleUpdateAgentStatus(t *testing.T) {
+	withAgents := func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:   "https://github.com/test/repo",
+			TmuxSession: "test-session",
+			Agents:      make(map[string]state.Agent),
+		})
+		s.AddAgent("test-repo", "test-agent", state.Agent{
+			Type:       state.AgentTypeWorker,
+			TmuxWindow: "test-agent",
+			CreatedAt:  time.Now(),
+		})
+		s.AddAgent("test-repo", "done-agent", state.Agent{
+			Type:            state.AgentTypeWorker,
+			TmuxWindow:      "done-agent",
+			CreatedAt:       time.Now(),
+			ReadyForCleanup: true,
+		})
+	}
+	d, cleanup := setupTestDaemonWithState(t, withAgents)
+	defer cleanup()
+
+	setStatus := func(agent, status string) socket.Response {
+		return d.handleUpdateAgentStatus(socket.Request{
+			Command: "update_agent_status",
+			Args:    map[string]interface{}{"repo": "test-repo", "agent": agent, "status": status},
+		})
+	}
+
+	if resp := setStatus("test-agent", "blocked"); !resp.Success {
+		t.Fatalf("handleUpdateAgentStatus() failed: %s", resp.Error)
+	}
+	if agent, _ := d.state.GetAgent("test-repo", "test-agent"); agent.Status != "blocked" {
+		t.Errorf("status = %q, want blocked", agent.Status)
+	}
+
+	// list_agents reports it next to the computed status
+	resp := d.handleListAgents(socket.Request{Command: "list_agents", Args: map[string]interface{}{"repo": "test-repo"}})
+	agents, _ := resp.Data.([]map[string]interface{})
+	found := false
+	for _, agent := range agents {
+		if agent["name"] == "test-agent" {
+			found = true
+			if agent["custom_status"] != "blocked" {
+				t.Errorf("custom_status = %v, want blocked", agent["custom_status"])
+			}
+		}
+	}
+	if !found {
+		t.Error("list_agents should include test-agent")
+	}
+
+	// Completing the agent clears it
+	if resp := d.handleCompleteAgent(socket.Request{
+		Command: "complete_agent",
+		Args:    map[string]interface{}{"repo": "test-repo", "agent": "test-agent"},
+	}); !resp.Success {
+		t.Fatalf("handleCompleteAgent() failed: %s", resp.Error)
+	}
+	if agent, _ := d.state.GetAgent("test-repo", "test-agent"); agent.Status != "" {
+		t.Errorf("status after completion = %q, want it cleared", agent.Status)
+	}
+
+	if resp := setStatus("done-agent", "waiting"); resp.Success || !contains(resp.Error, "pending cleanup") {
+		t.Errorf("setting the status of a completed agent = %+v, want it refused", resp)
+	}
+	if resp := setStatus("nonexistent", "waiting"); resp.Success || !contains(resp.Error, "not found") {
+		t.Errorf("setting the status of a missing agent = %+v, want not found", resp)
+	}
+}
+
+func TestHandleSetAgentUpstream(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:   "https://github.com/test/repo",
+			TmuxSession: "test-session",
+			Agents: map[string]state.Agent{
+				"dev":    {Type: state.AgentTypeWorkspace, TmuxWindow: "dev", CreatedAt: time.Now()},
+				"worker": {Type: state.AgentTypeWorker, TmuxWindow: "worker", CreatedAt: time.Now()},
+			},
+		})
+	})
+	defer cleanup()
+
+	setUpstream := func(agent, upstream string) socket.Response {
+		return d.handleSetAgentUpstream(socket.Request{
+			Command: "set_agent_upstream",
+			Args:    map[string]interface{}{"repo": "test-repo", "agent": agent, "upstream": upstream},
+		})
+	}
+
+	if resp := setUpstream("dev", "origin/shared"); !resp.Success {
+		t.Fatalf("handleSetAgentUpstream() failed: %s", resp.Error)
+	}
+	if agent, _ := d.state.GetAgent("test-repo", "dev"); agent.UpstreamBranch != "origin/shared" {
+		t.Errorf("upstream = %q, want origin/shared", agent.UpstreamBranch)
+	}
+	resp := d.handleListAgents(socket.Request{Command: "list_agents", Args: map[string]interface{}{"repo": "test-repo"}})
+	agents, _ := resp.Data.([]map[string]interface{})
+	for _, agent := range agents {
+		if agent["name"] == "dev" && agent["upstream_branch"] != "origin/shared" {
+			t.Errorf("list_agents upstream_branch = %v, want origin/shared", agent["upstream_branch"])
+		}
+	}
+
+	if resp := setUpstream("worker", "origin/shared"); resp.Success {
+		t.Error("only workspaces should take an upstream")
+	}
+	if resp := setUpstream("nope", "origin/shared"); resp.Success {
+		t.Error("an unknown agent should fail")
+	}
+	if resp := setUpstream("dev", ""); !resp.Success {
+		t.Fatalf("clearing the upstream failed: %s", resp.Error)
+	}
+	if agent, _ := d.state.GetAgent("test-repo", "dev"); agent.UpstreamBranch != "" {
+		t.Errorf("upstream after clearing = %q", agent.UpstreamBranch)
+	}
+}
+
 // TestHandleCompleteAgentTableDriven tests handleCompleteAgent with various argument combinations
 func TestHandleCompleteAgentTableDriven(t *testing.T) {
 	tests := []struct {
@@ -1062,6 +1366,125 @@ func TestHandleAddAgentCreatedAtIsSet(t *testing.T) {
 	}
 }
 
+func TestHandleAddAgentContextFiles(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:   "https://github.com/test/repo",
+			TmuxSession: "test-session",
+			Agents:      make(map[string]state.Agent),
+		})
+	})
+	defer cleanup()
+
+	files := []interface{}{".multiclaude/context/design.md", ".multiclaude/context/stdin.md"}
+	resp := d.handleAddAgent(socket.Request{
+		Command: "add_agent",
+		Args: map[string]interface{}{
+			"repo":          "test-repo",
+			"agent":         "context-agent",
+			"type":          "worker",
+			"worktree_path": "/tmp/test",
+			"tmux_window":   "context-agent",
+			"context_files": files,
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("handleAddAgent() failed: %s", resp.Error)
+	}
+
+	agent, _ := d.state.GetAgent("test-repo", "context-agent")
+	if len(agent.ContextFiles) != 2 || agent.ContextFiles[0] != files[0] || agent.ContextFiles[1] != files[1] {
+		t.Errorf("ContextFiles = %v, want %v", agent.ContextFiles, files)
+	}
+}
+
+func TestHandleAddAgentEnvironment(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:   "https://github.com/test/repo",
+			TmuxSession: "test-session",
+			Agents:      make(map[string]state.Agent),
+		})
+	})
+	defer cleanup()
+
+	args := map[string]interface{}{
+		"repo":          "test-repo",
+		"agent":         "supervisor",
+		"type":          "supervisor",
+		"worktree_path": "/tmp/test",
+		"tmux_window":   "supervisor",
+		"env":           map[string]interface{}{"ANTHROPIC_API_KEY": "sk-vault"},
+	}
+	resp := d.handleAddAgent(socket.Request{Command: "add_agent", Args: args})
+	if !resp.Success {
+		t.Fatalf("handleAddAgent() failed: %s", resp.Error)
+	}
+
+	agent, _ := d.state.GetAgent("test-repo", "supervisor")
+	if agent.Environment["ANTHROPIC_API_KEY"] != "sk-vault" {
+		t.Errorf("Environment = %v, want ANTHROPIC_API_KEY stored", agent.Environment)
+	}
+
+	args["agent"] = "bad-env"
+	args["tmux_window"] = "bad-env"
+	args["env"] = map[string]interface{}{"NOT VALID": "x"}
+	resp = d.handleAddAgent(socket.Request{Command: "add_agent", Args: args})
+	if resp.Success || !contains(resp.Error, "invalid environment variable") {
+		t.Errorf("handleAddAgent() with an invalid variable name = %+v, want an error", resp)
+	}
+}
+
+func TestHandleCreateWorkerRefusals(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:            "https://github.com/test/repo",
+			TmuxSession:          "test-session",
+			Agents:               make(map[string]state.Agent),
+			MaxConcurrentWorkers: 1,
+		})
+		s.AddAgent("test-repo", "busy-worker", state.Agent{
+			Type:       state.AgentTypeWorker,
+			TmuxWindow: "busy-worker",
+			Task:       "Fix the flaky test",
+			CreatedAt:  time.Now(),
+		})
+	})
+	defer cleanup()
+
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantError string
+		wantData  string
+	}{
+		{"missing task", map[string]interface{}{"repo": "test-repo"}, "task is required", ""},
+		{"push_to without branch", map[string]interface{}{"repo": "test-repo", "task": "t", "push_to": "work/x"}, "push_to requires branch", ""},
+		{"name with a path separator", map[string]interface{}{"repo": "test-repo", "task": "t", "name": "../escape"}, "invalid worker name", ""},
+		{"name with a slash", map[string]interface{}{"repo": "test-repo", "task": "t", "name": "feature/x"}, "cannot contain '/'", ""},
+		{"reserved name", map[string]interface{}{"repo": "test-repo", "task": "t", "name": "merge-queue"}, "name is reserved", ""},
+		{"unknown repo", map[string]interface{}{"repo": "nope", "task": "t"}, "not found", ""},
+		{"worker limit", map[string]interface{}{"repo": "test-repo", "task": "Another task"}, "worker limit", "limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := d.handleCreateWorker(socket.Request{Command: "create_worker", Args: tt.args})
+			if resp.Success {
+				t.Fatal("handleCreateWorker() should fail")
+			}
+			if !contains(resp.Error, tt.wantError) {
+				t.Errorf("handleCreateWorker() error = %q, want it to contain %q", resp.Error, tt.wantError)
+			}
+			if tt.wantData != "" {
+				data, _ := resp.Data.(map[string]interface{})
+				if _, ok := data[tt.wantData]; !ok {
+					t.Errorf("handleCreateWorker() data = %v, want %q", resp.Data, tt.wantData)
+				}
+			}
+		})
+	}
+}
+
 // TestHandleAddRepoEmptyAgentsMap verifies the Agents map is initialized
 func TestHandleAddRepoEmptyAgentsMap(t *testing.T) {
 	d, cleanup := setupTestDaemonWithState(t, nil)
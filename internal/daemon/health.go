@@ -0,0 +1,153 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/logging"
+	"github.com/dlorenc/multiclaude/internal/procs"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// healthCheckInterval is how often agent health is checked
+const healthCheckInterval = 2 * time.Minute
+
+// sessionLister looks up the tmux sessions and windows agents run in
+type sessionLister interface {
+	HasSession(ctx context.Context, name string) (bool, error)
+	ListWindowInfo(ctx context.Context, session string) ([]tmux.WindowInfo, error)
+}
+
+// agentKeeper acts on what the health check finds; the daemon implements
+// it
+type agentKeeper interface {
+	restoreRepoAgents(repoName string, repo *state.Repository) error
+	reconcileAgentWindow(repoName, agentName, session string, agent state.Agent, windows []tmux.WindowInfo) (state.Agent, bool)
+	autoRestartAgent(repoName, agentName string, agent state.Agent, repo *state.Repository, now time.Time)
+	cleanupDeadAgents(deadAgents map[string][]string)
+	cleanupOrphanedWorktrees()
+}
+
+// HealthChecker finds agents whose tmux window or process is gone,
+// restoring lost sessions, restarting persistent agents and cleaning up
+// the rest, then runs the daemon's periodic housekeeping
+type HealthChecker struct {
+	store  repoStore
+	tmux   sessionLister
+	agents agentKeeper
+	// foreignSessions surveys the tmux server, returning the sessions that
+	// belong to other multiclaude roots by session; nil skips the survey
+	foreignSessions func(ctx context.Context) map[string]string
+	// housekeeping runs after each check, in order
+	housekeeping []func()
+	logger       *logging.Logger
+}
+
+func (h *HealthChecker) Name() string { return "health check" }
+
+func (h *HealthChecker) Schedule() LoopSchedule {
+	return LoopSchedule{Every: healthCheckInterval, RunAtStart: true}
+}
+
+// Tick checks agent health, then runs the housekeeping
+func (h *HealthChecker) Tick(ctx context.Context) error {
+	err := h.Check(ctx)
+	for _, task := range h.housekeeping {
+		task()
+	}
+	return err
+}
+
+// Check checks if agents are still alive
+func (h *HealthChecker) Check(ctx context.Context) error {
+	h.logger.Debug("Checking agent health")
+
+	deadAgents := make(map[string][]string) // repo -> []agent names
+	var failures tickErrors
+
+	var foreign map[string]string
+	if h.foreignSessions != nil {
+		foreign = h.foreignSessions(ctx)
+	}
+
+	for repoName, repo := range h.store.GetAllRepos() {
+		// A session with this name that another root tagged is not ours:
+		// neither check its windows nor restore over it
+		if root, ok := foreign[repo.TmuxSession]; ok {
+			h.logger.Warn("Tmux session %s of repo %s belongs to multiclaude root %s; skipping its health check", repo.TmuxSession, repoName, root)
+			continue
+		}
+
+		// Check if tmux session exists
+		hasSession, err := h.tmux.HasSession(ctx, repo.TmuxSession)
+		if err != nil {
+			h.logger.Error("Failed to check session %s: %v", repo.TmuxSession, err)
+			failures.add(fmt.Errorf("checking session %s: %w", repo.TmuxSession, err))
+			continue
+		}
+
+		if !hasSession {
+			h.logger.Warn("Tmux session %s not found for repo %s, attempting restoration", repo.TmuxSession, repoName)
+			// Try to restore the session and agents instead of cleaning up
+			if err := h.agents.restoreRepoAgents(repoName, repo); err != nil {
+				h.logger.Error("Failed to restore repo %s: %v, marking all agents for cleanup", repoName, err)
+				failures.add(fmt.Errorf("restoring repo %s: %w", repoName, err))
+				// Only mark for cleanup if restoration failed
+				for agentName := range repo.Agents {
+					deadAgents[repoName] = append(deadAgents[repoName], agentName)
+				}
+			} else {
+				h.logger.Info("Successfully restored tmux session and agents for repo %s", repoName)
+			}
+			continue
+		}
+
+		// Windows are matched by ID, so list them once per session
+		windows, err := h.tmux.ListWindowInfo(ctx, repo.TmuxSession)
+		if err != nil {
+			h.logger.Error("Failed to list windows of session %s: %v", repo.TmuxSession, err)
+			failures.add(fmt.Errorf("listing windows of session %s: %w", repo.TmuxSession, err))
+			continue
+		}
+
+		// Check each agent
+		for agentName, agent := range repo.Agents {
+			// Check if agent is marked as ready for cleanup
+			if agent.ReadyForCleanup {
+				h.logger.Info("Agent %s is ready for cleanup", agentName)
+				deadAgents[repoName] = append(deadAgents[repoName], agentName)
+				continue
+			}
+
+			// Check if window exists, repairing a renamed one
+			agent, hasWindow := h.agents.reconcileAgentWindow(repoName, agentName, repo.TmuxSession, agent, windows)
+			if !hasWindow {
+				h.logger.Warn("Agent %s window not found, marking for cleanup", agentName)
+				deadAgents[repoName] = append(deadAgents[repoName], agentName)
+				continue
+			}
+
+			// Check if process is alive (if we have a PID)
+			if agent.PID > 0 && !procs.IsAlive(agent.PID) {
+				h.logger.Warn("Agent %s process (PID %d) not running", agentName, agent.PID)
+
+				// For persistent agents (supervisor, merge-queue, workspace), attempt auto-restart
+				if agent.Type == state.AgentTypeSupervisor || agent.Type == state.AgentTypeMergeQueue || agent.Type == state.AgentTypeWorkspace {
+					h.agents.autoRestartAgent(repoName, agentName, agent, repo, time.Now())
+				}
+				// For transient agents (workers, review), don't auto-restart - they complete and clean up
+			}
+		}
+	}
+
+	// Clean up dead agents
+	if len(deadAgents) > 0 {
+		h.agents.cleanupDeadAgents(deadAgents)
+	}
+
+	// Clean up orphaned worktrees
+	h.agents.cleanupOrphanedWorktrees()
+	return failures.err()
+}
@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/hooks"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// fireLifecycleEvent runs the repository's and the global lifecycle hooks
+// for event in the background. Hooks never hold up or fail the operation
+// that fired them: their output and failures only go to the daemon log.
+func (d *Daemon) fireLifecycleEvent(event hooks.Event) {
+	if !hooks.LifecycleHooksEnabled() {
+		return
+	}
+	event.Time = time.Now()
+	if event.GithubURL == "" {
+		if repo, exists := d.state.GetRepo(event.Repo); exists {
+			event.GithubURL = repo.GithubURL
+		}
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		lifecycleHooks, err := hooks.LoadLifecycleHooks(d.paths.ConfigDir, d.paths.RepoDir(event.Repo), event.Name)
+		if err != nil {
+			d.logger.Warn("Lifecycle hooks for %s: %v", event.Name, err)
+		}
+		for _, result := range hooks.RunLifecycleHooks(d.ctx, lifecycleHooks, event) {
+			d.logLifecycleResult(event, result)
+		}
+	}()
+}
+
+// logLifecycleResult appends a hook's output and outcome to the daemon log
+func (d *Daemon) logLifecycleResult(event hooks.Event, result hooks.LifecycleResult) {
+	for _, line := range strings.Split(strings.TrimRight(result.Output, "\n"), "\n") {
+		if line != "" {
+			d.logger.Info("[%s hook] %s", event.Name, line)
+		}
+	}
+	if result.Err != nil {
+		d.logger.Warn("Lifecycle hook %q for %s of %s/%s failed after %s: %v", result.Hook.Command, event.Name, event.Repo, event.Agent, result.Duration.Round(time.Millisecond), result.Err)
+		return
+	}
+	d.logger.Info("Lifecycle hook %q for %s of %s/%s finished in %s", result.Hook.Command, event.Name, event.Repo, event.Agent, result.Duration.Round(time.Millisecond))
+}
+
+// agentEvent describes a lifecycle event of an agent
+func agentEvent(name, repoName, agentName string, agent state.Agent) hooks.Event {
+	return hooks.Event{
+		Name:          name,
+		Repo:          repoName,
+		Agent:         agentName,
+		AgentType:     string(agent.Type),
+		Task:          agent.Task,
+		WorktreePath:  agent.WorktreePath,
+		Summary:       agent.Summary,
+		FailureReason: agent.FailureReason,
+		PRURL:         agent.PRURL,
+		Branch:        agentBranch(agent),
+	}
+}
+
+// isWorkerType reports whether agents of type t fire the worker_created and
+// worker_completed events
+func isWorkerType(t state.AgentType) bool {
+	return t == state.AgentTypeWorker || t == state.AgentTypeEphemeral
+}
@@ -0,0 +1,160 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/hooks"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestLifecycleHooksFireOnAgentEvents(t *testing.T) {
+	t.Setenv(hooks.LifecycleTestModeEnv, "1")
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	// The health check cleans up the completed worker only if the
+	// repository's session exists; one left by an earlier run belongs to
+	// that run's root, so start afresh
+	d.tmux.KillSession(d.ctx, "mc-lifecycle-hooks-test")
+	if err := d.tmux.CreateSession(d.ctx, "mc-lifecycle-hooks-test", true); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer d.tmux.KillSession(d.ctx, "mc-lifecycle-hooks-test")
+	d.tagSession("mc-lifecycle-hooks-test")
+
+	// Each hook appends the event to a file; a slow hook checks that
+	// operations do not wait for hooks
+	eventsFile := filepath.Join(t.TempDir(), "events")
+	hooksDir := filepath.Join(d.paths.RepoDir("test-repo"), ".multiclaude")
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		t.Fatalf("Failed to create hooks dir: %v", err)
+	}
+	record := `echo "$MULTICLAUDE_EVENT/$MULTICLAUDE_AGENT/$MULTICLAUDE_SUMMARY$MULTICLAUDE_REASON" >> ` + eventsFile
+	config := `{"hooks": {
+		"repo_initialized": ["` + strings.ReplaceAll(record, `"`, `\"`) + `"],
+		"worker_created": ["sleep 2", "` + strings.ReplaceAll(record, `"`, `\"`) + `"],
+		"worker_completed": ["exit 1", "` + strings.ReplaceAll(record, `"`, `\"`) + `"],
+		"agent_removed": ["` + strings.ReplaceAll(record, `"`, `\"`) + `"]
+	}}`
+	if err := os.WriteFile(filepath.Join(hooksDir, hooks.LifecycleConfigFile), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write lifecycle hooks: %v", err)
+	}
+
+	waitForEvents := func(want ...string) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			data, _ := os.ReadFile(eventsFile)
+			got := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if strings.Join(got, "\n") == strings.Join(want, "\n") {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("hooks recorded %q, want %q", got, want)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	resp := d.handleAddRepo(socket.Request{Args: map[string]interface{}{
+		"name":         "test-repo",
+		"github_url":   "https://github.com/test/repo",
+		"tmux_session": "mc-lifecycle-hooks-test",
+	}})
+	if !resp.Success {
+		t.Fatalf("add_repo failed: %s", resp.Error)
+	}
+	waitForEvents("repo_initialized//")
+
+	start := time.Now()
+	resp = d.handleAddAgent(socket.Request{Args: map[string]interface{}{
+		"repo":          "test-repo",
+		"agent":         "fox",
+		"type":          string(state.AgentTypeWorker),
+		"worktree_path": "/tmp/fox",
+		"tmux_window":   "fox",
+		"task":          "Fix the flaky login test",
+	}})
+	if !resp.Success {
+		t.Fatalf("add_agent failed: %s", resp.Error)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("add_agent took %s, should not wait for hooks", elapsed)
+	}
+	waitForEvents("repo_initialized//", "worker_created/fox/")
+
+	// A failing hook neither fails the operation nor stops the next hook.
+	// The completed worker is then cleaned up by the health check.
+	resp = d.handleCompleteAgent(socket.Request{Args: map[string]interface{}{
+		"repo":    "test-repo",
+		"agent":   "fox",
+		"summary": "Fixed it",
+	}})
+	if !resp.Success {
+		t.Fatalf("complete_agent failed: %s", resp.Error)
+	}
+	events := []string{"repo_initialized//", "worker_created/fox/", "worker_completed/fox/Fixed it", "agent_removed/fox/Fixed itcleaned_up"}
+	waitForEvents(events...)
+
+	if err := d.state.AddAgent("test-repo", "badger", state.Agent{Type: state.AgentTypeReview, TmuxWindow: "badger", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+	resp = d.handleRemoveAgent(socket.Request{Args: map[string]interface{}{
+		"repo":  "test-repo",
+		"agent": "badger",
+	}})
+	if !resp.Success {
+		t.Fatalf("remove_agent failed: %s", resp.Error)
+	}
+	waitForEvents(append(events, "agent_removed/badger/removed")...)
+}
+
+func TestLifecycleHooksSkippedInTestMode(t *testing.T) {
+	t.Setenv("MULTICLAUDE_TEST_MODE", "1")
+	t.Setenv(hooks.LifecycleTestModeEnv, "")
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	marker := filepath.Join(t.TempDir(), "fired")
+	hooksDir := filepath.Join(d.paths.RepoDir("test-repo"), ".multiclaude")
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		t.Fatalf("Failed to create hooks dir: %v", err)
+	}
+	config := `{"hooks": {"repo_initialized": ["touch ` + marker + `"]}}`
+	if err := os.WriteFile(filepath.Join(hooksDir, hooks.LifecycleConfigFile), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write lifecycle hooks: %v", err)
+	}
+
+	d.fireLifecycleEvent(hooks.Event{Name: hooks.EventRepoInitialized, Repo: "test-repo"})
+	time.Sleep(200 * time.Millisecond)
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("lifecycle hooks should not run in test mode")
+	}
+}
+
+func TestAgentEventBranch(t *testing.T) {
+	// A worker pushing to an existing PR branch is not on work/<name>
+	dir := t.TempDir()
+	runTestGit(t, dir, "init", "-q", "-b", "main")
+	runTestGit(t, dir, "commit", "-q", "--allow-empty", "-m", "Initial commit")
+	runTestGit(t, dir, "checkout", "-q", "-b", "fix-login")
+
+	event := agentEvent(hooks.EventWorkerCreated, "test-repo", "fox", state.Agent{Type: state.AgentTypeWorker, WorktreePath: dir})
+	if event.Branch != "fix-login" {
+		t.Errorf("Branch = %q, want the worktree's branch fix-login", event.Branch)
+	}
+
+	// Once the worktree is gone, the branch last recorded is used
+	event = agentEvent(hooks.EventAgentRemoved, "test-repo", "fox", state.Agent{Type: state.AgentTypeWorker, WorktreePath: filepath.Join(dir, "gone"), Branch: "work/fox"})
+	if event.Branch != "work/fox" {
+		t.Errorf("Branch = %q, want the recorded branch work/fox", event.Branch)
+	}
+
+	if event := agentEvent(hooks.EventWorkerCreated, "test-repo", "eph", state.Agent{Type: state.AgentTypeEphemeral, WorktreePath: dir}); event.Branch != "" {
+		t.Errorf("ephemeral agent Branch = %q, want empty", event.Branch)
+	}
+}
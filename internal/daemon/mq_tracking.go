@@ -0,0 +1,180 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/gh"
+	"github.com/dlorenc/multiclaude/internal/githuburl"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// handleMQTrackPR adds or updates the merge-queue's tracking record for a PR
+func (d *Daemon) handleMQTrackPR(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	number, errResp, ok := getPRNumberArg(req.Args)
+	if !ok {
+		return errResp
+	}
+
+	pr := state.TrackedPR{Number: number}
+	pr.URL, _ = req.Args["url"].(string)
+	pr.Status, _ = req.Args["status"].(string)
+	pr.Notes, _ = req.Args["notes"].(string)
+
+	if err := d.state.TrackPR(repoName, pr); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.logger.Info("Merge queue tracking PR #%d in %s (status: %s)", number, repoName, pr.Status)
+	return socket.Response{Success: true}
+}
+
+// handleMQUntrackPR removes the merge-queue's tracking record for a PR
+func (d *Daemon) handleMQUntrackPR(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	number, errResp, ok := getPRNumberArg(req.Args)
+	if !ok {
+		return errResp
+	}
+
+	if err := d.state.UntrackPR(repoName, number); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.logger.Info("Merge queue stopped tracking PR #%d in %s", number, repoName)
+	return socket.Response{Success: true}
+}
+
+// handleMQListPRs returns the PRs the merge-queue is tracking in a repository
+func (d *Daemon) handleMQListPRs(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	prs, err := d.state.ListTrackedPRs(repoName)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	result := make([]map[string]interface{}, len(prs))
+	for i, pr := range prs {
+		result[i] = map[string]interface{}{
+			"number":      pr.Number,
+			"url":         pr.URL,
+			"status":      pr.Status,
+			"notes":       pr.Notes,
+			"last_action": pr.LastAction,
+		}
+	}
+
+	return socket.Response{Success: true, Data: result}
+}
+
+// getPRNumberArg extracts the required positive "number" argument from
+// request Args. JSON numbers arrive as float64.
+func getPRNumberArg(args map[string]interface{}) (int, socket.Response, bool) {
+	number := 0
+	if v, ok := args["number"].(float64); ok {
+		number = int(v)
+	} else if v, ok := args["number"].(int); ok {
+		number = v
+	}
+	if number <= 0 {
+		return 0, socket.Response{
+			Success: false,
+			Error:   "missing 'number': a positive PR number is required",
+		}, false
+	}
+	return number, socket.Response{}, true
+}
+
+// pruneTrackedPRs drops merge-queue tracking records for PRs that GitHub
+// reports as merged or closed. PRs whose state cannot be looked up are kept.
+func (d *Daemon) pruneTrackedPRs() {
+	for repoName, repo := range d.state.GetAllRepos() {
+		if d.ghDisabled(repoName, ghFeaturePRPruning) {
+			continue
+		}
+		prs, err := d.state.ListTrackedPRs(repoName)
+		if err != nil || len(prs) == 0 {
+			continue
+		}
+		owner, name, err := githuburl.Parse(repo.GithubURL)
+		if err != nil {
+			continue
+		}
+
+		for _, pr := range prs {
+			ctx, cancel := context.WithTimeout(d.ctx, 30*time.Second)
+			prState, err := d.lookupPRState(ctx, owner, name, pr.Number)
+			cancel()
+			if d.ghFailed(repoName, ghFeaturePRPruning, err) {
+				break
+			}
+			if err != nil {
+				d.logger.Debug("Failed to look up state of PR #%d in %s: %v", pr.Number, repoName, err)
+				continue
+			}
+			if prState != "MERGED" && prState != "CLOSED" {
+				continue
+			}
+			if err := d.state.UntrackPR(repoName, pr.Number); err != nil {
+				d.logger.Warn("Failed to prune tracked PR #%d in %s: %v", pr.Number, repoName, err)
+				continue
+			}
+			d.logger.Info("Pruned tracked PR #%d in %s (%s)", pr.Number, repoName, strings.ToLower(prState))
+		}
+	}
+}
+
+// ghPRState returns the state GitHub reports for a PR: OPEN, CLOSED or MERGED
+func ghPRState(ctx context.Context, owner, name string, number int) (string, error) {
+	var pr struct {
+		State string `json:"state"`
+	}
+	if err := gh.PRView(ctx, gh.Repo{Slug: owner + "/" + name}, strconv.Itoa(number), "state", &pr); err != nil {
+		return "", err
+	}
+	return pr.State, nil
+}
+
+// trackedPRsSummary describes a repository's tracked PRs for a freshly
+// started merge-queue agent, or returns "" when none are tracked
+func (d *Daemon) trackedPRsSummary(repoName string) string {
+	prs, err := d.state.ListTrackedPRs(repoName)
+	if err != nil || len(prs) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("You are resuming merge-queue duty. Before the restart you were tracking these PRs:\n")
+	for _, pr := range prs {
+		status := pr.Status
+		if status == "" {
+			status = "tracked"
+		}
+		fmt.Fprintf(&b, "- #%d [%s] last action %s", pr.Number, status, pr.LastAction.Format(time.RFC3339))
+		if pr.URL != "" {
+			fmt.Fprintf(&b, " %s", pr.URL)
+		}
+		if pr.Notes != "" {
+			fmt.Fprintf(&b, " - %s", pr.Notes)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("Resume from this state rather than starting over: do not repeat reviews or approvals already recorded. " +
+		"Keep the records current with 'multiclaude agent mq track' and 'multiclaude agent mq untrack'.")
+	return b.String()
+}
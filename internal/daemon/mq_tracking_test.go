@@ -0,0 +1,135 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestHandleMQTrackAndListPRs(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	track := func(args map[string]interface{}) socket.Response {
+		args["repo"] = "test-repo"
+		return d.handleMQTrackPR(socket.Request{Command: "mq_track_pr", Args: args})
+	}
+
+	if resp := track(map[string]interface{}{"number": float64(12), "status": "waiting-ci", "url": "https://github.com/test/repo/pull/12"}); !resp.Success {
+		t.Fatalf("handleMQTrackPR() failed: %s", resp.Error)
+	}
+	if resp := track(map[string]interface{}{"number": float64(7), "status": "changes-requested", "notes": "asked for tests"}); !resp.Success {
+		t.Fatalf("handleMQTrackPR() failed: %s", resp.Error)
+	}
+	// Updating keeps fields that are not given
+	if resp := track(map[string]interface{}{"number": float64(12), "status": "approved"}); !resp.Success {
+		t.Fatalf("handleMQTrackPR() update failed: %s", resp.Error)
+	}
+	if resp := track(map[string]interface{}{"status": "approved"}); resp.Success {
+		t.Error("handleMQTrackPR() should require a PR number")
+	}
+
+	resp := d.handleMQListPRs(socket.Request{Command: "mq_list_prs", Args: map[string]interface{}{"repo": "test-repo"}})
+	if !resp.Success {
+		t.Fatalf("handleMQListPRs() failed: %s", resp.Error)
+	}
+	prs, _ := resp.Data.([]map[string]interface{})
+	if len(prs) != 2 {
+		t.Fatalf("expected 2 tracked PRs, got %d", len(prs))
+	}
+	if prs[0]["number"] != 7 || prs[1]["number"] != 12 {
+		t.Errorf("tracked PRs should be ordered by number, got %v and %v", prs[0]["number"], prs[1]["number"])
+	}
+	if prs[1]["status"] != "approved" || prs[1]["url"] != "https://github.com/test/repo/pull/12" {
+		t.Errorf("updated PR = %v, want status approved with its URL kept", prs[1])
+	}
+
+	summary := d.trackedPRsSummary("test-repo")
+	for _, want := range []string{"#7 [changes-requested]", "asked for tests", "#12 [approved]"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary missing %q:\n%s", want, summary)
+		}
+	}
+
+	resp = d.handleMQUntrackPR(socket.Request{Command: "mq_untrack_pr", Args: map[string]interface{}{"repo": "test-repo", "number": float64(7)}})
+	if !resp.Success {
+		t.Fatalf("handleMQUntrackPR() failed: %s", resp.Error)
+	}
+	resp = d.handleMQUntrackPR(socket.Request{Command: "mq_untrack_pr", Args: map[string]interface{}{"repo": "test-repo", "number": float64(7)}})
+	if resp.Success {
+		t.Error("handleMQUntrackPR() should fail for an untracked PR")
+	}
+}
+
+func TestTrackedPRsSummaryEmpty(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	if summary := d.trackedPRsSummary("test-repo"); summary != "" {
+		t.Errorf("expected no summary without tracked PRs, got: %s", summary)
+	}
+}
+
+func TestPruneTrackedPRs(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	for _, number := range []int{1, 2, 3, 4} {
+		if err := d.state.TrackPR("test-repo", state.TrackedPR{Number: number}); err != nil {
+			t.Fatalf("TrackPR() failed: %v", err)
+		}
+	}
+
+	prStates := map[int]string{1: "OPEN", 2: "MERGED", 3: "CLOSED"}
+	d.lookupPRState = func(ctx context.Context, owner, name string, number int) (string, error) {
+		if owner != "test" || name != "repo" {
+			t.Errorf("lookup for %s/%s, want test/repo", owner, name)
+		}
+		if prState, ok := prStates[number]; ok {
+			return prState, nil
+		}
+		return "", fmt.Errorf("gh unavailable")
+	}
+
+	d.pruneTrackedPRs()
+
+	prs, err := d.state.ListTrackedPRs("test-repo")
+	if err != nil {
+		t.Fatalf("ListTrackedPRs() failed: %v", err)
+	}
+	var numbers []int
+	for _, pr := range prs {
+		numbers = append(numbers, pr.Number)
+	}
+	// Open PRs and PRs whose state is unknown are kept
+	if fmt.Sprint(numbers) != "[1 4]" {
+		t.Errorf("tracked PRs after pruning = %v, want [1 4]", numbers)
+	}
+}
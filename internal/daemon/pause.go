@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// allowedWhilePaused are the socket commands a paused daemon still serves
+var allowedWhilePaused = map[string]bool{
+	"ping":               true,
+	"status":             true,
+	"stop":               true,
+	"pause":              true,
+	"resume":             true,
+	"repair_state":       true,
+	"connection_audit":   true,
+	"describe_state":     true,
+	"scheduled_messages": true,
+}
+
+// handlePause pauses the daemon for `multiclaude state restore --force`:
+// its loops skip their work and requests that could change state are
+// refused, so the state file can be swapped under it
+func (d *Daemon) handlePause(req socket.Request) socket.Response {
+	if d.paused.Swap(true) {
+		return socket.Response{Success: false, Error: "daemon is already paused"}
+	}
+	d.logger.Info("Daemon paused")
+	return socket.Response{Success: true}
+}
+
+// handleResume resumes a paused daemon, reloading the state file first
+// since it may have been restored from a backup while the daemon was paused
+func (d *Daemon) handleResume(req socket.Request) socket.Response {
+	if !d.paused.Load() {
+		return socket.Response{Success: false, Error: "daemon is not paused"}
+	}
+	if err := d.state.Reload(); err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("failed to reload state, daemon stays paused: %v", err)}
+	}
+	d.paused.Store(false)
+	d.logger.Info("Daemon resumed with reloaded state")
+	return socket.Response{Success: true}
+}
+
+// pausedResponse is the reply to requests a paused daemon refuses
+func pausedResponse(command string) socket.Response {
+	return socket.Response{
+		Success: false,
+		Error:   fmt.Sprintf("daemon is paused for a state restore and refuses %q; it resumes when the restore finishes, or restart it with 'multiclaude daemon stop' and 'multiclaude start'", command),
+	}
+}
@@ -0,0 +1,55 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestPauseAndResume(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	if resp := d.handleRequest(socket.Request{Command: "resume"}); resp.Success {
+		t.Error("resume should fail when the daemon is not paused")
+	}
+	if resp := d.handleRequest(socket.Request{Command: "pause"}); !resp.Success {
+		t.Fatalf("pause failed: %s", resp.Error)
+	}
+
+	// Requests that could change state are refused while paused
+	resp := d.handleRequest(socket.Request{Command: "set_current_repo", Args: map[string]interface{}{"name": "test-repo"}})
+	if resp.Success || !strings.Contains(resp.Error, "paused") {
+		t.Errorf("set_current_repo while paused = %+v, want it refused", resp)
+	}
+	if resp := d.handleRequest(socket.Request{Command: "ping"}); !resp.Success {
+		t.Errorf("ping while paused failed: %s", resp.Error)
+	}
+
+	// The state file changes under the paused daemon ...
+	restored := state.New(d.paths.StateFile)
+	restored.CurrentRepo = "restored"
+	if err := restored.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	// ... and resuming reloads it
+	if resp := d.handleRequest(socket.Request{Command: "resume"}); !resp.Success {
+		t.Fatalf("resume failed: %s", resp.Error)
+	}
+	if got := d.state.GetCurrentRepo(); got != "restored" {
+		t.Errorf("current repo after resume = %q, want the reloaded %q", got, "restored")
+	}
+	if len(d.state.ListRepos()) != 0 {
+		t.Errorf("repos after resume = %v, want the reloaded state's none", d.state.ListRepos())
+	}
+	if resp := d.handleRequest(socket.Request{Command: "list_repos"}); !resp.Success {
+		t.Errorf("list_repos after resume failed: %s", resp.Error)
+	}
+}
@@ -5,7 +5,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"syscall"
+
+	"github.com/dlorenc/multiclaude/internal/procs"
 )
 
 // PIDFile manages the daemon PID file
@@ -63,14 +64,7 @@ func (p *PIDFile) IsRunning() (bool, int, error) {
 		return false, 0, nil
 	}
 
-	// Check if process exists by sending signal 0
-	process, err := os.FindProcess(pid)
-	if err != nil {
-		return false, 0, nil
-	}
-
-	err = process.Signal(syscall.Signal(0))
-	if err != nil {
+	if !procs.IsAlive(pid) {
 		// Process doesn't exist or we don't have permission
 		return false, 0, nil
 	}
@@ -0,0 +1,74 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/githuburl"
+)
+
+// prWatchInterval is how often the daemon polls watched PRs
+const prWatchInterval = 5 * time.Minute
+
+// checkWatchedPRs messages each agent watching a PR that GitHub reports as
+// merged or closed, and stops watching it. PRs whose state cannot be looked
+// up are checked again next time.
+func (d *Daemon) checkWatchedPRs() {
+	for repoName, repo := range d.state.GetAllRepos() {
+		if d.ghDisabled(repoName, ghFeaturePRWatch) {
+			continue
+		}
+		owner, name, err := githuburl.Parse(repo.GithubURL)
+		if err != nil {
+			continue
+		}
+
+		for agentName, agent := range repo.Agents {
+			if !agent.WatchPR || agent.PRNumber <= 0 {
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(d.ctx, 30*time.Second)
+			prState, err := d.lookupPRState(ctx, owner, name, agent.PRNumber)
+			cancel()
+			if d.ghFailed(repoName, ghFeaturePRWatch, err) {
+				break
+			}
+			if err != nil {
+				d.logger.Debug("Failed to look up state of PR #%d watched by %s/%s: %v", agent.PRNumber, repoName, agentName, err)
+				continue
+			}
+
+			var msg string
+			switch prState {
+			case "MERGED":
+				msg = fmt.Sprintf("PR #%d (%s) has been merged. Further pushes to its branch will not reach it; "+
+					"remove this workspace with 'multiclaude workspace rm %s' when you are done with it.", agent.PRNumber, agent.PRURL, agentName)
+			case "CLOSED":
+				msg = fmt.Sprintf("PR #%d (%s) was closed without being merged.", agent.PRNumber, agent.PRURL)
+			default:
+				continue
+			}
+
+			// Stop watching before notifying so a failed save cannot
+			// repeat the message every interval
+			current, exists := d.state.GetAgent(repoName, agentName)
+			if !exists {
+				continue
+			}
+			current.WatchPR = false
+			if err := d.state.UpdateAgent(repoName, agentName, current); err != nil {
+				d.logger.Warn("Failed to stop watching PR #%d for %s/%s: %v", agent.PRNumber, repoName, agentName, err)
+				continue
+			}
+
+			if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, msg); err != nil {
+				d.logger.Warn("Failed to notify %s/%s about PR #%d: %v", repoName, agentName, agent.PRNumber, err)
+				continue
+			}
+			d.logger.Info("Notified %s/%s that PR #%d is %s", repoName, agentName, agent.PRNumber, strings.ToLower(prState))
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestCheckWatchedPRs(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"pr-1":      {Type: state.AgentTypeWorkspace, PRURL: "https://github.com/test/repo/pull/1", PRNumber: 1, WatchPR: true},
+			"pr-2":      {Type: state.AgentTypeWorkspace, PRURL: "https://github.com/test/repo/pull/2", PRNumber: 2, WatchPR: true},
+			"pr-3":      {Type: state.AgentTypeWorkspace, PRURL: "https://github.com/test/repo/pull/3", PRNumber: 3, WatchPR: true},
+			"pr-4":      {Type: state.AgentTypeWorkspace, PRURL: "https://github.com/test/repo/pull/4", PRNumber: 4, WatchPR: true},
+			"unwatched": {Type: state.AgentTypeWorkspace, PRURL: "https://github.com/test/repo/pull/5", PRNumber: 5},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	prStates := map[int]string{1: "OPEN", 2: "MERGED", 3: "CLOSED", 5: "MERGED"}
+	d.lookupPRState = func(ctx context.Context, owner, name string, number int) (string, error) {
+		if prState, ok := prStates[number]; ok {
+			return prState, nil
+		}
+		return "", fmt.Errorf("gh unavailable")
+	}
+
+	d.checkWatchedPRs()
+
+	msgMgr := d.getMessageManager()
+	for agentName, want := range map[string]struct {
+		watching bool
+		message  string
+	}{
+		"pr-1":      {watching: true},
+		"pr-2":      {message: "has been merged"},
+		"pr-3":      {message: "closed without being merged"},
+		"pr-4":      {watching: true},
+		"unwatched": {},
+	} {
+		agent, _ := d.state.GetAgent("test-repo", agentName)
+		if agent.WatchPR != want.watching {
+			t.Errorf("%s: WatchPR = %v, want %v", agentName, agent.WatchPR, want.watching)
+		}
+
+		msgs, err := msgMgr.List("test-repo", agentName)
+		if err != nil {
+			t.Fatalf("Failed to list messages of %s: %v", agentName, err)
+		}
+		if want.message == "" {
+			if len(msgs) != 0 {
+				t.Errorf("%s: expected no message, got %d", agentName, len(msgs))
+			}
+			continue
+		}
+		if len(msgs) != 1 || msgs[0].From != "daemon" || !strings.Contains(msgs[0].Body, want.message) {
+			t.Errorf("%s: expected one daemon message containing %q, got %+v", agentName, want.message, msgs)
+		}
+	}
+
+	// Notified agents are not notified again
+	d.checkWatchedPRs()
+	if msgs, _ := msgMgr.List("test-repo", "pr-2"); len(msgs) != 1 {
+		t.Errorf("pr-2 notified again: %d messages", len(msgs))
+	}
+}
+
+func TestHandleUpdateAgentPRWatch(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"pr-7": {Type: state.AgentTypeWorkspace},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	resp := d.handleRequest(socket.Request{
+		Command: "update_agent_pr",
+		Args: map[string]interface{}{
+			"repo":      "test-repo",
+			"agent":     "pr-7",
+			"pr_url":    "https://github.com/test/repo/pull/7",
+			"pr_number": float64(7),
+			"watch":     true,
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("update_agent_pr failed: %s", resp.Error)
+	}
+	agent, _ := d.state.GetAgent("test-repo", "pr-7")
+	if !agent.WatchPR || agent.PRNumber != 7 {
+		t.Errorf("agent = %+v, want PR 7 watched", agent)
+	}
+
+	// Without a PR number there is nothing to poll
+	if err := d.state.AddAgent("test-repo", "no-number", state.Agent{Type: state.AgentTypeWorkspace}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+	resp = d.handleRequest(socket.Request{
+		Command: "update_agent_pr",
+		Args: map[string]interface{}{
+			"repo":   "test-repo",
+			"agent":  "no-number",
+			"pr_url": "https://github.com/test/repo/pull/x",
+			"watch":  true,
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("update_agent_pr failed: %s", resp.Error)
+	}
+	if agent, _ := d.state.GetAgent("test-repo", "no-number"); agent.WatchPR {
+		t.Error("WatchPR should not be set without a PR number")
+	}
+}
@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// DefaultProfileDuration is how long a CPU profile runs when no duration is given
+const DefaultProfileDuration = 30 * time.Second
+
+// MaxProfileDuration caps a CPU profile so a request cannot tie up the
+// profiler indefinitely
+const MaxProfileDuration = 5 * time.Minute
+
+// ProfileTypes are the profiles start_profiling can take. Only the CPU
+// profile runs for a duration; the others are snapshots.
+var ProfileTypes = []string{"cpu", "mem", "goroutine"}
+
+// handleStartProfiling profiles the daemon and returns the profile in pprof
+// format. A CPU profile is collected for the requested duration, or until the
+// daemon stops; heap and goroutine profiles are taken immediately.
+func (d *Daemon) handleStartProfiling(req socket.Request) socket.Response {
+	profileType, _ := req.Args["type"].(string)
+	if profileType == "" {
+		profileType = "cpu"
+	}
+
+	duration := DefaultProfileDuration
+	if value, _ := req.Args["duration"].(string); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return socket.Response{Success: false, Error: fmt.Sprintf("invalid duration: %q", value)}
+		}
+		duration = parsed
+	}
+	if duration > MaxProfileDuration {
+		return socket.Response{Success: false, Error: fmt.Sprintf("duration %s is longer than the maximum of %s", duration, MaxProfileDuration)}
+	}
+
+	var buf bytes.Buffer
+	switch profileType {
+	case "cpu":
+		// Only one CPU profile can run at a time; StartCPUProfile reports that
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("failed to start CPU profile: %v", err)}
+		}
+		d.logger.Info("Collecting a CPU profile for %s", duration)
+		timer := time.NewTimer(duration)
+		select {
+		case <-timer.C:
+		case <-d.ctx.Done():
+			timer.Stop()
+		}
+		pprof.StopCPUProfile()
+
+	case "mem":
+		// Collect garbage first so the heap profile reflects live objects
+		runtime.GC()
+		if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("failed to write heap profile: %v", err)}
+		}
+
+	case "goroutine":
+		if err := pprof.Lookup("goroutine").WriteTo(&buf, 0); err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("failed to write goroutine profile: %v", err)}
+		}
+
+	default:
+		return socket.Response{Success: false, Error: fmt.Sprintf("unknown profile type: %q (must be one of: cpu, mem, goroutine)", profileType)}
+	}
+
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"type":    profileType,
+			"profile": buf.Bytes(),
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package daemon
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// isPprofProfile reports whether data is a gzipped protobuf, as pprof writes
+func isPprofProfile(t *testing.T, data []byte) bool {
+	t.Helper()
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	body, err := io.ReadAll(r)
+	return err == nil && len(body) > 0
+}
+
+func TestHandleStartProfiling(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	for _, args := range []map[string]interface{}{
+		{"type": "cpu", "duration": "50ms"},
+		{"type": "mem"},
+		{"type": "goroutine"},
+	} {
+		resp := d.handleRequest(socket.Request{Command: "start_profiling", Args: args})
+		if !resp.Success {
+			t.Fatalf("start_profiling %v failed: %s", args, resp.Error)
+		}
+		data := resp.Data.(map[string]interface{})
+		profile, _ := data["profile"].([]byte)
+		if data["type"] != args["type"] || !isPprofProfile(t, profile) {
+			t.Errorf("start_profiling %v returned a %v profile of %d bytes", args, data["type"], len(profile))
+		}
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"type": "block"},
+		{"type": "cpu", "duration": "soon"},
+		{"type": "cpu", "duration": "1h"},
+	} {
+		if resp := d.handleStartProfiling(socket.Request{Command: "start_profiling", Args: args}); resp.Success {
+			t.Errorf("start_profiling %v should fail", args)
+		}
+	}
+}
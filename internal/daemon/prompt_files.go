@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// promptFilePath returns where an agent's prompt file is written
+func (d *Daemon) promptFilePath(agentName string) string {
+	return filepath.Join(d.paths.Root, "prompts", agentName+".md")
+}
+
+// hashPromptFile returns the SHA-256 of a prompt file, or "" if it cannot
+// be read
+func hashPromptFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// promptDocsHash returns the hash stamped on the CLI reference in a prompt
+// file, or "" if it has none or cannot be read
+func promptDocsHash(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	_, hash, _ := prompts.ParseDocsStamp(string(data))
+	return hash
+}
+
+// promptFileUsable reports whether a prompt file exists and is not empty.
+// Claude started with a missing or empty --append-system-prompt-file runs
+// without the agent's instructions.
+func promptFileUsable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Size() > 0
+}
+
+// ensurePromptFile returns the path of an agent's prompt file, writing it
+// again from the agent's type and repository when it is missing or empty.
+// It reports whether the file was regenerated.
+func (d *Daemon) ensurePromptFile(repoName, agentName string, agent state.Agent, repo *state.Repository) (string, bool, error) {
+	promptFile := d.promptFilePath(agentName)
+	if promptFileUsable(promptFile) {
+		return promptFile, false, nil
+	}
+
+	var err error
+	if agent.Type == state.AgentTypeMergeQueue {
+		promptFile, err = d.writeMergeQueuePromptFile(repoName, agentName, repo.MergeQueueConfig)
+	} else {
+		promptFile, err = d.writePromptFile(repoName, prompts.AgentType(agent.Type), agentName)
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to regenerate prompt file: %w", err)
+	}
+	return promptFile, true, nil
+}
+
+// auditPromptFiles regenerates the missing or empty prompt files of all
+// registered agents, so that their next restart has instructions to load
+func (d *Daemon) auditPromptFiles() {
+	for repoName, repo := range d.state.GetAllRepos() {
+		for agentName, agent := range repo.Agents {
+			_, regenerated, err := d.ensurePromptFile(repoName, agentName, agent, repo)
+			if err != nil {
+				d.logger.Warn("Prompt file of %s/%s is missing and could not be regenerated: %v", repoName, agentName, err)
+				continue
+			}
+			if regenerated {
+				d.logger.Warn("Regenerated missing prompt file of %s/%s", repoName, agentName)
+			}
+		}
+	}
+}
+
+// promptDrift lists, as repo/agent, the agents whose prompt file no longer
+// matches the prompt they were started with. A running agent does not
+// reread its prompt, so these run on instructions other than those on disk
+// until they are restarted.
+func (d *Daemon) promptDrift() []string {
+	drifted := []string{}
+	for repoName, repo := range d.state.GetAllRepos() {
+		for agentName, agent := range repo.Agents {
+			if agent.PromptHash == "" {
+				continue
+			}
+			if hashPromptFile(d.promptFilePath(agentName)) != agent.PromptHash {
+				drifted = append(drifted, repoName+"/"+agentName)
+			}
+		}
+	}
+	sort.Strings(drifted)
+	return drifted
+}
@@ -0,0 +1,97 @@
+package daemon
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestAuditPromptFiles(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := os.MkdirAll(d.paths.RepoDir("test-repo"), 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"supervisor":  {Type: state.AgentTypeSupervisor},
+			"merge-queue": {Type: state.AgentTypeMergeQueue},
+			"worker-1":    {Type: state.AgentTypeWorker},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	// worker-1's file exists but was emptied; the others are missing
+	if _, err := d.writePromptFile("test-repo", "worker", "worker-1"); err != nil {
+		t.Fatalf("writePromptFile() failed: %v", err)
+	}
+	if err := os.WriteFile(d.promptFilePath("worker-1"), nil, 0644); err != nil {
+		t.Fatalf("Failed to empty prompt file: %v", err)
+	}
+
+	d.auditPromptFiles()
+	for _, name := range []string{"supervisor", "merge-queue", "worker-1"} {
+		if !promptFileUsable(d.promptFilePath(name)) {
+			t.Errorf("prompt file of %s was not regenerated", name)
+		}
+	}
+	if hashPromptFile(d.promptFilePath("merge-queue")) == hashPromptFile(d.promptFilePath("supervisor")) {
+		t.Error("merge-queue prompt should be regenerated with its own prompt")
+	}
+}
+
+func TestPromptDrift(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := os.MkdirAll(d.paths.RepoDir("test-repo"), 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      map[string]state.Agent{},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	for _, name := range []string{"alpha", "bravo"} {
+		if _, err := d.writePromptFile("test-repo", "worker", name); err != nil {
+			t.Fatalf("writePromptFile() failed: %v", err)
+		}
+		resp := d.dispatchRequest(socket.Request{Command: "add_agent", Args: map[string]interface{}{
+			"repo": "test-repo", "agent": name, "type": "worker",
+			"worktree_path": "/tmp/" + name, "tmux_window": name, "task": "task " + name,
+		}})
+		if !resp.Success {
+			t.Fatalf("add_agent failed: %s", resp.Error)
+		}
+	}
+	agent, _ := d.state.GetAgent("test-repo", "alpha")
+	if agent.PromptHash == "" || agent.PromptHash != hashPromptFile(d.promptFilePath("alpha")) {
+		t.Errorf("PromptHash = %q, want the hash of the prompt file", agent.PromptHash)
+	}
+	if drift := d.promptDrift(); len(drift) != 0 {
+		t.Errorf("promptDrift() = %v, want none", drift)
+	}
+
+	if err := os.WriteFile(d.promptFilePath("bravo"), []byte("edited"), 0644); err != nil {
+		t.Fatalf("Failed to edit prompt file: %v", err)
+	}
+	if drift := d.promptDrift(); !reflect.DeepEqual(drift, []string{"test-repo/bravo"}) {
+		t.Errorf("promptDrift() = %v, want [test-repo/bravo]", drift)
+	}
+
+	resp := d.dispatchRequest(socket.Request{Command: "status"})
+	data := resp.Data.(map[string]interface{})
+	if drift, _ := data["prompt_drift"].([]string); !reflect.DeepEqual(drift, []string{"test-repo/bravo"}) {
+		t.Errorf("status prompt_drift = %v, want [test-repo/bravo]", data["prompt_drift"])
+	}
+}
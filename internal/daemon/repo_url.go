@@ -0,0 +1,175 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
+	"github.com/dlorenc/multiclaude/internal/gh"
+	"github.com/dlorenc/multiclaude/internal/githuburl"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// handleSetRepoURL points a repository at a new GitHub URL, updating state,
+// the origin remote of the clone and every agent worktree, and telling
+// persistent agents about the move
+func (d *Daemon) handleSetRepoURL(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	newURL, errResp, ok := getRequiredStringArg(req.Args, "github_url", "GitHub repository URL is required (e.g., 'https://github.com/owner/repo')")
+	if !ok {
+		return errResp
+	}
+	newURL = strings.TrimRight(strings.TrimSpace(newURL), "/")
+
+	if _, _, err := githuburl.Parse(newURL); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", repoName)}
+	}
+	oldURL := repo.GithubURL
+
+	if err := d.state.UpdateGithubURL(repoName, newURL); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	d.clearRepoMove(repoName)
+	d.logger.Info("Changed GitHub URL for %s from %s to %s", repoName, oldURL, newURL)
+
+	// Worktrees normally share the clone's config, but set each one in case
+	// an agent has its own remote configuration
+	updated := 0
+	var failed []string
+	paths := []string{d.paths.RepoDir(repoName)}
+	for _, agent := range repo.Agents {
+		if agent.WorktreePath != "" {
+			paths = append(paths, agent.WorktreePath)
+		}
+	}
+	for _, path := range paths {
+		if err := setOriginURL(path, newURL); err != nil {
+			d.logger.Warn("Failed to update origin in %s: %v", path, err)
+			failed = append(failed, path)
+			continue
+		}
+		updated++
+	}
+
+	msgMgr := d.getMessageManager()
+	notice := fmt.Sprintf("This repository's GitHub remote moved from %s to %s. The origin remote has been updated; use the new owner/repo in any gh commands and PR links.", oldURL, newURL)
+	notified := 0
+	for agentName, agent := range repo.Agents {
+		if !isPersistentAgentType(agent.Type) {
+			continue
+		}
+		if _, err := msgMgr.Send(repoName, "daemon", agentName, notice); err != nil {
+			d.logger.Warn("Failed to notify %s/%s of URL change: %v", repoName, agentName, err)
+			continue
+		}
+		notified++
+	}
+
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"old_url":         oldURL,
+			"new_url":         newURL,
+			"remotes_updated": updated,
+			"remotes_failed":  failed,
+			"agents_notified": notified,
+		},
+	}
+}
+
+// isPersistentAgentType reports whether agents of this type outlive a single task
+func isPersistentAgentType(agentType state.AgentType) bool {
+	switch agentType {
+	case state.AgentTypeSupervisor, state.AgentTypeMergeQueue, state.AgentTypeWorkspace:
+		return true
+	}
+	return false
+}
+
+// setOriginURL runs git remote set-url origin in a clone or worktree
+func setOriginURL(path, url string) error {
+	cmd := exec.Command("git", "remote", "set-url", "origin", url)
+	cmd.Dir = path
+	_, _, err := cmdrun.Run(cmd)
+	return err
+}
+
+// checkRepoMoves asks GitHub for each repository's canonical name. GitHub
+// redirects renamed and transferred repositories, so a different full_name
+// means the URL in state is stale.
+func (d *Daemon) checkRepoMoves() {
+	for repoName, repo := range d.state.GetAllRepos() {
+		if d.ghDisabled(repoName, ghFeatureRepoMoves) {
+			continue
+		}
+		owner, name, err := githuburl.Parse(repo.GithubURL)
+		if err != nil {
+			continue
+		}
+
+		fullName, err := d.lookupRepoFullName(d.ctx, owner, name)
+		if d.ghFailed(repoName, ghFeatureRepoMoves, err) {
+			continue
+		}
+		if err != nil {
+			d.logger.Debug("Could not look up %s/%s on GitHub: %v", owner, name, err)
+			continue
+		}
+
+		if fullName == "" || strings.EqualFold(fullName, owner+"/"+name) {
+			d.clearRepoMove(repoName)
+			continue
+		}
+
+		newURL := "https://github.com/" + fullName
+		d.repoMovesMu.Lock()
+		known := d.repoMoves[repoName] == newURL
+		d.repoMoves[repoName] = newURL
+		d.repoMovesMu.Unlock()
+
+		if !known {
+			d.logger.Warn("Repository %s has moved to %s; run 'multiclaude repo set-url %s %s'", repoName, newURL, repoName, newURL)
+		}
+	}
+}
+
+// clearRepoMove forgets a detected move for a repository
+func (d *Daemon) clearRepoMove(repoName string) {
+	d.repoMovesMu.Lock()
+	defer d.repoMovesMu.Unlock()
+	delete(d.repoMoves, repoName)
+}
+
+// repoMovesSnapshot returns detected moves as repo name -> new URL
+func (d *Daemon) repoMovesSnapshot() map[string]string {
+	d.repoMovesMu.Lock()
+	defer d.repoMovesMu.Unlock()
+
+	moves := make(map[string]string, len(d.repoMoves))
+	for repo, url := range d.repoMoves {
+		moves[repo] = url
+	}
+	return moves
+}
+
+// ghRepoFullName returns the canonical owner/name GitHub reports for a repository
+func ghRepoFullName(ctx context.Context, owner, name string) (string, error) {
+	var repo struct {
+		FullName string `json:"full_name"`
+	}
+	if err := gh.API(ctx, fmt.Sprintf("repos/%s/%s", owner, name), &repo); err != nil {
+		return "", err
+	}
+	return repo.FullName, nil
+}
@@ -0,0 +1,141 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func gitOriginURL(t *testing.T, path string) string {
+	t.Helper()
+	out, err := exec.Command("git", "-C", path, "remote", "get-url", "origin").Output()
+	if err != nil {
+		t.Fatalf("Failed to get origin in %s: %v", path, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestHandleSetRepoURL(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	oldURL := "https://github.com/old-org/repo"
+	newURL := "https://github.com/new-org/repo"
+
+	repoPath := d.paths.RepoDir("test-repo")
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	for _, args := range [][]string{
+		{"init"},
+		{"remote", "add", "origin", oldURL},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", repoPath}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   oldURL,
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	for name, agentType := range map[string]state.AgentType{
+		"supervisor": state.AgentTypeSupervisor,
+		"worker-1":   state.AgentTypeWorker,
+	} {
+		if err := d.state.AddAgent("test-repo", name, state.Agent{Type: agentType, WorktreePath: repoPath}); err != nil {
+			t.Fatalf("Failed to add agent: %v", err)
+		}
+	}
+	d.repoMoves["test-repo"] = newURL
+
+	// Invalid URLs are rejected before anything changes
+	resp := d.handleSetRepoURL(socket.Request{Args: map[string]interface{}{"name": "test-repo", "github_url": "https://gitlab.com/new-org/repo"}})
+	if resp.Success {
+		t.Error("handleSetRepoURL() should reject a non-GitHub URL")
+	}
+	resp = d.handleSetRepoURL(socket.Request{Args: map[string]interface{}{"name": "missing", "github_url": newURL}})
+	if resp.Success {
+		t.Error("handleSetRepoURL() should fail for an unknown repo")
+	}
+
+	resp = d.handleSetRepoURL(socket.Request{Args: map[string]interface{}{"name": "test-repo", "github_url": newURL + "/"}})
+	if !resp.Success {
+		t.Fatalf("handleSetRepoURL() failed: %s", resp.Error)
+	}
+
+	repo, _ := d.state.GetRepo("test-repo")
+	if repo.GithubURL != newURL {
+		t.Errorf("GithubURL = %q, want %q", repo.GithubURL, newURL)
+	}
+	if got := gitOriginURL(t, repoPath); got != newURL {
+		t.Errorf("origin = %q, want %q", got, newURL)
+	}
+	if len(d.repoMovesSnapshot()) != 0 {
+		t.Error("setting the URL should clear the detected move")
+	}
+
+	// Only persistent agents are notified
+	msgMgr := d.getMessageManager()
+	if msgs, _ := msgMgr.List("test-repo", "supervisor"); len(msgs) != 1 || !strings.Contains(msgs[0].Body, newURL) {
+		t.Errorf("supervisor messages = %v, want one URL change notice", msgs)
+	}
+	if msgs, _ := msgMgr.List("test-repo", "worker-1"); len(msgs) != 0 {
+		t.Errorf("worker should not be notified, got %v", msgs)
+	}
+}
+
+func TestCheckRepoMoves(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	for name, url := range map[string]string{
+		"moved":     "https://github.com/old-org/moved",
+		"unchanged": "https://github.com/org/unchanged",
+		"offline":   "https://github.com/org/offline",
+	} {
+		if err := d.state.AddRepo(name, &state.Repository{GithubURL: url, Agents: make(map[string]state.Agent)}); err != nil {
+			t.Fatalf("Failed to add repo: %v", err)
+		}
+	}
+
+	d.lookupRepoFullName = func(ctx context.Context, owner, name string) (string, error) {
+		switch name {
+		case "moved":
+			return "new-org/moved", nil
+		case "unchanged":
+			return "Org/Unchanged", nil
+		}
+		return "", fmt.Errorf("network unavailable")
+	}
+
+	d.checkRepoMoves()
+
+	moves := d.repoMovesSnapshot()
+	if len(moves) != 1 || moves["moved"] != "https://github.com/new-org/moved" {
+		t.Errorf("repoMoves = %v, want only the moved repo", moves)
+	}
+
+	data := d.handleStatus(socket.Request{Command: "status"}).Data.(map[string]interface{})
+	if reported, _ := data["moved_repos"].(map[string]string); reported["moved"] != "https://github.com/new-org/moved" {
+		t.Errorf("status moved_repos = %v", data["moved_repos"])
+	}
+
+	// A move that GitHub no longer reports is cleared
+	d.lookupRepoFullName = func(ctx context.Context, owner, name string) (string, error) {
+		return owner + "/" + name, nil
+	}
+	d.checkRepoMoves()
+	if len(d.repoMovesSnapshot()) != 0 {
+		t.Errorf("repoMoves = %v, want none", d.repoMovesSnapshot())
+	}
+}
@@ -0,0 +1,121 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/logging"
+	"github.com/dlorenc/multiclaude/pkg/config"
+)
+
+// MaxLogFileSize is the threshold for log rotation (10MB)
+const MaxLogFileSize = 10 * 1024 * 1024
+
+// rotatorInterval is how often log sizes are checked
+const rotatorInterval = 2 * time.Minute
+
+// Rotator rotates agent and daemon logs that grow past MaxLogFileSize
+type Rotator struct {
+	paths  *config.Paths
+	logger *logging.Logger
+}
+
+func (r *Rotator) Name() string { return "log rotator" }
+
+func (r *Rotator) Schedule() LoopSchedule {
+	return LoopSchedule{Every: rotatorInterval, RunAtStart: true}
+}
+
+// Tick checks log files and rotates any that exceed MaxLogFileSize
+func (r *Rotator) Tick(ctx context.Context) error {
+	r.logger.Debug("Checking for log rotation")
+
+	var failures tickErrors
+	err := r.paths.WalkLogFiles(func(path string, info os.FileInfo) error {
+		if !isLogFile(path) {
+			return nil
+		}
+
+		if info.Size() > MaxLogFileSize {
+			if err := rotateLog(path); err != nil {
+				r.logger.Error("Failed to rotate log %s: %v", path, err)
+				failures.add(fmt.Errorf("rotating %s: %w", path, err))
+			} else {
+				r.logger.Info("Rotated log %s (was %d bytes)", path, info.Size())
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		r.logger.Error("Failed to walk output directory for log rotation: %v", err)
+		failures.add(fmt.Errorf("walking log files: %w", err))
+	}
+	return failures.err()
+}
+
+// rotateLog rotates a single log file by renaming it with a timestamp suffix
+func rotateLog(logPath string) error {
+	// Generate rotated filename with timestamp
+	timestamp := time.Now().Format("20060102-150405")
+	rotatedPath := logPath + "." + timestamp
+
+	// Rename the current log file. Renames across filesystems (possible when
+	// the output directory is a symlink onto another disk) fail with EXDEV,
+	// so fall back to copying and truncating.
+	if err := os.Rename(logPath, rotatedPath); err != nil {
+		if !errors.Is(err, syscall.EXDEV) {
+			return fmt.Errorf("failed to rename log: %w", err)
+		}
+		if err := copyTruncateLog(logPath, rotatedPath); err != nil {
+			return err
+		}
+	}
+
+	// The tmux pipe-pane will create a new file automatically when it next writes
+	// No need to recreate the file or restart the pipe
+
+	return nil
+}
+
+// copyTruncateLog rotates a log by copying it to rotatedPath and truncating
+// the original in place
+func copyTruncateLog(logPath, rotatedPath string) error {
+	src, err := os.Open(logPath)
+	if err != nil {
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(rotatedPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create rotated log: %w", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(rotatedPath)
+		return fmt.Errorf("failed to copy log: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(rotatedPath)
+		return fmt.Errorf("failed to copy log: %w", err)
+	}
+
+	if err := os.Truncate(logPath, 0); err != nil {
+		return fmt.Errorf("failed to truncate log: %w", err)
+	}
+	return nil
+}
+
+// isLogFile checks if a file is a log file
+func isLogFile(path string) bool {
+	base := filepath.Base(path)
+	// Only match .log files, not already-rotated files (which have timestamps)
+	return len(base) > 4 && base[len(base)-4:] == ".log"
+}
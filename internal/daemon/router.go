@@ -0,0 +1,98 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/logging"
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// routerInterval is how often pending messages are delivered
+const routerInterval = 2 * time.Minute
+
+// Router delivers pending messages to their recipients through each
+// recipient's transport
+type Router struct {
+	store        repoStore
+	messages     func() *messages.Manager
+	transportFor func(repoName string, repo *state.Repository, agentName string, agent state.Agent) DeliveryTransport
+	logger       *logging.Logger
+}
+
+func (r *Router) Name() string { return "message router" }
+
+func (r *Router) Schedule() LoopSchedule { return LoopSchedule{Every: routerInterval} }
+
+// Tick checks for pending messages and delivers them. Failed deliveries
+// stay pending and are retried on the next tick.
+func (r *Router) Tick(ctx context.Context) error {
+	r.logger.Debug("Routing messages")
+
+	msgMgr := r.messages()
+	now := time.Now()
+	var failures tickErrors
+
+	// Check each repository
+	for repoName, repo := range r.store.GetAllRepos() {
+		// Check each agent for messages
+		for agentName, agent := range repo.Agents {
+			// Skip workspace agent - it should only receive direct user input
+			if agent.Type == state.AgentTypeWorkspace {
+				continue
+			}
+
+			// Get unread messages (pending or delivered but not yet read)
+			unreadMsgs, err := msgMgr.ListUnread(repoName, agentName)
+			if err != nil {
+				r.logger.Error("Failed to list messages for %s/%s: %v", repoName, agentName, err)
+				failures.add(fmt.Errorf("listing messages for %s/%s: %w", repoName, agentName, err))
+				continue
+			}
+
+			transport := r.transportFor(repoName, repo, agentName, agent)
+
+			// Deliver each pending message
+			for _, msg := range unreadMsgs {
+				if msg.Status != messages.StatusPending {
+					// Already delivered, skip
+					continue
+				}
+
+				// A scheduled message that could not be delivered within
+				// ExpiryAge of its delivery time is stale; drop it
+				if msg.IsExpiredSchedule(now) {
+					if err := msgMgr.Delete(repoName, agentName, msg.ID); err != nil {
+						r.logger.Error("Failed to prune expired scheduled message %s: %v", msg.ID, err)
+					} else {
+						r.logger.Info("Pruned scheduled message %s to %s/%s: undelivered since %s", msg.ID, repoName, agentName, msg.ScheduledFor.Format(time.RFC3339))
+					}
+					continue
+				}
+
+				// Hold scheduled messages until their delivery time
+				if msg.IsScheduled(now) {
+					continue
+				}
+
+				if err := transport.Deliver(repoName, agent, *msg); err != nil {
+					r.logger.Error("Failed to deliver message %s to %s/%s via %s: %v", msg.ID, repoName, agentName, transport.Name(), err)
+					failures.add(fmt.Errorf("delivering message %s to %s/%s: %w", msg.ID, repoName, agentName, err))
+					continue
+				}
+
+				// Mark as delivered
+				if err := msgMgr.UpdateStatus(repoName, agentName, msg.ID, messages.StatusDelivered); err != nil {
+					r.logger.Error("Failed to update message %s status: %v", msg.ID, err)
+					failures.add(fmt.Errorf("marking message %s delivered: %w", msg.ID, err))
+					continue
+				}
+
+				r.logger.Info("Delivered message %s from %s to %s/%s via %s", msg.ID, msg.From, repoName, agentName, transport.Name())
+			}
+		}
+	}
+	return failures.err()
+}
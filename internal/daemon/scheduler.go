@@ -0,0 +1,234 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/logging"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// Loop is periodic work the daemon's scheduler runs, such as routing
+// messages or checking agent health
+type Loop interface {
+	// Name identifies the loop in logs and the status response
+	Name() string
+	// Schedule says when the loop runs
+	Schedule() LoopSchedule
+	// Tick does one pass of the loop's work. The error is what went wrong
+	// on this pass, for the status response; the loop runs again on its
+	// next tick either way.
+	Tick(ctx context.Context) error
+}
+
+// LoopSchedule says when a loop runs: every Every, and once Delay after
+// the daemon starts if RunAtStart is set
+type LoopSchedule struct {
+	Every      time.Duration
+	RunAtStart bool
+	Delay      time.Duration
+}
+
+// LoopStatus is what the scheduler knows about a loop's recent runs
+type LoopStatus struct {
+	Name         string    `json:"name"`
+	Every        string    `json:"every"`
+	Runs         int       `json:"runs"`
+	Running      bool      `json:"running"`
+	LastRun      time.Time `json:"last_run,omitempty"`
+	LastDuration string    `json:"last_duration,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+	LastErrorAt  time.Time `json:"last_error_at,omitempty"`
+	Panics       int       `json:"panics,omitempty"`
+}
+
+// repoStore is the part of the daemon state the loops use. They work on the
+// snapshot GetAllRepos returns, so no state lock is held while they run
+// git, gh or tmux, which can take seconds on a slow tmux server.
+type repoStore interface {
+	GetAllRepos() map[string]*state.Repository
+	UpdateAgent(repoName, agentName string, agent state.Agent) error
+}
+
+// scheduler runs each loop in its own goroutine with its own ticker. A
+// panic in a tick is recovered and recorded, and the loop carries on with
+// its next tick. Ticks are skipped while the daemon is paused.
+type scheduler struct {
+	logger *logging.Logger
+	paused func() bool
+
+	mu     sync.Mutex
+	loops  []Loop
+	status map[string]*LoopStatus
+}
+
+// newScheduler creates a scheduler whose loops skip their ticks while
+// paused returns true
+func newScheduler(logger *logging.Logger, paused func() bool) *scheduler {
+	return &scheduler{
+		logger: logger,
+		paused: paused,
+		status: make(map[string]*LoopStatus),
+	}
+}
+
+// Add registers a loop; loops added after Start are not run
+func (s *scheduler) Add(loop Loop) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loops = append(s.loops, loop)
+	s.status[loop.Name()] = &LoopStatus{Name: loop.Name(), Every: loop.Schedule().Every.String()}
+}
+
+// Start runs every loop until ctx is cancelled, tracking them in wg
+func (s *scheduler) Start(ctx context.Context, wg *sync.WaitGroup) {
+	s.mu.Lock()
+	loops := append([]Loop(nil), s.loops...)
+	s.mu.Unlock()
+
+	wg.Add(len(loops))
+	for _, loop := range loops {
+		go func(loop Loop) {
+			defer wg.Done()
+			s.run(ctx, loop)
+		}(loop)
+	}
+}
+
+// run drives one loop on its schedule until ctx is cancelled
+func (s *scheduler) run(ctx context.Context, loop Loop) {
+	name := loop.Name()
+	schedule := loop.Schedule()
+	s.logger.Info("Starting %s loop", name)
+
+	ticker := time.NewTicker(schedule.Every)
+	defer ticker.Stop()
+
+	if schedule.RunAtStart {
+		select {
+		case <-time.After(schedule.Delay):
+			s.tick(ctx, loop)
+		case <-ctx.Done():
+			s.logger.Info("%s loop stopped", name)
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			if s.paused() {
+				continue
+			}
+			s.tick(ctx, loop)
+		case <-ctx.Done():
+			s.logger.Info("%s loop stopped", name)
+			return
+		}
+	}
+}
+
+// tick runs one pass of a loop, recovering from a panic in it, and records
+// how it went
+func (s *scheduler) tick(ctx context.Context, loop Loop) {
+	name := loop.Name()
+	s.update(name, func(st *LoopStatus) { st.Running = true })
+
+	started := time.Now()
+	panicked := false
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				panicked = true
+				err = fmt.Errorf("panic: %v", r)
+				s.logger.Error("%s loop panicked: %v\n%s", name, r, debug.Stack())
+			}
+		}()
+		return loop.Tick(ctx)
+	}()
+	elapsed := time.Since(started)
+
+	if err != nil && !panicked {
+		s.logger.Warn("%s loop: %v", name, err)
+	}
+	s.update(name, func(st *LoopStatus) {
+		st.Running = false
+		st.Runs++
+		st.LastRun = started
+		st.LastDuration = elapsed.Round(time.Millisecond).String()
+		if err != nil {
+			st.LastError = err.Error()
+			st.LastErrorAt = started
+		} else {
+			st.LastError = ""
+		}
+		if panicked {
+			st.Panics++
+		}
+	})
+}
+
+// update changes the recorded status of a loop
+func (s *scheduler) update(name string, change func(*LoopStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.status[name]; ok {
+		change(st)
+	}
+}
+
+// Status returns the recorded status of every loop, in the order they were
+// added
+func (s *scheduler) Status() []LoopStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]LoopStatus, 0, len(s.loops))
+	for _, loop := range s.loops {
+		statuses = append(statuses, *s.status[loop.Name()])
+	}
+	return statuses
+}
+
+// loopFunc adapts a function to Loop, for loops with no state of their own
+type loopFunc struct {
+	name     string
+	schedule LoopSchedule
+	tick     func(ctx context.Context) error
+}
+
+func (l loopFunc) Name() string                   { return l.name }
+func (l loopFunc) Schedule() LoopSchedule         { return l.schedule }
+func (l loopFunc) Tick(ctx context.Context) error { return l.tick(ctx) }
+
+// tickErrors collects the failures of one pass of a loop that carries on
+// past them, into one error for the loop's status
+type tickErrors struct {
+	count int
+	first error
+}
+
+// add records err if it is not nil
+func (e *tickErrors) add(err error) {
+	if err == nil {
+		return
+	}
+	if e.first == nil {
+		e.first = err
+	}
+	e.count++
+}
+
+// err returns the collected failures as one error, or nil if there were none
+func (e *tickErrors) err() error {
+	switch e.count {
+	case 0:
+		return nil
+	case 1:
+		return e.first
+	default:
+		return fmt.Errorf("%w (and %d more failure(s))", e.first, e.count-1)
+	}
+}
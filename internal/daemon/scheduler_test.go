@@ -0,0 +1,193 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// waitFor polls cond until it holds or a few seconds pass
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSchedulerRecoversFromPanics(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	var ticks atomic.Int32
+	s := newScheduler(d.logger, func() bool { return false })
+	s.Add(loopFunc{
+		name:     "flaky",
+		schedule: LoopSchedule{Every: 10 * time.Millisecond, RunAtStart: true},
+		tick: func(context.Context) error {
+			switch ticks.Add(1) {
+			case 1:
+				panic("boom")
+			case 2:
+				return errors.New("still broken")
+			}
+			return nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	s.Start(ctx, &wg)
+
+	waitFor(t, "the loop to carry on after panicking", func() bool { return ticks.Load() >= 2 })
+	var status LoopStatus
+	waitFor(t, "the failed tick to be recorded", func() bool {
+		status = s.Status()[0]
+		return status.LastError == "still broken" || ticks.Load() > 2
+	})
+	if status.Panics != 1 || status.LastErrorAt.IsZero() {
+		t.Errorf("status after a panic and an error = %+v", status)
+	}
+	waitFor(t, "a successful tick to clear the error", func() bool {
+		status = s.Status()[0]
+		return status.Runs >= 3 && status.LastError == ""
+	})
+	if status.Name != "flaky" || status.Every != "10ms" || status.LastRun.IsZero() || status.Panics != 1 {
+		t.Errorf("status = %+v", status)
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestSchedulerSkipsTicksWhilePaused(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	var paused atomic.Bool
+	paused.Store(true)
+	var ticks atomic.Int32
+	s := newScheduler(d.logger, paused.Load)
+	s.Add(loopFunc{
+		name:     "paused",
+		schedule: LoopSchedule{Every: 5 * time.Millisecond},
+		tick:     func(context.Context) error { ticks.Add(1); return nil },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	s.Start(ctx, &wg)
+
+	time.Sleep(50 * time.Millisecond)
+	if n := ticks.Load(); n != 0 {
+		t.Errorf("loop ticked %d times while paused", n)
+	}
+	paused.Store(false)
+	waitFor(t, "the loop to tick once resumed", func() bool { return ticks.Load() > 0 })
+}
+
+// slowSessions is a tmux that takes delay to answer, to stand in for a slow
+// tmux server during a health check
+type slowSessions struct {
+	delay   time.Duration
+	entered chan struct{}
+	once    sync.Once
+}
+
+func (s *slowSessions) HasSession(ctx context.Context, name string) (bool, error) {
+	s.once.Do(func() { close(s.entered) })
+	time.Sleep(s.delay)
+	return true, nil
+}
+
+func (s *slowSessions) ListWindowInfo(ctx context.Context, session string) ([]tmux.WindowInfo, error) {
+	return []tmux.WindowInfo{{ID: "@1", Name: "supervisor"}}, nil
+}
+
+func TestSlowHealthCheckDoesNotStallSocket(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	slow := &slowSessions{delay: 2 * time.Second, entered: make(chan struct{})}
+	d.health.tmux = slow
+	if err := d.Start(); err != nil {
+		t.Fatalf("Failed to start daemon: %v", err)
+	}
+	defer d.Stop()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-slow-health-test",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor", TmuxWindowID: "@1", CreatedAt: time.Now()},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	checked := make(chan struct{})
+	go func() {
+		d.TriggerHealthCheck()
+		close(checked)
+	}()
+	<-slow.entered
+
+	client := socket.NewClient(d.paths.DaemonSock)
+	for _, command := range []string{"ping", "status", "list_repos", "list_agents"} {
+		start := time.Now()
+		resp, err := client.Send(socket.Request{Command: command, Args: map[string]interface{}{"repo": "test-repo"}})
+		if err != nil || !resp.Success {
+			t.Fatalf("%s failed: %v %v", command, err, resp.Error)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("%s took %s during a slow health check", command, elapsed)
+		}
+	}
+
+	// The state is not locked while the health check waits on tmux
+	start := time.Now()
+	if err := d.state.UpdateAgentStatus("test-repo", "supervisor", "busy"); err != nil {
+		t.Fatalf("Failed to update agent: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("updating the state took %s during a slow health check", elapsed)
+	}
+
+	select {
+	case <-checked:
+		t.Error("health check finished early; the slow tmux was not used")
+	default:
+	}
+	<-checked
+}
+
+func TestStatusReportsLoops(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	resp := d.handleStatus(socket.Request{})
+	loops, ok := resp.Data.(map[string]interface{})["loops"].([]LoopStatus)
+	if !ok {
+		t.Fatalf("status has no loops: %+v", resp.Data)
+	}
+	names := map[string]bool{}
+	for _, loop := range loops {
+		names[loop.Name] = true
+	}
+	for _, want := range []string{"health check", "log rotator", "message router", "wake", "worktree refresh", "claude binary", "PR watch", "upgrade check", "upstream sync"} {
+		if !names[want] {
+			t.Errorf("status loops = %+v, missing %s", loops, want)
+		}
+	}
+}
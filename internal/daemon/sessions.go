@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/sessions"
+)
+
+// tagSession records that a tmux session the daemon created belongs to its
+// root, so that daemons on other roots sharing the tmux server leave it
+// alone
+func (d *Daemon) tagSession(session string) {
+	if err := sessions.Tag(d.ctx, d.tmux, session, d.paths.Root); err != nil {
+		d.logger.Warn("Failed to tag tmux session %s with its root: %v", session, err)
+	}
+}
+
+// trackedSessions returns the tmux sessions of the repositories in state
+func (d *Daemon) trackedSessions() map[string]bool {
+	tracked := make(map[string]bool)
+	for _, repo := range d.state.GetAllRepos() {
+		if repo.TmuxSession != "" {
+			tracked[repo.TmuxSession] = true
+		}
+	}
+	return tracked
+}
+
+// surveySessions sorts the sessions on the tmux server by root, adopting
+// untagged sessions of tracked repositories, and remembers the result for
+// the status response. It returns the sessions of other roots, by session.
+func (d *Daemon) surveySessions(ctx context.Context) map[string]string {
+	survey, err := sessions.Take(ctx, d.tmux, d.paths.Root, d.trackedSessions())
+	if err != nil {
+		d.logger.Debug("Failed to survey tmux sessions: %v", err)
+		return nil
+	}
+
+	adopted, err := sessions.Adopt(ctx, d.tmux, d.paths.Root, survey)
+	if err != nil {
+		d.logger.Warn("Failed to adopt tmux sessions: %v", err)
+	}
+	if len(adopted) > 0 {
+		d.logger.Info("Adopted untagged tmux sessions: %s", strings.Join(adopted, ", "))
+	}
+
+	foreign := make(map[string]string)
+	for root, names := range survey.Foreign {
+		for _, name := range names {
+			foreign[name] = root
+		}
+	}
+	d.sessionsMu.Lock()
+	d.foreignSessions = survey.Foreign
+	d.unknownSessions = survey.Unknown
+	d.sessionsMu.Unlock()
+	return foreign
+}
+
+// sessionsSnapshot returns the sessions of other roots and the untagged
+// sessions no root has adopted, as last surveyed
+func (d *Daemon) sessionsSnapshot() map[string]interface{} {
+	d.sessionsMu.Lock()
+	defer d.sessionsMu.Unlock()
+	return map[string]interface{}{
+		"foreign": d.foreignSessions,
+		"unknown": d.unknownSessions,
+	}
+}
@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/sessions"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestHealthCheckLeavesOtherRootsSessionsAlone(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	if !d.tmux.IsTmuxAvailable() {
+		t.Skip("tmux not available, skipping test")
+	}
+
+	ctx := context.Background()
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	legacy := "mc-legacy-" + suffix
+	theirs := "mc-theirs-" + suffix
+	for _, name := range []string{legacy, theirs} {
+		if err := d.tmux.CreateSession(ctx, name, true); err != nil {
+			t.Fatalf("Failed to create session %s: %v", name, err)
+		}
+		defer d.tmux.KillSession(ctx, name)
+	}
+	if err := sessions.Tag(ctx, d.tmux, theirs, "/some/other/root"); err != nil {
+		t.Fatalf("Failed to tag session: %v", err)
+	}
+
+	// The repository's session has the name of another root's session,
+	// whose windows are not its agents'
+	if err := d.state.AddRepo("theirs", &state.Repository{
+		GithubURL:   "https://github.com/test/theirs",
+		TmuxSession: theirs,
+		Agents: map[string]state.Agent{
+			"worker": {Type: state.AgentTypeWorker, TmuxWindow: "worker", CreatedAt: time.Now()},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	if err := d.state.AddRepo("legacy", &state.Repository{
+		GithubURL:   "https://github.com/test/legacy",
+		TmuxSession: legacy,
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	if err := d.health.Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if _, exists := d.state.GetAgent("theirs", "worker"); !exists {
+		t.Error("agent in another root's session was cleaned up")
+	}
+
+	tags, err := d.tmux.ListSessionOption(ctx, sessions.RootOption)
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	if tags[legacy] != d.paths.Root {
+		t.Errorf("untagged session of a tracked repo tagged %q, want it adopted", tags[legacy])
+	}
+	if tags[theirs] != "/some/other/root" {
+		t.Errorf("another root's session retagged %q", tags[theirs])
+	}
+
+	resp := d.handleStatus(socket.Request{})
+	snapshot := resp.Data.(map[string]interface{})["sessions"].(map[string]interface{})
+	foreign, _ := snapshot["foreign"].(map[string][]string)
+	if names := foreign["/some/other/root"]; len(names) != 1 || names[0] != theirs {
+		t.Errorf("status sessions = %+v, want %s reported as foreign", snapshot, theirs)
+	}
+}
@@ -0,0 +1,95 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// docsKey identifies the CLI reference of an agent type in a repository
+type docsKey struct {
+	repo      string
+	agentType state.AgentType
+}
+
+// checkStaleDocs lists the agents whose prompt documents a CLI other than
+// the daemon's binary: started by an older or newer multiclaude, they may
+// run commands or flags that no longer exist. Agents newly found stale are
+// reported to their repository's supervisor, once per reference.
+func (d *Daemon) checkStaleDocs() {
+	if d.currentDocsHash == nil {
+		return
+	}
+
+	// The reference is generated once per repository and agent type
+	current := map[docsKey]string{}
+	notified := map[string]string{}
+	for repoName, repo := range d.state.GetAllRepos() {
+		for agentName, agent := range repo.Agents {
+			// Prompts written without a reference, e.g. by the daemon
+			// itself, have nothing to go stale
+			if agent.DocsHash == "" {
+				continue
+			}
+			key := docsKey{repoName, agent.Type}
+			hash, ok := current[key]
+			if !ok {
+				hash = d.currentDocsHash(repoName, agent.Type)
+				current[key] = hash
+			}
+			if hash != "" && hash != agent.DocsHash {
+				notified[repoName+"/"+agentName] = hash
+			}
+		}
+	}
+
+	stale := make([]string, 0, len(notified))
+	newlyStale := map[string][]string{}
+	d.staleDocsMu.Lock()
+	for name, hash := range notified {
+		stale = append(stale, name)
+		if d.staleDocsNotified[name] != hash {
+			repoName, agentName, _ := strings.Cut(name, "/")
+			newlyStale[repoName] = append(newlyStale[repoName], agentName)
+		}
+	}
+	sort.Strings(stale)
+	d.staleDocs = stale
+	d.staleDocsNotified = notified
+	d.staleDocsMu.Unlock()
+
+	for repoName, agents := range newlyStale {
+		sort.Strings(agents)
+		d.logger.Warn("Agents of %s run with a CLI reference from another multiclaude version: %s", repoName, strings.Join(agents, ", "))
+		d.notifyStaleDocs(repoName, agents)
+	}
+}
+
+// notifyStaleDocs tells a repository's supervisor which agents' prompts
+// document another CLI, and how to refresh them
+func (d *Daemon) notifyStaleDocs(repoName string, agents []string) {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return
+	}
+	if _, hasSupervisor := repo.Agents["supervisor"]; !hasSupervisor {
+		return
+	}
+	notice := fmt.Sprintf("FYI: %d agent(s) were started with a CLI reference from another multiclaude version (%s), "+
+		"so their prompts may mention commands or flags the installed multiclaude no longer has. "+
+		"Refresh an agent's reference and restart it with: multiclaude agent restart <name> --refresh-docs",
+		len(agents), strings.Join(agents, ", "))
+	if _, err := d.getMessageManager().Send(repoName, "daemon", "supervisor", notice); err != nil {
+		d.logger.Warn("Failed to tell the supervisor of %s about stale CLI references: %v", repoName, err)
+	}
+}
+
+// staleDocsSnapshot returns the agents found with a stale CLI reference by
+// the last check, as repo/agent
+func (d *Daemon) staleDocsSnapshot() []string {
+	d.staleDocsMu.Lock()
+	defer d.staleDocsMu.Unlock()
+	return append([]string{}, d.staleDocs...)
+}
@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestCheckStaleDocs(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor", DocsHash: "sup-current", CreatedAt: time.Now()},
+			"fox":        {Type: state.AgentTypeWorker, TmuxWindow: "fox", DocsHash: "worker-old", CreatedAt: time.Now()},
+			"owl":        {Type: state.AgentTypeWorker, TmuxWindow: "owl", CreatedAt: time.Now()},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	// Without the CLI's reference there is nothing to compare with
+	d.checkStaleDocs()
+	if stale := d.staleDocsSnapshot(); len(stale) != 0 {
+		t.Fatalf("stale = %v without a current reference", stale)
+	}
+
+	hashes := map[state.AgentType]string{state.AgentTypeSupervisor: "sup-current", state.AgentTypeWorker: "worker-new"}
+	calls := 0
+	d.currentDocsHash = func(repoName string, agentType state.AgentType) string {
+		calls++
+		return hashes[agentType]
+	}
+	supervisorMessages := func() []string {
+		t.Helper()
+		msgs, err := d.getMessageManager().List("test-repo", "supervisor")
+		if err != nil {
+			t.Fatalf("Failed to list messages: %v", err)
+		}
+		bodies := make([]string, len(msgs))
+		for i, msg := range msgs {
+			bodies[i] = msg.Body
+		}
+		return bodies
+	}
+
+	// owl's prompt has no reference, so only fox is stale
+	d.checkStaleDocs()
+	if stale := d.staleDocsSnapshot(); len(stale) != 1 || stale[0] != "test-repo/fox" {
+		t.Errorf("stale = %v, want [test-repo/fox]", stale)
+	}
+	if calls != 2 {
+		t.Errorf("reference generated %d times, want once per agent type", calls)
+	}
+	msgs := supervisorMessages()
+	if len(msgs) != 1 || !strings.Contains(msgs[0], "fox") || !strings.Contains(msgs[0], "--refresh-docs") {
+		t.Fatalf("supervisor messages = %q, want one naming fox and the refresh", msgs)
+	}
+
+	// It is reported once, and again only for another reference
+	d.checkStaleDocs()
+	if got := len(supervisorMessages()); got != 1 {
+		t.Errorf("supervisor got %d messages after a repeated check, want 1", got)
+	}
+	hashes[state.AgentTypeWorker] = "worker-newer"
+	d.checkStaleDocs()
+	if got := len(supervisorMessages()); got != 2 {
+		t.Errorf("supervisor got %d messages after the reference changed again, want 2", got)
+	}
+
+	// A refreshed agent is no longer stale
+	agent, _ := d.state.GetAgent("test-repo", "fox")
+	agent.DocsHash = "worker-newer"
+	if err := d.state.UpdateAgent("test-repo", "fox", agent); err != nil {
+		t.Fatalf("Failed to update agent: %v", err)
+	}
+	d.checkStaleDocs()
+	if stale := d.staleDocsSnapshot(); len(stale) != 0 {
+		t.Errorf("stale = %v after the refresh", stale)
+	}
+}
+
+func TestAddAgentRecordsDocsHash(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	docs := "# Multiclaude CLI Reference\n"
+	promptFile := d.promptFilePath("fox")
+	if err := os.MkdirAll(filepath.Dir(promptFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(promptFile, []byte("Worker prompt\n\n"+prompts.StampDocs(docs, "v1.0.0")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	add := func(name string, args map[string]interface{}) state.Agent {
+		t.Helper()
+		args["repo"], args["agent"], args["type"], args["worktree_path"], args["tmux_window"] = "test-repo", name, "worker", "/tmp/"+name, name
+		if resp := d.handleAddAgent(socket.Request{Command: "add_agent", Args: args}); !resp.Success {
+			t.Fatalf("add_agent failed: %s", resp.Error)
+		}
+		agent, _ := d.state.GetAgent("test-repo", name)
+		return agent
+	}
+
+	// The hash sent with the registration wins; without one it is read from
+	// the prompt file
+	if agent := add("owl", map[string]interface{}{"docs_hash": "sent"}); agent.DocsHash != "sent" {
+		t.Errorf("DocsHash = %q, want the one sent", agent.DocsHash)
+	}
+	if agent := add("fox", map[string]interface{}{}); agent.DocsHash != prompts.DocsHash(docs) {
+		t.Errorf("DocsHash = %q, want the prompt file's %q", agent.DocsHash, prompts.DocsHash(docs))
+	}
+}
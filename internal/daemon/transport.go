@@ -0,0 +1,193 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+const (
+	// TransportTmux delivers messages by pasting them into the agent's tmux window
+	TransportTmux = "tmux"
+	// TransportInbox appends messages to an inbox file in the agent's worktree
+	TransportInbox = "inbox"
+
+	// DefaultTransport is used when a repository does not configure one
+	DefaultTransport = TransportTmux
+
+	// InboxFileName is the per-agent inbox file written by the inbox transport
+	InboxFileName = ".multiclaude-inbox.md"
+)
+
+// DeliveryTransport delivers a routed message to an agent. Implementations
+// only perform the delivery; the router owns message status transitions.
+type DeliveryTransport interface {
+	// Name identifies the transport in repo config and daemon status
+	Name() string
+
+	// Deliver hands msg to the agent, returning an error if it could not be delivered
+	Deliver(repo string, agent state.Agent, msg messages.Message) error
+}
+
+// RegisterTransport makes a delivery transport available for selection in
+// repository config, replacing any existing transport with the same name
+func (d *Daemon) RegisterTransport(t DeliveryTransport) {
+	d.transportsMu.Lock()
+	defer d.transportsMu.Unlock()
+	d.transports[t.Name()] = t
+}
+
+// transportNames returns the names of all registered transports, sorted
+func (d *Daemon) transportNames() []string {
+	d.transportsMu.RLock()
+	defer d.transportsMu.RUnlock()
+
+	names := make([]string, 0, len(d.transports))
+	for name := range d.transports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getTransport returns the registered transport with the given name
+func (d *Daemon) getTransport(name string) (DeliveryTransport, bool) {
+	d.transportsMu.RLock()
+	defer d.transportsMu.RUnlock()
+	t, ok := d.transports[name]
+	return t, ok
+}
+
+// validateTransportName checks that a configured transport is registered.
+// An empty name is valid and selects the default.
+func (d *Daemon) validateTransportName(name string) (socket.Response, bool) {
+	if name == "" {
+		return socket.Response{}, true
+	}
+	if _, ok := d.getTransport(name); !ok {
+		return socket.Response{Success: false, Error: fmt.Sprintf("unknown message transport: %s (available: %s)", name, strings.Join(d.transportNames(), ", "))}, false
+	}
+	return socket.Response{}, true
+}
+
+// transportFor resolves the transport for an agent from its repository's
+// config. Unknown names fall back to the default so messages are not stranded.
+func (d *Daemon) transportFor(repoName string, repo *state.Repository, agentName string, agent state.Agent) DeliveryTransport {
+	name := repo.MessageTransport.TransportFor(agent.Type)
+	if name == "" {
+		name = DefaultTransport
+	}
+	// Ephemeral agents run in the primary checkout, which the inbox would write to
+	if agent.Type == state.AgentTypeEphemeral && name == TransportInbox {
+		name = TransportTmux
+	}
+
+	if t, ok := d.getTransport(name); ok {
+		return t
+	}
+
+	d.logger.Warn("Unknown message transport %q for %s/%s, falling back to %s", name, repoName, agentName, DefaultTransport)
+	t, _ := d.getTransport(DefaultTransport)
+	return t
+}
+
+// tmuxTransport pastes messages into the agent's tmux window
+type tmuxTransport struct {
+	ctx   context.Context
+	tmux  *tmux.Client
+	state *state.State
+}
+
+func (t *tmuxTransport) Name() string {
+	return TransportTmux
+}
+
+func (t *tmuxTransport) Deliver(repoName string, agent state.Agent, msg messages.Message) error {
+	repo, exists := t.state.GetRepo(repoName)
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	// Send using the atomic method to avoid race conditions where Enter
+	// might be lost between separate exec calls (issue #63)
+	// The footer puts the exact ack command in the agent's context
+	messageText := fmt.Sprintf("📨 Message from %s: %s %s", msg.From, msg.Body, messages.AckFooter(msg.ID))
+	return t.tmux.SendKeysLiteralWithEnter(t.ctx, repo.TmuxSession, agent.WindowTarget(), messageText)
+}
+
+// inboxTransport appends messages to a markdown inbox in the agent's worktree
+// for hook setups that watch a file instead of reading the terminal
+type inboxTransport struct{}
+
+func (t *inboxTransport) Name() string {
+	return TransportInbox
+}
+
+func (t *inboxTransport) Deliver(repoName string, agent state.Agent, msg messages.Message) error {
+	if agent.WorktreePath == "" {
+		return fmt.Errorf("agent has no worktree for inbox delivery")
+	}
+
+	// Keep the inbox out of the agent's commits
+	excludeFromGit(agent.WorktreePath, InboxFileName)
+
+	f, err := os.OpenFile(filepath.Join(agent.WorktreePath, InboxFileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open inbox: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(formatInboxEntry(msg)); err != nil {
+		return fmt.Errorf("failed to write inbox: %w", err)
+	}
+	return nil
+}
+
+// formatInboxEntry renders a message as a markdown section
+func formatInboxEntry(msg messages.Message) string {
+	return fmt.Sprintf("## 📨 Message from %s\n\n_%s · %s_\n\n%s\n\n%s\n\n---\n\n",
+		msg.From, msg.Timestamp.Format("2006-01-02 15:04:05"), msg.ID, strings.TrimSpace(msg.Body), messages.AckFooter(msg.ID))
+}
+
+// excludeFromGit adds pattern to the repository's info/exclude file if it is
+// not already listed. Failures are ignored; the worktree may not be a git repo.
+func excludeFromGit(worktreePath, pattern string) {
+	out, err := exec.Command("git", "-C", worktreePath, "rev-parse", "--path-format=absolute", "--git-path", "info/exclude").Output()
+	if err != nil {
+		return
+	}
+	excludePath := strings.TrimSpace(string(out))
+
+	data, err := os.ReadFile(excludePath)
+	if err != nil && !os.IsNotExist(err) {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == pattern {
+			return
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(excludePath), 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(excludePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		f.WriteString("\n")
+	}
+	f.WriteString(pattern + "\n")
+}
@@ -0,0 +1,310 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// recordingTransport records deliveries and optionally fails them
+type recordingTransport struct {
+	name      string
+	fail      bool
+	delivered []messages.Message
+}
+
+func (t *recordingTransport) Name() string { return t.name }
+
+func (t *recordingTransport) Deliver(repo string, agent state.Agent, msg messages.Message) error {
+	if t.fail {
+		return fmt.Errorf("delivery refused")
+	}
+	t.delivered = append(t.delivered, msg)
+	return nil
+}
+
+func addTransportTestRepo(t *testing.T, d *Daemon, worktreePath string) {
+	t.Helper()
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-transport-test",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	for name, agentType := range map[string]state.AgentType{
+		"supervisor": state.AgentTypeSupervisor,
+		"worker-1":   state.AgentTypeWorker,
+	} {
+		agent := state.Agent{
+			Type:         agentType,
+			WorktreePath: worktreePath,
+			TmuxWindow:   name,
+			CreatedAt:    time.Now(),
+		}
+		if err := d.state.AddAgent("test-repo", name, agent); err != nil {
+			t.Fatalf("Failed to add agent: %v", err)
+		}
+	}
+}
+
+func TestInboxTransportDeliver(t *testing.T) {
+	wtPath := t.TempDir()
+	if err := exec.Command("git", "init", wtPath).Run(); err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+
+	transport := &inboxTransport{}
+	if transport.Name() != TransportInbox {
+		t.Errorf("Name() = %q, want %q", transport.Name(), TransportInbox)
+	}
+
+	agent := state.Agent{Type: state.AgentTypeWorker, WorktreePath: wtPath}
+	for i, body := range []string{"first message", "second message"} {
+		msg := messages.Message{ID: fmt.Sprintf("msg-%d", i), From: "supervisor", Body: body, Timestamp: time.Now()}
+		if err := transport.Deliver("test-repo", agent, msg); err != nil {
+			t.Fatalf("Deliver() failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(wtPath, InboxFileName))
+	if err != nil {
+		t.Fatalf("Failed to read inbox: %v", err)
+	}
+	inbox := string(data)
+	if !strings.Contains(inbox, "Message from supervisor") || !strings.Contains(inbox, "msg-0") {
+		t.Errorf("inbox missing message header: %q", inbox)
+	}
+	if !strings.Contains(inbox, "[ack: multiclaude agent ack-message msg-1]") {
+		t.Errorf("inbox entries should end with the ack command: %q", inbox)
+	}
+	if strings.Index(inbox, "first message") > strings.Index(inbox, "second message") {
+		t.Errorf("inbox should append messages in order: %q", inbox)
+	}
+
+	// The inbox is excluded from git exactly once
+	exclude, err := os.ReadFile(filepath.Join(wtPath, ".git", "info", "exclude"))
+	if err != nil {
+		t.Fatalf("Failed to read exclude file: %v", err)
+	}
+	if n := strings.Count(string(exclude), InboxFileName); n != 1 {
+		t.Errorf("exclude file lists inbox %d times, want 1", n)
+	}
+	status, err := exec.Command("git", "-C", wtPath, "status", "--porcelain").Output()
+	if err != nil {
+		t.Fatalf("git status failed: %v", err)
+	}
+	if strings.Contains(string(status), InboxFileName) {
+		t.Errorf("inbox should be ignored by git, status: %q", status)
+	}
+
+	// Agents without a worktree cannot use the inbox
+	if err := transport.Deliver("test-repo", state.Agent{}, messages.Message{}); err == nil {
+		t.Error("Deliver() should fail without a worktree")
+	}
+}
+
+func TestTmuxTransportDeliver(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Skip("tmux not available")
+	}
+
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addTransportTestRepo(t, d, t.TempDir())
+
+	ctx := context.Background()
+	if err := tmuxClient.CreateSession(ctx, "mc-transport-test", true); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer tmuxClient.KillSession(ctx, "mc-transport-test")
+	if err := tmuxClient.CreateWindow(ctx, "mc-transport-test", "worker-1"); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	transport, ok := d.getTransport(TransportTmux)
+	if !ok {
+		t.Fatal("tmux transport should be registered by default")
+	}
+
+	agent, _ := d.state.GetAgent("test-repo", "worker-1")
+	msg := messages.Message{ID: "msg-1", From: "supervisor", Body: "hello"}
+	if err := transport.Deliver("test-repo", agent, msg); err != nil {
+		t.Errorf("Deliver() failed: %v", err)
+	}
+
+	if err := transport.Deliver("nonexistent", agent, msg); err == nil {
+		t.Error("Deliver() should fail for an unknown repo")
+	}
+}
+
+func TestRouteMessagesUsesConfiguredTransport(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	wtPath := t.TempDir()
+	addTransportTestRepo(t, d, wtPath)
+
+	recorder := &recordingTransport{name: "recorder"}
+	d.RegisterTransport(recorder)
+
+	// Workers use the inbox, everyone else the recorder
+	resp := d.handleUpdateRepoConfig(socket.Request{
+		Command: "update_repo_config",
+		Args: map[string]interface{}{
+			"name":              "test-repo",
+			"message_transport": "recorder",
+			"agent_transports":  map[string]interface{}{"worker": TransportInbox},
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("handleUpdateRepoConfig() failed: %s", resp.Error)
+	}
+
+	msgMgr := d.getMessageManager()
+	workerMsg, err := msgMgr.Send("test-repo", "supervisor", "worker-1", "please rebase")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	supervisorMsg, err := msgMgr.Send("test-repo", "worker-1", "supervisor", "done")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	d.routeMessages()
+
+	inbox, err := os.ReadFile(filepath.Join(wtPath, InboxFileName))
+	if err != nil || !strings.Contains(string(inbox), "please rebase") {
+		t.Errorf("worker message should be in the inbox, got %q (err %v)", inbox, err)
+	}
+	if len(recorder.delivered) != 1 || recorder.delivered[0].ID != supervisorMsg.ID {
+		t.Errorf("recorder deliveries = %v, want the supervisor message", recorder.delivered)
+	}
+
+	// Status transitions are the router's job regardless of transport
+	for agent, id := range map[string]string{"worker-1": workerMsg.ID, "supervisor": supervisorMsg.ID} {
+		msg, err := msgMgr.Get("test-repo", agent, id)
+		if err != nil {
+			t.Fatalf("Failed to get message: %v", err)
+		}
+		if msg.Status != messages.StatusDelivered {
+			t.Errorf("%s message status = %s, want %s", agent, msg.Status, messages.StatusDelivered)
+		}
+	}
+}
+
+func TestRouteMessagesLeavesFailedDeliveriesPending(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addTransportTestRepo(t, d, t.TempDir())
+
+	d.RegisterTransport(&recordingTransport{name: "broken", fail: true})
+	resp := d.handleUpdateRepoConfig(socket.Request{
+		Command: "update_repo_config",
+		Args:    map[string]interface{}{"name": "test-repo", "message_transport": "broken"},
+	})
+	if !resp.Success {
+		t.Fatalf("handleUpdateRepoConfig() failed: %s", resp.Error)
+	}
+
+	msgMgr := d.getMessageManager()
+	msg, err := msgMgr.Send("test-repo", "supervisor", "worker-1", "retry me")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	d.routeMessages()
+
+	got, err := msgMgr.Get("test-repo", "worker-1", msg.ID)
+	if err != nil {
+		t.Fatalf("Failed to get message: %v", err)
+	}
+	if got.Status != messages.StatusPending {
+		t.Errorf("message status = %s, want %s so it is retried", got.Status, messages.StatusPending)
+	}
+}
+
+func TestUpdateRepoConfigTransportValidation(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addTransportTestRepo(t, d, t.TempDir())
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+	}{
+		{"unknown default", map[string]interface{}{"message_transport": "carrier-pigeon"}},
+		{"unknown override", map[string]interface{}{"agent_transports": map[string]interface{}{"worker": "carrier-pigeon"}}},
+		{"invalid agent type", map[string]interface{}{"agent_transports": map[string]interface{}{"daemon": TransportInbox}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args["name"] = "test-repo"
+			resp := d.handleUpdateRepoConfig(socket.Request{Command: "update_repo_config", Args: tt.args})
+			if resp.Success {
+				t.Error("expected update to be rejected")
+			}
+		})
+	}
+
+	// Set then clear an override
+	for _, transport := range []string{TransportInbox, ""} {
+		resp := d.handleUpdateRepoConfig(socket.Request{
+			Command: "update_repo_config",
+			Args: map[string]interface{}{
+				"name":             "test-repo",
+				"agent_transports": map[string]interface{}{"worker": transport},
+			},
+		})
+		if !resp.Success {
+			t.Fatalf("handleUpdateRepoConfig() failed: %s", resp.Error)
+		}
+	}
+	repo, _ := d.state.GetRepo("test-repo")
+	if got := repo.MessageTransport.TransportFor(state.AgentTypeWorker); got != "" {
+		t.Errorf("worker override should be cleared, got %q", got)
+	}
+}
+
+func TestHandleStatusReportsTransports(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addTransportTestRepo(t, d, t.TempDir())
+
+	if err := d.state.UpdateRepoConfig("test-repo", func(r *state.Repository) error {
+		r.SetSetting("transport-worker", TransportInbox)
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateRepoConfig() failed: %v", err)
+	}
+
+	resp := d.handleStatus(socket.Request{Command: "status"})
+	data, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("unexpected status data: %T", resp.Data)
+	}
+
+	names, _ := data["transports"].([]string)
+	if strings.Join(names, ",") != "inbox,tmux" {
+		t.Errorf("transports = %v, want [inbox tmux]", names)
+	}
+
+	repoTransports, _ := data["message_transports"].(map[string]interface{})
+	cfg, _ := repoTransports["test-repo"].(map[string]string)
+	if cfg["default"] != DefaultTransport || cfg["worker"] != TransportInbox {
+		t.Errorf("message_transports[test-repo] = %v", cfg)
+	}
+}
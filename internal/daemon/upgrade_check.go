@@ -0,0 +1,64 @@
+package daemon
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/upgrade"
+)
+
+// Version is the running multiclaude version, which the automatic upgrade
+// check compares releases against. The CLI sets it before starting the
+// daemon.
+var Version = "dev"
+
+// upgradeCheckTick is how often the daemon looks whether an upgrade check
+// is due. The check itself runs only once per configured interval.
+const upgradeCheckTick = time.Minute
+
+// upgradeCheckDue reports whether the last saved check is older than the
+// configured interval. The check is never due when it is turned off.
+func (d *Daemon) upgradeCheckDue(now time.Time) bool {
+	interval := d.state.GetUpgradeCheckInterval()
+	if interval <= 0 {
+		return false
+	}
+	last, err := upgrade.ReadResult(d.paths.UpgradeCheckFile())
+	if err != nil || last == nil {
+		return true
+	}
+	return now.Sub(last.CheckedAt) >= interval
+}
+
+// checkForUpgrade asks GitHub for the latest release and saves the result
+// for the status command. The check is best-effort: a failure is only
+// logged.
+func (d *Daemon) checkForUpgrade() {
+	result, err := upgrade.Check(d.ctx, http.DefaultClient, upgrade.LatestReleaseURL, Version)
+	if err != nil {
+		d.logger.Warn("Upgrade check failed: %v", err)
+		return
+	}
+	if err := upgrade.WriteResult(d.paths.UpgradeCheckFile(), result); err != nil {
+		d.logger.Warn("Failed to save upgrade check: %v", err)
+		return
+	}
+	if result.Available {
+		d.logger.Info("multiclaude %s is available (running %s): %s", result.Latest, result.Current, result.ReleaseURL)
+	}
+}
+
+// handleSetUpgradeCheckInterval sets how often the daemon checks for a
+// newer release; "0" turns the check off
+func (d *Daemon) handleSetUpgradeCheckInterval(req socket.Request) socket.Response {
+	interval, errResp, ok := getRequiredStringArg(req.Args, "interval", "interval is required")
+	if !ok {
+		return errResp
+	}
+	if err := d.state.SetUpgradeCheckInterval(interval); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	d.logger.Info("Upgrade check interval set to %s", interval)
+	return socket.Response{Success: true}
+}
@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/upgrade"
+)
+
+func TestUpgradeCheckDue(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	now := time.Now()
+	if d.upgradeCheckDue(now) {
+		t.Error("upgrade check should not be due when no interval is set")
+	}
+
+	resp := d.dispatchRequest(socket.Request{Command: "set_upgrade_check_interval", Args: map[string]interface{}{"interval": "24h"}})
+	if !resp.Success {
+		t.Fatalf("set_upgrade_check_interval failed: %s", resp.Error)
+	}
+	if !d.upgradeCheckDue(now) {
+		t.Error("upgrade check should be due when it never ran")
+	}
+
+	if err := upgrade.WriteResult(d.paths.UpgradeCheckFile(), &upgrade.Result{Latest: "v1.0.0", CheckedAt: now.Add(-time.Hour)}); err != nil {
+		t.Fatalf("WriteResult() failed: %v", err)
+	}
+	if d.upgradeCheckDue(now) {
+		t.Error("upgrade check should not be due an hour after the last one")
+	}
+	if !d.upgradeCheckDue(now.Add(24 * time.Hour)) {
+		t.Error("upgrade check should be due a day after the last one")
+	}
+
+	resp = d.dispatchRequest(socket.Request{Command: "set_upgrade_check_interval", Args: map[string]interface{}{"interval": "often"}})
+	if resp.Success {
+		t.Error("set_upgrade_check_interval should reject an invalid duration")
+	}
+}
@@ -0,0 +1,158 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// upstreamSyncTick is how often the daemon looks for repositories due an
+// auto-sync
+const upstreamSyncTick = time.Minute
+
+// maxSyncNoticeCommits is how many pulled commits a sync notice lists
+const maxSyncNoticeCommits = 10
+
+// handleSyncRepo syncs a repository's primary clone with upstream (see
+// syncRepo)
+func (d *Daemon) handleSyncRepo(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	result, notified, err := d.syncRepo(repoName, false)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"remote":          result.Remote,
+			"branch":          result.Branch,
+			"old_commit":      result.OldCommit,
+			"new_commit":      result.NewCommit,
+			"commits":         result.Commits,
+			"pruned":          result.Pruned,
+			"agents_notified": notified,
+		},
+	}
+}
+
+// syncRepo fetches a repository's upstream into its primary clone,
+// fast-forwarding the default branch and pruning deleted remote branches.
+// When the branch moved, the sync is recorded in the task history and the
+// persistent agents, which work from the primary clone, are told which
+// commits arrived. It returns the number of agents notified.
+func (d *Daemon) syncRepo(repoName string, auto bool) (*worktree.SyncResult, int, error) {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return nil, 0, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	d.upstreamSyncMu.Lock()
+	d.lastUpstreamSync[repoName] = time.Now()
+	result, err := worktree.NewManager(d.paths.RepoDir(repoName)).SyncDefaultBranch()
+	d.upstreamSyncMu.Unlock()
+	if err != nil {
+		return result, 0, err
+	}
+
+	if len(result.Pruned) > 0 {
+		d.logger.Info("Pruned %d deleted remote branch(es) of %s: %s", len(result.Pruned), repoName, strings.Join(result.Pruned, ", "))
+	}
+	if result.OldCommit == result.NewCommit {
+		return result, 0, nil
+	}
+	commitRange := shortCommit(result.OldCommit) + ".." + shortCommit(result.NewCommit)
+	d.logger.Info("Synced %s %s with %s/%s: %s (%d commit(s))", repoName, result.Branch, result.Remote, result.Branch, commitRange, len(result.Commits))
+
+	name, task := "sync", "Synced "+result.Branch
+	if auto {
+		name, task = "auto-sync", "Auto-synced "+result.Branch
+	}
+	now := time.Now()
+	entry := state.TaskHistoryEntry{
+		Name:        name,
+		Task:        fmt.Sprintf("%s %s with %s (%d commit(s))", task, commitRange, result.Remote, len(result.Commits)),
+		Branch:      result.Branch,
+		Status:      state.TaskStatusSynced,
+		Summary:     strings.Join(result.Commits, "\n"),
+		CreatedAt:   now,
+		CompletedAt: now,
+	}
+	if err := d.state.AddTaskHistory(repoName, entry); err != nil {
+		d.logger.Warn("Failed to record sync of %s in task history: %v", repoName, err)
+	}
+
+	notice := syncNotice(result, commitRange)
+	msgMgr := d.getMessageManager()
+	notified := 0
+	for agentName, agent := range repo.Agents {
+		if !isPersistentAgentType(agent.Type) {
+			continue
+		}
+		if _, err := msgMgr.Send(repoName, "daemon", agentName, notice); err != nil {
+			d.logger.Warn("Failed to notify %s/%s of upstream sync: %v", repoName, agentName, err)
+			continue
+		}
+		notified++
+	}
+	return result, notified, nil
+}
+
+// syncNotice tells agents which commits a sync pulled
+func syncNotice(result *worktree.SyncResult, commitRange string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Upstream moved: %s was fast-forwarded to %s/%s (%s, %d commit(s)). "+
+		"New workers start from it; rebase or merge if your work depends on it.\n",
+		result.Branch, result.Remote, result.Branch, commitRange, len(result.Commits))
+	for i, commit := range result.Commits {
+		if i == maxSyncNoticeCommits {
+			fmt.Fprintf(&b, "... and %d more\n", len(result.Commits)-i)
+			break
+		}
+		fmt.Fprintf(&b, "- %s\n", commit)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// shortCommit abbreviates a commit hash for messages
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
+
+// autoSyncRepos syncs the repositories whose auto-sync interval has passed.
+// A repository whose remote cannot be fetched, e.g. while offline, is
+// skipped until its next interval.
+func (d *Daemon) autoSyncRepos(now time.Time) {
+	for repoName, repo := range d.state.GetAllRepos() {
+		interval := repo.AutoSyncInterval()
+		if interval == 0 {
+			continue
+		}
+		d.upstreamSyncMu.Lock()
+		last := d.lastUpstreamSync[repoName]
+		d.upstreamSyncMu.Unlock()
+		if now.Sub(last) < interval {
+			continue
+		}
+
+		if _, _, err := d.syncRepo(repoName, true); err != nil {
+			if errors.Is(err, worktree.ErrFetchFailed) {
+				d.logger.Info("Skipping auto-sync of %s, its remote is unreachable", repoName)
+				d.logger.Debug("Auto-sync fetch of %s failed: %v", repoName, err)
+				continue
+			}
+			d.logger.Warn("Auto-sync of %s failed: %v", repoName, err)
+		}
+	}
+}
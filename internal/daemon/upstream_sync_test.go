@@ -0,0 +1,146 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// runTestGit runs git with a test identity, failing the test on error
+func runTestGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.email=test@example.com", "-c", "user.name=Test"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+// setupSyncRepo creates an upstream repository and clones it as the
+// primary clone of test-repo, returning the upstream's path
+func setupSyncRepo(t *testing.T, d *Daemon) string {
+	t.Helper()
+	upstream := filepath.Join(t.TempDir(), "upstream")
+	if err := os.MkdirAll(upstream, 0755); err != nil {
+		t.Fatalf("Failed to create upstream: %v", err)
+	}
+	runTestGit(t, upstream, "init", "-q", "-b", "main")
+	runTestGit(t, upstream, "commit", "-q", "--allow-empty", "-m", "Initial commit")
+	runTestGit(t, upstream, "branch", "old-feature")
+	runTestGit(t, upstream, "clone", "-q", upstream, d.paths.RepoDir("test-repo"))
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor},
+			"worker-1":   {Type: state.AgentTypeWorker},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	return upstream
+}
+
+func TestHandleSyncRepo(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	upstream := setupSyncRepo(t, d)
+
+	runTestGit(t, upstream, "commit", "-q", "--allow-empty", "-m", "Fix the widget")
+	runTestGit(t, upstream, "branch", "-D", "old-feature")
+
+	resp := d.dispatchRequest(socket.Request{Command: "sync_repo", Args: map[string]interface{}{"name": "test-repo"}})
+	if !resp.Success {
+		t.Fatalf("sync_repo failed: %s", resp.Error)
+	}
+	data := resp.Data.(map[string]interface{})
+	if commits, _ := data["commits"].([]string); len(commits) != 1 || !strings.Contains(commits[0], "Fix the widget") {
+		t.Errorf("commits = %v, want the one upstream commit", data["commits"])
+	}
+	if pruned, _ := data["pruned"].([]string); len(pruned) != 1 || pruned[0] != "old-feature" {
+		t.Errorf("pruned = %v, want [old-feature]", data["pruned"])
+	}
+	if data["agents_notified"] != 1 {
+		t.Errorf("agents_notified = %v, want 1", data["agents_notified"])
+	}
+
+	msgMgr := d.getMessageManager()
+	if msgs, _ := msgMgr.List("test-repo", "supervisor"); len(msgs) != 1 || !strings.Contains(msgs[0].Body, "Fix the widget") {
+		t.Errorf("supervisor messages = %v, want one sync notice", msgs)
+	}
+	if msgs, _ := msgMgr.List("test-repo", "worker-1"); len(msgs) != 0 {
+		t.Errorf("worker should not be notified, got %v", msgs)
+	}
+
+	history, _ := d.state.GetTaskHistory("test-repo", 10)
+	if len(history) != 1 || history[0].Name != "sync" || history[0].Status != state.TaskStatusSynced {
+		t.Errorf("task history = %+v, want one sync entry", history)
+	}
+
+	// Nothing new upstream: nothing recorded or sent
+	resp = d.dispatchRequest(socket.Request{Command: "sync_repo", Args: map[string]interface{}{"name": "test-repo"}})
+	if !resp.Success {
+		t.Fatalf("second sync_repo failed: %s", resp.Error)
+	}
+	if history, _ := d.state.GetTaskHistory("test-repo", 10); len(history) != 1 {
+		t.Errorf("a sync that pulled nothing should not be recorded, got %+v", history)
+	}
+
+	resp = d.dispatchRequest(socket.Request{Command: "sync_repo", Args: map[string]interface{}{"name": "missing"}})
+	if resp.Success {
+		t.Error("sync_repo should fail for an unknown repository")
+	}
+}
+
+func TestAutoSyncRepos(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	upstream := setupSyncRepo(t, d)
+
+	// Repositories without an interval are never synced
+	runTestGit(t, upstream, "commit", "-q", "--allow-empty", "-m", "Upstream change")
+	now := time.Now()
+	d.autoSyncRepos(now)
+	if history, _ := d.state.GetTaskHistory("test-repo", 10); len(history) != 0 {
+		t.Fatalf("repository without auto-sync was synced: %+v", history)
+	}
+
+	if err := d.state.UpdateRepoConfig("test-repo", func(r *state.Repository) error {
+		r.SetSetting("auto-sync", "1h0m0s")
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateRepoConfig() failed: %v", err)
+	}
+	d.autoSyncRepos(now)
+	history, _ := d.state.GetTaskHistory("test-repo", 10)
+	if len(history) != 1 || history[0].Name != "auto-sync" {
+		t.Fatalf("task history = %+v, want one auto-sync entry", history)
+	}
+
+	// Not again until the interval has passed
+	runTestGit(t, upstream, "commit", "-q", "--allow-empty", "-m", "Another change")
+	d.autoSyncRepos(now.Add(time.Minute))
+	if history, _ := d.state.GetTaskHistory("test-repo", 10); len(history) != 1 {
+		t.Errorf("auto-sync ran before its interval: %+v", history)
+	}
+	d.autoSyncRepos(now.Add(2 * time.Hour))
+	if history, _ := d.state.GetTaskHistory("test-repo", 10); len(history) != 2 {
+		t.Errorf("auto-sync did not run after its interval: %+v", history)
+	}
+
+	// An unreachable remote is skipped without recording anything
+	if err := os.RemoveAll(upstream); err != nil {
+		t.Fatalf("Failed to remove upstream: %v", err)
+	}
+	d.autoSyncRepos(now.Add(4 * time.Hour))
+	if history, _ := d.state.GetTaskHistory("test-repo", 10); len(history) != 2 {
+		t.Errorf("an unreachable remote should be skipped: %+v", history)
+	}
+}
@@ -0,0 +1,195 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/githuburl"
+	"github.com/dlorenc/multiclaude/internal/prcomments"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// wakeCycle caches what the agents of one wake cycle share, so that each
+// PR and repository is looked up at most once per cycle
+type wakeCycle struct {
+	now        time.Time
+	prComments map[string]int         // by PR URL; -1 when the lookup failed
+	mainRefs   map[string]wakeMainRef // by repository name
+}
+
+// wakeMainRef is the remote and default branch of a repository; ok is false
+// when they could not be determined
+type wakeMainRef struct {
+	remote string
+	branch string
+	ok     bool
+}
+
+func newWakeCycle(now time.Time) *wakeCycle {
+	return &wakeCycle{
+		now:        now,
+		prComments: make(map[string]int),
+		mainRefs:   make(map[string]wakeMainRef),
+	}
+}
+
+// nudgeDeltas describes what changed for an agent since the wake loop last
+// looked: new unread messages, new comments on its PR and its branch falling
+// further behind main. It records the current counts in agent and reports
+// whether any of them changed, including counts that went down, so that a
+// later increase is noticed.
+func (d *Daemon) nudgeDeltas(repoName string, repo *state.Repository, agentName string, agent *state.Agent, cycle *wakeCycle) (deltas []string, changed bool) {
+	// An agent that cannot be looked at keeps its counts, so the news is
+	// reported once the lookup works again
+	if unread, senders, ok := d.unreadMessages(repoName, agentName, agent.LastSeenMessages, cycle.now); ok {
+		if unread > agent.LastSeenMessages {
+			deltas = append(deltas, fmt.Sprintf("%s from %s (read with 'multiclaude agent list-messages')",
+				plural(unread-agent.LastSeenMessages, "new message"), strings.Join(senders, ", ")))
+		}
+		changed = changed || unread != agent.LastSeenMessages
+		agent.LastSeenMessages = unread
+	}
+
+	if agent.Type != state.AgentTypeWorker {
+		return deltas, changed
+	}
+
+	if agent.PRNumber > 0 {
+		if comments, ok := d.prCommentCount(repoName, repo, agent, cycle); ok {
+			if comments > agent.LastSeenReviewComments {
+				deltas = append(deltas, fmt.Sprintf("%s on your PR %s (read with 'multiclaude agent pr-comments')",
+					plural(comments-agent.LastSeenReviewComments, "new review comment"), agent.PRURL))
+			}
+			changed = changed || comments != agent.LastSeenReviewComments
+			agent.LastSeenReviewComments = comments
+		}
+	}
+
+	if behind, mainBranch, ok := d.commitsBehind(repoName, agentName, agent, cycle); ok {
+		if behind > agent.LastSeenBehind {
+			deltas = append(deltas, fmt.Sprintf("your branch is now %s behind %s", plural(behind, "commit"), mainBranch))
+		}
+		changed = changed || behind != agent.LastSeenBehind
+		agent.LastSeenBehind = behind
+	}
+
+	return deltas, changed
+}
+
+// unreadMessages counts an agent's unread messages that are due, and names
+// the senders of those beyond the first seen, newest last
+func (d *Daemon) unreadMessages(repoName, agentName string, seen int, now time.Time) (int, []string, bool) {
+	unread, err := d.getMessageManager().ListUnread(repoName, agentName)
+	if err != nil {
+		d.logger.Debug("Could not list messages of %s/%s: %v", repoName, agentName, err)
+		return 0, nil, false
+	}
+
+	due := unread[:0]
+	for _, msg := range unread {
+		if !msg.IsScheduled(now) {
+			due = append(due, msg)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].DueAt().Before(due[j].DueAt()) })
+
+	var senders []string
+	if seen < len(due) {
+		known := make(map[string]bool)
+		for _, msg := range due[seen:] {
+			if !known[msg.From] {
+				known[msg.From] = true
+				senders = append(senders, msg.From)
+			}
+		}
+	}
+	return len(due), senders, true
+}
+
+// prCommentCount returns the number of comments and reviews on a worker's
+// PR, looking each PR up once per cycle
+func (d *Daemon) prCommentCount(repoName string, repo *state.Repository, agent *state.Agent, cycle *wakeCycle) (int, bool) {
+	if d.ghDisabled(repoName, ghFeatureWakeDeltas) {
+		return 0, false
+	}
+	key := agent.PRURL
+	if key == "" {
+		key = fmt.Sprintf("%s#%d", repo.GithubURL, agent.PRNumber)
+	}
+	if count, cached := cycle.prComments[key]; cached {
+		return count, count >= 0
+	}
+
+	cycle.prComments[key] = -1
+	owner, name, err := githuburl.Parse(repo.GithubURL)
+	if err != nil {
+		return 0, false
+	}
+	ctx, cancel := context.WithTimeout(d.ctx, 30*time.Second)
+	defer cancel()
+	count, err := d.lookupPRComments(ctx, owner, name, agent.PRNumber)
+	if d.ghFailed(repoName, ghFeatureWakeDeltas, err) {
+		return 0, false
+	}
+	if err != nil {
+		d.logger.Debug("Could not look up comments on PR #%d: %v", agent.PRNumber, err)
+		return 0, false
+	}
+	cycle.prComments[key] = count
+	return count, true
+}
+
+// commitsBehind returns how far a worker's branch is behind the
+// repository's default branch, as of the last fetch
+func (d *Daemon) commitsBehind(repoName, agentName string, agent *state.Agent, cycle *wakeCycle) (int, string, bool) {
+	if agent.WorktreePath == "" {
+		return 0, "", false
+	}
+	if _, err := os.Stat(agent.WorktreePath); err != nil {
+		return 0, "", false
+	}
+
+	ref, cached := cycle.mainRefs[repoName]
+	if !cached {
+		wt := worktree.NewManager(d.paths.RepoDir(repoName))
+		if remote, err := wt.GetUpstreamRemote(); err == nil {
+			if branch, err := wt.GetDefaultBranch(remote); err == nil {
+				ref = wakeMainRef{remote: remote, branch: branch, ok: true}
+			}
+		}
+		cycle.mainRefs[repoName] = ref
+	}
+	if !ref.ok {
+		return 0, "", false
+	}
+
+	wtState, err := worktree.GetWorktreeState(agent.WorktreePath, ref.remote, ref.branch)
+	if err != nil {
+		d.logger.Debug("Could not get worktree state for %s/%s: %v", repoName, agentName, err)
+		return 0, "", false
+	}
+	return wtState.CommitsBehind, ref.branch, true
+}
+
+// ghPRCommentCount returns the number of comments and reviews GitHub has
+// for a PR, counted as agent pr-comments counts them
+func ghPRCommentCount(ctx context.Context, owner, name string, number int) (int, error) {
+	feedback, err := prcomments.Fetch(ctx, owner, name, number)
+	if err != nil {
+		return 0, err
+	}
+	return feedback.Count(), nil
+}
+
+// plural formats a count with a noun, adding an s unless the count is one
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
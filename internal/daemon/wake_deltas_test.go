@@ -0,0 +1,211 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+func TestWakeLoopSkipsAgentsWithoutDeltas(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Skip("tmux not available")
+	}
+
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	sessionName := "mc-test-wake-deltas"
+	if err := tmuxClient.CreateSession(context.Background(), sessionName, true); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(context.Background(), sessionName)
+	for _, window := range []string{"supervisor", "merge-queue"} {
+		if err := tmuxClient.CreateWindow(context.Background(), sessionName, window); err != nil {
+			t.Fatalf("Failed to create %s window: %v", window, err)
+		}
+	}
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: sessionName,
+		Agents: map[string]state.Agent{
+			"supervisor":  {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor", CreatedAt: time.Now()},
+			"merge-queue": {Type: state.AgentTypeMergeQueue, TmuxWindow: "merge-queue", CreatedAt: time.Now()},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	// Only the supervisor has news
+	if _, err := d.getMessageManager().Send("test-repo", "merge-queue", "supervisor", "PR #3 is green"); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	d.TriggerWake()
+
+	supervisor, _ := d.state.GetAgent("test-repo", "supervisor")
+	if supervisor.LastNudge.IsZero() {
+		t.Error("supervisor has a new message and should be nudged")
+	}
+	if supervisor.LastSeenMessages != 1 {
+		t.Errorf("supervisor LastSeenMessages = %d, want 1", supervisor.LastSeenMessages)
+	}
+	mergeQueue, _ := d.state.GetAgent("test-repo", "merge-queue")
+	if !mergeQueue.LastNudge.IsZero() {
+		t.Error("merge-queue has no news and should not be nudged")
+	}
+
+	// Once the nudge is no longer recent, the same unread message is not news
+	supervisor.LastNudge = time.Now().Add(-10 * time.Minute)
+	if err := d.state.UpdateAgent("test-repo", "supervisor", supervisor); err != nil {
+		t.Fatalf("Failed to update agent: %v", err)
+	}
+	d.TriggerWake()
+
+	again, _ := d.state.GetAgent("test-repo", "supervisor")
+	if !again.LastNudge.Equal(supervisor.LastNudge) {
+		t.Error("supervisor should not be nudged again for a message it was already told about")
+	}
+}
+
+func TestNudgeDeltasMessages(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repo := &state.Repository{GithubURL: "https://github.com/test/repo", Agents: map[string]state.Agent{}}
+	msgMgr := d.getMessageManager()
+	for _, from := range []string{"supervisor", "merge-queue", "supervisor"} {
+		if _, err := msgMgr.Send("test-repo", from, "worker1", "hello"); err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+	}
+	// A message scheduled for later is not news yet
+	later := time.Now().Add(time.Hour)
+	if _, err := msgMgr.Schedule("test-repo", "supervisor", "worker1", "later", &later); err != nil {
+		t.Fatalf("Failed to schedule message: %v", err)
+	}
+
+	agent := state.Agent{Type: state.AgentTypeWorker, LastSeenMessages: 1}
+	deltas, changed := d.nudgeDeltas("test-repo", repo, "worker1", &agent, newWakeCycle(time.Now()))
+	if !changed || agent.LastSeenMessages != 3 {
+		t.Errorf("changed = %v, LastSeenMessages = %d, want true and 3", changed, agent.LastSeenMessages)
+	}
+	if len(deltas) != 1 || !strings.HasPrefix(deltas[0], "2 new messages from ") {
+		t.Fatalf("deltas = %q, want 2 new messages", deltas)
+	}
+
+	// Fewer unread messages are not news, but are recorded
+	messages, _ := msgMgr.ListUnread("test-repo", "worker1")
+	for _, msg := range messages {
+		if !msg.IsScheduled(time.Now()) {
+			msgMgr.Ack("test-repo", "worker1", msg.ID)
+		}
+	}
+	deltas, changed = d.nudgeDeltas("test-repo", repo, "worker1", &agent, newWakeCycle(time.Now()))
+	if len(deltas) != 0 || !changed || agent.LastSeenMessages != 0 {
+		t.Errorf("deltas = %q, changed = %v, LastSeenMessages = %d, want none, true and 0", deltas, changed, agent.LastSeenMessages)
+	}
+}
+
+func TestNudgeDeltasReviewComments(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	lookups := 0
+	comments := 3
+	d.lookupPRComments = func(ctx context.Context, owner, name string, number int) (int, error) {
+		lookups++
+		if number != 7 {
+			return 0, fmt.Errorf("gh unavailable")
+		}
+		return comments, nil
+	}
+
+	repo := &state.Repository{GithubURL: "https://github.com/test/repo", Agents: map[string]state.Agent{}}
+	worker := state.Agent{Type: state.AgentTypeWorker, PRURL: "https://github.com/test/repo/pull/7", PRNumber: 7, LastSeenReviewComments: 1}
+	other := worker
+
+	// Workers sharing a PR cause one lookup per cycle
+	cycle := newWakeCycle(time.Now())
+	deltas, _ := d.nudgeDeltas("test-repo", repo, "worker1", &worker, cycle)
+	if len(deltas) != 1 || !strings.HasPrefix(deltas[0], "2 new review comments on your PR") {
+		t.Errorf("deltas = %q, want 2 new review comments", deltas)
+	}
+	d.nudgeDeltas("test-repo", repo, "worker2", &other, cycle)
+	if lookups != 1 {
+		t.Errorf("lookups = %d, want 1 per cycle", lookups)
+	}
+
+	// No new comments, no news
+	deltas, changed := d.nudgeDeltas("test-repo", repo, "worker1", &worker, newWakeCycle(time.Now()))
+	if len(deltas) != 0 || changed {
+		t.Errorf("deltas = %q, changed = %v, want none", deltas, changed)
+	}
+
+	// A failed lookup keeps the count, so the comments are reported later
+	broken := state.Agent{Type: state.AgentTypeWorker, PRNumber: 8, LastSeenReviewComments: 2}
+	deltas, changed = d.nudgeDeltas("test-repo", repo, "worker3", &broken, newWakeCycle(time.Now()))
+	if len(deltas) != 0 || changed || broken.LastSeenReviewComments != 2 {
+		t.Errorf("deltas = %q, changed = %v, LastSeenReviewComments = %d after a failed lookup", deltas, changed, broken.LastSeenReviewComments)
+	}
+}
+
+func TestMarkPRCommentsReadQuietsNudges(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	d.lookupPRComments = func(ctx context.Context, owner, name string, number int) (int, error) {
+		return 5, nil
+	}
+	repo := &state.Repository{GithubURL: "https://github.com/test/repo", Agents: map[string]state.Agent{}}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	if err := d.state.AddAgent("test-repo", "worker", state.Agent{Type: state.AgentTypeWorker, PRURL: "https://github.com/test/repo/pull/7", PRNumber: 7}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+
+	// The worker reads all five comments with agent pr-comments
+	readAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	resp := d.handleMarkPRCommentsRead(socket.Request{
+		Command: "mark_pr_comments_read",
+		Args:    map[string]interface{}{"repo": "test-repo", "agent": "worker", "read_at": readAt.Format(time.RFC3339Nano), "count": float64(5)},
+	})
+	if !resp.Success {
+		t.Fatalf("handleMarkPRCommentsRead() failed: %s", resp.Error)
+	}
+
+	worker, _ := d.state.GetAgent("test-repo", "worker")
+	if !worker.PRCommentsReadAt.Equal(readAt) {
+		t.Errorf("PRCommentsReadAt = %v, want %v", worker.PRCommentsReadAt, readAt)
+	}
+	if deltas, _ := d.nudgeDeltas("test-repo", repo, "worker", &worker, newWakeCycle(time.Now())); len(deltas) != 0 {
+		t.Errorf("deltas = %q, want no nudge about comments already read", deltas)
+	}
+
+	resp = d.handleMarkPRCommentsRead(socket.Request{
+		Command: "mark_pr_comments_read",
+		Args:    map[string]interface{}{"repo": "test-repo", "agent": "worker", "read_at": "yesterday"},
+	})
+	if resp.Success {
+		t.Error("Expected failure with an invalid read_at")
+	}
+}
+
+func TestNudgeMessage(t *testing.T) {
+	got := nudgeMessage(state.AgentTypeWorker, []string{"1 new message from supervisor", "your branch is now 2 commits behind main"})
+	want := "Status check: 1 new message from supervisor; your branch is now 2 commits behind main."
+	if got != want {
+		t.Errorf("nudgeMessage() = %q, want %q", got, want)
+	}
+	if got := nudgeMessage(state.AgentTypeSupervisor, nil); got != "Status check: Review worker progress and check merge queue." {
+		t.Errorf("idle supervisor nudge = %q", got)
+	}
+}
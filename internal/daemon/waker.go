@@ -0,0 +1,108 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/logging"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// wakeInterval is how often agents are nudged, and how long an agent is
+// left alone after a nudge
+const wakeInterval = 2 * time.Minute
+
+// keySender types text into an agent's tmux window
+type keySender interface {
+	SendKeysLiteralWithEnter(ctx context.Context, session, windowName, text string) error
+}
+
+// Waker nudges agents with what changed for them since they were last
+// looked at
+type Waker struct {
+	store  repoStore
+	tmux   keySender
+	deltas func(repoName string, repo *state.Repository, agentName string, agent *state.Agent, cycle *wakeCycle) ([]string, bool)
+	logger *logging.Logger
+}
+
+func (w *Waker) Name() string { return "wake" }
+
+func (w *Waker) Schedule() LoopSchedule { return LoopSchedule{Every: wakeInterval} }
+
+// Tick nudges agents with what changed for them: new messages, new comments
+// on their PR and their branch falling behind main. Agents with no news are
+// left alone unless the repository sets NudgeWhenIdle.
+func (w *Waker) Tick(ctx context.Context) error {
+	w.logger.Debug("Waking agents")
+
+	now := time.Now()
+	cycle := newWakeCycle(now)
+	var failures tickErrors
+
+	for repoName, repo := range w.store.GetAllRepos() {
+		for agentName, agent := range repo.Agents {
+			// Skip workspace agent - it should only receive direct user input
+			if agent.Type == state.AgentTypeWorkspace {
+				continue
+			}
+
+			// Skip if nudged recently
+			if !agent.LastNudge.IsZero() && now.Sub(agent.LastNudge) < wakeInterval {
+				continue
+			}
+
+			deltas, changed := w.deltas(repoName, repo, agentName, &agent, cycle)
+			if len(deltas) == 0 && !repo.NudgeWhenIdle {
+				// Record counts that went down, e.g. messages read, so
+				// that the next increase is noticed
+				if changed {
+					if err := w.store.UpdateAgent(repoName, agentName, agent); err != nil {
+						w.logger.Error("Failed to update agent %s: %v", agentName, err)
+						failures.add(fmt.Errorf("updating agent %s/%s: %w", repoName, agentName, err))
+					}
+				}
+				continue
+			}
+
+			// Send message using atomic method to avoid race conditions (issue #63)
+			message := nudgeMessage(agent.Type, deltas)
+			if err := w.tmux.SendKeysLiteralWithEnter(ctx, repo.TmuxSession, agent.WindowTarget(), message); err != nil {
+				w.logger.Error("Failed to send wake message to agent %s: %v", agentName, err)
+				failures.add(fmt.Errorf("waking agent %s/%s: %w", repoName, agentName, err))
+				continue
+			}
+
+			// Update last nudge time
+			agent.LastNudge = now
+			if err := w.store.UpdateAgent(repoName, agentName, agent); err != nil {
+				w.logger.Error("Failed to update agent %s last nudge: %v", agentName, err)
+				failures.add(fmt.Errorf("updating agent %s/%s: %w", repoName, agentName, err))
+			}
+
+			w.logger.Debug("Woke agent %s in repo %s", agentName, repoName)
+		}
+	}
+	return failures.err()
+}
+
+// nudgeMessage is the wake message for an agent: what changed for it, or a
+// generic status check for its type when nothing did
+func nudgeMessage(agentType state.AgentType, deltas []string) string {
+	if len(deltas) > 0 {
+		return "Status check: " + strings.Join(deltas, "; ") + "."
+	}
+
+	switch agentType {
+	case state.AgentTypeSupervisor:
+		return "Status check: Review worker progress and check merge queue."
+	case state.AgentTypeMergeQueue:
+		return "Status check: Review open PRs and check CI status."
+	case state.AgentTypeReview:
+		return "Status check: Update on your review progress?"
+	default:
+		return "Status check: Update on your progress?"
+	}
+}
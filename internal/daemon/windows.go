@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// windowID returns the ID of the named window in session, or "" if it
+// cannot be looked up; the health check resolves a missing ID later
+func (d *Daemon) windowID(session, window string) string {
+	id, err := d.tmux.GetWindowID(d.ctx, session, window)
+	if err != nil {
+		d.logger.Warn("Failed to look up the ID of tmux window %s:%s: %v", session, window, err)
+		return ""
+	}
+	return id
+}
+
+// reconcileAgentWindow finds the agent's window among the session's windows
+// and repairs the recorded mapping, returning the agent as updated and
+// whether its window exists.
+//
+// The window is found by ID. Agents recorded before IDs were kept, or whose
+// window was recreated (e.g. when a lost session was restored), are found by
+// name instead and their ID recorded. A window found by ID under another
+// name was renamed by hand or by tmux's automatic-rename: it is renamed back
+// to the agent's name so attaching by name keeps working, and if that fails
+// the new name is recorded instead.
+func (d *Daemon) reconcileAgentWindow(repoName, agentName, session string, agent state.Agent, windows []tmux.WindowInfo) (state.Agent, bool) {
+	var found *tmux.WindowInfo
+	for i := range windows {
+		if agent.TmuxWindowID != "" && windows[i].ID == agent.TmuxWindowID {
+			found = &windows[i]
+			break
+		}
+	}
+	if found == nil {
+		for i := range windows {
+			if windows[i].Name == agent.TmuxWindow {
+				found = &windows[i]
+				break
+			}
+		}
+		if found == nil {
+			return agent, false
+		}
+		d.logger.Info("Recording tmux window ID %s for agent %s", found.ID, agentName)
+		agent.TmuxWindowID = found.ID
+		if err := d.state.UpdateAgentWindow(repoName, agentName, agent.TmuxWindow, agent.TmuxWindowID); err != nil {
+			d.logger.Warn("Failed to record tmux window ID for agent %s: %v", agentName, err)
+		}
+		return agent, true
+	}
+
+	if found.Name == agent.TmuxWindow {
+		return agent, true
+	}
+	d.logger.Warn("Agent %s window %s was renamed to %q", agentName, agent.TmuxWindow, found.Name)
+	err := d.tmux.RenameWindow(d.ctx, session, found.ID, agent.TmuxWindow)
+	if err == nil {
+		d.logger.Info("Renamed window %s back to %s", found.ID, agent.TmuxWindow)
+		return agent, true
+	}
+	d.logger.Warn("Failed to rename window %s back to %s, recording its new name: %v", found.ID, agent.TmuxWindow, err)
+	agent.TmuxWindow = found.Name
+	if err := d.state.UpdateAgentWindow(repoName, agentName, agent.TmuxWindow, agent.TmuxWindowID); err != nil {
+		d.logger.Warn("Failed to record tmux window name for agent %s: %v", agentName, err)
+	}
+	return agent, true
+}
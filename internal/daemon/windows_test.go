@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+func TestHealthCheckRepairsRenamedWindow(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Skip("tmux not available")
+	}
+
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sessionName := "mc-test-window-ids"
+	if err := tmuxClient.CreateSession(ctx, sessionName, true); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(ctx, sessionName)
+	if err := tmuxClient.CreateWindow(ctx, sessionName, "test-agent"); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+	windowID, err := tmuxClient.GetWindowID(ctx, sessionName, "test-agent")
+	if err != nil {
+		t.Fatalf("GetWindowID failed: %v", err)
+	}
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: sessionName,
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	// An agent recorded before window IDs were kept
+	if err := d.state.AddAgent("test-repo", "test-agent", state.Agent{
+		Type:       state.AgentTypeWorker,
+		TmuxWindow: "test-agent",
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+
+	d.TriggerHealthCheck()
+	agent, exists := d.state.GetAgent("test-repo", "test-agent")
+	if !exists || agent.TmuxWindowID != windowID {
+		t.Fatalf("health check should record window ID %s, got %+v (exists %v)", windowID, agent, exists)
+	}
+
+	// A renamed window is still reached by ID, then renamed back
+	if err := tmuxClient.RenameWindow(ctx, sessionName, windowID, "bash"); err != nil {
+		t.Fatalf("RenameWindow failed: %v", err)
+	}
+	if status := d.agentStatus(sessionName, agent); status != "running" {
+		t.Errorf("agentStatus of a renamed window = %q, want running", status)
+	}
+	d.TriggerHealthCheck()
+	agent, exists = d.state.GetAgent("test-repo", "test-agent")
+	if !exists {
+		t.Fatal("agent whose window was renamed should not be cleaned up")
+	}
+	if agent.TmuxWindow != "test-agent" || agent.TmuxWindowID != windowID {
+		t.Errorf("agent window = %s (%s), want test-agent (%s)", agent.TmuxWindow, agent.TmuxWindowID, windowID)
+	}
+	if has, _ := tmuxClient.HasWindow(ctx, sessionName, "test-agent"); !has {
+		t.Error("health check should rename the window back to the agent's name")
+	}
+
+	// A stale ID, as after the window was recreated, is resolved again by name
+	if err := d.state.UpdateAgentWindow("test-repo", "test-agent", "test-agent", "@999999"); err != nil {
+		t.Fatalf("UpdateAgentWindow failed: %v", err)
+	}
+	d.TriggerHealthCheck()
+	agent, exists = d.state.GetAgent("test-repo", "test-agent")
+	if !exists || agent.TmuxWindowID != windowID {
+		t.Errorf("stale window ID should be re-resolved to %s, got %+v (exists %v)", windowID, agent, exists)
+	}
+}
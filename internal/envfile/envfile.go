@@ -0,0 +1,117 @@
+// Package envfile loads per-repository environment files whose variables are
+// injected into agent tmux sessions.
+//
+// Values loaded from these files are secrets: they must never be written to
+// prompt files, messages, or logs. Register them with a redact.Redactor to
+// scrub them from captured output.
+package envfile
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// validKey matches POSIX-style environment variable names
+var validKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// RepoEnvPath returns the path of the env file checked into (or ignored by) a repository
+func RepoEnvPath(repoPath string) string {
+	return filepath.Join(repoPath, ".multiclaude", "env")
+}
+
+// Parse parses KEY=VALUE lines. Blank lines and lines starting with '#' are
+// ignored, an optional "export " prefix is accepted, and values may be wrapped
+// in single or double quotes.
+func Parse(data string) (map[string]string, error) {
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNum)
+		}
+		key = strings.TrimSpace(key)
+		if !validKey.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", lineNum, key)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 {
+			if (value[0] == '"' && value[len(value)-1] == '"') || (value[0] == '\'' && value[len(value)-1] == '\'') {
+				value = value[1 : len(value)-1]
+			}
+		}
+		env[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// Load reads and parses an env file. A missing file yields an empty map.
+func Load(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	env, err := Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse env file %s: %w", path, err)
+	}
+	return env, nil
+}
+
+// ForRepo returns the environment for a repository: variables from
+// <repo>/.multiclaude/env, overridden by variables from extraFile (if set).
+func ForRepo(repoPath, extraFile string) (map[string]string, error) {
+	env, err := Load(RepoEnvPath(repoPath))
+	if err != nil {
+		return nil, err
+	}
+	if extraFile == "" {
+		return env, nil
+	}
+	if _, err := os.Stat(extraFile); err != nil {
+		return nil, fmt.Errorf("env file %s: %w", extraFile, err)
+	}
+	extra, err := Load(extraFile)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		env[k] = v
+	}
+	return env, nil
+}
+
+// ValidKey reports whether name is a valid environment variable name
+func ValidKey(name string) bool {
+	return validKey.MatchString(name)
+}
+
+// Keys returns the variable names in env, sorted
+func Keys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
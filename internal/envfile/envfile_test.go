@@ -0,0 +1,122 @@
+package envfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := `# staging credentials
+API_TOKEN=abc123
+export REGION=us-east-1
+
+QUOTED="hello world"
+SINGLE='x=y'
+EMPTY=
+`
+	env, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := map[string]string{
+		"API_TOKEN": "abc123",
+		"REGION":    "us-east-1",
+		"QUOTED":    "hello world",
+		"SINGLE":    "x=y",
+		"EMPTY":     "",
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("Parse() = %v, want %v", env, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"missing equals", "API_TOKEN"},
+		{"invalid name", "1TOKEN=abc"},
+		{"name with space", "MY TOKEN=abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.data); err == nil {
+				t.Errorf("Parse(%q) should fail", tt.data)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	env, err := Load(filepath.Join(t.TempDir(), "does-not-exist"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(env) != 0 {
+		t.Errorf("Load() of missing file = %v, want empty", env)
+	}
+}
+
+func TestForRepo(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoPath := filepath.Join(tmpDir, "repo")
+	if err := os.MkdirAll(filepath.Join(repoPath, ".multiclaude"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(RepoEnvPath(repoPath), []byte("A=repo\nB=repo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Repo file only
+	env, err := ForRepo(repoPath, "")
+	if err != nil {
+		t.Fatalf("ForRepo() error = %v", err)
+	}
+	if env["A"] != "repo" || env["B"] != "repo" {
+		t.Errorf("ForRepo() = %v", env)
+	}
+
+	// Extra file overrides repo file
+	extra := filepath.Join(tmpDir, "extra.env")
+	if err := os.WriteFile(extra, []byte("B=extra\nC=extra\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env, err = ForRepo(repoPath, extra)
+	if err != nil {
+		t.Fatalf("ForRepo() error = %v", err)
+	}
+	want := map[string]string{"A": "repo", "B": "extra", "C": "extra"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("ForRepo() = %v, want %v", env, want)
+	}
+
+	// A configured extra file that disappeared is an error
+	if _, err := ForRepo(repoPath, filepath.Join(tmpDir, "missing.env")); err == nil {
+		t.Error("ForRepo() with missing extra file should fail")
+	}
+}
+
+func TestKeys(t *testing.T) {
+	keys := Keys(map[string]string{"B": "1", "A": "2", "C": "3"})
+	if !reflect.DeepEqual(keys, []string{"A", "B", "C"}) {
+		t.Errorf("Keys() = %v", keys)
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	for _, key := range []string{"TOKEN", "_private", "GH_TOKEN_2"} {
+		if !ValidKey(key) {
+			t.Errorf("ValidKey(%q) = false, want true", key)
+		}
+	}
+	for _, key := range []string{"", "2FA", "BAD-NAME", "A B"} {
+		if ValidKey(key) {
+			t.Errorf("ValidKey(%q) = true, want false", key)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package errors
+
+import (
+	goerrors "errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CommandError is an external command (git, gh, tmux) that failed, with what
+// it wrote to stderr. Its message always includes the stderr, so failures
+// that are not classified still say what went wrong instead of just
+// "exit status 1".
+type CommandError struct {
+	Name   string   // Command name, e.g. "git"
+	Args   []string // Arguments, without the name
+	Stderr string
+	Err    error
+}
+
+// Error implements the error interface
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("%s failed: %v", e.label(), e.Err)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// label names the command and its subcommand, e.g. "git worktree", skipping
+// global options such as git's -C <path>
+func (e *CommandError) label() string {
+	for i := 0; i < len(e.Args); i++ {
+		arg := e.Args[i]
+		if arg == "-C" || arg == "-c" || arg == "-L" || arg == "-S" {
+			i++
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return e.Name + " " + arg
+		}
+	}
+	return e.Name
+}
+
+// commandFailure is one entry of the classification table: a failure of
+// the named command (any command when empty) whose stderr matches pattern
+type commandFailure struct {
+	command string
+	pattern *regexp.Regexp
+	build   func(match []string, cause error) *CLIError
+}
+
+// commandFailures maps common git, gh and tmux failures to typed errors.
+// The first matching entry wins, so more specific patterns come first.
+var commandFailures = []commandFailure{
+	{
+		command: "gh",
+		pattern: regexp.MustCompile(`(?i)gh auth login|HTTP 401|Bad credentials|not logged in to any GitHub hosts|authentication required`),
+		build:   func(_ []string, cause error) *CLIError { return GHNotAuthenticated(cause) },
+	},
+	{
+		command: "git",
+		pattern: regexp.MustCompile(`'([^']+)' is already (?:checked out|used by worktree) at '([^']+)'`),
+		build: func(match []string, cause error) *CLIError {
+			return BranchCheckedOutElsewhere(match[1], match[2], cause)
+		},
+	},
+	{
+		command: "git",
+		pattern: regexp.MustCompile(`Authentication failed for '([^']+)'|could not read (?:Username|Password) for '([^']+)'|Permission denied \(publickey`),
+		build: func(match []string, cause error) *CLIError {
+			return GitAuthFailed(strings.TrimSuffix(firstNonEmpty(match[1:]...), "/"), cause)
+		},
+	},
+	{
+		command: "git",
+		pattern: regexp.MustCompile(`repository '([^']+)' not found`),
+		build: func(match []string, cause error) *CLIError {
+			return GitRemoteNotFound(strings.TrimSuffix(match[1], "/"), cause)
+		},
+	},
+	{
+		command: "git",
+		pattern: regexp.MustCompile(`Repository not found`),
+		build:   func(_ []string, cause error) *CLIError { return GitRemoteNotFound("", cause) },
+	},
+	{
+		command: "git",
+		pattern: regexp.MustCompile(`Unable to create '([^']+\.lock)': File exists`),
+		build:   func(match []string, cause error) *CLIError { return GitIndexLocked(match[1], cause) },
+	},
+	{
+		command: "git",
+		pattern: regexp.MustCompile(`\[rejected\].*\((?:non-fast-forward|fetch first)\)|Updates were rejected because`),
+		build:   func(_ []string, cause error) *CLIError { return GitPushRejected(cause) },
+	},
+	{
+		command: "git",
+		pattern: regexp.MustCompile(`not a git repository`),
+		build:   func(_ []string, cause error) *CLIError { return NotAGitRepository(cause) },
+	},
+	{
+		command: "tmux",
+		pattern: regexp.MustCompile(`no server running on|error connecting to \S+ \(No such file or directory\)`),
+		build:   func(_ []string, cause error) *CLIError { return TmuxNoServer(cause) },
+	},
+	{
+		command: "tmux",
+		pattern: regexp.MustCompile(`can't find (?:session|window|pane):? ?(\S*)`),
+		build:   func(match []string, cause error) *CLIError { return TmuxTargetNotFound(match[1], cause) },
+	},
+}
+
+// ClassifyCommand turns the failure of an external command into an error
+// that says what went wrong. Known git, gh and tmux failures become typed
+// errors with suggestions; anything else is a *CommandError carrying the
+// stderr. Either way the *CommandError is in the error chain.
+func ClassifyCommand(name string, args []string, stderr string, err error) error {
+	if err == nil {
+		return nil
+	}
+	name = filepath.Base(name)
+	cause := &CommandError{Name: name, Args: args, Stderr: stderr, Err: err}
+
+	if goerrors.Is(err, exec.ErrNotFound) {
+		if name == "gh" {
+			return GhNotInstalled(cause)
+		}
+		return New(CategoryConfig, fmt.Sprintf("could not find '%s' in PATH", name)).WithSuggestion(fmt.Sprintf("install %s, or check your PATH", name))
+	}
+
+	if classified := classifyOutput(name, stderr, cause); classified != nil {
+		return classified
+	}
+	return cause
+}
+
+// classifyOutput matches a command's output against the classification
+// table, returning nil when nothing matches. An empty name matches the
+// patterns of every command.
+func classifyOutput(name, output string, cause error) *CLIError {
+	for _, failure := range commandFailures {
+		if name != "" && failure.command != name {
+			continue
+		}
+		if match := failure.pattern.FindStringSubmatch(output); match != nil {
+			return failure.build(match, cause)
+		}
+	}
+	return nil
+}
+
+// classifiedSuggestion returns the suggestion for a failure whose output is
+// embedded in an error's message, e.g. one from the worktree package, or
+// the suggestion of a classified error in its chain
+func classifiedSuggestion(name string, cause error) string {
+	if cause == nil {
+		return ""
+	}
+	var cliErr *CLIError
+	if goerrors.As(cause, &cliErr) && cliErr.Suggestion != "" {
+		return cliErr.Suggestion
+	}
+	if classified := classifyOutput(name, cause.Error(), nil); classified != nil {
+		return classified.Suggestion
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
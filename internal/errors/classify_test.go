@@ -0,0 +1,258 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// exitStatus stands in for the *exec.ExitError of a failed command
+var exitStatus = errors.New("exit status 1")
+
+func TestClassifyCommand(t *testing.T) {
+	// Stderr fixtures are as captured from git 2.43, gh 2.45 and tmux 3.4
+	tests := []struct {
+		name       string
+		command    string
+		args       []string
+		stderr     string
+		category   Category
+		message    string // Substring of the classified message
+		suggestion string // Substring of the suggestion
+	}{
+		{
+			name:       "gh not logged in",
+			command:    "gh",
+			args:       []string{"pr", "create"},
+			stderr:     "To get started with GitHub CLI, please run:  gh auth login\nAlternatively, populate the GH_TOKEN environment variable with a GitHub API authentication token.\n",
+			category:   CategoryConfig,
+			message:    "not authenticated",
+			suggestion: "gh auth login",
+		},
+		{
+			name:       "gh bad token",
+			command:    "gh",
+			args:       []string{"pr", "view", "42"},
+			stderr:     "HTTP 401: Bad credentials (https://api.github.com/graphql)\nTry authenticating with:  gh auth login\n",
+			category:   CategoryConfig,
+			message:    "not authenticated",
+			suggestion: "gh auth login",
+		},
+		{
+			name:       "branch checked out in another worktree",
+			command:    "git",
+			args:       []string{"worktree", "add", "/tmp/wt", "work/fox"},
+			stderr:     "Preparing worktree (checking out 'work/fox')\nfatal: 'work/fox' is already checked out at '/home/me/.multiclaude/wts/repo/fox'\n",
+			category:   CategoryRuntime,
+			message:    "'work/fox' is already checked out at /home/me/.multiclaude/wts/repo/fox",
+			suggestion: "git worktree remove /home/me/.multiclaude/wts/repo/fox",
+		},
+		{
+			name:       "branch used by worktree",
+			command:    "git",
+			args:       []string{"checkout", "main"},
+			stderr:     "fatal: 'main' is already used by worktree at '/home/me/src/repo'\n",
+			category:   CategoryRuntime,
+			message:    "'main' is already checked out at /home/me/src/repo",
+			suggestion: "git worktree remove /home/me/src/repo",
+		},
+		{
+			name:       "https authentication failed",
+			command:    "git",
+			args:       []string{"push", "origin", "work/fox"},
+			stderr:     "remote: Invalid username or password.\nfatal: Authentication failed for 'https://github.com/org/repo.git/'\n",
+			category:   CategoryConfig,
+			message:    "authenticate with https://github.com/org/repo.git",
+			suggestion: "gh auth setup-git",
+		},
+		{
+			name:       "no credentials on a terminal-less fetch",
+			command:    "git",
+			args:       []string{"fetch", "origin"},
+			stderr:     "fatal: could not read Username for 'https://github.com': terminal prompts disabled\n",
+			category:   CategoryConfig,
+			message:    "authenticate with https://github.com",
+			suggestion: "gh auth setup-git",
+		},
+		{
+			name:       "ssh key rejected",
+			command:    "git",
+			args:       []string{"clone", "git@github.com:org/repo.git"},
+			stderr:     "Cloning into 'repo'...\ngit@github.com: Permission denied (publickey).\nfatal: Could not read from remote repository.\n\nPlease make sure you have the correct access rights\nand the repository exists.\n",
+			category:   CategoryConfig,
+			message:    "git could not authenticate with the remote",
+			suggestion: "ssh -T git@github.com",
+		},
+		{
+			name:       "remote repository not found",
+			command:    "git",
+			args:       []string{"clone", "https://github.com/org/missing"},
+			stderr:     "Cloning into 'missing'...\nremote: Repository not found.\nfatal: repository 'https://github.com/org/missing/' not found\n",
+			category:   CategoryNotFound,
+			message:    "remote repository https://github.com/org/missing not found",
+			suggestion: "gh auth status",
+		},
+		{
+			name:       "index lock left behind",
+			command:    "git",
+			args:       []string{"-C", "/home/me/src/repo", "commit", "-m", "wip"},
+			stderr:     "fatal: Unable to create '/home/me/src/repo/.git/index.lock': File exists.\n\nAnother git process seems to be running in this repository, e.g.\nan editor opened by 'git commit'. Please make sure all processes\nare terminated then try again. If it still fails, a git process\nmay have crashed in this repository earlier:\nremove the file manually to continue.\n",
+			category:   CategoryRuntime,
+			message:    "/home/me/src/repo/.git/index.lock",
+			suggestion: "rm /home/me/src/repo/.git/index.lock",
+		},
+		{
+			name:       "push rejected",
+			command:    "git",
+			args:       []string{"push", "origin", "work/fox"},
+			stderr:     "To github.com:org/repo.git\n ! [rejected]        work/fox -> work/fox (fetch first)\nerror: failed to push some refs to 'github.com:org/repo.git'\nhint: Updates were rejected because the remote contains work that you do not\nhint: have locally.\n",
+			category:   CategoryRuntime,
+			message:    "push was rejected",
+			suggestion: "rebase",
+		},
+		{
+			name:       "not a git repository",
+			command:    "/usr/bin/git",
+			args:       []string{"-C", "/tmp/gone", "status"},
+			stderr:     "fatal: not a git repository (or any of the parent directories): .git\n",
+			category:   CategoryConfig,
+			message:    "not a git repository",
+			suggestion: "multiclaude repair",
+		},
+		{
+			name:       "tmux server not running",
+			command:    "tmux",
+			args:       []string{"new-window", "-t", "mc-repo"},
+			stderr:     "no server running on /tmp/tmux-1000/default\n",
+			category:   CategoryRuntime,
+			message:    "no tmux server",
+			suggestion: "multiclaude start",
+		},
+		{
+			name:       "tmux socket missing",
+			command:    "tmux",
+			args:       []string{"send-keys", "-t", "mc-repo:fox", "hi"},
+			stderr:     "error connecting to /tmp/tmux-1000/default (No such file or directory)\n",
+			category:   CategoryRuntime,
+			message:    "no tmux server",
+			suggestion: "multiclaude start",
+		},
+		{
+			name:       "tmux window missing",
+			command:    "tmux",
+			args:       []string{"kill-window", "-t", "mc-repo:fox"},
+			stderr:     "can't find window: fox\n",
+			category:   CategoryNotFound,
+			message:    "'fox' not found",
+			suggestion: "multiclaude repair",
+		},
+		{
+			name:       "tmux session missing",
+			command:    "tmux",
+			args:       []string{"new-window", "-t", "mc-gone"},
+			stderr:     "can't find session: mc-gone\n",
+			category:   CategoryNotFound,
+			message:    "'mc-gone' not found",
+			suggestion: "multiclaude list",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ClassifyCommand(tt.command, tt.args, tt.stderr, exitStatus)
+
+			var cliErr *CLIError
+			if !errors.As(err, &cliErr) {
+				t.Fatalf("ClassifyCommand() = %T %v, want a *CLIError", err, err)
+			}
+			if cliErr.Category != tt.category {
+				t.Errorf("category = %v, want %v", cliErr.Category, tt.category)
+			}
+			if !strings.Contains(cliErr.Message, tt.message) {
+				t.Errorf("message = %q, want it to contain %q", cliErr.Message, tt.message)
+			}
+			if !strings.Contains(cliErr.Suggestion, tt.suggestion) {
+				t.Errorf("suggestion = %q, want it to contain %q", cliErr.Suggestion, tt.suggestion)
+			}
+
+			var cmdErr *CommandError
+			if !errors.As(err, &cmdErr) || cmdErr.Stderr != tt.stderr {
+				t.Errorf("the *CommandError with the stderr should be in the chain of %v", err)
+			}
+			if !errors.Is(err, exitStatus) {
+				t.Errorf("the original error should be in the chain of %v", err)
+			}
+		})
+	}
+}
+
+func TestClassifyCommand_OnlyMatchesOwnCommand(t *testing.T) {
+	// tmux's "can't find" has nothing to do with git
+	err := ClassifyCommand("git", []string{"log"}, "can't find session: x\n", exitStatus)
+	if _, ok := err.(*CommandError); !ok {
+		t.Errorf("ClassifyCommand() = %T %v, want an unclassified *CommandError", err, err)
+	}
+}
+
+func TestClassifyCommand_Unknown(t *testing.T) {
+	stderr := "fatal: ambiguous argument 'nope': unknown revision or path not in the working tree.\n"
+	err := ClassifyCommand("git", []string{"-C", "/repo", "rev-parse", "nope"}, stderr, exitStatus)
+
+	if _, ok := err.(*CommandError); !ok {
+		t.Fatalf("ClassifyCommand() = %T, want *CommandError", err)
+	}
+	want := "git rev-parse failed: exit status 1: fatal: ambiguous argument 'nope'"
+	if !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("Error() = %q, want prefix %q", err.Error(), want)
+	}
+
+	// Wrapping it keeps the stderr in the formatted message
+	formatted := Format(GitOperationFailed("resolve nope", err))
+	if !strings.Contains(formatted, "unknown revision") {
+		t.Errorf("formatted error should include the stderr, got: %s", formatted)
+	}
+}
+
+func TestClassifyCommand_NoStderr(t *testing.T) {
+	err := ClassifyCommand("tmux", []string{"kill-server"}, "", exitStatus)
+	if err.Error() != "tmux kill-server failed: exit status 1" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if ClassifyCommand("tmux", nil, "", nil) != nil {
+		t.Error("ClassifyCommand() of a nil error should be nil")
+	}
+}
+
+func TestClassifyCommand_NotInstalled(t *testing.T) {
+	notFound := &exec.Error{Name: "gh", Err: exec.ErrNotFound}
+	err := ClassifyCommand("gh", []string{"pr", "list"}, "", notFound)
+	if !strings.Contains(Format(err), "https://cli.github.com") {
+		t.Errorf("missing gh should suggest installing it, got: %s", Format(err))
+	}
+
+	notFound = &exec.Error{Name: "tmux", Err: exec.ErrNotFound}
+	err = ClassifyCommand("tmux", []string{"ls"}, "", notFound)
+	if !strings.Contains(Format(err), "'tmux' in PATH") {
+		t.Errorf("missing tmux should name the binary, got: %s", Format(err))
+	}
+}
+
+func TestClassifiedSuggestions(t *testing.T) {
+	// Suggestions carry through the existing wrappers, both for classified
+	// errors and for errors that only embed the command's output
+	classified := ClassifyCommand("tmux", []string{"new-window"}, "no server running on /tmp/tmux-0/default\n", exitStatus)
+	if got := TmuxOperationFailed("create window", classified).Suggestion; !strings.Contains(got, "multiclaude start") {
+		t.Errorf("TmuxOperationFailed suggestion = %q", got)
+	}
+
+	embedded := fmt.Errorf("failed to create worktree: exit status 128\nOutput: fatal: 'work/fox' is already checked out at '/wts/fox'")
+	if got := WorktreeCreationFailed(embedded).Suggestion; !strings.Contains(got, "git worktree remove /wts/fox") {
+		t.Errorf("WorktreeCreationFailed suggestion = %q", got)
+	}
+
+	if got := GitOperationFailed("status", errors.New("exit status 1")).Suggestion; !strings.Contains(got, "check git status") {
+		t.Errorf("GitOperationFailed fallback suggestion = %q", got)
+	}
+}
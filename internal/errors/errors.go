@@ -28,6 +28,7 @@ type CLIError struct {
 	Message    string
 	Suggestion string // Optional hint for how to fix the error
 	Cause      error  // Wrapped error
+	Code       int    // Process exit status; zero means 1
 }
 
 // Error implements the error interface
@@ -63,6 +64,15 @@ func (e *CLIError) WithSuggestion(suggestion string) *CLIError {
 	return e
 }
 
+// ExitCode returns the process exit status for err: the Code of a CLIError
+// that sets one, and 1 otherwise
+func ExitCode(err error) int {
+	if cliErr, ok := err.(*CLIError); ok && cliErr.Code != 0 {
+		return cliErr.Code
+	}
+	return 1
+}
+
 // Format returns a user-friendly formatted error message
 func Format(err error) string {
 	if err == nil {
@@ -71,8 +81,12 @@ func Format(err error) string {
 
 	var sb strings.Builder
 
-	// Check if it's a CLIError
+	// Check if it's a CLIError; one without a message, such as a
+	// predicate's answer no, prints nothing
 	if cliErr, ok := err.(*CLIError); ok {
+		if cliErr.Message == "" && cliErr.Cause == nil {
+			return ""
+		}
 		// Add category prefix
 		prefix := categoryPrefix(cliErr.Category)
 		sb.WriteString(prefix)
@@ -127,6 +141,16 @@ func DaemonNotRunning() *CLIError {
 	}
 }
 
+// DaemonMustBeStopped creates an error for offline operations that would
+// race with a running daemon rewriting the same files
+func DaemonMustBeStopped(operation string, pid int) *CLIError {
+	return &CLIError{
+		Category:   CategoryRuntime,
+		Message:    fmt.Sprintf("the daemon is running (PID: %d); stop it before %s", pid, operation),
+		Suggestion: "multiclaude daemon stop",
+	}
+}
+
 // DaemonCommunicationFailed creates an error for daemon communication failures
 func DaemonCommunicationFailed(operation string, cause error) *CLIError {
 	return &CLIError{
@@ -181,13 +205,35 @@ func InvalidPRURL() *CLIError {
 	}
 }
 
+// StageFailed creates an error for a failed stage of a multi-step command
+// such as init, keeping the category and suggestion of the stage's error
+func StageFailed(stage string, cause error) *CLIError {
+	if cliErr, ok := cause.(*CLIError); ok {
+		return &CLIError{
+			Category:   cliErr.Category,
+			Message:    fmt.Sprintf("%s stage failed: %s", stage, cliErr.Message),
+			Cause:      cliErr.Cause,
+			Suggestion: cliErr.Suggestion,
+		}
+	}
+	return &CLIError{
+		Category: CategoryRuntime,
+		Message:  fmt.Sprintf("%s stage failed", stage),
+		Cause:    cause,
+	}
+}
+
 // GitOperationFailed creates an error for git operation failures
 func GitOperationFailed(operation string, cause error) *CLIError {
+	suggestion := classifiedSuggestion("git", cause)
+	if suggestion == "" {
+		suggestion = "check git status and ensure the repository is in a clean state"
+	}
 	return &CLIError{
 		Category:   CategoryRuntime,
 		Message:    fmt.Sprintf("git %s failed", operation),
 		Cause:      cause,
-		Suggestion: "check git status and ensure the repository is in a clean state",
+		Suggestion: suggestion,
 	}
 }
 
@@ -219,8 +265,8 @@ func tmuxSuggestionForOperation(operation string, cause error) string {
 		return "a tmux session with this name already exists; kill it with: tmux kill-session -t <session-name>"
 	}
 
-	// Default: no specific suggestion
-	return ""
+	// Known tmux failures, e.g. no server running
+	return classifiedSuggestion("tmux", cause)
 }
 
 // WorktreeCreationFailed creates an error for worktree creation failures
@@ -241,6 +287,11 @@ func worktreeSuggestionForError(cause error) string {
 
 	errMsg := cause.Error()
 
+	// Known git failures, e.g. a branch checked out in a named worktree
+	if suggestion := classifiedSuggestion("git", cause); suggestion != "" {
+		return suggestion
+	}
+
 	// Check more specific patterns first before "already exists"
 
 	// Worktree path already exists (check before generic "already exists")
@@ -300,6 +351,28 @@ func ClaudeNotFound(cause error) *CLIError {
 	}
 }
 
+// PinnedClaudeUnavailable creates an error for a repository whose pinned
+// claude binary cannot be used. Agents are never started with another binary.
+func PinnedClaudeUnavailable(repo string, cause error) *CLIError {
+	return &CLIError{
+		Category:   CategoryConfig,
+		Message:    fmt.Sprintf("the claude binary pinned for '%s' cannot be used", repo),
+		Cause:      cause,
+		Suggestion: fmt.Sprintf("reinstall it, pin another with 'multiclaude config %s --pin-claude-path <path>', or unpin with 'multiclaude config %s --pin-claude-path='", repo, repo),
+	}
+}
+
+// TemplateUnavailable creates an error for an init --template repository
+// that cannot be read
+func TemplateUnavailable(url string, cause error) *CLIError {
+	return &CLIError{
+		Category:   CategoryConfig,
+		Message:    fmt.Sprintf("cannot use template repository %s", url),
+		Cause:      cause,
+		Suggestion: fmt.Sprintf("check the URL and your access with 'git ls-remote %s'; the template needs a .multiclaude/ directory", url),
+	}
+}
+
 // MissingArgument creates an error for missing required arguments
 func MissingArgument(argName, expectedType string) *CLIError {
 	msg := fmt.Sprintf("missing required argument: %s", argName)
@@ -340,6 +413,20 @@ func UnknownCommand(cmd string) *CLIError {
 	}
 }
 
+// UnknownFlag creates an error for a flag the command does not take,
+// naming the declared flag it is probably a typo of, if any
+func UnknownFlag(flag, didYouMean, command string) *CLIError {
+	msg := fmt.Sprintf("unknown flag %s", flag)
+	if didYouMean != "" {
+		msg = fmt.Sprintf("unknown flag %s, did you mean %s?", flag, didYouMean)
+	}
+	return &CLIError{
+		Category:   CategoryUsage,
+		Message:    msg,
+		Suggestion: command + " --help",
+	}
+}
+
 // NoRepositoriesFound creates an error for when no repositories are tracked
 func NoRepositoriesFound() *CLIError {
 	return &CLIError{
@@ -385,6 +472,175 @@ func WorkspaceNotFound(name, repo string) *CLIError {
 	}
 }
 
+// WorkspaceBranchNotFound creates an error for a workspace whose branch is not in the local repository
+func WorkspaceBranchNotFound(name, repo string) *CLIError {
+	return &CLIError{
+		Category:   CategoryNotFound,
+		Message:    fmt.Sprintf("branch 'workspace/%s' not found in repo '%s'", name, repo),
+		Suggestion: fmt.Sprintf("multiclaude workspace list --repo %s", repo),
+	}
+}
+
+// GroupNotFound creates an error for an unknown repository group
+func GroupNotFound(name string) *CLIError {
+	return &CLIError{
+		Category:   CategoryNotFound,
+		Message:    fmt.Sprintf("group '%s' not found", name),
+		Suggestion: "multiclaude group list",
+	}
+}
+
+// NoCommitsForPR creates an error for when a branch has nothing to open a pull request with
+func NoCommitsForPR(branch, base string) *CLIError {
+	return &CLIError{
+		Category:   CategoryUsage,
+		Message:    fmt.Sprintf("branch '%s' has no commits ahead of %s", branch, base),
+		Suggestion: "commit your changes in the workspace before creating a pull request",
+	}
+}
+
+// NoPRForWorkspace creates an error for when a workspace has no recorded pull request
+func NoPRForWorkspace(name, repo string) *CLIError {
+	return &CLIError{
+		Category:   CategoryNotFound,
+		Message:    fmt.Sprintf("no pull request recorded for workspace '%s' in repo '%s'", name, repo),
+		Suggestion: fmt.Sprintf("multiclaude workspace create-pr %s --repo %s", name, repo),
+	}
+}
+
+// GhNotInstalled creates an error for commands that need the GitHub CLI
+func GhNotInstalled(cause error) *CLIError {
+	return &CLIError{
+		Category:   CategoryConfig,
+		Message:    "the GitHub CLI (gh) is required but was not found",
+		Cause:      cause,
+		Suggestion: "install it from https://cli.github.com and run 'gh auth login'",
+	}
+}
+
+// GHNotAuthenticated creates an error for gh commands run without a GitHub login
+func GHNotAuthenticated(cause error) *CLIError {
+	return &CLIError{
+		Category:   CategoryConfig,
+		Message:    "the GitHub CLI (gh) is not authenticated",
+		Cause:      cause,
+		Suggestion: "gh auth login (or set GH_TOKEN)",
+	}
+}
+
+// GitAuthFailed creates an error for git operations the remote refused to authenticate
+func GitAuthFailed(remote string, cause error) *CLIError {
+	message := "git could not authenticate with the remote"
+	if remote != "" {
+		message = fmt.Sprintf("git could not authenticate with %s", remote)
+	}
+	return &CLIError{
+		Category:   CategoryConfig,
+		Message:    message,
+		Cause:      cause,
+		Suggestion: "check your credentials: 'gh auth setup-git' for HTTPS remotes, or 'ssh -T git@github.com' for SSH remotes",
+	}
+}
+
+// GitRemoteNotFound creates an error for a remote repository that does not exist or is not visible
+func GitRemoteNotFound(remote string, cause error) *CLIError {
+	message := "remote repository not found"
+	if remote != "" {
+		message = fmt.Sprintf("remote repository %s not found", remote)
+	}
+	return &CLIError{
+		Category:   CategoryNotFound,
+		Message:    message,
+		Cause:      cause,
+		Suggestion: "check the URL for typos; private repositories also look missing without access, so check 'gh auth status'",
+	}
+}
+
+// BranchCheckedOutElsewhere creates an error for a branch git refuses to check out twice
+func BranchCheckedOutElsewhere(branch, worktreePath string, cause error) *CLIError {
+	message := fmt.Sprintf("branch '%s' is already checked out in another worktree", branch)
+	suggestion := "multiclaude cleanup, or remove the other worktree with: git worktree remove <path>"
+	if worktreePath != "" {
+		message = fmt.Sprintf("branch '%s' is already checked out at %s", branch, worktreePath)
+		suggestion = fmt.Sprintf("remove that worktree if it is stale: git worktree remove %s (or run multiclaude cleanup)", worktreePath)
+	}
+	return &CLIError{
+		Category:   CategoryRuntime,
+		Message:    message,
+		Cause:      cause,
+		Suggestion: suggestion,
+	}
+}
+
+// NotAGitRepository creates an error for git commands run outside a repository
+func NotAGitRepository(cause error) *CLIError {
+	return &CLIError{
+		Category:   CategoryConfig,
+		Message:    "not a git repository",
+		Cause:      cause,
+		Suggestion: "multiclaude repair (the repository or worktree may have been deleted or moved)",
+	}
+}
+
+// GitIndexLocked creates an error for a git operation blocked by another git process's lock file
+func GitIndexLocked(lockPath string, cause error) *CLIError {
+	return &CLIError{
+		Category:   CategoryRuntime,
+		Message:    fmt.Sprintf("git is locked by %s", lockPath),
+		Cause:      cause,
+		Suggestion: fmt.Sprintf("wait for the other git process to finish; if none is running, remove the stale lock: rm %s", lockPath),
+	}
+}
+
+// GitPushRejected creates an error for a push the remote rejected because the branch moved on
+func GitPushRejected(cause error) *CLIError {
+	return &CLIError{
+		Category:   CategoryRuntime,
+		Message:    "git push was rejected because the remote branch has commits the local branch does not",
+		Cause:      cause,
+		Suggestion: "fetch and rebase onto the remote branch, then push again",
+	}
+}
+
+// TmuxNoServer creates an error for tmux commands run while no tmux server is running
+func TmuxNoServer(cause error) *CLIError {
+	return &CLIError{
+		Category:   CategoryRuntime,
+		Message:    "no tmux server is running",
+		Cause:      cause,
+		Suggestion: "multiclaude start (the daemon restores agent sessions), and check 'tmux ls' works for this user",
+	}
+}
+
+// TmuxTargetNotFound creates an error for a tmux session or window that does not exist
+func TmuxTargetNotFound(target string, cause error) *CLIError {
+	return &CLIError{
+		Category:   CategoryNotFound,
+		Message:    fmt.Sprintf("tmux session or window '%s' not found", target),
+		Cause:      cause,
+		Suggestion: "multiclaude list to see agents, or multiclaude repair to restore missing windows",
+	}
+}
+
+// WorkspaceHasUncommittedChanges creates an error for operations that need a clean workspace
+func WorkspaceHasUncommittedChanges(name string) *CLIError {
+	return &CLIError{
+		Category:   CategoryUsage,
+		Message:    fmt.Sprintf("workspace '%s' has uncommitted changes", name),
+		Suggestion: "commit or stash your changes in the workspace first",
+	}
+}
+
+// MergeConflict creates an error for a merge into a workspace that conflicted and was aborted
+func MergeConflict(branch, workspace string, files []string, cause error) *CLIError {
+	return &CLIError{
+		Category:   CategoryRuntime,
+		Message:    fmt.Sprintf("merging %s into workspace '%s' conflicts in %d file(s); the merge was aborted", branch, workspace, len(files)),
+		Cause:      cause,
+		Suggestion: "rerun with --strategy ours or --strategy theirs to settle conflicting hunks for one side, or merge by hand in the workspace",
+	}
+}
+
 // InvalidWorkspaceName creates an error for invalid workspace names
 func InvalidWorkspaceName(reason string) *CLIError {
 	return &CLIError{
@@ -394,6 +650,80 @@ func InvalidWorkspaceName(reason string) *CLIError {
 	}
 }
 
+// InvalidAgentName creates an error for invalid agent (worker) names
+func InvalidAgentName(name, reason string) *CLIError {
+	return &CLIError{
+		Category:   CategoryUsage,
+		Message:    fmt.Sprintf("invalid agent name '%s': %s", name, reason),
+		Suggestion: "agent names follow git branch naming rules and cannot be supervisor, merge-queue, workspace, or default",
+	}
+}
+
+// AgentAlreadyExists creates an error for when an agent name is already in use in a repository
+func AgentAlreadyExists(name, repo string) *CLIError {
+	return &CLIError{
+		Category:   CategoryUsage,
+		Message:    fmt.Sprintf("an agent named '%s' already exists in repo '%s'", name, repo),
+		Suggestion: fmt.Sprintf("choose a different --name or check existing agents: multiclaude work list --repo %s", repo),
+	}
+}
+
+// PossibleDuplicateWorker creates an error for a worker whose task matches that of a recently created worker
+func PossibleDuplicateWorker(existing, repo string) *CLIError {
+	return &CLIError{
+		Category:   CategoryUsage,
+		Message:    fmt.Sprintf("possible duplicate of worker '%s' in repo '%s', which was created recently with the same task", existing, repo),
+		Suggestion: fmt.Sprintf("attach to it with: multiclaude attach %s\nor start another worker anyway with --allow-duplicate", existing),
+	}
+}
+
+// WorkerLimitReached creates an error for when a repository already has its maximum number of workers
+func WorkerLimitReached(repo string, current, max int) *CLIError {
+	return &CLIError{
+		Category:   CategoryRuntime,
+		Message:    fmt.Sprintf("repository '%s' is at its worker limit (%d/%d)", repo, current, max),
+		Suggestion: fmt.Sprintf("wait for a worker to finish, or raise the limit: multiclaude daemon throttle %s --max-concurrent-agents <n>", repo),
+	}
+}
+
+// EphemeralLimitReached creates an error for when a repository already has its maximum number of ephemeral agents
+func EphemeralLimitReached(repo string, current, max int) *CLIError {
+	return &CLIError{
+		Category:   CategoryRuntime,
+		Message:    fmt.Sprintf("repository '%s' is at its ephemeral agent limit (%d/%d)", repo, current, max),
+		Suggestion: fmt.Sprintf("wait for an ephemeral agent to finish, or raise the limit: multiclaude daemon throttle %s --ephemeral --max-concurrent-agents <n>", repo),
+	}
+}
+
+// WorktreeLimitReached creates an error for when a repository already has its maximum number of worker worktrees
+func WorktreeLimitReached(repo string, current, max int) *CLIError {
+	return &CLIError{
+		Category:   CategoryRuntime,
+		Message:    fmt.Sprintf("repository '%s' is at its worktree limit (%d/%d)", repo, current, max),
+		Suggestion: fmt.Sprintf("remove a finished worker with 'multiclaude work rm <worker>', or raise the limit: multiclaude config %s --worktree-limit <n>", repo),
+	}
+}
+
+// ConfirmationRequired creates an error for when a command needs confirmation
+// but stdin is not a terminal (e.g. it was run by an agent or a script)
+func ConfirmationRequired(prompt string) *CLIError {
+	return &CLIError{
+		Category:   CategoryUsage,
+		Message:    fmt.Sprintf("confirmation required but stdin is not a terminal: %s", prompt),
+		Suggestion: "re-run with --yes (or set MULTICLAUDE_ASSUME_YES=1) to proceed without prompting",
+	}
+}
+
+// WizardNeedsTerminal creates an error for when init --wizard is run without
+// a terminal on stdin
+func WizardNeedsTerminal() *CLIError {
+	return &CLIError{
+		Category:   CategoryUsage,
+		Message:    "init --wizard needs an interactive terminal, but stdin is not one",
+		Suggestion: "pass the settings as flags instead: multiclaude init <github-url> [name] [--no-merge-queue] [--mq-track=all|author|assigned] [--no-workspace]",
+	}
+}
+
 // LogFileNotFound creates an error for when an agent's log file cannot be found
 func LogFileNotFound(agent, repo string) *CLIError {
 	return &CLIError{
@@ -412,6 +742,54 @@ func AgentNotInState(agent, repo string) *CLIError {
 	}
 }
 
+// Exit statuses of predicate commands such as `work exists`, which answer
+// yes (0) or no through their exit status so scripts need not parse output
+const (
+	// ExitNo is the answer no
+	ExitNo = 1
+	// ExitUndetermined means the answer could not be found, e.g. because
+	// of bad arguments or an unreachable daemon
+	ExitUndetermined = 2
+)
+
+// PredicateNo creates the error a predicate command returns to answer no.
+// It has no message, so nothing is printed.
+func PredicateNo() *CLIError {
+	return &CLIError{Category: CategoryRuntime, Code: ExitNo}
+}
+
+// Undetermined gives err the exit status of a predicate command that could
+// not answer. A nil err and the answer no are returned unchanged.
+func Undetermined(err error) error {
+	if err == nil {
+		return nil
+	}
+	cliErr, ok := err.(*CLIError)
+	if !ok {
+		return &CLIError{Category: CategoryRuntime, Message: err.Error(), Code: ExitUndetermined}
+	}
+	if cliErr.Message == "" && cliErr.Code == ExitNo {
+		return cliErr
+	}
+	cliErr.Code = ExitUndetermined
+	return cliErr
+}
+
+// ExitNotRegistered is the exit status of agent whoami when the agent is
+// not registered, so that hook scripts can tell it from other failures
+const ExitNotRegistered = 2
+
+// AgentNotRegistered creates an error for an agent the daemon has no record
+// of, e.g. after repair removed it
+func AgentNotRegistered(agent, repo string) *CLIError {
+	return &CLIError{
+		Category:   CategoryNotFound,
+		Message:    fmt.Sprintf("agent '%s' is not registered in repo '%s'", agent, repo),
+		Suggestion: fmt.Sprintf("multiclaude agent send-message supervisor \"%s is no longer registered; please remove and re-create it\"", agent),
+		Code:       ExitNotRegistered,
+	}
+}
+
 // NoSessionID creates an error for when an agent has no session ID
 func NoSessionID(agent string) *CLIError {
 	return &CLIError{
@@ -429,3 +807,78 @@ func InvalidDuration(value string) *CLIError {
 		Suggestion: "use format like '7d', '24h', or '30m' (days, hours, minutes)",
 	}
 }
+
+// MessageTemplateNotFound creates an error for an unknown message template
+func MessageTemplateNotFound(name string) *CLIError {
+	return &CLIError{
+		Category:   CategoryNotFound,
+		Message:    fmt.Sprintf("message template '%s' not found", name),
+		Suggestion: "multiclaude agent message-templates",
+	}
+}
+
+// MissingTemplateVariables creates an error for a message template rendered
+// without values for all of its variables
+func MissingTemplateVariables(name string, missing []string) *CLIError {
+	vars := make([]string, len(missing))
+	for i, v := range missing {
+		vars[i] = fmt.Sprintf("--var %s=<value>", v)
+	}
+	return &CLIError{
+		Category:   CategoryUsage,
+		Message:    fmt.Sprintf("message template '%s' is missing variables: %s", name, strings.Join(missing, ", ")),
+		Suggestion: fmt.Sprintf("pass %s", strings.Join(vars, " ")),
+	}
+}
+
+// InvalidSchedule creates an error for an unparseable send-message --schedule value
+func InvalidSchedule(value string) *CLIError {
+	return &CLIError{
+		Category:   CategoryUsage,
+		Message:    fmt.Sprintf("invalid --schedule time: %s", value),
+		Suggestion: "use a delay like '+2h', '+30m' or '+1d', or a local time like '2024-01-15 09:00'",
+	}
+}
+
+// InvalidTime creates an error for an unparseable time value
+func InvalidTime(value string) *CLIError {
+	return &CLIError{
+		Category:   CategoryUsage,
+		Message:    fmt.Sprintf("invalid time: %s", value),
+		Suggestion: "use a time like '15:04:05' (today), '2006-01-02T15:04:05Z' (RFC3339), or '30m ago'",
+	}
+}
+
+// BranchArchiveFailed creates an error for a branch that could not be bundled before removal
+func BranchArchiveFailed(branch string, cause error) *CLIError {
+	return &CLIError{
+		Category:   CategoryRuntime,
+		Message:    fmt.Sprintf("failed to archive branch '%s'", branch),
+		Cause:      cause,
+		Suggestion: "pass --force to remove it anyway, or --no-archive to skip archiving",
+	}
+}
+
+// ArchiveNotFound creates an error for a branch bundle that does not exist
+func ArchiveNotFound(name string) *CLIError {
+	return &CLIError{
+		Category:   CategoryNotFound,
+		Message:    fmt.Sprintf("archive '%s' not found", name),
+		Suggestion: "multiclaude work archives list",
+	}
+}
+
+// SubmoduleUpdateFailed creates an error for a new worktree whose git
+// submodules could not be checked out
+func SubmoduleUpdateFailed(cause error) *CLIError {
+	suggestion := "configure credentials for the submodules' remotes (e.g. a git credential helper or SSH key) so `git submodule update` works without prompting, or pass --no-submodules to skip them"
+	if cause != nil && strings.Contains(cause.Error(), "timed out") {
+		suggestion = "raise the timeout with: multiclaude config <repo> --submodule-timeout=<duration>, or pass --no-submodules to skip them"
+	}
+	return &CLIError{
+		Category:   CategoryRuntime,
+		Message:    "failed to check out git submodules in the new worktree",
+		Cause:      cause,
+		Suggestion: suggestion,
+	}
+}
@@ -191,6 +191,34 @@ func TestClaudeNotFound(t *testing.T) {
 	}
 }
 
+func TestPinnedClaudeUnavailable(t *testing.T) {
+	err := PinnedClaudeUnavailable("my-repo", errors.New("no such file"))
+	formatted := Format(err)
+
+	if !strings.Contains(formatted, "pinned for 'my-repo'") {
+		t.Errorf("expected repo name, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "--pin-claude-path=") {
+		t.Errorf("expected unpin suggestion, got: %s", formatted)
+	}
+}
+
+func TestTemplateUnavailable(t *testing.T) {
+	cause := errors.New("repository not found")
+	err := TemplateUnavailable("https://github.com/org/template", cause)
+
+	if err.Category != CategoryConfig {
+		t.Errorf("expected CategoryConfig, got %v", err.Category)
+	}
+	if err.Cause != cause {
+		t.Error("should wrap cause")
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "git ls-remote https://github.com/org/template") {
+		t.Errorf("expected ls-remote hint, got: %s", formatted)
+	}
+}
+
 func TestMissingArgument(t *testing.T) {
 	err := MissingArgument("repo", "string")
 	formatted := Format(err)
@@ -230,6 +258,20 @@ func TestUnknownCommand(t *testing.T) {
 	}
 }
 
+func TestUnknownFlag(t *testing.T) {
+	formatted := Format(UnknownFlag("--repository", "--repo", "multiclaude work list"))
+	if !strings.Contains(formatted, "unknown flag --repository, did you mean --repo?") {
+		t.Errorf("expected the flag and its likely fix, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "multiclaude work list --help") {
+		t.Errorf("expected the command's help as suggestion, got: %s", formatted)
+	}
+
+	if formatted := Format(UnknownFlag("--bogus", "", "multiclaude work")); strings.Contains(formatted, "did you mean") {
+		t.Errorf("expected no guess without a close flag, got: %s", formatted)
+	}
+}
+
 func TestWithSuggestion_Chaining(t *testing.T) {
 	err := New(CategoryRuntime, "failed").WithSuggestion("try again")
 
@@ -426,6 +468,25 @@ func TestGitOperationFailed(t *testing.T) {
 	}
 }
 
+func TestStageFailed(t *testing.T) {
+	cause := errors.New("exit status 128")
+	formatted := Format(StageFailed("clone", GitOperationFailed("clone", cause)))
+	if !strings.Contains(formatted, "clone stage failed: git clone failed: exit status 128") {
+		t.Errorf("expected the stage and the git error, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "Try: ") {
+		t.Errorf("expected the git error's suggestion, got: %s", formatted)
+	}
+
+	err := StageFailed("prompts", cause)
+	if err.Category != CategoryRuntime || err.Cause != cause {
+		t.Errorf("StageFailed() of a plain error = %+v", err)
+	}
+	if got := Format(err); got != "Error: prompts stage failed: exit status 128" {
+		t.Errorf("Format() = %q", got)
+	}
+}
+
 func TestNotInAgentContext(t *testing.T) {
 	err := NotInAgentContext()
 
@@ -644,6 +705,43 @@ func TestAgentNotInState(t *testing.T) {
 	}
 }
 
+func TestGroupNotFound(t *testing.T) {
+	err := GroupNotFound("backend")
+
+	if err.Category != CategoryNotFound {
+		t.Errorf("expected CategoryNotFound, got %v", err.Category)
+	}
+	if !strings.Contains(Format(err), "backend") {
+		t.Errorf("expected group name in message, got: %s", Format(err))
+	}
+	if err.Suggestion != "multiclaude group list" {
+		t.Errorf("unexpected suggestion: %s", err.Suggestion)
+	}
+}
+
+func TestAgentNotRegistered(t *testing.T) {
+	err := AgentNotRegistered("worker-1", "my-repo")
+
+	if err.Category != CategoryNotFound {
+		t.Errorf("expected CategoryNotFound, got %v", err.Category)
+	}
+	if !strings.Contains(err.Suggestion, "send-message supervisor") {
+		t.Errorf("expected a suggestion to ask the supervisor, got: %s", err.Suggestion)
+	}
+	if code := ExitCode(err); code != ExitNotRegistered {
+		t.Errorf("ExitCode() = %d, want %d", code, ExitNotRegistered)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if code := ExitCode(errors.New("plain")); code != 1 {
+		t.Errorf("ExitCode() of a plain error = %d, want 1", code)
+	}
+	if code := ExitCode(NoSessionID("worker-1")); code != 1 {
+		t.Errorf("ExitCode() of a CLIError without a code = %d, want 1", code)
+	}
+}
+
 func TestNoSessionID(t *testing.T) {
 	err := NoSessionID("worker-1")
 
@@ -688,6 +786,54 @@ func TestInvalidDuration(t *testing.T) {
 	}
 }
 
+func TestMessageTemplateNotFound(t *testing.T) {
+	err := MessageTemplateNotFound("deploy")
+
+	if err.Category != CategoryNotFound {
+		t.Errorf("expected CategoryNotFound, got %v", err.Category)
+	}
+
+	formatted := Format(err)
+	if !strings.Contains(formatted, "deploy") {
+		t.Errorf("expected template name in message, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "message-templates") {
+		t.Errorf("expected suggestion to list templates, got: %s", formatted)
+	}
+}
+
+func TestMissingTemplateVariables(t *testing.T) {
+	err := MissingTemplateVariables("deploy", []string{"service", "env"})
+
+	if err.Category != CategoryUsage {
+		t.Errorf("expected CategoryUsage, got %v", err.Category)
+	}
+
+	formatted := Format(err)
+	if !strings.Contains(formatted, "missing variables: service, env") {
+		t.Errorf("expected missing variables in message, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "--var service=<value> --var env=<value>") {
+		t.Errorf("expected --var suggestion, got: %s", formatted)
+	}
+}
+
+func TestInvalidTime(t *testing.T) {
+	err := InvalidTime("yesterday")
+
+	if err.Category != CategoryUsage {
+		t.Errorf("expected CategoryUsage, got %v", err.Category)
+	}
+
+	formatted := Format(err)
+	if !strings.Contains(formatted, "invalid time: yesterday") {
+		t.Errorf("expected value in message, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "30m ago") {
+		t.Errorf("expected relative example in suggestion, got: %s", formatted)
+	}
+}
+
 func TestMissingArgumentHasSuggestion(t *testing.T) {
 	err := MissingArgument("filename", "string")
 
@@ -709,3 +855,245 @@ func TestInvalidArgumentHasSuggestion(t *testing.T) {
 		t.Errorf("expected --help in suggestion, got: %s", err.Suggestion)
 	}
 }
+
+func TestInvalidAgentName(t *testing.T) {
+	err := InvalidAgentName("supervisor", "name is reserved")
+
+	if err.Category != CategoryUsage {
+		t.Errorf("expected CategoryUsage, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "supervisor") || !strings.Contains(formatted, "reserved") {
+		t.Errorf("expected name and reason in message, got: %s", formatted)
+	}
+	if err.Suggestion == "" {
+		t.Error("should have a suggestion")
+	}
+}
+
+func TestAgentAlreadyExists(t *testing.T) {
+	err := AgentAlreadyExists("worker-1", "my-repo")
+
+	if err.Category != CategoryUsage {
+		t.Errorf("expected CategoryUsage, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "worker-1") || !strings.Contains(formatted, "my-repo") {
+		t.Errorf("expected agent and repo in message, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "--name") {
+		t.Errorf("expected --name hint in suggestion, got: %s", formatted)
+	}
+}
+
+func TestWorkerLimitReached(t *testing.T) {
+	err := WorkerLimitReached("my-repo", 5, 5)
+
+	if err.Category != CategoryRuntime {
+		t.Errorf("expected CategoryRuntime, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "my-repo") || !strings.Contains(formatted, "5/5") {
+		t.Errorf("expected repo and count in message, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "daemon throttle") {
+		t.Errorf("expected throttle hint in suggestion, got: %s", formatted)
+	}
+}
+
+func TestEphemeralLimitReached(t *testing.T) {
+	err := EphemeralLimitReached("my-repo", 2, 2)
+
+	if err.Category != CategoryRuntime {
+		t.Errorf("expected CategoryRuntime, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "my-repo") || !strings.Contains(formatted, "2/2") {
+		t.Errorf("expected repo and count in message, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "--ephemeral") {
+		t.Errorf("expected ephemeral throttle hint in suggestion, got: %s", formatted)
+	}
+}
+
+func TestWorktreeLimitReached(t *testing.T) {
+	err := WorktreeLimitReached("my-repo", 3, 3)
+
+	if err.Category != CategoryRuntime {
+		t.Errorf("expected CategoryRuntime, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "my-repo") || !strings.Contains(formatted, "3/3") {
+		t.Errorf("expected repo and count in message, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "multiclaude work rm") {
+		t.Errorf("expected work rm hint in suggestion, got: %s", formatted)
+	}
+}
+
+func TestConfirmationRequired(t *testing.T) {
+	err := ConfirmationRequired("Continue with cleanup?")
+
+	if err.Category != CategoryUsage {
+		t.Errorf("expected CategoryUsage, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "Continue with cleanup?") {
+		t.Errorf("expected prompt in message, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "--yes") || !strings.Contains(formatted, "MULTICLAUDE_ASSUME_YES") {
+		t.Errorf("expected --yes hint in suggestion, got: %s", formatted)
+	}
+}
+
+func TestWizardNeedsTerminal(t *testing.T) {
+	err := WizardNeedsTerminal()
+
+	if err.Category != CategoryUsage {
+		t.Errorf("expected CategoryUsage, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "--wizard") {
+		t.Errorf("expected --wizard in message, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "--no-merge-queue") {
+		t.Errorf("expected flag hint in suggestion, got: %s", formatted)
+	}
+}
+
+func TestInvalidSchedule(t *testing.T) {
+	err := InvalidSchedule("tomorrow-ish")
+
+	if err.Category != CategoryUsage {
+		t.Errorf("expected CategoryUsage, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "tomorrow-ish") {
+		t.Errorf("expected value in message, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "+2h") {
+		t.Errorf("expected format hint in suggestion, got: %s", formatted)
+	}
+}
+
+func TestNoCommitsForPR(t *testing.T) {
+	err := NoCommitsForPR("workspace/dev", "origin/main")
+
+	if err.Category != CategoryUsage {
+		t.Errorf("expected CategoryUsage, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "workspace/dev") || !strings.Contains(formatted, "origin/main") {
+		t.Errorf("expected branch and base in message, got: %s", formatted)
+	}
+}
+
+func TestNoPRForWorkspace(t *testing.T) {
+	err := NoPRForWorkspace("dev", "my-repo")
+
+	if err.Category != CategoryNotFound {
+		t.Errorf("expected CategoryNotFound, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "create-pr dev") {
+		t.Errorf("expected create-pr hint in suggestion, got: %s", formatted)
+	}
+}
+
+func TestPossibleDuplicateWorker(t *testing.T) {
+	err := PossibleDuplicateWorker("happy-fox", "my-repo")
+
+	if err.Category != CategoryUsage {
+		t.Errorf("expected CategoryUsage, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "multiclaude attach happy-fox") || !strings.Contains(formatted, "--allow-duplicate") {
+		t.Errorf("expected attach command and --allow-duplicate hint, got: %s", formatted)
+	}
+}
+
+func TestWorkspaceBranchNotFound(t *testing.T) {
+	err := WorkspaceBranchNotFound("dev", "my-repo")
+
+	if err.Category != CategoryNotFound {
+		t.Errorf("expected CategoryNotFound, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "workspace/dev") || !strings.Contains(formatted, "workspace list --repo my-repo") {
+		t.Errorf("expected branch name and list hint, got: %s", formatted)
+	}
+}
+
+func TestDaemonMustBeStopped(t *testing.T) {
+	err := DaemonMustBeStopped("migrating paths", 4242)
+
+	if err.Category != CategoryRuntime {
+		t.Errorf("expected CategoryRuntime, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "4242") || !strings.Contains(formatted, "multiclaude daemon stop") {
+		t.Errorf("expected PID and stop suggestion, got: %s", formatted)
+	}
+}
+
+func TestGhNotInstalled(t *testing.T) {
+	err := GhNotInstalled(errors.New("executable file not found in $PATH"))
+
+	if err.Category != CategoryConfig {
+		t.Errorf("expected CategoryConfig, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "gh auth login") {
+		t.Errorf("expected install hint in suggestion, got: %s", formatted)
+	}
+}
+
+func TestWorkspaceHasUncommittedChanges(t *testing.T) {
+	err := WorkspaceHasUncommittedChanges("dev")
+
+	if err.Category != CategoryUsage {
+		t.Errorf("expected CategoryUsage, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "'dev'") || !strings.Contains(formatted, "stash") {
+		t.Errorf("expected workspace name and stash hint, got: %s", formatted)
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	err := MergeConflict("work/fix-bug", "dev", []string{"a.go", "b.go"}, errors.New("conflict"))
+
+	if err.Category != CategoryRuntime {
+		t.Errorf("expected CategoryRuntime, got %v", err.Category)
+	}
+	formatted := Format(err)
+	if !strings.Contains(formatted, "work/fix-bug") || !strings.Contains(formatted, "2 file(s)") || !strings.Contains(formatted, "--strategy") {
+		t.Errorf("expected branch, conflict count and strategy hint, got: %s", formatted)
+	}
+}
+
+func TestPredicateExitCodes(t *testing.T) {
+	no := PredicateNo()
+	if code := ExitCode(no); code != ExitNo {
+		t.Errorf("ExitCode() of PredicateNo = %d, want %d", code, ExitNo)
+	}
+	if msg := Format(no); msg != "" {
+		t.Errorf("Format() of PredicateNo = %q, want nothing", msg)
+	}
+	if Undetermined(no) != no {
+		t.Error("Undetermined() should leave the answer no unchanged")
+	}
+	if Undetermined(nil) != nil {
+		t.Error("Undetermined(nil) should be nil")
+	}
+
+	for _, err := range []error{errors.New("plain"), InvalidUsage("bad arguments")} {
+		undetermined := Undetermined(err)
+		if code := ExitCode(undetermined); code != ExitUndetermined {
+			t.Errorf("ExitCode() of Undetermined(%v) = %d, want %d", err, code, ExitUndetermined)
+		}
+		if Format(undetermined) == "" {
+			t.Errorf("Format() of Undetermined(%v) should explain the error", err)
+		}
+	}
+}
@@ -20,16 +20,20 @@ const (
 	StatusWarning   Status = "warning"
 	StatusError     Status = "error"
 	StatusPending   Status = "pending"
+	// StatusQuarantined is an agent the daemon stopped restarting because
+	// it kept crashing
+	StatusQuarantined Status = "quarantined"
 )
 
 // Colors for different statuses
 var (
-	Green  = color.New(color.FgGreen)
-	Yellow = color.New(color.FgYellow)
-	Red    = color.New(color.FgRed)
-	Cyan   = color.New(color.FgCyan)
-	Bold   = color.New(color.Bold)
-	Dim    = color.New(color.Faint)
+	Green   = color.New(color.FgGreen)
+	Yellow  = color.New(color.FgYellow)
+	Red     = color.New(color.FgRed)
+	Cyan    = color.New(color.FgCyan)
+	Magenta = color.New(color.FgMagenta)
+	Bold    = color.New(color.Bold)
+	Dim     = color.New(color.Faint)
 )
 
 // StatusColor returns the appropriate color for a status
@@ -39,7 +43,7 @@ func StatusColor(status Status) *color.Color {
 		return Green
 	case StatusWarning, StatusIdle, StatusPending:
 		return Yellow
-	case StatusError:
+	case StatusError, StatusQuarantined:
 		return Red
 	default:
 		return color.New()
@@ -61,6 +65,8 @@ func StatusIcon(status Status) string {
 		return "✗"
 	case StatusPending:
 		return "◦"
+	case StatusQuarantined:
+		return "⊘"
 	default:
 		return "-"
 	}
@@ -291,13 +297,21 @@ func (t *ColoredTable) totalWidth() int {
 	return total
 }
 
-// MessageBadge formats a message count badge
-func MessageBadge(pending, total int) string {
+// MessageBadge formats an agent's message counts: those still to be
+// delivered, those delivered or read but not acknowledged, and the total
+func MessageBadge(undelivered, awaitingAck, total int) string {
 	if total == 0 {
 		return Dim.Sprint("-")
 	}
-	if pending > 0 {
-		return Yellow.Sprintf("%d/%d", pending, total)
+	var parts []string
+	if undelivered > 0 {
+		parts = append(parts, fmt.Sprintf("%d queued", undelivered))
+	}
+	if awaitingAck > 0 {
+		parts = append(parts, fmt.Sprintf("%d unacked", awaitingAck))
+	}
+	if len(parts) == 0 {
+		return Dim.Sprintf("%d acked", total)
 	}
-	return Dim.Sprintf("0/%d", total)
+	return Yellow.Sprintf("%s of %d", strings.Join(parts, ", "), total)
 }
@@ -18,6 +18,7 @@ func TestStatusColor(t *testing.T) {
 		{StatusIdle, false},
 		{StatusPending, false},
 		{StatusError, false},
+		{StatusQuarantined, false},
 		{Status("unknown"), false},
 	}
 
@@ -46,6 +47,7 @@ func TestStatusIcon(t *testing.T) {
 		{StatusWarning, "⚠"},
 		{StatusError, "✗"},
 		{StatusPending, "◦"},
+		{StatusQuarantined, "⊘"},
 		{Status("unknown"), "-"},
 	}
 
@@ -207,23 +209,24 @@ func TestColoredStatus(t *testing.T) {
 
 func TestMessageBadge(t *testing.T) {
 	tests := []struct {
-		name     string
-		pending  int
-		total    int
-		contains string // substring that should be in result
+		name        string
+		undelivered int
+		awaitingAck int
+		total       int
+		contains    string // substring that should be in result
 	}{
-		{"no messages", 0, 0, "-"},
-		{"no pending", 0, 5, "0/5"},
-		{"some pending", 2, 5, "2/5"},
-		{"all pending", 5, 5, "5/5"},
-		{"single pending", 1, 1, "1/1"},
+		{"no messages", 0, 0, 0, "-"},
+		{"all acked", 0, 0, 5, "5 acked"},
+		{"queued", 2, 0, 5, "2 queued of 5"},
+		{"awaiting ack", 0, 3, 5, "3 unacked of 5"},
+		{"both", 1, 2, 5, "1 queued, 2 unacked of 5"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := MessageBadge(tt.pending, tt.total)
+			got := MessageBadge(tt.undelivered, tt.awaitingAck, tt.total)
 			if !strings.Contains(got, tt.contains) {
-				t.Errorf("MessageBadge(%d, %d) = %q, want to contain %q", tt.pending, tt.total, got, tt.contains)
+				t.Errorf("MessageBadge(%d, %d, %d) = %q, want to contain %q", tt.undelivered, tt.awaitingAck, tt.total, got, tt.contains)
 			}
 		})
 	}
@@ -311,3 +314,16 @@ func TestTableEmpty(t *testing.T) {
 		t.Error("Empty table should have separator")
 	}
 }
+
+func TestTerminalWidth(t *testing.T) {
+	t.Setenv("COLUMNS", "132")
+	if got := TerminalWidth(); got != 132 {
+		t.Errorf("TerminalWidth() with COLUMNS=132 = %d", got)
+	}
+
+	// Tests don't run with a terminal on stdout
+	t.Setenv("COLUMNS", "")
+	if got := TerminalWidth(); got <= 0 {
+		t.Errorf("TerminalWidth() = %d, want a positive width", got)
+	}
+}
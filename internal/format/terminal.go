@@ -0,0 +1,32 @@
+package format
+
+import (
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultTerminalWidth is used when the terminal width cannot be determined
+const DefaultTerminalWidth = 80
+
+// TerminalWidth returns the width of the terminal attached to stdout.
+// $COLUMNS takes precedence; when stdout is not a terminal (e.g. piped),
+// DefaultTerminalWidth is returned.
+func TerminalWidth() int {
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 {
+		return DefaultTerminalWidth
+	}
+	return int(ws.Col)
+}
+
+// IsTerminal reports whether f is a terminal, e.g. to decide between
+// redrawing progress in place and logging it line by line
+func IsTerminal(f *os.File) bool {
+	_, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	return err == nil
+}
@@ -0,0 +1,217 @@
+// Package gh runs the GitHub CLI. Whether gh is installed and logged in is
+// checked once and remembered, so that commands needing gh can fail fast
+// with instructions, and background features can turn themselves off,
+// instead of every call failing the same way.
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dlorenc/multiclaude/internal/cmdrun"
+)
+
+// ErrGHUnavailable is matched by the errors returned when gh is not
+// installed or not logged in
+var ErrGHUnavailable = goerrors.New("the GitHub CLI (gh) is unavailable")
+
+// UnavailableError says why gh cannot be used
+type UnavailableError struct {
+	// NotInstalled is set when gh is not in PATH; otherwise gh auth status
+	// failed
+	NotInstalled bool
+	Err          error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrGHUnavailable, e.Reason())
+}
+
+// Unwrap returns the failed PATH lookup or gh auth status
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, ErrGHUnavailable) match
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrGHUnavailable
+}
+
+// Reason is a short description of what is wrong, for status output
+func (e *UnavailableError) Reason() string {
+	if e.NotInstalled {
+		return "not installed"
+	}
+	return "not authenticated"
+}
+
+var (
+	checkMu sync.Mutex
+	// checked holds the result of gh auth status for each gh binary
+	checked = make(map[string]error)
+)
+
+// Check returns nil if gh is in PATH and logged in, or an *UnavailableError.
+// gh auth status runs once for each gh binary found; later calls reuse its
+// result.
+func Check(ctx context.Context) error {
+	path, err := exec.LookPath("gh")
+	if err != nil {
+		return &UnavailableError{NotInstalled: true, Err: err}
+	}
+
+	checkMu.Lock()
+	defer checkMu.Unlock()
+	if err, ok := checked[path]; ok {
+		return err
+	}
+	_, _, err = cmdrun.Run(exec.CommandContext(ctx, path, "auth", "status"))
+	if err != nil {
+		// A cancelled check says nothing about gh
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err = &UnavailableError{Err: err}
+	}
+	checked[path] = err
+	return err
+}
+
+// Reset forgets the results of Check, e.g. after logging in
+func Reset() {
+	checkMu.Lock()
+	defer checkMu.Unlock()
+	checked = make(map[string]error)
+}
+
+// Run runs gh with args in dir ("" for the current directory) after Check,
+// returning its stdout. Failures are classified by cmdrun.
+func Run(ctx context.Context, dir string, args ...string) (string, error) {
+	if err := Check(ctx); err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Dir = dir
+	return cmdrun.Output(cmd)
+}
+
+// Repo picks the repository a pr command works on: Slug ("owner/name")
+// when set, otherwise the clone in Dir
+type Repo struct {
+	Dir  string
+	Slug string
+}
+
+func (r Repo) run(ctx context.Context, args ...string) (string, error) {
+	if r.Slug != "" {
+		args = append(args, "--repo", r.Slug)
+	}
+	return Run(ctx, r.Dir, args...)
+}
+
+// runJSON runs a gh command that prints JSON and decodes it into out
+func (r Repo) runJSON(ctx context.Context, out interface{}, args ...string) error {
+	output, err := r.run(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(output), out); err != nil {
+		return fmt.Errorf("failed to parse gh %s output: %w", strings.Join(args[:2], " "), err)
+	}
+	return nil
+}
+
+// PRView decodes gh pr view --json fields for pr (a number, URL or branch)
+// into out
+func PRView(ctx context.Context, repo Repo, pr, fields string, out interface{}) error {
+	return repo.runJSON(ctx, out, "pr", "view", pr, "--json", fields)
+}
+
+// PRViewWeb opens pr in the browser
+func PRViewWeb(ctx context.Context, repo Repo, pr string) error {
+	_, err := repo.run(ctx, "pr", "view", pr, "--web")
+	return err
+}
+
+// PRList decodes gh pr list --json fields into out, which should point to
+// a slice. filter holds further gh pr list flags, e.g. --head branch.
+func PRList(ctx context.Context, repo Repo, fields string, out interface{}, filter ...string) error {
+	args := append([]string{"pr", "list", "--json", fields}, filter...)
+	return repo.runJSON(ctx, out, args...)
+}
+
+// PRCreateOptions are the gh pr create flags multiclaude uses
+type PRCreateOptions struct {
+	Head, Base  string
+	Title, Body string
+	Draft       bool
+}
+
+// PRCreate opens a pull request and returns its URL
+func PRCreate(ctx context.Context, repo Repo, opts PRCreateOptions) (string, error) {
+	args := []string{"pr", "create", "--head", opts.Head, "--base", opts.Base, "--title", opts.Title, "--body", opts.Body}
+	if opts.Draft {
+		args = append(args, "--draft")
+	}
+	output, err := repo.run(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	// gh prints the PR URL as the last line of its output
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1]), nil
+}
+
+// RepoView decodes gh repo view --json fields for slug ("owner/name") into
+// out
+func RepoView(ctx context.Context, slug, fields string, out interface{}) error {
+	return Repo{}.runJSON(ctx, out, "repo", "view", slug, "--json", fields)
+}
+
+// API decodes the response to a GET of a GitHub REST API path, e.g.
+// repos/owner/name, into out
+func API(ctx context.Context, path string, out interface{}) error {
+	return Repo{}.runJSON(ctx, out, "api", path)
+}
+
+// GraphQL runs a GitHub GraphQL query with variables vars and decodes the
+// data of the response into out. String variables are passed as they are,
+// others (e.g. numbers) are typed by gh.
+func GraphQL(ctx context.Context, query string, vars map[string]interface{}, out interface{}) error {
+	args := []string{"api", "graphql", "-f", "query=" + query}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if s, ok := vars[name].(string); ok {
+			args = append(args, "-f", name+"="+s)
+		} else {
+			args = append(args, "-F", fmt.Sprintf("%s=%v", name, vars[name]))
+		}
+	}
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := (Repo{}).runJSON(ctx, &resp, args...); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("GitHub GraphQL query failed: %s", resp.Errors[0].Message)
+	}
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		return fmt.Errorf("failed to parse gh api graphql output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package gh
+
+import (
+	"context"
+	goerrors "errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// installFakeGH puts a gh shell script with body first in PATH, leaving
+// only it and the shell's basics available. Calls are logged to the
+// returned file, one line of arguments per call.
+func installFakeGH(t *testing.T, body string) (callsFile string) {
+	t.Helper()
+	Reset()
+	t.Cleanup(Reset)
+
+	binDir := t.TempDir()
+	callsFile = filepath.Join(binDir, "calls")
+	script := "#!/bin/sh\necho \"$@\" >> " + callsFile + "\n" + body
+	if err := os.WriteFile(filepath.Join(binDir, "gh"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake gh: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+"/usr/bin:/bin")
+	return callsFile
+}
+
+func readCalls(t *testing.T, callsFile string) []string {
+	t.Helper()
+	data, err := os.ReadFile(callsFile)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to read calls: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestCheckMissing(t *testing.T) {
+	Reset()
+	t.Setenv("PATH", t.TempDir())
+
+	err := Check(context.Background())
+	var unavailable *UnavailableError
+	if !goerrors.As(err, &unavailable) || !unavailable.NotInstalled {
+		t.Fatalf("Check() = %v, want gh not installed", err)
+	}
+	if !goerrors.Is(err, ErrGHUnavailable) || unavailable.Reason() != "not installed" {
+		t.Errorf("Check() = %v (%s), want it to match ErrGHUnavailable", err, unavailable.Reason())
+	}
+
+	var pr struct{ State string }
+	if err := PRView(context.Background(), Repo{Slug: "acme/widgets"}, "1", "state", &pr); !goerrors.Is(err, ErrGHUnavailable) {
+		t.Errorf("PRView() without gh = %v, want ErrGHUnavailable", err)
+	}
+}
+
+func TestCheckUnauthenticated(t *testing.T) {
+	callsFile := installFakeGH(t, `if [ "$1" = auth ]; then
+  echo "You are not logged into any GitHub hosts. To log in, run: gh auth login" >&2
+  exit 1
+fi
+echo '{}'
+`)
+
+	for i := 0; i < 2; i++ {
+		err := Check(context.Background())
+		var unavailable *UnavailableError
+		if !goerrors.As(err, &unavailable) || unavailable.NotInstalled || unavailable.Reason() != "not authenticated" {
+			t.Fatalf("Check() = %v, want gh not authenticated", err)
+		}
+	}
+	if _, err := Run(context.Background(), "", "pr", "list"); !goerrors.Is(err, ErrGHUnavailable) {
+		t.Errorf("Run() = %v, want ErrGHUnavailable", err)
+	}
+
+	// gh auth status ran once and nothing else ran
+	if calls := readCalls(t, callsFile); len(calls) != 1 || calls[0] != "auth status" {
+		t.Errorf("gh calls = %q, want a single auth status", calls)
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	callsFile := installFakeGH(t, `case "$1 $2" in
+"pr view") echo '{"state":"MERGED"}' ;;
+"pr list") echo '[{"number":7}]' ;;
+"pr create") echo "Creating pull request"; echo "https://github.com/acme/widgets/pull/8" ;;
+"api repos/acme/widgets") echo '{"full_name":"acme/gadgets"}' ;;
+"api graphql") echo '{"data":{"viewer":{"login":"octocat"}}}' ;;
+"repo view") echo "repository not found" >&2; exit 1 ;;
+esac
+`)
+	ctx := context.Background()
+
+	var pr struct {
+		State string `json:"state"`
+	}
+	if err := PRView(ctx, Repo{Slug: "acme/widgets"}, "7", "state", &pr); err != nil || pr.State != "MERGED" {
+		t.Errorf("PRView() = %+v, %v", pr, err)
+	}
+
+	var prs []struct {
+		Number int `json:"number"`
+	}
+	if err := PRList(ctx, Repo{Dir: t.TempDir()}, "number", &prs, "--head", "feature"); err != nil || len(prs) != 1 || prs[0].Number != 7 {
+		t.Errorf("PRList() = %+v, %v", prs, err)
+	}
+
+	url, err := PRCreate(ctx, Repo{}, PRCreateOptions{Head: "feature", Base: "main", Title: "Add it", Body: "Body", Draft: true})
+	if err != nil || url != "https://github.com/acme/widgets/pull/8" {
+		t.Errorf("PRCreate() = %q, %v", url, err)
+	}
+
+	var repo struct {
+		FullName string `json:"full_name"`
+	}
+	if err := API(ctx, "repos/acme/widgets", &repo); err != nil || repo.FullName != "acme/gadgets" {
+		t.Errorf("API() = %+v, %v", repo, err)
+	}
+
+	var viewer struct {
+		Viewer struct {
+			Login string `json:"login"`
+		} `json:"viewer"`
+	}
+	vars := map[string]interface{}{"owner": "acme", "number": 7}
+	if err := GraphQL(ctx, "query { viewer { login } }", vars, &viewer); err != nil || viewer.Viewer.Login != "octocat" {
+		t.Errorf("GraphQL() = %+v, %v", viewer, err)
+	}
+
+	// Other failures are not gh being unavailable
+	if err := RepoView(ctx, "acme/missing", "name", &repo); err == nil || goerrors.Is(err, ErrGHUnavailable) {
+		t.Errorf("RepoView() of a missing repository = %v, want a plain failure", err)
+	}
+
+	want := []string{
+		"auth status",
+		"pr view 7 --json state --repo acme/widgets",
+		"pr list --json number --head feature",
+		"pr create --head feature --base main --title Add it --body Body --draft",
+		"api repos/acme/widgets",
+		"api graphql -f query=query { viewer { login } } -F number=7 -f owner=acme",
+		"repo view acme/missing --json name",
+	}
+	if calls := readCalls(t, callsFile); strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("gh calls =\n%s\nwant\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+}
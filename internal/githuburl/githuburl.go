@@ -0,0 +1,73 @@
+// Package githuburl parses the GitHub repository URL forms multiclaude accepts.
+package githuburl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// prefixes are the recognized GitHub URL prefixes, lowercased
+var prefixes = []string{
+	"git@github.com:",
+	"https://github.com/",
+	"http://github.com/",
+	"git://github.com/",
+}
+
+// Normalize normalizes GitHub URLs to a common format for comparison.
+// It handles SSH (git@github.com:user/repo.git), HTTPS, HTTP and git:// formats.
+// Returns lowercase "github.com/user/repo" format, or "" for non-GitHub URLs.
+func Normalize(url string) string {
+	path, ok := repoPath(url)
+	if !ok {
+		return ""
+	}
+	return strings.ToLower("github.com/" + path)
+}
+
+// Parse extracts the owner and repository name from a GitHub URL, preserving case
+func Parse(url string) (owner, repo string, err error) {
+	path, ok := repoPath(url)
+	if !ok {
+		return "", "", fmt.Errorf("not a GitHub repository URL: %q", url)
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("GitHub URL must be of the form https://github.com/<owner>/<repo>: %q", url)
+	}
+	return parts[0], parts[1], nil
+}
+
+// ParsePR extracts the owner, repository name and number from the URL of
+// a pull request, e.g. https://github.com/owner/repo/pull/123
+func ParsePR(url string) (owner, repo string, number int, err error) {
+	repoURL, num, found := strings.Cut(strings.TrimSuffix(strings.TrimSpace(url), "/"), "/pull/")
+	if !found {
+		return "", "", 0, fmt.Errorf("not a GitHub pull request URL: %q", url)
+	}
+	number, err = strconv.Atoi(num)
+	if err != nil || number <= 0 {
+		return "", "", 0, fmt.Errorf("not a GitHub pull request URL: %q", url)
+	}
+	owner, repo, err = Parse(repoURL)
+	if err != nil {
+		return "", "", 0, err
+	}
+	return owner, repo, number, nil
+}
+
+// repoPath returns the path after the host, without a trailing slash or .git
+func repoPath(url string) (string, bool) {
+	url = strings.TrimSpace(url)
+	lowerURL := strings.ToLower(url)
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(lowerURL, prefix) {
+			path := strings.TrimSuffix(url[len(prefix):], "/")
+			return strings.TrimSuffix(path, ".git"), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,128 @@
+package githuburl
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{
+			name: "SSH format",
+			url:  "git@github.com:user/repo.git",
+			want: "github.com/user/repo",
+		},
+		{
+			name: "SSH format without .git",
+			url:  "git@github.com:user/repo",
+			want: "github.com/user/repo",
+		},
+		{
+			name: "HTTPS format",
+			url:  "https://github.com/user/repo",
+			want: "github.com/user/repo",
+		},
+		{
+			name: "HTTPS format with .git",
+			url:  "https://github.com/user/repo.git",
+			want: "github.com/user/repo",
+		},
+		{
+			name: "HTTP format",
+			url:  "http://github.com/user/repo",
+			want: "github.com/user/repo",
+		},
+		{
+			name: "git:// protocol",
+			url:  "git://github.com/user/repo.git",
+			want: "github.com/user/repo",
+		},
+		{
+			name: "mixed case",
+			url:  "https://GitHub.com/User/Repo",
+			want: "github.com/user/repo",
+		},
+		{
+			name: "whitespace trimmed",
+			url:  "  https://github.com/user/repo  ",
+			want: "github.com/user/repo",
+		},
+		{
+			name: "non-GitHub URL",
+			url:  "https://gitlab.com/user/repo",
+			want: "",
+		},
+		{
+			name: "empty string",
+			url:  "",
+			want: "",
+		},
+		{
+			name: "organization repo SSH",
+			url:  "git@github.com:myorg/myproject.git",
+			want: "github.com/myorg/myproject",
+		},
+		{
+			name: "nested path SSH",
+			url:  "git@github.com:user/nested/path.git",
+			want: "github.com/user/nested/path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Normalize(tt.url)
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		url       string
+		wantOwner string
+		wantRepo  string
+		wantErr   bool
+	}{
+		{url: "https://github.com/MyOrg/My-Repo", wantOwner: "MyOrg", wantRepo: "My-Repo"},
+		{url: "https://github.com/owner/repo.git", wantOwner: "owner", wantRepo: "repo"},
+		{url: "https://github.com/owner/repo/", wantOwner: "owner", wantRepo: "repo"},
+		{url: "git@github.com:owner/repo.git", wantOwner: "owner", wantRepo: "repo"},
+		{url: "https://gitlab.com/owner/repo", wantErr: true},
+		{url: "https://github.com/owner", wantErr: true},
+		{url: "https://github.com/owner/repo/pull/1", wantErr: true},
+		{url: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			owner, repo, err := Parse(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+			if owner != tt.wantOwner || repo != tt.wantRepo {
+				t.Errorf("Parse(%q) = %q, %q, want %q, %q", tt.url, owner, repo, tt.wantOwner, tt.wantRepo)
+			}
+		})
+	}
+}
+
+func TestParsePR(t *testing.T) {
+	owner, repo, number, err := ParsePR("https://github.com/MyOrg/My-Repo/pull/42")
+	if err != nil || owner != "MyOrg" || repo != "My-Repo" || number != 42 {
+		t.Errorf("ParsePR() = %q, %q, %d, %v", owner, repo, number, err)
+	}
+	for _, url := range []string{
+		"https://github.com/owner/repo",
+		"https://github.com/owner/repo/pull/",
+		"https://github.com/owner/repo/pull/abc",
+		"https://gitlab.com/owner/repo/pull/1",
+	} {
+		if _, _, _, err := ParsePR(url); err == nil {
+			t.Errorf("ParsePR(%q) should fail", url)
+		}
+	}
+}
@@ -1,4 +1,6 @@
-// Package hooks provides utilities for managing Claude hooks configuration.
+// Package hooks manages the Claude hooks configuration copied into agent
+// worktrees, and runs the lifecycle hooks multiclaude itself executes on the
+// host when workers and repositories come and go.
 package hooks
 
 import (
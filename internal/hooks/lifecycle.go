@@ -0,0 +1,226 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LifecycleConfigFile maps lifecycle events to commands run on the host. It
+// is read from a repository's .multiclaude directory and from the
+// multiclaude config directory, whose hooks apply to every repository.
+const LifecycleConfigFile = "lifecycle-hooks.json"
+
+// DefaultLifecycleTimeout is how long a lifecycle hook may run unless its
+// config file sets a timeout
+const DefaultLifecycleTimeout = 30 * time.Second
+
+// LifecycleTestModeEnv set to 1 runs lifecycle hooks even in
+// MULTICLAUDE_TEST_MODE, where they are skipped otherwise
+const LifecycleTestModeEnv = "MULTICLAUDE_LIFECYCLE_HOOKS"
+
+// Lifecycle events
+const (
+	EventWorkerCreated   = "worker_created"
+	EventWorkerCompleted = "worker_completed"
+	EventAgentRemoved    = "agent_removed"
+	EventRepoInitialized = "repo_initialized"
+)
+
+// LifecycleEvents lists the lifecycle events in the order they happen
+var LifecycleEvents = []string{EventRepoInitialized, EventWorkerCreated, EventWorkerCompleted, EventAgentRemoved}
+
+// IsLifecycleEvent reports whether name is one of LifecycleEvents
+func IsLifecycleEvent(name string) bool {
+	for _, event := range LifecycleEvents {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// LifecycleConfig is the content of LifecycleConfigFile, e.g.
+//
+//	{"timeout": "10s", "hooks": {"worker_completed": ["./scripts/notify.sh"]}}
+type LifecycleConfig struct {
+	Timeout string              `json:"timeout,omitempty"` // Go duration; default DefaultLifecycleTimeout
+	Hooks   map[string][]string `json:"hooks"`             // event -> shell commands, run in order
+}
+
+// Event describes a lifecycle event. Hooks get it as JSON on stdin and as
+// MULTICLAUDE_* environment variables (see Env).
+type Event struct {
+	Name          string    `json:"event"`
+	Repo          string    `json:"repo"`
+	GithubURL     string    `json:"github_url,omitempty"`
+	Agent         string    `json:"agent,omitempty"`
+	AgentType     string    `json:"agent_type,omitempty"`
+	Task          string    `json:"task,omitempty"`
+	Branch        string    `json:"branch,omitempty"`
+	WorktreePath  string    `json:"worktree_path,omitempty"`
+	Summary       string    `json:"summary,omitempty"`        // worker_completed: the completion report
+	FailureReason string    `json:"failure_reason,omitempty"` // worker_completed: set if the task failed
+	PRURL         string    `json:"pr_url,omitempty"`
+	Reason        string    `json:"reason,omitempty"` // agent_removed: removed, or cleaned_up by the daemon
+	Synthetic     bool      `json:"synthetic,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// Env returns the event's fields as MULTICLAUDE_* environment variables,
+// leaving out empty ones
+func (e Event) Env() []string {
+	vars := []struct{ name, value string }{
+		{"MULTICLAUDE_EVENT", e.Name},
+		{"MULTICLAUDE_REPO", e.Repo},
+		{"MULTICLAUDE_GITHUB_URL", e.GithubURL},
+		{"MULTICLAUDE_AGENT", e.Agent},
+		{"MULTICLAUDE_AGENT_TYPE", e.AgentType},
+		{"MULTICLAUDE_TASK", e.Task},
+		{"MULTICLAUDE_BRANCH", e.Branch},
+		{"MULTICLAUDE_WORKTREE", e.WorktreePath},
+		{"MULTICLAUDE_SUMMARY", e.Summary},
+		{"MULTICLAUDE_FAILURE_REASON", e.FailureReason},
+		{"MULTICLAUDE_PR_URL", e.PRURL},
+		{"MULTICLAUDE_REASON", e.Reason},
+		{"MULTICLAUDE_EVENT_TIME", e.Time.Format(time.RFC3339)},
+	}
+	if e.Synthetic {
+		vars = append(vars, struct{ name, value string }{"MULTICLAUDE_SYNTHETIC", "1"})
+	}
+	var env []string
+	for _, v := range vars {
+		if v.value != "" {
+			env = append(env, v.name+"="+v.value)
+		}
+	}
+	return env
+}
+
+// LifecycleHook is a command configured for an event
+type LifecycleHook struct {
+	Command string
+	Source  string // config file the hook came from
+	Dir     string // directory the command runs in
+	Timeout time.Duration
+}
+
+// LifecycleHooksEnabled reports whether lifecycle hooks should run: always,
+// except in MULTICLAUDE_TEST_MODE unless LifecycleTestModeEnv is set
+func LifecycleHooksEnabled() bool {
+	return os.Getenv("MULTICLAUDE_TEST_MODE") != "1" || os.Getenv(LifecycleTestModeEnv) == "1"
+}
+
+// LoadLifecycleHooks returns the hooks for event: those in configDir's
+// LifecycleConfigFile, then those in repoPath's .multiclaude directory.
+// Global hooks run in configDir and repository hooks in repoPath, so
+// scripts can be named relative to either. Either directory may be empty,
+// and a missing file has no hooks. A file that cannot be read or parsed is
+// reported, and the hooks of the other are still returned.
+func LoadLifecycleHooks(configDir, repoPath, event string) ([]LifecycleHook, error) {
+	var hooks []LifecycleHook
+	var errs []string
+	for _, source := range []struct{ path, dir string }{
+		{filepath.Join(configDir, LifecycleConfigFile), configDir},
+		{filepath.Join(repoPath, ".multiclaude", LifecycleConfigFile), repoPath},
+	} {
+		if source.dir == "" {
+			continue
+		}
+		cfg, err := readLifecycleConfig(source.path)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if cfg == nil {
+			continue
+		}
+		timeout := DefaultLifecycleTimeout
+		if cfg.Timeout != "" {
+			timeout, _ = time.ParseDuration(cfg.Timeout)
+		}
+		for _, command := range cfg.Hooks[event] {
+			hooks = append(hooks, LifecycleHook{Command: command, Source: source.path, Dir: source.dir, Timeout: timeout})
+		}
+	}
+	if len(errs) > 0 {
+		return hooks, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return hooks, nil
+}
+
+// readLifecycleConfig reads and checks a LifecycleConfigFile, returning nil
+// if it does not exist
+func readLifecycleConfig(path string) (*LifecycleConfig, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var cfg LifecycleConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	if cfg.Timeout != "" {
+		if d, err := time.ParseDuration(cfg.Timeout); err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid %s: timeout %q is not a positive duration", path, cfg.Timeout)
+		}
+	}
+	for event := range cfg.Hooks {
+		if !IsLifecycleEvent(event) {
+			return nil, fmt.Errorf("invalid %s: unknown event %q (events: %s)", path, event, strings.Join(LifecycleEvents, ", "))
+		}
+	}
+	return &cfg, nil
+}
+
+// LifecycleResult is the outcome of running a lifecycle hook
+type LifecycleResult struct {
+	Hook     LifecycleHook
+	Output   string // stdout and stderr, interleaved
+	Duration time.Duration
+	Err      error // nil if the command exited 0
+}
+
+// RunLifecycleHooks runs hooks one after another with sh -c, each with the
+// event as JSON on stdin and in its environment, and stops each at its
+// timeout. A failing hook does not stop the ones after it.
+func RunLifecycleHooks(ctx context.Context, hooks []LifecycleHook, event Event) []LifecycleResult {
+	payload, _ := json.Marshal(event)
+	results := make([]LifecycleResult, 0, len(hooks))
+	for _, hook := range hooks {
+		results = append(results, runLifecycleHook(ctx, hook, event, payload))
+	}
+	return results
+}
+
+func runLifecycleHook(ctx context.Context, hook LifecycleHook, event Event, payload []byte) LifecycleResult {
+	ctx, cancel := context.WithTimeout(ctx, hook.Timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	cmd.Dir = hook.Dir
+	cmd.Env = append(os.Environ(), event.Env()...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// Don't wait long for children of a killed hook that hold its output open
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	result := LifecycleResult{Hook: hook, Output: output.String(), Duration: time.Since(start), Err: err}
+	if ctx.Err() == context.DeadlineExceeded {
+		result.Err = fmt.Errorf("timed out after %s", hook.Timeout)
+	}
+	return result
+}
@@ -0,0 +1,146 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeLifecycleConfig writes a LifecycleConfigFile into dir
+func writeLifecycleConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", dir, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, LifecycleConfigFile), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write lifecycle hooks config: %v", err)
+	}
+}
+
+func TestLoadLifecycleHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+	configDir := filepath.Join(tmpDir, "config")
+	repoPath := filepath.Join(tmpDir, "repo")
+
+	hooks, err := LoadLifecycleHooks(configDir, repoPath, EventWorkerCreated)
+	if err != nil || len(hooks) != 0 {
+		t.Fatalf("LoadLifecycleHooks() with no config = %v, %v, want no hooks", hooks, err)
+	}
+
+	writeLifecycleConfig(t, configDir, `{"hooks": {"worker_created": ["global.sh"]}}`)
+	writeLifecycleConfig(t, filepath.Join(repoPath, ".multiclaude"),
+		`{"timeout": "5s", "hooks": {"worker_created": ["first.sh", "second.sh"], "agent_removed": ["removed.sh"]}}`)
+
+	hooks, err = LoadLifecycleHooks(configDir, repoPath, EventWorkerCreated)
+	if err != nil {
+		t.Fatalf("LoadLifecycleHooks() error = %v", err)
+	}
+	var commands []string
+	for _, hook := range hooks {
+		commands = append(commands, hook.Command)
+	}
+	if strings.Join(commands, " ") != "global.sh first.sh second.sh" {
+		t.Errorf("hooks = %v, want global ones first, then the repository's in order", commands)
+	}
+	if hooks[0].Dir != configDir || hooks[0].Timeout != DefaultLifecycleTimeout {
+		t.Errorf("global hook = %+v, want it run in %s with the default timeout", hooks[0], configDir)
+	}
+	if hooks[1].Dir != repoPath || hooks[1].Timeout != 5*time.Second {
+		t.Errorf("repository hook = %+v, want it run in %s with a 5s timeout", hooks[1], repoPath)
+	}
+
+	// A broken file is reported, and the other file's hooks still run
+	for _, content := range []string{
+		`{"hooks": {"worker_deleted": ["x.sh"]}}`,
+		`{"timeout": "soon", "hooks": {}}`,
+		`not json`,
+	} {
+		writeLifecycleConfig(t, configDir, content)
+		hooks, err = LoadLifecycleHooks(configDir, repoPath, EventAgentRemoved)
+		if err == nil {
+			t.Errorf("LoadLifecycleHooks() should reject %s", content)
+		}
+		if len(hooks) != 1 || hooks[0].Command != "removed.sh" {
+			t.Errorf("hooks with a broken global config = %+v, want the repository's", hooks)
+		}
+	}
+}
+
+func TestRunLifecycleHooks(t *testing.T) {
+	dir := t.TempDir()
+	event := Event{
+		Name:      EventWorkerCompleted,
+		Repo:      "my-repo",
+		Agent:     "fox",
+		AgentType: "worker",
+		Summary:   "Fixed the flaky test",
+		Time:      time.Now(),
+	}
+	hooks := []LifecycleHook{
+		{Command: `echo "$MULTICLAUDE_EVENT $MULTICLAUDE_AGENT: $MULTICLAUDE_SUMMARY"; cat > payload.json`, Dir: dir, Timeout: 5 * time.Second},
+		{Command: "echo oops >&2; exit 3", Dir: dir, Timeout: 5 * time.Second},
+		{Command: "sleep 5", Dir: dir, Timeout: 100 * time.Millisecond},
+		{Command: "pwd", Dir: dir, Timeout: 5 * time.Second},
+	}
+
+	results := RunLifecycleHooks(context.Background(), hooks, event)
+	if len(results) != len(hooks) {
+		t.Fatalf("got %d results, want %d", len(results), len(hooks))
+	}
+
+	if results[0].Err != nil || results[0].Output != "worker_completed fox: Fixed the flaky test\n" {
+		t.Errorf("first hook = %q, %v", results[0].Output, results[0].Err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "payload.json"))
+	if err != nil {
+		t.Fatalf("hook should have saved its stdin: %v", err)
+	}
+	var payload Event
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Agent != "fox" || payload.Summary != event.Summary {
+		t.Errorf("stdin payload = %s (%v), want the event as JSON", data, err)
+	}
+
+	if results[1].Err == nil || results[1].Output != "oops\n" {
+		t.Errorf("failing hook = %q, %v, want its stderr and an error", results[1].Output, results[1].Err)
+	}
+	if results[2].Err == nil || !strings.Contains(results[2].Err.Error(), "timed out") || results[2].Duration > 3*time.Second {
+		t.Errorf("slow hook = %v after %s, want it stopped at its timeout", results[2].Err, results[2].Duration)
+	}
+	// Failures do not stop the hooks after them
+	if results[3].Err != nil || strings.TrimSpace(results[3].Output) != dir {
+		t.Errorf("last hook = %q, %v, want it run in %s", results[3].Output, results[3].Err, dir)
+	}
+}
+
+func TestLifecycleHooksEnabled(t *testing.T) {
+	t.Setenv("MULTICLAUDE_TEST_MODE", "")
+	t.Setenv(LifecycleTestModeEnv, "")
+	if !LifecycleHooksEnabled() {
+		t.Error("lifecycle hooks should run outside test mode")
+	}
+	t.Setenv("MULTICLAUDE_TEST_MODE", "1")
+	if LifecycleHooksEnabled() {
+		t.Error("lifecycle hooks should be skipped in test mode")
+	}
+	t.Setenv(LifecycleTestModeEnv, "1")
+	if !LifecycleHooksEnabled() {
+		t.Errorf("%s=1 should run lifecycle hooks in test mode", LifecycleTestModeEnv)
+	}
+}
+
+func TestEventEnv(t *testing.T) {
+	env := Event{Name: EventAgentRemoved, Repo: "my-repo", Reason: "removed", Synthetic: true}.Env()
+	joined := strings.Join(env, "\n")
+	for _, want := range []string{"MULTICLAUDE_EVENT=agent_removed", "MULTICLAUDE_REPO=my-repo", "MULTICLAUDE_REASON=removed", "MULTICLAUDE_SYNTHETIC=1"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Env() = %v, missing %s", env, want)
+		}
+	}
+	if strings.Contains(joined, "MULTICLAUDE_TASK") {
+		t.Errorf("Env() = %v, should leave out empty fields", env)
+	}
+}
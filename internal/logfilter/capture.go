@@ -0,0 +1,114 @@
+package logfilter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// PipeCommand returns the shell command output capture pipes a pane to, to
+// append it to logFile: the running binary's filter, or with raw set, the
+// output unchanged
+func PipeCommand(logFile string, raw bool) (string, error) {
+	if raw {
+		return "cat >> " + shellQuote(logFile), nil
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get executable path: %w", err)
+	}
+	return CaptureCommand(executable, logFile), nil
+}
+
+// CaptureCommand returns the shell command tmux pipe-pane runs to capture a
+// pane through the filter: executable, the multiclaude binary, reading the
+// pane's output on stdin and appending it to logFile with Capture.
+func CaptureCommand(executable, logFile string) string {
+	return fmt.Sprintf("%s logs _capture %s", shellQuote(executable), shellQuote(logFile))
+}
+
+// TimeLayout is the timestamp Capture starts each line with: RFC 3339 to the
+// millisecond, so that the lines of several agents' logs can be ordered
+const TimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// Capture filters everything read from r and appends it to the file at
+// path until r ends, each line starting with the time it was captured.
+// When the file is rotated away (renamed or removed), capture continues in
+// a new file at path rather than the rotated one. Each line kept is passed
+// through rewrite, when not nil, before it is written, so that what must
+// not reach the disk (e.g. secrets) never does.
+func Capture(r io.Reader, path string, rewrite func(line string) string) error {
+	out := &appendFile{path: path}
+	defer out.close()
+
+	f := New(&lineWriter{w: out, rewrite: rewrite, now: time.Now})
+	if _, err := io.Copy(f, r); err != nil {
+		f.Flush()
+		return err
+	}
+	return f.Flush()
+}
+
+// lineWriter stamps and rewrites the lines a Filter writes, one per Write,
+// before passing them on
+type lineWriter struct {
+	w       io.Writer
+	rewrite func(line string) string
+	now     func() time.Time
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	line := strings.TrimSuffix(string(p), "\n")
+	if l.rewrite != nil {
+		line = l.rewrite(line)
+	}
+	if _, err := io.WriteString(l.w, l.now().Format(TimeLayout)+" "+line+"\n"); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// appendFile appends to the file at a path, reopening it whenever the path
+// no longer names the file it has open
+type appendFile struct {
+	path string
+	file *os.File
+}
+
+func (a *appendFile) Write(p []byte) (int, error) {
+	if a.file != nil && !a.current() {
+		a.close()
+	}
+	if a.file == nil {
+		file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return 0, err
+		}
+		a.file = file
+	}
+	return a.file.Write(p)
+}
+
+// current reports whether the open file is still the one at the path
+func (a *appendFile) current() bool {
+	open, err := a.file.Stat()
+	if err != nil {
+		return false
+	}
+	named, err := os.Stat(a.path)
+	return err == nil && os.SameFile(open, named)
+}
+
+func (a *appendFile) close() {
+	if a.file != nil {
+		a.file.Close()
+		a.file = nil
+	}
+}
+
+// shellQuote quotes s as a single word for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,242 @@
+// Package logfilter reduces the output tmux captures from an agent's pane to
+// the lines worth keeping. A terminal UI such as Claude's redraws its whole
+// screen many times a second; captured as is, that is mostly cursor
+// movement and line erasing around copies of lines already written. The
+// filter removes the escape sequences, splits the output where the cursor
+// moves, and drops lines that were written recently, leaving what changed.
+package logfilter
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DefaultWindow is how many distinct lines a Filter remembers. A line seen
+// again within that many others is a redraw and is dropped.
+const DefaultWindow = 512
+
+// maxLineLength bounds the line being collected, so output that never
+// breaks a line cannot grow it without limit
+const maxLineLength = 64 * 1024
+
+// maxCursorForward bounds the spaces a cursor-forward sequence stands for
+const maxCursorForward = 256
+
+// spinnerRunes are the glyphs a terminal UI animates at the start of a
+// status line; a line that differs only in these is the same line
+const spinnerRunes = "✻✽✶✳✢·⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏◐◓◑◒"
+
+// parser states, for escape sequences split across writes
+const (
+	stateText = iota
+	stateEscape
+	stateCSI
+	stateString       // OSC, DCS and the like, up to BEL or ESC \
+	stateStringEscape // ESC inside a string, maybe starting its ESC \ end
+	stateCharset      // ESC ( and similar take one more byte
+)
+
+// Stats counts what went into and came out of a Filter
+type Stats struct {
+	BytesIn  int64
+	BytesOut int64
+	Lines    int64 // Lines found in the input
+	Dropped  int64 // Lines dropped as repeats
+}
+
+// Filter is an io.Writer that writes the lines of what it is given, without
+// escape sequences and redraws, to another writer. Lines are written as
+// they end, so the output lags the input by at most one line; Flush writes
+// the last one.
+type Filter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	window int
+	state  int
+	csi    []byte // parameters of the CSI sequence being read
+	line   bytes.Buffer
+	recent map[string]int64 // line key -> when it was last seen
+	seen   int64            // lines seen so far, the clock of recent
+	err    error
+	stats  Stats
+}
+
+// New returns a Filter writing to w that remembers DefaultWindow lines
+func New(w io.Writer) *Filter {
+	return NewWithWindow(w, DefaultWindow)
+}
+
+// NewWithWindow returns a Filter writing to w that remembers window lines
+func NewWithWindow(w io.Writer, window int) *Filter {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Filter{w: w, window: window, recent: make(map[string]int64)}
+}
+
+// Write filters p. It returns the first error from the underlying writer,
+// after which the filter writes nothing more.
+func (f *Filter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return 0, f.err
+	}
+	f.stats.BytesIn += int64(len(p))
+
+	for _, b := range p {
+		switch f.state {
+		case stateText:
+			switch {
+			case b == 0x1b:
+				f.state = stateEscape
+			case b == '\n' || b == '\r':
+				f.endLine()
+			case b == '\t':
+				f.add(' ')
+			case b < 0x20 || b == 0x7f:
+				// Bells, backspaces and other controls
+			default:
+				f.add(b)
+			}
+		case stateEscape:
+			switch b {
+			case '[':
+				f.state, f.csi = stateCSI, f.csi[:0]
+			case ']', 'P', 'X', '^', '_':
+				f.state = stateString
+			case '(', ')', '*', '+', '#', '%':
+				f.state = stateCharset
+			case 'D', 'E', 'M':
+				// Index, next line and reverse index move to another line
+				f.endLine()
+				f.state = stateText
+			default:
+				f.state = stateText
+			}
+		case stateCSI:
+			if b >= 0x40 && b <= 0x7e {
+				f.endCSI(b)
+				f.state = stateText
+			} else if len(f.csi) < 32 {
+				f.csi = append(f.csi, b)
+			}
+		case stateString:
+			switch b {
+			case 0x07:
+				f.state = stateText
+			case 0x1b:
+				f.state = stateStringEscape
+			}
+		case stateStringEscape:
+			if b == '\\' {
+				f.state = stateText
+			} else {
+				f.state = stateString
+			}
+		case stateCharset:
+			f.state = stateText
+		}
+		if f.err != nil {
+			return len(p), f.err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes the line collected so far, if it is one to keep
+func (f *Filter) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err == nil {
+		f.endLine()
+	}
+	return f.err
+}
+
+// Stats returns what the filter has processed so far
+func (f *Filter) Stats() Stats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// add appends a byte of text to the current line
+func (f *Filter) add(b byte) {
+	if f.line.Len() < maxLineLength {
+		f.line.WriteByte(b)
+	}
+}
+
+// endCSI handles the end of a CSI sequence. Sequences that move the cursor
+// to another line or column end the current line, as what follows is
+// drawn somewhere else; moving forward stands for spaces. The rest (colors,
+// erasing) only change how text looks.
+func (f *Filter) endCSI(final byte) {
+	switch final {
+	case 'A', 'B', 'E', 'F', 'G', 'H', 'J', 'd', 'f', '`':
+		f.endLine()
+	case 'C':
+		n := 1
+		if params := string(f.csi); params != "" {
+			n = 0
+			for _, r := range params {
+				if r < '0' || r > '9' {
+					break
+				}
+				n = n*10 + int(r-'0')
+			}
+		}
+		for i := 0; i < min(n, maxCursorForward); i++ {
+			f.add(' ')
+		}
+	}
+}
+
+// endLine writes the current line unless it is blank or a recent repeat
+func (f *Filter) endLine() {
+	text := strings.TrimRightFunc(f.line.String(), unicode.IsSpace)
+	f.line.Reset()
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	f.stats.Lines++
+
+	key := lineKey(text)
+	f.seen++
+	last, repeated := f.recent[key]
+	f.recent[key] = f.seen
+	if repeated && f.seen-last <= int64(f.window) {
+		f.stats.Dropped++
+		return
+	}
+	f.forget()
+
+	n, err := io.WriteString(f.w, text+"\n")
+	f.stats.BytesOut += int64(n)
+	if err != nil {
+		f.err = err
+	}
+}
+
+// forget drops the lines not seen within the window, once there are twice
+// as many remembered as the window holds
+func (f *Filter) forget() {
+	if len(f.recent) < 2*f.window {
+		return
+	}
+	for key, at := range f.recent {
+		if f.seen-at > int64(f.window) {
+			delete(f.recent, key)
+		}
+	}
+}
+
+// lineKey is what two lines must share to count as the same: their text,
+// without surrounding space or the spinner glyphs at its start
+func lineKey(text string) string {
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(text), spinnerRunes+" "))
+}
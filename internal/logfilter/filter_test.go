@@ -0,0 +1,248 @@
+package logfilter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func filterString(t *testing.T, chunks ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	f := New(&out)
+	for _, chunk := range chunks {
+		if _, err := f.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+	if err := f.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	return out.String()
+}
+
+func TestFilterStripsEscapeSequences(t *testing.T) {
+	tests := map[string]struct {
+		in   string
+		want string
+	}{
+		"plain":          {"hello\nworld\n", "hello\nworld\n"},
+		"colors":         {"\x1b[1;31merror\x1b[0m: failed\n", "error: failed\n"},
+		"erase line":     {"\x1b[2Kprogress\n", "progress\n"},
+		"title":          {"\x1b]0;claude\x07output\n", "output\n"},
+		"title with ST":  {"\x1b]0;claude\x1b\\output\n", "output\n"},
+		"cursor up":      {"first\x1b[1Asecond\n", "first\nsecond\n"},
+		"cursor forward": {"a\x1b[3Cb\n", "a   b\n"},
+		"carriage":       {"one\rtwo\r\n", "one\ntwo\n"},
+		"blank lines":    {"a\n\n   \n\x1b[2K\nb\n", "a\nb\n"},
+		"no newline":     {"last line", "last line\n"},
+		"charset":        {"\x1b(Bboxed\n", "boxed\n"},
+	}
+	for name, tt := range tests {
+		if got := filterString(t, tt.in); got != tt.want {
+			t.Errorf("%s: filtered %q = %q, want %q", name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFilterHandlesSequencesSplitAcrossWrites(t *testing.T) {
+	got := filterString(t, "red \x1b[3", "1mtext\x1b", "[0m done\x1b]0;ti", "tle\x1b", "\\\n")
+	if got != "red text done\n" {
+		t.Errorf("filtered output = %q, want %q", got, "red text done\n")
+	}
+}
+
+func TestFilterDropsRedraws(t *testing.T) {
+	// A status line redrawn in place with a changing spinner, and a box
+	// redrawn under it, as a terminal UI does
+	var in strings.Builder
+	for i, glyph := range []string{"✻", "✽", "✶", "✳"} {
+		if i > 0 {
+			in.WriteString("\x1b[2K\x1b[1A\x1b[2K\x1b[1A\x1b[2K\x1b[G")
+		}
+		in.WriteString(glyph + " Thinking… (esc to interrupt)\n│ > │\n╰───╯")
+	}
+	in.WriteString("\nDone.\n")
+
+	want := "✻ Thinking… (esc to interrupt)\n│ > │\n╰───╯\nDone.\n"
+	if got := filterString(t, in.String()); got != want {
+		t.Errorf("filtered output = %q, want %q", got, want)
+	}
+}
+
+func TestFilterWindow(t *testing.T) {
+	var out bytes.Buffer
+	f := NewWithWindow(&out, 2)
+	f.Write([]byte("a\nb\na\nc\nd\na\n"))
+
+	// The second a is within two lines of the first; the third is not
+	if got, want := out.String(), "a\nb\nc\nd\na\n"; got != want {
+		t.Errorf("filtered output = %q, want %q", got, want)
+	}
+	stats := f.Stats()
+	if stats.Lines != 6 || stats.Dropped != 1 {
+		t.Errorf("Stats() = %+v, want 6 lines and 1 dropped", stats)
+	}
+}
+
+// TestFilterClaudeTUIFixture runs the filter over output shaped like
+// Claude's terminal UI working through a task, written the way Ink redraws:
+// the animated status line and the input box under it erased and drawn
+// again many times a second, with tool calls written above them as they
+// finish. The reduction is what the README quotes.
+func TestFilterClaudeTUIFixture(t *testing.T) {
+	raw, err := os.ReadFile("testdata/claude-tui.log")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	var out bytes.Buffer
+	f := New(&out)
+	// Write in chunks the size of a pipe read, so sequences get split
+	for rest := raw; len(rest) > 0; {
+		n := min(len(rest), 4096)
+		if _, err := f.Write(rest[:n]); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+		rest = rest[n:]
+	}
+	if err := f.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	stats := f.Stats()
+	if stats.BytesIn != int64(len(raw)) || stats.BytesOut != int64(out.Len()) {
+		t.Errorf("Stats() = %+v, want %d bytes in and %d out", stats, len(raw), out.Len())
+	}
+	reduction := 100 - float64(stats.BytesOut)*100/float64(stats.BytesIn)
+	t.Logf("filtered %d bytes to %d (%.1f%% smaller), dropping %d of %d lines",
+		stats.BytesIn, stats.BytesOut, reduction, stats.Dropped, stats.Lines)
+	if reduction < 90 {
+		t.Errorf("filter reduced the capture by %.1f%%, want at least 90%%", reduction)
+	}
+
+	text := out.String()
+	if strings.Contains(text, "\x1b") {
+		t.Error("filtered output still contains escape sequences")
+	}
+	// Every tool call and its result survives, once
+	for _, want := range []string{
+		"⏺ Read(internal/cli/cli.go)",
+		"⎿  Read 7412 lines",
+		"⏺ Edit(internal/daemon/daemon.go)",
+		"ok  github.com/dlorenc/multiclaude/internal/cli 14.2s",
+		"the change builds and the tests pass",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("filtered output is missing %q", want)
+		}
+	}
+	if n := strings.Count(text, "? for shortcuts"); n != 1 {
+		t.Errorf("the shortcuts hint appears %d times, want once", n)
+	}
+}
+
+func TestCaptureFollowsRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- Capture(pr, path, nil) }()
+
+	fmt.Fprint(pw, "\x1b[32mbefore\x1b[0m rotation\n")
+	// Rotate once the first line is written, as the daemon does
+	waitForFile(t, path, "before rotation\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(pw, "after rotation\nunfinished")
+	pw.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Capture() failed: %v", err)
+	}
+
+	if data := readCaptured(t, path+".1"); data != "before rotation\n" {
+		t.Errorf("rotated log = %q, want only the line before rotation", data)
+	}
+	if data := readCaptured(t, path); data != "after rotation\nunfinished\n" {
+		t.Errorf("new log = %q, want the lines after rotation", data)
+	}
+}
+
+func TestCaptureCommandQuotes(t *testing.T) {
+	got := CaptureCommand("/opt/multi claude/bin", "/logs/it's.log")
+	want := `'/opt/multi claude/bin' logs _capture '/logs/it'\''s.log'`
+	if got != want {
+		t.Errorf("CaptureCommand() = %s, want %s", got, want)
+	}
+}
+
+// readCaptured returns a captured log without the timestamps starting its
+// lines, failing the test if a line has none
+func readCaptured(t *testing.T, path string) string {
+	t.Helper()
+	data, _ := os.ReadFile(path)
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		stamp, text, _ := strings.Cut(line, " ")
+		if _, err := time.Parse(TimeLayout, stamp); err != nil {
+			t.Fatalf("line %q does not start with a timestamp: %v", line, err)
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// waitForFile waits until a captured log holds want, timestamps aside
+func waitForFile(t *testing.T, path, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if readCaptured(t, path) == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s never contained %q", path, want)
+}
+
+func TestCaptureRewritesLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+	input := "export TOKEN=\x1b[1mhunter2\x1b[0m\nplain line\n"
+	redact := func(line string) string { return strings.ReplaceAll(line, "hunter2", "<secret>") }
+	if err := Capture(strings.NewReader(input), path, redact); err != nil {
+		t.Fatalf("Capture() failed: %v", err)
+	}
+	if data := readCaptured(t, path); data != "export TOKEN=<secret>\nplain line\n" {
+		t.Errorf("log = %q, want the secret rewritten before it is written", data)
+	}
+}
+
+func TestCaptureStampsLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+	before := time.Now().Truncate(time.Millisecond)
+	if err := Capture(strings.NewReader("first\nsecond\n"), path, nil); err != nil {
+		t.Fatalf("Capture() failed: %v", err)
+	}
+	after := time.Now()
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log = %q, want two lines", data)
+	}
+	for _, line := range lines {
+		stamp, _, _ := strings.Cut(line, " ")
+		ts, err := time.Parse(TimeLayout, stamp)
+		if err != nil || ts.Before(before) || ts.After(after) {
+			t.Errorf("line %q: stamp %v (%v) not within the capture", line, ts, err)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Package logstream streams new lines of agent logs to WebSocket clients,
+// for dashboards and editor plugins built around multiclaude.
+package logstream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultPollInterval is how often log files are checked for new lines
+const DefaultPollInterval = 250 * time.Millisecond
+
+// clientBuffer is how many lines may queue for a client before it is
+// dropped as too slow
+const clientBuffer = 256
+
+// writeTimeout bounds writing a frame to a client
+const writeTimeout = 10 * time.Second
+
+// Line is one log line, sent to clients as a JSON text frame
+type Line struct {
+	Agent string `json:"agent"`
+	Repo  string `json:"repo"`
+	Line  string `json:"line"`
+	// Timestamp is when the line was read from the log, as agent logs do not
+	// timestamp every line
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Source is an agent log file to stream
+type Source struct {
+	Repo  string
+	Agent string
+	Path  string
+}
+
+// Options configure a Server
+type Options struct {
+	// Sources returns the log files to stream. It is called on every poll,
+	// so that logs of agents started later are picked up.
+	Sources func() []Source
+	// PollInterval is how often the logs are checked; zero means
+	// DefaultPollInterval
+	PollInterval time.Duration
+	// Rewrite, if set, is applied to each line before it is sent, e.g. to
+	// redact secrets
+	Rewrite func(repo, line string) string
+}
+
+// Server tails agent logs and broadcasts new lines to every connected
+// WebSocket client
+type Server struct {
+	opts Options
+
+	mu      sync.Mutex
+	clients map[*client]bool
+	served  bool          // whether any client has connected
+	empty   chan struct{} // closed when the last client disconnects
+}
+
+// tail is the read position in one log file
+type tail struct {
+	offset  int64
+	partial []byte // an unterminated last line, completed by a later read
+}
+
+// client is one WebSocket connection
+type client struct {
+	conn  net.Conn
+	lines chan []byte
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewServer creates a server for the logs opts lists
+func NewServer(opts Options) *Server {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	return &Server{
+		opts:    opts,
+		clients: make(map[*client]bool),
+		empty:   make(chan struct{}),
+	}
+}
+
+// Handler serves the /ws WebSocket endpoint
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", s.serveWS)
+	return mux
+}
+
+// Empty is closed when the last connected client disconnects, after at least
+// one has connected
+func (s *Server) Empty() <-chan struct{} {
+	return s.empty
+}
+
+// Run polls the logs until ctx is done. Only lines written after Run starts
+// are streamed; a log that appears later is streamed from its start.
+func (s *Server) Run(ctx context.Context) {
+	tails := make(map[string]*tail)
+	for _, src := range s.opts.Sources() {
+		if info, err := os.Stat(src.Path); err == nil {
+			tails[src.Path] = &tail{offset: info.Size()}
+		}
+	}
+
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.closeAll()
+			return
+		case <-ticker.C:
+			s.poll(tails)
+		}
+	}
+}
+
+// poll reads the lines added to each log since the last poll and sends them
+func (s *Server) poll(tails map[string]*tail) {
+	for _, src := range s.opts.Sources() {
+		t := tails[src.Path]
+		if t == nil {
+			t = &tail{}
+			tails[src.Path] = t
+		}
+		for _, text := range t.read(src.Path) {
+			if s.opts.Rewrite != nil {
+				text = s.opts.Rewrite(src.Repo, text)
+			}
+			s.broadcast(Line{Agent: src.Agent, Repo: src.Repo, Line: text, Timestamp: time.Now()})
+		}
+	}
+}
+
+// read returns the complete lines added to a log since the last read. A log
+// that shrank was truncated or replaced, and is read again from its start.
+func (t *tail) read(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	if info.Size() < t.offset {
+		t.offset, t.partial = 0, nil
+	}
+	if info.Size() == t.offset {
+		return nil
+	}
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(f, info.Size()-t.offset))
+	if err != nil {
+		return nil
+	}
+	t.offset += int64(len(data))
+
+	data = append(t.partial, data...)
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		t.partial = data
+		return nil
+	}
+	t.partial = append([]byte(nil), data[end+1:]...)
+
+	var lines []string
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		lines = append(lines, string(bytes.TrimSuffix(line, []byte("\r"))))
+	}
+	return lines
+}
+
+// broadcast queues a line for every client, dropping clients that have
+// fallen too far behind
+func (s *Server) broadcast(line Line) {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		select {
+		case c.lines <- data:
+		default:
+			c.close()
+		}
+	}
+}
+
+// serveWS upgrades a connection and streams lines to it until either side
+// closes it
+func (s *Server) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, reader, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+	c := &client{conn: conn, lines: make(chan []byte, clientBuffer), done: make(chan struct{})}
+	s.add(c)
+	defer s.remove(c)
+
+	// Clients only send control frames; answer pings and stop on close
+	pongs := make(chan []byte, 1)
+	go func() {
+		defer c.close()
+		for {
+			opcode, payload, err := readFrame(reader)
+			if err != nil || opcode == opClose {
+				return
+			}
+			if opcode == opPing {
+				select {
+				case pongs <- payload:
+				default:
+				}
+			}
+		}
+	}()
+
+	for {
+		var opcode byte
+		var payload []byte
+		select {
+		case <-c.done:
+			conn.SetWriteDeadline(time.Now().Add(time.Second))
+			writeFrame(conn, opClose, nil)
+			conn.Close()
+			return
+		case payload = <-c.lines:
+			opcode = opText
+		case payload = <-pongs:
+			opcode = opPong
+		}
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := writeFrame(conn, opcode, payload); err != nil {
+			c.close()
+		}
+	}
+}
+
+func (s *Server) add(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[c] = true
+	s.served = true
+}
+
+func (s *Server) remove(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, c)
+	if s.served && len(s.clients) == 0 {
+		select {
+		case <-s.empty:
+		default:
+			close(s.empty)
+		}
+	}
+}
+
+// closeAll disconnects every client
+func (s *Server) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		c.close()
+	}
+}
+
+// close asks the client's writer to send a close frame and hang up
+func (c *client) close() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// Clients returns how many clients are connected
+func (s *Server) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
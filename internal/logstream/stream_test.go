@@ -0,0 +1,209 @@
+package logstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dial opens a WebSocket connection to the test server's /ws endpoint
+func dial(t *testing.T, srv *httptest.Server) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, reader, resp := handshake(t, srv, "")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Handshake status = %d, want 101", resp.StatusCode)
+	}
+	// The example from RFC 6455
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", got)
+	}
+	return conn, reader
+}
+
+// handshake sends a WebSocket handshake to the test server's /ws endpoint,
+// with an Origin header unless origin is empty
+func handshake(t *testing.T, srv *httptest.Server, origin string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	header := ""
+	if origin != "" {
+		header = "Origin: " + origin + "\r\n"
+	}
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n%s\r\n", key, header)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Reading handshake failed: %v", err)
+	}
+	return conn, reader, resp
+}
+
+// readLine reads the next text frame as a Line
+func readLine(t *testing.T, conn net.Conn, reader *bufio.Reader) Line {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	opcode, payload, err := readFrame(reader)
+	if err != nil {
+		t.Fatalf("readFrame failed: %v", err)
+	}
+	if opcode != opText {
+		t.Fatalf("opcode = %d, want a text frame", opcode)
+	}
+	var line Line
+	if err := json.Unmarshal(payload, &line); err != nil {
+		t.Fatalf("frame %q is not a Line: %v", payload, err)
+	}
+	return line
+}
+
+// waitForClients waits until the server has n clients
+func waitForClients(t *testing.T, s *Server, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Clients() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("server has %d clients, want %d", s.Clients(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func appendFile(t *testing.T, path, text string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerStreamsNewLines(t *testing.T) {
+	dir := t.TempDir()
+	supervisorLog := filepath.Join(dir, "supervisor.log")
+	workerLog := filepath.Join(dir, "worker.log")
+	appendFile(t, supervisorLog, "written before the stream started\n")
+
+	s := NewServer(Options{
+		Sources: func() []Source {
+			return []Source{
+				{Repo: "repo", Agent: "supervisor", Path: supervisorLog},
+				{Repo: "repo", Agent: "worker", Path: workerLog},
+			}
+		},
+		PollInterval: 10 * time.Millisecond,
+		Rewrite:      func(repo, line string) string { return strings.ReplaceAll(line, "hunter2", "<secret>") },
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	// Every client gets every line
+	conn1, reader1 := dial(t, srv)
+	defer conn1.Close()
+	conn2, reader2 := dial(t, srv)
+	defer conn2.Close()
+	waitForClients(t, s, 2)
+
+	appendFile(t, supervisorLog, "password hunter2\npartial")
+	for _, c := range []struct {
+		conn   net.Conn
+		reader *bufio.Reader
+	}{{conn1, reader1}, {conn2, reader2}} {
+		line := readLine(t, c.conn, c.reader)
+		if line.Agent != "supervisor" || line.Repo != "repo" || line.Line != "password <secret>" || line.Timestamp.IsZero() {
+			t.Errorf("line = %+v", line)
+		}
+	}
+
+	// An unterminated line is sent once it is complete, and a log created
+	// after the stream started is sent from its start
+	appendFile(t, supervisorLog, " line\n")
+	if line := readLine(t, conn1, reader1); line.Line != "partial line" {
+		t.Errorf("line = %q, want the completed partial line", line.Line)
+	}
+	appendFile(t, workerLog, "worker started\n")
+	if line := readLine(t, conn1, reader1); line.Agent != "worker" || line.Line != "worker started" {
+		t.Errorf("line = %+v, want the worker's first line", line)
+	}
+}
+
+func TestServerEmpty(t *testing.T) {
+	s := NewServer(Options{Sources: func() []Source { return nil }, PollInterval: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	select {
+	case <-s.Empty():
+		t.Fatal("Empty() should not be closed before any client connected")
+	default:
+	}
+
+	conn, _ := dial(t, srv)
+	waitForClients(t, s, 1)
+
+	// A close frame from the client ends its stream
+	writeFrame(conn, opClose, nil)
+	select {
+	case <-s.Empty():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Empty() should be closed after the last client disconnected")
+	}
+	conn.Close()
+}
+
+func TestServeWSRejectsPlainRequests(t *testing.T) {
+	s := NewServer(Options{Sources: func() []Source { return nil }})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/ws")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestServeWSRejectsOtherOrigins(t *testing.T) {
+	s := NewServer(Options{Sources: func() []Source { return nil }})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	for origin, want := range map[string]int{
+		"https://evil.example.com": http.StatusForbidden,
+		"null":                     http.StatusForbidden,
+		"http://localhost:3000":    http.StatusSwitchingProtocols,
+		"http://127.0.0.1:8080":    http.StatusSwitchingProtocols,
+	} {
+		conn, _, resp := handshake(t, srv, origin)
+		if resp.StatusCode != want {
+			t.Errorf("origin %s: status = %d, want %d", origin, resp.StatusCode, want)
+		}
+		conn.Close()
+	}
+}
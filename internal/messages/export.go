@@ -0,0 +1,146 @@
+package messages
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// exportedMessage is one line of a message export: a message, where it was
+// stored, and the whole body of a spilled message, so the export stands on
+// its own
+type exportedMessage struct {
+	Repo  string `json:"repo"`
+	Agent string `json:"agent"`
+	*Message
+	SpilledBody string `json:"spilled_body,omitempty"`
+}
+
+// ExportToFile writes every message of an agent, whatever its status, to
+// path as newline-delimited JSON, oldest first
+func (m *Manager) ExportToFile(repoName, agentName, path string) error {
+	msgs, err := m.List(repoName, agentName)
+	if err != nil {
+		return err
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		if !msgs[i].Timestamp.Equal(msgs[j].Timestamp) {
+			return msgs[i].Timestamp.Before(msgs[j].Timestamp)
+		}
+		return msgs[i].ID < msgs[j].ID
+	})
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, msg := range msgs {
+		line := exportedMessage{Repo: repoName, Agent: agentName, Message: msg}
+		if msg.Spilled() {
+			if line.SpilledBody, err = m.FullBody(repoName, agentName, msg); err != nil {
+				break
+			}
+		}
+		if err = enc.Encode(line); err != nil {
+			err = fmt.Errorf("failed to write message %s: %w", msg.ID, err)
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// ImportFromFile writes the messages in an export made by ExportToFile back
+// to the repository and agent they were exported from, and returns how
+// many it wrote. Messages whose IDs are already there are skipped, so
+// importing the same file twice changes nothing.
+func (m *Manager) ImportFromFile(path string) (int, error) {
+	return m.ImportFromFileAs(path, "", "")
+}
+
+// ImportFromFileAs is ImportFromFile, but writes the messages to repoName
+// and agentName instead where those are set
+func (m *Manager) ImportFromFileAs(path, repoName, agentName string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open message export: %w", err)
+	}
+	defer f.Close()
+
+	// Check the whole file before writing anything
+	var lines []exportedMessage
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 2*DefaultHardCap)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var line exportedMessage
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return 0, fmt.Errorf("line %d of %s is not an exported message: %w", n, path, err)
+		}
+		if line.Message == nil {
+			// No message fields at all; the ID check below rejects it
+			line.Message = &Message{}
+		}
+		if repoName != "" {
+			line.Repo = repoName
+		}
+		if agentName != "" {
+			line.Agent, line.To = agentName, agentName
+		}
+		for _, name := range []string{line.Repo, line.Agent, line.ID} {
+			if !isPathElement(name) {
+				return 0, fmt.Errorf("line %d of %s has an invalid repository, agent or message ID: %q", n, path, name)
+			}
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read message export: %w", err)
+	}
+
+	imported := 0
+	for _, line := range lines {
+		msgPath := filepath.Join(m.agentDir(line.Repo, line.Agent), line.ID+".json")
+		if _, err := os.Stat(msgPath); err == nil {
+			continue
+		}
+		msg := line.Message
+		if msg.Spilled() {
+			msg.BodyFile = msg.ID + bodyFileExt
+			if err := m.ensureAgentDir(line.Repo, line.Agent); err != nil {
+				return imported, err
+			}
+			if err := os.WriteFile(filepath.Join(m.agentDir(line.Repo, line.Agent), msg.BodyFile), []byte(line.SpilledBody), 0644); err != nil {
+				return imported, fmt.Errorf("failed to write message body: %w", err)
+			}
+		}
+		if err := m.write(line.Repo, line.Agent, msg); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// isPathElement reports whether name can be used as a single element of a
+// path under the messages directory
+func isPathElement(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
@@ -0,0 +1,103 @@
+package messages
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportAndImportMessages(t *testing.T) {
+	m := NewManager(t.TempDir())
+
+	// One message of each status, and a spilled one
+	var ids []string
+	for _, status := range []Status{StatusPending, StatusDelivered, StatusRead, StatusAcked} {
+		msg, err := m.Send("test-repo", "supervisor", "worker1", "message "+string(status))
+		if err != nil {
+			t.Fatalf("Send() failed: %v", err)
+		}
+		if err := m.UpdateStatus("test-repo", "worker1", msg.ID, status); err != nil {
+			t.Fatalf("UpdateStatus() failed: %v", err)
+		}
+		ids = append(ids, msg.ID)
+	}
+	large := strings.Repeat("log line\n", 2000)
+	spilled, err := m.Send("test-repo", "supervisor", "worker1", large)
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	ids = append(ids, spilled.ID)
+
+	exportPath := filepath.Join(t.TempDir(), "worker1.ndjson")
+	if err := m.ExportToFile("test-repo", "worker1", exportPath); err != nil {
+		t.Fatalf("ExportToFile() failed: %v", err)
+	}
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != len(ids) {
+		t.Fatalf("export has %d lines, want %d", len(lines), len(ids))
+	}
+
+	// Importing into an empty messages directory restores every message
+	other := NewManager(t.TempDir())
+	n, err := other.ImportFromFile(exportPath)
+	if err != nil {
+		t.Fatalf("ImportFromFile() failed: %v", err)
+	}
+	if n != len(ids) {
+		t.Errorf("ImportFromFile() imported %d messages, want %d", n, len(ids))
+	}
+	for i, status := range []Status{StatusPending, StatusDelivered, StatusRead, StatusAcked} {
+		msg, err := other.Get("test-repo", "worker1", ids[i])
+		if err != nil || msg.Status != status {
+			t.Errorf("imported message %s = %+v, %v; want status %s", ids[i], msg, err, status)
+		}
+	}
+	msg, err := other.Get("test-repo", "worker1", spilled.ID)
+	if err != nil {
+		t.Fatalf("Get() of the spilled message failed: %v", err)
+	}
+	if full, err := other.FullBody("test-repo", "worker1", msg); err != nil || full != large {
+		t.Errorf("FullBody() of the imported spilled message returned %d bytes, %v; want %d", len(full), err, len(large))
+	}
+
+	// Importing again skips the messages already there
+	if n, err := other.ImportFromFile(exportPath); err != nil || n != 0 {
+		t.Errorf("second ImportFromFile() = %d, %v; want 0 imported", n, err)
+	}
+
+	// Importing as another agent readdresses the messages
+	if n, err := other.ImportFromFileAs(exportPath, "", "worker2"); err != nil || n != len(ids) {
+		t.Errorf("ImportFromFileAs() = %d, %v; want %d imported", n, err, len(ids))
+	}
+	if msg, err := other.Get("test-repo", "worker2", ids[0]); err != nil || msg.To != "worker2" {
+		t.Errorf("message imported as worker2 = %+v, %v; want it addressed to worker2", msg, err)
+	}
+}
+
+func TestImportFromFileRejectsInvalidLines(t *testing.T) {
+	m := NewManager(t.TempDir())
+	dir := t.TempDir()
+
+	tests := map[string]string{
+		"not json":      "{\"repo\": \"test-repo\"\n",
+		"path in ID":    `{"repo": "test-repo", "agent": "worker1", "id": "../escape"}` + "\n",
+		"missing agent": `{"repo": "test-repo", "id": "abc"}` + "\n",
+		"no message":    `{"repo": "test-repo", "agent": "worker1"}` + "\n",
+	}
+	for name, content := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".ndjson")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := m.ImportFromFile(path); err == nil {
+			t.Errorf("%s: ImportFromFile() imported %d messages, want an error", name, n)
+		}
+	}
+	if _, err := m.ImportFromFile(filepath.Join(dir, "missing.ndjson")); err == nil {
+		t.Error("ImportFromFile() of a missing file should fail")
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,8 +21,23 @@ const (
 	StatusDelivered Status = "delivered"
 	StatusRead      Status = "read"
 	StatusAcked     Status = "acked"
+	// StatusFailed marks a message that never reached its recipient because
+	// the recipient was removed first
+	StatusFailed Status = "failed"
 )
 
+// daemonSender is who the daemon's own messages, such as bounce notices,
+// are from
+const daemonSender = "daemon"
+
+// sendLockFile is the lock SendOnce holds in the recipient's message
+// directory; List skips it as it is not a .json file
+const sendLockFile = ".send.lock"
+
+// bounceFallback is told about messages that bounced when their sender is
+// gone too
+const bounceFallback = "supervisor"
+
 // Message represents a message between agents
 type Message struct {
 	ID        string     `json:"id"`
@@ -29,11 +47,78 @@ type Message struct {
 	Body      string     `json:"body"`
 	Status    Status     `json:"status"`
 	AckedAt   *time.Time `json:"acked_at,omitempty"`
+	// ReadAt is when the message was marked read
+	ReadAt *time.Time `json:"read_at,omitempty"`
+	// AutoAcked is set when the daemon acked the message after it sat read
+	// for the repository's auto-ack period, rather than the agent
+	AutoAcked bool `json:"auto_acked,omitempty"`
+	// ScheduledFor defers delivery until the given time; nil delivers immediately
+	ScheduledFor *time.Time `json:"scheduled_for,omitempty"`
+	// IdempotencyKey is chosen by the sender so that sending the same message
+	// again, e.g. on a retry, does not deliver it twice (see SendOnce)
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// BodyFile names the file, in the recipient's message directory, that
+	// holds a body too large to deliver inline; Body then has an excerpt
+	BodyFile string `json:"body_file,omitempty"`
+}
+
+// ExpiryAge is how long a message can go unread after it is due before it
+// counts as expired. Expired messages are only reported, except scheduled
+// messages still pending delivery, which are pruned.
+const ExpiryAge = 24 * time.Hour
+
+// AckFooter returns the line appended to a delivered message that gives the
+// agent the exact command to acknowledge it
+func AckFooter(messageID string) string {
+	return fmt.Sprintf("[ack: multiclaude agent ack-message %s]", messageID)
+}
+
+// DueAt returns when the message should be delivered: its scheduled time,
+// or when it was sent
+func (msg *Message) DueAt() time.Time {
+	if msg.ScheduledFor != nil {
+		return *msg.ScheduledFor
+	}
+	return msg.Timestamp
+}
+
+// IsScheduled reports whether the message is waiting for a future delivery time
+func (msg *Message) IsScheduled(now time.Time) bool {
+	return msg.ScheduledFor != nil && now.Before(*msg.ScheduledFor)
+}
+
+// IsExpiredSchedule reports whether a scheduled message is still pending
+// more than ExpiryAge after its delivery time
+func (msg *Message) IsExpiredSchedule(now time.Time) bool {
+	return msg.ScheduledFor != nil && msg.Status == StatusPending && now.Sub(*msg.ScheduledFor) > ExpiryAge
+}
+
+// MessageSummary aggregates message counts by status
+type MessageSummary struct {
+	TotalMessages     int        `json:"total_messages"`
+	PendingMessages   int        `json:"pending_messages"`
+	DeliveredMessages int        `json:"delivered_messages"`
+	ReadMessages      int        `json:"read_messages"`
+	AckedMessages     int        `json:"acked_messages"`
+	ExpiredMessages   int        `json:"expired_messages"`         // Unread (pending or delivered) for longer than ExpiryAge
+	OldestPending     *time.Time `json:"oldest_pending,omitempty"` // Timestamp of the oldest pending message
+}
+
+// Unread returns the number of messages that have not been read yet
+func (s *MessageSummary) Unread() int {
+	return s.PendingMessages + s.DeliveredMessages
+}
+
+// AwaitingAck returns the number of messages that reached the agent
+// (delivered or read) but have not been acknowledged
+func (s *MessageSummary) AwaitingAck() int {
+	return s.DeliveredMessages + s.ReadMessages
 }
 
 // Manager handles message filesystem operations
 type Manager struct {
 	messagesRoot string
+	limits       Limits
 }
 
 // NewManager creates a new message manager
@@ -43,22 +128,106 @@ func NewManager(messagesRoot string) *Manager {
 
 // Send creates a new message file
 func (m *Manager) Send(repoName, from, to, body string) (*Message, error) {
-	msg := &Message{
-		ID:        fmt.Sprintf("msg-%s", uuid.New().String()[:13]),
-		From:      from,
-		To:        to,
-		Timestamp: time.Now(),
-		Body:      body,
-		Status:    StatusPending,
+	return m.Schedule(repoName, from, to, body, nil)
+}
+
+// Schedule creates a new message file that is not delivered before at.
+// A nil at delivers the message as soon as possible, like Send.
+func (m *Manager) Schedule(repoName, from, to, body string, at *time.Time) (*Message, error) {
+	return m.create(repoName, from, to, body, at, "")
+}
+
+// create writes a new message, spilling a body over the size limit to a file
+func (m *Manager) create(repoName, from, to, body string, at *time.Time, idempotencyKey string) (*Message, error) {
+	if len(body) > m.limits.EffectiveHardCap() {
+		return nil, &BodyTooLargeError{Size: len(body), Cap: m.limits.EffectiveHardCap()}
 	}
 
+	msg := newMessage(from, to, body, at)
+	msg.IdempotencyKey = idempotencyKey
+	if len(body) > m.limits.EffectiveMaxBodySize() {
+		if err := m.spill(repoName, msg); err != nil {
+			return nil, err
+		}
+	}
 	if err := m.write(repoName, to, msg); err != nil {
+		if msg.BodyFile != "" {
+			os.Remove(filepath.Join(m.agentDir(repoName, to), msg.BodyFile))
+		}
 		return nil, err
 	}
 
 	return msg, nil
 }
 
+// newMessage returns a pending message with a fresh ID
+func newMessage(from, to, body string, at *time.Time) *Message {
+	return &Message{
+		ID:           fmt.Sprintf("msg-%s", uuid.New().String()[:13]),
+		From:         from,
+		To:           to,
+		Timestamp:    time.Now(),
+		Body:         body,
+		Status:       StatusPending,
+		ScheduledFor: at,
+	}
+}
+
+// SendOnce creates a message like Schedule unless a message from the same
+// sender to the same recipient already carries idempotencyKey. That message
+// is returned instead, with duplicate set, and nothing is written. Senders
+// racing with the same key are serialized by a lock on the recipient's
+// message directory. Keys only match messages that still exist, so a key
+// can be reused once its message has been deleted.
+func (m *Manager) SendOnce(repoName, from, to, body, idempotencyKey string, at *time.Time) (msg *Message, duplicate bool, err error) {
+	if idempotencyKey == "" {
+		return nil, false, fmt.Errorf("idempotency key cannot be empty")
+	}
+	if err := m.ensureAgentDir(repoName, to); err != nil {
+		return nil, false, err
+	}
+
+	unlock, err := m.lockAgentDir(repoName, to)
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+
+	existing, err := m.List(repoName, to)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, msg := range existing {
+		if msg.From == from && msg.IdempotencyKey == idempotencyKey {
+			return msg, true, nil
+		}
+	}
+
+	msg, err = m.create(repoName, from, to, body, at, idempotencyKey)
+	if err != nil {
+		return nil, false, err
+	}
+	return msg, false, nil
+}
+
+// lockAgentDir takes an exclusive lock on an agent's message directory,
+// which must exist, and returns the function that releases it
+func (m *Manager) lockAgentDir(repoName, agentName string) (func(), error) {
+	path := filepath.Join(m.agentDir(repoName, agentName), sendLockFile)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open message lock: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock messages of %s: %w", agentName, err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
 // List returns all messages for an agent
 func (m *Manager) List(repoName, agentName string) ([]*Message, error) {
 	dir := m.agentDir(repoName, agentName)
@@ -103,8 +272,11 @@ func (m *Manager) UpdateStatus(repoName, agentName, messageID string, status Sta
 	}
 
 	msg.Status = status
-	if status == StatusAcked {
-		now := time.Now()
+	now := time.Now()
+	switch status {
+	case StatusRead:
+		msg.ReadAt = &now
+	case StatusAcked:
 		msg.AckedAt = &now
 	}
 
@@ -116,12 +288,47 @@ func (m *Manager) Ack(repoName, agentName, messageID string) error {
 	return m.UpdateStatus(repoName, agentName, messageID, StatusAcked)
 }
 
+// AutoAck acknowledges an agent's messages that have been read for longer
+// than after, marking them AutoAcked, and returns how many it acked. Messages
+// read before ReadAt was recorded count from when they were sent.
+func (m *Manager) AutoAck(repoName, agentName string, after time.Duration, now time.Time) (int, error) {
+	messages, err := m.List(repoName, agentName)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, msg := range messages {
+		if msg.Status != StatusRead {
+			continue
+		}
+		readAt := msg.Timestamp
+		if msg.ReadAt != nil {
+			readAt = *msg.ReadAt
+		}
+		if now.Sub(readAt) <= after {
+			continue
+		}
+
+		msg.Status = StatusAcked
+		msg.AckedAt = &now
+		msg.AutoAcked = true
+		if err := m.write(repoName, agentName, msg); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
 // Delete removes a message file
 func (m *Manager) Delete(repoName, agentName, messageID string) error {
 	path := filepath.Join(m.agentDir(repoName, agentName), messageID+".json")
 	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to delete message: %w", err)
 	}
+	// A spilled body goes with its message
+	os.Remove(filepath.Join(m.agentDir(repoName, agentName), messageID+bodyFileExt))
 	return nil
 }
 
@@ -144,6 +351,56 @@ func (m *Manager) DeleteAcked(repoName, agentName string) (int, error) {
 	return count, nil
 }
 
+// Find looks up a message by ID across every agent in a repository
+func (m *Manager) Find(repoName, messageID string) (*Message, error) {
+	entries, err := os.ReadDir(filepath.Join(m.messagesRoot, repoName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read repo messages dir: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if msg, err := m.Get(repoName, entry.Name(), messageID); err == nil {
+			return msg, nil
+		}
+	}
+	return nil, fmt.Errorf("message %s not found in repository %s", messageID, repoName)
+}
+
+// ListScheduled returns the pending messages in a repository whose delivery
+// time is still in the future, soonest first
+func (m *Manager) ListScheduled(repoName string, now time.Time) ([]*Message, error) {
+	entries, err := os.ReadDir(filepath.Join(m.messagesRoot, repoName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Message{}, nil
+		}
+		return nil, fmt.Errorf("failed to read repo messages dir: %w", err)
+	}
+
+	scheduled := []*Message{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		messages, err := m.List(repoName, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range messages {
+			if msg.Status == StatusPending && msg.IsScheduled(now) {
+				scheduled = append(scheduled, msg)
+			}
+		}
+	}
+
+	sort.Slice(scheduled, func(i, j int) bool {
+		return scheduled[i].ScheduledFor.Before(*scheduled[j].ScheduledFor)
+	})
+	return scheduled, nil
+}
+
 // ListUnread returns all unread messages for an agent
 func (m *Manager) ListUnread(repoName, agentName string) ([]*Message, error) {
 	messages, err := m.List(repoName, agentName)
@@ -161,6 +418,70 @@ func (m *Manager) ListUnread(repoName, agentName string) ([]*Message, error) {
 	return unread, nil
 }
 
+// Summary returns message statistics across all agents in a repository
+func (m *Manager) Summary(repoName string) (*MessageSummary, error) {
+	summary := &MessageSummary{}
+
+	entries, err := os.ReadDir(filepath.Join(m.messagesRoot, repoName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return summary, nil
+		}
+		return nil, fmt.Errorf("failed to read repo messages dir: %w", err)
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		messages, err := m.List(repoName, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		summary.add(messages, now)
+	}
+
+	return summary, nil
+}
+
+// AgentSummary returns message statistics for a single agent
+func (m *Manager) AgentSummary(repoName, agentName string) (*MessageSummary, error) {
+	messages, err := m.List(repoName, agentName)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &MessageSummary{}
+	summary.add(messages, time.Now())
+	return summary, nil
+}
+
+// add counts messages into the summary
+func (s *MessageSummary) add(messages []*Message, now time.Time) {
+	for _, msg := range messages {
+		s.TotalMessages++
+		switch msg.Status {
+		case StatusPending:
+			s.PendingMessages++
+			// Messages scheduled for later are not overdue
+			if due := msg.DueAt(); !msg.IsScheduled(now) && (s.OldestPending == nil || due.Before(*s.OldestPending)) {
+				s.OldestPending = &due
+			}
+		case StatusDelivered:
+			s.DeliveredMessages++
+		case StatusRead:
+			s.ReadMessages++
+		case StatusAcked:
+			s.AckedMessages++
+		}
+
+		if (msg.Status == StatusPending || msg.Status == StatusDelivered) && now.Sub(msg.DueAt()) > ExpiryAge {
+			s.ExpiredMessages++
+		}
+	}
+}
+
 // agentDir returns the directory path for an agent's messages
 func (m *Manager) agentDir(repoName, agentName string) string {
 	return filepath.Join(m.messagesRoot, repoName, agentName)
@@ -207,7 +528,64 @@ func (m *Manager) read(repoName, agentName, filename string) (*Message, error) {
 	return &msg, nil
 }
 
-// CleanupOrphaned removes message directories for non-existent agents
+// Bounce marks the messages a removed agent never read (pending or
+// delivered) as failed and sends each sender a notice quoting the message.
+// When the sender is not among liveAgents the notice goes to the supervisor
+// instead, if it is live. Messages from the daemon are marked failed
+// without a notice. Returns how many messages were marked failed.
+func (m *Manager) Bounce(repoName, agentName string, liveAgents []string) (int, error) {
+	messages, err := m.List(repoName, agentName)
+	if err != nil {
+		return 0, err
+	}
+
+	live := make(map[string]bool, len(liveAgents))
+	for _, agent := range liveAgents {
+		live[agent] = true
+	}
+
+	count := 0
+	for _, msg := range messages {
+		if msg.Status != StatusPending && msg.Status != StatusDelivered {
+			continue
+		}
+
+		msg.Status = StatusFailed
+		if err := m.write(repoName, agentName, msg); err != nil {
+			return count, err
+		}
+		count++
+
+		if msg.From == daemonSender {
+			continue
+		}
+		notice := bounceNotice(msg, agentName)
+		to := msg.From
+		if !live[to] {
+			if !live[bounceFallback] || bounceFallback == agentName {
+				continue
+			}
+			notice = fmt.Sprintf("%s is gone too, so this is escalated to you.\n\n%s", msg.From, notice)
+			to = bounceFallback
+		}
+		if _, err := m.Send(repoName, daemonSender, to, notice); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// bounceNotice tells the sender of a message that it was not delivered,
+// quoting the message
+func bounceNotice(msg *Message, agentName string) string {
+	quoted := "> " + strings.ReplaceAll(msg.Body, "\n", "\n> ")
+	return fmt.Sprintf("could not deliver to %s: agent was removed\n\nUndelivered message %s from %s, sent %s:\n%s",
+		agentName, msg.ID, msg.From, msg.Timestamp.Format(time.RFC3339), quoted)
+}
+
+// CleanupOrphaned removes message directories for non-existent agents,
+// bouncing the messages they never read first. A directory whose messages
+// cannot be bounced is kept.
 func (m *Manager) CleanupOrphaned(repoName string, validAgents []string) (int, error) {
 	repoDir := filepath.Join(m.messagesRoot, repoName)
 
@@ -232,6 +610,9 @@ func (m *Manager) CleanupOrphaned(repoName string, validAgents []string) (int, e
 
 		if !validAgentMap[entry.Name()] {
 			// This is an orphaned agent directory
+			if _, err := m.Bounce(repoName, entry.Name(), validAgents); err != nil {
+				continue
+			}
 			path := filepath.Join(repoDir, entry.Name())
 			if err := os.RemoveAll(path); err == nil {
 				count++
@@ -1,10 +1,14 @@
 package messages
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+	"unicode/utf8"
 )
 
 func TestNewManager(t *testing.T) {
@@ -202,6 +206,51 @@ func TestAckMessage(t *testing.T) {
 	}
 }
 
+func TestAutoAck(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir)
+
+	repoName := "test-repo"
+	agentName := "worker1"
+
+	read, _ := m.Send(repoName, "supervisor", agentName, "Read a while ago")
+	delivered, _ := m.Send(repoName, "supervisor", agentName, "Delivered only")
+	for id, status := range map[string]Status{read.ID: StatusRead, delivered.ID: StatusDelivered} {
+		if err := m.UpdateStatus(repoName, agentName, id, status); err != nil {
+			t.Fatalf("UpdateStatus() failed: %v", err)
+		}
+	}
+
+	got, _ := m.Get(repoName, agentName, read.ID)
+	if got.ReadAt == nil {
+		t.Fatal("ReadAt not set when the message was read")
+	}
+
+	// Not read for long enough yet
+	if n, err := m.AutoAck(repoName, agentName, time.Hour, time.Now()); err != nil || n != 0 {
+		t.Errorf("AutoAck() = %d, %v; want nothing acked", n, err)
+	}
+
+	n, err := m.AutoAck(repoName, agentName, time.Hour, time.Now().Add(2*time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("AutoAck() = %d, %v; want 1 acked", n, err)
+	}
+	got, _ = m.Get(repoName, agentName, read.ID)
+	if got.Status != StatusAcked || !got.AutoAcked || got.AckedAt == nil {
+		t.Errorf("read message after AutoAck() = %+v, want auto-acked", got)
+	}
+	got, _ = m.Get(repoName, agentName, delivered.ID)
+	if got.Status != StatusDelivered || got.AutoAcked {
+		t.Errorf("delivered message after AutoAck() = %+v, want it untouched", got)
+	}
+}
+
+func TestAckFooter(t *testing.T) {
+	if got := AckFooter("msg-123"); got != "[ack: multiclaude agent ack-message msg-123]" {
+		t.Errorf("AckFooter() = %q", got)
+	}
+}
+
 func TestDeleteMessage(t *testing.T) {
 	tmpDir := t.TempDir()
 	m := NewManager(tmpDir)
@@ -373,3 +422,414 @@ func TestCleanupOrphaned(t *testing.T) {
 		}
 	}
 }
+
+func TestBounce(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir)
+	repoName := "test-repo"
+
+	send := func(from, body string, status Status) *Message {
+		t.Helper()
+		msg, err := m.Send(repoName, from, "worker", body)
+		if err != nil {
+			t.Fatalf("Send() failed: %v", err)
+		}
+		if status != StatusPending {
+			if err := m.UpdateStatus(repoName, "worker", msg.ID, status); err != nil {
+				t.Fatalf("UpdateStatus() failed: %v", err)
+			}
+		}
+		return msg
+	}
+	pending := send("reviewer", "please rebase\nthen push", StatusPending)
+	delivered := send("reviewer", "are you done?", StatusDelivered)
+	read := send("reviewer", "already read", StatusRead)
+	acked := send("reviewer", "already acked", StatusAcked)
+	orphaned := send("gone-worker", "from a removed agent", StatusPending)
+	system := send("daemon", "worktree synced", StatusPending)
+
+	count, err := m.Bounce(repoName, "worker", []string{"reviewer", "supervisor"})
+	if err != nil {
+		t.Fatalf("Bounce() failed: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("Bounce() count = %d, want 4", count)
+	}
+
+	for _, tc := range []struct {
+		msg  *Message
+		want Status
+	}{
+		{pending, StatusFailed},
+		{delivered, StatusFailed},
+		{read, StatusRead},
+		{acked, StatusAcked},
+		{orphaned, StatusFailed},
+		{system, StatusFailed},
+	} {
+		got, err := m.Get(repoName, "worker", tc.msg.ID)
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		if got.Status != tc.want {
+			t.Errorf("message %q status = %s, want %s", tc.msg.Body, got.Status, tc.want)
+		}
+	}
+
+	// The live sender is told about both of its unread messages, quoted
+	notices, _ := m.List(repoName, "reviewer")
+	if len(notices) != 2 {
+		t.Fatalf("reviewer got %d notices, want 2", len(notices))
+	}
+	var bodies []string
+	for _, n := range notices {
+		if n.From != "daemon" || !strings.HasPrefix(n.Body, "could not deliver to worker: agent was removed") {
+			t.Errorf("unexpected notice: %+v", n)
+		}
+		bodies = append(bodies, n.Body)
+	}
+	joined := strings.Join(bodies, "\n")
+	if !strings.Contains(joined, "> please rebase\n> then push") || !strings.Contains(joined, "> are you done?") {
+		t.Errorf("notices should quote the undelivered messages, got:\n%s", joined)
+	}
+
+	// The message from a removed sender is escalated to the supervisor; the
+	// daemon's own message is not reported
+	escalated, _ := m.List(repoName, "supervisor")
+	if len(escalated) != 1 || !strings.Contains(escalated[0].Body, "gone-worker is gone too") || !strings.Contains(escalated[0].Body, "> from a removed agent") {
+		t.Errorf("supervisor should get one escalation, got %+v", escalated)
+	}
+
+	// Failed messages do not bounce again
+	if count, err := m.Bounce(repoName, "worker", []string{"reviewer", "supervisor"}); err != nil || count != 0 {
+		t.Errorf("second Bounce() = %d, %v; want 0", count, err)
+	}
+}
+
+func TestCleanupOrphanedBounces(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir)
+	repoName := "test-repo"
+
+	if _, err := m.Send(repoName, "supervisor", "dead-worker", "pick up issue 12"); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	count, err := m.CleanupOrphaned(repoName, []string{"supervisor"})
+	if err != nil || count != 1 {
+		t.Fatalf("CleanupOrphaned() = %d, %v; want 1", count, err)
+	}
+
+	notices, _ := m.List(repoName, "supervisor")
+	if len(notices) != 1 || !strings.Contains(notices[0].Body, "could not deliver to dead-worker") || !strings.Contains(notices[0].Body, "> pick up issue 12") {
+		t.Errorf("supervisor should be told its message bounced, got %+v", notices)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, repoName, "dead-worker")); !os.IsNotExist(err) {
+		t.Error("orphaned directory should be removed after bouncing")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir)
+	repoName := "test-repo"
+
+	// Nothing sent yet
+	summary, err := m.Summary(repoName)
+	if err != nil {
+		t.Fatalf("Summary() failed: %v", err)
+	}
+	if summary.TotalMessages != 0 || summary.OldestPending != nil {
+		t.Errorf("Summary() for empty repo = %+v, want zero", summary)
+	}
+
+	var sent []*Message
+	for _, to := range []string{"worker1", "worker1", "worker1", "worker2", "worker2"} {
+		msg, err := m.Send(repoName, "supervisor", to, "hello")
+		if err != nil {
+			t.Fatalf("Send() failed: %v", err)
+		}
+		sent = append(sent, msg)
+	}
+
+	if err := m.UpdateStatus(repoName, "worker1", sent[0].ID, StatusDelivered); err != nil {
+		t.Fatalf("UpdateStatus(delivered) failed: %v", err)
+	}
+	if err := m.UpdateStatus(repoName, "worker1", sent[1].ID, StatusRead); err != nil {
+		t.Fatalf("UpdateStatus(read) failed: %v", err)
+	}
+	if err := m.Ack(repoName, "worker2", sent[3].ID); err != nil {
+		t.Fatalf("Ack() failed: %v", err)
+	}
+
+	// Age the last pending message past ExpiryAge
+	old := sent[4]
+	old.Timestamp = time.Now().Add(-ExpiryAge - time.Hour)
+	if err := m.write(repoName, "worker2", old); err != nil {
+		t.Fatalf("write() failed: %v", err)
+	}
+
+	summary, err = m.Summary(repoName)
+	if err != nil {
+		t.Fatalf("Summary() failed: %v", err)
+	}
+	want := MessageSummary{
+		TotalMessages:     5,
+		PendingMessages:   2,
+		DeliveredMessages: 1,
+		ReadMessages:      1,
+		AckedMessages:     1,
+		ExpiredMessages:   1,
+	}
+	got := *summary
+	got.OldestPending = nil
+	if got != want {
+		t.Errorf("Summary() = %+v, want %+v", got, want)
+	}
+	if summary.Unread() != 3 {
+		t.Errorf("Unread() = %d, want 3", summary.Unread())
+	}
+	if summary.OldestPending == nil || !summary.OldestPending.Equal(old.Timestamp) {
+		t.Errorf("OldestPending = %v, want %v", summary.OldestPending, old.Timestamp)
+	}
+
+	agentSummary, err := m.AgentSummary(repoName, "worker1")
+	if err != nil {
+		t.Fatalf("AgentSummary() failed: %v", err)
+	}
+	if agentSummary.TotalMessages != 3 || agentSummary.PendingMessages != 1 || agentSummary.DeliveredMessages != 1 || agentSummary.ReadMessages != 1 {
+		t.Errorf("AgentSummary(worker1) = %+v", agentSummary)
+	}
+	if agentSummary.ExpiredMessages != 0 {
+		t.Errorf("AgentSummary(worker1).ExpiredMessages = %d, want 0", agentSummary.ExpiredMessages)
+	}
+}
+
+func TestSendOnce(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir)
+
+	first, duplicate, err := m.SendOnce("test-repo", "supervisor", "worker1", "Rebase on main", "rebase-1", nil)
+	if err != nil || duplicate {
+		t.Fatalf("SendOnce() = %v, %v; want a new message", duplicate, err)
+	}
+	if first.IdempotencyKey != "rebase-1" {
+		t.Errorf("IdempotencyKey = %q, want rebase-1", first.IdempotencyKey)
+	}
+
+	// The same key returns the same message without writing another
+	again, duplicate, err := m.SendOnce("test-repo", "supervisor", "worker1", "Rebase on main", "rebase-1", nil)
+	if err != nil || !duplicate || again.ID != first.ID {
+		t.Errorf("SendOnce() again = %+v, %v, %v; want message %s as a duplicate", again, duplicate, err, first.ID)
+	}
+	if msgs, _ := m.List("test-repo", "worker1"); len(msgs) != 1 {
+		t.Errorf("List() length = %d, want 1", len(msgs))
+	}
+
+	// Keys are per sender and recipient
+	if _, duplicate, _ := m.SendOnce("test-repo", "merge-queue", "worker1", "Rebase on main", "rebase-1", nil); duplicate {
+		t.Error("the same key from another sender should send a new message")
+	}
+	if _, duplicate, _ := m.SendOnce("test-repo", "supervisor", "worker2", "Rebase on main", "rebase-1", nil); duplicate {
+		t.Error("the same key to another recipient should send a new message")
+	}
+	if _, duplicate, _ := m.SendOnce("test-repo", "supervisor", "worker1", "Rebase on main", "rebase-2", nil); duplicate {
+		t.Error("another key should send a new message")
+	}
+
+	// Once the message is gone the key can be used again
+	if err := m.Delete("test-repo", "worker1", first.ID); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if msg, duplicate, _ := m.SendOnce("test-repo", "supervisor", "worker1", "Rebase on main", "rebase-1", nil); duplicate || msg.ID == first.ID {
+		t.Error("a key whose message was deleted should send a new message")
+	}
+
+	if _, _, err := m.SendOnce("test-repo", "supervisor", "worker1", "hi", "", nil); err == nil {
+		t.Error("SendOnce() with an empty key should fail")
+	}
+}
+
+func TestSendOnceConcurrent(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir)
+
+	const senders = 10
+	ids := make(chan string, senders)
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg, _, err := m.SendOnce("test-repo", "supervisor", "worker1", "Rebase on main", "rebase-1", nil)
+			if err != nil {
+				t.Errorf("SendOnce() failed: %v", err)
+				return
+			}
+			ids <- msg.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool)
+	for id := range ids {
+		seen[id] = true
+	}
+	if len(seen) != 1 {
+		t.Errorf("concurrent sends with one key returned %d message IDs, want 1", len(seen))
+	}
+	if msgs, _ := m.List("test-repo", "worker1"); len(msgs) != 1 {
+		t.Errorf("List() length = %d, want 1", len(msgs))
+	}
+}
+
+func TestSendSpillsLargeBodies(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir)
+
+	body := strings.Repeat("FAIL: TestSomething\n", 1000) // ~20 KB
+	msg, err := m.Send("test-repo", "worker1", "supervisor", body)
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if !msg.Spilled() || msg.BodyFile != msg.ID+".body" {
+		t.Fatalf("a %d byte body should be spilled, got BodyFile %q", len(body), msg.BodyFile)
+	}
+	if len(msg.Body) > 2*excerptSize || !strings.HasPrefix(msg.Body, "FAIL: TestSomething") {
+		t.Errorf("Body should be a short excerpt, got %d bytes", len(msg.Body))
+	}
+	if !strings.Contains(msg.Body, "multiclaude agent read-message "+msg.ID+" --full") {
+		t.Errorf("Body should say how to read the rest, got %q", msg.Body[len(msg.Body)-200:])
+	}
+
+	stored, err := m.Get("test-repo", "supervisor", msg.ID)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	full, err := m.FullBody("test-repo", "supervisor", stored)
+	if err != nil || full != body {
+		t.Errorf("FullBody() returned %d bytes, %v; want the original %d bytes", len(full), err, len(body))
+	}
+
+	// The body file is not listed as a message and goes with its message
+	if msgs, _ := m.List("test-repo", "supervisor"); len(msgs) != 1 {
+		t.Errorf("List() length = %d, want 1", len(msgs))
+	}
+	if err := m.Delete("test-repo", "supervisor", msg.ID); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "test-repo", "supervisor", msg.BodyFile)); !os.IsNotExist(err) {
+		t.Errorf("body file should be deleted with its message, stat error %v", err)
+	}
+
+	// Small bodies are stored inline
+	small, err := m.Send("test-repo", "worker1", "supervisor", "all good")
+	if err != nil || small.Spilled() || small.Body != "all good" {
+		t.Errorf("small message = %+v, %v; want it inline", small, err)
+	}
+	if full, _ := m.FullBody("test-repo", "supervisor", small); full != "all good" {
+		t.Errorf("FullBody() of an inline message = %q", full)
+	}
+}
+
+func TestSendLimits(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir).WithLimits(Limits{MaxBodySize: 100, HardCap: 1000})
+
+	if msg, err := m.Send("test-repo", "worker1", "supervisor", strings.Repeat("a", 100)); err != nil || msg.Spilled() {
+		t.Errorf("a body at the limit should be inline: %+v, %v", msg, err)
+	}
+	msg, err := m.Send("test-repo", "worker1", "supervisor", strings.Repeat("é", 60))
+	if err != nil || !msg.Spilled() {
+		t.Fatalf("a body over the limit should be spilled: %+v, %v", msg, err)
+	}
+	// The excerpt is capped by the limit and does not split characters
+	excerpt := strings.SplitN(msg.Body, "\n\n", 2)[0]
+	if len(excerpt) > 100 || !utf8.ValidString(excerpt) {
+		t.Errorf("excerpt = %q, want at most 100 bytes of valid UTF-8", excerpt)
+	}
+
+	_, err = m.Send("test-repo", "worker1", "supervisor", strings.Repeat("a", 1001))
+	var tooLarge *BodyTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Size != 1001 || tooLarge.Cap != 1000 {
+		t.Errorf("Send() over the hard cap = %v, want a BodyTooLargeError", err)
+	}
+	if msgs, _ := m.List("test-repo", "supervisor"); len(msgs) != 2 {
+		t.Errorf("List() length = %d, want 2 (nothing written for the rejected body)", len(msgs))
+	}
+}
+
+func TestScheduleMessage(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir)
+	now := time.Now()
+
+	later := now.Add(3 * time.Hour)
+	soon := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+	for _, at := range []*time.Time{&later, &soon, &past} {
+		if _, err := m.Schedule("test-repo", "supervisor", "worker1", "hello", at); err != nil {
+			t.Fatalf("Schedule() failed: %v", err)
+		}
+	}
+	plain, err := m.Send("test-repo", "supervisor", "worker2", "now")
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if plain.ScheduledFor != nil || plain.IsScheduled(now) {
+		t.Error("Send() should not schedule the message")
+	}
+
+	scheduled, err := m.ListScheduled("test-repo", now)
+	if err != nil {
+		t.Fatalf("ListScheduled() failed: %v", err)
+	}
+	if len(scheduled) != 2 {
+		t.Fatalf("ListScheduled() returned %d messages, want the 2 in the future", len(scheduled))
+	}
+	if !scheduled[0].ScheduledFor.Equal(soon) || !scheduled[1].ScheduledFor.Equal(later) {
+		t.Errorf("ListScheduled() should order messages soonest first, got %v then %v", scheduled[0].ScheduledFor, scheduled[1].ScheduledFor)
+	}
+
+	// Find searches every agent's messages
+	found, err := m.Find("test-repo", plain.ID)
+	if err != nil || found.To != "worker2" {
+		t.Errorf("Find() = %v, %v; want the message to worker2", found, err)
+	}
+	if _, err := m.Find("test-repo", "msg-missing"); err == nil {
+		t.Error("Find() should fail for an unknown message")
+	}
+}
+
+func TestScheduledMessageExpiry(t *testing.T) {
+	now := time.Now()
+	stale := now.Add(-ExpiryAge - time.Minute)
+	recent := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		msg     Message
+		expired bool
+	}{
+		{"pending past expiry", Message{Status: StatusPending, ScheduledFor: &stale}, true},
+		{"pending recently due", Message{Status: StatusPending, ScheduledFor: &recent}, false},
+		{"pending in the future", Message{Status: StatusPending, ScheduledFor: &future}, false},
+		{"delivered past expiry", Message{Status: StatusDelivered, ScheduledFor: &stale}, false},
+		{"unscheduled", Message{Status: StatusPending, Timestamp: stale}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.msg.IsExpiredSchedule(now); got != tt.expired {
+				t.Errorf("IsExpiredSchedule() = %v, want %v", got, tt.expired)
+			}
+		})
+	}
+
+	// A message scheduled for later is neither overdue nor expired
+	summary := &MessageSummary{}
+	summary.add([]*Message{{Status: StatusPending, Timestamp: stale, ScheduledFor: &future}}, now)
+	if summary.ExpiredMessages != 0 || summary.OldestPending != nil {
+		t.Errorf("summary = %+v, want a future scheduled message not counted as overdue", summary)
+	}
+}
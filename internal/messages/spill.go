@@ -0,0 +1,118 @@
+package messages
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// DefaultMaxBodySize is the largest body delivered inline when a repository
+// does not set one. Larger bodies are spilled to a file so that pasting a
+// message into an agent's terminal stays quick and its context small.
+const DefaultMaxBodySize = 8 * 1024
+
+// DefaultHardCap is the largest body accepted at all when a repository does
+// not set one
+const DefaultHardCap = 5 * 1024 * 1024
+
+// excerptSize is how much of a spilled body is kept inline
+const excerptSize = 1024
+
+// bodyFileExt is the extension of spilled bodies; List skips these files as
+// they are not .json
+const bodyFileExt = ".body"
+
+// Limits bound the size of message bodies. Zero values use the defaults.
+type Limits struct {
+	// MaxBodySize is the largest body delivered inline
+	MaxBodySize int
+	// HardCap is the largest body accepted
+	HardCap int
+}
+
+// EffectiveMaxBodySize returns MaxBodySize, or the default when it is unset
+func (l Limits) EffectiveMaxBodySize() int {
+	if l.MaxBodySize > 0 {
+		return l.MaxBodySize
+	}
+	return DefaultMaxBodySize
+}
+
+// EffectiveHardCap returns HardCap, or the default when it is unset
+func (l Limits) EffectiveHardCap() int {
+	if l.HardCap > 0 {
+		return l.HardCap
+	}
+	return DefaultHardCap
+}
+
+// BodyTooLargeError is returned for a body over the hard cap
+type BodyTooLargeError struct {
+	Size int
+	Cap  int
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("message body is %d bytes, over the %d byte limit", e.Size, e.Cap)
+}
+
+// WithLimits returns a manager for the same messages that enforces limits
+func (m *Manager) WithLimits(limits Limits) *Manager {
+	return &Manager{messagesRoot: m.messagesRoot, limits: limits}
+}
+
+// Spilled reports whether the message body was too large to deliver inline,
+// so Body only has an excerpt
+func (msg *Message) Spilled() bool {
+	return msg.BodyFile != ""
+}
+
+// FullBody returns the complete body of a message to agentName, reading it
+// from its file if it was spilled
+func (m *Manager) FullBody(repoName, agentName string, msg *Message) (string, error) {
+	if !msg.Spilled() {
+		return msg.Body, nil
+	}
+	data, err := os.ReadFile(filepath.Join(m.agentDir(repoName, agentName), msg.BodyFile))
+	if err != nil {
+		return "", fmt.Errorf("failed to read message body: %w", err)
+	}
+	return string(data), nil
+}
+
+// spill writes the body of a new message to a file in the recipient's
+// message directory and replaces it with an excerpt that says how to read
+// the rest
+func (m *Manager) spill(repoName string, msg *Message) error {
+	if err := m.ensureAgentDir(repoName, msg.To); err != nil {
+		return err
+	}
+
+	body := msg.Body
+	bodyFile := msg.ID + bodyFileExt
+	if err := os.WriteFile(filepath.Join(m.agentDir(repoName, msg.To), bodyFile), []byte(body), 0644); err != nil {
+		return fmt.Errorf("failed to write message body: %w", err)
+	}
+
+	size := excerptSize
+	if max := m.limits.EffectiveMaxBodySize(); size > max {
+		size = max
+	}
+	msg.BodyFile = bodyFile
+	msg.Body = fmt.Sprintf("%s\n\n[... excerpt of a %d byte message. Read all of it with: multiclaude agent read-message %s --full]",
+		excerpt(body, size), len(body), msg.ID)
+	return nil
+}
+
+// excerpt returns at most size bytes from the start of s without splitting
+// a UTF-8 character
+func excerpt(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	for size > 0 && !utf8.RuneStart(s[size]) {
+		size--
+	}
+	return s[:size]
+}
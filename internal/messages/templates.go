@@ -0,0 +1,195 @@
+package messages
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Built-in message templates, used unless a repository defines its own
+// template of the same name
+//
+//go:embed templates/*.md
+var templateFS embed.FS
+
+// TemplateSourceBuiltin is the Source of templates shipped with multiclaude
+const TemplateSourceBuiltin = "built-in"
+
+// templateVar matches a {{name}} placeholder
+var templateVar = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_-]+)\s*\}\}`)
+
+// templateDescription matches an optional leading <!-- description --> line
+var templateDescription = regexp.MustCompile(`^<!--\s*(.*?)\s*-->\n?`)
+
+// Template is a reusable message body with {{var}} placeholders
+type Template struct {
+	Name        string
+	Description string
+	Body        string
+	Source      string // TemplateSourceBuiltin or the path of the repository's template file
+}
+
+// MissingVariablesError is returned by Render when placeholders have no value
+type MissingVariablesError struct {
+	Template string
+	Missing  []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return fmt.Sprintf("template %q is missing variables: %s", e.Template, strings.Join(e.Missing, ", "))
+}
+
+// TemplatesDir returns the directory a repository keeps its message templates in
+func TemplatesDir(repoPath string) string {
+	return filepath.Join(repoPath, ".multiclaude", "messages")
+}
+
+// BuiltinTemplates returns the templates shipped with multiclaude, sorted by name
+func BuiltinTemplates() []Template {
+	entries, _ := templateFS.ReadDir("templates")
+
+	var templates []Template
+	for _, entry := range entries {
+		content, err := templateFS.ReadFile("templates/" + entry.Name())
+		if err != nil {
+			continue
+		}
+		templates = append(templates, parseTemplate(entry.Name(), string(content), TemplateSourceBuiltin))
+	}
+	return templates
+}
+
+// LoadTemplates returns the built-in templates merged with those in the
+// repository's .multiclaude/messages directory, sorted by name. Repository
+// templates replace built-in ones of the same name.
+func LoadTemplates(repoPath string) ([]Template, error) {
+	byName := make(map[string]Template)
+	for _, t := range BuiltinTemplates() {
+		byName[t.Name] = t
+	}
+
+	repoTemplates, err := LoadRepoTemplates(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range repoTemplates {
+		byName[t.Name] = t
+	}
+
+	templates := make([]Template, 0, len(byName))
+	for _, t := range byName {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// LoadRepoTemplates returns only the templates defined in the repository,
+// sorted by name. A missing templates directory is not an error.
+func LoadRepoTemplates(repoPath string) ([]Template, error) {
+	dir := TemplatesDir(repoPath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read message templates: %w", err)
+	}
+
+	var templates []Template
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message template %s: %w", entry.Name(), err)
+		}
+		templates = append(templates, parseTemplate(entry.Name(), string(content), path))
+	}
+	return templates, nil
+}
+
+// FindTemplate looks up the named template from LoadTemplates
+func FindTemplate(repoPath, name string) (Template, bool, error) {
+	templates, err := LoadTemplates(repoPath)
+	if err != nil {
+		return Template{}, false, err
+	}
+	for _, t := range templates {
+		if t.Name == name {
+			return t, true, nil
+		}
+	}
+	return Template{}, false, nil
+}
+
+// parseTemplate builds a Template from a file name and its contents
+func parseTemplate(filename, content, source string) Template {
+	t := Template{
+		Name:   strings.TrimSuffix(filename, ".md"),
+		Body:   content,
+		Source: source,
+	}
+	if m := templateDescription.FindStringSubmatch(content); m != nil {
+		t.Description = m[1]
+		t.Body = content[len(m[0]):]
+	}
+	t.Body = strings.TrimSpace(t.Body)
+	return t
+}
+
+// Variables returns the names of the placeholders in the template, in order
+// of first appearance
+func (t Template) Variables() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range templateVar.FindAllStringSubmatch(t.Body, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// Render substitutes vars into the template's placeholders. Every
+// placeholder must have a value; otherwise a *MissingVariablesError lists
+// the ones without.
+func (t Template) Render(vars map[string]string) (string, error) {
+	var missing []string
+	for _, name := range t.Variables() {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", &MissingVariablesError{Template: t.Name, Missing: missing}
+	}
+
+	return templateVar.ReplaceAllStringFunc(t.Body, func(placeholder string) string {
+		return vars[templateVar.FindStringSubmatch(placeholder)[1]]
+	}), nil
+}
+
+// TemplatesDoc returns a markdown list of templates, their descriptions and
+// the variables they take
+func TemplatesDoc(templates []Template) string {
+	var sb strings.Builder
+	for _, t := range templates {
+		sb.WriteString(fmt.Sprintf("- `%s`", t.Name))
+		if t.Description != "" {
+			sb.WriteString(" - " + t.Description)
+		}
+		if vars := t.Variables(); len(vars) > 0 {
+			sb.WriteString(fmt.Sprintf(" (variables: %s)", strings.Join(vars, ", ")))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,131 @@
+package messages
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinTemplates(t *testing.T) {
+	templates := BuiltinTemplates()
+
+	var names []string
+	for _, tmpl := range templates {
+		names = append(names, tmpl.Name)
+		if tmpl.Description == "" {
+			t.Errorf("built-in template %q has no description", tmpl.Name)
+		}
+		if strings.Contains(tmpl.Body, "<!--") {
+			t.Errorf("built-in template %q body still contains its description comment", tmpl.Name)
+		}
+		if tmpl.Source != TemplateSourceBuiltin {
+			t.Errorf("Source = %q, want %q", tmpl.Source, TemplateSourceBuiltin)
+		}
+	}
+
+	want := []string{"open-pr", "rebase", "status-update"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("built-in templates = %v, want %v", names, want)
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	tmpl := Template{
+		Name: "greet",
+		Body: "Hi {{name}}, please look at {{ branch }}. Thanks, {{name}}",
+	}
+
+	if got, want := tmpl.Variables(), []string{"name", "branch"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Variables() = %v, want %v", got, want)
+	}
+
+	got, err := tmpl.Render(map[string]string{"name": "worker1", "branch": "main", "unused": "x"})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if want := "Hi worker1, please look at main. Thanks, worker1"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	_, err = tmpl.Render(map[string]string{"other": "x"})
+	var missingErr *MissingVariablesError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("Render() error = %v, want *MissingVariablesError", err)
+	}
+	if !reflect.DeepEqual(missingErr.Missing, []string{"name", "branch"}) {
+		t.Errorf("Missing = %v, want [name branch]", missingErr.Missing)
+	}
+	if !strings.Contains(err.Error(), "name, branch") {
+		t.Errorf("error should list missing variables, got: %v", err)
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	repoPath := t.TempDir()
+
+	// No templates directory: only built-ins
+	templates, err := LoadTemplates(repoPath)
+	if err != nil {
+		t.Fatalf("LoadTemplates() failed: %v", err)
+	}
+	if len(templates) != len(BuiltinTemplates()) {
+		t.Errorf("expected only built-in templates, got %d", len(templates))
+	}
+
+	dir := TemplatesDir(repoPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create templates dir: %v", err)
+	}
+	files := map[string]string{
+		"rebase.md":   "<!-- Repo-specific rebase -->\nRebase onto {{base}} please.\n",
+		"deploy.md":   "Deploy {{service}} now.",
+		"ignored.txt": "not a template",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write template: %v", err)
+		}
+	}
+
+	templates, err = LoadTemplates(repoPath)
+	if err != nil {
+		t.Fatalf("LoadTemplates() failed: %v", err)
+	}
+	var names []string
+	for _, tmpl := range templates {
+		names = append(names, tmpl.Name)
+	}
+	if want := []string{"deploy", "open-pr", "rebase", "status-update"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("templates = %v, want %v", names, want)
+	}
+
+	rebase, found, err := FindTemplate(repoPath, "rebase")
+	if err != nil || !found {
+		t.Fatalf("FindTemplate() = %v, %v; want the repo template", found, err)
+	}
+	if rebase.Description != "Repo-specific rebase" || rebase.Body != "Rebase onto {{base}} please." {
+		t.Errorf("repo template should override built-in, got %+v", rebase)
+	}
+	if rebase.Source == TemplateSourceBuiltin {
+		t.Error("repo template Source should be its file path")
+	}
+
+	if _, found, err := FindTemplate(repoPath, "missing"); err != nil || found {
+		t.Errorf("FindTemplate() for an unknown template = %v, %v; want not found", found, err)
+	}
+}
+
+func TestTemplatesDoc(t *testing.T) {
+	doc := TemplatesDoc([]Template{
+		{Name: "deploy", Description: "Ship it", Body: "Deploy {{service}}"},
+		{Name: "plain", Body: "No variables"},
+	})
+
+	want := "- `deploy` - Ship it (variables: service)\n- `plain`\n"
+	if doc != want {
+		t.Errorf("TemplatesDoc() = %q, want %q", doc, want)
+	}
+}
@@ -0,0 +1,246 @@
+package names
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Scheme is a way of generating agent names
+type Scheme string
+
+const (
+	// SchemeDocker generates Docker-style names, e.g. happy-platypus
+	SchemeDocker Scheme = "docker"
+	// SchemeDated generates names from the user, date and task, e.g.
+	// jd-0612-fix-login
+	SchemeDated Scheme = "dated"
+	// SchemeTaskSlug generates names from the first words of the task,
+	// e.g. fix-login-redirect
+	SchemeTaskSlug Scheme = "task-slug"
+	// SchemeTemplate fills a template such as "{user}-{slug}"
+	SchemeTemplate Scheme = "template"
+)
+
+// Schemes lists the naming schemes, for help and error messages
+var Schemes = []Scheme{SchemeDocker, SchemeDated, SchemeTaskSlug, SchemeTemplate}
+
+// datedTemplate is the template of SchemeDated
+const datedTemplate = "{user}-{date}-{slug}"
+
+// SlugWords is how many words of a task a slug keeps
+const SlugWords = 4
+
+// maxSlugLen caps the length of a slug, so names stay usable as tmux window
+// and branch names
+const maxSlugLen = 32
+
+// maxCollisionSuffix bounds the -2, -3, ... suffixes tried for a taken name
+const maxCollisionSuffix = 100
+
+// templateVar matches a {variable} in a name template
+var templateVar = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// templateVars are the variables a name template can use
+var templateVars = map[string]bool{
+	"user": true, // the user's login name
+	"date": true, // month and day, e.g. 0612
+	"slug": true, // the first words of the task, e.g. fix-login-redirect
+	"name": true, // a Docker-style name, e.g. happy-platypus
+}
+
+// Config selects how a repository's agents are named. The zero value uses
+// Docker-style names.
+type Config struct {
+	Scheme   Scheme
+	Template string // used by SchemeTemplate
+}
+
+// Vars are the values names are generated from
+type Vars struct {
+	User string
+	Date time.Time
+	Task string
+}
+
+// ParseScheme checks a scheme name. An empty name is SchemeDocker.
+func ParseScheme(s string) (Scheme, error) {
+	if s == "" {
+		return SchemeDocker, nil
+	}
+	for _, scheme := range Schemes {
+		if Scheme(s) == scheme {
+			return scheme, nil
+		}
+	}
+	return "", fmt.Errorf("unknown name scheme %q (must be one of: %s)", s, schemeList())
+}
+
+// ValidateTemplate checks that a name template uses only known variables
+// and at least one of them, so that different tasks can get different names
+func ValidateTemplate(template string) error {
+	if template == "" {
+		return fmt.Errorf("name template cannot be empty")
+	}
+	matches := templateVar.FindAllStringSubmatch(template, -1)
+	if len(matches) == 0 {
+		return fmt.Errorf("name template %q uses no variables (available: {user}, {date}, {slug}, {name})", template)
+	}
+	for _, m := range matches {
+		if !templateVars[m[1]] {
+			return fmt.Errorf("name template %q uses unknown variable {%s} (available: {user}, {date}, {slug}, {name})", template, m[1])
+		}
+	}
+	if rest := templateVar.ReplaceAllString(template, ""); strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("name template %q has an unmatched brace", template)
+	}
+	return nil
+}
+
+// Generate returns a name for an agent that taken reports as free. A name
+// that is taken gets a -2, -3, ... suffix; Docker-style names are first
+// drawn again a few times. The name is checked with Violation, so a
+// template cannot produce an unusable name.
+func (c Config) Generate(vars Vars, taken func(string) bool) (string, error) {
+	isTaken := func(name string) bool { return taken(name) || IsReserved(name) }
+
+	var base string
+	switch c.Scheme {
+	case "", SchemeDocker:
+		for attempt := 0; attempt < 10; attempt++ {
+			base = Generate()
+			if !isTaken(base) {
+				return base, nil
+			}
+		}
+	case SchemeDated:
+		base = expand(datedTemplate, vars)
+	case SchemeTaskSlug:
+		base = Slug(vars.Task, SlugWords)
+	case SchemeTemplate:
+		if err := ValidateTemplate(c.Template); err != nil {
+			return "", err
+		}
+		base = expand(c.Template, vars)
+	default:
+		return "", fmt.Errorf("unknown name scheme %q (must be one of: %s)", c.Scheme, schemeList())
+	}
+
+	if reason := Violation(base); reason != "" {
+		return "", fmt.Errorf("generated name %q is invalid: %s", base, reason)
+	}
+	if !isTaken(base) {
+		return base, nil
+	}
+	for n := 2; n <= maxCollisionSuffix; n++ {
+		if name := fmt.Sprintf("%s-%d", base, n); !isTaken(name) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no free name based on %q", base)
+}
+
+// expand fills the variables of a name template
+func expand(template string, vars Vars) string {
+	return templateVar.ReplaceAllStringFunc(template, func(v string) string {
+		switch v {
+		case "{user}":
+			if user := slugWords(vars.User, 3); user != "" {
+				return user
+			}
+			return "user"
+		case "{date}":
+			return vars.Date.Format("0102")
+		case "{slug}":
+			return Slug(vars.Task, SlugWords)
+		case "{name}":
+			return Generate()
+		}
+		return v
+	})
+}
+
+// foldAccents maps common accented Latin letters to ASCII
+var foldAccents = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae",
+	'ç': "c", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ñ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'œ': "oe",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'ÿ': "y", 'ß': "ss",
+}
+
+// Slug turns the first words of text into a lowercase, hyphen-separated
+// ASCII name, e.g. "Fix the login redirect!" becomes "fix-the-login-redirect".
+// Accented Latin letters lose their accents, apostrophes are dropped and
+// any other character separates words. Text without usable words gives
+// "task".
+func Slug(text string, words int) string {
+	if slug := slugWords(text, words); slug != "" {
+		return slug
+	}
+	return "task"
+}
+
+// slugWords is Slug, returning "" for text without usable words
+func slugWords(text string, words int) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '\'' || r == '’':
+			// "don't" is one word
+		case foldAccents[r] != "":
+			b.WriteString(foldAccents[r])
+		default:
+			// Spaces, punctuation, symbols and letters of scripts that
+			// cannot be spelled in ASCII separate words
+			b.WriteRune(' ')
+		}
+	}
+
+	fields := strings.Fields(b.String())
+	if len(fields) > words {
+		fields = fields[:words]
+	}
+	slug := ""
+	for _, field := range fields {
+		next := field
+		if slug != "" {
+			next = slug + "-" + field
+		}
+		if len(next) > maxSlugLen {
+			if slug == "" {
+				slug = field[:maxSlugLen]
+			}
+			break
+		}
+		slug = next
+	}
+	return slug
+}
+
+// CurrentUser returns the login name of the user running multiclaude, for
+// the {user} variable
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		// Windows usernames include the domain
+		if i := strings.LastIndex(u.Username, `\`); i >= 0 {
+			return u.Username[i+1:]
+		}
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// schemeList joins the scheme names for error messages
+func schemeList() string {
+	list := make([]string, len(Schemes))
+	for i, scheme := range Schemes {
+		list[i] = string(scheme)
+	}
+	return strings.Join(list, ", ")
+}
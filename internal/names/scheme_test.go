@@ -0,0 +1,141 @@
+package names
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Fix the login redirect", "fix-the-login-redirect"},
+		{"Fix the login redirect on Safari", "fix-the-login-redirect"},
+		{"  fix   login  ", "fix-login"},
+		{"Fix login (again)!!", "fix-login-again"},
+		{"Don't crash on empty input", "dont-crash-on-empty"},
+		{"Don’t crash", "dont-crash"},
+		{"feat: add --verbose flag", "feat-add-verbose-flag"},
+		{"v1.2.3 release notes", "v1-2-3-release"},
+		{"Übersetzung für Straße", "ubersetzung-fur-strasse"},
+		{"Café naïve résumé", "cafe-naive-resume"},
+		{"修复 login bug", "login-bug"},
+		{"🚀 ship it 🚀", "ship-it"},
+		{"日本語", "task"},
+		{"!!!", "task"},
+		{"", "task"},
+		{strings.Repeat("a", 40) + " b", strings.Repeat("a", maxSlugLen)},
+		{"implement authentication middleware refactoring", "implement-authentication"},
+	}
+
+	for _, tt := range tests {
+		if got := Slug(tt.text, SlugWords); got != tt.want {
+			t.Errorf("Slug(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+		if got := Slug(tt.text, SlugWords); Violation(got) != "" {
+			t.Errorf("Slug(%q) = %q is not a valid name: %s", tt.text, got, Violation(got))
+		}
+	}
+}
+
+func TestParseScheme(t *testing.T) {
+	for _, valid := range []string{"docker", "dated", "task-slug", "template"} {
+		if scheme, err := ParseScheme(valid); err != nil || string(scheme) != valid {
+			t.Errorf("ParseScheme(%q) = %q, %v", valid, scheme, err)
+		}
+	}
+	if scheme, err := ParseScheme(""); err != nil || scheme != SchemeDocker {
+		t.Errorf("ParseScheme(\"\") = %q, %v, want docker", scheme, err)
+	}
+	if _, err := ParseScheme("random"); err == nil {
+		t.Error("ParseScheme(\"random\") should fail")
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	for _, valid := range []string{"{user}-{date}-{slug}", "{slug}", "team-{name}"} {
+		if err := ValidateTemplate(valid); err != nil {
+			t.Errorf("ValidateTemplate(%q) failed: %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"", "worker", "{owner}-{slug}", "{slug", "{slug}}"} {
+		if err := ValidateTemplate(invalid); err == nil {
+			t.Errorf("ValidateTemplate(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestConfigGenerate(t *testing.T) {
+	vars := Vars{User: "jd", Date: time.Date(2026, 6, 12, 9, 0, 0, 0, time.UTC), Task: "Fix login redirect"}
+	free := func(string) bool { return false }
+
+	tests := []struct {
+		config Config
+		want   string
+	}{
+		{Config{Scheme: SchemeDated}, "jd-0612-fix-login-redirect"},
+		{Config{Scheme: SchemeTaskSlug}, "fix-login-redirect"},
+		{Config{Scheme: SchemeTemplate, Template: "{user}/{slug}"}, "jd/fix-login-redirect"},
+		{Config{Scheme: SchemeTemplate, Template: "team-{date}"}, "team-0612"},
+	}
+	for _, tt := range tests {
+		got, err := tt.config.Generate(vars, free)
+		if err != nil {
+			t.Errorf("%+v: Generate() failed: %v", tt.config, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%+v: Generate() = %q, want %q", tt.config, got, tt.want)
+		}
+	}
+
+	// The zero value and docker give Docker-style names
+	for _, config := range []Config{{}, {Scheme: SchemeDocker}} {
+		got, err := config.Generate(vars, free)
+		if err != nil || len(strings.Split(got, "-")) != 2 {
+			t.Errorf("%+v: Generate() = %q, %v, want adjective-animal", config, got, err)
+		}
+	}
+}
+
+func TestConfigGenerateCollisions(t *testing.T) {
+	vars := Vars{Task: "fix login"}
+	config := Config{Scheme: SchemeTaskSlug}
+
+	taken := map[string]bool{"fix-login": true, "fix-login-2": true}
+	got, err := config.Generate(vars, func(name string) bool { return taken[name] })
+	if err != nil || got != "fix-login-3" {
+		t.Errorf("Generate() = %q, %v, want fix-login-3", got, err)
+	}
+
+	// Reserved names count as taken
+	got, err = config.Generate(Vars{Task: "supervisor"}, func(string) bool { return false })
+	if err != nil || got != "supervisor-2" {
+		t.Errorf("Generate() for a reserved name = %q, %v, want supervisor-2", got, err)
+	}
+
+	// Running out of suffixes is an error rather than a duplicate
+	if _, err := config.Generate(vars, func(string) bool { return true }); err == nil {
+		t.Error("Generate() should fail when every name is taken")
+	}
+}
+
+func TestConfigGenerateInvalid(t *testing.T) {
+	vars := Vars{User: "jd", Task: "fix login"}
+	free := func(string) bool { return false }
+
+	for _, config := range []Config{
+		{Scheme: "random"},
+		{Scheme: SchemeTemplate},
+		{Scheme: SchemeTemplate, Template: "{owner}"},
+		// The template is valid but the name is not a valid branch name
+		{Scheme: SchemeTemplate, Template: "{slug}..{user}"},
+		{Scheme: SchemeTemplate, Template: "-{slug}"},
+	} {
+		if got, err := config.Generate(vars, free); err == nil {
+			t.Errorf("%+v: Generate() = %q, want an error", config, got)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package names
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reserved are names used by the persistent agents (and their tmux windows)
+// that workers must never take
+var reserved = map[string]bool{
+	"supervisor":  true,
+	"merge-queue": true,
+	"workspace":   true,
+	"default":     true,
+}
+
+// IsReserved reports whether name belongs to a persistent agent
+func IsReserved(name string) bool {
+	return reserved[name]
+}
+
+// Violation returns why name cannot be used as an agent or workspace name,
+// which also names a git branch, or "" if it is valid
+func Violation(name string) string {
+	if name == "" {
+		return "name cannot be empty"
+	}
+
+	// Git branch name restrictions
+	// - Cannot start with . or -
+	// - Cannot contain consecutive dots ..
+	// - Cannot contain \ or any of these characters: ~ ^ : ? * [ @ { } space
+	// - Cannot end with . or /
+	// - Cannot be "." or ".."
+
+	if name == "." || name == ".." {
+		return "cannot be '.' or '..'"
+	}
+
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "-") {
+		return "cannot start with '.' or '-'"
+	}
+
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, "/") {
+		return "cannot end with '.' or '/'"
+	}
+
+	if strings.Contains(name, "..") {
+		return "cannot contain '..'"
+	}
+
+	invalidChars := []string{"\\", "~", "^", ":", "?", "*", "[", "@", "{", "}", " ", "\t", "\n"}
+	for _, char := range invalidChars {
+		if strings.Contains(name, char) {
+			return fmt.Sprintf("cannot contain '%s'", char)
+		}
+	}
+
+	return ""
+}
+
+// AgentViolation returns why name cannot be used as a worker name, or "" if
+// it is valid. Besides the rules of Violation, a worker name also names a
+// directory under the repository's worktrees, so it cannot contain '/', and
+// it cannot be reserved for a persistent agent.
+func AgentViolation(name string) string {
+	if reason := Violation(name); reason != "" {
+		return reason
+	}
+	if strings.Contains(name, "/") {
+		return "cannot contain '/'"
+	}
+	if IsReserved(name) {
+		return "name is reserved"
+	}
+	return ""
+}
@@ -0,0 +1,365 @@
+// Package prcomments fetches the review feedback on a pull request: inline
+// review threads, reviews and conversation comments.
+//
+// agent pr-comments shows it to the worker whose PR it is, and the daemon's
+// wake loop counts it to nudge the worker about new comments. Both use Fetch
+// and Count, so a nudge about three new review comments means three items
+// the worker's next agent pr-comments marks as new.
+package prcomments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/gh"
+)
+
+// Comment is a comment on a review thread or on the PR's conversation
+type Comment struct {
+	Author    string
+	Body      string
+	CreatedAt time.Time
+	URL       string
+}
+
+// Thread is an inline review thread on a line of the diff
+type Thread struct {
+	Path string
+	// Line is the line the thread is on, or was on when the thread is
+	// outdated; 0 for comments on a whole file
+	Line     int
+	Resolved bool
+	Outdated bool
+	Comments []Comment
+}
+
+// Review is a submitted review. Reviews that neither approve nor request
+// changes and have no body only hold inline comments, and are left out.
+type Review struct {
+	Author      string
+	State       string // APPROVED, CHANGES_REQUESTED, COMMENTED or DISMISSED
+	Body        string
+	SubmittedAt time.Time
+	URL         string
+}
+
+// Feedback is everything reviewers said on a PR, oldest first
+type Feedback struct {
+	Threads  []Thread
+	Reviews  []Review
+	Comments []Comment // on the conversation, outside any thread
+}
+
+// Count returns how many comments and reviews there are in all
+func (f *Feedback) Count() int {
+	return f.CountSince(time.Time{})
+}
+
+// CountSince returns how many comments and reviews were made after t
+func (f *Feedback) CountSince(t time.Time) int {
+	n := 0
+	for _, thread := range f.Threads {
+		for _, c := range thread.Comments {
+			if c.CreatedAt.After(t) {
+				n++
+			}
+		}
+	}
+	for _, r := range f.Reviews {
+		if r.SubmittedAt.After(t) {
+			n++
+		}
+	}
+	for _, c := range f.Comments {
+		if c.CreatedAt.After(t) {
+			n++
+		}
+	}
+	return n
+}
+
+// Latest returns when the newest comment or review was made, or the zero
+// time when there are none
+func (f *Feedback) Latest() time.Time {
+	var latest time.Time
+	later := func(t time.Time) {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	for _, thread := range f.Threads {
+		for _, c := range thread.Comments {
+			later(c.CreatedAt)
+		}
+	}
+	for _, r := range f.Reviews {
+		later(r.SubmittedAt)
+	}
+	for _, c := range f.Comments {
+		later(c.CreatedAt)
+	}
+	return latest
+}
+
+// queryFunc runs a GraphQL query; gh.GraphQL outside tests
+type queryFunc func(ctx context.Context, query string, vars map[string]interface{}, out interface{}) error
+
+// Fetch returns the feedback on pull request number of owner/name, paging
+// through as many threads, comments and reviews as there are
+func Fetch(ctx context.Context, owner, name string, number int) (*Feedback, error) {
+	return fetch(ctx, gh.GraphQL, owner, name, number)
+}
+
+func fetch(ctx context.Context, query queryFunc, owner, name string, number int) (*Feedback, error) {
+	var f Feedback
+	vars := func() map[string]interface{} {
+		return map[string]interface{}{"owner": owner, "name": name, "number": number}
+	}
+
+	err := paginate(ctx, query, threadsQuery, vars(), func(data json.RawMessage) (pageInfo, error) {
+		var page struct {
+			Repository struct {
+				PullRequest *struct {
+					ReviewThreads struct {
+						PageInfo pageInfo     `json:"pageInfo"`
+						Nodes    []threadNode `json:"nodes"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return pageInfo{}, err
+		}
+		pr := page.Repository.PullRequest
+		if pr == nil {
+			return pageInfo{}, fmt.Errorf("pull request %s/%s#%d not found", owner, name, number)
+		}
+		for _, node := range pr.ReviewThreads.Nodes {
+			thread := node.thread()
+			if node.Comments.PageInfo.HasNextPage {
+				more, err := fetchThreadComments(ctx, query, node.ID, node.Comments.PageInfo.EndCursor)
+				if err != nil {
+					return pageInfo{}, err
+				}
+				thread.Comments = append(thread.Comments, more...)
+			}
+			f.Threads = append(f.Threads, thread)
+		}
+		return pr.ReviewThreads.PageInfo, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = paginate(ctx, query, reviewsQuery, vars(), func(data json.RawMessage) (pageInfo, error) {
+		var page struct {
+			Repository struct {
+				PullRequest struct {
+					Reviews struct {
+						PageInfo pageInfo     `json:"pageInfo"`
+						Nodes    []reviewNode `json:"nodes"`
+					} `json:"reviews"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return pageInfo{}, err
+		}
+		for _, node := range page.Repository.PullRequest.Reviews.Nodes {
+			if node.Body == "" && node.State == "COMMENTED" {
+				continue
+			}
+			f.Reviews = append(f.Reviews, Review{
+				Author:      node.Author.name(),
+				State:       node.State,
+				Body:        node.Body,
+				SubmittedAt: node.SubmittedAt,
+				URL:         node.URL,
+			})
+		}
+		return page.Repository.PullRequest.Reviews.PageInfo, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = paginate(ctx, query, commentsQuery, vars(), func(data json.RawMessage) (pageInfo, error) {
+		var page struct {
+			Repository struct {
+				PullRequest struct {
+					Comments commentConnection `json:"comments"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return pageInfo{}, err
+		}
+		f.Comments = append(f.Comments, page.Repository.PullRequest.Comments.comments()...)
+		return page.Repository.PullRequest.Comments.PageInfo, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// fetchThreadComments returns the comments of a thread after cursor, for
+// threads too long for the first page
+func fetchThreadComments(ctx context.Context, query queryFunc, threadID, cursor string) ([]Comment, error) {
+	var comments []Comment
+	vars := map[string]interface{}{"id": threadID, "after": cursor}
+	err := paginate(ctx, query, threadCommentsQuery, vars, func(data json.RawMessage) (pageInfo, error) {
+		var page struct {
+			Node struct {
+				Comments commentConnection `json:"comments"`
+			} `json:"node"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return pageInfo{}, err
+		}
+		comments = append(comments, page.Node.Comments.comments()...)
+		return page.Node.Comments.PageInfo, nil
+	})
+	return comments, err
+}
+
+// paginate runs query until decode reports the last page, passing the end
+// cursor of each page as the $after of the next
+func paginate(ctx context.Context, query queryFunc, q string, vars map[string]interface{}, decode func(json.RawMessage) (pageInfo, error)) error {
+	for {
+		var data json.RawMessage
+		if err := query(ctx, q, vars, &data); err != nil {
+			return err
+		}
+		info, err := decode(data)
+		if err != nil {
+			return err
+		}
+		if !info.HasNextPage || info.EndCursor == "" {
+			return nil
+		}
+		vars["after"] = info.EndCursor
+	}
+}
+
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// actor is a GitHub user
+type actor struct {
+	Login string `json:"login"`
+}
+
+// name returns the user's login; deleted accounts are null in responses
+func (a *actor) name() string {
+	if a == nil {
+		return "ghost"
+	}
+	return a.Login
+}
+
+type commentNode struct {
+	Author    *actor    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+	URL       string    `json:"url"`
+}
+
+type commentConnection struct {
+	PageInfo pageInfo      `json:"pageInfo"`
+	Nodes    []commentNode `json:"nodes"`
+}
+
+func (c commentConnection) comments() []Comment {
+	comments := make([]Comment, 0, len(c.Nodes))
+	for _, node := range c.Nodes {
+		comments = append(comments, Comment{
+			Author:    node.Author.name(),
+			Body:      node.Body,
+			CreatedAt: node.CreatedAt,
+			URL:       node.URL,
+		})
+	}
+	return comments
+}
+
+type threadNode struct {
+	ID           string            `json:"id"`
+	Path         string            `json:"path"`
+	Line         int               `json:"line"`
+	OriginalLine int               `json:"originalLine"`
+	IsResolved   bool              `json:"isResolved"`
+	IsOutdated   bool              `json:"isOutdated"`
+	Comments     commentConnection `json:"comments"`
+}
+
+func (n threadNode) thread() Thread {
+	line := n.Line
+	if line == 0 {
+		line = n.OriginalLine
+	}
+	return Thread{
+		Path:     n.Path,
+		Line:     line,
+		Resolved: n.IsResolved,
+		Outdated: n.IsOutdated,
+		Comments: n.Comments.comments(),
+	}
+}
+
+type reviewNode struct {
+	Author      *actor    `json:"author"`
+	State       string    `json:"state"`
+	Body        string    `json:"body"`
+	SubmittedAt time.Time `json:"submittedAt"`
+	URL         string    `json:"url"`
+}
+
+const commentFields = `pageInfo { hasNextPage endCursor }
+nodes { author { login } body createdAt url }`
+
+// The queries ask for 100 nodes a page, the most GitHub allows
+const threadsQuery = `query($owner: String!, $name: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100, after: $after) {
+        pageInfo { hasNextPage endCursor }
+        nodes {
+          id path line originalLine isResolved isOutdated
+          comments(first: 100) { ` + commentFields + ` }
+        }
+      }
+    }
+  }
+}`
+
+const threadCommentsQuery = `query($id: ID!, $after: String) {
+  node(id: $id) {
+    ... on PullRequestReviewThread {
+      comments(first: 100, after: $after) { ` + commentFields + ` }
+    }
+  }
+}`
+
+const reviewsQuery = `query($owner: String!, $name: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      reviews(first: 100, after: $after) {
+        pageInfo { hasNextPage endCursor }
+        nodes { author { login } state body submittedAt url }
+      }
+    }
+  }
+}`
+
+const commentsQuery = `query($owner: String!, $name: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      comments(first: 100, after: $after) { ` + commentFields + ` }
+    }
+  }
+}`
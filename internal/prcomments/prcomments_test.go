@@ -0,0 +1,103 @@
+package prcomments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGitHub answers queries from pages of JSON data, keyed by the
+// connection queried and the $after cursor
+type fakeGitHub struct {
+	pages   map[string]string
+	queries []string
+}
+
+func (f *fakeGitHub) query(ctx context.Context, query string, vars map[string]interface{}, out interface{}) error {
+	var kind string
+	switch {
+	case strings.Contains(query, "reviewThreads"):
+		kind = "threads"
+	case strings.Contains(query, "node(id"):
+		kind = "thread " + vars["id"].(string)
+	case strings.Contains(query, "reviews("):
+		kind = "reviews"
+	default:
+		kind = "comments"
+	}
+	after, _ := vars["after"].(string)
+	key := strings.TrimSpace(kind + " " + after)
+	f.queries = append(f.queries, key)
+
+	data, ok := f.pages[key]
+	if !ok {
+		return fmt.Errorf("unexpected query %q", key)
+	}
+	return json.Unmarshal([]byte(data), out)
+}
+
+func comment(author, at string) string {
+	return fmt.Sprintf(`{"author":{"login":%q},"body":"fix this","createdAt":%q,"url":"https://github.com/o/r/pull/7#c"}`, author, at)
+}
+
+func TestFetch(t *testing.T) {
+	github := &fakeGitHub{pages: map[string]string{
+		// Two pages of threads; the first thread has a second page of comments
+		"threads": `{"repository":{"pullRequest":{"reviewThreads":{
+			"pageInfo":{"hasNextPage":true,"endCursor":"t1"},
+			"nodes":[{"id":"T1","path":"main.go","line":0,"originalLine":12,"isResolved":true,"isOutdated":true,
+				"comments":{"pageInfo":{"hasNextPage":true,"endCursor":"c1"},"nodes":[` + comment("alice", "2026-01-01T10:00:00Z") + `]}}]}}}}`,
+		"thread T1 c1": `{"node":{"comments":{"pageInfo":{"hasNextPage":false},"nodes":[` + comment("bob", "2026-01-01T11:00:00Z") + `]}}}`,
+		"threads t1": `{"repository":{"pullRequest":{"reviewThreads":{
+			"pageInfo":{"hasNextPage":false},
+			"nodes":[{"id":"T2","path":"util.go","line":3,"comments":{"pageInfo":{},"nodes":[` + comment("alice", "2026-01-02T10:00:00Z") + `]}}]}}}}`,
+		// The bare review holding inline comments is left out
+		"reviews": `{"repository":{"pullRequest":{"reviews":{"pageInfo":{},"nodes":[
+			{"author":{"login":"alice"},"state":"COMMENTED","body":"","submittedAt":"2026-01-01T10:00:00Z"},
+			{"author":null,"state":"CHANGES_REQUESTED","body":"","submittedAt":"2026-01-02T10:00:00Z"}]}}}}`,
+		"comments": `{"repository":{"pullRequest":{"comments":{"pageInfo":{},"nodes":[` + comment("carol", "2026-01-03T10:00:00Z") + `]}}}}`,
+	}}
+
+	f, err := fetch(context.Background(), github.query, "o", "r", 7)
+	if err != nil {
+		t.Fatalf("fetch() error = %v", err)
+	}
+	if len(f.Threads) != 2 || len(f.Threads[0].Comments) != 2 || f.Threads[0].Comments[1].Author != "bob" {
+		t.Fatalf("threads = %+v", f.Threads)
+	}
+	if first := f.Threads[0]; first.Line != 12 || !first.Resolved || !first.Outdated {
+		t.Errorf("outdated thread = %+v, want it on its original line", first)
+	}
+	if len(f.Reviews) != 1 || f.Reviews[0].Author != "ghost" || f.Reviews[0].State != "CHANGES_REQUESTED" {
+		t.Errorf("reviews = %+v", f.Reviews)
+	}
+	if len(f.Comments) != 1 || f.Comments[0].Author != "carol" {
+		t.Errorf("comments = %+v", f.Comments)
+	}
+
+	if got := f.Count(); got != 5 {
+		t.Errorf("Count() = %d, want 5", got)
+	}
+	since := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	if got := f.CountSince(since); got != 3 {
+		t.Errorf("CountSince() = %d, want 3", got)
+	}
+	if want := time.Date(2026, 1, 3, 10, 0, 0, 0, time.UTC); !f.Latest().Equal(want) {
+		t.Errorf("Latest() = %v, want %v", f.Latest(), want)
+	}
+	if got := strings.Join(github.queries, ","); got != "threads,thread T1 c1,threads t1,reviews,comments" {
+		t.Errorf("queries = %s", got)
+	}
+}
+
+func TestFetchMissingPR(t *testing.T) {
+	github := &fakeGitHub{pages: map[string]string{
+		"threads": `{"repository":{"pullRequest":null}}`,
+	}}
+	if _, err := fetch(context.Background(), github.query, "o", "r", 404); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("fetch() of a missing PR = %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+// Package procs inspects processes on the local machine.
+package procs
+
+import (
+	"os"
+	"syscall"
+)
+
+// IsAlive reports whether a process with the given PID is running. It sends
+// signal 0, which checks that the process exists without signalling it, so a
+// process owned by another user also reads as not running.
+func IsAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
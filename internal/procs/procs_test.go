@@ -0,0 +1,24 @@
+package procs
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestIsAlive(t *testing.T) {
+	if !IsAlive(os.Getpid()) {
+		t.Error("IsAlive() should be true for the test process")
+	}
+	if IsAlive(0) || IsAlive(-1) {
+		t.Error("IsAlive() should be false for non-positive PIDs")
+	}
+
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to run true: %v", err)
+	}
+	if IsAlive(cmd.Process.Pid) {
+		t.Error("IsAlive() should be false for a process that has exited")
+	}
+}
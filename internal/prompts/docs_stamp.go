@@ -0,0 +1,63 @@
+package prompts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// docsStampPrefix opens the CLI reference in a prompt, followed by the
+// version of the binary that generated it and the hash of its content
+const docsStampPrefix = "<!-- multiclaude-docs "
+
+// docsStampEnd closes the CLI reference in a prompt
+const docsStampEnd = "<!-- /multiclaude-docs -->"
+
+// DocsHash returns a short hash of a CLI reference, which changes with any
+// command, flag or note it documents
+func DocsHash(docs string) string {
+	sum := sha256.Sum256([]byte(docs))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// StampDocs wraps a CLI reference in markers naming the binary version that
+// generated it and its hash, so that a prompt documenting commands another
+// binary no longer has can be told apart and its reference replaced
+func StampDocs(docs, version string) string {
+	return fmt.Sprintf("%sversion=%s hash=%s -->\n\n%s\n\n%s", docsStampPrefix, version, DocsHash(docs), docs, docsStampEnd)
+}
+
+// ParseDocsStamp returns the version and hash stamped on the CLI reference
+// in a prompt; ok is false when the prompt has none
+func ParseDocsStamp(prompt string) (version, hash string, ok bool) {
+	start := strings.Index(prompt, docsStampPrefix)
+	if start < 0 {
+		return "", "", false
+	}
+	line, _, _ := strings.Cut(prompt[start+len(docsStampPrefix):], "-->")
+	for _, field := range strings.Fields(line) {
+		if v, found := strings.CutPrefix(field, "version="); found {
+			version = v
+		} else if h, found := strings.CutPrefix(field, "hash="); found {
+			hash = h
+		}
+	}
+	return version, hash, hash != ""
+}
+
+// ReplaceDocs swaps the stamped CLI reference in a prompt for another
+// stamped one, leaving the rest of the prompt as it is; ok is false when
+// the prompt has no stamped reference
+func ReplaceDocs(prompt, stampedDocs string) (string, bool) {
+	start := strings.Index(prompt, docsStampPrefix)
+	if start < 0 {
+		return prompt, false
+	}
+	end := strings.Index(prompt[start:], docsStampEnd)
+	if end < 0 {
+		return prompt, false
+	}
+	end += start + len(docsStampEnd)
+	return prompt[:start] + stampedDocs + prompt[end:], true
+}
@@ -0,0 +1,46 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocsStamp(t *testing.T) {
+	docs := "# Multiclaude CLI Reference\n\n## work\n"
+	prompt, err := GetPrompt(t.TempDir(), TypeWorker, StampDocs(docs, "v1.2.0"))
+	if err != nil {
+		t.Fatalf("GetPrompt() error = %v", err)
+	}
+	prompt = "## Scope\n\n" + prompt
+
+	version, hash, ok := ParseDocsStamp(prompt)
+	if !ok || version != "v1.2.0" || hash != DocsHash(docs) {
+		t.Errorf("ParseDocsStamp() = %q, %q, %v; want v1.2.0, %q", version, hash, ok, DocsHash(docs))
+	}
+	if DocsHash(docs) == DocsHash(docs+"--flag\n") {
+		t.Error("DocsHash should change with the reference")
+	}
+
+	newDocs := "# Multiclaude CLI Reference\n\n## work\n\n## workspace\n"
+	replaced, ok := ReplaceDocs(prompt, StampDocs(newDocs, "v1.3.0"))
+	if !ok {
+		t.Fatal("ReplaceDocs() found no reference")
+	}
+	if _, hash, _ := ParseDocsStamp(replaced); hash != DocsHash(newDocs) {
+		t.Errorf("hash after ReplaceDocs = %q, want %q", hash, DocsHash(newDocs))
+	}
+	if !strings.HasPrefix(replaced, "## Scope\n\n") || !strings.Contains(replaced, "## workspace") || strings.Count(replaced, docsStampEnd) != 1 {
+		t.Errorf("ReplaceDocs() should only swap the reference:\n%s", replaced)
+	}
+	// What follows the reference is kept
+	if !strings.HasSuffix(replaced, prompt[strings.Index(prompt, docsStampEnd)+len(docsStampEnd):]) {
+		t.Error("ReplaceDocs() lost the end of the prompt")
+	}
+
+	if _, _, ok := ParseDocsStamp("no reference here"); ok {
+		t.Error("ParseDocsStamp() should find nothing in an unstamped prompt")
+	}
+	if _, ok := ReplaceDocs("no reference here", StampDocs(newDocs, "v1.3.0")); ok {
+		t.Error("ReplaceDocs() should fail on an unstamped prompt")
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/dlorenc/multiclaude/internal/messages"
 	"github.com/dlorenc/multiclaude/internal/prompts/commands"
 )
 
@@ -19,6 +21,7 @@ const (
 	TypeMergeQueue AgentType = "merge-queue"
 	TypeWorkspace  AgentType = "workspace"
 	TypeReview     AgentType = "review"
+	TypeEphemeral  AgentType = "ephemeral"
 )
 
 // Embedded default prompts
@@ -38,6 +41,9 @@ var defaultWorkspacePrompt string
 //go:embed review.md
 var defaultReviewPrompt string
 
+//go:embed ephemeral.md
+var defaultEphemeralPrompt string
+
 // GetDefaultPrompt returns the default prompt for the given agent type
 func GetDefaultPrompt(agentType AgentType) string {
 	switch agentType {
@@ -51,29 +57,45 @@ func GetDefaultPrompt(agentType AgentType) string {
 		return defaultWorkspacePrompt
 	case TypeReview:
 		return defaultReviewPrompt
+	case TypeEphemeral:
+		return defaultEphemeralPrompt
 	default:
 		return ""
 	}
 }
 
-// LoadCustomPrompt loads a custom prompt from the repository's .multiclaude directory
-// Returns empty string if the file doesn't exist
-func LoadCustomPrompt(repoPath string, agentType AgentType) (string, error) {
-	var filename string
+// CustomPromptTypes are the agent types whose prompt a repository can extend
+// with a file in its .multiclaude directory
+var CustomPromptTypes = []AgentType{TypeSupervisor, TypeWorker, TypeMergeQueue, TypeWorkspace, TypeReview, TypeEphemeral}
+
+// CustomPromptFile returns the name of the file in a repository's .multiclaude
+// directory that extends the prompt for an agent type
+func CustomPromptFile(agentType AgentType) (string, error) {
 	switch agentType {
 	case TypeSupervisor:
-		filename = "SUPERVISOR.md"
+		return "SUPERVISOR.md", nil
 	case TypeWorker:
-		filename = "WORKER.md"
+		return "WORKER.md", nil
 	case TypeMergeQueue:
-		filename = "REVIEWER.md"
+		return "REVIEWER.md", nil
 	case TypeWorkspace:
-		filename = "WORKSPACE.md"
+		return "WORKSPACE.md", nil
 	case TypeReview:
-		filename = "REVIEW.md"
+		return "REVIEW.md", nil
+	case TypeEphemeral:
+		return "EPHEMERAL.md", nil
 	default:
 		return "", fmt.Errorf("unknown agent type: %s", agentType)
 	}
+}
+
+// LoadCustomPrompt loads a custom prompt from the repository's .multiclaude directory
+// Returns empty string if the file doesn't exist
+func LoadCustomPrompt(repoPath string, agentType AgentType) (string, error) {
+	filename, err := CustomPromptFile(agentType)
+	if err != nil {
+		return "", err
+	}
 
 	promptPath := filepath.Join(repoPath, ".multiclaude", filename)
 
@@ -91,6 +113,36 @@ func LoadCustomPrompt(repoPath string, agentType AgentType) (string, error) {
 	return string(content), nil
 }
 
+// SeedCustomPrompts writes a template custom prompt file for each type in
+// CustomPromptTypes into the repository's .multiclaude directory, leaving
+// existing files untouched. It returns the paths of the files it wrote.
+func SeedCustomPrompts(repoPath string) ([]string, error) {
+	dir := filepath.Join(repoPath, ".multiclaude")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	var written []string
+	for _, agentType := range CustomPromptTypes {
+		filename, err := CustomPromptFile(agentType)
+		if err != nil {
+			return written, err
+		}
+		path := filepath.Join(dir, filename)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		template := fmt.Sprintf("<!--\nRepository-specific instructions for the %s agent.\n"+
+			"multiclaude appends this file to the built-in %s prompt.\n"+
+			"Replace this comment with guidance for this project, or delete the file.\n-->\n", agentType, agentType)
+		if err := os.WriteFile(path, []byte(template), 0644); err != nil {
+			return written, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
 // GetPrompt returns the complete prompt for an agent, combining default, custom prompts, CLI docs, and slash commands
 func GetPrompt(repoPath string, agentType AgentType, cliDocs string) (string, error) {
 	defaultPrompt := GetDefaultPrompt(agentType)
@@ -115,6 +167,16 @@ func GetPrompt(repoPath string, agentType AgentType, cliDocs string) (string, er
 		result += fmt.Sprintf("\n\n---\n\n%s", slashCommands)
 	}
 
+	// Add templates the repository defines for send-message --template.
+	// The built-in ones are listed in the CLI documentation.
+	repoTemplates, err := messages.LoadRepoTemplates(repoPath)
+	if err != nil {
+		return "", err
+	}
+	if len(repoTemplates) > 0 {
+		result += fmt.Sprintf("\n\n---\n\n## Repository Message Templates\n\nThis repository defines these templates for `multiclaude agent send-message <to> --template <name>`:\n\n%s", messages.TemplatesDoc(repoTemplates))
+	}
+
 	// Add custom prompt if it exists
 	if customPrompt != "" {
 		result += fmt.Sprintf("\n\n---\n\nRepository-specific instructions:\n\n%s", customPrompt)
@@ -165,6 +227,141 @@ Monitor and process all multiclaude-labeled PRs regardless of author or assignee
 	}
 }
 
+// GenerateReviewGatePrompt generates prompt text telling the merge queue
+// agent to wait for a review agent's approval before merging. PRs whose
+// title matches reviewPattern (a regexp) are gated, or all PRs when it is
+// empty. Returns "" when review is not required.
+func GenerateReviewGatePrompt(reviewRequired bool, reviewPattern string) string {
+	if !reviewRequired {
+		return ""
+	}
+
+	scope := "**every PR** you track"
+	check := ""
+	if reviewPattern != "" {
+		scope = fmt.Sprintf("PRs whose title matches the regular expression `%s`", reviewPattern)
+		check = `
+To see which PRs this applies to, compare each title against the pattern:
+` + "```bash" + `
+gh pr view <number> --json title --jq .title
+` + "```" + `
+PRs whose title does not match are merged as usual.
+`
+	}
+
+	return fmt.Sprintf(`## Review Gate: Review Required
+
+**IMPORTANT**: This repository requires an automated review before merging %s.
+
+For each such PR, once CI passes:
+1. Spawn a review agent: `+"`multiclaude review <pr-url>`"+`
+2. Wait for its summary message ("Review complete for PR #<number>...")
+3. Merge only if the review found no blocking issues ("Safe to merge")
+
+Do NOT merge a gated PR before its review agent has reported back, even if CI
+and human reviews are green. If the review found blocking issues, spawn a fix
+worker and review the PR again after the fix lands.
+%s`, scope, check)
+}
+
+// GenerateNoSupervisorPrompt generates prompt text telling an agent that its
+// repository has no supervisor (multiclaude init --no-supervisor), so that
+// instructions to report to or ask the supervisor are not followed. It goes
+// before the agent's prompt, which mentions the supervisor throughout.
+func GenerateNoSupervisorPrompt() string {
+	return `## No Supervisor
+
+**IMPORTANT**: This repository has no supervisor agent. Workers are managed directly by a human operator.
+
+Wherever the instructions below say to message, notify, report to or ask the supervisor, do not send
+that message: nobody would read it. Instead:
+- Put questions and reports in your own output, where the operator reads them, and wait for an answer
+  when you cannot make progress without one
+- Workers still signal completion with ` + "`multiclaude agent complete`" + `; the merge queue is told as usual
+- Decisions the instructions leave to the supervisor are the operator's`
+}
+
+// WithoutSupervisor prepends GenerateNoSupervisorPrompt to the prompt of any
+// agent but a supervisor
+func WithoutSupervisor(agentType AgentType, prompt string) string {
+	if agentType == TypeSupervisor {
+		return prompt
+	}
+	return GenerateNoSupervisorPrompt() + "\n\n" + prompt
+}
+
+// GenerateGitIdentityPrompt returns a prompt section stating the committer
+// identity and signing set up in the agent's worktree, or "" if the
+// repository sets neither. signingFormat is git's gpg.format.
+func GenerateGitIdentityPrompt(name, email string, sign bool, signingFormat string) string {
+	var identity string
+	switch {
+	case name != "" && email != "":
+		identity = fmt.Sprintf("`%s <%s>`", name, email)
+	case name != "":
+		identity = fmt.Sprintf("`%s`", name)
+	case email != "":
+		identity = fmt.Sprintf("`<%s>`", email)
+	}
+	if identity == "" && !sign {
+		return ""
+	}
+
+	var line string
+	switch {
+	case identity != "" && sign:
+		line = fmt.Sprintf("Your commits are made as %s and signed with the repository's %s key.", identity, signingFormat)
+	case identity != "":
+		line = fmt.Sprintf("Your commits are made as %s.", identity)
+	default:
+		line = fmt.Sprintf("Your commits are signed with the repository's %s key.", signingFormat)
+	}
+	return "## Git Identity\n\n" + line + " This is set in your worktree's git config: do not change `user.name`, `user.email` or the signing settings, and do not commit with `--no-gpg-sign`."
+}
+
+// WithGitIdentity appends a GenerateGitIdentityPrompt section to the prompt
+// of the agent types that commit: workers and workspaces
+func WithGitIdentity(agentType AgentType, prompt, section string) string {
+	if section == "" || (agentType != TypeWorker && agentType != TypeWorkspace) {
+		return prompt
+	}
+	return prompt + "\n\n" + section
+}
+
+// GenerateContextPrompt returns a "Current Context" section listing a
+// repository's context variables (multiclaude workspace set-context) as a
+// markdown table sorted by key, or "" if there are none
+func GenerateContextPrompt(vars map[string]string) string {
+	if len(vars) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	cell := strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+	var b strings.Builder
+	b.WriteString("## Current Context\n\n")
+	b.WriteString("Current facts about this repository, set with `multiclaude workspace set-context`. Take them into account in your work.\n\n")
+	b.WriteString("| Key | Value |\n")
+	b.WriteString("|-----|-------|\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "| %s | %s |\n", key, cell.Replace(vars[key]))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// WithContext appends a GenerateContextPrompt section to a prompt
+func WithContext(prompt string, vars map[string]string) string {
+	section := GenerateContextPrompt(vars)
+	if section == "" {
+		return prompt
+	}
+	return prompt + "\n\n" + section
+}
+
 // GetSlashCommandsPrompt returns a formatted prompt section containing all available
 // slash commands. This can be included in agent prompts to document the available
 // commands.
@@ -18,6 +18,7 @@ func TestGetDefaultPrompt(t *testing.T) {
 		{"merge-queue", TypeMergeQueue, false},
 		{"workspace", TypeWorkspace, false},
 		{"review", TypeReview, false},
+		{"ephemeral", TypeEphemeral, false},
 		{"unknown", AgentType("unknown"), true},
 	}
 
@@ -88,6 +89,18 @@ func TestGetDefaultPromptContent(t *testing.T) {
 	if !strings.Contains(reviewPrompt, "multiclaude agent complete") {
 		t.Error("review prompt should mention complete command")
 	}
+
+	// Verify ephemeral prompt
+	ephemeralPrompt := GetDefaultPrompt(TypeEphemeral)
+	if !strings.Contains(ephemeralPrompt, "ephemeral agent") {
+		t.Error("ephemeral prompt should mention 'ephemeral agent'")
+	}
+	if !strings.Contains(ephemeralPrompt, "You Are Read-Only") || !strings.Contains(ephemeralPrompt, "Commit") {
+		t.Error("ephemeral prompt should forbid commits and file changes")
+	}
+	if !strings.Contains(ephemeralPrompt, "multiclaude agent complete") {
+		t.Error("ephemeral prompt should mention complete command")
+	}
 }
 
 func TestLoadCustomPrompt(t *testing.T) {
@@ -193,6 +206,59 @@ func TestLoadCustomPrompt(t *testing.T) {
 			t.Errorf("expected %q, got %q", customContent, prompt)
 		}
 	})
+
+	t.Run("with custom ephemeral prompt", func(t *testing.T) {
+		customContent := "Custom ephemeral instructions"
+		promptPath := filepath.Join(multiclaudeDir, "EPHEMERAL.md")
+		if err := os.WriteFile(promptPath, []byte(customContent), 0644); err != nil {
+			t.Fatalf("failed to write custom prompt: %v", err)
+		}
+
+		prompt, err := LoadCustomPrompt(tmpDir, TypeEphemeral)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if prompt != customContent {
+			t.Errorf("expected %q, got %q", customContent, prompt)
+		}
+	})
+}
+
+func TestSeedCustomPrompts(t *testing.T) {
+	repoPath := t.TempDir()
+	multiclaudeDir := filepath.Join(repoPath, ".multiclaude")
+	if err := os.MkdirAll(multiclaudeDir, 0755); err != nil {
+		t.Fatalf("failed to create .multiclaude dir: %v", err)
+	}
+	existing := filepath.Join(multiclaudeDir, "WORKER.md")
+	if err := os.WriteFile(existing, []byte("Keep me"), 0644); err != nil {
+		t.Fatalf("failed to write existing prompt: %v", err)
+	}
+
+	written, err := SeedCustomPrompts(repoPath)
+	if err != nil {
+		t.Fatalf("SeedCustomPrompts() failed: %v", err)
+	}
+	if len(written) != len(CustomPromptTypes)-1 {
+		t.Errorf("wrote %d files, want %d (all but the existing one)", len(written), len(CustomPromptTypes)-1)
+	}
+
+	if content, _ := os.ReadFile(existing); string(content) != "Keep me" {
+		t.Errorf("existing override was modified: %q", content)
+	}
+	prompt, err := LoadCustomPrompt(repoPath, TypeMergeQueue)
+	if err != nil {
+		t.Fatalf("LoadCustomPrompt() failed: %v", err)
+	}
+	if !strings.Contains(prompt, "merge-queue agent") {
+		t.Errorf("seeded merge-queue template should be loaded as its override, got: %q", prompt)
+	}
+
+	// Seeding again writes nothing
+	written, err = SeedCustomPrompts(repoPath)
+	if err != nil || len(written) != 0 {
+		t.Errorf("second SeedCustomPrompts() = %v, %v; want no files written", written, err)
+	}
 }
 
 func TestGenerateTrackingModePrompt(t *testing.T) {
@@ -245,6 +311,25 @@ func TestGenerateTrackingModePrompt(t *testing.T) {
 	}
 }
 
+func TestGenerateReviewGatePrompt(t *testing.T) {
+	if result := GenerateReviewGatePrompt(false, "breaking:"); result != "" {
+		t.Errorf("GenerateReviewGatePrompt(false) = %q, want empty", result)
+	}
+
+	all := GenerateReviewGatePrompt(true, "")
+	if !strings.HasPrefix(all, "## Review Gate: Review Required") || !strings.Contains(all, "every PR") {
+		t.Errorf("GenerateReviewGatePrompt(true, \"\") should gate every PR, got %q", all)
+	}
+	if !strings.Contains(all, "multiclaude review <pr-url>") {
+		t.Error("GenerateReviewGatePrompt() should say how to spawn a review agent")
+	}
+
+	filtered := GenerateReviewGatePrompt(true, "breaking:")
+	if !strings.Contains(filtered, "`breaking:`") || strings.Contains(filtered, "every PR") {
+		t.Errorf("GenerateReviewGatePrompt(true, \"breaking:\") should gate matching PRs only, got %q", filtered)
+	}
+}
+
 func TestGetPrompt(t *testing.T) {
 	// Create temporary repo directory
 	tmpDir, err := os.MkdirTemp("", "multiclaude-prompts-test-*")
@@ -307,6 +392,31 @@ func TestGetPrompt(t *testing.T) {
 		if !strings.Contains(prompt, "CLI Documentation") {
 			t.Error("prompt should contain CLI docs")
 		}
+		if strings.Contains(prompt, "Repository Message Templates") {
+			t.Error("prompt should not list repository templates when there are none")
+		}
+	})
+
+	t.Run("with repository message templates", func(t *testing.T) {
+		templatesDir := filepath.Join(tmpDir, ".multiclaude", "messages")
+		if err := os.MkdirAll(templatesDir, 0755); err != nil {
+			t.Fatalf("failed to create templates dir: %v", err)
+		}
+		content := "<!-- Ask for a deploy -->\nPlease deploy {{service}}."
+		if err := os.WriteFile(filepath.Join(templatesDir, "deploy.md"), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write template: %v", err)
+		}
+
+		prompt, err := GetPrompt(tmpDir, TypeSupervisor, "")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if !strings.Contains(prompt, "Repository Message Templates") {
+			t.Error("prompt should have a repository message templates section")
+		}
+		if !strings.Contains(prompt, "- `deploy` - Ask for a deploy (variables: service)") {
+			t.Error("prompt should list the repository's templates")
+		}
 	})
 }
 
@@ -402,6 +512,7 @@ func TestGetPromptIncludesSlashCommandsForAllAgentTypes(t *testing.T) {
 		TypeMergeQueue,
 		TypeWorkspace,
 		TypeReview,
+		TypeEphemeral,
 	}
 
 	for _, agentType := range agentTypes {
@@ -485,3 +596,64 @@ func TestGetSlashCommandsPromptNonEmpty(t *testing.T) {
 		t.Errorf("GetSlashCommandsPrompt() seems too short (got %d bytes), expected substantial content", len(prompt))
 	}
 }
+
+func TestWithoutSupervisor(t *testing.T) {
+	section := GenerateNoSupervisorPrompt()
+	if !strings.HasPrefix(section, "## No Supervisor") {
+		t.Errorf("GenerateNoSupervisorPrompt() should start with a heading, got %q", section[:min(len(section), 50)])
+	}
+
+	worker := WithoutSupervisor(TypeWorker, "worker prompt")
+	if !strings.HasPrefix(worker, section) || !strings.HasSuffix(worker, "worker prompt") {
+		t.Errorf("WithoutSupervisor(worker) should prepend the section, got %q", worker)
+	}
+	if got := WithoutSupervisor(TypeSupervisor, "supervisor prompt"); got != "supervisor prompt" {
+		t.Errorf("WithoutSupervisor(supervisor) = %q, want the prompt unchanged", got)
+	}
+}
+
+func TestGenerateGitIdentityPrompt(t *testing.T) {
+	if got := GenerateGitIdentityPrompt("", "", false, "openpgp"); got != "" {
+		t.Errorf("GenerateGitIdentityPrompt() without settings = %q, want empty", got)
+	}
+
+	full := GenerateGitIdentityPrompt("Jane Doe [bot]", "jane@example.com", true, "ssh")
+	if !strings.HasPrefix(full, "## Git Identity") || !strings.Contains(full, "`Jane Doe [bot] <jane@example.com>`") || !strings.Contains(full, "signed with the repository's ssh key") {
+		t.Errorf("GenerateGitIdentityPrompt() = %q", full)
+	}
+	if signedOnly := GenerateGitIdentityPrompt("", "", true, "openpgp"); !strings.Contains(signedOnly, "signed with the repository's openpgp key") {
+		t.Errorf("GenerateGitIdentityPrompt() signing only = %q", signedOnly)
+	}
+
+	if got := WithGitIdentity(TypeWorker, "worker prompt", full); !strings.HasPrefix(got, "worker prompt") || !strings.HasSuffix(got, full) {
+		t.Errorf("WithGitIdentity(worker) should append the section, got %q", got)
+	}
+	if got := WithGitIdentity(TypeMergeQueue, "mq prompt", full); got != "mq prompt" {
+		t.Errorf("WithGitIdentity(merge-queue) = %q, want the prompt unchanged", got)
+	}
+}
+
+func TestGenerateContextPrompt(t *testing.T) {
+	if got := GenerateContextPrompt(nil); got != "" {
+		t.Errorf("GenerateContextPrompt(nil) = %q, want empty", got)
+	}
+	if got := WithContext("prompt", nil); got != "prompt" {
+		t.Errorf("WithContext() without context = %q, want the prompt unchanged", got)
+	}
+
+	section := GenerateContextPrompt(map[string]string{
+		"TEAM":        "alice | bob",
+		"FREEZE_DATE": "2024-03-01",
+		"GOALS":       "ship\nfix bugs",
+	})
+	if !strings.HasPrefix(section, "## Current Context") {
+		t.Errorf("GenerateContextPrompt() should start with a heading, got %q", section)
+	}
+	rows := "| FREEZE_DATE | 2024-03-01 |\n| GOALS | ship<br>fix bugs |\n| TEAM | alice \\| bob |"
+	if !strings.HasSuffix(section, rows) {
+		t.Errorf("GenerateContextPrompt() rows should be sorted and escaped, got:\n%s", section)
+	}
+	if got := WithContext("prompt", map[string]string{"A": "b"}); !strings.HasPrefix(got, "prompt\n\n## Current Context") {
+		t.Errorf("WithContext() should append the section, got %q", got)
+	}
+}
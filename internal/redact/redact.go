@@ -3,6 +3,7 @@ package redact
 import (
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -15,6 +16,7 @@ type Redactor struct {
 	repoCounter  int
 	agentCounter map[string]int // per-type counters
 	homeDir      string
+	secrets      []string // literal values that must never appear in output
 }
 
 // New creates a new Redactor instance
@@ -88,8 +90,37 @@ func (r *Redactor) GitHubURL(url string) string {
 	return url
 }
 
+// AddSecrets registers literal values (e.g. from a repo env file) that are
+// replaced wherever they appear. Empty values are ignored.
+func (r *Redactor) AddSecrets(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range values {
+		if v != "" {
+			r.secrets = append(r.secrets, v)
+		}
+	}
+	// Replace longer values first so overlapping secrets are fully hidden
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+}
+
+// Secrets replaces registered secret values in text with a placeholder
+func (r *Redactor) Secrets(text string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range r.secrets {
+		text = strings.ReplaceAll(text, v, "<secret>")
+	}
+	return text
+}
+
 // Text redacts all sensitive information in a block of text
 func (r *Redactor) Text(text string) string {
+	// Redact registered secret values before anything else can rewrite them
+	text = r.Secrets(text)
+
 	// Redact home directory paths
 	if r.homeDir != "" {
 		text = strings.ReplaceAll(text, r.homeDir, "/Users/<user>")
@@ -138,6 +138,24 @@ SSH URL: git@github.com:secret-org/private-project.git`
 	}
 }
 
+func TestRedactor_Secrets(t *testing.T) {
+	r := New()
+	r.AddSecrets("tok-123", "", "tok-123-extended")
+
+	text := "export API_TOKEN=tok-123-extended\nretrying with tok-123"
+	result := r.Text(text)
+
+	if strings.Contains(result, "tok-123") {
+		t.Errorf("text still contains secret: %s", result)
+	}
+	if strings.Contains(result, "extended") {
+		t.Errorf("longer secret should be replaced before its prefix: %s", result)
+	}
+	if strings.Count(result, "<secret>") != 2 {
+		t.Errorf("expected 2 <secret> placeholders, got: %s", result)
+	}
+}
+
 func TestRedactor_ConsistentMapping(t *testing.T) {
 	r := New()
 
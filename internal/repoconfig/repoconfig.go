@@ -0,0 +1,475 @@
+// Package repoconfig is the registry of per-repository settings shown and
+// changed by multiclaude config: each key's type, default and validation.
+// Where a key's value is kept in a state.Repository is bound in package
+// state, which saves it in the repository's config map.
+package repoconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/envfile"
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/names"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/claude"
+)
+
+// Type is the kind of value a key holds
+type Type string
+
+const (
+	Bool     Type = "bool"
+	Int      Type = "int"
+	Duration Type = "duration"
+	Enum     Type = "enum"
+	String   Type = "string"
+	Path     Type = "path"
+)
+
+// Key is a repository setting. Values are handled as strings in a canonical
+// form (see Parse); an empty value stands for the default.
+type Key struct {
+	Name        string
+	Type        Type
+	Default     string
+	Values      []string // The values of an Enum
+	Description string
+	// Legacy is the key's name in get_repo_config and update_repo_config
+	// arguments from before the registry, if it had one
+	Legacy string
+
+	// parse replaces the parsing of the key's type
+	parse func(value string) (string, error)
+	// check validates a parsed value further
+	check func(value string) error
+}
+
+// Keys returns the registered keys, in the order they are listed
+func Keys() []*Key {
+	return keys
+}
+
+// Lookup returns the key with a name
+func Lookup(name string) (*Key, bool) {
+	for _, k := range keys {
+		if k.Name == name {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// Get returns the key's value in a repository
+func (k *Key) Get(r *state.Repository) string {
+	value, _ := r.Setting(k.Name)
+	return value
+}
+
+// Parse validates a value given for the key and returns it in canonical
+// form: true or false, a base-10 integer, a Go duration (days are accepted
+// as "7d"), or an absolute path. An empty value is returned as is.
+func (k *Key) Parse(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	var err error
+	if k.parse != nil {
+		value, err = k.parse(value)
+	} else {
+		value, err = parseType(k.Type, k.Values, value)
+	}
+	if err != nil {
+		return "", err
+	}
+	if k.check != nil {
+		if err := k.check(value); err != nil {
+			return "", err
+		}
+	}
+	return value, nil
+}
+
+// LegacyValue converts a canonical value to the JSON type the key had in
+// get_repo_config responses before the registry
+func (k *Key) LegacyValue(value string) interface{} {
+	switch k.Type {
+	case Bool:
+		return value == "true"
+	case Int:
+		n, _ := strconv.Atoi(value)
+		return n
+	default:
+		return value
+	}
+}
+
+func parseType(t Type, values []string, value string) (string, error) {
+	switch t {
+	case Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not true or false", value)
+		}
+		return strconv.FormatBool(b), nil
+	case Int:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("%q is not a non-negative integer", value)
+		}
+		return strconv.Itoa(n), nil
+	case Duration:
+		d, err := parseDuration(value)
+		if err != nil || d < 0 {
+			return "", fmt.Errorf("%q is not a duration such as 30m, 12h or 7d", value)
+		}
+		return d.String(), nil
+	case Enum:
+		for _, v := range values {
+			if value == v {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("%q is not one of: %s", value, strings.Join(values, ", "))
+	case Path:
+		abs, err := filepath.Abs(value)
+		if err != nil {
+			return "", err
+		}
+		return abs, nil
+	default:
+		return value, nil
+	}
+}
+
+// parseDuration parses a Go duration, or a whole number of days such as "7d"
+func parseDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// byteSizeUnits are the suffixes parseByteSize accepts, longest first
+var byteSizeUnits = []struct {
+	suffix string
+	size   int
+}{
+	{"kb", 1024}, {"mb", 1024 * 1024}, {"k", 1024}, {"m", 1024 * 1024}, {"b", 1},
+}
+
+// parseByteSize parses a size such as 8192, 8KB or 5MB (units are
+// multiples of 1024, case-insensitive) into a number of bytes
+func parseByteSize(value string) (string, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+	multiplier := 1
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, multiplier = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), unit.size
+			break
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("invalid size %q (use bytes, or a number with KB or MB)", value)
+	}
+	return strconv.Itoa(n * multiplier), nil
+}
+
+// Values returns every key's value in a repository, by key name
+func Values(r *state.Repository) map[string]string {
+	values := make(map[string]string, len(keys))
+	for _, k := range keys {
+		values[k.Name] = k.Get(r)
+	}
+	return values
+}
+
+// Apply sets and unsets keys of a repository, all or none of them: set
+// values are parsed as by Key.Parse, and r is only changed when the
+// resulting configuration is valid as a whole. Unset keys revert to their
+// defaults.
+func Apply(r *state.Repository, set map[string]string, unset []string) error {
+	for name := range set {
+		if _, ok := Lookup(name); !ok {
+			return fmt.Errorf("unknown config key %q", name)
+		}
+	}
+	next := *r
+	next.MessageTransport.ByAgentType = copyTransports(r.MessageTransport.ByAgentType)
+
+	for _, name := range unset {
+		k, ok := Lookup(name)
+		if !ok {
+			return fmt.Errorf("unknown config key %q", name)
+		}
+		next.SetSetting(k.Name, "")
+	}
+	for _, k := range keys {
+		value, ok := set[k.Name]
+		if !ok {
+			continue
+		}
+		parsed, err := k.Parse(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s value: %w", k.Name, err)
+		}
+		next.SetSetting(k.Name, parsed)
+	}
+
+	// A signing key file means SSH signing unless a format is given with it
+	if key, ok := set["signing-key"]; ok && key != "" {
+		_, hasFormat := set["signing-format"]
+		info, err := os.Stat(key)
+		isFile := err == nil && !info.IsDir()
+		if isFile && !hasFormat {
+			next.GitIdentity.SigningFormat = state.SigningFormatSSH
+		} else if !isFile && hasFormat && next.GitIdentity.SigningFormat == state.SigningFormatSSH {
+			return fmt.Errorf("invalid signing-key value: %q is not an SSH key file", key)
+		}
+	}
+
+	if err := validate(&next); err != nil {
+		return err
+	}
+	*r = next
+	return nil
+}
+
+// validate checks the constraints between keys
+func validate(r *state.Repository) error {
+	if limits := r.MessageLimits(); limits.EffectiveMaxBodySize() > limits.EffectiveHardCap() {
+		return fmt.Errorf("message-max-size (%d bytes) cannot exceed message-hard-cap (%d bytes)", limits.EffectiveMaxBodySize(), limits.EffectiveHardCap())
+	}
+	if r.NameScheme == string(names.SchemeTemplate) && r.NameTemplate == "" {
+		return fmt.Errorf("the %q name scheme needs a name-template", names.SchemeTemplate)
+	}
+	return r.GitIdentity.Validate()
+}
+
+func copyTransports(m map[state.AgentType]string) map[state.AgentType]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[state.AgentType]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func positiveDuration(value string) error {
+	if d, _ := time.ParseDuration(value); d <= 0 {
+		return fmt.Errorf("%q is not a positive duration", value)
+	}
+	return nil
+}
+
+// transportAgentTypes are the agent types whose message transport can be set
+// apart from the repository's
+var transportAgentTypes = []state.AgentType{
+	state.AgentTypeSupervisor, state.AgentTypeWorker, state.AgentTypeMergeQueue, state.AgentTypeWorkspace, state.AgentTypeReview, state.AgentTypeEphemeral,
+}
+
+// TransportKey returns the key of an agent type's message transport
+func TransportKey(agentType string) string {
+	return "transport-" + agentType
+}
+
+func schemeNames() []string {
+	list := make([]string, len(names.Schemes))
+	for i, scheme := range names.Schemes {
+		list[i] = string(scheme)
+	}
+	return list
+}
+
+var keys = append([]*Key{
+	{
+		Name: "mq-enabled", Type: Bool, Default: "true", Legacy: "mq_enabled",
+		Description: "Run the merge-queue agent",
+	},
+	{
+		Name: "mq-track", Type: Enum, Default: string(state.TrackModeAll), Legacy: "mq_track_mode",
+		Values:      []string{string(state.TrackModeAll), string(state.TrackModeAuthor), string(state.TrackModeAssigned)},
+		Description: "PRs the merge queue tracks: all, author or assigned",
+	},
+	{
+		Name: "mq-review-enabled", Type: Bool, Default: "false", Legacy: "mq_review_required",
+		Description: "Have a review agent approve PRs before the merge queue merges them",
+	},
+	{
+		Name: "mq-review-pattern", Type: String, Legacy: "mq_review_pattern",
+		Description: "Regular expression; only PRs whose title matches need review (empty: all PRs)",
+		check: func(v string) error {
+			_, err := regexp.Compile(v)
+			return err
+		},
+	},
+	{
+		Name: "env-file", Type: Path, Legacy: "env_file",
+		Description: "File of KEY=VALUE variables for the repository's agents, besides .multiclaude/env",
+		check: func(v string) error {
+			if _, err := os.Stat(v); err != nil {
+				return err
+			}
+			_, err := envfile.Load(v)
+			return err
+		},
+	},
+	{
+		Name: "min-claude-version", Type: String, Legacy: "min_claude_version",
+		Description: "Oldest claude version agents may run with (empty: any)",
+		check:       claude.ValidateVersion,
+	},
+	{
+		Name: "pin-claude-path", Type: Path, Legacy: "claude_path",
+		Description: "Start agents with this claude binary only (empty: claude in PATH)",
+		check:       claude.CheckExecutable,
+	},
+	{
+		Name: "max-concurrent-workers", Type: Int, Default: "0", Legacy: "max_concurrent_workers",
+		Description: "Most worker agents at once; 0 for no limit",
+	},
+	{
+		Name: "max-concurrent-ephemeral", Type: Int, Default: "0", Legacy: "max_concurrent_ephemeral",
+		Description: "Most ephemeral agents at once; 0 for no limit",
+	},
+	{
+		Name: "worktree-limit", Type: Int, Default: "0", Legacy: "worktree_limit",
+		Description: "Most worker worktrees the repository may have; 0 for no limit",
+	},
+	{
+		Name: "duplicate-window", Type: Duration, Default: state.DefaultDuplicateWindow.String(), Legacy: "duplicate_window",
+		Description: "How long a worker's task blocks a duplicate; 0 disables the check",
+	},
+	{
+		Name: "archive-max-age", Type: Duration, Default: state.DefaultArchiveMaxAge.String(), Legacy: "archive_max_age",
+		Description: "Age at which the daemon removes branch bundles; 0 keeps them",
+	},
+	{
+		Name: "archive-max-size-mb", Type: Int, Default: "0", Legacy: "archive_max_size_mb",
+		Description: "Cap on the total size of branch bundles, in MB; 0 for no cap",
+	},
+	{
+		Name: "submodule-timeout", Type: Duration, Default: state.DefaultSubmoduleTimeout.String(), Legacy: "submodule_timeout",
+		Description: "Longest time to check out submodules in a new worktree",
+		check:       positiveDuration,
+	},
+	{
+		Name: "auto-ack-after", Type: Duration, Default: "0s", Legacy: "auto_ack_after",
+		Description: "Acknowledge messages that have been read for this long; 0 turns it off",
+	},
+	{
+		Name: "auto-sync", Type: Duration, Default: "0s", Legacy: "auto_sync",
+		Description: "How often the daemon syncs the primary clone with upstream; 0 turns it off",
+	},
+	{
+		Name: "crash-loop-limit", Type: Int, Default: strconv.Itoa(state.DefaultCrashLoopLimit), Legacy: "crash_loop_limit",
+		Description: "Automatic restarts of an agent within crash-loop-window before it is quarantined",
+	},
+	{
+		Name: "crash-loop-window", Type: Duration, Default: state.DefaultCrashLoopWindow.String(), Legacy: "crash_loop_window",
+		Description: "Period automatic restarts are counted over",
+	},
+	{
+		Name: "name-scheme", Type: Enum, Default: string(names.SchemeDocker), Legacy: "name_scheme",
+		Values:      schemeNames(),
+		Description: "How workers are named: docker, dated, task-slug or template",
+		parse: func(v string) (string, error) {
+			scheme, err := names.ParseScheme(v)
+			return string(scheme), err
+		},
+	},
+	{
+		Name: "name-template", Type: String, Legacy: "name_template",
+		Description: "Worker name template for the template scheme, e.g. {user}-{slug}",
+		check:       names.ValidateTemplate,
+	},
+	{
+		Name: "message-max-size", Type: Int, Default: strconv.Itoa(messages.DefaultMaxBodySize), Legacy: "message_max_size",
+		Description: "Largest message body, in bytes (or with KB or MB), delivered in full",
+		parse:       parseByteSize,
+	},
+	{
+		Name: "message-hard-cap", Type: Int, Default: strconv.Itoa(messages.DefaultHardCap), Legacy: "message_hard_cap",
+		Description: "Largest message body, in bytes (or with KB or MB), accepted",
+		parse:       parseByteSize,
+	},
+	{
+		Name: "nudge-when-idle", Type: Bool, Default: "false", Legacy: "nudge_when_idle",
+		Description: "Nudge every agent every cycle, even with nothing new",
+	},
+	{
+		Name: "raw-logs", Type: Bool, Default: "false", Legacy: "raw_logs",
+		Description: "Keep agent output logs unfiltered, escape sequences and redraws included",
+	},
+	{
+		Name: "snapshot-keep", Type: Int, Default: strconv.Itoa(state.DefaultSnapshotKeep), Legacy: "snapshot_keep",
+		Description: "Snapshots kept per workspace",
+	},
+	{
+		Name: "git-name", Type: String, Legacy: "git_name",
+		Description: "Committer name in agent worktrees (empty: git's own configuration)",
+	},
+	{
+		Name: "git-email", Type: String, Legacy: "git_email",
+		Description: "Committer email in agent worktrees (empty: git's own configuration)",
+	},
+	{
+		Name: "git-bot-suffix", Type: String, Legacy: "git_bot_suffix",
+		Description: "Text appended to the committer name, e.g. [bot]",
+	},
+	{
+		Name: "sign-commits", Type: Bool, Default: "false", Legacy: "sign_commits",
+		Description: "Sign commits made in agent worktrees",
+	},
+	{
+		Name: "signing-key", Type: String, Legacy: "signing_key",
+		Description: "GPG key ID, or SSH key file (which implies signing-format=ssh), to sign with",
+		parse: func(v string) (string, error) {
+			// A file is an SSH key; anything else is a GPG key ID
+			if info, err := os.Stat(v); err == nil && !info.IsDir() {
+				return filepath.Abs(v)
+			}
+			return v, nil
+		},
+	},
+	{
+		Name: "signing-format", Type: Enum, Default: state.SigningFormatOpenPGP, Legacy: "signing_format",
+		Values:      []string{state.SigningFormatOpenPGP, state.SigningFormatSSH},
+		Description: "openpgp or ssh",
+		parse: func(v string) (string, error) {
+			if v == "gpg" {
+				return state.SigningFormatOpenPGP, nil
+			}
+			return parseType(Enum, []string{state.SigningFormatOpenPGP, state.SigningFormatSSH}, v)
+		},
+	},
+	{
+		Name: "transport", Type: String, Legacy: "message_transport",
+		Description: "How messages reach agents (empty: the daemon's default)",
+	},
+}, transportKeys()...)
+
+// transportKeys returns the keys of the agent types' message transports,
+// which override the repository's
+func transportKeys() []*Key {
+	out := make([]*Key, 0, len(transportAgentTypes))
+	for _, agentType := range transportAgentTypes {
+		out = append(out, &Key{
+			Name: TransportKey(string(agentType)), Type: String,
+			Description: fmt.Sprintf("How messages reach %s agents, overriding transport", agentType),
+		})
+	}
+	return out
+}
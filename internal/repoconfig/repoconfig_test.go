@@ -0,0 +1,143 @@
+package repoconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestDefaults(t *testing.T) {
+	// A repository that sets nothing has every key at its default
+	values := Values(&state.Repository{})
+	for _, k := range Keys() {
+		if values[k.Name] != k.Default {
+			t.Errorf("%s = %q in a new repository, want its default %q", k.Name, values[k.Name], k.Default)
+		}
+		if k.Description == "" {
+			t.Errorf("%s has no description", k.Name)
+		}
+		if _, ok := (&state.Repository{}).Setting(k.Name); !ok {
+			t.Errorf("%s has no setting in package state", k.Name)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "env")
+	if err := os.WriteFile(envFile, []byte("TOKEN=abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, value, want string
+		wantErr          bool
+	}{
+		{"mq-enabled", "false", "false", false},
+		{"mq-enabled", "maybe", "", true},
+		{"mq-track", "author", "author", false},
+		{"mq-track", "mine", "", true},
+		{"mq-review-pattern", "(breaking", "", true},
+		{"worktree-limit", "4", "4", false},
+		{"worktree-limit", "-1", "", true},
+		{"duplicate-window", "30m", "30m0s", false},
+		{"duplicate-window", "0", "0s", false},
+		{"archive-max-age", "7d", "168h0m0s", false},
+		{"archive-max-age", "soon", "", true},
+		{"submodule-timeout", "0", "", true},
+		{"message-max-size", "8KB", "8192", false},
+		{"message-max-size", "lots", "", true},
+		{"name-scheme", "task-slug", "task-slug", false},
+		{"name-scheme", "sequential", "", true},
+		{"name-template", "{owner}", "", true},
+		{"signing-format", "gpg", state.SigningFormatOpenPGP, false},
+		{"env-file", envFile, envFile, false},
+		{"env-file", "/nonexistent/env", "", true},
+		{"min-claude-version", "1.2", "", true},
+		{"mq-track", "", "", false},
+	}
+	for _, tt := range tests {
+		k, ok := Lookup(tt.key)
+		if !ok {
+			t.Fatalf("Lookup(%q) found nothing", tt.key)
+		}
+		got, err := k.Parse(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s.Parse(%q) = %q, %v; want %q, error %v", tt.key, tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	repo := &state.Repository{}
+	err := Apply(repo, map[string]string{
+		"mq-enabled":          "false",
+		"duplicate-window":    "1h",
+		"transport-worker":    "inbox",
+		"transport-workspace": "inbox",
+		"auto-sync":           "0",
+	}, nil)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	// Setting one merge-queue key keeps the defaults of the others
+	if repo.MergeQueueConfig.Enabled || repo.MergeQueueConfig.TrackMode != state.TrackModeAll {
+		t.Errorf("merge queue config = %+v", repo.MergeQueueConfig)
+	}
+	if repo.DuplicateWindow != "1h0m0s" || repo.MessageTransport.TransportFor(state.AgentTypeWorker) != "inbox" || repo.AutoSync != "" {
+		t.Errorf("repo = %+v", repo)
+	}
+	if repo.MessageTransport.TransportFor(state.AgentTypeWorkspace) != "inbox" {
+		t.Errorf("repo = %+v", repo)
+	}
+
+	// Nothing changes when any key fails, including the checks across keys
+	transports := repo.MessageTransport.ByAgentType
+	for _, set := range []map[string]string{
+		{"mq-enabled": "true", "bogus": "1"},
+		{"transport-worker": "tmux", "snapshot-keep": "-1"},
+		{"transport-worker": "", "message-max-size": "64KB", "message-hard-cap": "1KB"},
+		{"name-scheme": "template"},
+		{"git-bot-suffix": "[bot]"},
+	} {
+		if err := Apply(repo, set, nil); err == nil {
+			t.Errorf("Apply(%v) should fail", set)
+		}
+	}
+	if repo.MergeQueueConfig.Enabled || transports[state.AgentTypeWorker] != "inbox" || repo.SnapshotKeep != 0 {
+		t.Errorf("a failed Apply changed the repository: %+v", repo)
+	}
+
+	if err := Apply(repo, nil, []string{"mq-enabled", "transport-worker", "duplicate-window"}); err != nil {
+		t.Fatalf("Apply() unset error = %v", err)
+	}
+	values := Values(repo)
+	for _, name := range []string{"mq-enabled", "transport-worker", "duplicate-window"} {
+		k, _ := Lookup(name)
+		if values[name] != k.Default {
+			t.Errorf("%s = %q after unset, want %q", name, values[name], k.Default)
+		}
+	}
+	if err := Apply(repo, nil, []string{"bogus"}); err == nil || !strings.Contains(err.Error(), "unknown config key") {
+		t.Errorf("unsetting an unknown key = %v", err)
+	}
+}
+
+func TestApplySigningKeyFile(t *testing.T) {
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(key, []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	repo := &state.Repository{}
+	if err := Apply(repo, map[string]string{"sign-commits": "true", "signing-key": key}, nil); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if repo.GitIdentity.SigningFormat != state.SigningFormatSSH {
+		t.Errorf("a key file should imply ssh signing, got %q", repo.GitIdentity.SigningFormat)
+	}
+	if err := Apply(repo, map[string]string{"signing-key": "ABCDEF", "signing-format": "ssh"}, nil); err == nil {
+		t.Error("ssh signing with a GPG key ID should fail")
+	}
+}
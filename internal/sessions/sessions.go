@@ -0,0 +1,124 @@
+// Package sessions tells the tmux sessions of one multiclaude root apart
+// from another's when several roots (MULTICLAUDE_HOME) share a tmux server.
+//
+// Each session multiclaude creates is tagged with the session option
+// @multiclaude-root, set to the root it belongs to. Cleanup only ever kills
+// sessions tagged with its own root; sessions from before tagging are
+// adopted by the root that tracks them and otherwise left alone.
+package sessions
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RootOption is the tmux user option a session's root is recorded in
+const RootOption = "@multiclaude-root"
+
+// Prefix starts the name of every session multiclaude creates
+const Prefix = "mc-"
+
+// Server is the tmux server sessions are tagged on; *tmux.Client
+// implements it
+type Server interface {
+	SetSessionOption(ctx context.Context, session, option, value string) error
+	ListSessionOption(ctx context.Context, option string) (map[string]string, error)
+}
+
+// Tag records that session belongs to root
+func Tag(ctx context.Context, server Server, session, root string) error {
+	return server.SetSessionOption(ctx, session, RootOption, filepath.Clean(root))
+}
+
+// Survey sorts the multiclaude sessions on a tmux server by who they
+// belong to, as seen from one root
+type Survey struct {
+	// Own are sessions tagged with this root that it tracks
+	Own []string
+	// Orphaned are sessions tagged with this root that it no longer
+	// tracks; they are safe to kill
+	Orphaned []string
+	// Adoptable are untagged sessions this root tracks, created before
+	// sessions were tagged
+	Adoptable []string
+	// Unknown are untagged mc-* sessions this root does not track. They
+	// may belong to another root that has not tagged them yet.
+	Unknown []string
+	// Foreign are sessions tagged with another root, by root
+	Foreign map[string][]string
+}
+
+// Take surveys the sessions on server for root, which tracks the sessions
+// in tracked
+func Take(ctx context.Context, server Server, root string, tracked map[string]bool) (Survey, error) {
+	tags, err := server.ListSessionOption(ctx, RootOption)
+	if err != nil {
+		return Survey{}, err
+	}
+	root = filepath.Clean(root)
+
+	survey := Survey{Foreign: make(map[string][]string)}
+	for session, tag := range tags {
+		switch {
+		case tag == root && tracked[session]:
+			survey.Own = append(survey.Own, session)
+		case tag == root:
+			survey.Orphaned = append(survey.Orphaned, session)
+		case tag != "":
+			survey.Foreign[tag] = append(survey.Foreign[tag], session)
+		case tracked[session]:
+			survey.Adoptable = append(survey.Adoptable, session)
+		case strings.HasPrefix(session, Prefix):
+			survey.Unknown = append(survey.Unknown, session)
+		}
+	}
+
+	sort.Strings(survey.Own)
+	sort.Strings(survey.Orphaned)
+	sort.Strings(survey.Adoptable)
+	sort.Strings(survey.Unknown)
+	for _, foreign := range survey.Foreign {
+		sort.Strings(foreign)
+	}
+	return survey, nil
+}
+
+// IsForeign reports whether session is tagged with another root
+func (s Survey) IsForeign(session string) bool {
+	for _, foreign := range s.Foreign {
+		for _, name := range foreign {
+			if name == session {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ForeignCount returns how many sessions belong to other roots
+func (s Survey) ForeignCount() int {
+	n := 0
+	for _, foreign := range s.Foreign {
+		n += len(foreign)
+	}
+	return n
+}
+
+// Adopt tags the adoptable sessions with root, returning those it tagged
+// and the first error
+func Adopt(ctx context.Context, server Server, root string, survey Survey) ([]string, error) {
+	var adopted []string
+	var firstErr error
+	for _, session := range survey.Adoptable {
+		if err := Tag(ctx, server, session, root); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		adopted = append(adopted, session)
+	}
+	return adopted, firstErr
+}
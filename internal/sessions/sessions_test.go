@@ -0,0 +1,92 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeServer is a tmux server holding session options in memory
+type fakeServer struct {
+	tags    map[string]string
+	failSet bool
+}
+
+func (f *fakeServer) SetSessionOption(ctx context.Context, session, option, value string) error {
+	if f.failSet {
+		return errors.New("set-option failed")
+	}
+	if _, ok := f.tags[session]; !ok {
+		return errors.New("no such session")
+	}
+	f.tags[session] = value
+	return nil
+}
+
+func (f *fakeServer) ListSessionOption(ctx context.Context, option string) (map[string]string, error) {
+	tags := make(map[string]string, len(f.tags))
+	for session, tag := range f.tags {
+		tags[session] = tag
+	}
+	return tags, nil
+}
+
+func TestTake(t *testing.T) {
+	server := &fakeServer{tags: map[string]string{
+		"mc-ours":     "/home/a/.multiclaude",
+		"mc-gone":     "/home/a/.multiclaude",
+		"mc-theirs":   "/home/b/.multiclaude",
+		"mc-legacy":   "",
+		"mc-stranger": "",
+		"scratch":     "",
+	}}
+	tracked := map[string]bool{"mc-ours": true, "mc-legacy": true, "mc-theirs": true}
+
+	// A trailing slash on the root does not matter
+	survey, err := Take(context.Background(), server, "/home/a/.multiclaude/", tracked)
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	want := Survey{
+		Own:       []string{"mc-ours"},
+		Orphaned:  []string{"mc-gone"},
+		Adoptable: []string{"mc-legacy"},
+		Unknown:   []string{"mc-stranger"},
+		Foreign:   map[string][]string{"/home/b/.multiclaude": {"mc-theirs"}},
+	}
+	if !reflect.DeepEqual(survey, want) {
+		t.Errorf("Take() = %+v, want %+v", survey, want)
+	}
+	if !survey.IsForeign("mc-theirs") || survey.IsForeign("mc-ours") || survey.ForeignCount() != 1 {
+		t.Errorf("foreign sessions of %+v", survey)
+	}
+}
+
+func TestAdopt(t *testing.T) {
+	server := &fakeServer{tags: map[string]string{"mc-legacy": "", "mc-stranger": ""}}
+	survey, err := Take(context.Background(), server, "/root/mc", map[string]bool{"mc-legacy": true})
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+
+	adopted, err := Adopt(context.Background(), server, "/root/mc", survey)
+	if err != nil || !reflect.DeepEqual(adopted, []string{"mc-legacy"}) {
+		t.Errorf("Adopt() = %v, %v", adopted, err)
+	}
+	if server.tags["mc-legacy"] != "/root/mc" || server.tags["mc-stranger"] != "" {
+		t.Errorf("tags after Adopt() = %v", server.tags)
+	}
+
+	// Once adopted the session is the root's own
+	survey, _ = Take(context.Background(), server, "/root/mc", map[string]bool{"mc-legacy": true})
+	if !reflect.DeepEqual(survey.Own, []string{"mc-legacy"}) || len(survey.Adoptable) != 0 {
+		t.Errorf("survey after Adopt() = %+v", survey)
+	}
+
+	server = &fakeServer{tags: map[string]string{"mc-legacy": ""}, failSet: true}
+	survey, _ = Take(context.Background(), server, "/root/mc", map[string]bool{"mc-legacy": true})
+	if adopted, err := Adopt(context.Background(), server, "/root/mc", survey); err == nil || len(adopted) != 0 {
+		t.Errorf("Adopt() with failing tmux = %v, %v", adopted, err)
+	}
+}
@@ -6,12 +6,17 @@ import (
 	"io"
 	"net"
 	"os"
+	"strconv"
+	"time"
 )
 
 // Request represents a request sent to the daemon
 type Request struct {
 	Command string                 `json:"command"`
 	Args    map[string]interface{} `json:"args,omitempty"`
+	// Meta describes the requesting process (pid, uid, cwd) for auditing.
+	// Client.Send fills it in when left nil.
+	Meta map[string]string `json:"meta,omitempty"`
 }
 
 // Response represents a response from the daemon
@@ -33,11 +38,26 @@ func NewClient(socketPath string) *Client {
 
 // Send sends a request to the daemon and returns the response
 func (c *Client) Send(req Request) (*Response, error) {
-	conn, err := net.Dial("unix", c.socketPath)
+	return c.SendTimeout(req, 0)
+}
+
+// SendTimeout is Send giving up once timeout has passed, from connecting to
+// reading the response. A zero timeout waits as long as Send does.
+func (c *Client) SendTimeout(req Request, timeout time.Duration) (*Response, error) {
+	conn, err := net.DialTimeout("unix", c.socketPath, timeout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to daemon: %w", err)
 	}
 	defer conn.Close()
+	if timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, fmt.Errorf("failed to set deadline: %w", err)
+		}
+	}
+
+	if req.Meta == nil {
+		req.Meta = callerMeta()
+	}
 
 	// Send request
 	if err := json.NewEncoder(conn).Encode(req); err != nil {
@@ -53,6 +73,18 @@ func (c *Client) Send(req Request) (*Response, error) {
 	return &resp, nil
 }
 
+// callerMeta describes the current process for the daemon's audit log
+func callerMeta() map[string]string {
+	meta := map[string]string{
+		"pid": strconv.Itoa(os.Getpid()),
+		"uid": strconv.Itoa(os.Getuid()),
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		meta["cwd"] = cwd
+	}
+	return meta
+}
+
 // Server listens on a Unix socket for requests
 type Server struct {
 	socketPath string
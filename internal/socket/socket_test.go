@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -346,3 +347,75 @@ func TestServerStopRemovesSocket(t *testing.T) {
 		t.Error("Socket file should be removed after Stop()")
 	}
 }
+
+func TestClientSendsCallerMeta(t *testing.T) {
+	tmpDir := t.TempDir()
+	sockPath := filepath.Join(tmpDir, "test.sock")
+
+	received := make(chan Request, 1)
+	server := NewServer(sockPath, HandlerFunc(func(req Request) Response {
+		received <- req
+		return Response{Success: true}
+	}))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer server.Stop()
+	go server.Serve()
+
+	client := NewClient(sockPath)
+	if _, err := client.Send(Request{Command: "test"}); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	req := <-received
+	if req.Meta["pid"] != strconv.Itoa(os.Getpid()) {
+		t.Errorf("Meta[pid] = %q, want %d", req.Meta["pid"], os.Getpid())
+	}
+	if req.Meta["uid"] != strconv.Itoa(os.Getuid()) {
+		t.Errorf("Meta[uid] = %q, want %d", req.Meta["uid"], os.Getuid())
+	}
+	if req.Meta["cwd"] == "" {
+		t.Error("Meta[cwd] should be set")
+	}
+
+	// Explicit metadata is passed through unchanged
+	if _, err := client.Send(Request{Command: "test", Meta: map[string]string{"pid": "1"}}); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if req := <-received; req.Meta["pid"] != "1" || req.Meta["cwd"] != "" {
+		t.Errorf("explicit Meta was modified: %v", req.Meta)
+	}
+}
+
+func TestClientSendTimeout(t *testing.T) {
+	tmpDir := t.TempDir()
+	sockPath := filepath.Join(tmpDir, "test.sock")
+
+	release := make(chan struct{})
+	defer close(release)
+	server := NewServer(sockPath, HandlerFunc(func(req Request) Response {
+		if req.Command == "slow" {
+			<-release
+		}
+		return Response{Success: true}
+	}))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer server.Stop()
+	go server.Serve()
+
+	client := NewClient(sockPath)
+	if _, err := client.SendTimeout(Request{Command: "fast"}, time.Second); err != nil {
+		t.Fatalf("SendTimeout() failed: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := client.SendTimeout(Request{Command: "slow"}, 50*time.Millisecond); err == nil {
+		t.Error("SendTimeout() should fail when the response takes longer than the timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("SendTimeout() took %s to give up", elapsed)
+	}
+}
@@ -0,0 +1,337 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupsDirName is the directory next to the state file that holds its
+// backups
+const BackupsDirName = "state-backups"
+
+// BackupManifestFile lists the backups in the backups directory, for
+// reading without parsing file names
+const BackupManifestFile = "manifest.json"
+
+// Default retention of state backups
+const (
+	DefaultBackupKeep = 10
+	DefaultBackupDays = 7
+)
+
+// backupIDLayout is the time layout of backup IDs, which sort as they were
+// taken
+const backupIDLayout = "20060102T150405.000000Z"
+
+// BackupPolicy is which backups of the state file are kept: the Keep most
+// recent, plus the newest of each of the last Days days. Zero fields take
+// the defaults.
+type BackupPolicy struct {
+	Keep int `json:"keep,omitempty"`
+	Days int `json:"days,omitempty"`
+}
+
+// withDefaults fills in the zero fields of p
+func (p BackupPolicy) withDefaults() BackupPolicy {
+	if p.Keep <= 0 {
+		p.Keep = DefaultBackupKeep
+	}
+	if p.Days <= 0 {
+		p.Days = DefaultBackupDays
+	}
+	return p
+}
+
+// BackupInfo describes one backup of the state file
+type BackupInfo struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	Size int64     `json:"size"`
+}
+
+// BackupManifest is the content of BackupManifestFile. The backups are
+// oldest first.
+type BackupManifest struct {
+	Policy  BackupPolicy `json:"policy"`
+	Backups []BackupInfo `json:"backups"`
+}
+
+// BackupDir returns the backups directory of the state file at statePath
+func BackupDir(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), BackupsDirName)
+}
+
+// BackupPath returns the file of the backup with the given ID
+func BackupPath(statePath, id string) string {
+	return filepath.Join(BackupDir(statePath), "state-"+id+".json")
+}
+
+// ListBackups returns the backups of the state file at statePath, oldest
+// first, and the policy they are kept by. The files in the backups
+// directory are authoritative; the manifest only supplies the policy.
+func ListBackups(statePath string) ([]BackupInfo, BackupPolicy, error) {
+	dir := BackupDir(statePath)
+	manifest, err := readBackupManifest(dir)
+	if err != nil {
+		return nil, BackupPolicy{}, err
+	}
+	backups, err := scanBackups(dir)
+	return backups, manifest.Policy.withDefaults(), err
+}
+
+// SetBackupPolicy changes the retention of the state file's backups and
+// prunes the ones it no longer keeps
+func SetBackupPolicy(statePath string, policy BackupPolicy) error {
+	if policy.Keep < 0 || policy.Days < 0 {
+		return fmt.Errorf("backup retention must not be negative")
+	}
+	dir := BackupDir(statePath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create backups directory: %w", err)
+	}
+	manifest, err := readBackupManifest(dir)
+	if err != nil {
+		return err
+	}
+	manifest.Policy = policy
+	return pruneBackups(dir, manifest, time.Now())
+}
+
+// FindBackup returns the backup a restore asks for: the one with ID query,
+// or else the newest taken at or before the time query names, in RFC 3339
+// or as a local "2006-01-02 15:04[:05]" or "2006-01-02"
+func FindBackup(statePath, query string) (BackupInfo, error) {
+	backups, _, err := ListBackups(statePath)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	for _, b := range backups {
+		if b.ID == query {
+			return b, nil
+		}
+	}
+
+	at, err := parseBackupTime(query)
+	if err != nil {
+		return BackupInfo{}, fmt.Errorf("no backup %q, and it is not a time: use an ID from 'multiclaude state backups list' or a time like 2006-01-02T15:04:05Z", query)
+	}
+	for i := len(backups) - 1; i >= 0; i-- {
+		if !backups[i].Time.After(at) {
+			return backups[i], nil
+		}
+	}
+	return BackupInfo{}, fmt.Errorf("no backup was taken at or before %s", at.Local().Format(time.RFC3339))
+}
+
+// parseBackupTime parses a point in time for FindBackup. A date alone means
+// the end of that day.
+func parseBackupTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// LoadBackup loads the backup with the given ID, for inspecting it before a
+// restore. The returned state must not be saved.
+func LoadBackup(statePath, id string) (*State, error) {
+	path := BackupPath(statePath, id)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to read backup %s: %w", id, err)
+	}
+	return Load(path)
+}
+
+// RestoreBackup replaces the state file with the backup with the given ID.
+// The state being replaced is backed up first, so a restore can be undone
+// by restoring that backup.
+func RestoreBackup(statePath, id string) error {
+	data, err := os.ReadFile(BackupPath(statePath, id))
+	if err != nil {
+		return fmt.Errorf("failed to read backup %s: %w", id, err)
+	}
+	var check State
+	if err := json.Unmarshal(data, &check); err != nil {
+		return fmt.Errorf("backup %s is not a valid state file: %w", id, err)
+	}
+
+	if current, err := os.ReadFile(statePath); err == nil {
+		if err := writeBackup(statePath, current, time.Now()); err != nil {
+			return fmt.Errorf("failed to back up the current state: %w", err)
+		}
+	}
+	return writeFileAtomic(statePath, data)
+}
+
+// writeBackup adds data, the content of the state file just saved, to its
+// backups and prunes them. Saving unchanged state adds no backup, so the
+// kept backups are distinct states.
+func writeBackup(statePath string, data []byte, now time.Time) error {
+	dir := BackupDir(statePath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create backups directory: %w", err)
+	}
+
+	backups, err := scanBackups(dir)
+	if err != nil {
+		return err
+	}
+	if len(backups) > 0 {
+		latest, err := os.ReadFile(BackupPath(statePath, backups[len(backups)-1].ID))
+		if err == nil && bytes.Equal(latest, data) {
+			return nil
+		}
+	}
+
+	if err := writeFileAtomic(BackupPath(statePath, now.UTC().Format(backupIDLayout)), data); err != nil {
+		return err
+	}
+
+	manifest, err := readBackupManifest(dir)
+	if err != nil {
+		return err
+	}
+	return pruneBackups(dir, manifest, now)
+}
+
+// pruneBackups removes the backups in dir that manifest's policy does not
+// keep, then rewrites the manifest to list the rest
+func pruneBackups(dir string, manifest BackupManifest, now time.Time) error {
+	backups, err := scanBackups(dir)
+	if err != nil {
+		return err
+	}
+
+	keep := keptBackups(backups, manifest.Policy.withDefaults(), now)
+	manifest.Backups = nil
+	for _, b := range backups {
+		if !keep[b.ID] {
+			if err := os.Remove(filepath.Join(dir, "state-"+b.ID+".json")); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove backup %s: %w", b.ID, err)
+			}
+			continue
+		}
+		manifest.Backups = append(manifest.Backups, b)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup manifest: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(dir, BackupManifestFile), data)
+}
+
+// keptBackups returns the IDs of the backups, oldest first, that policy
+// keeps: the policy.Keep most recent, and the newest of each local day from
+// policy.Days-1 days ago to today
+func keptBackups(backups []BackupInfo, policy BackupPolicy, now time.Time) map[string]bool {
+	keep := make(map[string]bool)
+	for i := max(0, len(backups)-policy.Keep); i < len(backups); i++ {
+		keep[backups[i].ID] = true
+	}
+
+	y, m, d := now.Local().Date()
+	firstDay := time.Date(y, m, d, 0, 0, 0, 0, time.Local).AddDate(0, 0, -(policy.Days - 1))
+	newestOfDay := make(map[string]string)
+	for _, b := range backups {
+		if t := b.Time.Local(); !t.Before(firstDay) {
+			newestOfDay[t.Format("2006-01-02")] = b.ID
+		}
+	}
+	for _, id := range newestOfDay {
+		keep[id] = true
+	}
+	return keep
+}
+
+// scanBackups returns the backups in dir, oldest first
+func scanBackups(dir string) ([]BackupInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backups directory: %w", err)
+	}
+
+	var backups []BackupInfo
+	for _, entry := range entries {
+		id, ok := strings.CutPrefix(entry.Name(), "state-")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		id, ok = strings.CutSuffix(id, ".json")
+		if !ok {
+			continue
+		}
+		taken, err := time.Parse(backupIDLayout, id)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{ID: id, Time: taken, Size: info.Size()})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].ID < backups[j].ID })
+	return backups, nil
+}
+
+// readBackupManifest reads the manifest in dir, which is empty if there is
+// none yet
+func readBackupManifest(dir string) (BackupManifest, error) {
+	var manifest BackupManifest
+	data, err := os.ReadFile(filepath.Join(dir, BackupManifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		return manifest, fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("failed to parse backup manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	tmpPath := tmp.Name()
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0600)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveWritesBackups(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	s := New(statePath)
+
+	if err := s.AddRepo("a", &Repository{}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	// Saving unchanged state adds no backup
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if err := s.AddRepo("b", &Repository{}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	backups, policy, err := ListBackups(statePath)
+	if err != nil {
+		t.Fatalf("ListBackups() failed: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("got %d backups, want 2: %+v", len(backups), backups)
+	}
+	if policy.Keep != DefaultBackupKeep || policy.Days != DefaultBackupDays {
+		t.Errorf("policy = %+v, want the defaults", policy)
+	}
+	latest, err := LoadBackup(statePath, backups[1].ID)
+	if err != nil {
+		t.Fatalf("LoadBackup() failed: %v", err)
+	}
+	if len(latest.ListRepos()) != 2 {
+		t.Errorf("latest backup has repos %v, want a and b", latest.ListRepos())
+	}
+
+	manifest, err := readBackupManifest(BackupDir(statePath))
+	if err != nil || len(manifest.Backups) != 2 {
+		t.Errorf("manifest = %+v, %v, want 2 backups", manifest, err)
+	}
+	entries, _ := os.ReadDir(BackupDir(statePath))
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == ".tmp" {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestKeptBackups(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	var backups []BackupInfo
+	add := func(at time.Time) {
+		backups = append(backups, BackupInfo{ID: at.UTC().Format(backupIDLayout), Time: at.UTC()})
+	}
+	// Two a day for ten days, then five within the last hour
+	for day := 9; day >= 0; day-- {
+		add(now.AddDate(0, 0, -day).Add(-6 * time.Hour))
+		add(now.AddDate(0, 0, -day).Add(-3 * time.Hour))
+	}
+	for i := 5; i > 0; i-- {
+		add(now.Add(-time.Duration(i) * time.Minute))
+	}
+
+	keep := keptBackups(backups, BackupPolicy{Keep: 3, Days: 4}, now)
+
+	var want []string
+	for day := 3; day >= 1; day-- {
+		want = append(want, now.AddDate(0, 0, -day).Add(-3*time.Hour).UTC().Format(backupIDLayout))
+	}
+	for _, b := range backups[len(backups)-3:] {
+		want = append(want, b.ID)
+	}
+	if len(keep) != len(want) {
+		t.Errorf("kept %d backups, want %d: %v", len(keep), len(want), keep)
+	}
+	for _, id := range want {
+		if !keep[id] {
+			t.Errorf("backup %s should be kept", id)
+		}
+	}
+}
+
+func TestFindAndRestoreBackup(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	dir := BackupDir(statePath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	taken := []time.Time{
+		time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+	}
+	for i, at := range taken {
+		data := []byte(`{"repos": {}, "current_repo": "backup-` + string(rune('0'+i)) + `"}`)
+		if err := os.WriteFile(filepath.Join(dir, "state-"+at.Format(backupIDLayout)+".json"), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query   string
+		want    time.Time
+		wantErr bool
+	}{
+		{query: taken[0].Format(backupIDLayout), want: taken[0]},
+		{query: "2026-03-02T08:00:00Z", want: taken[0]},
+		{query: "2026-03-02T09:00:00Z", want: taken[1]},
+		{query: "2026-03-05T00:00:00Z", want: taken[1]},
+		{query: "2026-02-01T00:00:00Z", wantErr: true},
+		{query: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := FindBackup(statePath, tt.query)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("FindBackup(%q) = %s, want an error", tt.query, got.ID)
+			}
+			continue
+		}
+		if err != nil || !got.Time.Equal(tt.want) {
+			t.Errorf("FindBackup(%q) = %+v, %v, want the backup of %s", tt.query, got, err, tt.want)
+		}
+	}
+
+	current := New(statePath)
+	current.CurrentRepo = "current"
+	if err := current.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if err := RestoreBackup(statePath, taken[0].Format(backupIDLayout)); err != nil {
+		t.Fatalf("RestoreBackup() failed: %v", err)
+	}
+	if err := current.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if current.CurrentRepo != "backup-0" {
+		t.Errorf("CurrentRepo after restore = %q, want backup-0", current.CurrentRepo)
+	}
+
+	// The replaced state was backed up
+	backups, _, _ := ListBackups(statePath)
+	replaced, err := LoadBackup(statePath, backups[len(backups)-1].ID)
+	if err != nil || replaced.CurrentRepo != "current" {
+		t.Errorf("newest backup = %+v, %v, want the replaced state", replaced, err)
+	}
+
+	if err := RestoreBackup(statePath, "20200101T000000.000000Z"); err == nil {
+		t.Error("RestoreBackup() of a missing backup should fail")
+	}
+}
+
+func TestSetBackupPolicy(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	s := New(statePath)
+	for i := 0; i < 4; i++ {
+		s.CurrentRepo = string(rune('a' + i))
+		if err := s.Save(); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+		// Backup IDs have microsecond resolution
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := SetBackupPolicy(statePath, BackupPolicy{Keep: 2, Days: 1}); err != nil {
+		t.Fatalf("SetBackupPolicy() failed: %v", err)
+	}
+	backups, policy, err := ListBackups(statePath)
+	if err != nil {
+		t.Fatalf("ListBackups() failed: %v", err)
+	}
+	if policy.Keep != 2 || policy.Days != 1 {
+		t.Errorf("policy = %+v, want keep 2 and days 1", policy)
+	}
+	// The last two, the newest of which is also today's
+	if len(backups) != 2 {
+		t.Errorf("got %d backups, want 2", len(backups))
+	}
+
+	if err := SetBackupPolicy(statePath, BackupPolicy{Keep: -1}); err == nil {
+		t.Error("SetBackupPolicy() should reject a negative policy")
+	}
+}
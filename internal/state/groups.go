@@ -0,0 +1,168 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CreateGroup creates a named group of tracked repositories. A repository
+// may belong to any number of groups.
+func (s *State) CreateGroup(name string, repos []string) error {
+	if err := ValidateGroupName(name); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.Groups[name]; exists {
+		return fmt.Errorf("group %q already exists", name)
+	}
+	if err := s.checkTrackedUnlocked(repos); err != nil {
+		return err
+	}
+
+	if s.Groups == nil {
+		s.Groups = make(map[string][]string)
+	}
+	s.Groups[name] = mergeMembers(nil, repos)
+	return s.saveUnlocked()
+}
+
+// AddToGroup adds tracked repositories to a group. Repositories already in
+// it are left as they are.
+func (s *State) AddToGroup(name string, repos []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members, exists := s.Groups[name]
+	if !exists {
+		return fmt.Errorf("group %q not found", name)
+	}
+	if err := s.checkTrackedUnlocked(repos); err != nil {
+		return err
+	}
+
+	s.Groups[name] = mergeMembers(members, repos)
+	return s.saveUnlocked()
+}
+
+// RemoveFromGroup removes repositories from a group. It fails if one of
+// them is not a member, and leaves the group in place when it empties.
+func (s *State) RemoveFromGroup(name string, repos []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members, exists := s.Groups[name]
+	if !exists {
+		return fmt.Errorf("group %q not found", name)
+	}
+	remove := make(map[string]bool, len(repos))
+	for _, repo := range repos {
+		remove[repo] = true
+	}
+
+	kept := make([]string, 0, len(members))
+	for _, member := range members {
+		if remove[member] {
+			delete(remove, member)
+			continue
+		}
+		kept = append(kept, member)
+	}
+	for repo := range remove {
+		return fmt.Errorf("repository %q is not in group %q", repo, name)
+	}
+
+	s.Groups[name] = kept
+	return s.saveUnlocked()
+}
+
+// DeleteGroup deletes a group; its repositories are not affected
+func (s *State) DeleteGroup(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.Groups[name]; !exists {
+		return fmt.Errorf("group %q not found", name)
+	}
+	delete(s.Groups, name)
+	return s.saveUnlocked()
+}
+
+// GetGroup returns a copy of a group's member repositories, sorted by name
+func (s *State) GetGroup(name string) ([]string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	members, exists := s.Groups[name]
+	if !exists {
+		return nil, false
+	}
+	return append([]string{}, members...), true
+}
+
+// ListGroups returns a copy of every group and its member repositories
+func (s *State) ListGroups() map[string][]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := make(map[string][]string, len(s.Groups))
+	for name, members := range s.Groups {
+		groups[name] = append([]string{}, members...)
+	}
+	return groups
+}
+
+// ValidateGroupName checks a group name: letters, digits, '_', '-' and '.',
+// as for context keys
+func ValidateGroupName(name string) error {
+	if name == "" {
+		return fmt.Errorf("group name must not be empty")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.') {
+			return fmt.Errorf("invalid group name %q (use letters, digits, '_', '-' and '.')", name)
+		}
+	}
+	return nil
+}
+
+// checkTrackedUnlocked fails unless every repository is tracked (caller
+// must hold lock)
+func (s *State) checkTrackedUnlocked(repos []string) error {
+	for _, repo := range repos {
+		if _, exists := s.Repos[repo]; !exists {
+			return fmt.Errorf("repository %q not found", repo)
+		}
+	}
+	return nil
+}
+
+// removeFromGroupsUnlocked drops a repository from every group it belongs
+// to (caller must hold lock)
+func (s *State) removeFromGroupsUnlocked(repo string) {
+	for name, members := range s.Groups {
+		kept := members[:0]
+		for _, member := range members {
+			if member != repo {
+				kept = append(kept, member)
+			}
+		}
+		s.Groups[name] = kept
+	}
+}
+
+// mergeMembers returns the sorted union of a group's members and repos
+func mergeMembers(members, repos []string) []string {
+	seen := make(map[string]bool, len(members)+len(repos))
+	merged := make([]string, 0, len(members)+len(repos))
+	for _, repo := range append(append([]string{}, members...), repos...) {
+		if !seen[repo] {
+			seen[repo] = true
+			merged = append(merged, repo)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
@@ -0,0 +1,77 @@
+package state
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGroups(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	s := New(statePath)
+	for _, name := range []string{"api", "web", "docs"} {
+		if err := s.AddRepo(name, &Repository{Agents: make(map[string]Agent)}); err != nil {
+			t.Fatalf("AddRepo(%s) failed: %v", name, err)
+		}
+	}
+
+	if err := s.CreateGroup("backend", []string{"web", "api", "api"}); err != nil {
+		t.Fatalf("CreateGroup() failed: %v", err)
+	}
+	if members, ok := s.GetGroup("backend"); !ok || !reflect.DeepEqual(members, []string{"api", "web"}) {
+		t.Errorf("GetGroup() = %v, %v, want sorted members without duplicates", members, ok)
+	}
+	if err := s.CreateGroup("backend", nil); err == nil {
+		t.Error("CreateGroup() of an existing group should fail")
+	}
+	if err := s.CreateGroup("other", []string{"missing"}); err == nil {
+		t.Error("CreateGroup() with an untracked repository should fail")
+	}
+	if err := s.CreateGroup("bad name", nil); err == nil {
+		t.Error("CreateGroup() with an invalid name should fail")
+	}
+
+	// A repository may belong to several groups
+	if err := s.CreateGroup("everything", []string{"api"}); err != nil {
+		t.Fatalf("CreateGroup() failed: %v", err)
+	}
+	if err := s.AddToGroup("everything", []string{"docs", "web", "api"}); err != nil {
+		t.Fatalf("AddToGroup() failed: %v", err)
+	}
+	if err := s.AddToGroup("missing", []string{"api"}); err == nil {
+		t.Error("AddToGroup() of an unknown group should fail")
+	}
+	if err := s.AddToGroup("everything", []string{"missing"}); err == nil {
+		t.Error("AddToGroup() of an untracked repository should fail")
+	}
+
+	if err := s.RemoveFromGroup("everything", []string{"docs"}); err != nil {
+		t.Fatalf("RemoveFromGroup() failed: %v", err)
+	}
+	if err := s.RemoveFromGroup("everything", []string{"docs"}); err == nil {
+		t.Error("RemoveFromGroup() of a non-member should fail")
+	}
+
+	// Removing a repository removes it from its groups; the state persists
+	if err := s.RemoveRepo("api"); err != nil {
+		t.Fatalf("RemoveRepo() failed: %v", err)
+	}
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	want := map[string][]string{"backend": {"web"}, "everything": {"web"}}
+	if groups := loaded.ListGroups(); !reflect.DeepEqual(groups, want) {
+		t.Errorf("ListGroups() = %v, want %v", groups, want)
+	}
+
+	if err := s.DeleteGroup("backend"); err != nil {
+		t.Fatalf("DeleteGroup() failed: %v", err)
+	}
+	if _, ok := s.GetGroup("backend"); ok {
+		t.Error("deleted group still exists")
+	}
+	if err := s.DeleteGroup("backend"); err == nil {
+		t.Error("DeleteGroup() of an unknown group should fail")
+	}
+}
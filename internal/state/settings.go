@@ -0,0 +1,363 @@
+package state
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/names"
+)
+
+// transportSettingPrefix starts the names of the settings that override a
+// repository's message transport for one agent type
+const transportSettingPrefix = "transport-"
+
+// setting binds a repository setting, by its name in multiclaude config
+// (see package repoconfig), to the typed field it is decoded into. Values
+// are strings in repoconfig's canonical form; setting "" restores the
+// default.
+type setting struct {
+	name string
+	get  func(r *Repository) string
+	set  func(r *Repository, value string)
+}
+
+// legacySettingFields are the JSON fields settings were saved in before the
+// config map. They are still read, and so migrated into it on the next save.
+var legacySettingFields = []string{
+	"merge_queue_config", "env_file", "max_concurrent_workers", "max_concurrent_ephemeral",
+	"worktree_limit", "message_transport", "min_claude_version", "claude_path",
+	"duplicate_window", "archive_max_age", "archive_max_size_mb", "submodule_timeout",
+	"auto_ack_after", "auto_sync", "crash_loop_limit", "crash_loop_window",
+	"name_scheme", "name_template", "message_max_size", "message_hard_cap",
+	"nudge_when_idle", "raw_logs", "snapshot_keep", "git_identity",
+}
+
+// Setting returns the effective value of a repository setting; ok is false
+// for a name no setting has
+func (r *Repository) Setting(name string) (value string, ok bool) {
+	if agentType, isTransport := strings.CutPrefix(name, transportSettingPrefix); isTransport {
+		return r.MessageTransport.ByAgentType[AgentType(agentType)], true
+	}
+	s, ok := lookupSetting(name)
+	if !ok {
+		return "", false
+	}
+	return s.get(r), true
+}
+
+// SetSetting sets a repository setting to a value in canonical form, or
+// back to its default for ""; ok is false for a name no setting has
+func (r *Repository) SetSetting(name, value string) (ok bool) {
+	if agentType, isTransport := strings.CutPrefix(name, transportSettingPrefix); isTransport {
+		if value == "" {
+			delete(r.MessageTransport.ByAgentType, AgentType(agentType))
+			return true
+		}
+		if r.MessageTransport.ByAgentType == nil {
+			r.MessageTransport.ByAgentType = make(map[AgentType]string)
+		}
+		r.MessageTransport.ByAgentType[AgentType(agentType)] = value
+		return true
+	}
+	s, ok := lookupSetting(name)
+	if !ok {
+		return false
+	}
+	s.set(r, value)
+	return true
+}
+
+func lookupSetting(name string) (setting, bool) {
+	for _, s := range settings {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// configMap returns the settings of a repository that differ from their
+// defaults, as saved in the state file's config map. Keys this binary does
+// not know, from another multiclaude version, are kept as they were read.
+func (r *Repository) configMap() map[string]string {
+	config := make(map[string]string, len(r.unknownConfig))
+	for name, value := range r.unknownConfig {
+		config[name] = value
+	}
+	var defaults Repository
+	for _, s := range settings {
+		if value := s.get(r); value != s.get(&defaults) {
+			config[s.name] = value
+		}
+	}
+	for agentType, transport := range r.MessageTransport.ByAgentType {
+		if transport != "" {
+			config[transportSettingPrefix+string(agentType)] = transport
+		}
+	}
+	return config
+}
+
+// repositoryFields is a Repository without its JSON methods
+type repositoryFields Repository
+
+// MarshalJSON saves a repository's settings in a generic "config" map, by
+// setting name, rather than in the typed fields they are decoded into
+func (r Repository) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(repositoryFields(r))
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for _, legacy := range legacySettingFields {
+		delete(fields, legacy)
+	}
+	if config := r.configMap(); len(config) > 0 {
+		if fields["config"], err = json.Marshal(config); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON reads a repository, decoding the settings of its config
+// map into their typed fields. Settings still in their legacy fields are
+// read too; the config map takes precedence.
+func (r *Repository) UnmarshalJSON(data []byte) error {
+	var saved struct {
+		repositoryFields
+		Config map[string]string `json:"config"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	*r = Repository(saved.repositoryFields)
+
+	for name, value := range saved.Config {
+		if !r.SetSetting(name, value) {
+			if r.unknownConfig == nil {
+				r.unknownConfig = make(map[string]string)
+			}
+			r.unknownConfig[name] = value
+		}
+	}
+	return nil
+}
+
+// mergeQueue returns a repository's merge-queue config, which is unset as a
+// whole in repositories from before it existed
+func (r *Repository) mergeQueue() MergeQueueConfig {
+	if r.MergeQueueConfig.TrackMode == "" {
+		return DefaultMergeQueueConfig()
+	}
+	return r.MergeQueueConfig
+}
+
+// updateMergeQueue changes one of a repository's merge-queue settings
+func (r *Repository) updateMergeQueue(update func(c *MergeQueueConfig)) {
+	c := r.mergeQueue()
+	update(&c)
+	r.MergeQueueConfig = c
+}
+
+func atoi(value string) int {
+	n, _ := strconv.Atoi(value)
+	return n
+}
+
+// offWhenZero stores a zero duration as unset, for settings where both mean
+// off or the default
+func offWhenZero(value string) string {
+	if value == "0s" {
+		return ""
+	}
+	return value
+}
+
+var settings = []setting{
+	{
+		name: "mq-enabled",
+		get:  func(r *Repository) string { return strconv.FormatBool(r.mergeQueue().Enabled) },
+		set: func(r *Repository, v string) {
+			r.updateMergeQueue(func(c *MergeQueueConfig) { c.Enabled = v != "false" })
+		},
+	},
+	{
+		name: "mq-track",
+		get:  func(r *Repository) string { return string(r.mergeQueue().TrackMode) },
+		set: func(r *Repository, v string) {
+			if v == "" {
+				v = string(TrackModeAll)
+			}
+			r.updateMergeQueue(func(c *MergeQueueConfig) { c.TrackMode = TrackMode(v) })
+		},
+	},
+	{
+		name: "mq-review-enabled",
+		get:  func(r *Repository) string { return strconv.FormatBool(r.mergeQueue().MQReviewRequired) },
+		set: func(r *Repository, v string) {
+			r.updateMergeQueue(func(c *MergeQueueConfig) { c.MQReviewRequired = v == "true" })
+		},
+	},
+	{
+		name: "mq-review-pattern",
+		get:  func(r *Repository) string { return r.mergeQueue().MQReviewPattern },
+		set: func(r *Repository, v string) {
+			r.updateMergeQueue(func(c *MergeQueueConfig) { c.MQReviewPattern = v })
+		},
+	},
+	{
+		name: "env-file",
+		get:  func(r *Repository) string { return r.EnvFile },
+		set:  func(r *Repository, v string) { r.EnvFile = v },
+	},
+	{
+		name: "min-claude-version",
+		get:  func(r *Repository) string { return r.MinClaudeVersion },
+		set:  func(r *Repository, v string) { r.MinClaudeVersion = v },
+	},
+	{
+		name: "pin-claude-path",
+		get:  func(r *Repository) string { return r.ClaudePath },
+		set:  func(r *Repository, v string) { r.ClaudePath = v },
+	},
+	{
+		name: "max-concurrent-workers",
+		get:  func(r *Repository) string { return strconv.Itoa(r.MaxConcurrentWorkers) },
+		set:  func(r *Repository, v string) { r.MaxConcurrentWorkers = atoi(v) },
+	},
+	{
+		name: "max-concurrent-ephemeral",
+		get:  func(r *Repository) string { return strconv.Itoa(r.MaxConcurrentEphemeral) },
+		set:  func(r *Repository, v string) { r.MaxConcurrentEphemeral = atoi(v) },
+	},
+	{
+		name: "worktree-limit",
+		get:  func(r *Repository) string { return strconv.Itoa(r.WorktreeLimit) },
+		set:  func(r *Repository, v string) { r.WorktreeLimit = atoi(v) },
+	},
+	{
+		name: "duplicate-window",
+		get:  func(r *Repository) string { return r.DuplicateWindowDuration().String() },
+		set:  func(r *Repository, v string) { r.DuplicateWindow = v },
+	},
+	{
+		name: "archive-max-age",
+		get:  func(r *Repository) string { return r.ArchiveMaxAgeDuration().String() },
+		set:  func(r *Repository, v string) { r.ArchiveMaxAge = v },
+	},
+	{
+		name: "archive-max-size-mb",
+		get:  func(r *Repository) string { return strconv.Itoa(r.ArchiveMaxSizeMB) },
+		set:  func(r *Repository, v string) { r.ArchiveMaxSizeMB = atoi(v) },
+	},
+	{
+		name: "submodule-timeout",
+		get:  func(r *Repository) string { return r.SubmoduleTimeoutDuration().String() },
+		set:  func(r *Repository, v string) { r.SubmoduleTimeout = v },
+	},
+	{
+		name: "auto-ack-after",
+		get:  func(r *Repository) string { return r.AutoAckAfterDuration().String() },
+		set:  func(r *Repository, v string) { r.AutoAckAfter = offWhenZero(v) },
+	},
+	{
+		name: "auto-sync",
+		get:  func(r *Repository) string { return r.AutoSyncInterval().String() },
+		set:  func(r *Repository, v string) { r.AutoSync = offWhenZero(v) },
+	},
+	{
+		name: "crash-loop-limit",
+		get:  func(r *Repository) string { return strconv.Itoa(r.CrashLoopMaxRestarts()) },
+		set:  func(r *Repository, v string) { r.CrashLoopLimit = atoi(v) },
+	},
+	{
+		name: "crash-loop-window",
+		get:  func(r *Repository) string { return r.CrashLoopWindowDuration().String() },
+		set:  func(r *Repository, v string) { r.CrashLoopWindow = offWhenZero(v) },
+	},
+	{
+		name: "name-scheme",
+		get: func(r *Repository) string {
+			if r.NameScheme == "" {
+				return string(names.SchemeDocker)
+			}
+			return r.NameScheme
+		},
+		set: func(r *Repository, v string) {
+			if v == string(names.SchemeDocker) {
+				v = ""
+			}
+			r.NameScheme = v
+		},
+	},
+	{
+		name: "name-template",
+		get:  func(r *Repository) string { return r.NameTemplate },
+		set:  func(r *Repository, v string) { r.NameTemplate = v },
+	},
+	{
+		name: "message-max-size",
+		get:  func(r *Repository) string { return strconv.Itoa(r.MessageLimits().EffectiveMaxBodySize()) },
+		set:  func(r *Repository, v string) { r.MessageMaxSize = atoi(v) },
+	},
+	{
+		name: "message-hard-cap",
+		get:  func(r *Repository) string { return strconv.Itoa(r.MessageLimits().EffectiveHardCap()) },
+		set:  func(r *Repository, v string) { r.MessageHardCap = atoi(v) },
+	},
+	{
+		name: "nudge-when-idle",
+		get:  func(r *Repository) string { return strconv.FormatBool(r.NudgeWhenIdle) },
+		set:  func(r *Repository, v string) { r.NudgeWhenIdle = v == "true" },
+	},
+	{
+		name: "raw-logs",
+		get:  func(r *Repository) string { return strconv.FormatBool(r.RawLogs) },
+		set:  func(r *Repository, v string) { r.RawLogs = v == "true" },
+	},
+	{
+		name: "snapshot-keep",
+		get:  func(r *Repository) string { return strconv.Itoa(r.SnapshotKeepCount()) },
+		set:  func(r *Repository, v string) { r.SnapshotKeep = atoi(v) },
+	},
+	{
+		name: "git-name",
+		get:  func(r *Repository) string { return r.GitIdentity.Name },
+		set:  func(r *Repository, v string) { r.GitIdentity.Name = v },
+	},
+	{
+		name: "git-email",
+		get:  func(r *Repository) string { return r.GitIdentity.Email },
+		set:  func(r *Repository, v string) { r.GitIdentity.Email = v },
+	},
+	{
+		name: "git-bot-suffix",
+		get:  func(r *Repository) string { return r.GitIdentity.BotSuffix },
+		set:  func(r *Repository, v string) { r.GitIdentity.BotSuffix = v },
+	},
+	{
+		name: "sign-commits",
+		get:  func(r *Repository) string { return strconv.FormatBool(r.GitIdentity.SignCommits) },
+		set:  func(r *Repository, v string) { r.GitIdentity.SignCommits = v == "true" },
+	},
+	{
+		name: "signing-key",
+		get:  func(r *Repository) string { return r.GitIdentity.SigningKey },
+		set:  func(r *Repository, v string) { r.GitIdentity.SigningKey = v },
+	},
+	{
+		name: "signing-format",
+		get:  func(r *Repository) string { return r.GitIdentity.EffectiveSigningFormat() },
+		set:  func(r *Repository, v string) { r.GitIdentity.SigningFormat = v },
+	},
+	{
+		name: "transport",
+		get:  func(r *Repository) string { return r.MessageTransport.Default },
+		set:  func(r *Repository, v string) { r.MessageTransport.Default = v },
+	},
+}
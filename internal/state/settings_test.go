@@ -0,0 +1,139 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setSetting sets a repository setting, failing the test if it cannot
+func setSetting(t *testing.T, s *State, repoName, name, value string) {
+	t.Helper()
+	if err := s.UpdateRepoConfig(repoName, func(r *Repository) error {
+		if !r.SetSetting(name, value) {
+			t.Fatalf("SetSetting(%q) found no such setting", name)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateRepoConfig() failed: %v", err)
+	}
+}
+
+// savedRepo returns a repository's fields as saved in a state file
+func savedRepo(t *testing.T, statePath, repoName string) map[string]json.RawMessage {
+	t.Helper()
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		t.Fatalf("failed to read state file: %v", err)
+	}
+	var saved struct {
+		Repos map[string]map[string]json.RawMessage `json:"repos"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("failed to parse state file: %v", err)
+	}
+	return saved.Repos[repoName]
+}
+
+func TestSettingsSavedInConfigMap(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	setSetting(t, s, "test-repo", "mq-track", string(TrackModeAuthor))
+	setSetting(t, s, "test-repo", "snapshot-keep", "5")
+	setSetting(t, s, "test-repo", "transport-worker", "inbox")
+	// A setting at its default is left out of the map
+	setSetting(t, s, "test-repo", "nudge-when-idle", "false")
+
+	saved := savedRepo(t, statePath, "test-repo")
+	var config map[string]string
+	if err := json.Unmarshal(saved["config"], &config); err != nil {
+		t.Fatalf("config map not saved: %v", err)
+	}
+	want := map[string]string{"mq-track": "author", "snapshot-keep": "5", "transport-worker": "inbox"}
+	if len(config) != len(want) {
+		t.Errorf("config = %v, want %v", config, want)
+	}
+	for name, value := range want {
+		if config[name] != value {
+			t.Errorf("config[%s] = %q, want %q", name, config[name], value)
+		}
+	}
+	for _, legacy := range []string{"merge_queue_config", "snapshot_keep", "message_transport"} {
+		if _, ok := saved[legacy]; ok {
+			t.Errorf("setting saved in its legacy field %s", legacy)
+		}
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	repo, _ := loaded.GetRepo("test-repo")
+	if repo.MergeQueueConfig.TrackMode != TrackModeAuthor || !repo.MergeQueueConfig.Enabled {
+		t.Errorf("MergeQueueConfig after reload = %+v", repo.MergeQueueConfig)
+	}
+	if repo.SnapshotKeepCount() != 5 || repo.MessageTransport.TransportFor(AgentTypeWorker) != "inbox" {
+		t.Errorf("settings not decoded after reload: %+v", repo)
+	}
+}
+
+func TestLegacySettingsMigrateToConfigMap(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	legacy := `{"repos": {"test-repo": {
+		"github_url": "https://github.com/test/repo",
+		"agents": {},
+		"merge_queue_config": {"enabled": false, "track_mode": "assigned"},
+		"env_file": "/secrets/repo.env",
+		"max_concurrent_workers": 3,
+		"git_identity": {"name": "Jane Doe"},
+		"config": {"worktree-limit": "4", "from-a-newer-version": "x"}
+	}}}`
+	if err := os.WriteFile(statePath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("failed to write state file: %v", err)
+	}
+
+	s, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	repo, _ := s.GetRepo("test-repo")
+	if repo.MergeQueueConfig.Enabled || repo.MergeQueueConfig.TrackMode != TrackModeAssigned {
+		t.Errorf("MergeQueueConfig = %+v, want disabled and assigned", repo.MergeQueueConfig)
+	}
+	if repo.EnvFile != "/secrets/repo.env" || repo.MaxConcurrentWorkers != 3 || repo.GitIdentity.Name != "Jane Doe" || repo.WorktreeLimit != 4 {
+		t.Errorf("legacy settings not read: %+v", repo)
+	}
+
+	// The next save moves them into the config map, keeping unknown keys
+	setSetting(t, s, "test-repo", "raw-logs", "true")
+	saved := savedRepo(t, statePath, "test-repo")
+	for _, field := range legacySettingFields {
+		if _, ok := saved[field]; ok {
+			t.Errorf("legacy field %s still saved", field)
+		}
+	}
+	var config map[string]string
+	if err := json.Unmarshal(saved["config"], &config); err != nil {
+		t.Fatalf("config map not saved: %v", err)
+	}
+	want := map[string]string{
+		"mq-enabled":             "false",
+		"mq-track":               "assigned",
+		"env-file":               "/secrets/repo.env",
+		"max-concurrent-workers": "3",
+		"git-name":               "Jane Doe",
+		"worktree-limit":         "4",
+		"raw-logs":               "true",
+		"from-a-newer-version":   "x",
+	}
+	for name, value := range want {
+		if config[name] != value {
+			t.Errorf("config[%s] = %q, want %q", name, config[name], value)
+		}
+	}
+}
@@ -1,11 +1,19 @@
 package state
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/pkg/config"
 )
 
 // AgentType represents the type of agent
@@ -17,6 +25,7 @@ const (
 	AgentTypeMergeQueue AgentType = "merge-queue"
 	AgentTypeWorkspace  AgentType = "workspace"
 	AgentTypeReview     AgentType = "review"
+	AgentTypeEphemeral  AgentType = "ephemeral"
 )
 
 // TrackMode defines which PRs the merge queue should track
@@ -37,6 +46,12 @@ type MergeQueueConfig struct {
 	Enabled bool `json:"enabled"`
 	// TrackMode determines which PRs to track: "all", "author", or "assigned" (default: "all")
 	TrackMode TrackMode `json:"track_mode"`
+	// MQReviewRequired makes the merge queue agent wait for a review agent's
+	// approval before merging a PR
+	MQReviewRequired bool `json:"review_required,omitempty"`
+	// MQReviewPattern is a regexp matched against PR titles to pick the PRs
+	// that need review when MQReviewRequired is set. Empty means all PRs.
+	MQReviewPattern string `json:"review_pattern,omitempty"`
 }
 
 // DefaultMergeQueueConfig returns the default merge queue configuration
@@ -47,6 +62,110 @@ func DefaultMergeQueueConfig() MergeQueueConfig {
 	}
 }
 
+// MessageTransportConfig selects how routed messages are delivered to a
+// repository's agents. Empty values fall back to the daemon's default transport.
+type MessageTransportConfig struct {
+	// Default is the transport used for all agents in the repository
+	Default string `json:"default,omitempty"`
+	// ByAgentType overrides the transport for specific agent types
+	ByAgentType map[AgentType]string `json:"by_agent_type,omitempty"`
+}
+
+// TransportFor returns the transport configured for an agent type, or "" if unset
+func (c MessageTransportConfig) TransportFor(agentType AgentType) string {
+	if name := c.ByAgentType[agentType]; name != "" {
+		return name
+	}
+	return c.Default
+}
+
+// copy returns a deep copy of the config
+func (c MessageTransportConfig) copy() MessageTransportConfig {
+	out := MessageTransportConfig{Default: c.Default}
+	if len(c.ByAgentType) > 0 {
+		out.ByAgentType = make(map[AgentType]string, len(c.ByAgentType))
+		for k, v := range c.ByAgentType {
+			out.ByAgentType[k] = v
+		}
+	}
+	return out
+}
+
+// Signing formats of GitIdentity.SigningFormat, named as git's gpg.format
+const (
+	SigningFormatOpenPGP = "openpgp"
+	SigningFormatSSH     = "ssh"
+)
+
+// GitIdentity is the committer identity and commit signing set up in each of
+// a repository's worktrees. Empty values leave git's own configuration in
+// effect.
+type GitIdentity struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	// BotSuffix is appended to Name, marking commits as made by an agent,
+	// e.g. "[bot]" commits as "Jane Doe [bot]"
+	BotSuffix   string `json:"bot_suffix,omitempty"`
+	SignCommits bool   `json:"sign_commits,omitempty"`
+	// SigningKey is a GPG key ID, or the path of an SSH key. Empty means
+	// gpg's default key.
+	SigningKey string `json:"signing_key,omitempty"`
+	// SigningFormat is SigningFormatOpenPGP or SigningFormatSSH; empty
+	// means OpenPGP
+	SigningFormat string `json:"signing_format,omitempty"`
+}
+
+// CommitterName returns the name commits are made under, with the bot
+// suffix, or "" if no name is set
+func (g GitIdentity) CommitterName() string {
+	if g.Name == "" || g.BotSuffix == "" {
+		return g.Name
+	}
+	return g.Name + " " + g.BotSuffix
+}
+
+// EffectiveSigningFormat returns the signing format, defaulting to OpenPGP
+func (g GitIdentity) EffectiveSigningFormat() string {
+	if g.SigningFormat == "" {
+		return SigningFormatOpenPGP
+	}
+	return g.SigningFormat
+}
+
+// Validate checks the signing settings
+func (g GitIdentity) Validate() error {
+	switch g.SigningFormat {
+	case "", SigningFormatOpenPGP, SigningFormatSSH:
+	default:
+		return fmt.Errorf("invalid signing format %q (must be openpgp or ssh)", g.SigningFormat)
+	}
+	if g.SignCommits && g.SigningFormat == SigningFormatSSH && g.SigningKey == "" {
+		return fmt.Errorf("signing commits with ssh needs a signing key")
+	}
+	if g.BotSuffix != "" && g.Name == "" {
+		return fmt.Errorf("a bot suffix needs a committer name")
+	}
+	return nil
+}
+
+// GitConfig returns the git config values applied to each worktree. Keys
+// with empty values are unset, so git's own configuration applies.
+func (g GitIdentity) GitConfig() map[string]string {
+	values := map[string]string{
+		"user.name":       g.CommitterName(),
+		"user.email":      g.Email,
+		"commit.gpgsign":  "",
+		"user.signingkey": "",
+		"gpg.format":      "",
+	}
+	if g.SignCommits {
+		values["commit.gpgsign"] = "true"
+		values["user.signingkey"] = g.SigningKey
+		values["gpg.format"] = g.EffectiveSigningFormat()
+	}
+	return values
+}
+
 // TaskStatus represents the status of a completed task
 type TaskStatus string
 
@@ -63,54 +182,373 @@ const (
 	TaskStatusFailed TaskStatus = "failed"
 	// TaskStatusUnknown means the status couldn't be determined
 	TaskStatusUnknown TaskStatus = "unknown"
+	// TaskStatusSynced marks an entry recording that the default branch was
+	// synced with upstream rather than a worker's task
+	TaskStatusSynced TaskStatus = "synced"
 )
 
 // TaskHistoryEntry represents a completed task in the history
 type TaskHistoryEntry struct {
-	Name          string     `json:"name"`                     // Worker name
-	Task          string     `json:"task"`                     // Task description
-	Branch        string     `json:"branch"`                   // Git branch
-	PRURL         string     `json:"pr_url,omitempty"`         // Pull request URL if created
-	PRNumber      int        `json:"pr_number,omitempty"`      // PR number for quick lookup
-	Status        TaskStatus `json:"status"`                   // Current status
-	Summary       string     `json:"summary,omitempty"`        // Brief summary of what was accomplished
-	FailureReason string     `json:"failure_reason,omitempty"` // Why the task failed (if applicable)
-	CreatedAt     time.Time  `json:"created_at"`               // When the task was started
-	CompletedAt   time.Time  `json:"completed_at,omitempty"`   // When the task was completed
+	Name          string       `json:"name"`                     // Worker name
+	Task          string       `json:"task"`                     // Task description
+	Branch        string       `json:"branch"`                   // Git branch
+	PRURL         string       `json:"pr_url,omitempty"`         // Pull request URL if created
+	PRNumber      int          `json:"pr_number,omitempty"`      // PR number for quick lookup
+	Status        TaskStatus   `json:"status"`                   // Current status
+	Summary       string       `json:"summary,omitempty"`        // Brief summary of what was accomplished
+	FailureReason string       `json:"failure_reason,omitempty"` // Why the task failed (if applicable)
+	CreatedAt     time.Time    `json:"created_at"`               // When the task was started
+	CompletedAt   time.Time    `json:"completed_at,omitempty"`   // When the task was completed
+	TaskUpdates   []TaskUpdate `json:"task_updates,omitempty"`   // Changes to the task while the worker ran
+}
+
+// TaskUpdate records a change to a running worker's task
+type TaskUpdate struct {
+	OldTask   string    `json:"old_task"`
+	NewTask   string    `json:"new_task"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TrackedPR is a pull request the merge-queue agent is tracking. Records are
+// persisted so a restarted merge-queue agent can resume where it left off.
+type TrackedPR struct {
+	Number     int       `json:"number"`
+	URL        string    `json:"url,omitempty"`
+	Status     string    `json:"status,omitempty"` // Status assigned by the merge-queue agent, e.g. "approved"
+	Notes      string    `json:"notes,omitempty"`
+	LastAction time.Time `json:"last_action"`
 }
 
 // Agent represents an agent's state
 type Agent struct {
-	Type            AgentType `json:"type"`
-	WorktreePath    string    `json:"worktree_path"`
-	TmuxWindow      string    `json:"tmux_window"`
-	SessionID       string    `json:"session_id"`
-	PID             int       `json:"pid"`
-	Task            string    `json:"task,omitempty"`             // Only for workers
-	Summary         string    `json:"summary,omitempty"`          // Brief summary of work done (workers only)
-	FailureReason   string    `json:"failure_reason,omitempty"`   // Why the task failed (workers only)
-	PRURL           string    `json:"pr_url,omitempty"`           // Pull request URL if created (workers only)
-	PRNumber        int       `json:"pr_number,omitempty"`        // PR number for quick lookup (workers only)
-	CreatedAt       time.Time `json:"created_at"`
-	LastNudge       time.Time `json:"last_nudge,omitempty"`
-	ReadyForCleanup bool      `json:"ready_for_cleanup,omitempty"` // Only for workers
+	Type            AgentType    `json:"type"`
+	WorktreePath    string       `json:"worktree_path"`
+	TmuxWindow      string       `json:"tmux_window"`
+	TmuxWindowID    string       `json:"tmux_window_id,omitempty"` // tmux's immutable window ID ("@3"); resolved from TmuxWindow when missing
+	SessionID       string       `json:"session_id"`
+	PID             int          `json:"pid"`
+	Task            string       `json:"task,omitempty"`           // Only for workers
+	Summary         string       `json:"summary,omitempty"`        // Brief summary of work done (workers only)
+	FailureReason   string       `json:"failure_reason,omitempty"` // Why the task failed (workers only)
+	PRURL           string       `json:"pr_url,omitempty"`         // Pull request URL if created (workers only)
+	PRNumber        int          `json:"pr_number,omitempty"`      // PR number for quick lookup (workers only)
+	OriginWorker    string       `json:"origin_worker,omitempty"`  // Worker this one was split from (workers only)
+	ContextFiles    []string     `json:"context_files,omitempty"`  // Context files copied into the worktree, relative to it
+	Paths           []string     `json:"paths,omitempty"`          // Directories the task is scoped to, relative to the worktree (workers only)
+	TaskUpdates     []TaskUpdate `json:"task_updates,omitempty"`   // Changes to the task since the worker started (workers only)
+	CreatedAt       time.Time    `json:"created_at"`
+	LastNudge       time.Time    `json:"last_nudge,omitempty"`
+	ReadyForCleanup bool         `json:"ready_for_cleanup,omitempty"` // Only for workers
+	Status          string       `json:"status,omitempty"`            // Set by hand with agent set-status, e.g. "blocked"; cleared when the agent completes
+	// Environment holds variables set for this agent alone, e.g. with agent
+	// set-env, so they are set again when the daemon restarts it. Values are
+	// often secrets, which is why the state file is private to its owner.
+	Environment map[string]string `json:"environment,omitempty"`
+	// WatchPR asks the daemon to poll the agent's PR and message the agent
+	// once it is merged or closed (workspace create-from-pr --watch)
+	WatchPR bool `json:"watch_pr,omitempty"`
+	// Branch is the branch last seen checked out in the agent's worktree by
+	// the filesystem watcher (daemon start --watch-fs), which notices
+	// branches switched by running git by hand
+	Branch string `json:"branch,omitempty"`
+	// UpstreamBranch is the remote-tracking branch ("origin/feature") set
+	// with workspace set-upstream, which workspace create-pr opens PRs
+	// against (workspaces only)
+	UpstreamBranch string `json:"upstream_branch,omitempty"`
+	// LastSeenMessages, LastSeenReviewComments and LastSeenBehind are the
+	// unread messages, comments on the agent's PR and commits its branch was
+	// behind main when the wake loop last looked, so that nudges only
+	// mention what changed since
+	LastSeenMessages       int `json:"last_seen_messages,omitempty"`
+	LastSeenReviewComments int `json:"last_seen_review_comments,omitempty"`
+	LastSeenBehind         int `json:"last_seen_behind,omitempty"`
+	// PRCommentsReadAt is when the newest comment on the agent's PR that
+	// agent pr-comments showed it was made, so the next run marks only
+	// later ones as new
+	PRCommentsReadAt time.Time `json:"pr_comments_read_at,omitempty"`
+	// PromptHash is the SHA-256 of the prompt file the agent was last
+	// started with, so a prompt rewritten on disk since can be detected
+	PromptHash string `json:"prompt_hash,omitempty"`
+	// DocsHash is the hash stamped on the CLI reference in that prompt
+	// (prompts.DocsHash), so the daemon can tell agents documented commands
+	// its binary no longer has without reading their prompt files
+	DocsHash string `json:"docs_hash,omitempty"`
+	// RestartAttempts are the times the daemon restarted the agent after
+	// its process died, within the repository's crash-loop window
+	RestartAttempts []time.Time `json:"restart_attempts,omitempty"`
+	// QuarantinedAt is when the daemon stopped restarting the agent because
+	// it kept dying (see Repository.CrashLoopLimit); zero when it is not
+	// quarantined. QuarantineOutput is the end of its pane at the time.
+	QuarantinedAt    time.Time `json:"quarantined_at,omitempty"`
+	QuarantineOutput string    `json:"quarantine_output,omitempty"`
+}
+
+// Quarantined reports whether the daemon has stopped restarting the agent
+func (a Agent) Quarantined() bool {
+	return !a.QuarantinedAt.IsZero()
+}
+
+// WorkDir returns the directory the agent's Claude runs in: the first path
+// its task is scoped to, or else its worktree
+func (a Agent) WorkDir() string {
+	if len(a.Paths) > 0 && a.WorktreePath != "" {
+		return filepath.Join(a.WorktreePath, a.Paths[0])
+	}
+	return a.WorktreePath
+}
+
+// WindowTarget returns what to address the agent's tmux window by: its
+// window ID, which survives the window being renamed, or its name when the
+// ID has not been resolved yet
+func (a Agent) WindowTarget() string {
+	if a.TmuxWindowID != "" {
+		return a.TmuxWindowID
+	}
+	return a.TmuxWindow
 }
 
 // Repository represents a tracked repository's state
+//
+// The settings changed with multiclaude config are saved in the state file
+// as a generic "config" map, by setting name (see settings.go); the typed
+// fields below hold them decoded. Their JSON tags are those of state files
+// from before the map, which are still read.
 type Repository struct {
 	GithubURL        string             `json:"github_url"`
 	TmuxSession      string             `json:"tmux_session"`
 	Agents           map[string]Agent   `json:"agents"`
 	TaskHistory      []TaskHistoryEntry `json:"task_history,omitempty"`
 	MergeQueueConfig MergeQueueConfig   `json:"merge_queue_config,omitempty"`
+	// EnvFile is an optional path to a KEY=VALUE file injected into agent sessions.
+	// Only the path is persisted; values are read when agents start.
+	EnvFile string `json:"env_file,omitempty"`
+	// MaxConcurrentWorkers caps the number of worker agents in the repository.
+	// Zero means no limit. Supervisor, workspace and merge-queue agents are exempt.
+	MaxConcurrentWorkers int `json:"max_concurrent_workers,omitempty"`
+	// MaxConcurrentEphemeral caps the number of ephemeral agents in the
+	// repository. Zero means no limit. Ephemeral agents do not count towards
+	// MaxConcurrentWorkers.
+	MaxConcurrentEphemeral int `json:"max_concurrent_ephemeral,omitempty"`
+	// WorktreeLimit caps the number of worker worktrees in the repository, for
+	// repos where git slows down with many worktrees. Zero means no limit.
+	WorktreeLimit int `json:"worktree_limit,omitempty"`
+	// MessageTransport selects how routed messages reach the repository's agents
+	MessageTransport MessageTransportConfig `json:"message_transport,omitempty"`
+	// MinClaudeVersion is the oldest claude binary version agents may be
+	// started with (e.g. "1.5.0"). Empty means any version.
+	MinClaudeVersion string `json:"min_claude_version,omitempty"`
+	// ClaudePath pins the claude binary agents are started with. When set,
+	// agents are never started with any other binary. Empty means the
+	// claude found in PATH.
+	ClaudePath string `json:"claude_path,omitempty"`
+	// TrackedPRs are the pull requests the merge-queue agent is tracking,
+	// ordered by PR number
+	TrackedPRs []TrackedPR `json:"tracked_prs,omitempty"`
+	// DuplicateWindow is how long after a worker is created another worker
+	// with the same task is refused as a likely duplicate, as a Go duration
+	// (e.g. "30m"). Empty means DefaultDuplicateWindow; "0" disables the check.
+	DuplicateWindow string `json:"duplicate_window,omitempty"`
+	// ArchiveMaxAge is how long bundles of removed workers' branches are
+	// kept, as a Go duration. Empty means DefaultArchiveMaxAge; "0" keeps
+	// them until the size cap is reached.
+	ArchiveMaxAge string `json:"archive_max_age,omitempty"`
+	// ArchiveMaxSizeMB caps the total size of the repository's branch
+	// bundles; the oldest are removed first. Zero means no cap.
+	ArchiveMaxSizeMB int `json:"archive_max_size_mb,omitempty"`
+	// HasSubmodules records whether the repository declares git submodules,
+	// which are checked out in each new worktree. The daemon refreshes it.
+	HasSubmodules bool `json:"has_submodules,omitempty"`
+	// SubmoduleTimeout bounds checking out submodules in a new worktree, as a
+	// Go duration. Empty means DefaultSubmoduleTimeout.
+	SubmoduleTimeout string `json:"submodule_timeout,omitempty"`
+	// AutoAckAfter is how long a message may stay read before the daemon
+	// acknowledges it on the agent's behalf, as a Go duration. Empty means
+	// messages are only acknowledged by agents.
+	AutoAckAfter string `json:"auto_ack_after,omitempty"`
+	// AutoSync is how often the daemon syncs the primary clone's default
+	// branch with upstream (see multiclaude repo sync), as a Go duration.
+	// Empty means never.
+	AutoSync string `json:"auto_sync,omitempty"`
+	// CrashLoopLimit is how many times the daemon restarts an agent whose
+	// process died within CrashLoopWindow before quarantining it. Zero means
+	// DefaultCrashLoopLimit.
+	CrashLoopLimit int `json:"crash_loop_limit,omitempty"`
+	// CrashLoopWindow is the period CrashLoopLimit counts restarts over, as a
+	// Go duration. Empty means DefaultCrashLoopWindow.
+	CrashLoopWindow string `json:"crash_loop_window,omitempty"`
+	// NameScheme is how worker and review agent names are generated (see
+	// names.Scheme). Empty means Docker-style names.
+	NameScheme string `json:"name_scheme,omitempty"`
+	// NameTemplate is the template of the "template" name scheme, e.g.
+	// "{user}-{date}-{slug}"
+	NameTemplate string `json:"name_template,omitempty"`
+	// MessageMaxSize is the largest message body, in bytes, delivered
+	// inline; larger bodies are spilled to a file. Zero means
+	// messages.DefaultMaxBodySize.
+	MessageMaxSize int `json:"message_max_size,omitempty"`
+	// MessageHardCap is the largest message body, in bytes, accepted at all.
+	// Zero means messages.DefaultHardCap.
+	MessageHardCap int `json:"message_hard_cap,omitempty"`
+	// NudgeWhenIdle makes the wake loop nudge agents every cycle even when
+	// nothing changed for them, rather than only when there is news
+	NudgeWhenIdle bool `json:"nudge_when_idle,omitempty"`
+	// RawLogs keeps agent output logs as tmux captures them, escape
+	// sequences and redraws included, instead of filtering them to the
+	// lines that changed. Meant for debugging the filter or the TUI.
+	RawLogs bool `json:"raw_logs,omitempty"`
+	// SnapshotKeep is how many snapshots of each workspace are kept; older
+	// ones are pruned. Zero means DefaultSnapshotKeep.
+	SnapshotKeep int `json:"snapshot_keep,omitempty"`
+	// NoSupervisor records that the repository was initialized without a
+	// supervisor (init --no-supervisor). The supervisor name stays reserved,
+	// and adding a supervisor agent clears it.
+	NoSupervisor bool `json:"no_supervisor,omitempty"`
+	// GitIdentity is the committer identity and commit signing set up in
+	// each new worktree
+	GitIdentity GitIdentity `json:"git_identity,omitempty"`
+	// ContextVars are key-value pairs (sprint goals, freeze dates, ...)
+	// listed in the "Current Context" section of prompt files written from
+	// then on
+	ContextVars map[string]string `json:"context_vars,omitempty"`
+
+	// unknownConfig are the settings of the config map this binary has no
+	// setting for, kept for the multiclaude version that wrote them
+	unknownConfig map[string]string
+}
+
+// DefaultDuplicateWindow is the duplicate window of repositories that do not
+// set one
+const DefaultDuplicateWindow = 10 * time.Minute
+
+// DuplicateWindowDuration returns the repository's duplicate window, falling
+// back to DefaultDuplicateWindow when it is unset or invalid
+func (r *Repository) DuplicateWindowDuration() time.Duration {
+	if r.DuplicateWindow == "" {
+		return DefaultDuplicateWindow
+	}
+	window, err := time.ParseDuration(r.DuplicateWindow)
+	if err != nil || window < 0 {
+		return DefaultDuplicateWindow
+	}
+	return window
+}
+
+// DefaultArchiveMaxAge is how long branch bundles are kept in repositories
+// that do not set ArchiveMaxAge
+const DefaultArchiveMaxAge = 30 * 24 * time.Hour
+
+// ArchiveMaxAgeDuration returns how long the repository keeps branch bundles,
+// falling back to DefaultArchiveMaxAge when it is unset or invalid. Zero
+// means no age limit.
+func (r *Repository) ArchiveMaxAgeDuration() time.Duration {
+	if r.ArchiveMaxAge == "" {
+		return DefaultArchiveMaxAge
+	}
+	maxAge, err := time.ParseDuration(r.ArchiveMaxAge)
+	if err != nil || maxAge < 0 {
+		return DefaultArchiveMaxAge
+	}
+	return maxAge
+}
+
+// DefaultSubmoduleTimeout bounds submodule checkouts in repositories that do
+// not set SubmoduleTimeout
+const DefaultSubmoduleTimeout = 10 * time.Minute
+
+// SubmoduleTimeoutDuration returns how long checking out submodules in a new
+// worktree may take, falling back to DefaultSubmoduleTimeout when it is unset
+// or invalid
+func (r *Repository) SubmoduleTimeoutDuration() time.Duration {
+	if r.SubmoduleTimeout == "" {
+		return DefaultSubmoduleTimeout
+	}
+	timeout, err := time.ParseDuration(r.SubmoduleTimeout)
+	if err != nil || timeout <= 0 {
+		return DefaultSubmoduleTimeout
+	}
+	return timeout
+}
+
+// AutoAckAfterDuration returns how long a message may stay read before it is
+// acknowledged automatically. Zero, also for an invalid value, means never.
+func (r *Repository) AutoAckAfterDuration() time.Duration {
+	if r.AutoAckAfter == "" {
+		return 0
+	}
+	after, err := time.ParseDuration(r.AutoAckAfter)
+	if err != nil || after < 0 {
+		return 0
+	}
+	return after
+}
+
+// AutoSyncInterval returns how often the daemon syncs the repository's
+// default branch, or 0 if it does not
+func (r *Repository) AutoSyncInterval() time.Duration {
+	if r.AutoSync == "" {
+		return 0
+	}
+	interval, err := time.ParseDuration(r.AutoSync)
+	if err != nil || interval < 0 {
+		return 0
+	}
+	return interval
+}
+
+// DefaultCrashLoopLimit and DefaultCrashLoopWindow bound the restarts of an
+// agent in repositories that do not set CrashLoopLimit or CrashLoopWindow
+const (
+	DefaultCrashLoopLimit  = 5
+	DefaultCrashLoopWindow = 10 * time.Minute
+)
+
+// CrashLoopMaxRestarts returns how many restarts within the crash-loop
+// window an agent gets before it is quarantined
+func (r *Repository) CrashLoopMaxRestarts() int {
+	if r.CrashLoopLimit <= 0 {
+		return DefaultCrashLoopLimit
+	}
+	return r.CrashLoopLimit
+}
+
+// CrashLoopWindowDuration returns the period restarts are counted over,
+// falling back to DefaultCrashLoopWindow when it is unset or invalid
+func (r *Repository) CrashLoopWindowDuration() time.Duration {
+	if r.CrashLoopWindow == "" {
+		return DefaultCrashLoopWindow
+	}
+	window, err := time.ParseDuration(r.CrashLoopWindow)
+	if err != nil || window <= 0 {
+		return DefaultCrashLoopWindow
+	}
+	return window
+}
+
+// DefaultSnapshotKeep is how many snapshots of each workspace are kept in
+// repositories that do not set SnapshotKeep
+const DefaultSnapshotKeep = 20
+
+// SnapshotKeepCount returns how many snapshots of each workspace the
+// repository keeps
+func (r *Repository) SnapshotKeepCount() int {
+	if r.SnapshotKeep <= 0 {
+		return DefaultSnapshotKeep
+	}
+	return r.SnapshotKeep
 }
 
 // State represents the entire daemon state
 type State struct {
 	Repos       map[string]*Repository `json:"repos"`
 	CurrentRepo string                 `json:"current_repo,omitempty"`
-	mu          sync.RWMutex
-	path        string
+	// Groups maps a group name to its member repositories, sorted by name
+	Groups map[string][]string `json:"groups,omitempty"`
+	// UpgradeCheckInterval is how often the daemon checks for a newer
+	// multiclaude release, as a Go duration. Empty or "0" means never.
+	UpgradeCheckInterval string `json:"upgrade_check_interval,omitempty"`
+
+	mu   sync.RWMutex
+	path string
 }
 
 // New creates a new empty state
@@ -152,22 +590,7 @@ func (s *State) Save() error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	data, err := json.MarshalIndent(s, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal state: %w", err)
-	}
-
-	// Write to temp file first, then rename for atomicity
-	tmpPath := s.path + ".tmp"
-	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write state file: %w", err)
-	}
-
-	if err := os.Rename(tmpPath, s.path); err != nil {
-		return fmt.Errorf("failed to rename state file: %w", err)
-	}
-
-	return nil
+	return s.saveUnlocked()
 }
 
 // AddRepo adds a new repository to the state
@@ -206,6 +629,7 @@ func (s *State) RemoveRepo(name string) error {
 	}
 
 	delete(s.Repos, name)
+	s.removeFromGroupsUnlocked(name)
 	return s.saveUnlocked()
 }
 
@@ -272,24 +696,55 @@ func (s *State) GetAllRepos() map[string]*Repository {
 	// Create a deep copy to avoid concurrent access issues
 	repos := make(map[string]*Repository, len(s.Repos))
 	for name, repo := range s.Repos {
-		// Copy the repository
-		repoCopy := &Repository{
-			GithubURL:        repo.GithubURL,
-			TmuxSession:      repo.TmuxSession,
-			Agents:           make(map[string]Agent, len(repo.Agents)),
-			MergeQueueConfig: repo.MergeQueueConfig,
-		}
-		// Copy agents
+		// Copy the repository, then its maps and slices
+		repoCopy := *repo
+		repoCopy.Agents = make(map[string]Agent, len(repo.Agents))
 		for agentName, agent := range repo.Agents {
 			repoCopy.Agents[agentName] = agent
 		}
-		repos[name] = repoCopy
+		repoCopy.TaskHistory = append([]TaskHistoryEntry(nil), repo.TaskHistory...)
+		repoCopy.TrackedPRs = append([]TrackedPR(nil), repo.TrackedPRs...)
+		repoCopy.MessageTransport = repo.MessageTransport.copy()
+		repoCopy.ContextVars = copyContextVars(repo.ContextVars)
+		repoCopy.unknownConfig = copyContextVars(repo.unknownConfig)
+		repos[name] = &repoCopy
 	}
 	return repos
 }
 
+// TaskHash identifies a worker task within a repository, ignoring case and
+// differences in whitespace, so that the same task typed twice hashes the same
+func TaskHash(repoName, task string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(task)), " ")
+	sum := sha256.Sum256([]byte(repoName + "\x00" + normalized))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// DuplicateTaskError is returned by AddAgentUnlessDuplicate when a live
+// worker recently created in the repository has the same task
+type DuplicateTaskError struct {
+	Repo     string
+	Existing string // Name of the worker with the same task
+}
+
+func (e *DuplicateTaskError) Error() string {
+	return fmt.Sprintf("possible duplicate of worker %q in repository %q: it was created recently with the same task", e.Existing, e.Repo)
+}
+
 // AddAgent adds a new agent to a repository
 func (s *State) AddAgent(repoName, agentName string, agent Agent) error {
+	return s.addAgent(repoName, agentName, agent, false)
+}
+
+// AddAgentUnlessDuplicate adds an agent like AddAgent, but refuses a worker
+// whose task has the same TaskHash as a live worker created within the
+// repository's duplicate window, returning a *DuplicateTaskError. The check
+// and the add happen under one lock, so racing callers cannot both succeed.
+func (s *State) AddAgentUnlessDuplicate(repoName, agentName string, agent Agent) error {
+	return s.addAgent(repoName, agentName, agent, true)
+}
+
+func (s *State) addAgent(repoName, agentName string, agent Agent, refuseDuplicate bool) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -302,6 +757,28 @@ func (s *State) AddAgent(repoName, agentName string, agent Agent) error {
 		return fmt.Errorf("agent %q already exists in repository %q", agentName, repoName)
 	}
 
+	if refuseDuplicate && agent.Type == AgentTypeWorker && agent.Task != "" {
+		if existing := findDuplicateWorker(repoName, repo, agent); existing != "" {
+			return &DuplicateTaskError{Repo: repoName, Existing: existing}
+		}
+	}
+
+	if agent.Type == AgentTypeWorker && repo.MaxConcurrentWorkers > 0 {
+		if workers := countAgents(repo, AgentTypeWorker); workers >= repo.MaxConcurrentWorkers {
+			return fmt.Errorf("repository %q is at its worker limit (%d/%d)", repoName, workers, repo.MaxConcurrentWorkers)
+		}
+	}
+	if agent.Type == AgentTypeWorker && agent.WorktreePath != "" && repo.WorktreeLimit > 0 {
+		if worktrees := countWorkerWorktrees(repo); worktrees >= repo.WorktreeLimit {
+			return fmt.Errorf("repository %q is at its worktree limit (%d/%d); free a slot with 'multiclaude work rm <worker>'", repoName, worktrees, repo.WorktreeLimit)
+		}
+	}
+	if agent.Type == AgentTypeEphemeral && repo.MaxConcurrentEphemeral > 0 {
+		if ephemeral := countAgents(repo, AgentTypeEphemeral); ephemeral >= repo.MaxConcurrentEphemeral {
+			return fmt.Errorf("repository %q is at its ephemeral agent limit (%d/%d)", repoName, ephemeral, repo.MaxConcurrentEphemeral)
+		}
+	}
+
 	repo.Agents[agentName] = agent
 	return s.saveUnlocked()
 }
@@ -344,6 +821,265 @@ func (s *State) UpdateAgentPID(repoName, agentName string, pid int) error {
 	return s.saveUnlocked()
 }
 
+// UpdateAgentWindow records the name and ID of an agent's tmux window
+func (s *State) UpdateAgentWindow(repoName, agentName, window, windowID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.TmuxWindow = window
+	agent.TmuxWindowID = windowID
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// UpdateAgentStatus sets the status a human gave an agent; an empty status
+// clears it
+func (s *State) UpdateAgentStatus(repoName, agentName, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.Status = status
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// UpdateAgentBranch records the branch checked out in an agent's worktree
+func (s *State) UpdateAgentBranch(repoName, agentName, branch string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.Branch = branch
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// UpdateAgentUpstream records the remote-tracking branch an agent's branch
+// was set to track
+func (s *State) UpdateAgentUpstream(repoName, agentName, upstream string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.UpstreamBranch = upstream
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// MarkPRCommentsRead records that an agent has read the comments on its PR
+// up to readAt, count of them in all, so that the wake loop only nudges it
+// about comments made since
+func (s *State) MarkPRCommentsRead(repoName, agentName string, readAt time.Time, count int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	if readAt.After(agent.PRCommentsReadAt) {
+		agent.PRCommentsReadAt = readAt
+	}
+	agent.LastSeenReviewComments = count
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// UpdateAgentPromptHash records the hash of the prompt file an agent was
+// started with, and of the CLI reference stamped in it
+func (s *State) UpdateAgentPromptHash(repoName, agentName, hash, docsHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.PromptHash = hash
+	agent.DocsHash = docsHash
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// RecordRestartAttempt records that the daemon restarted an agent at the
+// given time, forgetting attempts older than window, and returns the
+// attempts within it
+func (s *State) RecordRestartAttempt(repoName, agentName string, at time.Time, window time.Duration) ([]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return nil, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return nil, fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	attempts := []time.Time{}
+	for _, attempt := range agent.RestartAttempts {
+		if at.Sub(attempt) < window {
+			attempts = append(attempts, attempt)
+		}
+	}
+	agent.RestartAttempts = append(attempts, at)
+	repo.Agents[agentName] = agent
+	return agent.RestartAttempts, s.saveUnlocked()
+}
+
+// QuarantineAgent marks an agent as quarantined, so the daemon stops
+// restarting it, keeping the end of its pane output
+func (s *State) QuarantineAgent(repoName, agentName string, at time.Time, output string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.QuarantinedAt = at
+	agent.QuarantineOutput = output
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// ClearQuarantine lifts an agent's quarantine and forgets its restart
+// attempts
+func (s *State) ClearQuarantine(repoName, agentName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.QuarantinedAt = time.Time{}
+	agent.QuarantineOutput = ""
+	agent.RestartAttempts = nil
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// SetAgentEnvironment merges env into the agent's Environment, replacing the
+// values of variables it already had
+func (s *State) SetAgentEnvironment(repoName, agentName string, env map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	// Copy rather than update in place: copies of the agent handed out by
+	// GetAgent share the map
+	merged := make(map[string]string, len(agent.Environment)+len(env))
+	for key, value := range agent.Environment {
+		merged[key] = value
+	}
+	for key, value := range env {
+		merged[key] = value
+	}
+	agent.Environment = merged
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// UpdateAgentTask replaces a worker's task and records the change in its
+// task updates. It returns the previous task.
+func (s *State) UpdateAgentTask(repoName, agentName, task string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return "", fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return "", fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+	if agent.Type != AgentTypeWorker && agent.Type != AgentTypeEphemeral {
+		return "", fmt.Errorf("agent %q is a %s; only workers have a task", agentName, agent.Type)
+	}
+
+	oldTask := agent.Task
+	agent.Task = task
+	agent.TaskUpdates = append(agent.TaskUpdates, TaskUpdate{OldTask: oldTask, NewTask: task, UpdatedAt: time.Now()})
+	repo.Agents[agentName] = agent
+	return oldTask, s.saveUnlocked()
+}
+
 // RemoveAgent removes an agent from a repository
 func (s *State) RemoveAgent(repoName, agentName string) error {
 	s.mu.Lock()
@@ -406,8 +1142,146 @@ func (s *State) GetMergeQueueConfig(repoName string) (MergeQueueConfig, error) {
 	return repo.MergeQueueConfig, nil
 }
 
-// UpdateMergeQueueConfig updates the merge queue config for a repository
-func (s *State) UpdateMergeQueueConfig(repoName string, config MergeQueueConfig) error {
+// UpdateRepoConfig changes a repository's settings with update (see
+// repoconfig.Apply), saving the state unless it fails
+func (s *State) UpdateRepoConfig(repoName string, update func(*Repository) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	if err := update(repo); err != nil {
+		return err
+	}
+	return s.saveUnlocked()
+}
+
+// UpdateGithubURL sets the GitHub URL for a repository
+func (s *State) UpdateGithubURL(repoName, githubURL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.GithubURL = githubURL
+	return s.saveUnlocked()
+}
+
+// SetUpgradeCheckInterval sets how often the daemon checks for a newer
+// release (see State.UpgradeCheckInterval). An empty value or "0" turns
+// the check off.
+func (s *State) SetUpgradeCheckInterval(interval string) error {
+	if interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return fmt.Errorf("invalid upgrade check interval %q: %w", interval, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("upgrade check interval must not be negative, got %s", interval)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.UpgradeCheckInterval = interval
+	return s.saveUnlocked()
+}
+
+// GetUpgradeCheckInterval returns how often the daemon checks for a newer
+// release, or 0 if it does not
+func (s *State) GetUpgradeCheckInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.UpgradeCheckInterval == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s.UpgradeCheckInterval)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// MessageLimits returns the repository's message size limits
+func (r *Repository) MessageLimits() messages.Limits {
+	return messages.Limits{MaxBodySize: r.MessageMaxSize, HardCap: r.MessageHardCap}
+}
+
+// SetContextVar sets a context variable of a repository. An empty value
+// removes it.
+func (s *State) SetContextVar(repoName, key, value string) error {
+	if err := ValidateContextKey(key); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	if value == "" {
+		delete(repo.ContextVars, key)
+	} else {
+		if repo.ContextVars == nil {
+			repo.ContextVars = make(map[string]string)
+		}
+		repo.ContextVars[key] = value
+	}
+	return s.saveUnlocked()
+}
+
+// GetContextVars returns a copy of a repository's context variables
+func (s *State) GetContextVars(repoName string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return nil, fmt.Errorf("repository %q not found", repoName)
+	}
+	return copyContextVars(repo.ContextVars), nil
+}
+
+// ValidateContextKey checks a context variable name: letters, digits, '_',
+// '-' and '.', as it is shown in a markdown table and passed on the command
+// line
+func ValidateContextKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("context key must not be empty")
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.') {
+			return fmt.Errorf("invalid context key %q (use letters, digits, '_', '-' and '.')", key)
+		}
+	}
+	return nil
+}
+
+func copyContextVars(vars map[string]string) map[string]string {
+	if len(vars) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(vars))
+	for k, v := range vars {
+		out[k] = v
+	}
+	return out
+}
+
+// SetNoSupervisor records whether a repository runs without a supervisor
+// agent (see Repository.NoSupervisor)
+func (s *State) SetNoSupervisor(repoName string, noSupervisor bool) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -416,10 +1290,174 @@ func (s *State) UpdateMergeQueueConfig(repoName string, config MergeQueueConfig)
 		return fmt.Errorf("repository %q not found", repoName)
 	}
 
-	repo.MergeQueueConfig = config
+	repo.NoSupervisor = noSupervisor
 	return s.saveUnlocked()
 }
 
+// SetHasSubmodules records whether a repository declares git submodules. It
+// only saves when the value changes.
+func (s *State) SetHasSubmodules(repoName string, hasSubmodules bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+	if repo.HasSubmodules == hasSubmodules {
+		return nil
+	}
+
+	repo.HasSubmodules = hasSubmodules
+	return s.saveUnlocked()
+}
+
+// countAgents returns the number of agents of a type in a repository.
+// Callers must hold the lock.
+func countAgents(repo *Repository, agentType AgentType) int {
+	count := 0
+	for _, agent := range repo.Agents {
+		if agent.Type == agentType {
+			count++
+		}
+	}
+	return count
+}
+
+// FindDuplicateWorker returns the live worker that AddAgentUnlessDuplicate
+// would refuse a new worker with this task for, or "" if there is none
+func (s *State) FindDuplicateWorker(repoName, task string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return "", fmt.Errorf("repository %q not found", repoName)
+	}
+	return findDuplicateWorker(repoName, repo, Agent{Type: AgentTypeWorker, Task: task, CreatedAt: time.Now()}), nil
+}
+
+// findDuplicateWorker returns the oldest live worker in a repository that
+// was created within its duplicate window with the same task as agent, or
+// "" if there is none. Callers must hold the lock.
+func findDuplicateWorker(repoName string, repo *Repository, agent Agent) string {
+	window := repo.DuplicateWindowDuration()
+	if window == 0 {
+		return ""
+	}
+	hash := TaskHash(repoName, agent.Task)
+	createdAt := agent.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	var existing string
+	var existingCreated time.Time
+	for name, other := range repo.Agents {
+		if other.Type != AgentTypeWorker || other.ReadyForCleanup || other.Task == "" {
+			continue
+		}
+		if createdAt.Sub(other.CreatedAt) > window || TaskHash(repoName, other.Task) != hash {
+			continue
+		}
+		if existing == "" || other.CreatedAt.Before(existingCreated) || (other.CreatedAt.Equal(existingCreated) && name < existing) {
+			existing, existingCreated = name, other.CreatedAt
+		}
+	}
+	return existing
+}
+
+// countWorkerWorktrees returns the number of worker agents with a worktree
+// in a repository. Callers must hold the lock.
+func countWorkerWorktrees(repo *Repository) int {
+	count := 0
+	for _, agent := range repo.Agents {
+		if agent.Type == AgentTypeWorker && agent.WorktreePath != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// TrackPR adds or updates the merge-queue's tracking record for a PR. An
+// empty URL, status or notes keeps the existing value; LastAction is set to
+// now when zero.
+func (s *State) TrackPR(repoName string, pr TrackedPR) error {
+	if pr.Number <= 0 {
+		return fmt.Errorf("invalid PR number %d", pr.Number)
+	}
+	if pr.LastAction.IsZero() {
+		pr.LastAction = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	for i, existing := range repo.TrackedPRs {
+		if existing.Number != pr.Number {
+			continue
+		}
+		if pr.URL == "" {
+			pr.URL = existing.URL
+		}
+		if pr.Status == "" {
+			pr.Status = existing.Status
+		}
+		if pr.Notes == "" {
+			pr.Notes = existing.Notes
+		}
+		repo.TrackedPRs[i] = pr
+		return s.saveUnlocked()
+	}
+
+	repo.TrackedPRs = append(repo.TrackedPRs, pr)
+	sort.Slice(repo.TrackedPRs, func(i, j int) bool {
+		return repo.TrackedPRs[i].Number < repo.TrackedPRs[j].Number
+	})
+	return s.saveUnlocked()
+}
+
+// UntrackPR removes the merge-queue's tracking record for a PR
+func (s *State) UntrackPR(repoName string, number int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	for i, pr := range repo.TrackedPRs {
+		if pr.Number == number {
+			repo.TrackedPRs = append(repo.TrackedPRs[:i], repo.TrackedPRs[i+1:]...)
+			return s.saveUnlocked()
+		}
+	}
+
+	return fmt.Errorf("PR #%d is not tracked in repository %q", number, repoName)
+}
+
+// ListTrackedPRs returns a copy of the merge-queue's tracked PRs for a
+// repository, ordered by PR number
+func (s *State) ListTrackedPRs(repoName string) ([]TrackedPR, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return nil, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	prs := make([]TrackedPR, len(repo.TrackedPRs))
+	copy(prs, repo.TrackedPRs)
+	return prs, nil
+}
+
 // AddTaskHistory adds a completed task to the repository's history
 func (s *State) AddTaskHistory(repoName string, entry TaskHistoryEntry) error {
 	s.mu.Lock()
@@ -518,6 +1556,66 @@ func (s *State) UpdateTaskHistorySummary(repoName, taskName, summary, failureRea
 	return fmt.Errorf("task %q not found in history", taskName)
 }
 
+// PathChange is an absolute path in the state that MigrateRoot rewrote
+type PathChange struct {
+	Repo  string
+	Agent string // Empty for repository settings
+	Field string // JSON name of the field, e.g. "worktree_path"
+	Old   string
+	New   string
+}
+
+// MigrateRoot rewrites the absolute paths in the state that lie under
+// oldRoot so they lie under newRoot, after the multiclaude directory was
+// moved. That covers agent worktrees, env files, pinned claude binaries and
+// repositories initialized from a local path. It returns the changes sorted
+// by repository, agent and field, and saves the state unless dryRun is set.
+func (s *State) MigrateRoot(oldRoot, newRoot string, dryRun bool) ([]PathChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changes []PathChange
+	rebase := func(repoName, agentName, field string, path *string) {
+		if *path == "" {
+			return
+		}
+		if rebased, ok := config.RebasePath(*path, oldRoot, newRoot); ok && rebased != *path {
+			changes = append(changes, PathChange{Repo: repoName, Agent: agentName, Field: field, Old: *path, New: rebased})
+			if !dryRun {
+				*path = rebased
+			}
+		}
+	}
+
+	for repoName, repo := range s.Repos {
+		rebase(repoName, "", "github_url", &repo.GithubURL)
+		rebase(repoName, "", "config.env-file", &repo.EnvFile)
+		rebase(repoName, "", "config.pin-claude-path", &repo.ClaudePath)
+		for agentName, agent := range repo.Agents {
+			rebase(repoName, agentName, "worktree_path", &agent.WorktreePath)
+			if !dryRun {
+				repo.Agents[agentName] = agent
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		if a.Agent != b.Agent {
+			return a.Agent < b.Agent
+		}
+		return a.Field < b.Field
+	})
+
+	if dryRun || len(changes) == 0 {
+		return changes, nil
+	}
+	return changes, s.saveUnlocked()
+}
+
 // saveUnlocked saves state without acquiring lock (caller must hold lock)
 func (s *State) saveUnlocked() error {
 	data, err := json.MarshalIndent(s, "", "  ")
@@ -525,8 +1623,9 @@ func (s *State) saveUnlocked() error {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
+	// Write to temp file first, then rename for atomicity
 	tmpPath := s.path + ".tmp"
-	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write state file: %w", err)
 	}
 
@@ -534,5 +1633,26 @@ func (s *State) saveUnlocked() error {
 		return fmt.Errorf("failed to rename state file: %w", err)
 	}
 
+	// A failed backup does not fail the save it follows; the next save
+	// tries again
+	_ = writeBackup(s.path, data, time.Now())
+
+	return nil
+}
+
+// Reload replaces the state with the content of its file, e.g. after the
+// file was restored from a backup
+func (s *State) Reload() error {
+	loaded, err := Load(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Repos = loaded.Repos
+	s.CurrentRepo = loaded.CurrentRepo
+	s.Groups = loaded.Groups
+	s.UpgradeCheckInterval = loaded.UpgradeCheckInterval
 	return nil
 }
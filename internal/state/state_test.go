@@ -1,9 +1,12 @@
 package state
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -654,6 +657,118 @@ func TestGetAllReposIsSnapshot(t *testing.T) {
 	}
 }
 
+func TestGetAllReposCopiesEveryField(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "state.json"))
+	repo := &Repository{
+		GithubURL:        "https://github.com/test/repo",
+		Agents:           map[string]Agent{"fox": {Type: AgentTypeWorker}},
+		TaskHistory:      []TaskHistoryEntry{{Name: "owl"}},
+		TrackedPRs:       []TrackedPR{{Number: 7}},
+		DuplicateWindow:  "30m0s",
+		ArchiveMaxAge:    "48h0m0s",
+		ArchiveMaxSizeMB: 500,
+		MessageTransport: MessageTransportConfig{ByAgentType: map[AgentType]string{AgentTypeWorker: "inbox"}},
+		ContextVars:      map[string]string{"FREEZE": "yes"},
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	copied := s.GetAllRepos()["test-repo"]
+	if !reflect.DeepEqual(copied, repo) {
+		t.Errorf("GetAllRepos() = %+v, want %+v", copied, repo)
+	}
+	copied.TrackedPRs[0].Number = 8
+	copied.MessageTransport.ByAgentType[AgentTypeWorker] = "tmux"
+	if repo.TrackedPRs[0].Number != 7 || repo.MessageTransport.ByAgentType[AgentTypeWorker] != "inbox" {
+		t.Error("GetAllRepos() shares slices or maps with the state")
+	}
+}
+
+func TestUpdateAgentTask(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	if err := s.AddAgent("test-repo", "worker", Agent{Type: AgentTypeWorker, Task: "First task"}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+	if err := s.AddAgent("test-repo", "supervisor", Agent{Type: AgentTypeSupervisor}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+
+	for _, task := range []string{"Second task", "Third task"} {
+		if _, err := s.UpdateAgentTask("test-repo", "worker", task); err != nil {
+			t.Fatalf("UpdateAgentTask() failed: %v", err)
+		}
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	agent, _ := loaded.GetAgent("test-repo", "worker")
+	if agent.Task != "Third task" {
+		t.Errorf("Task = %q, want Third task", agent.Task)
+	}
+	if len(agent.TaskUpdates) != 2 || agent.TaskUpdates[0].OldTask != "First task" || agent.TaskUpdates[1].OldTask != "Second task" {
+		t.Errorf("TaskUpdates = %+v, want both changes in order", agent.TaskUpdates)
+	}
+
+	if _, err := s.UpdateAgentTask("test-repo", "supervisor", "x"); err == nil {
+		t.Error("UpdateAgentTask() should refuse an agent that is not a worker")
+	}
+	if _, err := s.UpdateAgentTask("test-repo", "missing", "x"); err == nil {
+		t.Error("UpdateAgentTask() should fail for a missing agent")
+	}
+}
+
+func TestSetAgentEnvironment(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	if err := s.AddAgent("test-repo", "supervisor", Agent{
+		Type:        AgentTypeSupervisor,
+		Environment: map[string]string{"ANTHROPIC_API_KEY": "old", "REGION": "eu"},
+	}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+
+	before, _ := s.GetAgent("test-repo", "supervisor")
+	if err := s.SetAgentEnvironment("test-repo", "supervisor", map[string]string{"ANTHROPIC_API_KEY": "new"}); err != nil {
+		t.Fatalf("SetAgentEnvironment() failed: %v", err)
+	}
+	if before.Environment["ANTHROPIC_API_KEY"] != "old" {
+		t.Error("SetAgentEnvironment() modified a copy returned earlier by GetAgent")
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	agent, _ := loaded.GetAgent("test-repo", "supervisor")
+	if agent.Environment["ANTHROPIC_API_KEY"] != "new" || agent.Environment["REGION"] != "eu" {
+		t.Errorf("Environment = %v, want the new key merged into the old", agent.Environment)
+	}
+
+	info, err := os.Stat(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("state file mode = %o, want 600 since it holds agent environments", perm)
+	}
+
+	if err := s.SetAgentEnvironment("test-repo", "missing", map[string]string{"X": "y"}); err == nil {
+		t.Error("SetAgentEnvironment() should fail for a missing agent")
+	}
+}
+
 func TestUpdateAgentNonExistentRepo(t *testing.T) {
 	tmpDir := t.TempDir()
 	statePath := filepath.Join(tmpDir, "state.json")
@@ -863,18 +978,12 @@ func TestGetMergeQueueConfig(t *testing.T) {
 	}
 }
 
-func TestUpdateMergeQueueConfig(t *testing.T) {
+func TestMergeQueueSettings(t *testing.T) {
 	tmpDir := t.TempDir()
 	statePath := filepath.Join(tmpDir, "state.json")
 
 	s := New(statePath)
 
-	// Test non-existent repo
-	err := s.UpdateMergeQueueConfig("nonexistent", MergeQueueConfig{})
-	if err == nil {
-		t.Error("UpdateMergeQueueConfig() should fail for nonexistent repo")
-	}
-
 	// Add repo
 	repo := &Repository{
 		GithubURL:   "https://github.com/test/repo",
@@ -886,14 +995,8 @@ func TestUpdateMergeQueueConfig(t *testing.T) {
 	}
 
 	// Update config
-	newConfig := MergeQueueConfig{
-		Enabled:   false,
-		TrackMode: TrackModeAssigned,
-	}
-
-	if err := s.UpdateMergeQueueConfig("test-repo", newConfig); err != nil {
-		t.Fatalf("UpdateMergeQueueConfig() failed: %v", err)
-	}
+	setSetting(t, s, "test-repo", "mq-enabled", "false")
+	setSetting(t, s, "test-repo", "mq-track", string(TrackModeAssigned))
 
 	// Verify update
 	updatedConfig, err := s.GetMergeQueueConfig("test-repo")
@@ -1285,3 +1388,803 @@ func TestTaskHistoryPersistence(t *testing.T) {
 		t.Errorf("Loaded entry status = %q, want 'merged'", history[0].Status)
 	}
 }
+
+func TestEnvFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	setSetting(t, s, "test-repo", "env-file", "/secrets/staging.env")
+
+	// Persisted across reload
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	loadedRepo, _ := loaded.GetRepo("test-repo")
+	if loadedRepo.EnvFile != "/secrets/staging.env" {
+		t.Errorf("EnvFile after reload = %q, want %q", loadedRepo.EnvFile, "/secrets/staging.env")
+	}
+
+	// Copied by GetAllRepos
+	if got := s.GetAllRepos()["test-repo"].EnvFile; got != "/secrets/staging.env" {
+		t.Errorf("GetAllRepos() EnvFile = %q", got)
+	}
+}
+
+func TestMaxConcurrentWorkers(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	setSetting(t, s, "test-repo", "max-concurrent-workers", "1")
+
+	if err := s.AddAgent("test-repo", "worker-1", Agent{Type: AgentTypeWorker}); err != nil {
+		t.Fatalf("AddAgent() first worker failed: %v", err)
+	}
+	if err := s.AddAgent("test-repo", "worker-2", Agent{Type: AgentTypeWorker}); err == nil {
+		t.Error("AddAgent() should fail when the worker limit is reached")
+	}
+
+	// Non-worker agents are exempt from the limit
+	for name, agentType := range map[string]AgentType{
+		"supervisor":  AgentTypeSupervisor,
+		"merge-queue": AgentTypeMergeQueue,
+		"workspace":   AgentTypeWorkspace,
+	} {
+		if err := s.AddAgent("test-repo", name, Agent{Type: agentType}); err != nil {
+			t.Errorf("AddAgent(%s) failed: %v", name, err)
+		}
+	}
+
+	// Persisted across reload and copied by GetAllRepos
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	loadedRepo, _ := loaded.GetRepo("test-repo")
+	if loadedRepo.MaxConcurrentWorkers != 1 {
+		t.Errorf("MaxConcurrentWorkers after reload = %d, want 1", loadedRepo.MaxConcurrentWorkers)
+	}
+	if got := s.GetAllRepos()["test-repo"].MaxConcurrentWorkers; got != 1 {
+		t.Errorf("GetAllRepos() MaxConcurrentWorkers = %d, want 1", got)
+	}
+
+	// Removing the limit allows more workers
+	setSetting(t, s, "test-repo", "max-concurrent-workers", "")
+	if err := s.AddAgent("test-repo", "worker-2", Agent{Type: AgentTypeWorker}); err != nil {
+		t.Errorf("AddAgent() after reset failed: %v", err)
+	}
+}
+
+func TestMaxConcurrentEphemeral(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	setSetting(t, s, "test-repo", "max-concurrent-workers", "1")
+	setSetting(t, s, "test-repo", "max-concurrent-ephemeral", "1")
+
+	// Workers and ephemeral agents are counted separately
+	if err := s.AddAgent("test-repo", "worker-1", Agent{Type: AgentTypeWorker}); err != nil {
+		t.Fatalf("AddAgent() worker failed: %v", err)
+	}
+	if err := s.AddAgent("test-repo", "ephemeral-1", Agent{Type: AgentTypeEphemeral}); err != nil {
+		t.Fatalf("AddAgent() ephemeral agent failed: %v", err)
+	}
+	if err := s.AddAgent("test-repo", "ephemeral-2", Agent{Type: AgentTypeEphemeral}); err == nil {
+		t.Error("AddAgent() should fail when the ephemeral agent limit is reached")
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	loadedRepo, _ := loaded.GetRepo("test-repo")
+	if loadedRepo.MaxConcurrentEphemeral != 1 {
+		t.Errorf("MaxConcurrentEphemeral after reload = %d, want 1", loadedRepo.MaxConcurrentEphemeral)
+	}
+	if got := s.GetAllRepos()["test-repo"].MaxConcurrentEphemeral; got != 1 {
+		t.Errorf("GetAllRepos() MaxConcurrentEphemeral = %d, want 1", got)
+	}
+}
+
+func TestWorktreeLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	setSetting(t, s, "test-repo", "worktree-limit", "1")
+
+	if err := s.AddAgent("test-repo", "worker-1", Agent{Type: AgentTypeWorker, WorktreePath: "/tmp/worker-1"}); err != nil {
+		t.Fatalf("AddAgent() worker failed: %v", err)
+	}
+	err := s.AddAgent("test-repo", "worker-2", Agent{Type: AgentTypeWorker, WorktreePath: "/tmp/worker-2"})
+	if err == nil {
+		t.Fatal("AddAgent() should fail when the worktree limit is reached")
+	}
+	if !strings.Contains(err.Error(), "work rm") {
+		t.Errorf("AddAgent() error should suggest work rm, got: %v", err)
+	}
+	// Only worker worktrees count towards the limit
+	if err := s.AddAgent("test-repo", "ws", Agent{Type: AgentTypeWorkspace, WorktreePath: "/tmp/ws"}); err != nil {
+		t.Errorf("AddAgent() workspace should not count towards the worktree limit: %v", err)
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	loadedRepo, _ := loaded.GetRepo("test-repo")
+	if loadedRepo.WorktreeLimit != 1 {
+		t.Errorf("WorktreeLimit after reload = %d, want 1", loadedRepo.WorktreeLimit)
+	}
+	if got := s.GetAllRepos()["test-repo"].WorktreeLimit; got != 1 {
+		t.Errorf("GetAllRepos() WorktreeLimit = %d, want 1", got)
+	}
+}
+
+func TestTrackedPRs(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	if err := s.TrackPR("test-repo", TrackedPR{Number: 42, URL: "https://github.com/test/repo/pull/42", Status: "waiting-ci", Notes: "flaky test"}); err != nil {
+		t.Fatalf("TrackPR() failed: %v", err)
+	}
+	if err := s.TrackPR("test-repo", TrackedPR{Number: 7, Status: "approved"}); err != nil {
+		t.Fatalf("TrackPR() failed: %v", err)
+	}
+	if err := s.TrackPR("test-repo", TrackedPR{Number: 42, Status: "approved"}); err != nil {
+		t.Fatalf("TrackPR() update failed: %v", err)
+	}
+	if err := s.TrackPR("test-repo", TrackedPR{Number: 0}); err == nil {
+		t.Error("TrackPR() should reject an invalid PR number")
+	}
+	if err := s.TrackPR("nonexistent", TrackedPR{Number: 1}); err == nil {
+		t.Error("TrackPR() should fail for nonexistent repo")
+	}
+
+	// Records survive a reload, ordered by number, with unset fields kept on update
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	prs, err := loaded.ListTrackedPRs("test-repo")
+	if err != nil {
+		t.Fatalf("ListTrackedPRs() failed: %v", err)
+	}
+	if len(prs) != 2 || prs[0].Number != 7 || prs[1].Number != 42 {
+		t.Fatalf("ListTrackedPRs() = %+v, want PRs 7 and 42", prs)
+	}
+	if prs[1].Status != "approved" || prs[1].Notes != "flaky test" || prs[1].URL == "" {
+		t.Errorf("updated PR = %+v, want status approved with URL and notes kept", prs[1])
+	}
+	if prs[1].LastAction.IsZero() {
+		t.Error("TrackPR() should set LastAction")
+	}
+
+	if err := s.UntrackPR("test-repo", 7); err != nil {
+		t.Fatalf("UntrackPR() failed: %v", err)
+	}
+	if err := s.UntrackPR("test-repo", 7); err == nil {
+		t.Error("UntrackPR() should fail for an untracked PR")
+	}
+	if prs, _ := s.ListTrackedPRs("test-repo"); len(prs) != 1 || prs[0].Number != 42 {
+		t.Errorf("ListTrackedPRs() after untrack = %+v, want only PR 42", prs)
+	}
+}
+
+func TestMessageTransportConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	// Unset config resolves to empty for every agent type
+	if got := (MessageTransportConfig{}).TransportFor(AgentTypeWorker); got != "" {
+		t.Errorf("TransportFor() on empty config = %q, want empty", got)
+	}
+
+	setSetting(t, s, "test-repo", "transport", "tmux")
+	setSetting(t, s, "test-repo", "transport-worker", "inbox")
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	loadedRepo, _ := loaded.GetRepo("test-repo")
+	if got := loadedRepo.MessageTransport.TransportFor(AgentTypeWorker); got != "inbox" {
+		t.Errorf("TransportFor(worker) after reload = %q, want inbox", got)
+	}
+	if got := loadedRepo.MessageTransport.TransportFor(AgentTypeSupervisor); got != "tmux" {
+		t.Errorf("TransportFor(supervisor) after reload = %q, want tmux", got)
+	}
+
+	copied := s.GetAllRepos()["test-repo"].MessageTransport
+	copied.ByAgentType[AgentTypeWorker] = "mutated"
+	if got := s.GetAllRepos()["test-repo"].MessageTransport.TransportFor(AgentTypeWorker); got != "inbox" {
+		t.Errorf("GetAllRepos() should deep copy transport config, got %q", got)
+	}
+}
+
+func TestUpdateGithubURL(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{
+		GithubURL: "https://github.com/old-org/repo",
+		Agents:    make(map[string]Agent),
+	}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	if err := s.UpdateGithubURL("test-repo", "https://github.com/new-org/repo"); err != nil {
+		t.Fatalf("UpdateGithubURL() failed: %v", err)
+	}
+	if err := s.UpdateGithubURL("missing", "https://github.com/new-org/repo"); err == nil {
+		t.Error("UpdateGithubURL() should fail for an unknown repo")
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	repo, _ := loaded.GetRepo("test-repo")
+	if repo.GithubURL != "https://github.com/new-org/repo" {
+		t.Errorf("GithubURL = %q, want the new URL", repo.GithubURL)
+	}
+}
+
+func TestMigrateRoot(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{
+		GithubURL:  "https://github.com/org/repo",
+		EnvFile:    "/old/mc/env/test-repo.env",
+		ClaudePath: "/usr/local/bin/claude",
+		Agents: map[string]Agent{
+			"supervisor": {Type: AgentTypeSupervisor, WorktreePath: "/old/mc/repos/test-repo"},
+			"fox":        {Type: AgentTypeWorker, WorktreePath: "/old/mc/wts/test-repo/fox"},
+			"elsewhere":  {Type: AgentTypeWorker, WorktreePath: "/mnt/wts/test-repo/elsewhere"},
+		},
+	}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	// A dry run reports the changes without making them
+	changes, err := s.MigrateRoot("/old/mc", "/new/mc", true)
+	if err != nil {
+		t.Fatalf("MigrateRoot() dry run failed: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("MigrateRoot() dry run returned %d changes, want 3: %+v", len(changes), changes)
+	}
+	repo, _ := s.GetRepo("test-repo")
+	if repo.Agents["fox"].WorktreePath != "/old/mc/wts/test-repo/fox" {
+		t.Error("MigrateRoot() dry run should not change the state")
+	}
+
+	changes, err = s.MigrateRoot("/old/mc", "/new/mc", false)
+	if err != nil {
+		t.Fatalf("MigrateRoot() failed: %v", err)
+	}
+	want := []PathChange{
+		{Repo: "test-repo", Field: "config.env-file", Old: "/old/mc/env/test-repo.env", New: "/new/mc/env/test-repo.env"},
+		{Repo: "test-repo", Agent: "fox", Field: "worktree_path", Old: "/old/mc/wts/test-repo/fox", New: "/new/mc/wts/test-repo/fox"},
+		{Repo: "test-repo", Agent: "supervisor", Field: "worktree_path", Old: "/old/mc/repos/test-repo", New: "/new/mc/repos/test-repo"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("MigrateRoot() = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+
+	// The migrated state is saved
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	repo, _ = loaded.GetRepo("test-repo")
+	if repo.Agents["fox"].WorktreePath != "/new/mc/wts/test-repo/fox" {
+		t.Errorf("fox worktree = %q, want it under the new root", repo.Agents["fox"].WorktreePath)
+	}
+	if repo.Agents["elsewhere"].WorktreePath != "/mnt/wts/test-repo/elsewhere" {
+		t.Errorf("worktrees outside the old root should be kept, got %q", repo.Agents["elsewhere"].WorktreePath)
+	}
+	if repo.ClaudePath != "/usr/local/bin/claude" || repo.GithubURL != "https://github.com/org/repo" {
+		t.Error("paths outside the old root should be kept")
+	}
+}
+
+func TestTaskHash(t *testing.T) {
+	base := TaskHash("repo", "Add tests for the parser")
+	if got := TaskHash("repo", "  add TESTS for\tthe parser\n"); got != base {
+		t.Errorf("TaskHash() should ignore case and whitespace: %q != %q", got, base)
+	}
+	if got := TaskHash("other-repo", "Add tests for the parser"); got == base {
+		t.Error("TaskHash() should differ between repositories")
+	}
+	if got := TaskHash("repo", "Add tests for the lexer"); got == base {
+		t.Error("TaskHash() should differ between tasks")
+	}
+}
+
+func TestAddAgentUnlessDuplicate(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(filepath.Join(tmpDir, "state.json"))
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	now := time.Now()
+	worker := func(task string, created time.Time) Agent {
+		return Agent{Type: AgentTypeWorker, Task: task, CreatedAt: created}
+	}
+	if err := s.AddAgentUnlessDuplicate("test-repo", "fox", worker("Fix the login bug", now.Add(-time.Minute))); err != nil {
+		t.Fatalf("AddAgentUnlessDuplicate() first worker failed: %v", err)
+	}
+
+	err := s.AddAgentUnlessDuplicate("test-repo", "owl", worker("fix the  login bug", now))
+	var dupErr *DuplicateTaskError
+	if !errors.As(err, &dupErr) || dupErr.Existing != "fox" {
+		t.Fatalf("AddAgentUnlessDuplicate() = %v, want a duplicate of fox", err)
+	}
+	if existing, _ := s.FindDuplicateWorker("test-repo", "Fix the login bug"); existing != "fox" {
+		t.Errorf("FindDuplicateWorker() = %q, want fox", existing)
+	}
+
+	// AddAgent and other tasks are not checked
+	if err := s.AddAgentUnlessDuplicate("test-repo", "owl", worker("Fix the logout bug", now)); err != nil {
+		t.Errorf("AddAgentUnlessDuplicate() with another task failed: %v", err)
+	}
+	if err := s.AddAgent("test-repo", "bat", worker("Fix the login bug", now)); err != nil {
+		t.Errorf("AddAgent() should allow duplicates: %v", err)
+	}
+
+	// Workers outside the window or ready for cleanup do not count
+	s2 := New(filepath.Join(tmpDir, "state2.json"))
+	if err := s2.AddRepo("test-repo", &Repository{Agents: map[string]Agent{
+		"old":  worker("Fix the login bug", now.Add(-time.Hour)),
+		"done": {Type: AgentTypeWorker, Task: "Fix the login bug", CreatedAt: now, ReadyForCleanup: true},
+	}}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	if err := s2.AddAgentUnlessDuplicate("test-repo", "new", worker("Fix the login bug", now)); err != nil {
+		t.Errorf("AddAgentUnlessDuplicate() should ignore old and finished workers: %v", err)
+	}
+
+	// A window of 0 disables the check
+	setSetting(t, s2, "test-repo", "duplicate-window", "0s")
+	if err := s2.AddAgentUnlessDuplicate("test-repo", "newer", worker("Fix the login bug", now)); err != nil {
+		t.Errorf("AddAgentUnlessDuplicate() with the check disabled failed: %v", err)
+	}
+}
+
+func TestDuplicateWindow(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	repo, _ := s.GetRepo("test-repo")
+	if got := repo.DuplicateWindowDuration(); got != DefaultDuplicateWindow {
+		t.Errorf("default DuplicateWindowDuration() = %s, want %s", got, DefaultDuplicateWindow)
+	}
+
+	setSetting(t, s, "test-repo", "duplicate-window", "30m0s")
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	repo, _ = loaded.GetRepo("test-repo")
+	if got := repo.DuplicateWindowDuration(); got != 30*time.Minute {
+		t.Errorf("DuplicateWindowDuration() after reload = %s, want 30m", got)
+	}
+}
+
+func TestSetUpgradeCheckInterval(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	s := New(statePath)
+
+	if got := s.GetUpgradeCheckInterval(); got != 0 {
+		t.Errorf("default GetUpgradeCheckInterval() = %s, want 0", got)
+	}
+
+	if err := s.SetUpgradeCheckInterval("24h"); err != nil {
+		t.Fatalf("SetUpgradeCheckInterval() failed: %v", err)
+	}
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := loaded.GetUpgradeCheckInterval(); got != 24*time.Hour {
+		t.Errorf("GetUpgradeCheckInterval() after reload = %s, want 24h", got)
+	}
+
+	for _, invalid := range []string{"daily", "-1h"} {
+		if err := s.SetUpgradeCheckInterval(invalid); err == nil {
+			t.Errorf("SetUpgradeCheckInterval(%q) should fail", invalid)
+		}
+	}
+	if err := s.SetUpgradeCheckInterval("0"); err != nil {
+		t.Fatalf("SetUpgradeCheckInterval(0) failed: %v", err)
+	}
+	if got := s.GetUpgradeCheckInterval(); got != 0 {
+		t.Errorf("GetUpgradeCheckInterval() after turning off = %s, want 0", got)
+	}
+}
+
+func TestArchiveRetention(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	repo, _ := s.GetRepo("test-repo")
+	if got := repo.ArchiveMaxAgeDuration(); got != DefaultArchiveMaxAge {
+		t.Errorf("default ArchiveMaxAgeDuration() = %s, want %s", got, DefaultArchiveMaxAge)
+	}
+
+	setSetting(t, s, "test-repo", "archive-max-age", "0s")
+	setSetting(t, s, "test-repo", "archive-max-size-mb", "500")
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	repo, _ = loaded.GetRepo("test-repo")
+	if got := repo.ArchiveMaxAgeDuration(); got != 0 {
+		t.Errorf("ArchiveMaxAgeDuration() after reload = %s, want 0 (no age limit)", got)
+	}
+	if repo.ArchiveMaxSizeMB != 500 {
+		t.Errorf("ArchiveMaxSizeMB after reload = %d, want 500", repo.ArchiveMaxSizeMB)
+	}
+}
+
+func TestSubmoduleSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	repo, _ := s.GetRepo("test-repo")
+	if got := repo.SubmoduleTimeoutDuration(); got != DefaultSubmoduleTimeout {
+		t.Errorf("default SubmoduleTimeoutDuration() = %s, want %s", got, DefaultSubmoduleTimeout)
+	}
+
+	setSetting(t, s, "test-repo", "submodule-timeout", "2m0s")
+	if err := s.SetHasSubmodules("test-repo", true); err != nil {
+		t.Fatalf("SetHasSubmodules() failed: %v", err)
+	}
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	repo, _ = loaded.GetRepo("test-repo")
+	if got := repo.SubmoduleTimeoutDuration(); got != 2*time.Minute {
+		t.Errorf("SubmoduleTimeoutDuration() after reload = %s, want 2m", got)
+	}
+	if !repo.HasSubmodules {
+		t.Error("HasSubmodules not saved")
+	}
+
+	if err := s.SetHasSubmodules("missing", true); err == nil {
+		t.Error("SetHasSubmodules() should fail for an unknown repository")
+	}
+}
+
+func TestAutoAckAfter(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	repo, _ := s.GetRepo("test-repo")
+	if got := repo.AutoAckAfterDuration(); got != 0 {
+		t.Errorf("default AutoAckAfterDuration() = %s, want 0 (off)", got)
+	}
+
+	setSetting(t, s, "test-repo", "auto-ack-after", "24h0m0s")
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	repo, _ = loaded.GetRepo("test-repo")
+	if got := repo.AutoAckAfterDuration(); got != 24*time.Hour {
+		t.Errorf("AutoAckAfterDuration() after reload = %s, want 24h", got)
+	}
+
+	setSetting(t, s, "test-repo", "auto-ack-after", "0s")
+	repo, _ = s.GetRepo("test-repo")
+	if repo.AutoAckAfter != "" {
+		t.Errorf("AutoAckAfter = %q after turning it off, want empty", repo.AutoAckAfter)
+	}
+}
+
+func TestAutoSync(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	setSetting(t, s, "test-repo", "auto-sync", "1h0m0s")
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	repo, _ := loaded.GetRepo("test-repo")
+	if got := repo.AutoSyncInterval(); got != time.Hour {
+		t.Errorf("AutoSyncInterval() after reload = %s, want 1h", got)
+	}
+
+	setSetting(t, s, "test-repo", "auto-sync", "0s")
+	repo, _ = s.GetRepo("test-repo")
+	if repo.AutoSync != "" || repo.AutoSyncInterval() != 0 {
+		t.Errorf("AutoSync = %q after turning it off, want empty", repo.AutoSync)
+	}
+}
+
+func TestNudgeWhenIdle(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	setSetting(t, s, "test-repo", "nudge-when-idle", "true")
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if repo, _ := loaded.GetRepo("test-repo"); !repo.NudgeWhenIdle {
+		t.Error("NudgeWhenIdle should be saved")
+	}
+	if repos := loaded.GetAllRepos(); !repos["test-repo"].NudgeWhenIdle {
+		t.Error("GetAllRepos() should copy NudgeWhenIdle")
+	}
+}
+
+func TestSnapshotKeep(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	if repo, _ := s.GetRepo("test-repo"); repo.SnapshotKeepCount() != DefaultSnapshotKeep {
+		t.Errorf("SnapshotKeepCount() = %d, want the default %d", repo.SnapshotKeepCount(), DefaultSnapshotKeep)
+	}
+	setSetting(t, s, "test-repo", "snapshot-keep", "5")
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if repo, _ := loaded.GetRepo("test-repo"); repo.SnapshotKeepCount() != 5 {
+		t.Errorf("SnapshotKeepCount() = %d, want 5", repo.SnapshotKeepCount())
+	}
+	if repos := loaded.GetAllRepos(); repos["test-repo"].SnapshotKeep != 5 {
+		t.Error("GetAllRepos() should copy SnapshotKeep")
+	}
+}
+
+func TestNameScheme(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	setSetting(t, s, "test-repo", "name-scheme", "dated")
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if repo := loaded.GetAllRepos()["test-repo"]; repo.NameScheme != "dated" {
+		t.Errorf("NameScheme after reload = %q, want dated", repo.NameScheme)
+	}
+
+	// docker is the default, so it is stored as empty
+	setSetting(t, s, "test-repo", "name-scheme", "docker")
+	if repo, _ := s.GetRepo("test-repo"); repo.NameScheme != "" {
+		t.Errorf("NameScheme = %q after choosing docker, want empty", repo.NameScheme)
+	}
+}
+
+func TestSetNoSupervisor(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	if err := s.SetNoSupervisor("test-repo", true); err != nil {
+		t.Fatalf("SetNoSupervisor() failed: %v", err)
+	}
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if repo, _ := loaded.GetRepo("test-repo"); !repo.NoSupervisor {
+		t.Error("NoSupervisor should persist")
+	}
+	if repos := loaded.GetAllRepos(); !repos["test-repo"].NoSupervisor {
+		t.Error("GetAllRepos() should copy NoSupervisor")
+	}
+
+	if err := s.SetNoSupervisor("missing", true); err == nil {
+		t.Error("SetNoSupervisor() should fail for an unknown repository")
+	}
+}
+
+func TestGitIdentity(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	identity := GitIdentity{Name: "Jane Doe", Email: "jane@example.com", BotSuffix: "[bot]", SignCommits: true, SigningKey: "/keys/id_ed25519", SigningFormat: SigningFormatSSH}
+	if err := s.UpdateRepoConfig("test-repo", func(r *Repository) error {
+		r.GitIdentity = identity
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateRepoConfig() failed: %v", err)
+	}
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if repos := loaded.GetAllRepos(); repos["test-repo"].GitIdentity != identity {
+		t.Errorf("GitIdentity = %+v, want %+v", repos["test-repo"].GitIdentity, identity)
+	}
+
+	values := identity.GitConfig()
+	want := map[string]string{
+		"user.name":       "Jane Doe [bot]",
+		"user.email":      "jane@example.com",
+		"commit.gpgsign":  "true",
+		"user.signingkey": "/keys/id_ed25519",
+		"gpg.format":      "ssh",
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("GitConfig()[%s] = %q, want %q", key, values[key], value)
+		}
+	}
+
+	// Without signing the signing keys are unset, leaving git's own config
+	unsigned := GitIdentity{Email: "jane@example.com"}.GitConfig()
+	if unsigned["commit.gpgsign"] != "" || unsigned["gpg.format"] != "" || unsigned["user.name"] != "" {
+		t.Errorf("GitConfig() without signing or a name = %v", unsigned)
+	}
+	if _, ok := unsigned["user.signingkey"]; !ok {
+		t.Error("GitConfig() should list signing keys to unset")
+	}
+
+	for _, invalid := range []GitIdentity{
+		{SigningFormat: "x509"},
+		{SignCommits: true, SigningFormat: SigningFormatSSH},
+		{BotSuffix: "[bot]"},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should fail", invalid)
+		}
+	}
+}
+
+func TestSetContextVar(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	if err := s.SetContextVar("test-repo", "FREEZE_DATE", "2024-03-01"); err != nil {
+		t.Fatalf("SetContextVar() failed: %v", err)
+	}
+	if err := s.SetContextVar("test-repo", "sprint.goal", "Ship it"); err != nil {
+		t.Fatalf("SetContextVar() failed: %v", err)
+	}
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	vars, err := loaded.GetContextVars("test-repo")
+	if err != nil || len(vars) != 2 || vars["FREEZE_DATE"] != "2024-03-01" {
+		t.Errorf("GetContextVars() = %v, %v", vars, err)
+	}
+
+	// Copies are independent of the state
+	vars["FREEZE_DATE"] = "changed"
+	if repos := loaded.GetAllRepos(); repos["test-repo"].ContextVars["FREEZE_DATE"] != "2024-03-01" {
+		t.Error("GetContextVars() should return a copy")
+	}
+
+	if err := s.SetContextVar("test-repo", "FREEZE_DATE", ""); err != nil {
+		t.Fatalf("SetContextVar() removing failed: %v", err)
+	}
+	if vars, _ := s.GetContextVars("test-repo"); len(vars) != 1 {
+		t.Errorf("an empty value should remove the key, got %v", vars)
+	}
+
+	for _, key := range []string{"", "has space", "a|b"} {
+		if err := s.SetContextVar("test-repo", key, "x"); err == nil {
+			t.Errorf("SetContextVar(%q) should fail", key)
+		}
+	}
+	if err := s.SetContextVar("missing", "KEY", "x"); err == nil {
+		t.Error("SetContextVar() should fail for an unknown repository")
+	}
+}
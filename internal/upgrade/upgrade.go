@@ -0,0 +1,125 @@
+// Package upgrade checks GitHub for a multiclaude release newer than the
+// running one.
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/dlorenc/multiclaude/pkg/claude"
+)
+
+// LatestReleaseURL is the GitHub API endpoint for the latest release
+const LatestReleaseURL = "https://api.github.com/repos/dlorenc/multiclaude/releases/latest"
+
+// Timeout bounds a check, so that an unreachable GitHub never holds up
+// the caller for long
+const Timeout = 5 * time.Second
+
+// Result is the outcome of a check, as written to the upgrade check file
+type Result struct {
+	// Current is the running version, "dev" for a build without one
+	Current string `json:"current"`
+	// Latest is the tag of the latest release, e.g. "v1.4.0"
+	Latest string `json:"latest"`
+	// ReleaseURL is the latest release's page, with its release notes
+	ReleaseURL string `json:"release_url"`
+	// Available is set when the latest release is newer than Current. It is
+	// never set for a version that cannot be compared, such as "dev".
+	Available bool `json:"available"`
+	// CheckedAt is when GitHub was asked
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// release is the part of the GitHub release API response that is used
+type release struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+// Check asks the GitHub releases API at url for the latest release and
+// compares it with current. It gives up after Timeout.
+func Check(ctx context.Context, client *http.Client, url, current string) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query GitHub releases: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub releases API returned %s", resp.Status)
+	}
+
+	var latest release
+	if err := json.NewDecoder(resp.Body).Decode(&latest); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub release: %w", err)
+	}
+	if latest.TagName == "" {
+		return nil, fmt.Errorf("GitHub release has no tag")
+	}
+
+	result := &Result{
+		Current:    current,
+		Latest:     latest.TagName,
+		ReleaseURL: latest.HTMLURL,
+		CheckedAt:  time.Now(),
+	}
+	result.Available = isNewer(latest.TagName, current)
+	return result, nil
+}
+
+// isNewer reports whether the release tagged tag is newer than version.
+// Versions without a major.minor.patch number are never older.
+func isNewer(tag, version string) bool {
+	latest, err := claude.ParseVersion(tag)
+	if err != nil {
+		return false
+	}
+	running, err := claude.ParseVersion(version)
+	if err != nil {
+		return false
+	}
+	cmp, err := claude.CompareVersions(latest, running)
+	return err == nil && cmp > 0
+}
+
+// WriteResult saves a check's result to path
+func WriteResult(path string, result *Result) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode upgrade check: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write upgrade check: %w", err)
+	}
+	return nil
+}
+
+// ReadResult loads the result saved at path. It returns nil and no error
+// when no check has been saved yet.
+func ReadResult(path string) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse upgrade check: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,90 @@
+package upgrade
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func releaseServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheck(t *testing.T) {
+	server := releaseServer(t, http.StatusOK, `{"tag_name": "v1.4.0", "html_url": "https://github.com/dlorenc/multiclaude/releases/tag/v1.4.0"}`)
+
+	tests := []struct {
+		current   string
+		available bool
+	}{
+		{"v1.3.2", true},
+		{"1.3.2", true},
+		{"v1.4.0", false},
+		{"v1.10.0", false},
+		{"dev", false},
+	}
+	for _, tt := range tests {
+		result, err := Check(context.Background(), server.Client(), server.URL, tt.current)
+		if err != nil {
+			t.Fatalf("Check(%s) failed: %v", tt.current, err)
+		}
+		if result.Available != tt.available {
+			t.Errorf("Check(%s).Available = %v, want %v", tt.current, result.Available, tt.available)
+		}
+		if result.Latest != "v1.4.0" || result.ReleaseURL == "" || result.Current != tt.current {
+			t.Errorf("Check(%s) = %+v", tt.current, result)
+		}
+	}
+}
+
+func TestCheckFailures(t *testing.T) {
+	for name, server := range map[string]*httptest.Server{
+		"status":   releaseServer(t, http.StatusForbidden, `{"message": "rate limited"}`),
+		"bad json": releaseServer(t, http.StatusOK, `not json`),
+		"no tag":   releaseServer(t, http.StatusOK, `{}`),
+	} {
+		if _, err := Check(context.Background(), server.Client(), server.URL, "v1.0.0"); err == nil {
+			t.Errorf("%s: Check() should fail", name)
+		}
+	}
+
+	// A slow server is given up on
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := Check(ctx, slow.Client(), slow.URL, "v1.0.0"); err == nil {
+		t.Error("Check() should fail when the server does not answer in time")
+	}
+}
+
+func TestResultFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upgrade-check.json")
+
+	if result, err := ReadResult(path); result != nil || err != nil {
+		t.Errorf("ReadResult() of a missing file = %+v, %v, want nothing", result, err)
+	}
+
+	want := &Result{Current: "v1.0.0", Latest: "v1.1.0", ReleaseURL: "https://example.com", Available: true, CheckedAt: time.Now().UTC().Truncate(time.Second)}
+	if err := WriteResult(path, want); err != nil {
+		t.Fatalf("WriteResult() failed: %v", err)
+	}
+	got, err := ReadResult(path)
+	if err != nil {
+		t.Fatalf("ReadResult() failed: %v", err)
+	}
+	if *got != *want {
+		t.Errorf("ReadResult() = %+v, want %+v", got, want)
+	}
+}
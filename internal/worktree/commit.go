@@ -0,0 +1,30 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNothingToCommit is returned by Commit when no changes are staged
+var ErrNothingToCommit = errors.New("nothing to commit")
+
+// Commit commits the changes staged in the worktree at path and returns the
+// new commit. With addAll every change in the worktree, including untracked
+// files, is staged first.
+func Commit(path, message string, addAll bool) (string, error) {
+	if addAll {
+		if output, err := gitCommand(path, "add", "-A").CombinedOutput(); err != nil {
+			return "", fmt.Errorf("failed to stage changes: %w\nOutput: %s", err, output)
+		}
+	}
+
+	// diff --quiet exits 1 when there are staged changes
+	if err := gitCommand(path, "diff", "--cached", "--quiet").Run(); err == nil {
+		return "", ErrNothingToCommit
+	}
+
+	if output, err := gitCommand(path, "commit", "-m", message).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to commit: %w\nOutput: %s", err, output)
+	}
+	return revParse(path, "HEAD")
+}
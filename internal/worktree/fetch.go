@@ -0,0 +1,88 @@
+package worktree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FetchResult describes what Fetch did
+type FetchResult struct {
+	// NewCommits maps each worktree path given to Fetch to the number of
+	// commits that arrived on the remote branch it follows
+	NewCommits map[string]int
+	// Pruned lists the remote-tracking branches removed because their
+	// branch was deleted on the remote, without the remote's name
+	Pruned []string
+}
+
+// Fetch fetches remote into the repository, pruning remote-tracking
+// branches deleted there if prune is set, and counts for each of
+// worktrees the commits that arrived on the branch it follows: its
+// branch's upstream, or the remote's default branch if it has none.
+// Worktrees share their repository's refs, so one fetch serves them all.
+func (m *Manager) Fetch(remote string, prune bool, worktrees []string) (*FetchResult, error) {
+	result := &FetchResult{NewCommits: make(map[string]int, len(worktrees))}
+
+	before, err := m.remoteBranches(remote)
+	if err != nil {
+		return nil, err
+	}
+	followed := make(map[string]string, len(worktrees))
+	old := make(map[string]string, len(worktrees))
+	for _, path := range worktrees {
+		followed[path] = m.followedRef(path, remote)
+		// A branch new on the remote counts from what the worktree has
+		if commit, err := revParse(path, followed[path]); err == nil {
+			old[path] = commit
+		} else {
+			old[path] = "HEAD"
+		}
+	}
+
+	args := []string{"fetch", remote}
+	if prune {
+		args = []string{"fetch", "--prune", remote}
+	}
+	if output, err := m.git(args...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%w from %s: %w\nOutput: %s", ErrFetchFailed, remote, err, output)
+	}
+
+	after, err := m.remoteBranches(remote)
+	if err != nil {
+		return nil, err
+	}
+	remaining := make(map[string]bool, len(after))
+	for _, branch := range after {
+		remaining[branch] = true
+	}
+	for _, branch := range before {
+		if !remaining[branch] {
+			result.Pruned = append(result.Pruned, branch)
+		}
+	}
+
+	for _, path := range worktrees {
+		output, err := gitCommand(path, "rev-list", "--count", old[path]+".."+followed[path]).Output()
+		if err != nil {
+			// The followed branch was deleted on the remote
+			continue
+		}
+		result.NewCommits[path], _ = strconv.Atoi(strings.TrimSpace(string(output)))
+	}
+	return result, nil
+}
+
+// followedRef returns the ref whose new commits matter to the worktree at
+// path: the upstream of its branch, or the remote's default branch
+func (m *Manager) followedRef(path, remote string) string {
+	output, err := gitCommand(path, "rev-parse", "--symbolic-full-name", "@{upstream}").Output()
+	if upstream := strings.TrimSpace(string(output)); err == nil && upstream != "" {
+		return upstream
+	}
+	branch, err := m.GetDefaultBranch(remote)
+	if err != nil {
+		branch = "main"
+	}
+	return "refs/remotes/" + remote + "/" + branch
+}
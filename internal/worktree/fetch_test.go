@@ -0,0 +1,65 @@
+package worktree
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFetch(t *testing.T) {
+	upstream, clone := createClone(t)
+	createBranch(t, upstream, "old")
+	m := NewManager(clone)
+	if _, err := m.Fetch("origin", false, nil); err != nil {
+		t.Fatalf("Fetch() failed: %v", err)
+	}
+
+	// One worktree follows its upstream, the other the default branch
+	tracking := filepath.Join(t.TempDir(), "tracking")
+	if err := m.CreateNewBranch(tracking, "workspace/tracking", "origin/feature"); err != nil {
+		t.Fatalf("CreateNewBranch() failed: %v", err)
+	}
+	runGit(t, tracking, "branch", "--set-upstream-to", "origin/feature")
+	local := filepath.Join(t.TempDir(), "local")
+	if err := m.CreateNewBranch(local, "workspace/local", "main"); err != nil {
+		t.Fatalf("CreateNewBranch() failed: %v", err)
+	}
+	worktrees := []string{tracking, local}
+
+	result, err := m.Fetch("origin", true, worktrees)
+	if err != nil {
+		t.Fatalf("Fetch() failed: %v", err)
+	}
+	if want := map[string]int{tracking: 0, local: 0}; !reflect.DeepEqual(result.NewCommits, want) || len(result.Pruned) != 0 {
+		t.Errorf("Fetch() of an up to date repository = %+v", result)
+	}
+
+	commitFile(t, upstream, "one.txt", "one", "Add one")
+	commitFile(t, upstream, "two.txt", "two", "Add two")
+	runGit(t, upstream, "checkout", "-q", "feature")
+	commitFile(t, upstream, "three.txt", "three", "Add three")
+	runGit(t, upstream, "checkout", "-q", "main")
+	runGit(t, upstream, "branch", "-D", "old")
+
+	result, err = m.Fetch("origin", false, worktrees)
+	if err != nil {
+		t.Fatalf("Fetch() failed: %v", err)
+	}
+	if want := map[string]int{tracking: 1, local: 2}; !reflect.DeepEqual(result.NewCommits, want) {
+		t.Errorf("NewCommits = %v, want %v", result.NewCommits, want)
+	}
+	if len(result.Pruned) != 0 {
+		t.Errorf("Pruned = %v without prune", result.Pruned)
+	}
+
+	result, err = m.Fetch("origin", true, worktrees)
+	if err != nil {
+		t.Fatalf("Fetch() failed: %v", err)
+	}
+	if !reflect.DeepEqual(result.Pruned, []string{"old"}) {
+		t.Errorf("Pruned = %v, want [old]", result.Pruned)
+	}
+	if result.NewCommits[local] != 0 {
+		t.Errorf("NewCommits[local] = %d after a second fetch, want 0", result.NewCommits[local])
+	}
+}
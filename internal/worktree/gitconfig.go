@@ -0,0 +1,96 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SetWorktreeConfig sets git config values that apply only to the worktree
+// at path, turning on per-worktree configuration (extensions.worktreeConfig)
+// in its repository first. An empty value unsets the key, so that the
+// repository's or user's value applies again.
+func SetWorktreeConfig(path string, values map[string]string) error {
+	if output, err := gitCommand(path, "config", "extensions.worktreeConfig", "true").CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to enable per-worktree config: %w\nOutput: %s", err, output)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if values[key] == "" {
+			// Exit status 5 means the key was not set
+			output, err := gitCommand(path, "config", "--worktree", "--unset-all", key).CombinedOutput()
+			var exitErr *exec.ExitError
+			if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 5) {
+				return fmt.Errorf("failed to unset %s: %w\nOutput: %s", key, err, output)
+			}
+			continue
+		}
+		if output, err := gitCommand(path, "config", "--worktree", key, values[key]).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to set %s: %w\nOutput: %s", key, err, output)
+		}
+	}
+	return nil
+}
+
+// GetWorktreeConfig returns the value of key in the per-worktree config of
+// the worktree at path, or "" if it is not set there
+func GetWorktreeConfig(path, key string) (string, error) {
+	output, err := gitCommand(path, "config", "--worktree", "--get", key).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return "", nil
+	}
+	if err != nil {
+		// Without extensions.worktreeConfig nothing is set per worktree
+		if enabled, _ := gitCommand(path, "config", "--bool", "extensions.worktreeConfig").Output(); strings.TrimSpace(string(enabled)) != "true" {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// TestSign signs a test blob the way git signs commits with gpg.format
+// format ("openpgp" or "ssh") and user.signingkey key, reporting why it
+// fails. An empty openpgp key means gpg's default key.
+func TestSign(format, key string) error {
+	dir, err := os.MkdirTemp("", "multiclaude-sign-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	blob := filepath.Join(dir, "blob")
+	if err := os.WriteFile(blob, []byte("multiclaude signing test\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write test blob: %w", err)
+	}
+
+	var cmd *exec.Cmd
+	switch format {
+	case "ssh":
+		if key == "" {
+			return fmt.Errorf("ssh signing needs a signing key")
+		}
+		cmd = exec.Command("ssh-keygen", "-Y", "sign", "-n", "git", "-f", key, blob)
+	case "", "openpgp":
+		args := []string{"--batch", "--detach-sign", "--armor", "--output", blob + ".asc"}
+		if key != "" {
+			args = append(args, "--local-user", key)
+		}
+		cmd = exec.Command("gpg", append(args, blob)...)
+	default:
+		return fmt.Errorf("unknown signing format %q", format)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w\nOutput: %s", cmd.Args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
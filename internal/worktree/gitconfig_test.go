@@ -0,0 +1,78 @@
+package worktree
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetWorktreeConfig(t *testing.T) {
+	repoPath, wtPath := createSnapshotWorkspace(t)
+	repoName := strings.TrimSpace(gitOutput(t, repoPath, "config", "user.name"))
+
+	err := SetWorktreeConfig(wtPath, map[string]string{
+		"user.name":      "Jane Doe [bot]",
+		"user.email":     "jane@example.com",
+		"commit.gpgsign": "",
+	})
+	if err != nil {
+		t.Fatalf("SetWorktreeConfig() failed: %v", err)
+	}
+
+	if got := strings.TrimSpace(gitOutput(t, wtPath, "config", "user.name")); got != "Jane Doe [bot]" {
+		t.Errorf("worktree user.name = %q", got)
+	}
+	if got, err := GetWorktreeConfig(wtPath, "user.email"); err != nil || got != "jane@example.com" {
+		t.Errorf("GetWorktreeConfig(user.email) = %q, %v", got, err)
+	}
+	// The main checkout and the repository config are untouched
+	if got := strings.TrimSpace(gitOutput(t, repoPath, "config", "user.name")); got != repoName {
+		t.Errorf("main checkout user.name = %q, want %q", got, repoName)
+	}
+	if got, err := GetWorktreeConfig(repoPath, "user.name"); err != nil || got != "" {
+		t.Errorf("GetWorktreeConfig() of the main checkout = %q, %v, want unset", got, err)
+	}
+
+	// Commits in the worktree use the identity
+	writeTestFile(t, filepath.Join(wtPath, "change.txt"), "change\n")
+	runGit(t, wtPath, "add", "change.txt")
+	runGit(t, wtPath, "commit", "-m", "Change")
+	if got := gitOutput(t, wtPath, "log", "-1", "--format=%an <%ae>"); got != "Jane Doe [bot] <jane@example.com>\n" {
+		t.Errorf("commit author = %q", got)
+	}
+
+	// Empty values unset, falling back to the repository's config
+	if err := SetWorktreeConfig(wtPath, map[string]string{"user.name": "", "user.email": ""}); err != nil {
+		t.Fatalf("SetWorktreeConfig() unsetting failed: %v", err)
+	}
+	if got, err := GetWorktreeConfig(wtPath, "user.name"); err != nil || got != "" {
+		t.Errorf("GetWorktreeConfig(user.name) after unsetting = %q, %v", got, err)
+	}
+	if got := strings.TrimSpace(gitOutput(t, wtPath, "config", "user.name")); got != repoName {
+		t.Errorf("worktree user.name after unsetting = %q, want %q", got, repoName)
+	}
+}
+
+func TestTestSign(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	if output, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen failed: %v\n%s", err, output)
+	}
+
+	if err := TestSign("ssh", key); err != nil {
+		t.Errorf("TestSign() with a usable key failed: %v", err)
+	}
+	if err := TestSign("ssh", key+".missing"); err == nil {
+		t.Error("TestSign() with a missing key should fail")
+	}
+	if err := TestSign("ssh", ""); err == nil {
+		t.Error("TestSign() with ssh and no key should fail")
+	}
+	if err := TestSign("x509", "key"); err == nil {
+		t.Error("TestSign() with an unknown format should fail")
+	}
+}
@@ -0,0 +1,141 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Merge strategies accepted by MergeBranch. Ours and theirs keep git's
+// default strategy and settle conflicting hunks in favor of the worktree's
+// branch or the merged branch; recursive selects git's recursive strategy.
+const (
+	MergeStrategyOurs      = "ours"
+	MergeStrategyTheirs    = "theirs"
+	MergeStrategyRecursive = "recursive"
+)
+
+// MergeOptions controls MergeBranch
+type MergeOptions struct {
+	// Strategy is one of the MergeStrategy constants, or "" for git's default
+	Strategy string
+	// Squash combines the merged branch's commits into a single commit
+	Squash bool
+	// Message is the commit message; git's default when empty
+	Message string
+}
+
+// MergeResult describes what MergeBranch did
+type MergeResult struct {
+	// Commit is the worktree's HEAD after the merge
+	Commit string
+	// UpToDate is set when the branch had nothing to merge
+	UpToDate bool
+	// Conflicts lists the conflicting files of an aborted merge
+	Conflicts []string
+	// Output is what git merge printed
+	Output string
+}
+
+// mergeStrategyArgs returns the git merge arguments for a strategy
+func mergeStrategyArgs(strategy string) ([]string, error) {
+	switch strategy {
+	case "":
+		return nil, nil
+	case MergeStrategyOurs, MergeStrategyTheirs:
+		return []string{"-X", strategy}, nil
+	case MergeStrategyRecursive:
+		return []string{"-s", "recursive"}, nil
+	}
+	return nil, fmt.Errorf("unknown merge strategy %q (must be ours, theirs or recursive)", strategy)
+}
+
+// MergeBranch merges branch into the branch checked out in the worktree at
+// path, committing the result. The worktree must have no uncommitted
+// changes and no merge in progress. If the merge conflicts it is aborted,
+// leaving the worktree as it was, and the conflicting files are returned in
+// the result along with an error.
+func MergeBranch(path, branch string, opts MergeOptions) (*MergeResult, error) {
+	strategyArgs, err := mergeStrategyArgs(opts.Strategy)
+	if err != nil {
+		return nil, err
+	}
+	gitDir, err := GitDir(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, "MERGE_HEAD")); err == nil {
+		return nil, fmt.Errorf("a merge is already in progress (run 'git merge --continue' or 'git merge --abort')")
+	}
+	if dirty, err := HasUncommittedChanges(path); err != nil {
+		return nil, err
+	} else if dirty {
+		return nil, fmt.Errorf("the worktree has uncommitted changes")
+	}
+
+	before, err := revParse(path, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]string{"merge", "--no-edit"}, strategyArgs...)
+	if opts.Squash {
+		args = append(args, "--squash")
+	} else {
+		args = append(args, "--no-ff")
+		if opts.Message != "" {
+			args = append(args, "-m", opts.Message)
+		}
+	}
+	args = append(args, branch)
+	output, mergeErr := gitCommand(path, args...).CombinedOutput()
+	result := &MergeResult{Output: strings.TrimSpace(string(output))}
+
+	if mergeErr != nil {
+		conflicts, _ := gitCommand(path, "diff", "--name-only", "--diff-filter=U").Output()
+		for _, file := range strings.Split(strings.TrimSpace(string(conflicts)), "\n") {
+			if file != "" {
+				result.Conflicts = append(result.Conflicts, file)
+			}
+		}
+		// reset --merge also undoes a squash merge, which has no MERGE_HEAD
+		// for merge --abort to go back to
+		if abortOutput, err := gitCommand(path, "reset", "--merge").CombinedOutput(); err != nil {
+			return result, fmt.Errorf("merge of %s failed and could not be aborted: %w\nOutput: %s", branch, err, abortOutput)
+		}
+		if len(result.Conflicts) > 0 {
+			return result, fmt.Errorf("merge of %s conflicts in %d file(s) and was aborted", branch, len(result.Conflicts))
+		}
+		return result, fmt.Errorf("merge of %s failed: %w\nOutput: %s", branch, mergeErr, result.Output)
+	}
+
+	// A squash merge only stages the changes, if there are any
+	if opts.Squash && gitCommand(path, "diff", "--cached", "--quiet").Run() != nil {
+		message := opts.Message
+		if message == "" {
+			message = fmt.Sprintf("Squash merge branch '%s'", branch)
+		}
+		if output, err := gitCommand(path, "commit", "-m", message).CombinedOutput(); err != nil {
+			gitCommand(path, "reset", "--merge").Run()
+			return result, fmt.Errorf("failed to commit squash merge of %s: %w\nOutput: %s", branch, err, output)
+		}
+	}
+
+	if result.Commit, err = revParse(path, "HEAD"); err != nil {
+		return result, err
+	}
+	if result.Commit == before {
+		result.UpToDate = true
+	}
+	return result, nil
+}
+
+// revParse returns the commit a revision names in the worktree at path
+func revParse(path, rev string) (string, error) {
+	output, err := gitCommand(path, "rev-parse", "--verify", rev+"^{commit}").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", rev, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
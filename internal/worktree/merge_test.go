@@ -0,0 +1,129 @@
+package worktree
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createMergeWorktrees creates a repository with a workspace and a worker
+// worktree, both branched from main
+func createMergeWorktrees(t *testing.T) (wsPath, workerPath string) {
+	t.Helper()
+	repoPath, cleanup := createTestRepo(t)
+	t.Cleanup(cleanup)
+
+	m := NewManager(repoPath)
+	wsPath = filepath.Join(repoPath, "wt-dev")
+	workerPath = filepath.Join(repoPath, "wt-worker")
+	if err := m.CreateNewBranch(wsPath, "workspace/dev", "main"); err != nil {
+		t.Fatalf("Failed to create workspace worktree: %v", err)
+	}
+	if err := m.CreateNewBranch(workerPath, "work/fix", "main"); err != nil {
+		t.Fatalf("Failed to create worker worktree: %v", err)
+	}
+	return wsPath, workerPath
+}
+
+func commitFile(t *testing.T, dir, name, content, message string) {
+	t.Helper()
+	writeTestFile(t, filepath.Join(dir, name), content)
+	runGit(t, dir, "add", name)
+	runGit(t, dir, "commit", "-m", message)
+}
+
+func TestMergeBranch(t *testing.T) {
+	wsPath, workerPath := createMergeWorktrees(t)
+	commitFile(t, workerPath, "fix.txt", "fixed\n", "Fix it")
+	commitFile(t, wsPath, "feature.txt", "feature\n", "Add feature")
+	workerHead := gitOutput(t, workerPath, "rev-parse", "HEAD")
+
+	result, err := MergeBranch(wsPath, "work/fix", MergeOptions{Message: "Merge worker fix"})
+	if err != nil {
+		t.Fatalf("MergeBranch() failed: %v", err)
+	}
+	if result.UpToDate || result.Commit != strings.TrimSpace(gitOutput(t, wsPath, "rev-parse", "HEAD")) {
+		t.Errorf("result = %+v, want the new HEAD", result)
+	}
+	if parents := strings.Fields(gitOutput(t, wsPath, "log", "-1", "--format=%P")); len(parents) != 2 {
+		t.Errorf("merge commit has parents %v, want two", parents)
+	}
+	if got := gitOutput(t, wsPath, "log", "-1", "--format=%s"); got != "Merge worker fix\n" {
+		t.Errorf("merge commit message = %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(wsPath, "fix.txt")); got != "fixed\n" {
+		t.Errorf("fix.txt = %q, want the worker's change", got)
+	}
+
+	// Only the workspace branch moved
+	if got := gitOutput(t, workerPath, "rev-parse", "HEAD"); got != workerHead {
+		t.Errorf("worker HEAD moved from %s to %s", workerHead, got)
+	}
+
+	// Merging again has nothing to do
+	again, err := MergeBranch(wsPath, "work/fix", MergeOptions{})
+	if err != nil || !again.UpToDate {
+		t.Errorf("second MergeBranch() = %+v, %v, want up to date", again, err)
+	}
+}
+
+func TestMergeBranchConflicts(t *testing.T) {
+	wsPath, workerPath := createMergeWorktrees(t)
+	commitFile(t, workerPath, "README.md", "# Worker\n", "Worker README")
+	commitFile(t, wsPath, "README.md", "# Workspace\n", "Workspace README")
+	head := gitOutput(t, wsPath, "rev-parse", "HEAD")
+
+	for _, squash := range []bool{false, true} {
+		result, err := MergeBranch(wsPath, "work/fix", MergeOptions{Squash: squash})
+		if err == nil || result == nil || strings.Join(result.Conflicts, ",") != "README.md" {
+			t.Fatalf("MergeBranch(squash=%v) = %+v, %v, want a conflict in README.md", squash, result, err)
+		}
+		// The merge was aborted, leaving the workspace as it was
+		if got := gitOutput(t, wsPath, "rev-parse", "HEAD"); got != head {
+			t.Errorf("HEAD moved from %s to %s", head, got)
+		}
+		if status := gitOutput(t, wsPath, "status", "--porcelain"); status != "" {
+			t.Errorf("workspace not clean after the aborted merge:\n%s", status)
+		}
+	}
+
+	// theirs settles the conflict for the worker
+	if _, err := MergeBranch(wsPath, "work/fix", MergeOptions{Strategy: MergeStrategyTheirs}); err != nil {
+		t.Fatalf("MergeBranch() with theirs failed: %v", err)
+	}
+	if got := readTestFile(t, filepath.Join(wsPath, "README.md")); got != "# Worker\n" {
+		t.Errorf("README.md = %q, want the worker's", got)
+	}
+
+	if _, err := MergeBranch(wsPath, "work/fix", MergeOptions{Strategy: "octopus"}); err == nil {
+		t.Error("MergeBranch() with an unknown strategy should fail")
+	}
+}
+
+func TestMergeBranchSquash(t *testing.T) {
+	wsPath, workerPath := createMergeWorktrees(t)
+	commitFile(t, workerPath, "one.txt", "one\n", "First")
+	commitFile(t, workerPath, "two.txt", "two\n", "Second")
+	before := strings.TrimSpace(gitOutput(t, wsPath, "rev-parse", "HEAD"))
+
+	result, err := MergeBranch(wsPath, "work/fix", MergeOptions{Squash: true, Message: "Squash worker fix"})
+	if err != nil {
+		t.Fatalf("MergeBranch() failed: %v", err)
+	}
+	if parents := strings.TrimSpace(gitOutput(t, wsPath, "log", "-1", "--format=%P")); parents != before {
+		t.Errorf("squash commit parents = %q, want only the previous HEAD %s", parents, before)
+	}
+	if got := gitOutput(t, wsPath, "log", "-1", "--format=%s"); got != "Squash worker fix\n" {
+		t.Errorf("squash commit message = %q", got)
+	}
+	files := gitOutput(t, wsPath, "show", "--name-only", "--format=", result.Commit)
+	if files != "one.txt\ntwo.txt\n" {
+		t.Errorf("squash commit files = %q, want both of the worker's", files)
+	}
+
+	// Uncommitted changes stop the merge before it starts
+	writeTestFile(t, filepath.Join(wsPath, "README.md"), "# Edited\n")
+	if _, err := MergeBranch(wsPath, "work/fix", MergeOptions{}); err == nil || !strings.Contains(err.Error(), "uncommitted") {
+		t.Errorf("MergeBranch() with uncommitted changes = %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package worktree
+
+import (
+	"fmt"
+)
+
+// ResolveCommit returns the commit a revision names in the worktree at path:
+// a commit ID, a branch or tag name, or a relative revision such as HEAD~3
+func ResolveCommit(path, rev string) (string, error) {
+	return revParse(path, rev)
+}
+
+// ResetTo moves the branch checked out in the worktree at path to rev and
+// returns the commit it now points at. A hard reset also discards the
+// worktree's uncommitted changes; a soft reset keeps the index and working
+// tree, so the changes of the commits reset past become staged.
+func ResetTo(path, rev string, soft bool) (string, error) {
+	commit, err := revParse(path, rev)
+	if err != nil {
+		return "", err
+	}
+
+	mode := "--hard"
+	if soft {
+		mode = "--soft"
+	}
+	if output, err := gitCommand(path, "reset", mode, commit).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to reset to %s: %w\nOutput: %s", rev, err, output)
+	}
+	return commit, nil
+}
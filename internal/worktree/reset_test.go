@@ -0,0 +1,87 @@
+package worktree
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResetTo(t *testing.T) {
+	wsPath, _ := createMergeWorktrees(t)
+	base := strings.TrimSpace(gitOutput(t, wsPath, "rev-parse", "HEAD"))
+	commitFile(t, wsPath, "one.txt", "one\n", "Add one")
+	runGit(t, wsPath, "tag", "v-one")
+	commitFile(t, wsPath, "two.txt", "two\n", "Add two")
+	commitFile(t, wsPath, "three.txt", "three\n", "Add three")
+
+	// Relative revisions are resolved before the reset
+	commit, err := ResetTo(wsPath, "HEAD~1", false)
+	if err != nil {
+		t.Fatalf("ResetTo(HEAD~1) failed: %v", err)
+	}
+	if head := strings.TrimSpace(gitOutput(t, wsPath, "rev-parse", "HEAD")); commit != head {
+		t.Errorf("ResetTo() = %s, want the new HEAD %s", commit, head)
+	}
+	if got := gitOutput(t, wsPath, "log", "-1", "--format=%s"); got != "Add two\n" {
+		t.Errorf("HEAD is %q, want Add two", got)
+	}
+
+	// A hard reset discards uncommitted changes
+	writeTestFile(t, filepath.Join(wsPath, "two.txt"), "edited\n")
+	if _, err := ResetTo(wsPath, "v-one", false); err != nil {
+		t.Fatalf("ResetTo(v-one) failed: %v", err)
+	}
+	if dirty, _ := HasUncommittedChanges(wsPath); dirty {
+		t.Error("hard reset left uncommitted changes")
+	}
+
+	// A soft reset keeps the changes reset past, staged
+	if _, err := ResetTo(wsPath, base, true); err != nil {
+		t.Fatalf("ResetTo(base, soft) failed: %v", err)
+	}
+	if got := readTestFile(t, filepath.Join(wsPath, "one.txt")); got != "one\n" {
+		t.Errorf("one.txt = %q after soft reset, want it kept", got)
+	}
+	if staged := gitOutput(t, wsPath, "diff", "--cached", "--name-only"); staged != "one.txt\n" {
+		t.Errorf("staged files = %q, want one.txt", staged)
+	}
+
+	if _, err := ResetTo(wsPath, "no-such-ref", false); err == nil {
+		t.Error("ResetTo() of an unknown revision should fail")
+	}
+}
+
+func TestCommit(t *testing.T) {
+	wsPath, _ := createMergeWorktrees(t)
+	before := strings.TrimSpace(gitOutput(t, wsPath, "rev-parse", "HEAD"))
+
+	if _, err := Commit(wsPath, "Nothing", false); err != ErrNothingToCommit {
+		t.Errorf("Commit() with nothing staged = %v, want ErrNothingToCommit", err)
+	}
+
+	// Without addAll only staged changes are committed
+	writeTestFile(t, filepath.Join(wsPath, "staged.txt"), "staged\n")
+	writeTestFile(t, filepath.Join(wsPath, "untracked.txt"), "untracked\n")
+	runGit(t, wsPath, "add", "staged.txt")
+	commit, err := Commit(wsPath, "Add staged", false)
+	if err != nil {
+		t.Fatalf("Commit() failed: %v", err)
+	}
+	if head := strings.TrimSpace(gitOutput(t, wsPath, "rev-parse", "HEAD")); commit != head || commit == before {
+		t.Errorf("Commit() = %s, want the new HEAD %s", commit, head)
+	}
+	if files := gitOutput(t, wsPath, "show", "--name-only", "--format=", "HEAD"); files != "staged.txt\n" {
+		t.Errorf("committed files = %q, want staged.txt", files)
+	}
+
+	// With addAll untracked files are committed too
+	if _, err := Commit(wsPath, "Add the rest", true); err != nil {
+		t.Fatalf("Commit(addAll) failed: %v", err)
+	}
+	if dirty, _ := HasUncommittedChanges(wsPath); dirty {
+		t.Error("Commit(addAll) left uncommitted changes")
+	}
+	if got := gitOutput(t, wsPath, "log", "-1", "--format=%s"); got != "Add the rest\n" {
+		t.Errorf("HEAD is %q, want Add the rest", got)
+	}
+}
@@ -0,0 +1,276 @@
+package worktree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// snapshotRefPrefix is where snapshots are kept, one ref per snapshot under
+// the workspace's name. They are neither branches nor tags, so they stay out
+// of the workspace branch's history and of git branch and git log.
+const snapshotRefPrefix = "refs/multiclaude/snapshots/"
+
+// snapshotIDLayout names snapshots after the time they were taken
+const snapshotIDLayout = "20060102-150405"
+
+// snapshotIdentity authors snapshot commits, so that taking one works
+// without a git identity configured
+var snapshotIdentity = []string{
+	"GIT_AUTHOR_NAME=multiclaude", "GIT_AUTHOR_EMAIL=multiclaude@localhost",
+	"GIT_COMMITTER_NAME=multiclaude", "GIT_COMMITTER_EMAIL=multiclaude@localhost",
+}
+
+// Snapshot is a saved state of a workspace's working tree
+type Snapshot struct {
+	// ID names the snapshot within its workspace, e.g. 20260612-143005
+	ID    string
+	Label string
+	// Created is when the snapshot was taken
+	Created time.Time
+	// Commit holds the saved tree; its parent is the commit checked out
+	// when the snapshot was taken
+	Commit string
+}
+
+// Ref returns the snapshot's ref for a workspace
+func (s Snapshot) Ref(workspace string) string {
+	return snapshotRef(workspace, s.ID)
+}
+
+func snapshotRef(workspace, id string) string {
+	return snapshotRefPrefix + workspace + "/" + id
+}
+
+// CreateSnapshot saves the working tree of the worktree at path as a snapshot
+// of workspace, without touching the working tree, the index or HEAD.
+// Tracked and untracked files are saved; files matched by .gitignore are not.
+// The tree is written through a temporary index, so staged changes stay
+// staged.
+func CreateSnapshot(path, workspace, label string, now time.Time) (*Snapshot, error) {
+	gitDir, err := GitDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start from a copy of the real index so unchanged files are not hashed
+	// again, then stage everything in the copy
+	tmpIndex, err := os.CreateTemp(gitDir, "multiclaude-snapshot-index-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	tmpIndexPath := tmpIndex.Name()
+	defer os.Remove(tmpIndexPath)
+	copied, err := copyIndex(filepath.Join(gitDir, "index"), tmpIndex)
+	tmpIndex.Close()
+	if err != nil {
+		return nil, err
+	}
+	if !copied {
+		// git reads an empty file as a corrupt index, but a missing one as
+		// an empty index
+		os.Remove(tmpIndexPath)
+	}
+	indexEnv := "GIT_INDEX_FILE=" + tmpIndexPath
+
+	add := gitCommand(path, "add", "--all", "--", ".")
+	add.Env = append(add.Env, indexEnv)
+	if output, err := add.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to stage the working tree: %w\nOutput: %s", err, output)
+	}
+
+	writeTree := gitCommand(path, "write-tree")
+	writeTree.Env = append(writeTree.Env, indexEnv)
+	output, err := writeTree.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write the snapshot tree: %w", err)
+	}
+	tree := strings.TrimSpace(string(output))
+
+	label = strings.Join(strings.Fields(label), " ")
+	args := []string{"commit-tree", tree, "-m", label}
+	if head, err := gitCommand(path, "rev-parse", "--verify", "--quiet", "HEAD").Output(); err == nil {
+		args = append(args, "-p", strings.TrimSpace(string(head)))
+	}
+	commitTree := gitCommand(path, args...)
+	date := fmt.Sprintf("%d +0000", now.Unix())
+	commitTree.Env = append(commitTree.Env, snapshotIdentity...)
+	commitTree.Env = append(commitTree.Env, "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+	output, err = commitTree.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit the snapshot: %w", err)
+	}
+	commit := strings.TrimSpace(string(output))
+
+	// Snapshots taken within the same second get a suffix
+	id := now.UTC().Format(snapshotIDLayout)
+	for n := 2; snapshotExists(path, workspace, id); n++ {
+		id = fmt.Sprintf("%s-%d", now.UTC().Format(snapshotIDLayout), n)
+	}
+	// The empty old value makes update-ref fail rather than replace a
+	// snapshot taken concurrently under the same ID
+	if output, err := gitCommand(path, "update-ref", snapshotRef(workspace, id), commit, "").CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to save snapshot ref: %w\nOutput: %s", err, output)
+	}
+
+	return &Snapshot{ID: id, Label: label, Created: time.Unix(now.Unix(), 0), Commit: commit}, nil
+}
+
+// copyIndex copies the index at src into dst, reporting false if there is no
+// index yet
+func copyIndex(src string, dst io.Writer) (bool, error) {
+	f, err := os.Open(src)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read index: %w", err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(dst, f); err != nil {
+		return false, fmt.Errorf("failed to copy index: %w", err)
+	}
+	return true, nil
+}
+
+func snapshotExists(path, workspace, id string) bool {
+	return gitCommand(path, "rev-parse", "--verify", "--quiet", snapshotRef(workspace, id)).Run() == nil
+}
+
+// ListSnapshots returns the snapshots of a workspace, newest first. path may
+// be any checkout of the repository, as refs are shared by its worktrees.
+func ListSnapshots(path, workspace string) ([]Snapshot, error) {
+	prefix := snapshotRefPrefix + workspace + "/"
+	cmd := gitCommand(path, "for-each-ref", "--format=%(refname)%00%(objectname)%00%(committerdate:unix)%00%(contents:subject)", prefix)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	var snapshots []Snapshot
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		id := strings.TrimPrefix(fields[0], prefix)
+		// Refs of a workspace whose name starts with this one's, e.g. a/b
+		// under a, are not this workspace's
+		if strings.Contains(id, "/") {
+			continue
+		}
+		created, _ := strconv.ParseInt(fields[2], 10, 64)
+		snapshots = append(snapshots, Snapshot{ID: id, Commit: fields[1], Created: time.Unix(created, 0), Label: fields[3]})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		if !snapshots[i].Created.Equal(snapshots[j].Created) {
+			return snapshots[i].Created.After(snapshots[j].Created)
+		}
+		return snapshots[i].ID > snapshots[j].ID
+	})
+	return snapshots, nil
+}
+
+// GetSnapshot returns one snapshot of a workspace
+func GetSnapshot(path, workspace, id string) (*Snapshot, error) {
+	snapshots, err := ListSnapshots(path, workspace)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range snapshots {
+		if s.ID == id {
+			return &s, nil
+		}
+	}
+	return nil, fmt.Errorf("workspace %s has no snapshot %q", workspace, id)
+}
+
+// SnapshotStat summarizes a snapshot against the commit it was taken on, in
+// the words of git diff --shortstat, e.g. "2 files changed, 5 insertions(+)".
+// It is "" when the snapshot has no changes.
+func SnapshotStat(path string, s Snapshot) (string, error) {
+	output, err := gitCommand(path, "show", "--shortstat", "--format=", s.Commit).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize snapshot %s: %w", s.ID, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RestoreSnapshot makes the working tree of the worktree at path match a
+// snapshot: files are restored, and files added since are removed. Files
+// matched by .gitignore are left alone, as are HEAD and the branch, and the
+// index is reset to HEAD. Anything not in a snapshot or commit is lost, so
+// callers check the tree is clean or save it first.
+func RestoreSnapshot(path, workspace, id string) error {
+	snapshot, err := GetSnapshot(path, workspace, id)
+	if err != nil {
+		return err
+	}
+
+	steps := [][]string{
+		// Remove untracked files; those in the snapshot are written back
+		{"clean", "-f", "-d", "--quiet"},
+		// Check out the snapshot's tree over the working tree, removing
+		// tracked files it does not have
+		{"read-tree", "--reset", "-u", snapshot.Commit},
+		// Leave the restored changes unstaged, as they were
+		{"reset", "--quiet"},
+	}
+	for _, args := range steps {
+		if output, err := gitCommand(path, args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to restore snapshot %s (git %s): %w\nOutput: %s", id, args[0], err, output)
+		}
+	}
+	return nil
+}
+
+// PruneSnapshots deletes all but the newest keep snapshots of a workspace
+// and returns those deleted
+func PruneSnapshots(path, workspace string, keep int) ([]Snapshot, error) {
+	snapshots, err := ListSnapshots(path, workspace)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) <= keep {
+		return nil, nil
+	}
+
+	var deleted []Snapshot
+	for _, s := range snapshots[keep:] {
+		if output, err := gitCommand(path, "update-ref", "-d", s.Ref(workspace)).CombinedOutput(); err != nil {
+			return deleted, fmt.Errorf("failed to delete snapshot %s: %w\nOutput: %s", s.ID, err, output)
+		}
+		deleted = append(deleted, s)
+	}
+	return deleted, nil
+}
+
+// SnapshotWorkspaces lists the workspaces that have snapshots in the
+// repository at path, including workspaces removed since
+func SnapshotWorkspaces(path string) ([]string, error) {
+	output, err := gitCommand(path, "for-each-ref", "--format=%(refname)", snapshotRefPrefix).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var workspaces []string
+	for _, ref := range strings.Fields(string(output)) {
+		rest := strings.TrimPrefix(ref, snapshotRefPrefix)
+		i := strings.LastIndex(rest, "/")
+		if i <= 0 {
+			continue
+		}
+		if workspace := rest[:i]; !seen[workspace] {
+			seen[workspace] = true
+			workspaces = append(workspaces, workspace)
+		}
+	}
+	sort.Strings(workspaces)
+	return workspaces, nil
+}
@@ -0,0 +1,230 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	output, err := gitCommand(dir, args...).Output()
+	if err != nil {
+		t.Fatalf("git %s failed: %v", strings.Join(args, " "), err)
+	}
+	return string(output)
+}
+
+// createSnapshotWorkspace creates a repository with a workspace worktree that
+// has a committed .gitignore
+func createSnapshotWorkspace(t *testing.T) (repoPath, wtPath string) {
+	t.Helper()
+	repoPath, cleanup := createTestRepo(t)
+	t.Cleanup(cleanup)
+
+	wtPath = filepath.Join(repoPath, "wt-dev")
+	if err := NewManager(repoPath).CreateNewBranch(wtPath, "workspace/dev", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	writeTestFile(t, filepath.Join(wtPath, ".gitignore"), "*.log\n")
+	runGit(t, wtPath, "add", ".gitignore")
+	runGit(t, wtPath, "commit", "-m", "Ignore logs")
+	return repoPath, wtPath
+}
+
+func TestCreateSnapshotLeavesWorkspaceAlone(t *testing.T) {
+	_, wtPath := createSnapshotWorkspace(t)
+
+	writeTestFile(t, filepath.Join(wtPath, "README.md"), "# Edited\n")
+	writeTestFile(t, filepath.Join(wtPath, "staged.txt"), "staged\n")
+	runGit(t, wtPath, "add", "staged.txt")
+	writeTestFile(t, filepath.Join(wtPath, "notes", "untracked.txt"), "untracked\n")
+	writeTestFile(t, filepath.Join(wtPath, "debug.log"), "ignored\n")
+
+	headBefore := gitOutput(t, wtPath, "rev-parse", "HEAD")
+	statusBefore := gitOutput(t, wtPath, "status", "--porcelain")
+
+	now := time.Date(2026, 6, 12, 14, 30, 5, 0, time.UTC)
+	snapshot, err := CreateSnapshot(wtPath, "dev", "before\nlunch", now)
+	if err != nil {
+		t.Fatalf("CreateSnapshot() failed: %v", err)
+	}
+	if snapshot.ID != "20260612-143005" || snapshot.Label != "before lunch" || !snapshot.Created.Equal(now) {
+		t.Errorf("snapshot = %+v", snapshot)
+	}
+
+	// Nothing in the workspace changed, including what was staged
+	if got := gitOutput(t, wtPath, "rev-parse", "HEAD"); got != headBefore {
+		t.Errorf("HEAD moved from %s to %s", headBefore, got)
+	}
+	if got := gitOutput(t, wtPath, "status", "--porcelain"); got != statusBefore {
+		t.Errorf("status changed from\n%s\nto\n%s", statusBefore, got)
+	}
+	if got := gitOutput(t, wtPath, "branch", "--list"); strings.Contains(got, "snapshot") {
+		t.Errorf("snapshot shows up as a branch:\n%s", got)
+	}
+
+	// The snapshot has tracked, staged and untracked files but not ignored ones
+	files := gitOutput(t, wtPath, "ls-tree", "-r", "--name-only", snapshot.Commit)
+	for _, want := range []string{"README.md", "staged.txt", "notes/untracked.txt", ".gitignore"} {
+		if !strings.Contains(files, want+"\n") {
+			t.Errorf("snapshot is missing %s:\n%s", want, files)
+		}
+	}
+	if strings.Contains(files, "debug.log") {
+		t.Errorf("snapshot should not include ignored files:\n%s", files)
+	}
+	if got := gitOutput(t, wtPath, "show", snapshot.Commit+":README.md"); got != "# Edited\n" {
+		t.Errorf("snapshot README.md = %q", got)
+	}
+
+	stat, err := SnapshotStat(wtPath, *snapshot)
+	if err != nil || !strings.Contains(stat, "3 files changed") {
+		t.Errorf("SnapshotStat() = %q, %v, want 3 files changed", stat, err)
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	_, wtPath := createSnapshotWorkspace(t)
+
+	writeTestFile(t, filepath.Join(wtPath, "README.md"), "# Before lunch\n")
+	writeTestFile(t, filepath.Join(wtPath, "draft.txt"), "draft\n")
+	snapshot, err := CreateSnapshot(wtPath, "dev", "before lunch", time.Now())
+	if err != nil {
+		t.Fatalf("CreateSnapshot() failed: %v", err)
+	}
+
+	// After lunch: edits, a deleted file, new tracked and untracked files,
+	// and an ignored file
+	writeTestFile(t, filepath.Join(wtPath, "README.md"), "# After lunch\n")
+	os.Remove(filepath.Join(wtPath, "draft.txt"))
+	writeTestFile(t, filepath.Join(wtPath, "tracked-new.txt"), "new\n")
+	runGit(t, wtPath, "add", "tracked-new.txt")
+	writeTestFile(t, filepath.Join(wtPath, "scratch", "untracked-new.txt"), "new\n")
+	writeTestFile(t, filepath.Join(wtPath, "build.log"), "ignored\n")
+	headBefore := gitOutput(t, wtPath, "rev-parse", "HEAD")
+
+	if err := RestoreSnapshot(wtPath, "dev", snapshot.ID); err != nil {
+		t.Fatalf("RestoreSnapshot() failed: %v", err)
+	}
+
+	if got := readTestFile(t, filepath.Join(wtPath, "README.md")); got != "# Before lunch\n" {
+		t.Errorf("README.md = %q, want the snapshot's", got)
+	}
+	if got := readTestFile(t, filepath.Join(wtPath, "draft.txt")); got != "draft\n" {
+		t.Errorf("draft.txt = %q, want it restored", got)
+	}
+	for _, gone := range []string{"tracked-new.txt", "scratch/untracked-new.txt"} {
+		if _, err := os.Stat(filepath.Join(wtPath, gone)); !os.IsNotExist(err) {
+			t.Errorf("%s was added after the snapshot and should be removed", gone)
+		}
+	}
+	if got := readTestFile(t, filepath.Join(wtPath, "build.log")); got != "ignored\n" {
+		t.Errorf("ignored build.log = %q, want it left alone", got)
+	}
+
+	// HEAD is unchanged and the restored changes are unstaged, as they were
+	if got := gitOutput(t, wtPath, "rev-parse", "HEAD"); got != headBefore {
+		t.Errorf("HEAD moved from %s to %s", headBefore, got)
+	}
+	status := gitOutput(t, wtPath, "status", "--porcelain")
+	if want := " M README.md\n?? draft.txt\n"; status != want {
+		t.Errorf("status after restore =\n%s\nwant\n%s", status, want)
+	}
+
+	if err := RestoreSnapshot(wtPath, "dev", "19990101-000000"); err == nil {
+		t.Error("RestoreSnapshot() of an unknown snapshot should fail")
+	}
+}
+
+func TestListAndPruneSnapshots(t *testing.T) {
+	repoPath, wtPath := createSnapshotWorkspace(t)
+
+	base := time.Date(2026, 6, 12, 9, 0, 0, 0, time.UTC)
+	var ids []string
+	for i := 0; i < 4; i++ {
+		s, err := CreateSnapshot(wtPath, "dev", "", base.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatalf("CreateSnapshot() failed: %v", err)
+		}
+		ids = append(ids, s.ID)
+	}
+	// Two snapshots in the same second get distinct IDs
+	same, err := CreateSnapshot(wtPath, "dev", "again", base.Add(3*time.Hour))
+	if err != nil || same.ID != ids[3]+"-2" {
+		t.Fatalf("CreateSnapshot() in the same second = %+v, %v, want ID %s-2", same, err, ids[3])
+	}
+	// Another workspace whose name starts with dev's
+	if _, err := CreateSnapshot(wtPath, "dev/other", "", base); err != nil {
+		t.Fatalf("CreateSnapshot() failed: %v", err)
+	}
+
+	// Refs are shared, so the main checkout sees the workspace's snapshots
+	snapshots, err := ListSnapshots(repoPath, "dev")
+	if err != nil {
+		t.Fatalf("ListSnapshots() failed: %v", err)
+	}
+	var got []string
+	for _, s := range snapshots {
+		got = append(got, s.ID)
+	}
+	want := []string{same.ID, ids[3], ids[2], ids[1], ids[0]}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("ListSnapshots() = %v, want %v (newest first)", got, want)
+	}
+
+	workspaces, err := SnapshotWorkspaces(repoPath)
+	if err != nil || strings.Join(workspaces, " ") != "dev dev/other" {
+		t.Errorf("SnapshotWorkspaces() = %v, %v", workspaces, err)
+	}
+
+	deleted, err := PruneSnapshots(repoPath, "dev", 2)
+	if err != nil {
+		t.Fatalf("PruneSnapshots() failed: %v", err)
+	}
+	if len(deleted) != 3 {
+		t.Errorf("PruneSnapshots() deleted %d snapshots, want 3", len(deleted))
+	}
+	snapshots, _ = ListSnapshots(repoPath, "dev")
+	if len(snapshots) != 2 || snapshots[0].ID != same.ID || snapshots[1].ID != ids[3] {
+		t.Errorf("snapshots after pruning = %+v, want the newest two", snapshots)
+	}
+	if other, _ := ListSnapshots(repoPath, "dev/other"); len(other) != 1 {
+		t.Errorf("pruning dev should not touch dev/other, which has %d snapshots", len(other))
+	}
+}
+
+func TestCreateSnapshotWithoutCommits(t *testing.T) {
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-b", "main")
+	writeTestFile(t, filepath.Join(dir, "first.txt"), "first\n")
+
+	snapshot, err := CreateSnapshot(dir, "dev", "empty repo", time.Now())
+	if err != nil {
+		t.Fatalf("CreateSnapshot() without commits failed: %v", err)
+	}
+	if got := gitOutput(t, dir, "show", snapshot.Commit+":first.txt"); got != "first\n" {
+		t.Errorf("snapshot first.txt = %q", got)
+	}
+}
@@ -0,0 +1,165 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDiverged is returned by SyncDefaultBranch when the local default
+// branch has commits the remote does not, so it cannot be fast-forwarded
+var ErrDiverged = errors.New("default branch has diverged from the remote")
+
+// ErrFetchFailed is returned by SyncDefaultBranch when the remote could
+// not be fetched, typically because it is unreachable
+var ErrFetchFailed = errors.New("failed to fetch")
+
+// SyncResult describes what SyncDefaultBranch did
+type SyncResult struct {
+	// Remote and Branch are the remote fetched and its default branch
+	Remote string
+	Branch string
+	// OldCommit and NewCommit are the local branch's commit before and
+	// after; they are equal when there was nothing to pull
+	OldCommit string
+	NewCommit string
+	// Commits are the pulled commits as "<short hash> <subject>", newest
+	// first
+	Commits []string
+	// Pruned lists the remote-tracking branches removed because their
+	// branch was deleted on the remote, without the remote's name
+	Pruned []string
+}
+
+// SyncDefaultBranch fetches the upstream remote of the repository, pruning
+// remote-tracking branches deleted there, and fast-forwards the local
+// default branch to the remote's. Other branches and worktrees are never
+// touched: if the default branch is checked out in a worktree other than
+// the repository's own checkout, it is left alone and an error returned.
+// A default branch with local commits the remote lacks is not changed
+// either; the error wraps ErrDiverged.
+func (m *Manager) SyncDefaultBranch() (*SyncResult, error) {
+	remote, err := m.GetUpstreamRemote()
+	if err != nil {
+		return nil, err
+	}
+	result := &SyncResult{Remote: remote}
+
+	before, err := m.remoteBranches(remote)
+	if err != nil {
+		return nil, err
+	}
+	if output, err := m.git("fetch", "--prune", remote).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%w from %s: %w\nOutput: %s", ErrFetchFailed, remote, err, output)
+	}
+	after, err := m.remoteBranches(remote)
+	if err != nil {
+		return nil, err
+	}
+	remaining := make(map[string]bool, len(after))
+	for _, branch := range after {
+		remaining[branch] = true
+	}
+	for _, branch := range before {
+		if !remaining[branch] {
+			result.Pruned = append(result.Pruned, branch)
+		}
+	}
+
+	if result.Branch, err = m.GetDefaultBranch(remote); err != nil {
+		return nil, err
+	}
+	remoteRef := remote + "/" + result.Branch
+	if result.OldCommit, err = revParse(m.repoPath, "refs/heads/"+result.Branch); err != nil {
+		return nil, err
+	}
+	result.NewCommit = result.OldCommit
+	target, err := revParse(m.repoPath, "refs/remotes/"+remoteRef)
+	if err != nil {
+		return nil, err
+	}
+	if target == result.OldCommit || m.isAncestor(target, result.OldCommit) {
+		// Nothing to pull
+		return result, nil
+	}
+	if !m.isAncestor(result.OldCommit, target) {
+		ahead, behind := m.aheadBehind(result.OldCommit, target)
+		return result, fmt.Errorf("%w: %s has %d commit(s) that %s lacks, and is %d behind", ErrDiverged, result.Branch, ahead, remoteRef, behind)
+	}
+
+	output, err := m.git("log", "--format=%h %s", result.OldCommit+".."+target).Output()
+	if err != nil {
+		return result, fmt.Errorf("failed to list pulled commits: %w", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			result.Commits = append(result.Commits, line)
+		}
+	}
+
+	if err := m.fastForwardBranch(result.Branch, remoteRef, result.OldCommit, target); err != nil {
+		return result, err
+	}
+	result.NewCommit = target
+	return result, nil
+}
+
+// fastForwardBranch moves branch from old to target. If the branch is
+// checked out in the repository's own checkout its files are updated with
+// it; if it is checked out in another worktree it is left alone.
+func (m *Manager) fastForwardBranch(branch, remoteRef, old, target string) error {
+	current, _ := m.git("symbolic-ref", "--short", "-q", "HEAD").Output()
+	if strings.TrimSpace(string(current)) == branch {
+		if output, err := m.git("merge", "--ff-only", remoteRef).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to fast-forward %s: %w\nOutput: %s", branch, err, output)
+		}
+		return nil
+	}
+
+	worktrees, err := m.List()
+	if err != nil {
+		return err
+	}
+	for _, wt := range worktrees {
+		if wt.Branch == branch {
+			return fmt.Errorf("%s is checked out in worktree %s; not updating it", branch, wt.Path)
+		}
+	}
+	if output, err := m.git("update-ref", "refs/heads/"+branch, target, old).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to fast-forward %s: %w\nOutput: %s", branch, err, output)
+	}
+	return nil
+}
+
+// remoteBranches lists the remote-tracking branches of remote, without
+// the remote's name or its HEAD
+func (m *Manager) remoteBranches(remote string) ([]string, error) {
+	refs, err := m.ListRemoteBranchesWithPrefix(remote, "")
+	if err != nil {
+		return nil, err
+	}
+	branches := refs[:0]
+	for _, ref := range refs {
+		if ref != "HEAD" && ref != remote {
+			branches = append(branches, ref)
+		}
+	}
+	return branches, nil
+}
+
+// isAncestor reports whether commit is reachable from descendant
+func (m *Manager) isAncestor(commit, descendant string) bool {
+	return m.git("merge-base", "--is-ancestor", commit, descendant).Run() == nil
+}
+
+// aheadBehind counts the commits of local missing from remote and of
+// remote missing from local
+func (m *Manager) aheadBehind(local, remote string) (int, int) {
+	output, err := m.git("rev-list", "--left-right", "--count", local+"..."+remote).Output()
+	if err != nil {
+		return 0, 0
+	}
+	var ahead, behind int
+	fmt.Sscanf(strings.TrimSpace(string(output)), "%d %d", &ahead, &behind)
+	return ahead, behind
+}
@@ -0,0 +1,93 @@
+package worktree
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// createClone clones a new test repository, returning the upstream and the
+// clone
+func createClone(t *testing.T) (upstream, clone string) {
+	t.Helper()
+	upstream, cleanup := createTestRepo(t)
+	t.Cleanup(cleanup)
+	createBranch(t, upstream, "feature")
+
+	clone = filepath.Join(t.TempDir(), "clone")
+	runGit(t, upstream, "clone", "-q", upstream, clone)
+	runGit(t, clone, "config", "user.name", "Test User")
+	runGit(t, clone, "config", "user.email", "test@example.com")
+	return upstream, clone
+}
+
+func TestSyncDefaultBranch(t *testing.T) {
+	upstream, clone := createClone(t)
+	m := NewManager(clone)
+
+	result, err := m.SyncDefaultBranch()
+	if err != nil {
+		t.Fatalf("SyncDefaultBranch() failed: %v", err)
+	}
+	if result.OldCommit != result.NewCommit || len(result.Commits) != 0 || len(result.Pruned) != 0 {
+		t.Errorf("SyncDefaultBranch() of an up to date clone = %+v", result)
+	}
+
+	commitFile(t, upstream, "one.txt", "one", "Add one")
+	commitFile(t, upstream, "two.txt", "two", "Add two")
+	runGit(t, upstream, "branch", "-D", "feature")
+
+	result, err = m.SyncDefaultBranch()
+	if err != nil {
+		t.Fatalf("SyncDefaultBranch() failed: %v", err)
+	}
+	if result.Remote != "origin" || result.Branch != "main" {
+		t.Errorf("SyncDefaultBranch() synced %s/%s, want origin/main", result.Remote, result.Branch)
+	}
+	if len(result.Commits) != 2 {
+		t.Errorf("Commits = %v, want 2", result.Commits)
+	}
+	if !reflect.DeepEqual(result.Pruned, []string{"feature"}) {
+		t.Errorf("Pruned = %v, want [feature]", result.Pruned)
+	}
+	upstreamHead, _ := ResolveCommit(upstream, "main")
+	if head, _ := ResolveCommit(clone, "HEAD"); head != upstreamHead || result.NewCommit != upstreamHead {
+		t.Errorf("clone is at %s (result %s), want %s", head, result.NewCommit, upstreamHead)
+	}
+	if _, err := m.SyncDefaultBranch(); err != nil {
+		t.Errorf("second SyncDefaultBranch() failed: %v", err)
+	}
+}
+
+func TestSyncDefaultBranchNotCheckedOut(t *testing.T) {
+	upstream, clone := createClone(t)
+	runGit(t, clone, "checkout", "-q", "-b", "local")
+	commitFile(t, upstream, "one.txt", "one", "Add one")
+
+	result, err := NewManager(clone).SyncDefaultBranch()
+	if err != nil {
+		t.Fatalf("SyncDefaultBranch() failed: %v", err)
+	}
+	if main, _ := ResolveCommit(clone, "main"); main != result.NewCommit || len(result.Commits) != 1 {
+		t.Errorf("main is at %s, result %+v", main, result)
+	}
+	if branch, _ := GetCurrentBranch(clone); branch != "local" {
+		t.Errorf("checked out branch = %s, want local", branch)
+	}
+}
+
+func TestSyncDefaultBranchDiverged(t *testing.T) {
+	upstream, clone := createClone(t)
+	commitFile(t, upstream, "one.txt", "one", "Add one")
+	commitFile(t, clone, "local.txt", "local", "Local change")
+	before, _ := ResolveCommit(clone, "main")
+
+	_, err := NewManager(clone).SyncDefaultBranch()
+	if !errors.Is(err, ErrDiverged) {
+		t.Fatalf("SyncDefaultBranch() error = %v, want ErrDiverged", err)
+	}
+	if after, _ := ResolveCommit(clone, "main"); after != before {
+		t.Error("a diverged branch should not be changed")
+	}
+}
@@ -0,0 +1,160 @@
+package worktree
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HeadState is what a worktree has checked out
+type HeadState struct {
+	Branch string // short branch name; "" when HEAD is detached
+	Commit string // commit HEAD is at; "" on a branch with no commits yet
+}
+
+// ReadHeadState reads the HEAD of the worktree (or repository) at path
+// straight from its git directory, without running git, so that it is
+// cheap enough to poll
+func ReadHeadState(path string) (HeadState, error) {
+	gitDir, err := resolveGitDir(path)
+	if err != nil {
+		return HeadState{}, err
+	}
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return HeadState{}, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	head := strings.TrimSpace(string(data))
+
+	ref, isRef := strings.CutPrefix(head, "ref: ")
+	if !isRef {
+		return HeadState{Commit: head}, nil
+	}
+	state := HeadState{Branch: strings.TrimPrefix(ref, "refs/heads/")}
+	state.Commit = readRef(commonGitDir(gitDir), ref)
+	return state, nil
+}
+
+// resolveGitDir returns the git directory of the worktree at path: .git
+// itself in a main checkout, or the directory a linked worktree's .git file
+// points to
+func resolveGitDir(path string) (string, error) {
+	dotGit := filepath.Join(path, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return "", fmt.Errorf("not a git worktree: %w", err)
+	}
+	if info.IsDir() {
+		return dotGit, nil
+	}
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", fmt.Errorf("failed to read .git file: %w", err)
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok {
+		return "", fmt.Errorf("unrecognized .git file in %s", path)
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(path, gitDir)
+	}
+	return gitDir, nil
+}
+
+// commonGitDir returns the git directory shared by all worktrees of the
+// repository gitDir belongs to, where branches are stored
+func commonGitDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	common := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitDir, common)
+	}
+	return common
+}
+
+// readRef returns the commit ref points to in the git directory commonDir,
+// looking in packed-refs if it is not a loose ref, or "" if it has none
+func readRef(commonDir, ref string) string {
+	if data, err := os.ReadFile(filepath.Join(commonDir, filepath.FromSlash(ref))); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	f, err := os.Open(filepath.Join(commonDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if commit, name, ok := strings.Cut(scanner.Text(), " "); ok && name == ref {
+			return commit
+		}
+	}
+	return ""
+}
+
+// Kinds of WatchEvent
+const (
+	// WatchEventAdded is a worktree seen for the first time
+	WatchEventAdded = "added"
+	// WatchEventBranch is a worktree that has another branch checked out,
+	// or a detached HEAD
+	WatchEventBranch = "branch"
+	// WatchEventCommit is a worktree whose HEAD moved to another commit on
+	// the same branch (or while detached), e.g. a commit, reset or pull
+	WatchEventCommit = "commit"
+)
+
+// WatchEvent is a change a HeadWatcher saw in a worktree
+type WatchEvent struct {
+	Path string
+	Kind string
+	Old  HeadState
+	New  HeadState
+}
+
+// HeadWatcher notices worktrees whose HEAD changes, including changes made
+// by running git by hand, by polling their git directories
+type HeadWatcher struct {
+	seen map[string]HeadState
+}
+
+// NewHeadWatcher creates a HeadWatcher that has seen no worktrees yet
+func NewHeadWatcher() *HeadWatcher {
+	return &HeadWatcher{seen: make(map[string]HeadState)}
+}
+
+// Poll reads the HEAD of each worktree in paths and returns what changed
+// since the last Poll. A worktree that cannot be read is skipped and
+// compared with what was last read once it can be; worktrees left out of
+// paths are forgotten.
+func (w *HeadWatcher) Poll(paths []string) []WatchEvent {
+	var events []WatchEvent
+	current := make(map[string]HeadState, len(paths))
+	for _, path := range paths {
+		old, known := w.seen[path]
+		state, err := ReadHeadState(path)
+		if err != nil {
+			if known {
+				current[path] = old
+			}
+			continue
+		}
+		current[path] = state
+
+		switch {
+		case !known:
+			events = append(events, WatchEvent{Path: path, Kind: WatchEventAdded, New: state})
+		case state.Branch != old.Branch:
+			events = append(events, WatchEvent{Path: path, Kind: WatchEventBranch, Old: old, New: state})
+		case state.Commit != old.Commit:
+			events = append(events, WatchEvent{Path: path, Kind: WatchEventCommit, Old: old, New: state})
+		}
+	}
+	w.seen = current
+	return events
+}
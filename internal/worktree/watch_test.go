@@ -0,0 +1,79 @@
+package worktree
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadHeadState(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	state, err := ReadHeadState(repoPath)
+	if err != nil {
+		t.Fatalf("ReadHeadState() error = %v", err)
+	}
+	if state.Branch != "main" || state.Commit != strings.TrimSpace(gitOutput(t, repoPath, "rev-parse", "HEAD")) {
+		t.Errorf("ReadHeadState() of the main checkout = %+v", state)
+	}
+
+	// A linked worktree, with its branch packed so it is not a loose ref
+	wtPath := filepath.Join(t.TempDir(), "wt")
+	runGit(t, repoPath, "worktree", "add", "-b", "work/fox", wtPath)
+	runGit(t, repoPath, "pack-refs", "--all")
+	state, err = ReadHeadState(wtPath)
+	if err != nil {
+		t.Fatalf("ReadHeadState() of a worktree error = %v", err)
+	}
+	if state.Branch != "work/fox" || state.Commit != strings.TrimSpace(gitOutput(t, wtPath, "rev-parse", "HEAD")) {
+		t.Errorf("ReadHeadState() of the worktree = %+v", state)
+	}
+
+	runGit(t, wtPath, "checkout", "--detach")
+	state, err = ReadHeadState(wtPath)
+	if err != nil || state.Branch != "" || state.Commit == "" {
+		t.Errorf("ReadHeadState() of a detached HEAD = %+v, %v", state, err)
+	}
+
+	if _, err := ReadHeadState(t.TempDir()); err == nil {
+		t.Error("ReadHeadState() of a plain directory should fail")
+	}
+}
+
+func TestHeadWatcher(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+	wtPath := filepath.Join(t.TempDir(), "wt")
+	runGit(t, repoPath, "worktree", "add", "-b", "work/fox", wtPath)
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	w := NewHeadWatcher()
+	paths := []string{wtPath, missing}
+	events := w.Poll(paths)
+	if len(events) != 1 || events[0].Kind != WatchEventAdded || events[0].New.Branch != "work/fox" {
+		t.Fatalf("first Poll() = %+v, want the worktree added", events)
+	}
+	if events := w.Poll(paths); len(events) != 0 {
+		t.Errorf("Poll() with nothing changed = %+v", events)
+	}
+
+	runGit(t, wtPath, "commit", "--allow-empty", "-m", "By hand")
+	events = w.Poll(paths)
+	if len(events) != 1 || events[0].Kind != WatchEventCommit || events[0].Old.Commit == events[0].New.Commit {
+		t.Errorf("Poll() after a commit = %+v", events)
+	}
+
+	runGit(t, wtPath, "checkout", "-b", "experiment")
+	events = w.Poll(paths)
+	if len(events) != 1 || events[0].Kind != WatchEventBranch || events[0].Old.Branch != "work/fox" || events[0].New.Branch != "experiment" {
+		t.Errorf("Poll() after checking out a branch = %+v", events)
+	}
+
+	// A worktree dropped from the paths is forgotten, and added again
+	// when it is back
+	w.Poll(nil)
+	if events := w.Poll(paths); len(events) != 1 || events[0].Kind != WatchEventAdded {
+		t.Errorf("Poll() of a forgotten worktree = %+v", events)
+	}
+}
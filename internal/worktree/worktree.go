@@ -1,27 +1,107 @@
 package worktree
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// gitEnvOverrides are environment variables that pin git to a particular
+// repository regardless of the working directory. They are dropped from the
+// environment so each command finds the repository from the directory it
+// runs in.
+var gitEnvOverrides = []string{"GIT_DIR", "GIT_WORK_TREE", "GIT_COMMON_DIR", "GIT_INDEX_FILE"}
+
+// gitCommand returns a git command that runs in dir
+func gitCommand(dir string, args ...string) *exec.Cmd {
+	return gitCommandContext(context.Background(), dir, args...)
+}
+
+// gitCommandContext returns a git command that runs in dir and is killed
+// when ctx is done
+func gitCommandContext(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if !slices.Contains(gitEnvOverrides, name) {
+			env = append(env, kv)
+		}
+	}
+	cmd.Env = env
+	return cmd
+}
+
+// GitCommonDir returns the absolute path of the git directory shared by all
+// worktrees of the repository at path. This works whether path is a regular
+// clone, a linked worktree, a bare repository, or a checkout whose .git is a
+// file pointing at a git directory elsewhere.
+func GitCommonDir(path string) (string, error) {
+	return revParseDir(path, "--git-common-dir")
+}
+
+// GitDir returns the absolute path of the git directory for the worktree at
+// path. For linked worktrees this is the per-worktree directory holding
+// HEAD and any in-progress rebase or merge state.
+func GitDir(path string) (string, error) {
+	return revParseDir(path, "--git-dir")
+}
+
+// revParseDir runs git rev-parse with a directory-valued option and makes the
+// result absolute (git reports it relative to the working directory)
+func revParseDir(path, option string) (string, error) {
+	cmd := gitCommand(path, "rev-parse", option)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve git directory: %w", err)
+	}
+
+	dir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(path, dir)
+	}
+	return filepath.Abs(dir)
+}
+
 // Manager handles git worktree operations
 type Manager struct {
 	repoPath string
+	gitDir   string
 }
 
-// NewManager creates a new worktree manager for a repository
+// NewManager creates a new worktree manager for a repository. repoPath may be
+// any checkout of the repository (or the bare repository itself); operations
+// run against the shared git directory resolved from it.
 func NewManager(repoPath string) *Manager {
-	return &Manager{repoPath: repoPath}
+	m := &Manager{repoPath: repoPath}
+	if gitDir, err := GitCommonDir(repoPath); err == nil {
+		m.gitDir = gitDir
+	}
+	return m
+}
+
+// git returns a git command for a repository-level operation. When the git
+// directory could not be resolved, git discovers it from repoPath instead.
+func (m *Manager) git(args ...string) *exec.Cmd {
+	if m.gitDir != "" {
+		args = append([]string{"--git-dir=" + m.gitDir}, args...)
+	}
+	return gitCommand(m.repoPath, args...)
 }
 
 // Create creates a new git worktree
 func (m *Manager) Create(path, branch string) error {
-	cmd := exec.Command("git", "worktree", "add", path, branch)
-	cmd.Dir = m.repoPath
+	cmd := m.git("worktree", "add", path, branch)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to create worktree: %w\nOutput: %s", err, output)
 	}
@@ -30,14 +110,44 @@ func (m *Manager) Create(path, branch string) error {
 
 // CreateNewBranch creates a new worktree with a new branch
 func (m *Manager) CreateNewBranch(path, newBranch, startPoint string) error {
-	cmd := exec.Command("git", "worktree", "add", "-b", newBranch, path, startPoint)
-	cmd.Dir = m.repoPath
+	cmd := m.git("worktree", "add", "-b", newBranch, path, startPoint)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to create worktree with new branch: %w\nOutput: %s", err, output)
 	}
 	return nil
 }
 
+// HasSubmodules reports whether the checkout at path declares git submodules
+func HasSubmodules(path string) bool {
+	info, err := os.Stat(filepath.Join(path, ".gitmodules"))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// UpdateSubmodules checks out the submodules of the worktree at path,
+// recursively, writing git's progress to out. Git is not allowed to prompt
+// for credentials, so private submodules without configured access fail
+// instead of hanging until ctx is done.
+func UpdateSubmodules(ctx context.Context, path string, out io.Writer) error {
+	cmd := gitCommandContext(ctx, path, "submodule", "update", "--init", "--recursive")
+	cmd.Env = append(cmd.Env, "GIT_TERMINAL_PROMPT=0")
+
+	// Keep a copy of the output for the error
+	var output bytes.Buffer
+	w := io.Writer(&output)
+	if out != nil {
+		w = io.MultiWriter(out, &output)
+	}
+	cmd.Stdout, cmd.Stderr = w, w
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("submodule update timed out: %w\nOutput: %s", ctx.Err(), output.String())
+		}
+		return fmt.Errorf("submodule update failed: %w\nOutput: %s", err, output.String())
+	}
+	return nil
+}
+
 // Remove removes a git worktree
 func (m *Manager) Remove(path string, force bool) error {
 	args := []string{"worktree", "remove", path}
@@ -45,8 +155,7 @@ func (m *Manager) Remove(path string, force bool) error {
 		args = append(args, "--force")
 	}
 
-	cmd := exec.Command("git", args...)
-	cmd.Dir = m.repoPath
+	cmd := m.git(args...)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to remove worktree: %w\nOutput: %s", err, output)
 	}
@@ -55,44 +164,94 @@ func (m *Manager) Remove(path string, force bool) error {
 
 // List returns a list of all worktrees
 func (m *Manager) List() ([]WorktreeInfo, error) {
-	cmd := exec.Command("git", "worktree", "list", "--porcelain")
-	cmd.Dir = m.repoPath
+	cmd := m.git("worktree", "list", "--porcelain")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list worktrees: %w", err)
 	}
 
-	return parseWorktreeList(string(output)), nil
+	// A bare repository is listed first but has no checkout
+	var worktrees []WorktreeInfo
+	for i, wt := range parseWorktreeList(string(output)) {
+		if wt.Bare {
+			continue
+		}
+		// When the git dir is separate from the main checkout, git reports
+		// the git dir itself as the main worktree's path
+		if i == 0 && m.gitDir != "" && samePath(wt.Path, m.gitDir) {
+			if top, err := m.mainCheckout(); err == nil {
+				wt.Path = top
+			}
+		}
+		worktrees = append(worktrees, wt)
+	}
+	return worktrees, nil
 }
 
-// Exists checks if a worktree exists at the given path
-func (m *Manager) Exists(path string) (bool, error) {
-	worktrees, err := m.List()
+// mainCheckout finds the main worktree of a repository whose git dir lives
+// outside it: core.worktree if set, otherwise the checkout containing
+// repoPath, provided that is the main worktree rather than a linked one
+func (m *Manager) mainCheckout() (string, error) {
+	cmd := m.git("config", "--get", "core.worktree")
+	if output, err := cmd.Output(); err == nil {
+		dir := strings.TrimSpace(string(output))
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(m.gitDir, dir)
+		}
+		return filepath.Clean(dir), nil
+	}
+
+	cmd = gitCommand(m.repoPath, "rev-parse", "--show-toplevel")
+	output, err := cmd.Output()
 	if err != nil {
-		return false, err
+		return "", fmt.Errorf("failed to find main worktree: %w", err)
 	}
+	top := strings.TrimSpace(string(output))
 
+	gitDir, err := GitDir(top)
+	if err != nil {
+		return "", err
+	}
+	if !samePath(gitDir, m.gitDir) {
+		return "", fmt.Errorf("%s is a linked worktree", top)
+	}
+	return top, nil
+}
+
+// samePath reports whether two paths refer to the same location, resolving
+// symlinks where possible (important on macOS)
+func samePath(a, b string) bool {
+	return resolvePath(a) == resolvePath(b)
+}
+
+// resolvePath returns the absolute, symlink-free form of a path so paths
+// from git and from the filesystem compare equal. If the path does not
+// exist, its deepest existing parent is resolved instead, so a missing
+// worktree under a symlinked directory still matches its resolved location.
+func resolvePath(path string) string {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return false, err
+		return path
+	}
+	if evalPath, err := filepath.EvalSymlinks(absPath); err == nil {
+		return evalPath
 	}
-	// Resolve symlinks for accurate comparison (important on macOS)
-	evalPath, err := filepath.EvalSymlinks(absPath)
+	parent := filepath.Dir(absPath)
+	if parent == absPath {
+		return absPath
+	}
+	return filepath.Join(resolvePath(parent), filepath.Base(absPath))
+}
+
+// Exists checks if a worktree exists at the given path
+func (m *Manager) Exists(path string) (bool, error) {
+	worktrees, err := m.List()
 	if err != nil {
-		// Path might not exist yet, use absPath
-		evalPath = absPath
+		return false, err
 	}
 
 	for _, wt := range worktrees {
-		wtAbs, err := filepath.Abs(wt.Path)
-		if err != nil {
-			continue
-		}
-		wtEval, err := filepath.EvalSymlinks(wtAbs)
-		if err != nil {
-			wtEval = wtAbs
-		}
-		if wtEval == evalPath {
+		if samePath(wt.Path, path) {
 			return true, nil
 		}
 	}
@@ -102,8 +261,7 @@ func (m *Manager) Exists(path string) (bool, error) {
 
 // Prune removes worktree information for missing paths
 func (m *Manager) Prune() error {
-	cmd := exec.Command("git", "worktree", "prune")
-	cmd.Dir = m.repoPath
+	cmd := m.git("worktree", "prune")
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to prune worktrees: %w\nOutput: %s", err, output)
 	}
@@ -112,8 +270,7 @@ func (m *Manager) Prune() error {
 
 // HasUncommittedChanges checks if a worktree has uncommitted changes
 func HasUncommittedChanges(path string) (bool, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
-	cmd.Dir = path
+	cmd := gitCommand(path, "status", "--porcelain")
 	output, err := cmd.Output()
 	if err != nil {
 		return false, fmt.Errorf("failed to check git status: %w", err)
@@ -125,16 +282,14 @@ func HasUncommittedChanges(path string) (bool, error) {
 // HasUnpushedCommits checks if a worktree has unpushed commits
 func HasUnpushedCommits(path string) (bool, error) {
 	// First check if there's a tracking branch
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
-	cmd.Dir = path
+	cmd := gitCommand(path, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
 	if err := cmd.Run(); err != nil {
 		// No tracking branch, so no unpushed commits
 		return false, nil
 	}
 
 	// Check for commits ahead of upstream
-	cmd = exec.Command("git", "rev-list", "--count", "@{u}..")
-	cmd.Dir = path
+	cmd = gitCommand(path, "rev-list", "--count", "@{u}..")
 	output, err := cmd.Output()
 	if err != nil {
 		return false, fmt.Errorf("failed to check unpushed commits: %w", err)
@@ -146,8 +301,7 @@ func HasUnpushedCommits(path string) (bool, error) {
 
 // GetCurrentBranch returns the current branch name for a worktree
 func GetCurrentBranch(path string) (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	cmd.Dir = path
+	cmd := gitCommand(path, "rev-parse", "--abbrev-ref", "HEAD")
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get current branch: %w", err)
@@ -156,11 +310,78 @@ func GetCurrentBranch(path string) (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// CommitsAhead returns the number of commits on HEAD that are not reachable from base
+func CommitsAhead(path, base string) (int, error) {
+	cmd := gitCommand(path, "rev-list", "--count", base+"..HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count commits ahead of %s: %w", base, err)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse commit count: %w", err)
+	}
+	return count, nil
+}
+
+// LastCommitMessage returns the subject and body of the HEAD commit
+func LastCommitMessage(path string) (subject, body string, err error) {
+	cmd := gitCommand(path, "log", "-1", "--format=%s%n%b")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read last commit message: %w", err)
+	}
+
+	parts := strings.SplitN(string(output), "\n", 2)
+	subject = strings.TrimSpace(parts[0])
+	if len(parts) > 1 {
+		body = strings.TrimSpace(parts[1])
+	}
+	return subject, body, nil
+}
+
+// PushBranch pushes a branch to a remote and sets it as the upstream
+func PushBranch(path, remote, branch string) error {
+	cmd := gitCommand(path, "push", "-u", remote, branch)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to push %s to %s: %w\nOutput: %s", branch, remote, err, output)
+	}
+	return nil
+}
+
+// SetUpstream makes the branch checked out in a worktree track
+// <remote>/<branch>, which must have been fetched
+func SetUpstream(path, remote, branch string) error {
+	cmd := gitCommand(path, "branch", "--set-upstream-to="+remote+"/"+branch)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set upstream to %s/%s: %w\nOutput: %s", remote, branch, err, output)
+	}
+	return nil
+}
+
+// GetUpstream returns the remote-tracking branch ("origin/main") the branch
+// checked out in a worktree tracks, or "" if it tracks none
+func GetUpstream(path string) (string, error) {
+	cmd := gitCommand(path, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	output, err := cmd.Output()
+	if err != nil {
+		// git fails the same way for a branch without an upstream and a
+		// detached HEAD; only a worktree git cannot read is an error
+		if _, dirErr := GitDir(path); dirErr != nil {
+			return "", fmt.Errorf("failed to get upstream: %w", dirErr)
+		}
+		return "", nil
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 // WorktreeInfo contains information about a worktree
 type WorktreeInfo struct {
 	Path   string
 	Commit string
 	Branch string
+	Bare   bool
 }
 
 // parseWorktreeList parses the output of `git worktree list --porcelain`
@@ -170,7 +391,8 @@ func parseWorktreeList(output string) []WorktreeInfo {
 
 	lines := strings.Split(output, "\n")
 	for _, line := range lines {
-		line = strings.TrimSpace(line)
+		// Only strip line endings: worktree paths may contain spaces
+		line = strings.TrimRight(line, "\r")
 		if line == "" {
 			if current.Path != "" {
 				worktrees = append(worktrees, current)
@@ -179,6 +401,11 @@ func parseWorktreeList(output string) []WorktreeInfo {
 			continue
 		}
 
+		if line == "bare" {
+			current.Bare = true
+			continue
+		}
+
 		parts := strings.SplitN(line, " ", 2)
 		if len(parts) != 2 {
 			continue
@@ -204,8 +431,7 @@ func parseWorktreeList(output string) []WorktreeInfo {
 
 // BranchExists checks if a branch exists in the repository
 func (m *Manager) BranchExists(branchName string) (bool, error) {
-	cmd := exec.Command("git", "show-ref", "--verify", "--quiet", "refs/heads/"+branchName)
-	cmd.Dir = m.repoPath
+	cmd := m.git("show-ref", "--verify", "--quiet", "refs/heads/"+branchName)
 	err := cmd.Run()
 	if err != nil {
 		// Exit code 1 means branch doesn't exist
@@ -219,8 +445,7 @@ func (m *Manager) BranchExists(branchName string) (bool, error) {
 
 // RenameBranch renames a branch from oldName to newName
 func (m *Manager) RenameBranch(oldName, newName string) error {
-	cmd := exec.Command("git", "branch", "-m", oldName, newName)
-	cmd.Dir = m.repoPath
+	cmd := m.git("branch", "-m", oldName, newName)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to rename branch: %w\nOutput: %s", err, output)
 	}
@@ -229,8 +454,7 @@ func (m *Manager) RenameBranch(oldName, newName string) error {
 
 // DeleteBranch force deletes a branch (git branch -D)
 func (m *Manager) DeleteBranch(branchName string) error {
-	cmd := exec.Command("git", "branch", "-D", branchName)
-	cmd.Dir = m.repoPath
+	cmd := m.git("branch", "-D", branchName)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to delete branch: %w\nOutput: %s", err, output)
 	}
@@ -239,8 +463,7 @@ func (m *Manager) DeleteBranch(branchName string) error {
 
 // ListBranchesWithPrefix lists all branches that start with the given prefix
 func (m *Manager) ListBranchesWithPrefix(prefix string) ([]string, error) {
-	cmd := exec.Command("git", "for-each-ref", "--format=%(refname:short)", "refs/heads/"+prefix)
-	cmd.Dir = m.repoPath
+	cmd := m.git("for-each-ref", "--format=%(refname:short)", "refs/heads/"+prefix)
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list branches: %w", err)
@@ -255,6 +478,109 @@ func (m *Manager) ListBranchesWithPrefix(prefix string) ([]string, error) {
 	return branches, nil
 }
 
+// ListRemoteBranchesWithPrefix lists the remote-tracking branches of remote
+// whose names start with prefix. Names are returned without the remote, e.g.
+// "work/fox" for origin/work/fox.
+func (m *Manager) ListRemoteBranchesWithPrefix(remote, prefix string) ([]string, error) {
+	return m.listRefs("refs/remotes/"+remote+"/"+prefix, remote+"/")
+}
+
+// ListMergedBranches lists the local branches starting with prefix whose
+// commits are all reachable from into
+func (m *Manager) ListMergedBranches(into, prefix string) ([]string, error) {
+	return m.listRefs("refs/heads/"+prefix, "", "--merged", into)
+}
+
+// ListMergedRemoteBranches lists the remote-tracking branches of remote
+// starting with prefix whose commits are all reachable from into, like
+// git branch -r --merged. Names are returned without the remote.
+func (m *Manager) ListMergedRemoteBranches(remote, into, prefix string) ([]string, error) {
+	return m.listRefs("refs/remotes/"+remote+"/"+prefix, remote+"/", "--merged", into)
+}
+
+// listRefs lists the short names of the refs matching pattern, with trim
+// removed from the front of each
+func (m *Manager) listRefs(pattern, trim string, options ...string) ([]string, error) {
+	args := append([]string{"for-each-ref", "--format=%(refname:short)"}, options...)
+	output, err := m.git(append(args, pattern)...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w\nOutput: %s", err, output)
+	}
+
+	var branches []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			branches = append(branches, strings.TrimPrefix(line, trim))
+		}
+	}
+	return branches, nil
+}
+
+// LastCommitTime returns the committer date of the commit ref points to
+func (m *Manager) LastCommitTime(ref string) (time.Time, error) {
+	output, err := m.git("log", "-1", "--format=%ct", ref, "--").CombinedOutput()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read last commit of %s: %w\nOutput: %s", ref, err, output)
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse commit date of %s: %w", ref, err)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// SafeDeleteBranch deletes a branch only if it is merged (git branch -d)
+func (m *Manager) SafeDeleteBranch(branchName string) error {
+	cmd := m.git("branch", "-d", branchName)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to delete branch: %w\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+// CreateBundle writes branch and all of its history to a git bundle at path,
+// from which it can be fetched again even once the branch is deleted
+func (m *Manager) CreateBundle(branchName, path string) error {
+	cmd := m.git("bundle", "create", path, "refs/heads/"+branchName)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create bundle of %s: %w\nOutput: %s", branchName, err, output)
+	}
+	return nil
+}
+
+// BundleHeads lists the refs stored in the bundle at path, e.g.
+// "refs/heads/work/fox"
+func (m *Manager) BundleHeads(path string) ([]string, error) {
+	output, err := m.git("bundle", "list-heads", path).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle %s: %w\nOutput: %s", path, err, output)
+	}
+
+	var refs []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		// Each line is "<sha> <ref>"
+		if _, ref, ok := strings.Cut(line, " "); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// FetchBundle creates branch from ref in the bundle at path. It fails if
+// the branch already exists.
+func (m *Manager) FetchBundle(path, ref, branchName string) error {
+	if exists, err := m.BranchExists(branchName); err != nil {
+		return err
+	} else if exists {
+		return fmt.Errorf("branch %s already exists", branchName)
+	}
+	cmd := m.git("fetch", path, ref+":refs/heads/"+branchName)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to fetch %s from bundle: %w\nOutput: %s", ref, err, output)
+	}
+	return nil
+}
+
 // FindOrphanedBranches finds branches with the given prefix that don't have corresponding worktrees
 func (m *Manager) FindOrphanedBranches(prefix string) ([]string, error) {
 	// Get all branches with the prefix
@@ -371,15 +697,13 @@ To fix this, you can either:
 // It prefers "upstream" if it exists, otherwise falls back to "origin"
 func (m *Manager) GetUpstreamRemote() (string, error) {
 	// Check if "upstream" remote exists
-	cmd := exec.Command("git", "remote", "get-url", "upstream")
-	cmd.Dir = m.repoPath
+	cmd := m.git("remote", "get-url", "upstream")
 	if err := cmd.Run(); err == nil {
 		return "upstream", nil
 	}
 
 	// Fall back to "origin"
-	cmd = exec.Command("git", "remote", "get-url", "origin")
-	cmd.Dir = m.repoPath
+	cmd = m.git("remote", "get-url", "origin")
 	if err := cmd.Run(); err == nil {
 		return "origin", nil
 	}
@@ -390,8 +714,7 @@ func (m *Manager) GetUpstreamRemote() (string, error) {
 // GetDefaultBranch returns the default branch name for a remote (e.g., "main" or "master")
 func (m *Manager) GetDefaultBranch(remote string) (string, error) {
 	// Try to get the default branch from the remote's HEAD
-	cmd := exec.Command("git", "symbolic-ref", fmt.Sprintf("refs/remotes/%s/HEAD", remote))
-	cmd.Dir = m.repoPath
+	cmd := m.git("symbolic-ref", fmt.Sprintf("refs/remotes/%s/HEAD", remote))
 	output, err := cmd.Output()
 	if err == nil {
 		// Output is like "refs/remotes/origin/main" - extract the branch name
@@ -404,8 +727,7 @@ func (m *Manager) GetDefaultBranch(remote string) (string, error) {
 
 	// Fallback: check for common branch names
 	for _, branch := range []string{"main", "master"} {
-		cmd := exec.Command("git", "rev-parse", "--verify", fmt.Sprintf("refs/remotes/%s/%s", remote, branch))
-		cmd.Dir = m.repoPath
+		cmd := m.git("rev-parse", "--verify", fmt.Sprintf("refs/remotes/%s/%s", remote, branch))
 		if err := cmd.Run(); err == nil {
 			return branch, nil
 		}
@@ -414,10 +736,16 @@ func (m *Manager) GetDefaultBranch(remote string) (string, error) {
 	return "", fmt.Errorf("could not determine default branch for remote %s", remote)
 }
 
+// IsDirAt reports whether path, relative to the repository root, is a
+// directory in the tree of ref
+func IsDirAt(repoPath, ref, path string) bool {
+	output, err := gitCommand(repoPath, "cat-file", "-t", ref+":"+path).Output()
+	return err == nil && strings.TrimSpace(string(output)) == "tree"
+}
+
 // FetchRemote fetches updates from a remote
 func (m *Manager) FetchRemote(remote string) error {
-	cmd := exec.Command("git", "fetch", remote)
-	cmd.Dir = m.repoPath
+	cmd := m.git("fetch", remote)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to fetch from %s: %w\nOutput: %s", remote, err, output)
 	}
@@ -448,8 +776,7 @@ func (m *Manager) FindMergedUpstreamBranches(branchPrefix string) ([]string, err
 
 	// Get branches merged into upstream's default branch
 	upstreamRef := fmt.Sprintf("%s/%s", remote, defaultBranch)
-	cmd := exec.Command("git", "branch", "--merged", upstreamRef, "--format=%(refname:short)")
-	cmd.Dir = m.repoPath
+	cmd := m.git("branch", "--merged", upstreamRef, "--format=%(refname:short)")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list merged branches: %w", err)
@@ -478,8 +805,7 @@ func (m *Manager) FindMergedUpstreamBranches(branchPrefix string) ([]string, err
 
 // DeleteRemoteBranch deletes a branch from a remote
 func (m *Manager) DeleteRemoteBranch(remote, branchName string) error {
-	cmd := exec.Command("git", "push", remote, "--delete", branchName)
-	cmd.Dir = m.repoPath
+	cmd := m.git("push", remote, "--delete", branchName)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to delete remote branch: %w\nOutput: %s", err, output)
 	}
@@ -537,8 +863,12 @@ func (m *Manager) CleanupMergedBranches(branchPrefix string, deleteRemote bool)
 	return deleted, nil
 }
 
-// CleanupOrphaned removes worktree directories that exist on disk but not in git
-func CleanupOrphaned(wtRootDir string, manager *Manager) ([]string, error) {
+// FindOrphaned returns worktree directories that exist on disk but not in
+// git. Paths on both sides are symlink-resolved before comparing, so a
+// worktrees directory that is itself a symlink (e.g. onto another disk)
+// does not make every worktree look orphaned. Symlinks inside wtRootDir are
+// never reported.
+func FindOrphaned(wtRootDir string, manager *Manager) ([]string, error) {
 	// Get all worktrees from git
 	gitWorktrees, err := manager.List()
 	if err != nil {
@@ -547,24 +877,15 @@ func CleanupOrphaned(wtRootDir string, manager *Manager) ([]string, error) {
 
 	gitPaths := make(map[string]bool)
 	for _, wt := range gitWorktrees {
-		absPath, err := filepath.Abs(wt.Path)
-		if err != nil {
-			continue
-		}
-		// Resolve symlinks for accurate comparison (important on macOS)
-		evalPath, err := filepath.EvalSymlinks(absPath)
-		if err != nil {
-			evalPath = absPath
-		}
-		gitPaths[evalPath] = true
+		gitPaths[resolvePath(wt.Path)] = true
 	}
 
 	// Find directories in wtRootDir that aren't in git worktrees
-	var removed []string
+	var orphaned []string
 	entries, err := os.ReadDir(wtRootDir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return removed, nil
+			return orphaned, nil
 		}
 		return nil, err
 	}
@@ -573,23 +894,26 @@ func CleanupOrphaned(wtRootDir string, manager *Manager) ([]string, error) {
 		if !entry.IsDir() {
 			continue
 		}
-
 		path := filepath.Join(wtRootDir, entry.Name())
-		absPath, err := filepath.Abs(path)
-		if err != nil {
-			continue
-		}
-		// Resolve symlinks for accurate comparison (important on macOS)
-		evalPath, err := filepath.EvalSymlinks(absPath)
-		if err != nil {
-			evalPath = absPath
+		if !gitPaths[resolvePath(path)] {
+			orphaned = append(orphaned, path)
 		}
+	}
 
-		if !gitPaths[evalPath] {
-			// This is an orphaned directory
-			if err := os.RemoveAll(path); err == nil {
-				removed = append(removed, path)
-			}
+	return orphaned, nil
+}
+
+// CleanupOrphaned removes worktree directories that exist on disk but not in git
+func CleanupOrphaned(wtRootDir string, manager *Manager) ([]string, error) {
+	orphaned, err := FindOrphaned(wtRootDir, manager)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, path := range orphaned {
+		if err := os.RemoveAll(path); err == nil {
+			removed = append(removed, path)
 		}
 	}
 
@@ -618,13 +942,11 @@ func GetWorktreeState(worktreePath string, remote string, mainBranch string) (Wo
 	}
 
 	// Get current branch (or detect detached HEAD)
-	cmd := exec.Command("git", "symbolic-ref", "--short", "HEAD")
-	cmd.Dir = worktreePath
+	cmd := gitCommand(worktreePath, "symbolic-ref", "--short", "HEAD")
 	output, err := cmd.Output()
 	if err != nil {
 		// Check if it's detached HEAD (different error than not being a git repo)
-		cmd2 := exec.Command("git", "rev-parse", "--verify", "HEAD")
-		cmd2.Dir = worktreePath
+		cmd2 := gitCommand(worktreePath, "rev-parse", "--verify", "HEAD")
 		if err2 := cmd2.Run(); err2 != nil {
 			return state, fmt.Errorf("not a git repository or invalid state: %w", err)
 		}
@@ -635,11 +957,11 @@ func GetWorktreeState(worktreePath string, remote string, mainBranch string) (Wo
 		state.Branch = strings.TrimSpace(string(output))
 	}
 
-	// Check for mid-rebase state
-	gitDir := filepath.Join(worktreePath, ".git")
-	// For worktrees, .git is a file pointing to the real git dir
-	if content, err := os.ReadFile(gitDir); err == nil && strings.HasPrefix(string(content), "gitdir:") {
-		gitDir = strings.TrimSpace(strings.TrimPrefix(string(content), "gitdir:"))
+	// Check for mid-rebase state. Rebase and merge state lives in the
+	// per-worktree git dir, which for linked worktrees is not <path>/.git
+	gitDir, err := GitDir(worktreePath)
+	if err != nil {
+		return state, err
 	}
 	rebaseDir := filepath.Join(gitDir, "rebase-merge")
 	rebaseApplyDir := filepath.Join(gitDir, "rebase-apply")
@@ -680,8 +1002,7 @@ func GetWorktreeState(worktreePath string, remote string, mainBranch string) (Wo
 	}
 
 	// Check commits behind/ahead of remote main
-	cmd = exec.Command("git", "rev-list", "--left-right", "--count", fmt.Sprintf("%s/%s...HEAD", remote, mainBranch))
-	cmd.Dir = worktreePath
+	cmd = gitCommand(worktreePath, "rev-list", "--left-right", "--count", fmt.Sprintf("%s/%s...HEAD", remote, mainBranch))
 	output, err = cmd.Output()
 	if err != nil {
 		// If we can't check, assume we can't safely auto-refresh
@@ -739,10 +1060,10 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 
 	// Check for detached HEAD, mid-rebase, or mid-merge states
 	// These must be resolved before we can safely refresh
-	gitDir := filepath.Join(worktreePath, ".git")
-	// For worktrees, .git is a file pointing to the real git dir
-	if content, err := os.ReadFile(gitDir); err == nil && strings.HasPrefix(string(content), "gitdir:") {
-		gitDir = strings.TrimSpace(strings.TrimPrefix(string(content), "gitdir:"))
+	gitDir, err := GitDir(worktreePath)
+	if err != nil {
+		result.Error = err
+		return result
 	}
 
 	// Check for mid-rebase state
@@ -765,13 +1086,11 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 	}
 
 	// Get current branch (also detects detached HEAD)
-	cmd := exec.Command("git", "symbolic-ref", "--short", "HEAD")
-	cmd.Dir = worktreePath
+	cmd := gitCommand(worktreePath, "symbolic-ref", "--short", "HEAD")
 	output, err := cmd.Output()
 	if err != nil {
 		// Check if it's detached HEAD vs not a git repo
-		cmd2 := exec.Command("git", "rev-parse", "--verify", "HEAD")
-		cmd2.Dir = worktreePath
+		cmd2 := gitCommand(worktreePath, "rev-parse", "--verify", "HEAD")
 		if cmd2.Run() == nil {
 			result.Skipped = true
 			result.SkipReason = "detached HEAD (checkout a branch first)"
@@ -791,8 +1110,7 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 	}
 
 	// Fetch latest from remote
-	cmd = exec.Command("git", "fetch", remote, mainBranch)
-	cmd.Dir = worktreePath
+	cmd = gitCommand(worktreePath, "fetch", remote, mainBranch)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		result.Error = fmt.Errorf("failed to fetch from %s: %w\nOutput: %s", remote, err, output)
 		return result
@@ -809,8 +1127,7 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 	stashName := ""
 	if hasChanges {
 		stashName = fmt.Sprintf("refresh-stash-%d", os.Getpid())
-		cmd = exec.Command("git", "stash", "push", "--include-untracked", "-m", stashName)
-		cmd.Dir = worktreePath
+		cmd = gitCommand(worktreePath, "stash", "push", "--include-untracked", "-m", stashName)
 		if output, err := cmd.CombinedOutput(); err != nil {
 			result.Error = fmt.Errorf("failed to stash changes: %w\nOutput: %s", err, output)
 			return result
@@ -819,36 +1136,31 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 	}
 
 	// Get current commit count before rebase
-	cmd = exec.Command("git", "rev-list", "--count", fmt.Sprintf("%s/%s..HEAD", remote, mainBranch))
-	cmd.Dir = worktreePath
+	cmd = gitCommand(worktreePath, "rev-list", "--count", fmt.Sprintf("%s/%s..HEAD", remote, mainBranch))
 	countOutput, _ := cmd.Output()
 	commitsBefore := strings.TrimSpace(string(countOutput))
 
 	// Rebase onto main
-	cmd = exec.Command("git", "rebase", fmt.Sprintf("%s/%s", remote, mainBranch))
-	cmd.Dir = worktreePath
+	cmd = gitCommand(worktreePath, "rebase", fmt.Sprintf("%s/%s", remote, mainBranch))
 	rebaseOutput, rebaseErr := cmd.CombinedOutput()
 
 	if rebaseErr != nil {
 		// Check if there are conflicts
-		cmd = exec.Command("git", "diff", "--name-only", "--diff-filter=U")
-		cmd.Dir = worktreePath
+		cmd = gitCommand(worktreePath, "diff", "--name-only", "--diff-filter=U")
 		conflictOutput, _ := cmd.Output()
 		conflictFiles := strings.Split(strings.TrimSpace(string(conflictOutput)), "\n")
 		if len(conflictFiles) > 0 && conflictFiles[0] != "" {
 			result.HasConflicts = true
 			result.ConflictFiles = conflictFiles
 			// Abort the rebase to leave the worktree in a clean state
-			abortCmd := exec.Command("git", "rebase", "--abort")
-			abortCmd.Dir = worktreePath
+			abortCmd := gitCommand(worktreePath, "rebase", "--abort")
 			abortCmd.Run()
 		}
 		result.Error = fmt.Errorf("rebase failed: %w\nOutput: %s", rebaseErr, rebaseOutput)
 
 		// Restore stash if we stashed
 		if result.WasStashed {
-			popCmd := exec.Command("git", "stash", "pop")
-			popCmd.Dir = worktreePath
+			popCmd := gitCommand(worktreePath, "stash", "pop")
 			if popCmd.Run() == nil {
 				result.StashRestored = true
 			}
@@ -864,8 +1176,7 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 
 	// Restore stash if we stashed
 	if result.WasStashed {
-		cmd = exec.Command("git", "stash", "pop")
-		cmd.Dir = worktreePath
+		cmd = gitCommand(worktreePath, "stash", "pop")
 		if err := cmd.Run(); err != nil {
 			// Stash pop might fail if there are conflicts
 			result.Error = fmt.Errorf("stash pop failed (manual resolution may be needed): %w", err)
@@ -899,3 +1210,35 @@ func (m *Manager) RefreshWorktreeWithDefaults(worktreePath string) RefreshResult
 
 	return RefreshWorktree(worktreePath, remote, mainBranch)
 }
+
+// ContextDir is where files handed to an agent as context for its task are
+// copied, relative to the agent's worktree
+const ContextDir = ".multiclaude/context"
+
+// WriteContextFile copies data into the worktree's context directory as
+// name and returns its path relative to the worktree. The directory gets a
+// .gitignore that ignores everything in it, so context files never end up
+// on the agent's branch.
+func WriteContextFile(worktreePath, name string, data []byte) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." || name == ".gitignore" {
+		return "", fmt.Errorf("invalid context file name %q", name)
+	}
+
+	dir := filepath.Join(worktreePath, ContextDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create context directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("# Generated by multiclaude: context files stay out of git\n*\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write context .gitignore: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write context file %s: %w", name, err)
+	}
+	return filepath.Join(ContextDir, name), nil
+}
+
+// RemoveContextDir removes the worktree's context directory and the files
+// in it. A missing directory is not an error.
+func RemoveContextDir(worktreePath string) error {
+	return os.RemoveAll(filepath.Join(worktreePath, ContextDir))
+}
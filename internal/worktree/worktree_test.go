@@ -1,6 +1,7 @@
 package worktree
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -542,6 +543,102 @@ func TestGetCurrentBranch(t *testing.T) {
 	}
 }
 
+func TestCommitsAheadAndPushBranch(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+
+	wtPath := filepath.Join(repoPath, "wt-ahead")
+	if err := manager.CreateNewBranch(wtPath, "feature", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+
+	ahead, err := CommitsAhead(wtPath, "main")
+	if err != nil {
+		t.Fatalf("CommitsAhead failed: %v", err)
+	}
+	if ahead != 0 {
+		t.Errorf("Expected 0 commits ahead, got %d", ahead)
+	}
+
+	if err := os.WriteFile(filepath.Join(wtPath, "feature.txt"), []byte("feature"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	for _, args := range [][]string{
+		{"add", "feature.txt"},
+		{"commit", "-m", "Add feature", "-m", "Longer description."},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = wtPath
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+
+	ahead, err = CommitsAhead(wtPath, "main")
+	if err != nil {
+		t.Fatalf("CommitsAhead failed: %v", err)
+	}
+	if ahead != 1 {
+		t.Errorf("Expected 1 commit ahead, got %d", ahead)
+	}
+
+	subject, body, err := LastCommitMessage(wtPath)
+	if err != nil {
+		t.Fatalf("LastCommitMessage failed: %v", err)
+	}
+	if subject != "Add feature" || body != "Longer description." {
+		t.Errorf("LastCommitMessage = (%q, %q)", subject, body)
+	}
+
+	if _, err := CommitsAhead(wtPath, "no-such-branch"); err == nil {
+		t.Error("CommitsAhead should fail for an unknown base")
+	}
+
+	// Push to a bare remote
+	remotePath := filepath.Join(t.TempDir(), "remote.git")
+	if err := exec.Command("git", "init", "--bare", remotePath).Run(); err != nil {
+		t.Fatalf("Failed to create bare remote: %v", err)
+	}
+	cmd := exec.Command("git", "remote", "add", "origin", remotePath)
+	cmd.Dir = repoPath
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to add remote: %v", err)
+	}
+
+	if err := PushBranch(wtPath, "origin", "feature"); err != nil {
+		t.Fatalf("PushBranch failed: %v", err)
+	}
+	hasUnpushed, err := HasUnpushedCommits(wtPath)
+	if err != nil {
+		t.Fatalf("HasUnpushedCommits failed: %v", err)
+	}
+	if hasUnpushed {
+		t.Error("Branch should have no unpushed commits after PushBranch")
+	}
+
+	// PushBranch sets the upstream, which SetUpstream can point elsewhere
+	if upstream, err := GetUpstream(wtPath); err != nil || upstream != "origin/feature" {
+		t.Errorf("GetUpstream() = %q, %v; want origin/feature", upstream, err)
+	}
+	if err := PushBranch(repoPath, "origin", "main"); err != nil {
+		t.Fatalf("PushBranch main failed: %v", err)
+	}
+	if err := SetUpstream(wtPath, "origin", "main"); err != nil {
+		t.Fatalf("SetUpstream failed: %v", err)
+	}
+	if upstream, err := GetUpstream(wtPath); err != nil || upstream != "origin/main" {
+		t.Errorf("GetUpstream() = %q, %v; want origin/main", upstream, err)
+	}
+	if err := SetUpstream(wtPath, "origin", "no-such-branch"); err == nil {
+		t.Error("SetUpstream should fail for a branch the remote does not have")
+	}
+	if upstream, err := GetUpstream(repoPath); err != nil || upstream != "origin/main" {
+		t.Errorf("GetUpstream() of the pushed main = %q, %v", upstream, err)
+	}
+}
+
 func TestCleanupOrphaned(t *testing.T) {
 	repoPath, cleanup := createTestRepo(t)
 	defer cleanup()
@@ -608,6 +705,73 @@ func TestCleanupOrphaned(t *testing.T) {
 	}
 }
 
+func TestCleanupOrphanedSymlinkedRoot(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+
+	// The worktrees directory is a symlink onto another location
+	dataDir := t.TempDir()
+	wtRootDir := filepath.Join(t.TempDir(), "wts")
+	if err := os.Symlink(dataDir, wtRootDir); err != nil {
+		t.Fatalf("Failed to symlink worktree root: %v", err)
+	}
+
+	// One worktree is created through the symlink, one through the target
+	viaLink := filepath.Join(wtRootDir, "via-link")
+	if err := manager.CreateNewBranch(viaLink, "via-link", "main"); err != nil {
+		t.Fatalf("Failed to create worktree via symlink: %v", err)
+	}
+	viaTarget := filepath.Join(dataDir, "via-target")
+	if err := manager.CreateNewBranch(viaTarget, "via-target", "main"); err != nil {
+		t.Fatalf("Failed to create worktree via target: %v", err)
+	}
+	orphanedPath := filepath.Join(wtRootDir, "orphaned-dir")
+	if err := os.MkdirAll(orphanedPath, 0755); err != nil {
+		t.Fatalf("Failed to create orphaned directory: %v", err)
+	}
+
+	orphaned, err := FindOrphaned(wtRootDir, manager)
+	if err != nil {
+		t.Fatalf("FindOrphaned failed: %v", err)
+	}
+	if len(orphaned) != 1 || orphaned[0] != orphanedPath {
+		t.Errorf("FindOrphaned() = %v, want only %s", orphaned, orphanedPath)
+	}
+
+	removed, err := CleanupOrphaned(wtRootDir, manager)
+	if err != nil {
+		t.Fatalf("CleanupOrphaned failed: %v", err)
+	}
+	if len(removed) != 1 {
+		t.Errorf("Expected to remove 1 directory, removed %d: %v", len(removed), removed)
+	}
+	for _, path := range []string{viaLink, viaTarget} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Worktree %s should not be removed: %v", path, err)
+		}
+	}
+}
+
+func TestResolvePathMissingLeaf(t *testing.T) {
+	dataDir := t.TempDir()
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(dataDir, link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	// A path that doesn't exist yet still resolves through its parent
+	resolvedData, err := filepath.EvalSymlinks(dataDir)
+	if err != nil {
+		t.Fatalf("EvalSymlinks failed: %v", err)
+	}
+	want := filepath.Join(resolvedData, "missing", "leaf")
+	if got := resolvePath(filepath.Join(link, "missing", "leaf")); got != want {
+		t.Errorf("resolvePath() = %q, want %q", got, want)
+	}
+}
+
 func TestWorktreeInfoParsing(t *testing.T) {
 	repoPath, cleanup := createTestRepo(t)
 	defer cleanup()
@@ -1364,6 +1528,30 @@ branch refs/heads/main`
 			t.Errorf("Expected branch 'main', got '%s'", result[0].Branch)
 		}
 	})
+
+	t.Run("bare repository and paths with spaces", func(t *testing.T) {
+		input := `worktree /path/to/project/.bare
+bare
+
+worktree /path/to/project/my feature
+HEAD abc123
+branch refs/heads/feature
+
+`
+		result := parseWorktreeList(input)
+		if len(result) != 2 {
+			t.Fatalf("Expected 2 entries, got %d", len(result))
+		}
+		if !result[0].Bare {
+			t.Error("Expected first entry to be marked bare")
+		}
+		if result[1].Bare {
+			t.Error("Expected second entry not to be marked bare")
+		}
+		if result[1].Path != "/path/to/project/my feature" {
+			t.Errorf("Expected path with space preserved, got '%s'", result[1].Path)
+		}
+	})
 }
 
 func TestDeleteBranch(t *testing.T) {
@@ -1501,6 +1689,180 @@ func TestListBranchesWithPrefix(t *testing.T) {
 	})
 }
 
+func TestListMergedBranches(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+
+	// work/merged is at main; work/unmerged has a commit of its own
+	createBranch(t, repoPath, "work/merged")
+	runGit(t, repoPath, "checkout", "-q", "-b", "work/unmerged")
+	runGit(t, repoPath, "commit", "-q", "--allow-empty", "-m", "Unmerged")
+	runGit(t, repoPath, "checkout", "-q", "main")
+
+	remotePath := t.TempDir()
+	runGit(t, remotePath, "init", "-q", "--bare")
+	runGit(t, repoPath, "remote", "add", "origin", remotePath)
+	runGit(t, repoPath, "push", "-q", "origin", "main", "work/merged", "work/unmerged")
+
+	merged, err := manager.ListMergedBranches("main", "work/")
+	if err != nil {
+		t.Fatalf("ListMergedBranches() failed: %v", err)
+	}
+	if len(merged) != 1 || merged[0] != "work/merged" {
+		t.Errorf("ListMergedBranches() = %v, want [work/merged]", merged)
+	}
+
+	merged, err = manager.ListMergedRemoteBranches("origin", "origin/main", "work/")
+	if err != nil {
+		t.Fatalf("ListMergedRemoteBranches() failed: %v", err)
+	}
+	if len(merged) != 1 || merged[0] != "work/merged" {
+		t.Errorf("ListMergedRemoteBranches() = %v, want [work/merged]", merged)
+	}
+
+	all, err := manager.ListRemoteBranchesWithPrefix("origin", "work/")
+	if err != nil {
+		t.Fatalf("ListRemoteBranchesWithPrefix() failed: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("ListRemoteBranchesWithPrefix() = %v, want both work/ branches", all)
+	}
+
+	// git branch -d refuses the unmerged branch
+	if err := manager.SafeDeleteBranch("work/unmerged"); err == nil {
+		t.Error("SafeDeleteBranch() should refuse an unmerged branch")
+	}
+	if err := manager.SafeDeleteBranch("work/merged"); err != nil {
+		t.Errorf("SafeDeleteBranch() of a merged branch failed: %v", err)
+	}
+}
+
+func TestLastCommitTime(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+
+	cmd := exec.Command("git", "commit", "-q", "--allow-empty", "-m", "Old")
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE=1700000000 +0000")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git commit failed: %v\n%s", err, output)
+	}
+
+	last, err := manager.LastCommitTime("main")
+	if err != nil {
+		t.Fatalf("LastCommitTime() failed: %v", err)
+	}
+	if !last.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("LastCommitTime() = %v, want %v", last, time.Unix(1700000000, 0))
+	}
+
+	if _, err := manager.LastCommitTime("no-such-branch"); err == nil {
+		t.Error("LastCommitTime() of a missing branch should fail")
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	createBranch(t, repoPath, "work/fox")
+	runGit(t, repoPath, "checkout", "-q", "work/fox")
+	runGit(t, repoPath, "commit", "-q", "--allow-empty", "-m", "Fox work")
+	runGit(t, repoPath, "checkout", "-q", "main")
+
+	bundle := filepath.Join(t.TempDir(), "fox.bundle")
+	if err := manager.CreateBundle("work/fox", bundle); err != nil {
+		t.Fatalf("CreateBundle() failed: %v", err)
+	}
+	if err := manager.DeleteBranch("work/fox"); err != nil {
+		t.Fatalf("DeleteBranch() failed: %v", err)
+	}
+
+	heads, err := manager.BundleHeads(bundle)
+	if err != nil {
+		t.Fatalf("BundleHeads() failed: %v", err)
+	}
+	if len(heads) != 1 || heads[0] != "refs/heads/work/fox" {
+		t.Fatalf("BundleHeads() = %v, want [refs/heads/work/fox]", heads)
+	}
+
+	if err := manager.FetchBundle(bundle, heads[0], "work/fox"); err != nil {
+		t.Fatalf("FetchBundle() failed: %v", err)
+	}
+	subject, err := exec.Command("git", "-C", repoPath, "log", "-1", "--format=%s", "work/fox").Output()
+	if err != nil || strings.TrimSpace(string(subject)) != "Fox work" {
+		t.Errorf("restored branch head = %q (%v), want the bundled commit", subject, err)
+	}
+
+	if err := manager.FetchBundle(bundle, heads[0], "work/fox"); err == nil {
+		t.Error("FetchBundle() onto an existing branch should fail")
+	}
+	if err := manager.CreateBundle("no-such-branch", filepath.Join(t.TempDir(), "x.bundle")); err == nil {
+		t.Error("CreateBundle() of a missing branch should fail")
+	}
+}
+
+func TestUpdateSubmodules(t *testing.T) {
+	// Local submodules use the file protocol, which git refuses by default
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	libPath, cleanupLib := createTestRepo(t)
+	defer cleanupLib()
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	if HasSubmodules(repoPath) {
+		t.Error("HasSubmodules() = true before adding a submodule")
+	}
+	runGit(t, repoPath, "submodule", "add", "-q", libPath, "lib")
+	runGit(t, repoPath, "commit", "-q", "-m", "Add lib submodule")
+	if !HasSubmodules(repoPath) {
+		t.Error("HasSubmodules() = false with a .gitmodules file")
+	}
+
+	// A new worktree has an empty submodule directory until it is updated
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(t.TempDir(), "wt")
+	if err := manager.CreateNewBranch(wtPath, "work/sub", "HEAD"); err != nil {
+		t.Fatalf("CreateNewBranch() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "lib", "README.md")); err == nil {
+		t.Fatal("submodule checked out before UpdateSubmodules()")
+	}
+
+	var out strings.Builder
+	if err := UpdateSubmodules(context.Background(), wtPath, &out); err != nil {
+		t.Fatalf("UpdateSubmodules() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "lib", "README.md")); err != nil {
+		t.Errorf("submodule not checked out: %v", err)
+	}
+	if !strings.Contains(out.String(), "lib") {
+		t.Errorf("UpdateSubmodules() output = %q, want git's progress", out.String())
+	}
+
+	// An unreachable submodule fails with git's output
+	for _, path := range []string{libPath, filepath.Join(repoPath, ".git", "modules")} {
+		if err := os.RemoveAll(path); err != nil {
+			t.Fatalf("failed to remove %s: %v", path, err)
+		}
+	}
+	otherPath := filepath.Join(t.TempDir(), "other")
+	if err := manager.CreateNewBranch(otherPath, "work/other", "HEAD"); err != nil {
+		t.Fatalf("CreateNewBranch() failed: %v", err)
+	}
+	if err := UpdateSubmodules(context.Background(), otherPath, nil); err == nil || !strings.Contains(err.Error(), "Output:") {
+		t.Errorf("UpdateSubmodules() of an unreachable submodule = %v", err)
+	}
+}
+
 func TestFindOrphanedBranches(t *testing.T) {
 	t.Run("finds branches without worktrees", func(t *testing.T) {
 		repoPath, cleanup := createTestRepo(t)
@@ -2437,3 +2799,295 @@ func TestIsBehindMain(t *testing.T) {
 		}
 	})
 }
+
+// runGit runs a git command in dir and fails the test on error
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %v\nOutput: %s", strings.Join(args, " "), err, output)
+	}
+}
+
+func TestGitCommonDir(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(repoPath, "wt-common")
+	if err := manager.CreateNewBranch(wtPath, "feature/common", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+
+	want := filepath.Join(repoPath, ".git")
+	for _, path := range []string{repoPath, wtPath} {
+		got, err := GitCommonDir(path)
+		if err != nil {
+			t.Fatalf("GitCommonDir(%s) failed: %v", path, err)
+		}
+		if !samePath(got, want) {
+			t.Errorf("GitCommonDir(%s) = %s, want %s", path, got, want)
+		}
+	}
+
+	// The per-worktree git dir of a linked worktree is not the common dir
+	gitDir, err := GitDir(wtPath)
+	if err != nil {
+		t.Fatalf("GitDir failed: %v", err)
+	}
+	if !samePath(gitDir, filepath.Join(want, "worktrees", "wt-common")) {
+		t.Errorf("GitDir(%s) = %s, want the worktree's own git dir", wtPath, gitDir)
+	}
+
+	if _, err := GitCommonDir(t.TempDir()); err == nil {
+		t.Error("GitCommonDir should fail outside a repository")
+	}
+}
+
+func TestBareRepoWithWorktrees(t *testing.T) {
+	srcPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	// The bare-clone-plus-worktrees layout: project/.bare holds the
+	// repository, project/.git points at it, and checkouts live alongside
+	projectDir := t.TempDir()
+	runGit(t, projectDir, "clone", "--bare", srcPath, ".bare")
+	if err := os.WriteFile(filepath.Join(projectDir, ".git"), []byte("gitdir: ./.bare\n"), 0644); err != nil {
+		t.Fatalf("Failed to write gitfile: %v", err)
+	}
+	mainPath := filepath.Join(projectDir, "main")
+	runGit(t, projectDir, "worktree", "add", mainPath, "main")
+
+	for _, repoPath := range []string{projectDir, mainPath} {
+		manager := NewManager(repoPath)
+		if !samePath(manager.gitDir, filepath.Join(projectDir, ".bare")) {
+			t.Errorf("NewManager(%s) resolved git dir %q, want the bare repository", repoPath, manager.gitDir)
+		}
+
+		worktrees, err := manager.List()
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(worktrees) != 1 || !samePath(worktrees[0].Path, mainPath) || worktrees[0].Branch != "main" {
+			t.Errorf("List from %s = %+v, want only the main checkout", repoPath, worktrees)
+		}
+	}
+
+	manager := NewManager(projectDir)
+	wtPath := filepath.Join(projectDir, "feature")
+	if err := manager.CreateNewBranch(wtPath, "work/feature", "main"); err != nil {
+		t.Fatalf("CreateNewBranch failed: %v", err)
+	}
+	exists, err := manager.Exists(wtPath)
+	if err != nil {
+		t.Fatalf("Exists failed: %v", err)
+	}
+	if !exists {
+		t.Error("New worktree should exist")
+	}
+
+	branch, err := GetCurrentBranch(wtPath)
+	if err != nil {
+		t.Fatalf("GetCurrentBranch failed: %v", err)
+	}
+	if branch != "work/feature" {
+		t.Errorf("Expected branch work/feature, got %s", branch)
+	}
+
+	if err := os.WriteFile(filepath.Join(wtPath, "new.txt"), []byte("change\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	dirty, err := HasUncommittedChanges(wtPath)
+	if err != nil {
+		t.Fatalf("HasUncommittedChanges failed: %v", err)
+	}
+	if !dirty {
+		t.Error("Expected uncommitted changes")
+	}
+
+	unpushed, err := HasUnpushedCommits(wtPath)
+	if err != nil {
+		t.Fatalf("HasUnpushedCommits failed: %v", err)
+	}
+	if unpushed {
+		t.Error("Branch without upstream should not report unpushed commits")
+	}
+
+	// A relative gitdir pointer must still find the rebase state
+	if err := os.WriteFile(filepath.Join(wtPath, ".git"), []byte("gitdir: ../.bare/worktrees/feature\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite gitfile: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(projectDir, ".bare", "worktrees", "feature", "rebase-merge"), 0755); err != nil {
+		t.Fatalf("Failed to create rebase state: %v", err)
+	}
+	wtState, err := GetWorktreeState(wtPath, "origin", "main")
+	if err != nil {
+		t.Fatalf("GetWorktreeState failed: %v", err)
+	}
+	if !wtState.IsMidRebase {
+		t.Error("Expected worktree to be detected as mid-rebase")
+	}
+}
+
+func TestSeparateGitDirRepo(t *testing.T) {
+	srcPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	// A clone whose .git is a gitfile pointing at a git dir elsewhere, as
+	// with repos adopted from an existing local checkout
+	tmpDir := t.TempDir()
+	repoPath := filepath.Join(tmpDir, "repo")
+	gitDir := filepath.Join(tmpDir, "repo.git")
+	runGit(t, tmpDir, "clone", "--separate-git-dir", gitDir, srcPath, repoPath)
+	runGit(t, repoPath, "config", "user.name", "Test User")
+	runGit(t, repoPath, "config", "user.email", "test@example.com")
+
+	info, err := os.Stat(filepath.Join(repoPath, ".git"))
+	if err != nil {
+		t.Fatalf("Failed to stat .git: %v", err)
+	}
+	if info.IsDir() {
+		t.Fatal("Expected .git to be a file")
+	}
+
+	manager := NewManager(repoPath)
+	if !samePath(manager.gitDir, gitDir) {
+		t.Errorf("Expected git dir %s, got %q", gitDir, manager.gitDir)
+	}
+
+	wtPath := filepath.Join(tmpDir, "wt")
+	if err := manager.CreateNewBranch(wtPath, "work/separate", "main"); err != nil {
+		t.Fatalf("CreateNewBranch failed: %v", err)
+	}
+	worktrees, err := manager.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(worktrees) != 2 {
+		t.Fatalf("Expected 2 worktrees, got %+v", worktrees)
+	}
+	if !samePath(worktrees[0].Path, repoPath) {
+		t.Errorf("Expected main worktree at %s, got %s", repoPath, worktrees[0].Path)
+	}
+
+	branch, err := GetCurrentBranch(wtPath)
+	if err != nil {
+		t.Fatalf("GetCurrentBranch failed: %v", err)
+	}
+	if branch != "work/separate" {
+		t.Errorf("Expected branch work/separate, got %s", branch)
+	}
+
+	// The clone tracks origin/main, so a local commit is unpushed
+	runGit(t, repoPath, "commit", "--allow-empty", "-m", "local")
+	unpushed, err := HasUnpushedCommits(repoPath)
+	if err != nil {
+		t.Fatalf("HasUnpushedCommits failed: %v", err)
+	}
+	if !unpushed {
+		t.Error("Expected unpushed commits")
+	}
+
+	if err := manager.Remove(wtPath, false); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+}
+
+func TestGitEnvironmentIgnored(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+	otherPath, otherCleanup := createTestRepo(t)
+	defer otherCleanup()
+	createBranch(t, otherPath, "other-branch")
+	runGit(t, otherPath, "checkout", "other-branch")
+
+	// A GIT_DIR exported in the user's shell must not redirect commands
+	t.Setenv("GIT_DIR", filepath.Join(otherPath, ".git"))
+	t.Setenv("GIT_WORK_TREE", otherPath)
+
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(repoPath, "wt-env")
+	if err := manager.CreateNewBranch(wtPath, "feature/env", "main"); err != nil {
+		t.Fatalf("CreateNewBranch failed: %v", err)
+	}
+
+	worktrees, err := manager.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(worktrees) != 2 || !samePath(worktrees[0].Path, repoPath) {
+		t.Errorf("Expected worktrees of %s, got %+v", repoPath, worktrees)
+	}
+
+	branch, err := GetCurrentBranch(repoPath)
+	if err != nil {
+		t.Fatalf("GetCurrentBranch failed: %v", err)
+	}
+	if branch != "main" {
+		t.Errorf("Expected main, got %s", branch)
+	}
+}
+
+func TestWriteContextFile(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	rel, err := WriteContextFile(repoPath, "design.md", []byte("# Design\n"))
+	if err != nil {
+		t.Fatalf("WriteContextFile failed: %v", err)
+	}
+	if rel != filepath.Join(ContextDir, "design.md") {
+		t.Errorf("WriteContextFile returned %q", rel)
+	}
+	data, err := os.ReadFile(filepath.Join(repoPath, rel))
+	if err != nil || string(data) != "# Design\n" {
+		t.Errorf("context file content = %q, %v", data, err)
+	}
+
+	// Neither the file nor the generated .gitignore shows up in git
+	hasChanges, err := HasUncommittedChanges(repoPath)
+	if err != nil {
+		t.Fatalf("HasUncommittedChanges failed: %v", err)
+	}
+	if hasChanges {
+		t.Error("context files should be ignored by git")
+	}
+
+	for _, name := range []string{"", "../escape.md", "sub/dir.md", ".gitignore"} {
+		if _, err := WriteContextFile(repoPath, name, nil); err == nil {
+			t.Errorf("WriteContextFile(%q) should fail", name)
+		}
+	}
+
+	if err := RemoveContextDir(repoPath); err != nil {
+		t.Fatalf("RemoveContextDir failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, ContextDir)); !os.IsNotExist(err) {
+		t.Error("context directory should be removed")
+	}
+	if err := RemoveContextDir(repoPath); err != nil {
+		t.Errorf("RemoveContextDir on a missing directory failed: %v", err)
+	}
+}
+
+func TestIsDirAt(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+	commitFile(t, repoPath, "services/billing/main.go", "package main\n", "Add billing")
+
+	for path, want := range map[string]bool{
+		"services":         true,
+		"services/billing": true,
+		"services/missing": false,
+		"README.md":        false,
+	} {
+		if got := IsDirAt(repoPath, "main", path); got != want {
+			t.Errorf("IsDirAt(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if IsDirAt(repoPath, "no-such-branch", "services") {
+		t.Error("IsDirAt should be false for a missing ref")
+	}
+}
@@ -39,8 +39,16 @@ import (
 	"context"
 	"crypto/rand"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/dlorenc/multiclaude/pkg/tmux"
 )
 
 // TerminalRunner abstracts terminal interaction for running Claude.
@@ -89,6 +97,15 @@ type Runner struct {
 	// SkipPermissions controls whether to pass --dangerously-skip-permissions.
 	// This is required for non-interactive use. Defaults to true.
 	SkipPermissions bool
+
+	// MinVersion and MaxVersion bound the accepted `claude --version`
+	// (inclusive). Empty means unbounded. When either is set, the binary is
+	// checked once, on the first Start.
+	MinVersion string
+	MaxVersion string
+
+	versionOnce sync.Once
+	versionErr  error
 }
 
 // RunnerOption is a functional option for configuring a Runner.
@@ -130,6 +147,15 @@ func WithPermissions(skip bool) RunnerOption {
 	}
 }
 
+// WithBinaryValidator makes Start refuse to launch a claude binary whose
+// version falls outside [minVersion, maxVersion]. Either bound may be empty.
+func WithBinaryValidator(minVersion, maxVersion string) RunnerOption {
+	return func(r *Runner) {
+		r.MinVersion = minVersion
+		r.MaxVersion = maxVersion
+	}
+}
+
 // NewRunner creates a new Claude runner with the given options.
 func NewRunner(opts ...RunnerOption) *Runner {
 	r := &Runner{
@@ -153,6 +179,174 @@ func ResolveBinaryPath() string {
 	return "claude"
 }
 
+// versionPattern matches a semantic version such as 1.5.0 in version output
+var versionPattern = regexp.MustCompile(`\d+\.\d+\.\d+`)
+
+// ParseVersion extracts the first semantic version (major.minor.patch) from
+// output such as "1.5.0 (Claude Code)".
+func ParseVersion(output string) (string, error) {
+	version := versionPattern.FindString(output)
+	if version == "" {
+		return "", fmt.Errorf("no version found in %q", strings.TrimSpace(output))
+	}
+	return version, nil
+}
+
+// CompareVersions compares two semantic versions, returning -1, 0 or 1 as a
+// is older than, equal to or newer than b.
+func CompareVersions(a, b string) (int, error) {
+	pa, err := versionParts(a)
+	if err != nil {
+		return 0, err
+	}
+	pb, err := versionParts(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := range pa {
+		if pa[i] < pb[i] {
+			return -1, nil
+		}
+		if pa[i] > pb[i] {
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// ValidateVersion returns an error unless version has the form
+// major.minor.patch.
+func ValidateVersion(version string) error {
+	_, err := versionParts(version)
+	return err
+}
+
+// versionParts splits a version into its major, minor and patch numbers
+func versionParts(version string) ([3]int, error) {
+	var parts [3]int
+	fields := strings.Split(version, ".")
+	if len(fields) != 3 {
+		return parts, fmt.Errorf("invalid version %q: expected major.minor.patch", version)
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, fmt.Errorf("invalid version %q: expected major.minor.patch", version)
+		}
+		parts[i] = n
+	}
+	return parts, nil
+}
+
+// BinaryVersion runs `<binaryPath> --version` and returns the semantic
+// version it reports.
+func BinaryVersion(ctx context.Context, binaryPath string) (string, error) {
+	output, err := exec.CommandContext(ctx, binaryPath, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s --version: %w", binaryPath, err)
+	}
+	return ParseVersion(string(output))
+}
+
+// BinaryInfo identifies a claude binary: where it was found, the version it
+// reports and which file it is.
+type BinaryInfo struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	// Fingerprint is the resolved path, size and modification time of the
+	// file Path points to. claude ships as a single large executable, so
+	// this stands in for hashing it.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// SameBinary reports whether b and other are the same file at the same path.
+func (b BinaryInfo) SameBinary(other BinaryInfo) bool {
+	return b.Path == other.Path && b.Fingerprint == other.Fingerprint
+}
+
+// String describes the binary as "<version> (<path>)".
+func (b BinaryInfo) String() string {
+	return fmt.Sprintf("%s (%s)", b.Version, b.Path)
+}
+
+// InspectBinary records the version and fingerprint of the claude binary at
+// binaryPath. Symlinks are followed, since package managers usually install
+// claude behind one that stays put across upgrades.
+func InspectBinary(ctx context.Context, binaryPath string) (BinaryInfo, error) {
+	info := BinaryInfo{Path: binaryPath}
+
+	resolved, err := filepath.EvalSymlinks(binaryPath)
+	if err != nil {
+		return info, fmt.Errorf("failed to resolve %s: %w", binaryPath, err)
+	}
+	stat, err := os.Stat(resolved)
+	if err != nil {
+		return info, fmt.Errorf("failed to stat %s: %w", resolved, err)
+	}
+	info.Fingerprint = fmt.Sprintf("%s:%d:%d", resolved, stat.Size(), stat.ModTime().UnixNano())
+
+	info.Version, err = BinaryVersion(ctx, binaryPath)
+	if err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// CheckExecutable returns an error unless path is an absolute path to an
+// executable file.
+func CheckExecutable(path string) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("claude binary path %q must be absolute", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("claude binary %s is unavailable: %w", path, err)
+	}
+	if info.IsDir() || info.Mode()&0111 == 0 {
+		return fmt.Errorf("claude binary %s is not an executable file", path)
+	}
+	return nil
+}
+
+// ValidateBinary checks the claude binary's version against MinVersion and
+// MaxVersion. It is a no-op when neither is set. The result is cached, so
+// the binary is only run once per Runner.
+func (r *Runner) ValidateBinary(ctx context.Context) error {
+	if r.MinVersion == "" && r.MaxVersion == "" {
+		return nil
+	}
+	r.versionOnce.Do(func() {
+		r.versionErr = r.validateBinary(ctx)
+	})
+	return r.versionErr
+}
+
+func (r *Runner) validateBinary(ctx context.Context) error {
+	version, err := BinaryVersion(ctx, r.BinaryPath)
+	if err != nil {
+		return err
+	}
+	if r.MinVersion != "" {
+		cmp, err := CompareVersions(version, r.MinVersion)
+		if err != nil {
+			return fmt.Errorf("invalid minimum claude version: %w", err)
+		}
+		if cmp < 0 {
+			return fmt.Errorf("claude version %s is older than the minimum %s", version, r.MinVersion)
+		}
+	}
+	if r.MaxVersion != "" {
+		cmp, err := CompareVersions(version, r.MaxVersion)
+		if err != nil {
+			return fmt.Errorf("invalid maximum claude version: %w", err)
+		}
+		if cmp > 0 {
+			return fmt.Errorf("claude version %s is newer than the maximum %s", version, r.MaxVersion)
+		}
+	}
+	return nil
+}
+
 // Config contains configuration for starting a Claude instance.
 type Config struct {
 	// SessionID is the unique identifier for this Claude session.
@@ -183,6 +377,11 @@ type Config struct {
 	// This is useful for showing restart instructions or other information.
 	// If empty, no MOTD is displayed.
 	MOTD string
+
+	// EnvKeys names variables already set in the tmux session environment
+	// (via set-environment) that the window's shell must import before
+	// launching Claude. Only the names appear in the typed command.
+	EnvKeys []string
 }
 
 // StartResult contains information about a started Claude instance.
@@ -203,6 +402,11 @@ func (r *Runner) Start(ctx context.Context, session, window string, cfg Config)
 		return nil, fmt.Errorf("terminal runner not configured")
 	}
 
+	// Refuse to launch a binary outside the configured version range
+	if err := r.ValidateBinary(ctx); err != nil {
+		return nil, err
+	}
+
 	// Generate session ID if not provided
 	sessionID := cfg.SessionID
 	if sessionID == "" {
@@ -277,6 +481,11 @@ func (r *Runner) buildCommand(sessionID string, cfg Config) string {
 		cmd = fmt.Sprintf("cd %q && ", cfg.WorkDir)
 	}
 
+	// Import session environment variables by name so values are never typed
+	if len(cfg.EnvKeys) > 0 {
+		cmd += tmux.ImportEnvironmentCommand(cfg.EnvKeys...) + " && "
+	}
+
 	// Note: CLAUDE_CONFIG_DIR and CLAUDE_CODE_OAUTH_TOKEN are not used because
 	// Claude Code only reads credentials from ~/.claude/.credentials.json
 	// regardless of CLAUDE_CONFIG_DIR setting. Slash commands go in ~/.claude/commands/.
@@ -3,6 +3,8 @@ package claude
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -528,6 +530,19 @@ func TestBuildCommand(t *testing.T) {
 				"CLAUDE_CONFIG_DIR",
 			},
 		},
+		{
+			name: "with env keys",
+			config: Config{
+				SessionID: "test-session",
+				EnvKeys:   []string{"API_TOKEN", "REGION"},
+			},
+			contains: []string{
+				`eval "$(tmux show-environment -s API_TOKEN; tmux show-environment -s REGION)" && /path/to/claude`,
+			},
+			excludes: []string{
+				"export",
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -571,6 +586,184 @@ func TestResolveBinaryPath(t *testing.T) {
 	}
 }
 
+// fakeClaudeBinary writes a script that prints the given --version output
+func fakeClaudeBinary(t *testing.T, versionOutput string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "claude")
+	script := "#!/bin/sh\necho '" + versionOutput + "'\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake claude binary: %v", err)
+	}
+	return path
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{"1.5.0 (Claude Code)", "1.5.0", false},
+		{"claude version 2.0.13\n", "2.0.13", false},
+		{"no version here", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.output)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseVersion(%q) error = %v, wantErr %v", tt.output, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseVersion(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.5.0", "1.5.0", 0},
+		{"1.4.9", "1.5.0", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "1.99.99", 1},
+	}
+
+	for _, tt := range tests {
+		got, err := CompareVersions(tt.a, tt.b)
+		if err != nil {
+			t.Errorf("CompareVersions(%q, %q) failed: %v", tt.a, tt.b, err)
+		}
+		if got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	if _, err := CompareVersions("1.5", "1.5.0"); err == nil {
+		t.Error("CompareVersions() should reject versions without a patch number")
+	}
+}
+
+func TestWithBinaryValidator(t *testing.T) {
+	binary := fakeClaudeBinary(t, "1.5.2 (Claude Code)")
+
+	tests := []struct {
+		name    string
+		min     string
+		max     string
+		wantErr string
+	}{
+		{"in range", "1.5.0", "2.0.0", ""},
+		{"min only", "1.5.2", "", ""},
+		{"max only", "", "1.5.2", ""},
+		{"too old", "1.6.0", "", "older than the minimum 1.6.0"},
+		{"too new", "", "1.5.1", "newer than the maximum 1.5.1"},
+		{"invalid bound", "1.6", "", "invalid minimum claude version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewRunner(WithBinaryPath(binary), WithBinaryValidator(tt.min, tt.max))
+			err := runner.ValidateBinary(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateBinary() failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateBinary() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStartRejectsInvalidBinaryVersion(t *testing.T) {
+	binary := fakeClaudeBinary(t, "1.0.0 (Claude Code)")
+	terminal := &mockTerminal{getPanePIDReturn: 12345}
+	runner := NewRunner(
+		WithTerminal(terminal),
+		WithBinaryPath(binary),
+		WithBinaryValidator("1.5.0", ""),
+		WithStartupDelay(0),
+	)
+
+	if _, err := runner.Start(context.Background(), "session", "window", Config{}); err == nil {
+		t.Fatal("Start() should fail when the binary is older than the minimum version")
+	}
+	if len(terminal.sendKeysCalls) != 0 {
+		t.Errorf("Start() sent %d commands despite the version check failing", len(terminal.sendKeysCalls))
+	}
+}
+
+func TestValidateBinaryWithoutBounds(t *testing.T) {
+	// Without bounds the binary is never run, so a missing binary is fine
+	runner := NewRunner(WithBinaryPath("/nonexistent/claude"))
+	if err := runner.ValidateBinary(context.Background()); err != nil {
+		t.Errorf("ValidateBinary() without bounds failed: %v", err)
+	}
+}
+
+func TestInspectBinary(t *testing.T) {
+	binary := fakeClaudeBinary(t, "1.5.2 (Claude Code)")
+	link := filepath.Join(t.TempDir(), "claude")
+	if err := os.Symlink(binary, link); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	info, err := InspectBinary(context.Background(), link)
+	if err != nil {
+		t.Fatalf("InspectBinary() failed: %v", err)
+	}
+	if info.Path != link || info.Version != "1.5.2" || info.Fingerprint == "" {
+		t.Errorf("InspectBinary() = %+v", info)
+	}
+	if got := info.String(); got != "1.5.2 ("+link+")" {
+		t.Errorf("String() = %q", got)
+	}
+
+	// Upgrading the binary behind the symlink changes the fingerprint
+	upgraded := fakeClaudeBinary(t, "1.6.0 (Claude Code)")
+	if err := os.Remove(link); err != nil {
+		t.Fatalf("failed to remove symlink: %v", err)
+	}
+	if err := os.Symlink(upgraded, link); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	after, err := InspectBinary(context.Background(), link)
+	if err != nil {
+		t.Fatalf("InspectBinary() failed: %v", err)
+	}
+	if info.SameBinary(after) || after.Version != "1.6.0" {
+		t.Errorf("upgrade not detected: before %+v, after %+v", info, after)
+	}
+	if !after.SameBinary(after) {
+		t.Error("SameBinary() should hold for identical info")
+	}
+
+	if _, err := InspectBinary(context.Background(), "/nonexistent/claude"); err == nil {
+		t.Error("InspectBinary() should fail for a missing binary")
+	}
+}
+
+func TestCheckExecutable(t *testing.T) {
+	binary := fakeClaudeBinary(t, "1.5.2 (Claude Code)")
+	if err := CheckExecutable(binary); err != nil {
+		t.Errorf("CheckExecutable(%q) failed: %v", binary, err)
+	}
+
+	notExec := filepath.Join(t.TempDir(), "claude")
+	if err := os.WriteFile(notExec, []byte("data"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	for _, path := range []string{"claude", "/nonexistent/claude", notExec, t.TempDir()} {
+		if err := CheckExecutable(path); err == nil {
+			t.Errorf("CheckExecutable(%q) should fail", path)
+		}
+	}
+}
+
 // Note: TestBuildCommandClaudeConfigDirPrepended and TestStartWithClaudeConfigDir
 // were removed because CLAUDE_CONFIG_DIR is no longer used. Claude Code only reads
 // credentials from ~/.claude/.credentials.json regardless of CLAUDE_CONFIG_DIR,
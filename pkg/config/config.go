@@ -3,35 +3,81 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-// Paths holds all the directory and file paths used by multiclaude
+// PathsConfigFile is the optional file under ConfigDir that moves OutputDir
+// and WorktreesDir elsewhere, e.g. onto a larger disk, and can select the
+// XDG layout
+const PathsConfigFile = "paths.json"
+
+// XDGEnvVar selects the layout: 1 for the XDG layout, 0 for the classic
+// ~/.multiclaude layout. Unset, PathsConfigFile in the XDG config directory
+// decides.
+const XDGEnvVar = "MULTICLAUDE_XDG"
+
+// LayoutXDG is the PathOverrides.Layout value that selects the XDG layout
+const LayoutXDG = "xdg"
+
+// PathOverrides is the content of PathsConfigFile. Empty fields keep the
+// default location; set fields must be absolute paths.
+type PathOverrides struct {
+	// Layout "xdg" selects the XDG layout. It only has an effect in the XDG
+	// config directory ($XDG_CONFIG_HOME/multiclaude/paths.json).
+	Layout       string `json:"layout,omitempty"`
+	OutputDir    string `json:"output_dir,omitempty"`
+	WorktreesDir string `json:"worktrees_dir,omitempty"`
+}
+
+// Paths holds all the directory and file paths used by multiclaude. In the
+// classic layout everything lives under Root ($HOME/.multiclaude/). In the
+// XDG layout Root holds the durable state ($XDG_STATE_HOME/multiclaude/),
+// while logs go to CacheDir and PathsConfigFile to ConfigDir, so backups of
+// the state can skip the logs.
 type Paths struct {
 	Root            string // $HOME/.multiclaude/
+	ConfigDir       string // Holds paths.json: Root, or $XDG_CONFIG_HOME/multiclaude/
+	CacheDir        string // Holds logs: Root, or $XDG_CACHE_HOME/multiclaude/
 	DaemonPID       string // daemon.pid
 	DaemonSock      string // daemon.sock
-	DaemonLog       string // daemon.log
+	DaemonLog       string // daemon.log (under CacheDir)
 	StateFile       string // state.json
 	ReposDir        string // repos/
 	WorktreesDir    string // wts/
 	MessagesDir     string // messages/
-	OutputDir       string // output/
+	OutputDir       string // output/ (under CacheDir)
 	ClaudeConfigDir string // claude-config/
 }
 
-// DefaultPaths returns the default paths for multiclaude
+// DefaultPaths returns the default paths for multiclaude: the XDG layout if
+// it is selected (see UseXDG), otherwise the classic ~/.multiclaude layout
 func DefaultPaths() (*Paths, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return nil, err
 	}
 
-	root := filepath.Join(home, ".multiclaude")
+	paths := ClassicPaths(home)
+	if UseXDG(home) {
+		paths = XDGPaths(home)
+	}
+	if err := paths.LoadOverrides(); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
 
+// NewPaths returns the paths of a multiclaude directory at root, without
+// applying its PathsConfigFile
+func NewPaths(root string) *Paths {
 	return &Paths{
 		Root:            root,
+		ConfigDir:       root,
+		CacheDir:        root,
 		DaemonPID:       filepath.Join(root, "daemon.pid"),
 		DaemonSock:      filepath.Join(root, "daemon.sock"),
 		DaemonLog:       filepath.Join(root, "daemon.log"),
@@ -41,13 +87,106 @@ func DefaultPaths() (*Paths, error) {
 		MessagesDir:     filepath.Join(root, "messages"),
 		OutputDir:       filepath.Join(root, "output"),
 		ClaudeConfigDir: filepath.Join(root, "claude-config"),
-	}, nil
+	}
+}
+
+// ClassicPaths returns the paths of the classic layout, with everything
+// under ~/.multiclaude, without applying its PathsConfigFile
+func ClassicPaths(home string) *Paths {
+	return NewPaths(filepath.Join(home, ".multiclaude"))
+}
+
+// XDGPaths returns the paths of the XDG layout, without applying its
+// PathsConfigFile: state under $XDG_STATE_HOME, logs under $XDG_CACHE_HOME
+// and paths.json under $XDG_CONFIG_HOME, each in a multiclaude directory
+func XDGPaths(home string) *Paths {
+	paths := NewPaths(filepath.Join(xdgBaseDir("XDG_STATE_HOME", home, ".local/state"), "multiclaude"))
+	paths.ConfigDir = filepath.Join(xdgBaseDir("XDG_CONFIG_HOME", home, ".config"), "multiclaude")
+	paths.CacheDir = filepath.Join(xdgBaseDir("XDG_CACHE_HOME", home, ".cache"), "multiclaude")
+	paths.DaemonLog = filepath.Join(paths.CacheDir, "daemon.log")
+	paths.OutputDir = filepath.Join(paths.CacheDir, "output")
+	return paths
+}
+
+// xdgBaseDir returns an XDG base directory, falling back to its default
+// under home when the variable is unset or not absolute, as the XDG spec
+// requires
+func xdgBaseDir(name, home, fallback string) string {
+	if dir := os.Getenv(name); filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(home, fallback)
+}
+
+// UseXDG reports whether the XDG layout is selected: by XDGEnvVar, or else
+// by layout "xdg" in the XDG config directory's PathsConfigFile. The
+// config file is what `multiclaude migrate-paths` writes, and unlike the
+// environment variable it also reaches agents and the daemon.
+func UseXDG(home string) bool {
+	switch strings.ToLower(os.Getenv(XDGEnvVar)) {
+	case "1", "true", "yes":
+		return true
+	case "0", "false", "no":
+		return false
+	}
+
+	data, err := os.ReadFile(filepath.Join(XDGPaths(home).ConfigDir, PathsConfigFile))
+	if err != nil {
+		return false
+	}
+	var overrides PathOverrides
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return false
+	}
+	return overrides.Layout == LayoutXDG
+}
+
+// LoadOverrides applies PathsConfigFile from ConfigDir, if it exists
+func (p *Paths) LoadOverrides() error {
+	configPath := filepath.Join(p.configDir(), PathsConfigFile)
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+
+	var overrides PathOverrides
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", configPath, err)
+	}
+	if overrides.Layout != "" && overrides.Layout != LayoutXDG {
+		return fmt.Errorf("invalid layout in %s: %q (the only layout is %q)", configPath, overrides.Layout, LayoutXDG)
+	}
+	for name, dir := range map[string]string{"output_dir": overrides.OutputDir, "worktrees_dir": overrides.WorktreesDir} {
+		if dir != "" && !filepath.IsAbs(dir) {
+			return fmt.Errorf("invalid %s in %s: %q is not an absolute path", name, configPath, dir)
+		}
+	}
+
+	if overrides.OutputDir != "" {
+		p.OutputDir = filepath.Clean(overrides.OutputDir)
+	}
+	if overrides.WorktreesDir != "" {
+		p.WorktreesDir = filepath.Clean(overrides.WorktreesDir)
+	}
+	return nil
+}
+
+// configDir returns ConfigDir, or Root for Paths built without one
+func (p *Paths) configDir() string {
+	if p.ConfigDir == "" {
+		return p.Root
+	}
+	return p.ConfigDir
 }
 
 // EnsureDirectories creates all necessary directories if they don't exist
 func (p *Paths) EnsureDirectories() error {
 	dirs := []string{
 		p.Root,
+		filepath.Dir(p.DaemonLog),
 		p.ReposDir,
 		p.WorktreesDir,
 		p.MessagesDir,
@@ -64,6 +203,17 @@ func (p *Paths) EnsureDirectories() error {
 	return nil
 }
 
+// AuditLog returns the path to the append-only audit log
+func (p *Paths) AuditLog() string {
+	return filepath.Join(p.Root, "audit.log")
+}
+
+// UpgradeCheckFile returns the path to the result of the latest check for a
+// newer multiclaude release
+func (p *Paths) UpgradeCheckFile() string {
+	return filepath.Join(p.Root, "upgrade-check.json")
+}
+
 // RepoDir returns the path for a specific repository
 func (p *Paths) RepoDir(repoName string) string {
 	return filepath.Join(p.ReposDir, repoName)
@@ -99,6 +249,11 @@ func (p *Paths) WorkersOutputDir(repoName string) string {
 	return filepath.Join(p.RepoOutputDir(repoName), "workers")
 }
 
+// ArchivesDir returns the path for a repository's archived branch bundles
+func (p *Paths) ArchivesDir(repoName string) string {
+	return filepath.Join(p.RepoOutputDir(repoName), "archives")
+}
+
 // AgentLogFile returns the path to an agent's log file
 func (p *Paths) AgentLogFile(repoName, agentName string, isWorker bool) string {
 	if isWorker {
@@ -107,6 +262,33 @@ func (p *Paths) AgentLogFile(repoName, agentName string, isWorker bool) string {
 	return filepath.Join(p.RepoOutputDir(repoName), agentName+".log")
 }
 
+// WalkLogFiles calls fn for every regular *.log file under OutputDir.
+// OutputDir itself may be a symlink (e.g. onto another disk), but symlinks
+// inside it are not followed, so the walk never leaves the output directory.
+func (p *Paths) WalkLogFiles(fn func(path string, info os.FileInfo) error) error {
+	root, err := filepath.EvalSymlinks(p.OutputDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if !info.Mode().IsRegular() || !strings.HasSuffix(path, ".log") {
+			return nil
+		}
+		// Report paths under OutputDir as configured, not the resolved target
+		if rel, err := filepath.Rel(root, path); err == nil {
+			path = filepath.Join(p.OutputDir, rel)
+		}
+		return fn(path, info)
+	})
+}
+
 // AgentClaudeConfigDir returns the path for a specific agent's Claude config directory
 // This is used to set CLAUDE_CONFIG_DIR for per-agent slash commands
 func (p *Paths) AgentClaudeConfigDir(repoName, agentName string) string {
@@ -118,19 +300,25 @@ func (p *Paths) AgentCommandsDir(repoName, agentName string) string {
 	return filepath.Join(p.AgentClaudeConfigDir(repoName, agentName), "commands")
 }
 
+// RebasePath moves an absolute path from under oldRoot to the same place
+// under newRoot, e.g. after the multiclaude directory was moved to another
+// disk. It reports false, and returns path unchanged, if path is not oldRoot
+// or inside it.
+func RebasePath(path, oldRoot, newRoot string) (string, bool) {
+	cleaned := filepath.Clean(path)
+	oldRoot = filepath.Clean(oldRoot)
+	if cleaned == oldRoot {
+		return filepath.Clean(newRoot), true
+	}
+	rel, ok := strings.CutPrefix(cleaned, oldRoot+string(filepath.Separator))
+	if !ok {
+		return path, false
+	}
+	return filepath.Join(newRoot, rel), true
+}
+
 // NewTestPaths creates a Paths instance for testing with all paths under tmpDir.
 // This eliminates duplicate test setup code and ensures consistent path configuration.
 func NewTestPaths(tmpDir string) *Paths {
-	return &Paths{
-		Root:            tmpDir,
-		DaemonPID:       filepath.Join(tmpDir, "daemon.pid"),
-		DaemonSock:      filepath.Join(tmpDir, "daemon.sock"),
-		DaemonLog:       filepath.Join(tmpDir, "daemon.log"),
-		StateFile:       filepath.Join(tmpDir, "state.json"),
-		ReposDir:        filepath.Join(tmpDir, "repos"),
-		WorktreesDir:    filepath.Join(tmpDir, "wts"),
-		MessagesDir:     filepath.Join(tmpDir, "messages"),
-		OutputDir:       filepath.Join(tmpDir, "output"),
-		ClaudeConfigDir: filepath.Join(tmpDir, "claude-config"),
-	}
+	return NewPaths(tmpDir)
 }
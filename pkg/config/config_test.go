@@ -8,6 +8,7 @@ import (
 )
 
 func TestDefaultPaths(t *testing.T) {
+	t.Setenv(XDGEnvVar, "0")
 	paths, err := DefaultPaths()
 	if err != nil {
 		t.Fatalf("DefaultPaths() failed: %v", err)
@@ -122,6 +123,18 @@ func TestRepoPaths(t *testing.T) {
 	if agentMsgDir != expected {
 		t.Errorf("AgentMessagesDir() = %q, want %q", agentMsgDir, expected)
 	}
+
+	auditLog := paths.AuditLog()
+	expected = filepath.Join(tmpDir, "audit.log")
+	if auditLog != expected {
+		t.Errorf("AuditLog() = %q, want %q", auditLog, expected)
+	}
+
+	upgradeCheck := paths.UpgradeCheckFile()
+	expected = filepath.Join(tmpDir, "upgrade-check.json")
+	if upgradeCheck != expected {
+		t.Errorf("UpgradeCheckFile() = %q, want %q", upgradeCheck, expected)
+	}
 }
 
 func TestOutputPaths(t *testing.T) {
@@ -148,6 +161,13 @@ func TestOutputPaths(t *testing.T) {
 		t.Errorf("WorkersOutputDir() = %q, want %q", workersDir, expected)
 	}
 
+	// Test ArchivesDir
+	archivesDir := paths.ArchivesDir(repoName)
+	expected = filepath.Join(tmpDir, "output", repoName, "archives")
+	if archivesDir != expected {
+		t.Errorf("ArchivesDir() = %q, want %q", archivesDir, expected)
+	}
+
 	// Test AgentLogFile for system agent (not worker)
 	supervisorLog := paths.AgentLogFile(repoName, "supervisor", false)
 	expected = filepath.Join(tmpDir, "output", repoName, "supervisor.log")
@@ -269,3 +289,229 @@ func TestNewTestPaths(t *testing.T) {
 		t.Errorf("RepoDir() on NewTestPaths result = %q, unexpected", repoDir)
 	}
 }
+
+func TestLoadOverrides(t *testing.T) {
+	t.Run("no config file", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		paths := NewTestPaths(tmpDir)
+		if err := paths.LoadOverrides(); err != nil {
+			t.Fatalf("LoadOverrides() failed: %v", err)
+		}
+		if paths.OutputDir != filepath.Join(tmpDir, "output") {
+			t.Errorf("OutputDir = %q, want default", paths.OutputDir)
+		}
+	})
+
+	t.Run("relocates output and worktrees", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		dataDir := t.TempDir()
+		config := `{"output_dir": "` + filepath.Join(dataDir, "output") + `", "worktrees_dir": "` + filepath.Join(dataDir, "wts") + `/"}`
+		if err := os.WriteFile(filepath.Join(tmpDir, PathsConfigFile), []byte(config), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+
+		paths := NewTestPaths(tmpDir)
+		if err := paths.LoadOverrides(); err != nil {
+			t.Fatalf("LoadOverrides() failed: %v", err)
+		}
+		if paths.OutputDir != filepath.Join(dataDir, "output") {
+			t.Errorf("OutputDir = %q, want %q", paths.OutputDir, filepath.Join(dataDir, "output"))
+		}
+		if paths.WorktreesDir != filepath.Join(dataDir, "wts") {
+			t.Errorf("WorktreesDir = %q, want %q", paths.WorktreesDir, filepath.Join(dataDir, "wts"))
+		}
+		if got := paths.AgentLogFile("repo", "worker", true); !strings.HasPrefix(got, dataDir) {
+			t.Errorf("AgentLogFile() = %q, want it under %q", got, dataDir)
+		}
+		if paths.ReposDir != filepath.Join(tmpDir, "repos") {
+			t.Errorf("ReposDir = %q, should not be relocated", paths.ReposDir)
+		}
+	})
+
+	t.Run("rejects relative paths", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(tmpDir, PathsConfigFile), []byte(`{"output_dir": "data/output"}`), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		if err := NewTestPaths(tmpDir).LoadOverrides(); err == nil {
+			t.Error("LoadOverrides() should reject a relative output_dir")
+		}
+	})
+
+	t.Run("rejects unknown layout", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(tmpDir, PathsConfigFile), []byte(`{"layout": "flat"}`), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		if err := NewTestPaths(tmpDir).LoadOverrides(); err == nil {
+			t.Error("LoadOverrides() should reject an unknown layout")
+		}
+	})
+
+	t.Run("rejects invalid JSON", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(tmpDir, PathsConfigFile), []byte(`{`), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		if err := NewTestPaths(tmpDir).LoadOverrides(); err == nil {
+			t.Error("LoadOverrides() should reject invalid JSON")
+		}
+	})
+}
+
+func TestWalkLogFilesSymlinkedOutputDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := t.TempDir()
+	outsideDir := t.TempDir()
+
+	// ~/.multiclaude/output -> data drive
+	paths := NewTestPaths(tmpDir)
+	if err := os.Symlink(dataDir, paths.OutputDir); err != nil {
+		t.Fatalf("failed to symlink output dir: %v", err)
+	}
+
+	workersDir := filepath.Join(dataDir, "repo", "workers")
+	if err := os.MkdirAll(workersDir, 0755); err != nil {
+		t.Fatalf("failed to create workers dir: %v", err)
+	}
+	for _, name := range []string{filepath.Join(dataDir, "repo", "supervisor.log"), filepath.Join(workersDir, "fox.log"), filepath.Join(workersDir, "notes.txt")} {
+		if err := os.WriteFile(name, []byte("log\n"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	// Symlinks inside the output directory must not be followed out of it
+	if err := os.WriteFile(filepath.Join(outsideDir, "outside.log"), []byte("log\n"), 0644); err != nil {
+		t.Fatalf("failed to write outside log: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outsideDir, "outside.log"), filepath.Join(dataDir, "repo", "linked.log")); err != nil {
+		t.Fatalf("failed to symlink log: %v", err)
+	}
+	if err := os.Symlink(outsideDir, filepath.Join(dataDir, "repo", "linked-dir")); err != nil {
+		t.Fatalf("failed to symlink dir: %v", err)
+	}
+
+	var found []string
+	err := paths.WalkLogFiles(func(path string, info os.FileInfo) error {
+		found = append(found, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkLogFiles() failed: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(paths.OutputDir, "repo", "supervisor.log"),
+		filepath.Join(paths.OutputDir, "repo", "workers", "fox.log"),
+	}
+	if strings.Join(found, ",") != strings.Join(want, ",") {
+		t.Errorf("WalkLogFiles() found %v, want %v", found, want)
+	}
+}
+
+func TestWalkLogFilesMissingOutputDir(t *testing.T) {
+	paths := NewTestPaths(t.TempDir())
+	called := false
+	err := paths.WalkLogFiles(func(path string, info os.FileInfo) error {
+		called = true
+		return nil
+	})
+	if err != nil {
+		t.Errorf("WalkLogFiles() on a missing dir failed: %v", err)
+	}
+	if called {
+		t.Error("WalkLogFiles() should not report files for a missing dir")
+	}
+}
+
+func TestRebasePath(t *testing.T) {
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"/old/mc/wts/repo/fox", "/new/disk/mc/wts/repo/fox", true},
+		{"/old/mc", "/new/disk/mc", true},
+		{"/old/mc/", "/new/disk/mc", true},
+		{"/old/mc2/wts/repo/fox", "/old/mc2/wts/repo/fox", false},
+		{"/elsewhere/env", "/elsewhere/env", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := RebasePath(tt.path, "/old/mc", "/new/disk/mc")
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("RebasePath(%q) = %q, %v; want %q, %v", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestXDGPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", "/xdg/state")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "relative/cache")
+
+	paths := XDGPaths(home)
+	if paths.Root != "/xdg/state/multiclaude" {
+		t.Errorf("Root = %q, want /xdg/state/multiclaude", paths.Root)
+	}
+	if want := filepath.Join(home, ".config", "multiclaude"); paths.ConfigDir != want {
+		t.Errorf("ConfigDir = %q, want %q", paths.ConfigDir, want)
+	}
+	// A relative XDG variable is ignored, as the spec requires
+	if want := filepath.Join(home, ".cache", "multiclaude"); paths.CacheDir != want {
+		t.Errorf("CacheDir = %q, want %q", paths.CacheDir, want)
+	}
+	if paths.DaemonLog != filepath.Join(paths.CacheDir, "daemon.log") {
+		t.Errorf("DaemonLog = %q, want it under CacheDir", paths.DaemonLog)
+	}
+	if paths.OutputDir != filepath.Join(paths.CacheDir, "output") {
+		t.Errorf("OutputDir = %q, want it under CacheDir", paths.OutputDir)
+	}
+	if paths.StateFile != "/xdg/state/multiclaude/state.json" {
+		t.Errorf("StateFile = %q, want it under Root", paths.StateFile)
+	}
+}
+
+func TestUseXDG(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(XDGEnvVar, "")
+
+	if UseXDG(home) {
+		t.Error("UseXDG() = true without env or config, want false")
+	}
+
+	configDir := XDGPaths(home).ConfigDir
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("failed to create config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, PathsConfigFile), []byte(`{"layout": "xdg"}`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if !UseXDG(home) {
+		t.Error("UseXDG() = false with layout xdg in paths.json, want true")
+	}
+	paths, err := DefaultPaths()
+	if err != nil {
+		t.Fatalf("DefaultPaths() failed: %v", err)
+	}
+	if paths.Root != XDGPaths(home).Root {
+		t.Errorf("DefaultPaths().Root = %q, want the XDG state directory", paths.Root)
+	}
+
+	// The environment variable wins over the config file
+	t.Setenv(XDGEnvVar, "0")
+	if UseXDG(home) {
+		t.Errorf("UseXDG() = true with %s=0, want false", XDGEnvVar)
+	}
+	t.Setenv(XDGEnvVar, "1")
+	if err := os.Remove(filepath.Join(configDir, PathsConfigFile)); err != nil {
+		t.Fatalf("failed to remove config: %v", err)
+	}
+	if !UseXDG(home) {
+		t.Errorf("UseXDG() = false with %s=1, want true", XDGEnvVar)
+	}
+}
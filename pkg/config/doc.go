@@ -30,12 +30,42 @@ func DirectoryDocs() []PathDoc {
 			Type:        "file",
 			Notes:       "Useful for debugging daemon issues. Check this when agents behave unexpectedly.",
 		},
+		{
+			Path:        "audit.log",
+			Description: "Append-only NDJSON record of mutating operations",
+			Type:        "file",
+			Notes:       "One JSON object per line with time, command, redacted args, caller and result. Query with 'multiclaude audit'. Preserved by stop-all --clean.",
+		},
+		{
+			Path:        "upgrade-check.json",
+			Description: "Result of the latest check for a newer multiclaude release",
+			Type:        "file",
+			Notes:       "Written by 'multiclaude daemon check-upgrade' and by the daemon's periodic check, if one is configured. Shown by 'multiclaude daemon status'.",
+		},
 		{
 			Path:        "state.json",
 			Description: "Central state file containing all tracked repositories and agents",
 			Type:        "file",
 			Notes:       "Written atomically via temp file + rename. See StateDoc() for format details.",
 		},
+		{
+			Path:        "state-backups/",
+			Description: "Recent copies of state.json, one per save that changed it",
+			Type:        "directory",
+			Notes:       "Files state-<id>.json plus manifest.json listing them and the retention set by 'multiclaude state backups config' (default: the last 10 plus one a day for 7 days). Restore with 'multiclaude state restore'. Left out of bug reports by default.",
+		},
+		{
+			Path:        "paths.json",
+			Description: "Optional overrides that move output/ and wts/ to other locations",
+			Type:        "file",
+			Notes:       "JSON object with absolute output_dir and/or worktrees_dir, e.g. on a larger disk. Read when the CLI or daemon starts. Symlinking output/ or wts/ also works. In the XDG layout it lives in the config directory, where layout \"xdg\" selects that layout.",
+		},
+		{
+			Path:        "lifecycle-hooks.json",
+			Description: "Optional lifecycle hooks run for every repository",
+			Type:        "file",
+			Notes:       "Maps the events worker_created, worker_completed, agent_removed and repo_initialized to shell commands the daemon runs on the host, before those in a repository's .multiclaude/lifecycle-hooks.json. Lives in the config directory. Try them with 'multiclaude hooks test <event>'.",
+		},
 		{
 			Path:        "repos/",
 			Description: "Contains cloned git repositories (bare or working)",
@@ -52,7 +82,7 @@ func DirectoryDocs() []PathDoc {
 			Path:        "wts/",
 			Description: "Git worktrees for isolated agent working directories",
 			Type:        "directory",
-			Notes:       "Each agent gets its own worktree to work independently.",
+			Notes:       "Each agent gets its own worktree to work independently. Relocatable via worktrees_dir in paths.json.",
 		},
 		{
 			Path:        "wts/<repo-name>/",
@@ -66,6 +96,12 @@ func DirectoryDocs() []PathDoc {
 			Type:        "directory",
 			Notes:       "Agent types: supervisor, merge-queue, or worker names like happy-platypus.",
 		},
+		{
+			Path:        "wts/<repo-name>/<agent-name>/.multiclaude/context/",
+			Description: "Context files handed to the agent with --context-file or --context -",
+			Type:        "directory",
+			Notes:       "Holds a generated .gitignore so the files stay off the agent's branch. Removed with the worktree.",
+		},
 		{
 			Path:        "messages/",
 			Description: "Inter-agent message files for coordination",
@@ -82,7 +118,7 @@ func DirectoryDocs() []PathDoc {
 			Path:        "messages/<repo-name>/<agent-name>/",
 			Description: "Inbox directory for a specific agent",
 			Type:        "directory",
-			Notes:       "Contains msg-<uuid>.json files addressed to this agent.",
+			Notes:       "Contains msg-<uuid>.json files addressed to this agent, msg-<uuid>.body files holding bodies too large to deliver inline, and a .send.lock file for idempotent sends.",
 		},
 		{
 			Path:        "prompts/",
@@ -110,17 +146,39 @@ func StateDocs() []StateFieldDoc {
 		{Field: "repos.<name>.github_url", Type: "string", Description: "GitHub URL of the repository"},
 		{Field: "repos.<name>.tmux_session", Type: "string", Description: "Name of the tmux session for this repo"},
 		{Field: "repos.<name>.agents", Type: "map[string]Agent", Description: "Map of agent name to agent state"},
+		{Field: "repos.<name>.config", Type: "map[string]string", Description: "Settings changed with multiclaude config, by key (e.g. mq-track, env-file, transport-worker), in canonical form; keys at their default are left out. Replaces the per-setting fields of older versions, which are still read (omitempty)"},
+		{Field: "repos.<name>.has_submodules", Type: "bool", Description: "Whether the repository declares git submodules, which are checked out in new worktrees; refreshed by the daemon (omitempty)"},
+		{Field: "repos.<name>.no_supervisor", Type: "bool", Description: "Repository was initialized with --no-supervisor and has no supervisor agent yet; cleared by agent add-supervisor (omitempty)"},
+		{Field: "repos.<name>.context_vars", Type: "map[string]string", Description: "Context values set with workspace set-context, listed in the Current Context section of prompt files (omitempty)"},
+		{Field: "repos.<name>.tracked_prs", Type: "[]TrackedPR", Description: "PRs the merge-queue agent is tracking (number, url, status, notes, last_action); pruned when merged or closed (omitempty)"},
 
 		// Agent fields
-		{Field: "repos.<name>.agents.<name>.type", Type: "string", Description: "Agent type: supervisor, worker, merge-queue, or workspace"},
+		{Field: "repos.<name>.agents.<name>.type", Type: "string", Description: "Agent type: supervisor, worker, merge-queue, workspace, review, or ephemeral"},
 		{Field: "repos.<name>.agents.<name>.worktree_path", Type: "string", Description: "Absolute path to the agent's git worktree"},
 		{Field: "repos.<name>.agents.<name>.tmux_window", Type: "string", Description: "Tmux window name for this agent"},
+		{Field: "repos.<name>.agents.<name>.tmux_window_id", Type: "string", Description: "Immutable tmux window ID (e.g. \"@3\") the daemon targets the agent by, so renaming the window does not break delivery; resolved from tmux_window when missing (omitempty)"},
 		{Field: "repos.<name>.agents.<name>.session_id", Type: "string", Description: "UUID for Claude session context"},
 		{Field: "repos.<name>.agents.<name>.pid", Type: "int", Description: "Process ID of the Claude process"},
 		{Field: "repos.<name>.agents.<name>.task", Type: "string", Description: "Task description (workers only, omitempty)"},
+		{Field: "repos.<name>.agents.<name>.origin_worker", Type: "string", Description: "Worker this one was split from with work split (workers only, omitempty)"},
+		{Field: "repos.<name>.agents.<name>.context_files", Type: "[]string", Description: "Context files copied into the worktree with --context-file or --context -, relative to it (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.task_updates", Type: "[]TaskUpdate", Description: "Changes to the task with work set-task (old_task, new_task, updated_at); copied into the task history entry (workers only, omitempty)"},
 		{Field: "repos.<name>.agents.<name>.created_at", Type: "time.Time", Description: "When the agent was created"},
 		{Field: "repos.<name>.agents.<name>.last_nudge", Type: "time.Time", Description: "Last time agent was nudged (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.environment", Type: "map[string]string", Description: "Variables set for this agent alone (add_agent env or agent set-env), set again when the daemon restarts it; values are redacted from the audit log and bug report (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.watch_pr", Type: "bool", Description: "Daemon polls the agent's PR every 5 minutes and messages the agent when it is merged or closed, then clears the flag (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.branch", Type: "string", Description: "Branch last seen checked out in the agent's worktree by the filesystem watcher (`daemon start --watch-fs`) (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.upstream_branch", Type: "string", Description: "Remote-tracking branch set with `workspace set-upstream`, which `workspace create-pr` targets (workspaces only, omitempty)"},
+		{Field: "repos.<name>.agents.<name>.docs_hash", Type: "string", Description: "Hash stamped on the CLI reference in the agent's prompt, compared with the daemon binary's to find agents documented by another multiclaude version (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.last_seen_messages", Type: "int", Description: "Unread messages when the wake loop last looked; only more than this are reported (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.last_seen_review_comments", Type: "int", Description: "Comments and reviews on the agent's PR when the wake loop last looked (workers only, omitempty)"},
+		{Field: "repos.<name>.agents.<name>.pr_comments_read_at", Type: "time.Time", Description: "When the newest PR comment shown by agent pr-comments was made; later ones are marked new (workers only, omitempty)"},
+		{Field: "repos.<name>.agents.<name>.last_seen_behind", Type: "int", Description: "Commits the agent's branch was behind main when the wake loop last looked (workers only, omitempty)"},
+		{Field: "repos.<name>.agents.<name>.restart_attempts", Type: "[]time.Time", Description: "When the daemon automatically restarted the agent within the crash-loop window (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.quarantined_at", Type: "time.Time", Description: "When the daemon stopped restarting the agent because it kept dying; cleared by agent restart --clear-quarantine (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.quarantine_output", Type: "string", Description: "End of the agent's pane when it was quarantined (omitempty)"},
 		{Field: "repos.<name>.agents.<name>.ready_for_cleanup", Type: "bool", Description: "Whether worker is ready to be cleaned up (workers only, omitempty)"},
+		{Field: "repos.<name>.agents.<name>.status", Type: "string", Description: "Status set by hand with agent set-status, e.g. \"blocked\", shown in work list instead of the computed one; cleared when the agent completes (omitempty)"},
 	}
 }
 
@@ -139,7 +197,11 @@ func MessageDocs() []MessageFieldDoc {
 		{Field: "to", Type: "string", Description: "Recipient agent name"},
 		{Field: "timestamp", Type: "time.Time", Description: "When the message was sent"},
 		{Field: "body", Type: "string", Description: "Message content (markdown text)"},
-		{Field: "status", Type: "string", Description: "Message status: pending, delivered, read, or acked"},
+		{Field: "status", Type: "string", Description: "Message status: pending, delivered, read, acked, or failed (the recipient was removed before reading it; the sender, or the supervisor if the sender is gone too, gets a notice quoting it)"},
 		{Field: "acked_at", Type: "time.Time", Description: "When the message was acknowledged (omitempty)"},
+		{Field: "read_at", Type: "time.Time", Description: "When the message was marked read (omitempty)"},
+		{Field: "auto_acked", Type: "bool", Description: "Whether the daemon acknowledged the message after auto_ack_after (omitempty)"},
+		{Field: "body_file", Type: "string", Description: "File next to the message holding a body too large to deliver inline; body then holds an excerpt (omitempty)"},
+		{Field: "idempotency_key", Type: "string", Description: "Key given with send-message --idempotency-key; a second send with the same key, sender and recipient returns this message instead (omitempty)"},
 	}
 }
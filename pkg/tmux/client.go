@@ -48,7 +48,10 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Client wraps tmux operations for programmatic control of tmux sessions,
@@ -169,6 +172,105 @@ func (c *Client) ListSessions(ctx context.Context) ([]string, error) {
 	return sessions, nil
 }
 
+// SetSessionOption sets an option of a session, such as a user option
+// (whose name starts with @) to tag the session with.
+func (c *Client) SetSessionOption(ctx context.Context, session, option, value string) error {
+	cmd := c.tmuxCmd(ctx, "set-option", "-t", session, option, value)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &CommandError{Op: "set-option", Session: session, Err: err}
+	}
+	return nil
+}
+
+// ListSessionOption returns every session on the server with the value of
+// one of its options, "" where it is not set. The fields are separated by a
+// colon, which tmux does not allow in session names; tabs cannot be used
+// because tmux 3.3+ prints non-printable characters in formats as "_".
+func (c *Client) ListSessionOption(ctx context.Context, option string) (map[string]string, error) {
+	cmd := c.tmuxCmd(ctx, "list-sessions", "-F", "#{session_name}:#{"+option+"}")
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			// No sessions running
+			if exitErr.ExitCode() == 1 {
+				return map[string]string{}, nil
+			}
+		}
+		return nil, &CommandError{Op: "list-sessions", Err: err}
+	}
+
+	sessions := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(line, ":")
+		sessions[name] = value
+	}
+	return sessions, nil
+}
+
+// SetEnvironment sets a variable in the session environment using
+// tmux set-environment. Windows and panes created afterwards inherit it;
+// shells that are already running must re-read it (see tmux show-environment).
+// The value is passed as an argument and never typed into a pane.
+func (c *Client) SetEnvironment(ctx context.Context, session, name, value string) error {
+	cmd := c.tmuxCmd(ctx, "set-environment", "-t", session, name, value)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &CommandError{Op: "set-environment", Session: session, Err: err}
+	}
+	return nil
+}
+
+// ImportEnvironmentCommand returns a shell snippet that, when run in a pane,
+// imports the named session variables into the pane's shell. Use it to pick
+// up variables set with SetEnvironment after the shell was started, without
+// typing their values into the pane. Returns "" if names is empty.
+func ImportEnvironmentCommand(names ...string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	shows := make([]string, len(names))
+	for i, name := range names {
+		shows[i] = "tmux show-environment -s " + name
+	}
+	return fmt.Sprintf(`eval "$(%s)"`, strings.Join(shows, "; "))
+}
+
+// GetEnvironment returns the value of a variable in the session environment.
+// The second return value is false if the variable is not set.
+func (c *Client) GetEnvironment(ctx context.Context, session, name string) (string, bool, error) {
+	cmd := c.tmuxCmd(ctx, "show-environment", "-t", session, name)
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", false, ctx.Err()
+		}
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			// Unknown variable
+			return "", false, nil
+		}
+		return "", false, &CommandError{Op: "show-environment", Session: session, Err: err}
+	}
+
+	line := strings.TrimRight(string(output), "\n")
+	value, found := strings.CutPrefix(line, name+"=")
+	if !found {
+		// A leading "-" marks a variable that was removed from the environment
+		return "", false, nil
+	}
+	return value, true, nil
+}
+
 // =============================================================================
 // Window Management
 // =============================================================================
@@ -187,10 +289,15 @@ func (c *Client) CreateWindow(ctx context.Context, session, windowName string) e
 }
 
 // HasWindow checks if a window with the given name exists in the session.
-// Uses exact matching via tmux format strings.
+// Uses exact matching via tmux format strings. A window ID (see IsWindowID)
+// is matched against the windows' IDs instead.
 func (c *Client) HasWindow(ctx context.Context, session, windowName string) (bool, error) {
 	// Use -F to get just the window names, one per line
-	cmd := c.tmuxCmd(ctx, "list-windows", "-t", session, "-F", "#{window_name}")
+	field := "#{window_name}"
+	if IsWindowID(windowName) {
+		field = "#{window_id}"
+	}
+	cmd := c.tmuxCmd(ctx, "list-windows", "-t", session, "-F", field)
 	output, err := cmd.Output()
 	if err != nil {
 		if ctx.Err() != nil {
@@ -209,6 +316,47 @@ func (c *Client) HasWindow(ctx context.Context, session, windowName string) (boo
 	return false, nil
 }
 
+// IsWindowID reports whether window is a tmux window ID such as "@3"
+// rather than a name. IDs are assigned by tmux when a window is created and,
+// unlike names, never change for the window's lifetime. Every method taking
+// a window name also accepts an ID.
+func IsWindowID(window string) bool {
+	if len(window) < 2 || window[0] != '@' {
+		return false
+	}
+	_, err := strconv.Atoi(window[1:])
+	return err == nil
+}
+
+// GetWindowID returns the ID of the window with exactly the given name.
+// Returns a *WindowNotFoundError if the session has no such window.
+func (c *Client) GetWindowID(ctx context.Context, session, windowName string) (string, error) {
+	windows, err := c.ListWindowInfo(ctx, session)
+	if err != nil {
+		return "", err
+	}
+	for _, w := range windows {
+		if w.Name == windowName {
+			return w.ID, nil
+		}
+	}
+	return "", &WindowNotFoundError{Session: session, Window: windowName}
+}
+
+// RenameWindow renames a window, given by name or ID. This also turns off
+// tmux's automatic-rename for the window.
+func (c *Client) RenameWindow(ctx context.Context, session, window, newName string) error {
+	target := fmt.Sprintf("%s:%s", session, window)
+	cmd := c.tmuxCmd(ctx, "rename-window", "-t", target, newName)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &CommandError{Op: "rename-window", Session: session, Window: window, Err: err}
+	}
+	return nil
+}
+
 // KillWindow terminates a specific window in a session.
 func (c *Client) KillWindow(ctx context.Context, session, windowName string) error {
 	target := fmt.Sprintf("%s:%s", session, windowName)
@@ -240,6 +388,77 @@ func (c *Client) ListWindows(ctx context.Context, session string) ([]string, err
 	return windows, nil
 }
 
+// WindowInfo describes a window as listed by ListWindowInfo.
+type WindowInfo struct {
+	Index    int
+	ID       string // immutable window ID, e.g. "@3"
+	Name     string
+	Active   bool
+	Panes    int
+	Activity time.Time // last output in any of the window's panes
+}
+
+// windowInfoFormat lists a window's fields separated by colons, since tmux
+// 3.3+ prints tabs in formats as "_". The name comes last so a name
+// containing a colon is kept whole.
+const windowInfoFormat = "#{window_index}:#{window_active}:#{window_panes}:#{window_activity}:#{window_id}:#{window_name}"
+
+// ListWindowInfo returns the windows of the specified session in index order,
+// with each window's index, ID, name, active flag, pane count and last
+// activity.
+func (c *Client) ListWindowInfo(ctx context.Context, session string) ([]WindowInfo, error) {
+	cmd := c.tmuxCmd(ctx, "list-windows", "-t", session, "-F", windowInfoFormat)
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &CommandError{Op: "list-windows", Session: session, Err: err}
+	}
+
+	windows, err := parseWindowInfo(string(output))
+	if err != nil {
+		return nil, &CommandError{Op: "list-windows", Session: session, Err: err}
+	}
+	return windows, nil
+}
+
+// parseWindowInfo parses list-windows output in windowInfoFormat, sorting
+// the windows by index.
+func parseWindowInfo(output string) ([]WindowInfo, error) {
+	windows := []WindowInfo{}
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, ":", 6)
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("unexpected list-windows line %q", line)
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid window index %q: %w", fields[0], err)
+		}
+		panes, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pane count %q: %w", fields[2], err)
+		}
+		window := WindowInfo{
+			Index:  index,
+			ID:     fields[4],
+			Name:   fields[5],
+			Active: fields[1] == "1",
+			Panes:  panes,
+		}
+		if activity, err := strconv.ParseInt(fields[3], 10, 64); err == nil && activity > 0 {
+			window.Activity = time.Unix(activity, 0)
+		}
+		windows = append(windows, window)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Index < windows[j].Index })
+	return windows, nil
+}
+
 // =============================================================================
 // Text Input - The Key Differentiator
 // =============================================================================
@@ -365,6 +584,137 @@ func (c *Client) GetPanePID(ctx context.Context, session, windowName string) (in
 	return pid, nil
 }
 
+// CapturePane returns the last lines of the first pane of a window, as shown
+// on screen and in its scrollback, without trailing blank lines.
+func (c *Client) CapturePane(ctx context.Context, session, windowName string, lines int) (string, error) {
+	target := fmt.Sprintf("%s:%s", session, windowName)
+	cmd := c.tmuxCmd(ctx, "capture-pane", "-p", "-t", target, "-S", fmt.Sprintf("-%d", lines))
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", &CommandError{Op: "capture-pane", Session: session, Window: windowName, Err: err}
+	}
+
+	// -S counts back from the top of the visible screen, so the capture
+	// can be longer than asked for
+	text := strings.TrimRight(string(output), "\n ")
+	all := strings.Split(text, "\n")
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return strings.Join(all, "\n"), nil
+}
+
+// =============================================================================
+// Pane Environment
+// =============================================================================
+
+// RestartGracePeriod is how long RestartWithEnv waits for the processes in a
+// pane to exit after SIGTERM before respawning the pane anyway.
+const RestartGracePeriod = 5 * time.Second
+
+// SetPaneEnv sets key=value in the environment of the window's session using
+// tmux set-environment. tmux keeps environments per session, not per pane, so
+// every window of the session sees the change. Only processes started
+// afterwards inherit it: a process already running in the pane (such as
+// Claude) keeps its environment until it is restarted or re-reads the
+// variable itself, e.g. with ImportEnvironmentCommand. Use RestartWithEnv to
+// give variables to one pane only, restarting it. The value is passed as an
+// argument and never typed into a pane.
+func (c *Client) SetPaneEnv(ctx context.Context, session, windowName, key, value string) error {
+	// tmux resolves a session:window target for set-environment to the
+	// session alone, so check the window explicitly
+	exists, err := c.HasWindow(ctx, session, windowName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &WindowNotFoundError{Session: session, Window: windowName}
+	}
+
+	target := fmt.Sprintf("%s:%s", session, windowName)
+	cmd := c.tmuxCmd(ctx, "set-environment", "-t", target, key, value)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &CommandError{Op: "set-environment", Session: session, Window: windowName, Err: err}
+	}
+	return nil
+}
+
+// RestartWithEnv restarts the window's pane with env added to its
+// environment. The processes running under the pane's shell are sent SIGTERM
+// and given RestartGracePeriod to exit, then the pane is respawned with its
+// original command (tmux respawn-pane -k), which kills anything left over.
+//
+// The variables are set in the respawned pane only (respawn-pane -e). Unlike
+// SetPaneEnv, this leaves the session environment, which every window created
+// later starts from, unchanged.
+//
+// The pane's original command is normally a shell, so whatever ran inside it
+// must be relaunched by the caller; the pane's PID changes too.
+func (c *Client) RestartWithEnv(ctx context.Context, session, windowName string, env map[string]string) error {
+	exists, err := c.HasWindow(ctx, session, windowName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &WindowNotFoundError{Session: session, Window: windowName}
+	}
+
+	pid, err := c.GetPanePID(ctx, session, windowName)
+	if err != nil {
+		return err
+	}
+
+	// Ask the pane's child processes to exit cleanly. pkill exits 1 when
+	// nothing matched, which just means the shell was idle.
+	if err := exec.CommandContext(ctx, "pkill", "-TERM", "-P", strconv.Itoa(pid)).Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			return &CommandError{Op: "pkill", Session: session, Window: windowName, Err: err}
+		}
+	}
+
+	deadline := time.Now().Add(RestartGracePeriod)
+	for time.Now().Before(deadline) && hasChildProcesses(ctx, pid) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	target := fmt.Sprintf("%s:%s", session, windowName)
+	args := []string{"respawn-pane", "-k", "-t", target}
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "-e", key+"="+env[key])
+	}
+	cmd := c.tmuxCmd(ctx, args...)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &CommandError{Op: "respawn-pane", Session: session, Window: windowName, Err: err}
+	}
+	return nil
+}
+
+// hasChildProcesses reports whether the process pid has any children left
+func hasChildProcesses(ctx context.Context, pid int) bool {
+	return exec.CommandContext(ctx, "pgrep", "-P", strconv.Itoa(pid)).Run() == nil
+}
+
 // =============================================================================
 // Output Capture - Third Differentiator
 // =============================================================================
@@ -378,10 +728,17 @@ func (c *Client) GetPanePID(ctx context.Context, session, windowName string) (in
 //	// ... run commands in the pane ...
 //	client.StopPipePane(ctx, "my-session", "my-window")
 func (c *Client) StartPipePane(ctx context.Context, session, windowName, outputFile string) error {
+	// cat >> appends to the file so output is preserved
+	return c.StartPipePaneCommand(ctx, session, windowName, fmt.Sprintf("cat >> '%s'", outputFile))
+}
+
+// StartPipePaneCommand starts piping pane output to the stdin of a shell
+// command, which tmux runs for as long as the pipe is open. StartPipePane
+// is StartPipePaneCommand with a command appending to a file.
+func (c *Client) StartPipePaneCommand(ctx context.Context, session, windowName, command string) error {
 	target := fmt.Sprintf("%s:%s", session, windowName)
 	// Use -o to open a pipe (output only, not input)
-	// cat >> appends to the file so output is preserved
-	cmd := c.tmuxCmd(ctx, "pipe-pane", "-o", "-t", target, fmt.Sprintf("cat >> '%s'", outputFile))
+	cmd := c.tmuxCmd(ctx, "pipe-pane", "-o", "-t", target, command)
 	if err := cmd.Run(); err != nil {
 		if ctx.Err() != nil {
 			return ctx.Err()
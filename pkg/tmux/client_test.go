@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -259,6 +260,61 @@ func TestHasWindow(t *testing.T) {
 	}
 }
 
+func TestWindowID(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	sessionName := uniqueSessionName()
+
+	if err := client.CreateSession(ctx, sessionName, true); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer client.KillSession(ctx, sessionName)
+	if err := client.CreateWindow(ctx, sessionName, "agent"); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	id, err := client.GetWindowID(ctx, sessionName, "agent")
+	if err != nil {
+		t.Fatalf("GetWindowID failed: %v", err)
+	}
+	if !IsWindowID(id) {
+		t.Fatalf("GetWindowID = %q, want an @N window ID", id)
+	}
+	if _, err := client.GetWindowID(ctx, sessionName, "age"); !IsWindowNotFound(err) {
+		t.Errorf("GetWindowID of a name prefix = %v, want WindowNotFoundError", err)
+	}
+
+	// The ID keeps addressing the window after it is renamed
+	if err := client.RenameWindow(ctx, sessionName, id, "renamed"); err != nil {
+		t.Fatalf("RenameWindow failed: %v", err)
+	}
+	if exists, err := client.HasWindow(ctx, sessionName, "agent"); err != nil || exists {
+		t.Errorf("HasWindow(old name) = %v, %v; want false", exists, err)
+	}
+	if exists, err := client.HasWindow(ctx, sessionName, id); err != nil || !exists {
+		t.Errorf("HasWindow(%s) = %v, %v; want true", id, exists, err)
+	}
+	if got, err := client.GetWindowID(ctx, sessionName, "renamed"); err != nil || got != id {
+		t.Errorf("GetWindowID(renamed) = %q, %v; want %q", got, err, id)
+	}
+	if err := client.SendKeysLiteral(ctx, sessionName, id, "echo by-id"); err != nil {
+		t.Errorf("SendKeysLiteral by ID failed: %v", err)
+	}
+	if exists, err := client.HasWindow(ctx, sessionName, "@999999"); err != nil || exists {
+		t.Errorf("HasWindow(@999999) = %v, %v; want false", exists, err)
+	}
+}
+
+func TestIsWindowID(t *testing.T) {
+	for window, want := range map[string]bool{
+		"@0": true, "@12": true, "@": false, "worker": false, "@x": false, "": false, "1": false,
+	} {
+		if got := IsWindowID(window); got != want {
+			t.Errorf("IsWindowID(%q) = %v, want %v", window, got, want)
+		}
+	}
+}
+
 func TestHasWindowExactMatch(t *testing.T) {
 	ctx := context.Background()
 	client := NewClient()
@@ -395,6 +451,208 @@ func TestKillSession(t *testing.T) {
 	}
 }
 
+func TestSetEnvironment(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	sessionName := uniqueSessionName()
+
+	if err := client.CreateSession(ctx, sessionName, true); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer client.KillSession(ctx, sessionName)
+
+	// Unset variable should report not found
+	_, found, err := client.GetEnvironment(ctx, sessionName, "MC_TEST_VAR")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	if found {
+		t.Error("MC_TEST_VAR should not be set yet")
+	}
+
+	if err := client.SetEnvironment(ctx, sessionName, "MC_TEST_VAR", "secret value=1"); err != nil {
+		t.Fatalf("SetEnvironment failed: %v", err)
+	}
+
+	value, found, err := client.GetEnvironment(ctx, sessionName, "MC_TEST_VAR")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	if !found {
+		t.Fatal("MC_TEST_VAR should be set")
+	}
+	if value != "secret value=1" {
+		t.Errorf("GetEnvironment() = %q, want %q", value, "secret value=1")
+	}
+}
+
+func TestSessionOption(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	tagged := uniqueSessionName()
+	untagged := uniqueSessionName() + "-untagged"
+
+	for _, name := range []string{tagged, untagged} {
+		if err := client.CreateSession(ctx, name, true); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		defer client.KillSession(ctx, name)
+	}
+
+	if err := client.SetSessionOption(ctx, tagged, "@mc-test-tag", "/some root"); err != nil {
+		t.Fatalf("SetSessionOption failed: %v", err)
+	}
+	sessions, err := client.ListSessionOption(ctx, "@mc-test-tag")
+	if err != nil {
+		t.Fatalf("ListSessionOption failed: %v", err)
+	}
+	if value, ok := sessions[tagged]; !ok || value != "/some root" {
+		t.Errorf("tagged session = %q, %v, want /some root", value, ok)
+	}
+	if value, ok := sessions[untagged]; !ok || value != "" {
+		t.Errorf("untagged session = %q, %v, want listed without a value", value, ok)
+	}
+
+	if err := client.SetSessionOption(ctx, "nonexistent-session-xyz", "@mc-test-tag", "x"); err == nil {
+		t.Error("SetSessionOption should fail for non-existent session")
+	}
+}
+
+func TestImportEnvironmentCommand(t *testing.T) {
+	if got := ImportEnvironmentCommand(); got != "" {
+		t.Errorf("ImportEnvironmentCommand() = %q, want empty", got)
+	}
+
+	got := ImportEnvironmentCommand("A", "B")
+	want := `eval "$(tmux show-environment -s A; tmux show-environment -s B)"`
+	if got != want {
+		t.Errorf("ImportEnvironmentCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestSetEnvironmentOnNonExistentSession(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+
+	err := client.SetEnvironment(ctx, "nonexistent-session-xyz", "MC_TEST_VAR", "value")
+	if err == nil {
+		t.Error("SetEnvironment should fail for non-existent session")
+	}
+}
+
+func TestSetPaneEnv(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	sessionName := uniqueSessionName()
+
+	if err := client.CreateSession(ctx, sessionName, true); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer client.KillSession(ctx, sessionName)
+
+	windowName := "env-window"
+	if err := client.CreateWindow(ctx, sessionName, windowName); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	if err := client.SetPaneEnv(ctx, sessionName, windowName, "MC_TEST_VAR", "fresh token"); err != nil {
+		t.Fatalf("SetPaneEnv failed: %v", err)
+	}
+	value, found, err := client.GetEnvironment(ctx, sessionName, "MC_TEST_VAR")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	if !found || value != "fresh token" {
+		t.Errorf("GetEnvironment() = %q, %v; want %q", value, found, "fresh token")
+	}
+
+	err = client.SetPaneEnv(ctx, sessionName, "missing-window", "MC_TEST_VAR", "value")
+	if !IsWindowNotFound(err) {
+		t.Errorf("SetPaneEnv on a missing window = %v, want WindowNotFoundError", err)
+	}
+}
+
+func TestRestartWithEnv(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	sessionName := uniqueSessionName()
+
+	if err := client.CreateSession(ctx, sessionName, true); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer client.KillSession(ctx, sessionName)
+
+	windowName := "restart-window"
+	if err := client.CreateWindow(ctx, sessionName, windowName); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	// Leave a long-running process in the pane
+	if err := client.SendKeys(ctx, sessionName, windowName, "sleep 300"); err != nil {
+		t.Fatalf("Failed to send keys: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	oldPID, err := client.GetPanePID(ctx, sessionName, windowName)
+	if err != nil {
+		t.Fatalf("Failed to get pane PID: %v", err)
+	}
+
+	start := time.Now()
+	if err := client.RestartWithEnv(ctx, sessionName, windowName, map[string]string{"MC_TEST_VAR": "restarted"}); err != nil {
+		t.Fatalf("RestartWithEnv failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= RestartGracePeriod {
+		t.Errorf("RestartWithEnv took %v; SIGTERM should have stopped sleep quickly", elapsed)
+	}
+
+	newPID, err := client.GetPanePID(ctx, sessionName, windowName)
+	if err != nil {
+		t.Fatalf("Failed to get pane PID: %v", err)
+	}
+	if newPID == oldPID {
+		t.Error("Pane should have been respawned with a new process")
+	}
+
+	// The respawned shell has the new variable. It may not read input yet,
+	// so the command is sent again until it has run.
+	if got := paneEnvVar(t, client, sessionName, windowName, "MC_TEST_VAR"); got != "restarted" {
+		t.Errorf("Respawned pane sees MC_TEST_VAR = %q, want %q", got, "restarted")
+	}
+
+	// Other windows, including those created later, do not
+	if _, ok, err := client.GetEnvironment(ctx, sessionName, "MC_TEST_VAR"); err != nil || ok {
+		t.Errorf("MC_TEST_VAR set in the session environment (ok=%v, err=%v)", ok, err)
+	}
+	if err := client.CreateWindow(ctx, sessionName, "later-window"); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+	if got := paneEnvVar(t, client, sessionName, "later-window", "MC_TEST_VAR"); got != "" {
+		t.Errorf("Window created later sees MC_TEST_VAR = %q, want it unset", got)
+	}
+}
+
+// paneEnvVar returns the value of a variable in the shell of a window's pane,
+// sending the command to print it until the shell has run it
+func paneEnvVar(t *testing.T, client *Client, session, window, name string) string {
+	t.Helper()
+	ctx := context.Background()
+	outFile := filepath.Join(t.TempDir(), "env.txt")
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		if err := client.SendKeys(ctx, session, window, fmt.Sprintf("echo \"$%s\" > %s.tmp && mv %s.tmp %s", name, outFile, outFile, outFile)); err != nil {
+			t.Fatalf("Failed to send keys: %v", err)
+		}
+		for i := 0; i < 10; i++ {
+			time.Sleep(100 * time.Millisecond)
+			if data, err := os.ReadFile(outFile); err == nil {
+				return strings.TrimSpace(string(data))
+			}
+		}
+	}
+	t.Fatalf("The shell of %s:%s never ran the command", session, window)
+	return ""
+}
+
 func TestSendKeys(t *testing.T) {
 	ctx := context.Background()
 	client := NewClient()
@@ -661,6 +919,99 @@ func TestListWindows(t *testing.T) {
 	}
 }
 
+func TestListWindowInfo(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	sessionName := uniqueSessionName()
+
+	if err := client.CreateSession(ctx, sessionName, true); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer client.KillSession(ctx, sessionName)
+
+	if err := client.CreateWindow(ctx, sessionName, "window1"); err != nil {
+		t.Fatalf("Failed to create window1: %v", err)
+	}
+	if err := client.CreateWindow(ctx, sessionName, "window two"); err != nil {
+		t.Fatalf("Failed to create window two: %v", err)
+	}
+	if err := exec.Command("tmux", "split-window", "-d", "-t", sessionName+":window1").Run(); err != nil {
+		t.Fatalf("Failed to split window1: %v", err)
+	}
+
+	windows, err := client.ListWindowInfo(ctx, sessionName)
+	if err != nil {
+		t.Fatalf("Failed to list windows: %v", err)
+	}
+	if len(windows) != 3 {
+		t.Fatalf("Expected 3 windows, got %d: %+v", len(windows), windows)
+	}
+
+	active := 0
+	for i, w := range windows {
+		if i > 0 && w.Index <= windows[i-1].Index {
+			t.Errorf("Windows not in index order: %+v", windows)
+		}
+		if w.Active {
+			active++
+		}
+		if w.Activity.IsZero() {
+			t.Errorf("Window %q has no activity time", w.Name)
+		}
+	}
+	if active != 1 {
+		t.Errorf("Expected exactly one active window, got %d: %+v", active, windows)
+	}
+
+	byName := make(map[string]WindowInfo)
+	for _, w := range windows {
+		byName[w.Name] = w
+	}
+	if byName["window1"].Panes != 2 {
+		t.Errorf("window1 panes = %d, want 2", byName["window1"].Panes)
+	}
+	if w, ok := byName["window two"]; !ok || w.Panes != 1 {
+		t.Errorf("window two missing or wrong pane count: %+v", windows)
+	}
+
+	if _, err := client.ListWindowInfo(ctx, "nonexistent-session"); err == nil {
+		t.Error("ListWindowInfo on non-existent session should fail")
+	}
+}
+
+func TestParseWindowInfo(t *testing.T) {
+	output := "2:0:1:1700000100:@7:worker\n0:1:3:1700000000:@1:super:visor\n1:0:1:0:@2:merge-queue\n"
+	windows, err := parseWindowInfo(output)
+	if err != nil {
+		t.Fatalf("parseWindowInfo failed: %v", err)
+	}
+	if len(windows) != 3 {
+		t.Fatalf("Expected 3 windows, got %d", len(windows))
+	}
+
+	want := []WindowInfo{
+		{Index: 0, ID: "@1", Name: "super:visor", Active: true, Panes: 3, Activity: time.Unix(1700000000, 0)},
+		{Index: 1, ID: "@2", Name: "merge-queue", Panes: 1},
+		{Index: 2, ID: "@7", Name: "worker", Panes: 1, Activity: time.Unix(1700000100, 0)},
+	}
+	for i, w := range want {
+		got := windows[i]
+		if got.Index != w.Index || got.ID != w.ID || got.Name != w.Name || got.Active != w.Active || got.Panes != w.Panes || !got.Activity.Equal(w.Activity) {
+			t.Errorf("window %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	if windows, err := parseWindowInfo(""); err != nil || len(windows) != 0 {
+		t.Errorf("parseWindowInfo(\"\") = %v, %v; want no windows", windows, err)
+	}
+	if _, err := parseWindowInfo("x:0:1:0:@1:name\n"); err == nil {
+		t.Error("Expected error for invalid window index")
+	}
+	if _, err := parseWindowInfo("0:1\n"); err == nil {
+		t.Error("Expected error for truncated line")
+	}
+}
+
 func TestGetPanePID(t *testing.T) {
 	ctx := context.Background()
 	client := NewClient()
@@ -699,6 +1050,49 @@ func TestGetPanePID(t *testing.T) {
 	}
 }
 
+func TestCapturePane(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	sessionName := uniqueSessionName()
+
+	if err := client.CreateSession(ctx, sessionName, true); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer client.KillSession(ctx, sessionName)
+
+	windowName := "test-window"
+	if err := client.CreateWindow(ctx, sessionName, windowName); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+	if err := client.SendKeys(ctx, sessionName, windowName, "printf 'one\\ntwo\\nthree\\n'"); err != nil {
+		t.Fatalf("Failed to send keys: %v", err)
+	}
+
+	var output string
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		var err error
+		if output, err = client.CapturePane(ctx, sessionName, windowName, 50); err != nil {
+			t.Fatalf("CapturePane() failed: %v", err)
+		}
+		if strings.Contains(output, "one\ntwo\nthree") {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if !strings.Contains(output, "one\ntwo\nthree") {
+		t.Errorf("CapturePane() = %q, want the printed lines", output)
+	}
+
+	if output, err := client.CapturePane(ctx, sessionName, windowName, 1); err != nil || strings.Contains(output, "\n") {
+		t.Errorf("CapturePane(1) = %q, %v; want one line", output, err)
+	}
+
+	if _, err := client.CapturePane(ctx, "test-nonexistent-session", windowName, 10); err == nil {
+		t.Error("CapturePane() should fail for a missing session")
+	}
+}
+
 func TestMultipleSessions(t *testing.T) {
 	ctx := context.Background()
 	client := NewClient()